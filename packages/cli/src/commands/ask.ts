@@ -0,0 +1,167 @@
+/**
+ * cv ask command
+ * One-shot question over the codebase for scripts: one retrieval, one
+ * answer, no session. Retrieval, model and system prompt are the ones
+ * cv chat uses (config models.chat and prompts.chat).
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import ora from 'ora';
+import {
+  configManager,
+  createChatSession,
+  toChatHistory,
+  composeSystemPrompt,
+  getPromptVariables
+} from '@cv-git/core';
+import { findRepoRoot, withRequestTimeout } from '@cv-git/shared';
+import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { abortOnInterrupt, isAbortError } from '../utils/interrupt.js';
+import {
+  addRetrievalOptions,
+  addFileScopeOption,
+  resolveRetrieval,
+  resolveFileScope,
+  formatNearMiss
+} from '../utils/retrieval.js';
+import { addModelOption } from '../utils/model.js';
+import { addSystemPromptOptions, resolveSystemPrompt } from '../utils/system-prompt.js';
+import { addTimeoutOption, resolveChatTimeout, printTimeoutHint } from '../utils/timeout.js';
+import { printProxyHint } from '../utils/network.js';
+import {
+  CHAT_SYSTEM_PROMPT,
+  FileScope,
+  GatheredContext,
+  connectChatServices,
+  gatherContext,
+  formatCitation,
+  cleanup
+} from './chat.js';
+import { toAskResult } from '../utils/ask-result.js';
+
+export function askCommand(): Command {
+  const cmd = new Command('ask');
+
+  cmd
+    .description('Answer one question about the codebase and exit (non-interactive cv chat)')
+    .argument('<question>', 'Question to answer')
+    .option('--no-context', 'Answer without retrieving code')
+    .option('--no-stream', 'Print the answer once it is complete instead of streaming tokens');
+
+  addModelOption(cmd, 'chat');
+  addSystemPromptOptions(cmd, 'chat');
+  addRetrievalOptions(cmd);
+  addFileScopeOption(cmd);
+  addTimeoutOption(cmd);
+  addGlobalOptions(cmd);
+
+  cmd.action(async (question: string, options) => {
+    const output = createOutput(options);
+    // Ctrl-C cancels the search or the answer in flight
+    const interrupt = abortOnInterrupt();
+    let spinner: ReturnType<typeof ora> | undefined;
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        throw new Error('Not in a CV-Git repository. Run `cv init` first.');
+      }
+      if (!question.trim()) {
+        throw new Error('The question is empty');
+      }
+
+      const config = await configManager.load(repoRoot);
+      const retrieval = resolveRetrieval(options, config.search, { minScore: 0.5, topK: 5 }, repoRoot);
+      const scope: FileScope = { repoRoot, files: resolveFileScope(options.file, repoRoot) };
+
+      const custom = await resolveSystemPrompt('chat', options, config);
+      const systemPrompt = composeSystemPrompt(CHAT_SYSTEM_PROMPT, custom, custom && {
+        ...getPromptVariables(custom),
+        files: scope.files.join(', ')
+      });
+
+      const { client, vector, graph } = await connectChatServices(
+        repoRoot,
+        config,
+        { model: options.model, noContext: options.context === false, signal: interrupt.signal },
+        retrieval,
+        output
+      );
+
+      // Progress goes to stderr so stdout carries only the answer
+      const showProgress = !output.isJson && !options.quiet;
+
+      let context: GatheredContext = { text: '', chunkCount: 0, citations: [] };
+      if (vector || scope.files.length > 0) {
+        spinner = showProgress ? ora({ text: 'Searching codebase...', stream: process.stderr }).start() : undefined;
+        context = await gatherContext(question, vector, graph, retrieval, scope);
+        spinner?.stop();
+        const nearMiss = context.chunkCount === 0 ? formatNearMiss(context.nearMissScore, retrieval.minScore) : null;
+        if (nearMiss && showProgress) {
+          console.error(chalk.gray(nearMiss));
+        }
+      }
+      interrupt.signal.throwIfAborted();
+
+      // A single turn built the way chat builds one; the session is never saved
+      const session = createChatSession(client.getModel());
+      session.messages.push({ role: 'user', content: question, context: context.text || undefined, timestamp: Date.now() });
+      const messages = toChatHistory(session);
+
+      const stream = options.stream !== false && !output.isJson;
+      spinner = showProgress && !stream ? ora({ text: 'Thinking...', stream: process.stderr }).start() : undefined;
+      const answer = await withRequestTimeout(`${client.getProvider()} request`, (signal, keepAlive) => stream
+        ? client.chatStream(messages, systemPrompt, {
+            signal,
+            onToken: token => {
+              keepAlive();
+              process.stdout.write(token);
+            }
+          })
+        : client.chat(messages, systemPrompt, signal),
+        { timeoutMs: resolveChatTimeout(config, options.timeout), signal: interrupt.signal }
+      );
+      spinner?.stop();
+      interrupt.dispose();
+
+      if (output.isJson) {
+        output.json(toAskResult(question, answer, client.getModel(), context.citations));
+      } else {
+        if (stream) {
+          process.stdout.write('\n');
+        } else {
+          console.log(answer);
+        }
+        if (showProgress && context.citations.length > 0) {
+          console.error(chalk.gray('\nSources:'));
+          for (const citation of context.citations) {
+            console.error(chalk.gray(`  ${formatCitation(citation)}`));
+          }
+        }
+      }
+
+      await cleanup(vector, graph);
+    } catch (error: any) {
+      spinner?.stop();
+      if (interrupt.signal.aborted || isAbortError(error)) {
+        if (!output.isJson) console.error(chalk.yellow('\n[aborted]'));
+        process.exit(130);
+      }
+
+      if (output.isJson) {
+        output.error('Ask failed', error, 'ASK_FAILED');
+      } else {
+        console.error(chalk.red(`Error: ${error.message}`));
+        printProxyHint(error);
+        printTimeoutHint(error);
+        if (process.env.CV_DEBUG) {
+          console.error(chalk.gray(error.stack));
+        }
+      }
+      process.exit(1);
+    }
+  });
+
+  return cmd;
+}
@@ -6,7 +6,8 @@
  *
  * Categories:
  * - git: GitHub, GitLab, Bitbucket
- * - ai: Anthropic, OpenAI, OpenRouter
+ * - ai: Anthropic, OpenAI, OpenRouter, Ollama, Azure OpenAI, Gemini, Cohere, Voyage AI, HuggingFace,
+ *   OpenAI-compatible gateways
  * - dns: Cloudflare
  * - devops: AWS, DigitalOcean
  */
@@ -27,7 +28,25 @@ import {
   AnthropicAPICredential,
   OpenAIAPICredential,
   OpenRouterAPICredential,
+  OllamaEndpointCredential,
+  AzureOpenAICredential,
+  GeminiAPICredential,
+  CohereAPICredential,
+  VoyageAPICredential,
+  HuggingFaceAPICredential,
+  OpenAICompatibleCredential,
 } from '@cv-git/credentials';
+import {
+  createAzureOpenAIClient,
+  createGeminiClient,
+  CohereClient,
+  VoyageClient,
+  HuggingFaceClient,
+  DEFAULT_AZURE_API_VERSION,
+  DEFAULT_HUGGINGFACE_EMBEDDING_MODEL,
+  OPENAI_COMPATIBLE_PRESETS,
+  listOpenAICompatibleModels
+} from '@cv-git/core';
 import { GitHubAdapter, GitLabAdapter, BitbucketAdapter } from '@cv-git/platform';
 import { getPreferences } from '../config.js';
 import { getRequiredServices } from '../utils/preference-picker.js';
@@ -73,6 +92,9 @@ export function authCommand(): Command {
 
       const credentials = new CredentialManager();
       await credentials.init();
+      if (credentials.getProfileName() !== 'default') {
+        console.log(chalk.gray(`Keys are saved to profile ${credentials.getProfileName()}\n`));
+      }
 
       // Migrate from environment variables first
       console.log('Checking for environment variables to migrate...');
@@ -649,6 +671,27 @@ async function runSetup(
     case 'openrouter':
       await setupOpenRouter(credentials, autoBrowser);
       return true;
+    case 'ollama':
+      await setupOllama(credentials);
+      return true;
+    case 'azure':
+      await setupAzureOpenAI(credentials);
+      return true;
+    case 'gemini':
+      await setupGemini(credentials, autoBrowser);
+      return true;
+    case 'cohere':
+      await setupCohere(credentials, autoBrowser);
+      return true;
+    case 'voyage':
+      await setupVoyage(credentials, autoBrowser);
+      return true;
+    case 'huggingface':
+      await setupHuggingFace(credentials, autoBrowser);
+      return true;
+    case 'openai-compatible':
+      await setupOpenAICompatible(credentials);
+      return true;
 
     // DNS providers
     case 'cloudflare':
@@ -792,6 +835,139 @@ async function runTest(service: string, credentials: CredentialManager): Promise
         break;
       }
 
+      case 'azure': {
+        const azure = await credentials.getAzureOpenAI();
+        if (!azure) {
+          spinner.fail(chalk.red('Azure OpenAI not configured'));
+          console.log(chalk.gray('Run: ') + chalk.cyan('cv auth setup azure'));
+          return;
+        }
+        if (azure.chatDeployment) {
+          await createAzureOpenAIClient({
+            endpoint: azure.endpoint,
+            apiKey: azure.apiKey,
+            apiVersion: azure.apiVersion,
+            deployment: azure.chatDeployment,
+            maxTokens: 1,
+            maxRetryAttempts: 1,
+          }).chat([{ role: 'user', content: 'ping' }]);
+          spinner.succeed(chalk.green('Azure OpenAI chat deployment reachable'));
+        } else {
+          spinner.succeed(chalk.green('Azure OpenAI credentials found'));
+        }
+        console.log(chalk.gray('  Endpoint:    ') + chalk.white(azure.endpoint));
+        console.log(chalk.gray('  API version: ') + chalk.white(azure.apiVersion));
+        console.log(chalk.gray('  Chat:        ') + chalk.white(azure.chatDeployment || '(not set)'));
+        console.log(chalk.gray('  Embeddings:  ') + chalk.white(azure.embeddingDeployment || '(not set)'));
+        break;
+      }
+
+      case 'gemini': {
+        const key = await credentials.getGeminiKey();
+        if (!key) {
+          spinner.fail(chalk.red('Gemini API key not found'));
+          console.log(chalk.gray('Run: ') + chalk.cyan('cv auth setup gemini'));
+          return;
+        }
+        await createGeminiClient({ apiKey: key, maxTokens: 1, maxRetryAttempts: 1 })
+          .chat([{ role: 'user', content: 'ping' }]);
+        spinner.succeed(chalk.green('Gemini API key valid'));
+        console.log(chalk.gray('  Key: ') + chalk.white(key.substring(0, 10) + '...'));
+        break;
+      }
+
+      case 'cohere': {
+        const key = await credentials.getCohereKey();
+        if (!key) {
+          spinner.fail(chalk.red('Cohere API key not found'));
+          console.log(chalk.gray('Run: ') + chalk.cyan('cv auth setup cohere'));
+          return;
+        }
+        await new CohereClient({ apiKey: key, maxRetryAttempts: 1 }).embed(['ping']);
+        spinner.succeed(chalk.green('Cohere API key valid'));
+        console.log(chalk.gray('  Key: ') + chalk.white(key.substring(0, 10) + '...'));
+        break;
+      }
+
+      case 'voyage': {
+        const key = await credentials.getVoyageKey();
+        if (!key) {
+          spinner.fail(chalk.red('Voyage AI API key not found'));
+          console.log(chalk.gray('Run: ') + chalk.cyan('cv auth setup voyage'));
+          return;
+        }
+        await new VoyageClient({ apiKey: key, maxRetryAttempts: 1 }).embed(['ping']);
+        spinner.succeed(chalk.green('Voyage AI API key valid'));
+        console.log(chalk.gray('  Key: ') + chalk.white(key.substring(0, 10) + '...'));
+        break;
+      }
+
+      case 'huggingface': {
+        const cred = await credentials.getHuggingFace();
+        if (!cred) {
+          spinner.fail(chalk.red('HuggingFace token not found'));
+          console.log(chalk.gray('Run: ') + chalk.cyan('cv auth setup huggingface'));
+          return;
+        }
+        const model = cred.model || DEFAULT_HUGGINGFACE_EMBEDDING_MODEL;
+        const [vector] = await new HuggingFaceClient({ apiKey: cred.apiKey, baseUrl: cred.baseUrl, maxRetryAttempts: 1 }).embed(['ping'], model);
+        spinner.succeed(chalk.green(cred.baseUrl ? 'TEI server reachable' : 'HuggingFace token valid'));
+        if (cred.apiKey) {
+          console.log(chalk.gray('  Token:      ') + chalk.white(cred.apiKey.substring(0, 10) + '...'));
+        }
+        if (cred.baseUrl) {
+          console.log(chalk.gray('  URL:        ') + chalk.white(cred.baseUrl));
+        } else {
+          console.log(chalk.gray('  Model:      ') + chalk.white(model));
+        }
+        console.log(chalk.gray('  Dimensions: ') + chalk.white(String(vector.length)));
+        break;
+      }
+
+      case 'openai-compatible': {
+        const cred = await credentials.getOpenAICompatible();
+        if (!cred) {
+          spinner.fail(chalk.red('OpenAI-compatible gateway not configured'));
+          console.log(chalk.gray('Run: ') + chalk.cyan('cv auth setup openai-compatible'));
+          return;
+        }
+        const models = await listOpenAICompatibleModels(cred.baseUrl, cred.apiKey);
+        spinner.succeed(chalk.green('Gateway reachable'));
+        console.log(chalk.gray('  URL:             ') + chalk.white(cred.baseUrl));
+        console.log(chalk.gray('  Models served:   ') + chalk.white(String(models.length)));
+        if (cred.chatModel) {
+          console.log(chalk.gray('  Chat model:      ') + chalk.white(cred.chatModel));
+        }
+        if (cred.embeddingModel) {
+          console.log(chalk.gray('  Embedding model: ') + chalk.white(cred.embeddingModel));
+        }
+        break;
+      }
+
+      case 'ollama': {
+        const endpoint = await credentials.getOllamaEndpoint();
+        if (!endpoint) {
+          spinner.fail(chalk.red('Ollama endpoint not configured'));
+          console.log(chalk.gray('Run: ') + chalk.cyan('cv auth setup ollama'));
+          return;
+        }
+        const models = await fetchOllamaModels(endpoint.baseUrl);
+        if (!models) {
+          spinner.fail(chalk.red(`Ollama not reachable at ${endpoint.baseUrl}`));
+          console.log(chalk.gray('Start Ollama with: ') + chalk.cyan('ollama serve'));
+          return;
+        }
+        if (models.includes(endpoint.model.split(':')[0])) {
+          spinner.succeed(chalk.green('Ollama endpoint reachable'));
+        } else {
+          spinner.warn(chalk.yellow(`Ollama reachable, but model ${endpoint.model} is not installed`));
+          console.log(chalk.gray('Run: ') + chalk.cyan(`ollama pull ${endpoint.model}`));
+        }
+        console.log(chalk.gray('  URL:   ') + chalk.white(endpoint.baseUrl));
+        console.log(chalk.gray('  Model: ') + chalk.white(endpoint.model));
+        break;
+      }
+
       case 'cloudflare': {
         spinner.stop();
         await testCloudflare(credentials);
@@ -827,7 +1003,7 @@ async function runTest(service: string, credentials: CredentialManager): Promise
         spinner.fail(chalk.red(`Unknown service: ${service}`));
         console.log(chalk.gray('\nAvailable services:'));
         console.log(chalk.gray('  Git: github, gitlab, bitbucket, cv-hub, controlfab'));
-        console.log(chalk.gray('  AI: anthropic, openai, openrouter'));
+        console.log(chalk.gray('  AI: anthropic, openai, openrouter, ollama, azure, gemini, cohere, voyage, huggingface, openai-compatible'));
         console.log(chalk.gray('  DNS: cloudflare'));
         console.log(chalk.gray('  DevOps: aws, digitalocean, digitalocean-spaces'));
         console.log(chalk.gray('  Publish: npm'));
@@ -1067,11 +1243,13 @@ async function setupAnthropic(credentials: CredentialManager, autoBrowser: boole
 
   await credentials.store<AnthropicAPICredential>({
     type: CredentialType.ANTHROPIC_API,
-    name: 'default',
+    name: credentials.getProfileName(),
     apiKey,
   });
 
   console.log(chalk.green('✅ Anthropic authentication configured!\n'));
+  console.log(chalk.gray('cv chat and cv code -p anthropic now call Claude directly.'));
+  console.log(chalk.gray('To cache retrieved code between questions: ') + chalk.cyan('cv config set ai.promptCaching true\n'));
 }
 
 async function setupOpenAI(credentials: CredentialManager, autoBrowser: boolean = true): Promise<void> {
@@ -1110,7 +1288,7 @@ async function setupOpenAI(credentials: CredentialManager, autoBrowser: boolean
 
   await credentials.store<OpenAIAPICredential>({
     type: CredentialType.OPENAI_API,
-    name: 'default',
+    name: credentials.getProfileName(),
     apiKey,
   });
 
@@ -1153,13 +1331,465 @@ async function setupOpenRouter(credentials: CredentialManager, autoBrowser: bool
 
   await credentials.store<OpenRouterAPICredential>({
     type: CredentialType.OPENROUTER_API,
-    name: 'default',
+    name: credentials.getProfileName(),
     apiKey,
   });
 
   console.log(chalk.green('✅ OpenRouter authentication configured!\n'));
 }
 
+/**
+ * List model names installed on an Ollama server (null if unreachable)
+ */
+async function fetchOllamaModels(baseUrl: string): Promise<string[] | null> {
+  try {
+    const response = await fetch(`${baseUrl.replace(/\/$/, '')}/api/tags`, {
+      signal: AbortSignal.timeout(5000),
+    });
+    if (!response.ok) return null;
+    const data = await response.json() as { models?: Array<{ name: string }> };
+    return (data.models || []).map(m => m.name.split(':')[0]);
+  } catch {
+    return null;
+  }
+}
+
+async function setupOllama(credentials: CredentialManager): Promise<void> {
+  console.log(chalk.bold('──────────────────────────────────────────'));
+  console.log(chalk.bold.cyan('Ollama (Local Embeddings)'));
+  console.log(chalk.bold('──────────────────────────────────────────\n'));
+
+  console.log(chalk.gray('Embeddings are generated locally - no code is sent to a cloud provider.'));
+  console.log(chalk.gray('Install Ollama from ') + chalk.blue('https://ollama.com') + chalk.gray(', then run ') + chalk.white('ollama serve'));
+  console.log();
+
+  const existing = await credentials.getOllamaEndpoint();
+
+  const { baseUrl, model } = await inquirer.prompt([
+    {
+      type: 'input',
+      name: 'baseUrl',
+      message: 'Ollama base URL:',
+      default: existing?.baseUrl || 'http://localhost:11434',
+      validate: (input: string) => {
+        try {
+          const url = new URL(input.trim());
+          return url.protocol === 'http:' || url.protocol === 'https:' || 'URL must start with http:// or https://';
+        } catch {
+          return 'Invalid URL';
+        }
+      },
+      filter: (input: string) => input.trim().replace(/\/$/, ''),
+    },
+    {
+      type: 'input',
+      name: 'model',
+      message: 'Embedding model:',
+      default: existing?.model || 'nomic-embed-text',
+      validate: (input: string) => (input && input.trim() ? true : 'Model name is required'),
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  const spinner = ora(`Checking Ollama at ${baseUrl}...`).start();
+  const models = await fetchOllamaModels(baseUrl);
+  if (!models) {
+    spinner.warn(chalk.yellow(`Ollama not reachable at ${baseUrl} - saving anyway`));
+  } else if (!models.includes(model.split(':')[0])) {
+    spinner.warn(chalk.yellow(`Model ${model} is not installed. Run: ollama pull ${model}`));
+  } else {
+    spinner.succeed(chalk.green(`Ollama reachable, model ${model} installed`));
+  }
+
+  await credentials.store<OllamaEndpointCredential>({
+    type: CredentialType.OLLAMA_ENDPOINT,
+    name: credentials.getProfileName(),
+    baseUrl,
+    model,
+  });
+
+  console.log(chalk.green('✅ Ollama endpoint configured!'));
+  console.log(chalk.gray('Set ') + chalk.white('embedding.provider') + chalk.gray(' to ') + chalk.white('ollama') +
+    chalk.gray(' in .cv/config.json (the default), then run ') + chalk.cyan('cv sync --force') + chalk.gray(' to rebuild the index.\n'));
+}
+
+async function setupAzureOpenAI(credentials: CredentialManager): Promise<void> {
+  console.log(chalk.bold('──────────────────────────────────────────'));
+  console.log(chalk.bold.cyan('Azure OpenAI'));
+  console.log(chalk.bold('──────────────────────────────────────────\n'));
+
+  console.log(chalk.gray('Find the endpoint and keys under ') + chalk.white('Resource Management > Keys and Endpoint') +
+    chalk.gray(' in the Azure portal.'));
+  console.log(chalk.gray('Deployment names are the names you gave each model deployment, not the model names.'));
+  console.log();
+
+  const existing = await credentials.getAzureOpenAI();
+
+  const answers = await inquirer.prompt([
+    {
+      type: 'input',
+      name: 'endpoint',
+      message: 'Endpoint URL:',
+      default: existing?.endpoint,
+      validate: (input: string) => {
+        try {
+          return new URL(input.trim()).protocol === 'https:' || 'Endpoint must start with https://';
+        } catch {
+          return 'Invalid URL (e.g. https://my-resource.openai.azure.com)';
+        }
+      },
+      filter: (input: string) => input.trim().replace(/\/+$/, ''),
+    },
+    {
+      type: 'password',
+      name: 'apiKey',
+      message: existing ? 'API key (leave empty to keep current):' : 'API key:',
+      validate: (input: string) => (existing || (input && input.trim()) ? true : 'API key is required'),
+      filter: (input: string) => input.trim(),
+    },
+    {
+      type: 'input',
+      name: 'apiVersion',
+      message: 'API version:',
+      default: existing?.apiVersion || DEFAULT_AZURE_API_VERSION,
+      filter: (input: string) => input.trim(),
+    },
+    {
+      type: 'input',
+      name: 'chatDeployment',
+      message: 'Chat deployment name (for cv explain / cv chat, empty to skip):',
+      default: existing?.chatDeployment,
+      filter: (input: string) => input.trim(),
+    },
+    {
+      type: 'input',
+      name: 'embeddingDeployment',
+      message: 'Embedding deployment name (for cv sync, empty to skip):',
+      default: existing?.embeddingDeployment,
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  await credentials.store<AzureOpenAICredential>({
+    type: CredentialType.AZURE_OPENAI,
+    name: credentials.getProfileName(),
+    endpoint: answers.endpoint,
+    apiKey: answers.apiKey || existing!.apiKey,
+    apiVersion: answers.apiVersion || DEFAULT_AZURE_API_VERSION,
+    chatDeployment: answers.chatDeployment || undefined,
+    embeddingDeployment: answers.embeddingDeployment || undefined,
+  });
+
+  console.log(chalk.green('✅ Azure OpenAI configured!'));
+  console.log(chalk.gray('Set ') + chalk.white('ai.provider') + chalk.gray(' and/or ') + chalk.white('embedding.provider') +
+    chalk.gray(' to ') + chalk.white('azure') + chalk.gray(' in .cv/config.json, then run ') + chalk.cyan('cv sync --force') +
+    chalk.gray(' to rebuild the index.\n'));
+}
+
+async function setupGemini(credentials: CredentialManager, autoBrowser: boolean = true): Promise<void> {
+  console.log(chalk.bold('──────────────────────────────────────────'));
+  console.log(chalk.bold.cyan('Google Gemini Authentication'));
+  console.log(chalk.bold('──────────────────────────────────────────\n'));
+
+  const url = 'https://aistudio.google.com/app/apikey';
+
+  if (autoBrowser) {
+    console.log(chalk.cyan('Opening browser to get API key...'));
+    await openBrowser(url);
+    console.log();
+  }
+
+  console.log(chalk.gray('URL: ') + chalk.blue(url));
+  console.log(chalk.gray('Copy your API key (starts with ') + chalk.white('AIza') + chalk.gray(')'));
+  console.log();
+
+  const { apiKey } = await inquirer.prompt([
+    {
+      type: 'password',
+      name: 'apiKey',
+      message: 'Enter your Gemini API key:',
+      validate: (input: string) => (input && input.trim() ? true : 'API key is required'),
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  await credentials.store<GeminiAPICredential>({
+    type: CredentialType.GEMINI_API,
+    name: credentials.getProfileName(),
+    apiKey,
+  });
+
+  console.log(chalk.green('✅ Gemini authentication configured!'));
+  console.log(chalk.gray('Set ') + chalk.white('ai.provider') + chalk.gray(' to ') + chalk.white('gemini') +
+    chalk.gray(' for cv explain / cv chat, or run ') + chalk.cyan('cv code -p gemini') + chalk.gray('.'));
+  console.log(chalk.gray('For embeddings, set ') + chalk.white('embedding.provider') + chalk.gray(' to ') + chalk.white('gemini') +
+    chalk.gray(' and run ') + chalk.cyan('cv sync --force') + chalk.gray(' to rebuild the index.\n'));
+}
+
+async function setupCohere(credentials: CredentialManager, autoBrowser: boolean = true): Promise<void> {
+  console.log(chalk.bold('──────────────────────────────────────────'));
+  console.log(chalk.bold.cyan('Cohere Authentication'));
+  console.log(chalk.bold('──────────────────────────────────────────\n'));
+
+  const url = 'https://dashboard.cohere.com/api-keys';
+
+  if (autoBrowser) {
+    console.log(chalk.cyan('Opening browser to get API key...'));
+    await openBrowser(url);
+    console.log();
+  }
+
+  console.log(chalk.gray('URL: ') + chalk.blue(url));
+  console.log();
+
+  const { apiKey } = await inquirer.prompt([
+    {
+      type: 'password',
+      name: 'apiKey',
+      message: 'Enter your Cohere API key:',
+      validate: (input: string) => (input && input.trim() ? true : 'API key is required'),
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  await credentials.store<CohereAPICredential>({
+    type: CredentialType.COHERE_API,
+    name: credentials.getProfileName(),
+    apiKey,
+  });
+
+  console.log(chalk.green('✅ Cohere authentication configured!'));
+  console.log(chalk.gray('Set ') + chalk.white('embedding.provider') + chalk.gray(' to ') + chalk.white('cohere') +
+    chalk.gray(' and run ') + chalk.cyan('cv sync --force') + chalk.gray(' to rebuild the index.\n'));
+}
+
+async function setupVoyage(credentials: CredentialManager, autoBrowser: boolean = true): Promise<void> {
+  console.log(chalk.bold('──────────────────────────────────────────'));
+  console.log(chalk.bold.cyan('Voyage AI Authentication'));
+  console.log(chalk.bold('──────────────────────────────────────────\n'));
+
+  const url = 'https://dash.voyageai.com/api-keys';
+
+  if (autoBrowser) {
+    console.log(chalk.cyan('Opening browser to get API key...'));
+    await openBrowser(url);
+    console.log();
+  }
+
+  console.log(chalk.gray('URL: ') + chalk.blue(url));
+  console.log(chalk.gray('Copy your API key (starts with ') + chalk.white('pa-') + chalk.gray(')'));
+  console.log();
+
+  const { apiKey } = await inquirer.prompt([
+    {
+      type: 'password',
+      name: 'apiKey',
+      message: 'Enter your Voyage AI API key:',
+      validate: (input: string) => (input && input.trim() ? true : 'API key is required'),
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  await credentials.store<VoyageAPICredential>({
+    type: CredentialType.VOYAGE_API,
+    name: credentials.getProfileName(),
+    apiKey,
+  });
+
+  console.log(chalk.green('✅ Voyage AI authentication configured!'));
+  console.log(chalk.gray('Set ') + chalk.white('embedding.provider') + chalk.gray(' to ') + chalk.white('voyage') +
+    chalk.gray(' and run ') + chalk.cyan('cv sync --force') + chalk.gray(' to rebuild the index.\n'));
+}
+
+async function setupHuggingFace(credentials: CredentialManager, autoBrowser: boolean = true): Promise<void> {
+  console.log(chalk.bold('──────────────────────────────────────────'));
+  console.log(chalk.bold.cyan('HuggingFace Authentication'));
+  console.log(chalk.bold('──────────────────────────────────────────\n'));
+
+  const url = 'https://huggingface.co/settings/tokens';
+
+  if (autoBrowser) {
+    console.log(chalk.cyan('Opening browser to get access token...'));
+    await openBrowser(url);
+    console.log();
+  }
+
+  console.log(chalk.gray('URL: ') + chalk.blue(url));
+  console.log(chalk.gray('Create a token with ') + chalk.white('Make calls to Inference Providers') +
+    chalk.gray(' permission (starts with ') + chalk.white('hf_') + chalk.gray(')'));
+  console.log(chalk.gray('For a self-hosted Text Embeddings Inference server, enter its URL; the token is then optional.'));
+  console.log();
+
+  const existing = await credentials.getHuggingFace();
+
+  const answers = await inquirer.prompt([
+    {
+      type: 'input',
+      name: 'baseUrl',
+      message: 'TEI server URL (leave blank for the hosted Inference API):',
+      default: existing?.baseUrl || '',
+      validate: (input: string) => {
+        if (!input.trim()) return true;
+        try {
+          const url = new URL(input.trim());
+          return url.protocol === 'http:' || url.protocol === 'https:' || 'URL must start with http:// or https://';
+        } catch {
+          return 'Invalid URL';
+        }
+      },
+      filter: (input: string) => input.trim().replace(/\/+$/, ''),
+    },
+    {
+      type: 'password',
+      name: 'apiKey',
+      message: 'Enter your HuggingFace access token:',
+      validate: (input: string, current?: { baseUrl?: string }) =>
+        (input && input.trim()) || current?.baseUrl ? true : 'Access token is required for the hosted Inference API',
+      filter: (input: string) => input.trim(),
+    },
+    {
+      type: 'input',
+      name: 'model',
+      message: 'Default embedding model:',
+      default: existing?.model || DEFAULT_HUGGINGFACE_EMBEDDING_MODEL,
+      validate: (input: string) => (input && input.trim() ? true : 'Model ID is required'),
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  await credentials.store<HuggingFaceAPICredential>({
+    type: CredentialType.HUGGINGFACE_API,
+    name: credentials.getProfileName(),
+    apiKey: answers.apiKey || undefined,
+    model: answers.model,
+    baseUrl: answers.baseUrl || undefined,
+  });
+
+  console.log(chalk.green('✅ HuggingFace authentication configured!'));
+  if (answers.baseUrl) {
+    console.log(chalk.gray('The TEI server decides the model; ') + chalk.white(answers.model) +
+      chalk.gray(' is recorded in the index fingerprint, so it should match what the server serves.'));
+  }
+  console.log(chalk.gray('Set ') + chalk.white('embedding.provider') + chalk.gray(' to ') + chalk.white('huggingface') +
+    chalk.gray(' and run ') + chalk.cyan('cv sync --force') + chalk.gray(' to rebuild the index.\n'));
+}
+
+async function setupOpenAICompatible(credentials: CredentialManager): Promise<void> {
+  console.log(chalk.bold('──────────────────────────────────────────'));
+  console.log(chalk.bold.cyan('OpenAI-Compatible Gateway'));
+  console.log(chalk.bold('──────────────────────────────────────────\n'));
+
+  console.log(chalk.gray('Any API that speaks the OpenAI protocol works: LiteLLM, vLLM, Groq, Together and others.'));
+  console.log(chalk.gray('Model names are sent to the gateway exactly as you enter them.'));
+  console.log();
+
+  const existing = await credentials.getOpenAICompatible();
+
+  const { preset } = await inquirer.prompt([
+    {
+      type: 'list',
+      name: 'preset',
+      message: 'Gateway:',
+      choices: [
+        ...Object.entries(OPENAI_COMPATIBLE_PRESETS).map(([value, { name, baseUrl }]) => ({
+          name: `${chalk.cyan(name)} ${chalk.gray(`— ${baseUrl}`)}`,
+          value,
+        })),
+        { name: chalk.cyan('Other'), value: 'other' },
+      ],
+      default: existing ? 'other' : 'litellm',
+    },
+  ]);
+
+  const answers = await inquirer.prompt([
+    {
+      type: 'input',
+      name: 'baseUrl',
+      message: 'Base URL (including the version path, e.g. /v1):',
+      default: OPENAI_COMPATIBLE_PRESETS[preset]?.baseUrl || existing?.baseUrl,
+      validate: (input: string) => {
+        try {
+          const url = new URL(input.trim());
+          return url.protocol === 'http:' || url.protocol === 'https:' || 'URL must start with http:// or https://';
+        } catch {
+          return 'Invalid URL';
+        }
+      },
+      filter: (input: string) => input.trim().replace(/\/+$/, ''),
+    },
+    {
+      type: 'password',
+      name: 'apiKey',
+      message: 'API key (leave blank if the gateway needs none):',
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  // Listing models checks the URL and the key in one request
+  const spinner = ora(`Checking ${answers.baseUrl}...`).start();
+  let models: string[] = [];
+  try {
+    models = await listOpenAICompatibleModels(answers.baseUrl, answers.apiKey || undefined);
+    spinner.succeed(chalk.green(`Gateway reachable, ${models.length} model${models.length === 1 ? '' : 's'} served`));
+  } catch (error: any) {
+    spinner.fail(chalk.red(`Gateway check failed: ${error.cause?.message ?? error.message}`));
+    const { save } = await inquirer.prompt([
+      {
+        type: 'confirm',
+        name: 'save',
+        message: 'Save these settings anyway?',
+        default: false,
+      },
+    ]);
+    if (!save) return;
+  }
+
+  const { chatModel, embeddingModel } = await inquirer.prompt([
+    {
+      type: 'input',
+      name: 'chatModel',
+      message: 'Chat model (leave blank to use the gateway only for embeddings):',
+      default: existing?.chatModel || models[0] || '',
+      filter: (input: string) => input.trim(),
+    },
+    {
+      type: 'input',
+      name: 'embeddingModel',
+      message: 'Embedding model (leave blank to use the gateway only for chat):',
+      default: existing?.embeddingModel || '',
+      validate: (input: string, current?: { chatModel?: string }) =>
+        (input && input.trim()) || current?.chatModel ? true : 'Enter a chat model, an embedding model or both',
+      filter: (input: string) => input.trim(),
+    },
+  ]);
+
+  for (const model of [chatModel, embeddingModel]) {
+    if (model && models.length > 0 && !models.includes(model)) {
+      console.log(chalk.yellow(`⚠ ${model} is not in the gateway's model list; it will be sent as given`));
+    }
+  }
+
+  await credentials.store<OpenAICompatibleCredential>({
+    type: CredentialType.OPENAI_COMPATIBLE,
+    name: credentials.getProfileName(),
+    baseUrl: answers.baseUrl,
+    apiKey: answers.apiKey || undefined,
+    chatModel: chatModel || undefined,
+    embeddingModel: embeddingModel || undefined,
+  });
+
+  console.log(chalk.green('✅ OpenAI-compatible gateway configured!'));
+  if (chatModel) {
+    console.log(chalk.gray('Set ') + chalk.white('ai.provider') + chalk.gray(' to ') + chalk.white('openai-compatible') +
+      chalk.gray(' to chat through it.'));
+  }
+  if (embeddingModel) {
+    console.log(chalk.gray('Set ') + chalk.white('embedding.provider') + chalk.gray(' to ') + chalk.white('openai-compatible') +
+      chalk.gray(' and run ') + chalk.cyan('cv sync --force') + chalk.gray(' to rebuild the index.'));
+  }
+  console.log();
+}
+
 /**
  * Detect GitLab token type by testing various API endpoints
  */
@@ -4,7 +4,7 @@
  * Organizes authentication providers into logical categories:
  * - dns: DNS providers (Cloudflare)
  * - devops: Cloud infrastructure (AWS, DigitalOcean)
- * - ai: AI/LLM services (Anthropic, OpenAI, OpenRouter)
+ * - ai: AI/LLM services (Anthropic, OpenAI, OpenRouter, Ollama, Azure OpenAI, Gemini, Cohere, Voyage AI, HuggingFace)
  * - git: Git platforms (GitHub, GitLab, Bitbucket)
  */
 
@@ -70,7 +70,7 @@ export const AUTH_CATEGORIES: AuthCategory[] = [
   {
     id: 'ai',
     name: 'AI Services',
-    description: 'AI/LLM providers (Anthropic, OpenAI, OpenRouter)',
+    description: 'AI/LLM providers (Anthropic, OpenAI, OpenRouter, Ollama, Azure OpenAI, Gemini, Cohere, Voyage AI, HuggingFace)',
     providers: [
       {
         id: 'anthropic',
@@ -87,6 +87,41 @@ export const AUTH_CATEGORIES: AuthCategory[] = [
         name: 'OpenRouter',
         description: 'Multi-model AI gateway',
       },
+      {
+        id: 'ollama',
+        name: 'Ollama',
+        description: 'Local embeddings (no API key, code stays on your machine)',
+      },
+      {
+        id: 'azure',
+        name: 'Azure OpenAI',
+        description: 'Chat and embeddings through Azure OpenAI deployments',
+      },
+      {
+        id: 'gemini',
+        name: 'Google Gemini',
+        description: 'Gemini chat models and text-embedding-004 embeddings',
+      },
+      {
+        id: 'cohere',
+        name: 'Cohere',
+        description: 'Cohere embed-v3 embeddings',
+      },
+      {
+        id: 'voyage',
+        name: 'Voyage AI',
+        description: 'Voyage code embeddings',
+      },
+      {
+        id: 'huggingface',
+        name: 'HuggingFace',
+        description: 'Open embedding models via the Inference API or a TEI server',
+      },
+      {
+        id: 'openai-compatible',
+        name: 'OpenAI-compatible',
+        description: 'Any OpenAI-compatible gateway (LiteLLM, vLLM, Groq, Together) for chat and embeddings',
+      },
     ],
   },
   {
@@ -0,0 +1,224 @@
+/**
+ * cv bench command
+ * Measures retrieval quality against golden cases
+ *
+ * Each case is a query and the files a good answer needs. Bench searches the
+ * index with the current settings, or with each value of --sweep, and
+ * reports recall@k and MRR. Only the embedding provider is called.
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import Table from 'cli-table3';
+import {
+  configManager,
+  createVectorManager,
+  readManifest,
+  generateRepoId,
+  getIndexDir,
+  getVectorBackendOptions,
+  DEFAULT_CONTEXT_MIN_SCORE,
+  DEFAULT_CONTEXT_TOP_K,
+  BenchHit,
+  BenchParameter,
+  BenchSummary,
+  loadBenchCases,
+  parseSweepRange,
+  summarizeBench
+} from '@cv-git/core';
+import { findRepoRoot, getCVDir } from '@cv-git/shared';
+import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { logProviderServed } from '../utils/providers.js';
+import { getEmbeddingCredentials, embeddingVectorOptions } from '../utils/credentials.js';
+import { addRetrievalOptions, resolveRetrieval } from '../utils/retrieval.js';
+
+/**
+ * Settings one row of the results was measured at
+ */
+interface BenchSetting {
+  minScore: number;
+  topK: number;
+  efSearch?: number;
+}
+
+interface BenchRow extends BenchSummary {
+  efSearch?: number;
+}
+
+export function benchCommand(): Command {
+  const cmd = new Command('bench');
+
+  cmd
+    .description('Measure retrieval quality (recall@k, MRR) against golden queries')
+    .argument('<cases>', 'YAML or JSON file of { query, expected_files } cases')
+    .option('--sweep <param-and-range...>', 'Vary one setting: min-score, top-k or ef, then start:end:step or a list (e.g. --sweep min-score 0.1:0.5:0.05)');
+
+  addRetrievalOptions(cmd);
+  addGlobalOptions(cmd);
+
+  cmd.action(async (casesFile: string, options) => {
+    const output = createOutput(options);
+    const spinner = output.spinner('Loading cases...');
+    spinner.start();
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        spinner.fail(chalk.red('Not in a CV-Git repository'));
+        output.error('Run `cv init` first');
+        process.exit(1);
+      }
+
+      const config = await configManager.load(repoRoot);
+      const retrieval = resolveRetrieval(options, config.search, {
+        minScore: DEFAULT_CONTEXT_MIN_SCORE,
+        topK: DEFAULT_CONTEXT_TOP_K
+      }, repoRoot);
+      const cases = await loadBenchCases(casesFile);
+
+      let sweep: { parameter: BenchParameter; values: number[] } | undefined;
+      if (options.sweep) {
+        if (options.sweep.length !== 2) {
+          throw new Error('--sweep takes a setting and a range, e.g. --sweep min-score 0.1:0.5:0.05');
+        }
+        const [parameter, range] = options.sweep;
+        sweep = { parameter, values: parseSweepRange(parameter, range) };
+      }
+
+      const base: BenchSetting = { minScore: retrieval.minScore, topK: retrieval.topK, efSearch: retrieval.efSearch };
+      const settings: BenchSetting[] = sweep
+        ? sweep.values.map(value => ({
+          ...base,
+          ...(sweep!.parameter === 'min-score' ? { minScore: value } : sweep!.parameter === 'top-k' ? { topK: value } : { efSearch: value })
+        }))
+        : [base];
+
+      const embeddingCreds = await getEmbeddingCredentials({
+        provider: config.embedding?.provider,
+        ollamaUrl: config.embedding?.url,
+        ollamaModel: config.embedding?.model,
+        azure: config.azure
+      });
+
+      // Use the same repo-isolated collections that cv sync writes to
+      const manifest = await readManifest(getCVDir(repoRoot));
+      const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+
+      spinner.text = 'Connecting to vector database...';
+      const vector = createVectorManager({
+        url: config.vector.url,
+        ...getVectorBackendOptions(config.vector),
+        repoId,
+        ...embeddingVectorOptions(embeddingCreds, config),
+        indexDir: getIndexDir(repoRoot),
+        onEmbeddingServed: logProviderServed(options, 'Embedding')
+      });
+      await vector.connect();
+
+      // Search once per ef value with the largest top-k; min-score and top-k
+      // are then applied to the ranked results, so a sweep costs no extra queries
+      const maxTopK = Math.max(...settings.map(s => s.topK));
+      const rows: BenchRow[] = [];
+      for (const efSearch of [...new Set(settings.map(s => s.efSearch))]) {
+        vector.setEfSearch(efSearch);
+        const hits: BenchHit[][] = [];
+        for (const [i, benchCase] of cases.entries()) {
+          spinner.text = `Searching ${i + 1}/${cases.length}${efSearch ? ` (ef ${efSearch})` : ''}...`;
+          const results = await vector.searchCode(benchCase.query, maxTopK, {
+            tests: retrieval.tests,
+            packages: retrieval.packages,
+            kinds: retrieval.kinds,
+            extensions: retrieval.extensions
+          });
+          hits.push(results.map(r => ({ file: r.payload.file, score: r.score })));
+        }
+        for (const setting of settings.filter(s => s.efSearch === efSearch)) {
+          rows.push({ ...summarizeBench(cases, hits, setting.minScore, setting.topK), efSearch });
+        }
+      }
+      spinner.stop();
+      await vector.close();
+
+      if (output.isJson) {
+        output.json({
+          cases: cases.length,
+          sweep: sweep?.parameter,
+          results: rows
+        });
+        return;
+      }
+
+      if (sweep) {
+        printSweep(rows, sweep.parameter);
+      } else {
+        printCases(rows[0]);
+      }
+    } catch (error: any) {
+      spinner.fail(chalk.red('Benchmark failed'));
+      output.error(error.message, error);
+      process.exit(1);
+    }
+  });
+
+  return cmd;
+}
+
+function printCases(row: BenchRow): void {
+  const table = new Table({
+    head: [chalk.cyan('Query'), chalk.cyan('First hit'), chalk.cyan(`Recall@${row.topK}`), chalk.cyan('Missing')],
+    colWidths: [48, 11, 11, 40],
+    wordWrap: true
+  });
+
+  for (const result of row.results) {
+    table.push([
+      result.query,
+      result.firstHitRank !== undefined ? `#${result.firstHitRank}` : chalk.red('none'),
+      formatRatio(result.recall),
+      result.missing.join(', ')
+    ]);
+  }
+  console.log(table.toString());
+  console.log();
+  printSummary(row);
+}
+
+function printSweep(rows: BenchRow[], parameter: BenchParameter): void {
+  const best = rows.reduce((a, b) => (b.mrr > a.mrr || (b.mrr === a.mrr && b.recall > a.recall) ? b : a));
+  const table = new Table({
+    head: [chalk.cyan(parameter), chalk.cyan('Recall@k'), chalk.cyan('MRR'), chalk.cyan('Hits')]
+  });
+
+  for (const row of rows) {
+    const value = parameter === 'min-score' ? row.minScore : parameter === 'top-k' ? row.topK : row.efSearch;
+    const mark = (text: string) => row === best ? chalk.green(text) : text;
+    table.push([
+      mark(String(value)),
+      mark(formatRatio(row.recall)),
+      mark(row.mrr.toFixed(3)),
+      mark(`${row.hits}/${row.cases}`)
+    ]);
+  }
+  console.log(table.toString());
+  console.log(chalk.gray(`Best MRR in green. ${describeFixed(rows[0], parameter)}`));
+}
+
+function printSummary(row: BenchRow): void {
+  console.log(`${chalk.bold(`Recall@${row.topK}:`)} ${formatRatio(row.recall)}   ${chalk.bold('MRR:')} ${row.mrr.toFixed(3)}   ${chalk.bold('Hits:')} ${row.hits}/${row.cases}`);
+  console.log(chalk.gray(describeFixed(row)));
+}
+
+/**
+ * Settings that were not swept, e.g. "min-score 0.25, top-k 10"
+ */
+function describeFixed(row: BenchRow, swept?: BenchParameter): string {
+  return [
+    swept !== 'min-score' ? `min-score ${row.minScore}` : '',
+    swept !== 'top-k' ? `top-k ${row.topK}` : '',
+    swept !== 'ef' && row.efSearch ? `ef ${row.efSearch}` : ''
+  ].filter(Boolean).join(', ');
+}
+
+function formatRatio(value: number): string {
+  return `${(value * 100).toFixed(1)}%`;
+}
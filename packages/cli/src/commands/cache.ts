@@ -1,6 +1,6 @@
 /**
  * CV Cache Command
- * Manage the content-addressed embedding cache and in-memory caches
+ * Manage the content-addressed embedding cache, cached AI responses and in-memory caches
  */
 
 import { Command } from 'commander';
@@ -12,7 +12,10 @@ import path from 'path';
 import {
   configManager,
   createVectorManager,
-  getGlobalCache
+  getGlobalCache,
+  getEmbeddingCacheDir,
+  getResponseCacheDir,
+  ResponseCache
 } from '@cv-git/core';
 import { findRepoRoot } from '@cv-git/shared';
 import { getEmbeddingCredentials } from '../utils/credentials.js';
@@ -68,7 +71,8 @@ export function createCacheCommand(): Command {
           openrouterApiKey: embeddingCreds.openrouterApiKey,
           openaiApiKey: embeddingCreds.openaiApiKey,
           collections: config.vector.collections,
-          cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+          cacheDir: getEmbeddingCacheDir(repoRoot),
+          cacheMaxSizeBytes: config.embedding?.cacheMaxBytes
         });
 
         await vector.connect();
@@ -95,6 +99,7 @@ export function createCacheCommand(): Command {
             [chalk.bold('Dimensions'), stats.dimensions.toString()],
             [chalk.bold('Total Entries'), stats.totalEntries.toLocaleString()],
             [chalk.bold('Storage Size'), formatBytes(stats.totalSizeBytes)],
+            [chalk.bold('Size Limit'), `${formatBytes(stats.maxSizeBytes)} (LRU eviction)`],
             [chalk.bold('Cache Hits'), stats.cacheHits.toLocaleString()],
             [chalk.bold('Cache Misses'), stats.cacheMisses.toLocaleString()],
             [chalk.bold('Hit Rate'), formatPercent(stats.hitRate)]
@@ -128,8 +133,10 @@ export function createCacheCommand(): Command {
   // ═══════════════════════════════════════════════════════════════════════════
   cache
     .command('clear')
-    .description('Clear the embedding cache')
+    .description('Clear the embedding cache and cached AI responses')
     .option('-f, --force', 'Skip confirmation')
+    .option('--responses', 'Only clear cached AI responses (.cv/cache/responses)')
+    .option('--embeddings', 'Only clear the embedding cache')
     .action(async (options) => {
       try {
         const repoRoot = await findRepoRoot();
@@ -138,6 +145,13 @@ export function createCacheCommand(): Command {
           process.exit(1);
         }
 
+        // Neither flag clears both
+        const clearResponses = options.responses || !options.embeddings;
+        const clearEmbeddings = options.embeddings || !options.responses;
+        const what = clearResponses && clearEmbeddings
+          ? 'the embedding cache and cached AI responses'
+          : clearEmbeddings ? 'the embedding cache' : 'cached AI responses';
+
         if (!options.force) {
           const readline = await import('readline');
           const rl = readline.createInterface({
@@ -146,7 +160,7 @@ export function createCacheCommand(): Command {
           });
 
           const answer = await new Promise<string>(resolve => {
-            rl.question(chalk.yellow('Are you sure you want to clear the embedding cache? (y/N) '), resolve);
+            rl.question(chalk.yellow(`Are you sure you want to clear ${what}? (y/N) `), resolve);
           });
           rl.close();
 
@@ -156,27 +170,34 @@ export function createCacheCommand(): Command {
           }
         }
 
-        const spinner = ora('Clearing embedding cache...').start();
+        if (clearResponses) {
+          const removed = await new ResponseCache(getResponseCacheDir(repoRoot)).clear();
+          console.log(chalk.green('✔'), `Cleared ${removed} cached response${removed === 1 ? '' : 's'}`);
+        }
 
-        const config = await configManager.load(repoRoot);
-        const embeddingCreds = await getEmbeddingCredentials({
-          openRouterKey: config.embedding?.apiKey,
-          openaiKey: config.ai?.apiKey
-        });
+        if (clearEmbeddings) {
+          const spinner = ora('Clearing embedding cache...').start();
 
-        const vector = createVectorManager({
-          url: config.vector.url,
-          openrouterApiKey: embeddingCreds.openrouterApiKey,
-          openaiApiKey: embeddingCreds.openaiApiKey,
-          collections: config.vector.collections,
-          cacheDir: path.join(repoRoot, '.cv', 'embeddings')
-        });
+          const config = await configManager.load(repoRoot);
+          const embeddingCreds = await getEmbeddingCredentials({
+            openRouterKey: config.embedding?.apiKey,
+            openaiKey: config.ai?.apiKey
+          });
 
-        await vector.connect();
-        await vector.clearCache();
-        await vector.close();
+          const vector = createVectorManager({
+            url: config.vector.url,
+            openrouterApiKey: embeddingCreds.openrouterApiKey,
+            openaiApiKey: embeddingCreds.openaiApiKey,
+            collections: config.vector.collections,
+            cacheDir: getEmbeddingCacheDir(repoRoot)
+          });
 
-        spinner.succeed(chalk.green('Embedding cache cleared'));
+          await vector.connect();
+          await vector.clearCache();
+          await vector.close();
+
+          spinner.succeed(chalk.green('Embedding cache cleared'));
+        }
 
       } catch (error: any) {
         console.error(chalk.red(`Error: ${error.message}`));
@@ -212,7 +233,7 @@ export function createCacheCommand(): Command {
           openrouterApiKey: embeddingCreds.openrouterApiKey,
           openaiApiKey: embeddingCreds.openaiApiKey,
           collections: config.vector.collections,
-          cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+          cacheDir: getEmbeddingCacheDir(repoRoot)
         });
 
         await vector.connect();
@@ -273,7 +294,7 @@ export function createCacheCommand(): Command {
           openrouterApiKey: embeddingCreds.openrouterApiKey,
           openaiApiKey: embeddingCreds.openaiApiKey,
           collections: config.vector.collections,
-          cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+          cacheDir: getEmbeddingCacheDir(repoRoot)
         });
 
         await vector.connect();
@@ -305,7 +326,7 @@ export function createCacheCommand(): Command {
           process.exit(1);
         }
 
-        const cachePath = path.join(repoRoot, '.cv', 'embeddings');
+        const cachePath = getEmbeddingCacheDir(repoRoot);
         console.log(cachePath);
 
         // Check if exists and show size
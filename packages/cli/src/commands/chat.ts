@@ -7,31 +7,90 @@ import { Command } from 'commander';
 import chalk from 'chalk';
 import ora from 'ora';
 import * as readline from 'readline';
+import { promises as fs } from 'fs';
+import * as path from 'path';
 import {
   configManager,
-  createVectorManager,
   createGraphManager,
   createOpenRouterClient,
+  createAzureOpenAIClient,
+  createGeminiClient,
+  createAnthropicClient,
+  createOpenAICompatibleClient,
+  AIClient,
   OPENROUTER_MODELS,
-  OpenRouterMessage,
   VectorManager,
   GraphManager,
+  applyMinScore,
+  getIndexDir,
+  readManifest,
+  generateRepoId,
+  gatherFileChunks,
+  mergeFileChunks,
+  deduplicateChunks,
+  ChatSession,
+  createChatSession,
+  saveChatSession,
+  loadChatSession,
+  loadLatestChatSession,
+  listChatSessions,
+  toChatHistory,
+  getContextBudget,
+  formatChatTranscript,
+  composeSystemPrompt,
+  getPromptVariables,
+  buildFollowUpPrompt,
+  parseFollowUps
 } from '@cv-git/core';
-import { findRepoRoot } from '@cv-git/shared';
+import { findRepoRoot, getCVDir, VectorSearchResult, CodeChunkPayload, CVConfig } from '@cv-git/shared';
 import { CredentialManager } from '@cv-git/credentials';
 import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { abortOnInterrupt, isAbortError } from '../utils/interrupt.js';
+import { getAzureOpenAISettings, toAzureDeployment, getAnthropicApiKey, getGeminiApiKey, getOpenAICompatibleSettings, createVectorManagerFromCredentials } from '../utils/credentials.js';
+import {
+  addRetrievalOptions,
+  addFileScopeOption,
+  resolveRetrieval,
+  resolveFileScope,
+  formatNearMiss,
+  RetrievalSettings
+} from '../utils/retrieval.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { resolveEmbeddingTimeout } from '../utils/timeout.js';
+import { addSystemPromptOptions, resolveSystemPrompt } from '../utils/system-prompt.js';
+import { printProxyHint } from '../utils/network.js';
+import { addSuggestOption, resolveSuggest, printFollowUps } from '../utils/follow-ups.js';
+import { ChatCitation, toAskResult } from '../utils/ask-result.js';
+import { addFormatOption, resolveAnswerFormat, stripMarkdown, PlainTextStream, AnswerFormat } from '../utils/answer-format.js';
 
 interface ChatOptions {
   model?: string;
   noContext?: boolean;
   contextLimit?: string;
+  minScore?: string;
+  topK?: string;
+  ef?: string;
+  tests?: boolean;
+  package?: string;
+  kind?: string;
+  ext?: string;
+  file?: string[];
+  systemPrompt?: string;
+  systemPromptFile?: string;
+  rawPrompt?: boolean;
+  stream?: boolean;
+  suggest?: boolean;
+  resume?: string;
+  list?: boolean;
+  new?: boolean;
+  format?: string;
   verbose?: boolean;
   quiet?: boolean;
   json?: boolean;
 }
 
-// System prompt for code-aware chat
-const SYSTEM_PROMPT = `You are an expert software engineer assistant with access to a codebase knowledge graph.
+// System prompt for code-aware chat (and cv ask)
+export const CHAT_SYSTEM_PROMPT = `You are an expert software engineer assistant with access to a codebase knowledge graph.
 
 When answering questions:
 1. Reference specific files and line numbers when discussing code
@@ -47,13 +106,34 @@ export function chatCommand(): Command {
   cmd
     .description('Interactive AI chat with codebase context')
     .argument('[question]', 'One-shot question (omit for interactive mode)')
-    .option('-m, --model <model>', 'Model to use (e.g., claude-sonnet-4-5, gpt-4o, llama-3.1-70b)')
     .option('--no-context', 'Disable automatic context injection')
-    .option('-c, --context-limit <n>', 'Max code chunks to include', '5');
-
+    .option('-c, --context-limit <n>', 'Max code chunks to include (alias for --top-k, default: 5)')
+    .option('--no-stream', 'Wait for the full response instead of streaming tokens')
+    .option('--resume <id>', 'Resume a saved session (see --list)')
+    .option('--new', 'Start a fresh session instead of continuing the latest one (one-shot questions always do unless --resume)')
+    .option('--list', 'List saved chat sessions');
+
+  addModelOption(cmd, 'chat');
+  addSystemPromptOptions(cmd, 'chat');
+  addRetrievalOptions(cmd);
+  addFileScopeOption(cmd);
+  addSuggestOption(cmd);
+  addFormatOption(cmd);
   addGlobalOptions(cmd);
 
   cmd.action(async (question: string | undefined, options: ChatOptions) => {
+    let format: AnswerFormat;
+    try {
+      format = resolveAnswerFormat(options);
+    } catch (error: any) {
+      console.error(chalk.red(error.message));
+      process.exit(1);
+    }
+    // --format json is --json; plain text is for logs, so without colors too
+    options.json = format === 'json';
+    if (format === 'plain') {
+      chalk.level = 0;
+    }
     const output = createOutput(options as any);
 
     try {
@@ -64,95 +144,87 @@ export function chatCommand(): Command {
         process.exit(1);
       }
 
-      // Load configuration
-      const config = await configManager.load(repoRoot);
-
-      // Get API keys
-      let openrouterApiKey = process.env.OPENROUTER_API_KEY;
-      let openaiApiKey = process.env.OPENAI_API_KEY;
-
-      try {
-        const credentials = new CredentialManager();
-        await credentials.init();
-
-        if (!openrouterApiKey) {
-          openrouterApiKey = await credentials.getOpenRouterKey() || undefined;
-        }
-        if (!openaiApiKey) {
-          openaiApiKey = await credentials.getOpenAIKey() || undefined;
-        }
-      } catch {
-        // Credential manager not available
+      if (options.list) {
+        await listSessions(repoRoot, output);
+        return;
       }
-
-      if (!openrouterApiKey) {
-        console.error(chalk.red('OpenRouter API key not found.'));
-        console.error(chalk.gray('Run: cv auth setup openrouter'));
-        console.error(chalk.gray('Or set: export OPENROUTER_API_KEY=sk-or-...'));
+      if (format === 'json' && !question) {
+        console.error(chalk.red('--format json needs a question, e.g. cv chat --format json "how are tokens refreshed?"'));
+        process.exit(1);
+      }
+      if (options.resume && options.new) {
+        console.error(chalk.red('--resume and --new cannot be combined'));
         process.exit(1);
       }
 
-      // Initialize OpenRouter client
-      const model = options.model || 'claude-sonnet-4-5';
-      const client = createOpenRouterClient({
-        apiKey: openrouterApiKey,
-        model,
+      // Load configuration
+      const config = await configManager.load(repoRoot);
+      const retrieval = resolveRetrieval(
+        {
+          minScore: options.minScore,
+          topK: options.topK ?? options.contextLimit,
+          ef: options.ef,
+          tests: options.tests,
+          package: options.package,
+          kind: options.kind,
+          ext: options.ext
+        },
+        config.search,
+        { minScore: 0.5, topK: 5 },
+        repoRoot
+      );
+      const scope: FileScope = { repoRoot, files: resolveFileScope(options.file, repoRoot) };
+      const suggest = resolveSuggest(options.suggest, config);
+
+      // A custom prompt goes before the built-in instructions (or replaces them with --raw-prompt)
+      const custom = await resolveSystemPrompt('chat', options, config);
+      const systemPrompt = composeSystemPrompt(CHAT_SYSTEM_PROMPT, custom, custom && {
+        ...getPromptVariables(custom),
+        files: scope.files.join(', ')
       });
 
-      // Initialize vector manager for context (if available)
-      let vector: VectorManager | null = null;
-      let graph: GraphManager | null = null;
-
-      if (options.noContext !== true) {
-        const embeddingKey = openrouterApiKey || openaiApiKey;
-        if (embeddingKey && config.vector) {
-          try {
-            vector = createVectorManager({
-              url: config.vector.url,
-              openrouterApiKey: openrouterApiKey,
-              openaiApiKey: openaiApiKey,
-              collections: config.vector.collections,
-              embeddingModel: config.embedding?.model
-            });
-            await vector.connect();
-          } catch (e) {
-            output.debug?.('Vector DB not available, continuing without semantic search');
-          }
-        }
+      const { client, vector, graph } = await connectChatServices(repoRoot, config, options, retrieval, output);
 
-        if (config.graph) {
-          try {
-            graph = createGraphManager(config.graph.url, config.graph.database);
-            await graph.connect();
-          } catch (e) {
-            output.debug?.('Graph DB not available, continuing without relationships');
-          }
+      // The REPL continues the latest session unless --new; a one-shot
+      // question starts a fresh one unless --resume names a session
+      const previous = options.resume
+        ? await loadChatSession(repoRoot, options.resume)
+        : options.new || question ? null : await loadLatestChatSession(repoRoot);
+      const session = previous ?? createChatSession(client.getModel());
+
+      // Show startup info (JSON output is the answer alone)
+      if (format !== 'json') {
+        console.log();
+        console.log(chalk.bold.cyan('cv chat') + chalk.gray(` - using ${client.getModel()}`));
+        if (previous) {
+          const turns = previous.messages.filter(m => m.role === 'user').length;
+          console.log(chalk.green('✓') + chalk.gray(` Continuing session ${previous.id} (${turns} question${turns === 1 ? '' : 's'}; --new for a fresh one)`));
+        }
+        if (vector) {
+          console.log(chalk.green('✓') + chalk.gray(' Knowledge graph context enabled'));
+        } else {
+          console.log(chalk.yellow('○') + chalk.gray(' No context (run `cv sync` first)'));
+        }
+        if (scope.files.length > 0) {
+          console.log(chalk.green('✓') + chalk.gray(` Always including ${scope.files.join(', ')}`));
         }
+        console.log();
       }
 
-      // Show startup info
-      console.log();
-      console.log(chalk.bold.cyan('cv chat') + chalk.gray(` - using ${client.getModel()}`));
-      if (vector) {
-        console.log(chalk.green('✓') + chalk.gray(' Knowledge graph context enabled'));
-      } else {
-        console.log(chalk.yellow('○') + chalk.gray(' No context (run `cv sync` first)'));
-      }
-      console.log();
-
       // One-shot mode
       if (question) {
-        await handleSingleQuestion(question, client, vector, graph, parseInt(options.contextLimit || '5', 10));
+        await handleSingleQuestion(question, session, client, vector, graph, retrieval, scope, systemPrompt, options.stream !== false, suggest, format, config.ai);
         await cleanup(vector, graph);
         return;
       }
 
       // Interactive mode
-      await interactiveChat(client, vector, graph, parseInt(options.contextLimit || '5', 10));
+      await interactiveChat(session, client, vector, graph, retrieval, scope, systemPrompt, options.stream !== false, suggest, format, config.ai);
       await cleanup(vector, graph);
 
     } catch (error: any) {
       console.error(chalk.red(`Error: ${error.message}`));
+      printProxyHint(error);
       if (process.env.CV_DEBUG) {
         console.error(chalk.gray(error.stack));
       }
@@ -193,72 +265,297 @@ export function chatCommand(): Command {
   return cmd;
 }
 
+/**
+ * Chat client plus the vector and graph stores used for context. The
+ * stores are null when --no-context is set or they can't be reached.
+ */
+export interface ChatServices {
+  client: AIClient;
+  vector: VectorManager | null;
+  graph: GraphManager | null;
+}
+
+/**
+ * Create the configured chat client and connect to the stores for context
+ * (shared by cv chat and cv ask)
+ */
+export async function connectChatServices(
+  repoRoot: string,
+  config: CVConfig,
+  options: { model?: string; noContext?: boolean; signal?: AbortSignal },
+  retrieval: RetrievalSettings,
+  output: ReturnType<typeof createOutput>
+): Promise<ChatServices> {
+  // Get the OpenRouter key (used when Claude is not called directly)
+  let openrouterApiKey = process.env.OPENROUTER_API_KEY;
+
+  try {
+    const credentials = new CredentialManager();
+    await credentials.init();
+
+    if (!openrouterApiKey) {
+      openrouterApiKey = await credentials.getOpenRouterKey() || undefined;
+    }
+  } catch {
+    // Credential manager not available
+  }
+
+  let client: AIClient;
+  // Claude is called directly when there is an Anthropic key, otherwise through OpenRouter
+  const anthropicApiKey = config.ai.provider === 'anthropic' ? await getAnthropicApiKey(config.ai.apiKey) : null;
+
+  if (config.ai.provider === 'azure') {
+    // Azure OpenAI: --model names a deployment, not a model
+    const azure = await getAzureOpenAISettings(config.azure);
+    const deployment = resolveModel('chat', options.model, config, 'azure') || azure?.chatDeployment;
+    if (!azure || !deployment) {
+      console.error(chalk.red('Azure OpenAI chat deployment not configured.'));
+      console.error(chalk.gray('Run: cv auth setup azure'));
+      process.exit(1);
+    }
+    client = createAzureOpenAIClient(toAzureDeployment(azure, deployment));
+  } else if (config.ai.provider === 'gemini') {
+    const geminiApiKey = await getGeminiApiKey(config.ai.apiKey);
+    if (!geminiApiKey) {
+      console.error(chalk.red('Gemini API key not found.'));
+      console.error(chalk.gray('Run: cv auth setup gemini'));
+      console.error(chalk.gray('Or set: export GEMINI_API_KEY=...'));
+      process.exit(1);
+    }
+    client = createGeminiClient({ apiKey: geminiApiKey, model: resolveModel('chat', options.model, config, 'gemini') });
+  } else if (config.ai.provider === 'openai-compatible') {
+    // Any OpenAI-compatible gateway: the model name is sent as given
+    const gateway = await getOpenAICompatibleSettings();
+    const model = resolveModel('chat', options.model, config, 'openai-compatible') || gateway?.chatModel;
+    if (!gateway || !model) {
+      console.error(chalk.red('OpenAI-compatible base URL or chat model not configured.'));
+      console.error(chalk.gray('Run: cv auth setup openai-compatible'));
+      process.exit(1);
+    }
+    client = createOpenAICompatibleClient({
+      baseUrl: gateway.baseUrl,
+      apiKey: gateway.apiKey,
+      model,
+      maxTokens: config.ai.maxTokens,
+    });
+  } else if (anthropicApiKey) {
+    client = createAnthropicClient({
+      apiKey: anthropicApiKey,
+      model: resolveModel('chat', options.model, config, 'anthropic') || config.ai.model,
+      maxTokens: config.ai.maxTokens,
+      promptCaching: config.ai.promptCaching,
+    });
+  } else {
+    if (!openrouterApiKey) {
+      console.error(chalk.red('OpenRouter API key not found.'));
+      console.error(chalk.gray('Run: cv auth setup openrouter (or cv auth setup anthropic)'));
+      console.error(chalk.gray('Or set: export OPENROUTER_API_KEY=sk-or-...'));
+      process.exit(1);
+    }
+
+    // Initialize OpenRouter client
+    const model = resolveModel('chat', options.model, config, 'openrouter') || 'claude-sonnet-4-5';
+    client = createOpenRouterClient({
+      apiKey: openrouterApiKey,
+      model,
+    });
+  }
+
+  // Initialize vector manager for context (if available)
+  let vector: VectorManager | null = null;
+  let graph: GraphManager | null = null;
+
+  if (options.noContext !== true) {
+    if (config.vector) {
+      try {
+        // Use the same repo-isolated collections that cv sync writes to
+        const manifest = await readManifest(getCVDir(repoRoot));
+        const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+
+        // Embeds with embedding.provider, whichever provider serves chat
+        vector = await createVectorManagerFromCredentials(config, {
+          repoId,
+          efSearch: retrieval.efSearch,
+          indexDir: getIndexDir(repoRoot),
+          embeddingTimeoutMs: resolveEmbeddingTimeout(config),
+          signal: options.signal
+        });
+        await vector.connect();
+      } catch (e) {
+        output.debug?.('Vector DB not available, continuing without semantic search');
+      }
+    }
+
+    if (config.graph) {
+      try {
+        graph = createGraphManager(config.graph.url, config.graph.database);
+        await graph.connect();
+      } catch (e) {
+        output.debug?.('Graph DB not available, continuing without relationships');
+      }
+    }
+  }
+
+  return { client, vector, graph };
+}
+
 /**
  * Handle a single question (one-shot mode)
  */
 async function handleSingleQuestion(
   question: string,
-  client: ReturnType<typeof createOpenRouterClient>,
+  session: ChatSession,
+  client: AIClient,
   vector: VectorManager | null,
   graph: GraphManager | null,
-  contextLimit: number
+  retrieval: RetrievalSettings,
+  scope: FileScope,
+  systemPrompt: string,
+  stream: boolean,
+  suggest: boolean,
+  format: AnswerFormat,
+  ai: CVConfig['ai']
 ): Promise<void> {
   // Gather context
   let context = '';
-  if (vector) {
+  let citations: ChatCitation[] = [];
+  if (vector || scope.files.length > 0) {
     const spinner = ora('Searching codebase...').start();
-    context = await gatherContext(question, vector, graph, contextLimit);
+    const result = await gatherContext(question, vector, graph, retrieval, scope);
     spinner.stop();
+    context = result.text;
+    citations = result.citations;
+    if (format !== 'json') printNearMiss(result, retrieval);
   }
 
-  // Build message with context
-  const userMessage = context
-    ? `<codebase_context>\n${context}\n</codebase_context>\n\n${question}`
-    : question;
+  // Earlier turns of the session come first, each with the context it was asked with
+  session.messages.push({ role: 'user', content: question, context: context || undefined, timestamp: Date.now() });
+  const messages = toChatHistory(session, getHistoryBudget(client, ai));
+
+  if (format === 'json') {
+    const spinner = ora('Thinking...').start();
+    const answer = await client.chat(messages, systemPrompt);
+    spinner.stop();
+    await recordAnswer(session, scope.repoRoot, answer);
+    const followUps = suggest ? await suggestFollowUps(client, question, answer, citations) : undefined;
+    // The cv ask --json fields, plus the session to resume
+    console.log(JSON.stringify({
+      ...toAskResult(question, answer, client.getModel(), citations),
+      session: session.id,
+      ...(followUps ? { followUps } : {})
+    }, null, 2));
+    return;
+  }
 
-  // Stream response
+  if (!stream) {
+    const spinner = ora('Thinking...').start();
+    const response = await client.chat(messages, systemPrompt);
+    spinner.stop();
+    console.log(chalk.cyan('Assistant: ') + (format === 'plain' ? stripMarkdown(response) : response) + '\n');
+    printCitations(citations, 'Sources:');
+    await recordAnswer(session, scope.repoRoot, response);
+    if (suggest) {
+      printFollowUps(await suggestFollowUps(client, question, response, citations));
+    }
+    return;
+  }
+
+  // Stream response; Ctrl-C aborts the request
   process.stdout.write(chalk.cyan('Assistant: '));
 
-  await client.chatStream(
-    [{ role: 'user', content: userMessage }],
-    SYSTEM_PROMPT,
-    {
-      onToken: (token) => process.stdout.write(token),
-      onComplete: () => console.log('\n'),
+  const interrupt = abortOnInterrupt();
+  const plain = format === 'plain' ? new PlainTextStream() : null;
+  let partial = '';
+  try {
+    const response = await client.chatStream(
+      messages,
+      systemPrompt,
+      {
+        signal: interrupt.signal,
+        onToken: (token) => {
+          partial += token;
+          process.stdout.write(plain ? plain.push(token) : token);
+        },
+        onComplete: () => {
+          if (plain) process.stdout.write(plain.flush());
+          console.log('\n');
+        },
+      }
+    );
+    printCitations(citations, 'Sources:');
+    await recordAnswer(session, scope.repoRoot, response);
+    if (suggest) {
+      printFollowUps(await suggestFollowUps(client, question, response, citations));
     }
-  );
+  } catch (error) {
+    if (!interrupt.signal.aborted && !isAbortError(error)) throw error;
+    console.log(chalk.yellow('\n[aborted]'));
+    await recordAnswer(session, scope.repoRoot, partial);
+  } finally {
+    interrupt.dispose();
+  }
 }
 
 /**
  * Interactive chat mode
  */
 async function interactiveChat(
-  client: ReturnType<typeof createOpenRouterClient>,
+  initialSession: ChatSession,
+  client: AIClient,
   vector: VectorManager | null,
   graph: GraphManager | null,
-  contextLimit: number
+  retrieval: RetrievalSettings,
+  scope: FileScope,
+  systemPrompt: string,
+  stream: boolean,
+  suggest: boolean,
+  format: AnswerFormat,
+  ai: CVConfig['ai']
 ): Promise<void> {
   const rl = readline.createInterface({
     input: process.stdin,
     output: process.stdout,
   });
 
-  const messages: OpenRouterMessage[] = [];
+  let session = initialSession;
+  // Suggestions after the last answer, asked by typing their number
+  let followUps: string[] = [];
+  // Code cited for the last answer, shown by /sources
+  let lastCitations: ChatCitation[] = [];
+
+  // Ctrl-C aborts the response in flight; at the prompt it exits
+  let inFlight: AbortController | null = null;
+  rl.on('SIGINT', () => {
+    if (inFlight) {
+      inFlight.abort();
+    } else {
+      rl.close();
+    }
+  });
 
-  console.log(chalk.gray('Type your questions. Commands: /help, /clear, /model <name>, /quit\n'));
+  console.log(chalk.gray('Type your questions. Commands: /help, /file <path>, /sources, /save <file>, /clear, /model <name>, /quit\n'));
 
   const askQuestion = (): void => {
     rl.question(chalk.green('You: '), async (input) => {
-      const trimmed = input.trim();
+      let trimmed = input.trim();
 
       if (!trimmed) {
         askQuestion();
         return;
       }
 
+      const picked = /^\d+$/.test(trimmed) ? followUps[parseInt(trimmed, 10) - 1] : undefined;
+      if (picked) {
+        trimmed = picked;
+        console.log(chalk.gray(`  ${picked}`));
+      }
+
       // Handle commands
       if (trimmed.startsWith('/')) {
-        await handleCommand(trimmed, client, messages, rl);
+        await handleCommand(trimmed, client, rl, session, scope, lastCitations, () => {
+          session = createChatSession(client.getModel());
+          lastCitations = [];
+        });
         if (trimmed === '/quit' || trimmed === '/exit') {
           return;
         }
@@ -266,40 +563,72 @@ async function interactiveChat(
         return;
       }
 
+      followUps = [];
+
       // Gather context for this message
       let context = '';
-      if (vector) {
+      let citations: ChatCitation[] = [];
+      if (vector || scope.files.length > 0) {
         const spinner = ora('Searching...').start();
-        context = await gatherContext(trimmed, vector, graph, contextLimit);
+        const result = await gatherContext(trimmed, vector, graph, retrieval, scope);
         spinner.stop();
         // Clear spinner line
         process.stdout.write('\r\x1b[K');
+        context = result.text;
+        citations = result.citations;
+        printNearMiss(result, retrieval);
       }
 
-      // Build message with context
-      const userMessage = context
-        ? `<codebase_context>\n${context}\n</codebase_context>\n\n${trimmed}`
-        : trimmed;
-
-      messages.push({ role: 'user', content: userMessage });
+      session.messages.push({ role: 'user', content: trimmed, context: context || undefined, timestamp: Date.now() });
+      const messages = toChatHistory(session, getHistoryBudget(client, ai));
 
-      // Stream response
-      process.stdout.write(chalk.cyan('Assistant: '));
+      const controller = new AbortController();
+      inFlight = stream ? controller : null;
+      let partial = '';
 
       try {
-        const response = await client.chatStream(
-          messages,
-          SYSTEM_PROMPT,
-          {
-            onToken: (token) => process.stdout.write(token),
-          }
-        );
+        let response: string;
+        if (stream) {
+          process.stdout.write(chalk.cyan('Assistant: '));
+          const plain = format === 'plain' ? new PlainTextStream() : null;
+          response = await client.chatStream(
+            messages,
+            systemPrompt,
+            {
+              signal: controller.signal,
+              onToken: (token) => {
+                partial += token;
+                process.stdout.write(plain ? plain.push(token) : token);
+              },
+            }
+          );
+          if (plain) process.stdout.write(plain.flush());
+          console.log('\n');
+        } else {
+          const spinner = ora('Thinking...').start();
+          response = await client.chat(messages, systemPrompt);
+          spinner.stop();
+          console.log(chalk.cyan('Assistant: ') + (format === 'plain' ? stripMarkdown(response) : response) + '\n');
+        }
 
-        console.log('\n');
-        messages.push({ role: 'assistant', content: response });
+        await recordAnswer(session, scope.repoRoot, response);
+        lastCitations = citations;
+        if (suggest) {
+          followUps = await suggestFollowUps(client, trimmed, response, citations);
+          printFollowUps(followUps, 'Type a number to ask one');
+        }
       } catch (error: any) {
-        console.log();
-        console.error(chalk.red(`Error: ${error.message}`));
+        if (controller.signal.aborted || isAbortError(error)) {
+          console.log(chalk.yellow('\n[aborted]\n'));
+          await recordAnswer(session, scope.repoRoot, partial);
+        } else {
+          // Drop the unanswered question so the history stays consistent
+          session.messages.pop();
+          console.log();
+          console.error(chalk.red(`Error: ${error.message}`));
+        }
+      } finally {
+        inFlight = null;
       }
 
       askQuestion();
@@ -310,43 +639,104 @@ async function interactiveChat(
 
   // Handle Ctrl+C gracefully
   rl.on('close', () => {
+    if (session.messages.length > 0) {
+      console.log(chalk.gray(`\nSession saved. Resume with: cv chat --resume ${session.id}`));
+    }
     console.log(chalk.gray('\nGoodbye!'));
     process.exit(0);
   });
 }
 
 /**
- * Handle chat commands
+ * Handle chat commands. They run locally and are never sent to the model.
  */
 async function handleCommand(
   command: string,
-  client: ReturnType<typeof createOpenRouterClient>,
-  messages: OpenRouterMessage[],
-  rl: readline.Interface
+  client: AIClient,
+  rl: readline.Interface,
+  session: ChatSession,
+  scope: FileScope,
+  lastCitations: ChatCitation[],
+  startNewSession: () => void
 ): Promise<void> {
   const parts = command.split(' ');
   const cmd = parts[0].toLowerCase();
+  // Paths may contain spaces
+  const arg = command.slice(parts[0].length).trim();
 
   switch (cmd) {
     case '/help':
       console.log(chalk.gray(`
 Commands:
   /help           Show this help
-  /clear          Clear conversation history
+  /file <path>    Include a file in the context of every question from now on
+  /sources        Show the code retrieved for the last answer
+  /save <file>    Write the conversation to a Markdown file
+  /clear          Start a new session (the current one stays saved)
   /model <name>   Switch model (e.g., /model gpt-4o)
   /models         List available models
   /quit           Exit chat
 `));
       break;
 
+    case '/file': {
+      if (!arg) {
+        const files = scope.files.length > 0 ? scope.files.join(', ') : 'none';
+        console.log(chalk.gray(`Usage: /file <path>. Always included: ${files}\n`));
+        break;
+      }
+      try {
+        const [file] = resolveFileScope([arg], scope.repoRoot);
+        if (scope.files.includes(file)) {
+          console.log(chalk.gray(`${file} is already included.\n`));
+        } else {
+          scope.files.push(file);
+          console.log(chalk.gray(`Including ${file} with every question.\n`));
+        }
+      } catch (error: any) {
+        console.log(chalk.yellow(`${error.message.replace(/^--file /, '')}\n`));
+      }
+      break;
+    }
+
+    case '/sources':
+      if (lastCitations.length === 0) {
+        console.log(chalk.gray('No code was retrieved for the last answer.\n'));
+        break;
+      }
+      printCitations(lastCitations, 'Sources for the last answer:');
+      break;
+
+    case '/save': {
+      if (!arg) {
+        console.log(chalk.gray('Usage: /save <file>\n'));
+        break;
+      }
+      if (session.messages.length === 0) {
+        console.log(chalk.gray('Nothing to save yet.\n'));
+        break;
+      }
+      try {
+        const file = path.resolve(arg);
+        await fs.mkdir(path.dirname(file), { recursive: true });
+        await fs.writeFile(file, formatChatTranscript(session), 'utf-8');
+        console.log(chalk.gray(`Saved the conversation to ${path.relative(process.cwd(), file) || file}\n`));
+      } catch (error: any) {
+        console.log(chalk.yellow(`Could not save: ${error.message}\n`));
+      }
+      break;
+    }
+
     case '/clear':
-      messages.length = 0;
-      console.log(chalk.gray('Conversation cleared.\n'));
+      startNewSession();
+      console.log(chalk.gray('Started a new session.\n'));
       break;
 
     case '/model':
-      if (parts[1]) {
-        client.setModel(parts[1]);
+      if (arg) {
+        client.setModel(arg);
+        // Later answers are saved with the model that gave them
+        session.model = client.getModel();
         console.log(chalk.gray(`Switched to ${client.getModel()}\n`));
       } else {
         console.log(chalk.gray(`Current model: ${client.getModel()}\n`));
@@ -372,27 +762,129 @@ Commands:
   }
 }
 
+/**
+ * Tokens of session history sent with a question: the current model's
+ * context window (or ai.contextWindow) minus the answer reserve. The oldest
+ * turns beyond it are left out.
+ */
+function getHistoryBudget(client: AIClient, ai: CVConfig['ai']): number {
+  return getContextBudget({ model: client.getModel(), maxOutputTokens: ai.maxTokens, contextWindow: ai.contextWindow });
+}
+
+/**
+ * Add the answer to the question just asked and save the session. An empty
+ * answer (aborted before any token) drops the question instead.
+ */
+async function recordAnswer(session: ChatSession, repoRoot: string, answer: string): Promise<void> {
+  if (answer) {
+    session.messages.push({ role: 'assistant', content: answer, timestamp: Date.now() });
+  } else {
+    session.messages.pop();
+  }
+  if (session.messages.length === 0) return;
+
+  try {
+    await saveChatSession(repoRoot, session);
+  } catch (error: any) {
+    console.error(chalk.yellow(`Could not save chat session: ${error.message}`));
+  }
+}
+
+/**
+ * Print saved sessions, most recent first
+ */
+async function listSessions(repoRoot: string, output: ReturnType<typeof createOutput>): Promise<void> {
+  const sessions = await listChatSessions(repoRoot);
+  if (output.isJson) {
+    output.json(sessions);
+    return;
+  }
+  if (sessions.length === 0) {
+    console.log(chalk.gray('No saved chat sessions.'));
+    return;
+  }
+
+  console.log(chalk.bold('\nChat sessions:\n'));
+  for (const session of sessions) {
+    const updated = new Date(session.updatedAt).toLocaleString();
+    const turns = `${session.turns} question${session.turns === 1 ? '' : 's'}`;
+    console.log(`  ${chalk.cyan(session.id)}  ${chalk.gray(`${updated} · ${turns} · ${session.model}`)}`);
+    if (session.title) {
+      console.log(`    ${session.title}`);
+    }
+  }
+  console.log(chalk.gray('\nResume with: cv chat --resume <id>\n'));
+}
+
+/** Files named with --file, always included as context */
+export interface FileScope {
+  repoRoot: string;
+  files: string[];
+}
+
+/**
+ * A code chunk given to the model as context
+ */
+export interface GatheredContext {
+  text: string;
+  /** Number of chunks that passed the threshold */
+  chunkCount: number;
+  /** The chunks in the order they appear in the context */
+  citations: ChatCitation[];
+  nearMissScore?: number;
+}
+
 /**
  * Gather relevant context from the knowledge graph
  */
-async function gatherContext(
+export async function gatherContext(
   query: string,
-  vector: VectorManager,
+  vector: VectorManager | null,
   graph: GraphManager | null,
-  limit: number
-): Promise<string> {
+  retrieval: RetrievalSettings,
+  scope: FileScope
+): Promise<GatheredContext> {
   const parts: string[] = [];
+  let chunkCount = 0;
+  let citations: ChatCitation[] = [];
+  let nearMissScore: number | undefined;
 
-  // Search for relevant code
+  // Search for relevant code; files named with --file come first and skip the threshold
   try {
-    const chunks = await vector.searchCode(query, limit, { minScore: 0.5 });
+    let chunks: VectorSearchResult<CodeChunkPayload>[] = [];
+    if (vector) {
+      const results = await vector.searchCode(query, retrieval.topK, {
+        withVectors: true,
+        tests: retrieval.tests,
+        packages: retrieval.packages,
+        kinds: retrieval.kinds,
+        extensions: retrieval.extensions
+      });
+      const thresholded = applyMinScore(results, retrieval.minScore);
+      chunks = thresholded.results;
+      nearMissScore = thresholded.nearMissScore;
+    }
+    if (scope.files.length > 0) {
+      const fileChunks = await gatherFileChunks(scope.repoRoot, scope.files, { query, vector: vector || undefined });
+      chunks = mergeFileChunks(fileChunks, chunks);
+    }
+    chunks = deduplicateChunks(chunks, retrieval.dedupeThreshold).chunks;
+    chunkCount = chunks.length;
+    citations = chunks.map(({ payload, score }) => ({
+      file: payload.file,
+      startLine: payload.startLine,
+      endLine: payload.endLine,
+      ...(payload.symbolName ? { symbol: payload.symbolName } : {}),
+      ...(payload.wholeFile ? {} : { score })
+    }));
 
     if (chunks.length > 0) {
       parts.push('## Relevant Code\n');
 
       for (const chunk of chunks) {
         const { payload, score } = chunk;
-        parts.push(`### ${payload.file}:${payload.startLine}-${payload.endLine} (${(score * 100).toFixed(0)}% match)`);
+        const relevance = payload.wholeFile ? 'requested file' : `${(score * 100).toFixed(0)}% match`;
+        parts.push(`### ${payload.file}:${payload.startLine}-${payload.endLine} (${relevance})`);
         if (payload.symbolName) {
           parts.push(`Symbol: ${payload.symbolName} (${payload.symbolKind})`);
         }
@@ -437,13 +929,69 @@ async function gatherContext(
     // Return empty context on error
   }
 
-  return parts.join('\n');
+  return { text: parts.join('\n'), chunkCount, citations, nearMissScore };
+}
+
+/**
+ * One source line, e.g. "src/auth/session.ts:12-40 (refreshToken) 83%"
+ */
+export function formatCitation(citation: ChatCitation): string {
+  const symbol = citation.symbol ? ` (${citation.symbol})` : '';
+  const score = citation.score !== undefined ? ` ${(citation.score * 100).toFixed(0)}%` : ' (requested file)';
+  return `${citation.file}:${citation.startLine}-${citation.endLine}${symbol}${score}`;
+}
+
+/**
+ * Follow-up questions naming the code cited for an answer; none when no
+ * indexed code was attached or the request fails
+ */
+async function suggestFollowUps(
+  client: AIClient,
+  question: string,
+  answer: string,
+  citations: ChatCitation[]
+): Promise<string[]> {
+  if (citations.length === 0 || !answer) return [];
+
+  const spinner = ora('Suggesting follow-up questions...').start();
+  try {
+    const response = await client.chat([{ role: 'user', content: buildFollowUpPrompt(question, answer, citations) }]);
+    return parseFollowUps(response, citations);
+  } catch (error: any) {
+    console.error(chalk.gray(`Could not suggest follow-up questions: ${error.message}`));
+    return [];
+  } finally {
+    spinner.stop();
+  }
+}
+
+/**
+ * The code an answer was given as context, under a heading
+ */
+function printCitations(citations: ChatCitation[], heading: string): void {
+  if (citations.length === 0) return;
+  console.log(chalk.gray(heading));
+  for (const citation of citations) {
+    console.log(chalk.gray(`  ${formatCitation(citation)}`));
+  }
+  console.log();
+}
+
+/**
+ * Explain why no code was attached when every chunk fell below --min-score
+ */
+function printNearMiss(context: GatheredContext, retrieval: RetrievalSettings): void {
+  if (context.chunkCount > 0) return;
+  const nearMiss = formatNearMiss(context.nearMissScore, retrieval.minScore);
+  if (nearMiss) {
+    console.log(chalk.gray(nearMiss) + '\n');
+  }
 }
 
 /**
  * Cleanup resources
  */
-async function cleanup(vector: VectorManager | null, graph: GraphManager | null): Promise<void> {
+export async function cleanup(vector: VectorManager | null, graph: GraphManager | null): Promise<void> {
   if (vector) await vector.close();
   if (graph) await graph.close();
 }
@@ -9,13 +9,15 @@ import chalk from 'chalk';
 import ora from 'ora';
 import * as readline from 'readline';
 import * as path from 'path';
+import { promises as fs } from 'fs';
 import {
   configManager,
-  createVectorManager,
   createGraphManager,
   createGitManager,
   createOpenRouterClient,
   createOllamaClient,
+  createGeminiClient,
+  createAnthropicClient,
   createCodeAssistant,
   createAIClient,
   detectAvailableProviders,
@@ -31,22 +33,46 @@ import {
   GitManager,
   CodeAssistant,
   CodePhase,
+  ContextSnapshot,
   Edit,
+  ProcessResult,
+  InsertionTarget,
+  buildInsertionInstruction,
+  extractGeneratedCode,
+  locateInsertion,
+  endOfFile,
+  createInsertionEdit,
+  getVectorBackendOptions,
+  getIndexDir,
+  readManifest,
+  generateRepoId,
+  loadRepoLanguages,
+  RepoLanguages,
 } from '@cv-git/core';
-import { findRepoRoot, loadWorkspace, findWorkspaceRoot, CVWorkspace } from '@cv-git/shared';
+import { findRepoRoot, getCVDir, loadWorkspace, findWorkspaceRoot, CVWorkspace } from '@cv-git/shared';
 import { CredentialManager } from '@cv-git/credentials';
 import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { logProviderServed } from '../utils/providers.js';
 import { ensureInfrastructure, checkSyncState } from '../utils/infrastructure.js';
+import { createVectorManagerFromCredentials, getAnthropicApiKey, getGeminiApiKey } from '../utils/credentials.js';
 import { getEditPromptText, parseEditAction, formatEditSummary, EditAction } from '../utils/prompts.js';
 import { divider, labeledDivider, statusLine, colorizeDiff } from '../utils/formatting.js';
+import { addRetrievalOptions, resolveRetrieval, formatNearMiss } from '../utils/retrieval.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
 
 interface CodeOptions {
   model?: string;
-  provider?: 'openrouter' | 'ollama' | 'auto';
+  provider?: 'openrouter' | 'anthropic' | 'ollama' | 'gemini' | 'auto';
   ollamaUrl?: string;
   yes?: boolean;
   resume?: string;
+  apply?: string;
+  after?: string;
+  line?: string;
   contextLimit?: string;
+  language?: string;
+  minScore?: string;
+  topK?: string;
   verbose?: boolean;
   quiet?: boolean;
   json?: boolean;
@@ -58,19 +84,41 @@ export function codeCommand(): Command {
   cmd
     .description('AI-powered code editing with knowledge graph context')
     .argument('[instruction]', 'One-shot instruction (omit for interactive mode)')
-    .option('-m, --model <model>', 'Model to use (e.g., claude-sonnet-4-5, qwen2.5-coder:14b)')
-    .option('-p, --provider <provider>', 'AI provider: openrouter, ollama, or auto (default: auto)', 'auto')
+    .option('-p, --provider <provider>', 'AI provider: openrouter, anthropic, ollama, gemini, or auto (default: auto)', 'auto')
     .option('--ollama-url <url>', 'Ollama server URL (default: http://localhost:11434)')
     .option('-y, --yes', 'Auto-approve all edits (no confirmation)')
     .option('-r, --resume <id>', 'Resume a previous session')
-    .option('-c, --context-limit <n>', 'Token limit for context', '100000');
-
+    .option('--apply <file>', 'Insert the generated code into this file, then exit')
+    .option('--after <symbol>', 'With --apply: insert after this function, class or method (Class.method)')
+    .option('--line <n>', 'With --apply: insert at this line')
+    .option('-c, --context-limit <n>', 'Token limit for context', '100000')
+    .option('--language <language>', 'Language to generate code in (default: detected from the repo)');
+
+  addModelOption(cmd, 'code');
+  addRetrievalOptions(cmd);
   addGlobalOptions(cmd);
 
   cmd.action(async (instruction: string | undefined, options: CodeOptions) => {
     const output = createOutput(options as any);
 
     try {
+      if ((options.after || options.line) && !options.apply) {
+        throw new Error('--after and --line need --apply <file>');
+      }
+      if (options.after && options.line) {
+        throw new Error('--after and --line cannot be combined');
+      }
+      if (options.apply && !instruction) {
+        throw new Error('--apply needs an instruction, e.g. cv code "add a retry helper" --apply src/http.ts');
+      }
+      if (options.apply && !options.yes && !process.stdin.isTTY) {
+        throw new Error('--apply asks for confirmation; pass --yes when stdin is not a terminal');
+      }
+      const insertLine = options.line !== undefined ? parseInt(options.line, 10) : undefined;
+      if (insertLine !== undefined && !(insertLine >= 1)) {
+        throw new Error(`--line must be a line number, got ${options.line}`);
+      }
+
       // Check for workspace mode first
       const workspaceRoot = await findWorkspaceRoot();
       let workspace: CVWorkspace | null = null;
@@ -93,8 +141,23 @@ export function codeCommand(): Command {
         graphDatabase = 'cv-git';
       }
 
+      let insertion: InsertionTarget | undefined;
+      if (options.apply) {
+        const file = path.relative(repoRoot, path.resolve(options.apply)).split(path.sep).join('/');
+        if (file.startsWith('..') || path.isAbsolute(file)) {
+          throw new Error(`--apply file ${options.apply} is outside the repository`);
+        }
+        try {
+          await fs.access(path.join(repoRoot, file));
+        } catch {
+          throw new Error(`--apply file ${options.apply} does not exist`);
+        }
+        insertion = { file, afterSymbol: options.after, line: insertLine };
+      }
+
       // Load configuration
       const config = await configManager.load(repoRoot);
+      const retrieval = resolveRetrieval(options, config.search, { minScore: 0.5, topK: 15 }, repoRoot);
 
       // Use workspace graph database if available
       if (workspace) {
@@ -103,9 +166,8 @@ export function codeCommand(): Command {
         graphDatabase = config.graph.database;
       }
 
-      // Get API keys
+      // Get the OpenRouter key for chat (embeddings use the configured provider)
       let openrouterApiKey = process.env.OPENROUTER_API_KEY;
-      let openaiApiKey = process.env.OPENAI_API_KEY;
 
       try {
         const credentials = new CredentialManager();
@@ -114,9 +176,6 @@ export function codeCommand(): Command {
         if (!openrouterApiKey) {
           openrouterApiKey = await credentials.getOpenRouterKey() || undefined;
         }
-        if (!openaiApiKey) {
-          openaiApiKey = await credentials.getOpenAIKey() || undefined;
-        }
       } catch {
         // Credential manager not available
       }
@@ -153,7 +212,41 @@ export function codeCommand(): Command {
       // Create AI client based on provider
       let aiClient: AIClient;
 
-      if (requestedProvider === 'ollama' || (requestedProvider === 'auto' && providers.ollama)) {
+      if (requestedProvider === 'gemini') {
+        // Use Google Gemini (never picked by auto)
+        const geminiApiKey = await getGeminiApiKey();
+        if (!geminiApiKey) {
+          console.error(chalk.red('Gemini API key not found.'));
+          console.error(chalk.gray('Run: cv auth setup gemini'));
+          console.error(chalk.gray('Or set: export GEMINI_API_KEY=...'));
+          process.exit(1);
+        }
+
+        aiClient = createGeminiClient({
+          apiKey: geminiApiKey,
+          model: resolveModel('code', options.model, config, 'gemini'),
+          maxTokens: 8192,
+        });
+
+      } else if (requestedProvider === 'anthropic') {
+        // Use the Anthropic API directly (never picked by auto)
+        const anthropicApiKey = await getAnthropicApiKey(config.ai.apiKey);
+        if (!anthropicApiKey) {
+          console.error(chalk.red('Anthropic API key not found.'));
+          console.error(chalk.gray('Run: cv auth setup anthropic'));
+          console.error(chalk.gray('Or set: export ANTHROPIC_API_KEY=sk-ant-...'));
+          process.exit(1);
+        }
+
+        aiClient = createAnthropicClient({
+          apiKey: anthropicApiKey,
+          model: resolveModel('code', options.model, config, 'anthropic')
+            || (config.ai.provider === 'anthropic' ? config.ai.model : undefined),
+          maxTokens: 8192,
+          promptCaching: config.ai.promptCaching,
+        });
+
+      } else if (requestedProvider === 'ollama' || (requestedProvider === 'auto' && providers.ollama)) {
         // Use Ollama
         if (!providers.ollama) {
           console.error(chalk.red('Ollama is not running.'));
@@ -162,7 +255,7 @@ export function codeCommand(): Command {
           process.exit(1);
         }
 
-        const ollamaModel = options.model || 'qwen2.5-coder:14b';
+        const ollamaModel = resolveModel('code', options.model, config, 'ollama') || 'qwen2.5-coder:14b';
         aiClient = createOllamaClient({
           baseUrl: options.ollamaUrl,
           model: ollamaModel,
@@ -216,7 +309,7 @@ export function codeCommand(): Command {
           process.exit(1);
         }
 
-        const model = options.model || 'claude-sonnet-4-5';
+        const model = resolveModel('code', options.model, config, 'openrouter') || 'claude-sonnet-4-5';
         aiClient = createOpenRouterClient({
           apiKey: openrouterApiKey,
           model,
@@ -265,15 +358,21 @@ export function codeCommand(): Command {
         }
       }
 
-      const embeddingKey = openrouterApiKey || openaiApiKey;
-      if (embeddingKey && infra.qdrant.available && infra.qdrant.url) {
+      const backendOptions = getVectorBackendOptions(config.vector);
+      const storeReady = backendOptions.backend === 'pgvector' || backendOptions.backend === 'local' ||
+        (infra.qdrant.available && !!infra.qdrant.url);
+      if (storeReady) {
         try {
-          vector = createVectorManager({
-            url: infra.qdrant.url,
-            openrouterApiKey: openrouterApiKey,
-            openaiApiKey: openaiApiKey,
-            collections: config.vector?.collections || { codeChunks: 'code_chunks', docstrings: 'docstrings', commits: 'commits' },
-            embeddingModel: config.embedding?.model
+          // Use the same repo-isolated collections that cv sync writes to,
+          // embedding with the configured provider
+          const manifest = await readManifest(getCVDir(repoRoot));
+          const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+          vector = await createVectorManagerFromCredentials(config, {
+            url: infra.qdrant.url || config.vector.url,
+            repoId,
+            efSearch: retrieval.efSearch,
+            indexDir: getIndexDir(repoRoot),
+            onEmbeddingServed: logProviderServed(options, 'Embedding')
           });
           await vector.connect();
         } catch (e) {
@@ -294,6 +393,14 @@ export function codeCommand(): Command {
         }
       }
 
+      // Dominant languages (recorded by cv sync) steer generation toward the repo's idioms
+      let repoLanguages: RepoLanguages | undefined;
+      try {
+        repoLanguages = await loadRepoLanguages(gitRoot, git);
+      } catch {
+        output.debug?.('Could not detect repository languages');
+      }
+
       // Create CodeAssistant
       const assistant = createCodeAssistant(
         repoRoot,
@@ -301,7 +408,17 @@ export function codeCommand(): Command {
         graph,
         git,
         aiClient,
-        { contextLimit: parseInt(options.contextLimit || '100000', 10) }
+        {
+          contextLimit: parseInt(options.contextLimit || '100000', 10),
+          topK: retrieval.topK,
+          minScore: retrieval.minScore,
+          tests: retrieval.tests,
+          packages: retrieval.packages,
+          kinds: retrieval.kinds,
+          extensions: retrieval.extensions,
+          language: options.language,
+          repoLanguages
+        }
       );
 
       // Initialize or resume session
@@ -319,6 +436,9 @@ export function codeCommand(): Command {
         console.log(chalk.gray('  Repos:     ') + chalk.gray(workspace.repos.map(r => r.name).join(', ')));
       }
       console.log(chalk.gray('  Branch:    ') + chalk.white(branch) + chalk.gray(` @ ${commitSha.slice(0, 7)}`));
+      if (options.language || repoLanguages?.primary.length) {
+        console.log(chalk.gray('  Language:  ') + chalk.white(options.language || repoLanguages!.primary.join(', ')));
+      }
       if (vector && graph) {
         console.log(chalk.gray('  Context:   ') + statusLine('success', `Knowledge graph: ${graphDatabase}`));
       } else if (graph) {
@@ -328,13 +448,20 @@ export function codeCommand(): Command {
       }
       console.log();
 
+      // Insert generated code into a file and stop
+      if (insertion) {
+        await handleApplyInstruction(instruction!, insertion, repoRoot, assistant, options.yes || false, retrieval.minScore);
+        await cleanup(vector, graph);
+        return;
+      }
+
       // Process initial instruction if provided, then continue to interactive mode
       if (instruction) {
-        await handleSingleInstruction(instruction, assistant, options.yes || false);
+        await handleSingleInstruction(instruction, assistant, options.yes || false, retrieval.minScore);
       }
 
       // Always enter interactive mode (like Claude Code)
-      await interactiveMode(assistant, options.yes || false);
+      await interactiveMode(assistant, options.yes || false, retrieval.minScore);
       await cleanup(vector, graph);
 
     } catch (error: any) {
@@ -532,8 +659,80 @@ function getPhaseSpinnerText(phase: CodePhase, message?: string): string {
 async function handleSingleInstruction(
   instruction: string,
   assistant: CodeAssistant,
-  autoApprove: boolean
+  autoApprove: boolean,
+  minScore: number
 ): Promise<void> {
+  const result = await streamInstruction(instruction, assistant);
+  printNearMiss(result.contextSnapshot, minScore);
+
+  // Show pending edits with visual separation
+  if (result.edits.length > 0) {
+    await showAndApplyEdits(assistant, result.edits, autoApprove);
+  } else {
+    console.log(divider('light'));
+  }
+  console.log();
+}
+
+/**
+ * Handle an instruction whose generated code is inserted into a file (--apply)
+ */
+async function handleApplyInstruction(
+  instruction: string,
+  target: InsertionTarget,
+  repoRoot: string,
+  assistant: CodeAssistant,
+  autoApprove: boolean,
+  minScore: number
+): Promise<void> {
+  const original = await fs.readFile(path.join(repoRoot, target.file), 'utf-8');
+  assistant.addFile(target.file);
+
+  const result = await streamInstruction(buildInsertionInstruction(instruction, target), assistant);
+  printNearMiss(result.contextSnapshot, minScore);
+
+  const rl = autoApprove ? undefined : readline.createInterface({ input: process.stdin, output: process.stdout });
+  try {
+    const code = extractGeneratedCode(result.response);
+    if (!code) {
+      if (result.edits.length > 0) {
+        console.log(chalk.yellow('The response has edits instead of code to insert.'));
+        await showAndApplyEdits(assistant, result.edits, autoApprove, rl);
+        return;
+      }
+      throw new Error('The response has no code block to insert');
+    }
+
+    // The code is inserted as generated; drop file blocks the model added anyway
+    for (const edit of result.edits) {
+      assistant.rejectEdit(edit.id);
+    }
+
+    let point = await locateInsertion(original, target);
+    if (!point) {
+      console.log(chalk.yellow(`Symbol ${target.afterSymbol} not found in ${target.file}.`));
+      const append = autoApprove ||
+        (await question(rl!, 'Append the code to the end of the file instead? [y/N] ')).trim().toLowerCase().startsWith('y');
+      if (!append) {
+        console.log(chalk.gray('Nothing was inserted.\n'));
+        return;
+      }
+      point = endOfFile(original);
+    }
+
+    const edit = createInsertionEdit(target.file, original, code, point, result.edits[0]?.messageId);
+    assistant.addPendingEdit(edit);
+    console.log(chalk.gray(`Inserting into ${target.file} ${point.label}`));
+    await showAndApplyEdits(assistant, [edit], autoApprove, rl);
+  } finally {
+    rl?.close();
+  }
+}
+
+/**
+ * Send an instruction and stream the response, hiding edit blocks (shown as diffs afterwards)
+ */
+async function streamInstruction(instruction: string, assistant: CodeAssistant): Promise<ProcessResult> {
   const spinner = ora({ text: 'Initializing...', spinner: 'dots' }).start();
   let responseStarted = false;
   let inCodeBlock = false;
@@ -610,20 +809,24 @@ async function handleSingleInstruction(
     }
 
     console.log('\n');
-
-    // Show pending edits with visual separation
-    if (result.edits.length > 0) {
-      await showAndApplyEdits(assistant, result.edits, autoApprove);
-    } else {
-      console.log(divider('light'));
-    }
-    console.log();
+    return result;
   } catch (error: any) {
     spinner.stop();
     throw error;
   }
 }
 
+/**
+ * Explain why no code was retrieved when every chunk fell below --min-score
+ */
+function printNearMiss(snapshot: ContextSnapshot, minScore: number): void {
+  if (snapshot.symbols.length > 0) return;
+  const nearMiss = formatNearMiss(snapshot.nearMissScore, minScore);
+  if (nearMiss) {
+    console.log(chalk.gray(nearMiss) + '\n');
+  }
+}
+
 /**
  * Promisified readline question
  */
@@ -638,7 +841,8 @@ function question(rl: readline.Interface, prompt: string): Promise<string> {
  */
 async function interactiveMode(
   assistant: CodeAssistant,
-  autoApprove: boolean
+  autoApprove: boolean,
+  minScore: number
 ): Promise<void> {
   const rl = readline.createInterface({
     input: process.stdin,
@@ -745,6 +949,7 @@ async function interactiveMode(
       }
 
       console.log('\n');
+      printNearMiss(result.contextSnapshot, minScore);
 
       // Show pending edits with visual separation
       if (result.edits.length > 0) {
@@ -17,9 +17,13 @@ import { addGlobalOptions } from '../utils/output.js';
 import { CredentialManager, CredentialType, GitPlatform } from '@cv-git/credentials';
 import {
   createCommitAnalyzer,
+  resolveCommitConvention,
+  AIManagerFallback,
   CommitAnalysis,
   GeneratedCommitMessage
 } from '@cv-git/core';
+import { getAIProvider } from '../utils/model.js';
+import { resolveChatBackend } from '../utils/providers.js';
 
 /**
  * Find git repository root (works with any git repo, not just CV-initialized)
@@ -72,10 +76,37 @@ interface CommitOptions {
   dryRun?: boolean;
   type?: string;
   scope?: string;
+  diffBudget?: number;
   verbose?: boolean;
   quiet?: boolean;
 }
 
+/**
+ * Check whether anything is staged for commit; with `includeUnstaged`,
+ * whether `git commit -a` would find anything
+ */
+function hasStagedChanges(repoRoot: string, includeUnstaged: boolean = false): boolean {
+  try {
+    // Exits 0 when the index (or working tree) matches HEAD, 1 when there are changes
+    execSync(includeUnstaged ? 'git diff HEAD --quiet' : 'git diff --cached --quiet', { cwd: repoRoot, stdio: 'ignore' });
+    return false;
+  } catch {
+    return true;
+  }
+}
+
+/**
+ * Whether git passthrough args already supply a commit message
+ */
+function hasMessageArg(args: string[]): boolean {
+  return args.some(arg =>
+    arg === '-F' || arg.startsWith('--file') ||
+    arg === '-C' || arg.startsWith('--reuse-message') ||
+    arg === '-c' || arg.startsWith('--reedit-message') ||
+    arg.startsWith('--fixup') || arg.startsWith('--squash')
+  );
+}
+
 export function commitCommand(): Command {
   const cmd = new Command('commit');
 
@@ -85,9 +116,10 @@ export function commitCommand(): Command {
     .option('-a, --all', 'Automatically stage modified and deleted files')
     .option('--amend', 'Amend the previous commit')
     .option('-g, --generate', 'Generate commit message using AI + knowledge graph')
-    .option('--dry-run', 'Show generated message without committing')
+    .option('--dry-run', 'Show generated message without committing (implies --generate)')
     .option('--type <type>', 'Override commit type (feat, fix, refactor, etc.)')
     .option('--scope <scope>', 'Override commit scope')
+    .option('--diff-budget <tokens>', 'Maximum tokens of staged diff sent to the LLM (default: 4000)', parseInt)
     .option('-q, --quiet', 'Output only the generated message (for use in hooks/scripts)')
     .allowUnknownOption(true); // Allow git passthrough options
 
@@ -104,8 +136,8 @@ export function commitCommand(): Command {
       // Check if CV is initialized - warn if not but continue
       const cvInitialized = isCVInitialized(repoRoot);
 
-      // If --generate flag is used, run AI generation
-      if (options.generate) {
+      // AI generation is opt-in; otherwise git takes the message or opens the editor
+      if (options.generate || options.dryRun) {
         if (!cvInitialized) {
           console.log(chalk.yellow('Note: CV not initialized. Run `cv init` for enhanced analysis with knowledge graph.'));
         }
@@ -128,17 +160,23 @@ export function commitCommand(): Command {
         console.log(chalk.gray(`Using identity: ${identity.name} <${identity.email}>`));
       }
 
-      const spinner = ora('Committing...').start();
+      // Without a message git opens the editor, which needs the terminal
+      const opensEditor = !options.message && !hasMessageArg(command.args) && !command.args.includes('--no-edit');
+      const spinner = opensEditor ? null : ora('Committing...').start();
 
       try {
-        await gitCommit(options, command.args, identity);
-        spinner.succeed(chalk.green('Committed successfully'));
+        await gitCommit(options, command.args, identity, opensEditor);
+        if (spinner) {
+          spinner.succeed(chalk.green('Committed successfully'));
+        } else {
+          console.log(chalk.green('✔ Committed successfully'));
+        }
 
         // Show commit info
         const commitInfo = execSync('git log -1 --oneline', { encoding: 'utf-8' }).trim();
         console.log(chalk.cyan(`  ${commitInfo}`));
       } catch (error: any) {
-        spinner.fail(chalk.red('Commit failed'));
+        if (spinner) spinner.fail(chalk.red('Commit failed'));
         console.error(chalk.red(error.message));
         process.exit(1);
       }
@@ -161,6 +199,48 @@ async function handleGenerateCommit(
   extraArgs: string[],
   cvInitialized: boolean
 ): Promise<void> {
+  // -a stages tracked changes at commit time; stage them now so the message covers them.
+  // A dry run only previews, so it reads them from the working tree instead.
+  const previewAll = !!options.all && !!options.dryRun;
+  if (options.all && !options.dryRun) {
+    execSync('git add -u', { cwd: repoRoot, stdio: 'ignore' });
+  }
+
+  if (!hasStagedChanges(repoRoot, previewAll)) {
+    if (!options.quiet) {
+      console.error(chalk.yellow('Nothing staged to commit.'));
+      console.error(chalk.gray('Stage your changes first:'));
+      console.error(chalk.gray('  git add <file>...   ') + chalk.gray('# stage specific files'));
+      console.error(chalk.gray('  git add -A          ') + chalk.gray('# stage everything'));
+      console.error(chalk.gray('Or use ') + chalk.cyan('cv commit -a') + chalk.gray(' to include all tracked changes.'));
+    }
+    process.exit(1);
+  }
+
+  // Use the configured chat provider and model when CV is initialized
+  const { configManager } = await import('@cv-git/core');
+  let config: any = undefined;
+  if (cvInitialized) {
+    try {
+      config = await configManager.load(repoRoot);
+    } catch {
+      // Config unreadable, use analyzer defaults
+    }
+  }
+
+  // A configured gateway serves the message instead of Anthropic/OpenRouter
+  let gateway: AIManagerFallback | undefined;
+  if (config && getAIProvider(config) === 'openai-compatible') {
+    try {
+      gateway = await resolveChatBackend(config);
+    } catch (error: any) {
+      if (!options.quiet) {
+        console.error(chalk.red(error.message));
+      }
+      process.exit(1);
+    }
+  }
+
   // Check for API keys
   const credentials = new CredentialManager();
   await credentials.init();
@@ -190,10 +270,13 @@ async function handleGenerateCommit(
   }
 
   // Determine provider and API key to use
-  let provider: 'anthropic' | 'openrouter';
-  let apiKey: string;
+  let provider: 'anthropic' | 'openrouter' | 'openai-compatible';
+  let apiKey: string | undefined;
 
-  if (anthropicKey) {
+  if (gateway) {
+    provider = 'openai-compatible';
+    apiKey = gateway.apiKey || undefined;
+  } else if (anthropicKey) {
     provider = 'anthropic';
     apiKey = anthropicKey;
   } else if (openRouterKey) {
@@ -212,22 +295,36 @@ async function handleGenerateCommit(
   const spinner = options.quiet ? null : ora('Analyzing staged changes...').start();
 
   try {
+    // Import GitManager dynamically
+    const { createGitManager, createGraphManager } = await import('@cv-git/core');
+    const git = createGitManager(repoRoot);
+
+    // Message format from commit.* in the config, with the ticket ID of the branch
+    const branch = await git.getCurrentBranch().catch(() => undefined);
+    const convention = resolveCommitConvention(config?.commit, branch);
+    if (options.scope && convention.scopes && !convention.scopes.includes(options.scope)) {
+      throw new Error(`Scope "${options.scope}" is not in commit.scopes (${convention.scopes.join(', ')})`);
+    }
+    if (convention.missingTicket && spinner) {
+      spinner.info(chalk.gray(`No ticket ID in branch ${branch ?? '(unknown)'}; the message will have none`));
+      spinner.start('Analyzing staged changes...');
+    }
+
     // Create analyzer with the available provider
     const analyzer = createCommitAnalyzer({
       repoRoot,
       provider,
-      apiKey
+      apiKey,
+      model: gateway ? (gateway.model ?? config?.ai?.model) : provider === 'anthropic' ? config?.ai?.model : undefined,
+      baseUrl: gateway?.openaiCompatibleUrl,
+      diffTokenBudget: options.diffBudget,
+      convention
     });
 
-    // Import GitManager dynamically
-    const { createGitManager, createGraphManager, configManager } = await import('@cv-git/core');
-    const git = createGitManager(repoRoot);
-
     // Try to connect to graph if CV is initialized
     let graph: any = undefined;
-    if (cvInitialized) {
+    if (config) {
       try {
-        const config = await configManager.load(repoRoot);
         graph = createGraphManager(config.graph.url, config.graph.database);
         await graph.connect();
         if (spinner) spinner.text = 'Analyzing with knowledge graph context...';
@@ -237,7 +334,7 @@ async function handleGenerateCommit(
     }
 
     // Analyze staged changes
-    const analysis = await analyzer.analyzeStaged(git, graph);
+    const analysis = await analyzer.analyzeStaged(git, graph, previewAll);
 
     if (spinner) spinner.text = 'Generating commit message...';
 
@@ -259,8 +356,12 @@ async function handleGenerateCommit(
       await graph.close();
     }
 
-    // Quiet mode: output only the message and exit
+    // Quiet mode: output only the message and exit; hooks get no message that breaks the convention
     if (options.quiet) {
+      if (generated.violations?.length) {
+        printViolations(generated);
+        process.exit(1);
+      }
       console.log(generated.fullMessage);
       return;
     }
@@ -294,6 +395,7 @@ async function handleGenerateCommit(
     console.log(generated.fullMessage);
     console.log(chalk.cyan('─'.repeat(60)));
     console.log();
+    printViolations(generated);
 
     // If dry-run, just exit
     if (options.dryRun) {
@@ -329,6 +431,7 @@ async function handleGenerateCommit(
       console.log(newGenerated.fullMessage);
       console.log(chalk.cyan('─'.repeat(60)));
       console.log();
+      printViolations(newGenerated);
 
       const action2 = await prompt(chalk.yellow('[A]ccept / [C]ancel? '));
       if (action2.toLowerCase() === 'a' || action2.toLowerCase() === 'accept' || action2 === '') {
@@ -349,6 +452,19 @@ async function handleGenerateCommit(
   }
 }
 
+/**
+ * Rules of the commit convention a generated message still breaks after
+ * regenerating (on stderr, so --quiet output stays the message alone)
+ */
+function printViolations(generated: GeneratedCommitMessage): void {
+  if (!generated.violations?.length) return;
+  console.error(chalk.yellow('⚠ The message does not follow the commit conventions (commit.* in .cv/config.json):'));
+  for (const violation of generated.violations) {
+    console.error(chalk.yellow(`  - ${violation}`));
+  }
+  console.error();
+}
+
 /**
  * Edit message with system editor
  */
@@ -457,11 +573,13 @@ async function getIdentityFromCredentials(): Promise<{ name: string; email: stri
 
 /**
  * Run git commit with identity and options
+ * With `interactive`, git gets the terminal so it can open the editor
  */
 async function gitCommit(
   options: CommitOptions,
   extraArgs: string[],
-  identity: { name: string; email: string } | null
+  identity: { name: string; email: string } | null,
+  interactive: boolean = false
 ): Promise<void> {
   return new Promise((resolve, reject) => {
     const args = ['commit'];
@@ -495,12 +613,13 @@ async function gitCommit(
       !arg.startsWith('-g') &&
       !arg.startsWith('--dry-run') &&
       !arg.startsWith('--type') &&
-      !arg.startsWith('--scope')
+      !arg.startsWith('--scope') &&
+      !arg.startsWith('--diff-budget')
     );
     args.push(...filteredArgs);
 
     const git = spawn('git', args, {
-      stdio: ['inherit', 'pipe', 'pipe'],
+      stdio: interactive ? 'inherit' : ['inherit', 'pipe', 'pipe'],
     });
 
     let stdout = '';
@@ -8,7 +8,16 @@ import chalk from 'chalk';
 import { getConfig } from '../config.js';
 import { spawn } from 'child_process';
 import Table from 'cli-table3';
+import { CVConfig, findRepoRoot } from '@cv-git/shared';
 import {
+  configManager,
+  getConfigPathOverride,
+  getConfigKeySpec,
+  parseConfigValue,
+  readConfigKey,
+  listConfigKeys,
+  isSecretConfigKey,
+  maskConfigSecret,
   loadCVGitConfig,
   saveCVGitConfig,
   detectPrivilegeMode,
@@ -25,15 +34,22 @@ export function configCommand(): Command {
   cmd
     .command('get')
     .description('Get a configuration value')
-    .argument('<key>', 'Configuration key (e.g., platform.type, ai.model)')
+    .argument('<key>', 'Configuration key (e.g., ai.model, search.minScore)')
     .option('--json', 'Output as JSON')
+    .option('--global', 'Use the user config (~/.cv/config.json) instead of the repository config')
     .action(async (key: string, options) => {
       try {
-        const config = getConfig();
-        const value = await config.getNested(key);
+        const repoConfig = options.global ? null : await loadRepoConfig();
+        let value: unknown;
+        if (repoConfig) {
+          getConfigKeySpec(key);
+          value = readConfigKey(repoConfig, key);
+        } else {
+          value = await getConfig().getNested(key);
+        }
 
         if (value === undefined) {
-          console.error(chalk.red(`✗ Configuration key '${key}' not found`));
+          console.error(chalk.red(`✗ Configuration key '${key}' not ${repoConfig ? 'set' : 'found'}`));
           process.exit(1);
         }
 
@@ -51,15 +67,13 @@ export function configCommand(): Command {
   // cv config set <key> <value>
   cmd
     .command('set')
-    .description('Set a configuration value')
+    .description('Set a configuration value (checked against the key\'s type and allowed values)')
     .argument('<key>', 'Configuration key')
-    .argument('<value>', 'Value to set')
+    .argument('<value>', 'Value to set (lists: comma-separated or a JSON array)')
     .option('--json', 'Treat value as JSON')
+    .option('--global', 'Use the user config (~/.cv/config.json) instead of the repository config')
     .action(async (key: string, value: string, options) => {
       try {
-        const config = getConfig();
-
-        // Parse value
         let parsedValue: any = value;
         if (options.json) {
           try {
@@ -68,14 +82,26 @@ export function configCommand(): Command {
             console.error(chalk.red('✗ Invalid JSON value'));
             process.exit(1);
           }
-        } else {
+        }
+
+        if (!options.global && await loadRepoConfig()) {
+          if (!options.json) {
+            parsedValue = parseConfigValue(key, value);
+          }
+          await configManager.setValue(key, parsedValue);
+          const shown = isSecretConfigKey(key) && typeof parsedValue === 'string' ? maskConfigSecret(parsedValue) : parsedValue;
+          console.log(chalk.green('✓'), `Set ${chalk.cyan(key)} = ${formatValue(shown)}${profileNote()}`);
+          return;
+        }
+
+        if (!options.json) {
           // Auto-detect type
           if (value === 'true') parsedValue = true;
           else if (value === 'false') parsedValue = false;
           else if (!isNaN(Number(value)) && value !== '') parsedValue = Number(value);
         }
 
-        await config.setNested(key, parsedValue);
+        await getConfig().setNested(key, parsedValue);
         console.log(chalk.green('✓'), `Set ${chalk.cyan(key)} = ${formatValue(parsedValue)}`);
       } catch (error: any) {
         console.error(chalk.red('✗ Error setting config:'), error.message);
@@ -83,15 +109,62 @@ export function configCommand(): Command {
       }
     });
 
+  // cv config unset <key>
+  cmd
+    .command('unset')
+    .description('Remove a configuration value so its default applies again')
+    .argument('<key>', 'Configuration key')
+    .option('--global', 'Use the user config (~/.cv/config.json) instead of the repository config')
+    .action(async (key: string, options) => {
+      try {
+        let removed: boolean;
+        if (!options.global && await loadRepoConfig()) {
+          removed = await configManager.unsetValue(key);
+        } else {
+          const config = getConfig();
+          removed = (await config.getNested(key)) !== undefined;
+          if (removed) {
+            await config.setNested(key, undefined);
+          }
+        }
+
+        if (removed) {
+          console.log(chalk.green('✓'), `Unset ${chalk.cyan(key)}${profileNote()}`);
+        } else {
+          console.log(chalk.yellow(`${key} is not set${profileNote()}`));
+        }
+      } catch (error: any) {
+        console.error(chalk.red('✗ Error unsetting config:'), error.message);
+        process.exit(1);
+      }
+    });
+
   // cv config list
   cmd
     .command('list')
-    .description('List all configuration values')
+    .description('List all configuration values (secrets masked)')
     .option('--json', 'Output as JSON')
+    .option('--global', 'Use the user config (~/.cv/config.json) instead of the repository config')
     .action(async (options) => {
       try {
-        const config = getConfig();
-        const fullConfig = await config.load();
+        const repoConfig = options.global ? null : await loadRepoConfig();
+        if (repoConfig) {
+          const entries = listConfigKeys(repoConfig);
+          if (options.json) {
+            console.log(JSON.stringify(Object.fromEntries(entries), null, 2));
+            return;
+          }
+
+          console.log(chalk.bold('\n📋 CV-Git Configuration\n'));
+          console.log(chalk.gray(`${configManager.getConfigPath()}${profileNote()}\n`));
+          for (const [key, value] of entries) {
+            printKeyValue(`  ${key}`, Array.isArray(value) ? value.join(', ') : value);
+          }
+          console.log();
+          return;
+        }
+
+        const fullConfig = await getConfig().load();
 
         if (options.json) {
           console.log(JSON.stringify(fullConfig, null, 2));
@@ -345,6 +418,28 @@ export function configCommand(): Command {
   return cmd;
 }
 
+/**
+ * The repository config (or the --config file) with the --profile applied,
+ * as written: ${VAR} references are shown, not expanded. Null outside a
+ * repository, where get/set/list use the user config.
+ */
+async function loadRepoConfig(): Promise<CVConfig | null> {
+  const repoRoot = await findRepoRoot();
+  if (!repoRoot && !getConfigPathOverride()) {
+    return null;
+  }
+  await configManager.load(repoRoot ?? process.cwd());
+  return configManager.getLiteral();
+}
+
+/**
+ * " (profile work)" when a profile is selected
+ */
+function profileNote(): string {
+  const profile = configManager.getProfile();
+  return profile ? chalk.gray(` (profile ${profile})`) : '';
+}
+
 /**
  * Format value for display
  */
@@ -16,6 +16,7 @@ import {
   configManager,
   createVectorManager,
   createGraphManager,
+  getVectorBackendOptions
 } from '@cv-git/core';
 import { findRepoRoot, VectorSearchResult, CodeChunkPayload, SymbolNode } from '@cv-git/shared';
 import { PRDClient } from '@cv-git/prd-client';
@@ -140,12 +141,14 @@ export function contextCommand(): Command {
       const useLocal = !!(ollamaUrl || lmstudioUrl);
       const vector = createVectorManager({
         url: config.vector.url,
+        ...getVectorBackendOptions(config.vector),
         ollamaUrl,
         lmstudioUrl,
         openrouterApiKey: useLocal ? undefined : openrouterApiKey,
         openaiApiKey: useLocal ? undefined : openaiApiKey,
         collections: config.vector.collections,
-        vectorSize: (embeddingProvider === 'ollama' || embeddingProvider === 'lmstudio') ? 768 : 1536
+        vectorSize: (embeddingProvider === 'ollama' || embeddingProvider === 'lmstudio') ? 768 : 1536,
+        embeddingFallbacks: config.embedding?.providers
       });
       await vector.connect();
 
@@ -8,7 +8,7 @@
 import { Command } from 'commander';
 import chalk from 'chalk';
 import ora from 'ora';
-import { spawn, execSync } from 'child_process';
+import { spawn, execFileSync } from 'child_process';
 import * as fs from 'fs';
 import * as path from 'path';
 import {
@@ -16,11 +16,25 @@ import {
   createAIManager,
   createGraphManager,
   createGraphService,
-  createGitManager
+  createGitManager,
+  createVectorManager,
+  readManifest,
+  generateRepoId,
+  getIndexDir,
+  getVectorBackendOptions,
+  parseChangedRanges,
+  findComplexChanges,
+  ComplexChange,
+  DiffExplanation,
+  DEFAULT_COMPLEXITY_THRESHOLD,
+  AIManagerFallback
 } from '@cv-git/core';
-import { findRepoRoot as findCVRepoRoot } from '@cv-git/shared';
+import { findRepoRoot as findCVRepoRoot, getCVDir, CVConfig } from '@cv-git/shared';
 import { addGlobalOptions, createOutput } from '../utils/output.js';
-import { getAnthropicApiKey } from '../utils/credentials.js';
+import { resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { resolveChatTimeout } from '../utils/timeout.js';
+import { getEmbeddingCredentials, embeddingVectorOptions } from '../utils/credentials.js';
 
 /**
  * Find git repository root
@@ -43,6 +57,7 @@ function findGitRoot(startDir: string = process.cwd()): string | null {
 interface DiffOptions {
   staged?: boolean;
   cached?: boolean;
+  commit?: string;
   stat?: boolean;
   shortstat?: boolean;
   summary?: boolean;
@@ -54,6 +69,8 @@ interface DiffOptions {
   conventional?: boolean;
   impact?: boolean;
   strict?: boolean;
+  complexityThreshold?: string;
+  model?: string;
   verbose?: boolean;
   quiet?: boolean;
   json?: boolean;
@@ -67,6 +84,7 @@ export function diffCommand(): Command {
     .argument('[commit...]', 'Commits or refs to compare')
     .option('--staged', 'Show staged changes (same as --cached)')
     .option('--cached', 'Show staged changes')
+    .option('--commit <sha>', 'Show (or explain) the changes made by a commit')
     .option('--stat', 'Show diffstat')
     .option('--shortstat', 'Show only summary line')
     .option('--summary', 'Show condensed summary')
@@ -78,6 +96,8 @@ export function diffCommand(): Command {
     .option('--conventional', 'Generate a conventional commit message for the changes')
     .option('--impact', 'Include impact analysis of changed symbols')
     .option('--strict', 'Use stricter review criteria (with --review)')
+    .option('--model <model>', 'Model for AI analysis (default: config models.diff, then ai.model)')
+    .option('--complexity-threshold <n>', `Flag changed functions at or above this complexity (with --explain, default: ${DEFAULT_COMPLEXITY_THRESHOLD})`)
     .allowUnknownOption(true);
 
   addGlobalOptions(cmd);
@@ -95,8 +115,15 @@ export function diffCommand(): Command {
       // Check if any AI feature is requested
       const needsAI = options.explain || options.review || options.conventional;
 
-      // Build git diff arguments
-      const args = ['diff'];
+      if (options.commit && (options.staged || options.cached || commits.length > 0)) {
+        throw new Error('--commit cannot be combined with --staged or other refs');
+      }
+      if (options.complexityThreshold !== undefined && !/^[1-9]\d*$/.test(options.complexityThreshold)) {
+        throw new Error(`--complexity-threshold must be a positive integer, got ${options.complexityThreshold}`);
+      }
+
+      // Build git diff arguments (git show for a single historical commit)
+      const args = options.commit ? ['show', '--format='] : ['diff'];
 
       if (options.staged || options.cached) args.push('--cached');
       if (options.stat) args.push('--stat');
@@ -106,7 +133,9 @@ export function diffCommand(): Command {
       if (options.nameStatus) args.push('--name-status');
 
       // Add commits/refs
-      if (commits.length > 0) {
+      if (options.commit) {
+        args.push(options.commit);
+      } else if (commits.length > 0) {
         args.push(...commits);
       }
 
@@ -121,12 +150,13 @@ export function diffCommand(): Command {
 
       if (options.json) {
         // JSON output mode
-        const diffOutput = execSync(`git ${args.slice(1).join(' ')}`, {
+        const diffOutput = execFileSync('git', args, {
           cwd: repoRoot,
           encoding: 'utf-8',
+          maxBuffer: 64 * 1024 * 1024,
         });
 
-        const stats = getDiffStats(repoRoot, options.staged || options.cached);
+        const stats = getDiffStats(repoRoot, options);
 
         const result: any = {
           diff: diffOutput,
@@ -149,9 +179,10 @@ export function diffCommand(): Command {
         // Normal output - passthrough to git
         if (options.analyze || needsAI) {
           // Get diff for analysis
-          const diffOutput = execSync(`git ${args.slice(1).join(' ')}`, {
+          const diffOutput = execFileSync('git', args, {
             cwd: repoRoot,
             encoding: 'utf-8',
+            maxBuffer: 64 * 1024 * 1024,
           });
 
           // Show the diff (unless only requesting conventional commit message)
@@ -216,10 +247,12 @@ async function runGitDiff(args: string[], cwd: string): Promise<void> {
 /**
  * Get diff statistics
  */
-function getDiffStats(cwd: string, staged: boolean = false): { files: number; insertions: number; deletions: number } {
+function getDiffStats(cwd: string, options: DiffOptions): { files: number; insertions: number; deletions: number } {
   try {
-    const args = staged ? '--cached --shortstat' : '--shortstat';
-    const output = execSync(`git diff ${args}`, {
+    const args = options.commit
+      ? ['show', '--format=', '--shortstat', options.commit]
+      : ['diff', ...(options.staged || options.cached ? ['--cached'] : []), '--shortstat'];
+    const output = execFileSync('git', args, {
       cwd,
       encoding: 'utf-8',
     });
@@ -312,7 +345,7 @@ async function analyzeDiff(diff: string, repoRoot: string): Promise<string> {
 
   analysis.push(chalk.bold('Suggested commit type: ') + chalk.yellow(suggestedType));
   analysis.push('');
-  analysis.push(chalk.gray('Tip: Run `cv diff --explain` for AI-powered explanation'));
+  analysis.push(chalk.gray('Tip: Run `cv diff --explain` for an AI summary of the changes and their risks'));
   analysis.push(chalk.gray('     Run `cv diff --review` for AI-powered code review'));
   analysis.push(chalk.gray('     Run `cv diff --conventional` for commit message generation'));
 
@@ -341,12 +374,15 @@ async function runAIAnalysis(
 
   // Load configuration
   const config = await configManager.load(cvRepoRoot);
+  const model = resolveModel('diff', options.model, config);
 
-  // Get API key
-  const anthropicApiKey = await getAnthropicApiKey(config.ai?.apiKey);
-  if (!anthropicApiKey) {
-    console.error(chalk.red('\nAnthropic API key not found.'));
-    console.error(chalk.gray('Run `cv auth setup anthropic` to configure.'));
+  // Chat backend for ai.provider: Anthropic, an Azure chat deployment, Gemini
+  // or an OpenAI-compatible gateway
+  let backend: AIManagerFallback;
+  try {
+    backend = await resolveChatBackend(config);
+  } catch (error: any) {
+    console.error(chalk.red(`\n${error.message}`));
     return null;
   }
 
@@ -357,9 +393,15 @@ async function runAIAnalysis(
   const git = createGitManager(repoRoot);
   const ai = createAIManager(
     {
-      provider: 'anthropic',
-      model: config.ai?.model || 'claude-sonnet-4-5-20250514',
-      apiKey: anthropicApiKey
+      ...chatBackendOptions(backend, model, config),
+      timeoutMs: resolveChatTimeout(config),
+      promptCaching: config.ai.promptCaching,
+      fallbacks: await resolveAIFallbacks(config),
+      onServed: logProviderServed(options),
+      redaction: {
+        enabled: config.redaction?.enabled !== false,
+        patterns: config.redaction?.patterns
+      }
     },
     undefined,
     undefined,
@@ -410,18 +452,27 @@ async function runAIAnalysis(
 
   // Handle --explain
   if (options.explain) {
-    spinner.start('Generating AI explanation...');
+    if (!options.json) spinner.start('Looking up complex functions...');
+    const threshold = options.complexityThreshold
+      ? parseInt(options.complexityThreshold, 10)
+      : DEFAULT_COMPLEXITY_THRESHOLD;
+    const complexChanges = await findComplexChangesInIndex(diff, cvRepoRoot, config, threshold);
+
+    if (!options.json) spinner.text = 'Generating AI explanation...';
     try {
-      const explanation = await ai.explain(`Explain what these code changes do. Be concise:\n\n${diff}${impactInfo}`);
-      spinner.succeed('Explanation generated');
-      console.log(chalk.bold.cyan('\n📖 AI Explanation:\n'));
-      console.log(chalk.gray('─'.repeat(60)));
-      console.log(explanation);
-      console.log('');
+      const explanation = await ai.explainDiff(diff + impactInfo, complexChanges);
+      if (!options.json) {
+        spinner.succeed(explanation.parts > 1 ? `Explanation generated (${explanation.parts} parts)` : 'Explanation generated');
+        displayExplanation(explanation);
+      }
       results.explanation = explanation;
     } catch (error: any) {
-      spinner.fail('Explanation failed');
-      console.error(chalk.red(`Error: ${error.message}`));
+      if (options.json) {
+        results.explanationError = error.message;
+      } else {
+        spinner.fail('Explanation failed');
+        console.error(chalk.red(`Error: ${error.message}`));
+      }
     }
   }
 
@@ -481,3 +532,74 @@ ${diff}`;
 
   return Object.keys(results).length > 0 ? results : null;
 }
+
+/**
+ * Complex functions (by stored chunk complexity) that the diff touches.
+ * Best-effort: without a vector index there is nothing to correlate with.
+ */
+async function findComplexChangesInIndex(
+  diff: string,
+  cvRepoRoot: string,
+  config: CVConfig,
+  threshold: number
+): Promise<ComplexChange[]> {
+  const ranges = parseChangedRanges(diff);
+  if (ranges.length === 0 || !config.vector) return [];
+
+  try {
+    const manifest = await readManifest(getCVDir(cvRepoRoot));
+    const embeddingCreds = await getEmbeddingCredentials({
+      provider: config.embedding?.provider,
+      ollamaUrl: config.embedding?.url,
+      ollamaModel: config.embedding?.model,
+      azure: config.azure
+    });
+    const vector = createVectorManager({
+      url: config.vector.url,
+      ...getVectorBackendOptions(config.vector),
+      repoId: manifest?.repository?.id || generateRepoId(cvRepoRoot),
+      ...embeddingVectorOptions(embeddingCreds, config),
+      indexDir: getIndexDir(cvRepoRoot)
+    });
+    await vector.connect();
+    try {
+      const files = Array.from(new Set(ranges.map(r => r.file)));
+      return findComplexChanges(ranges, await vector.getFileChunks(files), threshold);
+    } finally {
+      await vector.close();
+    }
+  } catch {
+    return [];
+  }
+}
+
+/**
+ * Print a diff explanation
+ */
+function displayExplanation(explanation: DiffExplanation): void {
+  console.log(chalk.bold.cyan('\n📖 AI Explanation:\n'));
+  console.log(chalk.gray('─'.repeat(60)));
+  console.log(explanation.summary);
+
+  if (explanation.complexChanges.length > 0) {
+    console.log(chalk.bold.yellow('\n⚠ Touches complex functions:'));
+    for (const change of explanation.complexChanges) {
+      console.log(
+        chalk.yellow(`  • ${change.symbolName}`) +
+        chalk.gray(` ${change.file}:${change.startLine} (complexity ${change.complexity})`)
+      );
+    }
+  }
+
+  if (explanation.risks.length > 0) {
+    console.log(chalk.bold('\nRisks:'));
+    const colors = { low: chalk.gray, medium: chalk.yellow, high: chalk.red };
+    for (const risk of explanation.risks) {
+      const location = risk.file ? chalk.gray(` (${risk.file})`) : '';
+      console.log(`  ${colors[risk.severity](`[${risk.severity}]`)} ${risk.description}${location}`);
+    }
+  } else {
+    console.log(chalk.green('\nNo notable risks identified.'));
+  }
+  console.log('');
+}
@@ -1,40 +1,87 @@
 /**
  * cv do command
  * AI-driven task execution with Claude
+ *
+ * A task is planned, then turned into file edits that are shown as diffs
+ * and saved as a plan file before anything is written. Edits are applied
+ * after confirming each file, or later with `cv do --apply <planfile>`,
+ * which refuses to run if a file changed since the plan was made.
  */
 
 import { Command } from 'commander';
 import chalk from 'chalk';
 import ora from 'ora';
 import * as readline from 'readline';
+import * as path from 'path';
 import {
   configManager,
   createAIManager,
-  createVectorManager,
   createGraphManager,
-  createGitManager
+  createGitManager,
+  loadRepoLanguages,
+  pickPromptLanguage,
+  DEFAULT_CONTEXT_MIN_SCORE,
+  DEFAULT_CONTEXT_TOP_K,
+  getIndexDir,
+  readManifest,
+  generateRepoId,
+  createEditParser,
+  createFileOperations,
+  buildEditPlan,
+  findChangedSincePlan,
+  toFileEdit,
+  readEditPlan,
+  writeEditPlan,
+  getDefaultEditPlanPath,
+  EditPlan,
+  PlannedEdit,
+  AIManagerFallback
 } from '@cv-git/core';
-import { findRepoRoot } from '@cv-git/shared';
+import { findRepoRoot, getCVDir } from '@cv-git/shared';
 import { Plan } from '@cv-git/shared';
+import { colorizeDiff } from '../utils/formatting.js';
 import { addGlobalOptions } from '../utils/output.js';
-import { getAnthropicApiKey, getEmbeddingCredentials } from '../utils/credentials.js';
+import { createVectorManagerFromCredentials } from '../utils/credentials.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { resolveChatTimeout } from '../utils/timeout.js';
+import { addRetrievalOptions, addRerankOption, resolveRetrieval, resolveReranker, formatNearMiss, formatBudgetNote, formatPinnedBudgetWarning, formatDuplicateNote, formatRerankNote } from '../utils/retrieval.js';
+import { addContextOnlyOption, printContextPreview } from '../utils/context-preview.js';
+import { addSystemPromptOptions, resolveSystemPrompt, previewPrompts } from '../utils/system-prompt.js';
+import { printProxyHint } from '../utils/network.js';
 
 export function doCommand(): Command {
   const cmd = new Command('do');
 
   cmd
-    .description('Execute a task with AI assistance')
-    .argument('<task>', 'Task description in natural language')
+    .description('Execute a task with AI assistance: plan it, review the edits as diffs, then apply them')
+    .argument('[task]', 'Task description in natural language')
     .option('--plan-only', 'Only generate the plan, do not generate code')
-    .option('--yes', 'Skip approval prompts')
-    .option('--prd <refs>', 'Include PRD context (e.g., PRD-123 or comma-separated list)');
-
+    .option('--save-plan [file]', 'Save the proposed edits to a plan file without applying them (default: .cv/plans/do-<time>.json)')
+    .option('--apply <planfile>', 'Apply the edits of a saved plan, confirming each file')
+    .option('--yes', 'Skip approval prompts (apply every edit without asking)')
+    .option('--prd <refs>', 'Include PRD context (e.g., PRD-123 or comma-separated list)')
+    .option('--language <language>', 'Language to generate code in (default: detected from the repo)')
+    .option('--no-redact', 'Send retrieved code without masking secrets');
+
+  addModelOption(cmd, 'do');
+  addSystemPromptOptions(cmd, 'do');
+  addRetrievalOptions(cmd);
+  addRerankOption(cmd);
+  addContextOnlyOption(cmd);
   addGlobalOptions(cmd);
 
-  cmd.action(async (task: string, options) => {
+  cmd.action(async (task: string | undefined, options) => {
       let spinner = ora('Initializing...').start();
 
       try {
+        if (options.apply && task) {
+          throw new Error('--apply takes a plan file instead of a task');
+        }
+        if (options.apply && (options.savePlan || options.planOnly)) {
+          throw new Error('--apply cannot be combined with --save-plan or --plan-only');
+        }
+
         // Find repository root
         const repoRoot = await findRepoRoot();
         if (!repoRoot) {
@@ -43,40 +90,56 @@ export function doCommand(): Command {
           process.exit(1);
         }
 
+        if (options.apply) {
+          spinner.stop();
+          await applySavedPlan(repoRoot, options.apply, options);
+          return;
+        }
+        if (!task) {
+          throw new Error('Describe the task, e.g. cv do "add a retry helper", or apply a plan with cv do --apply <planfile>');
+        }
+
         // Load configuration
         const config = await configManager.load(repoRoot);
-
-        // Check for API keys (CredentialManager -> config -> env var)
-        const anthropicApiKey = await getAnthropicApiKey(config.ai.apiKey);
-        if (!anthropicApiKey) {
-          spinner.fail(chalk.red('Anthropic API key not found'));
-          console.error();
-          console.error(chalk.yellow('Set your Anthropic API key:'));
-          console.error(chalk.gray('  cv auth setup anthropic'));
-          console.error(chalk.gray('  export ANTHROPIC_API_KEY=sk-ant-...'));
+        const retrieval = resolveRetrieval(options, config.search, {
+          minScore: DEFAULT_CONTEXT_MIN_SCORE,
+          topK: DEFAULT_CONTEXT_TOP_K
+        }, repoRoot);
+        const rerank = await resolveReranker(options.rerank, config.search);
+        const model = resolveModel('do', options.model, config);
+        const systemPrompt = await resolveSystemPrompt('do', options, config, { query: task });
+
+        // Chat backend for ai.provider: Anthropic, an Azure chat deployment, Gemini
+        // or an OpenAI-compatible gateway
+        let backend: AIManagerFallback;
+        try {
+          backend = await resolveChatBackend(config);
+        } catch (error: any) {
+          spinner.fail(chalk.red(error.message));
           process.exit(1);
         }
 
-        // Get embedding credentials (OpenRouter preferred, fallback to OpenAI)
-        const embeddingCreds = await getEmbeddingCredentials();
-
         // Initialize components
         spinner.text = 'Connecting to services...';
 
-        // Vector manager (optional)
+        // Use the same repo-isolated collections that cv sync writes to
+        const manifest = await readManifest(getCVDir(repoRoot));
+        const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+
+        // Vector manager (optional; embeds with the configured provider)
         let vector = undefined;
-        if ((embeddingCreds.openrouterApiKey || embeddingCreds.openaiApiKey) && config.vector) {
+        if (config.vector) {
           try {
-            vector = createVectorManager({
-              url: config.vector.url,
-              openrouterApiKey: embeddingCreds.openrouterApiKey,
-              openaiApiKey: embeddingCreds.openaiApiKey,
-              collections: config.vector.collections,
-              embeddingModel: config.embedding?.model
+            vector = await createVectorManagerFromCredentials(config, {
+              repoId,
+              efSearch: retrieval.efSearch,
+              indexDir: getIndexDir(repoRoot),
+              onEmbeddingServed: logProviderServed(options, 'Embedding')
             });
             await vector.connect();
           } catch (error) {
             console.log(chalk.gray('  ⚠ Could not connect to vector DB'));
+            vector = undefined;
           }
         }
 
@@ -90,11 +153,21 @@ export function doCommand(): Command {
         // AI manager
         const ai = createAIManager(
           {
-            provider: 'anthropic',
-            model: config.ai.model,
-            apiKey: anthropicApiKey,
+            ...chatBackendOptions(backend, model, config),
+            timeoutMs: resolveChatTimeout(config),
+            promptCaching: config.ai.promptCaching,
+            fallbacks: await resolveAIFallbacks(config),
+            onServed: logProviderServed(options),
+            contextWindow: config.ai.contextWindow,
+            reranker: rerank?.reranker,
+            rerankCandidates: rerank?.candidates,
             prdUrl: config.cvprd?.url || process.env.CVPRD_URL,
-            prdApiKey: config.cvprd?.apiKey
+            prdApiKey: config.cvprd?.apiKey,
+            redaction: {
+              enabled: options.redact !== false && config.redaction?.enabled !== false,
+              patterns: config.redaction?.patterns
+            },
+            systemPrompt
           },
           vector,
           graph,
@@ -109,15 +182,64 @@ export function doCommand(): Command {
         // Step 1: Gather context
         spinner.text = 'Gathering context...';
         const context = await ai.gatherContext(task, {
+          maxChunks: retrieval.topK,
+          minScore: retrieval.minScore,
+          dedupeThreshold: retrieval.dedupeThreshold,
+          tests: retrieval.tests,
+          packages: retrieval.packages,
+          kinds: retrieval.kinds,
+          extensions: retrieval.extensions,
           includeGitStatus: true,
           prdRefs
         });
 
+        // Tell the model which language to write; mixed repos go by the retrieved code
+        const repoLanguages = await loadRepoLanguages(repoRoot, git);
+        context.repoLanguages = repoLanguages.primary;
+        context.language = pickPromptLanguage(
+          repoLanguages,
+          context.chunks.map(c => c.payload.file),
+          options.language
+        );
+
+        if (options.contextOnly) {
+          spinner.stop();
+          // cv do sends the plan prompt, then (after approval) the code prompt with the same context
+          const prompts = previewPrompts(systemPrompt, 'plan', ai.buildPrompt('plan', task, context), context);
+          if (!options.planOnly) {
+            prompts.push(...previewPrompts(systemPrompt, 'code', ai.buildPrompt('code', task, context), context, task));
+          }
+          printContextPreview(context, prompts, options.json);
+          await graph.close();
+          if (vector) await vector.close();
+          return;
+        }
+
         let contextMsg = `Found ${context.chunks.length} code chunks and ${context.symbols.length} symbols`;
         if (context.prdContext) {
           contextMsg += ` + PRD context`;
         }
         spinner.succeed(chalk.green(contextMsg));
+        const nearMiss = context.chunks.length === 0 ? formatNearMiss(context.nearMissScore, retrieval.minScore) : null;
+        if (nearMiss) {
+          console.log(chalk.gray(`  ${nearMiss}`));
+        }
+        const budgetNote = formatBudgetNote(context.budget);
+        if (budgetNote && options.verbose) {
+          console.log(chalk.gray(`  ${budgetNote}`));
+        }
+        const pinnedWarning = formatPinnedBudgetWarning(context.budget);
+        if (pinnedWarning) {
+          console.log(chalk.yellow(`  ${pinnedWarning}`));
+        }
+        const duplicateNote = formatDuplicateNote(context.duplicates);
+        if (duplicateNote && options.verbose) {
+          console.log(chalk.gray(`  ${duplicateNote}`));
+        }
+        const rerankNote = formatRerankNote(context.rerank, context.chunks.filter(chunk => chunk.rerank).length);
+        if (rerankNote && options.verbose) {
+          console.log(chalk.gray(`  ${rerankNote}`));
+        }
 
         // Step 2: Generate plan
         spinner = ora('Generating plan...').start();
@@ -127,8 +249,8 @@ export function doCommand(): Command {
         // Display plan
         displayPlan(plan);
 
-        // Step 3: Get user approval
-        if (!options.yes && !options.planOnly) {
+        // Step 3: Get user approval (a saved plan changes nothing, so it needs none)
+        if (!options.yes && !options.planOnly && !options.savePlan && process.stdin.isTTY) {
           const approved = await askForApproval('Proceed with code generation?');
           if (!approved) {
             console.log(chalk.yellow('Task cancelled'));
@@ -146,50 +268,54 @@ export function doCommand(): Command {
           return;
         }
 
-        // Step 4: Generate code
-        console.log();
-        console.log(chalk.bold.cyan('Generated Code:'));
-        console.log(chalk.gray('─'.repeat(80)));
-        console.log();
-
-        spinner = ora('Generating code...').start();
+        // Step 4: Generate the edits, resolved against the working tree
+        spinner = ora('Generating edits...').start();
+        const response = await ai.generateCode(task, context, undefined, plan);
+        const head = await git.getLastCommitSha().catch(() => undefined);
+        const { plan: editPlan, rejected } = await buildEditPlan(
+          repoRoot,
+          task,
+          createEditParser().parseResponse(response, 'do'),
+          { head, steps: plan.steps }
+        );
+        spinner.stop();
 
-        const generatedCode = await ai.generateCode(task, context, {
-          onToken: (token) => {
-            if (!spinner.isSpinning) {
-              process.stdout.write(token);
-            }
-          },
-          onComplete: () => {
-            if (spinner.isSpinning) {
-              spinner.stop();
-            }
-            console.log();
-            console.log();
-            console.log(chalk.gray('─'.repeat(80)));
-          }
-        });
+        await graph.close();
+        if (vector) await vector.close();
 
-        if (spinner.isSpinning) {
-          spinner.stop();
-          console.log(generatedCode);
+        for (const edit of rejected) {
+          console.log(chalk.yellow(`⚠ Left out an edit to ${edit.file}: ${edit.error}`));
+        }
+        if (editPlan.edits.length === 0) {
+          // Nothing to apply; the answer may still be useful as text
+          console.log(chalk.yellow('The response has no file edits that can be applied:'));
           console.log();
-          console.log(chalk.gray('─'.repeat(80)));
+          console.log(response);
+          return;
         }
 
-        console.log();
-        console.log(chalk.green('✓ Code generated successfully'));
-        console.log();
-        console.log(chalk.bold('Next steps:'));
-        console.log(chalk.gray('  1. Review the generated code above'));
-        console.log(chalk.gray('  2. Apply the changes manually to your files'));
-        console.log(chalk.gray('  3. Test the changes'));
-        console.log(chalk.gray('  4. Commit when ready: git commit -m "..."'));
-        console.log();
+        // Step 5: Save the plan, so it can be reviewed and applied later
+        const planFile = typeof options.savePlan === 'string'
+          ? path.resolve(options.savePlan)
+          : getDefaultEditPlanPath(repoRoot);
+        await writeEditPlan(planFile, editPlan);
+        const shownPlanFile = path.relative(process.cwd(), planFile) || planFile;
 
-        // Close connections
-        await graph.close();
-        if (vector) await vector.close();
+        const interactive = !options.yes && !options.savePlan && process.stdin.isTTY;
+        if (!interactive) {
+          displayEditDiffs(editPlan.edits);
+        }
+        displayEditSummary(editPlan.edits);
+        console.log(chalk.gray(`Plan saved to ${shownPlanFile}`));
+
+        if (options.savePlan || (!options.yes && !process.stdin.isTTY)) {
+          console.log(chalk.cyan(`Nothing applied. Review the plan, then run: cv do --apply ${shownPlanFile}`));
+          return;
+        }
+
+        // Step 6: Apply, confirming each file unless --yes
+        console.log();
+        await applyEditPlan(repoRoot, editPlan, !!options.yes, shownPlanFile);
 
       } catch (error: any) {
         if (spinner) {
@@ -197,6 +323,7 @@ export function doCommand(): Command {
         }
 
         console.error(chalk.red(`Error: ${error.message}`));
+        printProxyHint(error);
 
         if (error.message.includes('API key')) {
           console.error();
@@ -219,6 +346,124 @@ export function doCommand(): Command {
   return cmd;
 }
 
+/**
+ * Apply a plan saved by an earlier `cv do`
+ */
+async function applySavedPlan(repoRoot: string, planFile: string, options: any): Promise<void> {
+  if (!options.yes && !process.stdin.isTTY) {
+    throw new Error('--apply asks for confirmation per file; pass --yes when stdin is not a terminal');
+  }
+
+  const plan = await readEditPlan(path.resolve(planFile));
+  console.log(chalk.bold('Task:'), plan.task);
+  if (plan.edits.length === 0) {
+    console.log(chalk.yellow('The plan has no edits.'));
+    return;
+  }
+  displayEditSummary(plan.edits);
+  console.log();
+  await applyEditPlan(repoRoot, plan, !!options.yes, planFile);
+}
+
+/**
+ * Apply a plan's edits, asking before each file unless applyAll.
+ * Nothing is written if a file changed since the plan was made.
+ */
+async function applyEditPlan(repoRoot: string, plan: EditPlan, applyAll: boolean, planFile: string): Promise<void> {
+  const changed = await findChangedSincePlan(repoRoot, plan);
+  if (changed.length > 0) {
+    throw new Error(
+      `Changed since the plan was made: ${changed.join(', ')}\n` +
+      'Nothing was applied, so those changes are not overwritten. Run cv do again for a new plan.'
+    );
+  }
+
+  const files = createFileOperations(repoRoot);
+  let applied = 0;
+  let skipped = 0;
+  const failed: string[] = [];
+
+  for (const [index, edit] of plan.edits.entries()) {
+    if (!applyAll) {
+      console.log(colorizeDiff(edit.diff));
+      const answer = await askEditChoice(`Apply ${describeEdit(edit)}?`);
+      console.log();
+      if (answer === 'quit') {
+        skipped += plan.edits.length - index;
+        break;
+      }
+      if (answer === 'no') {
+        skipped++;
+        continue;
+      }
+      applyAll = answer === 'all';
+    }
+
+    const result = await files.applyEdit(toFileEdit(edit));
+    if (result.success) {
+      applied++;
+      console.log(chalk.green(`✓ ${describeEdit(edit)}`));
+    } else {
+      failed.push(edit.file);
+      console.log(chalk.red(`✗ ${describeEdit(edit)}: ${result.error}`));
+    }
+  }
+
+  console.log();
+  console.log(chalk.bold(`Applied ${applied} of ${plan.edits.length} edit(s)`) +
+    (skipped > 0 ? chalk.gray(`, skipped ${skipped}`) : '') +
+    (failed.length > 0 ? chalk.red(`, ${failed.length} failed`) : ''));
+  if (applied > 0) {
+    console.log(chalk.gray('  Previous versions of changed files are in .cv/backups'));
+    console.log(chalk.gray('  Review with: git diff'));
+  }
+  if (skipped > 0 || failed.length > 0) {
+    console.log(chalk.gray(`  The plan is still in ${planFile}`));
+  }
+  if (failed.length > 0) {
+    process.exitCode = 1;
+  }
+}
+
+function describeEdit(edit: PlannedEdit): string {
+  switch (edit.type) {
+    case 'create':
+      return `create ${edit.file}`;
+    case 'delete':
+      return `delete ${edit.file}`;
+    case 'rename':
+      return `rename ${edit.file} → ${edit.newPath}`;
+    default:
+      return `modify ${edit.file}`;
+  }
+}
+
+/**
+ * Display every edit's diff
+ */
+function displayEditDiffs(edits: PlannedEdit[]): void {
+  console.log();
+  for (const edit of edits) {
+    console.log(colorizeDiff(edit.diff));
+  }
+}
+
+/**
+ * Display the files a plan changes, with added and removed line counts
+ */
+function displayEditSummary(edits: PlannedEdit[]): void {
+  console.log();
+  console.log(chalk.bold.cyan(`Proposed edits (${edits.length}):`));
+  for (const edit of edits) {
+    const lines = edit.diff.split('\n');
+    const added = lines.filter(l => l.startsWith('+') && !l.startsWith('+++')).length;
+    const removed = lines.filter(l => l.startsWith('-') && !l.startsWith('---')).length;
+    console.log(`  ${getTypeColor(edit.type)(edit.type.padEnd(7))} ${edit.newPath ? `${edit.file} → ${edit.newPath}` : edit.file}` +
+      chalk.gray(edit.type === 'rename' ? '' : `  +${added} -${removed}`));
+  }
+  console.log();
+}
+
 /**
  * Display a plan
  */
@@ -292,6 +537,24 @@ function getTypeColor(type: string): (text: string) => string {
   }
 }
 
+/**
+ * Ask whether to apply an edit: yes, no, all remaining, or quit
+ */
+async function askEditChoice(question: string): Promise<'yes' | 'no' | 'all' | 'quit'> {
+  const rl = readline.createInterface({
+    input: process.stdin,
+    output: process.stdout
+  });
+
+  return new Promise(resolve => {
+    rl.question(chalk.cyan(`${question} (y/N/a=all/q=quit): `), answer => {
+      rl.close();
+      const choice = answer.trim().toLowerCase();
+      resolve(choice === 'y' || choice === 'yes' ? 'yes' : choice === 'a' || choice === 'all' ? 'all' : choice === 'q' || choice === 'quit' ? 'quit' : 'no');
+    });
+  });
+}
+
 /**
  * Ask for user approval
  */
@@ -1,6 +1,7 @@
 /**
  * CV Docs Command
- * Documentation management and search for the knowledge graph
+ * Documentation management and search for the knowledge graph, and
+ * doc comment generation for source files (`cv docs <file>`)
  */
 
 import { Command } from 'commander';
@@ -14,12 +15,24 @@ import {
   createSyncEngine,
   createParser,
   createGitManager,
-  createIngestManager
+  createIngestManager,
+  getEmbeddingCacheDir,
+  createAIManager,
+  createUnifiedDiff,
+  findDocTargets,
+  insertDocComments,
+  AIManager,
+  AIManagerFallback
 } from '@cv-git/core';
-import { findRepoRoot, DocumentType } from '@cv-git/shared';
+import { findRepoRoot, DocumentType, detectLanguage, CVConfig } from '@cv-git/shared';
 import { glob } from 'glob';
 import { promises as fs } from 'fs';
+import * as readline from 'readline';
 import { getEmbeddingCredentials } from '../utils/credentials.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { resolveChatTimeout } from '../utils/timeout.js';
+import { colorizeDiff } from '../utils/formatting.js';
 import * as path from 'path';
 
 /**
@@ -52,7 +65,24 @@ function resolveDocLink(fromDoc: string, target: string): string {
  */
 export function createDocsCommand(): Command {
   const docs = new Command('docs')
-    .description('Documentation management and search');
+    .description('Documentation management and search, or generate doc comments for source files')
+    .argument('[files...]', 'Source files to add doc comments to')
+    .option('--check', 'Only report exported symbols missing doc comments; exit 1 if any')
+    .option('--overwrite', 'Regenerate doc comments that already exist')
+    .option('-y, --yes', 'Write the changes without asking')
+    .option('--no-redact', 'Send code without masking secrets');
+
+  addModelOption(docs, 'docs');
+
+  // ═══════════════════════════════════════════════════════════════════════════
+  // cv docs <files...> - Generate doc comments for undocumented exports
+  // ═══════════════════════════════════════════════════════════════════════════
+  docs.action(async (files: string[], options) => {
+    if (files.length === 0) {
+      docs.help();
+    }
+    await generateDocComments(files, options);
+  });
 
   // ═══════════════════════════════════════════════════════════════════════════
   // cv docs sync - Index markdown documentation
@@ -98,7 +128,9 @@ export function createDocsCommand(): Command {
             openrouterApiKey: embeddingCreds.openrouterApiKey,
             openaiApiKey: embeddingCreds.openaiApiKey,
             collections: config.vector.collections,
-            cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+            cacheDir: getEmbeddingCacheDir(repoRoot),
+            embeddingFallbacks: config.embedding?.providers,
+            onEmbeddingServed: logProviderServed(options, 'Embedding')
           });
 
           await vector.connect();
@@ -204,7 +236,9 @@ export function createDocsCommand(): Command {
                 openrouterApiKey: embeddingCreds.openrouterApiKey,
                 openaiApiKey: embeddingCreds.openaiApiKey,
                 collections: config.vector.collections,
-                cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+                cacheDir: getEmbeddingCacheDir(repoRoot),
+                embeddingFallbacks: config.embedding?.providers,
+                onEmbeddingServed: logProviderServed(options, 'Embedding')
               });
 
               await vector.connect();
@@ -858,7 +892,9 @@ export function createDocsCommand(): Command {
           openrouterApiKey: embeddingCreds.openrouterApiKey,
           openaiApiKey: embeddingCreds.openaiApiKey,
           collections: config.vector.collections,
-          cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+          cacheDir: getEmbeddingCacheDir(repoRoot),
+          embeddingFallbacks: config.embedding?.providers,
+          onEmbeddingServed: logProviderServed(options, 'Embedding')
         });
 
         await vector.connect();
@@ -1074,5 +1110,168 @@ export function createDocsCommand(): Command {
   return docs;
 }
 
+interface FileDocs {
+  file: string;
+  absolutePath: string;
+  language: string;
+  content: string;
+  targets: ReturnType<typeof findDocTargets>;
+}
+
+/**
+ * cv docs <files...>: report (--check) or generate doc comments for
+ * exported symbols, show the result as a diff, and write it after confirmation
+ */
+async function generateDocComments(fileArgs: string[], options: any): Promise<void> {
+  const spinner = ora('Scanning for undocumented symbols...').start();
+
+  try {
+    const repoRoot = await findRepoRoot();
+    if (!repoRoot) {
+      spinner.fail(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+      process.exit(1);
+    }
+
+    const parser = createParser();
+    const scanned: FileDocs[] = [];
+    for (const fileArg of fileArgs) {
+      const absolutePath = path.resolve(fileArg);
+      const file = path.relative(repoRoot, absolutePath).split(path.sep).join('/');
+      if (file.startsWith('..')) {
+        spinner.fail(chalk.red(`${fileArg} is outside the repository`));
+        process.exit(1);
+      }
+
+      let content: string;
+      try {
+        content = await fs.readFile(absolutePath, 'utf-8');
+      } catch {
+        spinner.fail(chalk.red(`Cannot read ${file}`));
+        process.exit(1);
+      }
+
+      const language = detectLanguage(file);
+      const parsed = await parser.parseFile(file, content, language);
+      const targets = findDocTargets(parsed.symbols, content, language, options.check ? false : !!options.overwrite);
+      scanned.push({ file, absolutePath, language, content, targets });
+    }
+
+    const total = scanned.reduce((sum, f) => sum + f.targets.length, 0);
+
+    if (options.check) {
+      spinner.stop();
+      for (const { file, targets } of scanned) {
+        for (const { symbol } of targets) {
+          console.log(`${chalk.cyan(`${file}:${symbol.startLine}`)} ${chalk.gray(symbol.kind)} ${symbol.name}`);
+        }
+      }
+      if (total > 0) {
+        console.log(chalk.red(`\n${total} exported symbol(s) missing doc comments`));
+        process.exitCode = 1;
+      } else {
+        console.log(chalk.green('✓ All exported symbols are documented'));
+      }
+      return;
+    }
+
+    if (total === 0) {
+      spinner.succeed(chalk.green(options.overwrite ? 'No exported symbols to document' : 'All exported symbols are documented'));
+      return;
+    }
+
+    const config = await configManager.load(repoRoot);
+    const ai = await createDocsAI(config, options, spinner);
+
+    const updates: Array<{ file: string; absolutePath: string; updated: string }> = [];
+    for (const entry of scanned.filter(f => f.targets.length > 0)) {
+      spinner.text = `Writing doc comments for ${entry.targets.length} symbol(s) in ${entry.file}...`;
+      const comments = await ai.generateDocComments(entry.file, entry.language, entry.targets);
+      const updated = insertDocComments(entry.content, entry.language, comments);
+      const diff = createUnifiedDiff(entry.file, entry.content, updated);
+      if (diff) {
+        spinner.stop();
+        console.log(colorizeDiff(diff));
+        spinner.start();
+        updates.push({ file: entry.file, absolutePath: entry.absolutePath, updated });
+      }
+    }
+    spinner.stop();
+
+    if (updates.length === 0) {
+      console.log(chalk.yellow('No doc comments generated.'));
+      return;
+    }
+
+    if (!options.yes) {
+      if (!process.stdin.isTTY) {
+        console.log(chalk.gray('Not written (no terminal to confirm). Rerun with --yes to write.'));
+        return;
+      }
+      if (!await askForApproval(`Write doc comments to ${updates.length} file(s)?`)) {
+        console.log(chalk.gray('Not written.'));
+        return;
+      }
+    }
+
+    for (const { absolutePath, updated } of updates) {
+      await fs.writeFile(absolutePath, updated, 'utf-8');
+    }
+    console.log(chalk.green(`✓ Documented ${updates.map(u => u.file).join(', ')}`));
+  } catch (error: any) {
+    spinner.fail(chalk.red('Doc comment generation failed'));
+    console.error(chalk.red(`Error: ${error.message}`));
+    if (process.env.CV_DEBUG) {
+      console.error(chalk.gray(error.stack));
+    }
+    process.exitCode = 1;
+  }
+}
+
+/**
+ * AI manager for the configured chat provider
+ */
+async function createDocsAI(config: CVConfig, options: any, spinner: ReturnType<typeof ora>): Promise<AIManager> {
+  const model = resolveModel('docs', options.model, config);
+
+  // Anthropic, an Azure chat deployment, Gemini or an OpenAI-compatible gateway
+  let backend: AIManagerFallback;
+  try {
+    backend = await resolveChatBackend(config);
+  } catch (error: any) {
+    spinner.fail(chalk.red(error.message));
+    process.exit(1);
+  }
+
+  return createAIManager({
+    ...chatBackendOptions(backend, model, config),
+    timeoutMs: resolveChatTimeout(config),
+    promptCaching: config.ai.promptCaching,
+    fallbacks: await resolveAIFallbacks(config),
+    onServed: logProviderServed(options),
+    maxTokens: config.ai.maxTokens,
+    redaction: {
+      enabled: options.redact !== false && config.redaction?.enabled !== false,
+      patterns: config.redaction?.patterns
+    }
+  });
+}
+
+/**
+ * Ask for user approval
+ */
+async function askForApproval(question: string): Promise<boolean> {
+  const rl = readline.createInterface({
+    input: process.stdin,
+    output: process.stdout
+  });
+
+  return new Promise(resolve => {
+    rl.question(chalk.cyan(`${question} (y/N): `), answer => {
+      rl.close();
+      resolve(answer.toLowerCase() === 'y' || answer.toLowerCase() === 'yes');
+    });
+  });
+}
+
 // Export for index.ts
 export { createDocsCommand as docsCommand };
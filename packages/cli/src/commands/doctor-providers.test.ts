@@ -0,0 +1,157 @@
+/**
+ * Tests for cv doctor provider checks: credentials, test calls and the
+ * vector index fingerprint
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { writeIndexMetadata } from '@cv-git/core';
+
+const mocks = vi.hoisted(() => ({
+  findRepoRoot: vi.fn(),
+  loadConfig: vi.fn(),
+  createAIManager: vi.fn(),
+  getAnthropicKey: vi.fn(),
+  getOpenRouterKey: vi.fn(),
+  getOpenAICompatible: vi.fn(),
+  getEmbeddingCredentials: vi.fn(),
+}));
+
+vi.mock('@cv-git/shared', async (importOriginal) => ({
+  ...(await importOriginal<any>()),
+  findRepoRoot: mocks.findRepoRoot,
+}));
+
+vi.mock('@cv-git/core', async (importOriginal) => ({
+  ...(await importOriginal<any>()),
+  configManager: { load: mocks.loadConfig },
+  createAIManager: mocks.createAIManager,
+}));
+
+// The chat backend resolves in @cv-git/core, so stored keys are mocked where it reads them
+vi.mock('@cv-git/credentials', async (importOriginal) => ({
+  ...(await importOriginal<any>()),
+  CredentialManager: class {
+    async init() {}
+    getAnthropicKey = mocks.getAnthropicKey;
+    getOpenRouterKey = mocks.getOpenRouterKey;
+    getOpenAICompatible = mocks.getOpenAICompatible;
+  },
+}));
+
+vi.mock('../utils/credentials.js', async (importOriginal) => ({
+  ...(await importOriginal<any>()),
+  getEmbeddingCredentials: mocks.getEmbeddingCredentials,
+}));
+
+import { checkProviders, checkIndexFingerprint, splitRemediation } from './doctor.js';
+
+const identity = { provider: 'openai', model: 'text-embedding-3-small', dimensions: 1536 };
+const fakeVector = (info: typeof identity) => ({ getEmbeddingInfo: () => info }) as any;
+
+describe('cv doctor — provider checks', () => {
+  let repoRoot: string;
+
+  beforeEach(() => {
+    repoRoot = fs.mkdtempSync(path.join(os.tmpdir(), 'cv-doctor-providers-'));
+    vi.resetAllMocks();
+    mocks.findRepoRoot.mockResolvedValue(repoRoot);
+    mocks.getEmbeddingCredentials.mockRejectedValue(new Error('OpenAI API key not found. Run: cv auth setup openai'));
+    for (const name of ['ANTHROPIC_API_KEY', 'CV_ANTHROPIC_KEY', 'OPENROUTER_API_KEY', 'CV_OPENAI_COMPATIBLE_URL']) {
+      vi.stubEnv(name, '');
+    }
+  });
+
+  afterEach(() => {
+    fs.rmSync(repoRoot, { recursive: true, force: true });
+    vi.unstubAllEnvs();
+  });
+
+  describe('splitRemediation', () => {
+    it('moves a trailing "Run: <command>" into the fix', () => {
+      expect(splitRemediation('Gemini API key not found. Run: cv auth setup gemini')).toEqual({
+        message: 'Gemini API key not found',
+        fix: 'Run "cv auth setup gemini"',
+      });
+    });
+
+    it('keeps a message without a command as it is', () => {
+      expect(splitRemediation('Connection refused')).toEqual({ message: 'Connection refused' });
+    });
+  });
+
+  describe('checkIndexFingerprint', () => {
+    it('fails when no index has been built', async () => {
+      const result = await checkIndexFingerprint(repoRoot, fakeVector(identity));
+      expect(result.status).toBe('fail');
+      expect(result.fix).toBe('Run "cv sync" to build it');
+    });
+
+    it('fails when the index was built with another embedding model', async () => {
+      await writeIndexMetadata(repoRoot, identity, 'abc1234def');
+
+      const result = await checkIndexFingerprint(repoRoot, fakeVector({ provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 }));
+
+      expect(result.status).toBe('fail');
+      expect(result.message).toContain('provider, model, dimensions differ');
+      expect(result.fix).toContain('cv sync --force');
+    });
+
+    it('passes when the index matches the embedding provider in use', async () => {
+      await writeIndexMetadata(repoRoot, identity, 'abc1234def');
+
+      const result = await checkIndexFingerprint(repoRoot, fakeVector(identity));
+
+      expect(result).toEqual({ name: 'Vector Index', status: 'pass', message: 'Built with openai text-embedding-3-small (1536d) at abc1234' });
+    });
+
+    it('warns without comparing when the embedding check failed', async () => {
+      await writeIndexMetadata(repoRoot, identity, 'abc1234def');
+
+      expect((await checkIndexFingerprint(repoRoot, null)).status).toBe('warn');
+    });
+  });
+
+  describe('checkProviders', () => {
+    it('reports missing credentials with the setup command and skips the test calls', async () => {
+      mocks.loadConfig.mockResolvedValue({ ai: { provider: 'anthropic', model: 'claude-sonnet-4-5' }, embedding: { provider: 'openai' }, vector: { url: '' } });
+      mocks.getAnthropicKey.mockResolvedValue(null);
+      mocks.getOpenRouterKey.mockResolvedValue('sk-or-test');
+
+      const results = await checkProviders();
+      const byName = Object.fromEntries(results.map(r => [r.name, r]));
+
+      expect(byName['Chat Credentials']).toEqual({
+        name: 'Chat Credentials',
+        status: 'fail',
+        message: 'Anthropic API key not found; the OpenRouter key only serves cv chat',
+        fix: 'Run "cv auth setup anthropic"',
+      });
+      expect(byName['Embedding Credentials']).toMatchObject({ status: 'fail', fix: 'Run "cv auth setup openai"' });
+      expect(byName['Test Chat']).toBeUndefined();
+      expect(byName['Test Embedding']).toBeUndefined();
+      expect(byName['Vector Index'].status).toBe('fail');
+      expect(mocks.createAIManager).not.toHaveBeenCalled();
+    });
+
+    it('tests chat through an OpenAI-compatible gateway with its chat model', async () => {
+      mocks.loadConfig.mockResolvedValue({ ai: { provider: 'openai-compatible', model: 'claude-sonnet-4-5' }, embedding: { provider: 'openai' }, vector: { url: '' } });
+      mocks.getOpenAICompatible.mockResolvedValue({ baseUrl: 'http://localhost:8000/v1', chatModel: 'qwen2.5-coder' });
+      mocks.createAIManager.mockReturnValue({ chat: vi.fn().mockResolvedValue('OK'), getModel: () => 'qwen2.5-coder' });
+
+      const results = await checkProviders();
+      const byName = Object.fromEntries(results.map(r => [r.name, r]));
+
+      expect(byName['Chat Credentials']).toMatchObject({ status: 'pass', message: 'openai-compatible credentials found' });
+      expect(byName['Test Chat']).toMatchObject({ status: 'pass', message: 'openai-compatible qwen2.5-coder replied' });
+      expect(mocks.createAIManager).toHaveBeenCalledWith(expect.objectContaining({
+        provider: 'openai-compatible',
+        model: 'qwen2.5-coder',
+        openaiCompatibleUrl: 'http://localhost:8000/v1',
+      }));
+      expect(mocks.getAnthropicKey).not.toHaveBeenCalled();
+    });
+  });
+});
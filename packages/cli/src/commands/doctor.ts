@@ -23,8 +23,27 @@ import {
   getOllamaUrl,
   GraphManager,
   DeployConfigLoader,
+  configManager,
+  createAIManager,
+  createVectorManager,
+  getVectorBackendOptions,
+  readManifest,
+  generateRepoId,
+  getIndexDir,
+  readIndexMetadata,
+  checkIndexCompatibility,
+  VectorManager,
+  AIManagerFallback,
 } from '@cv-git/core';
+import { CVConfig, findRepoRoot, getCVDir } from '@cv-git/shared';
 import { loadServicesFile } from '../utils/services.js';
+import {
+  EmbeddingCredentials,
+  embeddingVectorOptions,
+  getEmbeddingCredentials,
+} from '../utils/credentials.js';
+import { resolveChatBackend } from '../utils/providers.js';
+import { resolveChatTimeout, resolveEmbeddingTimeout } from '../utils/timeout.js';
 import {
   readCredentials,
   getMachineName,
@@ -105,7 +124,7 @@ function discoverOllamaPort(): number | null {
 
 const execAsync = promisify(exec);
 
-interface DiagnosticResult {
+export interface DiagnosticResult {
   name: string;
   status: 'pass' | 'warn' | 'fail';
   message: string;
@@ -136,6 +155,7 @@ export function doctorCommand(): Command {
       results.push(await checkFalkorDB());
       results.push(await checkQdrant());
       results.push(await checkOllama());
+      results.push(...await checkProviders());
 
       // Sprint 1-5 subsystem checks
       results.push(await checkGraphCounts());
@@ -658,6 +678,173 @@ async function checkCredentials(): Promise<DiagnosticResult> {
   }
 }
 
+/**
+ * Check the repository's providers end to end: the config loads, the chat and
+ * embedding credentials resolve, one embedding and one chat call succeed, and
+ * the vector index exists and was built by the current embedding provider.
+ * Each step only runs when the ones it needs passed.
+ */
+export async function checkProviders(): Promise<DiagnosticResult[]> {
+  const results: DiagnosticResult[] = [];
+  const repoRoot = await findRepoRoot();
+  if (!repoRoot) {
+    return [{
+      name: 'Repository Config',
+      status: 'warn',
+      message: 'Not in a CV-Git repository - provider checks skipped',
+      fix: 'Run "cv init" in the repository',
+    }];
+  }
+
+  let config: CVConfig;
+  try {
+    config = await configManager.load(repoRoot);
+    results.push({
+      name: 'Repository Config',
+      status: 'pass',
+      message: `Loaded .cv/config.json (chat: ${config.ai.provider}, embeddings: ${config.embedding.provider})`,
+    });
+  } catch (error: any) {
+    results.push({
+      name: 'Repository Config',
+      status: 'fail',
+      message: `Could not load .cv/config.json: ${error.message}`,
+      fix: 'Fix the file, or run "cv config list" to see the values it holds',
+    });
+    return results;
+  }
+
+  let chat: AIManagerFallback | null = null;
+  try {
+    chat = await resolveChatBackend(config);
+    results.push({ name: 'Chat Credentials', status: 'pass', message: `${chat.provider} credentials found` });
+  } catch (error: any) {
+    results.push({ name: 'Chat Credentials', status: 'fail', ...splitRemediation(error.message) });
+  }
+
+  let embedding: EmbeddingCredentials | null = null;
+  try {
+    embedding = await getEmbeddingCredentials({
+      provider: config.embedding?.provider,
+      ollamaUrl: config.embedding?.url,
+      ollamaModel: config.embedding?.model,
+      azure: config.azure
+    });
+    results.push({ name: 'Embedding Credentials', status: 'pass', message: `Using ${embedding.provider}` });
+  } catch (error: any) {
+    results.push({ name: 'Embedding Credentials', status: 'fail', ...splitRemediation(error.message) });
+  }
+
+  let vector: VectorManager | null = null;
+  if (embedding) {
+    try {
+      const manifest = await readManifest(getCVDir(repoRoot));
+      vector = createVectorManager({
+        url: config.vector.url,
+        ...getVectorBackendOptions(config.vector),
+        repoId: manifest?.repository?.id || generateRepoId(repoRoot),
+        embeddingTimeoutMs: resolveEmbeddingTimeout(config),
+        // A cached vector or a fallback provider would hide a broken provider
+        enableCache: false,
+        maxRetryAttempts: 1,
+        ...embeddingVectorOptions(embedding, config),
+        embeddingFallbacks: undefined,
+        indexDir: getIndexDir(repoRoot)
+      });
+      await vector.connect();
+      const values = await vector.embed('cv doctor embedding check', 'query');
+      const info = vector.getEmbeddingInfo();
+      results.push({
+        name: 'Test Embedding',
+        status: 'pass',
+        message: `${info.provider} ${info.model} returned a ${values.length}-dimension vector`,
+      });
+    } catch (error: any) {
+      results.push({
+        name: 'Test Embedding',
+        status: 'fail',
+        message: `Embedding call failed: ${error.message}`,
+        fix: `Check the key with "cv auth test ${embedding.provider}" and the model with "cv config get embedding.model"`,
+      });
+      await vector?.close();
+      vector = null;
+    }
+  }
+
+  if (chat) {
+    try {
+      const ai = createAIManager({
+        ...chat,
+        model: chat.model ?? config.ai.model,
+        maxTokens: 16,
+        timeoutMs: resolveChatTimeout(config)
+      });
+      await ai.chat([{ role: 'user', content: 'Reply with the single word OK.' }]);
+      results.push({ name: 'Test Chat', status: 'pass', message: `${chat.provider} ${ai.getModel()} replied` });
+    } catch (error: any) {
+      results.push({
+        name: 'Test Chat',
+        status: 'fail',
+        message: `Chat call failed: ${error.message}`,
+        fix: `Check the key with "cv auth test ${chat.provider}" and the model with "cv config get ai.model"`,
+      });
+    }
+  }
+
+  results.push(await checkIndexFingerprint(repoRoot, vector));
+  await vector?.close();
+  return results;
+}
+
+/**
+ * Move a trailing "Run: <command>" out of a credential error into the fix
+ */
+export function splitRemediation(message: string): Pick<DiagnosticResult, 'message' | 'fix'> {
+  const match = message.match(/^(.*?)\.?\s*Run: (.+)$/s);
+  return match ? { message: match[1], fix: `Run "${match[2]}"` } : { message };
+}
+
+/**
+ * Check the vector index exists and matches the embedding provider in use
+ */
+export async function checkIndexFingerprint(repoRoot: string, vector: VectorManager | null): Promise<DiagnosticResult> {
+  const metadata = await readIndexMetadata(repoRoot);
+  if (!metadata) {
+    return {
+      name: 'Vector Index',
+      status: 'fail',
+      message: 'No vector index has been built for this repository',
+      fix: 'Run "cv sync" to build it',
+    };
+  }
+
+  const indexed = `${metadata.provider} ${metadata.model} (${metadata.dimensions}d)`;
+  if (!vector) {
+    return {
+      name: 'Vector Index',
+      status: 'warn',
+      message: `Built with ${indexed}; not compared, the embedding check failed`,
+    };
+  }
+
+  const current = vector.getEmbeddingInfo();
+  const { compatible, mismatches } = checkIndexCompatibility(metadata, current);
+  if (!compatible) {
+    return {
+      name: 'Vector Index',
+      status: 'fail',
+      message: `Built with ${indexed}, but embeddings now use ${current.provider} ${current.model} (${current.dimensions}d) - ${mismatches.join(', ')} differ`,
+      fix: `Run "cv sync --force" to rebuild it, or switch back to ${metadata.provider} (${metadata.model})`,
+    };
+  }
+
+  return {
+    name: 'Vector Index',
+    status: 'pass',
+    message: `Built with ${indexed}${metadata.lastIndexedCommit ? ` at ${metadata.lastIndexedCommit.slice(0, 7)}` : ''}`,
+  };
+}
+
 /**
  * Check FalkorDB
  */
@@ -12,101 +12,466 @@ import {
   createVectorManager,
   createGraphManager,
   createGitManager,
-  createRLMRouter
+  createRLMRouter,
+  readManifest,
+  generateRepoId,
+  getIndexDir,
+  DEFAULT_CONTEXT_MIN_SCORE,
+  DEFAULT_CONTEXT_TOP_K,
+  getVectorBackendOptions,
+  resolveLanguageHint,
+  VectorManager,
+  GraphManager,
+  AIManagerFallback,
+  GitManager,
+  STDIN_FILE,
+  readIndexMetadata,
+  describeFileFilter,
+  fileFilterMiss,
+  SyncFileFilter,
+  findCitedLocations,
+  numberSources,
+  resolveInlineCitations,
+  InlineCitation,
+  getSessionUsage,
+  totalCompletionTokens,
+  CitedLocation,
+  ResponseCache,
+  ResponseCacheKey,
+  getResponseCacheDir,
+  DEFAULT_RESPONSE_CACHE_TTL_SECONDS,
+  MAX_EXPAND_DEPTH,
+  ChangeSummary,
+  ChangeSummaryCommit,
+  TouchedSymbol,
+  MAX_SUMMARY_COMMITS,
+  parseChangedRanges,
+  findTouchedSymbols,
+  collectAdHocFiles,
+  indexAdHocFiles,
+  DEFAULT_AD_HOC_MAX_FILES,
+  readIndexedCodeChunks,
+  buildGroundingIndex,
+  findUngroundedSymbols
 } from '@cv-git/core';
-import { findRepoRoot } from '@cv-git/shared';
-import { addGlobalOptions } from '../utils/output.js';
-import { getAnthropicApiKey, getEmbeddingCredentials } from '../utils/credentials.js';
+import { findRepoRoot, getCVDir, CodeChunkPayload, Context, VectorSearchResult } from '@cv-git/shared';
+import * as fs from 'fs';
+import * as path from 'path';
+import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { getEmbeddingCredentials, embeddingVectorOptions } from '../utils/credentials.js';
+import { abortOnInterrupt, isAbortError } from '../utils/interrupt.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend } from '../utils/providers.js';
+import { addTimeoutOption, resolveChatTimeout, resolveEmbeddingTimeout, printTimeoutHint } from '../utils/timeout.js';
+import {
+  addRetrievalOptions,
+  addFileScopeOption,
+  resolveRetrieval,
+  resolveFileScope,
+  formatNearMiss,
+  formatBudgetNote,
+  formatPinnedBudgetWarning,
+  formatDuplicateNote,
+  addRerankOption,
+  resolveReranker,
+  formatRerankNote
+} from '../utils/retrieval.js';
+import { addContextOnlyOption, printContextPreview } from '../utils/context-preview.js';
+import { printProxyHint } from '../utils/network.js';
+import { STDIN_ARG, addLanguageOption, readStdin } from '../utils/stdin.js';
+import { addSystemPromptOptions, resolveSystemPrompt, previewPrompts } from '../utils/system-prompt.js';
+import { pickLocation, openInEditor } from '../utils/editor.js';
+import { addColorOption, shouldHighlight, highlightCodeBlocks, CodeBlockHighlighter } from '../utils/highlight.js';
+import { addFormatOption, resolveAnswerFormat, stripMarkdown, PlainTextStream, AnswerFormat } from '../utils/answer-format.js';
+import { addSuggestOption, resolveSuggest, printFollowUps } from '../utils/follow-ups.js';
 
 export function explainCommand(): Command {
   const cmd = new Command('explain');
 
   cmd
     .description('Explain code, files, or concepts using AI')
-    .argument('<target>', 'What to explain (symbol name, file path, or concept)')
+    .argument('[target]', 'What to explain (symbol name, file path, or concept), or - for code piped on stdin; with --since, what to focus on')
+    .option('--since <ref>', 'Explain what changed between <ref> and HEAD and why, grouping related commits into themes')
     .option('--no-stream', 'Disable streaming output')
     .option('--deep', 'Use RLM-powered deep reasoning for complex queries')
     .option('--trace', 'Show reasoning trace (only with --deep)')
-    .option('--max-depth <n>', 'Maximum recursion depth for deep reasoning (default: 5)', '5');
-
+    .option('--max-depth <n>', 'Maximum recursion depth for deep reasoning (default: 5)', '5')
+    .option('--history <n>', 'Include the last n commits touching the retrieved files (messages and stats)')
+    .option('--depth <n>', `Also include definitions of what the retrieved code uses, following references n levels deep (0-${MAX_EXPAND_DEPTH})`, '0')
+    .option('--no-redact', 'Send retrieved code without masking secrets')
+    .option('--open', 'Open the cited code in $CV_EDITOR or $EDITOR afterwards (pick one if several are cited)')
+    .option('--no-cache', 'Ask the model even if the same question was answered against the current index')
+    .option('--no-index', 'Answer from the --file/--dir files, embedded on the fly, instead of the synced index')
+    .option('--dir <path>', 'With --no-index, embed the source files under this directory (repeatable)', (value: string, previous: string[] = []) => [...previous, value])
+    .option('--max-files <n>', 'With --no-index, refuse to embed more than n files', String(DEFAULT_AD_HOC_MAX_FILES))
+    .option('--verify', 'Flag symbols the answer names that are not in the index (default: grounding.enabled)')
+    .option('--no-verify', 'Do not check the answer\'s symbols even when grounding.enabled is set');
+
+  addLanguageOption(cmd);
+  addModelOption(cmd, 'explain');
+  addTimeoutOption(cmd);
+  addSystemPromptOptions(cmd, 'explain');
+  addRetrievalOptions(cmd);
+  addRerankOption(cmd);
+  addFileScopeOption(cmd);
+  addContextOnlyOption(cmd);
+  addSuggestOption(cmd);
+  addColorOption(cmd);
+  addFormatOption(cmd);
   addGlobalOptions(cmd);
 
-  cmd.action(async (target: string, options) => {
+  cmd.action(async (targetArg: string | undefined, options) => {
+      let format: AnswerFormat;
+      try {
+        format = resolveAnswerFormat(options);
+      } catch (error: any) {
+        console.error(chalk.red(error.message));
+        process.exit(1);
+      }
+      // --format json is --json
+      options.json = format === 'json';
+
+      // --no-color and NO_COLOR turn off every color, not only the highlighting; so does plain
+      const highlight = format === 'markdown' && shouldHighlight(options);
+      if (options.color === false || process.env.NO_COLOR || format === 'plain') {
+        chalk.level = 0;
+      }
+      const output = createOutput(options);
       let spinner = ora('Initializing...').start();
 
+      // Piped code is explained on its own, without the repository or its index
+      const piped = targetArg === STDIN_ARG;
+      const expandDepth = parseInt(options.depth, 10);
+      if (!(expandDepth >= 0 && expandDepth <= MAX_EXPAND_DEPTH)) {
+        spinner.fail(chalk.red(`Invalid --depth value: ${options.depth} (expected 0-${MAX_EXPAND_DEPTH})`));
+        process.exit(1);
+      }
+      if (!targetArg && !options.since) {
+        spinner.fail(chalk.red('Nothing to explain: give a target, or --since <ref> to explain the changes since a ref'));
+        process.exit(1);
+      }
+      // Only --since runs without a target
+      const target = targetArg ?? '';
+      if (options.since && (piped || options.deep || options.contextOnly || options.open || options.history || options.suggest || options.verify)) {
+        const flag = piped ? 'stdin' : options.deep ? '--deep' : options.contextOnly ? '--context-only' : options.open ? '--open'
+          : options.history ? '--history' : options.suggest ? '--suggest' : '--verify';
+        spinner.fail(chalk.red(`--since cannot be combined with ${flag}`));
+        process.exit(1);
+      }
+      if (piped && (options.deep || options.file || options.history || options.open || expandDepth > 0 || options.rerank || options.suggest)) {
+        const flag = options.deep ? '--deep' : options.file ? '--file' : options.history ? '--history' : options.open ? '--open'
+          : options.rerank ? '--rerank' : options.suggest ? '--suggest' : '--depth';
+        spinner.fail(chalk.red(`${flag} cannot be used when explaining code from stdin`));
+        process.exit(1);
+      }
+      const historyCommits = options.history !== undefined ? parseInt(options.history, 10) : undefined;
+      if (historyCommits !== undefined && !(historyCommits > 0)) {
+        spinner.fail(chalk.red(`Invalid --history value: ${options.history} (expected a number of commits)`));
+        process.exit(1);
+      }
+      // --no-index embeds the named files for this one question; there is no index, graph or history to draw on
+      const adHoc = options.index === false;
+      if (options.dir && !adHoc) {
+        spinner.fail(chalk.red('--dir requires --no-index (use --file to add files to indexed context)'));
+        process.exit(1);
+      }
+      const maxFiles = parseInt(options.maxFiles, 10);
+      if (adHoc) {
+        if (piped || options.since || options.deep || historyCommits !== undefined || expandDepth > 0) {
+          const flag = piped ? 'stdin' : options.since ? '--since' : options.deep ? '--deep' : historyCommits !== undefined ? '--history' : '--depth';
+          spinner.fail(chalk.red(`--no-index cannot be combined with ${flag}`));
+          process.exit(1);
+        }
+        if (!options.file && !options.dir) {
+          spinner.fail(chalk.red('--no-index needs the files to answer from: --file <path> or --dir <path>'));
+          process.exit(1);
+        }
+        if (!(maxFiles > 0)) {
+          spinner.fail(chalk.red(`Invalid --max-files value: ${options.maxFiles} (expected a number of files)`));
+          process.exit(1);
+        }
+      }
+
+      // Ctrl-C cancels whichever request is in flight: the query embedding or the completion
+      const interrupt = abortOnInterrupt();
+
       try {
         // Find repository root
         const repoRoot = await findRepoRoot();
-        if (!repoRoot) {
+        if (!repoRoot && !piped && !adHoc) {
           spinner.fail(chalk.red('Not in a CV-Git repository'));
           console.error(chalk.gray('Run `cv init` first'));
           process.exit(1);
         }
 
-        // Load configuration
-        const config = await configManager.load(repoRoot);
-
-        // Check for API keys (CredentialManager -> config -> env var)
-        const anthropicApiKey = await getAnthropicApiKey(config.ai.apiKey);
-        if (!anthropicApiKey) {
-          spinner.fail(chalk.red('Anthropic API key not found'));
-          console.error();
-          console.error(chalk.yellow('Set your Anthropic API key:'));
-          console.error(chalk.gray('  cv auth setup anthropic'));
-          console.error(chalk.gray('  export ANTHROPIC_API_KEY=sk-ant-...'));
+        // Outside a repository, --no-index files are relative to the current directory
+        const scopeRoot = repoRoot ?? (adHoc ? process.cwd() : null);
+
+        // Load configuration (defaults for piped code or --no-index outside a repository)
+        const config = repoRoot ? await configManager.load(repoRoot) : configManager.getDefaults();
+        const retrieval = resolveRetrieval(options, config.search, {
+          minScore: DEFAULT_CONTEXT_MIN_SCORE,
+          topK: DEFAULT_CONTEXT_TOP_K
+        }, scopeRoot);
+        const files = scopeRoot ? resolveFileScope(options.file, scopeRoot) : [];
+        const rerank = piped ? null : await resolveReranker(options.rerank, config.search);
+        // Follow-ups name indexed code, so piped code gets none; neither does --deep
+        const suggest = !piped && !options.deep && resolveSuggest(options.suggest, config);
+        // --verify checks the answer's symbols against the index (or the --no-index files, or the piped code)
+        const verify = !options.since && !options.deep && (options.verify ?? config.grounding?.enabled ?? false);
+        let adHocPayloads: CodeChunkPayload[] = [];
+        const ungroundedSymbols = async (answer: string, chunks: VectorSearchResult<CodeChunkPayload>[]): Promise<string[]> => {
+          const indexed = piped ? [] : adHoc ? adHocPayloads : await readIndexedCodeChunks(getIndexDir(repoRoot!));
+          return findUngroundedSymbols(answer, buildGroundingIndex([...indexed, ...chunks.map(chunk => chunk.payload)]));
+        };
+        const model = resolveModel('explain', options.model, config);
+        const systemPrompt = await resolveSystemPrompt('explain', options, config);
+
+        // Chat backend for ai.provider: Anthropic, an Azure chat deployment, Gemini
+        // or an OpenAI-compatible gateway (which gets the model name as given)
+        let backend: AIManagerFallback;
+        try {
+          backend = await resolveChatBackend(config);
+        } catch (error: any) {
+          spinner.fail(chalk.red(error.message));
           process.exit(1);
         }
+        const deployment = backend.azure ? model ?? backend.azure.deployment : undefined;
+
+        // Files outside the last sync's --include/--exclude/--ext were never indexed
+        const indexMetadata = piped || adHoc ? null : await readIndexMetadata(repoRoot!);
+        const indexFilter: SyncFileFilter | undefined = indexMetadata?.fileFilter;
+        const targetMiss = indexFilter && target ? fileFilterMiss(path.relative(repoRoot!, path.resolve(target)), indexFilter) : null;
+        // Code blocks without a language tag are highlighted as the repo's main language
+        const codeLanguage = piped ? options.language : indexMetadata?.languages?.primary[0];
+        const render = (text: string) => format === 'plain'
+          ? stripMarkdown(text)
+          : highlight ? highlightCodeBlocks(text, codeLanguage) : text;
+
+        // Identical questions against an unchanged index (same commit, not re-synced
+        // since) are answered from the cache. --history and --since depend on commits
+        // after the indexed one, so they are never cached.
+        const cacheTtl = config.cache?.responseTtl ?? DEFAULT_RESPONSE_CACHE_TTL_SECONDS;
+        const cacheable = options.cache !== false && cacheTtl > 0 && !options.deep && !options.contextOnly
+          && historyCommits === undefined && !options.since && !!indexMetadata?.lastIndexedCommit;
+        const responseCache = cacheable ? new ResponseCache(getResponseCacheDir(repoRoot!), cacheTtl) : null;
+        const cacheKey: ResponseCacheKey | null = responseCache ? {
+          command: 'explain',
+          model: `${config.ai.provider}/${deployment ?? model ?? backend.model ?? config.ai.model}`,
+          query: target,
+          indexedCommit: indexMetadata!.lastIndexedCommit!,
+          indexUpdatedAt: indexMetadata!.updatedAt,
+          topK: retrieval.topK,
+          minScore: retrieval.minScore,
+          options: {
+            files,
+            tests: retrieval.tests,
+            packages: retrieval.packages,
+            kinds: retrieval.kinds,
+            extensions: retrieval.extensions,
+            dedupeThreshold: retrieval.dedupeThreshold,
+            efSearch: retrieval.efSearch,
+            depth: expandDepth,
+            rerank: rerank ? { model: rerank.reranker.model, candidates: rerank.candidates } : undefined,
+            followUps: suggest || undefined,
+            redact: options.redact !== false && config.redaction?.enabled !== false,
+            redactionPatterns: config.redaction?.patterns,
+            systemPrompt,
+            maxTokens: config.ai.maxTokens,
+            temperature: config.ai.temperature
+          }
+        } : null;
+
+        const hit = cacheKey ? await responseCache!.get<CachedExplanation>(cacheKey) : null;
+        if (hit) {
+          interrupt.dispose();
+          const cited = resolveInlineCitations(hit.value.answer, numberSources(hit.value.chunks));
+          const ungrounded = verify ? await ungroundedSymbols(hit.value.answer, hit.value.chunks) : undefined;
+          if (output.isJson) {
+            spinner.stop();
+            output.json(explainJson(target, cited, hit.value, true, ungrounded));
+            return;
+          }
 
-        // Get embedding credentials (OpenRouter preferred, fallback to OpenAI)
-        const embeddingCreds = await getEmbeddingCredentials();
+          spinner.succeed(chalk.green(`Answered from the cache (asked ${new Date(hit.createdAt).toLocaleString()}; --no-cache to ask again)`));
+          console.log();
+          console.log(chalk.bold.cyan('Explanation:'));
+          console.log(chalk.gray('─'.repeat(80)));
+          console.log();
+          console.log(render(cited.text));
+          console.log();
+          printSources(cited.citations, cited.dropped, false);
+          if (ungrounded) printUngrounded(ungrounded);
+          console.log(chalk.gray('─'.repeat(80)));
+          if (hit.value.followUps) {
+            console.log();
+            printFollowUps(hit.value.followUps, FOLLOW_UP_HINT);
+          }
 
-        // Initialize components
-        spinner.text = 'Connecting to services...';
+          if (options.open) {
+            const location = await pickLocation(
+              cited.citations.length > 0 ? citedLocations(cited.citations) : findCitedLocations(hit.value.answer, hit.value.chunks)
+            );
+            if (location) {
+              await openInEditor(scopeRoot!, location, config.editor?.openCommand);
+            }
+          }
+          return;
+        }
 
-        // Vector manager (optional but recommended)
-        let vector = undefined;
-        if ((embeddingCreds.openrouterApiKey || embeddingCreds.openaiApiKey) && config.vector) {
-          try {
+        // Piped code needs none of the index services
+        let vector: VectorManager | undefined;
+        let graph: GraphManager | undefined;
+        let git: GitManager | undefined;
+        if (!piped) {
+          // Get embedding credentials (configured provider, else OpenRouter > OpenAI > Ollama)
+          const embeddingCreds = await getEmbeddingCredentials({
+            provider: config.embedding?.provider,
+            ollamaUrl: config.embedding?.url,
+            ollamaModel: config.embedding?.model,
+            azure: config.azure
+          });
+          const embeddingOptions = {
+            embeddingTimeoutMs: resolveEmbeddingTimeout(config),
+            signal: interrupt.signal,
+            ...embeddingVectorOptions(embeddingCreds, config),
+            onEmbeddingServed: logProviderServed(options, 'Embedding')
+          };
+
+          if (adHoc) {
+            spinner.text = 'Collecting files...';
+            const adHocFiles = await collectAdHocFiles(scopeRoot!, files, options.dir ?? [], maxFiles);
+
+            // A throwaway in-memory store: nothing is read from or written to .cv
+            spinner.text = 'Connecting to the embedding provider...';
             vector = createVectorManager({
-              url: config.vector.url,
-              openrouterApiKey: embeddingCreds.openrouterApiKey,
-              openaiApiKey: embeddingCreds.openaiApiKey,
-              collections: config.vector.collections,
-              embeddingModel: config.embedding?.model
+              url: config.vector?.url ?? '',
+              backend: 'memory',
+              repoId: generateRepoId(scopeRoot!),
+              efSearch: retrieval.efSearch,
+              ...embeddingOptions
             });
             await vector.connect();
-          } catch (error) {
-            console.log(chalk.gray('  ⚠ Could not connect to vector DB - continuing without semantic search'));
-          }
-        }
 
-        // Graph manager
-        const graph = createGraphManager(config.graph.url, config.graph.database);
-        await graph.connect();
+            spinner.text = `Embedding ${adHocFiles.length} file${adHocFiles.length === 1 ? '' : 's'}...`;
+            const indexed = await indexAdHocFiles(vector, scopeRoot!, adHocFiles, {
+              onProgress: (embedded, total) => {
+                spinner.text = `Embedding chunks... ${embedded}/${total}`;
+              }
+            });
+            adHocPayloads = indexed.payloads;
+            for (const skipped of indexed.skipped) {
+              console.error(chalk.gray(`  ⚠ Skipped ${skipped.file}: ${skipped.reason}`));
+            }
+            if (!output.isJson) {
+              spinner.info(chalk.gray(`Embedded ${indexed.chunks} chunk${indexed.chunks === 1 ? '' : 's'} from ${indexed.files} file${indexed.files === 1 ? '' : 's'} (--no-index)`));
+              spinner = ora('Gathering context...').start();
+            }
+          } else {
+            // Use the same repo-isolated collections and graph that cv sync writes to
+            const manifest = await readManifest(getCVDir(repoRoot!));
+            const repoId = manifest?.repository?.id || generateRepoId(repoRoot!);
+
+            // Initialize components
+            spinner.text = 'Connecting to services...';
+
+            // Vector manager (optional but recommended)
+            if (config.vector) {
+              try {
+                vector = createVectorManager({
+                  url: config.vector.url,
+                  ...getVectorBackendOptions(config.vector),
+                  repoId,
+                  efSearch: retrieval.efSearch,
+                  // Reload the persisted index if Qdrant lost it (e.g. after a restart)
+                  indexDir: getIndexDir(repoRoot!),
+                  ...embeddingOptions
+                });
+                await vector.connect();
+              } catch (error) {
+                console.error(chalk.gray('  ⚠ Could not connect to vector DB - continuing without semantic search'));
+                vector = undefined;
+              }
+            }
+
+            // Graph manager
+            graph = createGraphManager({ url: config.graph.url, repoId });
+            await graph.connect();
 
-        // Git manager
-        const git = createGitManager(repoRoot);
+            // Git manager
+            git = createGitManager(repoRoot!);
+          }
+        }
 
         // AI manager
         const ai = createAIManager(
           {
-            provider: 'anthropic',
-            model: config.ai.model,
-            apiKey: anthropicApiKey
+            ...backend,
+            model: model ?? backend.model ?? config.ai.model,
+            contextWindow: config.ai.contextWindow,
+            reranker: rerank?.reranker,
+            rerankCandidates: rerank?.candidates,
+            timeoutMs: resolveChatTimeout(config, options.timeout),
+            promptCaching: config.ai.promptCaching,
+            fallbacks: await resolveAIFallbacks(config),
+            onServed: logProviderServed(options),
+            signal: interrupt.signal,
+            azure: backend.azure ? { ...backend.azure, deployment: deployment! } : undefined,
+            redaction: {
+              enabled: options.redact !== false && config.redaction?.enabled !== false,
+              patterns: config.redaction?.patterns
+            },
+            systemPrompt
           },
           vector,
           graph,
           git
         );
 
+        if (options.since) {
+          if (!(await git!.commitExists(options.since))) {
+            spinner.fail(chalk.red(`Unknown ref: ${options.since}`));
+            process.exit(1);
+          }
+
+          spinner.text = `Reading commits since ${options.since}...`;
+          const summary = await summarizeChangesSince(ai, git!, vector, options.since, targetArg, spinner);
+          interrupt.dispose();
+          await graph?.close();
+          if (vector) await vector.close();
+
+          if (output.isJson) {
+            spinner.stop();
+            output.json(summary);
+          } else if (summary.commitCount === 0) {
+            spinner.info(chalk.gray(`No commits since ${options.since}`));
+          } else {
+            spinner.succeed(chalk.green(`Explained ${summary.commitCount} commit${summary.commitCount === 1 ? '' : 's'} since ${options.since}`));
+            printChangeSummary(summary, render);
+          }
+          return;
+        }
+
+        if (options.deep && (options.contextOnly || options.open || output.isJson || options.verify)) {
+          const flag = options.open ? '--open' : options.contextOnly ? '--context-only' : output.isJson ? '--json' : '--verify';
+          spinner.fail(chalk.red(`${flag} cannot be combined with --deep`));
+          process.exit(1);
+        }
+
         // Use RLM Router for deep reasoning if --deep flag is set
         if (options.deep) {
+          if (backend.provider !== 'anthropic') {
+            spinner.fail(chalk.red('--deep requires the Anthropic provider'));
+            process.exit(1);
+          }
+          // The deep reasoning router does not take a signal; let Ctrl-C exit as usual
+          interrupt.dispose();
           spinner.text = 'Starting deep reasoning...';
 
           const rlm = createRLMRouter(
             {
-              apiKey: anthropicApiKey,
-              model: config.ai.model || 'claude-sonnet-4-5-20250514',
+              apiKey: backend.apiKey,
+              model: model ?? (config.ai.model || 'claude-sonnet-4-5-20250514'),
               maxDepth: parseInt(options.maxDepth, 10) || 5,
               maxTokens: config.ai.maxTokens
             },
@@ -147,52 +512,126 @@ export function explainCommand(): Command {
             console.log(chalk.bold.cyan('Answer:'));
             console.log(chalk.gray('─'.repeat(80)));
             console.log();
-            console.log(result.answer);
+            console.log(render(result.answer));
             console.log();
             console.log(chalk.gray('─'.repeat(80)));
 
             // Close connections
-            await graph.close();
+            await graph?.close();
             if (vector) await vector.close();
             return;
 
           } catch (error: any) {
             spinner.fail(chalk.red('Deep reasoning failed'));
             console.error(chalk.red(`Error: ${error.message}`));
-            await graph.close();
+            await graph?.close();
             if (vector) await vector.close();
             process.exit(1);
           }
         }
 
-        spinner.text = 'Gathering context...';
+        spinner.text = piped ? 'Reading stdin...' : 'Gathering context...';
+
+        // Gather context for the target; piped code is its own context
+        const context = piped
+          ? ai.pipedCodeContext(await readStdin(), resolveLanguageHint(options.language))
+          : await ai.gatherContext(target, {
+            maxChunks: retrieval.topK,
+            minScore: retrieval.minScore,
+            dedupeThreshold: retrieval.dedupeThreshold,
+            // --no-index files are all in the store already and are ranked like the rest
+            specificFiles: adHoc ? [] : files,
+            tests: retrieval.tests,
+            packages: retrieval.packages,
+            kinds: retrieval.kinds,
+            extensions: retrieval.extensions,
+            historyCommits,
+            expandDepth
+          });
+        const explainTarget = piped ? STDIN_FILE : target;
 
-        // Gather context for the target
-        const context = await ai.gatherContext(target);
+        if (options.contextOnly) {
+          spinner.stop();
+          printContextPreview(context, previewPrompts(systemPrompt, 'explain', ai.buildPrompt('explain', explainTarget, context), context, explainTarget), options.json);
+          await graph?.close();
+          if (vector) await vector.close();
+          return;
+        }
 
         if (context.chunks.length === 0 && context.symbols.length === 0) {
+          if (output.isJson) {
+            spinner.stop();
+            await graph?.close();
+            if (vector) await vector.close();
+            output.error('No relevant code found', undefined, 'NO_CONTEXT');
+            process.exit(1);
+          }
           spinner.warn(chalk.yellow('No relevant code found'));
+          const nearMiss = formatNearMiss(context.nearMissScore, retrieval.minScore);
+          if (nearMiss) {
+            console.log(chalk.gray(`  ${nearMiss}`));
+          }
           console.log();
           console.log(chalk.gray('Tips:'));
-          console.log(chalk.gray('  • Make sure you have run `cv sync`'));
+          console.log(chalk.gray(adHoc
+            ? '  • Add the files that hold the answer with --file or --dir'
+            : '  • Make sure you have run `cv sync`'));
+          if (indexFilter) {
+            console.log(chalk.gray(`  • The index only covers ${describeFileFilter(indexFilter)}; run \`cv sync\` without filters for the rest`));
+          }
           console.log(chalk.gray('  • Try a different query or symbol name'));
           console.log(chalk.gray('  • Use `cv find` to search for code first'));
           console.log();
 
-          await graph.close();
+          await graph?.close();
           if (vector) await vector.close();
           process.exit(1);
         }
 
+        // The numbered sections of the prompt, which the answer cites as [n]
+        const sources = numberSources(context.chunks);
+
+        if (output.isJson) {
+          spinner.text = 'Generating explanation...';
+          const usageBefore = getSessionUsage().length;
+          const explanation = await ai.explain(explainTarget, context);
+          const answer = resolveInlineCitations(explanation, sources);
+          if (suggest) {
+            spinner.text = 'Suggesting follow-up questions...';
+          }
+          const followUps = suggest ? await suggestFollowUps(ai, explainTarget, explanation, context) : undefined;
+          const result = { ...explainResult(ai, context.chunks, explanation, usageBefore), ...(followUps ? { followUps } : {}) };
+          const ungrounded = verify ? await ungroundedSymbols(explanation, context.chunks) : undefined;
+          spinner.stop();
+          interrupt.dispose();
+          await graph?.close();
+          if (vector) await vector.close();
+
+          if (cacheKey) await storeInCache(responseCache!, cacheKey, result);
+          output.json(explainJson(explainTarget, answer, result, false, ungrounded));
+          return;
+        }
+
         spinner.succeed(
           chalk.green(
-            `Found ${context.chunks.length} code chunks and ${context.symbols.length} symbols`
+            piped
+              ? `Read ${context.chunks[0].payload.endLine} lines from stdin`
+              : `Found ${context.chunks.length} code chunks and ${context.symbols.length} symbols`
           )
         );
 
         // Show context summary
         console.log();
         console.log(chalk.bold.cyan('Context:'));
+        if (files.length > 0) {
+          console.log(chalk.gray(`  📌 ${files.join(', ')}`));
+        }
+        if (indexFilter) {
+          console.log(chalk.gray(`  🔎 Index limited to ${describeFileFilter(indexFilter)} (cv sync filters)`));
+          if (targetMiss && fs.existsSync(path.resolve(target))) {
+            console.log(chalk.yellow(`  ⚠ ${target} is not in the index (${targetMiss}); related code in it was not searched`));
+          }
+        }
         if (context.chunks.length > 0) {
           console.log(chalk.gray(`  📄 ${context.chunks.length} relevant code sections`));
           context.chunks.slice(0, 3).forEach(chunk => {
@@ -203,9 +642,51 @@ export function explainCommand(): Command {
             );
           });
         }
+        const rerankNote = formatRerankNote(context.rerank, context.chunks.filter(chunk => chunk.rerank).length);
+        if (rerankNote) {
+          console.log(chalk.gray(`  🔀 ${rerankNote}`));
+          if (options.verbose) {
+            context.chunks.forEach((chunk, i) => {
+              if (!chunk.rerank) return;
+              const { vectorRank, vectorScore } = chunk.rerank;
+              console.log(chalk.gray(
+                `     #${vectorRank} → #${i + 1} ${chunk.payload.file}:${chunk.payload.startLine} ` +
+                `(similarity ${vectorScore.toFixed(3)}, relevance ${chunk.score.toFixed(3)})`
+              ));
+            });
+          }
+        }
         if (context.symbols.length > 0) {
           console.log(chalk.gray(`  🔗 ${context.symbols.length} related symbols`));
         }
+        if (context.expansion) {
+          const { added, dropped, depth } = context.expansion;
+          const note = dropped > 0 ? ` (${dropped} more left out to fit the token budget)` : '';
+          console.log(chalk.gray(`  🧩 ${added} definition${added === 1 ? '' : 's'} of code the sections use (--depth ${depth})${note}`));
+        }
+        if (historyCommits) {
+          const history = context.history || [];
+          const cut = context.historyBudget;
+          const note = cut
+            ? ` (${[cut.dropped && `${cut.dropped} older left out`, cut.truncated && `${cut.truncated} message${cut.truncated === 1 ? '' : 's'} truncated`].filter(Boolean).join(', ')} to fit the token budget)`
+            : '';
+          console.log(chalk.gray(`  📜 ${history.length} recent commit${history.length === 1 ? '' : 's'}${note}`));
+        }
+        if (context.redactedSecrets) {
+          console.log(chalk.gray(`  🔒 ${context.redactedSecrets} secret${context.redactedSecrets === 1 ? '' : 's'} masked (--no-redact to disable)`));
+        }
+        const budgetNote = formatBudgetNote(context.budget);
+        if (budgetNote && options.verbose) {
+          console.log(chalk.gray(`  ✂️  ${budgetNote}`));
+        }
+        const pinnedWarning = formatPinnedBudgetWarning(context.budget);
+        if (pinnedWarning) {
+          console.log(chalk.yellow(`  ${pinnedWarning}`));
+        }
+        const duplicateNote = formatDuplicateNote(context.duplicates);
+        if (duplicateNote && options.verbose) {
+          console.log(chalk.gray(`  ♻️  ${duplicateNote}`));
+        }
         console.log();
 
         // Generate explanation
@@ -213,42 +694,94 @@ export function explainCommand(): Command {
         console.log(chalk.gray('─'.repeat(80)));
         console.log();
 
+        let explanation: string;
+        let cited: ReturnType<typeof resolveInlineCitations>;
+        const usageBefore = getSessionUsage().length;
         if (options.stream) {
-          // Stream the response
-          await ai.explain(target, context, {
-            onToken: (token) => {
-              process.stdout.write(token);
-            },
-            onComplete: (fullText) => {
-              console.log();
-              console.log();
-              console.log(chalk.gray('─'.repeat(80)));
-            },
-            onError: (error) => {
-              console.error(chalk.red(`\nError: ${error.message}`));
-            }
-          });
+          // Stream the response; Ctrl-C aborts the request
+          // Code lines are highlighted (or plain text stripped) as each one completes
+          const highlighter = format === 'plain'
+            ? new PlainTextStream()
+            : highlight ? new CodeBlockHighlighter(codeLanguage) : null;
+          try {
+            explanation = await ai.explain(explainTarget, context, {
+              signal: interrupt.signal,
+              onToken: (token) => {
+                process.stdout.write(highlighter ? highlighter.push(token) : token);
+              },
+              onComplete: () => {
+                if (highlighter) process.stdout.write(highlighter.flush());
+                console.log();
+                console.log();
+              }
+            });
+            // Markers for unknown sources were already printed; the source list flags them
+            cited = resolveInlineCitations(explanation, sources);
+          } catch (error) {
+            if (!interrupt.signal.aborted && !isAbortError(error)) throw error;
+            console.log(chalk.yellow('\n\n[aborted]'));
+            await graph?.close();
+            if (vector) await vector.close();
+            process.exit(130);
+          }
         } else {
           // Non-streaming
           spinner = ora('Asking Claude...').start();
-          const explanation = await ai.explain(target, context);
+          explanation = await ai.explain(explainTarget, context);
           spinner.stop();
 
-          console.log(explanation);
+          cited = resolveInlineCitations(explanation, sources);
+          console.log(render(cited.text));
           console.log();
-          console.log(chalk.gray('─'.repeat(80)));
+        }
+        printSources(cited.citations, cited.dropped, options.stream);
+        if (verify) printUngrounded(await ungroundedSymbols(explanation, context.chunks));
+        console.log(chalk.gray('─'.repeat(80)));
+
+        const result = explainResult(ai, context.chunks, explanation, usageBefore);
+        if (suggest) {
+          console.log();
+          spinner = ora('Suggesting follow-up questions...').start();
+          const followUps = await suggestFollowUps(ai, explainTarget, explanation, context);
+          spinner.stop();
+          printFollowUps(followUps, FOLLOW_UP_HINT);
+          result.followUps = followUps;
+        }
+
+        if (cacheKey) {
+          await storeInCache(responseCache!, cacheKey, result);
         }
 
         // Close connections
-        await graph.close();
+        interrupt.dispose();
+        await graph?.close();
         if (vector) await vector.close();
 
+        if (options.open) {
+          const location = await pickLocation(
+            cited.citations.length > 0 ? citedLocations(cited.citations) : findCitedLocations(explanation, context.chunks)
+          );
+          if (location) {
+            await openInEditor(scopeRoot!, location, config.editor?.openCommand);
+          } else if (!context.chunks.length) {
+            console.log(chalk.gray('Nothing to open: no indexed code was used as context'));
+          }
+        }
+
       } catch (error: any) {
+        if (interrupt.signal.aborted || isAbortError(error)) {
+          spinner?.stop();
+          console.log(chalk.yellow('\n[aborted]'));
+          process.exit(130);
+        }
+
         if (spinner) {
           spinner.fail(chalk.red('Explanation failed'));
         }
 
         console.error(chalk.red(`Error: ${error.message}`));
+        printProxyHint(error);
+        printTimeoutHint(error);
 
         if (error.message.includes('API key')) {
           console.error();
@@ -270,3 +803,214 @@ export function explainCommand(): Command {
 
   return cmd;
 }
+
+/**
+ * An answer as --json reports it and the response cache stores it
+ */
+interface CachedExplanation {
+  answer: string;
+  model: string;
+  /** The chunks the prompt carried, in prompt order */
+  chunks: VectorSearchResult<CodeChunkPayload>[];
+  tokens: ReturnType<typeof totalCompletionTokens>;
+  /** Suggested next questions (--suggest) */
+  followUps?: string[];
+}
+
+const FOLLOW_UP_HINT = 'Ask one with: cv explain "<question>"';
+
+/**
+ * Themes of the commits since a ref, grounded in their diffs and the
+ * indexed symbols the range touches
+ */
+async function summarizeChangesSince(
+  ai: ReturnType<typeof createAIManager>,
+  git: GitManager,
+  vector: VectorManager | undefined,
+  since: string,
+  focus: string | undefined,
+  spinner: ReturnType<typeof ora>
+): Promise<ChangeSummary> {
+  const totalCommits = await git.countCommits(since);
+  if (totalCommits === 0) {
+    return { since, overview: '', themes: [], commitCount: 0, omitted: 0 };
+  }
+
+  // A longer range is summarized from its newest commits
+  const commits: ChangeSummaryCommit[] = [];
+  for (const commit of await git.getCommitLog(MAX_SUMMARY_COMMITS, since)) {
+    commits.push({ ...commit, diff: await git.getCommitDiff(commit.sha, 1).catch(() => undefined) });
+  }
+
+  // Without the index the summary rests on the diffs alone
+  let symbols: TouchedSymbol[] = [];
+  if (vector) {
+    const ranges = parseChangedRanges(await git.getRawDiff(`${since}..HEAD`));
+    const files = Array.from(new Set(ranges.map(r => r.file)));
+    if (files.length > 0) {
+      symbols = findTouchedSymbols(ranges, await vector.getFileChunks(files).catch(() => []));
+    }
+  }
+
+  spinner.text = `Explaining ${commits.length < totalCommits ? `the last ${commits.length} of ${totalCommits}` : totalCommits} commit${totalCommits === 1 ? '' : 's'}...`;
+  return await ai.summarizeChanges({ since, commits, totalCommits, symbols, focus });
+}
+
+/**
+ * Print the themes of a change summary, release-notes style
+ */
+function printChangeSummary(summary: ChangeSummary, render: (text: string) => string): void {
+  console.log();
+  console.log(chalk.bold.cyan(`Changes since ${summary.since}:`));
+  console.log(chalk.gray('─'.repeat(80)));
+  if (summary.overview) {
+    console.log();
+    console.log(render(summary.overview));
+  }
+
+  for (const theme of summary.themes) {
+    console.log();
+    console.log(chalk.bold(`• ${theme.title}`));
+    if (theme.summary) {
+      console.log(`  ${render(theme.summary)}`);
+    }
+    console.log(chalk.gray(`  ${theme.commits.map(sha => sha.slice(0, 7)).join(' ')}`));
+  }
+
+  console.log();
+  if (summary.omitted > 0) {
+    console.log(chalk.gray(`${summary.omitted} older commit${summary.omitted === 1 ? ' was' : 's were'} left out; use a later ref to cover them`));
+  }
+  console.log(chalk.gray('─'.repeat(80)));
+}
+
+/**
+ * Follow-up questions to an answer; a failed request only costs the suggestions
+ */
+async function suggestFollowUps(
+  ai: ReturnType<typeof createAIManager>,
+  target: string,
+  answer: string,
+  context: Context
+): Promise<string[]> {
+  try {
+    return await ai.suggestFollowUps(target, answer, context);
+  } catch (error: any) {
+    if (isAbortError(error)) throw error;
+    console.error(chalk.gray(`Could not suggest follow-up questions: ${error.message}`));
+    return [];
+  }
+}
+
+function explainResult(
+  ai: ReturnType<typeof createAIManager>,
+  chunks: VectorSearchResult<CodeChunkPayload>[],
+  answer: string,
+  usageBefore: number
+): CachedExplanation {
+  return {
+    answer,
+    model: ai.getModel(),
+    // Stored embeddings are not needed to show or cite a chunk
+    chunks: ai.explainPromptChunks({ chunks, symbols: [], files: [] }).map(({ vector: _vector, ...chunk }) => chunk),
+    tokens: totalCompletionTokens(getSessionUsage().slice(usageBefore))
+  };
+}
+
+/**
+ * Cache an answer; a cache that cannot be written only costs the next run a request
+ */
+async function storeInCache(cache: ResponseCache, key: ResponseCacheKey, result: CachedExplanation): Promise<void> {
+  try {
+    await cache.set(key, result);
+  } catch (error: any) {
+    if (process.env.CV_DEBUG) {
+      console.error(chalk.gray(`Could not cache the response: ${error.message}`));
+    }
+  }
+}
+
+/**
+ * The --json output. Sources are exactly the chunks the prompt carried, in
+ * prompt order, so source n is the section [n] cites.
+ */
+function explainJson(
+  target: string,
+  cited: ReturnType<typeof resolveInlineCitations>,
+  result: CachedExplanation,
+  cached: boolean,
+  ungrounded?: string[]
+): Record<string, unknown> {
+  return {
+    target,
+    answer: cited.text,
+    model: result.model,
+    sources: result.chunks.map(chunk => ({
+      file: chunk.payload.file,
+      ...(chunk.payload.package ? { package: chunk.payload.package } : {}),
+      startLine: chunk.payload.startLine,
+      endLine: chunk.payload.endLine,
+      score: chunk.score,
+      snippet: chunk.payload.text,
+      ...(chunk.expansion ? { expansion: chunk.expansion } : {}),
+      ...(chunk.rerank ? { rerank: chunk.rerank } : {})
+    })),
+    citations: cited.citations,
+    droppedCitations: cited.dropped,
+    tokens: result.tokens,
+    ...(result.followUps ? { followUps: result.followUps } : {}),
+    ...(ungrounded ? { ungroundedSymbols: ungrounded } : {}),
+    cached
+  };
+}
+
+/**
+ * The numbered list the answer's [n] markers refer to. Markers for sections
+ * the model was never given are reported, not listed.
+ */
+function printSources(citations: InlineCitation[], dropped: number[], streamed: boolean): void {
+  if (citations.length > 0) {
+    console.log(chalk.bold.cyan('Sources:'));
+    for (const citation of citations) {
+      const symbol = citation.expansion
+        ? chalk.gray(` (definition of ${citation.expansion.name}, used by ${citation.expansion.from})`)
+        : citation.symbol ? chalk.gray(` (${citation.symbol})`) : '';
+      console.log(`  ${chalk.yellow(`[${citation.index}]`)} ${citation.file}:${citation.startLine}-${citation.endLine}${symbol}`);
+    }
+    console.log();
+  }
+  if (dropped.length > 0) {
+    const unique = [...new Set(dropped)];
+    const markers = unique.map(n => `[${n}]`).join(' ');
+    const one = unique.length === 1;
+    console.log(chalk.yellow(`⚠ Citation${one ? '' : 's'} ${markers} matched no source given to the model` +
+      (streamed ? '' : ` and ${one ? 'was' : 'were'} removed`)));
+    console.log();
+  }
+}
+
+/**
+ * The --verify result: the symbols the index lacks, which the model may have invented
+ */
+function printUngrounded(symbols: string[]): void {
+  if (symbols.length === 0) {
+    console.log(chalk.gray('✓ Every symbol the answer names is in the index'));
+  } else {
+    console.log(chalk.yellow(`⚠ Not in the index, so possibly invented: ${symbols.map(symbol => `\`${symbol}\``).join(', ')}`));
+  }
+  console.log();
+}
+
+/**
+ * Inline citations as editor locations, most cited first
+ */
+function citedLocations(citations: InlineCitation[]): CitedLocation[] {
+  return citations
+    .map(citation => ({
+      file: citation.file,
+      line: citation.startLine,
+      symbol: citation.symbol,
+      mentions: citation.offsets.length
+    }))
+    .sort((a, b) => b.mentions - a.mentions);
+}
@@ -1,34 +1,53 @@
 /**
- * cv find command
- * Semantic search over codebase
+ * cv find command (alias: cv search)
+ * Semantic search over codebase, without an LLM call
+ *
+ * With --history (or --with-history) it searches the commit messages
+ * `cv sync --history` embedded.
  */
 
 import { Command } from 'commander';
 import chalk from 'chalk';
 import {
   configManager,
-  createVectorManager,
   getStorageInfo,
-  loadVectorsOnly
+  loadVectorsOnly,
+  readManifest,
+  generateRepoId,
+  getIndexDir,
+  readIndexedCodeChunks,
+  applyMinScore
 } from '@cv-git/core';
 import { findRepoRoot, getCVDir } from '@cv-git/shared';
 import { VectorSearchResult, CodeChunkPayload } from '@cv-git/shared';
 import { addGlobalOptions, createOutput } from '../utils/output.js';
-import { getEmbeddingCredentials } from '../utils/credentials.js';
-import { getPreferences } from '../config.js';
-import { ensureOllama } from '../utils/infrastructure.js';
+import { logProviderServed } from '../utils/providers.js';
+import { createVectorManagerFromCredentials } from '../utils/credentials.js';
+import { addRetrievalOptions, resolveRetrieval, formatNearMiss } from '../utils/retrieval.js';
+import { printProxyHint } from '../utils/network.js';
+import { CommitMatch, SNIPPET_LINES, toSearchMatch, toCommitMatch, resolveFindFiles } from '../utils/search-results.js';
+
+const DEFAULT_FIND_LIMIT = 10;
+const DEFAULT_FIND_MIN_SCORE = 0.5;
 
 export function findCommand(): Command {
   const cmd = new Command('find');
 
   cmd
-    .description('Search for code using natural language')
+    .alias('search')
+    .description('Search for code using natural language (no AI provider needed)')
     .argument('<query>', 'Search query in natural language')
-    .option('-l, --limit <number>', 'Maximum number of results', '10')
+    .option('-l, --limit <number>', `Maximum number of results (same as --top-k, default: ${DEFAULT_FIND_LIMIT})`)
     .option('--language <lang>', 'Filter by programming language')
-    .option('--file <path>', 'Filter by file path (partial match)')
-    .option('--min-score <score>', 'Minimum similarity score (0-1)', '0.5');
-
+    .option(
+      '--file <path>',
+      'Only search this file, or indexed files whose path contains it (repeatable)',
+      (value: string, previous: string[] = []) => [...previous, value]
+    )
+    .option('--history', 'Search commit history instead of code (needs `cv sync --history`)')
+    .option('--with-history', 'Search commit history as well as code');
+
+  addRetrievalOptions(cmd);
   addGlobalOptions(cmd);
 
   cmd.action(async (query: string, options) => {
@@ -40,76 +59,28 @@ export function findCommand(): Command {
         const repoRoot = await findRepoRoot();
         if (!repoRoot) {
           spinner.fail(chalk.red('Not in a CV-Git repository'));
-          console.error(chalk.gray('Run `cv init` first'));
+          output.error('Run `cv init` first');
           process.exit(1);
         }
 
         // Load configuration
         const config = await configManager.load(repoRoot);
-
-        // Load user preferences to determine embedding provider
-        const prefsManager = getPreferences();
-        const prefs = await prefsManager.load();
-        const embeddingProvider = config.embedding?.provider || prefs.embeddingProvider || 'ollama';
-
-        // Get embedding credentials
-        let ollamaUrl: string | undefined;
-        let lmstudioUrl: string | undefined;
-        let openrouterApiKey: string | undefined;
-        let openaiApiKey: string | undefined;
-
-        if (embeddingProvider === 'ollama') {
-          spinner.text = 'Checking Ollama...';
-          const ollamaInfo = await ensureOllama({ silent: true });
-          if (ollamaInfo) {
-            ollamaUrl = ollamaInfo.url;
-            spinner.text = `Using Ollama at ${ollamaUrl}...`;
-          } else {
-            spinner.text = 'Ollama not available, checking cloud providers...';
-            const embeddingCreds = await getEmbeddingCredentials({
-              openRouterKey: config.embedding?.apiKey,
-              openaiKey: config.ai?.apiKey
-            });
-            openrouterApiKey = embeddingCreds.openrouterApiKey;
-            openaiApiKey = embeddingCreds.openaiApiKey;
-          }
-        } else if (embeddingProvider === 'lmstudio') {
-          spinner.text = 'Checking LM Studio...';
-          const { isLMStudioRunning, getLMStudioUrl } = await import('@cv-git/core');
-          const lmUrl = getLMStudioUrl();
-          if (await isLMStudioRunning(lmUrl)) {
-            lmstudioUrl = lmUrl;
-            spinner.text = `Using LM Studio at ${lmUrl}...`;
-          } else {
-            spinner.text = 'LM Studio not available, checking cloud providers...';
-            const embeddingCreds = await getEmbeddingCredentials({
-              openRouterKey: config.embedding?.apiKey,
-              openaiKey: config.ai?.apiKey
-            });
-            openrouterApiKey = embeddingCreds.openrouterApiKey;
-            openaiApiKey = embeddingCreds.openaiApiKey;
-          }
-        } else {
-          const embeddingCreds = await getEmbeddingCredentials({
-            openRouterKey: config.embedding?.apiKey,
-            openaiKey: config.ai?.apiKey
-          });
-          openrouterApiKey = embeddingCreds.openrouterApiKey;
-          openaiApiKey = embeddingCreds.openaiApiKey;
-          spinner.text = `Using ${embeddingCreds.provider} for embeddings...`;
-        }
-
-        // Initialize vector manager with proper credentials
-        spinner.text = 'Connecting to Qdrant...';
-        const useLocal = !!(ollamaUrl || lmstudioUrl);
-        const vector = createVectorManager({
-          url: config.vector.url,
-          ollamaUrl,
-          lmstudioUrl,
-          openrouterApiKey: useLocal ? undefined : openrouterApiKey,
-          openaiApiKey: useLocal ? undefined : openaiApiKey,
-          collections: config.vector.collections,
-          vectorSize: (embeddingProvider === 'ollama' || embeddingProvider === 'lmstudio') ? 768 : 1536
+        const retrieval = resolveRetrieval({ ...options, topK: options.topK ?? options.limit }, config.search, {
+          minScore: DEFAULT_FIND_MIN_SCORE,
+          topK: DEFAULT_FIND_LIMIT
+        }, repoRoot);
+
+        // Use the same repo-isolated collections that cv sync writes to
+        const manifest = await readManifest(getCVDir(repoRoot));
+        const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+
+        spinner.text = 'Connecting to vector database...';
+        const vector = await createVectorManagerFromCredentials(config, {
+          repoId,
+          efSearch: retrieval.efSearch,
+          // Reload the persisted index if Qdrant lost it (e.g. after a restart)
+          indexDir: getIndexDir(repoRoot),
+          onEmbeddingServed: logProviderServed(options, 'Embedding')
         });
 
         await vector.connect();
@@ -118,7 +89,7 @@ export function findCommand(): Command {
         // Check if vectors are available in database
         let hasVectors = false;
         try {
-          const collectionInfo = await vector.getCollectionInfo('code_chunks');
+          const collectionInfo = await vector.getCollectionInfo(vector.getCollectionNames().codeChunks);
           hasVectors = (collectionInfo?.points_count || 0) > 0;
         } catch {
           // Collection might not exist
@@ -130,7 +101,7 @@ export function findCommand(): Command {
           if (storageInfo && storageInfo.stats.vectors > 0) {
             spinner.start(`Loading ${storageInfo.stats.vectors} vectors from .cv/ storage...`);
             try {
-              const loadedCount = await loadVectorsOnly(repoRoot, vector);
+              const loadedCount = await loadVectorsOnly(repoRoot, vector, { isolateByRepo: true });
               spinner.succeed(`Loaded ${loadedCount} vectors from local storage`);
             } catch (loadError: any) {
               spinner.warn(`Could not load vectors: ${loadError.message}`);
@@ -142,38 +113,92 @@ export function findCommand(): Command {
           }
         }
 
+        // --file names a file (exact) or part of an indexed path
+        let file: string[] | undefined;
+        if (options.file) {
+          const indexed = await readIndexedCodeChunks(getIndexDir(repoRoot));
+          file = resolveFindFiles(options.file, repoRoot, indexed.map(chunk => chunk.file));
+        }
+
         // Perform search
         spinner.start('Searching...');
 
-        const limit = parseInt(options.limit, 10);
-        const minScore = parseFloat(options.minScore);
-
-        const results = await vector.searchCode(query, limit, {
-          language: options.language,
-          file: options.file,
-          minScore
-        });
+        const searchCode = !options.history;
+        const searchHistory = options.history || options.withHistory;
+
+        // A --file that matches no indexed file has nothing to search
+        const noFileMatch = file !== undefined && file.length === 0;
+        const code = searchCode
+          ? applyMinScore(
+            noFileMatch ? [] : await vector.searchCode(query, retrieval.topK, {
+              language: options.language,
+              file,
+              tests: retrieval.tests,
+              packages: retrieval.packages,
+              kinds: retrieval.kinds,
+              extensions: retrieval.extensions
+            }),
+            retrieval.minScore
+          )
+          : undefined;
+        const history = searchHistory
+          ? applyMinScore(await vector.searchCommits(query, retrieval.topK), retrieval.minScore)
+          : undefined;
 
         spinner.stop();
+        await vector.close();
+
+        const commits = history?.results.map(toCommitMatch);
+
+        if (output.isJson) {
+          output.json({
+            query,
+            minScore: retrieval.minScore,
+            topK: retrieval.topK,
+            results: code?.results.map(toSearchMatch),
+            nearMissScore: code?.results.length === 0 ? code.nearMissScore : undefined,
+            commits,
+            commitsNearMissScore: commits?.length === 0 ? history?.nearMissScore : undefined
+          });
+          return;
+        }
 
         // Display results
-        if (results.length === 0) {
-          console.log();
-          console.log(chalk.yellow('No results found'));
-          console.log(chalk.gray('Try:'));
-          console.log(chalk.gray('  • Using different keywords'));
-          console.log(chalk.gray('  • Lowering --min-score'));
-          console.log(chalk.gray('  • Removing filters'));
-          console.log();
-        } else {
-          displaySearchResults(query, results);
+        if (code) {
+          if (code.results.length === 0) {
+            console.log();
+            console.log(chalk.yellow('No results found'));
+            const nearMiss = formatNearMiss(code.nearMissScore, retrieval.minScore);
+            if (nearMiss) {
+              console.log(chalk.gray(`  ${nearMiss}`));
+            }
+            console.log(chalk.gray('Try:'));
+            console.log(chalk.gray('  • Using different keywords'));
+            console.log(chalk.gray('  • Lowering --min-score'));
+            console.log(chalk.gray('  • Removing filters'));
+            console.log();
+          } else {
+            displaySearchResults(query, code.results);
+          }
         }
 
-        await vector.close();
+        if (commits && history) {
+          if (commits.length === 0) {
+            console.log(chalk.yellow('No matching commits found'));
+            const nearMiss = formatNearMiss(history.nearMissScore, retrieval.minScore);
+            console.log(chalk.gray(nearMiss ? `  ${nearMiss}` : '  Run `cv sync --history` to index commit history'));
+          } else {
+            printCommits(commits);
+          }
+        }
 
       } catch (error: any) {
         spinner.fail(chalk.red('Search failed'));
-        console.error(chalk.red(`Error: ${error.message}`));
+        output.error(error.message, error);
+        if (output.isJson) {
+          process.exit(1);
+        }
+        printProxyHint(error);
 
         if (error.message.includes('ECONNREFUSED')) {
           console.error();
@@ -246,3 +271,17 @@ function displaySearchResults(
   console.log(chalk.gray(`Found ${results.length} results`));
   console.log();
 }
+
+function printCommits(commits: CommitMatch[]): void {
+  for (const [i, commit] of commits.entries()) {
+    console.log(`${chalk.gray(`${i + 1}.`)} ${chalk.cyan(commit.sha.slice(0, 7))} ${chalk.gray(`${commit.date} ${commit.author}`)} ${chalk.yellow(commit.score.toFixed(3))}`);
+    console.log(chalk.gray('   │ ') + commit.subject);
+    if (commit.files.length > 0) {
+      const listed = commit.files.slice(0, SNIPPET_LINES).join(', ');
+      const more = commit.files.length > SNIPPET_LINES ? ` (+${commit.files.length - SNIPPET_LINES} more)` : '';
+      console.log(chalk.gray(`   │ ${listed}${more}`));
+    }
+    console.log();
+  }
+  console.log(chalk.gray(`${commits.length} commit${commits.length === 1 ? '' : 's'}`));
+}
@@ -7,7 +7,7 @@ import { Command } from 'commander';
 import chalk from 'chalk';
 import * as path from 'path';
 import inquirer from 'inquirer';
-import { configManager } from '@cv-git/core';
+import { configManager, CONFIG_TEMPLATES, ConfigTemplateName, isConfigTemplateName, findConfigPlaceholders } from '@cv-git/core';
 import * as fs from 'fs';
 import {
   ensureDir,
@@ -58,7 +58,9 @@ export function initCommand(): Command {
     .option('-y, --yes', 'Non-interactive mode with defaults (for AI/automation)')
     .option('--platform <platform>', 'Git platform: github, gitlab, bitbucket (default: github)')
     .option('--ai-provider <provider>', 'AI provider: anthropic, openai, openrouter (default: anthropic)')
-    .option('--embedding-provider <provider>', 'Embedding provider: ollama, lmstudio, openai, openrouter (default: ollama)');
+    .option('--embedding-provider <provider>', 'Embedding provider: ollama, lmstudio, openai, openrouter (default: ollama)')
+    .option('--template <provider>', `Write a starter config for a provider: ${Object.keys(CONFIG_TEMPLATES).join(', ')}`)
+    .option('--force', 'Overwrite an existing .cv/config.json');
 
   addGlobalOptions(cmd);
 
@@ -66,6 +68,12 @@ export function initCommand(): Command {
       const output = createOutput(options);
 
       try {
+        const template: ConfigTemplateName | undefined = options.template;
+        if (template !== undefined && !isConfigTemplateName(template)) {
+          output.error(`Unknown template: ${template} (expected one of: ${Object.keys(CONFIG_TEMPLATES).join(', ')})`);
+          process.exit(1);
+        }
+
         const currentDir = process.cwd();
         const projectName = options.name || path.basename(currentDir);
         const prefsManager = getPreferences();
@@ -176,12 +184,14 @@ export function initCommand(): Command {
           spinner.warn(chalk.yellow('CV-Git workspace already initialized in this directory'));
           return;
         }
-        try {
-          await configManager.load(currentDir);
-          spinner.warn(chalk.yellow('CV-Git is already initialized in this directory'));
-          return;
-        } catch {
-          // Not initialized, proceed
+        if (!options.force) {
+          try {
+            await configManager.load(currentDir);
+            spinner.warn(chalk.yellow('CV-Git is already initialized in this directory (--force to overwrite the config)'));
+            return;
+          } catch {
+            // Not initialized, proceed
+          }
         }
 
         // Determine mode based on detection and options
@@ -295,11 +305,13 @@ export function initCommand(): Command {
 
         if (mode === 'workspace') {
           // Initialize workspace mode
-          await initWorkspace(currentDir, projectName, detected.childRepos || [], spinner, output);
+          await initWorkspace(currentDir, projectName, detected.childRepos || [], spinner, output, template);
         } else {
           // Initialize single repo mode
-          await initSingleRepo(currentDir, projectName, spinner, output);
+          await initSingleRepo(currentDir, projectName, spinner, output, template);
         }
+        // Values the template cannot guess, such as an Azure endpoint
+        const placeholders = template ? findConfigPlaceholders(configManager.get()) : [];
 
         // Install Claude Code hooks for session knowledge
         await installClaudeHooks(currentDir, nonInteractive);
@@ -330,12 +342,18 @@ export function initCommand(): Command {
         spinner.succeed(`CV-Git ${mode === 'workspace' ? 'workspace' : 'repository'} initialized successfully!`);
 
         if (output.isJson) {
-          output.json({ success: true, name: projectName, cvDir, mode, preferences });
+          output.json({ success: true, name: projectName, cvDir, mode, preferences, template, placeholders });
         } else {
           console.log();
+          if (template) {
+            console.log(chalk.gray(`Config written from the ${template} template: ${CONFIG_TEMPLATES[template].description}`));
+            console.log();
+          }
 
-          // Check which global credentials exist for the selected preferences
-          const requiredServices = getRequiredServices(preferences);
+          // Check which global credentials exist for the selected preferences (or the template)
+          const requiredServices = template
+            ? [preferences.gitPlatform, ...CONFIG_TEMPLATES[template].services]
+            : getRequiredServices(preferences);
           const credentialStatus = await checkGlobalCredentials(requiredServices);
 
           if (credentialStatus.configured.length > 0) {
@@ -380,14 +398,26 @@ export function initCommand(): Command {
 
           console.log();
           console.log(chalk.bold('Next steps:'));
+          let step = 1;
           if (credentialStatus.missing.length > 0) {
-            console.log(chalk.gray('  1. Set up missing credentials:'));
-            console.log(chalk.cyan('     cv auth setup'));
+            console.log(chalk.gray(`  ${step++}. Set up missing credentials:`));
+            if (template) {
+              for (const service of credentialStatus.missing) {
+                console.log(chalk.cyan(`     cv auth setup ${service}`));
+              }
+            } else {
+              console.log(chalk.cyan('     cv auth setup'));
+            }
             console.log();
-            console.log(chalk.gray('  2. Sync your repository:'));
-          } else {
-            console.log(chalk.gray('  1. Sync your repository:'));
           }
+          if (placeholders.length > 0) {
+            console.log(chalk.gray(`  ${step++}. Replace the placeholders in .cv/config.json:`));
+            for (const key of placeholders) {
+              console.log(chalk.cyan(`     cv config set ${key} <value>`));
+            }
+            console.log();
+          }
+          console.log(chalk.gray(`  ${step}. Sync your repository:`));
           console.log(chalk.cyan('     cv sync'));
           console.log();
           console.log(chalk.gray('  Then start using CV-Git:'));
@@ -412,10 +442,11 @@ async function initSingleRepo(
   repoRoot: string,
   repoName: string,
   spinner: any,
-  output: any
+  output: any,
+  template?: ConfigTemplateName
 ): Promise<void> {
   spinner.text = 'Creating configuration...';
-  await configManager.init(repoRoot, repoName);
+  await configManager.init(repoRoot, repoName, template);
 }
 
 /**
@@ -426,7 +457,8 @@ async function initWorkspace(
   workspaceName: string,
   childRepos: WorkspaceRepo[],
   spinner: any,
-  output: any
+  output: any,
+  template?: ConfigTemplateName
 ): Promise<void> {
   spinner.text = 'Creating workspace configuration...';
 
@@ -443,7 +475,7 @@ async function initWorkspace(
 
   // Also create a minimal config.json for compatibility
   spinner.text = 'Creating configuration...';
-  await configManager.init(workspaceRoot, workspaceName);
+  await configManager.init(workspaceRoot, workspaceName, template);
 }
 
 /**
@@ -481,6 +513,9 @@ async function checkGlobalCredentials(services: string[]): Promise<{
         case 'openrouter':
           hasCredential = !!(await credentials.getOpenRouterKey());
           break;
+        case 'azure':
+          hasCredential = !!(await credentials.getAzureOpenAI());
+          break;
       }
     } catch {
       hasCredential = false;
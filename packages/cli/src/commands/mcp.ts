@@ -0,0 +1,49 @@
+/**
+ * cv mcp command
+ * Start the CV-Git MCP server (@cv-git/mcp-server) on stdio for the current
+ * repository, so editors and agents can search, explain, and look up symbols
+ * without shelling out to individual commands
+ *
+ * stdout carries the protocol, so everything else is written to stderr.
+ */
+
+import { Command } from 'commander';
+import { findRepoRoot } from '@cv-git/shared';
+
+export function mcpCommand(): Command {
+  const cmd = new Command('mcp');
+
+  cmd
+    .description('Start the CV-Git MCP server on stdio (cv_find, cv_explain, cv_get_symbol, ...)')
+    .option('--no-redact', 'Serve and send code to the model without masking secrets');
+
+  cmd.action(async (options) => {
+    // Anything printed to stdout would corrupt the protocol stream
+    console.log = console.error;
+    console.info = console.error;
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        console.error('Not in a CV-Git repository. Run `cv init` first.');
+        process.exit(1);
+      }
+
+      // The server reads its settings from the environment
+      if (options.redact === false) {
+        process.env.CV_NO_REDACT = '1';
+      }
+
+      // The server connects to stdio and registers its handlers on import
+      await import('@cv-git/mcp-server');
+    } catch (error: any) {
+      console.error(`Error: ${error.message}`);
+      if (process.env.CV_DEBUG) {
+        console.error(error.stack);
+      }
+      process.exit(1);
+    }
+  });
+
+  return cmd;
+}
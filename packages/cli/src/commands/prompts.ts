@@ -0,0 +1,65 @@
+/**
+ * cv prompts command
+ * List the named prompt templates in .cv/prompts, used with --prompt <name>
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import Table from 'cli-table3';
+import * as path from 'path';
+import { listNamedPrompts, getPromptsDir, SYSTEM_PROMPT_VARIABLES } from '@cv-git/core';
+import { findRepoRoot } from '@cv-git/shared';
+import { addGlobalOptions, createOutput } from '../utils/output.js';
+
+export function promptsCommand(): Command {
+  const prompts = new Command('prompts')
+    .description('Manage named prompt templates in .cv/prompts');
+
+  const list = prompts
+    .command('list')
+    .description('List named prompts for cv do --prompt and cv review --prompt');
+
+  addGlobalOptions(list);
+
+  list.action(async (options) => {
+    const output = createOutput(options);
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+        process.exit(1);
+      }
+
+      const named = await listNamedPrompts(repoRoot);
+      if (output.isJson) {
+        output.json({ dir: getPromptsDir(repoRoot), prompts: named });
+        return;
+      }
+
+      if (named.length === 0) {
+        console.log(chalk.gray(`No named prompts. Add one as ${path.relative(process.cwd(), getPromptsDir(repoRoot)) || '.'}/<name>.md, then run cv review --prompt <name>.`));
+        console.log(chalk.gray(`  Placeholders: ${Object.keys(SYSTEM_PROMPT_VARIABLES).map(name => `{{${name}}}`).join(', ')}`));
+        return;
+      }
+
+      const table = new Table({
+        head: [chalk.cyan('Name'), chalk.cyan('Description')]
+      });
+      for (const prompt of named) {
+        table.push([prompt.name, prompt.description]);
+      }
+      console.log(table.toString());
+      console.log(chalk.gray(`Use one with --prompt <name>, e.g. cv review --prompt ${named[0].name}`));
+    } catch (error: any) {
+      if (output.isJson) {
+        output.json({ error: error.message });
+      } else {
+        console.error(chalk.red(`Error: ${error.message}`));
+      }
+      process.exitCode = 1;
+    }
+  });
+
+  return prompts;
+}
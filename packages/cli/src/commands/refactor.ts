@@ -0,0 +1,222 @@
+/**
+ * cv refactor command
+ * Ask the model to refactor a file (or one function in it), review the
+ * proposed change as a unified diff, and apply it after confirmation
+ *
+ * Files with uncommitted changes are backed up under .cv/backups before
+ * being overwritten; clean files can always be restored from git.
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import ora from 'ora';
+import * as fs from 'fs/promises';
+import * as path from 'path';
+import * as readline from 'readline';
+import {
+  configManager,
+  createAIManager,
+  createGitManager,
+  createParser,
+  applyRefactor,
+  createUnifiedDiff,
+  RefactorTarget,
+  AIManagerFallback
+} from '@cv-git/core';
+import { findRepoRoot, getCVDir, detectLanguage, SymbolNode } from '@cv-git/shared';
+import { addGlobalOptions } from '../utils/output.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { resolveChatTimeout } from '../utils/timeout.js';
+import { colorizeDiff } from '../utils/formatting.js';
+
+const REFACTORABLE_KINDS = new Set(['function', 'method']);
+
+export function refactorCommand(): Command {
+  const cmd = new Command('refactor');
+
+  cmd
+    .description('Refactor a file or function with AI, showing a diff before applying it')
+    .argument('<instruction>', 'What to change (e.g. "extract the retry loop into a helper")')
+    .argument('<file>', 'File to refactor')
+    .option('--symbol <name>', 'Only refactor this function or method')
+    .option('-y, --yes', 'Apply the change without asking')
+    .option('--no-redact', 'Send code without masking secrets');
+
+  addModelOption(cmd, 'refactor');
+  addGlobalOptions(cmd);
+
+  cmd.action(async (instruction: string, fileArg: string, options) => {
+    const spinner = ora('Initializing...').start();
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        spinner.fail(chalk.red('Not in a CV-Git repository'));
+        console.error(chalk.gray('Run `cv init` first'));
+        process.exit(1);
+      }
+
+      const absolutePath = path.resolve(fileArg);
+      const file = path.relative(repoRoot, absolutePath).split(path.sep).join('/');
+      if (file.startsWith('..')) {
+        spinner.fail(chalk.red(`${fileArg} is outside the repository`));
+        process.exit(1);
+      }
+
+      let content: string;
+      try {
+        content = await fs.readFile(absolutePath, 'utf-8');
+      } catch {
+        spinner.fail(chalk.red(`Cannot read ${file}`));
+        process.exit(1);
+      }
+
+      const config = await configManager.load(repoRoot);
+      const language = detectLanguage(file);
+      const target: RefactorTarget = { file, language, content };
+      const model = resolveModel('refactor', options.model, config);
+
+      if (options.symbol) {
+        spinner.text = `Looking up ${options.symbol}...`;
+        const candidates = await findSymbolInFile(file, content, language, options.symbol);
+        if (candidates.length === 0) {
+          spinner.fail(chalk.red(`No function or method named ${options.symbol} in ${file}`));
+          process.exitCode = 1;
+          return;
+        }
+        if (candidates.length > 1) {
+          spinner.warn(chalk.yellow(`${options.symbol} is ambiguous (${candidates.length} candidates):`));
+          for (const candidate of candidates) {
+            console.log(`  ${chalk.cyan(candidate.qualifiedName)} ${chalk.gray(`${file}:${candidate.startLine}`)}`);
+          }
+          console.log(chalk.gray('\nPass Class.method to choose one.'));
+          process.exitCode = 1;
+          return;
+        }
+        const symbol = candidates[0];
+        target.symbol = { name: symbol.name, kind: symbol.kind, startLine: symbol.startLine, endLine: symbol.endLine };
+      }
+
+      // Anthropic, an Azure chat deployment, Gemini or an OpenAI-compatible gateway
+      let backend: AIManagerFallback;
+      try {
+        backend = await resolveChatBackend(config);
+      } catch (error: any) {
+        spinner.fail(chalk.red(error.message));
+        process.exit(1);
+      }
+
+      const ai = createAIManager({
+        ...chatBackendOptions(backend, model, config),
+        timeoutMs: resolveChatTimeout(config),
+        promptCaching: config.ai.promptCaching,
+        fallbacks: await resolveAIFallbacks(config),
+        onServed: logProviderServed(options),
+        maxTokens: config.ai.maxTokens,
+        redaction: {
+          enabled: options.redact !== false && config.redaction?.enabled !== false,
+          patterns: config.redaction?.patterns
+        }
+      });
+
+      const scope = target.symbol ? `${target.symbol.name} in ${file}` : file;
+      spinner.text = `Refactoring ${scope}...`;
+      const code = await ai.refactorCode(instruction, target);
+      const updated = applyRefactor(target, code);
+      spinner.stop();
+
+      const diff = createUnifiedDiff(file, content, updated);
+      if (!diff) {
+        console.log(chalk.yellow('No changes proposed.'));
+        return;
+      }
+
+      console.log(colorizeDiff(diff));
+      const added = diff.split('\n').filter(l => l.startsWith('+') && !l.startsWith('+++')).length;
+      const removed = diff.split('\n').filter(l => l.startsWith('-') && !l.startsWith('---')).length;
+      console.log(chalk.gray(`\n${file}: +${added} -${removed}`));
+
+      // Uncommitted work in the file can't be recovered from git, so it gets backed up
+      const status = await createGitManager(repoRoot).getStatus();
+      const dirty = [...status.modified, ...status.added, ...status.deleted, ...status.untracked, ...status.staged];
+      const fileDirty = dirty.includes(file);
+      if (dirty.length > 0) {
+        console.log(chalk.yellow(fileDirty
+          ? `⚠ ${file} has uncommitted changes; a backup will be kept under .cv/backups`
+          : '⚠ The working tree has uncommitted changes; review them separately from this refactor'));
+      }
+
+      if (!options.yes) {
+        if (!process.stdin.isTTY) {
+          console.log(chalk.gray('Not applied (no terminal to confirm). Rerun with --yes to apply.'));
+          return;
+        }
+        if (!await askForApproval('Apply this change?')) {
+          console.log(chalk.gray('Not applied.'));
+          return;
+        }
+      }
+
+      const backup = fileDirty ? await backUpFile(repoRoot, file, content) : undefined;
+      await fs.writeFile(absolutePath, updated, 'utf-8');
+      console.log(chalk.green(`✓ Refactored ${scope}`));
+      if (backup) {
+        console.log(chalk.gray(`  Previous version saved at ${backup}`));
+      } else {
+        console.log(chalk.gray(`  Undo with: git checkout -- ${file}`));
+      }
+    } catch (error: any) {
+      spinner.fail(chalk.red('Refactor failed'));
+      console.error(chalk.red(`Error: ${error.message}`));
+      if (process.env.CV_DEBUG) {
+        console.error(chalk.gray(error.stack));
+      }
+      process.exitCode = 1;
+    }
+  });
+
+  return cmd;
+}
+
+/**
+ * Functions and methods in a file matching a name or qualified name
+ */
+async function findSymbolInFile(file: string, content: string, language: string, name: string): Promise<SymbolNode[]> {
+  const parsed = await createParser().parseFile(file, content, language);
+  // Qualified names are "<file>:<name>" or "<file>:<Class>.<method>"
+  const qualifiedMatch = (s: SymbolNode) => s.qualifiedName === name || s.qualifiedName.endsWith(`:${name}`);
+  const symbols = parsed.symbols.filter(s => REFACTORABLE_KINDS.has(s.kind) && (s.name === name || qualifiedMatch(s)));
+
+  // An exact qualified-name match is never ambiguous
+  const exact = symbols.filter(qualifiedMatch);
+  return exact.length > 0 ? exact : symbols;
+}
+
+/**
+ * Copy a file's current contents to .cv/backups/<file>.<timestamp>
+ */
+async function backUpFile(repoRoot: string, file: string, content: string): Promise<string> {
+  const stamp = new Date().toISOString().replace(/[:.]/g, '-');
+  const backupPath = path.join(getCVDir(repoRoot), 'backups', `${file}.${stamp}`);
+  await fs.mkdir(path.dirname(backupPath), { recursive: true });
+  await fs.writeFile(backupPath, content, 'utf-8');
+  return path.relative(repoRoot, backupPath);
+}
+
+/**
+ * Ask for user approval
+ */
+async function askForApproval(question: string): Promise<boolean> {
+  const rl = readline.createInterface({
+    input: process.stdin,
+    output: process.stdout
+  });
+
+  return new Promise(resolve => {
+    rl.question(chalk.cyan(`${question} (y/N): `), answer => {
+      rl.close();
+      resolve(answer.toLowerCase() === 'y' || answer.toLowerCase() === 'yes');
+    });
+  });
+}
@@ -0,0 +1,92 @@
+/**
+ * cv refs command
+ * List the call sites of a function or method, with the function each call
+ * is in, from the chunk metadata and text stored by cv sync. No embedding
+ * call or vector database needed.
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import {
+  getIndexDir,
+  readIndexedCodeChunks,
+  findReferences
+} from '@cv-git/core';
+import { findRepoRoot } from '@cv-git/shared';
+
+export function refsCommand(): Command {
+  const cmd = new Command('refs');
+
+  cmd
+    .description('Find the callers of a function or method by name')
+    .argument('<symbol>', 'Symbol name (exact)')
+    .option('-a, --all', 'Include references that are not calls (imports, assignments, callbacks)')
+    .option('-l, --limit <number>', 'Maximum number of results', '50')
+    .option('--json', 'Output as JSON')
+    .action(async (symbol: string, options) => {
+      try {
+        const repoRoot = await findRepoRoot();
+        if (!repoRoot) {
+          console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+          process.exit(1);
+        }
+
+        const chunks = await readIndexedCodeChunks(getIndexDir(repoRoot));
+        if (chunks.length === 0) {
+          console.error(chalk.yellow('No indexed code found in .cv/index. Run `cv sync` first.'));
+          process.exit(1);
+        }
+
+        const { definitions, references } = findReferences(chunks, symbol, {
+          all: options.all,
+          limit: parseInt(options.limit, 10)
+        });
+
+        if (options.json) {
+          console.log(JSON.stringify({
+            symbol,
+            definitions: definitions.map(def => ({
+              file: def.file,
+              kind: def.kind,
+              language: def.language,
+              startLine: def.startLine,
+              endLine: def.endLine,
+              signature: def.signature
+            })),
+            references
+          }, null, 2));
+          return;
+        }
+
+        console.log();
+        if (definitions.length === 0) {
+          console.log(chalk.gray(`No indexed definition of ${symbol}; searching every language`));
+        }
+        for (const def of definitions) {
+          console.log(chalk.bold(symbol) + chalk.gray(` ${def.kind ?? 'symbol'} defined at `) + chalk.cyan(`${def.file}:${def.startLine}`));
+        }
+        console.log();
+
+        if (references.length === 0) {
+          console.log(chalk.yellow(`No ${options.all ? 'references to' : 'calls of'} ${symbol} found`));
+          console.log(chalk.gray('Matching is on the code indexed by `cv sync`; run it again if files changed.\n'));
+          return;
+        }
+
+        for (const ref of references) {
+          const caller = ref.caller ? chalk.bold(ref.caller.name) : chalk.gray('(top level)');
+          const label = ref.kind === 'call' ? '' : chalk.gray(' (reference)');
+          console.log(chalk.cyan(`${ref.file}:${ref.line}`) + '  ' + caller + label);
+          console.log(chalk.gray('  │ ') + ref.text);
+        }
+
+        const callers = new Set(references.map(ref => ref.caller ? `${ref.file}\0${ref.caller.name}` : ref.file));
+        console.log(chalk.gray(`\n${references.length} ${references.length === 1 ? 'site' : 'sites'} in ${callers.size} ${callers.size === 1 ? 'caller' : 'callers'}\n`));
+      } catch (error: any) {
+        console.error(chalk.red(`Error: ${error.message}`));
+        process.exit(1);
+      }
+    });
+
+  return cmd;
+}
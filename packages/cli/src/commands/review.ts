@@ -1,108 +1,306 @@
 /**
  * cv review command
  * AI-powered code review using Claude
+ *
+ * With --fix, findings that can be fixed within their own lines get a
+ * proposed patch; the combined diff is applied after confirmation and the
+ * review runs again to check that each fixed finding is gone.
  */
 
 import { Command } from 'commander';
 import chalk from 'chalk';
-import ora from 'ora';
 import {
   configManager,
   createAIManager,
-  createVectorManager,
   createGraphManager,
-  createGitManager
+  createGitManager,
+  reviewExitCode,
+  isReviewSeverity,
+  REVIEW_EXIT_CODES,
+  summarizeFindings,
+  parseReviewCategories,
+  groupFindingsByCategory,
+  REVIEW_SEVERITIES,
+  REVIEW_CATEGORIES,
+  DEFAULT_CONTEXT_MIN_SCORE,
+  DEFAULT_CONTEXT_TOP_K,
+  getIndexDir,
+  readManifest,
+  generateRepoId,
+  buildPipedCodeDiff,
+  resolveLanguageHint,
+  filterDiffFiles,
+  parseDiffHunks,
+  isTestFile,
+  applyReviewBaseline,
+  createReviewBaseline,
+  readReviewBaseline,
+  writeReviewBaseline,
+  getDefaultReviewBaselinePath,
+  checkFixable,
+  applyReviewFixes,
+  isFindingResolved,
+  createUnifiedDiff,
+  ReviewParseError,
+  resolveGitHubPullRequest,
+  planGitHubReview,
+  postGitHubReview,
+  GitHubReviewClient,
+  GitHubReviewPlan,
+  GitHubPullRequestRef,
+  AIManager,
+  AIManagerFallback,
+  AppliedReviewFix,
+  ReviewFix,
+  SkippedReviewFix
 } from '@cv-git/core';
-import { findRepoRoot } from '@cv-git/shared';
-import { addGlobalOptions } from '../utils/output.js';
-import { getAnthropicApiKey, getEmbeddingCredentials } from '../utils/credentials.js';
+import * as fs from 'fs/promises';
+import * as path from 'path';
+import * as readline from 'readline';
+import { findRepoRoot, getCVDir, detectLanguage, ReviewFinding, ReviewResult, ReviewRules, ReviewSeverity } from '@cv-git/shared';
+import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { createVectorManagerFromCredentials } from '../utils/credentials.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { addTimeoutOption, resolveChatTimeout, resolveEmbeddingTimeout, printTimeoutHint } from '../utils/timeout.js';
+import { abortOnInterrupt, isAbortError } from '../utils/interrupt.js';
+import { addRetrievalOptions, addRerankOption, resolveRetrieval, resolveReranker, formatNearMiss, formatBudgetNote, formatPinnedBudgetWarning, formatDuplicateNote, formatRerankNote } from '../utils/retrieval.js';
+import { addContextOnlyOption, printContextPreview } from '../utils/context-preview.js';
+import { printProxyHint } from '../utils/network.js';
+import { STDIN_ARG, addLanguageOption, readStdin } from '../utils/stdin.js';
+import { addSystemPromptOptions, resolveSystemPrompt, previewPrompts } from '../utils/system-prompt.js';
+import { colorizeDiff } from '../utils/formatting.js';
+
+const collect = (value: string, previous: string[] = []) => [...previous, value];
 
 export function reviewCommand(): Command {
   const cmd = new Command('review');
 
   cmd
     .description('Review code changes with AI')
-    .argument('[ref]', 'Git ref to review (default: HEAD)', 'HEAD')
+    .argument('[ref]', 'Git ref to review (default: HEAD), or - to review code piped on stdin', 'HEAD')
     .option('--staged', 'Review staged changes instead of a commit')
-    .option('--context', 'Include related code context in review');
-
+    .option('--diff', 'Review only the changed lines; findings point at line numbers in the new files')
+    .option('--unified <lines>', 'Lines of unchanged context around each hunk with --diff', '3')
+    .option('--context', 'Include related code context in review')
+    .option('--tests-only', 'Review only changed test files (and, with --context, retrieve only test code)')
+    .option('--no-redact', 'Send context code without masking secrets')
+    .option('--fail-on <severity>', `Exit with code 1 only if a finding is at or above this severity (${REVIEW_SEVERITIES.join(', ')}); without it the exit code is the highest severity found`)
+    .option('--disable <categories>', `Leave out findings of these categories, e.g. style,documentation (repeatable; ${REVIEW_CATEGORIES.join(', ')})`, collect)
+    .option('--baseline <file>', 'Leave out findings recorded in this baseline file, so only new ones are reported')
+    .option('--write-baseline [file]', 'Record the findings as accepted in a baseline file (default: .cv/review-baseline.json)')
+    .option('--fix', 'Propose fixes for the findings, apply them after confirmation, then review again (needs a clean working tree)')
+    .option('-y, --yes', 'Apply the --fix patch without asking')
+    .option('--github', 'Review a GitHub pull request and post the findings on it as review comments (token in GITHUB_TOKEN)')
+    .option('--repo <owner/name>', 'Repository of the pull request for --github (default: GITHUB_REPOSITORY)')
+    .option('--pr <number>', 'Pull request to review with --github (default: the one of the GitHub Actions event)')
+    .option('--dry-run', 'With --github, print the comments instead of posting them');
+
+  addLanguageOption(cmd);
+  addModelOption(cmd, 'review');
+  addTimeoutOption(cmd);
+  addSystemPromptOptions(cmd, 'review');
+  addRetrievalOptions(cmd);
+  addRerankOption(cmd);
+  addContextOnlyOption(cmd);
   addGlobalOptions(cmd);
 
   cmd.action(async (ref: string, options) => {
-      let spinner = ora('Initializing...').start();
+      const output = createOutput(options);
+      const startSpinner = (text: string) => {
+        const s = output.spinner(text);
+        s.start();
+        return s;
+      };
+
+      // Findings are structured (categorized, with severities) unless a raw prompt replaces the findings format
+      const structured = !options.rawPrompt;
+      if (options.failOn && !isReviewSeverity(options.failOn)) {
+        output.error(`Invalid --fail-on severity: ${options.failOn} (expected ${REVIEW_SEVERITIES.join(', ')})`);
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+      if (options.rawPrompt && (output.isJson || options.failOn || options.disable || options.baseline || options.writeBaseline)) {
+        output.error('--raw-prompt cannot be used with --json, --fail-on, --disable or baselines, which need the built-in findings format');
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+      const disabled = parseReviewCategories(options.disable || []);
+      if (disabled.unknown.length > 0) {
+        output.error(`Invalid --disable category: ${disabled.unknown.join(', ')} (expected ${REVIEW_CATEGORIES.join(', ')})`);
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+      const contextLines = options.diff ? parseInt(options.unified, 10) : undefined;
+      if (contextLines !== undefined && !(contextLines >= 0)) {
+        output.error(`Invalid --unified value: ${options.unified} (expected a number of lines)`);
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+      // Piped code is reviewed on its own, without the repository or its index
+      const piped = ref === STDIN_ARG;
+      if (piped && (options.staged || options.context || options.testsOnly)) {
+        output.error(`${options.staged ? '--staged' : options.context ? '--context' : '--tests-only'} cannot be used when reviewing code from stdin`);
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+      // Fixes are written to the working tree and checked by a second review of the same changes
+      if (options.fix && (piped || options.staged || output.isJson || options.rawPrompt || options.contextOnly)) {
+        output.error('--fix cannot be used with stdin, --staged, --json, --raw-prompt or --context-only');
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+
+      // --github reviews the pull request's diff and comments on its changed lines
+      if (options.github && (piped || options.staged || options.fix || options.rawPrompt || options.contextOnly)) {
+        output.error('--github cannot be used with stdin, --staged, --fix, --raw-prompt or --context-only');
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+      if (!options.github && (options.repo || options.pr || options.dryRun)) {
+        output.error('--repo, --pr and --dry-run need --github');
+        process.exit(REVIEW_EXIT_CODES.error);
+      }
+
+      let spinner = startSpinner('Initializing...');
+      // Ctrl-C cancels the context search or the review request in flight
+      const interrupt = abortOnInterrupt();
 
       try {
         // Find repository root
         const repoRoot = await findRepoRoot();
-        if (!repoRoot) {
+        if (!repoRoot && !piped) {
           spinner.fail(chalk.red('Not in a CV-Git repository'));
           console.error(chalk.gray('Run `cv init` first'));
-          process.exit(1);
+          process.exit(REVIEW_EXIT_CODES.error);
         }
 
-        // Load configuration
-        const config = await configManager.load(repoRoot);
-
-        // Check for API keys (CredentialManager -> config -> env var)
-        const anthropicApiKey = await getAnthropicApiKey(config.ai.apiKey);
-        if (!anthropicApiKey) {
-          spinner.fail(chalk.red('Anthropic API key not found'));
-          console.error();
-          console.error(chalk.yellow('Set your Anthropic API key:'));
-          console.error(chalk.gray('  cv auth setup anthropic'));
-          console.error(chalk.gray('  export ANTHROPIC_API_KEY=sk-ant-...'));
-          process.exit(1);
+        // Load configuration (defaults for piped code outside a repository)
+        const config = repoRoot ? await configManager.load(repoRoot) : configManager.getDefaults();
+        const retrieval = resolveRetrieval(options, config.search, {
+          minScore: DEFAULT_CONTEXT_MIN_SCORE,
+          topK: DEFAULT_CONTEXT_TOP_K
+        }, repoRoot);
+        const rerank = options.context ? await resolveReranker(options.rerank, config.search) : null;
+        const model = resolveModel('review', options.model, config);
+        const rules = resolveReviewRules(config.review, disabled.categories);
+        if (REVIEW_CATEGORIES.every(category => rules.disable?.includes(category))) {
+          spinner.fail(chalk.red('Every review category is disabled'));
+          process.exit(REVIEW_EXIT_CODES.error);
+        }
+        const reviewOptions = {
+          changedLinesOnly: !!(options.diff || options.github),
+          language: piped ? resolveLanguageHint(options.language) || undefined : undefined,
+          rules
+        };
+        const systemPrompt = await resolveSystemPrompt('review', options, config);
+        // Read before the review so a bad path fails without spending a request
+        const baseline = options.baseline ? await readReviewBaseline(path.resolve(options.baseline)) : undefined;
+        const baselineFile = options.writeBaseline === true
+          ? getDefaultReviewBaselinePath(repoRoot ?? process.cwd())
+          : options.writeBaseline ? path.resolve(options.writeBaseline) : undefined;
+
+        // Chat backend for ai.provider: Anthropic, an Azure chat deployment, Gemini
+        // or an OpenAI-compatible gateway
+        let backend: AIManagerFallback;
+        try {
+          backend = await resolveChatBackend(config);
+        } catch (error: any) {
+          spinner.fail(chalk.red(error.message));
+          process.exit(REVIEW_EXIT_CODES.error);
         }
-
-        // Get embedding credentials (OpenRouter preferred, fallback to OpenAI)
-        const embeddingCreds = await getEmbeddingCredentials();
 
         // Initialize components
         spinner.text = 'Connecting to services...';
 
         // Git manager
-        const git = createGitManager(repoRoot);
-
-        // Get diff
-        spinner.text = 'Getting code changes...';
-        let diff: string;
+        const git = repoRoot ? createGitManager(repoRoot) : undefined;
+
+        // Fixes must be the only uncommitted changes, so they can be reviewed and undone on their own
+        if (options.fix) {
+          const status = await git!.getStatus();
+          const dirty = [...status.modified, ...status.added, ...status.deleted, ...status.untracked, ...status.staged];
+          if (dirty.length > 0) {
+            spinner.fail(chalk.red(`--fix needs a clean working tree (${dirty.length} uncommitted change(s))`));
+            console.error(chalk.gray('  Commit or stash your changes, then review a commit, e.g. cv review HEAD~1 --fix'));
+            process.exit(REVIEW_EXIT_CODES.error);
+          }
+        }
 
-        if (options.staged) {
-          diff = await git.getRawDiff('--staged');
-        } else {
-          diff = await git.getRawDiff(ref);
+        // The pull request to comment on; posting needs a token, reading a public one does not
+        let github: { pr: GitHubPullRequestRef; client: GitHubReviewClient; headSha?: string } | undefined;
+        if (options.github) {
+          const pr = await resolveGitHubPullRequest({ repo: options.repo, pr: options.pr });
+          const token = process.env.GITHUB_TOKEN;
+          if (!token && !options.dryRun) {
+            spinner.fail(chalk.red('GITHUB_TOKEN is not set'));
+            console.error(chalk.gray('  In GitHub Actions: env: GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }} (with pull-requests: write)'));
+            process.exit(REVIEW_EXIT_CODES.error);
+          }
+          github = { pr, client: new GitHubReviewClient(pr, token) };
         }
 
+        // Get diff
+        spinner.text = piped ? 'Reading stdin...' : github ? `Getting the diff of ${formatPullRequest(github.pr)}...` : 'Getting code changes...';
+        const readDiff = async (): Promise<string> => {
+          let changes: string;
+          if (github) {
+            const pull = await github.client.getPullRequestDiff();
+            github.headSha = pull.headSha;
+            changes = pull.diff;
+          } else if (piped) {
+            changes = buildPipedCodeDiff(await readStdin());
+          } else if (options.staged) {
+            changes = await git!.getRawDiff('--staged', contextLines);
+          } else {
+            changes = await git!.getRawDiff(ref, contextLines);
+          }
+          return options.testsOnly ? filterDiffFiles(changes, isTestFile) : changes;
+        };
+        const diff = await readDiff();
+
         if (!diff || diff.trim().length === 0) {
-          spinner.warn(chalk.yellow('No changes to review'));
+          if (output.isJson) {
+            const empty: ReviewResult = { findings: [], summary: summarizeFindings([]) };
+            output.json(empty);
+            process.exit(REVIEW_EXIT_CODES.clean);
+          }
+          spinner.warn(chalk.yellow(options.testsOnly ? 'No test file changes to review' : 'No changes to review'));
           console.log();
           console.log(chalk.gray('Tips:'));
           console.log(chalk.gray('  • Make some changes and stage them: git add .'));
           console.log(chalk.gray('  • Review staged changes: cv review --staged'));
           console.log(chalk.gray('  • Review a specific commit: cv review <commit-sha>'));
           console.log();
-          process.exit(0);
+          process.exit(REVIEW_EXIT_CODES.clean);
         }
 
-        spinner.succeed(chalk.green('Changes retrieved'));
+        spinner.succeed(chalk.green(piped ? 'Code read from stdin' : 'Changes retrieved'));
+
+        // A custom prompt's {{query}} and {{files}} describe what is reviewed
+        if (systemPrompt) {
+          systemPrompt.variables = {
+            query: piped ? 'code from stdin' : github ? `pull request ${formatPullRequest(github.pr)}` : options.staged ? 'staged changes' : ref,
+            files: parseDiffHunks(diff).map(file => file.path).join(', ')
+          };
+        }
 
         // Optional: gather context
         let context = undefined;
         if (options.context) {
-          // Vector manager (optional)
+          // Use the same repo-isolated collections that cv sync writes to
+          const manifest = await readManifest(getCVDir(repoRoot!));
+          const repoId = manifest?.repository?.id || generateRepoId(repoRoot!);
+
+          // Vector manager (optional; embeds with the configured provider)
           let vector = undefined;
-          if ((embeddingCreds.openrouterApiKey || embeddingCreds.openaiApiKey) && config.vector) {
+          if (config.vector) {
             try {
-              vector = createVectorManager({
-                url: config.vector.url,
-                openrouterApiKey: embeddingCreds.openrouterApiKey,
-                openaiApiKey: embeddingCreds.openaiApiKey,
-                collections: config.vector.collections,
-                embeddingModel: config.embedding?.model
+              vector = await createVectorManagerFromCredentials(config, {
+                repoId,
+                efSearch: retrieval.efSearch,
+                indexDir: getIndexDir(repoRoot!),
+                embeddingTimeoutMs: resolveEmbeddingTimeout(config),
+                signal: interrupt.signal,
+                onEmbeddingServed: logProviderServed(options, 'Embedding')
               });
               await vector.connect();
             } catch (error) {
-              console.log(chalk.gray('  ⚠ Could not connect to vector DB'));
+              if (!output.isJson) console.log(chalk.gray('  ⚠ Could not connect to vector DB'));
+              vector = undefined;
             }
           }
 
@@ -113,18 +311,54 @@ export function reviewCommand(): Command {
           // AI manager for context gathering
           const contextAI = createAIManager(
             {
-              provider: 'anthropic',
-              model: config.ai.model,
-              apiKey: anthropicApiKey
+              ...chatBackendOptions(backend, model, config),
+              contextWindow: config.ai.contextWindow,
+              reranker: rerank?.reranker,
+              rerankCandidates: rerank?.candidates,
+              timeoutMs: resolveChatTimeout(config, options.timeout),
+              promptCaching: config.ai.promptCaching,
+              signal: interrupt.signal,
+              redaction: {
+                enabled: options.redact !== false && config.redaction?.enabled !== false,
+                patterns: config.redaction?.patterns
+              }
             },
             vector,
             graph,
             git
           );
 
-          spinner = ora('Gathering code context...').start();
-          context = await contextAI.gatherContext('code review');
+          spinner = startSpinner('Gathering code context...');
+          context = await contextAI.gatherContext('code review', {
+            maxChunks: retrieval.topK,
+            minScore: retrieval.minScore,
+            dedupeThreshold: retrieval.dedupeThreshold,
+            tests: retrieval.tests,
+            packages: retrieval.packages,
+            kinds: retrieval.kinds,
+            extensions: retrieval.extensions
+          });
           spinner.succeed(chalk.green('Context gathered'));
+          const nearMiss = context.chunks.length === 0 ? formatNearMiss(context.nearMissScore, retrieval.minScore) : null;
+          if (nearMiss && !output.isJson) {
+            console.log(chalk.gray(`  ${nearMiss}`));
+          }
+          const budgetNote = formatBudgetNote(context.budget);
+          if (budgetNote && options.verbose && !output.isJson) {
+            console.log(chalk.gray(`  ${budgetNote}`));
+          }
+          const pinnedWarning = formatPinnedBudgetWarning(context.budget);
+          if (pinnedWarning && !output.isJson) {
+            console.log(chalk.yellow(`  ${pinnedWarning}`));
+          }
+          const duplicateNote = formatDuplicateNote(context.duplicates);
+          if (duplicateNote && options.verbose && !output.isJson) {
+            console.log(chalk.gray(`  ${duplicateNote}`));
+          }
+          const rerankNote = formatRerankNote(context.rerank, context.chunks.filter(chunk => chunk.rerank).length);
+          if (rerankNote && options.verbose && !output.isJson) {
+            console.log(chalk.gray(`  ${rerankNote}`));
+          }
 
           await graph.close();
           if (vector) await vector.close();
@@ -133,23 +367,95 @@ export function reviewCommand(): Command {
         // AI manager for review
         const ai = createAIManager(
           {
-            provider: 'anthropic',
-            model: config.ai.model,
-            apiKey: anthropicApiKey
+            ...chatBackendOptions(backend, model, config),
+            timeoutMs: resolveChatTimeout(config, options.timeout),
+            promptCaching: config.ai.promptCaching,
+            fallbacks: await resolveAIFallbacks(config),
+            onServed: logProviderServed(options),
+            signal: interrupt.signal,
+            systemPrompt
           },
           undefined,
           undefined,
           git
         );
 
+        if (options.contextOnly) {
+          // Without --context the prompt is just the diff and instructions
+          const previewContext = context ?? { chunks: [], symbols: [], files: [] };
+          const kind = structured ? 'review-structured' : 'review';
+          printContextPreview(previewContext, previewPrompts(systemPrompt, kind, ai.buildPrompt(kind, diff, previewContext, reviewOptions), previewContext, structured ? undefined : diff), output.isJson);
+          return;
+        }
+
+        if (structured) {
+          spinner = startSpinner('Analyzing changes...');
+          let result = await ai.reviewCodeStructured(diff, context, reviewOptions);
+          spinner.stop();
+
+          if (baselineFile) {
+            await writeReviewBaseline(baselineFile, createReviewBaseline(result.findings));
+            const note = `Baseline of ${result.findings.length} finding(s) written to ${path.relative(process.cwd(), baselineFile) || baselineFile}`;
+            if (output.isJson) {
+              console.error(note);
+            } else {
+              console.log(chalk.green(`\n✔ ${note}`));
+            }
+          }
+          if (baseline) {
+            result = applyReviewBaseline(result, baseline);
+          }
+
+          if (output.isJson) {
+            output.json(result);
+          } else {
+            printFindings(result);
+          }
+
+          if (github) {
+            await commentOnPullRequest(github, result, diff, !!options.dryRun, startSpinner, output.isJson);
+          }
+
+          if (options.fix && result.findings.length > 0) {
+            const fixer = createAIManager({
+              ...chatBackendOptions(backend, model, config),
+              timeoutMs: resolveChatTimeout(config, options.timeout),
+              promptCaching: config.ai.promptCaching,
+              fallbacks: await resolveAIFallbacks(config),
+              onServed: logProviderServed(options),
+              signal: interrupt.signal,
+              redaction: {
+                enabled: options.redact !== false && config.redaction?.enabled !== false,
+                patterns: config.redaction?.patterns
+              }
+            });
+            const applied = await proposeFixes(repoRoot!, result.findings, fixer, startSpinner, options.yes);
+
+            if (applied.length > 0) {
+              // Review the same changes again, now including the fixes
+              spinner = startSpinner('Reviewing the fixed code...');
+              result = await ai.reviewCodeStructured(await readDiff(), context, reviewOptions);
+              if (baseline) {
+                result = applyReviewBaseline(result, baseline);
+              }
+              spinner.stop();
+              printFixOutcome(applied, result.findings);
+            }
+          }
+
+          // The highest severity, or with --fail-on whether it was reached (see REVIEW_EXIT_CODES)
+          process.exitCode = reviewExitCode(result.findings, options.failOn as ReviewSeverity | undefined);
+          return;
+        }
+
         // Generate review
         console.log();
         console.log(chalk.bold.cyan('Code Review:'));
         console.log(chalk.gray('─'.repeat(80)));
         console.log();
 
-        spinner = ora('Analyzing changes...').start();
-        const review = await ai.reviewCode(diff, context);
+        spinner = startSpinner('Analyzing changes...');
+        const review = await ai.reviewCode(diff, context, reviewOptions);
         spinner.stop();
 
         console.log(review);
@@ -167,11 +473,37 @@ export function reviewCommand(): Command {
         console.log();
 
       } catch (error: any) {
+        if (interrupt.signal.aborted || isAbortError(error)) {
+          spinner?.stop();
+          if (!output.isJson) console.log(chalk.yellow('\n[aborted]'));
+          process.exit(130);
+        }
+
+        // A review that cannot be read fails the gate instead of passing as clean
+        if (error instanceof ReviewParseError) {
+          spinner?.fail(chalk.red('Review failed: the response could not be read'));
+          console.error(chalk.gray('Raw response:'));
+          console.error(error.response);
+          if (output.isJson) {
+            output.error('Review failed', error, 'REVIEW_UNREADABLE');
+          } else {
+            console.error(chalk.red(`Error: ${error.message}`));
+          }
+          process.exit(REVIEW_EXIT_CODES.error);
+        }
+
         if (spinner) {
           spinner.fail(chalk.red('Review failed'));
         }
 
+        if (output.isJson) {
+          output.error('Review failed', error, 'REVIEW_FAILED');
+          process.exit(REVIEW_EXIT_CODES.error);
+        }
+
         console.error(chalk.red(`Error: ${error.message}`));
+        printProxyHint(error);
+        printTimeoutHint(error);
 
         if (error.message.includes('API key')) {
           console.error();
@@ -192,9 +524,291 @@ export function reviewCommand(): Command {
           console.error(chalk.gray(error.stack));
         }
 
-        process.exit(1);
+        process.exit(REVIEW_EXIT_CODES.error);
       }
     });
 
   return cmd;
 }
+
+/**
+ * Post the findings on the pull request, editing cv's comments from earlier
+ * runs, or with --dry-run print what would be posted
+ */
+async function commentOnPullRequest(
+  github: { pr: GitHubPullRequestRef; client: GitHubReviewClient; headSha?: string },
+  result: ReviewResult,
+  diff: string,
+  dryRun: boolean,
+  startSpinner: (text: string) => { text: string; stop(): unknown; succeed(text: string): unknown },
+  json: boolean
+): Promise<void> {
+  const spinner = startSpinner(`Reading earlier cv comments on ${formatPullRequest(github.pr)}...`);
+  const plan = planGitHubReview(result, diff, {
+    inline: await github.client.listReviewComments(),
+    conversation: await github.client.listConversationComments()
+  });
+
+  if (dryRun) {
+    spinner.stop();
+    printGitHubPlan(plan, github.pr, json ? console.error : console.log);
+    return;
+  }
+
+  spinner.text = `Posting the review on ${formatPullRequest(github.pr)}...`;
+  const posted = await postGitHubReview(github.client, plan, github.headSha ?? '');
+  spinner.succeed(`${formatPullRequest(github.pr)}: ${posted.created} comment(s) posted, ${posted.updated} updated`);
+}
+
+/**
+ * What --github would post, for --dry-run
+ */
+function printGitHubPlan(plan: GitHubReviewPlan, pr: GitHubPullRequestRef, log: (text: string) => void): void {
+  log('');
+  log(chalk.bold.cyan(`Would post on ${formatPullRequest(pr)} (--dry-run):`));
+  for (const comment of plan.create) {
+    log('');
+    log(chalk.cyan(`  New comment on ${comment.path}:${comment.line} (diff position ${comment.position})`));
+    log(indentComment(comment.body));
+  }
+  for (const update of plan.update) {
+    log('');
+    log(chalk.cyan(`  Edit comment ${update.id}${update.resolved ? ' (resolved)' : ''}`));
+    log(indentComment(update.body));
+  }
+  log('');
+  log(chalk.cyan(plan.summary.id !== undefined ? `  Edit summary comment ${plan.summary.id}` : '  New summary comment'));
+  log(indentComment(plan.summary.body));
+  log('');
+}
+
+function indentComment(body: string): string {
+  return body.split('\n').filter(line => !line.startsWith('<!-- cv-review:')).map(line => chalk.gray(`    ${line}`)).join('\n');
+}
+
+function formatPullRequest(pr: GitHubPullRequestRef): string {
+  return `${pr.owner}/${pr.repo}#${pr.number}`;
+}
+
+/**
+ * Ask for a fix of each finding, show the combined diff, and write it after
+ * confirmation. Returns the fixes written (none if declined).
+ */
+async function proposeFixes(
+  repoRoot: string,
+  findings: ReviewFinding[],
+  fixer: AIManager,
+  startSpinner: (text: string) => { text: string; stop(): unknown },
+  yes: boolean | undefined
+): Promise<AppliedReviewFix[]> {
+  const contents = new Map<string, string>();
+  const fixes: ReviewFix[] = [];
+  const skipped: SkippedReviewFix[] = [];
+  const spinner = startSpinner('Fixing findings...');
+
+  for (const [i, finding] of findings.entries()) {
+    spinner.text = `Fixing ${formatLocation(finding)} (${i + 1}/${findings.length})...`;
+    if (!isInsideRepo(repoRoot, finding.file)) {
+      skipped.push({ finding, reason: 'outside the repository' });
+      continue;
+    }
+    if (!contents.has(finding.file)) {
+      const content = await fs.readFile(path.join(repoRoot, finding.file), 'utf-8').catch(() => undefined);
+      if (content === undefined) {
+        skipped.push({ finding, reason: 'file not found in the working tree' });
+        continue;
+      }
+      contents.set(finding.file, content);
+    }
+
+    const content = contents.get(finding.file)!;
+    const reason = checkFixable(finding, content);
+    if (reason) {
+      skipped.push({ finding, reason });
+      continue;
+    }
+    const replacement = await fixer.fixReviewFinding(finding, detectLanguage(finding.file), content);
+    if (replacement === null) {
+      skipped.push({ finding, reason: 'no safe fix within its lines' });
+    } else {
+      fixes.push({ finding, replacement });
+    }
+  }
+  spinner.stop();
+
+  const updates: Array<{ file: string; updated: string }> = [];
+  const applied: AppliedReviewFix[] = [];
+  let diff = '';
+  for (const [file, content] of contents) {
+    const plan = applyReviewFixes(content, fixes.filter(fix => fix.finding.file === file));
+    skipped.push(...plan.skipped);
+    if (plan.applied.length === 0) continue;
+    updates.push({ file, updated: plan.content });
+    applied.push(...plan.applied);
+    diff += createUnifiedDiff(file, content, plan.content);
+  }
+
+  console.log(chalk.bold.cyan('Proposed fixes:'));
+  console.log();
+  if (diff) {
+    console.log(colorizeDiff(diff));
+  }
+  console.log(chalk.gray(`${applied.length} of ${findings.length} finding(s) fixed in ${updates.length} file(s)`));
+  for (const { finding, reason } of skipped) {
+    console.log(chalk.gray(`  Skipped ${formatLocation(finding)}: ${reason}`));
+  }
+  console.log();
+
+  if (applied.length === 0) {
+    return [];
+  }
+  if (!yes) {
+    if (!process.stdin.isTTY) {
+      console.log(chalk.gray('Not applied (no terminal to confirm). Rerun with --yes to apply.'));
+      return [];
+    }
+    if (!await askForApproval(`Apply ${applied.length} fix(es)?`)) {
+      console.log(chalk.gray('Not applied.'));
+      return [];
+    }
+  }
+
+  for (const { file, updated } of updates) {
+    await fs.writeFile(path.join(repoRoot, file), updated, 'utf-8');
+  }
+  console.log(chalk.green(`✓ Applied ${applied.length} fix(es) to ${updates.map(u => u.file).join(', ')}`));
+  console.log(chalk.gray(`  Undo with: git checkout -- ${updates.map(u => u.file).join(' ')}`));
+  return applied;
+}
+
+function isInsideRepo(repoRoot: string, file: string): boolean {
+  const relative = path.relative(repoRoot, path.resolve(repoRoot, file));
+  return relative !== '' && !relative.startsWith('..') && !path.isAbsolute(relative);
+}
+
+/**
+ * Which fixed findings the second review no longer reports
+ */
+function printFixOutcome(applied: AppliedReviewFix[], findings: ReviewFinding[]): void {
+  console.log();
+  console.log(chalk.bold.cyan('After fixing:'));
+  let resolved = 0;
+  for (const fix of applied) {
+    if (isFindingResolved(fix, findings)) {
+      resolved++;
+      console.log(chalk.green(`  ✔ ${formatLocation(fix.finding)} ${fix.finding.message}`));
+    } else {
+      console.log(chalk.yellow(`  ⚠ ${formatLocation(fix.finding)} still reported: ${fix.finding.message}`));
+    }
+  }
+  console.log(chalk.gray(`${resolved} of ${applied.length} fixed finding(s) resolved; ${findings.length} finding(s) reported now`));
+  console.log();
+}
+
+/**
+ * Ask for user approval
+ */
+async function askForApproval(question: string): Promise<boolean> {
+  const rl = readline.createInterface({
+    input: process.stdin,
+    output: process.stdout
+  });
+
+  return new Promise(resolve => {
+    rl.question(chalk.cyan(`${question} (y/N): `), answer => {
+      rl.close();
+      resolve(answer.toLowerCase() === 'y' || answer.toLowerCase() === 'yes');
+    });
+  });
+}
+
+const SEVERITY_COLORS: Record<ReviewSeverity, (text: string) => string> = {
+  critical: chalk.bgRed.white,
+  high: chalk.red,
+  medium: chalk.yellow,
+  low: chalk.gray
+};
+
+/**
+ * Print structured findings for human readers
+ */
+function printFindings(result: ReviewResult): void {
+  console.log();
+  console.log(chalk.bold.cyan('Code Review:'));
+  console.log(chalk.gray('─'.repeat(80)));
+  console.log();
+
+  if (result.summary.overview) {
+    console.log(result.summary.overview);
+    console.log();
+  }
+
+  if (result.findings.length === 0) {
+    console.log(chalk.green('No issues found.'));
+  }
+
+  for (const group of groupFindingsByCategory(result.findings)) {
+    console.log(chalk.bold(`${formatCategory(group.category)} (${group.findings.length})`));
+    console.log();
+    // Most severe first within a category; sortFindings keeps file order for ties
+    const findings = [...group.findings].sort((a, b) => REVIEW_SEVERITIES.indexOf(b.severity) - REVIEW_SEVERITIES.indexOf(a.severity));
+    for (const finding of findings) {
+      console.log(`  ${SEVERITY_COLORS[finding.severity](finding.severity.toUpperCase().padEnd(8))} ${chalk.cyan(formatLocation(finding))}`);
+      console.log(`    ${finding.message}`);
+      if (finding.suggestion) {
+        console.log(chalk.gray(`    → ${finding.suggestion}`));
+      }
+      console.log();
+    }
+  }
+
+  const counts = REVIEW_SEVERITIES
+    .filter(severity => result.summary.bySeverity[severity] > 0)
+    .map(severity => `${result.summary.bySeverity[severity]} ${severity}`);
+  console.log(chalk.gray('─'.repeat(80)));
+  console.log(chalk.bold(`${result.summary.total} finding(s)`) + (counts.length ? chalk.gray(` (${counts.join(', ')})`) : ''));
+  if (result.summary.disabledCategories?.length) {
+    console.log(chalk.gray(`Disabled categories: ${result.summary.disabledCategories.join(', ')}`));
+  }
+  if (result.summary.baselined) {
+    console.log(chalk.gray(`${result.summary.baselined} finding(s) in the baseline not shown`));
+  }
+  console.log();
+}
+
+/**
+ * Merge --disable into the config's review rules; severities pinned in the
+ * config are keyed by category like the findings
+ */
+function resolveReviewRules(configured: ReviewRules | undefined, disable: string[]): ReviewRules {
+  const configuredDisable = parseReviewCategories(configured?.disable || []);
+  if (configuredDisable.unknown.length > 0) {
+    console.error(chalk.yellow(`⚠ Ignoring unknown review.disable categories: ${configuredDisable.unknown.join(', ')}`));
+  }
+
+  const severity: Record<string, ReviewSeverity> = {};
+  for (const [name, value] of Object.entries(configured?.severity || {})) {
+    const category = parseReviewCategories([name]).categories[0];
+    if (category && isReviewSeverity(value)) {
+      severity[category] = value;
+    } else {
+      console.error(chalk.yellow(`⚠ Ignoring review.severity.${name}: ${value} (expected a category and one of ${REVIEW_SEVERITIES.join(', ')})`));
+    }
+  }
+
+  return {
+    disable: [...new Set([...configuredDisable.categories, ...disable])],
+    severity
+  };
+}
+
+function formatCategory(category: string): string {
+  return category.split('-').map(word => word.charAt(0).toUpperCase() + word.slice(1)).join(' ');
+}
+
+function formatLocation(finding: ReviewFinding): string {
+  if (!finding.startLine) return finding.file;
+  return finding.endLine > finding.startLine
+    ? `${finding.file}:${finding.startLine}-${finding.endLine}`
+    : `${finding.file}:${finding.startLine}`;
+}
@@ -0,0 +1,206 @@
+/**
+ * cv summarize command
+ * Write a high-level architecture overview of the repository from a diverse,
+ * central sample of the persisted index. Overviews are cached per indexed commit.
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import ora from 'ora';
+import * as fs from 'fs/promises';
+import * as path from 'path';
+import {
+  configManager,
+  createAIManager,
+  getIndexDir,
+  readIndexSnapshotManifest,
+  readIndexSnapshotCollection,
+  readManifest,
+  generateRepoId,
+  getVectorCollectionName,
+  selectOverviewSamples,
+  readCachedOverview,
+  writeCachedOverview,
+  OVERVIEW_DEPTHS,
+  OverviewDepth,
+  AIManagerFallback
+} from '@cv-git/core';
+import { findRepoRoot, getCVDir, CodeChunkPayload } from '@cv-git/shared';
+import { addGlobalOptions, createOutput, OutputManager } from '../utils/output.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { resolveChatTimeout } from '../utils/timeout.js';
+import { abortOnInterrupt, isAbortError } from '../utils/interrupt.js';
+import { printProxyHint } from '../utils/network.js';
+
+export function summarizeCommand(): Command {
+  const cmd = new Command('summarize');
+
+  cmd
+    .description('Generate an architecture overview of the repository from the index')
+    .option('--depth <level>', `Level of detail: ${OVERVIEW_DEPTHS.join(', ')}`, 'standard')
+    .option('-o, --output <file>', 'Write the overview to a file (e.g. README-ARCH.md)')
+    .option('--refresh', 'Regenerate even if an overview of the indexed commit is cached')
+    .option('--no-stream', 'Wait for the full overview instead of streaming it')
+    .option('--no-redact', 'Send code without masking secrets');
+
+  addModelOption(cmd, 'summarize');
+  addGlobalOptions(cmd);
+
+  cmd.action(async (options) => {
+    const output = createOutput(options);
+    const spinner = output.isJson ? null : ora('Reading the index...').start();
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        spinner?.fail(chalk.red('Not in a CV-Git repository'));
+        console.error(chalk.gray('Run `cv init` first'));
+        process.exit(1);
+      }
+
+      const depth = options.depth as OverviewDepth;
+      if (!OVERVIEW_DEPTHS.includes(depth)) {
+        throw new Error(`Invalid --depth: ${options.depth} (expected ${OVERVIEW_DEPTHS.join(', ')})`);
+      }
+
+      const indexDir = getIndexDir(repoRoot);
+      const snapshot = await readIndexSnapshotManifest(indexDir);
+      if (!snapshot) {
+        throw new Error('No persisted index found. Run `cv sync` first.');
+      }
+      const commit = snapshot.lastIndexedCommit;
+
+      const config = await configManager.load(repoRoot);
+      const model = resolveModel('summarize', options.model, config);
+
+      // A cached overview is reused until the index moves to another commit
+      const cached = commit && !options.refresh ? await readCachedOverview(repoRoot, commit, depth) : null;
+      if (cached) {
+        spinner?.succeed(chalk.green(`Using the overview cached for ${commit!.slice(0, 12)} (--refresh to regenerate)`));
+        await emitOverview(cached.overview, { commit, depth, cached: true, files: [] }, options, output);
+        return;
+      }
+
+      const manifest = await readManifest(getCVDir(repoRoot));
+      const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+      const isolated = getVectorCollectionName(repoId, 'code_chunks');
+      const collection = snapshot.collections[isolated] !== undefined
+        ? isolated
+        : Object.keys(snapshot.collections).find(name => name.endsWith('code_chunks'));
+      const points = collection ? await readIndexSnapshotCollection(indexDir, collection) : [];
+      if (points.length === 0) {
+        throw new Error('The index has no code chunks. Run `cv sync` first.');
+      }
+
+      const sample = selectOverviewSamples(points.map(point => point.payload as unknown as CodeChunkPayload), depth);
+      const modules = new Set(sample.files.map(f => f.module));
+      spinner?.succeed(chalk.green(
+        `Sampled ${sample.files.length} of ${sample.totalFiles} files across ${modules.size} module${modules.size === 1 ? '' : 's'}`
+      ));
+      if (options.verbose && !output.isJson) {
+        for (const file of sample.files) {
+          const notes = [file.importedBy > 0 ? `imported by ${file.importedBy}` : '', file.entryPoint ? 'entry point' : ''].filter(Boolean);
+          console.log(chalk.gray(`  • ${file.file}${notes.length ? ` (${notes.join(', ')})` : ''}`));
+        }
+      }
+
+      // Anthropic, an Azure chat deployment, Gemini or an OpenAI-compatible gateway
+      let backend: AIManagerFallback;
+      try {
+        backend = await resolveChatBackend(config);
+      } catch (error: any) {
+        spinner.fail(chalk.red(error.message));
+        process.exit(1);
+      }
+
+      const ai = createAIManager({
+        ...chatBackendOptions(backend, model, config),
+        timeoutMs: resolveChatTimeout(config),
+        promptCaching: config.ai.promptCaching,
+        fallbacks: await resolveAIFallbacks(config),
+        onServed: logProviderServed(options),
+        maxTokens: config.ai.maxTokens,
+        redaction: {
+          enabled: options.redact !== false && config.redaction?.enabled !== false,
+          patterns: config.redaction?.patterns
+        }
+      });
+
+      const repoName = path.basename(repoRoot);
+      const info = { commit, depth, cached: false, files: sample.files.map(f => f.file) };
+      let overview: string;
+
+      if (options.stream && !options.output && !output.isJson) {
+        console.log();
+        console.log(chalk.bold.cyan('Architecture Overview:'));
+        console.log(chalk.gray('─'.repeat(80)));
+        const interrupt = abortOnInterrupt();
+        try {
+          overview = await ai.summarizeRepository(sample, depth, repoName, {
+            signal: interrupt.signal,
+            onToken: token => process.stdout.write(token),
+            onComplete: () => console.log()
+          });
+        } catch (error) {
+          if (!interrupt.signal.aborted && !isAbortError(error)) throw error;
+          console.log(chalk.yellow('\n[aborted]'));
+          process.exitCode = 130;
+          return;
+        } finally {
+          interrupt.dispose();
+        }
+        console.log(chalk.gray('─'.repeat(80)));
+      } else {
+        const waiting = output.isJson ? null : ora('Writing the overview...').start();
+        overview = await ai.summarizeRepository(sample, depth, repoName);
+        waiting?.stop();
+        await emitOverview(overview, info, options, output);
+      }
+
+      if (commit) {
+        await writeCachedOverview(repoRoot, { commit, depth, model: model ?? config.ai.model, generatedAt: new Date().toISOString(), overview });
+      }
+    } catch (error: any) {
+      if (output.isJson) {
+        output.json({ error: error.message });
+      } else {
+        spinner?.fail(chalk.red('Could not summarize the repository'));
+        console.error(chalk.red(`Error: ${error.message}`));
+        printProxyHint(error);
+        if (process.env.CV_DEBUG) {
+          console.error(chalk.gray(error.stack));
+        }
+      }
+      process.exitCode = 1;
+    }
+  });
+
+  return cmd;
+}
+
+/**
+ * Print the overview, or write it to --output
+ */
+async function emitOverview(
+  overview: string,
+  info: { commit?: string; depth: OverviewDepth; cached: boolean; files: string[] },
+  options: { output?: string },
+  output: OutputManager
+): Promise<void> {
+  if (options.output) {
+    await fs.writeFile(path.resolve(options.output), overview.trimEnd() + '\n', 'utf-8');
+  }
+
+  if (output.isJson) {
+    output.json({ ...info, output: options.output, overview });
+  } else if (options.output) {
+    console.log(chalk.green(`✓ Wrote ${options.output}`));
+  } else {
+    console.log();
+    console.log(chalk.bold.cyan('Architecture Overview:'));
+    console.log(chalk.gray('─'.repeat(80)));
+    console.log(overview);
+    console.log(chalk.gray('─'.repeat(80)));
+  }
+}
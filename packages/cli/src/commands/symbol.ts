@@ -0,0 +1,76 @@
+/**
+ * cv symbol command
+ * Look up a symbol by name (exact, then fuzzy) in the chunk metadata stored
+ * by cv sync. No embedding call or vector database needed.
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import {
+  getIndexDir,
+  readIndexedCodeChunks,
+  lookupSymbols,
+  SYMBOL_KIND_FILTERS
+} from '@cv-git/core';
+import { findRepoRoot } from '@cv-git/shared';
+
+export function symbolCommand(): Command {
+  const cmd = new Command('symbol');
+
+  cmd
+    .description('Find where a function, type, or method is defined by name')
+    .argument('<name>', 'Symbol name (partial names and typos are matched too)')
+    .option('-k, --kind <kind>', `Only symbols of this kind (${Object.keys(SYMBOL_KIND_FILTERS).join(', ')})`)
+    .option('-l, --limit <number>', 'Maximum number of results', '10')
+    .option('--json', 'Output as JSON')
+    .action(async (name: string, options) => {
+      try {
+        const repoRoot = await findRepoRoot();
+        if (!repoRoot) {
+          console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+          process.exit(1);
+        }
+
+        const chunks = await readIndexedCodeChunks(getIndexDir(repoRoot));
+        if (chunks.length === 0) {
+          console.error(chalk.yellow('No indexed code found in .cv/index. Run `cv sync` first.'));
+          process.exit(1);
+        }
+
+        const matches = lookupSymbols(chunks, name, {
+          kind: options.kind,
+          limit: parseInt(options.limit, 10)
+        });
+
+        if (options.json) {
+          console.log(JSON.stringify({ query: name, matches }, null, 2));
+          return;
+        }
+
+        if (matches.length === 0) {
+          console.log(chalk.yellow(`\nNo symbols matching "${name}"${options.kind ? ` of kind ${options.kind}` : ''}`));
+          console.log(chalk.gray('Symbols are recorded by `cv sync`; try `cv find` for a semantic search.\n'));
+          return;
+        }
+
+        console.log();
+        for (const match of matches) {
+          const label = match.match === 'exact' ? '' : chalk.gray(' (fuzzy)');
+          console.log(chalk.bold(match.name) + chalk.gray(` ${match.kind ?? 'symbol'}`) + label);
+          console.log(chalk.cyan(`  ${match.file}:${match.startLine}-${match.endLine}`));
+          if (match.signature) {
+            console.log(`  ${match.signature}`);
+          }
+          for (const line of match.snippet.split('\n')) {
+            console.log(chalk.gray('  │ ') + line);
+          }
+          console.log();
+        }
+      } catch (error: any) {
+        console.error(chalk.red(`Error: ${error.message}`));
+        process.exit(1);
+      }
+    });
+
+  return cmd;
+}
@@ -17,7 +17,36 @@ import {
   exportToStorage,
   generateRepoId,
   readManifest,
-  createCodebaseSummaryService
+  createCodebaseSummaryService,
+  isOllamaRunning,
+  VectorManager,
+  getIndexDir,
+  AzureOpenAIDeployment,
+  getEmbeddingCacheDir,
+  getVectorBackendOptions,
+  IndexWarning,
+  FileDecision,
+  DEFAULT_HUGGINGFACE_EMBEDDING_MODEL,
+  normalizeFileFilter,
+  isFileFilterEmpty,
+  buildWorkspaceIndex,
+  WorkspaceIndex,
+  checkIndexCompatibility,
+  writeIndexMetadata,
+  describeFileFilter,
+  DEFAULT_WATCH_DEBOUNCE_MS,
+  GitManager,
+  syncHistoryIndex,
+  readIndexMetadata,
+  SyncLimitCheck,
+  SyncLimitExceeded,
+  SyncLimitError,
+  estimateUsageCost,
+  formatUsageCost,
+  CodeParser,
+  SyncOptions,
+  SyncEstimate,
+  getLMStudioUrl
 } from '@cv-git/core';
 import {
   findRepoRoot,
@@ -27,15 +56,26 @@ import {
   CVWorkspace,
   WorkspaceRepo,
   getCVDir,
+  formatBytes,
 } from '@cv-git/shared';
 import * as fs from 'fs/promises';
 import * as path from 'path';
+import inquirer from 'inquirer';
 import { CredentialManager } from '@cv-git/credentials';
 import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { logProviderServed } from '../utils/providers.js';
 import { checkCredentials, displayCompactStatus } from '../utils/config-check.js';
-import { getAnthropicApiKey } from '../utils/credentials.js';
+import { getAnthropicApiKey, getStoredOllamaEndpoint, getAzureOpenAISettings, toAzureDeployment, getGeminiApiKey, getCohereApiKey, getVoyageApiKey, getHuggingFaceSettings, HuggingFaceSettings, getOpenAICompatibleSettings, OpenAICompatibleSettings, getEmbeddingCredentials } from '../utils/credentials.js';
 import { ensureFalkorDB, ensureQdrant, ensureOllama, isDockerAvailable } from '../utils/infrastructure.js';
 import { getPreferences } from '../config.js';
+import { findWorktreeMismatch, confirmWorktreeIndex } from '../utils/worktree.js';
+import { printProxyHint } from '../utils/network.js';
+import { addTimeoutOption, resolveEmbeddingTimeout, printTimeoutHint } from '../utils/timeout.js';
+import { abortOnInterrupt, isAbortError } from '../utils/interrupt.js';
+import { watchAndSync } from '../utils/sync-watch.js';
+
+/** Accumulate a repeatable option */
+const collect = (value: string, previous: string[] = []) => [...previous, value];
 
 export function syncCommand(): Command {
   const cmd = new Command('sync');
@@ -44,25 +84,66 @@ export function syncCommand(): Command {
     .description('Synchronize the knowledge graph with the repository')
     .option('--delta', 'Smart delta sync - only process changed files (default)')
     .option('--incremental', 'Only sync changed files (legacy)')
-    .option('--full', 'Force full sync of all files')
+    .option('--full', 'Force a complete reindex of all files (ignore the git delta)')
     .option('--force', 'Force full rebuild (clears graph first)')
     .option('--reset-delta', 'Reset delta tracking (forces full sync next time)')
+    .option('--include <glob>', 'Only index files matching this glob or directory (repeatable)', collect)
+    .option('--exclude <glob>', 'Do not index files matching this glob or directory (repeatable)', collect)
+    .option('--ext <list>', 'Only index files with these extensions, e.g. .go,.ts (repeatable)', collect)
     .option('--max-files <number>', 'Maximum number of files to process per run (for large repos)', parseInt)
+    .option('--limit-files <number>', 'Ask before embedding more files than this (default: sync.limits.maxFiles or 10000; 0 disables)', parseInt)
+    .option('--limit-bytes <number>', 'Ask before embedding more bytes than this in total (default: sync.limits.maxTotalBytes or 100MB; 0 disables)', parseInt)
+    .option('--limit-file-bytes <number>', 'Ask before embedding a file larger than this (default: sync.limits.maxBytes or 512KB; 0 disables)', parseInt)
+    .option('-y, --yes', 'Sync past the size limits without asking')
+    .option('--estimate', 'Chunk the files a full sync would embed and print the token count and cost, without calling any API')
     .option('--batch-size <number>', 'Batch size for embedding generation (default: 50)', parseInt)
+    .option('--dimensions <number>', 'Shorten vectors to this many dimensions, for models that support it (config embedding.outputDimensions)', parseInt)
+    .option('--concurrency <number>', 'Files read and parsed in parallel while embedding (default: 10)', parseInt)
     .option('--continue', 'Continue from where the last chunked sync left off')
+    .option('--resume', 'Resume an interrupted full sync from its checkpoint, skipping files already embedded (default)')
+    .option('--restart', 'Discard the checkpoint of an interrupted sync and embed everything again')
     .option('--no-embeddings', 'Skip vector embeddings (graph-only sync)')
     .option('--summaries', 'Generate hierarchical summaries for changed symbols (default: enabled)')
     .option('--no-summaries', 'Skip summary generation')
     .option('--summary-strategy <strategy>', 'Summary cost strategy: free, budget, quality (default: free)', 'free')
-    .option('--summary-budget <cents>', 'Maximum LLM budget in cents for summary generation (default: 5)', parseInt);
-
+    .option('--summary-budget <cents>', 'Maximum LLM budget in cents for summary generation (default: 5)', parseInt)
+    .option('--history', 'Also embed commit messages into the history index searched by `cv search --history` (default: sync.history.enabled)')
+    .option('--no-history', 'Do not embed commit history even when sync.history.enabled is set')
+    .option('--history-diffs', 'Embed a truncated diff with each commit message (config sync.history.diffs)')
+    .option('--history-limit <number>', 'Most commits to embed per sync (default: sync.history.maxCommits or 500)', parseInt)
+    .option('--watch', 'Keep running and re-index changed files as they are saved (Ctrl-C to stop)')
+    .option('--debounce <ms>', `Quiet time before a batch of changes is synced with --watch (default: ${DEFAULT_WATCH_DEBOUNCE_MS})`, parseInt);
+
+  addTimeoutOption(cmd, 'embedding');
   addGlobalOptions(cmd);
 
   cmd.action(async (options) => {
       const output = createOutput(options);
       let spinner: any;
+      // Ctrl-C cancels in-flight embedding requests and stops the sync between files
+      const interrupt = abortOnInterrupt();
 
       try {
+        if (options.concurrency !== undefined && !(options.concurrency >= 1)) {
+          throw new Error('--concurrency must be a positive integer');
+        }
+        if (options.debounce !== undefined && !(options.debounce >= 0)) {
+          throw new Error('--debounce must be a number of milliseconds');
+        }
+        if (options.historyLimit !== undefined && !(options.historyLimit >= 1)) {
+          throw new Error('--history-limit must be a positive integer');
+        }
+        for (const flag of ['limitFiles', 'limitBytes', 'limitFileBytes']) {
+          if (options[flag] !== undefined && !(options[flag] >= 0)) {
+            throw new Error(`--${flag.replace(/[A-Z]/g, c => `-${c.toLowerCase()}`)} must be a non-negative integer`);
+          }
+        }
+        const watchConflict = ['full', 'force', 'incremental', 'maxFiles', 'continue', 'resetDelta', 'history', 'estimate']
+          .find(flag => options[flag] !== undefined && options[flag] !== false);
+        if (options.watch && watchConflict) {
+          throw new Error(`--watch cannot be combined with --${watchConflict.replace(/[A-Z]/g, c => `-${c.toLowerCase()}`)}`);
+        }
+
         // Find repository root
         const repoRoot = await findRepoRoot();
         if (!repoRoot) {
@@ -70,6 +151,12 @@ export function syncCommand(): Command {
           process.exit(1);
         }
 
+        // A worktree nested in the indexed checkout would sync the outer tree instead
+        const mismatch = await findWorktreeMismatch(repoRoot);
+        if (mismatch && !(await confirmWorktreeIndex(mismatch, 'Sync'))) {
+          process.exit(0);
+        }
+
         // Check if this is a workspace
         const workspace = await loadWorkspace(repoRoot);
 
@@ -84,6 +171,18 @@ export function syncCommand(): Command {
           // Workspace mode - sync all repos
           console.log(chalk.cyan(`\nWorkspace: ${workspace.name}`));
           console.log(chalk.gray(`Repos: ${workspace.repos.map(r => r.name).join(', ')}\n`));
+          if (options.watch) {
+            throw new Error('--watch is not supported for workspaces; run it in one of the repos');
+          }
+          if (options.include || options.exclude || options.ext) {
+            output.warn('--include, --exclude and --ext are not supported for workspaces; syncing every repo in full');
+          }
+          if (options.estimate) {
+            throw new Error('--estimate is not supported for workspaces; run it in one of the repos');
+          }
+          if (options.history) {
+            output.warn('--history is not supported for workspaces; run it in one of the repos');
+          }
           await syncWorkspace(workspace, config, options, output);
           return;
         }
@@ -106,7 +205,38 @@ export function syncCommand(): Command {
         }
 
         // Parser
-        const parser = createParser();
+        const parser = createParser({ maxChunkLines: config.sync?.maxChunkLines, overlapLines: config.sync?.chunkOverlapLines });
+
+        // File selection options shared by every sync mode
+        // --verbose lists each skipped file (.gitignore, .cvignore, binary, too large, ...)
+        // and each piece of code too large for the embedding model
+        // --include/--exclude/--ext apply on top of the ignore files and config patterns
+        const fileOptions = {
+          fileFilter: normalizeFileFilter({ include: options.include, exclude: options.exclude, extensions: options.ext }),
+          maxFileSize: config.sync?.maxFileSize,
+          concurrency: options.concurrency,
+          restart: options.restart,
+          signal: interrupt.signal,
+          onFileSkipped: options.verbose
+            ? (file: string, reason: string) => console.log(chalk.gray(`  Skipped ${file}: ${reason}`))
+            : undefined,
+          // ...and why each file is re-embedded or left alone (content hash first, then mtime)
+          onFileDecision: options.verbose
+            ? (d: FileDecision) => console.log(chalk.gray(`  ${d.action === 'embed' ? 'Embed' : d.action === 'remove' ? 'Remove' : 'Skip'} ${d.file}: ${d.reason}`))
+            : undefined,
+          onChunkSkipped: options.verbose
+            ? (w: IndexWarning) => console.log(chalk.gray(
+              `  Skipped ${w.file}:${w.startLine}-${w.endLine}: ~${w.estimatedTokens} tokens, over the ${w.maxTokens}-token embedding limit`
+            ))
+            : undefined
+        };
+
+        // --estimate chunks and measures the files without starting or calling anything
+        if (options.estimate) {
+          spinner.stop();
+          await printSyncEstimate(repoRoot, repoId, git, parser, fileOptions, options, config, output);
+          return;
+        }
 
         // Graph manager - auto-start FalkorDB if configured for embedded mode
         spinner.text = 'Setting up FalkorDB...';
@@ -126,7 +256,7 @@ export function syncCommand(): Command {
 
         // Update config with the actual URL we're using
         if (graphUrl !== config.graph.url) {
-          await configManager.update({ graph: { ...config.graph, url: graphUrl } });
+          await configManager.update({ graph: { url: graphUrl } });
         }
 
         // Create graph manager with repo-specific database for isolation
@@ -139,6 +269,12 @@ export function syncCommand(): Command {
         let vector = undefined;
         let ollamaUrl: string | undefined;
         let lmstudioUrl: string | undefined;
+        let azureEmbedding: AzureOpenAIDeployment | undefined;
+        let geminiApiKey: string | undefined;
+        let cohereApiKey: string | undefined;
+        let voyageApiKey: string | undefined;
+        let huggingface: HuggingFaceSettings | null = null;
+        let gateway: OpenAICompatibleSettings | null = null;
         let openaiApiKey = config.ai.apiKey || process.env.OPENAI_API_KEY;
         let openrouterApiKey = process.env.OPENROUTER_API_KEY;
 
@@ -178,11 +314,17 @@ export function syncCommand(): Command {
           // Ollama for local embeddings (default)
           spinner = output.spinner('Setting up Ollama for embeddings...').start();
 
-          const ollamaInfo = await ensureOllama({
-            silent: true,
-            pullModel: true,
-            model: config.embedding?.model || 'nomic-embed-text',
-          });
+          // Prefer an endpoint configured with `cv auth setup ollama`
+          const storedEndpoint = await getStoredOllamaEndpoint();
+          const ollamaModel = storedEndpoint?.model || config.embedding?.model || 'nomic-embed-text';
+
+          const ollamaInfo = storedEndpoint && await isOllamaRunning(storedEndpoint.baseUrl)
+            ? { url: storedEndpoint.baseUrl, started: false, modelReady: true }
+            : await ensureOllama({
+                silent: true,
+                pullModel: true,
+                model: ollamaModel,
+              });
 
           if (ollamaInfo) {
             ollamaUrl = ollamaInfo.url;
@@ -201,7 +343,7 @@ export function syncCommand(): Command {
             // Set environment for VectorManager
             process.env.CV_EMBEDDING_PROVIDER = 'ollama';
             process.env.CV_OLLAMA_URL = ollamaUrl;
-            process.env.CV_EMBEDDING_MODEL = config.embedding?.model || 'nomic-embed-text';
+            process.env.CV_EMBEDDING_MODEL = ollamaModel;
           } else {
             spinner.warn('Ollama not available (Docker required)');
             // Fall back to cloud providers if Ollama unavailable
@@ -236,6 +378,64 @@ export function syncCommand(): Command {
               }
             }
           }
+        } else if (embeddingProvider === 'azure') {
+          // Azure OpenAI: embeddings go to the configured embedding deployment
+          const azureSettings = await getAzureOpenAISettings(config.azure);
+          if (azureSettings?.embeddingDeployment) {
+            azureEmbedding = toAzureDeployment(azureSettings, azureSettings.embeddingDeployment);
+            output.info(`Using Azure OpenAI deployment ${azureSettings.embeddingDeployment} at ${azureSettings.endpoint}`);
+          } else {
+            output.warn('Azure OpenAI embedding deployment not configured. Run: cv auth setup azure');
+          }
+        } else if (embeddingProvider === 'gemini') {
+          // Google Gemini: text-embedding-004 through batchEmbedContents
+          geminiApiKey = await getGeminiApiKey() || undefined;
+          if (geminiApiKey) {
+            output.info('Using Gemini embeddings (text-embedding-004)');
+          } else {
+            output.warn('Gemini API key not found. Run: cv auth setup gemini');
+          }
+        } else if (embeddingProvider === 'cohere') {
+          // Cohere: code is embedded as search_document, queries as search_query
+          cohereApiKey = await getCohereApiKey() || undefined;
+          if (cohereApiKey) {
+            output.info('Using Cohere embeddings (embed-english-v3.0)');
+          } else {
+            output.warn('Cohere API key not found. Run: cv auth setup cohere');
+          }
+        } else if (embeddingProvider === 'voyage') {
+          // Voyage AI: code is embedded as document, queries as query
+          voyageApiKey = await getVoyageApiKey() || undefined;
+          if (voyageApiKey) {
+            output.info('Using Voyage AI embeddings (voyage-code-3)');
+          } else {
+            output.warn('Voyage AI API key not found. Run: cv auth setup voyage');
+          }
+        } else if (embeddingProvider === 'huggingface') {
+          // HuggingFace: open models via the Inference API or a self-hosted TEI server
+          huggingface = await getHuggingFaceSettings();
+          if (huggingface) {
+            if (huggingface.model && !process.env.CV_EMBEDDING_MODEL) {
+              process.env.CV_EMBEDDING_MODEL = huggingface.model;
+            }
+            const model = process.env.CV_EMBEDDING_MODEL || DEFAULT_HUGGINGFACE_EMBEDDING_MODEL;
+            output.info(huggingface.baseUrl
+              ? `Using HuggingFace TEI embeddings at ${huggingface.baseUrl}`
+              : `Using HuggingFace embeddings (${model})`);
+          } else {
+            output.warn('HuggingFace token not found. Run: cv auth setup huggingface');
+          }
+        } else if (embeddingProvider === 'openai-compatible') {
+          // Any OpenAI-compatible gateway: the model name is sent as given
+          gateway = await getOpenAICompatibleSettings();
+          if (gateway) {
+            if (gateway.embeddingModel && !process.env.CV_EMBEDDING_MODEL) {
+              process.env.CV_EMBEDDING_MODEL = gateway.embeddingModel;
+            }
+            output.info(`Using OpenAI-compatible embeddings at ${gateway.baseUrl}`);
+          } else {
+            output.warn('OpenAI-compatible base URL not configured. Run: cv auth setup openai-compatible');
+          }
         } else if (embeddingProvider === 'openrouter' && openrouterApiKey) {
           // Use OpenRouter for embeddings
           if (!process.env.OPENROUTER_API_KEY) {
@@ -246,67 +446,101 @@ export function syncCommand(): Command {
           }
         }
 
-        // Set up Qdrant if we have any embedding capability
+        // Set up the vector store if we have any embedding capability
         const skipEmbeddings = options.embeddings === false;
-        const hasEmbeddingCapability = ollamaUrl || lmstudioUrl || openaiApiKey || openrouterApiKey;
+        const hasEmbeddingCapability = ollamaUrl || lmstudioUrl || azureEmbedding || geminiApiKey || cohereApiKey || voyageApiKey || huggingface || gateway || openaiApiKey || openrouterApiKey;
 
         if (skipEmbeddings) {
           output.info('Skipping vector embeddings (--no-embeddings)');
         } else if (hasEmbeddingCapability && config.vector) {
-          spinner = output.spinner('Setting up Qdrant...').start();
-
-          const qdrantInfo = await ensureQdrant({ silent: true });
+          const backendOptions = getVectorBackendOptions(config.vector);
+          const usePgvector = backendOptions.backend === 'pgvector';
+          const useLocalStore = backendOptions.backend === 'local';
+          const storeName = usePgvector ? 'pgvector' : useLocalStore ? 'local vector store' : 'Qdrant';
           let qdrantUrl = '';
 
-          if (qdrantInfo) {
-            qdrantUrl = qdrantInfo.url;
-            if (qdrantInfo.started) {
-              spinner.succeed(`Qdrant started on ${qdrantUrl}`);
-            } else {
-              spinner.succeed(`Using Qdrant at ${qdrantUrl}`);
-            }
+          if (usePgvector || useLocalStore) {
+            // Postgres is managed externally and the local store runs in-process; nothing to start
+            qdrantUrl = config.vector.url;
+          } else {
+            spinner = output.spinner('Setting up Qdrant...').start();
+            const qdrantInfo = await ensureQdrant({ silent: true });
+
+            if (qdrantInfo) {
+              qdrantUrl = qdrantInfo.url;
+              if (qdrantInfo.started) {
+                spinner.succeed(`Qdrant started on ${qdrantUrl}`);
+              } else {
+                spinner.succeed(`Using Qdrant at ${qdrantUrl}`);
+              }
 
-            // Update config with the actual URL we're using
-            if (qdrantUrl !== config.vector.url) {
-              await configManager.update({ vector: { ...config.vector, url: qdrantUrl } });
+              // Update config with the actual URL we're using
+              if (qdrantUrl !== config.vector.url) {
+                await configManager.update({ vector: { url: qdrantUrl } });
+              }
+            } else {
+              spinner.warn('Qdrant not available (Docker required)');
+              output.info('Continuing without vector search...');
             }
-          } else {
-            spinner.warn('Qdrant not available (Docker required)');
-            output.info('Continuing without vector search...');
           }
 
-          if (qdrantUrl) {
+          if (qdrantUrl || usePgvector || useLocalStore) {
             try {
-              spinner = output.spinner('Connecting to Qdrant...').start();
+              spinner = output.spinner(`Connecting to ${storeName}...`).start();
               // Create vector manager with repo-specific collections for isolation
               const useLocal = !!(ollamaUrl || lmstudioUrl);
               vector = createVectorManager({
                 url: qdrantUrl,
+                ...backendOptions,
                 repoId,
                 ollamaUrl,
                 lmstudioUrl,
+                azure: azureEmbedding,
+                geminiApiKey,
+                cohereApiKey,
+                voyageApiKey,
+                huggingfaceApiKey: huggingface?.apiKey,
+                huggingfaceUrl: huggingface?.baseUrl,
+                openaiCompatible: gateway ? { baseUrl: gateway.baseUrl, apiKey: gateway.apiKey } : undefined,
                 openrouterApiKey: useLocal ? undefined : openrouterApiKey,
                 openaiApiKey: useLocal ? undefined : openaiApiKey,
-                cacheDir: path.join(repoRoot, '.cv', 'embeddings'),
-                vectorSize: (embeddingProvider === 'ollama' || embeddingProvider === 'lmstudio') ? 768 : 1536,
+                cacheDir: getEmbeddingCacheDir(repoRoot),
+                cacheMaxSizeBytes: config.embedding?.cacheMaxBytes,
+                indexDir: getIndexDir(repoRoot),
+                // --force rebuilds at the configured size rather than the existing index's
+                embeddingDimensions: options.dimensions ?? config.embedding?.outputDimensions ?? (options.force ? 0 : undefined),
+                embeddingBatchSize: config.embedding?.batchSize,
+                embeddingBatchTokens: config.embedding?.maxBatchTokens,
+                embeddingConcurrency: config.embedding?.concurrency,
+                stripComments: config.sync?.stripComments,
+                maxRetryAttempts: config.embedding?.maxRetryAttempts,
+                embeddingTimeoutMs: resolveEmbeddingTimeout(config, options.timeout),
+                signal: interrupt.signal,
+                onRetry: options.verbose
+                  ? ({ attempt, maxAttempts, delayMs, error }) => console.log(chalk.gray(
+                      `  Embedding request failed (attempt ${attempt}/${maxAttempts}): ${error?.message}; retrying in ${(delayMs / 1000).toFixed(1)}s`
+                    ))
+                  : undefined,
+                embeddingFallbacks: config.embedding?.providers,
+                onEmbeddingServed: logProviderServed(options, 'Embedding'),
               });
               await vector.connect();
 
               // Log collection names being used
               const collections = vector.getCollectionNames();
-              output.debug(`Qdrant collections: ${collections.codeChunks}, ${collections.docstrings}, ${collections.commits}`);
+              output.debug(`${storeName} collections: ${collections.codeChunks}, ${collections.docstrings}, ${collections.commits}`);
 
               // Log cache status
               if (vector.isCacheEnabled()) {
                 const cacheStats = await vector.getCacheStats();
                 if (cacheStats && cacheStats.totalEntries > 0) {
-                  output.debug(`Embedding cache: ${cacheStats.totalEntries} entries, ${cacheStats.hitRate.toFixed(1)}% hit rate`);
+                  output.debug(`Embedding cache: ${cacheStats.totalEntries} entries, ${(cacheStats.hitRate * 100).toFixed(1)}% lifetime hit rate`);
                 }
               }
 
-              spinner.succeed(`Connected to Qdrant (collections: ${repoId}_*)`);
+              spinner.succeed(`Connected to ${storeName} (collections: ${repoId}_*)`);
             } catch (error: any) {
-              spinner.warn(`Could not connect to Qdrant: ${error.message}`);
+              spinner.warn(`Could not connect to ${storeName}: ${error.message}`);
               output.info('Continuing without vector search...');
               vector = undefined;
             }
@@ -329,6 +563,50 @@ export function syncCommand(): Command {
           return;
         }
 
+        if (!isFileFilterEmpty(fileOptions.fileFilter)) {
+          output.info(`Limiting sync to: ${describeFileFilter(fileOptions.fileFilter)}`);
+        }
+
+        // Full and delta syncs stop and ask before embedding more than the limits allow
+        const limitOptions = {
+          limits: {
+            maxFiles: options.limitFiles ?? config.sync?.limits?.maxFiles,
+            maxTotalBytes: options.limitBytes ?? config.sync?.limits?.maxTotalBytes,
+            maxBytes: options.limitFileBytes ?? config.sync?.limits?.maxBytes
+          },
+          confirmLimits: (check: SyncLimitCheck) => confirmSyncLimits(check, vector, options.yes)
+        };
+
+        if (options.watch) {
+          // The watcher handles Ctrl-C itself: stop watching, finish the batch in flight, then write the index
+          interrupt.dispose();
+          await watchAndSync(repoRoot, syncEngine, {
+            syncOptions: {
+              excludePatterns: config.sync?.excludePatterns?.length ? config.sync.excludePatterns : undefined,
+              includeLanguages: config.sync?.includeLanguages?.length ? config.sync.includeLanguages : undefined,
+              ...fileOptions,
+              // Summaries cost LLM calls and commit history only moves on commit; neither runs per save
+              generateSummaries: options.summaries === true,
+              syncCommits: false
+            },
+            debounceMs: options.debounce ?? DEFAULT_WATCH_DEBOUNCE_MS,
+            verbose: options.verbose
+          });
+
+          spinner = output.spinner('Writing the index to .cv/ storage...').start();
+          try {
+            const exportResult = await exportToStorage(repoRoot, graph, vector, getExportEmbeddingConfig(config, vector));
+            spinner.succeed(`Exported to .cv/: ${exportResult.stats.files} files, ${exportResult.stats.symbols} symbols`);
+          } catch (exportError: any) {
+            spinner.warn(`Export to .cv/ failed: ${exportError.message}`);
+            output.debug(exportError.stack);
+          }
+
+          await graph.close();
+          if (vector) await vector.close();
+          return;
+        }
+
         // Handle chunked sync (for large repositories)
         if (options.maxFiles || options.continue) {
           const chunkedOptions = {
@@ -336,7 +614,8 @@ export function syncCommand(): Command {
             batchSize: options.batchSize || 50,
             continueFromLast: options.continue,
             excludePatterns: config.sync?.excludePatterns,
-            includeLanguages: config.sync?.includeLanguages
+            includeLanguages: config.sync?.includeLanguages,
+            ...fileOptions
           };
 
           // Check for existing progress if --continue
@@ -364,16 +643,15 @@ export function syncCommand(): Command {
           }
 
           displaySyncResults(result.syncState);
+          await reportEmbeddingCacheHits(vector, output);
 
           // Export to .cv/ if complete
           if (result.progress.complete) {
+            await syncHistory(repoRoot, git, vector, options, config, output);
+
             spinner = output.spinner('Exporting to .cv/ storage...').start();
             try {
-              const embeddingConfig = config.embedding ? {
-                provider: config.embedding.provider || 'openrouter',
-                model: config.embedding.model || 'openai/text-embedding-3-small',
-                dimensions: 1536
-              } : undefined;
+              const embeddingConfig = getExportEmbeddingConfig(config, vector);
 
               const exportResult = await exportToStorage(repoRoot, graph, vector, embeddingConfig);
               spinner.succeed(
@@ -410,7 +688,9 @@ export function syncCommand(): Command {
 
             const syncState = await syncEngine.deltaSync({
               excludePatterns: config.sync.excludePatterns,
-              includeLanguages: config.sync.includeLanguages
+              includeLanguages: config.sync.includeLanguages,
+              ...fileOptions,
+              ...limitOptions
             });
 
             console.log();
@@ -422,6 +702,8 @@ export function syncCommand(): Command {
 
             const graphStats = await graph.getStats();
             displaySyncResults(syncState, graphStats);
+            await reportEmbeddingCacheHits(vector, output);
+            await syncHistory(repoRoot, git, vector, options, config, output);
             await graph.close();
             if (vector) await vector.close();
             return;
@@ -435,7 +717,9 @@ export function syncCommand(): Command {
 
           const syncState = await syncEngine.deltaSync({
             excludePatterns: config.sync?.excludePatterns?.length ? config.sync.excludePatterns : undefined,
-            includeLanguages: config.sync?.includeLanguages?.length ? config.sync.includeLanguages : undefined
+            includeLanguages: config.sync?.includeLanguages?.length ? config.sync.includeLanguages : undefined,
+            ...fileOptions,
+            ...limitOptions
           });
 
           console.log();
@@ -450,6 +734,8 @@ export function syncCommand(): Command {
 
           const graphStats = await graph.getStats();
           displayDeltaSyncResults(syncState, graphStats);
+          await reportEmbeddingCacheHits(vector, output);
+          await syncHistory(repoRoot, git, vector, options, config, output);
 
           // Export to .cv/ if anything changed
           if (syncState.delta.added.length > 0 ||
@@ -457,11 +743,7 @@ export function syncCommand(): Command {
               syncState.delta.deleted.length > 0) {
             spinner = output.spinner('Exporting to .cv/ storage...').start();
             try {
-              const embeddingConfig = config.embedding ? {
-                provider: config.embedding.provider || 'openrouter',
-                model: config.embedding.model || 'openai/text-embedding-3-small',
-                dimensions: 1536
-              } : undefined;
+              const embeddingConfig = getExportEmbeddingConfig(config, vector);
 
               const exportResult = await exportToStorage(repoRoot, graph, vector, embeddingConfig);
               spinner.succeed(
@@ -488,6 +770,10 @@ export function syncCommand(): Command {
         if (forceFullSync) {
           spinner.text = 'Clearing existing graph...';
           await graph.clear();
+
+          // Rebuild vectors too - the embedding provider may have changed
+          spinner.text = 'Clearing existing vector index...';
+          await syncEngine.resetVectorIndex();
         }
 
         spinner.stop(); // Stop spinner so sync engine can log progress
@@ -496,7 +782,9 @@ export function syncCommand(): Command {
         // undefined means "use defaults", empty array means "exclude nothing"
         const syncState = await syncEngine.fullSync({
           excludePatterns: config.sync?.excludePatterns?.length ? config.sync.excludePatterns : undefined,
-          includeLanguages: config.sync?.includeLanguages?.length ? config.sync.includeLanguages : undefined
+          includeLanguages: config.sync?.includeLanguages?.length ? config.sync.includeLanguages : undefined,
+          ...fileOptions,
+          ...limitOptions
         });
 
         console.log(); // Newline after sync logs
@@ -504,16 +792,14 @@ export function syncCommand(): Command {
 
         const graphStats = await graph.getStats();
         displaySyncResults(syncState, graphStats);
+        await reportEmbeddingCacheHits(vector, output);
+        await syncHistory(repoRoot, git, vector, options, config, output);
 
         // Export to .cv/ files for portability
         console.log();
         spinner = output.spinner('Exporting to .cv/ storage...').start();
         try {
-          const embeddingConfig = config.embedding ? {
-            provider: config.embedding.provider || 'openrouter',
-            model: config.embedding.model || 'openai/text-embedding-3-small',
-            dimensions: 1536
-          } : undefined;
+          const embeddingConfig = getExportEmbeddingConfig(config, vector);
 
           const exportResult = await exportToStorage(repoRoot, graph, vector, embeddingConfig);
           spinner.succeed(
@@ -548,6 +834,19 @@ export function syncCommand(): Command {
         }
 
       } catch (error: any) {
+        if (interrupt.signal.aborted || isAbortError(error)) {
+          spinner?.stop();
+          console.error(chalk.yellow('\n✖ Sync cancelled'));
+          console.error(chalk.gray('  Run `cv sync` again to finish; a full sync resumes from its checkpoint'));
+          process.exit(130);
+        }
+
+        if (error instanceof SyncLimitError) {
+          console.error(chalk.yellow('✖ Sync cancelled: nothing was embedded'));
+          console.error(chalk.gray('  Narrow it with --include/--exclude/--ext or .cvignore, raise sync.limits, or pass --yes'));
+          process.exit(1);
+        }
+
         if (spinner) {
           spinner.fail(chalk.red('Sync failed'));
         } else {
@@ -555,6 +854,8 @@ export function syncCommand(): Command {
         }
 
         console.error(chalk.red(`Error: ${error.message}`));
+        printProxyHint(error);
+        printTimeoutHint(error, 'embedding.timeout');
 
         if (error.stack && process.env.CV_DEBUG) {
           console.error(chalk.gray(error.stack));
@@ -580,6 +881,219 @@ export function syncCommand(): Command {
   return cmd;
 }
 
+/**
+ * Show what a sync over its limits would embed, with a rough cost, and ask
+ * whether to go on. --yes goes on; without a terminal to ask, it stops.
+ */
+async function confirmSyncLimits(check: SyncLimitCheck, vector: VectorManager | undefined, yes: boolean): Promise<boolean> {
+  const { plan } = check;
+  console.log();
+  console.log(chalk.yellow('⚠ This sync is over its size limits:'));
+  for (const exceeded of check.exceeded) {
+    console.log(chalk.yellow(`  ${describeSyncLimit(exceeded)}`));
+  }
+
+  let projection = `${plan.files} files, ${formatBytes(plan.totalBytes)}: about ${plan.estimatedEmbeddings.toLocaleString()} embeddings, ~${plan.estimatedTokens.toLocaleString()} tokens`;
+  const embedding = vector?.getEmbeddingInfo();
+  const cost = embedding ? estimateUsageCost(embedding.provider, embedding.model, plan.estimatedTokens) : null;
+  if (embedding && cost !== null) {
+    projection += cost === 0 ? ` (free with ${embedding.provider})` : ` (~${formatUsageCost(cost)} with ${embedding.model})`;
+  }
+  console.log(`  ${projection}`);
+  console.log(chalk.gray(`  Largest: ${plan.largestFiles.map(f => `${f.file} (${formatBytes(f.bytes)})`).join(', ')}`));
+
+  if (yes) {
+    return true;
+  }
+  if (!process.stdin.isTTY) {
+    console.log(chalk.gray('  Not a terminal, so not asking: pass --yes to sync anyway'));
+    return false;
+  }
+
+  const { proceed } = await inquirer.prompt([{
+    type: 'confirm',
+    name: 'proceed',
+    message: 'Sync anyway?',
+    default: false
+  }]);
+  return proceed;
+}
+
+function describeSyncLimit(exceeded: SyncLimitExceeded): string {
+  switch (exceeded.limit) {
+    case 'maxFiles':
+      return `${exceeded.actual} files to embed (limit ${exceeded.value}, sync.limits.maxFiles or --limit-files)`;
+    case 'maxTotalBytes':
+      return `${formatBytes(exceeded.actual)} to embed (limit ${formatBytes(exceeded.value)}, sync.limits.maxTotalBytes or --limit-bytes)`;
+    case 'maxBytes': {
+      const files = exceeded.files || [];
+      const shown = files.slice(0, 3).join(', ') + (files.length > 3 ? `, +${files.length - 3} more` : '');
+      return `${files.length} file${files.length === 1 ? '' : 's'} over ${formatBytes(exceeded.value)} (sync.limits.maxBytes or --limit-file-bytes): ${shown}`;
+    }
+  }
+}
+
+/**
+ * cv sync --estimate: chunk the files a full sync would embed and print the
+ * chunk and token counts, with the cost at the configured embedding
+ * provider's pricing. The vector manager is only created to measure chunks
+ * as it would prepare them; nothing is started, connected to or called.
+ */
+async function printSyncEstimate(
+  repoRoot: string,
+  repoId: string,
+  git: GitManager,
+  parser: CodeParser,
+  fileOptions: SyncOptions,
+  options: any,
+  config: any,
+  output: any
+): Promise<void> {
+  const prefs = await getPreferences().load();
+  const provider = config.embedding?.provider || prefs.embeddingProvider || 'ollama';
+
+  let vector: VectorManager | undefined;
+  try {
+    // LM Studio is found at its URL; every other provider by its stored credentials
+    const embeddingCreds = provider === 'lmstudio' ? undefined : await getEmbeddingCredentials({
+      provider,
+      ollamaUrl: config.embedding?.url,
+      ollamaModel: config.embedding?.model,
+      azure: config.azure
+    });
+    vector = createVectorManager({
+      url: '',
+      backend: 'memory',
+      repoId,
+      ollamaUrl: embeddingCreds?.ollamaUrl,
+      lmstudioUrl: provider === 'lmstudio' ? getLMStudioUrl() : undefined,
+      azure: embeddingCreds?.azure,
+      geminiApiKey: embeddingCreds?.geminiApiKey,
+      cohereApiKey: embeddingCreds?.cohereApiKey,
+      voyageApiKey: embeddingCreds?.voyageApiKey,
+      huggingfaceApiKey: embeddingCreds?.huggingfaceApiKey,
+      huggingfaceUrl: embeddingCreds?.huggingfaceUrl,
+      openaiCompatible: embeddingCreds?.openaiCompatible,
+      openrouterApiKey: embeddingCreds?.openrouterApiKey,
+      openaiApiKey: embeddingCreds?.openaiApiKey,
+      embeddingModel: embeddingCreds?.ollamaModel || embeddingCreds?.huggingfaceModel || embeddingCreds?.openaiCompatibleModel || config.embedding?.model,
+      stripComments: config.sync?.stripComments
+    });
+  } catch (error: any) {
+    output.warn(`${error.message}; estimating tokens without a price`);
+  }
+
+  const spinner = output.spinner('Chunking files...').start();
+  const syncEngine = createSyncEngine(repoRoot, git, parser, createGraphManager({ url: config.graph.url, repoId }), vector);
+  let estimate: SyncEstimate;
+  try {
+    estimate = await syncEngine.estimateSync({
+      excludePatterns: config.sync?.excludePatterns?.length ? config.sync.excludePatterns : undefined,
+      includeLanguages: config.sync?.includeLanguages?.length ? config.sync.includeLanguages : undefined,
+      ...fileOptions
+    });
+  } finally {
+    spinner.stop();
+  }
+
+  const embedding = vector?.getEmbeddingInfo();
+  console.log();
+  console.log(chalk.bold(`Full sync estimate${embedding ? ` (${embedding.provider} / ${embedding.model})` : ''}:`));
+  if (!isFileFilterEmpty(fileOptions.fileFilter)) {
+    console.log(chalk.gray(`  Limited to: ${describeFileFilter(fileOptions.fileFilter)}`));
+  }
+  console.log(`  Files:   ${estimate.files.toLocaleString()} (${formatBytes(estimate.totalBytes)})`);
+  console.log(`  Chunks:  ${estimate.chunks.toLocaleString()}`);
+  console.log(`  Tokens:  ~${estimate.tokens.toLocaleString()}`);
+
+  const cost = embedding ? estimateUsageCost(embedding.provider, embedding.model, estimate.tokens) : null;
+  if (embedding && cost !== null) {
+    console.log(`  Cost:    ${cost === 0 ? `free with ${embedding.provider}` : `~${formatUsageCost(cost)}`}`);
+  } else if (embedding) {
+    console.log(`  Cost:    unknown (no pricing for ${embedding.model})`);
+  }
+
+  if (estimate.largestFiles.length > 0) {
+    console.log('  Largest:');
+    for (const file of estimate.largestFiles) {
+      console.log(chalk.gray(`    ${file.file}  ${formatBytes(file.bytes)}, ${file.chunks} chunk${file.chunks === 1 ? '' : 's'}, ~${file.tokens.toLocaleString()} tokens`));
+    }
+  }
+  if (estimate.skippedChunks > 0) {
+    console.log(chalk.yellow(`  ${estimate.skippedChunks} piece(s) of code are over the embedding model's token limit and would be skipped`));
+  }
+  if (estimate.failed.length > 0) {
+    console.log(chalk.yellow(`  ${estimate.failed.length} file(s) could not be parsed${options.verbose ? ':' : ' (--verbose lists them)'}`));
+    if (options.verbose) {
+      for (const failed of estimate.failed) {
+        console.log(chalk.gray(`    ${failed.file}: ${failed.reason}`));
+      }
+    }
+  }
+
+  console.log();
+  console.log(chalk.gray('  Nothing was embedded. Delta syncs only embed changed files, and cached embeddings are reused.'));
+}
+
+/**
+ * Embed new commits into the history index when --history or
+ * sync.history.enabled asks for it, then save the index again
+ */
+async function syncHistory(
+  repoRoot: string,
+  git: GitManager,
+  vector: VectorManager | undefined,
+  options: any,
+  config: any,
+  output: any
+): Promise<void> {
+  const history = config.sync?.history;
+  if (!(options.history ?? history?.enabled ?? false)) return;
+  if (!vector) {
+    output.warn('Skipping commit history: no embedding provider');
+    return;
+  }
+
+  const spinner = output.spinner('Embedding commit history...').start();
+  try {
+    const result = await syncHistoryIndex(repoRoot, git, vector, {
+      maxCommits: options.historyLimit ?? history?.maxCommits,
+      diffs: options.historyDiffs ?? history?.diffs,
+      onProgress: (indexed, total) => { spinner.text = `Embedding commit history (${indexed}/${total})...`; }
+    });
+
+    if (result.indexed > 0 || result.rebuilt) {
+      const metadata = await readIndexMetadata(repoRoot);
+      await vector.saveIndex(getIndexDir(repoRoot), metadata?.lastIndexedCommit);
+    }
+    spinner.succeed(result.indexed > 0
+      ? `History index: embedded ${result.indexed} commit${result.indexed === 1 ? '' : 's'} (${result.total} in total)`
+      : `History index up to date (${result.total} commits)`);
+  } catch (error: any) {
+    if (isAbortError(error)) throw error;
+    spinner.warn(`Commit history indexing failed: ${error.message}`);
+    output.debug(error.stack);
+  }
+}
+
+/**
+ * Embedding config recorded in the .cv/ manifest
+ * Uses the live vector manager when available so detected dimensions are exact
+ */
+function getExportEmbeddingConfig(
+  config: any,
+  vector?: VectorManager
+): { provider: string; model: string; dimensions: number } | undefined {
+  if (vector) {
+    return vector.getEmbeddingInfo();
+  }
+  return config.embedding ? {
+    provider: config.embedding.provider || 'openrouter',
+    model: config.embedding.model || 'openai/text-embedding-3-small',
+    dimensions: config.embedding.dimensions || 1536
+  } : undefined;
+}
+
 /**
  * Sync all repos in a workspace
  */
@@ -599,7 +1113,7 @@ async function syncWorkspace(
   }
   spinner.succeed(`Using FalkorDB at ${falkorInfo.url}`);
 
-  // Set up Qdrant if we have API keys
+  // Embeddings need an OpenAI or OpenRouter key
   let openaiApiKey = config.ai?.apiKey || process.env.OPENAI_API_KEY;
   let openrouterApiKey = process.env.OPENROUTER_API_KEY;
 
@@ -616,13 +1130,20 @@ async function syncWorkspace(
     // Credential manager not available
   }
 
-  let qdrantUrl: string | null = null;
-  if (openaiApiKey || openrouterApiKey) {
-    const qdrantInfo = await ensureQdrant({ silent: true });
-    if (qdrantInfo) {
-      qdrantUrl = qdrantInfo.url;
-      spinner = output.spinner(`Using Qdrant at ${qdrantUrl}`).start();
-      spinner.succeed(`Using Qdrant at ${qdrantUrl}`);
+  // Each repo keeps its own index; the workspace index is assembled from them
+  const backendOptions = getVectorBackendOptions(config.vector);
+  const useQdrant = !backendOptions.backend;
+  let vectorUrl: string | null = null;
+  if ((openaiApiKey || openrouterApiKey) && options.embeddings !== false) {
+    if (!useQdrant) {
+      vectorUrl = config.vector?.url || '';
+    } else {
+      const qdrantInfo = await ensureQdrant({ silent: true });
+      if (qdrantInfo) {
+        vectorUrl = qdrantInfo.url;
+        spinner = output.spinner(`Using Qdrant at ${vectorUrl}`).start();
+        spinner.succeed(`Using Qdrant at ${vectorUrl}`);
+      }
     }
   }
 
@@ -632,22 +1153,23 @@ async function syncWorkspace(
   await graph.connect();
   spinner.succeed(`Connected to graph: ${workspace.graphDatabase}`);
 
-  // Set up vector if available
-  let vector: any = null;
-  if (qdrantUrl && (openaiApiKey || openrouterApiKey)) {
-    try {
-      vector = createVectorManager({
-        url: qdrantUrl,
-        openrouterApiKey,
-        openaiApiKey,
-        collections: config.vector?.collections || { codeChunks: 'code_chunks', docstrings: 'docstrings', commits: 'commits' },
-        cacheDir: path.join(workspace.root, '.cv', 'embeddings')  // Content-addressed cache
-      });
-      await vector.connect();
-    } catch {
-      output.warn('Vector DB not available, continuing without embeddings');
-    }
-  }
+  const createWorkspaceVector = (root: string, repoId: string, member = true) => createVectorManager({
+    url: vectorUrl!,
+    ...backendOptions,
+    // A configured Qdrant collection name is the workspace's; the repos would all share it
+    qdrant: member && backendOptions.qdrant ? { apiKey: backendOptions.qdrant.apiKey } : backendOptions.qdrant,
+    repoId,
+    openrouterApiKey,
+    openaiApiKey,
+    cacheDir: getEmbeddingCacheDir(workspace.root),  // Content-addressed cache, shared by the repos
+    cacheMaxSizeBytes: config.embedding?.cacheMaxBytes,
+    indexDir: getIndexDir(root),
+    embeddingDimensions: options.dimensions ?? config.embedding?.outputDimensions ?? (options.force ? 0 : undefined),
+    embeddingTimeoutMs: resolveEmbeddingTimeout(config, options.timeout),
+    stripComments: config.sync?.stripComments,
+    embeddingFallbacks: config.embedding?.providers,
+    onEmbeddingServed: logProviderServed(options, 'Embedding')
+  });
 
   // Track overall stats
   const overallStats = {
@@ -670,7 +1192,7 @@ async function syncWorkspace(
         repo,
         workspace,
         graph,
-        vector,
+        vectorUrl !== null ? createWorkspaceVector : null,
         config,
         options,
         output
@@ -697,10 +1219,26 @@ async function syncWorkspace(
   workspace.lastSyncedAt = new Date().toISOString();
   await saveWorkspace(workspace);
 
-  // Close connections
   await graph.close();
-  if (vector) {
-    await vector.close();
+
+  let workspaceIndex: WorkspaceIndex | undefined;
+  if (vectorUrl !== null) {
+    spinner = output.spinner('Building workspace index...').start();
+    let vector: VectorManager | undefined;
+    try {
+      const manifest = await readManifest(getCVDir(workspace.root));
+      vector = createWorkspaceVector(workspace.root, manifest?.repository?.id || generateRepoId(workspace.root), false);
+      await vector.connect();
+      workspaceIndex = await loadWorkspaceIndex(workspace, vector);
+      spinner.succeed(`Workspace index: ${workspaceIndex.repos.length} repo(s) in ${vector.getCollectionNames().codeChunks}`);
+      for (const skipped of workspaceIndex.skipped) {
+        output.warn(`${skipped.name} left out of the workspace index: ${skipped.reason}`);
+      }
+    } catch (error: any) {
+      spinner.warn(`Could not build the workspace index: ${error.message}`);
+    } finally {
+      await vector?.close();
+    }
   }
 
   // Display results
@@ -714,6 +1252,12 @@ async function syncWorkspace(
   if (overallStats.totalVectors > 0) {
     console.log(chalk.cyan('  Vectors stored:    '), overallStats.totalVectors);
   }
+  if (workspaceIndex) {
+    console.log(chalk.cyan('  Indexed commits:   '));
+    for (const repo of workspaceIndex.repos) {
+      console.log(`    ${repo.name.padEnd(20)} ${repo.commit ? repo.commit.slice(0, 7) : chalk.gray('unknown')}  ${chalk.gray(`${repo.chunks} chunks`)}`);
+    }
+  }
   console.log(chalk.gray('─'.repeat(50)));
 
   if (overallStats.errors.length > 0) {
@@ -723,10 +1267,47 @@ async function syncWorkspace(
   console.log();
   console.log(chalk.bold('Next steps:'));
   console.log(chalk.gray('  • Use AI code assistant:'), chalk.cyan('cv code'));
-  console.log(chalk.gray('  • Search across repos:  '), chalk.cyan('cv find "authentication"'));
+  console.log(chalk.gray('  • Search across repos:  '), chalk.cyan('cv search "authentication"'));
+  console.log(chalk.gray('  • Ask across repos:     '), chalk.cyan('cv explain "how do the services authenticate?"'));
   console.log();
 }
 
+/**
+ * Replace the workspace collections with the repos' indexes and persist
+ * them, with each repo's indexed commit in the index metadata
+ */
+async function loadWorkspaceIndex(workspace: CVWorkspace, vector: VectorManager): Promise<WorkspaceIndex> {
+  const names = vector.getCollectionNames();
+  const index = await buildWorkspaceIndex(
+    workspace.repos.map(repo => ({ name: repo.name, path: repo.path, root: repo.absolutePath })),
+    { code_chunks: names.codeChunks, docstrings: names.docstrings, document_chunks: names.documentChunks }
+  );
+
+  const compatibility = checkIndexCompatibility(index.fingerprint, vector.getEmbeddingInfo());
+  if (!compatibility.compatible) {
+    throw new Error(`the repos were embedded with ${index.fingerprint.provider} / ${index.fingerprint.model}, ` +
+      `the workspace is configured for ${vector.getEmbeddingInfo().provider} / ${vector.getEmbeddingInfo().model}`);
+  }
+
+  for (const collection of [names.codeChunks, names.docstrings, names.documentChunks]) {
+    await vector.clearCollection(collection);
+    await vector.upsertBatch(collection, index.collections.get(collection) || []);
+  }
+  await vector.saveIndex();
+  await writeIndexMetadata(
+    workspace.root,
+    index.fingerprint,
+    undefined,
+    undefined,
+    { warnings: index.warnings },
+    undefined,
+    undefined,
+    index.repos
+  );
+
+  return index;
+}
+
 /**
  * Sync a single repo within a workspace context
  */
@@ -734,7 +1315,7 @@ async function syncSingleRepoInWorkspace(
   repo: WorkspaceRepo,
   workspace: CVWorkspace,
   graph: any,
-  vector: any,
+  createVector: ((root: string, repoId: string) => VectorManager) | null,
   config: any,
   options: any,
   output: any
@@ -758,18 +1339,32 @@ async function syncSingleRepoInWorkspace(
   const lastCommit = commits.length > 0 ? commits[0].sha : undefined;
 
   // Create parser
-  const parser = createParser();
+  const parser = createParser({ maxChunkLines: config.sync?.maxChunkLines, overlapLines: config.sync?.chunkOverlapLines });
+
+  // The repo is indexed into its own collections and .cv/index, at its own HEAD
+  let vector: VectorManager | undefined;
+  if (createVector) {
+    const manifest = await readManifest(getCVDir(repoPath));
+    vector = createVector(repoPath, manifest?.repository?.id || generateRepoId(repoPath));
+    try {
+      await vector.connect();
+    } catch (error: any) {
+      output.warn(`${repo.name}: vector store not available (${error.message}), continuing without embeddings`);
+      vector = undefined;
+    }
+  }
 
-  // Create sync engine with repo prefix for file paths
   const syncEngine = createSyncEngine(repoPath, git, parser, graph, vector);
 
-  // Run sync with repo name prefix
-  const syncState = await syncEngine.fullSync({
-    excludePatterns: config.sync?.excludePatterns || [],
-    includeLanguages: config.sync?.includeLanguages || [],
-    // The sync engine will need to prefix paths with repo name
-    // For now, we'll use the standard sync
-  });
+  let syncState;
+  try {
+    syncState = await syncEngine.fullSync({
+      excludePatterns: config.sync?.excludePatterns || [],
+      includeLanguages: config.sync?.includeLanguages || [],
+    });
+  } finally {
+    await vector?.close();
+  }
 
   return {
     fileCount: syncState.fileCount,
@@ -780,6 +1375,22 @@ async function syncSingleRepoInWorkspace(
   };
 }
 
+/**
+ * Report how many embeddings this run reused from the cache (--verbose)
+ */
+async function reportEmbeddingCacheHits(vector: VectorManager | undefined, output: any): Promise<void> {
+  if (!output.isVerbose || !vector?.isCacheEnabled()) return;
+
+  const stats = await vector.getCacheStats();
+  const lookups = stats ? stats.sessionHits + stats.sessionMisses : 0;
+  if (!stats || lookups === 0) return;
+
+  output.debug(
+    `Embedding cache: ${stats.sessionHits}/${lookups} hits (${(stats.sessionHitRate * 100).toFixed(1)}%), ` +
+    `${stats.totalEntries} entries, ${formatBytes(stats.totalSizeBytes)} of ${formatBytes(stats.maxSizeBytes)}`
+  );
+}
+
 function displaySyncResults(syncState: any, graphStats?: { fileCount: number; symbolCount: number }): void {
   console.log();
   console.log(chalk.bold('Sync Results:'));
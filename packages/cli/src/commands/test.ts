@@ -0,0 +1,299 @@
+/**
+ * cv test command
+ * Generate unit tests for a function using its definition and callers as context
+ *
+ * The symbol is looked up in the knowledge graph (falling back to semantic search),
+ * and the test file follows the framework and layout of the repo's existing tests.
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import ora from 'ora';
+import * as fs from 'fs/promises';
+import * as path from 'path';
+import {
+  configManager,
+  createAIManager,
+  createVectorManager,
+  createGraphManager,
+  createGitManager,
+  readManifest,
+  generateRepoId,
+  getIndexDir,
+  getVectorBackendOptions,
+  detectTestConventions,
+  extractCodeBlock,
+  isTestFile,
+  GraphManager,
+  VectorManager,
+  TestGenerationContext,
+  AIManagerFallback
+} from '@cv-git/core';
+import { findRepoRoot, getCVDir, detectLanguage, SymbolNode } from '@cv-git/shared';
+import { addGlobalOptions } from '../utils/output.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { resolveChatTimeout } from '../utils/timeout.js';
+import { getEmbeddingCredentials, embeddingVectorOptions } from '../utils/credentials.js';
+
+/** Callers included as usage examples */
+const MAX_CALLERS = 5;
+/** Lines of each caller shown to the model */
+const CALLER_EXCERPT_LINES = 30;
+/** Existing test files sampled for framework detection */
+const TEST_SAMPLE_SIZE = 5;
+/** Lines of the example test shown to the model */
+const EXAMPLE_TEST_LINES = 80;
+
+const TESTABLE_KINDS = new Set(['function', 'method']);
+
+export function testCommand(): Command {
+  const cmd = new Command('test');
+
+  cmd
+    .description('Generate unit tests for a function or method')
+    .argument('<symbol>', 'Function or method name (or qualified name)')
+    .option('--file <path>', 'File that defines the symbol (to pick between candidates)')
+    .option('--write', 'Write the test file instead of printing it')
+    .option('--force', 'Overwrite an existing test file (with --write)')
+    .option('--no-redact', 'Send code without masking secrets');
+
+  addModelOption(cmd, 'test');
+  addGlobalOptions(cmd);
+
+  cmd.action(async (symbolName: string, options) => {
+    const spinner = ora('Initializing...').start();
+    let graph: GraphManager | undefined;
+    let vector: VectorManager | undefined;
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        spinner.fail(chalk.red('Not in a CV-Git repository'));
+        console.error(chalk.gray('Run `cv init` first'));
+        process.exit(1);
+      }
+
+      const config = await configManager.load(repoRoot);
+
+      const model = resolveModel('test', options.model, config);
+
+      // Anthropic, an Azure chat deployment, Gemini or an OpenAI-compatible gateway
+      let backend: AIManagerFallback;
+      try {
+        backend = await resolveChatBackend(config);
+      } catch (error: any) {
+        spinner.fail(chalk.red(error.message));
+        process.exit(1);
+      }
+
+      const manifest = await readManifest(getCVDir(repoRoot));
+      const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+
+      spinner.text = 'Connecting to services...';
+      graph = createGraphManager({ url: config.graph.url, repoId });
+      await graph.connect();
+
+      spinner.text = `Looking up ${symbolName}...`;
+      let candidates = await findSymbolCandidates(graph, symbolName);
+
+      // Not in the graph by that name: try semantic search over the index
+      if (candidates.length === 0 && config.vector) {
+        try {
+          const embeddingCreds = await getEmbeddingCredentials({
+            provider: config.embedding?.provider,
+            ollamaUrl: config.embedding?.url,
+            ollamaModel: config.embedding?.model,
+            azure: config.azure
+          });
+          vector = createVectorManager({
+            url: config.vector.url,
+            ...getVectorBackendOptions(config.vector),
+            repoId,
+            ...embeddingVectorOptions(embeddingCreds, config),
+            indexDir: getIndexDir(repoRoot),
+            onEmbeddingServed: logProviderServed(options, 'Embedding')
+          });
+          await vector.connect();
+          candidates = await findSymbolsBySearch(graph, vector, symbolName);
+        } catch {
+          // No vector search available; fall through to "not found"
+        }
+      }
+
+      if (options.file) {
+        const file = path.relative(repoRoot, path.resolve(options.file)).split(path.sep).join('/');
+        candidates = candidates.filter(c => c.file === file);
+      }
+
+      if (candidates.length === 0) {
+        spinner.fail(chalk.red(`No function or method named ${symbolName} found`));
+        console.error(chalk.gray('  Make sure you have run `cv sync`, or check the name with `cv find`'));
+        process.exitCode = 1;
+        return;
+      }
+
+      if (candidates.length > 1) {
+        spinner.warn(chalk.yellow(`${symbolName} is ambiguous (${candidates.length} candidates):`));
+        for (const candidate of candidates) {
+          console.log(`  ${chalk.cyan(candidate.qualifiedName)} ${chalk.gray(`${candidate.file}:${candidate.startLine}`)}`);
+        }
+        console.log(chalk.gray('\nPass the qualified name or --file <path> to choose one.'));
+        process.exitCode = 1;
+        return;
+      }
+
+      const symbol = candidates[0];
+      spinner.text = 'Gathering context...';
+      const context = await buildTestContext(repoRoot, symbol, graph);
+
+      const testPath = path.join(repoRoot, context.conventions.testFile);
+      if (options.write && !options.force && await fileExists(testPath)) {
+        spinner.fail(chalk.red(`${context.conventions.testFile} already exists`));
+        console.error(chalk.gray('  Use --force to overwrite it, or omit --write to print the tests'));
+        process.exitCode = 1;
+        return;
+      }
+
+      const ai = createAIManager(
+        {
+          ...chatBackendOptions(backend, model, config),
+          timeoutMs: resolveChatTimeout(config),
+          promptCaching: config.ai.promptCaching,
+          fallbacks: await resolveAIFallbacks(config),
+          onServed: logProviderServed(options),
+          maxTokens: config.ai.maxTokens,
+          redaction: {
+            enabled: options.redact !== false && config.redaction?.enabled !== false,
+            patterns: config.redaction?.patterns
+          }
+        },
+        vector,
+        graph,
+        createGitManager(repoRoot)
+      );
+
+      spinner.text = `Generating ${context.conventions.framework} tests for ${symbol.name}...`;
+      const code = extractCodeBlock(await ai.generateTests(context));
+      spinner.stop();
+
+      if (options.write) {
+        await fs.mkdir(path.dirname(testPath), { recursive: true });
+        await fs.writeFile(testPath, code, 'utf-8');
+        console.log(chalk.green(`✓ Wrote ${context.conventions.testFile}`) +
+          chalk.gray(` (${context.conventions.framework}, ${context.callers.length} callers as context)`));
+      } else {
+        console.error(chalk.gray(`// ${context.conventions.testFile} (${context.conventions.framework})`));
+        process.stdout.write(code);
+      }
+    } catch (error: any) {
+      spinner.fail(chalk.red('Test generation failed'));
+      console.error(chalk.red(`Error: ${error.message}`));
+      if (process.env.CV_DEBUG) {
+        console.error(chalk.gray(error.stack));
+      }
+      process.exitCode = 1;
+    } finally {
+      if (graph) await graph.close();
+      if (vector) await vector.close();
+    }
+  });
+
+  return cmd;
+}
+
+/**
+ * Functions and methods matching a name or qualified name, skipping test code
+ */
+async function findSymbolCandidates(graph: GraphManager, name: string): Promise<SymbolNode[]> {
+  const results = await graph.query(
+    'MATCH (s:Symbol) WHERE s.name = $name OR s.qualifiedName = $name RETURN s',
+    { name }
+  );
+  const symbols = results.map(r => r.s as SymbolNode);
+
+  // An exact qualified-name match is never ambiguous
+  const exact = symbols.filter(s => s.qualifiedName === name);
+  return (exact.length > 0 ? exact : symbols)
+    .filter(s => TESTABLE_KINDS.has(s.kind) && !isTestFile(s.file));
+}
+
+/**
+ * Resolve semantic search hits to graph symbols
+ */
+async function findSymbolsBySearch(graph: GraphManager, vector: VectorManager, query: string): Promise<SymbolNode[]> {
+  const results = await vector.searchCode(query, 5, { minScore: 0.5 });
+  const found = new Map<string, SymbolNode>();
+
+  for (const { payload } of results) {
+    if (!payload.symbolName || isTestFile(payload.file)) continue;
+    for (const symbol of await graph.getFileSymbols(payload.file)) {
+      if (symbol.name === payload.symbolName && TESTABLE_KINDS.has(symbol.kind)) {
+        found.set(symbol.qualifiedName, symbol);
+      }
+    }
+  }
+
+  return Array.from(found.values());
+}
+
+/**
+ * Collect the definition, callers, and test conventions for a symbol
+ */
+async function buildTestContext(repoRoot: string, symbol: SymbolNode, graph: GraphManager): Promise<TestGenerationContext> {
+  const language = detectLanguage(symbol.file);
+  const source = await readLines(repoRoot, symbol.file, symbol.startLine, symbol.endLine);
+
+  const callers: TestGenerationContext['callers'] = [];
+  for (const caller of (await graph.getCallers(symbol.qualifiedName)).slice(0, MAX_CALLERS)) {
+    try {
+      const end = Math.min(caller.endLine, caller.startLine + CALLER_EXCERPT_LINES - 1);
+      callers.push({ symbol: caller, excerpt: await readLines(repoRoot, caller.file, caller.startLine, end) });
+    } catch {
+      // Caller's file is gone since the last sync
+    }
+  }
+
+  // Existing tests in the same language, nearest to the source file first
+  const tracked = await createGitManager(repoRoot).getTrackedFiles();
+  const sourceDir = path.posix.dirname(symbol.file);
+  const existingTests = tracked
+    .filter(file => isTestFile(file) && detectLanguage(file) === language)
+    .sort((a, b) => Number(!a.startsWith(sourceDir)) - Number(!b.startsWith(sourceDir)));
+
+  const samples: Array<{ file: string; content: string }> = [];
+  for (const file of existingTests.slice(0, TEST_SAMPLE_SIZE)) {
+    try {
+      samples.push({ file, content: await fs.readFile(path.join(repoRoot, file), 'utf-8') });
+    } catch {
+      // Deleted in the working tree
+    }
+  }
+
+  const conventions = detectTestConventions(symbol.file, language, existingTests, samples.map(s => s.content));
+  const example = samples[0];
+
+  return {
+    symbol,
+    source,
+    callers,
+    conventions,
+    exampleTest: example
+      ? { file: example.file, content: example.content.split('\n').slice(0, EXAMPLE_TEST_LINES).join('\n') }
+      : undefined
+  };
+}
+
+async function readLines(repoRoot: string, file: string, startLine: number, endLine: number): Promise<string> {
+  const content = await fs.readFile(path.join(repoRoot, file), 'utf-8');
+  return content.split('\n').slice(startLine - 1, endLine).join('\n');
+}
+
+async function fileExists(filePath: string): Promise<boolean> {
+  try {
+    await fs.access(filePath);
+    return true;
+  } catch {
+    return false;
+  }
+}
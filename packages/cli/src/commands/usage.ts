@@ -0,0 +1,117 @@
+/**
+ * cv usage command
+ * Report tokens and estimated cost of API requests from .cv/usage.jsonl
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import Table from 'cli-table3';
+import {
+  readUsageLog,
+  summarizeUsage,
+  parseUsageSince,
+  formatUsageCost,
+  UsageTotals
+} from '@cv-git/core';
+import { findRepoRoot } from '@cv-git/shared';
+import { addGlobalOptions, createOutput } from '../utils/output.js';
+
+const GROUPINGS = ['command', 'day', 'model'] as const;
+type Grouping = typeof GROUPINGS[number];
+
+export function usageCommand(): Command {
+  const cmd = new Command('usage');
+
+  cmd
+    .description('Show token usage and estimated cost of AI and embedding requests')
+    .option('--since <when>', 'Only count requests since a date or age (e.g. 7d, 12h, 2025-01-31)')
+    .option('--by <grouping>', `Group by ${GROUPINGS.join(', ')} (default: command and day)`);
+
+  addGlobalOptions(cmd);
+
+  cmd.action(async (options) => {
+    const output = createOutput(options);
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+        process.exit(1);
+      }
+
+      if (options.by && !GROUPINGS.includes(options.by)) {
+        throw new Error(`Invalid --by: ${options.by} (expected ${GROUPINGS.join(', ')})`);
+      }
+      const groupings: Grouping[] = options.by ? [options.by] : ['command', 'day'];
+      const since = options.since ? parseUsageSince(options.since) : undefined;
+
+      const records = await readUsageLog(repoRoot, since);
+      const [total] = summarizeUsage(records.map(r => ({ ...r, command: 'total' })), 'command');
+
+      if (output.isJson) {
+        output.json({
+          since: since?.toISOString(),
+          total: total ?? null,
+          ...Object.fromEntries(groupings.map(by => [`by${by[0].toUpperCase()}${by.slice(1)}`, summarizeUsage(records, by)]))
+        });
+        return;
+      }
+
+      if (!total) {
+        console.log(chalk.gray(since ? `No API requests recorded since ${since.toLocaleString()}.` : 'No API requests recorded yet.'));
+        return;
+      }
+
+      for (const by of groupings) {
+        console.log(chalk.bold(`\nBy ${by}`));
+        printTotals(summarizeUsage(records, by), by);
+      }
+
+      console.log();
+      console.log(`Total: ${chalk.bold(formatCost(total))} over ${total.requests.toLocaleString()} requests, ` +
+        `${(total.inputTokens + total.outputTokens).toLocaleString()} tokens` +
+        (since ? chalk.gray(` since ${since.toLocaleString()}`) : ''));
+      if (total.unpriced > 0) {
+        console.log(chalk.gray(`  ${total.unpriced} request${total.unpriced === 1 ? '' : 's'} used models without a known price and are not in the cost.`));
+      }
+      console.log(chalk.gray('  Costs are estimates from list prices; tokens are estimated where the API did not report them.'));
+    } catch (error: any) {
+      if (output.isJson) {
+        output.json({ error: error.message });
+      } else {
+        console.error(chalk.red(`Error: ${error.message}`));
+      }
+      process.exitCode = 1;
+    }
+  });
+
+  return cmd;
+}
+
+function formatCost(totals: UsageTotals): string {
+  if (totals.unpriced === totals.requests) return '-';
+  return `~${formatUsageCost(totals.cost)}`;
+}
+
+function printTotals(rows: UsageTotals[], by: Grouping): void {
+  const table = new Table({
+    head: [
+      chalk.cyan(by === 'command' ? 'Command' : by === 'day' ? 'Day' : 'Model'),
+      chalk.cyan('Requests'),
+      chalk.cyan('Input tokens'),
+      chalk.cyan('Output tokens'),
+      chalk.cyan('Cost')
+    ]
+  });
+
+  for (const row of rows) {
+    table.push([
+      row.key,
+      row.requests.toLocaleString(),
+      row.inputTokens.toLocaleString(),
+      row.outputTokens.toLocaleString(),
+      formatCost(row)
+    ]);
+  }
+  console.log(table.toString());
+}
@@ -0,0 +1,580 @@
+/**
+ * cv index command
+ * Inspect the persisted vector index in .cv/index, share it as an archive,
+ * and initialize external vector stores
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import Table from 'cli-table3';
+import {
+  configManager,
+  createGitManager,
+  getIndexDir,
+  getVectorBackendOptions,
+  getEmbeddingModelDimensions,
+  initPgVectorStore,
+  buildPgVectorMigration,
+  DEFAULT_PGVECTOR_TABLE,
+  PgVectorIndexType,
+  readIndexMetadata,
+  readIndexSnapshotManifest,
+  INDEX_SCHEMA_VERSION,
+  verifyIndex,
+  repairIndex,
+  IndexIssue,
+  createVectorManager,
+  readManifest,
+  generateRepoId,
+  exportIndexArchive,
+  readIndexArchive,
+  importIndexArchive,
+  findIndexArchivePathWarnings,
+  checkIndexCompatibility,
+  EmbeddingIdentity,
+  VectorManager
+} from '@cv-git/core';
+import { findRepoRoot, getCVDir, WorktreeInfo } from '@cv-git/shared';
+import { findWorktreeMismatch, formatWorktreeMismatch } from '../utils/worktree.js';
+import { getEmbeddingCredentials, embeddingVectorOptions, createVectorManagerFromCredentials } from '../utils/credentials.js';
+
+/** Index warnings listed before the rest are summarized */
+const MAX_LISTED_WARNINGS = 20;
+
+/**
+ * Strip the repo ID prefix from an isolated collection name
+ */
+function displayCollectionName(collection: string): string {
+  const match = collection.match(/^[a-f0-9]{8,}_(.+)$/);
+  return match ? match[1] : collection;
+}
+
+/**
+ * Replace the vector store's collections with the persisted index. Null
+ * (and the store untouched) when it can't be reached or embeds with
+ * another configuration than the index was built with.
+ */
+async function reloadStoreFromSnapshot(repoRoot: string): Promise<number | null> {
+  const config = await configManager.load(repoRoot);
+  const manifest = await readManifest(getCVDir(repoRoot));
+  const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+
+  let vector: VectorManager;
+  try {
+    vector = await createVectorManagerFromCredentials(config, { repoId });
+    await vector.connect();
+  } catch {
+    return null;
+  }
+
+  try {
+    return await vector.reloadIndex(getIndexDir(repoRoot));
+  } catch {
+    return null;
+  } finally {
+    await vector.close();
+  }
+}
+
+/**
+ * Create the index command with subcommands
+ */
+export function indexCommand(): Command {
+  const index = new Command('index')
+    .description('Inspect, export and import the persisted vector index, and initialize pgvector storage');
+
+  // ═══════════════════════════════════════════════════════════════════════════
+  // cv index init - Create the pgvector table and ANN index
+  // ═══════════════════════════════════════════════════════════════════════════
+  index
+    .command('init')
+    .description('Create the pgvector extension, table and index (vector.provider: pgvector)')
+    .option('--dimensions <n>', 'Vector dimensions (default: config or embedding model)')
+    .option('--index-type <type>', 'ANN index: hnsw or ivfflat (default: config or hnsw)')
+    .option('--lists <n>', 'Number of ivfflat lists', '100')
+    .option('--dry-run', 'Print the SQL without running it')
+    .action(async (options) => {
+      try {
+        const repoRoot = await findRepoRoot();
+        if (!repoRoot) {
+          console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+          process.exit(1);
+        }
+
+        const config = await configManager.load(repoRoot);
+        const backend = getVectorBackendOptions(config.vector);
+        if (backend.backend !== 'pgvector') {
+          console.log(chalk.yellow(`vector.provider is ${config.vector.provider}; Qdrant collections are created automatically by \`cv sync\`.`));
+          console.log(chalk.gray('Set vector.provider to "pgvector" and vector.pgvector.connectionString to use Postgres.'));
+          return;
+        }
+
+        const pgConfig = config.vector.pgvector;
+        const dimensions = options.dimensions !== undefined
+          ? parseInt(options.dimensions, 10)
+          : pgConfig?.dimensions ?? getEmbeddingModelDimensions(config.embedding?.model || '');
+        if (!dimensions) {
+          throw new Error(`Unknown dimensions for embedding model ${config.embedding?.model || '(none)'}; pass --dimensions`);
+        }
+
+        const migration = {
+          table: pgConfig?.table,
+          dimensions,
+          indexType: (options.indexType || pgConfig?.indexType || 'hnsw') as PgVectorIndexType,
+          lists: parseInt(options.lists, 10)
+        };
+
+        if (options.dryRun) {
+          console.log(buildPgVectorMigration(migration).map(sql => `${sql};`).join('\n\n'));
+          return;
+        }
+
+        if (!backend.pgvector?.connectionString) {
+          throw new Error('No connection string: set vector.pgvector.connectionString or CV_PGVECTOR_URL');
+        }
+
+        await initPgVectorStore(backend.pgvector.connectionString, migration);
+        console.log(chalk.green(
+          `✓ pgvector table ${pgConfig?.table || DEFAULT_PGVECTOR_TABLE} ready ` +
+          `(${dimensions} dimensions, ${migration.indexType} index)`
+        ));
+        console.log(chalk.gray('Run `cv sync` to populate it.'));
+      } catch (error: any) {
+        console.error(chalk.red(`Error: ${error.message}`));
+        process.exit(1);
+      }
+    });
+
+  // ═══════════════════════════════════════════════════════════════════════════
+  // cv index status - Show what is stored in .cv/index
+  // ═══════════════════════════════════════════════════════════════════════════
+  index
+    .command('status')
+    .description('Show stored vector counts, embedding model, and indexed commit')
+    .option('--json', 'Output as JSON')
+    .action(async (options) => {
+      try {
+        const repoRoot = await findRepoRoot();
+        if (!repoRoot) {
+          console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+          process.exit(1);
+        }
+
+        const indexDir = getIndexDir(repoRoot);
+        const snapshot = await readIndexSnapshotManifest(indexDir);
+        const metadata = await readIndexMetadata(repoRoot);
+
+        let headCommit: string | undefined;
+        let worktree: WorktreeInfo | undefined;
+        try {
+          worktree = await createGitManager(repoRoot).getWorktreeInfo();
+          headCommit = worktree.head;
+        } catch {
+          // Not a git checkout
+        }
+        const mismatch = await findWorktreeMismatch(repoRoot);
+        // Index built in another worktree (e.g. .cv copied from the main checkout)
+        const foreignIndex = !!metadata?.worktree && !!worktree && metadata.worktree.path !== worktree.path;
+
+        const indexedCommit = snapshot?.lastIndexedCommit || metadata?.lastIndexedCommit;
+        const totalVectors = snapshot
+          ? Object.values(snapshot.collections).reduce((sum, count) => sum + count, 0)
+          : 0;
+
+        if (options.json) {
+          console.log(JSON.stringify({
+            path: indexDir,
+            persisted: !!snapshot,
+            schemaVersion: snapshot?.schemaVersion ?? null,
+            vectors: totalVectors,
+            collections: snapshot
+              ? Object.fromEntries(Object.entries(snapshot.collections).map(([name, count]) => [displayCollectionName(name), count]))
+              : {},
+            provider: snapshot?.fingerprint.provider || metadata?.provider || null,
+            model: snapshot?.fingerprint.model || metadata?.model || null,
+            dimensions: snapshot?.fingerprint.dimensions || metadata?.dimensions || null,
+            inputTypes: snapshot?.fingerprint.inputTypes || metadata?.inputTypes || null,
+            indexedCommit: indexedCommit || null,
+            headCommit: headCommit || null,
+            upToDate: !!indexedCommit && indexedCommit === headCommit && !foreignIndex,
+            worktree: metadata?.worktree
+              ? { path: metadata.worktree.path, branch: metadata.worktree.branch ?? null, detached: !metadata.worktree.branch }
+              : null,
+            currentWorktree: worktree ? { path: worktree.path, branch: worktree.branch ?? null, detached: !worktree.branch } : null,
+            worktreeMismatch: mismatch ? formatWorktreeMismatch(mismatch) : null,
+            savedAt: snapshot?.savedAt || null,
+            importedFrom: metadata?.importedFrom || null,
+            reindexReason: metadata?.reindexReason || null,
+            warnings: metadata?.warnings || []
+          }, null, 2));
+          return;
+        }
+
+        console.log(chalk.bold.cyan('\nVector Index Status\n'));
+
+        if (metadata?.reindexReason) {
+          console.log(chalk.yellow(`⚠ Marked for reindex: ${metadata.reindexReason}`));
+          console.log(chalk.gray('  The next `cv sync` rebuilds the index.\n'));
+        }
+
+        if (!snapshot) {
+          console.log(chalk.yellow(`No persisted index found in ${indexDir}`));
+          console.log(chalk.gray(`(schema v${INDEX_SCHEMA_VERSION}; older or unreadable indexes are ignored)`));
+          console.log(chalk.gray('Run `cv sync` to build and persist the index.\n'));
+          return;
+        }
+
+        const commitLabel = indexedCommit
+          ? indexedCommit.substring(0, 8) + (indexedCommit === headCommit && !foreignIndex
+            ? chalk.green(' (up to date)')
+            : chalk.yellow(` (HEAD is ${headCommit?.substring(0, 8) || 'unknown'})`))
+          : chalk.gray('unknown');
+
+        const table = new Table({
+          chars: { 'mid': '', 'left-mid': '', 'mid-mid': '', 'right-mid': '' }
+        });
+
+        table.push(
+          [chalk.bold('Vectors'), totalVectors.toLocaleString()],
+          [chalk.bold('Provider'), snapshot.fingerprint.provider],
+          [chalk.bold('Model'), snapshot.fingerprint.model],
+          [chalk.bold('Dimensions'), snapshot.fingerprint.dimensions.toString()],
+          ...(snapshot.fingerprint.inputTypes
+            ? [[chalk.bold('Input Types'), `${snapshot.fingerprint.inputTypes.document} / ${snapshot.fingerprint.inputTypes.query}`]]
+            : []),
+          [chalk.bold('Indexed Commit'), commitLabel],
+          ...(metadata?.worktree
+            ? [[chalk.bold('Worktree'), `${metadata.worktree.path} ` + chalk.gray(`(${metadata.worktree.branch ?? 'detached HEAD'})`)]]
+            : []),
+          [chalk.bold('Saved'), new Date(snapshot.savedAt).toLocaleString()],
+          ...(metadata?.importedFrom
+            ? [[chalk.bold('Imported From'), `${metadata.importedFrom.archive} ` + chalk.gray(
+                `(built at ${metadata.importedFrom.sourceCommit?.substring(0, 8) || 'unknown commit'} in ${metadata.importedFrom.sourcePath}, ` +
+                `exported ${new Date(metadata.importedFrom.exportedAt).toLocaleString()})`
+              )]]
+            : []),
+          [chalk.bold('Schema'), `v${snapshot.schemaVersion}`]
+        );
+
+        console.log(table.toString());
+
+        if (foreignIndex) {
+          console.log(chalk.yellow(`\n⚠ This index was built in worktree ${metadata!.worktree!.path}, not ${worktree!.path}.`));
+          console.log(chalk.gray('  Run `cv sync` to rebuild it for this worktree.'));
+        }
+        if (mismatch) {
+          console.log(chalk.yellow(`\n⚠ ${formatWorktreeMismatch(mismatch)}`));
+          console.log(chalk.gray(`  Run \`cv init\` in ${mismatch.worktree.path} to give this worktree its own index.`));
+        }
+
+        const collections = Object.entries(snapshot.collections);
+        if (collections.length > 0) {
+          console.log(chalk.gray('\nCollections:'));
+          for (const [name, count] of collections) {
+            console.log(`  ${displayCollectionName(name).padEnd(20)} ${count.toLocaleString()}`);
+          }
+        }
+
+        const warnings = metadata?.warnings || [];
+        if (warnings.length > 0) {
+          console.log(chalk.yellow(`\nWarnings (${warnings.length}):`));
+          for (const warning of warnings.slice(0, MAX_LISTED_WARNINGS)) {
+            const symbol = warning.symbolName ? ` (${warning.symbolName})` : '';
+            console.log(`  ${warning.file}:${warning.startLine}-${warning.endLine}${symbol} not indexed: ` +
+              chalk.gray(`~${warning.estimatedTokens} tokens, limit ${warning.maxTokens}`));
+          }
+          if (warnings.length > MAX_LISTED_WARNINGS) {
+            console.log(chalk.gray(`  ...and ${warnings.length - MAX_LISTED_WARNINGS} more (use --json for the full list)`));
+          }
+        }
+
+        console.log('');
+      } catch (error: any) {
+        console.error(chalk.red(`Error: ${error.message}`));
+        process.exit(1);
+      }
+    });
+
+  // ═══════════════════════════════════════════════════════════════════════════
+  // cv index verify - Check .cv/index for corrupt or stale entries
+  // ═══════════════════════════════════════════════════════════════════════════
+  index
+    .command('verify')
+    .description('Check the persisted index for unreadable entries, wrong dimensions, and deleted files')
+    .option('--fix', 'Prune bad vectors, or mark the index for a rebuild when it cannot be repaired')
+    .option('--json', 'Output as JSON')
+    .action(async (options) => {
+      try {
+        const repoRoot = await findRepoRoot();
+        if (!repoRoot) {
+          console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+          process.exit(1);
+        }
+
+        const result = options.fix
+          ? await repairIndex(repoRoot)
+          : { verification: await verifyIndex(repoRoot), pruned: 0, reindexReason: undefined };
+        const { verification } = result;
+        const issues = verification.issues;
+
+        // The store keeps serving pruned vectors until it reloads the snapshot
+        const reloaded = options.fix && !result.reindexReason && result.pruned > 0
+          ? await reloadStoreFromSnapshot(repoRoot)
+          : null;
+
+        if (options.json) {
+          console.log(JSON.stringify({
+            path: verification.indexDir,
+            persisted: verification.persisted,
+            vectors: verification.vectors,
+            issues: issues.map(issue => ({
+              ...issue,
+              collection: issue.collection ? displayCollectionName(issue.collection) : undefined
+            })),
+            reindexPending: verification.reindexPending || null,
+            ...(options.fix ? { pruned: result.pruned, reloaded, reindexReason: result.reindexReason || null } : {})
+          }, null, 2));
+          if (issues.length > 0 && !options.fix) {
+            process.exit(1);
+          }
+          return;
+        }
+
+        console.log(chalk.bold.cyan('\nVector Index Verification\n'));
+
+        if (!verification.persisted) {
+          console.log(chalk.yellow(`No persisted index found in ${verification.indexDir}`));
+          console.log(chalk.gray('Run `cv sync` to build and persist the index.\n'));
+          return;
+        }
+
+        if (verification.reindexPending) {
+          console.log(chalk.yellow(`⚠ Already marked for reindex: ${verification.reindexPending}\n`));
+        }
+
+        if (issues.length === 0) {
+          console.log(chalk.green(`✓ ${verification.vectors.toLocaleString()} vectors checked, no issues found\n`));
+          return;
+        }
+
+        console.log(`${verification.vectors.toLocaleString()} vectors checked, ${issues.length} issue${issues.length === 1 ? '' : 's'} found:`);
+        for (const issue of issues) {
+          printIndexIssue(issue);
+        }
+        console.log('');
+
+        if (!options.fix) {
+          const unrepairable = issues.some(issue => !issue.repairable);
+          console.log(chalk.gray(unrepairable
+            ? 'Run `cv index verify --fix` to mark the index for a rebuild, then `cv sync`.'
+            : 'Run `cv index verify --fix` to prune the bad vectors.'));
+          console.log('');
+          process.exit(1);
+        }
+
+        if (result.reindexReason) {
+          console.log(chalk.yellow('The index cannot be repaired in place: the snapshot was removed and the index marked for a rebuild.'));
+          console.log(chalk.gray('Run `cv sync` to rebuild it.'));
+        } else {
+          console.log(chalk.green(`✓ Pruned ${result.pruned.toLocaleString()} vector${result.pruned === 1 ? '' : 's'} from the snapshot`));
+          if (reloaded !== null) {
+            console.log(chalk.green(`✓ Reloaded the vector store from the snapshot (${reloaded.toLocaleString()} vectors)`));
+          } else if (result.pruned > 0) {
+            console.log(chalk.yellow('Could not reach the vector store: it keeps the pruned vectors until `cv sync --force` rebuilds the index.'));
+          }
+        }
+        console.log('');
+      } catch (error: any) {
+        console.error(chalk.red(`Error: ${error.message}`));
+        process.exit(1);
+      }
+    });
+
+  // ═══════════════════════════════════════════════════════════════════════════
+  // cv index export - Write .cv/index to a portable archive
+  // ═══════════════════════════════════════════════════════════════════════════
+  index
+    .command('export <file>')
+    .description('Write the persisted index to an archive others can import (e.g. a CI build artifact)')
+    .option('--json', 'Output as JSON')
+    .action(async (file: string, options) => {
+      try {
+        const repoRoot = await findRepoRoot();
+        if (!repoRoot) {
+          console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+          process.exit(1);
+        }
+
+        const manifest = await readManifest(getCVDir(repoRoot));
+        const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+        const header = await exportIndexArchive(repoRoot, file, { repoId });
+        const vectors = Object.values(header.collections).reduce((sum, count) => sum + count, 0);
+
+        let headCommit: string | undefined;
+        try {
+          headCommit = (await createGitManager(repoRoot).getWorktreeInfo()).head;
+        } catch {
+          // Not a git checkout
+        }
+
+        if (options.json) {
+          console.log(JSON.stringify({
+            file,
+            vectors,
+            provider: header.fingerprint.provider,
+            model: header.fingerprint.model,
+            dimensions: header.fingerprint.dimensions,
+            sourceCommit: header.sourceCommit || null,
+            headCommit: headCommit || null,
+            exportedAt: header.exportedAt
+          }, null, 2));
+          return;
+        }
+
+        console.log(chalk.green(
+          `✓ Exported ${vectors.toLocaleString()} vectors to ${file} ` +
+          chalk.gray(`(${header.fingerprint.provider} / ${header.fingerprint.model}, commit ${header.sourceCommit?.substring(0, 8) || 'unknown'})`)
+        ));
+        if (header.metadata?.reindexReason) {
+          console.log(chalk.yellow(`⚠ The index is marked for reindex: ${header.metadata.reindexReason}`));
+        } else if (headCommit && header.sourceCommit !== headCommit) {
+          console.log(chalk.yellow(`⚠ The index is at ${header.sourceCommit?.substring(0, 8) || 'an unknown commit'}, HEAD is ${headCommit.substring(0, 8)}. Run \`cv sync\` first to share an up-to-date index.`));
+        }
+      } catch (error: any) {
+        console.error(chalk.red(`Error: ${error.message}`));
+        process.exit(1);
+      }
+    });
+
+  // ═══════════════════════════════════════════════════════════════════════════
+  // cv index import - Replace .cv/index with an archive's
+  // ═══════════════════════════════════════════════════════════════════════════
+  index
+    .command('import <file>')
+    .description('Replace the index with one exported by `cv index export`, instead of re-syncing')
+    .option('--json', 'Output as JSON')
+    .action(async (file: string, options) => {
+      try {
+        const repoRoot = await findRepoRoot();
+        if (!repoRoot) {
+          console.error(chalk.red('Not in a CV-Git repository. Run `cv init` first.'));
+          process.exit(1);
+        }
+
+        const config = await configManager.load(repoRoot);
+        const archive = await readIndexArchive(file);
+        const { header } = archive;
+
+        // Connect without the persisted index: it is about to be replaced
+        const embeddingCreds = await getEmbeddingCredentials({
+          provider: config.embedding?.provider,
+          ollamaUrl: config.embedding?.url,
+          ollamaModel: config.embedding?.model,
+          azure: config.azure
+        });
+        const manifest = await readManifest(getCVDir(repoRoot));
+        const repoId = manifest?.repository?.id || generateRepoId(repoRoot);
+        const nativeDimensions = getEmbeddingModelDimensions(header.fingerprint.model);
+        const vector = createVectorManager({
+          url: config.vector.url,
+          ...getVectorBackendOptions(config.vector),
+          repoId,
+          ...embeddingVectorOptions(embeddingCreds, config),
+          // An archive of shortened vectors asks for the same size
+          embeddingDimensions: config.embedding?.outputDimensions ??
+            (nativeDimensions && nativeDimensions !== header.fingerprint.dimensions ? header.fingerprint.dimensions : undefined)
+        });
+        await vector.connect();
+
+        try {
+          // Queries must be embedded the way the archived vectors were
+          const current = vector.getEmbeddingInfo();
+          if (!checkIndexCompatibility(header.fingerprint, current).compatible) {
+            throw new Error(
+              'The archive was built with another embedding configuration.\n' +
+              `  Archive: ${describeIdentity(header.fingerprint)}\n` +
+              `  Current: ${describeIdentity(current)}\n` +
+              'Set embedding.provider and embedding.model to match the archive, or run `cv sync` to build the index here.'
+            );
+          }
+
+          const warnings = findIndexArchivePathWarnings(archive, repoRoot);
+          let worktree: WorktreeInfo | undefined;
+          try {
+            const git = createGitManager(repoRoot);
+            worktree = await git.getWorktreeInfo();
+            if (header.sourceCommit && !(await git.commitExists(header.sourceCommit))) {
+              warnings.push(`Commit ${header.sourceCommit.substring(0, 8)} is not in this clone; fetch it so the next \`cv sync\` only re-embeds what changed since`);
+            }
+          } catch {
+            // Not a git checkout
+          }
+
+          await importIndexArchive(repoRoot, archive, file, {
+            repoId,
+            worktree: worktree ? { path: worktree.path, branch: worktree.branch } : undefined
+          });
+
+          // Load the imported vectors in place of the store's
+          for (const collection of Object.values(vector.getCollectionNames())) {
+            await vector.clearCollection(collection);
+          }
+          const restored = await vector.restoreIndex(getIndexDir(repoRoot));
+
+          if (options.json) {
+            console.log(JSON.stringify({
+              file,
+              vectors: restored,
+              provider: header.fingerprint.provider,
+              model: header.fingerprint.model,
+              dimensions: header.fingerprint.dimensions,
+              sourceCommit: header.sourceCommit || null,
+              sourcePath: header.sourcePath,
+              exportedAt: header.exportedAt,
+              warnings
+            }, null, 2));
+            return;
+          }
+
+          console.log(chalk.green(
+            `✓ Imported ${restored.toLocaleString()} vectors from ${file} ` +
+            chalk.gray(`(built at ${header.sourceCommit?.substring(0, 8) || 'unknown commit'}, exported ${new Date(header.exportedAt).toLocaleString()})`)
+          ));
+          for (const warning of warnings) {
+            console.log(chalk.yellow(`⚠ ${warning}`));
+          }
+          if (worktree && header.sourceCommit !== worktree.head) {
+            console.log(chalk.gray('Run `cv sync` to bring the index up to HEAD; only the files changed since are re-embedded.'));
+          }
+        } finally {
+          await vector.close();
+        }
+      } catch (error: any) {
+        console.error(chalk.red(`Error: ${error.message}`));
+        process.exit(1);
+      }
+    });
+
+  return index;
+}
+
+function describeIdentity(identity: EmbeddingIdentity): string {
+  return `${identity.provider} / ${identity.model} (${identity.dimensions} dimensions)`;
+}
+
+/**
+ * Print one verification issue with the deleted files it covers
+ */
+function printIndexIssue(issue: IndexIssue): void {
+  const where = issue.collection ? chalk.bold(displayCollectionName(issue.collection)) + ': ' : '';
+  const marker = issue.repairable ? chalk.yellow('  ⚠') : chalk.red('  ✗');
+  console.log(`${marker} ${where}${issue.message}` + (issue.repairable ? '' : chalk.gray(' (needs a rebuild)')));
+
+  const files = issue.files || [];
+  for (const file of files.slice(0, MAX_LISTED_WARNINGS)) {
+    console.log(chalk.gray(`      ${file}`));
+  }
+  if (files.length > MAX_LISTED_WARNINGS) {
+    console.log(chalk.gray(`      ...and ${files.length - MAX_LISTED_WARNINGS} more (use --json for the full list)`));
+  }
+}
@@ -0,0 +1,241 @@
+/**
+ * cv why command
+ * Explain why a line (or range of lines) exists: git blame finds the commits
+ * that introduced it, and their messages and diffs are explained together
+ * with the function the lines belong to
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import ora from 'ora';
+import * as fs from 'fs/promises';
+import * as path from 'path';
+import {
+  configManager,
+  createAIManager,
+  createGitManager,
+  createParser,
+  parseLineTarget,
+  groupBlameByCommit,
+  selectHunks,
+  formatLineRanges,
+  MAX_WHY_COMMITS,
+  WhyContext,
+  AIManagerFallback
+} from '@cv-git/core';
+import { findRepoRoot, detectLanguage } from '@cv-git/shared';
+import { addGlobalOptions, createOutput } from '../utils/output.js';
+import { addModelOption, resolveModel } from '../utils/model.js';
+import { logProviderServed, resolveAIFallbacks, resolveChatBackend, chatBackendOptions } from '../utils/providers.js';
+import { resolveChatTimeout } from '../utils/timeout.js';
+import { abortOnInterrupt, isAbortError } from '../utils/interrupt.js';
+
+const ENCLOSING_KINDS = new Set(['function', 'method', 'class']);
+
+export function whyCommand(): Command {
+  const cmd = new Command('why');
+
+  cmd
+    .description('Explain why a line exists, from the commits that introduced it')
+    .argument('<target>', 'Line or range: <file>:<line> or <file>:<start>-<end>')
+    .option('--no-stream', 'Wait for the full explanation instead of streaming it')
+    .option('--no-redact', 'Send code without masking secrets');
+
+  addModelOption(cmd, 'why');
+  addGlobalOptions(cmd);
+
+  cmd.action(async (targetArg: string, options) => {
+    const output = createOutput(options);
+    const spinner = output.isJson ? null : ora('Initializing...').start();
+
+    try {
+      const repoRoot = await findRepoRoot();
+      if (!repoRoot) {
+        spinner?.fail(chalk.red('Not in a CV-Git repository'));
+        console.error(chalk.gray('Run `cv init` first'));
+        process.exit(1);
+      }
+
+      const parsed = parseLineTarget(targetArg);
+      const absolutePath = path.resolve(parsed.file);
+      const file = path.relative(repoRoot, absolutePath).split(path.sep).join('/');
+      if (file.startsWith('..')) {
+        throw new Error(`${parsed.file} is outside the repository`);
+      }
+
+      let content: string;
+      try {
+        content = await fs.readFile(absolutePath, 'utf-8');
+      } catch {
+        throw new Error(`Cannot read ${file}`);
+      }
+      const fileLines = content.split('\n');
+      const lineCount = content.endsWith('\n') ? fileLines.length - 1 : fileLines.length;
+      if (parsed.endLine > lineCount) {
+        throw new Error(`${file} has ${lineCount} lines`);
+      }
+      const target = { ...parsed, file };
+
+      const config = await configManager.load(repoRoot);
+      const model = resolveModel('why', options.model, config);
+      const git = createGitManager(repoRoot);
+
+      // Who introduced the lines, and the hunks that did it
+      if (spinner) spinner.text = 'Running git blame...';
+      const commits = groupBlameByCommit(await git.blame(file, target.startLine, target.endLine));
+      for (const commit of commits.filter(c => !c.uncommitted).slice(0, MAX_WHY_COMMITS)) {
+        const details = await git.getCommit(commit.sha);
+        commit.message = details.message;
+        try {
+          const diff = await git.getCommitFileDiff(commit.sha, commit.originalFile || file);
+          commit.diff = selectHunks(diff, commit.originalLines);
+        } catch {
+          // Root or merge commits may have no diff to show
+        }
+      }
+
+      const language = detectLanguage(file);
+      const context: WhyContext = {
+        target,
+        language,
+        code: fileLines.slice(target.startLine - 1, target.endLine).join('\n'),
+        commits,
+        symbol: await findEnclosingSymbol(file, content, language, target.startLine, target.endLine)
+      };
+
+      // Anthropic, an Azure chat deployment, Gemini or an OpenAI-compatible gateway
+      let backend: AIManagerFallback;
+      try {
+        backend = await resolveChatBackend(config);
+      } catch (error: any) {
+        spinner.fail(chalk.red(error.message));
+        process.exit(1);
+      }
+
+      const ai = createAIManager({
+        ...chatBackendOptions(backend, model, config),
+        timeoutMs: resolveChatTimeout(config),
+        promptCaching: config.ai.promptCaching,
+        fallbacks: await resolveAIFallbacks(config),
+        onServed: logProviderServed(options),
+        maxTokens: config.ai.maxTokens,
+        redaction: {
+          enabled: options.redact !== false && config.redaction?.enabled !== false,
+          patterns: config.redaction?.patterns
+        }
+      });
+
+      if (output.isJson) {
+        const explanation = await ai.explainWhy(context);
+        output.json({
+          target,
+          symbol: context.symbol && { name: context.symbol.name, kind: context.symbol.kind, startLine: context.symbol.startLine, endLine: context.symbol.endLine },
+          commits: commits.map(({ diff, originalLines, ...commit }) => commit),
+          explanation
+        });
+        return;
+      }
+
+      spinner!.stop();
+      printBlame(context);
+
+      console.log(chalk.bold.cyan('\nWhy:'));
+      console.log(chalk.gray('─'.repeat(80)));
+      if (options.stream) {
+        const interrupt = abortOnInterrupt();
+        try {
+          await ai.explainWhy(context, {
+            signal: interrupt.signal,
+            onToken: token => process.stdout.write(token),
+            onComplete: () => console.log()
+          });
+        } catch (error) {
+          if (!interrupt.signal.aborted && !isAbortError(error)) throw error;
+          console.log(chalk.yellow('\n[aborted]'));
+          process.exitCode = 130;
+          return;
+        } finally {
+          interrupt.dispose();
+        }
+      } else {
+        const waiting = ora('Asking the model...').start();
+        const explanation = await ai.explainWhy(context);
+        waiting.stop();
+        console.log(explanation);
+      }
+      console.log(chalk.gray('─'.repeat(80)));
+
+      const primary = commits.find(c => !c.uncommitted);
+      if (primary) {
+        console.log(chalk.gray(`\nIntroduced by ${primary.sha}`));
+        console.log(chalk.gray(`  Dig deeper: git show ${primary.sha.slice(0, 12)}`));
+      }
+    } catch (error: any) {
+      if (output.isJson) {
+        output.json({ error: error.message });
+      } else {
+        spinner?.fail(chalk.red('Could not explain the line history'));
+        console.error(chalk.red(`Error: ${error.message}`));
+        if (process.env.CV_DEBUG) {
+          console.error(chalk.gray(error.stack));
+        }
+      }
+      process.exitCode = 1;
+    }
+  });
+
+  return cmd;
+}
+
+/**
+ * Innermost function, method, or class that contains the whole range
+ */
+async function findEnclosingSymbol(
+  file: string,
+  content: string,
+  language: string,
+  startLine: number,
+  endLine: number
+): Promise<WhyContext['symbol']> {
+  try {
+    const parsed = await createParser().parseFile(file, content, language);
+    const enclosing = parsed.symbols
+      .filter(s => ENCLOSING_KINDS.has(s.kind) && s.startLine <= startLine && s.endLine >= endLine)
+      .sort((a, b) => (a.endLine - a.startLine) - (b.endLine - b.startLine))[0];
+    if (!enclosing) {
+      return undefined;
+    }
+
+    return {
+      name: enclosing.name,
+      kind: enclosing.kind,
+      startLine: enclosing.startLine,
+      endLine: enclosing.endLine,
+      source: content.split('\n').slice(enclosing.startLine - 1, enclosing.endLine).join('\n'),
+      docstring: enclosing.docstring
+    };
+  } catch {
+    // Unsupported language: explain from the history alone
+    return undefined;
+  }
+}
+
+/**
+ * Blame summary: one row per introducing commit
+ */
+function printBlame(context: WhyContext): void {
+  const { target, symbol } = context;
+  const range = target.startLine === target.endLine ? `${target.startLine}` : `${target.startLine}-${target.endLine}`;
+  console.log(chalk.bold(`\n${target.file}:${range}`) + (symbol ? chalk.gray(` in ${symbol.kind} ${symbol.name}`) : ''));
+  console.log();
+
+  for (const commit of context.commits) {
+    const lines = chalk.gray(`L${formatLineRanges(commit.lines)}`);
+    if (commit.uncommitted) {
+      console.log(`  ${chalk.yellow('uncommitted')} ${lines}`);
+      continue;
+    }
+    const date = new Date(commit.date).toISOString().slice(0, 10);
+    console.log(`  ${chalk.yellow(commit.sha.slice(0, 8))} ${chalk.gray(date)} ${chalk.cyan(commit.author)} ${commit.summary} ${lines}`);
+  }
+}
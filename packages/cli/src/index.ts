@@ -11,6 +11,11 @@ import { fileURLToPath } from 'node:url';
 import { Command } from 'commander';
 import chalk from 'chalk';
 import { applyOptionsInterceptor } from './utils/options-interceptor.js';
+import { applyNetworkOptions } from './utils/network.js';
+import { applyConfigOptions } from './utils/profile.js';
+import { applyEnvFileOptions } from './utils/env-file.js';
+import { applyLoggingOptions } from './utils/logging.js';
+import { applyUsageTracking } from './utils/usage.js';
 
 // Read version from package.json — works in both ESM (tsc) and CJS (esbuild bundle)
 const CLI_VERSION: string = (() => {
@@ -30,7 +35,17 @@ import { initCommand } from './commands/init.js';
 import { syncCommand } from './commands/sync.js';
 import { doCommand } from './commands/do.js';
 import { findCommand } from './commands/find.js';
+import { symbolCommand } from './commands/symbol.js';
+import { refsCommand } from './commands/refs.js';
 import { explainCommand } from './commands/explain.js';
+import { benchCommand } from './commands/bench.js';
+import { testCommand } from './commands/test.js';
+import { refactorCommand } from './commands/refactor.js';
+import { whyCommand } from './commands/why.js';
+import { summarizeCommand } from './commands/summarize.js';
+import { usageCommand } from './commands/usage.js';
+import { promptsCommand } from './commands/prompts.js';
+import { mcpCommand } from './commands/mcp.js';
 import { reviewCommand } from './commands/review.js';
 import { graphCommand } from './commands/graph.js';
 import { gitCommand } from './commands/git.js';
@@ -44,6 +59,7 @@ import { cloneCommand } from './commands/clone.js';
 import { cloneGroupCommand } from './commands/clone-group.js';
 import { contextCommand } from './commands/context.js';
 import { chatCommand } from './commands/chat.js';
+import { askCommand } from './commands/ask.js';
 import { pushCommand } from './commands/push.js';
 import { pullCommand } from './commands/pull.js';
 import { watchCommand } from './commands/watch.js';
@@ -57,6 +73,7 @@ import { importCommand } from './commands/import.js';
 import { servicesCommand } from './commands/services.js';
 import { createDocsCommand } from './commands/docs.js';
 import { createCacheCommand } from './commands/cache.js';
+import { indexCommand } from './commands/vector-index.js';
 import { verifyCommand } from './commands/verify.js';
 import { addCommand } from './commands/add.js';
 import { diffCommand } from './commands/diff.js';
@@ -105,6 +122,7 @@ program.addCommand(cloneCommand());          // Clone and initialize
 program.addCommand(cloneGroupCommand());     // Clone entire group/subgroup
 program.addCommand(contextCommand());        // Generate AI context
 program.addCommand(chatCommand());           // AI chat with codebase context
+program.addCommand(askCommand());            // One-shot codebase Q&A
 program.addCommand(pushCommand());           // Git push with auto-sync
 program.addCommand(pullCommand());           // Git pull with auto-sync
 program.addCommand(watchCommand());          // File watcher with auto-sync
@@ -116,7 +134,17 @@ program.addCommand(initCommand());
 program.addCommand(syncCommand());
 program.addCommand(doCommand());
 program.addCommand(findCommand());
+program.addCommand(symbolCommand());
+program.addCommand(refsCommand());
+program.addCommand(benchCommand());
 program.addCommand(explainCommand());
+program.addCommand(testCommand());
+program.addCommand(refactorCommand());
+program.addCommand(whyCommand());
+program.addCommand(summarizeCommand());
+program.addCommand(usageCommand());
+program.addCommand(promptsCommand());
+program.addCommand(mcpCommand());
 program.addCommand(reviewCommand());
 program.addCommand(graphCommand());
 program.addCommand(gitCommand());
@@ -144,6 +172,7 @@ program.addCommand(importCommand());        // Import PRD data from cv-prd expor
 program.addCommand(servicesCommand());      // Service discovery and management
 program.addCommand(createDocsCommand());    // Documentation management (cv docs)
 program.addCommand(createCacheCommand());   // Embedding cache management (cv cache)
+program.addCommand(indexCommand());         // Persisted vector index (cv index)
 program.addCommand(verifyCommand());        // CLI verification (cv verify)
 program.addCommand(bugreportCommand());     // Bug reporting (cv bugreport)
 program.addCommand(depsCommand());          // Dependency analysis (cv deps)
@@ -163,6 +192,21 @@ program.exitOverride((err) => {
   process.exit(err.exitCode || 1);
 });
 
+// --log-level / --log-file for every command (first, so the other hooks can log)
+applyLoggingOptions(program);
+
+// .env and --env-file for every command (before config ${VAR} references and credentials read them)
+applyEnvFileOptions(program);
+
+// --config / --profile for every command (before the hooks that load the config)
+applyConfigOptions(program);
+
+// --proxy / --ca-bundle for every command
+applyNetworkOptions(program);
+
+// Token and cost log of every command's API requests
+applyUsageTracking(program);
+
 // Apply options interceptor to all commands
 applyOptionsInterceptor(program);
 
@@ -0,0 +1,65 @@
+/**
+ * Tests for the answer formats of cv explain and cv chat
+ */
+
+import { describe, it, expect } from 'vitest';
+import { PlainTextStream, resolveAnswerFormat, stripMarkdown } from './answer-format';
+
+const answer = [
+  '## How tokens are checked',
+  '',
+  'The **check** hashes the `password` with _bcrypt_ [1]:',
+  '',
+  '```ts',
+  'const ok = hash(password) === stored; // **not** emphasis',
+  '```',
+  '',
+  '* See [the docs](https://example.com/auth) and `__init__` [2].',
+  '> Tokens expire after a day.'
+].join('\n');
+
+describe('resolveAnswerFormat', () => {
+  it('should default to markdown on a terminal and plain when piped', () => {
+    expect(resolveAnswerFormat({}, { isTTY: true })).toBe('markdown');
+    expect(resolveAnswerFormat({}, { isTTY: false })).toBe('plain');
+    expect(resolveAnswerFormat({ format: 'markdown' }, { isTTY: false })).toBe('markdown');
+    expect(resolveAnswerFormat({ json: true }, { isTTY: true })).toBe('json');
+  });
+
+  it('should reject unknown formats and --json with another format', () => {
+    expect(() => resolveAnswerFormat({ format: 'html' })).toThrow('Invalid --format: html');
+    expect(() => resolveAnswerFormat({ format: 'plain', json: true })).toThrow('--json cannot be combined with --format plain');
+  });
+});
+
+describe('stripMarkdown', () => {
+  it('should strip headings, emphasis, links and fences but keep code and citations', () => {
+    expect(stripMarkdown(answer)).toBe([
+      'How tokens are checked',
+      '',
+      'The check hashes the password with bcrypt [1]:',
+      '',
+      'const ok = hash(password) === stored; // **not** emphasis',
+      '',
+      '- See the docs (https://example.com/auth) and __init__ [2].',
+      'Tokens expire after a day.'
+    ].join('\n'));
+  });
+
+  it('should leave identifiers with underscores alone', () => {
+    expect(stripMarkdown('load_config calls read_file_sync')).toBe('load_config calls read_file_sync');
+  });
+});
+
+describe('PlainTextStream', () => {
+  it('should give the same output when the answer streams in token by token', () => {
+    const stream = new PlainTextStream();
+    let streamed = '';
+    for (const token of answer.match(/.{1,3}/gs)!) {
+      streamed += stream.push(token);
+    }
+    streamed += stream.flush();
+
+    expect(streamed).toBe(stripMarkdown(answer));
+  });
+});
@@ -0,0 +1,122 @@
+/**
+ * Answer output formats shared by cv explain and cv chat
+ * markdown prints the model's answer as written (fences, headings, and
+ * highlighting on a terminal). plain strips the Markdown for logs, keeping
+ * code and [n] citation markers as they are. json is the structured result
+ * that --json prints.
+ */
+
+import { Command } from 'commander';
+
+export type AnswerFormat = 'markdown' | 'plain' | 'json';
+
+export const ANSWER_FORMATS: AnswerFormat[] = ['markdown', 'plain', 'json'];
+
+/** Opens or closes a fenced block */
+const FENCE_LINE = /^\s*(`{3,}|~{3,})\s*([^\s`]*)/;
+
+/**
+ * Add --format to a command
+ */
+export function addFormatOption(cmd: Command): Command {
+  return cmd.option('--format <format>', `Output format: ${ANSWER_FORMATS.join(', ')} (default: markdown on a terminal, plain when piped)`);
+}
+
+/**
+ * The format to print in: --format, json for --json, else markdown when
+ * stdout is a terminal and plain when it is piped or redirected
+ */
+export function resolveAnswerFormat(
+  options: { format?: string; json?: boolean },
+  stream: { isTTY?: boolean } = process.stdout
+): AnswerFormat {
+  if (options.format !== undefined && !ANSWER_FORMATS.includes(options.format as AnswerFormat)) {
+    throw new Error(`Invalid --format: ${options.format} (expected ${ANSWER_FORMATS.join(', ')})`);
+  }
+  if (options.json && options.format && options.format !== 'json') {
+    throw new Error(`--json cannot be combined with --format ${options.format}`);
+  }
+  if (options.json) return 'json';
+  return (options.format as AnswerFormat | undefined) ?? (stream.isTTY ? 'markdown' : 'plain');
+}
+
+/**
+ * Markdown as plain text
+ */
+export function stripMarkdown(text: string): string {
+  const stream = new PlainTextStream();
+  return stream.push(text) + stream.flush();
+}
+
+/**
+ * Strips Markdown from an answer as it streams in. Lines are held until
+ * they end, since a line's first characters decide what it is.
+ */
+export class PlainTextStream {
+  private fence: string | null = null;
+  private line = '';
+
+  push(text: string): string {
+    let out = '';
+    const pieces = text.split('\n');
+    pieces.forEach((piece, i) => {
+      this.line += piece;
+      if (i < pieces.length - 1) {
+        const line = this.endLine();
+        if (line !== null) out += line + '\n';
+      }
+    });
+    return out;
+  }
+
+  /** The rest of the last line, once the answer is complete */
+  flush(): string {
+    const line = this.line ? this.endLine() : null;
+    return line ?? '';
+  }
+
+  /** The line as plain text, or null for a line that is dropped (a fence) */
+  private endLine(): string | null {
+    const line = this.line;
+    this.line = '';
+
+    const fence = FENCE_LINE.exec(line);
+    if (this.fence) {
+      // A closing fence uses the same character, at least as many times, and no tag
+      if (fence && !fence[2] && fence[1][0] === this.fence[0] && fence[1].length >= this.fence.length) {
+        this.fence = null;
+        return null;
+      }
+      return line;
+    }
+    if (fence) {
+      this.fence = fence[1];
+      return null;
+    }
+    return plainLine(line);
+  }
+}
+
+function plainLine(line: string): string {
+  if (/^\s*([-*_])(\s*\1){2,}\s*$/.test(line)) return '';
+
+  const prose = line
+    .replace(/^(\s*)#{1,6}\s+(.*?)(\s+#+)?\s*$/, '$1$2')
+    .replace(/^(\s*)>\s?/, '$1')
+    .replace(/^(\s*)[*+]\s+/, '$1- ');
+
+  // Inline code is kept as written, so `__init__` is not read as emphasis
+  return prose
+    .split(/(`+[^`]*`+)/)
+    .map((part, i) => (i % 2 === 1 ? part.replace(/^`+\s?|\s?`+$/g, '') : plainInline(part)))
+    .join('');
+}
+
+function plainInline(text: string): string {
+  return text
+    .replace(/!?\[([^\]]+)\]\(([^)\s]+)[^)]*\)/g, '$1 ($2)')
+    .replace(/\*\*(.+?)\*\*/g, '$1')
+    .replace(/(^|\W)__(.+?)__(?=\W|$)/g, '$1$2')
+    .replace(/(^|[^\w*])\*(?![\s*])([^*]+?)\*(?!\w)/g, '$1$2')
+    .replace(/(^|[^\w])_(?![\s_])([^_]+?)_(?=\W|$)/g, '$1$2');
+}
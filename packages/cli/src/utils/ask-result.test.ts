@@ -0,0 +1,38 @@
+/**
+ * Tests for the cv ask --json result, which scripts depend on
+ */
+
+import { describe, it, expect } from 'vitest';
+import { toAskResult } from './ask-result';
+
+describe('toAskResult', () => {
+  it('prints exactly question, answer, model and citations', () => {
+    const result = toAskResult('where are retries configured?', 'In retry.ts [1].', 'claude-sonnet-4-5', [
+      { file: 'src/retry.ts', startLine: 10, endLine: 24, symbol: 'retryWithBackoff', score: 0.82 }
+    ]);
+
+    expect(JSON.parse(JSON.stringify(result))).toEqual({
+      question: 'where are retries configured?',
+      answer: 'In retry.ts [1].',
+      model: 'claude-sonnet-4-5',
+      citations: [{ file: 'src/retry.ts', startLine: 10, endLine: 24, symbol: 'retryWithBackoff', score: 0.82 }]
+    });
+  });
+
+  it('leaves out score for --file files and symbol for chunks without one', () => {
+    const { citations } = toAskResult('q', 'a', 'm', [{ file: 'README.md', startLine: 1, endLine: 40 }]);
+
+    expect(Object.keys(citations[0])).toEqual(['file', 'startLine', 'endLine']);
+  });
+
+  it('drops fields that are not part of the documented shape', () => {
+    const chunk = { file: 'src/a.ts', startLine: 1, endLine: 2, score: 0.6, text: 'export const a = 1;' };
+    const { citations } = toAskResult('q', 'a', 'm', [chunk]);
+
+    expect(citations).toEqual([{ file: 'src/a.ts', startLine: 1, endLine: 2, score: 0.6 }]);
+  });
+
+  it('keeps an empty citations array when no code was retrieved', () => {
+    expect(toAskResult('q', 'a', 'm', []).citations).toEqual([]);
+  });
+});
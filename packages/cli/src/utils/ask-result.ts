@@ -0,0 +1,46 @@
+/**
+ * The `cv ask --json` result, which `cv chat --format json` extends with
+ * the session. Scripts depend on its shape.
+ */
+
+/**
+ * Code given to the model as context for an answer
+ */
+export interface ChatCitation {
+  file: string;
+  startLine: number;
+  endLine: number;
+  symbol?: string;
+  /** Similarity to the question; absent for files named with --file */
+  score?: number;
+}
+
+/**
+ * What `cv ask --json` prints. Scripts depend on it: add fields, never rename or remove them.
+ */
+export interface AskResult {
+  question: string;
+  answer: string;
+  model: string;
+  /** Code given to the model as context, best match first (files named with --file lead) */
+  citations: ChatCitation[];
+}
+
+/**
+ * Build the result from an answer, keeping only the documented citation
+ * fields and leaving out the ones a citation does not have
+ */
+export function toAskResult(question: string, answer: string, model: string, citations: ChatCitation[]): AskResult {
+  return {
+    question,
+    answer,
+    model,
+    citations: citations.map(citation => ({
+      file: citation.file,
+      startLine: citation.startLine,
+      endLine: citation.endLine,
+      ...(citation.symbol !== undefined ? { symbol: citation.symbol } : {}),
+      ...(citation.score !== undefined ? { score: citation.score } : {})
+    }))
+  };
+}
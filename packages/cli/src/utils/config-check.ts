@@ -19,6 +19,13 @@ export interface ConfigStatus {
   embeddingProviders: {
     openai: boolean;
     openrouter: boolean;
+    ollama: boolean;
+    azure: boolean;
+    gemini: boolean;
+    cohere: boolean;
+    voyage: boolean;
+    huggingface: boolean;
+    openaiCompatible: boolean;
   };
   aiProviders: {
     anthropic: boolean;
@@ -42,6 +49,13 @@ export async function checkCredentials(): Promise<ConfigStatus> {
     embeddingProviders: {
       openai: false,
       openrouter: false,
+      ollama: false,
+      azure: false,
+      gemini: false,
+      cohere: false,
+      voyage: false,
+      huggingface: false,
+      openaiCompatible: false,
     },
     aiProviders: {
       anthropic: false,
@@ -72,6 +86,27 @@ export async function checkCredentials(): Promise<ConfigStatus> {
       if (cred.type === CredentialType.OPENROUTER_API) {
         status.embeddingProviders.openrouter = true;
       }
+      if (cred.type === CredentialType.OLLAMA_ENDPOINT) {
+        status.embeddingProviders.ollama = true;
+      }
+      if (cred.type === CredentialType.AZURE_OPENAI) {
+        status.embeddingProviders.azure = true;
+      }
+      if (cred.type === CredentialType.GEMINI_API) {
+        status.embeddingProviders.gemini = true;
+      }
+      if (cred.type === CredentialType.COHERE_API) {
+        status.embeddingProviders.cohere = true;
+      }
+      if (cred.type === CredentialType.VOYAGE_API) {
+        status.embeddingProviders.voyage = true;
+      }
+      if (cred.type === CredentialType.HUGGINGFACE_API) {
+        status.embeddingProviders.huggingface = true;
+      }
+      if (cred.type === CredentialType.OPENAI_COMPATIBLE) {
+        status.embeddingProviders.openaiCompatible = true;
+      }
       if (cred.type === CredentialType.ANTHROPIC_API) {
         status.aiProviders.anthropic = true;
       }
@@ -101,7 +136,7 @@ export async function checkCredentials(): Promise<ConfigStatus> {
 
   // Compute aggregate status
   status.hasGitPlatform = status.gitPlatforms.github || status.gitPlatforms.gitlab || status.gitPlatforms.bitbucket;
-  status.hasEmbeddings = status.embeddingProviders.openai || status.embeddingProviders.openrouter;
+  status.hasEmbeddings = status.embeddingProviders.openai || status.embeddingProviders.openrouter || status.embeddingProviders.ollama || status.embeddingProviders.azure || status.embeddingProviders.gemini || status.embeddingProviders.cohere || status.embeddingProviders.voyage || status.embeddingProviders.huggingface || status.embeddingProviders.openaiCompatible;
   status.allRequired = status.hasGitPlatform && status.hasEmbeddings;
 
   return status;
@@ -144,9 +179,31 @@ export function displayConfigStatus(status: ConfigStatus, verbose: boolean = fal
     if (status.embeddingProviders.openrouter) {
       console.log(chalk.green('    ✓ OpenRouter configured'));
     }
+    if (status.embeddingProviders.ollama) {
+      console.log(chalk.green('    ✓ Ollama configured (local)'));
+    }
+    if (status.embeddingProviders.azure) {
+      console.log(chalk.green('    ✓ Azure OpenAI configured'));
+    }
+    if (status.embeddingProviders.gemini) {
+      console.log(chalk.green('    ✓ Gemini configured'));
+    }
+    if (status.embeddingProviders.cohere) {
+      console.log(chalk.green('    ✓ Cohere configured'));
+    }
+    if (status.embeddingProviders.voyage) {
+      console.log(chalk.green('    ✓ Voyage AI configured'));
+    }
+    if (status.embeddingProviders.huggingface) {
+      console.log(chalk.green('    ✓ HuggingFace configured'));
+    }
+    if (status.embeddingProviders.openaiCompatible) {
+      console.log(chalk.green('    ✓ OpenAI-compatible gateway configured'));
+    }
   } else {
     console.log(chalk.yellow('    ⚠ No embedding provider configured'));
-    console.log(chalk.gray('      Run: cv auth setup openai'));
+    console.log(chalk.gray('      Run: cv auth setup ollama (local)'));
+    console.log(chalk.gray('       or: cv auth setup openai'));
     console.log(chalk.gray('       or: cv auth setup openrouter'));
   }
 
@@ -186,6 +243,13 @@ export function displayCompactStatus(status: ConfigStatus): void {
   // Embeddings
   if (status.embeddingProviders.openai) parts.push(chalk.green('OpenAI'));
   else if (status.embeddingProviders.openrouter) parts.push(chalk.green('OpenRouter'));
+  else if (status.embeddingProviders.ollama) parts.push(chalk.green('Ollama'));
+  else if (status.embeddingProviders.azure) parts.push(chalk.green('Azure OpenAI'));
+  else if (status.embeddingProviders.gemini) parts.push(chalk.green('Gemini'));
+  else if (status.embeddingProviders.cohere) parts.push(chalk.green('Cohere'));
+  else if (status.embeddingProviders.voyage) parts.push(chalk.green('Voyage AI'));
+  else if (status.embeddingProviders.huggingface) parts.push(chalk.green('HuggingFace'));
+  else if (status.embeddingProviders.openaiCompatible) parts.push(chalk.green('OpenAI-compatible'));
   else parts.push(chalk.yellow('No Embeddings'));
 
   console.log(chalk.gray('  Credentials: ') + parts.join(chalk.gray(' | ')));
@@ -0,0 +1,78 @@
+/**
+ * --context-only output shared by explain, do and review
+ * Prints the retrieved context with scores and citations and the exact
+ * prompt(s) a command would send, so retrieval can be tuned without a
+ * generation call
+ */
+
+import chalk from 'chalk';
+import { Command } from 'commander';
+import { estimateTokens } from '@cv-git/core';
+import { Context } from '@cv-git/shared';
+
+export interface PromptPreview {
+  /** What the prompt is for, e.g. "plan" */
+  label: string;
+  text: string;
+}
+
+/**
+ * Add --context-only to a command
+ */
+export function addContextOnlyOption(command: Command): Command {
+  return command.option('--context-only', 'Print the retrieved context and the prompt, then exit without calling the model');
+}
+
+/**
+ * Print (or emit as JSON) the context and prompts
+ */
+export function printContextPreview(context: Context, prompts: PromptPreview[], json: boolean = false): void {
+  const chunks = context.chunks.map(chunk => ({
+    citation: `${chunk.payload.file}:${chunk.payload.startLine}-${chunk.payload.endLine}`,
+    score: chunk.score,
+    symbolName: chunk.payload.symbolName,
+    tokens: estimateTokens(chunk.payload.text)
+  }));
+  const withTokens = prompts.map(prompt => ({ ...prompt, tokens: estimateTokens(prompt.text) }));
+  const totalTokens = withTokens.reduce((sum, prompt) => sum + prompt.tokens, 0);
+
+  if (json) {
+    console.log(JSON.stringify({
+      chunks,
+      symbols: context.symbols.map(symbol => ({ name: symbol.name, kind: symbol.kind, file: symbol.file })),
+      nearMissScore: context.nearMissScore ?? null,
+      redactedSecrets: context.redactedSecrets ?? 0,
+      budget: context.budget ?? null,
+      duplicates: context.duplicates ?? 0,
+      prompts: withTokens,
+      totalTokens
+    }, null, 2));
+    return;
+  }
+
+  console.log();
+  console.log(chalk.bold.cyan(`Retrieved Context (${chunks.length} chunks, ${context.symbols.length} symbols):`));
+  if (chunks.length === 0) {
+    console.log(chalk.gray('  (no chunks passed --min-score)'));
+  }
+  chunks.forEach((chunk, i) => {
+    const score = chunk.score.toFixed(3);
+    const symbol = chunk.symbolName ? chalk.gray(` ${chunk.symbolName}`) : '';
+    console.log(`  ${String(i + 1).padStart(2)}. ${chalk.yellow(score)} ${chalk.cyan(chunk.citation)}${symbol} ${chalk.gray(`~${chunk.tokens} tokens`)}`);
+  });
+  for (const symbol of context.symbols) {
+    console.log(chalk.gray(`      ↳ ${symbol.name} (${symbol.kind}) in ${symbol.file}`));
+  }
+
+  for (const prompt of withTokens) {
+    console.log();
+    console.log(chalk.bold.cyan(`Prompt: ${prompt.label} (~${prompt.tokens.toLocaleString()} tokens)`));
+    console.log(chalk.gray('─'.repeat(80)));
+    console.log(prompt.text);
+    console.log(chalk.gray('─'.repeat(80)));
+  }
+
+  console.log();
+  console.log(chalk.gray(`Total prompt size: ~${totalTokens.toLocaleString()} tokens (estimated at 4 characters per token). No model was called.`));
+  console.log();
+}
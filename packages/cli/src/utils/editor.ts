@@ -0,0 +1,130 @@
+/**
+ * Opening cited code in the user's editor (cv explain --open)
+ * The editor comes from $CV_EDITOR, $VISUAL or $EDITOR, and is passed the line
+ * in its own syntax; config editor.openCommand overrides both.
+ */
+
+import { spawn } from 'child_process';
+import * as path from 'path';
+import chalk from 'chalk';
+import inquirer from 'inquirer';
+import { CitedLocation } from '@cv-git/core';
+
+type LineArgs = (file: string, line: number) => string[];
+
+const gotoFlag: LineArgs = (file, line) => ['-g', `${file}:${line}`];
+const plusLine: LineArgs = (file, line) => [`+${line}`, file];
+const colonLine: LineArgs = (file, line) => [`${file}:${line}`];
+const lineFlag: LineArgs = (file, line) => ['--line', String(line), file];
+
+/** How known editors take a line, by executable name */
+const EDITOR_LINE_ARGS: Record<string, LineArgs> = {
+  code: gotoFlag,
+  'code-insiders': gotoFlag,
+  codium: gotoFlag,
+  cursor: gotoFlag,
+  windsurf: gotoFlag,
+  vi: plusLine,
+  vim: plusLine,
+  nvim: plusLine,
+  gvim: plusLine,
+  mvim: plusLine,
+  nano: plusLine,
+  micro: plusLine,
+  emacs: plusLine,
+  emacsclient: plusLine,
+  kak: plusLine,
+  gedit: plusLine,
+  subl: colonLine,
+  zed: colonLine,
+  hx: colonLine,
+  idea: lineFlag,
+  webstorm: lineFlag,
+  goland: lineFlag,
+  pycharm: lineFlag,
+  clion: lineFlag,
+  rider: lineFlag,
+  phpstorm: lineFlag,
+  rubymine: lineFlag,
+  mate: (file, line) => ['-l', String(line), file]
+};
+
+export interface EditorInvocation {
+  command: string;
+  args: string[];
+}
+
+/**
+ * Command line that opens `file` at `line`. A template uses {file} and {line},
+ * e.g. "subl {file}:{line}". Editors without a known line syntax get just the file.
+ */
+export function editorInvocation(file: string, line: number, template?: string): EditorInvocation {
+  if (template) {
+    const [command, ...args] = splitCommand(template)
+      .map(part => part.replace(/\{file\}/g, file).replace(/\{line\}/g, String(line)));
+    if (!command) {
+      throw new Error('editor.openCommand is empty');
+    }
+    return { command, args };
+  }
+
+  const [command, ...flags] = splitCommand(process.env.CV_EDITOR || process.env.VISUAL || process.env.EDITOR || 'vi');
+  const name = path.basename(command).replace(/\.(exe|cmd|bat)$/i, '').toLowerCase();
+  const lineArgs = EDITOR_LINE_ARGS[name];
+  return { command, args: [...flags, ...(lineArgs ? lineArgs(file, line) : [file])] };
+}
+
+/**
+ * Split a command on whitespace, keeping quoted parts together
+ */
+function splitCommand(command: string): string[] {
+  return [...command.matchAll(/"([^"]*)"|'([^']*)'|(\S+)/g)].map(m => m[1] ?? m[2] ?? m[3]);
+}
+
+/**
+ * Let the user choose among several locations; the most cited is the default.
+ * Without a terminal to ask on, the most cited is used.
+ */
+export async function pickLocation(locations: CitedLocation[]): Promise<CitedLocation | undefined> {
+  if (locations.length <= 1 || !process.stdin.isTTY) {
+    return locations[0];
+  }
+
+  const { location } = await inquirer.prompt([
+    {
+      type: 'list',
+      name: 'location',
+      message: 'Open which location?',
+      choices: [
+        ...locations.map(loc => ({
+          name: `${chalk.cyan(`${loc.file}:${loc.line}`)}${loc.symbol ? ` ${loc.symbol}` : ''}` +
+            (loc.mentions > 1 ? chalk.gray(` (cited ${loc.mentions} times)`) : ''),
+          value: loc
+        })),
+        new inquirer.Separator(),
+        { name: 'Cancel', value: undefined }
+      ]
+    }
+  ]);
+  return location;
+}
+
+/**
+ * Open a repo-relative location in the editor and wait for the editor to exit
+ * (terminal editors) or hand off (GUI editors)
+ */
+export async function openInEditor(repoRoot: string, location: CitedLocation, template?: string): Promise<void> {
+  const { command, args } = editorInvocation(path.join(repoRoot, location.file), location.line, template);
+  console.log(chalk.gray(`Opening ${location.file}:${location.line} in ${path.basename(command)}`));
+
+  await new Promise<void>((resolve, reject) => {
+    const child = spawn(command, args, { stdio: 'inherit' });
+    child.on('error', error => reject(new Error(`Could not start editor ${command}: ${error.message} (set $CV_EDITOR or editor.openCommand)`)));
+    child.on('exit', code => {
+      if (code !== 0 && code !== null) {
+        console.log(chalk.yellow(`⚠ Editor exited with code ${code}`));
+      }
+      resolve();
+    });
+  });
+}
@@ -0,0 +1,48 @@
+/**
+ * .env loading
+ * Before any command runs, variables from --env-file and then the
+ * repository's .env are added to the environment, without overriding ones
+ * already set. Config ${VAR} references and credential lookups then see
+ * keys kept in .env as if they had been exported.
+ */
+
+import * as path from 'path';
+import chalk from 'chalk';
+import { Command } from 'commander';
+import { loadDotenv, DotenvResult, createLogger } from '@cv-git/core';
+import { findRepoRoot } from '@cv-git/shared';
+
+export const DOTENV_FILE = '.env';
+
+const log = createLogger('env');
+
+/**
+ * Add --env-file to the program and load the env files before any command runs
+ */
+export function applyEnvFileOptions(program: Command): Command {
+  program
+    .option('--env-file <path>', 'Load variables from this file before the repository .env (set variables are never overridden)');
+
+  program.hook('preAction', async (_thisCommand, actionCommand) => {
+    const flags = actionCommand.optsWithGlobals();
+
+    try {
+      // The first file to set a variable wins, so --env-file goes before .env
+      const results: DotenvResult[] = [];
+      if (flags.envFile) {
+        results.push(await loadDotenv(path.resolve(flags.envFile), { required: true }));
+      }
+      const root = (await findRepoRoot()) ?? process.cwd();
+      results.push(await loadDotenv(path.join(root, DOTENV_FILE)));
+
+      for (const result of results.filter(r => r.loaded.length > 0)) {
+        log.debug(`Loaded ${result.file}`, { variables: result.loaded });
+      }
+    } catch (error: any) {
+      console.error(chalk.red(`Error: ${error.message}`));
+      process.exit(1);
+    }
+  });
+
+  return program;
+}
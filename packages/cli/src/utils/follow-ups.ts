@@ -0,0 +1,40 @@
+/**
+ * Follow-up suggestions after an answer (cv explain, cv chat)
+ * Adds --suggest/--no-suggest and prints the suggested questions
+ */
+
+import { Command } from 'commander';
+import chalk from 'chalk';
+import { CVConfig } from '@cv-git/shared';
+
+/**
+ * Add --suggest/--no-suggest to a command that answers questions
+ */
+export function addSuggestOption(command: Command): Command {
+  return command
+    .option('--suggest', 'Suggest follow-up questions about the indexed code after the answer (default: followUps.enabled)')
+    .option('--no-suggest', 'Do not suggest follow-up questions even when followUps.enabled is set');
+}
+
+/**
+ * Whether to suggest follow-ups: the flag, else config followUps.enabled
+ */
+export function resolveSuggest(flag: boolean | undefined, config: CVConfig): boolean {
+  return flag ?? config.followUps?.enabled ?? false;
+}
+
+/**
+ * Print the suggestions, numbered; `hint` says how to ask one
+ */
+export function printFollowUps(suggestions: string[], hint?: string): void {
+  if (suggestions.length === 0) return;
+
+  console.log(chalk.bold.cyan('Follow-up questions:'));
+  suggestions.forEach((question, i) => {
+    console.log(`  ${chalk.cyan(`${i + 1}.`)} ${question}`);
+  });
+  if (hint) {
+    console.log(chalk.gray(`  ${hint}`));
+  }
+  console.log();
+}
@@ -0,0 +1,99 @@
+/**
+ * Tests for syntax highlighting of code blocks in answers
+ */
+
+import { describe, it, expect, afterEach } from 'vitest';
+import { CodeBlockHighlighter, highlightCodeBlocks, resolveHighlightLanguage, shouldHighlight } from './highlight';
+
+const ESC = '\u001b[';
+const strip = (text: string) => text.replace(/\u001b\[\d+m/g, '');
+
+const answer = [
+  'The check hashes the password [1]:',
+  '',
+  '```ts',
+  'const ok = hash(password) === stored; // constant time',
+  '```',
+  '',
+  'Done.'
+].join('\n');
+
+describe('highlightCodeBlocks', () => {
+  it('should color code in fenced blocks and leave prose alone', () => {
+    const highlighted = highlightCodeBlocks(answer);
+    const lines = highlighted.split('\n');
+
+    expect(strip(highlighted)).toBe(answer);
+    expect(lines[0]).toBe('The check hashes the password [1]:');
+    expect(lines[6]).toBe('Done.');
+    expect(lines[3]).toContain(`${ESC}35mconst${ESC}39m`);
+    expect(lines[3]).toContain(`${ESC}34mhash${ESC}39m`);
+    expect(lines[3]).toContain(`${ESC}90m// constant time${ESC}39m`);
+  });
+
+  it('should use the default language for untagged blocks only', () => {
+    const untagged = '```\ndef run():\n    return None\n```';
+    expect(highlightCodeBlocks(untagged, 'Python')).toContain(`${ESC}35mdef${ESC}39m`);
+    expect(strip(highlightCodeBlocks(untagged))).toBe(untagged);
+    expect(highlightCodeBlocks(untagged)).not.toContain(`${ESC}35m`);
+
+    // A tag the highlighter does not know is printed as is
+    expect(highlightCodeBlocks('```diff\n- def old\n```', 'Python')).not.toContain(`${ESC}35m`);
+  });
+
+  it('should keep strings and block comments across lines', () => {
+    const code = '```go\n/* started\n   return */\nmsg := "if // not a comment"\n```';
+    const lines = highlightCodeBlocks(code).split('\n');
+
+    expect(lines[2]).toBe(`${ESC}90m   return */${ESC}39m`);
+    expect(lines[3]).toContain(`${ESC}32m"if // not a comment"${ESC}39m`);
+  });
+});
+
+describe('CodeBlockHighlighter', () => {
+  it('should give the same output when the answer streams in token by token', () => {
+    const highlighter = new CodeBlockHighlighter();
+    let streamed = '';
+    for (const token of answer.match(/.{1,3}/gs)!) {
+      streamed += highlighter.push(token);
+    }
+    streamed += highlighter.flush();
+
+    expect(streamed).toBe(highlightCodeBlocks(answer));
+  });
+
+  it('should print prose at once and hold code until its line ends', () => {
+    const highlighter = new CodeBlockHighlighter();
+
+    expect(highlighter.push('Some text')).toBe('Some text');
+    expect(highlighter.push('\n``')).toBe('\n');
+    expect(strip(highlighter.push('`ts\nconst x'))).toBe('```ts\n');
+    expect(strip(highlighter.push(' = 1;\n'))).toBe('const x = 1;\n');
+  });
+});
+
+describe('shouldHighlight', () => {
+  const noColor = process.env.NO_COLOR;
+
+  afterEach(() => {
+    if (noColor === undefined) delete process.env.NO_COLOR;
+    else process.env.NO_COLOR = noColor;
+  });
+
+  it('should highlight only on a terminal without --no-color or NO_COLOR', () => {
+    delete process.env.NO_COLOR;
+    expect(shouldHighlight({}, { isTTY: true })).toBe(true);
+    expect(shouldHighlight({}, { isTTY: false })).toBe(false);
+    expect(shouldHighlight({ color: false }, { isTTY: true })).toBe(false);
+
+    process.env.NO_COLOR = '1';
+    expect(shouldHighlight({}, { isTTY: true })).toBe(false);
+  });
+
+  it('should resolve fence tags and language names', () => {
+    expect(resolveHighlightLanguage('tsx')).toBe('typescript');
+    expect(resolveHighlightLanguage('Go')).toBe('go');
+    expect(resolveHighlightLanguage('C++')).toBe('c');
+    expect(resolveHighlightLanguage('text')).toBeUndefined();
+  });
+});
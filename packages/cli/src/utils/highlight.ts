@@ -0,0 +1,295 @@
+/**
+ * Syntax highlighting for code in answers
+ * Fenced code blocks in model output are colored by language when stdout
+ * is a terminal. Highlighting is line by line, so it works on a streamed
+ * answer: prose is printed as it arrives, code a line at a time. The
+ * highlighter is a tokenizer (keywords, strings, comments, numbers), not a
+ * parser; unknown languages are printed as they are.
+ */
+
+import { Command } from 'commander';
+import { Chalk } from 'chalk';
+
+/** Colors are only added once shouldHighlight has said so */
+const colors = new Chalk({ level: 1 });
+
+interface Grammar {
+  keywords: Set<string>;
+  /** Line comment prefix */
+  lineComment: '//' | '#' | '--';
+  /** Supports /* block comments *\/ */
+  blockComments: boolean;
+}
+
+const C_KEYWORDS = [
+  'auto', 'break', 'case', 'char', 'const', 'continue', 'default', 'do', 'double', 'else', 'enum', 'extern',
+  'float', 'for', 'goto', 'if', 'inline', 'int', 'long', 'return', 'short', 'signed', 'sizeof', 'static',
+  'struct', 'switch', 'typedef', 'union', 'unsigned', 'void', 'volatile', 'while', 'NULL', 'true', 'false',
+  'bool', 'class', 'namespace', 'template', 'typename', 'public', 'private', 'protected', 'virtual', 'override',
+  'new', 'delete', 'this', 'nullptr', 'using', 'try', 'catch', 'throw', 'include', 'define'
+];
+
+const GRAMMARS: Record<string, Grammar> = {
+  typescript: grammar('//', true, [
+    'abstract', 'as', 'async', 'await', 'break', 'case', 'catch', 'class', 'const', 'continue', 'default',
+    'delete', 'do', 'else', 'enum', 'export', 'extends', 'false', 'finally', 'for', 'from', 'function', 'if',
+    'implements', 'import', 'in', 'instanceof', 'interface', 'let', 'new', 'null', 'of', 'private', 'protected',
+    'public', 'readonly', 'return', 'static', 'super', 'switch', 'this', 'throw', 'true', 'try', 'type',
+    'typeof', 'undefined', 'var', 'void', 'while', 'yield', 'string', 'number', 'boolean', 'any', 'unknown', 'never'
+  ]),
+  python: grammar('#', false, [
+    'and', 'as', 'assert', 'async', 'await', 'break', 'class', 'continue', 'def', 'del', 'elif', 'else',
+    'except', 'False', 'finally', 'for', 'from', 'global', 'if', 'import', 'in', 'is', 'lambda', 'None',
+    'nonlocal', 'not', 'or', 'pass', 'raise', 'return', 'self', 'True', 'try', 'while', 'with', 'yield'
+  ]),
+  go: grammar('//', true, [
+    'break', 'case', 'chan', 'const', 'continue', 'default', 'defer', 'else', 'fallthrough', 'for', 'func',
+    'go', 'goto', 'if', 'import', 'interface', 'map', 'package', 'range', 'return', 'select', 'struct',
+    'switch', 'type', 'var', 'nil', 'true', 'false', 'error', 'string', 'int', 'int64', 'bool', 'byte'
+  ]),
+  rust: grammar('//', true, [
+    'as', 'async', 'await', 'break', 'const', 'continue', 'crate', 'dyn', 'else', 'enum', 'extern', 'false',
+    'fn', 'for', 'if', 'impl', 'in', 'let', 'loop', 'match', 'mod', 'move', 'mut', 'pub', 'ref', 'return',
+    'self', 'Self', 'static', 'struct', 'super', 'trait', 'true', 'type', 'unsafe', 'use', 'where', 'while',
+    'Some', 'None', 'Ok', 'Err'
+  ]),
+  java: grammar('//', true, [
+    'abstract', 'boolean', 'break', 'case', 'catch', 'class', 'const', 'continue', 'default', 'do', 'double',
+    'else', 'enum', 'extends', 'false', 'final', 'finally', 'float', 'for', 'if', 'implements', 'import',
+    'instanceof', 'int', 'interface', 'long', 'new', 'null', 'package', 'private', 'protected', 'public',
+    'return', 'static', 'super', 'switch', 'this', 'throw', 'throws', 'true', 'try', 'void', 'while',
+    // Kotlin, Scala and C# share most of these
+    'fun', 'val', 'var', 'object', 'override', 'namespace', 'using', 'string', 'async', 'await'
+  ]),
+  c: grammar('//', true, C_KEYWORDS),
+  ruby: grammar('#', false, [
+    'begin', 'break', 'case', 'class', 'def', 'do', 'else', 'elsif', 'end', 'ensure', 'false',
+    'for', 'if', 'in', 'module', 'next', 'nil', 'not', 'or', 'and', 'redo', 'rescue', 'retry', 'return',
+    'self', 'super', 'then', 'true', 'unless', 'until', 'when', 'while', 'yield', 'require'
+  ]),
+  bash: grammar('#', false, [
+    'if', 'then', 'else', 'elif', 'fi', 'for', 'while', 'until', 'do', 'done', 'case', 'esac', 'in',
+    'function', 'return', 'local', 'export', 'echo', 'exit', 'set', 'unset'
+  ]),
+  sql: grammar('--', true, [
+    'select', 'from', 'where', 'insert', 'into', 'values', 'update', 'set', 'delete', 'create', 'table',
+    'alter', 'drop', 'index', 'join', 'left', 'right', 'inner', 'outer', 'on', 'group', 'by', 'order',
+    'having', 'limit', 'and', 'or', 'not', 'null', 'as', 'distinct', 'primary', 'key', 'references',
+    'SELECT', 'FROM', 'WHERE', 'INSERT', 'INTO', 'VALUES', 'UPDATE', 'SET', 'DELETE', 'CREATE', 'TABLE',
+    'ALTER', 'DROP', 'INDEX', 'JOIN', 'LEFT', 'RIGHT', 'INNER', 'OUTER', 'ON', 'GROUP', 'BY', 'ORDER',
+    'HAVING', 'LIMIT', 'AND', 'OR', 'NOT', 'NULL', 'AS', 'DISTINCT', 'PRIMARY', 'KEY', 'REFERENCES'
+  ])
+};
+
+/** Fence tags, file extensions and language names, by grammar */
+const LANGUAGE_ALIASES: Record<string, string> = {
+  ts: 'typescript', tsx: 'typescript', typescript: 'typescript', mts: 'typescript', cts: 'typescript',
+  js: 'typescript', jsx: 'typescript', javascript: 'typescript', mjs: 'typescript', cjs: 'typescript', json: 'typescript',
+  py: 'python', python: 'python', python3: 'python',
+  go: 'go', golang: 'go',
+  rs: 'rust', rust: 'rust',
+  java: 'java', kt: 'java', kotlin: 'java', scala: 'java', cs: 'java', csharp: 'java', 'c#': 'java', swift: 'java',
+  c: 'c', h: 'c', cpp: 'c', 'c++': 'c', cc: 'c', hpp: 'c',
+  rb: 'ruby', ruby: 'ruby',
+  sh: 'bash', bash: 'bash', zsh: 'bash', shell: 'bash', console: 'bash',
+  sql: 'sql'
+};
+
+/** Opens or closes a fenced block: the fence and the tag after it */
+const FENCE_LINE = /^\s*(`{3,}|~{3,})\s*([^\s`]*)/;
+
+/** A partial line that may still turn out to be a fence */
+const FENCE_PREFIX = /^\s*(`{0,2}|~{0,2})$/;
+
+function grammar(lineComment: Grammar['lineComment'], blockComments: boolean, keywords: string[]): Grammar {
+  return { keywords: new Set(keywords), lineComment, blockComments };
+}
+
+/**
+ * Add --no-color to a command
+ */
+export function addColorOption(cmd: Command): Command {
+  return cmd.option('--no-color', 'Print plain text, without colors or syntax highlighting');
+}
+
+/**
+ * Whether to color output: not with --no-color, NO_COLOR set, or output
+ * that is not a terminal (piped or redirected)
+ */
+export function shouldHighlight(options: { color?: boolean }, stream: { isTTY?: boolean } = process.stdout): boolean {
+  return options.color !== false && !process.env.NO_COLOR && !!stream.isTTY;
+}
+
+/**
+ * The grammar key for a fence tag or language name (undefined if unknown)
+ */
+export function resolveHighlightLanguage(language: string | undefined): string | undefined {
+  if (!language) return undefined;
+  return LANGUAGE_ALIASES[language.trim().toLowerCase().replace(/^\./, '')];
+}
+
+/**
+ * Highlight the fenced code blocks in text. Blocks without a tag use the
+ * default language (the repo's, usually).
+ */
+export function highlightCodeBlocks(text: string, defaultLanguage?: string): string {
+  const highlighter = new CodeBlockHighlighter(defaultLanguage);
+  return highlighter.push(text) + highlighter.flush();
+}
+
+/**
+ * Highlights an answer as it streams in. push() returns what can be printed
+ * so far; code lines are held until they end, so a token is never colored
+ * halfway through.
+ */
+export class CodeBlockHighlighter {
+  private readonly defaultLanguage?: string;
+  private block: { fence: string; grammar?: Grammar; inBlockComment: boolean } | null = null;
+  /** The line being received */
+  private line = '';
+  /** Characters of that line already printed (prose only) */
+  private printed = 0;
+
+  constructor(defaultLanguage?: string) {
+    this.defaultLanguage = resolveHighlightLanguage(defaultLanguage);
+  }
+
+  push(text: string): string {
+    let out = '';
+    const pieces = text.split('\n');
+    pieces.forEach((piece, i) => {
+      this.line += piece;
+      if (i < pieces.length - 1) {
+        out += this.endLine() + '\n';
+      }
+    });
+
+    // Prose is printed as it arrives, unless it may open a fence
+    if (!this.block && !FENCE_PREFIX.test(this.line) && !(this.printed === 0 && FENCE_LINE.test(this.line))) {
+      out += this.line.slice(this.printed);
+      this.printed = this.line.length;
+    }
+    return out;
+  }
+
+  /** The rest of the last line, once the answer is complete */
+  flush(): string {
+    if (!this.line) return '';
+    const out = this.block ? this.highlightLine(this.line) : this.line.slice(this.printed);
+    this.line = '';
+    this.printed = 0;
+    return out;
+  }
+
+  private endLine(): string {
+    const line = this.line;
+    const printed = this.printed;
+    this.line = '';
+    this.printed = 0;
+
+    const fence = FENCE_LINE.exec(line);
+    if (!this.block) {
+      if (fence && printed === 0) {
+        const language = fence[2] ? resolveHighlightLanguage(fence[2]) : this.defaultLanguage;
+        this.block = { fence: fence[1], grammar: language ? GRAMMARS[language] : undefined, inBlockComment: false };
+        return colors.gray(line);
+      }
+      return line.slice(printed);
+    }
+
+    // A closing fence uses the same character, at least as many times, and no tag
+    if (fence && !fence[2] && fence[1][0] === this.block.fence[0] && fence[1].length >= this.block.fence.length) {
+      this.block = null;
+      return colors.gray(line);
+    }
+    return this.highlightLine(line);
+  }
+
+  private highlightLine(line: string): string {
+    const grammar = this.block?.grammar;
+    if (!grammar) return line;
+    const result = highlightLine(line, grammar, this.block!.inBlockComment);
+    this.block!.inBlockComment = result.inBlockComment;
+    return result.text;
+  }
+}
+
+/**
+ * One line of code, colored. Block comments may continue across lines.
+ */
+function highlightLine(line: string, grammar: Grammar, inBlockComment: boolean): { text: string; inBlockComment: boolean } {
+  let out = '';
+  let i = 0;
+
+  while (i < line.length) {
+    if (inBlockComment) {
+      const end = line.indexOf('*/', i);
+      const stop = end === -1 ? line.length : end + 2;
+      out += colors.gray(line.slice(i, stop));
+      inBlockComment = end === -1;
+      i = stop;
+      continue;
+    }
+
+    const rest = line.slice(i);
+    if (rest.startsWith(grammar.lineComment)) {
+      out += colors.gray(rest);
+      break;
+    }
+    if (grammar.blockComments && rest.startsWith('/*')) {
+      inBlockComment = true;
+      continue;
+    }
+
+    const char = line[i];
+    // Rust lifetimes ('a) look like an unterminated char literal
+    if (char === '"' || char === '`' || (char === "'" && line.indexOf("'", i + 1) !== -1)) {
+      const end = stringEnd(line, i);
+      out += colors.green(line.slice(i, end));
+      i = end;
+      continue;
+    }
+
+    const number = /^\d[\w.]*/.exec(rest);
+    if (number && !/[\w$]/.test(line[i - 1] ?? '')) {
+      out += colors.yellow(number[0]);
+      i += number[0].length;
+      continue;
+    }
+
+    const word = /^[A-Za-z_$][\w$]*/.exec(rest);
+    if (word) {
+      const name = word[0];
+      if (grammar.keywords.has(name)) {
+        out += colors.magenta(name);
+      } else if (/^\s*\(/.test(line.slice(i + name.length))) {
+        out += colors.blue(name);
+      } else {
+        out += name;
+      }
+      i += name.length;
+      continue;
+    }
+
+    out += char;
+    i++;
+  }
+
+  return { text: out, inBlockComment };
+}
+
+/**
+ * Index just past the end of the string literal starting at i (the end of
+ * the line if it is not closed on it)
+ */
+function stringEnd(line: string, start: number): number {
+  const quote = line[start];
+  for (let i = start + 1; i < line.length; i++) {
+    if (line[i] === '\\') {
+      i++;
+    } else if (line[i] === quote) {
+      return i + 1;
+    }
+  }
+  return line.length;
+}
@@ -0,0 +1,64 @@
+/**
+ * Tests for Ctrl-C handling of in-flight AI requests
+ */
+
+import { describe, it, expect, afterEach } from 'vitest';
+import { abortOnInterrupt, isAbortError } from './interrupt';
+
+describe('abortOnInterrupt', () => {
+  const listenersBefore = process.listenerCount('SIGINT');
+
+  afterEach(() => {
+    expect(process.listenerCount('SIGINT')).toBe(listenersBefore);
+  });
+
+  it('aborts the signal on SIGINT', () => {
+    const guard = abortOnInterrupt();
+    expect(guard.signal.aborted).toBe(false);
+
+    process.emit('SIGINT', 'SIGINT');
+
+    expect(guard.signal.aborted).toBe(true);
+    guard.dispose();
+  });
+
+  it('stops listening once disposed', () => {
+    const guard = abortOnInterrupt();
+    expect(process.listenerCount('SIGINT')).toBe(listenersBefore + 1);
+
+    guard.dispose();
+
+    expect(process.listenerCount('SIGINT')).toBe(listenersBefore);
+    expect(guard.signal.aborted).toBe(false);
+  });
+
+  it('gives each guard its own signal', () => {
+    const first = abortOnInterrupt();
+    first.dispose();
+    const second = abortOnInterrupt();
+
+    process.emit('SIGINT', 'SIGINT');
+
+    expect(first.signal.aborted).toBe(false);
+    expect(second.signal.aborted).toBe(true);
+    second.dispose();
+  });
+});
+
+describe('isAbortError', () => {
+  it('recognizes fetch and Anthropic SDK aborts', () => {
+    const controller = new AbortController();
+    controller.abort();
+
+    expect(isAbortError(controller.signal.reason)).toBe(true);
+    expect(isAbortError(Object.assign(new Error('Request was aborted.'), { name: 'APIUserAbortError' }))).toBe(true);
+  });
+
+  it('rejects other errors and non-errors', () => {
+    expect(isAbortError(new Error('rate limited'))).toBe(false);
+    expect(isAbortError(Object.assign(new Error('timed out'), { name: 'TimeoutError' }))).toBe(false);
+    expect(isAbortError(null)).toBe(false);
+    expect(isAbortError(undefined)).toBe(false);
+    expect(isAbortError('AbortError')).toBe(false);
+  });
+});
@@ -0,0 +1,36 @@
+/**
+ * Interrupt handling for in-flight AI requests
+ * Lets Ctrl-C abort a streaming response instead of killing the process mid-write
+ */
+
+export interface InterruptGuard {
+  /** Signal to pass to the AI client's stream handler */
+  signal: AbortSignal;
+  /** Stop listening for Ctrl-C */
+  dispose: () => void;
+}
+
+/**
+ * Abort the returned signal on the next SIGINT
+ */
+export function abortOnInterrupt(): InterruptGuard {
+  const controller = new AbortController();
+  const onInterrupt = () => controller.abort();
+
+  process.once('SIGINT', onInterrupt);
+
+  return {
+    signal: controller.signal,
+    dispose: () => {
+      process.removeListener('SIGINT', onInterrupt);
+    }
+  };
+}
+
+/**
+ * Check whether an error came from aborting a request
+ */
+export function isAbortError(error: unknown): boolean {
+  const name = (error as { name?: string } | null)?.name;
+  return name === 'AbortError' || name === 'APIUserAbortError';
+}
@@ -0,0 +1,37 @@
+/**
+ * Logging options for every command
+ * --log-level (or CV_LOG) sets which diagnostics are written, and
+ * --log-file sends them to a file instead of stderr. Command results stay
+ * on stdout either way. At debug, each API request is logged with its
+ * provider, model, endpoint, status and latency.
+ */
+
+import * as path from 'path';
+import chalk from 'chalk';
+import { Command } from 'commander';
+import { configureLogging, resolveLogLevel } from '@cv-git/core';
+
+/**
+ * Add --log-level and --log-file to the program and apply them before any command runs
+ */
+export function applyLoggingOptions(program: Command): Command {
+  program
+    .option('--log-level <level>', 'Diagnostics to write: error, warn, info or debug (default: CV_LOG, else warn)')
+    .option('--log-file <path>', 'Append diagnostics to this file as JSON lines instead of stderr');
+
+  program.hook('preAction', (_thisCommand, actionCommand) => {
+    const flags = actionCommand.optsWithGlobals();
+
+    try {
+      configureLogging({
+        level: resolveLogLevel(flags.logLevel),
+        file: flags.logFile ? path.resolve(flags.logFile) : undefined
+      });
+    } catch (error: any) {
+      console.error(chalk.red(`Error: ${error.message}`));
+      process.exit(1);
+    }
+  });
+
+  return program;
+}
@@ -0,0 +1,35 @@
+/**
+ * Model selection shared by AI commands
+ * Adds --model and resolves it against config.models.<command> so every
+ * command picks and validates models the same way
+ */
+
+import { Command } from 'commander';
+import { CVConfig } from '@cv-git/shared';
+import { ModelProvider, getAIProvider, validateModel } from '@cv-git/core';
+
+export { getAIProvider } from '@cv-git/core';
+
+export type ModelCommand = keyof NonNullable<CVConfig['models']>;
+
+/**
+ * Add -m/--model to a command
+ */
+export function addModelOption(command: Command, name: ModelCommand): Command {
+  return command.option('-m, --model <model>', `Model for this run (default: config models.${name}, then ai.model)`);
+}
+
+/**
+ * Model override for a command: --model, then config.models.<command>.
+ * Returns undefined when neither is set, so the caller's default applies.
+ * Throws with the provider's known models when the name is not recognized.
+ */
+export function resolveModel(
+  command: ModelCommand,
+  flag: string | undefined,
+  config: CVConfig,
+  provider: ModelProvider = getAIProvider(config)
+): string | undefined {
+  const model = flag ?? config.models?.[command];
+  return model ? validateModel(provider, model) : undefined;
+}
@@ -0,0 +1,52 @@
+/**
+ * Global network options
+ * --proxy and --ca-bundle apply to every command, so they are resolved once
+ * in a preAction hook together with the repo's `network` config and the
+ * HTTPS_PROXY / HTTP_PROXY / NO_PROXY environment.
+ */
+
+import chalk from 'chalk';
+import { Command } from 'commander';
+import { configManager, configureProxy, describeProxyError, resolveProxySettings } from '@cv-git/core';
+import { findRepoRoot } from '@cv-git/shared';
+
+/**
+ * Add --proxy and --ca-bundle to the program and apply them before any command runs
+ */
+export function applyNetworkOptions(program: Command): Command {
+  program
+    .option('--proxy <url>', 'Send API requests through this HTTP(S) proxy (default: HTTPS_PROXY/HTTP_PROXY)')
+    .option('--ca-bundle <path>', 'Trust the CA certificates in this PEM file, e.g. for a TLS-intercepting proxy');
+
+  program.hook('preAction', async (_thisCommand, actionCommand) => {
+    const flags = actionCommand.optsWithGlobals();
+
+    let network;
+    try {
+      const repoRoot = await findRepoRoot();
+      network = repoRoot ? (await configManager.load(repoRoot)).network : undefined;
+    } catch {
+      // Commands that need the config report load errors themselves
+    }
+
+    try {
+      configureProxy(resolveProxySettings({ proxy: flags.proxy, caBundle: flags.caBundle }, network));
+    } catch (error: any) {
+      console.error(chalk.red(`Error: ${error.message}`));
+      process.exit(1);
+    }
+  });
+
+  return program;
+}
+
+/**
+ * Print a proxy-aware explanation below a command's error, if it was a
+ * connection failure while a proxy or CA bundle is configured
+ */
+export function printProxyHint(error: unknown): void {
+  const hint = describeProxyError(error);
+  if (hint && hint !== (error as any)?.message) {
+    console.error(chalk.yellow(hint));
+  }
+}
@@ -0,0 +1,31 @@
+/**
+ * Global config selection options
+ * --config points every command at another config file and --profile picks
+ * one of its named profiles. Both are handed to core and the credential
+ * manager through CV_CONFIG / CV_PROFILE, so they must be applied before any
+ * other hook loads the config.
+ */
+
+import * as path from 'path';
+import { Command } from 'commander';
+
+/**
+ * Add --config and --profile to the program and export them before any command runs
+ */
+export function applyConfigOptions(program: Command): Command {
+  program
+    .option('--config <path>', 'Use this config file instead of .cv/config.json (env: CV_CONFIG)')
+    .option('--profile <name>', 'Use a named profile from the config, e.g. work or personal (env: CV_PROFILE)');
+
+  program.hook('preAction', (_thisCommand, actionCommand) => {
+    const flags = actionCommand.optsWithGlobals();
+    if (flags.config) {
+      process.env.CV_CONFIG = path.resolve(flags.config);
+    }
+    if (flags.profile) {
+      process.env.CV_PROFILE = flags.profile;
+    }
+  });
+
+  return program;
+}
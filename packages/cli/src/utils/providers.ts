@@ -0,0 +1,27 @@
+/**
+ * Chat providers shared by AI commands
+ * Resolves config ai.provider into the backend AIManager sends completions
+ * to, ai.providers into the backends it tries while ai.provider is down
+ * (both in @cv-git/core, shared with the MCP server), and logs which
+ * provider served each request
+ */
+
+import chalk from 'chalk';
+import { ProviderServed } from '@cv-git/core';
+
+export { resolveChatBackend, chatBackendOptions, resolveAIFallbacks } from '@cv-git/core';
+
+/**
+ * onServed/onEmbeddingServed callback printing the provider that answered
+ * each request, with any that were down before it (--verbose only)
+ */
+export function logProviderServed(options: { verbose?: boolean }, kind = 'AI'): ((info: ProviderServed) => void) | undefined {
+  if (!options.verbose) return undefined;
+
+  return ({ provider, model, failed }) => {
+    const after = failed.length > 0
+      ? ` after ${failed.map(f => `${f.provider} failed (${f.error.message})`).join(', ')}`
+      : '';
+    console.log(chalk.gray(`  ${kind} request served by ${provider} (${model})${after}`));
+  };
+}
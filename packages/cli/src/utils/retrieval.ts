@@ -0,0 +1,260 @@
+/**
+ * Retrieval options shared by context-gathering commands
+ * Adds --min-score, --top-k, --no-tests, --package, --kind, --ext, --rerank and --file and resolves them against config.search
+ */
+
+import * as fs from 'fs';
+import * as path from 'path';
+import { Command } from 'commander';
+import { CVConfig, SymbolKind } from '@cv-git/shared';
+import { TestChunkFilter, Reranker, createReranker, resolvePackageScope, resolveKindFilter, DEFAULT_RERANK_CANDIDATES } from '@cv-git/core';
+import { getCohereApiKey } from './credentials.js';
+
+export interface RetrievalFlags {
+  minScore?: string;
+  topK?: string;
+  ef?: string;
+  /** false for --no-tests, true for --tests */
+  tests?: boolean;
+  /** cv review --tests-only */
+  testsOnly?: boolean;
+  /** --package <dir>, relative to the cwd */
+  package?: string;
+  /** --kind, comma-separated */
+  kind?: string;
+  /** --ext, comma-separated, with or without the dot */
+  ext?: string;
+}
+
+export interface RetrievalSettings {
+  minScore: number;
+  topK: number;
+  /** Near-duplicate similarity from config.search (core default when unset) */
+  dedupeThreshold?: number;
+  /** HNSW candidate list size (store default when unset) */
+  efSearch?: number;
+  /** Leave out test file chunks, or retrieve only those (all chunks when unset) */
+  tests?: TestChunkFilter;
+  /** Repo-relative package directories to retrieve from (every package when unset) */
+  packages?: string[];
+  /** Symbol kinds to retrieve (chunks of any kind when unset) */
+  kinds?: SymbolKind[];
+  /** Lowercase file extensions with the dot (every file when unset) */
+  extensions?: string[];
+}
+
+/**
+ * Add --min-score, --top-k, --ef, --tests/--no-tests, --package, --kind and --ext to a command
+ */
+export function addRetrievalOptions(command: Command): Command {
+  return command
+    .option('--min-score <score>', 'Minimum similarity (0-1) for retrieved code (default: config search.minScore)')
+    .option('--top-k <n>', 'Number of code chunks to retrieve (default: config search.topK)')
+    .option('--ef <n>', 'HNSW search candidates; higher is slower with better recall (default: config search.efSearch)')
+    .option('--no-tests', 'Leave code from test files out of the retrieved context (default: config search.excludeTests)')
+    .option('--tests', 'Include code from test files even when search.excludeTests is set')
+    .option('--package <dir>', 'Only retrieve code from the package in this directory and the packages nested in it')
+    .option('--kind <kinds>', 'Only retrieve chunks of these symbol kinds, e.g. method or func,type (comma-separated)')
+    .option('--ext <extensions>', 'Only retrieve code from files with these extensions, e.g. .go or ts,tsx (comma-separated)');
+}
+
+/**
+ * Resolve retrieval settings: flag, then config.search, then the command's defaults.
+ * Throws on values outside the valid range so typos are not silently ignored.
+ * --package needs the repository root to find the packages in.
+ */
+export function resolveRetrieval(
+  flags: RetrievalFlags,
+  config: CVConfig['search'] | undefined,
+  defaults: RetrievalSettings,
+  repoRoot?: string | null
+): RetrievalSettings {
+  const minScore = flags.minScore !== undefined
+    ? parseFloat(flags.minScore)
+    : config?.minScore ?? defaults.minScore;
+  if (!Number.isFinite(minScore) || minScore < 0 || minScore > 1) {
+    throw new Error(`Invalid --min-score: ${flags.minScore ?? minScore} (expected a number between 0 and 1)`);
+  }
+
+  const topK = flags.topK !== undefined
+    ? parseInt(flags.topK, 10)
+    : config?.topK ?? defaults.topK;
+  if (!Number.isInteger(topK) || topK < 1) {
+    throw new Error(`Invalid --top-k: ${flags.topK ?? topK} (expected a positive integer)`);
+  }
+
+  const dedupeThreshold = config?.dedupeThreshold ?? defaults.dedupeThreshold;
+  if (dedupeThreshold !== undefined && (!Number.isFinite(dedupeThreshold) || dedupeThreshold < 0 || dedupeThreshold > 1)) {
+    throw new Error(`Invalid search.dedupeThreshold: ${dedupeThreshold} (expected a number between 0 and 1)`);
+  }
+
+  const efSearch = flags.ef !== undefined ? parseInt(flags.ef, 10) : config?.efSearch;
+  if (efSearch !== undefined && (!Number.isInteger(efSearch) || efSearch < 1)) {
+    throw new Error(`Invalid --ef: ${flags.ef ?? efSearch} (expected a positive integer)`);
+  }
+
+  if (flags.testsOnly && flags.tests === false) {
+    throw new Error('--tests-only cannot be combined with --no-tests');
+  }
+  const excludeTests = flags.tests === false || (flags.tests === undefined && config?.excludeTests === true);
+  const tests: TestChunkFilter | undefined = flags.testsOnly ? 'only' : excludeTests ? 'exclude' : undefined;
+
+  if (flags.package !== undefined && !repoRoot) {
+    throw new Error('--package needs a repository to find the package in');
+  }
+  const packages = flags.package !== undefined ? resolvePackageScope(repoRoot!, flags.package) : undefined;
+
+  const kinds = flags.kind !== undefined
+    ? [...new Set(splitList(flags.kind, '--kind').flatMap(kind => resolveKindFilter(kind)!))]
+    : undefined;
+  const extensions = flags.ext !== undefined
+    ? [...new Set(splitList(flags.ext, '--ext').map(ext => (ext.startsWith('.') ? ext : `.${ext}`).toLowerCase()))]
+    : undefined;
+
+  return { minScore, topK, dedupeThreshold, efSearch, tests, packages, kinds, extensions };
+}
+
+/**
+ * The values of a comma-separated flag; throws when there are none
+ */
+function splitList(value: string, flag: string): string[] {
+  const values = value.split(',').map(v => v.trim()).filter(Boolean);
+  if (values.length === 0) {
+    throw new Error(`${flag} needs at least one value`);
+  }
+  return values;
+}
+
+/**
+ * Add --rerank/--no-rerank to a command whose context goes through AIManager.gatherContext
+ */
+export function addRerankOption(command: Command): Command {
+  return command
+    .option('--rerank', 'Reorder retrieved code with the reranker in config search.rerank (default: search.rerank.enabled)')
+    .option('--no-rerank', 'Keep the vector search order even when search.rerank.enabled is set');
+}
+
+/**
+ * The reranker and candidate count for --rerank or config search.rerank,
+ * or null when reranking is off. Throws if Cohere is chosen without a key.
+ */
+export async function resolveReranker(
+  flag: boolean | undefined,
+  config: CVConfig['search'] | undefined
+): Promise<{ reranker: Reranker; candidates: number } | null> {
+  const settings = config?.rerank;
+  if (!(flag ?? settings?.enabled ?? false)) {
+    return null;
+  }
+
+  const candidates = settings?.candidates ?? DEFAULT_RERANK_CANDIDATES;
+  if (!Number.isInteger(candidates) || candidates < 1) {
+    throw new Error(`Invalid search.rerank.candidates: ${candidates} (expected a positive integer)`);
+  }
+
+  const provider = settings?.provider ?? 'cohere';
+  const apiKey = provider === 'cohere' ? await getCohereApiKey() : process.env.CV_RERANK_API_KEY;
+  const reranker = createReranker({
+    provider,
+    model: settings?.model,
+    url: settings?.url,
+    apiKey: apiKey || undefined
+  });
+  return { reranker, candidates };
+}
+
+/**
+ * Describe how reranking reordered the retrieved chunks, or null if it did not run
+ */
+export function formatRerankNote(rerank: { model: string; candidates: number; moved: number } | undefined, kept: number): string | null {
+  if (!rerank) {
+    return null;
+  }
+  const order = rerank.moved === 0
+    ? 'vector order unchanged'
+    : `${rerank.moved} of ${kept} moved from their vector position`;
+  return `Reranked ${rerank.candidates} candidate${rerank.candidates === 1 ? '' : 's'} with ${rerank.model}: ${order}`;
+}
+
+/**
+ * Describe the best rejected score when nothing passed the threshold,
+ * or null if the search returned nothing at all
+ */
+export function formatNearMiss(nearMissScore: number | undefined, minScore: number): string | null {
+  if (nearMissScore === undefined) {
+    return null;
+  }
+  const suggested = Math.max(0, Math.floor(nearMissScore * 100) / 100);
+  return `Best match scored ${nearMissScore.toFixed(3)}, below --min-score ${minScore}. ` +
+    `Try --min-score ${suggested} to include it.`;
+}
+
+/**
+ * Describe chunks left out or cut short to fit the model's context window,
+ * or null if everything fit
+ */
+export function formatBudgetNote(budget: { dropped: number; truncated: number; pinned?: number } | undefined): string | null {
+  if (!budget) {
+    return null;
+  }
+  const parts: string[] = [];
+  if (budget.dropped > 0) parts.push(`${budget.dropped} chunk${budget.dropped === 1 ? '' : 's'} dropped`);
+  if (budget.truncated > 0) parts.push(`${budget.truncated} truncated`);
+  return `${parts.join(', ')} to fit the model's context window (set ai.contextWindow to adjust)`;
+}
+
+/**
+ * Warn that --file code was cut because it alone exceeds the context
+ * window, or null if all of it fit. Shown even without --verbose.
+ */
+export function formatPinnedBudgetWarning(budget: { pinned?: number } | undefined): string | null {
+  if (!budget?.pinned) {
+    return null;
+  }
+  return `⚠ ${budget.pinned} chunk${budget.pinned === 1 ? '' : 's'} of the --file files cut: they alone exceed the model's context window (name fewer files, or set ai.contextWindow)`;
+}
+
+/**
+ * Describe chunks dropped as duplicates of better matches, or null if there were none
+ */
+export function formatDuplicateNote(duplicates: number | undefined): string | null {
+  if (!duplicates) {
+    return null;
+  }
+  return `${duplicates} duplicate chunk${duplicates === 1 ? '' : 's'} skipped (search.dedupeThreshold to adjust)`;
+}
+
+/**
+ * Add repeatable --file <path> to a command
+ */
+export function addFileScopeOption(command: Command): Command {
+  return command.option(
+    '--file <path>',
+    'Always include this file as context (repeatable)',
+    (value: string, previous: string[] = []) => [...previous, value]
+  );
+}
+
+/**
+ * Turn --file values (relative to the cwd) into repo-relative paths.
+ * Throws if a file does not exist or is outside the repository.
+ */
+export function resolveFileScope(files: string[] | undefined, repoRoot: string): string[] {
+  if (!files || files.length === 0) {
+    return [];
+  }
+
+  const resolved = files.map(file => {
+    const absolute = path.resolve(file);
+    const relative = path.relative(repoRoot, absolute);
+    if (relative.startsWith('..') || path.isAbsolute(relative)) {
+      throw new Error(`--file ${file} is outside the repository`);
+    }
+    if (!fs.existsSync(absolute) || !fs.statSync(absolute).isFile()) {
+      throw new Error(`--file ${file} not found`);
+    }
+    return relative.split(path.sep).join('/');
+  });
+
+  return Array.from(new Set(resolved));
+}
@@ -0,0 +1,142 @@
+/**
+ * Tests for the search results cv find prints and returns with --json
+ */
+
+import { describe, it, expect, beforeAll, afterAll } from 'vitest';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { makeSnippet, matchIndexedFiles, resolveFindFiles, toSearchMatch, toCommitMatch, SNIPPET_WIDTH } from './search-results';
+
+describe('makeSnippet', () => {
+  it('should keep the first three non-blank lines', () => {
+    const text = ['', 'function load(id) {', '   ', '  const row = db.get(id);', '  return row;', '}'].join('\n');
+
+    expect(makeSnippet(text)).toBe('function load(id) {\n  const row = db.get(id);\n  return row;');
+  });
+
+  it('should cut long lines to the terminal width', () => {
+    const line = 'x'.repeat(SNIPPET_WIDTH + 30);
+    const snippet = makeSnippet(`${line}\nshort`);
+    const [first, second] = snippet.split('\n');
+
+    expect(first).toHaveLength(SNIPPET_WIDTH);
+    expect(first.endsWith('…')).toBe(true);
+    expect(second).toBe('short');
+    expect(makeSnippet('x'.repeat(SNIPPET_WIDTH))).toBe('x'.repeat(SNIPPET_WIDTH));
+  });
+});
+
+describe('toSearchMatch', () => {
+  it('should give the location, score and symbol of a chunk with a snippet of its text', () => {
+    const match = toSearchMatch({
+      id: 'c1',
+      score: 0.82,
+      payload: {
+        id: 'c1',
+        file: 'src/auth.ts',
+        language: 'typescript',
+        startLine: 10,
+        endLine: 14,
+        symbolName: 'refreshToken',
+        symbolKind: 'function',
+        text: 'export function refreshToken() {\n  return sign();\n}\n\n// end',
+        imports: [],
+        lastModified: 0
+      }
+    });
+
+    expect(match).toEqual({
+      file: 'src/auth.ts',
+      startLine: 10,
+      endLine: 14,
+      score: 0.82,
+      symbolName: 'refreshToken',
+      symbolKind: 'function',
+      package: undefined,
+      language: 'typescript',
+      snippet: 'export function refreshToken() {\n  return sign();\n}'
+    });
+  });
+});
+
+describe('toCommitMatch', () => {
+  it('should give the subject line and the commit date', () => {
+    const match = toCommitMatch({
+      id: 'abc',
+      score: 0.7,
+      payload: {
+        id: 'abc',
+        sha: 'abc1234def',
+        message: 'Set token expiry to 24h\n\nShorter expiry logged users out mid-session.',
+        author: 'Dana',
+        timestamp: Date.UTC(2024, 2, 5, 12),
+        filesChanged: ['src/auth.ts'],
+        symbolsChanged: []
+      }
+    });
+
+    expect(match).toEqual({
+      sha: 'abc1234def',
+      author: 'Dana',
+      date: '2024-03-05',
+      score: 0.7,
+      subject: 'Set token expiry to 24h',
+      files: ['src/auth.ts']
+    });
+  });
+});
+
+describe('matchIndexedFiles', () => {
+  const files = ['src/auth/login.ts', 'src/auth/session.ts', 'src/db.ts', 'src/auth/login.ts'];
+
+  it('should match every indexed file whose path contains the pattern', () => {
+    expect(matchIndexedFiles('auth/', files)).toEqual(['src/auth/login.ts', 'src/auth/session.ts']);
+    expect(matchIndexedFiles('login', files)).toEqual(['src/auth/login.ts']);
+  });
+
+  it('should accept ./ prefixes and backslashes', () => {
+    expect(matchIndexedFiles('./src\\db.ts', files)).toEqual(['src/db.ts']);
+  });
+
+  it('should match nothing for an unknown path', () => {
+    expect(matchIndexedFiles('billing', files)).toEqual([]);
+  });
+});
+
+describe('resolveFindFiles', () => {
+  let repoRoot: string;
+  const indexed = ['src/auth/login.ts', 'src/auth/session.ts', 'src/db.ts', 'test/db.ts'];
+
+  beforeAll(() => {
+    repoRoot = fs.mkdtempSync(path.join(os.tmpdir(), 'cv-find-files-'));
+    fs.mkdirSync(path.join(repoRoot, 'src'));
+    fs.writeFileSync(path.join(repoRoot, 'src', 'db.ts'), 'export const db = {};\n');
+  });
+
+  afterAll(() => {
+    fs.rmSync(repoRoot, { recursive: true, force: true });
+  });
+
+  it('should match an existing file exactly', () => {
+    expect(resolveFindFiles([path.join(repoRoot, 'src', 'db.ts')], repoRoot, indexed)).toEqual(['src/db.ts']);
+  });
+
+  it('should combine repeated values, exact and partial', () => {
+    const files = resolveFindFiles([path.join(repoRoot, 'src', 'db.ts'), 'auth/', 'login'], repoRoot, indexed);
+
+    expect(files).toEqual(['src/db.ts', 'src/auth/login.ts', 'src/auth/session.ts']);
+  });
+
+  it('should pass a partial path on as is without an index snapshot', () => {
+    expect(resolveFindFiles(['./src/auth/login.ts'], repoRoot, [])).toEqual(['src/auth/login.ts']);
+  });
+
+  it('should reject an existing file outside the repository', () => {
+    const outside = fs.mkdtempSync(path.join(os.tmpdir(), 'cv-find-outside-'));
+    fs.writeFileSync(path.join(outside, 'a.ts'), '');
+
+    expect(() => resolveFindFiles([path.join(outside, 'a.ts')], repoRoot, indexed)).toThrow('outside the repository');
+    fs.rmSync(outside, { recursive: true, force: true });
+  });
+});
@@ -0,0 +1,105 @@
+/**
+ * Search results of cv find
+ * Code chunks and commits as printed and as --json returns them: location,
+ * score, and a short snippet instead of the whole chunk text.
+ */
+
+import * as fs from 'fs';
+import * as path from 'path';
+import { VectorSearchResult, CodeChunkPayload, CommitPayload } from '@cv-git/shared';
+import { resolveFileScope } from './retrieval.js';
+
+export const SNIPPET_LINES = 3;
+export const SNIPPET_WIDTH = 120;
+
+export interface SearchMatch {
+  file: string;
+  startLine: number;
+  endLine: number;
+  score: number;
+  symbolName?: string;
+  symbolKind?: string;
+  package?: string;
+  language: string;
+  snippet: string;
+}
+
+export interface CommitMatch {
+  sha: string;
+  author: string;
+  date: string;
+  score: number;
+  subject: string;
+  files: string[];
+}
+
+export function toSearchMatch(result: VectorSearchResult<CodeChunkPayload>): SearchMatch {
+  const { payload } = result;
+  return {
+    file: payload.file,
+    startLine: payload.startLine,
+    endLine: payload.endLine,
+    score: result.score,
+    symbolName: payload.symbolName,
+    symbolKind: payload.symbolKind,
+    package: payload.package,
+    language: payload.language,
+    snippet: makeSnippet(payload.text)
+  };
+}
+
+export function toCommitMatch(result: VectorSearchResult<CommitPayload>): CommitMatch {
+  const { payload } = result;
+  return {
+    sha: payload.sha,
+    author: payload.author,
+    date: new Date(payload.timestamp).toISOString().slice(0, 10),
+    score: result.score,
+    subject: payload.message.split('\n')[0],
+    files: payload.filesChanged
+  };
+}
+
+/**
+ * Indexed files whose repo-relative path contains `pattern` (cv find --file
+ * is a partial match; the vector store only filters on whole paths)
+ */
+export function matchIndexedFiles(pattern: string, files: Iterable<string>): string[] {
+  const needle = pattern.replace(/\\/g, '/').replace(/^\.\//, '');
+  return Array.from(new Set(files)).filter(file => file.includes(needle)).sort();
+}
+
+/**
+ * Files cv find searches for its --file values. A value naming an existing
+ * file is resolved like cv explain --file (relative to the cwd, exact); any
+ * other value is part of a path and matches every indexed file containing
+ * it, or is passed on as is when there is no index snapshot to match against.
+ */
+export function resolveFindFiles(values: string[], repoRoot: string, indexedFiles: Iterable<string>): string[] {
+  const isFile = (value: string) => {
+    const absolute = path.resolve(value);
+    return fs.existsSync(absolute) && fs.statSync(absolute).isFile();
+  };
+  const indexed = Array.from(indexedFiles);
+
+  const files = resolveFileScope(values.filter(isFile), repoRoot);
+  for (const pattern of values.filter(value => !isFile(value))) {
+    files.push(...(indexed.length > 0
+      ? matchIndexedFiles(pattern, indexed)
+      : [pattern.replace(/\\/g, '/').replace(/^\.\//, '')]));
+  }
+
+  return Array.from(new Set(files));
+}
+
+/**
+ * First few non-blank lines, each truncated to a terminal-friendly width
+ */
+export function makeSnippet(text: string): string {
+  return text
+    .split('\n')
+    .filter(line => line.trim().length > 0)
+    .slice(0, SNIPPET_LINES)
+    .map(line => line.length > SNIPPET_WIDTH ? line.slice(0, SNIPPET_WIDTH - 1) + '…' : line)
+    .join('\n');
+}
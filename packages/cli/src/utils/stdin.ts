@@ -0,0 +1,37 @@
+/**
+ * Code piped on stdin
+ * `-` as the file argument of cv explain and cv review reads the code to
+ * work on from stdin instead of the repository
+ */
+
+import { Command } from 'commander';
+
+/** File argument that reads stdin */
+export const STDIN_ARG = '-';
+
+/**
+ * Add --language for hinting the language of piped code
+ */
+export function addLanguageOption(cmd: Command): Command {
+  return cmd.option('--language <lang>', 'Language of code piped with `-` (e.g. go, python, ts)');
+}
+
+/**
+ * Read all of stdin. Fails instead of waiting on a terminal when nothing is piped.
+ */
+export async function readStdin(): Promise<string> {
+  if (process.stdin.isTTY) {
+    throw new Error('Nothing piped to stdin (e.g. `cat file.go | cv review -`)');
+  }
+
+  const chunks: Buffer[] = [];
+  for await (const chunk of process.stdin) {
+    chunks.push(typeof chunk === 'string' ? Buffer.from(chunk) : chunk);
+  }
+
+  const content = Buffer.concat(chunks).toString('utf-8');
+  if (!content.trim()) {
+    throw new Error('No code on stdin');
+  }
+  return content;
+}
@@ -0,0 +1,184 @@
+/**
+ * cv sync --watch
+ * Keeps the index fresh while you edit: file events are batched (see
+ * ChangeBatcher) and each batch runs a delta sync, which re-embeds only the
+ * files whose content changed. One status line is printed per batch.
+ */
+
+import chalk from 'chalk';
+import * as path from 'path';
+import { watch } from 'chokidar';
+import {
+  SyncEngine,
+  SyncOptions,
+  IgnoreRules,
+  ChangeBatcher,
+  WatchEvent,
+  WatchBatch,
+  CVIGNORE_FILE,
+  isEditorTempFile,
+  fileFilterMiss
+} from '@cv-git/core';
+
+/** Never watched, whatever the ignore files say */
+const ALWAYS_IGNORED = ['.git', '.cv', 'node_modules'];
+
+/** Staging or committing changes which files git tracks, and so what is indexed */
+const GIT_INDEX = path.join('.git', 'index');
+
+export interface SyncWatchOptions {
+  /** Options for each delta sync (file filter, size limit, ...) */
+  syncOptions: SyncOptions;
+  debounceMs: number;
+  /** Show the sync engine's own progress and each ignored event */
+  verbose?: boolean;
+}
+
+/**
+ * Watch the repository and sync each batch of changes until Ctrl-C. The
+ * first Ctrl-C stops watching, lets the batch in flight finish and syncs
+ * what is still pending; the promise then resolves so the caller can write
+ * the index to disk. A second Ctrl-C exits at once.
+ */
+export async function watchAndSync(repoRoot: string, engine: SyncEngine, options: SyncWatchOptions): Promise<void> {
+  let rules = await IgnoreRules.load(repoRoot);
+  const batcher = new ChangeBatcher(options.debounceMs);
+  let timer: NodeJS.Timeout | undefined;
+  let running: Promise<void> | null = null;
+  let stopping = false;
+  // console.log is muted while a batch syncs; these messages are not sync progress
+  const print = console.log;
+
+  const isIgnoredPath = (relative: string) =>
+    ALWAYS_IGNORED.some(dir => relative === dir || relative.startsWith(dir + path.sep)) || rules.match(relative) !== null;
+
+  const start = (batch: WatchBatch) => {
+    running = syncBatch(engine, batch, options).finally(() => {
+      running = null;
+      schedule();
+    });
+  };
+
+  const flush = () => {
+    // A batch in flight picks up the rest when it finishes
+    if (running || batcher.size === 0) return;
+    start(batcher.take());
+  };
+
+  const schedule = () => {
+    if (timer) clearTimeout(timer);
+    timer = undefined;
+    const due = batcher.dueIn();
+    if (due === null || stopping) return;
+    timer = setTimeout(flush, due);
+  };
+
+  const onEvent = (event: WatchEvent, absolutePath: string) => {
+    const relative = path.relative(repoRoot, absolutePath);
+    const name = path.basename(relative);
+
+    if (name === '.gitignore' || name === CVIGNORE_FILE) {
+      // Files may have moved in or out of the index; the next delta sync sees which
+      void IgnoreRules.load(repoRoot).then(loaded => { rules = loaded; });
+    } else if (isEditorTempFile(relative) || isIgnoredPath(relative) || fileFilterMiss(relative, options.syncOptions.fileFilter)) {
+      if (options.verbose) {
+        print(chalk.gray(`  Ignored ${event} ${relative}`));
+      }
+      return;
+    }
+
+    batcher.record(event, relative);
+    schedule();
+  };
+
+  const watcher = watch(repoRoot, {
+    ignored: (absolutePath: string) => {
+      const relative = path.relative(repoRoot, absolutePath);
+      return relative !== '' && isIgnoredPath(relative);
+    },
+    persistent: true,
+    ignoreInitial: true,
+    // Wait for writes to settle so half-written files are not synced
+    awaitWriteFinish: { stabilityThreshold: 100, pollInterval: 50 }
+  });
+  const gitIndexWatcher = watch(path.join(repoRoot, GIT_INDEX), { persistent: true, ignoreInitial: true });
+
+  watcher
+    .on('add', file => onEvent('add', file))
+    .on('change', file => onEvent('change', file))
+    .on('unlink', file => onEvent('unlink', file))
+    .on('error', (error: unknown) => {
+      console.error(chalk.red(`Watcher error: ${error instanceof Error ? error.message : String(error)}`));
+    });
+  gitIndexWatcher.on('change', () => {
+    batcher.record('change', GIT_INDEX);
+    schedule();
+  });
+
+  await new Promise<void>(resolve => watcher.once('ready', () => resolve()));
+  print(chalk.green(`Watching ${repoRoot} for changes`) + chalk.gray(' (Ctrl-C to stop)'));
+
+  // Catch up with what changed since the last sync
+  print(chalk.gray('Catching up with changes since the last sync...'));
+  start({ added: [], modified: [], deleted: [] });
+
+  await new Promise<void>(resolve => {
+    process.once('SIGINT', async () => {
+      stopping = true;
+      if (timer) clearTimeout(timer);
+      process.once('SIGINT', () => {
+        console.error(chalk.yellow('\n✖ Sync cancelled'));
+        process.exit(130);
+      });
+
+      print(chalk.gray('\nStopping watcher...'));
+      await watcher.close();
+      await gitIndexWatcher.close();
+
+      // Finish the batch in flight, then sync what is still pending
+      await running;
+      if (batcher.size > 0) {
+        await syncBatch(engine, batcher.take(), options);
+      }
+      resolve();
+    });
+  });
+}
+
+/**
+ * Delta sync one batch and print its status line
+ */
+async function syncBatch(engine: SyncEngine, batch: WatchBatch, options: SyncWatchOptions): Promise<void> {
+  const time = chalk.gray(new Date().toLocaleTimeString());
+  if (options.verbose) {
+    const files = [...batch.added, ...batch.modified, ...batch.deleted].filter(file => file !== GIT_INDEX);
+    console.log(chalk.gray(`${new Date().toLocaleTimeString()} syncing ${files.join(', ') || 'git index change'}`));
+  }
+
+  // The engine logs every step of a sync; one line per batch is enough here
+  const log = console.log;
+  if (!options.verbose) console.log = () => {};
+  try {
+    const result = await engine.deltaSync(options.syncOptions);
+    console.log = log;
+
+    const { added, modified, deleted } = result.delta;
+    const summary = [
+      added.length > 0 ? chalk.green(`${added.length} added`) : null,
+      modified.length > 0 ? chalk.yellow(`${modified.length} modified`) : null,
+      deleted.length > 0 ? chalk.red(`${deleted.length} deleted`) : null
+    ].filter(Boolean).join(', ');
+    if (!summary) {
+      log(`${time} ${chalk.gray('· no indexed content changed')}`);
+      return;
+    }
+
+    const changed = [...added, ...modified, ...deleted];
+    const files = changed.length <= 3 ? changed.join(', ') : `${changed.slice(0, 2).join(', ')} +${changed.length - 2} more`;
+    const errors = result.errors.length > 0 ? chalk.yellow(` · ${result.errors.length} error${result.errors.length === 1 ? '' : 's'}`) : '';
+    log(`${time} ${chalk.green('✔')} ${summary} ${chalk.gray(`(${files}) · ${result.vectorCount} vectors · ${(result.syncDuration ?? 0).toFixed(1)}s`)}${errors}`);
+  } catch (error: any) {
+    console.log = log;
+    log(`${time} ${chalk.red('✖')} ${chalk.red(`Sync failed: ${error.message}`)}`);
+  }
+}
@@ -0,0 +1,85 @@
+/**
+ * Custom system prompts shared by AI commands
+ * Adds --system-prompt, --system-prompt-file, --prompt and --raw-prompt and
+ * resolves them against config.prompts.<command>
+ */
+
+import * as fs from 'fs/promises';
+import { Command } from 'commander';
+import { CVConfig, Context, findRepoRoot } from '@cv-git/shared';
+import { SystemPromptOptions, applySystemPrompt, loadNamedPrompt } from '@cv-git/core';
+import { PromptPreview } from './context-preview.js';
+
+export type PromptCommand = keyof NonNullable<CVConfig['prompts']>;
+
+/**
+ * Add the system prompt options to a command
+ */
+export function addSystemPromptOptions(command: Command, name: PromptCommand): Command {
+  return command
+    .option('--system-prompt <text>', `Custom system prompt for this run (default: config prompts.${name})`)
+    .option('--system-prompt-file <path>', 'Read the custom system prompt from a file')
+    .option('--prompt <name>', 'Use the named prompt in .cv/prompts/<name>.md (see cv prompts list)')
+    .option('--raw-prompt', 'Send the custom prompt in place of the built-in instructions instead of alongside them');
+}
+
+/**
+ * The custom system prompt for a command: --system-prompt-file, --prompt or
+ * --system-prompt, then config.prompts.<command>. Undefined when none is set.
+ * `variables` fill placeholders the command knows up front, such as {{query}}.
+ */
+export async function resolveSystemPrompt(
+  name: PromptCommand,
+  options: { systemPrompt?: string; systemPromptFile?: string; prompt?: string; rawPrompt?: boolean },
+  config: CVConfig,
+  variables?: Record<string, string>
+): Promise<SystemPromptOptions | undefined> {
+  const given = [
+    options.systemPrompt !== undefined ? '--system-prompt' : '',
+    options.systemPromptFile ? '--system-prompt-file' : '',
+    options.prompt ? '--prompt' : ''
+  ].filter(Boolean);
+  if (given.length > 1) {
+    throw new Error(`${given.join(' and ')} cannot be combined`);
+  }
+
+  let template = options.systemPrompt ?? config.prompts?.[name];
+  if (options.prompt) {
+    template = await loadNamedPrompt((await findRepoRoot()) ?? process.cwd(), options.prompt);
+  }
+  if (options.systemPromptFile) {
+    try {
+      template = await fs.readFile(options.systemPromptFile, 'utf-8');
+    } catch (error: any) {
+      throw new Error(`Cannot read --system-prompt-file ${options.systemPromptFile}: ${error.message}`);
+    }
+  }
+
+  if (!template?.trim()) {
+    if (options.rawPrompt) {
+      throw new Error(`--raw-prompt needs a custom prompt (--system-prompt, --system-prompt-file, --prompt, or config prompts.${name})`);
+    }
+    return undefined;
+  }
+
+  return { template, raw: !!options.rawPrompt, command: name, repo: config.repository?.name, variables };
+}
+
+/**
+ * The prompts a --context-only preview shows, with the custom system prompt
+ * applied. Without an input (prompts whose response is parsed) raw mode
+ * doesn't apply, as in AIManager.
+ */
+export function previewPrompts(
+  systemPrompt: SystemPromptOptions | undefined,
+  label: string,
+  prompt: string,
+  context: Context,
+  input?: string
+): PromptPreview[] {
+  const request = applySystemPrompt(systemPrompt, prompt, input, context);
+  return [
+    ...(request.system ? [{ label: 'system', text: request.system }] : []),
+    { label, text: request.prompt }
+  ];
+}
@@ -0,0 +1,62 @@
+/**
+ * Request timeouts shared by AI commands
+ * Adds --timeout and resolves it against config ai.timeout / embedding.timeout
+ * so a stuck provider fails with a timeout error instead of hanging
+ */
+
+import chalk from 'chalk';
+import { Command, InvalidArgumentError } from 'commander';
+import {
+  CVConfig,
+  RequestTimeoutError,
+  DEFAULT_CHAT_TIMEOUT_MS,
+  DEFAULT_EMBEDDING_TIMEOUT_MS,
+  timeoutFromSeconds
+} from '@cv-git/shared';
+
+/**
+ * Add --timeout <seconds> to a command
+ */
+export function addTimeoutOption(command: Command, kind: 'chat' | 'embedding' = 'chat'): Command {
+  const setting = kind === 'chat' ? 'ai.timeout' : 'embedding.timeout';
+  const defaultSeconds = (kind === 'chat' ? DEFAULT_CHAT_TIMEOUT_MS : DEFAULT_EMBEDDING_TIMEOUT_MS) / 1000;
+  return command.option(
+    '--timeout <seconds>',
+    `Cancel a request that takes longer than this, 0 for no limit (default: config ${setting}, then ${defaultSeconds})`,
+    parseTimeoutSeconds
+  );
+}
+
+/**
+ * Parse a --timeout value in seconds
+ */
+export function parseTimeoutSeconds(value: string): number {
+  const seconds = Number(value);
+  if (value.trim() === '' || !Number.isFinite(seconds) || seconds < 0) {
+    throw new InvalidArgumentError('expected a number of seconds (0 for no timeout)');
+  }
+  return seconds;
+}
+
+/**
+ * Chat request timeout in milliseconds: --timeout, then config ai.timeout
+ */
+export function resolveChatTimeout(config: CVConfig, flag?: number): number {
+  return timeoutFromSeconds(flag ?? config.ai?.timeout, DEFAULT_CHAT_TIMEOUT_MS);
+}
+
+/**
+ * Embeddings request timeout in milliseconds: --timeout, then config embedding.timeout
+ */
+export function resolveEmbeddingTimeout(config: CVConfig, flag?: number): number {
+  return timeoutFromSeconds(flag ?? config.embedding?.timeout, DEFAULT_EMBEDDING_TIMEOUT_MS);
+}
+
+/**
+ * Print how to raise the limit below a command's error, if it was a request timeout
+ */
+export function printTimeoutHint(error: unknown, setting: 'ai.timeout' | 'embedding.timeout' = 'ai.timeout'): void {
+  if (error instanceof RequestTimeoutError) {
+    console.error(chalk.yellow(`Raise the limit with --timeout <seconds> or ${setting} in .cv/config.json (0 for no limit)`));
+  }
+}
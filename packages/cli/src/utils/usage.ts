@@ -0,0 +1,69 @@
+/**
+ * Usage tracking for every command
+ * A preAction hook starts a usage session named after the command, and an
+ * exit handler appends its requests to .cv/usage.jsonl (unless
+ * `usage.enabled` is false). With `usage.footer`, a one-line cost summary is
+ * printed to stderr once the command finishes.
+ */
+
+import chalk from 'chalk';
+import { Command } from 'commander';
+import {
+  configManager,
+  startUsageSession,
+  flushUsageLog,
+  getSessionUsage,
+  getUsageLogPath,
+  formatUsageFooter
+} from '@cv-git/core';
+import { findRepoRoot } from '@cv-git/shared';
+
+/**
+ * Full name of a subcommand, e.g. "index status"
+ */
+function commandPath(command: Command): string {
+  const names: string[] = [];
+  for (let current: Command | null = command; current?.parent; current = current.parent) {
+    names.unshift(current.name());
+  }
+  return names.join(' ') || command.name();
+}
+
+/**
+ * Record API usage of whichever command runs
+ */
+export function applyUsageTracking(program: Command): Command {
+  let footer = false;
+  let quiet = false;
+
+  program.hook('preAction', async (_thisCommand, actionCommand) => {
+    const flags = actionCommand.optsWithGlobals();
+    quiet = !!(flags.json || flags.quiet);
+
+    let logFile: string | null = null;
+    try {
+      const repoRoot = await findRepoRoot();
+      if (repoRoot) {
+        const usage = (await configManager.load(repoRoot)).usage;
+        logFile = usage?.enabled === false ? null : getUsageLogPath(repoRoot);
+        footer = usage?.footer === true;
+      }
+    } catch {
+      // Commands that need the config report load errors themselves
+    }
+
+    startUsageSession(commandPath(actionCommand), logFile);
+  });
+
+  // Runs after process.exit() too, which many commands call directly
+  process.on('exit', () => {
+    flushUsageLog();
+
+    const line = footer && !quiet ? formatUsageFooter(getSessionUsage()) : null;
+    if (line) {
+      process.stderr.write(chalk.gray(`${line}\n`));
+    }
+  });
+
+  return program;
+}
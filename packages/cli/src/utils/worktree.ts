@@ -0,0 +1,68 @@
+/**
+ * Worktree checks
+ * A worktree nested inside another checkout (e.g. repo/.worktrees/feature)
+ * without its own .cv finds the outer checkout's index when cv walks up
+ * from the cwd. These helpers detect that so commands don't silently use,
+ * or sync, an index for a different tree.
+ */
+
+import * as path from 'path';
+import chalk from 'chalk';
+import inquirer from 'inquirer';
+import { createGitManager } from '@cv-git/core';
+import { WorktreeInfo } from '@cv-git/shared';
+
+export interface WorktreeMismatch {
+  /** Worktree the command was run in */
+  worktree: WorktreeInfo;
+  /** Checkout the CV index found belongs to */
+  indexRoot: string;
+}
+
+/**
+ * The worktree containing cwd, if it is not the one repoRoot's index covers
+ */
+export async function findWorktreeMismatch(repoRoot: string, cwd: string = process.cwd()): Promise<WorktreeMismatch | null> {
+  let worktree: WorktreeInfo;
+  try {
+    worktree = await createGitManager(cwd).getWorktreeInfo();
+  } catch {
+    return null;
+  }
+
+  // cv init in a subdirectory of a checkout is fine; a checkout inside the indexed tree is not
+  const relative = path.relative(path.resolve(repoRoot), worktree.path);
+  if (!relative || relative.startsWith('..') || path.isAbsolute(relative)) {
+    return null;
+  }
+  return { worktree, indexRoot: repoRoot };
+}
+
+/**
+ * Describe a mismatch in one line
+ */
+export function formatWorktreeMismatch(mismatch: WorktreeMismatch): string {
+  const branch = mismatch.worktree.branch ?? `detached at ${mismatch.worktree.head?.substring(0, 8) ?? 'unknown'}`;
+  return `You are in worktree ${mismatch.worktree.path} (${branch}), but the nearest CV index is for ${mismatch.indexRoot}.`;
+}
+
+/**
+ * Warn about a mismatch and, in a terminal, ask whether to go on with the
+ * outer checkout's index. Returns false if the user declines.
+ */
+export async function confirmWorktreeIndex(mismatch: WorktreeMismatch, action: string): Promise<boolean> {
+  console.error(chalk.yellow(`⚠ ${formatWorktreeMismatch(mismatch)}`));
+  console.error(chalk.gray(`  Run \`cv init\` in ${mismatch.worktree.path} to give this worktree its own index.`));
+
+  if (!process.stdin.isTTY) {
+    return true;
+  }
+
+  const { proceed } = await inquirer.prompt([{
+    type: 'confirm',
+    name: 'proceed',
+    message: `${action} ${mismatch.indexRoot} anyway?`,
+    default: false
+  }]);
+  return proceed;
+}
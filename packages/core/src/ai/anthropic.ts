@@ -0,0 +1,324 @@
+/**
+ * Anthropic Client
+ * Chat with Claude directly through the Messages API
+ *
+ * Anthropic takes the system prompt as a top-level `system` field rather
+ * than a message, expects user and assistant turns to alternate, and can
+ * cache long prompt prefixes: blocks marked with `cache_control` are billed
+ * at a fraction of the input price when the same prefix is sent again
+ * within a few minutes.
+ */
+
+import { getMaxRetryAttempts, retryWithBackoff, toApiError } from '@cv-git/shared';
+import { AIClient, AIMessage, AIStreamHandler } from './types.js';
+import { recordCompletionUsage } from '../usage/index.js';
+
+export const ANTHROPIC_API_URL = 'https://api.anthropic.com/v1';
+export const ANTHROPIC_API_VERSION = '2023-06-01';
+export const DEFAULT_ANTHROPIC_MODEL = 'claude-sonnet-4-20250514';
+
+/**
+ * Blocks shorter than this are not marked for caching: the API ignores
+ * cache_control on prefixes under ~1024 tokens, and writing the cache costs
+ * more than a plain request
+ */
+export const MIN_CACHEABLE_CHARS = 4096;
+
+/** The API allows at most four cache breakpoints per request */
+const MAX_CACHE_BREAKPOINTS = 4;
+
+export interface AnthropicOptions {
+  apiKey: string;
+  model?: string;
+  maxTokens?: number;
+  temperature?: number;
+  /** API base URL (default: https://api.anthropic.com/v1) */
+  baseUrl?: string;
+  /** Mark long system prompts and messages (e.g. retrieved code) for prompt caching */
+  promptCaching?: boolean;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+}
+
+export interface AnthropicTextBlock {
+  type: 'text';
+  text: string;
+  cache_control?: { type: 'ephemeral' };
+}
+
+export interface AnthropicMessage {
+  role: 'user' | 'assistant';
+  content: AnthropicTextBlock[];
+}
+
+export interface AnthropicUsage {
+  input_tokens?: number;
+  output_tokens?: number;
+  cache_creation_input_tokens?: number | null;
+  cache_read_input_tokens?: number | null;
+}
+
+interface AnthropicResponse {
+  content?: Array<{ type: string; text?: string }>;
+  usage?: AnthropicUsage;
+}
+
+/**
+ * Map our messages to Anthropic's messages and system prompt.
+ * System messages are folded into `system`, consecutive messages with the
+ * same role are merged because the roles must alternate, and a leading
+ * assistant message gets an empty user turn before it.
+ */
+export function toAnthropicMessages(
+  messages: AIMessage[],
+  systemPrompt?: string
+): { messages: AnthropicMessage[]; system?: AnthropicTextBlock[] } {
+  const system: string[] = systemPrompt ? [systemPrompt] : [];
+  const converted: AnthropicMessage[] = [];
+
+  for (const msg of messages) {
+    if (msg.role === 'system') {
+      system.push(msg.content);
+      continue;
+    }
+
+    const last = converted[converted.length - 1];
+    if (last && last.role === msg.role) {
+      last.content.push({ type: 'text', text: msg.content });
+    } else {
+      converted.push({ role: msg.role, content: [{ type: 'text', text: msg.content }] });
+    }
+  }
+
+  if (converted[0]?.role === 'assistant') {
+    converted.unshift({ role: 'user', content: [{ type: 'text', text: '(continue)' }] });
+  }
+
+  return {
+    messages: converted,
+    system: system.length > 0 ? [{ type: 'text', text: system.join('\n\n') }] : undefined
+  };
+}
+
+/**
+ * Mark the long blocks of a request for prompt caching, in place: the
+ * system prompt, then the latest user turns, so a repeated question or a
+ * follow-up in the same conversation reads the earlier prefix from the cache.
+ * Returns the number of blocks marked.
+ */
+export function applyPromptCaching(request: { messages: AnthropicMessage[]; system?: AnthropicTextBlock[] }): number {
+  const candidates: AnthropicTextBlock[] = [];
+
+  const system = request.system?.[request.system.length - 1];
+  if (system) candidates.push(system);
+
+  const userTurns = request.messages.filter(msg => msg.role === 'user').reverse();
+  for (const turn of userTurns) {
+    candidates.push(turn.content[turn.content.length - 1]);
+  }
+
+  let marked = 0;
+  for (const block of candidates) {
+    if (marked >= MAX_CACHE_BREAKPOINTS) break;
+    if (block.text.length < MIN_CACHEABLE_CHARS) continue;
+    block.cache_control = { type: 'ephemeral' };
+    marked++;
+  }
+  return marked;
+}
+
+export class AnthropicClient implements AIClient {
+  private apiKey: string;
+  private model: string;
+  private baseUrl: string;
+  private maxTokens: number;
+  private temperature: number;
+  private promptCaching: boolean;
+  private maxAttempts: number;
+
+  constructor(options: AnthropicOptions) {
+    this.apiKey = options.apiKey;
+    this.model = options.model || DEFAULT_ANTHROPIC_MODEL;
+    this.baseUrl = (options.baseUrl || ANTHROPIC_API_URL).replace(/\/+$/, '');
+    this.maxTokens = options.maxTokens || 4096;
+    this.temperature = options.temperature ?? 0.7;
+    this.promptCaching = options.promptCaching ?? false;
+    this.maxAttempts = options.maxRetryAttempts ?? getMaxRetryAttempts();
+  }
+
+  /**
+   * Get the provider name
+   */
+  getProvider(): string {
+    return 'anthropic';
+  }
+
+  /**
+   * Get the current model
+   */
+  getModel(): string {
+    return this.model;
+  }
+
+  /**
+   * Set a different model
+   */
+  setModel(model: string): void {
+    this.model = model;
+  }
+
+  /**
+   * Check if the client is configured
+   */
+  async isReady(): Promise<boolean> {
+    return !!this.apiKey;
+  }
+
+  /**
+   * Chat completion (non-streaming)
+   */
+  async chat(messages: AIMessage[], systemPrompt?: string, signal?: AbortSignal): Promise<string> {
+    const response = await this.post(this.buildRequest(messages, systemPrompt), signal);
+    const data = await response.json() as AnthropicResponse;
+    const text = (data.content || []).map(block => block.type === 'text' ? block.text || '' : '').join('');
+    this.recordUsage(messages, systemPrompt, text, data.usage);
+    return text;
+  }
+
+  /**
+   * Chat completion with streaming (server-sent events)
+   */
+  async chatStream(
+    messages: AIMessage[],
+    systemPrompt?: string,
+    handler?: AIStreamHandler
+  ): Promise<string> {
+    let fullText = '';
+    const usage: AnthropicUsage = {};
+
+    try {
+      const response = await this.post({ ...this.buildRequest(messages, systemPrompt), stream: true }, handler?.signal);
+
+      const reader = response.body?.getReader();
+      if (!reader) {
+        throw new Error('No response body');
+      }
+
+      const decoder = new TextDecoder();
+      let buffer = '';
+
+      while (true) {
+        const { done, value } = await reader.read();
+        if (done) break;
+
+        buffer += decoder.decode(value, { stream: true });
+        const lines = buffer.split('\n');
+        buffer = lines.pop() || '';
+
+        for (const line of lines) {
+          if (!line.startsWith('data: ')) continue;
+          let event: any;
+          try {
+            event = JSON.parse(line.slice(6));
+          } catch {
+            continue; // Skip partial or non-JSON lines
+          }
+
+          if (event.type === 'message_start') {
+            Object.assign(usage, event.message?.usage);
+          } else if (event.type === 'message_delta') {
+            Object.assign(usage, event.usage);
+          } else if (event.type === 'content_block_delta' && event.delta?.type === 'text_delta') {
+            const token: string = event.delta.text;
+            fullText += token;
+            handler?.onToken?.(token);
+          } else if (event.type === 'error') {
+            // Errors after the response started (e.g. overloaded) arrive in the stream
+            const error: any = new Error(`Anthropic API error: ${event.error?.message || 'stream error'}`);
+            error.status = event.error?.type === 'overloaded_error' ? 529 : undefined;
+            throw error;
+          }
+        }
+      }
+
+      this.recordUsage(messages, systemPrompt, fullText, usage);
+      handler?.onComplete?.(fullText);
+      return fullText;
+
+    } catch (error) {
+      handler?.onError?.(error as Error);
+      throw error;
+    }
+  }
+
+  /**
+   * Simple completion (single prompt)
+   */
+  async complete(prompt: string, handler?: AIStreamHandler): Promise<string> {
+    if (!handler) {
+      return this.chat([{ role: 'user', content: prompt }]);
+    }
+    return this.chatStream([{ role: 'user', content: prompt }], undefined, handler);
+  }
+
+  /**
+   * Cache reads and writes are billed as input, so they count towards it
+   */
+  private recordUsage(
+    messages: AIMessage[],
+    systemPrompt: string | undefined,
+    response: string,
+    usage?: AnthropicUsage
+  ): void {
+    const prompt = [systemPrompt ?? '', ...messages.map(m => m.content)].join('\n');
+    const inputTokens = usage?.input_tokens === undefined
+      ? undefined
+      : usage.input_tokens + (usage.cache_creation_input_tokens ?? 0) + (usage.cache_read_input_tokens ?? 0);
+    recordCompletionUsage('anthropic', this.model, prompt, response, {
+      inputTokens,
+      outputTokens: usage?.output_tokens
+    });
+  }
+
+  private buildRequest(messages: AIMessage[], systemPrompt?: string): Record<string, unknown> {
+    const request = toAnthropicMessages(messages, systemPrompt);
+    if (this.promptCaching) {
+      applyPromptCaching(request);
+    }
+    return {
+      model: this.model,
+      max_tokens: this.maxTokens,
+      temperature: this.temperature,
+      ...(request.system ? { system: request.system } : {}),
+      messages: request.messages
+    };
+  }
+
+  private async post(body: unknown, signal?: AbortSignal): Promise<Response> {
+    return retryWithBackoff(async () => {
+      const response = await fetch(`${this.baseUrl}/messages`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'x-api-key': this.apiKey,
+          'anthropic-version': ANTHROPIC_API_VERSION
+        },
+        body: JSON.stringify(body),
+        signal
+      });
+
+      if (!response.ok) {
+        // 529 (overloaded) is retried like a rate limit
+        throw await toApiError('Anthropic', response, body => body.error?.message);
+      }
+      return response;
+    }, { maxAttempts: this.maxAttempts, signal });
+  }
+}
+
+/**
+ * Create an Anthropic chat client
+ */
+export function createAnthropicClient(options: AnthropicOptions): AnthropicClient {
+  return new AnthropicClient(options);
+}
@@ -0,0 +1,199 @@
+/**
+ * Azure OpenAI Client
+ * Chat completions through an Azure OpenAI deployment
+ *
+ * Azure routes requests by deployment name rather than model name and
+ * authenticates with an `api-key` header instead of a bearer token.
+ */
+
+import { AzureOpenAI } from 'openai';
+import type OpenAI from 'openai';
+import { getMaxRetryAttempts } from '@cv-git/shared';
+import { AIClient, AIMessage, AIStreamHandler } from './types.js';
+import { proxyClientOptions } from '../config/proxy.js';
+import { recordCompletionUsage } from '../usage/index.js';
+
+export const DEFAULT_AZURE_API_VERSION = '2024-06-01';
+
+/**
+ * Connection settings for one Azure OpenAI deployment
+ */
+export interface AzureOpenAIDeployment {
+  /** Resource endpoint (e.g. https://my-resource.openai.azure.com) */
+  endpoint: string;
+  apiKey: string;
+  /** Value of the api-version query parameter (e.g. 2024-06-01) */
+  apiVersion?: string;
+  /** Deployment name configured in the Azure portal */
+  deployment: string;
+}
+
+export interface AzureOpenAIOptions extends AzureOpenAIDeployment {
+  maxTokens?: number;
+  temperature?: number;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+}
+
+/**
+ * Create an SDK client bound to a deployment.
+ * Shared by chat and embeddings so both send the same headers and query.
+ */
+export function createAzureOpenAISDK(deployment: AzureOpenAIDeployment, maxRetries: number = getMaxRetryAttempts() - 1): AzureOpenAI {
+  return new AzureOpenAI({
+    endpoint: deployment.endpoint.replace(/\/+$/, ''),
+    apiKey: deployment.apiKey,
+    apiVersion: deployment.apiVersion || DEFAULT_AZURE_API_VERSION,
+    deployment: deployment.deployment,
+    maxRetries,
+    ...proxyClientOptions()
+  });
+}
+
+export class AzureOpenAIClient implements AIClient {
+  private client: AzureOpenAI;
+  private deployment: string;
+  private maxTokens: number;
+  private temperature: number;
+
+  constructor(private options: AzureOpenAIOptions) {
+    this.client = createAzureOpenAISDK(options, (options.maxRetryAttempts ?? getMaxRetryAttempts()) - 1);
+    this.deployment = options.deployment;
+    this.maxTokens = options.maxTokens || 4096;
+    this.temperature = options.temperature ?? 0.7;
+  }
+
+  /**
+   * Get the provider name
+   */
+  getProvider(): string {
+    return 'azure';
+  }
+
+  /**
+   * Get the deployment requests are routed to
+   */
+  getModel(): string {
+    return this.deployment;
+  }
+
+  /**
+   * Switch to a different deployment on the same resource
+   */
+  setModel(deployment: string): void {
+    this.deployment = deployment;
+    this.client = createAzureOpenAISDK(
+      { ...this.options, deployment },
+      (this.options.maxRetryAttempts ?? getMaxRetryAttempts()) - 1
+    );
+  }
+
+  /**
+   * Check if the client is configured
+   */
+  async isReady(): Promise<boolean> {
+    return !!(this.options.endpoint && this.options.apiKey && this.deployment);
+  }
+
+  /**
+   * Chat completion (non-streaming)
+   */
+  async chat(messages: AIMessage[], systemPrompt?: string, signal?: AbortSignal): Promise<string> {
+    const response = await this.client.chat.completions.create({
+      model: this.deployment,
+      messages: this.toOpenAIMessages(messages, systemPrompt),
+      max_tokens: this.maxTokens,
+      temperature: this.temperature,
+    }, { signal });
+
+    const text = response.choices[0]?.message?.content || '';
+    recordCompletionUsage('azure', this.deployment, promptText(messages, systemPrompt), text, {
+      inputTokens: response.usage?.prompt_tokens,
+      outputTokens: response.usage?.completion_tokens
+    });
+    return text;
+  }
+
+  /**
+   * Chat completion with streaming
+   */
+  async chatStream(
+    messages: AIMessage[],
+    systemPrompt?: string,
+    handler?: AIStreamHandler
+  ): Promise<string> {
+    let fullText = '';
+
+    try {
+      const stream = await this.client.chat.completions.create({
+        model: this.deployment,
+        messages: this.toOpenAIMessages(messages, systemPrompt),
+        max_tokens: this.maxTokens,
+        temperature: this.temperature,
+        stream: true,
+      }, { signal: handler?.signal });
+
+      let usage: OpenAI.CompletionUsage | undefined;
+      for await (const chunk of stream) {
+        usage = chunk.usage ?? usage;
+        const token = chunk.choices[0]?.delta?.content || '';
+        if (token) {
+          fullText += token;
+          handler?.onToken?.(token);
+        }
+      }
+
+      recordCompletionUsage('azure', this.deployment, promptText(messages, systemPrompt), fullText, {
+        inputTokens: usage?.prompt_tokens,
+        outputTokens: usage?.completion_tokens
+      });
+      handler?.onComplete?.(fullText);
+      return fullText;
+
+    } catch (error) {
+      handler?.onError?.(error as Error);
+      throw error;
+    }
+  }
+
+  /**
+   * Simple completion (single prompt)
+   */
+  async complete(prompt: string, handler?: AIStreamHandler): Promise<string> {
+    if (!handler) {
+      return this.chat([{ role: 'user', content: prompt }]);
+    }
+    return this.chatStream([{ role: 'user', content: prompt }], undefined, handler);
+  }
+
+  private toOpenAIMessages(messages: AIMessage[], systemPrompt?: string): OpenAI.ChatCompletionMessageParam[] {
+    const openaiMessages: OpenAI.ChatCompletionMessageParam[] = [];
+
+    if (systemPrompt) {
+      openaiMessages.push({ role: 'system', content: systemPrompt });
+    }
+
+    for (const msg of messages) {
+      openaiMessages.push({
+        role: msg.role === 'system' ? 'system' : msg.role === 'user' ? 'user' : 'assistant',
+        content: msg.content,
+      });
+    }
+
+    return openaiMessages;
+  }
+}
+
+/**
+ * Create an Azure OpenAI chat client
+ */
+export function createAzureOpenAIClient(options: AzureOpenAIOptions): AzureOpenAIClient {
+  return new AzureOpenAIClient(options);
+}
+
+/**
+ * Prompt text of a request, for estimating tokens when the API reports none
+ */
+function promptText(messages: AIMessage[], systemPrompt?: string): string {
+  return [systemPrompt ?? '', ...messages.map(m => m.content)].join('\n');
+}
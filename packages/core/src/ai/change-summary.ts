@@ -0,0 +1,230 @@
+/**
+ * Change Summary
+ * Builds the prompt behind `cv explain --since <ref>`, which explains what
+ * changed between a ref and HEAD and why, as release-notes-style themes
+ *
+ * The model sees each commit's message, files and (truncated) diff, and
+ * the indexed symbols the range touches, and groups related commits into
+ * themes such as "Added token revocation to AuthService". Every commit
+ * ends up in exactly one theme: commits the model left out are collected
+ * under "Other changes", and SHAs it made up are dropped.
+ */
+
+import { CodeChunkPayload, GitCommit } from '@cv-git/shared';
+import { ChangedRange } from './diff-explain.js';
+import { truncateCommitMessage } from '../context/commit-history.js';
+
+/** Commits sent in one prompt; older ones in a longer range are left out */
+export const MAX_SUMMARY_COMMITS = 200;
+
+/** Diff text across all commits, split evenly between them */
+export const SUMMARY_DIFF_BUDGET_CHARS = 60000;
+
+/** Diff text for any one commit, however few there are */
+const MAX_COMMIT_DIFF_CHARS = 4000;
+
+/** Touched symbols listed in the prompt */
+const MAX_TOUCHED_SYMBOLS = 60;
+
+/** Theme that collects commits the model did not assign */
+export const OTHER_CHANGES_THEME = 'Other changes';
+
+/**
+ * Indexed symbol whose code the range changed
+ */
+export interface TouchedSymbol {
+  file: string;
+  symbolName: string;
+  symbolKind?: string;
+}
+
+export interface ChangeSummaryCommit extends GitCommit {
+  /** The commit's diff, if it was loaded */
+  diff?: string;
+}
+
+export interface ChangeSummaryInput {
+  /** The ref the range starts after, as the user gave it */
+  since: string;
+  /** Commits in the range, newest first */
+  commits: ChangeSummaryCommit[];
+  /** Commits in the whole range, when `commits` only holds the newest */
+  totalCommits?: number;
+  symbols: TouchedSymbol[];
+  /** What to focus the summary on (the explain target), if given */
+  focus?: string;
+}
+
+export interface ChangeTheme {
+  title: string;
+  /** Intent of the change and how it was done */
+  summary: string;
+  /** Full SHAs of the commits the theme covers, newest first */
+  commits: string[];
+  files: string[];
+}
+
+export interface ChangeSummary {
+  since: string;
+  overview: string;
+  themes: ChangeTheme[];
+  /** Commits in the range */
+  commitCount: number;
+  /** Older commits left out of the prompt */
+  omitted: number;
+}
+
+/**
+ * Indexed symbols whose chunks overlap the changed lines, or whose name
+ * git printed as a hunk's function context
+ */
+export function findTouchedSymbols(ranges: ChangedRange[], chunks: CodeChunkPayload[]): TouchedSymbol[] {
+  const found = new Map<string, TouchedSymbol>();
+
+  for (const chunk of chunks) {
+    if (!chunk.symbolName) continue;
+    const key = `${chunk.file}:${chunk.symbolName}`;
+    if (found.has(key)) continue;
+
+    const touched = ranges.some(r =>
+      r.file === chunk.file &&
+      ((r.startLine <= chunk.endLine && r.endLine >= chunk.startLine) ||
+        new RegExp(`\\b${escapeRegExp(chunk.symbolName!)}\\b`).test(r.context))
+    );
+    if (touched) {
+      found.set(key, { file: chunk.file, symbolName: chunk.symbolName, symbolKind: chunk.symbolKind });
+    }
+  }
+
+  return Array.from(found.values()).sort((a, b) => a.file.localeCompare(b.file) || a.symbolName.localeCompare(b.symbolName));
+}
+
+/**
+ * Prompt asking for the themes of a range of commits as JSON
+ */
+export function buildChangeSummaryPrompt(input: ChangeSummaryInput): string {
+  const commits = input.commits.slice(0, MAX_SUMMARY_COMMITS);
+  const omitted = (input.totalCommits ?? input.commits.length) - commits.length;
+  const diffChars = Math.min(MAX_COMMIT_DIFF_CHARS, Math.floor(SUMMARY_DIFF_BUDGET_CHARS / Math.max(commits.length, 1)));
+
+  let prompt = `You are an expert software engineer writing release notes for the changes since ${input.since}.\n\n`;
+  if (input.focus) {
+    prompt += `Focus on: ${input.focus}\n\n`;
+  }
+
+  prompt += `## Commits (oldest first)\n\n`;
+  for (const commit of [...commits].reverse()) {
+    prompt += `### ${commit.sha.slice(0, 7)} (${commit.author}, ${new Date(commit.date).toISOString().slice(0, 10)})\n\n`;
+    prompt += `${truncateCommitMessage(commit.message)}\n\n`;
+    if (commit.files.length > 0) {
+      prompt += `Files: ${commit.files.join(', ')}\n\n`;
+    }
+    const diff = commit.diff?.trim();
+    if (diff) {
+      const cut = diff.length > diffChars ? `${diff.slice(0, diffChars)}\n... (diff truncated)` : diff;
+      prompt += `\`\`\`diff\n${cut}\n\`\`\`\n\n`;
+    }
+  }
+  if (omitted > 0) {
+    prompt += `(${omitted} older commit${omitted === 1 ? '' : 's'} not shown)\n\n`;
+  }
+
+  if (input.symbols.length > 0) {
+    prompt += `## Indexed symbols the changes touch\n\n`;
+    for (const symbol of input.symbols.slice(0, MAX_TOUCHED_SYMBOLS)) {
+      prompt += `- \`${symbol.symbolName}\`${symbol.symbolKind ? ` (${symbol.symbolKind})` : ''} in ${symbol.file}\n`;
+    }
+    prompt += `\n`;
+  }
+
+  prompt += `## Requirements\n\n`;
+  prompt += `- Group related commits into themes: one feature, fix, or refactor each, titled like "Added token revocation to AuthService".\n`;
+  prompt += `- Explain the intent of each theme (what it enables or fixes, and why) in 1-3 sentences, not a list of its commits.\n`;
+  prompt += `- Ground every statement in the commits, diffs and symbols above; name symbols in backticks.\n`;
+  prompt += `- Put every commit in exactly one theme, by its short SHA; order themes by importance.\n\n`;
+  prompt += `Respond with ONLY JSON in this format:\n`;
+  prompt += `{\n`;
+  prompt += `  "overview": "One or two sentences on the range as a whole",\n`;
+  prompt += `  "themes": [\n`;
+  prompt += `    { "title": "Short title", "summary": "Intent and approach", "commits": ["abc1234"], "files": ["path/to/file"] }\n`;
+  prompt += `  ]\n`;
+  prompt += `}`;
+
+  return prompt;
+}
+
+/**
+ * Themes from the model's JSON, with every commit of the prompt in exactly
+ * one of them. An unparseable response becomes the overview, with all
+ * commits under "Other changes".
+ */
+export function parseChangeSummary(response: string, input: ChangeSummaryInput): ChangeSummary {
+  const commits = input.commits.slice(0, MAX_SUMMARY_COMMITS);
+  let parsed: any;
+  try {
+    const jsonMatch = response.match(/\{[\s\S]*\}/);
+    parsed = jsonMatch ? JSON.parse(jsonMatch[0]) : undefined;
+  } catch {
+    parsed = undefined;
+  }
+
+  const assigned = new Set<string>();
+  const themes: ChangeTheme[] = [];
+  for (const raw of Array.isArray(parsed?.themes) ? parsed.themes : []) {
+    if (!raw || typeof raw.title !== 'string' || !raw.title.trim()) continue;
+
+    const shas: string[] = [];
+    for (const ref of Array.isArray(raw.commits) ? raw.commits : []) {
+      const prefix = typeof ref === 'string' ? ref.trim().toLowerCase() : '';
+      const commit = prefix.length >= 4 ? commits.find(c => c.sha.startsWith(prefix)) : undefined;
+      if (commit && !assigned.has(commit.sha)) {
+        assigned.add(commit.sha);
+        shas.push(commit.sha);
+      }
+    }
+    if (shas.length === 0) continue;
+
+    themes.push({
+      title: raw.title.trim(),
+      summary: typeof raw.summary === 'string' ? raw.summary.trim() : '',
+      commits: commits.filter(c => shas.includes(c.sha)).map(c => c.sha),
+      files: themeFiles(raw.files, commits.filter(c => shas.includes(c.sha)))
+    });
+  }
+
+  const unassigned = commits.filter(c => !assigned.has(c.sha));
+  if (unassigned.length > 0) {
+    themes.push({
+      title: OTHER_CHANGES_THEME,
+      summary: unassigned.map(c => c.message.split('\n')[0].trim()).join('; '),
+      commits: unassigned.map(c => c.sha),
+      files: themeFiles(undefined, unassigned)
+    });
+  }
+
+  const overview = typeof parsed?.overview === 'string' && parsed.overview.trim()
+    ? parsed.overview.trim()
+    : parsed ? '' : response.trim();
+
+  const commitCount = input.totalCommits ?? input.commits.length;
+  return {
+    since: input.since,
+    overview,
+    themes,
+    commitCount,
+    omitted: commitCount - commits.length
+  };
+}
+
+/**
+ * Files the model named that its commits changed, else all files its commits changed
+ */
+function themeFiles(named: unknown, commits: GitCommit[]): string[] {
+  const changed = new Set(commits.flatMap(c => c.files));
+  const kept = (Array.isArray(named) ? named : []).filter((f): f is string => typeof f === 'string' && changed.has(f));
+  return Array.from(new Set(kept.length > 0 ? kept : changed));
+}
+
+function escapeRegExp(text: string): string {
+  return text.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+}
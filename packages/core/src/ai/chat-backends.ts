@@ -0,0 +1,123 @@
+/**
+ * Chat Backends
+ * Resolves config ai.provider into the backend AIManager sends completions
+ * to, and ai.providers into the backends it tries while ai.provider is down.
+ * Shared by the CLI's AI commands and the MCP server's tools.
+ */
+
+import type { CVConfig } from '@cv-git/shared';
+import type { AIManagerFallback } from './index.js';
+import { ModelProvider, getAIProvider } from './models.js';
+import {
+  getAnthropicApiKey,
+  getAzureOpenAISettings,
+  getGeminiApiKey,
+  getOpenAICompatibleSettings,
+  getOpenRouterApiKey
+} from '../config/credentials.js';
+
+/**
+ * Backend AIManager sends completions to for config ai.provider, or for
+ * `provider` when resolving a fallback. Claude is called directly, so an
+ * OpenRouter key alone is not enough (only cv chat routes Claude through
+ * OpenRouter). Throws with the setup command when credentials are missing.
+ */
+export async function resolveChatBackend(
+  config: CVConfig,
+  provider: ModelProvider = getAIProvider(config)
+): Promise<AIManagerFallback> {
+  // config ai.apiKey belongs to the primary provider
+  const configKey = provider === getAIProvider(config) ? config.ai.apiKey : undefined;
+
+  if (provider === 'azure') {
+    const azure = await getAzureOpenAISettings(config.azure);
+    if (!azure?.chatDeployment) {
+      throw new Error('Azure OpenAI chat deployment not configured. Run: cv auth setup azure');
+    }
+    return {
+      provider,
+      apiKey: azure.apiKey,
+      azure: { endpoint: azure.endpoint, apiVersion: azure.apiVersion, deployment: azure.chatDeployment }
+    };
+  }
+
+  if (provider === 'gemini') {
+    const apiKey = await getGeminiApiKey(configKey);
+    if (!apiKey) {
+      throw new Error('Gemini API key not found. Run: cv auth setup gemini');
+    }
+    return { provider, apiKey };
+  }
+
+  if (provider === 'openai-compatible') {
+    // The gateway's key is optional
+    const gateway = await getOpenAICompatibleSettings();
+    if (!gateway) {
+      throw new Error('OpenAI-compatible base URL not configured. Run: cv auth setup openai-compatible');
+    }
+    return { provider, apiKey: gateway.apiKey ?? '', model: gateway.chatModel, openaiCompatibleUrl: gateway.baseUrl };
+  }
+
+  const apiKey = await getAnthropicApiKey(configKey);
+  if (!apiKey) {
+    const openRouterOnly = !!(await getOpenRouterApiKey());
+    throw new Error(openRouterOnly
+      ? 'Anthropic API key not found; the OpenRouter key only serves cv chat. Run: cv auth setup anthropic'
+      : 'Anthropic API key not found. Run: cv auth setup anthropic');
+  }
+  return { provider: 'anthropic', apiKey };
+}
+
+/**
+ * AIManager options for a resolved backend. `model` (--model or
+ * models.<command>) names the Azure deployment or the model; without it the
+ * backend's own (a gateway's chat model, Azure's chat deployment) or config
+ * ai.model is used.
+ */
+export function chatBackendOptions(
+  backend: AIManagerFallback,
+  model: string | undefined,
+  config: CVConfig
+): AIManagerFallback {
+  return {
+    ...backend,
+    model: model ?? backend.model ?? config.ai.model,
+    azure: backend.azure ? { ...backend.azure, deployment: model ?? backend.azure.deployment } : undefined
+  };
+}
+
+/**
+ * Fallback backends from config ai.providers, in order, without the
+ * primary provider. Providers without credentials are left out: a fallback
+ * that cannot authenticate would only replace one error with another.
+ */
+export async function resolveAIFallbacks(
+  config: CVConfig,
+  primary: ModelProvider = getAIProvider(config)
+): Promise<AIManagerFallback[]> {
+  const fallbacks: AIManagerFallback[] = [];
+
+  for (const provider of new Set(config.ai.providers ?? [])) {
+    if (provider === primary) continue;
+
+    let backend: AIManagerFallback;
+    try {
+      backend = await resolveChatBackend(config, provider);
+    } catch {
+      continue;
+    }
+
+    if (backend.provider === 'openai-compatible') {
+      if (backend.model) fallbacks.push(backend);
+    } else if (backend.provider === 'gemini') {
+      fallbacks.push({ ...backend, model: config.ai.model });
+    } else if (backend.provider === 'anthropic') {
+      // Another provider's model name means nothing to Anthropic
+      fallbacks.push({ ...backend, model: config.ai.model?.startsWith('claude') ? config.ai.model : undefined });
+    } else {
+      fallbacks.push(backend);
+    }
+  }
+
+  return fallbacks;
+}
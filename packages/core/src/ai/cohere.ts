@@ -0,0 +1,118 @@
+/**
+ * Cohere Embeddings Client
+ * Embeds text through Cohere's v2 embed endpoint, and reranks retrieved
+ * code through its rerank endpoint
+ *
+ * Cohere embeddings are asymmetric: indexed code is embedded with
+ * `input_type: search_document` and search queries with `search_query`.
+ * Mixing the two up still returns vectors, just noticeably worse matches.
+ */
+
+import { getMaxRetryAttempts, retryWithBackoff, toApiError } from '@cv-git/shared';
+import { EmbeddingInputType } from './types.js';
+
+export const COHERE_API_URL = 'https://api.cohere.com/v2';
+export const DEFAULT_COHERE_EMBEDDING_MODEL = 'embed-english-v3.0';
+export const DEFAULT_COHERE_RERANK_MODEL = 'rerank-v3.5';
+
+/** Input types sent for indexed documents and for queries */
+export const COHERE_INPUT_TYPES: Record<EmbeddingInputType, string> = {
+  document: 'search_document',
+  query: 'search_query'
+};
+
+/** The embed endpoint accepts at most this many texts per request */
+const MAX_EMBED_BATCH = 96;
+
+export interface CohereOptions {
+  /** API key; may be empty for a local server with a Cohere-compatible API */
+  apiKey: string;
+  /** API base URL (default: the public v2 endpoint) */
+  baseUrl?: string;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+}
+
+/** Relevance of one document to the query, by its position in the request */
+export interface RerankResult {
+  index: number;
+  /** 0-1, higher is more relevant */
+  relevanceScore: number;
+}
+
+export class CohereClient {
+  private apiKey: string;
+  private baseUrl: string;
+  private maxAttempts: number;
+
+  constructor(options: CohereOptions) {
+    this.apiKey = options.apiKey;
+    this.baseUrl = (options.baseUrl || COHERE_API_URL).replace(/\/+$/, '');
+    this.maxAttempts = options.maxRetryAttempts ?? getMaxRetryAttempts();
+  }
+
+  /**
+   * Embed texts as documents (for the index) or queries (for search)
+   */
+  async embed(
+    texts: string[],
+    model: string = DEFAULT_COHERE_EMBEDDING_MODEL,
+    inputType: EmbeddingInputType = 'document',
+    signal?: AbortSignal
+  ): Promise<number[][]> {
+    const embeddings: number[][] = [];
+
+    for (let i = 0; i < texts.length; i += MAX_EMBED_BATCH) {
+      const response = await this.post('embed', {
+        model,
+        texts: texts.slice(i, i + MAX_EMBED_BATCH),
+        input_type: COHERE_INPUT_TYPES[inputType],
+        embedding_types: ['float']
+      }, signal);
+      const data = await response.json() as { embeddings?: { float?: number[][] } };
+      embeddings.push(...(data.embeddings?.float || []));
+    }
+
+    return embeddings;
+  }
+
+  /**
+   * Score documents against a query with a cross-encoder, most relevant first.
+   * The model may be omitted for servers that host a single reranker.
+   */
+  async rerank(
+    query: string,
+    documents: string[],
+    model: string | undefined = DEFAULT_COHERE_RERANK_MODEL,
+    signal?: AbortSignal
+  ): Promise<RerankResult[]> {
+    if (documents.length === 0) {
+      return [];
+    }
+
+    const response = await this.post('rerank', { model, query, documents }, signal);
+    const data = await response.json() as { results?: { index: number; relevance_score: number }[] };
+    return (data.results || [])
+      .map(result => ({ index: result.index, relevanceScore: result.relevance_score }))
+      .sort((a, b) => b.relevanceScore - a.relevanceScore);
+  }
+
+  private async post(endpoint: string, body: unknown, signal?: AbortSignal): Promise<Response> {
+    return retryWithBackoff(async () => {
+      const response = await fetch(`${this.baseUrl}/${endpoint}`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          ...(this.apiKey ? { 'Authorization': `Bearer ${this.apiKey}` } : {})
+        },
+        body: JSON.stringify(body),
+        signal
+      });
+
+      if (!response.ok) {
+        throw await toApiError('Cohere', response, body => body.message);
+      }
+      return response;
+    }, { maxAttempts: this.maxAttempts, signal });
+  }
+}
@@ -7,21 +7,33 @@
  * Supports multiple AI providers:
  * - Anthropic (direct)
  * - OpenRouter (for various models)
+ * - OpenAI-compatible gateways (vLLM, LiteLLM, ...)
  * - None (analysis only, for use with AI coding agents like Claude Code)
  */
 
 import Anthropic from '@anthropic-ai/sdk';
-import { SymbolNode, SymbolKind, ParsedFile } from '@cv-git/shared';
+import { SymbolNode, SymbolKind, ParsedFile, getMaxRetryAttempts } from '@cv-git/shared';
 import { GraphManager } from '../graph/index.js';
 import { GitManager } from '../git/index.js';
 import { CodeParser } from '../parser/index.js';
+import { proxyClientOptions } from '../config/proxy.js';
+import { recordCompletionUsage } from '../usage/index.js';
+import {
+  CommitConvention,
+  resolveCommitConvention,
+  formatCommitSubject,
+  formatCommitConventionRules,
+  describeCommitTemplate,
+  validateCommitMessage,
+  formatCommitRetryPrompt
+} from './commit-convention.js';
 import * as fs from 'fs/promises';
 import * as path from 'path';
 
 /**
  * AI Provider types supported for commit message generation
  */
-export type CommitAIProvider = 'anthropic' | 'openrouter' | 'none';
+export type CommitAIProvider = 'anthropic' | 'openrouter' | 'openai-compatible' | 'none';
 
 /**
  * Conventional commit types
@@ -89,15 +101,61 @@ export interface GeneratedCommitMessage {
   footer?: string;        // Optional footer (BREAKING CHANGE, etc.)
   fullMessage: string;    // Complete message
   analysis: CommitAnalysis;
+  violations?: string[];  // Rules of the commit convention the message still breaks
 }
 
 export interface CommitAnalyzerOptions {
   repoRoot: string;
   provider?: CommitAIProvider;  // Default: 'anthropic', use 'none' for analysis-only mode
   apiKey?: string;              // Required for 'anthropic' or 'openrouter' provider
-  model?: string;               // Model to use (default varies by provider)
+  model?: string;               // Model to use (default varies by provider; required for 'openai-compatible')
   maxTokens?: number;
   openRouterBaseUrl?: string;   // For OpenRouter: base URL (default: https://openrouter.ai/api/v1)
+  baseUrl?: string;             // For 'openai-compatible': the gateway's base URL
+  diffTokenBudget?: number;     // Max tokens of raw diff included in the prompt (default: 4000)
+  convention?: CommitConvention; // Message format and rules (default: Conventional Commits, 72-character subject)
+}
+
+/** Default token budget for the raw diff section of the prompt */
+const DEFAULT_DIFF_TOKEN_BUDGET = 4000;
+
+/** New attempts after a generated message breaks the commit convention */
+const MAX_REGENERATIONS = 2;
+
+/**
+ * Truncate a unified diff to a token budget (~4 characters per token).
+ * Every file keeps its header so the model still sees the full scope of the
+ * change; hunk bodies share the remaining budget evenly.
+ */
+export function truncateDiff(diff: string, maxTokens: number): { text: string; truncated: boolean } {
+  const maxChars = maxTokens * 4;
+  if (diff.length <= maxChars) {
+    return { text: diff, truncated: false };
+  }
+
+  // Split into per-file sections
+  const sections = diff.split(/(?=^diff --git )/m).filter(s => s.length > 0);
+  const headers = sections.map(section => {
+    const hunkStart = section.indexOf('\n@@');
+    return hunkStart === -1 ? section : section.slice(0, hunkStart + 1);
+  });
+
+  const headerChars = headers.reduce((sum, h) => sum + h.length, 0);
+  if (headerChars >= maxChars) {
+    // Too many files to show bodies at all - list headers until the budget runs out
+    return { text: headers.join('').slice(0, maxChars) + '\n... (diff truncated)', truncated: true };
+  }
+
+  const perFileBody = Math.floor((maxChars - headerChars) / sections.length);
+  const parts = sections.map((section, i) => {
+    const body = section.slice(headers[i].length);
+    if (body.length <= perFileBody) return section;
+    // Cut at a line boundary
+    const cut = body.lastIndexOf('\n', perFileBody);
+    return headers[i] + body.slice(0, cut > 0 ? cut : perFileBody) + '\n... (truncated)\n';
+  });
+
+  return { text: parts.join(''), truncated: true };
 }
 
 /**
@@ -108,14 +166,20 @@ export class CommitAnalyzer {
   private anthropicClient?: Anthropic;
   private openRouterApiKey?: string;
   private openRouterBaseUrl: string;
+  private gatewayUrl?: string;
+  private gatewayApiKey?: string;
   private model: string;
   private maxTokens: number;
+  private diffTokenBudget: number;
+  private convention: CommitConvention;
   private repoRoot: string;
   private parser: CodeParser;
 
   constructor(options: CommitAnalyzerOptions) {
     this.provider = options.provider || 'anthropic';
     this.maxTokens = options.maxTokens || 1024;
+    this.diffTokenBudget = options.diffTokenBudget || DEFAULT_DIFF_TOKEN_BUDGET;
+    this.convention = options.convention ?? resolveCommitConvention();
     this.repoRoot = options.repoRoot;
     this.parser = new CodeParser();
     this.openRouterBaseUrl = options.openRouterBaseUrl || 'https://openrouter.ai/api/v1';
@@ -125,7 +189,7 @@ export class CommitAnalyzer {
       if (!options.apiKey) {
         throw new Error('API key required for Anthropic provider');
       }
-      this.anthropicClient = new Anthropic({ apiKey: options.apiKey });
+      this.anthropicClient = new Anthropic({ apiKey: options.apiKey, maxRetries: getMaxRetryAttempts() - 1, ...proxyClientOptions() });
       this.model = options.model || 'claude-3-5-sonnet-20241022';
     } else if (this.provider === 'openrouter') {
       if (!options.apiKey) {
@@ -133,6 +197,14 @@ export class CommitAnalyzer {
       }
       this.openRouterApiKey = options.apiKey;
       this.model = options.model || 'anthropic/claude-3.5-sonnet';
+    } else if (this.provider === 'openai-compatible') {
+      if (!options.baseUrl || !options.model) {
+        throw new Error('Base URL and model required for OpenAI-compatible provider');
+      }
+      // The gateway's key is optional
+      this.gatewayApiKey = options.apiKey;
+      this.gatewayUrl = options.baseUrl.replace(/\/$/, '');
+      this.model = options.model;
     } else {
       // 'none' provider - analysis only mode
       this.model = '';
@@ -140,14 +212,16 @@ export class CommitAnalyzer {
   }
 
   /**
-   * Analyze staged changes
+   * Analyze staged changes; with `includeUnstaged`, also the tracked changes
+   * `git commit -a` would stage, without staging them
    */
   async analyzeStaged(
     git: GitManager,
-    graph?: GraphManager
+    graph?: GraphManager,
+    includeUnstaged: boolean = false
   ): Promise<CommitAnalysis> {
-    // Get staged diff
-    const rawDiff = await git.getRawDiff('--staged');
+    // Get staged diff (against HEAD for -a: staged and unstaged tracked changes)
+    const rawDiff = await git.getRawDiff(includeUnstaged ? 'HEAD' : '--staged');
 
     if (!rawDiff.trim()) {
       throw new Error('No staged changes to analyze. Stage your changes with `git add` first.');
@@ -158,7 +232,9 @@ export class CommitAnalyzer {
 
     // Get current status to identify staged files
     const status = await git.getStatus();
-    const stagedFiles = status.staged;
+    const stagedFiles = includeUnstaged
+      ? [...new Set([...status.staged, ...status.modified, ...status.deleted])]
+      : status.staged;
 
     // Analyze symbols in changed files
     const symbolAnalysis = await this.analyzeSymbolChanges(stagedFiles, git, graph);
@@ -171,9 +247,10 @@ export class CommitAnalyzer {
     // Detect breaking changes
     const breakingChanges = await this.detectBreakingChanges(symbolAnalysis, callersAffected);
 
-    // Infer commit type and scope
+    // Infer commit type and scope (only a scope the convention allows)
     const suggestedType = this.inferCommitType(symbolAnalysis, filesChanged);
-    const suggestedScope = this.inferScope(symbolAnalysis, filesChanged);
+    const inferredScope = this.inferScope(symbolAnalysis, filesChanged);
+    const suggestedScope = !this.convention.scopes || this.convention.scopes.includes(inferredScope) ? inferredScope : '';
 
     // Calculate complexity delta
     const complexityDelta = this.calculateComplexityDelta(symbolAnalysis);
@@ -204,6 +281,10 @@ export class CommitAnalyzer {
    *
    * If provider is 'none', returns a template message based on analysis.
    * This is useful for AI coding agents that want to generate the message themselves.
+   *
+   * A message that breaks the commit convention is regenerated with the
+   * broken rules listed, up to MAX_REGENERATIONS times; `violations` lists
+   * what the last attempt still breaks.
    */
   async generateMessage(analysis: CommitAnalysis): Promise<GeneratedCommitMessage> {
     // For 'none' provider, return a template based on analysis
@@ -212,6 +293,20 @@ export class CommitAnalyzer {
     }
 
     const prompt = this.buildPrompt(analysis);
+    let generated = this.parseGeneratedMessage(await this.complete(prompt), analysis);
+
+    for (let attempt = 0; attempt < MAX_REGENERATIONS && generated.violations!.length > 0; attempt++) {
+      const retry = formatCommitRetryPrompt(prompt, generated.fullMessage, generated.violations!);
+      generated = this.parseGeneratedMessage(await this.complete(retry), analysis);
+    }
+
+    return generated;
+  }
+
+  /**
+   * Send a prompt to the configured provider
+   */
+  private async complete(prompt: string): Promise<string> {
     let responseText: string;
 
     if (this.provider === 'anthropic' && this.anthropicClient) {
@@ -227,25 +322,39 @@ export class CommitAnalyzer {
         throw new Error('Unexpected response type from AI');
       }
       responseText = content.text;
+      recordCompletionUsage('anthropic', this.model, prompt, responseText, {
+        inputTokens: response.usage.input_tokens,
+        outputTokens: response.usage.output_tokens
+      });
 
     } else if (this.provider === 'openrouter' && this.openRouterApiKey) {
-      responseText = await this.callOpenRouter(prompt);
+      responseText = await this.callChatCompletions(prompt, this.openRouterBaseUrl, this.openRouterApiKey, 'OpenRouter', 'openrouter');
+
+    } else if (this.provider === 'openai-compatible' && this.gatewayUrl) {
+      responseText = await this.callChatCompletions(prompt, this.gatewayUrl, this.gatewayApiKey, 'OpenAI-compatible', 'openai-compatible');
 
     } else {
       throw new Error(`Invalid provider configuration: ${this.provider}`);
     }
 
-    return this.parseGeneratedMessage(responseText, analysis);
+    return responseText;
   }
 
   /**
-   * Call OpenRouter API for message generation
+   * Call an OpenAI-style chat completions API (OpenRouter or a gateway)
+   * for message generation
    */
-  private async callOpenRouter(prompt: string): Promise<string> {
-    const response = await fetch(`${this.openRouterBaseUrl}/chat/completions`, {
+  private async callChatCompletions(
+    prompt: string,
+    baseUrl: string,
+    apiKey: string | undefined,
+    label: string,
+    usageProvider: string
+  ): Promise<string> {
+    const response = await fetch(`${baseUrl}/chat/completions`, {
       method: 'POST',
       headers: {
-        'Authorization': `Bearer ${this.openRouterApiKey}`,
+        ...(apiKey ? { 'Authorization': `Bearer ${apiKey}` } : {}),
         'Content-Type': 'application/json',
         'HTTP-Referer': 'https://github.com/cv-git/cv-git',
         'X-Title': 'CV-Git Commit Analyzer'
@@ -260,16 +369,20 @@ export class CommitAnalyzer {
 
     if (!response.ok) {
       const error = await response.text();
-      throw new Error(`OpenRouter API error: ${response.status} - ${error}`);
+      throw new Error(`${label} API error: ${response.status} - ${error}`);
     }
 
     const data = await response.json() as any;
     const content = data.choices?.[0]?.message?.content;
 
     if (!content) {
-      throw new Error('No content in OpenRouter response');
+      throw new Error(`No content in ${label} response`);
     }
 
+    recordCompletionUsage(usageProvider, this.model, prompt, content, {
+      inputTokens: data.usage?.prompt_tokens,
+      outputTokens: data.usage?.completion_tokens
+    });
     return content;
   }
 
@@ -277,23 +390,25 @@ export class CommitAnalyzer {
    * Generate a template message without AI (for use by AI coding agents)
    */
   private generateTemplateMessage(analysis: CommitAnalysis): GeneratedCommitMessage {
-    const type = analysis.suggestedType;
-    const scope = analysis.suggestedScope ? `(${analysis.suggestedScope})` : '';
-
     // Generate subject based on analysis
-    let subject: string;
+    let description: string;
     if (analysis.symbolsAdded.length > 0 && analysis.symbolsDeleted.length === 0) {
       const mainSymbol = analysis.symbolsAdded[0];
-      subject = `${type}${scope}: add ${mainSymbol.kind} ${mainSymbol.name}`;
+      description = `add ${mainSymbol.kind} ${mainSymbol.name}`;
     } else if (analysis.symbolsDeleted.length > 0 && analysis.symbolsAdded.length === 0) {
       const mainSymbol = analysis.symbolsDeleted[0];
-      subject = `${type}${scope}: remove ${mainSymbol.kind} ${mainSymbol.name}`;
+      description = `remove ${mainSymbol.kind} ${mainSymbol.name}`;
     } else if (analysis.symbolsModified.length > 0) {
       const mainSymbol = analysis.symbolsModified[0];
-      subject = `${type}${scope}: update ${mainSymbol.kind} ${mainSymbol.name}`;
+      description = `update ${mainSymbol.kind} ${mainSymbol.name}`;
     } else {
-      subject = `${type}${scope}: update ${analysis.filesChanged[0] || 'code'}`;
+      description = `update ${analysis.filesChanged[0] || 'code'}`;
     }
+    const subject = formatCommitSubject(this.convention, {
+      type: analysis.suggestedType,
+      scope: analysis.suggestedScope || undefined,
+      subject: description
+    });
 
     // Build body
     let body = '';
@@ -312,11 +427,15 @@ export class CommitAnalyzer {
       body += `Removed: ${analysis.symbolsDeleted.map(s => s.name).slice(0, 5).join(', ')}\n`;
     }
 
-    // Build footer for breaking changes
-    let footer = '';
+    // Build footer for breaking changes, and the ticket when the subject has no place for it
+    const footers: string[] = [];
     if (analysis.isBreakingChange) {
-      footer = 'BREAKING CHANGE: ' + analysis.breakingChanges.map(bc => bc.reason).join('; ');
+      footers.push('BREAKING CHANGE: ' + analysis.breakingChanges.map(bc => bc.reason).join('; '));
+    }
+    if (this.convention.ticket && !subject.includes(this.convention.ticket)) {
+      footers.push(`Refs: ${this.convention.ticket}`);
     }
+    const footer = footers.join('\n');
 
     const fullMessage = [subject, body.trim(), footer].filter(Boolean).join('\n\n');
 
@@ -325,7 +444,8 @@ export class CommitAnalyzer {
       body: body.trim() || undefined,
       footer: footer || undefined,
       fullMessage,
-      analysis
+      analysis,
+      violations: validateCommitMessage(fullMessage, this.convention)
     };
   }
 
@@ -710,7 +830,12 @@ export class CommitAnalyzer {
    * Build prompt for AI
    */
   private buildPrompt(analysis: CommitAnalysis): string {
-    let prompt = `You are generating a git commit message following the Conventional Commits specification.
+    const diff = truncateDiff(analysis.rawDiff, this.diffTokenBudget);
+
+    const convention = this.convention;
+    let prompt = `You are generating a git commit message following ${
+      convention.name === 'conventional' && !convention.scopes ? 'the Conventional Commits specification' : 'the team\'s commit conventions'
+    }.
 
 ## Analysis Summary
 - Files changed: ${analysis.filesChanged.length}
@@ -762,15 +887,15 @@ export class CommitAnalyzer {
 - Type: ${analysis.suggestedType}
 - Scope: ${analysis.suggestedScope || '(none)'}
 
-## Raw Diff (truncated)
+## Raw Diff${diff.truncated ? ' (truncated)' : ''}
 \`\`\`diff
-${analysis.rawDiff.slice(0, 3000)}${analysis.rawDiff.length > 3000 ? '\n... (truncated)' : ''}
+${diff.text}
 \`\`\`
 
 ## Instructions
 Generate a commit message following this format:
 \`\`\`
-<type>(<scope>): <subject>
+${describeCommitTemplate(convention)}
 
 [optional body]
 
@@ -778,13 +903,14 @@ Generate a commit message following this format:
 \`\`\`
 
 Rules:
-1. The subject line should be 50-72 characters
-2. Use imperative mood ("add" not "added")
-3. Don't capitalize the first letter of the subject
-4. No period at the end of the subject
-5. Focus on WHY, not WHAT (the diff shows what)
-6. If there are breaking changes, include "BREAKING CHANGE:" in the footer
-7. Use the suggested type unless you have strong reason to change it
+${[
+  ...formatCommitConventionRules(convention),
+  'Use imperative mood ("add" not "added")',
+  'No period at the end of the subject',
+  'Focus on WHY, not WHAT (the diff shows what)',
+  'If there are breaking changes, include "BREAKING CHANGE:" in the footer',
+  'Use the suggested type unless you have strong reason to change it'
+].map((rule, i) => `${i + 1}. ${rule}`).join('\n')}
 
 Respond with ONLY the commit message, no explanations or markdown code blocks.`;
 
@@ -828,7 +954,8 @@ Respond with ONLY the commit message, no explanations or markdown code blocks.`;
       body,
       footer,
       fullMessage: message,
-      analysis
+      analysis,
+      violations: validateCommitMessage(message, this.convention)
     };
   }
 }
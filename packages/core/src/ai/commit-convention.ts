@@ -0,0 +1,259 @@
+/**
+ * Commit Conventions
+ * The message format `cv commit` generates to, from the commit section of
+ * .cv/config.json: a subject-line template (Conventional Commits, Angular,
+ * gitmoji, ticket-prefixed, or a custom one), the types and scopes allowed,
+ * the subject length limit, and a ticket ID taken from the branch name.
+ * Generated messages are checked against it, and regenerated with the
+ * broken rules listed when they do not conform.
+ */
+
+import { CVConfig, ConfigError } from '@cv-git/shared';
+
+export type CommitConventionName = NonNullable<NonNullable<CVConfig['commit']>['convention']>;
+
+/**
+ * A convention resolved from the config and the current branch
+ */
+export interface CommitConvention {
+  name: CommitConventionName;
+  /** Subject line with {type}, {scope}, {emoji}, {ticket} and {subject} placeholders */
+  template: string;
+  /** Commit types a message may use */
+  types: string[];
+  /** Scopes a message may use (undefined: any) */
+  scopes?: string[];
+  maxSubjectLength: number;
+  /** Ticket ID from the branch name */
+  ticket?: string;
+  /** The template asks for a ticket but the branch name has none, so {ticket} was left out */
+  missingTicket?: boolean;
+  /** Rules given to the generator besides the format */
+  rules: string[];
+}
+
+export const DEFAULT_MAX_SUBJECT_LENGTH = 72;
+export const DEFAULT_TICKET_PATTERN = '[A-Z][A-Z0-9]+-\\d+';
+
+const CONVENTIONAL_TYPES = ['feat', 'fix', 'refactor', 'docs', 'test', 'chore', 'style', 'perf', 'build', 'ci'];
+
+/** Gitmoji of each commit type */
+export const GITMOJI: Record<string, string> = {
+  feat: ':sparkles:',
+  fix: ':bug:',
+  refactor: ':recycle:',
+  docs: ':memo:',
+  test: ':white_check_mark:',
+  chore: ':wrench:',
+  style: ':art:',
+  perf: ':zap:',
+  build: ':package:',
+  ci: ':construction_worker:'
+};
+
+const LOWERCASE_SUBJECT = "Don't capitalize the first letter of the subject";
+
+const CONVENTIONS: Record<CommitConventionName, { template: string; types: string[]; rules: string[] }> = {
+  conventional: { template: '{type}({scope}): {subject}', types: CONVENTIONAL_TYPES, rules: [LOWERCASE_SUBJECT] },
+  // Angular's commit guidelines leave out chore and style
+  angular: {
+    template: '{type}({scope}): {subject}',
+    types: ['build', 'ci', 'docs', 'feat', 'fix', 'perf', 'refactor', 'test'],
+    rules: [LOWERCASE_SUBJECT]
+  },
+  gitmoji: { template: '{emoji} {subject}', types: CONVENTIONAL_TYPES, rules: ['Capitalize the first letter of the subject'] },
+  ticket: { template: '{ticket} {subject}', types: CONVENTIONAL_TYPES, rules: ['Capitalize the first letter of the subject'] }
+};
+
+/**
+ * The convention of the commit config, with the ticket ID of the branch
+ */
+export function resolveCommitConvention(config: CVConfig['commit'] = {}, branch?: string): CommitConvention {
+  const name = config.convention ?? 'conventional';
+  const preset = CONVENTIONS[name];
+  if (!preset) {
+    throw new ConfigError(`Invalid commit.convention: ${name} (expected one of: ${Object.keys(CONVENTIONS).join(', ')})`);
+  }
+
+  let template = config.template ?? preset.template;
+  if (!template.includes('{subject}')) {
+    throw new ConfigError(`commit.template must contain {subject}: ${template}`);
+  }
+
+  const wantsTicket = template.includes('{ticket}');
+  const pattern = config.ticketPattern ?? (wantsTicket ? DEFAULT_TICKET_PATTERN : undefined);
+  const ticket = pattern && branch ? extractTicketId(branch, pattern) : undefined;
+  if (wantsTicket && !ticket) {
+    template = dropPlaceholder(template, 'ticket');
+  }
+
+  return {
+    name,
+    template,
+    types: preset.types,
+    scopes: config.scopes?.length ? config.scopes : undefined,
+    maxSubjectLength: config.maxSubjectLength ?? DEFAULT_MAX_SUBJECT_LENGTH,
+    ticket,
+    missingTicket: wantsTicket && !ticket,
+    rules: [...preset.rules, ...(config.rules ?? [])]
+  };
+}
+
+/**
+ * The ticket ID in a branch name: the pattern's first group, or its whole
+ * match (e.g. ABC-123 in feature/ABC-123-login)
+ */
+export function extractTicketId(branch: string, pattern: string): string | undefined {
+  let regex: RegExp;
+  try {
+    regex = new RegExp(pattern);
+  } catch (error: any) {
+    throw new ConfigError(`Invalid commit.ticketPattern: ${error.message}`);
+  }
+  const match = branch.match(regex);
+  return match ? match[1] ?? match[0] : undefined;
+}
+
+/**
+ * A subject line in the convention's format
+ */
+export function formatCommitSubject(
+  convention: CommitConvention,
+  parts: { type: string; scope?: string; subject: string }
+): string {
+  const template = parts.scope ? convention.template : dropPlaceholder(convention.template, 'scope');
+  const values: Record<string, string | undefined> = {
+    type: parts.type,
+    scope: parts.scope,
+    emoji: GITMOJI[parts.type] ?? GITMOJI.chore,
+    ticket: convention.ticket,
+    subject: parts.subject
+  };
+  return template.replace(/\{(\w+)\}/g, (placeholder, name: string) => values[name] ?? placeholder);
+}
+
+/**
+ * The format and rules of the convention as generator instructions
+ */
+export function formatCommitConventionRules(convention: CommitConvention): string[] {
+  const { template } = convention;
+  const rules = [`The subject line follows the format above and is at most ${convention.maxSubjectLength} characters`];
+
+  if (template.includes('{type}')) {
+    rules.push(`<type> is one of: ${convention.types.join(', ')}`);
+  }
+  if (template.includes('{scope}')) {
+    rules.push(convention.scopes
+      ? `<scope> is one of: ${convention.scopes.join(', ')}; leave it out (with its brackets) if none fits`
+      : '<scope> is optional; leave it out (with its brackets) if no single area fits');
+  }
+  if (template.includes('{emoji}')) {
+    rules.push(`<emoji> is the gitmoji code of the kind of change: ${convention.types.map(type => `${GITMOJI[type]} ${type}`).join(', ')}`);
+  }
+  if (convention.ticket && !template.includes('{ticket}')) {
+    rules.push(`Reference the ticket in a footer line: Refs: ${convention.ticket}`);
+  }
+  return [...rules, ...convention.rules];
+}
+
+/**
+ * The template as shown to the generator, e.g. <type>(<scope>): <subject>,
+ * with the ticket filled in
+ */
+export function describeCommitTemplate(convention: CommitConvention): string {
+  return convention.template
+    .replace(/\{ticket\}/g, convention.ticket ?? '')
+    .replace(/\{(\w+)\}/g, '<$1>');
+}
+
+/**
+ * The rules a message breaks (empty when it conforms)
+ */
+export function validateCommitMessage(message: string, convention: CommitConvention): string[] {
+  const lines = message.split('\n');
+  const subject = lines[0].trimEnd();
+  const violations: string[] = [];
+
+  if (subject.length > convention.maxSubjectLength) {
+    violations.push(`The subject line has ${subject.length} characters; the limit is ${convention.maxSubjectLength}`);
+  }
+  if (lines.length > 1 && lines[1].trim() !== '') {
+    violations.push('The subject line must be followed by a blank line');
+  }
+
+  const parts = matchCommitTemplate(subject, convention);
+  if (!parts) {
+    violations.push(`The subject line does not follow the format ${describeCommitTemplate(convention)}`);
+  } else {
+    if (parts.type !== undefined && !convention.types.includes(parts.type)) {
+      violations.push(`Type "${parts.type}" is not one of: ${convention.types.join(', ')}`);
+    }
+    if (parts.scope !== undefined && convention.scopes && !convention.scopes.includes(parts.scope)) {
+      violations.push(`Scope "${parts.scope}" is not one of: ${convention.scopes.join(', ')}`);
+    }
+  }
+
+  if (convention.ticket && !convention.template.includes('{ticket}') && !message.includes(convention.ticket)) {
+    violations.push(`The message does not reference ticket ${convention.ticket}`);
+  }
+  return violations;
+}
+
+/**
+ * Prompt asking for a new message after one was rejected
+ */
+export function formatCommitRetryPrompt(prompt: string, message: string, violations: string[]): string {
+  return `${prompt}
+
+## Rejected Message
+This message was rejected:
+${message}
+
+It breaks these rules:
+${violations.map(violation => `- ${violation}`).join('\n')}
+
+Write a new message that follows every rule. Respond with ONLY the commit message.`;
+}
+
+/**
+ * The placeholders of a subject line, or null if it does not follow the
+ * template. The scope may be left out, with its brackets.
+ */
+function matchCommitTemplate(subject: string, convention: CommitConvention): Record<string, string | undefined> | null {
+  const templates = [convention.template];
+  if (convention.template.includes('{scope}')) {
+    templates.push(dropPlaceholder(convention.template, 'scope'));
+  }
+
+  for (const template of templates) {
+    const source = template.split(/(\{\w+\})/).map(part => {
+      const name = part.match(/^\{(\w+)\}$/)?.[1];
+      switch (name) {
+        case 'type': return '(?<type>[\\w-]+)';
+        case 'scope': return '(?<scope>[^()\\[\\]]+)';
+        case 'emoji': return '(?<emoji>:[\\w+-]+:|\\p{Extended_Pictographic}\\S*)';
+        case 'subject': return '(?<subject>\\S.*)';
+        case 'ticket': return escapeRegExp(convention.ticket ?? part);
+        default: return escapeRegExp(part);
+      }
+    }).join('');
+    const match = new RegExp(`^${source}$`, 'u').exec(subject);
+    if (match) return { ...match.groups };
+  }
+  return null;
+}
+
+/**
+ * A template without a placeholder, its brackets, and the separator after it
+ * when it starts the line
+ */
+function dropPlaceholder(template: string, name: string): string {
+  const placeholder = `[([]?\\{${name}\\}[)\\]]?`;
+  return template
+    .replace(new RegExp(`^${placeholder}[\\s:-]*`), '')
+    .replace(new RegExp(`\\s*${placeholder}`), '');
+}
+
+function escapeRegExp(text: string): string {
+  return text.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+}
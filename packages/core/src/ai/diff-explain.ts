@@ -0,0 +1,260 @@
+/**
+ * Diff Explanation
+ * Splits a unified diff into prompt-sized parts, correlates changed lines with
+ * indexed chunk metadata, and builds the prompts behind `cv diff --explain`
+ */
+
+import { CodeChunkPayload } from '@cv-git/shared';
+
+/** Functions at or above this cyclomatic complexity are flagged (same default as summaries) */
+export const DEFAULT_COMPLEXITY_THRESHOLD = 10;
+
+/** Largest diff part sent to the model in one prompt */
+export const DEFAULT_DIFF_PART_CHARS = 12000;
+
+/**
+ * Lines touched by one hunk of a diff
+ */
+export interface ChangedRange {
+  file: string;
+  /** First line of the hunk (new side; old side for deleted files) */
+  startLine: number;
+  endLine: number;
+  /** Function context git printed after the @@ marker, if any */
+  context: string;
+}
+
+/**
+ * A complex function touched by the diff
+ */
+export interface ComplexChange {
+  file: string;
+  symbolName: string;
+  complexity: number;
+  startLine: number;
+  endLine: number;
+}
+
+export interface DiffRisk {
+  description: string;
+  file?: string;
+  severity: 'low' | 'medium' | 'high';
+}
+
+export interface DiffExplanation {
+  summary: string;
+  risks: DiffRisk[];
+  complexChanges: ComplexChange[];
+  /** Number of parts the diff was split into */
+  parts: number;
+}
+
+/**
+ * Line ranges touched by each hunk in a unified diff
+ */
+export function parseChangedRanges(diff: string): ChangedRange[] {
+  const ranges: ChangedRange[] = [];
+  let oldFile = '';
+  let newFile = '';
+
+  for (const line of diff.split('\n')) {
+    if (line.startsWith('--- ')) {
+      oldFile = stripDiffPrefix(line.slice(4));
+    } else if (line.startsWith('+++ ')) {
+      newFile = stripDiffPrefix(line.slice(4));
+    } else if (line.startsWith('@@')) {
+      const match = line.match(/^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$/);
+      if (!match) continue;
+
+      const deleted = newFile === '/dev/null';
+      const start = parseInt(deleted ? match[1] : match[3], 10);
+      const count = parseInt((deleted ? match[2] : match[4]) ?? '1', 10);
+      ranges.push({
+        file: deleted ? oldFile : newFile,
+        startLine: start,
+        endLine: start + Math.max(count, 1) - 1,
+        context: match[5].trim()
+      });
+    }
+  }
+
+  return ranges;
+}
+
+function stripDiffPrefix(file: string): string {
+  const trimmed = file.split('\t')[0].trim();
+  return trimmed === '/dev/null' ? trimmed : trimmed.replace(/^[ab]\//, '');
+}
+
+/**
+ * Split a diff into parts of at most maxChars, keeping each file's header with
+ * its hunks. Files larger than the budget are split between hunks.
+ */
+export function splitDiff(diff: string, maxChars: number = DEFAULT_DIFF_PART_CHARS): string[] {
+  const sections = diff.split(/(?=^diff --git )/m).filter(s => s.trim().length > 0);
+  const parts: string[] = [];
+  let current = '';
+
+  const flush = () => {
+    if (current) parts.push(current);
+    current = '';
+  };
+
+  for (const section of sections) {
+    if (current.length + section.length <= maxChars) {
+      current += section;
+      continue;
+    }
+    flush();
+
+    if (section.length <= maxChars) {
+      current = section;
+      continue;
+    }
+
+    // Oversized file: repeat its header in front of each group of hunks
+    const [header, ...hunks] = section.split(/(?=^@@ )/m);
+    let piece = header;
+    for (const hunk of hunks) {
+      if (piece.length > header.length && piece.length + hunk.length > maxChars) {
+        parts.push(piece);
+        piece = header;
+      }
+      // A single hunk over budget is cut at a line boundary
+      const room = maxChars - header.length;
+      piece += hunk.length > room ? hunk.slice(0, Math.max(hunk.lastIndexOf('\n', room), 0)) + '\n... (hunk truncated)\n' : hunk;
+    }
+    parts.push(piece);
+  }
+  flush();
+
+  return parts;
+}
+
+/**
+ * Complex functions whose indexed chunks overlap the changed lines.
+ * A hunk also counts when git's function context names the symbol, which
+ * keeps historical diffs matched after the lines have moved.
+ */
+export function findComplexChanges(
+  ranges: ChangedRange[],
+  chunks: CodeChunkPayload[],
+  threshold: number = DEFAULT_COMPLEXITY_THRESHOLD
+): ComplexChange[] {
+  const found = new Map<string, ComplexChange>();
+
+  for (const chunk of chunks) {
+    if (!chunk.symbolName || (chunk.complexity ?? 0) < threshold) continue;
+
+    const touched = ranges.some(r =>
+      r.file === chunk.file &&
+      ((r.startLine <= chunk.endLine && r.endLine >= chunk.startLine) ||
+        new RegExp(`\\b${escapeRegExp(chunk.symbolName!)}\\b`).test(r.context))
+    );
+    if (!touched) continue;
+
+    const key = `${chunk.file}:${chunk.symbolName}`;
+    const existing = found.get(key);
+    if (!existing || chunk.complexity! > existing.complexity) {
+      found.set(key, {
+        file: chunk.file,
+        symbolName: chunk.symbolName,
+        complexity: chunk.complexity!,
+        startLine: chunk.startLine,
+        endLine: chunk.endLine
+      });
+    }
+  }
+
+  return Array.from(found.values()).sort((a, b) => b.complexity - a.complexity || a.file.localeCompare(b.file));
+}
+
+function escapeRegExp(text: string): string {
+  return text.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+}
+
+function formatComplexChanges(complexChanges: ComplexChange[]): string {
+  if (complexChanges.length === 0) return '';
+  let text = `## Complex Functions Touched\n\n`;
+  for (const change of complexChanges) {
+    text += `- ${change.symbolName} in ${change.file} (cyclomatic complexity ${change.complexity})\n`;
+  }
+  return text + `\nChanges to these deserve extra scrutiny.\n\n`;
+}
+
+/**
+ * Prompt summarizing one part of a diff that was too large for a single prompt
+ */
+export function buildDiffPartPrompt(part: string, index: number, total: number): string {
+  let prompt = `You are an expert software engineer. This is part ${index + 1} of ${total} of a diff.\n`;
+  prompt += `Summarize what changed in this part in a few bullet points, and note anything risky `;
+  prompt += `(behaviour changes, removed checks, error handling, concurrency, security, API changes).\n\n`;
+  prompt += `\`\`\`diff\n${part}\n\`\`\``;
+  return prompt;
+}
+
+/**
+ * Prompt producing the final explanation, from the diff itself or from part summaries
+ */
+export function buildDiffExplanationPrompt(
+  content: { diff: string } | { partSummaries: string[] },
+  complexChanges: ComplexChange[]
+): string {
+  let prompt = `You are an expert software engineer explaining a set of code changes before they are committed.\n\n`;
+
+  if ('diff' in content) {
+    prompt += `## Diff\n\n\`\`\`diff\n${content.diff}\n\`\`\`\n\n`;
+  } else {
+    prompt += `## Summaries Of Each Part Of The Diff\n\n`;
+    content.partSummaries.forEach((summary, i) => {
+      prompt += `### Part ${i + 1}\n${summary.trim()}\n\n`;
+    });
+  }
+
+  prompt += formatComplexChanges(complexChanges);
+
+  prompt += `Explain in plain English what changed and why it might be risky.\n`;
+  prompt += `Respond with JSON in this format:\n`;
+  prompt += `{\n`;
+  prompt += `  "summary": "A short plain-English summary of what changed and why",\n`;
+  prompt += `  "risks": [\n`;
+  prompt += `    { "description": "What could go wrong", "file": "path/to/file", "severity": "low|medium|high" }\n`;
+  prompt += `  ]\n`;
+  prompt += `}\n\n`;
+  prompt += `Use an empty risks array if the changes look safe.`;
+  return prompt;
+}
+
+/**
+ * Parse the model's JSON explanation; unparseable responses become the summary
+ */
+export function parseDiffExplanation(
+  response: string,
+  complexChanges: ComplexChange[],
+  parts: number
+): DiffExplanation {
+  let parsed: any;
+  try {
+    const jsonMatch = response.match(/\{[\s\S]*\}/);
+    parsed = jsonMatch ? JSON.parse(jsonMatch[0]) : undefined;
+  } catch {
+    parsed = undefined;
+  }
+
+  const risks: DiffRisk[] = [];
+  for (const raw of Array.isArray(parsed?.risks) ? parsed.risks : []) {
+    if (!raw || typeof raw.description !== 'string' || !raw.description.trim()) continue;
+    const severity = String(raw.severity).toLowerCase();
+    risks.push({
+      description: raw.description.trim(),
+      file: typeof raw.file === 'string' && raw.file.trim() ? raw.file.trim() : undefined,
+      severity: severity === 'low' || severity === 'high' ? severity : 'medium'
+    });
+  }
+
+  const summary = typeof parsed?.summary === 'string' && parsed.summary.trim()
+    ? parsed.summary.trim()
+    : response.trim();
+
+  return { summary, risks, complexChanges, parts };
+}
@@ -0,0 +1,151 @@
+/**
+ * Diff Review
+ * Parses a unified diff into hunks with new-file line numbers for
+ * `cv review --diff`, so the model sees (and reports against) the lines as
+ * they are after the change, and maps findings back onto the changed lines.
+ */
+
+import { ReviewFinding, ReviewResult } from '@cv-git/shared';
+import { FileDiff, DiffHunk } from '../code/types.js';
+import { sortFindings, summarizeFindings } from './review-findings.js';
+
+/**
+ * The sections of a unified diff whose file passes `keep` (the new path; the old one for deletions)
+ */
+export function filterDiffFiles(diff: string, keep: (file: string) => boolean): string {
+  return diff
+    .split(/(?=^diff --git )/m)
+    .filter(section => {
+      const header = section.match(/^diff --git a\/(.+?) b\/(.+)$/m);
+      if (!header) return false;
+      const deleted = /^\+\+\+ \/dev\/null/m.test(section);
+      return keep(deleted ? header[1] : header[2]);
+    })
+    .join('');
+}
+
+/**
+ * Files and hunks of a unified diff. Binary files and pure renames have no hunks and are left out.
+ */
+export function parseDiffHunks(diff: string): FileDiff[] {
+  const files: FileDiff[] = [];
+  let oldFile = '';
+  let current: FileDiff | null = null;
+  let hunk: DiffHunk | null = null;
+  let oldLine = 0;
+  let newLine = 0;
+
+  for (const line of diff.split('\n')) {
+    if (line.startsWith('diff --git ')) {
+      current = null;
+      hunk = null;
+    } else if (!hunk && line.startsWith('--- ')) {
+      oldFile = stripDiffPrefix(line.slice(4));
+    } else if (!hunk && line.startsWith('+++ ')) {
+      const newFile = stripDiffPrefix(line.slice(4));
+      const type = newFile === '/dev/null' ? 'delete' : oldFile === '/dev/null' ? 'create' : 'modify';
+      current = { path: type === 'delete' ? oldFile : newFile, type, hunks: [] };
+      files.push(current);
+    } else if (line.startsWith('@@') && current) {
+      const match = line.match(/^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@/);
+      if (!match) continue;
+      oldLine = parseInt(match[1], 10);
+      newLine = parseInt(match[3], 10);
+      hunk = {
+        oldStart: oldLine,
+        oldLines: parseInt(match[2] ?? '1', 10),
+        newStart: newLine,
+        newLines: parseInt(match[4] ?? '1', 10),
+        lines: []
+      };
+      current.hunks.push(hunk);
+    } else if (hunk) {
+      if (line.startsWith('+')) {
+        hunk.lines.push({ type: 'add', content: line.slice(1), newLineNumber: newLine++ });
+      } else if (line.startsWith('-')) {
+        hunk.lines.push({ type: 'remove', content: line.slice(1), oldLineNumber: oldLine++ });
+      } else if (line.startsWith(' ')) {
+        hunk.lines.push({ type: 'context', content: line.slice(1), oldLineNumber: oldLine++, newLineNumber: newLine++ });
+      }
+      // "\ No newline at end of file" and blank separators carry no code
+    }
+  }
+
+  return files.filter(file => file.hunks.length > 0);
+}
+
+function stripDiffPrefix(file: string): string {
+  const trimmed = file.split('\t')[0].trim();
+  return trimmed === '/dev/null' ? trimmed : trimmed.replace(/^[ab]\//, '');
+}
+
+/**
+ * Render hunks with each line prefixed by its new-file line number, e.g.
+ * `  42 + return total;`. Deleted lines have no number.
+ */
+export function formatNumberedDiff(files: FileDiff[]): string {
+  const width = Math.max(3, ...files.flatMap(file => file.hunks.map(hunk => String(hunk.newStart + hunk.newLines).length)));
+  const sections: string[] = [];
+
+  for (const file of files) {
+    const lines = [`### ${file.path}${file.type === 'delete' ? ' (deleted)' : ''}`];
+    for (const hunk of file.hunks) {
+      lines.push(`@@ new lines ${hunk.newStart}-${hunk.newStart + Math.max(hunk.newLines, 1) - 1} @@`);
+      for (const line of hunk.lines) {
+        const number = line.newLineNumber === undefined ? ''.padStart(width) : String(line.newLineNumber).padStart(width);
+        const marker = line.type === 'add' ? '+' : line.type === 'remove' ? '-' : ' ';
+        lines.push(`${number} ${marker} ${line.content}`);
+      }
+    }
+    sections.push(lines.join('\n'));
+  }
+
+  return sections.join('\n\n');
+}
+
+/**
+ * Keep findings on files in the diff and move each onto the changed lines:
+ * a finding outside every added line is snapped to the nearest one in its file.
+ * Findings on files the diff doesn't touch are dropped.
+ */
+export function mapFindingsToDiff(result: ReviewResult, files: FileDiff[]): ReviewResult {
+  const added = new Map<string, number[]>();
+  for (const file of files) {
+    const lines = file.hunks.flatMap(hunk => hunk.lines.filter(line => line.type === 'add').map(line => line.newLineNumber!));
+    // A hunk that only deletes lines is reported at its position in the new file
+    added.set(file.path, lines.length > 0 ? lines : file.hunks.map(hunk => hunk.newStart));
+  }
+
+  const findings: ReviewFinding[] = [];
+  for (const finding of result.findings) {
+    const file = matchDiffFile(finding.file, [...added.keys()]);
+    if (!file) continue;
+
+    const lines = added.get(file)!;
+    const touched = lines.filter(line => line >= finding.startLine && line <= finding.endLine);
+    if (touched.length > 0) {
+      findings.push({ ...finding, file, startLine: touched[0], endLine: touched[touched.length - 1] });
+      continue;
+    }
+    const nearest = lines.reduce((best, line) =>
+      Math.abs(line - finding.startLine) < Math.abs(best - finding.startLine) ? line : best
+    );
+    findings.push({ ...finding, file, startLine: nearest, endLine: nearest });
+  }
+
+  const sorted = sortFindings(findings);
+  const summary = summarizeFindings(sorted, result.summary.overview);
+  if (result.summary.disabledCategories) {
+    summary.disabledCategories = result.summary.disabledCategories;
+  }
+  return { findings: sorted, summary };
+}
+
+/**
+ * The diff path a finding refers to; models sometimes add a/ b/ prefixes or drop leading directories
+ */
+function matchDiffFile(file: string, diffFiles: string[]): string | undefined {
+  const normalized = stripDiffPrefix(file).replace(/^\.\//, '');
+  return diffFiles.find(candidate => candidate === normalized) ??
+    diffFiles.find(candidate => candidate.endsWith(`/${normalized}`));
+}
@@ -0,0 +1,273 @@
+/**
+ * Doc Comment Generation
+ * Finds exported symbols without doc comments, builds the prompt behind
+ * `cv docs <file>`, and inserts the generated comments in the file's own
+ * comment style
+ *
+ * The model only writes the comment text; delimiters, indentation and
+ * placement are done here so the result is always well-formed.
+ */
+
+import { SymbolNode } from '@cv-git/shared';
+import { REDACTED_SECRET } from '../security/redact.js';
+
+/** Symbol kinds that get doc comments */
+const DOCUMENTED_KINDS = new Set(['function', 'method', 'class', 'interface', 'type', 'struct', 'enum', 'trait']);
+
+export interface DocTarget {
+  symbol: SymbolNode;
+  /** The symbol's source lines */
+  source: string;
+  /** Whether the symbol already has a doc comment (only listed when overwriting) */
+  documented: boolean;
+}
+
+export interface DocComment {
+  symbol: SymbolNode;
+  /** Comment text without delimiters */
+  text: string;
+}
+
+/**
+ * Exported symbols that need a doc comment: undocumented ones, plus
+ * documented ones when overwriting
+ */
+export function findDocTargets(
+  symbols: SymbolNode[],
+  content: string,
+  language: string,
+  overwrite: boolean = false
+): DocTarget[] {
+  const lines = content.split('\n');
+  const targets: DocTarget[] = [];
+
+  for (const symbol of symbols) {
+    if (!DOCUMENTED_KINDS.has(symbol.kind) || !isExported(symbol, symbols, lines, language)) {
+      continue;
+    }
+    const documented = hasDocComment(symbol, lines, language);
+    if (documented && !overwrite) {
+      continue;
+    }
+    targets.push({
+      symbol,
+      source: lines.slice(symbol.startLine - 1, symbol.endLine).join('\n'),
+      documented
+    });
+  }
+
+  return targets.sort((a, b) => a.symbol.startLine - b.symbol.startLine);
+}
+
+/**
+ * Whether a symbol is part of the file's public API
+ */
+function isExported(symbol: SymbolNode, symbols: SymbolNode[], lines: string[], language: string): boolean {
+  switch (language) {
+    case 'go':
+      return /^[A-Z]/.test(symbol.name);
+    case 'typescript':
+    case 'javascript': {
+      if (symbol.kind !== 'method') {
+        return /^\s*export\s/.test(lines[symbol.startLine - 1] || '');
+      }
+      // Public methods of exported classes
+      const owner = symbols.find(s =>
+        s.kind === 'class' && s.startLine < symbol.startLine && s.endLine >= symbol.endLine
+      );
+      return symbol.visibility === 'public' && !symbol.name.startsWith('#') && !!owner &&
+        isExported(owner, symbols, lines, language);
+    }
+    case 'rust':
+      return /^\s*pub(\(crate\))?\s/.test(lines[symbol.startLine - 1] || '');
+    default:
+      return symbol.visibility === 'public';
+  }
+}
+
+/**
+ * Whether a symbol already has a doc comment, from the parser or the lines around it
+ */
+function hasDocComment(symbol: SymbolNode, lines: string[], language: string): boolean {
+  if (symbol.docstring?.trim()) {
+    return true;
+  }
+  if (language === 'python') {
+    return findPythonDocstring(symbol, lines) !== null;
+  }
+  return findCommentAbove(symbol, lines, language) !== null;
+}
+
+/**
+ * Line a comment goes above: the symbol's first line, or its first decorator/attribute
+ */
+function commentLine(symbol: SymbolNode, lines: string[]): number {
+  let index = symbol.startLine - 1;
+  while (index > 0 && /^\s*(@|#\[)/.test(lines[index - 1])) {
+    index--;
+  }
+  return index;
+}
+
+/**
+ * [start, end) line range of the comment block directly above a symbol
+ */
+function findCommentAbove(symbol: SymbolNode, lines: string[], language: string): [number, number] | null {
+  const end = commentLine(symbol, lines);
+  const linePrefix = language === 'rust' ? /^\s*\/\/[/!]?/ : /^\s*\/\//;
+  let start = end;
+
+  if (start > 0 && /\*\/\s*$/.test(lines[start - 1])) {
+    // Block comment: walk up to its opening
+    while (start > 0 && !/^\s*\/\*/.test(lines[start - 1])) start--;
+    return start > 0 ? [start - 1, end] : null;
+  }
+
+  while (start > 0 && linePrefix.test(lines[start - 1])) start--;
+  return start < end ? [start, end] : null;
+}
+
+/**
+ * Line after a Python def/class header (the first body line)
+ */
+function pythonBodyStart(symbol: SymbolNode, lines: string[]): number {
+  for (let i = symbol.startLine - 1; i < symbol.endLine; i++) {
+    if (/:\s*(#.*)?$/.test(lines[i])) {
+      return i + 1;
+    }
+  }
+  return symbol.startLine;
+}
+
+/**
+ * [start, end) line range of a Python docstring
+ */
+function findPythonDocstring(symbol: SymbolNode, lines: string[]): [number, number] | null {
+  const start = pythonBodyStart(symbol, lines);
+  const first = (lines[start] || '').trim();
+  const quote = first.startsWith('"""') ? '"""' : first.startsWith("'''") ? "'''" : null;
+  if (!quote) {
+    return null;
+  }
+  if (first.length > 3 && first.slice(3).includes(quote)) {
+    return [start, start + 1];
+  }
+  for (let i = start + 1; i < lines.length; i++) {
+    if (lines[i].includes(quote)) {
+      return [start, i + 1];
+    }
+  }
+  return null;
+}
+
+/**
+ * Prompt asking for one comment per target, as a JSON object keyed by qualified name
+ */
+export function buildDocCommentPrompt(file: string, language: string, targets: DocTarget[]): string {
+  let prompt = `You are an expert software engineer writing API documentation for ${file}.\n\n`;
+  prompt += `Write a doc comment for each ${language} symbol below.\n\n`;
+
+  for (const target of targets) {
+    prompt += `## ${target.symbol.qualifiedName} (${target.symbol.kind}, lines ${target.symbol.startLine}-${target.symbol.endLine})\n\n`;
+    prompt += `\`\`\`${language}\n${target.source}\n\`\`\`\n\n`;
+  }
+
+  prompt += `## Requirements\n\n`;
+  prompt += `- Describe what the symbol does and anything a caller must know (errors, side effects, units); don't restate the signature.\n`;
+  prompt += `- Be brief: one sentence for simple symbols, a short paragraph at most.\n`;
+  prompt += `- Follow ${language} documentation conventions.\n`;
+  if (language === 'go') {
+    prompt += `- Start each comment with the symbol's name, e.g. "ParseConfig reads ...".\n`;
+  }
+  prompt += `- Write plain text without comment delimiters (no //, /**, #, or quotes); they are added for you.\n`;
+  prompt += `- Never include ${REDACTED_SECRET} placeholders.\n\n`;
+  prompt += `Respond with ONLY a JSON object mapping each qualified name to its comment text, e.g. {"${targets[0]?.symbol.qualifiedName ?? 'file:name'}": "..."}.`;
+
+  return prompt;
+}
+
+/**
+ * Comments from the model's response, in target order; targets it skipped are left out
+ */
+export function parseDocComments(response: string, targets: DocTarget[]): DocComment[] {
+  const start = response.indexOf('{');
+  const end = response.lastIndexOf('}');
+  if (start < 0 || end <= start) {
+    throw new Error('The model did not return doc comments as JSON');
+  }
+
+  let parsed: Record<string, unknown>;
+  try {
+    parsed = JSON.parse(response.slice(start, end + 1));
+  } catch {
+    throw new Error('The model returned malformed JSON for the doc comments');
+  }
+
+  const comments: DocComment[] = [];
+  for (const { symbol } of targets) {
+    const text = parsed[symbol.qualifiedName] ?? parsed[symbol.name];
+    if (typeof text === 'string' && text.trim() && !text.includes(REDACTED_SECRET)) {
+      comments.push({ symbol, text: text.trim() });
+    }
+  }
+  return comments;
+}
+
+/**
+ * Comment text as comment lines in the language's style, at an indent
+ */
+export function formatDocComment(comment: DocComment, language: string, indent: string): string[] {
+  let text = comment.text.replace(/\r/g, '');
+
+  // Go doc comments begin with the name they document
+  if (language === 'go' && !new RegExp(`^${comment.symbol.name}\\b`).test(text)) {
+    text = `${comment.symbol.name} ${text.charAt(0).toLowerCase()}${text.slice(1)}`;
+  }
+
+  const body = text.split('\n').map(line => line.trimEnd());
+
+  switch (language) {
+    case 'go':
+      return body.map(line => `${indent}//${line ? ` ${line}` : ''}`);
+    case 'rust':
+      return body.map(line => `${indent}///${line ? ` ${line}` : ''}`);
+    case 'python':
+      return body.length === 1
+        ? [`${indent}"""${body[0]}"""`]
+        : [`${indent}"""${body[0]}`, ...body.slice(1).map(line => (line ? `${indent}${line}` : '')), `${indent}"""`];
+    default:
+      return [`${indent}/**`, ...body.map(line => `${indent} *${line ? ` ${line}` : ''}`), `${indent} */`];
+  }
+}
+
+/**
+ * New file contents with the comments inserted (replacing existing ones)
+ */
+export function insertDocComments(content: string, language: string, comments: DocComment[]): string {
+  const lines = content.split('\n');
+
+  // Bottom-up, so earlier line numbers stay valid
+  const ordered = [...comments].sort((a, b) => b.symbol.startLine - a.symbol.startLine);
+  for (const comment of ordered) {
+    const { symbol } = comment;
+
+    if (language === 'python') {
+      const bodyStart = pythonBodyStart(symbol, lines);
+      const header = lines[symbol.startLine - 1];
+      const bodyLine = lines[bodyStart];
+      const indent = bodyLine && bodyLine.trim() ? bodyLine.match(/^\s*/)![0] : `${header.match(/^\s*/)![0]}    `;
+      const existing = findPythonDocstring(symbol, lines);
+      const [from, to] = existing ?? [bodyStart, bodyStart];
+      lines.splice(from, to - from, ...formatDocComment(comment, language, indent));
+      continue;
+    }
+
+    const at = commentLine(symbol, lines);
+    const indent = lines[at].match(/^\s*/)![0];
+    const existing = findCommentAbove(symbol, lines, language);
+    const [from, to] = existing ?? [at, at];
+    lines.splice(from, to - from, ...formatDocComment(comment, language, indent));
+  }
+
+  return lines.join('\n');
+}
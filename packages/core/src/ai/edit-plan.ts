@@ -0,0 +1,268 @@
+/**
+ * Edit Plan
+ * The file edits `cv do` proposes, kept apart from applying them
+ *
+ * The model's edit blocks are resolved against the working tree into the
+ * content each file would have afterwards, with a unified diff to review.
+ * The plan is a JSON file: it can be read, trimmed (drop an edit to skip
+ * it) or tweaked (edit an edit's `content`) before `cv do --apply` applies
+ * it. Each edit records a hash of its file as the plan saw it, so a plan
+ * is not applied over changes made since.
+ */
+
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { getCVDir, PlanStep } from '@cv-git/shared';
+import { Edit } from '../code/types.js';
+import { createUnifiedDiff } from './refactor.js';
+
+export const EDIT_PLAN_VERSION = 1;
+
+export type PlannedEditType = 'create' | 'modify' | 'delete' | 'rename';
+
+export interface PlannedEdit {
+  file: string;
+  type: PlannedEditType;
+  /** Where a renamed file goes */
+  newPath?: string;
+  /** Hash of the file when the plan was made (null: it did not exist) */
+  baseHash: string | null;
+  /** The file's content after the edit (create and modify); this is what is applied */
+  content?: string;
+  /** Unified diff of the edit, for review; not applied */
+  diff: string;
+}
+
+export interface EditPlan {
+  version: number;
+  task: string;
+  createdAt: string;
+  /** Commit checked out when the plan was made */
+  head?: string;
+  /** The steps the edits were generated from */
+  steps?: PlanStep[];
+  edits: PlannedEdit[];
+}
+
+/** An edit block that could not be turned into a planned edit */
+export interface RejectedEdit {
+  file: string;
+  error: string;
+}
+
+/**
+ * Where `cv do` saves a plan when no file is given
+ */
+export function getDefaultEditPlanPath(repoRoot: string, now: Date = new Date()): string {
+  const stamp = now.toISOString().replace(/[-:]/g, '').replace('T', '-').slice(0, 15);
+  return path.join(getCVDir(repoRoot), 'plans', `do-${stamp}.json`);
+}
+
+/**
+ * Hash identifying a file's content
+ */
+export function hashFileContent(content: string): string {
+  return createHash('sha256').update(content).digest('hex');
+}
+
+/**
+ * Resolve parsed edit blocks against the working tree. Several blocks for
+ * one file are combined into one edit; blocks that cannot be applied (a
+ * search text not in the file, a path outside the repo) are rejected.
+ */
+export async function buildEditPlan(
+  repoRoot: string,
+  task: string,
+  edits: Edit[],
+  options: { head?: string; steps?: PlanStep[]; now?: Date } = {}
+): Promise<{ plan: EditPlan; rejected: RejectedEdit[] }> {
+  const rejected: RejectedEdit[] = [];
+  /** Content each touched file has in the working tree (null: missing) */
+  const original = new Map<string, string | null>();
+  /** Content each touched file will have (null: deleted) */
+  const planned = new Map<string, string | null>();
+  const renames = new Map<string, string>();
+  const order: string[] = [];
+
+  const read = async (file: string): Promise<string | null> => {
+    if (!original.has(file)) {
+      original.set(file, await readIfExists(path.join(repoRoot, file)));
+      order.push(file);
+    }
+    return planned.has(file) ? planned.get(file)! : original.get(file)!;
+  };
+
+  for (const edit of edits) {
+    try {
+      const file = toRepoPath(repoRoot, edit.file);
+      const current = await read(file);
+
+      switch (edit.type) {
+        case 'create':
+          planned.set(file, edit.newContent ?? '');
+          break;
+        case 'modify': {
+          if (current === null) throw new Error('file does not exist');
+          if (edit.searchReplaceBlocks?.length) {
+            let content = current;
+            for (const block of edit.searchReplaceBlocks) {
+              if (!content.includes(block.search)) {
+                throw new Error(`search text not found: ${block.search.split('\n')[0].trim().slice(0, 80)}`);
+              }
+              content = content.replace(block.search, () => block.replace);
+            }
+            planned.set(file, content);
+          } else {
+            planned.set(file, edit.newContent ?? current);
+          }
+          break;
+        }
+        case 'delete':
+          if (current === null) throw new Error('file does not exist');
+          planned.set(file, null);
+          break;
+        case 'rename': {
+          if (current === null) throw new Error('file does not exist');
+          const target = toRepoPath(repoRoot, edit.newPath || '');
+          if (await read(target) !== null) throw new Error(`${target} already exists`);
+          renames.set(file, target);
+          break;
+        }
+      }
+    } catch (error: any) {
+      rejected.push({ file: edit.file, error: error.message });
+    }
+  }
+
+  const plannedEdits: PlannedEdit[] = [];
+  for (const file of order) {
+    const before = original.get(file)!;
+    const baseHash = before === null ? null : hashFileContent(before);
+    const renamedTo = renames.get(file);
+
+    if (renamedTo) {
+      plannedEdits.push({ file, type: 'rename', newPath: renamedTo, baseHash, diff: `rename from ${file}\nrename to ${renamedTo}\n` });
+    }
+    if (!planned.has(file)) continue;
+
+    const after = planned.get(file)!;
+    if (after === null) {
+      if (before !== null) {
+        plannedEdits.push({ file, type: 'delete', baseHash, diff: editDiff(file, before, '', 'delete') });
+      }
+    } else if (before === null) {
+      plannedEdits.push({ file, type: 'create', baseHash, content: after, diff: editDiff(file, '', after, 'create') });
+    } else if (after !== before) {
+      plannedEdits.push({ file, type: 'modify', baseHash, content: after, diff: editDiff(file, before, after, 'modify') });
+    }
+  }
+
+  return {
+    plan: {
+      version: EDIT_PLAN_VERSION,
+      task,
+      createdAt: (options.now ?? new Date()).toISOString(),
+      head: options.head,
+      steps: options.steps,
+      edits: plannedEdits
+    },
+    rejected
+  };
+}
+
+/**
+ * Files that changed since the plan was made, so applying it would
+ * overwrite someone's work (empty when the plan is safe to apply)
+ */
+export async function findChangedSincePlan(repoRoot: string, plan: EditPlan): Promise<string[]> {
+  const changed: string[] = [];
+  for (const edit of plan.edits) {
+    const current = await readIfExists(path.join(repoRoot, edit.file));
+    const currentHash = current === null ? null : hashFileContent(current);
+    if (currentHash !== edit.baseHash) {
+      changed.push(edit.file);
+    } else if (edit.type === 'rename' && edit.newPath && await readIfExists(path.join(repoRoot, edit.newPath)) !== null) {
+      changed.push(edit.newPath);
+    }
+  }
+  return changed;
+}
+
+/**
+ * The edit FileOperations applies for a planned edit
+ */
+export function toFileEdit(edit: PlannedEdit, messageId: string = 'plan'): Edit {
+  return {
+    id: hashFileContent(`${edit.type}\0${edit.file}\0${edit.newPath ?? ''}`).slice(0, 16),
+    file: edit.file,
+    type: edit.type,
+    newPath: edit.newPath,
+    newContent: edit.content,
+    status: 'approved',
+    messageId,
+    createdAt: Date.now()
+  };
+}
+
+/**
+ * Read a plan file; throws if it is missing or not a plan
+ */
+export async function readEditPlan(file: string): Promise<EditPlan> {
+  let parsed: any;
+  try {
+    parsed = JSON.parse(await fs.readFile(file, 'utf-8'));
+  } catch (error: any) {
+    throw new Error(error.code === 'ENOENT'
+      ? `Plan not found: ${file}`
+      : `Could not read plan ${file}: ${error.message}`);
+  }
+
+  const valid = parsed && typeof parsed.task === 'string' && Array.isArray(parsed.edits) &&
+    parsed.edits.every((edit: any) => typeof edit?.file === 'string' && ['create', 'modify', 'delete', 'rename'].includes(edit.type) &&
+      (edit.type === 'delete' || edit.type === 'rename' ? true : typeof edit.content === 'string') &&
+      (edit.type !== 'rename' || typeof edit.newPath === 'string'));
+  if (!valid) {
+    throw new Error(`${file} is not a cv do plan`);
+  }
+  if (parsed.version > EDIT_PLAN_VERSION) {
+    throw new Error(`${file} was written by a newer version of cv (plan version ${parsed.version})`);
+  }
+  return parsed as EditPlan;
+}
+
+/**
+ * Write a plan file
+ */
+export async function writeEditPlan(file: string, plan: EditPlan): Promise<void> {
+  await fs.mkdir(path.dirname(file), { recursive: true });
+  await fs.writeFile(file, JSON.stringify(plan, null, 2) + '\n');
+}
+
+/**
+ * A repo-relative path with forward slashes; throws for paths outside the repo
+ */
+function toRepoPath(repoRoot: string, file: string): string {
+  const absolute = path.resolve(repoRoot, file);
+  const relative = path.relative(repoRoot, absolute);
+  if (!file || !relative || relative.startsWith('..') || path.isAbsolute(relative)) {
+    throw new Error(`${file || '(no path)'} is outside the repository`);
+  }
+  return relative.split(path.sep).join('/');
+}
+
+function editDiff(file: string, before: string, after: string, type: PlannedEditType): string {
+  const diff = createUnifiedDiff(file, before, after);
+  if (type === 'create') return diff.replace(`--- a/${file}\n`, '--- /dev/null\n');
+  if (type === 'delete') return diff.replace(`+++ b/${file}\n`, '+++ /dev/null\n');
+  return diff;
+}
+
+async function readIfExists(file: string): Promise<string | null> {
+  try {
+    return await fs.readFile(file, 'utf-8');
+  } catch (error: any) {
+    if (error.code === 'ENOENT') return null;
+    throw error;
+  }
+}
@@ -14,10 +14,14 @@ import {
   getModelsByProvider,
 } from './types.js';
 import { OpenRouterClient, createOpenRouterClient, OPENROUTER_MODELS } from './openrouter.js';
+import { createOpenAICompatibleClient } from './openai-compatible.js';
 import { OllamaClient, createOllamaClient, isOllamaRunning } from './ollama.js';
 import { LMStudioClient, createLMStudioClient, isLMStudioRunning } from './lmstudio.js';
+import { AzureOpenAIDeployment, createAzureOpenAIClient } from './azure.js';
+import { createGeminiClient } from './gemini.js';
+import { createAnthropicClient } from './anthropic.js';
 
-export type AIProvider = 'openrouter' | 'ollama' | 'lmstudio' | 'auto';
+export type AIProvider = 'openrouter' | 'anthropic' | 'ollama' | 'lmstudio' | 'azure' | 'gemini' | 'openai-compatible' | 'auto';
 
 export interface AIClientOptions {
   provider?: AIProvider;
@@ -25,6 +29,11 @@ export interface AIClientOptions {
   apiKey?: string;        // Required for OpenRouter
   ollamaUrl?: string;     // Optional Ollama URL (default: localhost:11434)
   lmstudioUrl?: string;   // Optional LM Studio URL (default: localhost:1234/v1)
+  azure?: AzureOpenAIDeployment;  // Required for Azure OpenAI
+  geminiApiKey?: string;  // Required for Gemini
+  anthropicApiKey?: string;  // Required for Anthropic
+  openaiCompatible?: { baseUrl: string; apiKey?: string };  // Required for OpenAI-compatible gateways
+  promptCaching?: boolean;   // Anthropic prompt caching for long prompts
   maxTokens?: number;
   temperature?: number;
 }
@@ -35,6 +44,10 @@ export interface AIClientOptions {
  * Provider selection:
  * - 'openrouter': Use OpenRouter cloud API (requires API key)
  * - 'ollama': Use local Ollama instance
+ * - 'azure': Use an Azure OpenAI deployment (requires endpoint, key and deployment)
+ * - 'gemini': Use the Google Gemini API (requires API key)
+ * - 'anthropic': Use the Anthropic Messages API directly (requires API key)
+ * - 'openai-compatible': Use any OpenAI-compatible gateway (requires base URL and model)
  * - 'auto': Try Ollama first, fall back to OpenRouter if available
  */
 export async function createAIClient(options: AIClientOptions): Promise<AIClient> {
@@ -58,6 +71,55 @@ export async function createAIClient(options: AIClientOptions): Promise<AIClient
     });
   }
 
+  if (provider === 'azure') {
+    if (!options.azure?.endpoint || !options.azure.apiKey || !options.azure.deployment) {
+      throw new Error('Azure OpenAI endpoint, API key and chat deployment required. Run: cv auth setup azure');
+    }
+    return createAzureOpenAIClient({
+      ...options.azure,
+      maxTokens: options.maxTokens,
+      temperature: options.temperature,
+    });
+  }
+
+  if (provider === 'gemini') {
+    if (!options.geminiApiKey) {
+      throw new Error('Gemini API key required. Set GEMINI_API_KEY or run: cv auth setup gemini');
+    }
+    return createGeminiClient({
+      apiKey: options.geminiApiKey,
+      model: options.model,
+      maxTokens: options.maxTokens,
+      temperature: options.temperature,
+    });
+  }
+
+  if (provider === 'anthropic') {
+    if (!options.anthropicApiKey) {
+      throw new Error('Anthropic API key required. Set ANTHROPIC_API_KEY or run: cv auth setup anthropic');
+    }
+    return createAnthropicClient({
+      apiKey: options.anthropicApiKey,
+      model: options.model,
+      maxTokens: options.maxTokens,
+      temperature: options.temperature,
+      promptCaching: options.promptCaching,
+    });
+  }
+
+  if (provider === 'openai-compatible') {
+    if (!options.openaiCompatible?.baseUrl || !options.model) {
+      throw new Error('OpenAI-compatible base URL and model required. Run: cv auth setup openai-compatible');
+    }
+    return createOpenAICompatibleClient({
+      baseUrl: options.openaiCompatible.baseUrl,
+      apiKey: options.openaiCompatible.apiKey,
+      model: options.model,
+      maxTokens: options.maxTokens,
+      temperature: options.temperature,
+    });
+  }
+
   if (provider === 'openrouter') {
     if (!options.apiKey) {
       throw new Error('OpenRouter API key required. Set OPENROUTER_API_KEY or use --provider ollama');
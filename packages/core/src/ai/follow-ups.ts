@@ -0,0 +1,110 @@
+/**
+ * Follow-up Suggestions
+ * Builds the prompt behind `cv explain --suggest` and `cv chat --suggest`,
+ * which asks for the questions worth asking next, and keeps only the ones
+ * grounded in the index: every file or symbol a suggestion names in
+ * backticks must be one of the indexed ones the answer was given.
+ */
+
+/** Suggestions shown after an answer */
+export const FOLLOW_UP_COUNT = 3;
+
+/** Subjects listed in the prompt, so a large context keeps it short */
+const MAX_SUBJECTS = 40;
+
+/** Answer text included in the prompt */
+const MAX_ANSWER_CHARS = 6000;
+
+/**
+ * Indexed code a follow-up may ask about
+ */
+export interface FollowUpSubject {
+  file: string;
+  symbol?: string;
+}
+
+/**
+ * Build the follow-up prompt for a question, its answer, and the indexed
+ * code the answer was given
+ */
+export function buildFollowUpPrompt(
+  question: string,
+  answer: string,
+  subjects: FollowUpSubject[],
+  count: number = FOLLOW_UP_COUNT
+): string {
+  const listed = uniqueSubjects(subjects).slice(0, MAX_SUBJECTS);
+  const trimmed = answer.length > MAX_ANSWER_CHARS ? `${answer.slice(0, MAX_ANSWER_CHARS)}\n...` : answer;
+
+  let prompt = `A developer exploring a codebase asked:\n\n${question}\n\n`;
+  prompt += `## Answer they received\n\n${trimmed}\n\n`;
+  prompt += `## Indexed code\n\n`;
+  for (const subject of listed) {
+    prompt += subject.symbol ? `- \`${subject.symbol}\` in \`${subject.file}\`\n` : `- \`${subject.file}\`\n`;
+  }
+  prompt += `\n## Requirements\n\n`;
+  prompt += `- Suggest ${count} short follow-up questions the developer is likely to ask next.\n`;
+  prompt += `- Each question must be answerable from the indexed code above and name at least one of its files or symbols in backticks.\n`;
+  prompt += `- Never name in backticks a file or symbol that is not listed above.\n`;
+  prompt += `- Do not repeat the question that was asked.\n\n`;
+  prompt += `Respond with ONLY a JSON array of strings, e.g. ["How does \`${listed[0]?.symbol ?? listed[0]?.file ?? 'name'}\` handle errors?"].`;
+
+  return prompt;
+}
+
+/**
+ * Suggestions from the model's response that name only known subjects,
+ * at most `count` of them. A malformed response gives no suggestions.
+ */
+export function parseFollowUps(
+  response: string,
+  subjects: FollowUpSubject[],
+  count: number = FOLLOW_UP_COUNT
+): string[] {
+  const start = response.indexOf('[');
+  const end = response.lastIndexOf(']');
+  if (start < 0 || end <= start) {
+    return [];
+  }
+
+  let parsed: unknown;
+  try {
+    parsed = JSON.parse(response.slice(start, end + 1));
+  } catch {
+    return [];
+  }
+  if (!Array.isArray(parsed)) {
+    return [];
+  }
+
+  const known = new Set(subjects.flatMap(subject => subject.symbol ? [subject.file, subject.symbol] : [subject.file]));
+  const suggestions: string[] = [];
+  for (const item of parsed) {
+    if (typeof item !== 'string') continue;
+    const question = item.trim().replace(/\s+/g, ' ');
+    const names = [...question.matchAll(/`([^`]+)`/g)].map(match => normalizeName(match[1]));
+    if (!question || names.length === 0 || !names.every(name => known.has(name))) continue;
+    if (suggestions.some(existing => existing.toLowerCase() === question.toLowerCase())) continue;
+    suggestions.push(question);
+    if (suggestions.length === count) break;
+  }
+  return suggestions;
+}
+
+/**
+ * A backticked name as it appears among the subjects: `file.ts:12-20` is
+ * the file, and `foo()` the symbol foo
+ */
+function normalizeName(name: string): string {
+  return name.trim().replace(/:\d+(?:-\d+)?$/, '').replace(/\(\)$/, '');
+}
+
+function uniqueSubjects(subjects: FollowUpSubject[]): FollowUpSubject[] {
+  const seen = new Set<string>();
+  return subjects.filter(subject => {
+    const key = `${subject.file}\0${subject.symbol ?? ''}`;
+    if (seen.has(key)) return false;
+    seen.add(key);
+    return true;
+  });
+}
@@ -0,0 +1,336 @@
+/**
+ * Google Gemini Client
+ * Chat through the Gemini API's generateContent endpoint, plus text-embedding-004
+ *
+ * Gemini names the assistant role `model`, takes the system prompt as a
+ * separate `systemInstruction`, and may refuse a prompt or stop a response
+ * for safety reasons - those come back as a successful HTTP response with
+ * no text, so they are turned into a GeminiSafetyError here.
+ */
+
+import { getMaxRetryAttempts, retryWithBackoff, toApiError } from '@cv-git/shared';
+import { AIClient, AIMessage, AIStreamHandler } from './types.js';
+import { recordCompletionUsage } from '../usage/index.js';
+
+export const GEMINI_API_URL = 'https://generativelanguage.googleapis.com/v1beta';
+export const DEFAULT_GEMINI_MODEL = 'gemini-1.5-pro';
+export const DEFAULT_GEMINI_EMBEDDING_MODEL = 'text-embedding-004';
+
+/** Finish reasons that mean the response was withheld rather than completed */
+const BLOCKED_FINISH_REASONS = new Set(['SAFETY', 'RECITATION', 'BLOCKLIST', 'PROHIBITED_CONTENT', 'SPII']);
+
+/** batchEmbedContents accepts at most this many requests */
+const MAX_EMBED_BATCH = 100;
+
+export interface GeminiOptions {
+  apiKey: string;
+  model?: string;
+  maxTokens?: number;
+  temperature?: number;
+  /** API base URL (default: the public v1beta endpoint) */
+  baseUrl?: string;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+}
+
+export interface GeminiContent {
+  role: 'user' | 'model';
+  parts: Array<{ text: string }>;
+}
+
+interface GeminiResponse {
+  candidates?: Array<{
+    content?: { parts?: Array<{ text?: string }> };
+    finishReason?: string;
+    safetyRatings?: Array<{ category: string; probability: string; blocked?: boolean }>;
+  }>;
+  promptFeedback?: {
+    blockReason?: string;
+    safetyRatings?: Array<{ category: string; probability: string; blocked?: boolean }>;
+  };
+  usageMetadata?: { promptTokenCount?: number; candidatesTokenCount?: number };
+}
+
+/**
+ * Gemini refused the prompt or withheld the response
+ */
+export class GeminiSafetyError extends Error {
+  constructor(
+    /** 'prompt' when the request was refused, 'response' when the answer was stopped */
+    public readonly stage: 'prompt' | 'response',
+    public readonly reason: string,
+    public readonly categories: string[]
+  ) {
+    const what = stage === 'prompt' ? 'Gemini blocked the prompt' : 'Gemini stopped the response';
+    const detail = categories.length > 0 ? ` (${categories.join(', ')})` : '';
+    super(`${what}: ${reason}${detail}. Try rephrasing the request or narrowing the context.`);
+    this.name = 'GeminiSafetyError';
+  }
+}
+
+/**
+ * Map our messages to Gemini's contents and system instruction.
+ * System messages are folded into the system instruction, `assistant`
+ * becomes `model`, and consecutive messages with the same role are merged
+ * because Gemini expects the roles to alternate.
+ */
+export function toGeminiContents(
+  messages: AIMessage[],
+  systemPrompt?: string
+): { contents: GeminiContent[]; systemInstruction?: { parts: Array<{ text: string }> } } {
+  const system: string[] = systemPrompt ? [systemPrompt] : [];
+  const contents: GeminiContent[] = [];
+
+  for (const msg of messages) {
+    if (msg.role === 'system') {
+      system.push(msg.content);
+      continue;
+    }
+
+    const role = msg.role === 'assistant' ? 'model' : 'user';
+    const last = contents[contents.length - 1];
+    if (last && last.role === role) {
+      last.parts.push({ text: msg.content });
+    } else {
+      contents.push({ role, parts: [{ text: msg.content }] });
+    }
+  }
+
+  return {
+    contents,
+    systemInstruction: system.length > 0 ? { parts: [{ text: system.join('\n\n') }] } : undefined
+  };
+}
+
+/**
+ * Text of a generateContent response (or one streamed chunk of it).
+ * Throws GeminiSafetyError for blocked prompts and withheld responses.
+ */
+export function extractGeminiText(response: GeminiResponse): string {
+  const feedback = response.promptFeedback;
+  if (feedback?.blockReason) {
+    throw new GeminiSafetyError('prompt', feedback.blockReason, blockedCategories(feedback.safetyRatings));
+  }
+
+  const candidate = response.candidates?.[0];
+  const text = (candidate?.content?.parts || []).map(p => p.text || '').join('');
+
+  if (candidate?.finishReason && BLOCKED_FINISH_REASONS.has(candidate.finishReason)) {
+    throw new GeminiSafetyError('response', candidate.finishReason, blockedCategories(candidate.safetyRatings));
+  }
+
+  return text;
+}
+
+function blockedCategories(ratings?: Array<{ category: string; probability: string; blocked?: boolean }>): string[] {
+  return (ratings || [])
+    .filter(r => r.blocked || r.probability === 'HIGH' || r.probability === 'MEDIUM')
+    .map(r => r.category.replace(/^HARM_CATEGORY_/, '').toLowerCase().replace(/_/g, ' '));
+}
+
+export class GeminiClient implements AIClient {
+  private apiKey: string;
+  private model: string;
+  private baseUrl: string;
+  private maxTokens: number;
+  private temperature: number;
+  private maxAttempts: number;
+
+  constructor(options: GeminiOptions) {
+    this.apiKey = options.apiKey;
+    this.model = options.model || DEFAULT_GEMINI_MODEL;
+    this.baseUrl = (options.baseUrl || GEMINI_API_URL).replace(/\/+$/, '');
+    this.maxTokens = options.maxTokens || 8192;
+    this.temperature = options.temperature ?? 0.7;
+    this.maxAttempts = options.maxRetryAttempts ?? getMaxRetryAttempts();
+  }
+
+  /**
+   * Get the provider name
+   */
+  getProvider(): string {
+    return 'gemini';
+  }
+
+  /**
+   * Get the current model
+   */
+  getModel(): string {
+    return this.model;
+  }
+
+  /**
+   * Set a different model
+   */
+  setModel(model: string): void {
+    this.model = model;
+  }
+
+  /**
+   * Check if the client is configured
+   */
+  async isReady(): Promise<boolean> {
+    return !!this.apiKey;
+  }
+
+  /**
+   * Chat completion (non-streaming)
+   */
+  async chat(messages: AIMessage[], systemPrompt?: string, signal?: AbortSignal): Promise<string> {
+    const response = await this.post(`models/${this.model}:generateContent`, this.buildRequest(messages, systemPrompt), signal);
+    const data = await response.json() as GeminiResponse;
+    const text = extractGeminiText(data);
+    this.recordUsage(messages, systemPrompt, text, data.usageMetadata);
+    return text;
+  }
+
+  /**
+   * Chat completion with streaming (server-sent events)
+   */
+  async chatStream(
+    messages: AIMessage[],
+    systemPrompt?: string,
+    handler?: AIStreamHandler
+  ): Promise<string> {
+    let fullText = '';
+    let usage: GeminiResponse['usageMetadata'];
+
+    try {
+      const response = await this.post(
+        `models/${this.model}:streamGenerateContent?alt=sse`,
+        this.buildRequest(messages, systemPrompt),
+        handler?.signal
+      );
+
+      const reader = response.body?.getReader();
+      if (!reader) {
+        throw new Error('No response body');
+      }
+
+      const decoder = new TextDecoder();
+      let buffer = '';
+
+      while (true) {
+        const { done, value } = await reader.read();
+        if (done) break;
+
+        buffer += decoder.decode(value, { stream: true });
+        const lines = buffer.split('\n');
+        buffer = lines.pop() || '';
+
+        for (const line of lines) {
+          if (!line.startsWith('data: ')) continue;
+          let chunk: GeminiResponse;
+          try {
+            chunk = JSON.parse(line.slice(6));
+          } catch {
+            continue; // Skip partial or non-JSON lines
+          }
+          // Every chunk carries the running totals; the last one has the final counts
+          usage = chunk.usageMetadata ?? usage;
+          const token = extractGeminiText(chunk);
+          if (token) {
+            fullText += token;
+            handler?.onToken?.(token);
+          }
+        }
+      }
+
+      this.recordUsage(messages, systemPrompt, fullText, usage);
+      handler?.onComplete?.(fullText);
+      return fullText;
+
+    } catch (error) {
+      handler?.onError?.(error as Error);
+      throw error;
+    }
+  }
+
+  /**
+   * Simple completion (single prompt)
+   */
+  async complete(prompt: string, handler?: AIStreamHandler): Promise<string> {
+    if (!handler) {
+      return this.chat([{ role: 'user', content: prompt }]);
+    }
+    return this.chatStream([{ role: 'user', content: prompt }], undefined, handler);
+  }
+
+  /**
+   * Embed texts with a Gemini embedding model (default: text-embedding-004),
+   * optionally truncated to fewer dimensions
+   */
+  async embed(
+    texts: string[],
+    model: string = DEFAULT_GEMINI_EMBEDDING_MODEL,
+    signal?: AbortSignal,
+    outputDimensionality?: number
+  ): Promise<number[][]> {
+    const modelPath = model.startsWith('models/') ? model : `models/${model}`;
+    const embeddings: number[][] = [];
+
+    for (let i = 0; i < texts.length; i += MAX_EMBED_BATCH) {
+      const batch = texts.slice(i, i + MAX_EMBED_BATCH);
+      const response = await this.post(`${modelPath}:batchEmbedContents`, {
+        requests: batch.map(text => ({
+          model: modelPath,
+          content: { parts: [{ text }] },
+          ...(outputDimensionality ? { outputDimensionality } : {})
+        }))
+      }, signal);
+      const data = await response.json() as { embeddings?: Array<{ values: number[] }> };
+      embeddings.push(...(data.embeddings || []).map(e => e.values));
+    }
+
+    return embeddings;
+  }
+
+  private recordUsage(
+    messages: AIMessage[],
+    systemPrompt: string | undefined,
+    response: string,
+    usage?: GeminiResponse['usageMetadata']
+  ): void {
+    const prompt = [systemPrompt ?? '', ...messages.map(m => m.content)].join('\n');
+    recordCompletionUsage('gemini', this.model, prompt, response, {
+      inputTokens: usage?.promptTokenCount,
+      outputTokens: usage?.candidatesTokenCount
+    });
+  }
+
+  private buildRequest(messages: AIMessage[], systemPrompt?: string): Record<string, unknown> {
+    return {
+      ...toGeminiContents(messages, systemPrompt),
+      generationConfig: {
+        maxOutputTokens: this.maxTokens,
+        temperature: this.temperature
+      }
+    };
+  }
+
+  private async post(endpoint: string, body: unknown, signal?: AbortSignal): Promise<Response> {
+    return retryWithBackoff(async () => {
+      const response = await fetch(`${this.baseUrl}/${endpoint}`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'x-goog-api-key': this.apiKey
+        },
+        body: JSON.stringify(body),
+        signal
+      });
+
+      if (!response.ok) {
+        throw await toApiError('Gemini', response, body => body.error?.message);
+      }
+      return response;
+    }, { maxAttempts: this.maxAttempts, signal });
+  }
+}
+
+/**
+ * Create a Gemini chat client
+ */
+export function createGeminiClient(options: GeminiOptions): GeminiClient {
+  return new GeminiClient(options);
+}
@@ -0,0 +1,350 @@
+/**
+ * GitHub Review
+ * Posts `cv review --github` findings on a pull request: an inline review
+ * comment on the changed line of each finding, and a summary comment on the
+ * conversation.
+ *
+ * Every comment carries a hidden marker with the finding's fingerprint (see
+ * review-baseline.ts), so a later run on the same pull request edits its own
+ * earlier comments instead of adding copies, and marks those whose finding
+ * is no longer reported as resolved. Comments stay on the line they were
+ * first posted on; GitHub does not move a review comment once posted.
+ */
+
+import { promises as fs } from 'fs';
+import { ReviewFinding, ReviewResult } from '@cv-git/shared';
+import { REVIEW_SEVERITIES } from './review-findings.js';
+import { findingFingerprint } from './review-baseline.js';
+
+export const DEFAULT_GITHUB_API_URL = 'https://api.github.com';
+
+/** Hidden marker of cv's comments, naming the finding's fingerprint or the summary */
+const MARKER_PATTERN = /<!-- cv-review:([\w-]+) -->/;
+const SUMMARY_ID = 'summary';
+
+/** Body of a cv comment whose finding a later review no longer reports */
+const RESOLVED_NOTE = '✅ No longer reported by `cv review`.';
+
+export interface GitHubPullRequestRef {
+  owner: string;
+  repo: string;
+  number: number;
+}
+
+/**
+ * A comment already on the pull request
+ */
+export interface GitHubComment {
+  id: number;
+  body: string;
+}
+
+export interface GitHubInlineComment {
+  path: string;
+  /** Lines below the file's first @@ header in the pull request diff */
+  position: number;
+  /** Line in the new file, for display */
+  line: number;
+  body: string;
+}
+
+/**
+ * What a review run posts and edits
+ */
+export interface GitHubReviewPlan {
+  /** Inline comments for findings not yet on the pull request, posted as one review */
+  create: GitHubInlineComment[];
+  /** cv's earlier inline comments to edit: findings reported again with new text, and resolved ones */
+  update: Array<{ id: number; body: string; resolved: boolean }>;
+  /** The summary comment, edited in place when an earlier run posted one */
+  summary: { body: string; id?: number };
+  /** Findings on lines without a diff position; listed in the summary only */
+  unplaced: ReviewFinding[];
+}
+
+/**
+ * The pull request to comment on: --repo/--pr, else the GitHub Actions
+ * environment (GITHUB_REPOSITORY, and the pull request of the event in
+ * GITHUB_EVENT_PATH or a refs/pull/<n>/merge GITHUB_REF)
+ */
+export async function resolveGitHubPullRequest(
+  options: { repo?: string; pr?: string | number },
+  env: NodeJS.ProcessEnv = process.env
+): Promise<GitHubPullRequestRef> {
+  const repo = options.repo ?? env.GITHUB_REPOSITORY;
+  const match = repo?.match(/^([\w.-]+)\/([\w.-]+)$/);
+  if (!match) {
+    throw new Error(repo
+      ? `Invalid repository "${repo}" (expected owner/name)`
+      : 'No repository: pass --repo owner/name or set GITHUB_REPOSITORY');
+  }
+
+  let number = options.pr !== undefined ? Number(options.pr) : undefined;
+  if (number === undefined && env.GITHUB_EVENT_PATH) {
+    const event = JSON.parse(await fs.readFile(env.GITHUB_EVENT_PATH, 'utf-8').catch(() => '{}'));
+    number = event.pull_request?.number ?? (event.issue?.pull_request ? event.issue.number : undefined);
+  }
+  if (number === undefined) {
+    const ref = env.GITHUB_REF?.match(/^refs\/pull\/(\d+)\//);
+    number = ref ? Number(ref[1]) : undefined;
+  }
+  if (number === undefined || !Number.isInteger(number) || number <= 0) {
+    throw new Error(options.pr !== undefined
+      ? `Invalid pull request number: ${options.pr}`
+      : 'No pull request: pass --pr <number> or run on a pull_request event');
+  }
+
+  return { owner: match[1], repo: match[2], number };
+}
+
+/**
+ * Diff position of each new-file line of each file in a unified diff.
+ * GitHub counts positions from the line below a file's first @@ header;
+ * later hunk headers count too, and the count restarts at the next file.
+ */
+export function mapDiffPositions(diff: string): Map<string, Map<number, number>> {
+  const files = new Map<string, Map<number, number>>();
+  let current: Map<number, number> | null = null;
+  let position = -1;
+  let newLine = 0;
+
+  for (const line of diff.split('\n')) {
+    if (line.startsWith('diff --git ')) {
+      current = null;
+      position = -1;
+    } else if (position < 0 && line.startsWith('+++ ')) {
+      const file = line.slice(4).split('\t')[0].trim();
+      current = file === '/dev/null' ? null : new Map();
+      if (current) files.set(file.replace(/^b\//, ''), current);
+    } else if (line.startsWith('@@') && current) {
+      position = position < 0 ? 0 : position + 1;
+      newLine = parseInt(line.match(/\+(\d+)/)?.[1] ?? '1', 10);
+    } else if (position >= 0 && current && line !== '') {
+      position++;
+      if (line.startsWith('+') || line.startsWith(' ')) {
+        current.set(newLine++, position);
+      }
+    }
+  }
+
+  return files;
+}
+
+/**
+ * Work out which comments to post and which of cv's earlier comments to
+ * edit. `result` holds findings already moved onto the changed lines (as
+ * `cv review --diff` reports them); `diff` is the pull request's diff.
+ */
+export function planGitHubReview(
+  result: ReviewResult,
+  diff: string,
+  existing: { inline: GitHubComment[]; conversation: GitHubComment[] }
+): GitHubReviewPlan {
+  const positions = mapDiffPositions(diff);
+  const earlier = new Map<string, GitHubComment>();
+  for (const comment of existing.inline) {
+    const id = commentMarker(comment.body);
+    if (id && !earlier.has(id)) earlier.set(id, comment);
+  }
+
+  const plan: GitHubReviewPlan = { create: [], update: [], summary: { body: '' }, unplaced: [] };
+  const reported = new Set<string>();
+  const seen = new Map<string, number>();
+
+  for (const finding of result.findings) {
+    // The same finding twice in a file gets a second marker
+    const fingerprint = findingFingerprint(finding);
+    const count = (seen.get(fingerprint) || 0) + 1;
+    seen.set(fingerprint, count);
+    const id = count === 1 ? fingerprint : `${fingerprint}-${count}`;
+
+    const body = formatFindingComment(id, finding);
+    const comment = earlier.get(id);
+    if (comment) {
+      reported.add(id);
+      if (comment.body !== body) plan.update.push({ id: comment.id, body, resolved: false });
+      continue;
+    }
+
+    const position = positions.get(finding.file)?.get(finding.startLine);
+    if (position === undefined) {
+      plan.unplaced.push(finding);
+      continue;
+    }
+    reported.add(id);
+    plan.create.push({ path: finding.file, position, line: finding.startLine, body });
+  }
+
+  for (const [id, comment] of earlier) {
+    const body = `${marker(id)}\n${RESOLVED_NOTE}`;
+    if (!reported.has(id) && comment.body !== body) {
+      plan.update.push({ id: comment.id, body, resolved: true });
+    }
+  }
+
+  const summary = existing.conversation.find(comment => commentMarker(comment.body) === SUMMARY_ID);
+  plan.summary = { body: formatSummaryComment(result, plan.unplaced), id: summary?.id };
+  return plan;
+}
+
+/**
+ * GitHub REST calls made by `cv review --github`
+ */
+export class GitHubReviewClient {
+  private apiUrl: string;
+
+  constructor(
+    private pr: GitHubPullRequestRef,
+    private token?: string,
+    apiUrl: string = process.env.GITHUB_API_URL || DEFAULT_GITHUB_API_URL
+  ) {
+    this.apiUrl = apiUrl.replace(/\/+$/, '');
+  }
+
+  /**
+   * The pull request's diff and the commit it was taken at
+   */
+  async getPullRequestDiff(): Promise<{ diff: string; headSha: string }> {
+    const pull = await this.request('GET', this.pullPath()) as { head?: { sha?: string } };
+    const diff = await this.request('GET', this.pullPath(), undefined, 'application/vnd.github.v3.diff') as string;
+    return { diff, headSha: pull.head?.sha ?? '' };
+  }
+
+  /** Inline review comments on the pull request */
+  listReviewComments(): Promise<GitHubComment[]> {
+    return this.listComments(`${this.pullPath()}/comments`);
+  }
+
+  /** Comments on the pull request's conversation */
+  listConversationComments(): Promise<GitHubComment[]> {
+    return this.listComments(`${this.repoPath()}/issues/${this.pr.number}/comments`);
+  }
+
+  /**
+   * Post inline comments as one review (event COMMENT: neither approves nor requests changes)
+   */
+  async createReview(headSha: string, comments: GitHubInlineComment[]): Promise<void> {
+    await this.request('POST', `${this.pullPath()}/reviews`, {
+      commit_id: headSha || undefined,
+      event: 'COMMENT',
+      comments: comments.map(({ path, position, body }) => ({ path, position, body }))
+    });
+  }
+
+  async updateReviewComment(id: number, body: string): Promise<void> {
+    await this.request('PATCH', `${this.repoPath()}/pulls/comments/${id}`, { body });
+  }
+
+  async createConversationComment(body: string): Promise<void> {
+    await this.request('POST', `${this.repoPath()}/issues/${this.pr.number}/comments`, { body });
+  }
+
+  async updateConversationComment(id: number, body: string): Promise<void> {
+    await this.request('PATCH', `${this.repoPath()}/issues/comments/${id}`, { body });
+  }
+
+  private repoPath(): string {
+    return `/repos/${this.pr.owner}/${this.pr.repo}`;
+  }
+
+  private pullPath(): string {
+    return `${this.repoPath()}/pulls/${this.pr.number}`;
+  }
+
+  private async listComments(path: string): Promise<GitHubComment[]> {
+    const comments: GitHubComment[] = [];
+    for (let page = 1; ; page++) {
+      const batch = await this.request('GET', `${path}?per_page=100&page=${page}`) as Array<{ id: number; body?: string }>;
+      comments.push(...batch.map(comment => ({ id: comment.id, body: comment.body ?? '' })));
+      if (batch.length < 100) return comments;
+    }
+  }
+
+  private async request(method: string, path: string, body?: unknown, accept: string = 'application/vnd.github+json'): Promise<unknown> {
+    const response = await fetch(`${this.apiUrl}${path}`, {
+      method,
+      headers: {
+        Accept: accept,
+        'X-GitHub-Api-Version': '2022-11-28',
+        ...(this.token ? { Authorization: `Bearer ${this.token}` } : {}),
+        ...(body ? { 'Content-Type': 'application/json' } : {})
+      },
+      body: body ? JSON.stringify(body) : undefined
+    });
+
+    if (!response.ok) {
+      const detail = await response.json().then((data: any) => data?.message, () => undefined);
+      const hint = response.status === 401 || response.status === 403 || response.status === 404
+        ? ' (check that GITHUB_TOKEN can read and write pull requests of this repository)'
+        : '';
+      throw new Error(`GitHub ${method} ${path.split('?')[0]} returned HTTP ${response.status}${detail ? `: ${detail}` : ''}${hint}`);
+    }
+    return accept.endsWith('diff') ? response.text() : response.json();
+  }
+}
+
+/**
+ * Post a plan: new inline comments as one review, then the edits, then the
+ * summary. Returns how many comments were posted and edited.
+ */
+export async function postGitHubReview(
+  client: GitHubReviewClient,
+  plan: GitHubReviewPlan,
+  headSha: string
+): Promise<{ created: number; updated: number }> {
+  if (plan.create.length > 0) {
+    await client.createReview(headSha, plan.create);
+  }
+  for (const update of plan.update) {
+    await client.updateReviewComment(update.id, update.body);
+  }
+  if (plan.summary.id !== undefined) {
+    await client.updateConversationComment(plan.summary.id, plan.summary.body);
+  } else {
+    await client.createConversationComment(plan.summary.body);
+  }
+  return {
+    created: plan.create.length + (plan.summary.id === undefined ? 1 : 0),
+    updated: plan.update.length + (plan.summary.id === undefined ? 0 : 1)
+  };
+}
+
+function marker(id: string): string {
+  return `<!-- cv-review:${id} -->`;
+}
+
+function commentMarker(body: string): string | null {
+  return MARKER_PATTERN.exec(body)?.[1] ?? null;
+}
+
+function formatFindingComment(id: string, finding: ReviewFinding): string {
+  const lines = [marker(id), `**${finding.severity.toUpperCase()}** · ${finding.category}`, '', finding.message];
+  if (finding.suggestion) {
+    lines.push('', `**Suggestion:** ${finding.suggestion}`);
+  }
+  return lines.join('\n');
+}
+
+function formatSummaryComment(result: ReviewResult, unplaced: ReviewFinding[]): string {
+  const lines = [marker(SUMMARY_ID), '### cv review', ''];
+  if (result.summary.overview) {
+    lines.push(result.summary.overview, '');
+  }
+
+  if (result.summary.total === 0) {
+    lines.push('No issues found.');
+  } else {
+    const counts = [...REVIEW_SEVERITIES].reverse()
+      .filter(severity => result.summary.bySeverity[severity] > 0)
+      .map(severity => `${result.summary.bySeverity[severity]} ${severity}`);
+    lines.push(`**${result.summary.total} finding(s)** (${counts.join(', ')})`);
+  }
+
+  if (unplaced.length > 0) {
+    lines.push('', 'Not on a line of the diff:');
+    for (const finding of unplaced) {
+      lines.push(`- \`${finding.file}:${finding.startLine}\` **${finding.severity}** ${finding.message}`);
+    }
+  }
+  return lines.join('\n');
+}
@@ -0,0 +1,125 @@
+/**
+ * HuggingFace Embeddings Client
+ * Embeds text with open models (e.g. BAAI/bge-large-en-v1.5) through the
+ * HuggingFace Inference API, or a self-hosted Text Embeddings Inference (TEI)
+ * server when a base URL is set
+ *
+ * The feature-extraction pipeline returns one vector per input for
+ * sentence-embedding models but one vector per token for plain encoders, so
+ * token-level output is mean-pooled. Vectors are L2-normalized either way.
+ */
+
+import { getMaxRetryAttempts, retryWithBackoff, toApiError } from '@cv-git/shared';
+
+export const HUGGINGFACE_API_URL = 'https://router.huggingface.co/hf-inference/models';
+export const DEFAULT_HUGGINGFACE_EMBEDDING_MODEL = 'BAAI/bge-large-en-v1.5';
+
+/** Texts sent per request; the hosted API and TEI's default both reject much larger batches */
+const MAX_EMBED_BATCH = 32;
+
+export interface HuggingFaceOptions {
+  /** Access token (hf_...); optional for a self-hosted TEI server */
+  apiKey?: string;
+  /** TEI server URL; when set, texts are posted to `<baseUrl>/embed` and the model is whatever the server serves */
+  baseUrl?: string;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+}
+
+/**
+ * Turn a feature-extraction response into one vector per input: a single
+ * vector (one input), a vector per input (pooled), or a vector per token
+ * per input (mean-pooled here)
+ */
+export function toEmbeddings(data: unknown, count: number): number[][] {
+  if (!Array.isArray(data) || data.length === 0) {
+    throw new Error('HuggingFace API returned no embeddings');
+  }
+
+  let vectors: number[][];
+  if (typeof data[0] === 'number') {
+    vectors = [data as number[]];
+  } else if (Array.isArray(data[0]) && typeof data[0][0] === 'number') {
+    vectors = data as number[][];
+  } else if (Array.isArray(data[0]) && Array.isArray(data[0][0])) {
+    vectors = (data as number[][][]).map(meanPool);
+  } else {
+    throw new Error('HuggingFace API returned an unexpected response shape');
+  }
+
+  if (vectors.length !== count) {
+    throw new Error(`HuggingFace API returned ${vectors.length} embeddings for ${count} inputs`);
+  }
+  return vectors.map(normalize);
+}
+
+function meanPool(tokens: number[][]): number[] {
+  const pooled = new Array<number>(tokens[0]?.length ?? 0).fill(0);
+  for (const token of tokens) {
+    for (let i = 0; i < pooled.length; i++) {
+      pooled[i] += token[i];
+    }
+  }
+  return pooled.map(value => value / tokens.length);
+}
+
+function normalize(vector: number[]): number[] {
+  const norm = Math.sqrt(vector.reduce((sum, value) => sum + value * value, 0));
+  return norm > 0 ? vector.map(value => value / norm) : vector;
+}
+
+export class HuggingFaceClient {
+  private apiKey?: string;
+  private baseUrl?: string;
+  private maxAttempts: number;
+
+  constructor(options: HuggingFaceOptions) {
+    this.apiKey = options.apiKey;
+    this.baseUrl = options.baseUrl?.replace(/\/+$/, '');
+    this.maxAttempts = options.maxRetryAttempts ?? getMaxRetryAttempts();
+  }
+
+  /**
+   * Embed texts with a model (ignored by a TEI server, which serves one model)
+   */
+  async embed(texts: string[], model: string = DEFAULT_HUGGINGFACE_EMBEDDING_MODEL, signal?: AbortSignal): Promise<number[][]> {
+    const embeddings: number[][] = [];
+
+    for (let i = 0; i < texts.length; i += MAX_EMBED_BATCH) {
+      const batch = texts.slice(i, i + MAX_EMBED_BATCH);
+      const response = await this.post(this.endpoint(model), { inputs: batch, normalize: true, truncate: true }, signal);
+      embeddings.push(...toEmbeddings(await response.json(), batch.length));
+    }
+
+    return embeddings;
+  }
+
+  private endpoint(model: string): string {
+    if (this.baseUrl) {
+      return `${this.baseUrl}/embed`;
+    }
+    return `${HUGGINGFACE_API_URL}/${model}/pipeline/feature-extraction`;
+  }
+
+  private async post(url: string, body: unknown, signal?: AbortSignal): Promise<Response> {
+    return retryWithBackoff(async () => {
+      const headers: Record<string, string> = { 'Content-Type': 'application/json' };
+      if (this.apiKey) {
+        headers['Authorization'] = `Bearer ${this.apiKey}`;
+      }
+
+      const response = await fetch(url, {
+        method: 'POST',
+        headers,
+        body: JSON.stringify(body),
+        signal
+      });
+
+      if (!response.ok) {
+        // 503 while a model loads is retried like a rate limit
+        throw await toApiError('HuggingFace', response, body => body.error);
+      }
+      return response;
+    }, { maxAttempts: this.maxAttempts, signal });
+  }
+}
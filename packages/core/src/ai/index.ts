@@ -3,7 +3,6 @@
  * Manages Claude API interactions and context assembly
  */
 
-import Anthropic from '@anthropic-ai/sdk';
 
 // Re-export CommitAnalyzer
 export {
@@ -15,8 +14,60 @@ export {
   SymbolChange,
   BreakingChange,
   CommitAnalyzerOptions,
-  CommitAIProvider
+  CommitAIProvider,
+  truncateDiff
 } from './commit-analyzer.js';
+export * from './commit-convention.js';
+export * from './review-findings.js';
+export * from './review-baseline.js';
+export * from './github-review.js';
+export * from './test-generation.js';
+export * from './refactor.js';
+export * from './review-fixes.js';
+export * from './edit-plan.js';
+export * from './diff-explain.js';
+export * from './diff-review.js';
+export * from './models.js';
+export * from './line-history.js';
+export * from './doc-comments.js';
+export * from './repo-overview.js';
+export * from './piped-code.js';
+export * from './system-prompt.js';
+export * from './named-prompts.js';
+export * from './inline-citations.js';
+export * from './response-cache.js';
+export * from './follow-ups.js';
+export * from './change-summary.js';
+export * from './provider-fallback.js';
+import { parseReviewResponse, applyReviewRules, REVIEW_CATEGORIES } from './review-findings.js';
+import { buildPipedCodeContext } from './piped-code.js';
+import { EXPLAIN_PROMPT_CHUNKS, buildCitationInstruction, explainSourceChunks } from './inline-citations.js';
+import { SystemPromptOptions, applySystemPrompt } from './system-prompt.js';
+import { parseDiffHunks, formatNumberedDiff, mapFindingsToDiff } from './diff-review.js';
+import { TestGenerationContext, buildTestGenerationPrompt } from './test-generation.js';
+import {
+  RefactorTarget,
+  buildRefactorPrompt,
+  extractRefactoredCode,
+  getRefactorSource,
+  restoreRedactedSecrets
+} from './refactor.js';
+import { WhyContext, buildWhyPrompt } from './line-history.js';
+import { buildReviewFixPrompt, getFixSource, parseReviewFix } from './review-fixes.js';
+import { DocTarget, DocComment, buildDocCommentPrompt, parseDocComments } from './doc-comments.js';
+import { OverviewDepth, OverviewSample, buildRepoOverviewPrompt } from './repo-overview.js';
+import { FollowUpSubject, buildFollowUpPrompt, parseFollowUps } from './follow-ups.js';
+import { ChangeSummary, ChangeSummaryInput, buildChangeSummaryPrompt, parseChangeSummary } from './change-summary.js';
+import { ProviderCandidate, ProviderServed, tryProviders } from './provider-fallback.js';
+import {
+  ComplexChange,
+  DiffExplanation,
+  DEFAULT_DIFF_PART_CHARS,
+  splitDiff,
+  buildDiffPartPrompt,
+  buildDiffExplanationPrompt,
+  parseDiffExplanation
+} from './diff-explain.js';
 import {
   Context,
   Plan,
@@ -24,37 +75,118 @@ import {
   Diff,
   SymbolNode,
   FileNode,
+  SymbolKind,
   VectorSearchResult,
   CodeChunkPayload,
-  ChatMessage
+  ChatMessage,
+  ReviewResult,
+  ReviewFinding,
+  ReviewRules,
+  withRequestTimeout,
+  DEFAULT_CHAT_TIMEOUT_MS
 } from '@cv-git/shared';
-import { VectorManager } from '../vector/index.js';
+import { VectorManager, applyMinScore, TestChunkFilter, DEFAULT_CONTEXT_MIN_SCORE, DEFAULT_CONTEXT_TOP_K } from '../vector/index.js';
+import { isIndexMismatchError } from '../vector/index-metadata.js';
 import { GraphManager } from '../graph/index.js';
 import { GitManager } from '../git/index.js';
+import { buildLanguageInstruction } from '../sync/languages.js';
 import { PRDClient, AIContext as PRDContext } from '@cv-git/prd-client';
+import { AIClient } from './types.js';
+import { AzureOpenAIDeployment, createAzureOpenAIClient } from './azure.js';
+import { DEFAULT_GEMINI_MODEL, createGeminiClient } from './gemini.js';
+import { createAnthropicClient } from './anthropic.js';
+import { createOpenAICompatibleClient } from './openai-compatible.js';
+import { SecretRedactor } from '../security/redact.js';
+import { gatherFileChunks, mergeFileChunks } from '../context/file-context.js';
+import { fitChunksToBudget, getContextBudget } from '../context/token-budget.js';
+import { fitHistoryToBudget, formatCommitHistory, HISTORY_BUDGET_SHARE } from '../context/commit-history.js';
+import { deduplicateChunks } from '../context/dedupe.js';
+import { expandWithDefinitions } from '../context/expand.js';
+import { DEFAULT_RERANK_CANDIDATES, Reranker, rerankChunks } from '../context/rerank.js';
+import { readIndexedCodeChunks } from '../vector/symbol-lookup.js';
+import { getIndexDir } from '../vector/index-store.js';
 
 export interface AIManagerOptions {
-  provider: 'anthropic';
+  provider: 'anthropic' | 'azure' | 'gemini' | 'openai-compatible';
   model: string;
   apiKey: string;
   maxTokens?: number;
   temperature?: number;
   prdUrl?: string;
   prdApiKey?: string;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+  /** Azure OpenAI deployment used when provider is 'azure' (apiKey is used as the api-key) */
+  azure?: Omit<AzureOpenAIDeployment, 'apiKey'>;
+  /** Gemini API base URL when provider is 'gemini' (apiKey is the Gemini API key) */
+  geminiUrl?: string;
+  /** Gateway base URL when provider is 'openai-compatible' (apiKey may be empty; model is sent as given) */
+  openaiCompatibleUrl?: string;
+  /** Mark long prompts (retrieved code) for Anthropic prompt caching (config ai.promptCaching) */
+  promptCaching?: boolean;
+  /** Context window used to budget retrieved code (default: the model's known window) */
+  contextWindow?: number;
+  /** Reorders vector search results before the top maxChunks are kept (config search.rerank) */
+  reranker?: Reranker;
+  /** Vector search results the reranker scores (default: 50) */
+  rerankCandidates?: number;
+  /** Mask secrets in retrieved chunks before prompting (default: enabled) */
+  redaction?: {
+    enabled?: boolean;
+    patterns?: string[];
+  };
+  /** Custom system prompt for the command (config prompts.<command>, --system-prompt) */
+  systemPrompt?: SystemPromptOptions;
+  /** Milliseconds a request may go without a response before it is cancelled; 0 disables (default: 120s) */
+  timeoutMs?: number;
+  /** Aborts every in-flight request when signalled (e.g. on Ctrl-C) */
+  signal?: AbortSignal;
+  /** Backends tried in order when the one before is down: 5xx, timeout, unreachable (config ai.providers) */
+  fallbacks?: AIManagerFallback[];
+  /** Called with the backend that answered each completion, e.g. for verbose logging */
+  onServed?: (info: ProviderServed) => void;
+}
+
+/**
+ * A backend completions fall back to; the model defaults as for the primary
+ */
+export type AIManagerFallback = Pick<AIManagerOptions, 'provider' | 'model' | 'apiKey' | 'azure' | 'geminiUrl' | 'openaiCompatibleUrl'>;
+
+/**
+ * Prompts that can be previewed without a generation call (--context-only)
+ */
+export type PromptKind = 'explain' | 'plan' | 'code' | 'review' | 'review-structured';
+
+export interface ReviewOptions {
+  /**
+   * Review only the changed hunks (cv review --diff): the model sees new-file
+   * line numbers and structured findings are mapped onto the added lines
+   */
+  changedLinesOnly?: boolean;
+  /** Language of the code when file names don't show it (piped input) */
+  language?: string;
+  /** Categories to leave out and severities to pin for structured findings */
+  rules?: ReviewRules;
 }
 
 export interface StreamHandler {
   onToken?: (token: string) => void;
   onComplete?: (fullText: string) => void;
   onError?: (error: Error) => void;
+  /** Aborts the in-flight request when signalled (e.g. on Ctrl-C) */
+  signal?: AbortSignal;
 }
 
 export class AIManager {
-  private client: Anthropic;
   private model: string;
   private maxTokens: number;
   private temperature: number;
   private prdClient?: PRDClient;
+  /** Backend completions are routed to (Anthropic, Azure OpenAI, Gemini or an OpenAI-compatible gateway) */
+  private delegate: AIClient;
+  /** The delegate, then the fallbacks tried when it is down */
+  private backends: ProviderCandidate<AIClient>[];
+  private redactor?: SecretRedactor;
 
   constructor(
     private options: AIManagerOptions,
@@ -62,11 +194,17 @@ export class AIManager {
     private graph?: GraphManager,
     private git?: GitManager
   ) {
-    this.client = new Anthropic({ apiKey: options.apiKey });
-    this.model = options.model || 'claude-3-5-sonnet-20241022';
     this.maxTokens = options.maxTokens || 4096;
     this.temperature = options.temperature || 0.7;
 
+    this.backends = [options, ...(options.fallbacks ?? [])].map(backend => this.createBackend(backend));
+    this.delegate = this.backends[0].client;
+    this.model = this.backends[0].model;
+
+    if (options.redaction?.enabled !== false) {
+      this.redactor = new SecretRedactor(options.redaction?.patterns);
+    }
+
     // Initialize PRD client if URL provided
     if (options.prdUrl) {
       this.prdClient = new PRDClient({
@@ -76,6 +214,65 @@ export class AIManager {
     }
   }
 
+  /**
+   * Client for one backend, with the model it is asked for
+   */
+  private createBackend(backend: AIManagerFallback): ProviderCandidate<AIClient> {
+    if (backend.provider === 'azure') {
+      if (!backend.azure?.endpoint || !backend.azure.deployment) {
+        throw new Error('Azure OpenAI endpoint and chat deployment required. Run: cv auth setup azure');
+      }
+      const client = createAzureOpenAIClient({
+        ...backend.azure,
+        apiKey: backend.apiKey,
+        maxTokens: this.maxTokens,
+        temperature: this.temperature,
+        maxRetryAttempts: this.options.maxRetryAttempts
+      });
+      return { provider: 'azure', model: backend.azure.deployment, client };
+    }
+
+    if (backend.provider === 'gemini') {
+      // Claude model names (the config default) mean nothing to Gemini
+      const model = backend.model?.startsWith('gemini') ? backend.model : DEFAULT_GEMINI_MODEL;
+      const client = createGeminiClient({
+        apiKey: backend.apiKey,
+        model,
+        baseUrl: backend.geminiUrl,
+        maxTokens: this.maxTokens,
+        temperature: this.temperature,
+        maxRetryAttempts: this.options.maxRetryAttempts
+      });
+      return { provider: 'gemini', model, client };
+    }
+
+    if (backend.provider === 'openai-compatible') {
+      if (!backend.openaiCompatibleUrl || !backend.model) {
+        throw new Error('OpenAI-compatible base URL and chat model required. Run: cv auth setup openai-compatible');
+      }
+      const client = createOpenAICompatibleClient({
+        baseUrl: backend.openaiCompatibleUrl,
+        apiKey: backend.apiKey,
+        model: backend.model,
+        maxTokens: this.maxTokens,
+        temperature: this.temperature,
+        maxRetryAttempts: this.options.maxRetryAttempts
+      });
+      return { provider: 'openai-compatible', model: backend.model, client };
+    }
+
+    const model = backend.model || 'claude-3-5-sonnet-20241022';
+    const client = createAnthropicClient({
+      apiKey: backend.apiKey,
+      model,
+      maxTokens: this.maxTokens,
+      temperature: this.temperature,
+      promptCaching: this.options.promptCaching,
+      maxRetryAttempts: this.options.maxRetryAttempts
+    });
+    return { provider: 'anthropic', model, client };
+  }
+
   /**
    * Gather relevant context for a query
    */
@@ -84,9 +281,26 @@ export class AIManager {
     options?: {
       maxChunks?: number;
       maxSymbols?: number;
+      /** Minimum similarity for a chunk to be included (default: 0.25) */
+      minScore?: number;
+      /** Similarity above which a chunk is dropped as a near-duplicate of a better one (default: 0.97) */
+      dedupeThreshold?: number;
       includeGitStatus?: boolean;
+      /** Repo-relative files to always include, regardless of minScore */
       specificFiles?: string[];
+      /** Leave test file chunks out of the search, or search only those (specificFiles are kept either way) */
+      tests?: TestChunkFilter;
+      /** Only search chunks of these packages (--package; specificFiles are kept either way) */
+      packages?: string[];
+      /** Only search chunks of symbols of these kinds (--kind) */
+      kinds?: SymbolKind[];
+      /** Only search chunks of files with these extensions (--ext) */
+      extensions?: string[];
       prdRefs?: string[];
+      /** Add up to this many recent commits touching the retrieved files (cv explain --history) */
+      historyCommits?: number;
+      /** Levels of references to follow from the retrieved chunks to their definitions (cv explain --depth) */
+      expandDepth?: number;
     }
   ): Promise<Context> {
     const context: Context = {
@@ -99,20 +313,83 @@ export class AIManager {
     // Extract PRD refs from query if not provided
     const prdRefs = options?.prdRefs || PRDClient.extractPRDReferences(query);
 
-    const maxChunks = options?.maxChunks || 10;
+    const maxChunks = options?.maxChunks || DEFAULT_CONTEXT_TOP_K;
     const maxSymbols = options?.maxSymbols || 20;
+    const minScore = options?.minScore ?? DEFAULT_CONTEXT_MIN_SCORE;
 
     // 1. Vector search for relevant code chunks
     if (this.vector) {
       try {
-        context.chunks = await this.vector.searchCode(query, maxChunks, {
-          minScore: 0.25  // Lowered from 0.6 to be more lenient with semantic matches
+        const reranker = this.options.reranker;
+        const limit = reranker ? Math.max(maxChunks, this.options.rerankCandidates ?? DEFAULT_RERANK_CANDIDATES) : maxChunks;
+        const results = await this.vector.searchCode(query, limit, {
+          withVectors: true,
+          tests: options?.tests,
+          packages: options?.packages,
+          kinds: options?.kinds,
+          extensions: options?.extensions
         });
+        const thresholded = applyMinScore(results, minScore);
+        context.chunks = thresholded.results;
+        context.nearMissScore = thresholded.nearMissScore;
+        if (reranker) {
+          context.chunks = await this.rerankCandidates(query, context, reranker, maxChunks);
+        }
       } catch (error) {
+        // An answer from mismatched vectors would look grounded but isn't
+        if (isIndexMismatchError(error)) throw error;
         console.error('Vector search failed:', error);
       }
     }
 
+    // Explicitly named files come first and bypass the threshold
+    if (options?.specificFiles?.length) {
+      const fileChunks = await gatherFileChunks(
+        this.git?.getRepoRoot() || process.cwd(),
+        options.specificFiles,
+        { query, vector: this.vector }
+      );
+      context.chunks = mergeFileChunks(fileChunks, context.chunks);
+    }
+
+    // Vendored or generated copies of the same code would waste the budget
+    const deduped = deduplicateChunks(context.chunks, options?.dedupeThreshold);
+    context.chunks = deduped.chunks;
+    if (deduped.duplicates > 0) {
+      context.duplicates = deduped.duplicates;
+    }
+
+    // Keep the highest-ranked chunks that fit the model's context window
+    const budget = getContextBudget({
+      model: this.model,
+      maxOutputTokens: this.maxTokens,
+      contextWindow: this.options.contextWindow
+    });
+    const budgeted = fitChunksToBudget(context.chunks, budget);
+    context.chunks = budgeted.chunks;
+    if (budgeted.dropped > 0 || budgeted.truncated > 0) {
+      context.budget = { dropped: budgeted.dropped, truncated: budgeted.truncated };
+      if (budgeted.pinned > 0) {
+        context.budget.pinned = budgeted.pinned;
+      }
+    }
+    let usedTokens = budgeted.tokens;
+
+    // Definitions of what the retrieved code uses, in what it left of the budget
+    if (options?.expandDepth && context.chunks.length > 0) {
+      usedTokens += await this.addDefinitions(context, options.expandDepth, budget - usedTokens);
+    }
+
+    if (this.redactor && context.chunks.length > 0) {
+      this.redactChunks(context);
+    }
+
+    // Commit messages of the retrieved files, in what the code left of the budget
+    if (options?.historyCommits && this.git && context.chunks.length > 0) {
+      const historyBudget = Math.min(budget - usedTokens, Math.floor(budget * HISTORY_BUDGET_SHARE));
+      await this.addCommitHistory(context, options.historyCommits, historyBudget);
+    }
+
     // 2. Graph queries for related symbols
     if (this.graph && context.chunks.length > 0) {
       try {
@@ -203,6 +480,105 @@ export class AIManager {
     return context;
   }
 
+  /**
+   * Context for code piped on stdin: the code as a single chunk, fitted to
+   * the context window and masked like retrieved code
+   */
+  pipedCodeContext(code: string, language?: string): Context {
+    const context = buildPipedCodeContext(code, language);
+
+    const budgeted = fitChunksToBudget(context.chunks, getContextBudget({
+      model: this.model,
+      maxOutputTokens: this.maxTokens,
+      contextWindow: this.options.contextWindow
+    }));
+    context.chunks = budgeted.chunks;
+    if (budgeted.truncated > 0) {
+      context.budget = { dropped: 0, truncated: budgeted.truncated };
+    }
+
+    if (this.redactor) {
+      this.redactChunks(context);
+    }
+    return context;
+  }
+
+  /**
+   * Mask secrets in chunk text so keys never reach the model
+   */
+  private redactChunks(context: Context): void {
+    let redacted = 0;
+    context.chunks = context.chunks.map(chunk => {
+      const { text, count } = this.redactor!.redact(chunk.payload.text);
+      if (count === 0) return chunk;
+      redacted += count;
+      return { ...chunk, payload: { ...chunk.payload, text } };
+    });
+    context.redactedSecrets = redacted;
+  }
+
+  /**
+   * Rerank the vector search results and keep the best maxChunks; if the
+   * reranker fails, the vector order is kept
+   */
+  private async rerankCandidates(query: string, context: Context, reranker: Reranker, maxChunks: number): Promise<Context['chunks']> {
+    const candidates = context.chunks;
+    try {
+      const reranked = await rerankChunks(query, candidates, reranker, maxChunks, this.options.signal);
+      context.rerank = { model: reranker.model, candidates: candidates.length, moved: reranked.moved };
+      return reranked.chunks;
+    } catch (error) {
+      if (this.options.signal?.aborted) throw error;
+      console.error('Reranking failed, keeping the vector search order:', error);
+      return candidates.slice(0, maxChunks);
+    }
+  }
+
+  /**
+   * Add the definitions of identifiers used by the chunks the explain prompt
+   * shows, looked up in the persisted index; returns the tokens they take
+   */
+  private async addDefinitions(context: Context, depth: number, budgetTokens: number): Promise<number> {
+    let indexed: CodeChunkPayload[];
+    try {
+      indexed = await readIndexedCodeChunks(getIndexDir(this.git?.getRepoRoot() || process.cwd()));
+    } catch (error) {
+      console.error('Reading the index for --depth failed:', error);
+      return 0;
+    }
+
+    const expanded = expandWithDefinitions(context.chunks.slice(0, EXPLAIN_PROMPT_CHUNKS), indexed, {
+      depth,
+      budgetTokens: Math.max(0, budgetTokens)
+    });
+    context.chunks.push(...expanded.chunks);
+    context.expansion = { depth, added: expanded.chunks.length, dropped: expanded.dropped };
+    return expanded.tokens;
+  }
+
+  /**
+   * Add recent commits touching the files of the retrieved chunks, newest first
+   */
+  private async addCommitHistory(context: Context, limit: number, budgetTokens: number): Promise<void> {
+    const files = [...new Set(context.chunks.map(chunk => chunk.payload.file))];
+    let commits;
+    try {
+      commits = await this.git!.getFilesHistory(files, limit);
+    } catch (error) {
+      console.error('Commit history lookup failed:', error);
+      return;
+    }
+
+    if (this.redactor) {
+      commits = commits.map(commit => ({ ...commit, message: this.redactor!.redact(commit.message).text }));
+    }
+    const fitted = fitHistoryToBudget(commits, Math.max(0, budgetTokens));
+    context.history = fitted.commits;
+    if (fitted.dropped > 0 || fitted.truncated > 0) {
+      context.historyBudget = { dropped: fitted.dropped, truncated: fitted.truncated };
+    }
+  }
+
   /**
    * Explain code or concept
    */
@@ -220,7 +596,22 @@ export class AIManager {
     const prompt = this.buildExplainPrompt(target, context);
 
     // Call Claude
-    return await this.complete(prompt, streamHandler);
+    return await this.complete(prompt, streamHandler, { input: target, context });
+  }
+
+  /**
+   * Suggest follow-up questions to an answer, naming only the indexed code
+   * its context held
+   */
+  async suggestFollowUps(question: string, answer: string, context: Context): Promise<string[]> {
+    const subjects: FollowUpSubject[] = [
+      ...this.explainPromptChunks(context).map(chunk => ({ file: chunk.payload.file, symbol: chunk.payload.symbolName })),
+      ...context.symbols.map(symbol => ({ file: symbol.file, symbol: symbol.name }))
+    ];
+    if (subjects.length === 0) {
+      return [];
+    }
+    return parseFollowUps(await this.complete(buildFollowUpPrompt(question, answer, subjects)), subjects);
   }
 
   /**
@@ -235,7 +626,7 @@ export class AIManager {
     // Build prompt
     const prompt = this.buildPlanPrompt(task, context);
 
-    // Call Claude
+    // Call Claude; no raw mode, since the response is parsed as a plan
     const response = await this.complete(prompt);
 
     // Parse the response into a Plan
@@ -248,7 +639,8 @@ export class AIManager {
   async generateCode(
     task: string,
     context?: Context,
-    streamHandler?: StreamHandler
+    streamHandler?: StreamHandler,
+    plan?: Plan
   ): Promise<string> {
     // Gather context if not provided
     if (!context) {
@@ -256,24 +648,175 @@ export class AIManager {
     }
 
     // Build prompt
-    const prompt = this.buildCodeGenerationPrompt(task, context);
+    const prompt = this.buildCodeGenerationPrompt(task, context, plan);
 
     // Call Claude
+    return await this.complete(prompt, streamHandler, { input: task, context });
+  }
+
+  /**
+   * Generate a unit test file for a symbol
+   */
+  async generateTests(
+    context: TestGenerationContext,
+    streamHandler?: StreamHandler
+  ): Promise<string> {
+    const redact = (text: string) => this.redactor ? this.redactor.redact(text).text : text;
+    const prompt = buildTestGenerationPrompt({
+      ...context,
+      source: redact(context.source),
+      callers: context.callers.map(c => ({ ...c, excerpt: redact(c.excerpt) })),
+      exampleTest: context.exampleTest && { ...context.exampleTest, content: redact(context.exampleTest.content) }
+    });
+
     return await this.complete(prompt, streamHandler);
   }
 
+  /**
+   * Refactor a file or one of its symbols following an instruction.
+   * Returns the rewritten code (the symbol only, when one is targeted) with
+   * any secrets masked for the prompt put back.
+   */
+  async refactorCode(
+    instruction: string,
+    target: RefactorTarget,
+    streamHandler?: StreamHandler
+  ): Promise<string> {
+    const source = getRefactorSource(target);
+    const { text: redacted, secrets = [] } = this.redactor ? this.redactor.redact(source, { indexed: true }) : { text: source, secrets: [] };
+    const response = await this.complete(buildRefactorPrompt(instruction, target, redacted), streamHandler);
+    return restoreRedactedSecrets(extractRefactoredCode(response), secrets);
+  }
+
+  /**
+   * Replacement for the lines of one review finding (`cv review --fix`),
+   * or null when the model finds no safe fix within them
+   */
+  async fixReviewFinding(finding: ReviewFinding, language: string, content: string): Promise<string | null> {
+    const redact = (text: string) => this.redactor ? this.redactor.redact(text).text : text;
+    const source = getFixSource(finding, content);
+    const { text: redacted, secrets = [] } = this.redactor ? this.redactor.redact(source, { indexed: true }) : { text: source, secrets: [] };
+    const code = parseReviewFix(await this.complete(buildReviewFixPrompt(finding, language, content, redacted, redact)));
+    return code === null ? null : restoreRedactedSecrets(code, secrets);
+  }
+
+  /**
+   * Write doc comments for symbols in one file.
+   * Returns the comment text per symbol; symbols the model skipped are left out.
+   */
+  async generateDocComments(
+    file: string,
+    language: string,
+    targets: DocTarget[]
+  ): Promise<DocComment[]> {
+    const redact = (text: string) => this.redactor ? this.redactor.redact(text).text : text;
+    const prompt = buildDocCommentPrompt(file, language, targets.map(t => ({ ...t, source: redact(t.source) })));
+    return parseDocComments(await this.complete(prompt), targets);
+  }
+
+  /**
+   * Explain why lines exist, from the commits that introduced them
+   */
+  async explainWhy(
+    context: WhyContext,
+    streamHandler?: StreamHandler
+  ): Promise<string> {
+    const redact = (text: string) => this.redactor ? this.redactor.redact(text).text : text;
+    const prompt = buildWhyPrompt({
+      ...context,
+      code: redact(context.code),
+      commits: context.commits.map(c => ({ ...c, diff: c.diff && redact(c.diff) })),
+      symbol: context.symbol && { ...context.symbol, source: redact(context.symbol.source) }
+    });
+
+    return await this.complete(prompt, streamHandler);
+  }
+
+  /**
+   * Explain a diff in plain English and list its risks.
+   * Diffs too large for one prompt are summarized part by part first.
+   */
+  async explainDiff(
+    diff: string,
+    complexChanges: ComplexChange[] = [],
+    maxPartChars: number = DEFAULT_DIFF_PART_CHARS
+  ): Promise<DiffExplanation> {
+    const text = this.redactor ? this.redactor.redact(diff).text : diff;
+    const parts = splitDiff(text, maxPartChars);
+
+    let prompt: string;
+    if (parts.length <= 1) {
+      prompt = buildDiffExplanationPrompt({ diff: parts[0] ?? '' }, complexChanges);
+    } else {
+      const partSummaries: string[] = [];
+      for (let i = 0; i < parts.length; i++) {
+        partSummaries.push(await this.complete(buildDiffPartPrompt(parts[i], i, parts.length)));
+      }
+      prompt = buildDiffExplanationPrompt({ partSummaries }, complexChanges);
+    }
+
+    return parseDiffExplanation(await this.complete(prompt), complexChanges, Math.max(parts.length, 1));
+  }
+
+  /**
+   * Group the commits of a range into themes and explain the intent of each
+   */
+  async summarizeChanges(input: ChangeSummaryInput): Promise<ChangeSummary> {
+    const redact = (text: string) => this.redactor ? this.redactor.redact(text).text : text;
+    const redacted: ChangeSummaryInput = {
+      ...input,
+      commits: input.commits.map(c => ({ ...c, message: redact(c.message), diff: c.diff && redact(c.diff) }))
+    };
+
+    return parseChangeSummary(await this.complete(buildChangeSummaryPrompt(redacted)), input);
+  }
+
+  /**
+   * Write an architecture overview of the repository from sampled files
+   */
+  async summarizeRepository(
+    sample: OverviewSample,
+    depth: OverviewDepth,
+    repoName?: string,
+    streamHandler?: StreamHandler
+  ): Promise<string> {
+    const redact = (text: string) => this.redactor ? this.redactor.redact(text).text : text;
+    const redacted: OverviewSample = {
+      ...sample,
+      files: sample.files.map(f => ({ ...f, chunks: f.chunks.map(c => ({ ...c, text: redact(c.text) })) }))
+    };
+
+    return await this.complete(buildRepoOverviewPrompt(redacted, depth, repoName), streamHandler);
+  }
+
   /**
    * Review code changes
    */
   async reviewCode(
     diff: string,
-    context?: Context
+    context?: Context,
+    options: ReviewOptions = {}
   ): Promise<string> {
     // Build prompt for code review
-    const prompt = this.buildReviewPrompt(diff, context);
+    const prompt = this.buildReviewPrompt(diff, context, options);
 
     // Call Claude
-    return await this.complete(prompt);
+    return await this.complete(prompt, undefined, { input: diff, context });
+  }
+
+  /**
+   * Review code changes and return structured findings
+   */
+  async reviewCodeStructured(
+    diff: string,
+    context?: Context,
+    options: ReviewOptions = {}
+  ): Promise<ReviewResult> {
+    // No raw mode here: the findings format is what the response is parsed by
+    const prompt = this.buildStructuredReviewPrompt(diff, context, options);
+    const response = await this.complete(prompt);
+    const result = applyReviewRules(parseReviewResponse(response), options.rules);
+    return options.changedLinesOnly ? mapFindingsToDiff(result, parseDiffHunks(diff)) : result;
   }
 
   /**
@@ -288,84 +831,129 @@ export class AIManager {
       content: msg.content
     }));
 
+    const { system } = applySystemPrompt(this.options.systemPrompt, '');
+
     if (streamHandler) {
-      return await this.streamComplete(anthropicMessages, streamHandler);
-    } else {
-      const response = await this.client.messages.create({
-        model: this.model,
-        max_tokens: this.maxTokens,
-        temperature: this.temperature,
-        messages: anthropicMessages
-      });
+      return await this.streamComplete(anthropicMessages, streamHandler, system);
+    }
+    return await this.sendMessages(anthropicMessages, system);
+  }
+
+  /**
+   * The model completions are sent to (the deployment for Azure)
+   */
+  getModel(): string {
+    return this.delegate.getModel();
+  }
 
-      return response.content[0].type === 'text' ? response.content[0].text : '';
+  /**
+   * The retrieved chunks an explain prompt built from this context includes,
+   * in prompt order: the numbered sections, or in raw mode the code that
+   * {{code}} places
+   */
+  explainPromptChunks(context: Context): VectorSearchResult<CodeChunkPayload>[] {
+    const custom = this.options.systemPrompt;
+    if (custom?.raw) {
+      const placesCode = /\{\{\s*code\s*\}\}/.test(custom.template);
+      const placesInput = /\{\{\s*input\s*\}\}/.test(custom.template);
+      // A template placing neither gets the input and code appended
+      return placesCode || !placesInput ? context.chunks : [];
     }
+    return explainSourceChunks(context.chunks);
   }
 
   /**
-   * Complete a prompt with Claude
+   * The prompt a command would send for this input and context, without
+   * sending it. The input is the explain target, the task, or the diff.
+   */
+  buildPrompt(kind: PromptKind, input: string, context: Context, review: ReviewOptions = {}): string {
+    switch (kind) {
+      case 'explain':
+        return this.buildExplainPrompt(input, context);
+      case 'plan':
+        return this.buildPlanPrompt(input, context);
+      case 'code':
+        return this.buildCodeGenerationPrompt(input, context);
+      case 'review':
+        return this.buildReviewPrompt(input, context, review);
+      case 'review-structured':
+        return this.buildStructuredReviewPrompt(input, context, review);
+    }
+  }
+
+  /**
+   * Complete a prompt with Claude. The material (input and context the prompt
+   * was built from) lets a raw custom prompt replace the built-in one.
    */
   private async complete(
     prompt: string,
-    streamHandler?: StreamHandler
+    streamHandler?: StreamHandler,
+    material?: { input: string; context?: Context }
   ): Promise<string> {
-    const messages = [{ role: 'user' as const, content: prompt }];
+    const request = applySystemPrompt(this.options.systemPrompt, prompt, material?.input, material?.context);
+    const messages = [{ role: 'user' as const, content: request.prompt }];
 
     if (streamHandler) {
-      return await this.streamComplete(messages, streamHandler);
+      return await this.streamComplete(messages, streamHandler, request.system);
     }
+    return await this.sendMessages(messages, request.system);
+  }
 
-    const response = await this.client.messages.create({
-      model: this.model,
-      max_tokens: this.maxTokens,
-      temperature: this.temperature,
-      messages
-    });
-
-    return response.content[0].type === 'text' ? response.content[0].text : '';
+  /**
+   * Non-streaming completion from the backend
+   */
+  private async sendMessages(
+    messages: Array<{ role: 'user' | 'assistant'; content: string }>,
+    system?: string
+  ): Promise<string> {
+    return tryProviders(
+      this.backends,
+      ({ client }) => this.withTimeout(client, undefined, signal => client.chat(messages, system, signal)),
+      { onServed: this.options.onServed }
+    );
   }
 
   /**
-   * Stream completion from Claude
+   * Stream completion from the backend
    */
   private async streamComplete(
     messages: Array<{ role: 'user' | 'assistant'; content: string }>,
-    streamHandler: StreamHandler
+    streamHandler: StreamHandler,
+    system?: string
   ): Promise<string> {
-    let fullText = '';
-
-    try {
-      const stream = await this.client.messages.create({
-        model: this.model,
-        max_tokens: this.maxTokens,
-        temperature: this.temperature,
-        messages,
-        stream: true
-      });
-
-      for await (const event of stream) {
-        if (event.type === 'content_block_delta' &&
-            event.delta.type === 'text_delta') {
-          const token = event.delta.text;
-          fullText += token;
-          if (streamHandler.onToken) {
-            streamHandler.onToken(token);
+    // Once tokens are printed, another backend's answer would be printed after them
+    let streamed = false;
+    return await tryProviders(
+      this.backends,
+      ({ client }) => this.withTimeout(client, streamHandler.signal, (signal, keepAlive) =>
+        client.chatStream(messages, system, {
+          ...streamHandler,
+          signal,
+          onToken: token => {
+            streamed = true;
+            keepAlive();
+            streamHandler.onToken?.(token);
           }
-        }
-      }
-
-      if (streamHandler.onComplete) {
-        streamHandler.onComplete(fullText);
-      }
-
-      return fullText;
+        })
+      ),
+      { canFallBack: () => !streamed, onServed: this.options.onServed }
+    );
+  }
 
-    } catch (error) {
-      if (streamHandler.onError) {
-        streamHandler.onError(error as Error);
-      }
-      throw error;
-    }
+  /**
+   * Run one request to a backend under the configured timeout, cancelled by
+   * the manager's signal or the stream handler's
+   */
+  private withTimeout<T>(
+    client: AIClient,
+    handlerSignal: AbortSignal | undefined,
+    fn: (signal: AbortSignal, keepAlive: () => void) => Promise<T>
+  ): Promise<T> {
+    const signals = [this.options.signal, handlerSignal].filter((signal): signal is AbortSignal => !!signal);
+    return withRequestTimeout(`${client.getProvider()} request`, fn, {
+      timeoutMs: this.options.timeoutMs ?? DEFAULT_CHAT_TIMEOUT_MS,
+      signal: signals.length > 1 ? AbortSignal.any(signals) : signals[0]
+    });
   }
 
   /**
@@ -375,10 +963,15 @@ export class AIManager {
     let prompt = `You are an expert software engineer analyzing a codebase. Explain the following in clear, concise terms:\n\n`;
     prompt += `Target: ${target}\n\n`;
 
-    if (context.chunks.length > 0) {
+    // Sections are numbered so the answer can cite them inline as [n]
+    const sources = explainSourceChunks(context.chunks);
+    if (sources.length > 0) {
       prompt += `## Relevant Code\n\n`;
-      for (const chunk of context.chunks.slice(0, 5)) {
-        prompt += `### ${chunk.payload.file}:${chunk.payload.startLine}\n`;
+      for (const [i, chunk] of sources.entries()) {
+        prompt += `### [${i + 1}] ${chunk.payload.file}:${chunk.payload.startLine}-${chunk.payload.endLine}\n`;
+        if (chunk.expansion) {
+          prompt += `Definition of ${chunk.expansion.name}, used by ${chunk.expansion.from}\n`;
+        }
         if (chunk.payload.symbolName) {
           prompt += `Symbol: ${chunk.payload.symbolName} (${chunk.payload.symbolKind})\n`;
         }
@@ -400,11 +993,22 @@ export class AIManager {
       prompt += `\n`;
     }
 
+    if (context.history?.length) {
+      prompt += formatCommitHistory(context.history);
+    }
+
     prompt += `\nProvide a clear explanation that covers:\n`;
     prompt += `1. What this code does\n`;
     prompt += `2. How it works (key logic)\n`;
     prompt += `3. How it fits into the larger system\n`;
     prompt += `4. Any important design decisions or patterns\n\n`;
+    if (context.history?.length) {
+      prompt += `Use the commit messages to explain why the code works this way, citing commits by short SHA; `;
+      prompt += `say so when the history does not make the reasons clear.\n\n`;
+    }
+    if (sources.length > 0) {
+      prompt += `${buildCitationInstruction(sources.length)}\n\n`;
+    }
     prompt += `Keep it concise but thorough.`;
 
     return prompt;
@@ -446,6 +1050,9 @@ export class AIManager {
     prompt += `  "estimatedComplexity": "low|medium|high",\n`;
     prompt += `  "risks": ["Any potential risks or concerns"]\n`;
     prompt += `}\n\n`;
+    if (context.language) {
+      prompt += `${buildLanguageInstruction(context.language, context.repoLanguages)}\n`;
+    }
     prompt += `Be specific about files and changes. Consider dependencies and testing.`;
 
     return prompt;
@@ -454,10 +1061,18 @@ export class AIManager {
   /**
    * Build prompt for code generation
    */
-  private buildCodeGenerationPrompt(task: string, context: Context): string {
+  private buildCodeGenerationPrompt(task: string, context: Context, plan?: Plan): string {
     let prompt = `You are an expert software engineer. Generate code for the following task:\n\n`;
     prompt += `Task: ${task}\n\n`;
 
+    if (plan?.steps.length) {
+      prompt += `## Approved Plan\n\n`;
+      plan.steps.forEach((step, i) => {
+        prompt += `${i + 1}. [${step.type}] ${step.file}: ${step.description}\n`;
+      });
+      prompt += `\n`;
+    }
+
     // Include PRD context if available
     if (context.prdContext) {
       prompt += `## Requirements Context\n\n`;
@@ -481,7 +1096,18 @@ export class AIManager {
     if (context.prdContext) {
       prompt += `5. Ensure all requirements from the PRD are addressed\n`;
     }
-    prompt += `\nFormat your response clearly with file paths and code blocks.`;
+    if (context.language) {
+      prompt += `\n${buildLanguageInstruction(context.language, context.repoLanguages)}\n`;
+    }
+    prompt += `\nWrite every change as an edit block; the blocks are applied to the files as written.\n\n`;
+    prompt += `A new file, or a file rewritten in full, is a code block whose info string is its path:\n`;
+    prompt += `\`\`\`path/to/file.ts\n(full content)\n\`\`\`\n\n`;
+    prompt += `A change to an existing file is one or more search/replace blocks; the SEARCH text must match the file exactly, whitespace included:\n`;
+    prompt += `\`\`\`path/to/file.ts\n<<<<<<< SEARCH\n(existing lines)\n=======\n(new lines)\n>>>>>>> REPLACE\n\`\`\`\n\n`;
+    prompt += `A deleted file is:\n`;
+    prompt += `\`\`\`path/to/file.ts\n<<<<<<< DELETE\n>>>>>>> DELETE\n\`\`\`\n\n`;
+    prompt += `To move a file, delete the old path and create the new one. `;
+    prompt += `Paths are relative to the repository root. Explain the changes briefly before the blocks.`;
 
     return prompt;
   }
@@ -489,9 +1115,19 @@ export class AIManager {
   /**
    * Build prompt for code review
    */
-  private buildReviewPrompt(diff: string, context?: Context): string {
+  private buildReviewPrompt(diff: string, context?: Context, options: ReviewOptions = {}): string {
     let prompt = `You are an expert code reviewer. Review the following changes:\n\n`;
-    prompt += `## Diff\n\`\`\`diff\n${diff}\n\`\`\`\n\n`;
+    if (options.language) {
+      prompt += `Language: ${options.language}\n\n`;
+    }
+    if (options.changedLinesOnly) {
+      prompt += `## Changed Hunks\n`;
+      prompt += `Each line starts with its line number in the new file, then + (added), - (removed), or a space (unchanged context).\n`;
+      prompt += `Only review the added lines; the context lines are there to help you understand them.\n\n`;
+      prompt += `\`\`\`\n${formatNumberedDiff(parseDiffHunks(diff))}\n\`\`\`\n\n`;
+    } else {
+      prompt += `## Diff\n\`\`\`diff\n${diff}\n\`\`\`\n\n`;
+    }
 
     if (context?.chunks && context.chunks.length > 0) {
       prompt += `## Related Code\n\n`;
@@ -513,6 +1149,39 @@ export class AIManager {
     return prompt;
   }
 
+  /**
+   * Build prompt for a code review with machine-readable findings
+   */
+  private buildStructuredReviewPrompt(diff: string, context?: Context, options: ReviewOptions = {}): string {
+    let prompt = this.buildReviewPrompt(diff, context, options).replace(/Be constructive and specific\.$/, '');
+    const disabled = options.rules?.disable || [];
+    const categories = REVIEW_CATEGORIES.filter(c => !disabled.includes(c));
+
+    prompt += `Respond with ONLY a JSON object in this format:\n`;
+    prompt += `{\n`;
+    prompt += `  "overview": "One or two sentence overall assessment",\n`;
+    prompt += `  "findings": [\n`;
+    prompt += `    {\n`;
+    prompt += `      "file": "path/to/file (as shown in the diff)",\n`;
+    prompt += `      "startLine": 10,\n`;
+    prompt += `      "endLine": 12,\n`;
+    prompt += `      "severity": "low" | "medium" | "high" | "critical",\n`;
+    prompt += `      "category": ${categories.map(c => `"${c}"`).join(' | ')},\n`;
+    prompt += `      "message": "What is wrong and why it matters",\n`;
+    prompt += `      "suggestion": "How to fix it (optional)"\n`;
+    prompt += `    }\n`;
+    prompt += `  ]\n`;
+    prompt += `}\n\n`;
+    prompt += `Categories: security is vulnerabilities and unsafe handling of input, auth or secrets; correctness is bugs and wrong results; `;
+    prompt += `performance is hot paths, repeated work in loops, N+1 queries and needless allocations; style is formatting and naming.\n`;
+    if (disabled.length > 0) {
+      prompt += `Do not report ${disabled.join(', ')} findings.\n`;
+    }
+    prompt += `Line numbers refer to the new version of the file. Use an empty findings array if there are no issues.`;
+
+    return prompt;
+  }
+
   /**
    * Parse plan from Claude response
    */
@@ -0,0 +1,143 @@
+/**
+ * Inline Citations
+ * Numbers the code sections given to `cv explain` so the answer can cite
+ * them as [1], [2], and checks the answer's markers against those sections:
+ * markers for sections that were never given are dropped.
+ */
+
+import { ChunkExpansion, CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+
+/** Retrieved code sections the explain prompt includes, best match first */
+export const EXPLAIN_PROMPT_CHUNKS = 5;
+
+/**
+ * A code section given to the model, by the number the prompt shows for it
+ */
+export interface NumberedSource {
+  /** 1-based, as in the [n] markers */
+  index: number;
+  /** Repo-relative, so same-named files of different packages stay apart */
+  file: string;
+  /** Package directory the file is in (see PackageResolver), for indexes synced with it */
+  package?: string;
+  startLine: number;
+  endLine: number;
+  symbol?: string;
+  /** Pulled in as a definition rather than retrieved (cv explain --depth) */
+  expansion?: ChunkExpansion;
+}
+
+/**
+ * A source the answer cites, with where its markers are in the answer
+ */
+export interface InlineCitation extends NumberedSource {
+  /** Character ranges in the answer of the markers citing it, e.g. of "[2]" or "[1, 2]" */
+  offsets: Array<{ start: number; end: number }>;
+}
+
+export interface ResolvedCitations {
+  /** The answer with markers for unknown sources removed */
+  text: string;
+  /** Cited sources, by number */
+  citations: InlineCitation[];
+  /** Numbers the answer cited that match no source, in citation order */
+  dropped: number[];
+}
+
+/** [1], [2, 3] or [2][3]; not array indexes (items[1]) or link text ([1](url)) */
+const MARKER_PATTERN = /(?<![\w`])\[(\d+(?:\s*,\s*\d+)*)\](?!\()/g;
+
+/** Fenced code blocks and inline code spans, where brackets are code */
+const CODE_PATTERN = /```[\s\S]*?(?:```|$)|`[^`\n]*`/g;
+
+/**
+ * The chunks the explain prompt numbers: the top retrieved chunks, then the
+ * definitions expanded from them
+ */
+export function explainSourceChunks(chunks: VectorSearchResult<CodeChunkPayload>[]): VectorSearchResult<CodeChunkPayload>[] {
+  return [
+    ...chunks.filter(chunk => !chunk.expansion).slice(0, EXPLAIN_PROMPT_CHUNKS),
+    ...chunks.filter(chunk => chunk.expansion)
+  ];
+}
+
+/**
+ * The sources the explain prompt numbers, in prompt order
+ */
+export function numberSources(chunks: VectorSearchResult<CodeChunkPayload>[]): NumberedSource[] {
+  return explainSourceChunks(chunks).map((chunk, i) => ({
+    index: i + 1,
+    file: chunk.payload.file,
+    startLine: chunk.payload.startLine,
+    endLine: chunk.payload.endLine,
+    symbol: chunk.payload.symbolName,
+    ...(chunk.payload.package ? { package: chunk.payload.package } : {}),
+    ...(chunk.expansion ? { expansion: chunk.expansion } : {})
+  }));
+}
+
+/**
+ * Instruction appended to a prompt whose code sections are numbered
+ */
+export function buildCitationInstruction(sourceCount: number): string {
+  return `Cite the code sections you rely on inline by their number in square brackets, ` +
+    `e.g. [1] or [2][3], right after the statement they support. ` +
+    `Only cite sections 1-${sourceCount} above; do not cite anything else this way.`;
+}
+
+/**
+ * Check an answer's [n] markers against the numbered sources. Markers (or
+ * the numbers inside one) that match no source are removed; offsets refer
+ * to the returned text.
+ */
+export function resolveInlineCitations(answer: string, sources: NumberedSource[]): ResolvedCitations {
+  const byIndex = new Map(sources.map(source => [source.index, source]));
+  const dropped: number[] = [];
+  let text = '';
+  let last = 0;
+
+  for (const { start, end, numbers } of findMarkers(answer)) {
+    const valid = numbers.filter(n => byIndex.has(n));
+    if (valid.length === numbers.length) continue;
+    dropped.push(...numbers.filter(n => !byIndex.has(n)));
+
+    // Rewrite the marker with its valid numbers, or remove it with the space before it
+    let before = answer.slice(last, start);
+    if (valid.length === 0) {
+      before = before.replace(/[ \t]+$/, '');
+    }
+    text += before + (valid.length > 0 ? `[${valid.join(', ')}]` : '');
+    last = end;
+  }
+  text += answer.slice(last);
+
+  // Offsets refer to the rewritten text, which only has valid markers left
+  const cited = new Map<number, InlineCitation>();
+  for (const { start, end, numbers } of findMarkers(text)) {
+    for (const n of numbers) {
+      const citation = cited.get(n) ?? { ...byIndex.get(n)!, offsets: [] };
+      citation.offsets.push({ start, end });
+      cited.set(n, citation);
+    }
+  }
+
+  return {
+    text,
+    citations: [...cited.values()].sort((a, b) => a.index - b.index),
+    dropped
+  };
+}
+
+/**
+ * [n] markers outside code, with the numbers each one cites
+ */
+function findMarkers(text: string): Array<{ start: number; end: number; numbers: number[] }> {
+  const code = [...text.matchAll(CODE_PATTERN)].map(m => ({ start: m.index!, end: m.index! + m[0].length }));
+  return [...text.matchAll(MARKER_PATTERN)]
+    .filter(m => !code.some(range => m.index! >= range.start && m.index! < range.end))
+    .map(m => ({
+      start: m.index!,
+      end: m.index! + m[0].length,
+      numbers: m[1].split(',').map(n => parseInt(n.trim(), 10))
+    }));
+}
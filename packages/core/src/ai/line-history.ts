@@ -0,0 +1,183 @@
+/**
+ * Line History
+ * Connects `git blame` for a line range to the commits that introduced it
+ * and builds the prompt behind `cv why`
+ */
+
+import { BlameLine } from '@cv-git/shared';
+
+/** Commits whose diffs are sent to the model; the rest are listed by subject */
+export const MAX_WHY_COMMITS = 3;
+
+/** Largest diff excerpt sent per commit */
+export const MAX_WHY_DIFF_CHARS = 4000;
+
+export interface LineTarget {
+  file: string;
+  startLine: number;
+  endLine: number;
+}
+
+/**
+ * A commit that introduced some of the blamed lines
+ */
+export interface BlameCommit {
+  sha: string;
+  author: string;
+  authorEmail: string;
+  date: number;
+  summary: string;
+  /** Full commit message, when loaded */
+  message?: string;
+  /** Path of the file in that commit */
+  originalFile: string;
+  /** Lines in the current file */
+  lines: number[];
+  /** The same lines numbered as in that commit */
+  originalLines: number[];
+  uncommitted: boolean;
+  /** Hunks of the commit's diff that touch the lines */
+  diff?: string;
+}
+
+export interface WhyContext {
+  target: LineTarget;
+  language: string;
+  /** Current text of the lines */
+  code: string;
+  commits: BlameCommit[];
+  /** Innermost function, method, or class containing the lines */
+  symbol?: { name: string; kind: string; startLine: number; endLine: number; source: string; docstring?: string };
+}
+
+/**
+ * Parse `<file>:<line>` or `<file>:<start>-<end>`
+ */
+export function parseLineTarget(target: string): LineTarget {
+  const match = target.match(/^(.+):(\d+)(?:-(\d+))?$/);
+  if (!match) {
+    throw new Error(`Expected <file>:<line> or <file>:<start>-<end>, got "${target}"`);
+  }
+
+  const startLine = parseInt(match[2], 10);
+  const endLine = match[3] ? parseInt(match[3], 10) : startLine;
+  if (startLine < 1 || endLine < startLine) {
+    throw new Error(`Invalid line range ${match[2]}${match[3] ? `-${match[3]}` : ''}`);
+  }
+
+  return { file: match[1], startLine, endLine };
+}
+
+/**
+ * Blamed lines grouped by commit, the commit behind most lines first
+ * (newer first on a tie)
+ */
+export function groupBlameByCommit(lines: BlameLine[]): BlameCommit[] {
+  const commits = new Map<string, BlameCommit>();
+
+  for (const line of lines) {
+    let commit = commits.get(line.sha);
+    if (!commit) {
+      commit = {
+        sha: line.sha,
+        author: line.author,
+        authorEmail: line.authorEmail,
+        date: line.date,
+        summary: line.summary,
+        originalFile: line.originalFile,
+        lines: [],
+        originalLines: [],
+        uncommitted: line.uncommitted
+      };
+      commits.set(line.sha, commit);
+    }
+    commit.lines.push(line.line);
+    commit.originalLines.push(line.originalLine);
+  }
+
+  return Array.from(commits.values()).sort((a, b) => b.lines.length - a.lines.length || b.date - a.date);
+}
+
+/**
+ * Keep the hunks of a diff whose new side contains any of the lines,
+ * with the file header, cut to maxChars
+ */
+export function selectHunks(diff: string, lines: number[], maxChars: number = MAX_WHY_DIFF_CHARS): string {
+  const [header, ...hunks] = diff.split(/(?=^@@ )/m);
+  const selected = hunks.filter(hunk => {
+    const match = hunk.match(/^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@/);
+    if (!match) return false;
+    const start = parseInt(match[1], 10);
+    const end = start + parseInt(match[2] ?? '1', 10) - 1;
+    return lines.some(line => line >= start && line <= end);
+  });
+
+  // A line can be attributed to a commit whose hunk git split differently; show the whole diff then
+  const text = header + (selected.length > 0 ? selected : hunks).join('');
+  if (text.length <= maxChars) {
+    return text;
+  }
+  const cut = text.lastIndexOf('\n', maxChars);
+  return text.slice(0, cut > 0 ? cut : maxChars) + '\n... (diff truncated)\n';
+}
+
+/**
+ * Describe a set of line numbers compactly, e.g. "12-14, 20"
+ */
+export function formatLineRanges(lines: number[]): string {
+  const sorted = [...new Set(lines)].sort((a, b) => a - b);
+  const ranges: string[] = [];
+  for (let i = 0; i < sorted.length; i++) {
+    const start = sorted[i];
+    while (i + 1 < sorted.length && sorted[i + 1] === sorted[i] + 1) i++;
+    ranges.push(start === sorted[i] ? `${start}` : `${start}-${sorted[i]}`);
+  }
+  return ranges.join(', ');
+}
+
+/**
+ * Prompt explaining why the lines exist, from the commits that introduced them
+ */
+export function buildWhyPrompt(context: WhyContext): string {
+  const { target, symbol } = context;
+  const range = target.startLine === target.endLine ? `line ${target.startLine}` : `lines ${target.startLine}-${target.endLine}`;
+
+  let prompt = `You are an expert software engineer explaining why a piece of code exists, using its git history.\n\n`;
+  prompt += `## Code (${target.file}, ${range})\n\n\`\`\`${context.language}\n${context.code}\n\`\`\`\n\n`;
+
+  if (symbol) {
+    prompt += `## Enclosing ${symbol.kind} \`${symbol.name}\` (lines ${symbol.startLine}-${symbol.endLine})\n\n`;
+    if (symbol.docstring) {
+      prompt += `${symbol.docstring}\n\n`;
+    }
+    prompt += `\`\`\`${context.language}\n${symbol.source}\n\`\`\`\n\n`;
+  }
+
+  prompt += `## Commits That Introduced These Lines\n\n`;
+  context.commits.forEach((commit, i) => {
+    const lines = formatLineRanges(commit.lines);
+    if (commit.uncommitted) {
+      prompt += `### Not committed yet (lines ${lines})\n\n`;
+      return;
+    }
+
+    prompt += `### ${commit.sha.slice(0, 8)} ${commit.summary}\n`;
+    prompt += `Author: ${commit.author}, ${new Date(commit.date).toISOString().slice(0, 10)}. Lines ${lines}.\n\n`;
+    if (i >= MAX_WHY_COMMITS) {
+      return;
+    }
+    if (commit.message && commit.message.trim() !== commit.summary) {
+      prompt += `Commit message:\n${commit.message.trim()}\n\n`;
+    }
+    if (commit.diff) {
+      prompt += `\`\`\`diff\n${commit.diff}\n\`\`\`\n\n`;
+    }
+  });
+
+  prompt += `Explain the intent behind these lines: what problem the change was solving, why it was written this way, `;
+  prompt += `and how it fits into the enclosing code. Base the explanation on the commit messages and diffs; `;
+  prompt += `say so when the history does not make the intent clear rather than guessing. `;
+  prompt += `Refer to commits by their short SHA. Keep it concise.`;
+
+  return prompt;
+}
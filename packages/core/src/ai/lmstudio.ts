@@ -164,69 +164,78 @@ export class LMStudioClient implements AIClient {
 
     const controller = new AbortController();
     const timeout = setTimeout(() => controller.abort(), this.timeoutMs);
+    // Forward the caller's abort; removed afterwards so a long-lived signal
+    // (e.g. one per chat session) does not collect a listener per request
+    const onAbort = () => controller.abort();
+    handler?.signal?.addEventListener('abort', onAbort, { once: true });
 
-    const response = await fetch(`${this.baseUrl}/chat/completions`, {
-      method: 'POST',
-      headers: {
-        'Content-Type': 'application/json',
-        'Authorization': 'Bearer lm-studio',
-      },
-      body: JSON.stringify({
-        model: this.model,
-        messages: lmMessages,
-        max_tokens: this.maxTokens,
-        temperature: this.temperature,
-        stream: true,
-      }),
-      signal: controller.signal,
-    });
-    clearTimeout(timeout);
-
-    if (!response.ok) {
-      const error = await response.text();
-      const err = new Error(`LM Studio API error: ${response.status} - ${error}`);
-      handler?.onError?.(err);
-      throw err;
-    }
+    try {
+      const response = await fetch(`${this.baseUrl}/chat/completions`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': 'Bearer lm-studio',
+        },
+        body: JSON.stringify({
+          model: this.model,
+          messages: lmMessages,
+          max_tokens: this.maxTokens,
+          temperature: this.temperature,
+          stream: true,
+        }),
+        signal: controller.signal,
+      });
+      clearTimeout(timeout);
 
-    let fullText = '';
-    const reader = response.body?.getReader();
-    if (!reader) {
-      throw new Error('No response body');
-    }
+      if (!response.ok) {
+        const error = await response.text();
+        const err = new Error(`LM Studio API error: ${response.status} - ${error}`);
+        handler?.onError?.(err);
+        throw err;
+      }
 
-    const decoder = new TextDecoder();
+      let fullText = '';
+      const reader = response.body?.getReader();
+      if (!reader) {
+        throw new Error('No response body');
+      }
 
-    try {
-      while (true) {
-        const { done, value } = await reader.read();
-        if (done) break;
-
-        const chunk = decoder.decode(value);
-        const lines = chunk.split('\n').filter(l => l.trim() && l.startsWith('data: '));
-
-        for (const line of lines) {
-          const jsonStr = line.slice(6); // Remove 'data: ' prefix
-          if (jsonStr === '[DONE]') continue;
-
-          try {
-            const json = JSON.parse(jsonStr);
-            const token = json.choices?.[0]?.delta?.content || '';
-            if (token) {
-              fullText += token;
-              handler?.onToken?.(token);
+      const decoder = new TextDecoder();
+
+      try {
+        while (true) {
+          const { done, value } = await reader.read();
+          if (done) break;
+
+          const chunk = decoder.decode(value);
+          const lines = chunk.split('\n').filter(l => l.trim() && l.startsWith('data: '));
+
+          for (const line of lines) {
+            const jsonStr = line.slice(6); // Remove 'data: ' prefix
+            if (jsonStr === '[DONE]') continue;
+
+            try {
+              const json = JSON.parse(jsonStr);
+              const token = json.choices?.[0]?.delta?.content || '';
+              if (token) {
+                fullText += token;
+                handler?.onToken?.(token);
+              }
+            } catch {
+              // Skip non-JSON lines
             }
-          } catch {
-            // Skip non-JSON lines
           }
         }
-      }
 
-      handler?.onComplete?.(fullText);
-      return fullText;
-    } catch (error) {
-      handler?.onError?.(error as Error);
-      throw error;
+        handler?.onComplete?.(fullText);
+        return fullText;
+      } catch (error) {
+        handler?.onError?.(error as Error);
+        throw error;
+      }
+    } finally {
+      clearTimeout(timeout);
+      handler?.signal?.removeEventListener('abort', onAbort);
     }
   }
 
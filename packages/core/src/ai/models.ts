@@ -0,0 +1,94 @@
+/**
+ * Model Names
+ * Known chat models per provider, used to reject a mistyped --model or
+ * models.<command> before any request is made
+ */
+
+import type { CVConfig } from '@cv-git/shared';
+import { ConfigError } from '../errors.js';
+import { OPENROUTER_MODELS } from './openrouter.js';
+
+export type ModelProvider = 'anthropic' | 'openrouter' | 'gemini' | 'azure' | 'ollama' | 'openai-compatible';
+
+/**
+ * The provider a createAIManager-based command talks to
+ */
+export function getAIProvider(config: CVConfig): ModelProvider {
+  const provider = config.ai.provider;
+  return provider === 'azure' || provider === 'gemini' || provider === 'openai-compatible' ? provider : 'anthropic';
+}
+
+/** Anthropic API models; dated snapshots (e.g. claude-3-5-sonnet-20241022) are accepted too */
+export const ANTHROPIC_MODELS = [
+  'claude-opus-4-1',
+  'claude-opus-4',
+  'claude-sonnet-4-5',
+  'claude-sonnet-4',
+  'claude-3-7-sonnet',
+  'claude-3-5-sonnet',
+  'claude-3-5-haiku',
+  'claude-3-opus',
+  'claude-3-haiku'
+];
+
+/** Gemini API models; versioned variants (e.g. gemini-1.5-pro-002) are accepted too */
+export const GEMINI_MODELS = [
+  'gemini-2.5-pro',
+  'gemini-2.5-flash',
+  'gemini-2.0-flash',
+  'gemini-1.5-pro',
+  'gemini-1.5-flash'
+];
+
+/**
+ * Models known for a provider. Empty for providers whose names are
+ * user-defined (Azure deployments, local Ollama tags, gateway models).
+ */
+export function getKnownModels(provider: ModelProvider): string[] {
+  switch (provider) {
+    case 'anthropic':
+      return ANTHROPIC_MODELS;
+    case 'gemini':
+      return GEMINI_MODELS;
+    case 'openrouter':
+      return Object.keys(OPENROUTER_MODELS);
+    default:
+      return [];
+  }
+}
+
+/**
+ * Whether a model name is valid for a provider
+ */
+export function isKnownModel(provider: ModelProvider, model: string): boolean {
+  switch (provider) {
+    case 'anthropic':
+      return ANTHROPIC_MODELS.some(known => model === known || new RegExp(`^${known}-(\\d{8}|latest)$`).test(model));
+    case 'gemini': {
+      const name = model.replace(/^models\//, '');
+      return GEMINI_MODELS.some(known => name === known || name.startsWith(`${known}-`));
+    }
+    case 'openrouter':
+      // Full vendor/model IDs are passed through to OpenRouter as is
+      return model in OPENROUTER_MODELS || /^[\w.-]+\/[\w.:-]+$/.test(model);
+    default:
+      // Azure deployments, Ollama tags and gateway models are checked when the client connects
+      return model.trim().length > 0;
+  }
+}
+
+/**
+ * Return the model if the provider knows it; otherwise throw a ConfigError
+ * listing the models that are
+ */
+export function validateModel(provider: ModelProvider, model: string): string {
+  if (isKnownModel(provider, model)) {
+    return model;
+  }
+
+  const known = getKnownModels(provider);
+  throw new ConfigError(
+    `Unknown ${provider} model: ${model}\nKnown models: ${known.join(', ')}`,
+    { provider, model, known }
+  );
+}
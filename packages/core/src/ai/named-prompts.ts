@@ -0,0 +1,78 @@
+/**
+ * Named Prompts
+ * Reusable prompt templates kept in `.cv/prompts/<name>.md`, so a team can
+ * version its review and task instructions with the code instead of
+ * retyping them. `--prompt <name>` sends one as the custom system prompt,
+ * with the same {{variable}} placeholders as config `prompts.<command>`.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { getCVDir } from '@cv-git/shared';
+
+export const PROMPTS_DIR = 'prompts';
+
+export interface NamedPrompt {
+  /** File name without .md, as given to --prompt */
+  name: string;
+  file: string;
+  /** First line of the template, without a Markdown heading marker */
+  description: string;
+}
+
+/**
+ * Directory named prompts are read from
+ */
+export function getPromptsDir(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), PROMPTS_DIR);
+}
+
+/**
+ * The named prompts of a repository, sorted by name. Empty when there is
+ * no .cv/prompts directory.
+ */
+export async function listNamedPrompts(repoRoot: string): Promise<NamedPrompt[]> {
+  const dir = getPromptsDir(repoRoot);
+  let entries: string[];
+  try {
+    entries = await fs.readdir(dir);
+  } catch (error: any) {
+    if (error.code === 'ENOENT') return [];
+    throw error;
+  }
+
+  const prompts: NamedPrompt[] = [];
+  for (const entry of entries.filter(e => e.endsWith('.md')).sort()) {
+    const file = path.join(dir, entry);
+    const content = await fs.readFile(file, 'utf-8');
+    prompts.push({ name: entry.slice(0, -3), file, description: describePrompt(content) });
+  }
+  return prompts;
+}
+
+/**
+ * The template of a named prompt. An unknown name fails with the names
+ * that exist.
+ */
+export async function loadNamedPrompt(repoRoot: string, name: string): Promise<string> {
+  // A name, not a path: .cv/prompts is the only place prompts come from
+  if (!/^[\w.-]+$/.test(name) || name.startsWith('.')) {
+    throw new Error(`Invalid prompt name "${name}": use the file name in .cv/prompts without .md`);
+  }
+
+  const file = path.join(getPromptsDir(repoRoot), `${name.replace(/\.md$/, '')}.md`);
+  try {
+    return await fs.readFile(file, 'utf-8');
+  } catch (error: any) {
+    if (error.code !== 'ENOENT') throw error;
+    const available = (await listNamedPrompts(repoRoot)).map(p => p.name);
+    throw new Error(available.length > 0
+      ? `No prompt named "${name}" in .cv/prompts (available: ${available.join(', ')})`
+      : `No prompt named "${name}": add one as .cv/prompts/${name}.md`);
+  }
+}
+
+function describePrompt(content: string): string {
+  const first = content.split('\n').find(line => line.trim() !== '') ?? '';
+  return first.trim().replace(/^#+\s*/, '');
+}
@@ -189,6 +189,7 @@ export class OllamaClient implements AIClient {
           temperature: this.temperature,
         },
       }),
+      signal: handler?.signal,
     });
 
     if (!response.ok) {
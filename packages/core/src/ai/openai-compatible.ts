@@ -0,0 +1,244 @@
+/**
+ * OpenAI-Compatible Chat Client
+ * Chat completions against any API that speaks the OpenAI protocol at a
+ * base URL: LiteLLM, vLLM, Groq, Together and the like. The model name is
+ * passed through verbatim. OpenRouter is a preset of this client (see
+ * openrouter.ts).
+ */
+
+import OpenAI from 'openai';
+import { getMaxRetryAttempts } from '@cv-git/shared';
+import { AIClient, AIMessage, AIStreamHandler } from './types.js';
+import { proxyClientOptions } from '../config/proxy.js';
+import { recordCompletionUsage } from '../usage/index.js';
+
+export interface OpenAICompatibleOptions {
+  /** Base URL of the API, including the version path (e.g. http://localhost:4000/v1) */
+  baseUrl: string;
+  /** Sent as a bearer token; optional for gateways without authentication */
+  apiKey?: string;
+  /** Model name, sent to the gateway as given */
+  model: string;
+  maxTokens?: number;
+  temperature?: number;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+  /** Headers sent with every request */
+  headers?: Record<string, string>;
+  /** Provider name reported for usage and fallbacks (default: openai-compatible) */
+  provider?: string;
+}
+
+/**
+ * Well-known gateways offered by `cv auth setup openai-compatible`; any other
+ * base URL works too
+ */
+export const OPENAI_COMPATIBLE_PRESETS: Record<string, { name: string; baseUrl: string }> = {
+  openrouter: { name: 'OpenRouter', baseUrl: 'https://openrouter.ai/api/v1' },
+  groq: { name: 'Groq', baseUrl: 'https://api.groq.com/openai/v1' },
+  together: { name: 'Together AI', baseUrl: 'https://api.together.xyz/v1' },
+  litellm: { name: 'LiteLLM proxy', baseUrl: 'http://localhost:4000/v1' },
+  vllm: { name: 'vLLM', baseUrl: 'http://localhost:8000/v1' }
+};
+
+/**
+ * The SDK refuses to start without a key; gateways without authentication ignore it
+ */
+const NO_API_KEY = 'none';
+
+export class OpenAICompatibleClient implements AIClient {
+  protected client: OpenAI;
+  protected model: string;
+  private provider: string;
+  private maxTokens: number;
+  private temperature: number;
+
+  constructor(options: OpenAICompatibleOptions) {
+    this.client = new OpenAI({
+      apiKey: options.apiKey || NO_API_KEY,
+      baseURL: normalizeBaseUrl(options.baseUrl),
+      maxRetries: (options.maxRetryAttempts ?? getMaxRetryAttempts()) - 1,
+      defaultHeaders: options.headers,
+      ...proxyClientOptions(),
+    });
+
+    this.model = options.model;
+    this.provider = options.provider || 'openai-compatible';
+    this.maxTokens = options.maxTokens || 4096;
+    this.temperature = options.temperature || 0.7;
+  }
+
+  /**
+   * Get the provider name
+   */
+  getProvider(): string {
+    return this.provider;
+  }
+
+  /**
+   * Get the current model being used
+   */
+  getModel(): string {
+    return this.model;
+  }
+
+  /**
+   * Set a different model
+   */
+  setModel(model: string): void {
+    this.model = model;
+  }
+
+  /**
+   * Check if the client is ready (endpoint reachable, API key valid)
+   */
+  async isReady(): Promise<boolean> {
+    try {
+      await this.client.models.list();
+      return true;
+    } catch {
+      return false;
+    }
+  }
+
+  /**
+   * Chat completion (non-streaming)
+   */
+  async chat(messages: AIMessage[], systemPrompt?: string): Promise<string> {
+    const response = await this.client.chat.completions.create({
+      model: this.model,
+      messages: toOpenAIMessages(messages, systemPrompt),
+      max_tokens: this.maxTokens,
+      temperature: this.temperature,
+    });
+
+    const text = response.choices[0]?.message?.content || '';
+    recordCompletionUsage(this.provider, this.model, promptText(messages, systemPrompt), text, {
+      inputTokens: response.usage?.prompt_tokens,
+      outputTokens: response.usage?.completion_tokens
+    });
+    return text;
+  }
+
+  /**
+   * Chat completion with streaming
+   */
+  async chatStream(
+    messages: AIMessage[],
+    systemPrompt?: string,
+    handler?: AIStreamHandler
+  ): Promise<string> {
+    let fullText = '';
+
+    try {
+      const stream = await this.client.chat.completions.create({
+        model: this.model,
+        messages: toOpenAIMessages(messages, systemPrompt),
+        max_tokens: this.maxTokens,
+        temperature: this.temperature,
+        stream: true,
+      }, { signal: handler?.signal });
+
+      let usage: OpenAI.CompletionUsage | undefined;
+      for await (const chunk of stream) {
+        usage = chunk.usage ?? usage;
+        const token = chunk.choices[0]?.delta?.content || '';
+        if (token) {
+          fullText += token;
+          handler?.onToken?.(token);
+        }
+      }
+
+      recordCompletionUsage(this.provider, this.model, promptText(messages, systemPrompt), fullText, {
+        inputTokens: usage?.prompt_tokens,
+        outputTokens: usage?.completion_tokens
+      });
+      handler?.onComplete?.(fullText);
+      return fullText;
+
+    } catch (error) {
+      handler?.onError?.(error as Error);
+      throw error;
+    }
+  }
+
+  /**
+   * Simple completion (single prompt)
+   */
+  async complete(prompt: string, handler?: AIStreamHandler): Promise<string> {
+    return this.chatStream(
+      [{ role: 'user', content: prompt }],
+      undefined,
+      handler
+    );
+  }
+}
+
+/**
+ * Create a client for an OpenAI-compatible API
+ */
+export function createOpenAICompatibleClient(options: OpenAICompatibleOptions): OpenAICompatibleClient {
+  return new OpenAICompatibleClient(options);
+}
+
+/**
+ * Base URL without trailing slashes, so `${baseUrl}/models` is well formed
+ */
+export function normalizeBaseUrl(baseUrl: string): string {
+  return baseUrl.trim().replace(/\/+$/, '');
+}
+
+/**
+ * IDs of the models an OpenAI-compatible API serves (GET {baseUrl}/models).
+ * Throws when the endpoint is unreachable, rejects the key or does not
+ * answer like an OpenAI API, so setup can report it.
+ */
+export async function listOpenAICompatibleModels(
+  baseUrl: string,
+  apiKey?: string,
+  timeoutMs = 10_000
+): Promise<string[]> {
+  const url = `${normalizeBaseUrl(baseUrl)}/models`;
+  const response = await fetch(url, {
+    headers: apiKey ? { Authorization: `Bearer ${apiKey}` } : {},
+    signal: AbortSignal.timeout(timeoutMs)
+  });
+
+  if (!response.ok) {
+    const detail = response.status === 401 || response.status === 403 ? ' (check the API key)' : '';
+    throw new Error(`${url} returned HTTP ${response.status}${detail}`);
+  }
+
+  const body = await response.json().catch(() => null) as { data?: Array<{ id?: unknown }> } | null;
+  const models = body?.data;
+  if (!Array.isArray(models)) {
+    throw new Error(`${url} did not return an OpenAI-style model list; is the base URL right (usually ends in /v1)?`);
+  }
+  return models
+    .map(model => model.id)
+    .filter((id): id is string => typeof id === 'string');
+}
+
+function toOpenAIMessages(messages: AIMessage[], systemPrompt?: string): OpenAI.ChatCompletionMessageParam[] {
+  const openaiMessages: OpenAI.ChatCompletionMessageParam[] = [];
+
+  if (systemPrompt) {
+    openaiMessages.push({ role: 'system', content: systemPrompt });
+  }
+
+  for (const msg of messages) {
+    openaiMessages.push({
+      role: msg.role === 'user' ? 'user' : 'assistant',
+      content: msg.content,
+    });
+  }
+
+  return openaiMessages;
+}
+
+/**
+ * Prompt text of a request, for estimating tokens when the API reports none
+ */
+function promptText(messages: AIMessage[], systemPrompt?: string): string {
+  return [systemPrompt ?? '', ...messages.map(m => m.content)].join('\n');
+}
@@ -1,16 +1,19 @@
 /**
  * OpenRouter Chat Client
- * OpenAI-compatible API for accessing various models via OpenRouter
+ * OpenAI-compatible API for accessing various models via OpenRouter,
+ * as a preset of the OpenAI-compatible client
  */
 
-import OpenAI from 'openai';
-import { AIClient, AIMessage, AIStreamHandler, RECOMMENDED_MODELS } from './types.js';
+import { AIMessage, AIStreamHandler, RECOMMENDED_MODELS } from './types.js';
+import { OpenAICompatibleClient, OPENAI_COMPATIBLE_PRESETS } from './openai-compatible.js';
 
 export interface OpenRouterOptions {
   apiKey: string;
   model?: string;
   maxTokens?: number;
   temperature?: number;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
 }
 
 // Re-export for backwards compatibility
@@ -54,42 +57,30 @@ export const OPENROUTER_MODELS = {
 
 export type ModelAlias = keyof typeof OPENROUTER_MODELS;
 
-export class OpenRouterClient implements AIClient {
-  private client: OpenAI;
-  private model: string;
-  private maxTokens: number;
-  private temperature: number;
+export const OPENROUTER_BASE_URL = OPENAI_COMPATIBLE_PRESETS.openrouter.baseUrl;
 
+/**
+ * OpenRouter preset of the OpenAI-compatible client: its base URL and
+ * attribution headers, and model aliases resolved to OpenRouter IDs
+ */
+export class OpenRouterClient extends OpenAICompatibleClient {
   constructor(options: OpenRouterOptions) {
-    this.client = new OpenAI({
+    // Resolve model alias or use directly
+    const modelInput = options.model || 'claude-sonnet-4-5';
+
+    super({
+      baseUrl: OPENROUTER_BASE_URL,
       apiKey: options.apiKey,
-      baseURL: 'https://openrouter.ai/api/v1',
-      defaultHeaders: {
+      model: OPENROUTER_MODELS[modelInput as ModelAlias] || modelInput,
+      maxTokens: options.maxTokens || 128000,
+      temperature: options.temperature,
+      maxRetryAttempts: options.maxRetryAttempts,
+      headers: {
         'HTTP-Referer': 'https://github.com/anthropics/cv-git',
         'X-Title': 'cv-git',
       },
+      provider: 'openrouter',
     });
-
-    // Resolve model alias or use directly
-    const modelInput = options.model || 'claude-sonnet-4-5';
-    this.model = OPENROUTER_MODELS[modelInput as ModelAlias] || modelInput;
-
-    this.maxTokens = options.maxTokens || 128000;
-    this.temperature = options.temperature || 0.7;
-  }
-
-  /**
-   * Get the provider name
-   */
-  getProvider(): string {
-    return 'openrouter';
-  }
-
-  /**
-   * Get the current model being used
-   */
-  getModel(): string {
-    return this.model;
   }
 
   /**
@@ -98,106 +89,6 @@ export class OpenRouterClient implements AIClient {
   setModel(model: string): void {
     this.model = OPENROUTER_MODELS[model as ModelAlias] || model;
   }
-
-  /**
-   * Check if the client is ready (API key valid)
-   */
-  async isReady(): Promise<boolean> {
-    try {
-      // Make a simple API call to verify the key
-      await this.client.models.list();
-      return true;
-    } catch {
-      return false;
-    }
-  }
-
-  /**
-   * Chat completion (non-streaming)
-   */
-  async chat(messages: AIMessage[], systemPrompt?: string): Promise<string> {
-    const openaiMessages: OpenAI.ChatCompletionMessageParam[] = [];
-
-    if (systemPrompt) {
-      openaiMessages.push({ role: 'system', content: systemPrompt });
-    }
-
-    for (const msg of messages) {
-      openaiMessages.push({
-        role: msg.role === 'user' ? 'user' : 'assistant',
-        content: msg.content,
-      });
-    }
-
-    const response = await this.client.chat.completions.create({
-      model: this.model,
-      messages: openaiMessages,
-      max_tokens: this.maxTokens,
-      temperature: this.temperature,
-    });
-
-    return response.choices[0]?.message?.content || '';
-  }
-
-  /**
-   * Chat completion with streaming
-   */
-  async chatStream(
-    messages: AIMessage[],
-    systemPrompt?: string,
-    handler?: AIStreamHandler
-  ): Promise<string> {
-    const openaiMessages: OpenAI.ChatCompletionMessageParam[] = [];
-
-    if (systemPrompt) {
-      openaiMessages.push({ role: 'system', content: systemPrompt });
-    }
-
-    for (const msg of messages) {
-      openaiMessages.push({
-        role: msg.role === 'user' ? 'user' : 'assistant',
-        content: msg.content,
-      });
-    }
-
-    let fullText = '';
-
-    try {
-      const stream = await this.client.chat.completions.create({
-        model: this.model,
-        messages: openaiMessages,
-        max_tokens: this.maxTokens,
-        temperature: this.temperature,
-        stream: true,
-      });
-
-      for await (const chunk of stream) {
-        const token = chunk.choices[0]?.delta?.content || '';
-        if (token) {
-          fullText += token;
-          handler?.onToken?.(token);
-        }
-      }
-
-      handler?.onComplete?.(fullText);
-      return fullText;
-
-    } catch (error) {
-      handler?.onError?.(error as Error);
-      throw error;
-    }
-  }
-
-  /**
-   * Simple completion (single prompt)
-   */
-  async complete(prompt: string, handler?: AIStreamHandler): Promise<string> {
-    return this.chatStream(
-      [{ role: 'user', content: prompt }],
-      undefined,
-      handler
-    );
-  }
 }
 
 /**
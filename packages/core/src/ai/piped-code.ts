@@ -0,0 +1,64 @@
+/**
+ * Piped Code
+ * Turns code read from stdin (`cv explain -`, `cv review -`) into the
+ * context and diff the prompts expect. The piped content is one file with
+ * no path, so its language comes from a --language hint and the index is
+ * not consulted.
+ */
+
+import { Context, detectLanguage } from '@cv-git/shared';
+
+/** File name piped code is shown under in prompts and findings */
+export const STDIN_FILE = '<stdin>';
+
+/**
+ * Normalize a --language hint: an extension (go, .py, tsx) or a language name
+ */
+export function resolveLanguageHint(hint?: string): string {
+  const name = hint?.trim().toLowerCase().replace(/^\./, '');
+  if (!name) return '';
+  const detected = detectLanguage(`${STDIN_FILE}.${name}`);
+  return detected === 'unknown' ? name : detected;
+}
+
+/**
+ * Context holding the piped code as a single chunk, in place of search results
+ */
+export function buildPipedCodeContext(code: string, language: string = ''): Context {
+  const text = code.replace(/\n$/, '');
+  const endLine = Math.max(1, text.split('\n').length);
+  return {
+    chunks: [{
+      id: `${STDIN_FILE}:1:${endLine}`,
+      score: 1,
+      payload: {
+        id: `${STDIN_FILE}:1:${endLine}`,
+        file: STDIN_FILE,
+        language,
+        startLine: 1,
+        endLine,
+        text,
+        imports: [],
+        lastModified: Date.now()
+      }
+    }],
+    symbols: [],
+    files: []
+  };
+}
+
+/**
+ * The piped code as a diff adding a new file, so reviews (and --diff line
+ * numbers) cover every line
+ */
+export function buildPipedCodeDiff(code: string): string {
+  const lines = code.replace(/\n$/, '').split('\n');
+  return [
+    `diff --git a/${STDIN_FILE} b/${STDIN_FILE}`,
+    'new file mode 100644',
+    '--- /dev/null',
+    `+++ b/${STDIN_FILE}`,
+    `@@ -0,0 +1,${lines.length} @@`,
+    ...lines.map(line => `+${line}`)
+  ].join('\n') + '\n';
+}
@@ -0,0 +1,60 @@
+/**
+ * Provider Fallback
+ * Runs a request against an ordered chain of providers (config ai.providers
+ * and embedding.providers). When a provider is down (5xx, timeout,
+ * unreachable) the next one gets the request; any other error comes from
+ * the request itself and is thrown as is.
+ */
+
+import { isProviderOutage } from '@cv-git/shared';
+
+/**
+ * Which provider answered a request, e.g. for verbose logging
+ */
+export interface ProviderServed {
+  provider: string;
+  model: string;
+  /** Providers that were down, in the order they were tried */
+  failed: Array<{ provider: string; error: Error }>;
+}
+
+export interface ProviderCandidate<C> {
+  provider: string;
+  model: string;
+  client: C;
+}
+
+export interface TryProvidersOptions {
+  /** Checked before falling back; false keeps the error (e.g. a stream already printed tokens) */
+  canFallBack?: () => boolean;
+  /** Called with the provider that answered */
+  onServed?: (info: ProviderServed) => void;
+}
+
+/**
+ * Send a request to the first provider, moving down the chain while
+ * providers are down. The last provider's error is thrown when all are.
+ */
+export async function tryProviders<C, T>(
+  candidates: ProviderCandidate<C>[],
+  request: (candidate: ProviderCandidate<C>) => Promise<T>,
+  options: TryProvidersOptions = {}
+): Promise<T> {
+  const failed: ProviderServed['failed'] = [];
+
+  for (const [i, candidate] of candidates.entries()) {
+    try {
+      const result = await request(candidate);
+      options.onServed?.({ provider: candidate.provider, model: candidate.model, failed });
+      return result;
+    } catch (error: any) {
+      const last = i === candidates.length - 1;
+      if (last || !isProviderOutage(error) || options.canFallBack?.() === false) {
+        throw error;
+      }
+      failed.push({ provider: candidate.provider, error });
+    }
+  }
+
+  throw new Error('No provider to send the request to');
+}
@@ -0,0 +1,260 @@
+/**
+ * Refactoring
+ * Builds the prompt behind `cv refactor`, splices the model's rewrite back
+ * into the file, and renders the change as a unified diff for review
+ */
+
+import { REDACTED_SECRET } from '../security/redact.js';
+
+/** Numbered placeholders of indexed redaction (see indexedSecretPlaceholder) */
+const INDEXED_PLACEHOLDER = /<REDACTED_SECRET_(\d+)>/g;
+
+/** Lines of unchanged context around each diff hunk (same as git) */
+export const DEFAULT_DIFF_CONTEXT = 3;
+
+/** Above this many line pairs (a 4MB table) the changed region is shown as a full replacement */
+const MAX_LCS_CELLS = 1_000_000;
+
+export interface RefactorTarget {
+  /** Repo-relative path */
+  file: string;
+  language: string;
+  /** Current file contents */
+  content: string;
+  /** Restrict the refactor to one function or method */
+  symbol?: { name: string; kind: string; startLine: number; endLine: number };
+}
+
+/**
+ * The code the model is asked to rewrite: the symbol's lines, or the whole file
+ */
+export function getRefactorSource(target: RefactorTarget): string {
+  if (!target.symbol) {
+    return target.content;
+  }
+  return target.content.split('\n').slice(target.symbol.startLine - 1, target.symbol.endLine).join('\n');
+}
+
+/**
+ * Prompt asking for the refactored code as a single fenced block
+ */
+export function buildRefactorPrompt(instruction: string, target: RefactorTarget, source: string = getRefactorSource(target)): string {
+  const { symbol } = target;
+  let prompt = `You are an expert software engineer refactoring existing code.\n\n`;
+  prompt += `## Instruction\n\n${instruction}\n\n`;
+
+  if (symbol) {
+    prompt += `## ${symbol.kind} \`${symbol.name}\` (${target.file}, lines ${symbol.startLine}-${symbol.endLine})\n\n`;
+  } else {
+    prompt += `## ${target.file}\n\n`;
+  }
+  prompt += `\`\`\`${target.language}\n${source}\n\`\`\`\n\n`;
+
+  prompt += `## Requirements\n\n`;
+  prompt += `- Change only what the instruction needs; keep everything else exactly as it is, including comments and formatting.\n`;
+  prompt += `- Preserve behaviour unless the instruction asks to change it.\n`;
+  if (symbol) {
+    prompt += `- Return the complete ${symbol.kind} \`${symbol.name}\` with its original indentation. ` +
+      `Keep its name and signature unless the instruction requires otherwise, and do not add code outside it.\n`;
+  } else {
+    prompt += `- Return the complete file, not just the changed parts.\n`;
+  }
+  prompt += `- Keep each <REDACTED_SECRET_n> placeholder exactly once, unchanged, where its value is used.\n\n`;
+  prompt += `Respond with ONLY the refactored code in a single fenced code block.`;
+
+  return prompt;
+}
+
+/**
+ * Code inside the response's fenced block. The block runs to the last fence,
+ * so code that itself contains backticks survives.
+ */
+export function extractRefactoredCode(response: string): string {
+  const open = response.match(/```[\w+#.-]*\n/);
+  if (!open) {
+    return response;
+  }
+
+  const bodyStart = open.index! + open[0].length;
+  const close = response.lastIndexOf('\n```');
+  return close >= bodyStart - 1 ? response.slice(bodyStart, close + 1) : response.slice(bodyStart);
+}
+
+/**
+ * Put secrets masked before prompting (indexed redaction) back into the
+ * model's code, each into its own numbered placeholder. Throws when a
+ * placeholder is missing, repeated, unknown or un-numbered, rather than
+ * writing placeholders (or a secret in the wrong place) into the file.
+ */
+export function restoreRedactedSecrets(code: string, secrets: string[]): string {
+  const seen = new Set<number>();
+  const problems: string[] = [];
+  for (const match of code.matchAll(INDEXED_PLACEHOLDER)) {
+    const index = Number(match[1]);
+    if (index < 1 || index > secrets.length) {
+      problems.push(`unknown placeholder ${match[0]}`);
+    } else if (seen.has(index)) {
+      problems.push(`${match[0]} appears more than once`);
+    }
+    seen.add(index);
+  }
+  for (let index = 1; index <= secrets.length; index++) {
+    if (!seen.has(index)) problems.push(`<REDACTED_SECRET_${index}> is missing`);
+  }
+  if (code.includes(REDACTED_SECRET)) {
+    problems.push(`${REDACTED_SECRET} has no number`);
+  }
+
+  if (problems.length > 0) {
+    throw new Error(
+      `The secrets masked in the prompt cannot be put back (${problems.join('; ')}); ` +
+      'rerun with --no-redact to refactor this code'
+    );
+  }
+  return code.replace(INDEXED_PLACEHOLDER, (_placeholder, index: string) => secrets[Number(index) - 1]);
+}
+
+/**
+ * New file contents with the refactored code in place of the target
+ */
+export function applyRefactor(target: RefactorTarget, code: string): string {
+  const endsWithNewline = target.content.endsWith('\n');
+  const trimmed = code.replace(/\n+$/, '');
+
+  if (!target.symbol) {
+    return endsWithNewline ? trimmed + '\n' : trimmed;
+  }
+
+  const lines = target.content.split('\n');
+  lines.splice(target.symbol.startLine - 1, target.symbol.endLine - target.symbol.startLine + 1, ...trimmed.split('\n'));
+  return lines.join('\n');
+}
+
+type DiffOp = { type: 'equal' | 'remove' | 'add'; line: string };
+
+/**
+ * Line edit script from a to b (longest common subsequence)
+ */
+function diffLines(a: string[], b: string[]): DiffOp[] {
+  // Unchanged head and tail need no table
+  let start = 0;
+  while (start < a.length && start < b.length && a[start] === b[start]) start++;
+  let endA = a.length;
+  let endB = b.length;
+  while (endA > start && endB > start && a[endA - 1] === b[endB - 1]) {
+    endA--;
+    endB--;
+  }
+
+  const ops: DiffOp[] = a.slice(0, start).map(line => ({ type: 'equal' as const, line }));
+  const midA = a.slice(start, endA);
+  const midB = b.slice(start, endB);
+  const n = midA.length;
+  const m = midB.length;
+
+  if (n * m > MAX_LCS_CELLS) {
+    ops.push(...midA.map(line => ({ type: 'remove' as const, line })));
+    ops.push(...midB.map(line => ({ type: 'add' as const, line })));
+  } else {
+    // lcs[i][j] = LCS length of midA[i..] and midB[j..]
+    const width = m + 1;
+    const lcs = new Uint32Array((n + 1) * width);
+    for (let i = n - 1; i >= 0; i--) {
+      for (let j = m - 1; j >= 0; j--) {
+        lcs[i * width + j] = midA[i] === midB[j]
+          ? lcs[(i + 1) * width + j + 1] + 1
+          : Math.max(lcs[(i + 1) * width + j], lcs[i * width + j + 1]);
+      }
+    }
+
+    let i = 0;
+    let j = 0;
+    while (i < n && j < m) {
+      if (midA[i] === midB[j]) {
+        ops.push({ type: 'equal', line: midA[i] });
+        i++;
+        j++;
+      } else if (lcs[(i + 1) * width + j] >= lcs[i * width + j + 1]) {
+        ops.push({ type: 'remove', line: midA[i++] });
+      } else {
+        ops.push({ type: 'add', line: midB[j++] });
+      }
+    }
+    while (i < n) ops.push({ type: 'remove', line: midA[i++] });
+    while (j < m) ops.push({ type: 'add', line: midB[j++] });
+  }
+
+  ops.push(...a.slice(endA).map(line => ({ type: 'equal' as const, line })));
+  return ops;
+}
+
+/**
+ * Lines of a file, without the empty string after a final newline
+ */
+function splitLines(text: string): string[] {
+  const lines = text.split('\n');
+  if (lines[lines.length - 1] === '') lines.pop();
+  return lines;
+}
+
+/**
+ * Unified diff between two versions of a file ('' when they are identical)
+ */
+export function createUnifiedDiff(
+  file: string,
+  before: string,
+  after: string,
+  context: number = DEFAULT_DIFF_CONTEXT
+): string {
+  if (before === after) {
+    return '';
+  }
+
+  const ops = diffLines(splitLines(before), splitLines(after));
+  const changes = ops.map((op, i) => (op.type === 'equal' ? -1 : i)).filter(i => i >= 0);
+  if (changes.length === 0) {
+    return '';
+  }
+
+  // Changes closer than two contexts apart share a hunk
+  const groups: Array<[number, number]> = [];
+  for (const index of changes) {
+    const last = groups[groups.length - 1];
+    if (last && index - last[1] <= context * 2 + 1) {
+      last[1] = index;
+    } else {
+      groups.push([index, index]);
+    }
+  }
+
+  // Line numbers before each op
+  const oldLineAt: number[] = [];
+  const newLineAt: number[] = [];
+  let oldLine = 1;
+  let newLine = 1;
+  for (const op of ops) {
+    oldLineAt.push(oldLine);
+    newLineAt.push(newLine);
+    if (op.type !== 'add') oldLine++;
+    if (op.type !== 'remove') newLine++;
+  }
+
+  const out = [`--- a/${file}`, `+++ b/${file}`];
+  for (const [first, last] of groups) {
+    const from = Math.max(0, first - context);
+    const to = Math.min(ops.length, last + context + 1);
+    const hunk = ops.slice(from, to);
+    const oldCount = hunk.filter(op => op.type !== 'add').length;
+    const newCount = hunk.filter(op => op.type !== 'remove').length;
+    // An empty side is numbered by the line before it, as git does
+    const oldStart = oldCount === 0 ? oldLineAt[from] - 1 : oldLineAt[from];
+    const newStart = newCount === 0 ? newLineAt[from] - 1 : newLineAt[from];
+
+    out.push(`@@ -${oldStart},${oldCount} +${newStart},${newCount} @@`);
+    for (const op of hunk) {
+      out.push((op.type === 'add' ? '+' : op.type === 'remove' ? '-' : ' ') + op.line);
+    }
+  }
+
+  return out.join('\n') + '\n';
+}
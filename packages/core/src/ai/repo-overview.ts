@@ -0,0 +1,303 @@
+/**
+ * Repository Overview
+ * Picks a diverse, central sample of indexed files from chunk metadata and
+ * builds the prompt behind `cv summarize`. Overviews are cached per indexed
+ * commit and depth in .cv/overview.json.
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import { CodeChunkPayload, getCVDir } from '@cv-git/shared';
+
+export type OverviewDepth = 'brief' | 'standard' | 'detailed';
+
+export const OVERVIEW_DEPTHS: OverviewDepth[] = ['brief', 'standard', 'detailed'];
+
+/**
+ * How much of the repository each depth shows the model
+ */
+export const OVERVIEW_DEPTH_LIMITS: Record<OverviewDepth, { files: number; chunksPerFile: number; maxChunkChars: number }> = {
+  brief: { files: 12, chunksPerFile: 1, maxChunkChars: 800 },
+  standard: { files: 30, chunksPerFile: 2, maxChunkChars: 1200 },
+  detailed: { files: 60, chunksPerFile: 3, maxChunkChars: 1600 }
+};
+
+const OVERVIEW_CACHE_FILE = 'overview.json';
+
+/** File names (without extension) that usually start a program or export a package */
+const ENTRY_POINT_NAMES = new Set(['index', 'main', 'cli', 'app', 'server', '__main__', '__init__', 'mod', 'lib']);
+
+/** Symbol kinds that describe a component better than a single function */
+const KIND_PRIORITY: Record<string, number> = { class: 0, interface: 1, struct: 1, type: 2, enum: 3, function: 4, method: 5 };
+
+const TEST_FILE = /(^|\/)(tests?|__tests__|spec)\/|\.(test|spec)\.[^/]+$|_test\.[^/]+$/;
+
+/**
+ * A file chosen for the overview
+ */
+export interface OverviewFile {
+  file: string;
+  /** Directory the file is grouped under (first two path segments) */
+  module: string;
+  language: string;
+  /** Other indexed files that import this one */
+  importedBy: number;
+  entryPoint: boolean;
+  /** Named symbols defined in the file, most descriptive first */
+  symbols: string[];
+  /** Chunks shown to the model */
+  chunks: CodeChunkPayload[];
+}
+
+export interface OverviewSample {
+  /** Every module with its indexed file count, largest first */
+  modules: Array<{ name: string; files: number }>;
+  files: OverviewFile[];
+  totalFiles: number;
+  totalChunks: number;
+}
+
+/**
+ * A cached overview
+ */
+export interface CachedOverview {
+  /** Indexed commit the overview was generated from */
+  commit: string;
+  depth: OverviewDepth;
+  model?: string;
+  generatedAt: string;
+  overview: string;
+}
+
+/**
+ * Module a file belongs to: its first two directories ('.' for top-level files)
+ */
+export function getOverviewModule(file: string): string {
+  const dirs = file.split('/').slice(0, -1);
+  return dirs.length === 0 ? '.' : dirs.slice(0, 2).join('/');
+}
+
+function stripExtension(file: string): string {
+  return file.replace(/\.[^./]+$/, '');
+}
+
+/**
+ * Count, for each indexed file, how many other indexed files import it.
+ * Relative specifiers are resolved against the importing file; dotted module
+ * names (Python, Java) are matched against the end of indexed paths.
+ */
+export function countImporters(imports: Map<string, Set<string>>): Map<string, number> {
+  const byKey = new Map<string, string>();
+  for (const file of imports.keys()) {
+    const key = stripExtension(file);
+    byKey.set(key, file);
+    if (path.posix.basename(key) === 'index' || path.posix.basename(key) === '__init__') {
+      byKey.set(path.posix.dirname(key), file);
+    }
+  }
+
+  const importers = new Map<string, Set<string>>();
+  for (const [file, specifiers] of imports) {
+    for (const specifier of specifiers) {
+      let target: string | undefined;
+      if (specifier.startsWith('.')) {
+        target = byKey.get(stripExtension(path.posix.normalize(path.posix.join(path.posix.dirname(file), specifier))));
+      } else if (/^[\w]+(\.[\w]+)+$/.test(specifier)) {
+        const suffix = specifier.replace(/\./g, '/');
+        for (const [key, candidate] of byKey) {
+          if (key === suffix || key.endsWith(`/${suffix}`)) {
+            target = candidate;
+            break;
+          }
+        }
+      }
+
+      if (target && target !== file) {
+        if (!importers.has(target)) importers.set(target, new Set());
+        importers.get(target)!.add(file);
+      }
+    }
+  }
+
+  return new Map(Array.from(importers, ([file, from]) => [file, from.size]));
+}
+
+/**
+ * Pick files for an overview: the most imported files and entry points of
+ * each module, taken round-robin across modules so small components aren't
+ * crowded out by the largest one. Test files are only used when nothing else
+ * is indexed.
+ */
+export function selectOverviewSamples(chunks: CodeChunkPayload[], depth: OverviewDepth = 'standard'): OverviewSample {
+  const limits = OVERVIEW_DEPTH_LIMITS[depth];
+
+  const byFile = new Map<string, CodeChunkPayload[]>();
+  for (const chunk of chunks) {
+    if (!chunk.file) continue;
+    if (!byFile.has(chunk.file)) byFile.set(chunk.file, []);
+    byFile.get(chunk.file)!.push(chunk);
+  }
+
+  const imports = new Map<string, Set<string>>();
+  for (const [file, fileChunks] of byFile) {
+    imports.set(file, new Set(fileChunks.flatMap(chunk => chunk.imports ?? [])));
+  }
+  const importers = countImporters(imports);
+
+  const candidates: Array<OverviewFile & { score: number }> = [];
+  for (const [file, fileChunks] of byFile) {
+    const entryPoint = ENTRY_POINT_NAMES.has(path.posix.basename(stripExtension(file)));
+    const importedBy = importers.get(file) ?? 0;
+
+    const ranked = [...fileChunks].sort((a, b) =>
+      Number(!a.symbolName) - Number(!b.symbolName) ||
+      (KIND_PRIORITY[a.symbolKind ?? ''] ?? 9) - (KIND_PRIORITY[b.symbolKind ?? ''] ?? 9) ||
+      Number(!a.docstring) - Number(!b.docstring) ||
+      a.startLine - b.startLine
+    );
+    const symbols = Array.from(new Set(ranked.filter(c => c.symbolName).map(c => c.symbolName!)));
+
+    candidates.push({
+      file,
+      module: getOverviewModule(file),
+      language: fileChunks[0].language,
+      importedBy,
+      entryPoint,
+      symbols,
+      chunks: ranked.slice(0, limits.chunksPerFile).map(chunk => ({
+        ...chunk,
+        text: chunk.text.length > limits.maxChunkChars
+          ? chunk.text.slice(0, limits.maxChunkChars) + '\n... (truncated)'
+          : chunk.text
+      })),
+      // Centrality first; a little credit for defining more symbols breaks ties
+      score: importedBy * 2 + (entryPoint ? 3 : 0) + Math.min(symbols.length, 20) * 0.1
+    });
+  }
+
+  const nonTests = candidates.filter(c => !TEST_FILE.test(c.file));
+  const pool = nonTests.length > 0 ? nonTests : candidates;
+
+  const groups = new Map<string, Array<OverviewFile & { score: number }>>();
+  for (const candidate of pool) {
+    if (!groups.has(candidate.module)) groups.set(candidate.module, []);
+    groups.get(candidate.module)!.push(candidate);
+  }
+  for (const group of groups.values()) {
+    group.sort((a, b) => b.score - a.score || a.file.localeCompare(b.file));
+  }
+
+  // Modules with the most central files go first in each round
+  const ordered = Array.from(groups.entries())
+    .sort(([a, x], [b, y]) => y[0].score - x[0].score || y.length - x.length || a.localeCompare(b))
+    .map(([, group]) => group);
+
+  const selected: OverviewFile[] = [];
+  for (let round = 0; selected.length < limits.files; round++) {
+    const picks = ordered.filter(group => group.length > round).map(group => group[round]);
+    if (picks.length === 0) break;
+    for (const { score, ...pick } of picks.slice(0, limits.files - selected.length)) {
+      selected.push(pick);
+    }
+  }
+
+  const moduleSizes = new Map<string, number>();
+  for (const file of byFile.keys()) {
+    const module = getOverviewModule(file);
+    moduleSizes.set(module, (moduleSizes.get(module) ?? 0) + 1);
+  }
+
+  return {
+    modules: Array.from(moduleSizes, ([name, files]) => ({ name, files }))
+      .sort((a, b) => b.files - a.files || a.name.localeCompare(b.name)),
+    files: selected,
+    totalFiles: byFile.size,
+    totalChunks: chunks.length
+  };
+}
+
+/**
+ * Prompt asking for an architecture overview of the sampled files
+ */
+export function buildRepoOverviewPrompt(sample: OverviewSample, depth: OverviewDepth = 'standard', repoName?: string): string {
+  let prompt = `You are an expert software engineer writing an architecture overview`;
+  prompt += repoName ? ` of the ${repoName} repository` : ` of a repository`;
+  prompt += ` for developers who are new to it.\n\n`;
+  prompt += `The repository has ${sample.totalFiles} indexed files. Below are the modules and a sample of `;
+  prompt += `its most central files: the ones imported by the most other files, and entry points.\n\n`;
+
+  prompt += `## Modules\n\n`;
+  for (const module of sample.modules.slice(0, 40)) {
+    prompt += `- ${module.name} (${module.files} file${module.files === 1 ? '' : 's'})\n`;
+  }
+  if (sample.modules.length > 40) {
+    prompt += `- ... and ${sample.modules.length - 40} more\n`;
+  }
+  prompt += `\n## Sampled Files\n\n`;
+
+  for (const file of sample.files) {
+    const notes = [file.module];
+    if (file.importedBy > 0) notes.push(`imported by ${file.importedBy} file${file.importedBy === 1 ? '' : 's'}`);
+    if (file.entryPoint) notes.push('entry point');
+    prompt += `### ${file.file} (${notes.join(', ')})\n`;
+    if (file.symbols.length > 0) {
+      prompt += `Symbols: ${file.symbols.slice(0, 15).join(', ')}${file.symbols.length > 15 ? ', ...' : ''}\n`;
+    }
+    for (const chunk of file.chunks) {
+      prompt += `\`\`\`${chunk.language}\n${chunk.text}\n\`\`\`\n`;
+    }
+    prompt += `\n`;
+  }
+
+  prompt += `Write the overview in Markdown with these sections:\n`;
+  prompt += `1. Overview - what the project does, in a few sentences\n`;
+  prompt += `2. Main Components - each major module or service and its responsibility\n`;
+  prompt += `3. Key Entry Points - where execution or the public API starts\n`;
+  prompt += `4. How It Fits Together - how the components call each other, naming concrete `;
+  prompt += `classes and functions (e.g. which service handles tokens and who calls it)\n`;
+  prompt += `5. Where To Start Reading - the files a newcomer should open first\n\n`;
+
+  if (depth === 'brief') {
+    prompt += `Keep it short: at most a few lines per section.`;
+  } else if (depth === 'detailed') {
+    prompt += `Be thorough: describe every component, its important types, and the main data flows.`;
+  } else {
+    prompt += `Aim for a page or two.`;
+  }
+  prompt += ` Only describe what the code shows; don't guess at features that aren't there.`;
+  return prompt;
+}
+
+function getOverviewCachePath(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), OVERVIEW_CACHE_FILE);
+}
+
+async function readOverviewCacheFile(repoRoot: string): Promise<CachedOverview[]> {
+  try {
+    const parsed = JSON.parse(await fs.readFile(getOverviewCachePath(repoRoot), 'utf-8'));
+    return Array.isArray(parsed?.overviews) ? parsed.overviews : [];
+  } catch {
+    return [];
+  }
+}
+
+/**
+ * The cached overview for an indexed commit and depth (null if there is none)
+ */
+export async function readCachedOverview(repoRoot: string, commit: string, depth: OverviewDepth): Promise<CachedOverview | null> {
+  const overviews = await readOverviewCacheFile(repoRoot);
+  return overviews.find(o => o.commit === commit && o.depth === depth) ?? null;
+}
+
+/**
+ * Cache an overview. Entries for other commits are dropped since a new index
+ * makes them stale.
+ */
+export async function writeCachedOverview(repoRoot: string, overview: CachedOverview): Promise<void> {
+  const overviews = (await readOverviewCacheFile(repoRoot))
+    .filter(o => o.commit === overview.commit && o.depth !== overview.depth);
+  overviews.push(overview);
+
+  await fs.mkdir(getCVDir(repoRoot), { recursive: true });
+  await fs.writeFile(getOverviewCachePath(repoRoot), JSON.stringify({ overviews }, null, 2), 'utf-8');
+}
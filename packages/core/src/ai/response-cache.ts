@@ -0,0 +1,169 @@
+/**
+ * Response Cache
+ *
+ * Answers to identical requests against an unchanged index. An entry is
+ * keyed on the command, model, normalized query, the commit the index was
+ * last synced to, and the retrieval settings, so a sync to a new commit
+ * makes every older entry a miss (and the next write removes them).
+ *
+ * Storage structure:
+ * .cv/
+ * └── cache/
+ *     └── responses/
+ *         └── {key hash}.json
+ */
+
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { getCVDir } from '@cv-git/shared';
+
+/** How long a cached response is served (config cache.responseTtl, in seconds) */
+export const DEFAULT_RESPONSE_CACHE_TTL_SECONDS = 24 * 60 * 60;
+
+/**
+ * Location of the response cache for a repository (.cv/cache/responses)
+ */
+export function getResponseCacheDir(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), 'cache', 'responses');
+}
+
+/**
+ * Everything a cached answer depends on
+ */
+export interface ResponseCacheKey {
+  command: string;
+  /** Provider and model, e.g. anthropic/claude-sonnet-4 */
+  model: string;
+  query: string;
+  /** Commit the index was last synced to */
+  indexedCommit: string;
+  /** When the index was last written; watch and uncommitted-file syncs change it without a new commit */
+  indexUpdatedAt: string;
+  topK: number;
+  minScore: number;
+  /** Other inputs that change the answer (file scope, custom prompt, ...) */
+  options?: Record<string, unknown>;
+}
+
+export interface CachedResponse<T> {
+  key: ResponseCacheKey;
+  createdAt: string;
+  expiresAt: string;
+  value: T;
+}
+
+/**
+ * Whitespace is collapsed; case is kept, since symbol names are case-sensitive
+ */
+export function normalizeCacheQuery(query: string): string {
+  return query.trim().replace(/\s+/g, ' ');
+}
+
+/**
+ * Hash of a key, the entry's file name
+ */
+export function responseCacheId(key: ResponseCacheKey): string {
+  const normalized = { ...key, query: normalizeCacheQuery(key.query) };
+  return createHash('sha256').update(stableStringify(normalized)).digest('hex');
+}
+
+/**
+ * JSON with object keys sorted, so equal keys hash the same however they were built
+ */
+function stableStringify(value: unknown): string {
+  if (Array.isArray(value)) {
+    return `[${value.map(item => stableStringify(item ?? null)).join(',')}]`;
+  }
+  if (typeof value === 'object' && value !== null) {
+    const entries = Object.entries(value)
+      .filter(([, item]) => item !== undefined)
+      .sort(([a], [b]) => (a < b ? -1 : a > b ? 1 : 0));
+    return `{${entries.map(([k, item]) => `${JSON.stringify(k)}:${stableStringify(item)}`).join(',')}}`;
+  }
+  return JSON.stringify(value);
+}
+
+export class ResponseCache {
+  constructor(
+    private dir: string,
+    private ttlSeconds: number = DEFAULT_RESPONSE_CACHE_TTL_SECONDS
+  ) {}
+
+  /**
+   * The cached response for a key, or null if there is none or it expired
+   */
+  async get<T>(key: ResponseCacheKey, now: number = Date.now()): Promise<CachedResponse<T> | null> {
+    const entry = await this.read<T>(path.join(this.dir, `${responseCacheId(key)}.json`));
+    if (!entry || Date.parse(entry.expiresAt) <= now) {
+      return null;
+    }
+    return entry;
+  }
+
+  /**
+   * Store a response, removing entries that expired or were made against
+   * another indexed commit
+   */
+  async set<T>(key: ResponseCacheKey, value: T, now: number = Date.now()): Promise<void> {
+    await fs.mkdir(this.dir, { recursive: true });
+    await this.prune(key.indexedCommit, now);
+
+    const entry: CachedResponse<T> = {
+      key,
+      createdAt: new Date(now).toISOString(),
+      expiresAt: new Date(now + this.ttlSeconds * 1000).toISOString(),
+      value
+    };
+    const file = path.join(this.dir, `${responseCacheId(key)}.json`);
+    // Write then rename, so a concurrent read never sees half an entry
+    const temp = `${file}.${process.pid}.tmp`;
+    await fs.writeFile(temp, JSON.stringify(entry));
+    await fs.rename(temp, file);
+  }
+
+  /**
+   * Remove expired entries, and those for another indexed commit when one is
+   * given. Returns the number removed.
+   */
+  async prune(indexedCommit?: string, now: number = Date.now()): Promise<number> {
+    let removed = 0;
+    for (const file of await this.files()) {
+      const entry = await this.read(file);
+      const stale = !entry
+        || Date.parse(entry.expiresAt) <= now
+        || (indexedCommit !== undefined && entry.key.indexedCommit !== indexedCommit);
+      if (stale) {
+        await fs.rm(file, { force: true });
+        removed++;
+      }
+    }
+    return removed;
+  }
+
+  /**
+   * Remove every entry. Returns the number removed.
+   */
+  async clear(): Promise<number> {
+    const files = await this.files();
+    await Promise.all(files.map(file => fs.rm(file, { force: true })));
+    return files.length;
+  }
+
+  private async files(): Promise<string[]> {
+    try {
+      const names = await fs.readdir(this.dir);
+      return names.filter(name => name.endsWith('.json')).map(name => path.join(this.dir, name));
+    } catch {
+      return [];
+    }
+  }
+
+  private async read<T>(file: string): Promise<CachedResponse<T> | null> {
+    try {
+      return JSON.parse(await fs.readFile(file, 'utf-8')) as CachedResponse<T>;
+    } catch {
+      return null;
+    }
+  }
+}
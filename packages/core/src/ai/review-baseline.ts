@@ -0,0 +1,139 @@
+/**
+ * Review Baseline
+ * Findings accepted for now, so `cv review --baseline` reports only new ones
+ *
+ * A finding is identified by a fingerprint of its file and its message, with
+ * the message normalized: case, quoting, whitespace and numbers (line
+ * references included) are ignored, so the same finding still matches after
+ * the code around it moves or the reviewer words it with another line number.
+ * A fingerprint recorded twice suppresses two findings, not all of them.
+ */
+
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { getCVDir, ReviewFinding, ReviewResult, ReviewSeverity } from '@cv-git/shared';
+import { summarizeFindings } from './review-findings.js';
+
+export const REVIEW_BASELINE_VERSION = 1;
+
+/**
+ * Where `cv review --write-baseline` writes when no file is given
+ */
+export function getDefaultReviewBaselinePath(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), 'review-baseline.json');
+}
+
+export interface ReviewBaselineEntry {
+  fingerprint: string;
+  file: string;
+  category: string;
+  severity: ReviewSeverity;
+  /** As the reviewer wrote it, for people reading the file */
+  message: string;
+}
+
+export interface ReviewBaseline {
+  version: number;
+  createdAt: string;
+  findings: ReviewBaselineEntry[];
+}
+
+/**
+ * The part of a message that identifies the finding
+ */
+export function normalizeFindingMessage(message: string): string {
+  return message
+    .toLowerCase()
+    .replace(/\b(lines?|l)\s*\d+(\s*(-|to|and)\s*\d+)?/g, 'line')
+    .replace(/\d+/g, '#')
+    .replace(/[`'"“”‘’]/g, '')
+    .replace(/\s+/g, ' ')
+    .replace(/[\s.;:!]+$/, '')
+    .trim();
+}
+
+/**
+ * Stable ID of a finding: its file and normalized message, not its lines
+ */
+export function findingFingerprint(finding: Pick<ReviewFinding, 'file' | 'message'>): string {
+  return createHash('sha256')
+    .update(`${finding.file}\0${normalizeFindingMessage(finding.message)}`)
+    .digest('hex')
+    .slice(0, 16);
+}
+
+/**
+ * A baseline accepting every given finding
+ */
+export function createReviewBaseline(findings: ReviewFinding[], now: Date = new Date()): ReviewBaseline {
+  return {
+    version: REVIEW_BASELINE_VERSION,
+    createdAt: now.toISOString(),
+    findings: findings.map(finding => ({
+      fingerprint: findingFingerprint(finding),
+      file: finding.file,
+      category: finding.category,
+      severity: finding.severity,
+      message: finding.message
+    }))
+  };
+}
+
+/**
+ * Drop the findings the baseline accepts and recount the summary
+ */
+export function applyReviewBaseline(result: ReviewResult, baseline: ReviewBaseline): ReviewResult {
+  const remaining = new Map<string, number>();
+  for (const entry of baseline.findings) {
+    remaining.set(entry.fingerprint, (remaining.get(entry.fingerprint) || 0) + 1);
+  }
+
+  const findings = result.findings.filter(finding => {
+    const fingerprint = findingFingerprint(finding);
+    const count = remaining.get(fingerprint) || 0;
+    if (count === 0) return true;
+    remaining.set(fingerprint, count - 1);
+    return false;
+  });
+
+  const summary = summarizeFindings(findings, result.summary.overview);
+  if (result.summary.disabledCategories) {
+    summary.disabledCategories = result.summary.disabledCategories;
+  }
+  summary.baselined = result.findings.length - findings.length;
+  return { findings, summary };
+}
+
+/**
+ * Read a baseline file; throws if it is missing or not a baseline
+ */
+export async function readReviewBaseline(file: string): Promise<ReviewBaseline> {
+  let parsed: any;
+  try {
+    parsed = JSON.parse(await fs.readFile(file, 'utf-8'));
+  } catch (error: any) {
+    throw new Error(error.code === 'ENOENT'
+      ? `Review baseline not found: ${file} (create it with cv review --write-baseline)`
+      : `Could not read review baseline ${file}: ${error.message}`);
+  }
+
+  if (!parsed || !Array.isArray(parsed.findings) || parsed.findings.some((entry: any) => typeof entry?.fingerprint !== 'string')) {
+    throw new Error(`${file} is not a review baseline`);
+  }
+  if (parsed.version > REVIEW_BASELINE_VERSION) {
+    throw new Error(`${file} was written by a newer version of cv (baseline version ${parsed.version})`);
+  }
+  return parsed as ReviewBaseline;
+}
+
+/**
+ * Write a baseline file, entries ordered by file so it diffs cleanly in review
+ */
+export async function writeReviewBaseline(file: string, baseline: ReviewBaseline): Promise<void> {
+  const findings = [...baseline.findings].sort((a, b) =>
+    (a.file < b.file ? -1 : a.file > b.file ? 1 : 0) || (a.fingerprint < b.fingerprint ? -1 : a.fingerprint > b.fingerprint ? 1 : 0)
+  );
+  await fs.mkdir(path.dirname(file), { recursive: true });
+  await fs.writeFile(file, JSON.stringify({ ...baseline, findings }, null, 2) + '\n');
+}
@@ -0,0 +1,316 @@
+/**
+ * Review Findings
+ * Parses, orders, and summarizes structured findings from an AI code review
+ *
+ * Every finding is tagged with one of a fixed set of categories. The
+ * reviewer's own labels ("perf", "bug", "vulnerability") are mapped onto that
+ * set, and each category has a default severity for findings that come back
+ * without a usable one. Review rules can disable categories or pin a
+ * category's severity (e.g. style is always low) for CI gating.
+ */
+
+import { AIError, ReviewFinding, ReviewResult, ReviewRules, ReviewSeverity, ReviewSummary } from '@cv-git/shared';
+
+/**
+ * Severities from least to most severe
+ */
+export const REVIEW_SEVERITIES: readonly ReviewSeverity[] = ['low', 'medium', 'high', 'critical'];
+
+export function isReviewSeverity(value: unknown): value is ReviewSeverity {
+  return typeof value === 'string' && (REVIEW_SEVERITIES as readonly string[]).includes(value);
+}
+
+/**
+ * Finding categories, in the order the human output groups them
+ */
+export const REVIEW_CATEGORIES = [
+  'security',
+  'correctness',
+  'performance',
+  'best-practices',
+  'testing',
+  'documentation',
+  'style'
+] as const;
+
+/** Category of findings the reviewer gave no recognizable category */
+export const GENERAL_REVIEW_CATEGORY = 'general';
+
+/**
+ * Severity of a finding whose severity is missing or invalid
+ */
+export const CATEGORY_DEFAULT_SEVERITY: Record<string, ReviewSeverity> = {
+  security: 'high',
+  correctness: 'medium',
+  performance: 'medium',
+  'best-practices': 'low',
+  testing: 'low',
+  documentation: 'low',
+  style: 'low',
+  [GENERAL_REVIEW_CATEGORY]: 'medium'
+};
+
+/** Other labels reviewers use for the categories */
+const CATEGORY_ALIASES: Record<string, string> = {
+  vulnerability: 'security',
+  vuln: 'security',
+  auth: 'security',
+  secrets: 'security',
+  bug: 'correctness',
+  bugs: 'correctness',
+  logic: 'correctness',
+  'error-handling': 'correctness',
+  concurrency: 'correctness',
+  perf: 'performance',
+  efficiency: 'performance',
+  hotspot: 'performance',
+  scalability: 'performance',
+  'best-practice': 'best-practices',
+  maintainability: 'best-practices',
+  design: 'best-practices',
+  tests: 'testing',
+  test: 'testing',
+  docs: 'documentation',
+  comments: 'documentation',
+  formatting: 'style',
+  naming: 'style',
+  readability: 'style'
+};
+
+export function isReviewCategory(value: unknown): boolean {
+  return typeof value === 'string' &&
+    ((REVIEW_CATEGORIES as readonly string[]).includes(value) || value === GENERAL_REVIEW_CATEGORY);
+}
+
+/**
+ * Map a reviewer's category label onto REVIEW_CATEGORIES ("Best Practices" and
+ * "perf" included); anything unrecognized is general
+ */
+export function normalizeCategory(value: unknown): string {
+  if (typeof value !== 'string') return GENERAL_REVIEW_CATEGORY;
+  const key = value.trim().toLowerCase().replace(/[\s_]+/g, '-');
+  const category = CATEGORY_ALIASES[key] || key;
+  return isReviewCategory(category) ? category : GENERAL_REVIEW_CATEGORY;
+}
+
+/**
+ * Split and normalize category lists (`--disable style,docs`), returning the
+ * entries that name no category separately so callers can reject them
+ */
+export function parseReviewCategories(values: string[]): { categories: string[]; unknown: string[] } {
+  const categories: string[] = [];
+  const unknown: string[] = [];
+  for (const value of values.flatMap(v => v.split(',')).map(v => v.trim()).filter(Boolean)) {
+    const category = normalizeCategory(value);
+    if (category === GENERAL_REVIEW_CATEGORY && value.toLowerCase() !== GENERAL_REVIEW_CATEGORY) {
+      unknown.push(value);
+    } else if (!categories.includes(category)) {
+      categories.push(category);
+    }
+  }
+  return { categories, unknown };
+}
+
+function severityRank(severity: ReviewSeverity): number {
+  return REVIEW_SEVERITIES.indexOf(severity);
+}
+
+/**
+ * Order findings by file, then line range, then severity (most severe first), then message.
+ * The result is stable across runs so JSON output can be diffed.
+ */
+export function sortFindings(findings: ReviewFinding[]): ReviewFinding[] {
+  return [...findings].sort((a, b) =>
+    compareStrings(a.file, b.file) ||
+    a.startLine - b.startLine ||
+    a.endLine - b.endLine ||
+    severityRank(b.severity) - severityRank(a.severity) ||
+    compareStrings(a.category, b.category) ||
+    compareStrings(a.message, b.message)
+  );
+}
+
+function compareStrings(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
+
+/**
+ * Return findings at or above a severity threshold
+ */
+export function findingsAtOrAbove(findings: ReviewFinding[], threshold: ReviewSeverity): ReviewFinding[] {
+  const min = severityRank(threshold);
+  return findings.filter(f => severityRank(f.severity) >= min);
+}
+
+/**
+ * cv review exit codes for CI. Without --fail-on the code is the highest
+ * severity found; with it, only whether the threshold was reached.
+ */
+export const REVIEW_EXIT_CODES = {
+  clean: 0,
+  low: 1,
+  medium: 2,
+  high: 3,
+  critical: 3,
+  /** At least one finding at or above --fail-on */
+  failed: 1,
+  /** Invalid options or a failed review; never a severity */
+  error: 4
+} as const;
+
+/**
+ * Exit code of a review with these findings
+ */
+export function reviewExitCode(findings: ReviewFinding[], failOn?: ReviewSeverity): number {
+  if (failOn) {
+    return findingsAtOrAbove(findings, failOn).length > 0 ? REVIEW_EXIT_CODES.failed : REVIEW_EXIT_CODES.clean;
+  }
+  const highest = findings.reduce<ReviewSeverity | undefined>(
+    (max, f) => (max === undefined || severityRank(f.severity) > severityRank(max) ? f.severity : max),
+    undefined
+  );
+  return highest ? REVIEW_EXIT_CODES[highest] : REVIEW_EXIT_CODES.clean;
+}
+
+/**
+ * Drop findings of disabled categories and apply the per-category severities
+ */
+export function applyReviewRules(result: ReviewResult, rules: ReviewRules = {}): ReviewResult {
+  const disabled = new Set(rules.disable || []);
+  const pinned = rules.severity || {};
+  if (disabled.size === 0 && Object.keys(pinned).length === 0) {
+    return result;
+  }
+
+  const findings = sortFindings(result.findings
+    .filter(f => !disabled.has(f.category))
+    .map(f => {
+      const severity = pinned[f.category];
+      return severity && isReviewSeverity(severity) ? { ...f, severity } : f;
+    }));
+
+  const summary = summarizeFindings(findings, result.summary.overview);
+  if (disabled.size > 0) {
+    summary.disabledCategories = [...disabled].sort(compareStrings);
+  }
+  return { findings, summary };
+}
+
+/**
+ * Group findings by category in REVIEW_CATEGORIES order, general last
+ */
+export function groupFindingsByCategory(findings: ReviewFinding[]): Array<{ category: string; findings: ReviewFinding[] }> {
+  const order = [...REVIEW_CATEGORIES, GENERAL_REVIEW_CATEGORY] as string[];
+  const groups = new Map<string, ReviewFinding[]>();
+  for (const finding of findings) {
+    const group = groups.get(finding.category) || [];
+    group.push(finding);
+    groups.set(finding.category, group);
+  }
+  return [...groups.entries()]
+    .sort(([a], [b]) => rankCategory(order, a) - rankCategory(order, b) || compareStrings(a, b))
+    .map(([category, grouped]) => ({ category, findings: grouped }));
+}
+
+function rankCategory(order: string[], category: string): number {
+  const rank = order.indexOf(category);
+  return rank === -1 ? order.length : rank;
+}
+
+/**
+ * Count findings by severity and category
+ */
+export function summarizeFindings(findings: ReviewFinding[], overview?: string): ReviewSummary {
+  const bySeverity = Object.fromEntries(REVIEW_SEVERITIES.map(s => [s, 0])) as Record<ReviewSeverity, number>;
+  const byCategory: Record<string, number> = {};
+
+  for (const finding of findings) {
+    bySeverity[finding.severity]++;
+    byCategory[finding.category] = (byCategory[finding.category] || 0) + 1;
+  }
+
+  return {
+    total: findings.length,
+    bySeverity,
+    byCategory: Object.fromEntries(Object.entries(byCategory).sort(([a], [b]) => compareStrings(a, b))),
+    overview
+  };
+}
+
+/**
+ * The reviewer's response holds no findings JSON, e.g. truncated or
+ * off-format output. Carries the raw response; a review that could not be
+ * read must not pass a --fail-on gate as clean.
+ */
+export class ReviewParseError extends AIError {
+  constructor(public response: string, reason: string) {
+    super(`Could not read the review findings: ${reason}`, { response });
+    this.name = 'ReviewParseError';
+  }
+}
+
+/**
+ * Parse the reviewer's JSON response into sorted findings.
+ * Malformed entries are dropped; a response without a findings list throws
+ * a ReviewParseError.
+ */
+export function parseReviewResponse(response: string): ReviewResult {
+  const jsonMatch = response.match(/\{[\s\S]*\}/);
+  if (!jsonMatch) {
+    throw new ReviewParseError(response, 'the response has no JSON object');
+  }
+
+  let parsed: any;
+  try {
+    parsed = JSON.parse(jsonMatch[0]);
+  } catch (error: any) {
+    throw new ReviewParseError(response, `invalid JSON (${error.message})`);
+  }
+  if (!Array.isArray(parsed?.findings)) {
+    throw new ReviewParseError(response, 'the JSON has no "findings" list');
+  }
+
+  const findings: ReviewFinding[] = [];
+  for (const raw of parsed.findings) {
+    const finding = normalizeFinding(raw);
+    if (finding) findings.push(finding);
+  }
+
+  const sorted = sortFindings(findings);
+  const overview = typeof parsed?.overview === 'string' && parsed.overview.trim()
+    ? parsed.overview.trim()
+    : undefined;
+
+  return { findings: sorted, summary: summarizeFindings(sorted, overview) };
+}
+
+function normalizeFinding(raw: any): ReviewFinding | null {
+  if (!raw || typeof raw.file !== 'string' || typeof raw.message !== 'string') {
+    return null;
+  }
+
+  const startLine = toLine(raw.startLine ?? raw.line);
+  const endLine = Math.max(startLine, toLine(raw.endLine ?? startLine));
+  const severity = typeof raw.severity === 'string' ? raw.severity.toLowerCase() : '';
+  const category = normalizeCategory(raw.category);
+
+  const finding: ReviewFinding = {
+    file: raw.file.trim(),
+    startLine,
+    endLine,
+    severity: isReviewSeverity(severity) ? severity : CATEGORY_DEFAULT_SEVERITY[category],
+    category,
+    message: raw.message.trim()
+  };
+
+  if (typeof raw.suggestion === 'string' && raw.suggestion.trim()) {
+    finding.suggestion = raw.suggestion.trim();
+  }
+
+  return finding;
+}
+
+function toLine(value: unknown): number {
+  const line = typeof value === 'number' ? value : parseInt(String(value), 10);
+  return Number.isFinite(line) && line > 0 ? Math.floor(line) : 0;
+}
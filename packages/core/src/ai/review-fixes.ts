@@ -0,0 +1,179 @@
+/**
+ * Review Fixes
+ * Builds the prompt behind `cv review --fix`, which asks for a replacement
+ * of exactly the lines a finding points at, and splices the replacements
+ * into the file
+ *
+ * The model answers NO_FIX for findings it cannot resolve within those
+ * lines (the fix belongs elsewhere, or needs a decision a person should
+ * make); those are skipped, as is a fix whose lines overlap one already
+ * planned for the same file. A fixed finding counts as resolved when the
+ * review run again no longer reports it.
+ */
+
+import { ReviewFinding } from '@cv-git/shared';
+import { REDACTED_SECRET } from '../security/redact.js';
+import { findingFingerprint } from './review-baseline.js';
+
+/** What the model answers when a finding can't be fixed safely in place */
+export const NO_FIX_MARKER = 'NO_FIX';
+
+/** Read-only lines shown above and below the lines to fix */
+const FIX_CONTEXT_LINES = 10;
+
+/** Most lines a finding may span and still be fixed in place */
+export const MAX_FIX_LINES = 80;
+
+export interface ReviewFix {
+  finding: ReviewFinding;
+  /** Code replacing lines startLine-endLine of the finding's file */
+  replacement: string;
+}
+
+export interface AppliedReviewFix extends ReviewFix {
+  /** Lines the replacement occupies in the fixed file */
+  newStartLine: number;
+  newEndLine: number;
+}
+
+export interface SkippedReviewFix {
+  finding: ReviewFinding;
+  reason: string;
+}
+
+/**
+ * Why a finding can't be fixed in place, or undefined if it can be tried
+ */
+export function checkFixable(finding: ReviewFinding, content: string): string | undefined {
+  const lineCount = content.split('\n').length;
+  if (!(finding.startLine >= 1) || finding.endLine < finding.startLine) {
+    return 'no line range to fix';
+  }
+  if (finding.endLine > lineCount) {
+    return `lines ${finding.startLine}-${finding.endLine} are past the end of the file`;
+  }
+  if (finding.endLine - finding.startLine + 1 > MAX_FIX_LINES) {
+    return `spans more than ${MAX_FIX_LINES} lines`;
+  }
+  return undefined;
+}
+
+/**
+ * The lines a finding points at
+ */
+export function getFixSource(finding: ReviewFinding, content: string): string {
+  return content.split('\n').slice(finding.startLine - 1, finding.endLine).join('\n');
+}
+
+/**
+ * Prompt asking for a replacement of the finding's lines as a single fenced
+ * block, or NO_FIX. `redact` masks the code shown around those lines.
+ */
+export function buildReviewFixPrompt(
+  finding: ReviewFinding,
+  language: string,
+  content: string,
+  source: string = getFixSource(finding, content),
+  redact: (text: string) => string = text => text
+): string {
+  const lines = content.split('\n');
+  const before = lines.slice(Math.max(0, finding.startLine - 1 - FIX_CONTEXT_LINES), finding.startLine - 1).join('\n');
+  const after = lines.slice(finding.endLine, finding.endLine + FIX_CONTEXT_LINES).join('\n');
+  const range = finding.startLine === finding.endLine ? `line ${finding.startLine}` : `lines ${finding.startLine}-${finding.endLine}`;
+
+  let prompt = `You are an expert software engineer fixing one code review finding.\n\n`;
+  prompt += `## Finding (${finding.severity} ${finding.category}, ${finding.file} ${range})\n\n${finding.message}\n`;
+  if (finding.suggestion) {
+    prompt += `\nSuggested fix: ${finding.suggestion}\n`;
+  }
+  if (before) {
+    prompt += `\n## Code before ${range} (do not change)\n\n\`\`\`${language}\n${redact(before)}\n\`\`\`\n`;
+  }
+  prompt += `\n## ${range[0].toUpperCase()}${range.slice(1)} (to replace)\n\n\`\`\`${language}\n${source}\n\`\`\`\n`;
+  if (after) {
+    prompt += `\n## Code after ${range} (do not change)\n\n\`\`\`${language}\n${redact(after)}\n\`\`\`\n`;
+  }
+
+  prompt += `\n## Requirements\n\n`;
+  prompt += `- Rewrite only the ${range} to replace, fixing the finding and nothing else; keep their indentation, comments and formatting.\n`;
+  prompt += `- The code before and after stays exactly as it is, and no other file is changed.\n`;
+  prompt += `- Keep each <REDACTED_SECRET_n> placeholder exactly once, unchanged, where its value is used; leave ${REDACTED_SECRET} out.\n`;
+  prompt += `- If the finding can't be fixed safely by changing only these lines (the fix belongs elsewhere, needs a design decision, or the finding is wrong), respond with exactly ${NO_FIX_MARKER}.\n\n`;
+  prompt += `Respond with ONLY the replacement lines in a single fenced code block, or ${NO_FIX_MARKER}.`;
+
+  return prompt;
+}
+
+/**
+ * The replacement in the model's response, or null for NO_FIX or a
+ * response without a code block
+ */
+export function parseReviewFix(response: string): string | null {
+  const trimmed = response.trim();
+  const open = trimmed.match(/```[\w+#.-]*\n/);
+  if (!open || trimmed.startsWith(NO_FIX_MARKER)) {
+    return null;
+  }
+
+  const bodyStart = open.index! + open[0].length;
+  const close = trimmed.lastIndexOf('\n```');
+  const code = close >= bodyStart - 1 ? trimmed.slice(bodyStart, close + 1) : trimmed.slice(bodyStart);
+  return code.replace(/\n+$/, '');
+}
+
+/**
+ * File contents with the fixes for one file spliced in. Fixes are taken in
+ * the order given (most severe first), so of two overlapping fixes the
+ * first is kept; fixes that change nothing are skipped.
+ */
+export function applyReviewFixes(
+  content: string,
+  fixes: ReviewFix[]
+): { content: string; applied: AppliedReviewFix[]; skipped: SkippedReviewFix[] } {
+  const kept: ReviewFix[] = [];
+  const skipped: SkippedReviewFix[] = [];
+
+  for (const fix of fixes) {
+    const { finding } = fix;
+    if (fix.replacement === getFixSource(finding, content).replace(/\n+$/, '')) {
+      skipped.push({ finding, reason: 'the fix changes nothing' });
+    } else if (kept.some(k => k.finding.startLine <= finding.endLine && finding.startLine <= k.finding.endLine)) {
+      skipped.push({ finding, reason: 'overlaps the lines of another fix' });
+    } else {
+      kept.push(fix);
+    }
+  }
+
+  // Top to bottom, tracking how earlier replacements moved the lines below them
+  const lines = content.split('\n');
+  const applied: AppliedReviewFix[] = [];
+  let shift = 0;
+  for (const fix of [...kept].sort((a, b) => a.finding.startLine - b.finding.startLine)) {
+    const { startLine, endLine } = fix.finding;
+    const replacement = fix.replacement === '' ? [] : fix.replacement.split('\n');
+    lines.splice(startLine - 1 + shift, endLine - startLine + 1, ...replacement);
+    applied.push({
+      ...fix,
+      newStartLine: startLine + shift,
+      newEndLine: startLine + shift + Math.max(replacement.length, 1) - 1
+    });
+    shift += replacement.length - (endLine - startLine + 1);
+  }
+
+  return { content: lines.join('\n'), applied, skipped };
+}
+
+/**
+ * Whether a fixed finding is gone from the findings of the review run
+ * again: nothing with the same fingerprint, and nothing of its category
+ * on the lines that replaced it
+ */
+export function isFindingResolved(fix: AppliedReviewFix, findings: ReviewFinding[]): boolean {
+  const fingerprint = findingFingerprint(fix.finding);
+  return !findings.some(finding =>
+    finding.file === fix.finding.file && (
+      findingFingerprint(finding) === fingerprint ||
+      (finding.category === fix.finding.category && finding.startLine <= fix.newEndLine && fix.newStartLine <= finding.endLine)
+    )
+  );
+}
@@ -0,0 +1,115 @@
+/**
+ * Custom System Prompts
+ * Per-command personas (config `prompts.<command>`, --system-prompt) that
+ * bias a command without losing the instructions its output depends on.
+ * The custom prompt is sent as the system prompt next to the command's own
+ * prompt; in raw mode it replaces the command's prompt instead, and the
+ * input and retrieved code are placed through {{input}} and {{code}}.
+ */
+
+import { Context } from '@cv-git/shared';
+
+export interface SystemPromptOptions {
+  /** Template with {{variable}} placeholders */
+  template: string;
+  /** Replace the command's built-in prompt instead of adding to it */
+  raw?: boolean;
+  /** Command the prompt is for, e.g. review */
+  command: string;
+  /** Repository name */
+  repo?: string;
+  /** Values the command knows up front, e.g. the query; they replace the computed ones */
+  variables?: Record<string, string>;
+}
+
+/**
+ * Variables available in custom prompts, with what they hold
+ */
+export const SYSTEM_PROMPT_VARIABLES: Record<string, string> = {
+  command: 'The command, e.g. review',
+  repo: 'Repository name',
+  query: 'The task (cv do), or what is reviewed (cv review), e.g. staged changes',
+  language: 'Language code is generated in (cv do), else the most common language of the code in context',
+  files: 'Comma-separated files of the code in context, or the changed files (cv review)',
+  input: 'The explain target, task, or diff',
+  code: 'The code in context as fenced blocks'
+};
+
+/**
+ * Values of the prompt variables for one request
+ */
+export function getPromptVariables(
+  options: Pick<SystemPromptOptions, 'command' | 'repo' | 'variables'>,
+  input: string = '',
+  context?: Context
+): Record<string, string> {
+  const chunks = context?.chunks ?? [];
+
+  const languageCounts = new Map<string, number>();
+  for (const chunk of chunks) {
+    if (chunk.payload.language) {
+      languageCounts.set(chunk.payload.language, (languageCounts.get(chunk.payload.language) ?? 0) + 1);
+    }
+  }
+  const language = context?.language
+    ?? Array.from(languageCounts.entries()).sort((a, b) => b[1] - a[1])[0]?.[0]
+    ?? '';
+
+  return {
+    command: options.command,
+    repo: options.repo ?? '',
+    query: '',
+    language,
+    files: Array.from(new Set(chunks.map(c => c.payload.file))).join(', '),
+    input,
+    code: chunks
+      .map(c => `### ${c.payload.file}:${c.payload.startLine}\n\`\`\`${c.payload.language}\n${c.payload.text}\n\`\`\``)
+      .join('\n\n'),
+    ...options.variables
+  };
+}
+
+/**
+ * Replace {{name}} placeholders. Unknown names are left as written.
+ */
+export function interpolatePrompt(template: string, variables: Record<string, string>): string {
+  return template.replace(/\{\{\s*(\w+)\s*\}\}/g, (match, name: string) =>
+    Object.prototype.hasOwnProperty.call(variables, name) ? variables[name] : match
+  );
+}
+
+/**
+ * The system prompt and user prompt to send for a command's built-in prompt.
+ * A raw template that never places {{input}} or {{code}} gets them appended,
+ * so the model still sees what it is asked about.
+ */
+export function applySystemPrompt(
+  options: SystemPromptOptions | undefined,
+  prompt: string,
+  input?: string,
+  context?: Context
+): { system?: string; prompt: string } {
+  if (!options) return { prompt };
+
+  const variables = getPromptVariables(options, input, context);
+  const custom = interpolatePrompt(options.template, variables).trim();
+
+  // Raw mode needs the material the built-in prompt would have carried
+  if (options.raw && input !== undefined) {
+    const placed = /\{\{\s*(input|code)\s*\}\}/.test(options.template);
+    const material = [variables.input, variables.code].filter(Boolean).join('\n\n');
+    return { prompt: placed || !material ? custom : `${custom}\n\n${material}` };
+  }
+
+  return { system: custom, prompt };
+}
+
+/**
+ * A command's own system prompt combined with a custom one: the custom
+ * prompt first, or alone in raw mode
+ */
+export function composeSystemPrompt(builtIn: string, options: SystemPromptOptions | undefined, variables?: Record<string, string>): string {
+  if (!options) return builtIn;
+  const custom = interpolatePrompt(options.template, variables ?? getPromptVariables(options)).trim();
+  return options.raw ? custom : `${custom}\n\n${builtIn}`;
+}
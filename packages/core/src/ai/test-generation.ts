@@ -0,0 +1,188 @@
+/**
+ * Test Generation
+ * Detects a project's test conventions and builds the context for `cv test`
+ */
+
+import * as path from 'path';
+import { SymbolNode } from '@cv-git/shared';
+
+export interface TestConventions {
+  language: string;
+  /** Detected test framework (e.g. vitest, jest, pytest, testing) */
+  framework: string;
+  /** Repo-relative path the generated test file should be written to */
+  testFile: string;
+  /** Language-specific guidance for the prompt */
+  guidance: string;
+}
+
+export interface TestGenerationContext {
+  symbol: SymbolNode;
+  /** Source of the symbol's definition */
+  source: string;
+  /** Callers with a short excerpt of each, showing real usage */
+  callers: Array<{ symbol: SymbolNode; excerpt: string }>;
+  conventions: TestConventions;
+  /** An existing test file to copy style from */
+  exampleTest?: { file: string; content: string };
+}
+
+/** Frameworks recognised from imports in existing test files, by language */
+const FRAMEWORK_SIGNATURES: Record<string, Array<{ framework: string; pattern: RegExp }>> = {
+  typescript: [
+    { framework: 'vitest', pattern: /from ['"]vitest['"]/ },
+    { framework: 'jest', pattern: /from ['"]@jest\/globals['"]|\bjest\.(fn|mock|spyOn)\(/ },
+    { framework: 'mocha', pattern: /from ['"](mocha|chai)['"]|require\(['"](mocha|chai)['"]\)/ },
+    { framework: 'node:test', pattern: /from ['"]node:test['"]/ }
+  ],
+  python: [
+    { framework: 'pytest', pattern: /^\s*import pytest|^\s*from pytest\b|@pytest\./m },
+    { framework: 'unittest', pattern: /^\s*import unittest|^\s*from unittest\b/m }
+  ],
+  go: [
+    { framework: 'testify', pattern: /"github\.com\/stretchr\/testify\// },
+    { framework: 'testing', pattern: /"testing"/ }
+  ],
+  java: [
+    { framework: 'junit5', pattern: /import org\.junit\.jupiter\./ },
+    { framework: 'junit4', pattern: /import org\.junit\.(Test|Assert|Before)\b/ }
+  ],
+  rust: [
+    { framework: 'cargo test', pattern: /#\[(cfg\(test\)|test)\]/ }
+  ]
+};
+
+const DEFAULT_FRAMEWORKS: Record<string, string> = {
+  typescript: 'vitest',
+  python: 'pytest',
+  go: 'testing',
+  java: 'junit5',
+  rust: 'cargo test'
+};
+
+const GUIDANCE: Record<string, string> = {
+  go: 'Write a _test.go file in the same package using the standard testing package. ' +
+    'Use table-driven tests: a slice of named cases iterated with t.Run.',
+  typescript: 'Use describe/it blocks and import the code under test with the same module paths the project uses.',
+  python: 'Name test functions test_*. Prefer plain asserts and parametrize cases where it helps.',
+  java: 'Put the tests in a class named after the class under test with a Test suffix.',
+  rust: 'Write #[test] functions; use assert_eq! and cover error cases.'
+};
+
+/**
+ * Whether a path looks like a test file by its language's naming convention
+ * (foo_test.go, foo.test.ts, test_foo.py, foo_spec.rb, FooTest.java, tests/...)
+ */
+export function isTestFile(file: string): boolean {
+  const base = path.posix.basename(file);
+  return /(\.|_)(test|spec)\.[a-z]+$/i.test(base) ||
+    /^test_.*\.py$/.test(base) ||
+    /Tests?\.(java|kt|scala|cs|swift|php)$/.test(base) ||
+    /(^|\/)(__tests__|tests?)\//.test(file);
+}
+
+/**
+ * Detect the test framework from the contents of existing test files
+ */
+export function detectTestFramework(language: string, testSources: string[]): string {
+  const signatures = FRAMEWORK_SIGNATURES[language] || [];
+  for (const { framework, pattern } of signatures) {
+    if (testSources.some(source => pattern.test(source))) {
+      return framework;
+    }
+  }
+  return DEFAULT_FRAMEWORKS[language] || 'the standard test framework';
+}
+
+/**
+ * Where a test for sourceFile should live.
+ * Existing test files decide between sibling tests and a separate test directory.
+ */
+export function getTestFilePath(sourceFile: string, language: string, existingTests: string[] = []): string {
+  const dir = path.posix.dirname(sourceFile);
+  const ext = path.posix.extname(sourceFile);
+  const name = path.posix.basename(sourceFile, ext);
+
+  switch (language) {
+    case 'go':
+      return path.posix.join(dir, `${name}_test.go`);
+    case 'python': {
+      const usesTestsDir = existingTests.some(t => /(^|\/)tests\//.test(t));
+      return usesTestsDir ? `tests/test_${name}.py` : path.posix.join(dir, `test_${name}.py`);
+    }
+    case 'java':
+      return path.posix.join(dir.replace(/(^|\/)src\/main\//, '$1src/test/'), `${name}Test.java`);
+    case 'rust':
+      return `tests/${name}.rs`;
+    default: {
+      const suffix = existingTests.some(t => /\.spec\.[jt]sx?$/.test(t)) ? 'spec' : 'test';
+      const testDir = existingTests.find(t => /(^|\/)tests\//.test(t) && /\.(test|spec)\.[jt]sx?$/.test(t));
+      const target = `${name}.${suffix}${ext}`;
+      return testDir ? path.posix.join(path.posix.dirname(testDir), target) : path.posix.join(dir, target);
+    }
+  }
+}
+
+/**
+ * Work out how tests are written for a source file
+ * @param existingTests - Repo-relative test files in the same language
+ * @param testSources - Contents of a sample of those files
+ */
+export function detectTestConventions(
+  sourceFile: string,
+  language: string,
+  existingTests: string[],
+  testSources: string[]
+): TestConventions {
+  return {
+    language,
+    framework: detectTestFramework(language, testSources),
+    testFile: getTestFilePath(sourceFile, language, existingTests),
+    guidance: GUIDANCE[language] || 'Follow the conventions of the existing tests.'
+  };
+}
+
+/**
+ * Build the prompt for generating a test file
+ */
+export function buildTestGenerationPrompt(context: TestGenerationContext): string {
+  const { symbol, conventions } = context;
+  let prompt = `You are an expert software engineer writing unit tests.\n\n`;
+  prompt += `Write a complete test file for ${symbol.kind} \`${symbol.name}\` defined in ${symbol.file}.\n`;
+  prompt += `The file will be saved as ${conventions.testFile} and run with ${conventions.framework}.\n\n`;
+
+  prompt += `## Definition\n\n`;
+  prompt += `\`\`\`${conventions.language}\n${context.source}\n\`\`\`\n\n`;
+
+  if (context.callers.length > 0) {
+    prompt += `## How It Is Called\n\n`;
+    for (const { symbol: caller, excerpt } of context.callers) {
+      prompt += `### ${caller.name} in ${caller.file}:${caller.startLine}\n`;
+      prompt += `\`\`\`${conventions.language}\n${excerpt}\n\`\`\`\n\n`;
+    }
+  }
+
+  if (context.exampleTest) {
+    prompt += `## Existing Test (match its style)\n\n`;
+    prompt += `### ${context.exampleTest.file}\n`;
+    prompt += `\`\`\`${conventions.language}\n${context.exampleTest.content}\n\`\`\`\n\n`;
+  }
+
+  prompt += `## Requirements\n\n`;
+  prompt += `- ${conventions.guidance}\n`;
+  prompt += `- Cover normal behaviour, edge cases, and error handling.\n`;
+  prompt += `- Import or reference the code under test relative to ${conventions.testFile}.\n`;
+  prompt += `- Do not test private helpers directly unless the language requires it.\n\n`;
+  prompt += `Respond with ONLY the contents of the test file in a single fenced code block.`;
+
+  return prompt;
+}
+
+/**
+ * Pull the file contents out of a fenced code block (or return the response as-is)
+ */
+export function extractCodeBlock(response: string): string {
+  const match = response.match(/```[\w+-]*\n([\s\S]*?)```/);
+  const code = match ? match[1] : response;
+  return code.trimEnd() + '\n';
+}
@@ -18,6 +18,8 @@ export interface AIStreamHandler {
   onToken?: (token: string) => void;
   onComplete?: (fullText: string) => void;
   onError?: (error: Error) => void;
+  /** Aborts the in-flight request when signalled (e.g. on Ctrl-C) */
+  signal?: AbortSignal;
 }
 
 /**
@@ -46,9 +48,9 @@ export interface AIClient {
   isReady(): Promise<boolean>;
 
   /**
-   * Chat completion (non-streaming)
+   * Chat completion (non-streaming); the signal aborts the request
    */
-  chat(messages: AIMessage[], systemPrompt?: string): Promise<string>;
+  chat(messages: AIMessage[], systemPrompt?: string, signal?: AbortSignal): Promise<string>;
 
   /**
    * Chat completion with streaming
@@ -65,6 +67,12 @@ export interface AIClient {
   complete(prompt: string, handler?: AIStreamHandler): Promise<string>;
 }
 
+/**
+ * What a text is embedded as. Asymmetric embedding models (Cohere, Voyage)
+ * embed indexed documents and search queries differently.
+ */
+export type EmbeddingInputType = 'document' | 'query';
+
 /**
  * Model information with capabilities
  */
@@ -0,0 +1,86 @@
+/**
+ * Voyage AI Embeddings Client
+ * Embeds text through Voyage's OpenAI-style embeddings endpoint
+ *
+ * Like Cohere, Voyage distinguishes documents from queries via `input_type`
+ * and prepends a different retrieval prompt for each.
+ */
+
+import { getMaxRetryAttempts, retryWithBackoff, toApiError } from '@cv-git/shared';
+import { EmbeddingInputType } from './types.js';
+
+export const VOYAGE_API_URL = 'https://api.voyageai.com/v1';
+export const DEFAULT_VOYAGE_EMBEDDING_MODEL = 'voyage-code-3';
+
+/** Input types sent for indexed documents and for queries */
+export const VOYAGE_INPUT_TYPES: Record<EmbeddingInputType, string> = {
+  document: 'document',
+  query: 'query'
+};
+
+/** The embeddings endpoint accepts at most this many texts per request */
+const MAX_EMBED_BATCH = 128;
+
+export interface VoyageOptions {
+  apiKey: string;
+  /** API base URL (default: the public v1 endpoint) */
+  baseUrl?: string;
+  /** Attempts per request on rate limits and transient errors, honoring Retry-After (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+}
+
+export class VoyageClient {
+  private apiKey: string;
+  private baseUrl: string;
+  private maxAttempts: number;
+
+  constructor(options: VoyageOptions) {
+    this.apiKey = options.apiKey;
+    this.baseUrl = (options.baseUrl || VOYAGE_API_URL).replace(/\/+$/, '');
+    this.maxAttempts = options.maxRetryAttempts ?? getMaxRetryAttempts();
+  }
+
+  /**
+   * Embed texts as documents (for the index) or queries (for search)
+   */
+  async embed(
+    texts: string[],
+    model: string = DEFAULT_VOYAGE_EMBEDDING_MODEL,
+    inputType: EmbeddingInputType = 'document',
+    signal?: AbortSignal
+  ): Promise<number[][]> {
+    const embeddings: number[][] = [];
+
+    for (let i = 0; i < texts.length; i += MAX_EMBED_BATCH) {
+      const response = await this.post('embeddings', {
+        model,
+        input: texts.slice(i, i + MAX_EMBED_BATCH),
+        input_type: VOYAGE_INPUT_TYPES[inputType]
+      }, signal);
+      const data = await response.json() as { data?: Array<{ embedding: number[]; index: number }> };
+      const batch = (data.data || []).slice().sort((a, b) => a.index - b.index);
+      embeddings.push(...batch.map(d => d.embedding));
+    }
+
+    return embeddings;
+  }
+
+  private async post(endpoint: string, body: unknown, signal?: AbortSignal): Promise<Response> {
+    return retryWithBackoff(async () => {
+      const response = await fetch(`${this.baseUrl}/${endpoint}`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${this.apiKey}`
+        },
+        body: JSON.stringify(body),
+        signal
+      });
+
+      if (!response.ok) {
+        throw await toApiError('Voyage', response, body => body.detail);
+      }
+      return response;
+    }, { maxAttempts: this.maxAttempts, signal });
+  }
+}
@@ -10,6 +10,7 @@ import { VectorManager } from '../vector/index.js';
 import { GraphManager } from '../graph/index.js';
 import { GitManager } from '../git/index.js';
 import { AIClient } from '../ai/types.js';
+import { pickPromptLanguage, buildLanguageInstruction } from '../sync/languages.js';
 import { ContextManager, createContextManager } from './context-manager.js';
 import { SessionManager, createSessionManager } from './session-manager.js';
 import { FileOperations, createFileOperations } from './file-ops.js';
@@ -164,8 +165,12 @@ export class CodeAssistant {
       userMessage,
       currentSession.activeContext,
       {
-        maxChunks: 15,
-        minScore: 0.5,
+        maxChunks: this.options.topK ?? 15,
+        minScore: this.options.minScore ?? 0.5,
+        tests: this.options.tests,
+        packages: this.options.packages,
+        kinds: this.options.kinds,
+        extensions: this.options.extensions,
       }
     );
 
@@ -261,7 +266,17 @@ export class CodeAssistant {
    */
   private buildSystemPrompt(context: ContextSnapshot): string {
     const contextFormatted = this.context.formatForPrompt(context);
-    return CODE_SYSTEM_PROMPT + '\n' + contextFormatted;
+    const repoLanguages = this.options.repoLanguages ?? null;
+    const relevantFiles = [...context.files, ...context.symbols]
+      .sort((a, b) => b.relevanceScore - a.relevanceScore)
+      .map((item) => ('path' in item ? item.path : item.file));
+    const language = pickPromptLanguage(repoLanguages, relevantFiles, this.options.language);
+
+    let prompt = CODE_SYSTEM_PROMPT + '\n' + contextFormatted;
+    if (language) {
+      prompt += `\n\n## Language\n\n${buildLanguageInstruction(language, repoLanguages?.primary)}\n`;
+    }
+    return prompt;
   }
 
   /**
@@ -329,6 +344,13 @@ export class CodeAssistant {
     return results;
   }
 
+  /**
+   * Add an edit built outside the response parser (e.g. an insertion) as pending
+   */
+  addPendingEdit(edit: Edit): void {
+    this.session.addPendingEdits([edit]);
+  }
+
   /**
    * Approve an edit (mark as ready to apply)
    */
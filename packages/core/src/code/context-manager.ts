@@ -12,7 +12,7 @@
 
 import { promises as fs } from 'fs';
 import * as path from 'path';
-import { VectorManager } from '../vector/index.js';
+import { VectorManager, applyMinScore } from '../vector/index.js';
 import { GraphManager } from '../graph/index.js';
 import {
   ActiveContext,
@@ -84,11 +84,16 @@ export class ContextManager {
       }
       try {
         const maxChunks = options.maxChunks || 10;
-        const minScore = options.minScore || 0.2; // Lower threshold for better recall on general queries
+        const minScore = options.minScore ?? 0.2; // Lower threshold for better recall on general queries
 
-        const vectorResults = await this.vector.searchCode(query, maxChunks, {
-          minScore,
-        });
+        const thresholded = applyMinScore(await this.vector.searchCode(query, maxChunks, {
+          tests: options.tests,
+          packages: options.packages,
+          kinds: options.kinds,
+          extensions: options.extensions
+        }), minScore);
+        const vectorResults = thresholded.results;
+        snapshot.nearMissScore = thresholded.nearMissScore;
 
         if (process.env.CV_DEBUG) {
           console.log(`[ContextManager] Vector search returned ${vectorResults.length} results`);
@@ -616,6 +621,7 @@ export class ContextManager {
       symbols: [],
       relationships: [],
       tokenCount: 0,
+      nearMissScore: snapshot.nearMissScore,
     };
 
     // Calculate tokens from files first
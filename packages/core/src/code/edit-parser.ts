@@ -236,6 +236,11 @@ export class EditParser {
           diff.hunks.push(hunk);
         }
       }
+    } else if (edit.type === 'modify' && edit.newContent !== undefined && originalContent !== undefined) {
+      const hunk = this.createReplacementHunk(originalContent, edit.newContent);
+      if (hunk) {
+        diff.hunks.push(hunk);
+      }
     }
 
     return diff;
@@ -348,6 +353,59 @@ export class EditParser {
     };
   }
 
+  /**
+   * Create a hunk for a full replacement, covering the lines between the
+   * unchanged start and end of the file (null if nothing changed)
+   */
+  private createReplacementHunk(originalContent: string, newContent: string): DiffHunk | null {
+    const CONTEXT_LINES = 3;
+    const oldLines = originalContent.replace(/\r\n/g, '\n').split('\n');
+    const newLines = newContent.replace(/\r\n/g, '\n').split('\n');
+
+    let prefix = 0;
+    while (prefix < oldLines.length && prefix < newLines.length && oldLines[prefix] === newLines[prefix]) {
+      prefix++;
+    }
+    let suffix = 0;
+    while (
+      suffix < oldLines.length - prefix &&
+      suffix < newLines.length - prefix &&
+      oldLines[oldLines.length - 1 - suffix] === newLines[newLines.length - 1 - suffix]
+    ) {
+      suffix++;
+    }
+    if (prefix === oldLines.length && prefix === newLines.length) {
+      return null;
+    }
+
+    const start = Math.max(0, prefix - CONTEXT_LINES);
+    const oldEnd = Math.min(oldLines.length, oldLines.length - suffix + CONTEXT_LINES);
+    const newEnd = newLines.length - (oldLines.length - oldEnd);
+
+    const lines: DiffLine[] = [];
+    for (let i = start; i < prefix; i++) {
+      lines.push({ type: 'context', content: oldLines[i], oldLineNumber: i + 1, newLineNumber: i + 1 });
+    }
+    for (let i = prefix; i < oldLines.length - suffix; i++) {
+      lines.push({ type: 'remove', content: oldLines[i], oldLineNumber: i + 1 });
+    }
+    for (let i = prefix; i < newLines.length - suffix; i++) {
+      lines.push({ type: 'add', content: newLines[i], newLineNumber: i + 1 });
+    }
+    for (let i = oldLines.length - suffix; i < oldEnd; i++) {
+      const newIndex = i + newLines.length - oldLines.length;
+      lines.push({ type: 'context', content: oldLines[i], oldLineNumber: i + 1, newLineNumber: newIndex + 1 });
+    }
+
+    return {
+      oldStart: start + 1,
+      oldLines: oldEnd - start,
+      newStart: start + 1,
+      newLines: newEnd - start,
+      lines,
+    };
+  }
+
   /**
    * Format a diff for terminal display
    */
@@ -13,3 +13,4 @@ export { FileOperations, createFileOperations } from './file-ops.js';
 export { ContextManager, createContextManager } from './context-manager.js';
 export { SessionManager, createSessionManager } from './session-manager.js';
 export { CodeAssistant, createCodeAssistant } from './assistant.js';
+export * from './insertion.js';
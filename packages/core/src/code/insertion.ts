@@ -0,0 +1,221 @@
+/**
+ * CV Code - Code Insertion
+ *
+ * Places generated code at a location in an existing file (`cv code --apply`):
+ * after a named symbol or before a line. The snippet is re-indented to its
+ * surroundings and written with the file's line endings.
+ */
+
+import * as path from 'path';
+import { v4 as uuidv4 } from 'uuid';
+import { SymbolNode } from '@cv-git/shared';
+import { CodeParser, createParser } from '../parser/index.js';
+import { Edit } from './types.js';
+
+/**
+ * Where generated code goes
+ */
+export interface InsertionTarget {
+  /** File relative to the repository root */
+  file: string;
+  /** Insert after this symbol (name, or Class.method) */
+  afterSymbol?: string;
+  /** Insert before this 1-based line (past the end appends) */
+  line?: number;
+}
+
+/**
+ * A resolved insertion point
+ */
+export interface InsertionPoint {
+  /** Number of lines kept above the inserted code */
+  afterLine: number;
+  /** Indentation of the inserted code */
+  indent: string;
+  /** Separate the code from its neighbours with a blank line */
+  padded: boolean;
+  /** Human-readable location, e.g. "after login (line 42)" */
+  label: string;
+}
+
+const EDIT_MARKERS = /^(<<<<<<< (SEARCH|DELETE)|=======|>>>>>>> (REPLACE|DELETE))$/m;
+
+/**
+ * Instruction for a model to write only the code to insert
+ */
+export function buildInsertionInstruction(instruction: string, target: InsertionTarget): string {
+  const where = target.afterSymbol
+    ? `after \`${target.afterSymbol}\` in ${target.file}`
+    : target.line !== undefined
+      ? `at line ${target.line} of ${target.file}`
+      : `at the end of ${target.file}`;
+
+  return `${instruction}\n\n` +
+    `Write only the new code to insert ${where}, as a single fenced code block. ` +
+    `Do not repeat the surrounding code and do not use SEARCH/REPLACE blocks; ` +
+    `the block is inserted into the file as written. Match the file's existing style.`;
+}
+
+/**
+ * The generated code in a response: the first fenced block that is not a
+ * SEARCH/REPLACE edit. Null if there is none.
+ */
+export function extractGeneratedCode(response: string): string | null {
+  const regex = /```[^\n`]*\n([\s\S]*?)```/g;
+  let match;
+  while ((match = regex.exec(response)) !== null) {
+    const code = match[1].replace(/\n$/, '');
+    if (code.trim() && !EDIT_MARKERS.test(code)) {
+      return code;
+    }
+  }
+  return null;
+}
+
+/**
+ * Find a symbol by name or qualified name (Class.method). Prefers an exact
+ * qualified match, then the first symbol with that name.
+ */
+export function findSymbol(symbols: SymbolNode[], name: string): SymbolNode | null {
+  // Qualified names are "<file>:<name>" or "<file>:<Class>.<method>"
+  const qualifiedMatch = (s: SymbolNode) => s.qualifiedName === name || s.qualifiedName.endsWith(`:${name}`);
+  const sorted = [...symbols].sort((a, b) => a.startLine - b.startLine);
+  return sorted.find(qualifiedMatch) ?? sorted.find(s => s.name === name) ?? null;
+}
+
+/**
+ * Find where code goes in a file. Null when the symbol isn't in the file.
+ */
+export function resolveInsertionPoint(
+  content: string,
+  target: Omit<InsertionTarget, 'file'>,
+  symbols: SymbolNode[] = []
+): InsertionPoint | null {
+  const lines = splitLines(content);
+
+  if (target.afterSymbol) {
+    const symbol = findSymbol(symbols, target.afterSymbol);
+    if (!symbol) return null;
+    const endLine = Math.min(symbol.endLine, lines.length);
+    return {
+      afterLine: endLine,
+      indent: leadingWhitespace(lines[symbol.startLine - 1] ?? ''),
+      padded: true,
+      label: `after ${target.afterSymbol} (line ${endLine})`
+    };
+  }
+
+  if (target.line !== undefined) {
+    const afterLine = Math.max(0, Math.min(target.line - 1, lines.length));
+    // Indent like the code at that point (the next non-blank line, else the previous one)
+    const neighbour = lines.slice(afterLine).find(line => line.trim())
+      ?? lines.slice(0, afterLine).reverse().find(line => line.trim())
+      ?? '';
+    return {
+      afterLine,
+      indent: leadingWhitespace(neighbour),
+      padded: false,
+      label: afterLine >= lines.length ? 'at the end of the file' : `at line ${afterLine + 1}`
+    };
+  }
+
+  return endOfFile(content);
+}
+
+/**
+ * The insertion point that appends to a file
+ */
+export function endOfFile(content: string): InsertionPoint {
+  return {
+    afterLine: splitLines(content).length,
+    indent: '',
+    padded: true,
+    label: 'at the end of the file'
+  };
+}
+
+/**
+ * Insert code into file content, keeping its line endings and final newline
+ */
+export function insertCode(content: string, code: string, point: InsertionPoint): string {
+  const eol = content.includes('\r\n') ? '\r\n' : '\n';
+  const lines = splitLines(content);
+  const above = lines.slice(0, point.afterLine);
+  const below = lines.slice(point.afterLine);
+
+  const inserted = reindent(splitLines(code.replace(/\r\n/g, '\n')), point.indent);
+  if (point.padded) {
+    if (above.length > 0 && above[above.length - 1].trim()) inserted.unshift('');
+    // No gap before the brace or dedent that closes the enclosing block
+    if (below.length > 0 && below[0].trim() && leadingWhitespace(below[0]).length >= point.indent.length) inserted.push('');
+  }
+
+  const endsWithNewline = content.length === 0 || content.endsWith('\n');
+  const result = [...above, ...inserted, ...below].join(eol);
+  return endsWithNewline ? result + eol : result;
+}
+
+/**
+ * Resolve an insertion target against a file, parsing it for symbols when
+ * inserting after one. Null when the symbol isn't found.
+ */
+export async function locateInsertion(
+  content: string,
+  target: InsertionTarget,
+  parser: CodeParser = createParser()
+): Promise<InsertionPoint | null> {
+  let symbols: SymbolNode[] = [];
+  if (target.afterSymbol) {
+    if (!parser.isExtensionSupported(path.extname(target.file))) {
+      return null;
+    }
+    symbols = (await parser.parseFile(target.file, content)).symbols;
+  }
+  return resolveInsertionPoint(content, target, symbols);
+}
+
+/**
+ * A pending edit that writes the file with the code inserted
+ */
+export function createInsertionEdit(
+  file: string,
+  originalContent: string,
+  code: string,
+  point: InsertionPoint,
+  messageId: string = uuidv4()
+): Edit {
+  return {
+    id: uuidv4(),
+    file,
+    type: 'modify',
+    originalContent,
+    newContent: insertCode(originalContent, code, point),
+    status: 'pending',
+    description: `Insert generated code ${point.label}`,
+    messageId,
+    createdAt: Date.now()
+  };
+}
+
+function splitLines(text: string): string[] {
+  if (text.length === 0) return [];
+  const lines = text.split(/\r?\n/);
+  if (lines[lines.length - 1] === '') lines.pop();
+  return lines;
+}
+
+function leadingWhitespace(line: string): string {
+  return line.match(/^[ \t]*/)![0];
+}
+
+/**
+ * Move lines to a new base indentation, keeping their relative indentation
+ */
+function reindent(lines: string[], indent: string): string[] {
+  const common = lines
+    .filter(line => line.trim())
+    .map(leadingWhitespace)
+    .reduce<string | null>((shortest, ws) => shortest === null || ws.length < shortest.length ? ws : shortest, null) ?? '';
+
+  return lines.map(line => line.trim() ? indent + line.slice(common.length) : '');
+}
@@ -5,6 +5,8 @@
  */
 
 import { SymbolKind } from '@cv-git/shared';
+import type { RepoLanguages } from '../sync/languages.js';
+import type { TestChunkFilter } from '../vector/index.js';
 
 // ============================================================================
 // Session Types
@@ -98,6 +100,9 @@ export interface ContextSnapshot {
 
   /** Total estimated tokens */
   tokenCount: number;
+
+  /** Best vector search score rejected by minScore */
+  nearMissScore?: number;
 }
 
 /**
@@ -224,6 +229,30 @@ export interface CodeOptions {
   /** Max context tokens */
   contextLimit?: number;
 
+  /** Code chunks retrieved per message (default: 15) */
+  topK?: number;
+
+  /** Min relevance score (0-1) for retrieved chunks (default: 0.5) */
+  minScore?: number;
+
+  /** Leave test file chunks out of retrieval, or retrieve only those */
+  tests?: TestChunkFilter;
+
+  /** Only retrieve chunks of these packages (--package) */
+  packages?: string[];
+
+  /** Only retrieve chunks of symbols of these kinds (--kind) */
+  kinds?: SymbolKind[];
+
+  /** Only retrieve chunks of files with these extensions (--ext) */
+  extensions?: string[];
+
+  /** Language to generate code in (default: detected per message) */
+  language?: string;
+
+  /** Languages detected in the repository */
+  repoLanguages?: RepoLanguages;
+
   /** Disable automatic context */
   noContext?: boolean;
 
@@ -252,6 +281,18 @@ export interface ContextOptions {
   /** Min relevance score (0-1) */
   minScore?: number;
 
+  /** Leave test file chunks out, or retrieve only those */
+  tests?: TestChunkFilter;
+
+  /** Only retrieve chunks of these packages */
+  packages?: string[];
+
+  /** Only retrieve chunks of symbols of these kinds */
+  kinds?: SymbolKind[];
+
+  /** Only retrieve chunks of files with these extensions */
+  extensions?: string[];
+
   /** Symbols to focus on */
   focusSymbols?: string[];
 }
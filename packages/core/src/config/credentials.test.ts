@@ -0,0 +1,121 @@
+/**
+ * Tests for credential resolution: stored credentials, then config values,
+ * then environment variables
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import { DEFAULT_AZURE_API_VERSION } from '../ai/azure.js';
+
+const mocks = vi.hoisted(() => ({
+  getAzureOpenAI: vi.fn(),
+  getAnthropicKey: vi.fn(),
+}));
+
+vi.mock('@cv-git/credentials', async (importOriginal) => ({
+  ...(await importOriginal<any>()),
+  CredentialManager: class {
+    async init() {}
+    getAzureOpenAI = mocks.getAzureOpenAI;
+    getAnthropicKey = mocks.getAnthropicKey;
+  },
+}));
+
+import { getAnthropicApiKey, getAzureOpenAISettings } from './credentials.js';
+
+describe('getAzureOpenAISettings', () => {
+  beforeEach(() => {
+    mocks.getAzureOpenAI.mockResolvedValue(null);
+    for (const name of ['ENDPOINT', 'API_KEY', 'API_VERSION', 'CHAT_DEPLOYMENT', 'EMBEDDING_DEPLOYMENT']) {
+      vi.stubEnv(`AZURE_OPENAI_${name}`, '');
+    }
+  });
+
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it('should prefer stored settings over config and environment', async () => {
+    mocks.getAzureOpenAI.mockResolvedValue({
+      endpoint: 'https://stored.openai.azure.com',
+      apiKey: 'stored-key',
+      apiVersion: '2024-10-21',
+      chatDeployment: 'stored-chat',
+      embeddingDeployment: 'stored-embed'
+    });
+    vi.stubEnv('AZURE_OPENAI_ENDPOINT', 'https://env.openai.azure.com');
+    vi.stubEnv('AZURE_OPENAI_API_KEY', 'env-key');
+
+    expect(await getAzureOpenAISettings({ endpoint: 'https://config.openai.azure.com', chatDeployment: 'config-chat' })).toEqual({
+      endpoint: 'https://stored.openai.azure.com',
+      apiKey: 'stored-key',
+      apiVersion: '2024-10-21',
+      chatDeployment: 'stored-chat',
+      embeddingDeployment: 'stored-embed'
+    });
+  });
+
+  it('should fall back to the environment, with the default API version', async () => {
+    vi.stubEnv('AZURE_OPENAI_ENDPOINT', 'https://env.openai.azure.com');
+    vi.stubEnv('AZURE_OPENAI_API_KEY', 'env-key');
+    vi.stubEnv('AZURE_OPENAI_EMBEDDING_DEPLOYMENT', 'env-embed');
+
+    expect(await getAzureOpenAISettings()).toEqual({
+      endpoint: 'https://env.openai.azure.com',
+      apiKey: 'env-key',
+      apiVersion: DEFAULT_AZURE_API_VERSION,
+      chatDeployment: undefined,
+      embeddingDeployment: 'env-embed'
+    });
+  });
+
+  it('should take config values over the environment, but the key only from storage or the environment', async () => {
+    vi.stubEnv('AZURE_OPENAI_ENDPOINT', 'https://env.openai.azure.com');
+    vi.stubEnv('AZURE_OPENAI_API_KEY', 'env-key');
+    vi.stubEnv('AZURE_OPENAI_CHAT_DEPLOYMENT', 'env-chat');
+
+    const settings = await getAzureOpenAISettings({ endpoint: 'https://config.openai.azure.com', chatDeployment: 'config-chat' });
+    expect(settings).toMatchObject({ endpoint: 'https://config.openai.azure.com', apiKey: 'env-key', chatDeployment: 'config-chat' });
+  });
+
+  it('should return null without an endpoint or a key', async () => {
+    vi.stubEnv('AZURE_OPENAI_ENDPOINT', 'https://env.openai.azure.com');
+    expect(await getAzureOpenAISettings()).toBeNull();
+
+    vi.stubEnv('AZURE_OPENAI_ENDPOINT', '');
+    vi.stubEnv('AZURE_OPENAI_API_KEY', 'env-key');
+    expect(await getAzureOpenAISettings()).toBeNull();
+  });
+});
+
+describe('getAnthropicApiKey', () => {
+  beforeEach(() => {
+    mocks.getAnthropicKey.mockResolvedValue(null);
+    vi.stubEnv('ANTHROPIC_API_KEY', '');
+    vi.stubEnv('CV_ANTHROPIC_KEY', '');
+  });
+
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it('should prefer the stored key, then the config key', async () => {
+    mocks.getAnthropicKey.mockResolvedValue('sk-ant-stored');
+    expect(await getAnthropicApiKey('sk-ant-config')).toBe('sk-ant-stored');
+
+    mocks.getAnthropicKey.mockResolvedValue(null);
+    vi.stubEnv('ANTHROPIC_API_KEY', 'sk-ant-env');
+    expect(await getAnthropicApiKey('sk-ant-config')).toBe('sk-ant-config');
+  });
+
+  it('should fall back to ANTHROPIC_API_KEY, then CV_ANTHROPIC_KEY', async () => {
+    vi.stubEnv('CV_ANTHROPIC_KEY', 'sk-ant-cv');
+    expect(await getAnthropicApiKey()).toBe('sk-ant-cv');
+
+    vi.stubEnv('ANTHROPIC_API_KEY', 'sk-ant-env');
+    expect(await getAnthropicApiKey()).toBe('sk-ant-env');
+  });
+
+  it('should return null without a key', async () => {
+    expect(await getAnthropicApiKey()).toBeNull();
+  });
+});
@@ -0,0 +1,631 @@
+/**
+ * Credential resolution shared by the CLI and the MCP server
+ *
+ * Provides unified API key retrieval with proper fallback order:
+ * 1. CredentialManager (keychain/file storage via cv auth setup)
+ * 2. Config file (.cv/config.json)
+ * 3. Environment variable
+ */
+
+import { CredentialManager } from '@cv-git/credentials';
+import type { CVConfig } from '@cv-git/shared';
+import { AzureOpenAIDeployment, DEFAULT_AZURE_API_VERSION } from '../ai/azure.js';
+import {
+  VectorManager,
+  VectorManagerOptions,
+  createVectorManager,
+  getVectorBackendOptions
+} from '../vector/index.js';
+import { getLMStudioUrl } from './service-urls.js';
+
+// Singleton credential manager instance
+let credentialManager: CredentialManager | null = null;
+
+/**
+ * Get or create a CredentialManager instance
+ */
+async function getCredentialManager(): Promise<CredentialManager> {
+  if (!credentialManager) {
+    credentialManager = new CredentialManager();
+    await credentialManager.init();
+  }
+  return credentialManager;
+}
+
+/**
+ * Get OpenAI API key with fallback order:
+ * 1. CredentialManager (keychain/file storage)
+ * 2. Config value (if provided)
+ * 3. Environment variable (OPENAI_API_KEY, then CV_OPENAI_KEY)
+ */
+export async function getOpenAIApiKey(configApiKey?: string): Promise<string | null> {
+  // 1. Try CredentialManager first
+  try {
+    const manager = await getCredentialManager();
+    const key = await manager.getOpenAIKey();
+    if (key) {
+      return key;
+    }
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  // 2. Try config value
+  if (configApiKey) {
+    return configApiKey;
+  }
+
+  // 3. Try environment variables
+  const envKey = process.env.OPENAI_API_KEY || process.env.CV_OPENAI_KEY;
+  if (envKey) {
+    return envKey;
+  }
+
+  return null;
+}
+
+/**
+ * Get OpenRouter API key with fallback order:
+ * 1. CredentialManager (keychain/file storage)
+ * 2. Config value (if provided)
+ * 3. Environment variable
+ */
+export async function getOpenRouterApiKey(configApiKey?: string): Promise<string | null> {
+  // 1. Try CredentialManager first
+  try {
+    const manager = await getCredentialManager();
+    const key = await manager.getOpenRouterKey();
+    if (key) {
+      return key;
+    }
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  // 2. Try config value
+  if (configApiKey) {
+    return configApiKey;
+  }
+
+  // 3. Try environment variable
+  const envKey = process.env.OPENROUTER_API_KEY;
+  if (envKey) {
+    return envKey;
+  }
+
+  return null;
+}
+
+/**
+ * Get Anthropic API key with fallback order:
+ * 1. CredentialManager (keychain/file storage)
+ * 2. Config value (if provided)
+ * 3. Environment variable (ANTHROPIC_API_KEY, then CV_ANTHROPIC_KEY)
+ */
+export async function getAnthropicApiKey(configApiKey?: string): Promise<string | null> {
+  // 1. Try CredentialManager first
+  try {
+    const manager = await getCredentialManager();
+    const key = await manager.getAnthropicKey();
+    if (key) {
+      return key;
+    }
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  // 2. Try config value
+  if (configApiKey) {
+    return configApiKey;
+  }
+
+  // 3. Try environment variables
+  const envKey = process.env.ANTHROPIC_API_KEY || process.env.CV_ANTHROPIC_KEY;
+  if (envKey) {
+    return envKey;
+  }
+
+  return null;
+}
+
+/**
+ * Get Google Gemini API key with fallback order:
+ * 1. CredentialManager (keychain/file storage)
+ * 2. Config value (if provided)
+ * 3. Environment variable (GEMINI_API_KEY, then GOOGLE_API_KEY)
+ */
+export async function getGeminiApiKey(configApiKey?: string): Promise<string | null> {
+  // 1. Try CredentialManager first
+  try {
+    const manager = await getCredentialManager();
+    const key = await manager.getGeminiKey();
+    if (key) {
+      return key;
+    }
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  // 2. Try config value
+  if (configApiKey) {
+    return configApiKey;
+  }
+
+  // 3. Try environment variables
+  const envKey = process.env.GEMINI_API_KEY || process.env.GOOGLE_API_KEY;
+  if (envKey) {
+    return envKey;
+  }
+
+  return null;
+}
+
+/**
+ * Get Cohere API key with fallback order:
+ * 1. CredentialManager (keychain/file storage)
+ * 2. Environment variable (COHERE_API_KEY, then CO_API_KEY)
+ */
+export async function getCohereApiKey(): Promise<string | null> {
+  try {
+    const manager = await getCredentialManager();
+    const key = await manager.getCohereKey();
+    if (key) {
+      return key;
+    }
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  return process.env.COHERE_API_KEY || process.env.CO_API_KEY || null;
+}
+
+/**
+ * Get Voyage AI API key with fallback order:
+ * 1. CredentialManager (keychain/file storage)
+ * 2. Environment variable (VOYAGE_API_KEY)
+ */
+export async function getVoyageApiKey(): Promise<string | null> {
+  try {
+    const manager = await getCredentialManager();
+    const key = await manager.getVoyageKey();
+    if (key) {
+      return key;
+    }
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  return process.env.VOYAGE_API_KEY || null;
+}
+
+/**
+ * HuggingFace Inference API (or TEI server) settings
+ */
+export interface HuggingFaceSettings {
+  /** Access token; may be unset for a TEI server */
+  apiKey?: string;
+  /** Embedding model chosen in `cv auth setup huggingface` */
+  model?: string;
+  /** TEI server URL; unset for the hosted Inference API */
+  baseUrl?: string;
+}
+
+/**
+ * Get HuggingFace settings, field by field:
+ * 1. CredentialManager (cv auth setup huggingface)
+ * 2. Environment variables (HF_TOKEN, then HUGGINGFACE_API_KEY; CV_HUGGINGFACE_URL)
+ * Returns null unless a token or a TEI server URL is available.
+ */
+export async function getHuggingFaceSettings(): Promise<HuggingFaceSettings | null> {
+  let stored = null;
+  try {
+    const manager = await getCredentialManager();
+    stored = await manager.getHuggingFace();
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  const apiKey = stored?.apiKey || process.env.HF_TOKEN || process.env.HUGGINGFACE_API_KEY;
+  const baseUrl = stored?.baseUrl || process.env.CV_HUGGINGFACE_URL;
+  if (!apiKey && !baseUrl) {
+    return null;
+  }
+
+  return { apiKey, model: stored?.model, baseUrl };
+}
+
+/**
+ * OpenAI-compatible gateway settings (LiteLLM, vLLM, Groq, Together, ...)
+ */
+export interface OpenAICompatibleSettings {
+  /** Base URL including the version path (e.g. http://localhost:4000/v1) */
+  baseUrl: string;
+  /** API key; may be unset for a gateway without authentication */
+  apiKey?: string;
+  /** Chat model chosen in `cv auth setup openai-compatible` */
+  chatModel?: string;
+  /** Embedding model chosen in `cv auth setup openai-compatible` */
+  embeddingModel?: string;
+}
+
+/**
+ * Get OpenAI-compatible gateway settings, field by field:
+ * 1. CredentialManager (cv auth setup openai-compatible)
+ * 2. Environment variables (CV_OPENAI_COMPATIBLE_URL, CV_OPENAI_COMPATIBLE_API_KEY)
+ * Returns null unless a base URL is available.
+ */
+export async function getOpenAICompatibleSettings(): Promise<OpenAICompatibleSettings | null> {
+  let stored = null;
+  try {
+    const manager = await getCredentialManager();
+    stored = await manager.getOpenAICompatible();
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  const baseUrl = stored?.baseUrl || process.env.CV_OPENAI_COMPATIBLE_URL;
+  if (!baseUrl) {
+    return null;
+  }
+
+  return {
+    baseUrl,
+    apiKey: stored?.apiKey || process.env.CV_OPENAI_COMPATIBLE_API_KEY,
+    chatModel: stored?.chatModel,
+    embeddingModel: stored?.embeddingModel
+  };
+}
+
+/**
+ * Ollama endpoint for local embeddings
+ */
+export interface OllamaEndpoint {
+  baseUrl: string;
+  model: string;
+}
+
+export const DEFAULT_OLLAMA_URL = 'http://localhost:11434';
+export const DEFAULT_OLLAMA_EMBEDDING_MODEL = 'nomic-embed-text';
+
+/**
+ * Get Ollama endpoint stored via `cv auth setup ollama` (null if not configured)
+ */
+export async function getStoredOllamaEndpoint(): Promise<OllamaEndpoint | null> {
+  try {
+    const manager = await getCredentialManager();
+    const cred = await manager.getOllamaEndpoint();
+    if (cred?.baseUrl) {
+      return { baseUrl: cred.baseUrl, model: cred.model || DEFAULT_OLLAMA_EMBEDDING_MODEL };
+    }
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+  return null;
+}
+
+/**
+ * Get Ollama endpoint with fallback order:
+ * 1. CredentialManager (cv auth setup ollama)
+ * 2. Config values (if provided)
+ * 3. Environment variables (CV_OLLAMA_URL / OLLAMA_URL, CV_EMBEDDING_MODEL)
+ * 4. Defaults (http://localhost:11434, nomic-embed-text)
+ */
+export async function getOllamaEndpoint(config?: { url?: string; model?: string }): Promise<OllamaEndpoint> {
+  const stored = await getStoredOllamaEndpoint();
+  if (stored) {
+    return stored;
+  }
+
+  return {
+    baseUrl: config?.url || process.env.CV_OLLAMA_URL || process.env.OLLAMA_URL || DEFAULT_OLLAMA_URL,
+    model: config?.model || process.env.CV_EMBEDDING_MODEL || DEFAULT_OLLAMA_EMBEDDING_MODEL,
+  };
+}
+
+/**
+ * Azure OpenAI resource and deployment names
+ */
+export interface AzureOpenAISettings {
+  endpoint: string;
+  apiKey: string;
+  apiVersion: string;
+  /** Deployment for explain/chat (deployment names differ from model names) */
+  chatDeployment?: string;
+  /** Deployment for sync embeddings */
+  embeddingDeployment?: string;
+}
+
+/**
+ * Get Azure OpenAI settings, field by field:
+ * 1. CredentialManager (cv auth setup azure)
+ * 2. Config values (`azure` in .cv/config.json)
+ * 3. Environment variables (AZURE_OPENAI_ENDPOINT, AZURE_OPENAI_API_KEY, AZURE_OPENAI_API_VERSION,
+ *    AZURE_OPENAI_CHAT_DEPLOYMENT, AZURE_OPENAI_EMBEDDING_DEPLOYMENT)
+ * Returns null unless an endpoint and API key are available.
+ */
+export async function getAzureOpenAISettings(config?: CVConfig['azure']): Promise<AzureOpenAISettings | null> {
+  let stored = null;
+  try {
+    const manager = await getCredentialManager();
+    stored = await manager.getAzureOpenAI();
+  } catch (error) {
+    // Credential manager failed, continue to fallbacks
+  }
+
+  const endpoint = stored?.endpoint || config?.endpoint || process.env.AZURE_OPENAI_ENDPOINT;
+  const apiKey = stored?.apiKey || process.env.AZURE_OPENAI_API_KEY;
+  if (!endpoint || !apiKey) {
+    return null;
+  }
+
+  return {
+    endpoint,
+    apiKey,
+    apiVersion: stored?.apiVersion || config?.apiVersion || process.env.AZURE_OPENAI_API_VERSION || DEFAULT_AZURE_API_VERSION,
+    chatDeployment: stored?.chatDeployment || config?.chatDeployment || process.env.AZURE_OPENAI_CHAT_DEPLOYMENT,
+    embeddingDeployment: stored?.embeddingDeployment || config?.embeddingDeployment || process.env.AZURE_OPENAI_EMBEDDING_DEPLOYMENT,
+  };
+}
+
+/**
+ * Embedding credentials with provider info
+ */
+export interface EmbeddingCredentials {
+  openrouterApiKey?: string;
+  openaiApiKey?: string;
+  /** Ollama base URL (set when provider is 'ollama') */
+  ollamaUrl?: string;
+  /** Ollama embedding model (set when provider is 'ollama') */
+  ollamaModel?: string;
+  /** Azure OpenAI embedding deployment (set when provider is 'azure') */
+  azure?: AzureOpenAIDeployment;
+  /** Gemini API key (set when provider is 'gemini') */
+  geminiApiKey?: string;
+  /** Cohere API key (set when provider is 'cohere') */
+  cohereApiKey?: string;
+  /** Voyage AI API key (set when provider is 'voyage') */
+  voyageApiKey?: string;
+  /** HuggingFace token (set when provider is 'huggingface'; may be unset for a TEI server) */
+  huggingfaceApiKey?: string;
+  /** TEI server URL (set when provider is 'huggingface' and one is configured) */
+  huggingfaceUrl?: string;
+  /** HuggingFace embedding model (set when provider is 'huggingface' and one was chosen) */
+  huggingfaceModel?: string;
+  /** Gateway base URL and key (set when provider is 'openai-compatible') */
+  openaiCompatible?: { baseUrl: string; apiKey?: string };
+  /** Gateway embedding model (set when provider is 'openai-compatible' and one was chosen) */
+  openaiCompatibleModel?: string;
+  provider: 'openrouter' | 'openai' | 'ollama' | 'azure' | 'gemini' | 'cohere' | 'voyage' | 'huggingface' | 'openai-compatible';
+}
+
+/**
+ * Get embedding credentials with provider priority: OpenRouter > OpenAI > Azure OpenAI > Ollama
+ * An explicit `provider: 'ollama'` preference selects Ollama even when cloud keys
+ * exist, so code never leaves the machine. `provider: 'azure'`, `'gemini'`, `'cohere'`,
+ * `'voyage'`, `'huggingface'` and `'openai-compatible'` likewise use only that provider
+ * (none of them is picked automatically).
+ * Returns both keys if available so VectorManager can handle fallbacks
+ */
+export async function getEmbeddingCredentials(config?: {
+  openaiKey?: string;
+  openRouterKey?: string;
+  provider?: string;
+  ollamaUrl?: string;
+  ollamaModel?: string;
+  azure?: CVConfig['azure'];
+}): Promise<EmbeddingCredentials> {
+  if (config?.provider === 'azure') {
+    const azure = await getAzureOpenAISettings(config.azure);
+    if (!azure?.embeddingDeployment) {
+      throw new Error('Azure OpenAI embedding deployment not configured. Run: cv auth setup azure');
+    }
+    return { azure: toAzureDeployment(azure, azure.embeddingDeployment), provider: 'azure' };
+  }
+
+  if (config?.provider === 'gemini') {
+    const geminiKey = await getGeminiApiKey();
+    if (!geminiKey) {
+      throw new Error('Gemini API key not found. Run: cv auth setup gemini');
+    }
+    return { geminiApiKey: geminiKey, provider: 'gemini' };
+  }
+
+  if (config?.provider === 'cohere') {
+    const cohereKey = await getCohereApiKey();
+    if (!cohereKey) {
+      throw new Error('Cohere API key not found. Run: cv auth setup cohere');
+    }
+    return { cohereApiKey: cohereKey, provider: 'cohere' };
+  }
+
+  if (config?.provider === 'voyage') {
+    const voyageKey = await getVoyageApiKey();
+    if (!voyageKey) {
+      throw new Error('Voyage AI API key not found. Run: cv auth setup voyage');
+    }
+    return { voyageApiKey: voyageKey, provider: 'voyage' };
+  }
+
+  if (config?.provider === 'huggingface') {
+    const huggingface = await getHuggingFaceSettings();
+    if (!huggingface) {
+      throw new Error('HuggingFace token not found. Run: cv auth setup huggingface');
+    }
+    return {
+      huggingfaceApiKey: huggingface.apiKey,
+      huggingfaceUrl: huggingface.baseUrl,
+      huggingfaceModel: huggingface.model,
+      provider: 'huggingface'
+    };
+  }
+
+  if (config?.provider === 'openai-compatible') {
+    const gateway = await getOpenAICompatibleSettings();
+    if (!gateway) {
+      throw new Error('OpenAI-compatible base URL not configured. Run: cv auth setup openai-compatible');
+    }
+    return {
+      openaiCompatible: { baseUrl: gateway.baseUrl, apiKey: gateway.apiKey },
+      openaiCompatibleModel: gateway.embeddingModel,
+      provider: 'openai-compatible'
+    };
+  }
+
+  if (config?.provider === 'ollama') {
+    const endpoint = await getOllamaEndpoint({ url: config.ollamaUrl, model: config.ollamaModel });
+    return {
+      ollamaUrl: endpoint.baseUrl,
+      ollamaModel: endpoint.model,
+      provider: 'ollama'
+    };
+  }
+
+  // Get both keys
+  const openRouterKey = await getOpenRouterApiKey(config?.openRouterKey);
+  const openaiKey = await getOpenAIApiKey(config?.openaiKey);
+
+  // Determine primary provider based on what's available
+  if (openRouterKey || openaiKey) {
+    return {
+      openrouterApiKey: openRouterKey || undefined,
+      openaiApiKey: openaiKey || undefined,
+      provider: openRouterKey ? 'openrouter' : 'openai'
+    };
+  }
+
+  const azure = await getAzureOpenAISettings(config?.azure);
+  if (azure?.embeddingDeployment) {
+    return { azure: toAzureDeployment(azure, azure.embeddingDeployment), provider: 'azure' };
+  }
+
+  const endpoint = await getOllamaEndpoint({ url: config?.ollamaUrl, model: config?.ollamaModel });
+  return {
+    ollamaUrl: endpoint.baseUrl,
+    ollamaModel: endpoint.model,
+    provider: 'ollama'
+  };
+}
+
+/**
+ * createVectorManager options for resolved embedding credentials: the
+ * provider's key or endpoint, the model chosen with it (ahead of config
+ * embedding.model), shortened dimensions and the embedding.providers fallbacks
+ */
+export function embeddingVectorOptions(creds: EmbeddingCredentials, config: CVConfig): Partial<VectorManagerOptions> {
+  return {
+    openrouterApiKey: creds.openrouterApiKey,
+    openaiApiKey: creds.openaiApiKey,
+    ollamaUrl: creds.ollamaUrl,
+    azure: creds.azure,
+    geminiApiKey: creds.geminiApiKey,
+    cohereApiKey: creds.cohereApiKey,
+    voyageApiKey: creds.voyageApiKey,
+    huggingfaceApiKey: creds.huggingfaceApiKey,
+    huggingfaceUrl: creds.huggingfaceUrl,
+    openaiCompatible: creds.openaiCompatible,
+    embeddingModel: creds.ollamaModel || creds.huggingfaceModel || creds.openaiCompatibleModel || config.embedding?.model,
+    embeddingDimensions: config.embedding?.outputDimensions,
+    embeddingFallbacks: config.embedding?.providers
+  };
+}
+
+/**
+ * Create a VectorManager (not yet connected) for config.vector that embeds
+ * with config embedding.provider, else OpenRouter > OpenAI > Azure > Ollama.
+ * `options` are added last, e.g. repoId, indexDir, efSearch or a signal.
+ * Throws if the configured provider has no credentials.
+ */
+export async function createVectorManagerFromCredentials(
+  config: CVConfig,
+  options: Partial<VectorManagerOptions> = {}
+): Promise<VectorManager> {
+  // LM Studio is found at its URL; every other provider by its stored credentials
+  if (config.embedding?.provider === 'lmstudio') {
+    return createVectorManager({
+      url: config.vector.url,
+      ...getVectorBackendOptions(config.vector),
+      lmstudioUrl: getLMStudioUrl(config.embedding.url),
+      embeddingModel: config.embedding.model,
+      embeddingDimensions: config.embedding.outputDimensions,
+      embeddingFallbacks: config.embedding.providers,
+      ...options
+    });
+  }
+
+  const creds = await getEmbeddingCredentials({
+    provider: config.embedding?.provider,
+    ollamaUrl: config.embedding?.url,
+    ollamaModel: config.embedding?.model,
+    azure: config.azure
+  });
+
+  return createVectorManager({
+    url: config.vector.url,
+    ...getVectorBackendOptions(config.vector),
+    ...embeddingVectorOptions(creds, config),
+    ...options
+  });
+}
+
+/**
+ * Bind Azure settings to one deployment
+ */
+export function toAzureDeployment(settings: AzureOpenAISettings, deployment: string): AzureOpenAIDeployment {
+  return {
+    endpoint: settings.endpoint,
+    apiKey: settings.apiKey,
+    apiVersion: settings.apiVersion,
+    deployment
+  };
+}
+
+/**
+ * Get embedding API key (tries OpenRouter first, then OpenAI)
+ * OpenRouter is preferred due to better model availability
+ * @deprecated Use getEmbeddingCredentials() instead for proper provider handling
+ */
+export async function getEmbeddingApiKey(config?: { openaiKey?: string; openRouterKey?: string }): Promise<string | null> {
+  // Try OpenRouter first (preferred - better model availability)
+  const openRouterKey = await getOpenRouterApiKey(config?.openRouterKey);
+  if (openRouterKey) {
+    return openRouterKey;
+  }
+
+  // Fall back to OpenAI
+  const openaiKey = await getOpenAIApiKey(config?.openaiKey);
+  if (openaiKey) {
+    return openaiKey;
+  }
+
+  return null;
+}
+
+/**
+ * Get AI/LLM API key for chat/explain/review commands
+ * Tries Anthropic first, then OpenAI, then OpenRouter
+ */
+export async function getAIApiKey(config?: {
+  anthropicKey?: string;
+  openaiKey?: string;
+  openRouterKey?: string;
+}): Promise<{ key: string; provider: 'anthropic' | 'openai' | 'openrouter' } | null> {
+  // Try Anthropic first (preferred for Claude)
+  const anthropicKey = await getAnthropicApiKey(config?.anthropicKey);
+  if (anthropicKey) {
+    return { key: anthropicKey, provider: 'anthropic' };
+  }
+
+  // Try OpenAI
+  const openaiKey = await getOpenAIApiKey(config?.openaiKey);
+  if (openaiKey) {
+    return { key: openaiKey, provider: 'openai' };
+  }
+
+  // Try OpenRouter
+  const openRouterKey = await getOpenRouterApiKey(config?.openRouterKey);
+  if (openRouterKey) {
+    return { key: openRouterKey, provider: 'openrouter' };
+  }
+
+  return null;
+}
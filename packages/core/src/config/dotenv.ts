@@ -0,0 +1,101 @@
+/**
+ * .env Files
+ *
+ * Loads KEY=value lines into the environment, so provider keys kept in a
+ * repository's .env (OPENAI_API_KEY, OPENROUTER_API_KEY, ...) reach config
+ * ${VAR} references and credential detection without being exported in the
+ * shell. Variables that are already set are never overridden.
+ *
+ * The format is the common subset of dotenv: `#` comments, an optional
+ * `export ` prefix, single-quoted values taken literally, double-quoted
+ * values with \n escapes and line breaks, and unquoted values ending at a
+ * ` #` comment.
+ */
+
+import { promises as fs } from 'fs';
+
+const LINE = /^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_.]*)\s*=\s*(.*)$/;
+
+export interface DotenvResult {
+  file: string;
+  /** Variables set from the file */
+  loaded: string[];
+  /** Variables in the file left alone because they were already set */
+  skipped: string[];
+}
+
+/**
+ * The variables of a .env file, in file order. A name given twice keeps its
+ * last value.
+ */
+export function parseDotenv(content: string): Record<string, string> {
+  const vars: Record<string, string> = {};
+  const lines = content.replace(/\r\n?/g, '\n').split('\n');
+
+  for (let i = 0; i < lines.length; i++) {
+    const match = LINE.exec(lines[i]);
+    if (!match) continue;
+    const [, name, rest] = match;
+
+    const quote = rest[0];
+    if (quote === '"' || quote === "'") {
+      // A quoted value may continue on the following lines until its closing quote
+      let raw = rest.slice(1);
+      let end = findClosingQuote(raw, quote);
+      while (end === -1 && i + 1 < lines.length) {
+        raw += '\n' + lines[++i];
+        end = findClosingQuote(raw, quote);
+      }
+      const value = end === -1 ? raw : raw.slice(0, end);
+      vars[name] = quote === '"' ? unescapeDoubleQuoted(value) : value;
+    } else {
+      vars[name] = rest.replace(/\s+#.*$/, '').trim();
+    }
+  }
+  return vars;
+}
+
+/**
+ * Set the variables of a .env file that are not already in `env`. A
+ * missing file loads nothing unless `required`.
+ */
+export async function loadDotenv(
+  file: string,
+  options: { required?: boolean; env?: NodeJS.ProcessEnv } = {}
+): Promise<DotenvResult> {
+  const env = options.env ?? process.env;
+  const result: DotenvResult = { file, loaded: [], skipped: [] };
+
+  let content: string;
+  try {
+    content = await fs.readFile(file, 'utf-8');
+  } catch (error: any) {
+    if (error.code === 'ENOENT' && !options.required) return result;
+    throw new Error(`Cannot read env file ${file}: ${error.code === 'ENOENT' ? 'no such file' : error.message}`);
+  }
+
+  for (const [name, value] of Object.entries(parseDotenv(content))) {
+    if (env[name] !== undefined) {
+      result.skipped.push(name);
+    } else {
+      env[name] = value;
+      result.loaded.push(name);
+    }
+  }
+  return result;
+}
+
+function findClosingQuote(text: string, quote: string): number {
+  for (let i = 0; i < text.length; i++) {
+    if (quote === '"' && text[i] === '\\') {
+      i++;
+    } else if (text[i] === quote) {
+      return i;
+    }
+  }
+  return -1;
+}
+
+function unescapeDoubleQuoted(value: string): string {
+  return value.replace(/\\([nrt"\\$])/g, (_, c: string) => ({ n: '\n', r: '\r', t: '\t' } as Record<string, string>)[c] ?? c);
+}
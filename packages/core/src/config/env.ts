@@ -0,0 +1,105 @@
+/**
+ * Environment Variable References
+ *
+ * String values in .cv/config.json may reference environment variables as
+ * ${VAR}, e.g. "apiKey": "${OPENROUTER_API_KEY}", so a shared config can be
+ * committed and its secrets injected per environment. References are
+ * expanded when the config is loaded. A value whose variable is unset only
+ * fails when it is read, so the variables of an unused provider need not be
+ * set. `$${VAR}` stands for a literal `${VAR}`.
+ */
+
+import { ConfigError } from '@cv-git/shared';
+
+const ENV_REFERENCE = /\$(\$)?\{([A-Za-z_][A-Za-z0-9_]*)\}/g;
+
+/** A value made only of references, which `cv config list` shows as written */
+const ONLY_REFERENCES = /^(?:\$\{[A-Za-z_][A-Za-z0-9_]*\})+$/;
+
+export interface ExpandedValue {
+  value: string;
+  /** Referenced variables that are not set */
+  missing: string[];
+}
+
+/**
+ * Expand the ${VAR} references in a string. An unset variable expands to
+ * the empty string and is reported in `missing`.
+ */
+export function expandEnvReferences(value: string, env: NodeJS.ProcessEnv = process.env): ExpandedValue {
+  const missing: string[] = [];
+  const expanded = value.replace(ENV_REFERENCE, (reference, escaped: string | undefined, name: string) => {
+    if (escaped) {
+      return reference.slice(1);
+    }
+    const resolved = env[name];
+    if (resolved === undefined) {
+      missing.push(name);
+      return '';
+    }
+    return resolved;
+  });
+  return { value: expanded, missing };
+}
+
+/**
+ * Whether a value is nothing but ${VAR} references (no literal secret in it)
+ */
+export function isEnvReference(value: string): boolean {
+  return ONLY_REFERENCES.test(value);
+}
+
+/**
+ * A copy of the config with references expanded in every string value.
+ * A value referencing an unset variable becomes a property that throws a
+ * ConfigError naming the key and the variable when it is read.
+ */
+export function interpolateConfigEnv<T>(config: T, env: NodeJS.ProcessEnv = process.env): T {
+  return interpolate(config, '', env) as T;
+}
+
+function interpolate(value: unknown, key: string, env: NodeJS.ProcessEnv): unknown {
+  if (typeof value === 'string') {
+    return expandEnvReferences(value, env).value;
+  }
+  if (Array.isArray(value)) {
+    return value.map((item, i) => interpolate(item, `${key}[${i}]`, env));
+  }
+  if (!value || typeof value !== 'object') {
+    return value;
+  }
+
+  const result: Record<string, unknown> = {};
+  for (const [name, child] of Object.entries(value)) {
+    const childKey = key ? `${key}.${name}` : name;
+    const missing = missingReferences(child, env);
+    if (missing.length > 0) {
+      Object.defineProperty(result, name, {
+        enumerable: true,
+        configurable: true,
+        get: () => {
+          const references = missing.map(variable => '${' + variable + '}').join(', ');
+          throw new ConfigError(
+            `${childKey} references ${references}, which ${missing.length === 1 ? 'is' : 'are'} not set in the environment`
+          );
+        }
+      });
+    } else {
+      result[name] = interpolate(child, childKey, env);
+    }
+  }
+  return result;
+}
+
+/**
+ * Unset variables referenced by a string, or by the strings of a list
+ */
+function missingReferences(value: unknown, env: NodeJS.ProcessEnv): string[] {
+  if (typeof value === 'string') {
+    return [...new Set(expandEnvReferences(value, env).missing)];
+  }
+  if (Array.isArray(value)) {
+    return [...new Set(value.flatMap(item => missingReferences(item, env)))];
+  }
+  return [];
+}
@@ -4,14 +4,20 @@
 
 import * as path from 'path';
 import * as fs from 'fs/promises';
-import { CVConfig, ConfigError } from '@cv-git/shared';
+import { CVConfig, ConfigError, ConfigProfile } from '@cv-git/shared';
 import { getCVDir, ensureDir, loadSharedCredentials } from '@cv-git/shared';
 import { generateRepoId, getGraphDatabaseName } from '../storage/repo-id.js';
 
 // Re-export service URL utilities
 export * from './service-urls.js';
+export * from './templates.js';
+export * from './env.js';
+export * from './dotenv.js';
 
 import { getFalkorDbUrl, getQdrantUrl, getOllamaUrl } from './service-urls.js';
+import { validateConfigValue, getConfigKeySpec, writeConfigKey, deleteConfigKey } from './keys.js';
+import { CONFIG_TEMPLATES, ConfigTemplateName } from './templates.js';
+import { interpolateConfigEnv } from './env.js';
 
 const DEFAULT_CONFIG: CVConfig = {
   version: '0.1.0',
@@ -124,21 +130,58 @@ const DEFAULT_CONFIG: CVConfig = {
   }
 };
 
+/**
+ * Config file to load instead of .cv/config.json (--config), relative to the cwd
+ */
+export function getConfigPathOverride(): string | undefined {
+  return process.env.CV_CONFIG ? path.resolve(process.env.CV_CONFIG) : undefined;
+}
+
+/**
+ * Profile selected with --profile or CV_PROFILE
+ */
+export function getSelectedProfile(): string | undefined {
+  return process.env.CV_PROFILE || undefined;
+}
+
+/**
+ * The config as seen with a profile applied: each section the profile sets
+ * replaces the base section, and defaults fill in what the profile leaves out
+ */
+export function applyConfigProfile(config: CVConfig, name: string): CVConfig {
+  const profile = config.profiles?.[name];
+  if (!profile) {
+    const defined = Object.keys(config.profiles || {});
+    throw new ConfigError(
+      `Unknown profile: ${name} (${defined.length > 0 ? `defined: ${defined.join(', ')}` : 'no profiles in the config'})`
+    );
+  }
+  return { ...config, ...profile } as CVConfig;
+}
+
 export class ConfigManager {
+  /** The effective config with ${VAR} references expanded (what get() returns) */
   private config: CVConfig | null = null;
+  /** The effective config as written, references unexpanded (what `cv config list` shows) */
+  private literalConfig: CVConfig | null = null;
   private configPath: string | null = null;
+  /** The file's contents merged with defaults, without the profile (what save() writes) */
+  private fileConfig: CVConfig | null = null;
+  private profile?: string;
 
   /**
-   * Initialize configuration for a repository
+   * Initialize configuration for a repository, optionally from a provider
+   * template (cv init --template). Overwrites an existing config.
    */
-  async init(repoRoot: string, repoName: string): Promise<CVConfig> {
+  async init(repoRoot: string, repoName: string, template?: ConfigTemplateName): Promise<CVConfig> {
     const cvDir = getCVDir(repoRoot);
     await ensureDir(cvDir);
 
     const repoId = generateRepoId(repoRoot);
+    const base: CVConfig = template ? this.deepMerge(DEFAULT_CONFIG, CONFIG_TEMPLATES[template].config) : DEFAULT_CONFIG;
 
     const config: CVConfig = {
-      ...DEFAULT_CONFIG,
+      ...base,
       repository: {
         root: repoRoot,
         name: repoName,
@@ -146,7 +189,7 @@ export class ConfigManager {
         repoId,
       },
       graph: {
-        ...DEFAULT_CONFIG.graph,
+        ...base.graph,
         database: getGraphDatabaseName(repoId),
       },
     };
@@ -155,57 +198,83 @@ export class ConfigManager {
     await fs.writeFile(configPath, JSON.stringify(config, null, 2));
 
     this.config = config;
+    this.literalConfig = config;
+    this.fileConfig = config;
     this.configPath = configPath;
+    this.profile = undefined;
 
     return config;
   }
 
   /**
-   * Load configuration from repository
+   * Load configuration from repository (or the --config file), with the
+   * --profile / CV_PROFILE profile applied
    */
   async load(repoRoot: string): Promise<CVConfig> {
     const cvDir = getCVDir(repoRoot);
-    const configPath = path.join(cvDir, 'config.json');
+    const override = getConfigPathOverride();
+    const configPath = override || path.join(cvDir, 'config.json');
 
     try {
       const data = await fs.readFile(configPath, 'utf-8');
       const config = JSON.parse(data) as CVConfig;
 
       // Merge with defaults to handle missing fields
-      this.config = this.mergeWithDefaults(config);
+      this.fileConfig = this.mergeWithDefaults(config);
       this.configPath = configPath;
+      this.profile = getSelectedProfile();
 
       // Auto-migrate legacy configs that use hardcoded 'cv-git' database
-      if (this.config.graph.database === 'cv-git') {
-        const repoId = this.config.repository.repoId || generateRepoId(repoRoot);
-        this.config.repository.repoId = repoId;
-        this.config.graph.database = getGraphDatabaseName(repoId);
+      if (this.fileConfig.graph.database === 'cv-git') {
+        const repoId = this.fileConfig.repository.repoId || generateRepoId(repoRoot);
+        this.fileConfig.repository.repoId = repoId;
+        this.fileConfig.graph.database = getGraphDatabaseName(repoId);
         // Persist the migration
         await this.save();
-      } else if (!this.config.repository.repoId) {
+      } else if (!this.fileConfig.repository.repoId) {
         // Config has a custom database name but no repoId — store repoId
-        this.config.repository.repoId = generateRepoId(repoRoot);
+        this.fileConfig.repository.repoId = generateRepoId(repoRoot);
         await this.save();
       }
 
-      return this.config;
+      this.resolveConfig();
+      return this.config!;
     } catch (error: any) {
+      if (error instanceof ConfigError) {
+        throw error;
+      }
       if (error.code === 'ENOENT') {
-        throw new ConfigError(`CV-Git not initialized in ${repoRoot}. Run 'cv init' first.`);
+        throw new ConfigError(override
+          ? `Config file not found: ${override}`
+          : `CV-Git not initialized in ${repoRoot}. Run 'cv init' first.`);
       }
       throw new ConfigError(`Failed to load config: ${error.message}`, error);
     }
   }
 
   /**
-   * Save configuration to disk
+   * Save configuration to disk (the base config and its profiles; a profile's values stay inside it)
    */
   async save(): Promise<void> {
-    if (!this.config || !this.configPath) {
+    if (!this.fileConfig || !this.configPath) {
       throw new ConfigError('No configuration loaded');
     }
 
-    await fs.writeFile(this.configPath, JSON.stringify(this.config, null, 2));
+    await fs.writeFile(this.configPath, JSON.stringify(this.fileConfig, null, 2));
+  }
+
+  /**
+   * Path of the loaded config file
+   */
+  getConfigPath(): string | null {
+    return this.configPath;
+  }
+
+  /**
+   * Name of the profile applied by the last load, if any
+   */
+  getProfile(): string | undefined {
+    return this.profile;
   }
 
   /**
@@ -219,19 +288,115 @@ export class ConfigManager {
   }
 
   /**
-   * Update configuration
+   * Current configuration as written, with ${VAR} references unexpanded
    */
-  async update(updates: Partial<CVConfig>): Promise<CVConfig> {
-    if (!this.config) {
+  getLiteral(): CVConfig {
+    if (!this.literalConfig) {
+      throw new ConfigError('Configuration not loaded. Call load() first.');
+    }
+    return this.literalConfig;
+  }
+
+  /**
+   * Default configuration, for commands that can run outside a repository
+   */
+  getDefaults(): CVConfig {
+    return structuredClone(DEFAULT_CONFIG);
+  }
+
+  /**
+   * Update configuration. Sections are merged key by key, so only the
+   * changed values need to be given (copying a section from get() would
+   * write its expanded ${VAR} references to the file). Sections the active
+   * profile overrides are updated in the profile, the rest in the base config.
+   */
+  async update(updates: { [K in keyof CVConfig]?: Partial<CVConfig[K]> }): Promise<CVConfig> {
+    if (!this.config || !this.fileConfig) {
+      throw new ConfigError('Configuration not loaded');
+    }
+
+    for (const [key, value] of Object.entries(updates)) {
+      const profile: ConfigProfile | undefined = this.profile ? this.fileConfig.profiles?.[this.profile] : undefined;
+      if (profile && key in profile) {
+        Object.assign(profile, this.deepMerge(profile, { [key]: value }));
+      } else {
+        this.fileConfig = this.deepMerge(this.fileConfig, { [key]: value });
+      }
+    }
+    this.resolveConfig();
+    await this.save();
+
+    return this.config!;
+  }
+
+  /**
+   * Set one dotted key, e.g. search.minScore. With a profile selected the
+   * value goes into the profile, which first gets its own copy of the section.
+   */
+  async setValue(key: string, value: unknown): Promise<CVConfig> {
+    if (!this.fileConfig) {
       throw new ConfigError('Configuration not loaded');
     }
 
-    this.config = this.deepMerge(this.config, updates);
+    const fileKey = this.locateKey(key, true);
+    validateConfigValue(fileKey, value);
+    writeConfigKey(this.fileConfig, fileKey, value);
+    this.resolveConfig();
     await this.save();
 
     return this.config!;
   }
 
+  /**
+   * Remove one dotted key (from the selected profile, if any), so its
+   * default applies again. Returns whether it was set.
+   */
+  async unsetValue(key: string): Promise<boolean> {
+    if (!this.fileConfig) {
+      throw new ConfigError('Configuration not loaded');
+    }
+
+    const fileKey = this.locateKey(key, false);
+    getConfigKeySpec(fileKey);
+    if (!deleteConfigKey(this.fileConfig, fileKey)) {
+      return false;
+    }
+    this.resolveConfig();
+    await this.save();
+
+    return true;
+  }
+
+  /**
+   * Where a key lives in the file: under the selected profile, unless it
+   * already names a profile. `copySection` gives the profile a copy of the
+   * base section first, since a profile's section replaces the base one.
+   */
+  private locateKey(key: string, copySection: boolean): string {
+    if (!this.profile || key.startsWith('profiles.')) {
+      return key;
+    }
+
+    const profile = this.fileConfig!.profiles![this.profile] as Record<string, unknown>;
+    const section = key.split('.')[0];
+    const base = (this.fileConfig as unknown as Record<string, unknown>)[section];
+    if (copySection && profile[section] === undefined && base !== undefined) {
+      profile[section] = JSON.parse(JSON.stringify(base));
+    }
+    return `profiles.${this.profile}.${key}`;
+  }
+
+  /**
+   * Apply the selected profile to the file config, fill in defaults, and
+   * expand environment variable references
+   */
+  private resolveConfig(): void {
+    this.literalConfig = this.profile
+      ? this.mergeWithDefaults(applyConfigProfile(this.fileConfig!, this.profile))
+      : this.fileConfig!;
+    this.config = interpolateConfigEnv(this.literalConfig);
+  }
+
   /**
    * Get API key for a service
    * Checks: repo config > shared ControlVector credentials > environment variables
@@ -357,3 +522,12 @@ export {
   setConfigValue,
   getConfigValue,
 } from './cv-git-config.js';
+
+// Re-export proxy and CA bundle configuration
+export * from './proxy.js';
+
+// Re-export config key specs for cv config get/set/unset
+export * from './keys.js';
+
+// Re-export credential resolution shared by the CLI and the MCP server
+export * from './credentials.js';
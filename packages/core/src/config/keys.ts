@@ -0,0 +1,334 @@
+/**
+ * Config Keys
+ *
+ * The dotted keys of .cv/config.json that `cv config get/set/unset` accept,
+ * with the type and range each one allows. Values given on the command line
+ * are parsed to the key's type and checked before they are written.
+ */
+
+import { CVConfig, ConfigError } from '@cv-git/shared';
+import { isEnvReference } from './env.js';
+
+export type ConfigValueType = 'string' | 'number' | 'boolean' | 'string[]';
+
+export interface ConfigKeySpec {
+  type: ConfigValueType;
+  /** Allowed values (strings, or numbers for numeric keys) */
+  values?: readonly (string | number)[];
+  min?: number;
+  max?: number;
+  integer?: boolean;
+  /** Masked by `cv config list` */
+  secret?: boolean;
+}
+
+const str: ConfigKeySpec = { type: 'string' };
+const bool: ConfigKeySpec = { type: 'boolean' };
+const list: ConfigKeySpec = { type: 'string[]' };
+const secret: ConfigKeySpec = { type: 'string', secret: true };
+const count: ConfigKeySpec = { type: 'number', integer: true, min: 1 };
+const nonNegative: ConfigKeySpec = { type: 'number', min: 0 };
+const similarity: ConfigKeySpec = { type: 'number', min: 0, max: 1 };
+const temperature: ConfigKeySpec = { type: 'number', min: 0, max: 2 };
+const oneOf = (...values: string[]): ConfigKeySpec => ({ type: 'string', values });
+const listOf = (...values: string[]): ConfigKeySpec => ({ type: 'string[]', values });
+
+const MODEL_COMMANDS = ['explain', 'do', 'review', 'chat', 'code', 'test', 'refactor', 'diff', 'why', 'docs', 'summarize'];
+const PROMPT_COMMANDS = ['explain', 'do', 'review', 'chat'];
+const REVIEW_SEVERITIES = ['low', 'medium', 'high', 'critical'];
+
+export const CONFIG_KEYS: Record<string, ConfigKeySpec> = {
+  'llm.provider': oneOf('anthropic', 'openai', 'ollama'),
+  'llm.model': str,
+  'llm.apiKey': secret,
+  'llm.maxTokens': count,
+  'llm.temperature': temperature,
+  'ai.provider': oneOf('anthropic', 'openai', 'ollama', 'azure', 'gemini', 'openai-compatible'),
+  'ai.model': str,
+  'ai.apiKey': secret,
+  'ai.maxTokens': count,
+  'ai.temperature': temperature,
+  'ai.contextWindow': count,
+  'ai.timeout': nonNegative,
+  'ai.promptCaching': bool,
+  'ai.providers': listOf('anthropic', 'azure', 'gemini', 'openai-compatible'),
+  'embedding.provider': oneOf('openrouter', 'openai', 'ollama', 'lmstudio', 'azure', 'gemini', 'cohere', 'voyage', 'huggingface', 'openai-compatible'),
+  'embedding.providers': listOf('openrouter', 'openai', 'ollama', 'lmstudio', 'azure', 'gemini', 'cohere', 'voyage', 'huggingface', 'openai-compatible'),
+  'embedding.model': str,
+  'embedding.apiKey': secret,
+  'embedding.url': str,
+  'embedding.dimensions': count,
+  'embedding.outputDimensions': count,
+  'embedding.batchSize': count,
+  'embedding.maxBatchTokens': count,
+  'embedding.concurrency': count,
+  'embedding.maxRetryAttempts': count,
+  'embedding.cacheMaxBytes': { ...nonNegative, integer: true },
+  'embedding.timeout': nonNegative,
+  'azure.endpoint': str,
+  'azure.apiVersion': str,
+  'azure.chatDeployment': str,
+  'azure.embeddingDeployment': str,
+  ...Object.fromEntries(MODEL_COMMANDS.map(command => [`models.${command}`, str])),
+  ...Object.fromEntries(PROMPT_COMMANDS.map(command => [`prompts.${command}`, str])),
+  'review.disable': list,
+  'commit.convention': oneOf('conventional', 'angular', 'gitmoji', 'ticket'),
+  'commit.template': str,
+  'commit.scopes': list,
+  'commit.maxSubjectLength': count,
+  'commit.ticketPattern': str,
+  'commit.rules': list,
+  'search.minScore': similarity,
+  'search.topK': count,
+  'search.dedupeThreshold': similarity,
+  'search.efSearch': count,
+  'search.excludeTests': bool,
+  'search.rerank.enabled': bool,
+  'search.rerank.provider': oneOf('cohere', 'local'),
+  'search.rerank.model': str,
+  'search.rerank.url': str,
+  'search.rerank.candidates': count,
+  'network.proxy': str,
+  'network.noProxy': str,
+  'network.caBundle': str,
+  'cache.responseTtl': { ...nonNegative, integer: true },
+  'usage.enabled': bool,
+  'usage.footer': bool,
+  'editor.openCommand': str,
+  'followUps.enabled': bool,
+  'grounding.enabled': bool,
+  'redaction.enabled': bool,
+  'redaction.patterns': list,
+  'graph.provider': oneOf('falkordb', 'falkordblite', 'ladybugdb', 'auto'),
+  'graph.url': str,
+  'graph.embedded': bool,
+  'graph.database': str,
+  'vector.provider': oneOf('qdrant', 'chroma', 'pgvector', 'local'),
+  'vector.url': str,
+  'vector.embedded': bool,
+  'vector.pgvector.connectionString': secret,
+  'vector.pgvector.table': str,
+  'vector.pgvector.indexType': oneOf('hnsw', 'ivfflat'),
+  'vector.pgvector.dimensions': count,
+  'vector.qdrant.apiKey': secret,
+  'vector.qdrant.collection': str,
+  'vector.hnsw.enabled': bool,
+  'vector.hnsw.m': count,
+  'vector.hnsw.efConstruction': count,
+  'vector.hnsw.minPoints': { ...nonNegative, integer: true },
+  'vector.collections.codeChunks': str,
+  'vector.collections.docstrings': str,
+  'vector.collections.commits': str,
+  'vector.collections.documentChunks': str,
+  'sync.autoSync': bool,
+  'sync.syncOnCommit': bool,
+  'sync.excludePatterns': list,
+  'sync.includeLanguages': list,
+  'sync.maxChunkLines': count,
+  'sync.chunkOverlapLines': { ...nonNegative, integer: true },
+  'sync.maxFileSize': count,
+  'sync.stripComments': oneOf('none', 'headers', 'all'),
+  'sync.limits.maxFiles': { ...nonNegative, integer: true },
+  'sync.limits.maxTotalBytes': { ...nonNegative, integer: true },
+  'sync.limits.maxBytes': { ...nonNegative, integer: true },
+  'sync.history.enabled': bool,
+  'sync.history.diffs': bool,
+  'sync.history.maxCommits': count,
+  'docs.enabled': bool,
+  'docs.patterns': list,
+  'docs.excludePatterns': list,
+  'docs.chunkByHeading': { type: 'number', values: [1, 2, 3] },
+  'docs.inferTypes': bool,
+  'features.enableChat': bool,
+  'features.enableAutoCommit': bool,
+  'features.enableTelemetry': bool,
+  'cvprd.url': str,
+  'cvprd.apiKey': secret,
+  'cvprd.enabled': bool
+};
+
+/** Top-level sections a profile cannot override */
+const NOT_IN_PROFILES = ['version', 'repository', 'profiles'];
+
+/**
+ * The spec of a key. `profiles.<name>.<key>` takes the spec of `<key>`, and
+ * `review.severity.<category>` takes a severity.
+ */
+export function getConfigKeySpec(key: string): ConfigKeySpec {
+  const profileKey = key.match(/^profiles\.[^.]+\.(.+)$/);
+  if (profileKey) {
+    if (NOT_IN_PROFILES.includes(profileKey[1].split('.')[0])) {
+      throw new ConfigError(`${profileKey[1]} cannot be set in a profile`);
+    }
+    return getConfigKeySpec(profileKey[1]);
+  }
+  if (/^review\.severity\.[\w-]+$/.test(key)) {
+    return oneOf(...REVIEW_SEVERITIES);
+  }
+
+  const spec = CONFIG_KEYS[key];
+  if (!spec) {
+    const section = key.split('.')[0];
+    const similar = Object.keys(CONFIG_KEYS).filter(k => k.startsWith(`${section}.`));
+    throw new ConfigError(`Unknown config key: ${key}` +
+      (similar.length > 0 ? ` (${section} keys: ${similar.map(k => k.slice(section.length + 1)).join(', ')})` : ''));
+  }
+  return spec;
+}
+
+/**
+ * Parse a command-line value to the key's type and check it against the spec
+ */
+export function parseConfigValue(key: string, raw: string): unknown {
+  const spec = getConfigKeySpec(key);
+  let value: unknown = raw;
+
+  if (spec.type === 'number') {
+    value = raw.trim() === '' ? NaN : Number(raw);
+  } else if (spec.type === 'boolean') {
+    if (!['true', 'false'].includes(raw)) {
+      throw new ConfigError(`${key} must be true or false, got ${raw}`);
+    }
+    value = raw === 'true';
+  } else if (spec.type === 'string[]') {
+    value = raw.trim().startsWith('[') ? parseJsonList(key, raw) : raw.split(',').map(part => part.trim()).filter(Boolean);
+  }
+
+  validateConfigValue(key, value);
+  return value;
+}
+
+/**
+ * Throw a ConfigError if a value does not fit the key
+ */
+export function validateConfigValue(key: string, value: unknown): void {
+  const spec = getConfigKeySpec(key);
+
+  if (spec.type === 'number') {
+    if (typeof value !== 'number' || !Number.isFinite(value)) {
+      throw new ConfigError(`${key} must be a number, got ${String(value)}`);
+    }
+    if (spec.integer && !Number.isInteger(value)) {
+      throw new ConfigError(`${key} must be a whole number, got ${value}`);
+    }
+    if (spec.min !== undefined && value < spec.min) {
+      throw new ConfigError(`${key} must be at least ${spec.min}, got ${value}`);
+    }
+    if (spec.max !== undefined && value > spec.max) {
+      throw new ConfigError(`${key} must be at most ${spec.max}, got ${value}`);
+    }
+  } else if (spec.type === 'string[]') {
+    if (!Array.isArray(value) || value.some(item => typeof item !== 'string')) {
+      throw new ConfigError(`${key} must be a list of strings`);
+    }
+  } else if (typeof value !== spec.type) {
+    throw new ConfigError(`${key} must be a ${spec.type}, got ${JSON.stringify(value)}`);
+  }
+
+  // Lists check each item
+  const items = Array.isArray(value) ? value : [value];
+  const invalid = spec.values && items.find(item => !spec.values!.includes(item as string | number));
+  if (spec.values && invalid !== undefined) {
+    throw new ConfigError(`Invalid ${key}: ${String(invalid)} (expected one of: ${spec.values.join(', ')})`);
+  }
+}
+
+function parseJsonList(key: string, raw: string): unknown {
+  try {
+    return JSON.parse(raw);
+  } catch {
+    throw new ConfigError(`${key} must be a JSON array or a comma-separated list`);
+  }
+}
+
+/**
+ * Value at a dotted key, or undefined
+ */
+export function readConfigKey(config: object, key: string): unknown {
+  let value: unknown = config;
+  for (const part of key.split('.')) {
+    if (typeof value !== 'object' || value === null) return undefined;
+    value = (value as Record<string, unknown>)[part];
+  }
+  return value;
+}
+
+/**
+ * Set a dotted key in place, creating the sections on the way
+ */
+export function writeConfigKey(config: object, key: string, value: unknown): void {
+  const parts = key.split('.');
+  let section = config as Record<string, unknown>;
+  for (const part of parts.slice(0, -1)) {
+    if (typeof section[part] !== 'object' || section[part] === null || Array.isArray(section[part])) {
+      section[part] = {};
+    }
+    section = section[part] as Record<string, unknown>;
+  }
+  section[parts[parts.length - 1]] = value;
+}
+
+/**
+ * Remove a dotted key in place; sections left empty are removed too.
+ * Returns whether the key was set.
+ */
+export function deleteConfigKey(config: object, key: string): boolean {
+  const parts = key.split('.');
+  const sections: Record<string, unknown>[] = [config as Record<string, unknown>];
+  for (const part of parts.slice(0, -1)) {
+    const next = sections[sections.length - 1][part];
+    if (typeof next !== 'object' || next === null) return false;
+    sections.push(next as Record<string, unknown>);
+  }
+
+  const last = parts[parts.length - 1];
+  if (!(last in sections[sections.length - 1])) return false;
+  delete sections[sections.length - 1][last];
+
+  for (let i = sections.length - 1; i > 0; i--) {
+    if (Object.keys(sections[i]).length > 0) break;
+    delete sections[i - 1][parts[i - 1]];
+  }
+  return true;
+}
+
+/**
+ * Whether a key holds a credential (API keys, tokens, connection strings)
+ */
+export function isSecretConfigKey(key: string): boolean {
+  const base = key.replace(/^profiles\.[^.]+\./, '');
+  return CONFIG_KEYS[base]?.secret === true || /(apiKey|token|password|secret)$/i.test(base);
+}
+
+/**
+ * Every set value as [dotted key, value], with secrets masked
+ */
+export function listConfigKeys(config: CVConfig): Array<[string, unknown]> {
+  const entries: Array<[string, unknown]> = [];
+  const walk = (value: unknown, prefix: string) => {
+    if (typeof value === 'object' && value !== null && !Array.isArray(value)) {
+      for (const [key, child] of Object.entries(value)) {
+        walk(child, prefix ? `${prefix}.${key}` : key);
+      }
+    } else if (value !== undefined) {
+      entries.push([prefix, isSecretConfigKey(prefix) && typeof value === 'string' ? maskConfigSecret(value) : value]);
+    }
+  };
+  walk(config, '');
+  return entries;
+}
+
+/**
+ * A secret as shown by `cv config list`: the first characters, or the
+ * connection string with its password hidden. A value that only
+ * references environment variables (${VAR}) holds no secret and is shown as is.
+ */
+export function maskConfigSecret(value: string): string {
+  if (isEnvReference(value)) {
+    return value;
+  }
+  if (/^[a-z][\w+.-]*:\/\/[^/]*@/i.test(value)) {
+    return value.replace(/^([a-z][\w+.-]*:\/\/[^:/@]*):[^@]*@/i, '$1:****@');
+  }
+  return value.length <= 8 ? '****' : `${value.slice(0, 4)}****`;
+}
@@ -0,0 +1,79 @@
+/**
+ * Config Templates
+ *
+ * Starter configs written by `cv init --template <provider>` for the common
+ * setups. A template only sets the sections that differ from the defaults;
+ * values the user has to supply are written as <placeholders>, and API keys
+ * are never written at all (they belong in `cv auth setup`).
+ */
+
+import { CVConfig } from '@cv-git/shared';
+import { DEFAULT_AZURE_API_VERSION } from '../ai/azure.js';
+import { getOllamaUrl } from './service-urls.js';
+
+export type ConfigTemplateName = 'openai' | 'openrouter' | 'ollama' | 'azure';
+
+export interface ConfigTemplate {
+  description: string;
+  /** Sections merged over the default config */
+  config: Partial<CVConfig>;
+  /** `cv auth setup` services the template needs credentials for */
+  services: string[];
+}
+
+export const CONFIG_TEMPLATES: Record<ConfigTemplateName, ConfigTemplate> = {
+  openai: {
+    description: 'OpenAI embeddings (text-embedding-3-small), Anthropic for answers',
+    config: {
+      embedding: { provider: 'openai', model: 'text-embedding-3-small', dimensions: 1536 }
+    },
+    services: ['anthropic', 'openai']
+  },
+  openrouter: {
+    description: 'Embeddings through OpenRouter (openai/text-embedding-3-small), Anthropic for answers',
+    config: {
+      embedding: { provider: 'openrouter', model: 'openai/text-embedding-3-small', dimensions: 1536 }
+    },
+    services: ['anthropic', 'openrouter']
+  },
+  ollama: {
+    description: 'Local embeddings with Ollama (nomic-embed-text), Anthropic for answers',
+    config: {
+      embedding: { provider: 'ollama', model: 'nomic-embed-text', url: getOllamaUrl(), dimensions: 768 }
+    },
+    services: ['anthropic']
+  },
+  azure: {
+    description: 'Azure OpenAI deployments for both answers and embeddings',
+    config: {
+      ai: { provider: 'azure', model: 'gpt-4o', maxTokens: 4096, temperature: 0.2 },
+      embedding: { provider: 'azure', model: 'text-embedding-3-small', dimensions: 1536 },
+      azure: {
+        endpoint: 'https://<resource>.openai.azure.com',
+        apiVersion: DEFAULT_AZURE_API_VERSION,
+        chatDeployment: '<chat-deployment>',
+        embeddingDeployment: '<embedding-deployment>'
+      }
+    },
+    services: ['azure']
+  }
+};
+
+export function isConfigTemplateName(name: string): name is ConfigTemplateName {
+  return Object.prototype.hasOwnProperty.call(CONFIG_TEMPLATES, name);
+}
+
+/**
+ * Dotted keys whose value still holds a <placeholder>
+ */
+export function findConfigPlaceholders(config: unknown, prefix = ''): string[] {
+  if (typeof config === 'string') {
+    return /<[^<>]+>/.test(config) ? [prefix] : [];
+  }
+  if (!config || typeof config !== 'object' || Array.isArray(config)) {
+    return [];
+  }
+  return Object.entries(config).flatMap(([key, value]) =>
+    findConfigPlaceholders(value, prefix ? `${prefix}.${key}` : key)
+  );
+}
@@ -0,0 +1,181 @@
+/**
+ * Retrieval Benchmark
+ *
+ * Scores retrieval against golden cases for `cv bench`: each case is a
+ * query and the files a good answer needs. Recall@k is the share of a
+ * case's expected files among the top k chunks, and MRR averages
+ * 1/rank of the first expected file, so settings such as minScore and
+ * topK can be compared by number instead of by feel.
+ */
+
+import { promises as fs } from 'fs';
+import { parse } from 'yaml';
+
+/**
+ * A golden case: a query and the files its results should include
+ */
+export interface BenchCase {
+  query: string;
+  expectedFiles: string[];
+}
+
+/**
+ * Settings a sweep can vary
+ */
+export type BenchParameter = 'min-score' | 'top-k' | 'ef';
+
+export const BENCH_PARAMETERS: BenchParameter[] = ['min-score', 'top-k', 'ef'];
+
+/**
+ * A ranked search result, reduced to what scoring needs
+ */
+export interface BenchHit {
+  file: string;
+  score: number;
+}
+
+export interface BenchCaseResult {
+  query: string;
+  expectedFiles: string[];
+  /** Distinct files in the top k, in rank order */
+  retrievedFiles: string[];
+  /** Expected files that were not retrieved */
+  missing: string[];
+  recall: number;
+  /** 1-based rank of the first expected file among the retrieved files */
+  firstHitRank?: number;
+  reciprocalRank: number;
+}
+
+export interface BenchSummary {
+  minScore: number;
+  topK: number;
+  cases: number;
+  /** Mean recall@k */
+  recall: number;
+  /** Mean reciprocal rank */
+  mrr: number;
+  /** Cases with at least one expected file retrieved */
+  hits: number;
+  results: BenchCaseResult[];
+}
+
+/**
+ * Parse golden cases from YAML or JSON: a list of `{ query, expected_files }`,
+ * or an object with such a list under `cases`. `expectedFiles` and a single
+ * `expected_file` are accepted too.
+ */
+export function parseBenchCases(content: string, source: string = 'bench file'): BenchCase[] {
+  let data: any;
+  try {
+    data = parse(content);
+  } catch (error: any) {
+    throw new Error(`${source} is not valid YAML or JSON: ${error.message}`);
+  }
+
+  const list = Array.isArray(data) ? data : data?.cases;
+  if (!Array.isArray(list) || list.length === 0) {
+    throw new Error(`${source} has no cases (expected a list of { query, expected_files })`);
+  }
+
+  return list.map((entry: any, i: number) => {
+    const expected = entry?.expected_files ?? entry?.expectedFiles ?? entry?.expected_file ?? entry?.expectedFile;
+    const expectedFiles = (Array.isArray(expected) ? expected : [expected])
+      .filter((file: unknown): file is string => typeof file === 'string' && file.trim() !== '')
+      .map(normalizeBenchPath);
+    if (typeof entry?.query !== 'string' || !entry.query.trim()) {
+      throw new Error(`${source}: case ${i + 1} has no query`);
+    }
+    if (expectedFiles.length === 0) {
+      throw new Error(`${source}: case ${i + 1} ("${entry.query}") has no expected_files`);
+    }
+    return { query: entry.query.trim(), expectedFiles };
+  });
+}
+
+/**
+ * Read golden cases from a file
+ */
+export async function loadBenchCases(file: string): Promise<BenchCase[]> {
+  return parseBenchCases(await fs.readFile(file, 'utf-8'), file);
+}
+
+/**
+ * Values of a `--sweep` range: `start:end:step` (end included) or a
+ * comma-separated list
+ */
+export function parseSweepRange(parameter: string, range: string): number[] {
+  if (!BENCH_PARAMETERS.includes(parameter as BenchParameter)) {
+    throw new Error(`Cannot sweep ${parameter}: expected one of ${BENCH_PARAMETERS.join(', ')}`);
+  }
+
+  let values: number[];
+  if (range.includes(':')) {
+    const [start, end, step] = range.split(':').map(Number);
+    if (![start, end, step].every(Number.isFinite) || step <= 0 || end < start) {
+      throw new Error(`Invalid sweep range ${range} (expected start:end:step, e.g. 0.1:0.5:0.05)`);
+    }
+    // Count steps rather than add them up, so 0.1 + 0.05 * n does not drift past end
+    const decimals = Math.max(...[start, step].map(n => (String(n).split('.')[1] || '').length));
+    const count = Math.floor((end - start) / step + 1e-9);
+    values = Array.from({ length: count + 1 }, (_, i) => Number((start + i * step).toFixed(decimals)));
+  } else {
+    values = range.split(',').map(value => Number(value.trim()));
+    if (values.some(value => !Number.isFinite(value))) {
+      throw new Error(`Invalid sweep values ${range} (expected numbers, e.g. 5,10,20)`);
+    }
+  }
+
+  if (parameter === 'min-score' && values.some(value => value < 0 || value > 1)) {
+    throw new Error('min-score values must be between 0 and 1');
+  }
+  if (parameter !== 'min-score' && values.some(value => !Number.isInteger(value) || value < 1)) {
+    throw new Error(`${parameter} values must be positive integers`);
+  }
+  return values;
+}
+
+/**
+ * Score one case's ranked hits (best first) at a minimum score and top k
+ */
+export function scoreBenchCase(benchCase: BenchCase, hits: BenchHit[], minScore: number, topK: number): BenchCaseResult {
+  const retrievedFiles: string[] = [];
+  for (const hit of hits.filter(h => h.score >= minScore).slice(0, topK)) {
+    const file = normalizeBenchPath(hit.file);
+    if (!retrievedFiles.includes(file)) retrievedFiles.push(file);
+  }
+
+  const missing = benchCase.expectedFiles.filter(file => !retrievedFiles.includes(file));
+  const firstHit = retrievedFiles.findIndex(file => benchCase.expectedFiles.includes(file));
+  return {
+    query: benchCase.query,
+    expectedFiles: benchCase.expectedFiles,
+    retrievedFiles,
+    missing,
+    recall: (benchCase.expectedFiles.length - missing.length) / benchCase.expectedFiles.length,
+    firstHitRank: firstHit >= 0 ? firstHit + 1 : undefined,
+    reciprocalRank: firstHit >= 0 ? 1 / (firstHit + 1) : 0
+  };
+}
+
+/**
+ * Score every case at one setting; `hits` holds each case's ranked results
+ * in case order, retrieved with at least `topK` results
+ */
+export function summarizeBench(cases: BenchCase[], hits: BenchHit[][], minScore: number, topK: number): BenchSummary {
+  const results = cases.map((benchCase, i) => scoreBenchCase(benchCase, hits[i] || [], minScore, topK));
+  const mean = (values: number[]) => values.reduce((sum, value) => sum + value, 0) / Math.max(values.length, 1);
+  return {
+    minScore,
+    topK,
+    cases: cases.length,
+    recall: mean(results.map(r => r.recall)),
+    mrr: mean(results.map(r => r.reciprocalRank)),
+    hits: results.filter(r => r.firstHitRank !== undefined).length,
+    results
+  };
+}
+
+function normalizeBenchPath(file: string): string {
+  return file.trim().replace(/\\/g, '/').replace(/^\.\//, '');
+}
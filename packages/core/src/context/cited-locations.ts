@@ -0,0 +1,79 @@
+/**
+ * Cited Locations
+ *
+ * Finds the `file:line` references in an answer that point at code it was
+ * given as context (`cv explain --open`). Models often shorten paths, so a
+ * citation matches a context file it is a path suffix of. Locations are
+ * ranked by how often the answer cites them.
+ */
+
+import { CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+
+export interface CitedLocation {
+  /** Repo-relative path */
+  file: string;
+  line: number;
+  symbol?: string;
+  /** Times the answer cites this location; 0 for a context chunk it never cites */
+  mentions: number;
+}
+
+/** `path/to/file.ext:120`, optionally a range (`:120-140`) or wrapped in backticks */
+const CITATION_PATTERN = /([\w@.\/-]*[\w-]\.[A-Za-z0-9]+):(\d+)(?:-\d+)?/g;
+
+/**
+ * Locations cited in the answer, most cited first (ties: first cited first).
+ * When the answer cites none, the context chunks in retrieval order.
+ */
+export function findCitedLocations(
+  answer: string,
+  chunks: VectorSearchResult<CodeChunkPayload>[]
+): CitedLocation[] {
+  const files = Array.from(new Set(chunks.map(chunk => chunk.payload.file)));
+  const cited = new Map<string, CitedLocation>();
+
+  for (const match of answer.matchAll(CITATION_PATTERN)) {
+    const file = resolveCitedFile(match[1], files);
+    const line = parseInt(match[2], 10);
+    if (!file || !(line > 0)) continue;
+
+    const key = `${file}:${line}`;
+    const existing = cited.get(key);
+    if (existing) {
+      existing.mentions++;
+    } else {
+      cited.set(key, { file, line, symbol: symbolAt(chunks, file, line), mentions: 1 });
+    }
+  }
+
+  if (cited.size > 0) {
+    // Map keeps first-cited order, and the sort is stable
+    return [...cited.values()].sort((a, b) => b.mentions - a.mentions);
+  }
+
+  const seen = new Set<string>();
+  const fallback: CitedLocation[] = [];
+  for (const { payload } of chunks) {
+    const key = `${payload.file}:${payload.startLine}`;
+    if (seen.has(key)) continue;
+    seen.add(key);
+    fallback.push({ file: payload.file, line: payload.startLine, symbol: payload.symbolName, mentions: 0 });
+  }
+  return fallback;
+}
+
+/**
+ * The context file a cited path refers to: an exact match, else the first
+ * (best-ranked) file it is a path suffix of
+ */
+function resolveCitedFile(cited: string, files: string[]): string | undefined {
+  const normalized = cited.replace(/^\.\//, '');
+  return files.find(file => file === normalized) ??
+    files.find(file => file.endsWith(`/${normalized}`));
+}
+
+function symbolAt(chunks: VectorSearchResult<CodeChunkPayload>[], file: string, line: number): string | undefined {
+  return chunks.find(({ payload }) =>
+    payload.file === file && payload.startLine <= line && line <= payload.endLine
+  )?.payload.symbolName;
+}
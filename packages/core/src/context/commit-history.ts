@@ -0,0 +1,85 @@
+/**
+ * Commit History Context
+ *
+ * Recent commits touching the retrieved files (`cv explain --history`), so
+ * the model can see the reasoning captured in commit messages, not just the
+ * code. Long messages are truncated and older commits dropped to keep the
+ * history within what is left of the token budget.
+ */
+
+import { GitCommit } from '@cv-git/shared';
+import { estimateTokens } from '../vector/embedding-batches.js';
+
+/** Longest commit message sent; the subject and the start of the body carry most of the why */
+export const MAX_HISTORY_MESSAGE_CHARS = 1200;
+
+/** Share of the context budget history may use, so it never crowds out the code */
+export const HISTORY_BUDGET_SHARE = 0.2;
+
+/** SHA, author, date, and stats line printed for each commit */
+const COMMIT_OVERHEAD_TOKENS = 25;
+
+export interface BudgetedHistory {
+  commits: GitCommit[];
+  /** Commits left out because the budget was used up */
+  dropped: number;
+  /** Commits whose message was cut short */
+  truncated: number;
+}
+
+/**
+ * Cut a commit message to `maxChars`, at a line break when there is one nearby
+ */
+export function truncateCommitMessage(message: string, maxChars: number = MAX_HISTORY_MESSAGE_CHARS): string {
+  const trimmed = message.trim();
+  if (trimmed.length <= maxChars) return trimmed;
+
+  const cut = trimmed.slice(0, maxChars);
+  const lineBreak = cut.lastIndexOf('\n');
+  return `${(lineBreak > maxChars / 2 ? cut.slice(0, lineBreak) : cut).trimEnd()}\n[...]`;
+}
+
+/**
+ * Keep the newest commits that fit in the budget, truncating long messages
+ */
+export function fitHistoryToBudget(commits: GitCommit[], budgetTokens: number): BudgetedHistory {
+  const kept: GitCommit[] = [];
+  let remaining = budgetTokens;
+  let truncated = 0;
+
+  for (const commit of commits) {
+    const message = truncateCommitMessage(commit.message);
+    const cost = estimateTokens(message) + COMMIT_OVERHEAD_TOKENS;
+    if (cost > remaining) break;
+
+    kept.push(message === commit.message.trim() ? commit : { ...commit, message });
+    if (message !== commit.message.trim()) truncated++;
+    remaining -= cost;
+  }
+
+  return { commits: kept, dropped: commits.length - kept.length, truncated };
+}
+
+/**
+ * Prompt section listing the commits, newest first
+ */
+export function formatCommitHistory(commits: GitCommit[]): string {
+  if (commits.length === 0) return '';
+
+  let section = `## Recent Commits To These Files\n\n`;
+  for (const commit of commits) {
+    const [subject, ...body] = commit.message.split('\n');
+    section += `### ${commit.sha.slice(0, 8)} ${subject}\n`;
+    section += `${commit.author}, ${new Date(commit.date).toISOString().slice(0, 10)}`;
+    if (commit.stats) {
+      section += `, ${commit.stats.filesChanged} file${commit.stats.filesChanged === 1 ? '' : 's'} changed (+${commit.stats.insertions} -${commit.stats.deletions})`;
+    }
+    section += `\n`;
+    const details = body.join('\n').trim();
+    if (details) {
+      section += `\n${details}\n`;
+    }
+    section += `\n`;
+  }
+  return section;
+}
@@ -0,0 +1,136 @@
+/**
+ * Context Deduplication
+ *
+ * Vendored copies, generated files, and copy-pasted helpers make retrieval
+ * return the same code more than once. Chunks with identical text, or whose
+ * embedding is nearly identical to a higher-scoring chunk, are collapsed so
+ * the context budget goes to distinct code. Overlapping line windows of one
+ * file are cut back to the lines a higher-scoring chunk does not already show.
+ */
+
+import { createHash } from 'crypto';
+import { CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+
+/** Cosine similarity above which two chunks count as the same code */
+export const DEFAULT_DEDUPE_THRESHOLD = 0.97;
+
+export interface DedupedChunks {
+  chunks: VectorSearchResult<CodeChunkPayload>[];
+  /** Chunks collapsed into a higher-scoring duplicate */
+  duplicates: number;
+}
+
+/**
+ * Cosine similarity of two embeddings (0 when the dimensions differ)
+ */
+export function cosineSimilarity(a: number[], b: number[]): number {
+  if (a.length !== b.length || a.length === 0) {
+    return 0;
+  }
+
+  let dot = 0;
+  let normA = 0;
+  let normB = 0;
+  for (let i = 0; i < a.length; i++) {
+    dot += a[i] * b[i];
+    normA += a[i] * a[i];
+    normB += b[i] * b[i];
+  }
+
+  const denominator = Math.sqrt(normA) * Math.sqrt(normB);
+  return denominator === 0 ? 0 : dot / denominator;
+}
+
+/**
+ * Hash of a chunk's text, ignoring line endings and trailing whitespace
+ */
+function textHash(text: string): string {
+  const normalized = text.split(/\r?\n/).map(line => line.trimEnd()).join('\n').trim();
+  return createHash('sha256').update(normalized).digest('hex');
+}
+
+/**
+ * Drop exact text duplicates and near-duplicates of higher-scoring chunks.
+ * Near-duplicates are found by embedding similarity, so chunks returned
+ * without a vector are only checked by hash. A chunk whose lines a
+ * higher-scoring chunk of the same file already covers is dropped, and one
+ * that shares lines at either end is trimmed to the rest. Pinned chunks
+ * (of --file files) are kept whole and decided first, so a search hit is
+ * what gives way when it repeats one. Kept chunks stay in their original
+ * order and have their vectors removed.
+ */
+export function deduplicateChunks(
+  chunks: VectorSearchResult<CodeChunkPayload>[],
+  threshold: number = DEFAULT_DEDUPE_THRESHOLD
+): DedupedChunks {
+  // Pinned first, then score order so the better match of a pair survives; ties keep retrieval order
+  const ranked = chunks
+    .map((chunk, index) => ({ chunk, index }))
+    .sort((a, b) => Number(!!b.chunk.payload.pinned) - Number(!!a.chunk.payload.pinned) ||
+      b.chunk.score - a.chunk.score || a.index - b.index);
+
+  const hashes = new Set<string>();
+  const vectors: number[][] = [];
+  const keep = new Map<number, VectorSearchResult<CodeChunkPayload>>();
+
+  for (const { chunk, index } of ranked) {
+    const pinned = chunk.payload.pinned === true;
+    const hash = textHash(chunk.payload.text);
+    if (!pinned && hashes.has(hash)) continue;
+
+    if (!pinned && chunk.vector && vectors.some(v => cosineSimilarity(v, chunk.vector!) >= threshold)) continue;
+
+    const trimmed = pinned ? chunk : trimOverlap(chunk, [...keep.values()]);
+    if (!trimmed) continue;
+
+    hashes.add(hash);
+    if (chunk.vector) vectors.push(chunk.vector);
+    keep.set(index, trimmed);
+  }
+
+  const kept = [...keep.entries()]
+    .sort(([a], [b]) => a - b)
+    .map(([, { vector, ...chunk }]) => chunk);
+
+  return { chunks: kept, duplicates: chunks.length - kept.length };
+}
+
+/**
+ * The chunk without the lines kept chunks of its file already show, or
+ * null when they show all of it. A chunk whose text does not match its line
+ * range cannot be cut and is only dropped when fully covered.
+ */
+function trimOverlap(
+  chunk: VectorSearchResult<CodeChunkPayload>,
+  kept: VectorSearchResult<CodeChunkPayload>[]
+): VectorSearchResult<CodeChunkPayload> | null {
+  const { file, startLine: originalStart, endLine: originalEnd, text } = chunk.payload;
+  let startLine = originalStart;
+  let endLine = originalEnd;
+
+  for (const { payload: other } of kept) {
+    if (other.file !== file) continue;
+    if (other.startLine <= startLine && other.endLine >= endLine) {
+      return null;
+    }
+    if (other.startLine <= startLine && other.endLine >= startLine) {
+      startLine = other.endLine + 1;
+    } else if (other.startLine <= endLine && other.endLine >= endLine) {
+      endLine = other.startLine - 1;
+    }
+  }
+
+  const lines = text.split('\n');
+  if ((startLine === originalStart && endLine === originalEnd) || lines.length !== originalEnd - originalStart + 1) {
+    return chunk;
+  }
+  return {
+    ...chunk,
+    payload: {
+      ...chunk.payload,
+      startLine,
+      endLine,
+      text: lines.slice(startLine - originalStart, endLine - originalStart + 1).join('\n')
+    }
+  };
+}
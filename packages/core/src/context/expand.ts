@@ -0,0 +1,170 @@
+/**
+ * Context Expansion
+ *
+ * Retrieval finds code that is similar to the question, not the helpers
+ * that code calls: `verifyPassword` may be retrieved without the
+ * `hashPassword` it relies on. `cv explain --depth <n>` follows the
+ * identifiers in the retrieved chunks to their definitions in the index,
+ * then (at depth 2 and up) the identifiers in those, while the token
+ * budget lasts. Expanded chunks record what referenced them, so they are
+ * cited apart from retrieved ones.
+ */
+
+import { CodeChunkPayload, SymbolKind, VectorSearchResult } from '@cv-git/shared';
+import { estimateTokens } from '../vector/embedding-batches.js';
+import { languageFamily, stripCommentsAndStrings } from '../vector/references.js';
+import { CHUNK_OVERHEAD_TOKENS } from './token-budget.js';
+
+/** Deepest --depth accepted; each level can add as many chunks as the one before */
+export const MAX_EXPAND_DEPTH = 3;
+
+/** Expanded chunks added at most, over all levels */
+export const DEFAULT_MAX_EXPANDED_CHUNKS = 10;
+
+/** Names defined more often than this (get, run, ...) are too ambiguous to follow */
+const MAX_DEFINITIONS_PER_NAME = 3;
+
+/** Kinds whose definition is worth pulling in; variables and constants are not */
+const DEFINITION_KINDS: SymbolKind[] = ['function', 'method', 'class', 'interface', 'type', 'enum', 'struct'];
+
+const IDENTIFIER_PATTERN = /[A-Za-z_$][\w$]*/g;
+
+export interface ExpandOptions {
+  /** Levels of references to follow (0: none) */
+  depth: number;
+  /** Tokens the expanded chunks may use */
+  budgetTokens: number;
+  maxChunks?: number;
+}
+
+export interface ExpandedContext {
+  /** Definitions to add after the retrieved chunks, in the order they were reached */
+  chunks: VectorSearchResult<CodeChunkPayload>[];
+  tokens: number;
+  /** Definitions found but left out for the budget or the chunk limit */
+  dropped: number;
+}
+
+/**
+ * Definitions of the identifiers the chunks use, looked up by symbol name
+ * in the indexed chunks. A definition in the referencing chunk's file is
+ * preferred, then one in the same language; the first piece of a symbol
+ * split into several chunks stands for it.
+ */
+export function expandWithDefinitions(
+  chunks: VectorSearchResult<CodeChunkPayload>[],
+  indexed: CodeChunkPayload[],
+  options: ExpandOptions
+): ExpandedContext {
+  const depth = Math.min(Math.max(options.depth, 0), MAX_EXPAND_DEPTH);
+  const maxChunks = options.maxChunks ?? DEFAULT_MAX_EXPANDED_CHUNKS;
+  const result: ExpandedContext = { chunks: [], tokens: 0, dropped: 0 };
+  if (depth === 0 || chunks.length === 0) {
+    return result;
+  }
+
+  const definitions = indexDefinitions(indexed);
+  // Symbols already in the context, by file and name
+  const included = new Set(chunks.map(chunk => symbolKey(chunk.payload)));
+  const skipped = new Set<string>();
+  let remaining = options.budgetTokens;
+  let frontier = chunks;
+
+  for (let level = 1; level <= depth && frontier.length > 0; level++) {
+    const next: VectorSearchResult<CodeChunkPayload>[] = [];
+
+    for (const chunk of frontier) {
+      for (const name of referencedNames(chunk.payload)) {
+        const definition = pickDefinition(definitions.get(name), chunk.payload);
+        if (!definition) continue;
+
+        const key = symbolKey(definition);
+        if (included.has(key) || skipped.has(key)) continue;
+
+        const cost = estimateTokens(definition.text) + CHUNK_OVERHEAD_TOKENS;
+        if (cost > remaining || result.chunks.length >= maxChunks) {
+          skipped.add(key);
+          result.dropped++;
+          continue;
+        }
+
+        included.add(key);
+        remaining -= cost;
+        result.tokens += cost;
+        const expanded: VectorSearchResult<CodeChunkPayload> = {
+          id: definition.id,
+          score: chunk.score,
+          payload: definition,
+          expansion: {
+            name,
+            from: chunk.payload.symbolName ?? `${chunk.payload.file}:${chunk.payload.startLine}`,
+            depth: level
+          }
+        };
+        result.chunks.push(expanded);
+        next.push(expanded);
+      }
+    }
+
+    frontier = next;
+  }
+
+  return result;
+}
+
+/**
+ * First piece of each definition, by symbol name
+ */
+function indexDefinitions(indexed: CodeChunkPayload[]): Map<string, CodeChunkPayload[]> {
+  const bySymbol = new Map<string, CodeChunkPayload>();
+  for (const chunk of indexed) {
+    if (!chunk.symbolName || !chunk.symbolKind || !DEFINITION_KINDS.includes(chunk.symbolKind)) continue;
+    const key = symbolKey(chunk);
+    const existing = bySymbol.get(key);
+    if (!existing || chunk.startLine < existing.startLine) {
+      bySymbol.set(key, chunk);
+    }
+  }
+
+  const byName = new Map<string, CodeChunkPayload[]>();
+  for (const chunk of bySymbol.values()) {
+    const list = byName.get(chunk.symbolName!);
+    if (list) {
+      list.push(chunk);
+    } else {
+      byName.set(chunk.symbolName!, [chunk]);
+    }
+  }
+  return byName;
+}
+
+/**
+ * Identifiers in a chunk's code, in order of first use, without its own name
+ */
+function referencedNames(chunk: CodeChunkPayload): string[] {
+  const names = new Set<string>();
+  let inBlockComment = false;
+  for (const line of chunk.text.split('\n')) {
+    const stripped = stripCommentsAndStrings(line, chunk.language, inBlockComment);
+    inBlockComment = stripped.inBlockComment;
+    for (const match of stripped.code.matchAll(IDENTIFIER_PATTERN)) {
+      names.add(match[0]);
+    }
+  }
+  if (chunk.symbolName) {
+    names.delete(chunk.symbolName);
+  }
+  return [...names];
+}
+
+function pickDefinition(candidates: CodeChunkPayload[] | undefined, from: CodeChunkPayload): CodeChunkPayload | undefined {
+  if (!candidates) return undefined;
+  const family = languageFamily(from.language);
+  const reachable = candidates.filter(candidate => family.includes(candidate.language));
+  return reachable.find(candidate => candidate.file === from.file) ??
+    (reachable.length <= MAX_DEFINITIONS_PER_NAME ? reachable[0] : undefined);
+}
+
+function symbolKey(chunk: CodeChunkPayload): string {
+  return chunk.symbolName ? `${chunk.file}\0${chunk.symbolName}` : `${chunk.file}\0${chunk.startLine}`;
+}
@@ -0,0 +1,116 @@
+/**
+ * File-Scoped Context
+ *
+ * Context for files the user named explicitly (e.g. `cv explain --file`).
+ * Small files are included whole; larger ones contribute their best-matching
+ * indexed chunks. Either way they bypass the minScore threshold, since the user
+ * already decided they are relevant, and are pinned: the token budget fits
+ * them before any search result.
+ */
+
+import * as fs from 'fs/promises';
+import * as path from 'path';
+import { CodeChunkPayload, VectorSearchResult, detectLanguage } from '@cv-git/shared';
+import { VectorManager } from '../vector/index.js';
+
+/** Files up to this size are included whole */
+export const DEFAULT_FILE_CONTEXT_MAX_BYTES = 24 * 1024;
+
+export interface FileContextOptions {
+  /** Query used to pick chunks from files too large to include whole */
+  query: string;
+  vector?: VectorManager;
+  /** Chunks to take from each large file (default: 5) */
+  maxChunksPerFile?: number;
+  /** Largest file included whole (default: 24KB) */
+  maxBytes?: number;
+}
+
+/**
+ * Build context chunks for explicitly named files
+ * @param repoRoot - Repository root the file paths are relative to
+ * @param files - Repo-relative file paths
+ */
+export async function gatherFileChunks(
+  repoRoot: string,
+  files: string[],
+  options: FileContextOptions
+): Promise<VectorSearchResult<CodeChunkPayload>[]> {
+  const maxBytes = options.maxBytes ?? DEFAULT_FILE_CONTEXT_MAX_BYTES;
+  const maxChunks = options.maxChunksPerFile ?? 5;
+  const chunks: VectorSearchResult<CodeChunkPayload>[] = [];
+
+  for (const file of files) {
+    const content = await fs.readFile(path.join(repoRoot, file), 'utf-8');
+
+    if (Buffer.byteLength(content, 'utf-8') <= maxBytes) {
+      chunks.push(wholeFileChunk(file, content));
+      continue;
+    }
+
+    let matches: VectorSearchResult<CodeChunkPayload>[] = [];
+    if (options.vector) {
+      try {
+        matches = await options.vector.searchCode(options.query, maxChunks, { file });
+      } catch {
+        // Fall back to the head of the file below
+      }
+    }
+
+    // Not indexed (or no vector store): use as much of the file as fits
+    chunks.push(...(matches.length > 0
+      ? matches.map(match => ({ ...match, payload: { ...match.payload, pinned: true } }))
+      : [wholeFileChunk(file, truncateToBytes(content, maxBytes), false)]));
+  }
+
+  return chunks;
+}
+
+/**
+ * Put file-scoped chunks first, then search results that add something new.
+ * Search hits inside a file that is already included whole are dropped.
+ */
+export function mergeFileChunks(
+  fileChunks: VectorSearchResult<CodeChunkPayload>[],
+  searchChunks: VectorSearchResult<CodeChunkPayload>[]
+): VectorSearchResult<CodeChunkPayload>[] {
+  const wholeFiles = new Set(fileChunks.filter(c => c.payload.wholeFile).map(c => c.payload.file));
+  const seen = new Set(fileChunks.map(c => c.id));
+
+  const merged = [...fileChunks];
+  for (const chunk of searchChunks) {
+    if (seen.has(chunk.id) || wholeFiles.has(chunk.payload.file)) continue;
+    seen.add(chunk.id);
+    merged.push(chunk);
+  }
+  return merged;
+}
+
+function wholeFileChunk(file: string, content: string, complete: boolean = true): VectorSearchResult<CodeChunkPayload> {
+  const id = `${file}:file`;
+  return {
+    id,
+    score: 1,
+    payload: {
+      id,
+      file,
+      language: detectLanguage(file),
+      startLine: 1,
+      endLine: content.split('\n').length,
+      text: content,
+      imports: [],
+      lastModified: 0,
+      wholeFile: complete,
+      pinned: true
+    }
+  };
+}
+
+/**
+ * Cut text to at most maxBytes, ending on a line boundary
+ */
+function truncateToBytes(text: string, maxBytes: number): string {
+  const cut = Buffer.from(text, 'utf-8').subarray(0, maxBytes).toString('utf-8');
+  const lastNewline = cut.lastIndexOf('\n');
+  return lastNewline > 0 ? cut.slice(0, lastNewline) : cut;
+}
@@ -0,0 +1,90 @@
+/**
+ * Answer Grounding
+ *
+ * Checks that the symbols an answer names exist in the indexed code
+ * (`cv explain --verify`). Models sometimes invent a plausible function or
+ * method; an answer that names one in an inline code span is flagged.
+ * A name counts as grounded when it is an indexed symbol or appears as an
+ * identifier anywhere in the indexed code, so library calls the repository
+ * makes are not flagged.
+ *
+ * This is a heuristic: only inline code spans that look like identifiers
+ * or calls (`parseConfig`, `AuthService.login()`) are checked, and a
+ * qualified name only by its last part.
+ */
+
+import { CodeChunkPayload, detectLanguage } from '@cv-git/shared';
+
+/** Identifiers, as the expansion scan finds them (see expand.ts) */
+const IDENTIFIER_PATTERN = /[A-Za-z_$][\w$]*/g;
+
+/** `name`, `Type.member`, `pkg::name` or `obj->name`, optionally called with arguments */
+const SYMBOL_SPAN = /^([A-Za-z_$][\w$]*(?:(?:\.|::|#|->)[A-Za-z_$][\w$]*)*)(?:\(.*\))?$/;
+
+/** Separators of a qualified name */
+const QUALIFIER = /\.|::|#|->/;
+
+/** Fenced code blocks, which quote or sketch code rather than name it */
+const FENCED_BLOCK = /^(```|~~~)[^\n]*\n[\s\S]*?^\1[ \t]*$/gm;
+
+const INLINE_CODE = /`([^`\n]+)`/g;
+
+/** Literals and keywords that are never symbols of the repository */
+const NOT_SYMBOLS = new Set([
+  'true', 'false', 'null', 'undefined', 'nil', 'None', 'True', 'False', 'NaN',
+  'this', 'self', 'super', 'void', 'async', 'await', 'return', 'const', 'let', 'var'
+]);
+
+/**
+ * Names the indexed code defines or uses: symbol names (and each part of a
+ * qualified one) and the identifiers in the chunk text
+ */
+export function buildGroundingIndex(chunks: CodeChunkPayload[]): Set<string> {
+  const known = new Set<string>();
+  for (const chunk of chunks) {
+    if (chunk.symbolName) {
+      known.add(chunk.symbolName);
+      for (const part of chunk.symbolName.split(QUALIFIER)) known.add(part);
+    }
+    for (const match of chunk.text.matchAll(IDENTIFIER_PATTERN)) {
+      known.add(match[0]);
+    }
+  }
+  return known;
+}
+
+/**
+ * Symbols the answer names in inline code, in order of first mention,
+ * without call arguments (`login()` gives `login`)
+ */
+export function extractSymbolReferences(answer: string): string[] {
+  const references = new Set<string>();
+  const prose = answer.replace(FENCED_BLOCK, '');
+
+  for (const match of prose.matchAll(INLINE_CODE)) {
+    const span = match[1].trim();
+    const symbol = SYMBOL_SPAN.exec(span)?.[1];
+    if (!symbol || !isCheckable(symbol)) continue;
+    references.add(symbol);
+  }
+  return [...references];
+}
+
+/**
+ * Symbols the answer names that the indexed code neither defines nor uses
+ */
+export function findUngroundedSymbols(answer: string, known: Set<string>): string[] {
+  return extractSymbolReferences(answer).filter(symbol => {
+    const parts = symbol.split(QUALIFIER);
+    return !known.has(symbol) && !known.has(parts[parts.length - 1]);
+  });
+}
+
+function isCheckable(symbol: string): boolean {
+  if (NOT_SYMBOLS.has(symbol)) return false;
+  // File names such as `auth.ts` look like member access
+  if (symbol.includes('.') && detectLanguage(symbol) !== 'unknown') return false;
+  // Environment variables and other constants written in capitals
+  if (!/[a-z]/.test(symbol)) return false;
+  return symbol.length > 2;
+}
@@ -21,6 +21,15 @@ export {
   loadTransitionState,
 } from './transition-model.js';
 export { ClaudeMdGenerator, ClaudeMdOptions } from './claude-md-generator.js';
+export * from './file-context.js';
+export * from './token-budget.js';
+export * from './dedupe.js';
+export * from './commit-history.js';
+export * from './cited-locations.js';
+export * from './grounding.js';
+export * from './expand.js';
+export * from './rerank.js';
+export * from './bench.js';
 
 export interface ContextRequest {
   // The task or query to gather context for
@@ -0,0 +1,113 @@
+/**
+ * Context Reranking
+ *
+ * Cosine similarity between a query and a chunk embedded on its own is a
+ * rough relevance signal: a chunk that merely shares vocabulary with an
+ * ambiguous question can outrank the code that answers it. A cross-encoder
+ * reads the query and each candidate together, so more candidates than
+ * needed are retrieved, scored by the reranker, and the best topK kept.
+ * Off by default; enabled with config search.rerank or --rerank.
+ */
+
+import { CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+import { CohereClient, DEFAULT_COHERE_RERANK_MODEL } from '../ai/cohere.js';
+
+/** Vector search results scored when search.rerank.candidates is unset */
+export const DEFAULT_RERANK_CANDIDATES = 50;
+
+/** Base URL of a local reranker server (llama.cpp, vLLM, Infinity, ...) */
+export const DEFAULT_LOCAL_RERANK_URL = 'http://localhost:8080/v1';
+
+/** Characters of a chunk sent to the reranker; cross-encoders truncate long input anyway */
+const MAX_DOCUMENT_CHARS = 4000;
+
+export interface Reranker {
+  /** Shown in the context summary */
+  readonly model: string;
+  /** Relevance (0-1) of each document to the query, most relevant first */
+  rerank(query: string, documents: string[], signal?: AbortSignal): Promise<{ index: number; score: number }[]>;
+}
+
+export interface RerankerOptions {
+  provider?: 'cohere' | 'local';
+  model?: string;
+  /** Required for Cohere; sent as a bearer token to a local server if set */
+  apiKey?: string;
+  url?: string;
+  maxRetryAttempts?: number;
+}
+
+/**
+ * Reranker for Cohere's rerank API, or a local server with the same API
+ */
+export function createReranker(options: RerankerOptions): Reranker {
+  const local = options.provider === 'local';
+  if (!local && !options.apiKey) {
+    throw new Error('Cohere API key required for reranking. Run: cv auth setup cohere');
+  }
+
+  const client = new CohereClient({
+    apiKey: options.apiKey || '',
+    baseUrl: options.url || (local ? DEFAULT_LOCAL_RERANK_URL : undefined),
+    maxRetryAttempts: options.maxRetryAttempts
+  });
+  const model = options.model || (local ? undefined : DEFAULT_COHERE_RERANK_MODEL);
+
+  return {
+    model: model || 'local reranker',
+    async rerank(query, documents, signal) {
+      const results = await client.rerank(query, documents, model, signal);
+      return results.map(result => ({ index: result.index, score: result.relevanceScore }));
+    }
+  };
+}
+
+export interface RerankedChunks {
+  chunks: VectorSearchResult<CodeChunkPayload>[];
+  /** Kept chunks whose position differs from the vector search order */
+  moved: number;
+}
+
+/**
+ * Reorder chunks by the reranker's score and keep the best topK. Each kept
+ * chunk takes the reranker's score and records its vector score and rank,
+ * so the effect of reranking can be shown.
+ */
+export async function rerankChunks(
+  query: string,
+  chunks: VectorSearchResult<CodeChunkPayload>[],
+  reranker: Reranker,
+  topK: number,
+  signal?: AbortSignal
+): Promise<RerankedChunks> {
+  if (chunks.length === 0) {
+    return { chunks: [], moved: 0 };
+  }
+
+  const scores = await reranker.rerank(query, chunks.map(chunkDocument), signal);
+
+  const reranked: VectorSearchResult<CodeChunkPayload>[] = [];
+  const seen = new Set<number>();
+  for (const { index, score } of scores) {
+    if (reranked.length >= topK) break;
+    if (seen.has(index) || !chunks[index]) continue;
+    seen.add(index);
+    reranked.push({
+      ...chunks[index],
+      score,
+      rerank: { vectorScore: chunks[index].score, vectorRank: index + 1 }
+    });
+  }
+
+  const moved = reranked.filter((chunk, position) => chunk.rerank!.vectorRank !== position + 1).length;
+  return { chunks: reranked, moved };
+}
+
+/**
+ * The text a chunk is reranked by: where it is, then its code
+ */
+function chunkDocument(chunk: VectorSearchResult<CodeChunkPayload>): string {
+  const { file, symbolName, text } = chunk.payload;
+  const header = symbolName ? `${file} (${symbolName})` : file;
+  return `${header}\n${text.slice(0, MAX_DOCUMENT_CHARS)}`;
+}
@@ -0,0 +1,158 @@
+/**
+ * Context Token Budget
+ *
+ * Keeps retrieved chunks within the selected model's context window.
+ * Chunks are ranked by score and added greedily; lower-ranked chunks that
+ * no longer fit are truncated or dropped, so a few large matches cannot
+ * push a prompt past the model's limit. Pinned chunks (files named with
+ * --file) are not ranked: they are fitted first, and cut only when they
+ * alone exceed the budget.
+ */
+
+import { CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+import { RECOMMENDED_MODELS } from '../ai/types.js';
+import { estimateTokens } from '../vector/embedding-batches.js';
+
+/** Context window assumed for models we know nothing about */
+export const DEFAULT_CONTEXT_WINDOW = 32768;
+
+/** Tokens kept free for the prompt's instructions, query, and related symbols */
+export const PROMPT_OVERHEAD_TOKENS = 2000;
+
+/** A truncated chunk shorter than this is not worth including */
+const MIN_TRUNCATED_CHUNK_TOKENS = 200;
+
+/** File path, symbol line, and code fence added around each chunk */
+export const CHUNK_OVERHEAD_TOKENS = 20;
+
+/**
+ * Context windows by model name prefix, most specific first.
+ * Provider prefixes (e.g. "anthropic/") are stripped before matching.
+ */
+const CONTEXT_WINDOWS: Array<[prefix: string, tokens: number]> = [
+  ['claude-', 200000],
+  ['gpt-4o', 128000],
+  ['gpt-4.1', 1000000],
+  ['gpt-4-turbo', 128000],
+  ['gpt-4-32k', 32768],
+  ['gpt-4', 8192],
+  ['gpt-3.5-turbo', 16385],
+  ['o1', 200000],
+  ['o3', 200000],
+  ['o4', 200000],
+  ['gemini-', 1000000],
+  ['deepseek', 128000],
+  ['qwen2.5-coder', 32768],
+  ['codellama', 16384],
+  ['llama3.1', 128000],
+  ['llama3', 8192],
+  ['mistral', 32768],
+  ['mixtral', 32768]
+];
+
+export interface ContextBudgetOptions {
+  model: string;
+  /** Tokens reserved for the model's answer */
+  maxOutputTokens: number;
+  /** Override the model's context window (ai.contextWindow) */
+  contextWindow?: number;
+}
+
+export interface BudgetedChunks {
+  chunks: VectorSearchResult<CodeChunkPayload>[];
+  /** Chunks left out because the budget was used up */
+  dropped: number;
+  /** Chunks cut short to fit */
+  truncated: number;
+  /** Pinned chunks among the dropped and truncated ones */
+  pinned: number;
+  /** Estimated tokens of the chunks kept */
+  tokens: number;
+}
+
+/**
+ * Context window of a model, from the model catalogue or its name
+ */
+export function getModelContextWindow(model: string): number {
+  const known = RECOMMENDED_MODELS[model] ||
+    Object.values(RECOMMENDED_MODELS).find(m => m.id === model);
+  if (known) {
+    return known.contextWindow;
+  }
+
+  const name = model.toLowerCase().split('/').pop() || '';
+  const match = CONTEXT_WINDOWS.find(([prefix]) => name.startsWith(prefix));
+  return match ? match[1] : DEFAULT_CONTEXT_WINDOW;
+}
+
+/**
+ * Tokens available for retrieved code: the context window minus the
+ * answer reserve and the rest of the prompt
+ */
+export function getContextBudget(options: ContextBudgetOptions): number {
+  const window = options.contextWindow || getModelContextWindow(options.model);
+  return Math.max(0, window - options.maxOutputTokens - PROMPT_OVERHEAD_TOKENS);
+}
+
+/**
+ * Keep the pinned chunks, then the highest-scoring others that fit in the
+ * budget. A chunk that does not fit is truncated to the remaining space when
+ * enough is left, otherwise dropped; smaller lower-ranked chunks can still
+ * fill gaps.
+ */
+export function fitChunksToBudget(
+  chunks: VectorSearchResult<CodeChunkPayload>[],
+  budgetTokens: number
+): BudgetedChunks {
+  // Stable sort keeps retrieval order between equal scores
+  const ranked = chunks
+    .map((chunk, index) => ({ chunk, index }))
+    .filter(({ chunk }) => !chunk.payload.pinned)
+    .sort((a, b) => b.chunk.score - a.chunk.score || a.index - b.index)
+    .map(({ chunk }) => chunk);
+
+  const kept: VectorSearchResult<CodeChunkPayload>[] = [];
+  let remaining = budgetTokens;
+  let dropped = 0;
+  let truncated = 0;
+  let pinned = 0;
+
+  for (const chunk of [...chunks.filter(c => c.payload.pinned), ...ranked]) {
+    const cost = estimateTokens(chunk.payload.text) + CHUNK_OVERHEAD_TOKENS;
+    if (cost <= remaining) {
+      kept.push(chunk);
+      remaining -= cost;
+      continue;
+    }
+
+    const room = remaining - CHUNK_OVERHEAD_TOKENS;
+    if (chunk.payload.pinned) pinned++;
+    if (room >= MIN_TRUNCATED_CHUNK_TOKENS) {
+      kept.push(truncateChunk(chunk, room));
+      truncated++;
+      remaining = 0;
+      continue;
+    }
+
+    dropped++;
+  }
+
+  return { chunks: kept, dropped, truncated, pinned, tokens: budgetTokens - remaining };
+}
+
+/**
+ * Cut a chunk's text to roughly maxTokens, ending on a line boundary
+ */
+function truncateChunk(chunk: VectorSearchResult<CodeChunkPayload>, maxTokens: number): VectorSearchResult<CodeChunkPayload> {
+  const maxChars = maxTokens * 4;
+  const cut = chunk.payload.text.slice(0, maxChars);
+  const lastNewline = cut.lastIndexOf('\n');
+  const text = lastNewline > 0 ? cut.slice(0, lastNewline) : cut;
+  const endLine = chunk.payload.startLine + text.split('\n').length - 1;
+
+  const payload: CodeChunkPayload = { ...chunk.payload, text, endLine };
+  if (payload.wholeFile) {
+    payload.wholeFile = false;
+  }
+  return { ...chunk, payload };
+}
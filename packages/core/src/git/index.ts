@@ -6,7 +6,7 @@
 import { simpleGit, SimpleGit, StatusResult, DiffResult, LogResult } from 'simple-git';
 import * as path from 'path';
 import * as fs from 'fs/promises';
-import { GitError, WorkingTreeStatus, GitCommit, GitDiff } from '@cv-git/shared';
+import { GitError, WorkingTreeStatus, WorktreeInfo, GitCommit, GitDiff, GitFileChange, BlameLine } from '@cv-git/shared';
 
 const HOOK_MARKER = '# CV-GIT HOOK';
 
@@ -70,6 +70,42 @@ export class GitManager {
     }
   }
 
+  /**
+   * Worktree this checkout lives in and what HEAD resolves to.
+   * Works in detached-HEAD state, where getCurrentBranch() only returns "HEAD".
+   */
+  async getWorktreeInfo(): Promise<WorktreeInfo> {
+    try {
+      const [toplevel, gitDir, commonDir] = (await this.git.revparse(['--show-toplevel', '--git-dir', '--git-common-dir']))
+        .trim()
+        .split('\n');
+
+      let branch: string | undefined;
+      try {
+        branch = (await this.git.raw(['symbolic-ref', '--quiet', '--short', 'HEAD'])).trim() || undefined;
+      } catch {
+        // Detached HEAD
+      }
+
+      let head: string | undefined;
+      try {
+        head = (await this.git.revparse(['HEAD'])).trim();
+      } catch {
+        // No commits yet
+      }
+
+      return {
+        path: path.resolve(toplevel),
+        branch,
+        head,
+        // --git-dir and --git-common-dir may be relative to the repo root
+        linked: path.resolve(this.repoRoot, gitDir) !== path.resolve(this.repoRoot, commonDir)
+      };
+    } catch (error: any) {
+      throw new GitError(`Failed to get worktree info: ${error.message}`, error);
+    }
+  }
+
   /**
    * Get working tree status
    */
@@ -102,6 +138,18 @@ export class GitManager {
     }
   }
 
+  /**
+   * Get untracked files that are not ignored (.gitignore, .git/info/exclude)
+   */
+  async getUntrackedFiles(): Promise<string[]> {
+    try {
+      const result = await this.git.raw(['ls-files', '--others', '--exclude-standard']);
+      return result.trim().split('\n').filter(f => f.length > 0);
+    } catch (error: any) {
+      throw new GitError(`Failed to get untracked files: ${error.message}`, error);
+    }
+  }
+
   /**
    * Get recent commits
    */
@@ -184,6 +232,66 @@ export class GitManager {
     }
   }
 
+  /**
+   * Most recent commits touching any of the files, newest first, with full
+   * messages and `--shortstat` totals
+   */
+  async getFilesHistory(files: string[], limit: number = 10): Promise<GitCommit[]> {
+    if (files.length === 0 || limit <= 0) return [];
+
+    try {
+      // Record and field separators keep multi-line messages intact
+      const output = await this.git.raw([
+        'log', `--max-count=${limit}`, '--shortstat', '--format=%x1e%H%x1f%an%x1f%ae%x1f%at%x1f%B%x1f',
+        '--', ...files
+      ]);
+      return parseShortstatLog(output);
+    } catch (error: any) {
+      throw new GitError(`Failed to get history of ${files.length} files: ${error.message}`, error);
+    }
+  }
+
+  /**
+   * Commits reachable from HEAD, newest first, with full messages and the
+   * files each touched; with `since`, only those made after that commit
+   */
+  async getCommitLog(limit: number, since?: string): Promise<GitCommit[]> {
+    if (limit <= 0) return [];
+
+    try {
+      const output = await this.git.raw([
+        'log', `--max-count=${limit}`, '--name-only', '--format=%x1e%H%x1f%an%x1f%ae%x1f%at%x1f%B%x1f',
+        since ? `${since}..HEAD` : 'HEAD'
+      ]);
+      return parseNameOnlyLog(output);
+    } catch (error: any) {
+      throw new GitError(`Failed to get commit log: ${error.message}`, error);
+    }
+  }
+
+  /**
+   * Number of commits reachable from HEAD; with `since`, made after that commit
+   */
+  async countCommits(since?: string): Promise<number> {
+    try {
+      const output = await this.git.raw(['rev-list', '--count', since ? `${since}..HEAD` : 'HEAD']);
+      return parseInt(output.trim(), 10) || 0;
+    } catch (error: any) {
+      throw new GitError(`Failed to count commits: ${error.message}`, error);
+    }
+  }
+
+  /**
+   * Diff of a commit against its first parent (the whole tree for a root commit)
+   */
+  async getCommitDiff(sha: string, contextLines: number = 3): Promise<string> {
+    try {
+      return await this.git.show([`-U${contextLines}`, '--format=', '--first-parent', sha]);
+    } catch (error: any) {
+      throw new GitError(`Failed to get diff of ${sha}: ${error.message}`, error);
+    }
+  }
+
   /**
    * Get diff between two commits
    */
@@ -207,9 +315,12 @@ export class GitManager {
   /**
    * Get raw diff text (for review, etc.)
    */
-  async getRawDiff(ref?: string): Promise<string> {
+  async getRawDiff(ref?: string, contextLines?: number): Promise<string> {
     try {
-      const args = ref ? [ref] : [];
+      const args = [
+        ...(contextLines !== undefined ? [`--unified=${contextLines}`] : []),
+        ...(ref ? [ref] : [])
+      ];
       const diff = await this.git.diff(args);
       return diff || '';
     } catch (error: any) {
@@ -230,6 +341,29 @@ export class GitManager {
     }
   }
 
+  /**
+   * Blame a range of lines (1-based, inclusive) in the working-tree file
+   */
+  async blame(filePath: string, startLine: number, endLine: number): Promise<BlameLine[]> {
+    try {
+      const output = await this.git.raw(['blame', '--porcelain', '-L', `${startLine},${endLine}`, '--', filePath]);
+      return parseBlamePorcelain(output);
+    } catch (error: any) {
+      throw new GitError(`Failed to blame ${filePath}:${startLine}-${endLine}: ${error.message}`, error);
+    }
+  }
+
+  /**
+   * Diff a commit made to one file (the file's path as of that commit)
+   */
+  async getCommitFileDiff(sha: string, filePath: string, contextLines: number = 3): Promise<string> {
+    try {
+      return await this.git.show([`-U${contextLines}`, '--format=', sha, '--', filePath]);
+    } catch (error: any) {
+      throw new GitError(`Failed to get diff of ${filePath} in ${sha}: ${error.message}`, error);
+    }
+  }
+
   /**
    * Create a new branch
    */
@@ -276,6 +410,51 @@ export class GitManager {
     }
   }
 
+  /**
+   * Get files changed between two commits with their change status
+   * Renames are detected (-M) so callers can move data instead of rebuilding it
+   */
+  async getFileChangesSince(fromCommit: string, toCommit: string = 'HEAD'): Promise<GitFileChange[]> {
+    try {
+      const diff = await this.git.diff(['--name-status', '-M', fromCommit, toCommit]);
+      const changes: GitFileChange[] = [];
+
+      for (const line of diff.split('\n')) {
+        if (!line.trim()) continue;
+        const [code, first, second] = line.split('\t');
+        const status = code.charAt(0) as GitFileChange['status'];
+
+        if ((status === 'R' || status === 'C') && second) {
+          changes.push({
+            status,
+            path: second,
+            oldPath: first,
+            similarity: parseInt(code.slice(1), 10) || 0
+          });
+        } else if (['A', 'M', 'D', 'T'].includes(status) && first) {
+          changes.push({ status, path: first });
+        }
+      }
+
+      return changes;
+    } catch (error: any) {
+      throw new GitError(`Failed to get file changes: ${error.message}`, error);
+    }
+  }
+
+  /**
+   * Check whether a commit exists in the local repository
+   * (it may be gone after a rebase, force-push or gc)
+   */
+  async commitExists(sha: string): Promise<boolean> {
+    try {
+      await this.git.raw(['cat-file', '-e', `${sha}^{commit}`]);
+      return true;
+    } catch {
+      return false;
+    }
+  }
+
   /**
    * Get the git blob hash for a file
    * This is the SHA-1 hash of the file content as stored in git
@@ -488,6 +667,122 @@ ${existingContent.replace(/^#!.*\n/, '')}
   }
 }
 
+/** SHA git blame reports for lines that are not committed yet */
+const UNCOMMITTED_SHA = '0'.repeat(40);
+
+/**
+ * Parse `git blame --porcelain`. Commit headers are only printed the first
+ * time a commit appears, so they are remembered per SHA.
+ */
+export function parseBlamePorcelain(output: string): BlameLine[] {
+  const commits = new Map<string, { author: string; authorEmail: string; date: number; summary: string; filename: string }>();
+  const lines: BlameLine[] = [];
+  let current: { sha: string; originalLine: number; line: number } | undefined;
+
+  for (const raw of output.split('\n')) {
+    if (raw.startsWith('\t')) {
+      if (!current) continue;
+      const meta = commits.get(current.sha);
+      lines.push({
+        line: current.line,
+        originalLine: current.originalLine,
+        originalFile: meta?.filename || '',
+        sha: current.sha,
+        author: meta?.author || '',
+        authorEmail: meta?.authorEmail || '',
+        date: meta?.date || 0,
+        summary: meta?.summary || '',
+        content: raw.slice(1),
+        uncommitted: current.sha === UNCOMMITTED_SHA
+      });
+      current = undefined;
+      continue;
+    }
+
+    const header = raw.match(/^([0-9a-f]{40}) (\d+) (\d+)(?: \d+)?$/);
+    if (header) {
+      current = { sha: header[1], originalLine: parseInt(header[2], 10), line: parseInt(header[3], 10) };
+      if (!commits.has(current.sha)) {
+        commits.set(current.sha, { author: '', authorEmail: '', date: 0, summary: '', filename: '' });
+      }
+      continue;
+    }
+
+    const meta = current && commits.get(current.sha);
+    if (!meta) continue;
+    const space = raw.indexOf(' ');
+    const key = space < 0 ? raw : raw.slice(0, space);
+    const value = space < 0 ? '' : raw.slice(space + 1);
+    if (key === 'author') meta.author = value;
+    else if (key === 'author-mail') meta.authorEmail = value.replace(/^<|>$/g, '');
+    else if (key === 'author-time') meta.date = parseInt(value, 10) * 1000;
+    else if (key === 'summary') meta.summary = value;
+    else if (key === 'filename') meta.filename = value;
+  }
+
+  return lines;
+}
+
+/**
+ * Parse `git log --shortstat --format=%x1e%H%x1f%an%x1f%ae%x1f%at%x1f%B%x1f`.
+ * Each record is the commit fields followed by its shortstat line, if any
+ * (merges and empty commits have none).
+ */
+export function parseShortstatLog(output: string): GitCommit[] {
+  const commits: GitCommit[] = [];
+
+  for (const record of output.split('\x1e')) {
+    const fields = record.split('\x1f');
+    if (fields.length < 6) continue;
+    const [sha, author, authorEmail, time, message, rest] = fields;
+
+    const commit: GitCommit = {
+      sha: sha.trim(),
+      message: message.trim(),
+      author,
+      authorEmail,
+      date: parseInt(time, 10) * 1000,
+      files: []
+    };
+    const stat = rest.match(/(\d+) files? changed(?:, (\d+) insertions?\(\+\))?(?:, (\d+) deletions?\(-\))?/);
+    if (stat) {
+      commit.stats = {
+        filesChanged: parseInt(stat[1], 10),
+        insertions: parseInt(stat[2] || '0', 10),
+        deletions: parseInt(stat[3] || '0', 10)
+      };
+    }
+    commits.push(commit);
+  }
+
+  return commits;
+}
+
+/**
+ * Parse `git log --name-only --format=%x1e%H%x1f%an%x1f%ae%x1f%at%x1f%B%x1f`.
+ * Each record is the commit fields followed by the paths it touched, one per line.
+ */
+export function parseNameOnlyLog(output: string): GitCommit[] {
+  const commits: GitCommit[] = [];
+
+  for (const record of output.split('\x1e')) {
+    const fields = record.split('\x1f');
+    if (fields.length < 6) continue;
+    const [sha, author, authorEmail, time, message, rest] = fields;
+
+    commits.push({
+      sha: sha.trim(),
+      message: message.trim(),
+      author,
+      authorEmail,
+      date: parseInt(time, 10) * 1000,
+      files: rest.split('\n').map(line => line.trim()).filter(line => line.length > 0)
+    });
+  }
+
+  return commits;
+}
+
 /**
  * Create a GitManager instance
  */
@@ -8,11 +8,19 @@ export * from './parser/index.js';
 export * from './graph/index.js';
 export * from './vector/index.js';
 export * from './ai/index.js';
+export * from './ai/openai-compatible.js';
 export * from './ai/openrouter.js';
 export * from './ai/ollama.js';
 export * from './ai/lmstudio.js';
+export * from './ai/azure.js';
+export * from './ai/gemini.js';
+export * from './ai/anthropic.js';
+export * from './ai/cohere.js';
+export * from './ai/voyage.js';
+export * from './ai/huggingface.js';
 export * from './ai/types.js';
 export * from './ai/factory.js';
+export * from './ai/chat-backends.js';
 export * from './ai/system-capabilities.js';
 export * from './sync/index.js';
 export * from './config/index.js';
@@ -21,6 +29,8 @@ export * from './storage/index.js';
 export * from './context/index.js';
 export * from './deps/index.js';
 export * from './services/index.js';
+export * from './usage/index.js';
+export * from './logging/index.js';
 
 // Gateway (CV-Hub client)
 export * from './gateway/index.js';
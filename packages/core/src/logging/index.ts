@@ -0,0 +1,230 @@
+/**
+ * Logging
+ *
+ * Leveled diagnostics (error, warn, info, debug) kept apart from command
+ * output: they go to stderr, or to a file with --log-file, so stdout stays
+ * clean for pipes and --json. Like the usage session, the configuration is
+ * process-wide: the CLI sets it before a command runs.
+ *
+ * At debug, every API request made with fetch is logged with its provider,
+ * model, endpoint, status and latency.
+ *
+ * Level priority: --log-level > CV_LOG > CV_DEBUG (debug) > warn
+ */
+
+import * as fs from 'fs';
+
+export type LogLevel = 'error' | 'warn' | 'info' | 'debug';
+
+// Log level priority (lower = more important)
+export const LOG_LEVELS: Record<LogLevel, number> = {
+  error: 0,
+  warn: 1,
+  info: 2,
+  debug: 3
+};
+
+export const DEFAULT_LOG_LEVEL: LogLevel = 'warn';
+
+export interface LogEntry {
+  timestamp: string;
+  level: LogLevel;
+  component: string;
+  message: string;
+  data?: Record<string, unknown>;
+  duration?: number;
+}
+
+export interface LoggingOptions {
+  level?: LogLevel;
+  /** Append entries to this file as JSON lines instead of writing them to stderr */
+  file?: string;
+}
+
+/** Ports of the local services, which have no hostname to name them by */
+const LOCAL_PORT_PROVIDERS: Record<string, string> = {
+  '11434': 'ollama',
+  '1234': 'lmstudio',
+  '6333': 'qdrant'
+};
+
+/** API hosts by provider; a host ending in one of these belongs to it */
+const HOST_PROVIDERS: Array<[string, string]> = [
+  ['anthropic.com', 'anthropic'],
+  ['openrouter.ai', 'openrouter'],
+  ['openai.azure.com', 'azure'],
+  ['openai.com', 'openai'],
+  ['generativelanguage.googleapis.com', 'gemini'],
+  ['cohere.com', 'cohere'],
+  ['cohere.ai', 'cohere'],
+  ['voyageai.com', 'voyage'],
+  ['huggingface.co', 'huggingface'],
+  ['cloud.qdrant.io', 'qdrant']
+];
+
+// Until configureLogging runs; an invalid CV_LOG is reported there
+let configuredLevel: LogLevel = process.env.CV_DEBUG ? 'debug' : DEFAULT_LOG_LEVEL;
+let logFile: string | null = null;
+
+/** The fetch wrapped while requests are logged */
+let unloggedFetch: typeof fetch | null = null;
+
+/**
+ * Parse a level name, throwing for anything else
+ */
+export function parseLogLevel(value: string): LogLevel {
+  const level = value.trim().toLowerCase();
+  if (!(level in LOG_LEVELS)) {
+    throw new Error(`Invalid log level "${value}" (expected ${Object.keys(LOG_LEVELS).join(', ')})`);
+  }
+  return level as LogLevel;
+}
+
+/**
+ * Level from a flag or the environment. CV_DEBUG, which predates the
+ * levels, still turns on debug output.
+ */
+export function resolveLogLevel(flag?: string, env: NodeJS.ProcessEnv = process.env): LogLevel {
+  const value = flag || env.CV_LOG || env.CV_LOG_LEVEL;
+  if (value) return parseLogLevel(value);
+  return env.CV_DEBUG ? 'debug' : DEFAULT_LOG_LEVEL;
+}
+
+/**
+ * Set the level and destination of all loggers. At debug, API requests
+ * are logged too.
+ */
+export function configureLogging(options: LoggingOptions): void {
+  configuredLevel = options.level ?? resolveLogLevel();
+  logFile = options.file ?? null;
+
+  if (configuredLevel === 'debug' && !unloggedFetch) {
+    unloggedFetch = globalThis.fetch;
+    globalThis.fetch = loggingFetch;
+  } else if (configuredLevel !== 'debug' && unloggedFetch) {
+    globalThis.fetch = unloggedFetch;
+    unloggedFetch = null;
+  }
+}
+
+/**
+ * Whether entries at a level are written
+ */
+export function isLogLevelEnabled(level: LogLevel): boolean {
+  return LOG_LEVELS[level] <= LOG_LEVELS[configuredLevel];
+}
+
+/**
+ * Whether fetch requests are being logged. The SDKs' own transports bypass
+ * fetch, so they are handed the global fetch while this is true.
+ */
+export function isRequestLoggingEnabled(): boolean {
+  return unloggedFetch !== null;
+}
+
+/**
+ * One line of stderr output for an entry
+ */
+export function formatLogEntry(entry: LogEntry): string {
+  const levelStr = entry.level.toUpperCase().padEnd(5);
+  const durationStr = entry.duration !== undefined ? ` (${entry.duration}ms)` : '';
+  const dataStr = entry.data ? ` ${JSON.stringify(entry.data)}` : '';
+  return `[${entry.timestamp}] ${levelStr} [${entry.component}] ${entry.message}${durationStr}${dataStr}`;
+}
+
+function writeLog(entry: LogEntry): void {
+  if (!isLogLevelEnabled(entry.level)) return;
+
+  if (logFile) {
+    // Synchronous, so entries logged just before process.exit() are kept
+    try {
+      fs.appendFileSync(logFile, JSON.stringify(entry) + '\n');
+      return;
+    } catch {
+      // Fall back to stderr rather than lose the entry
+    }
+  }
+  process.stderr.write(formatLogEntry(entry) + '\n');
+}
+
+export interface Logger {
+  error(message: string, data?: Record<string, unknown>): void;
+  warn(message: string, data?: Record<string, unknown>): void;
+  info(message: string, data?: Record<string, unknown>): void;
+  debug(message: string, data?: Record<string, unknown>): void;
+}
+
+/**
+ * Create a logger for a component, e.g. `createLogger('vector')`
+ */
+export function createLogger(component: string): Logger {
+  const log = (level: LogLevel, message: string, data?: Record<string, unknown>) => {
+    writeLog({ timestamp: new Date().toISOString(), level, component, message, data });
+  };
+
+  return {
+    error: (message, data) => log('error', message, data),
+    warn: (message, data) => log('warn', message, data),
+    info: (message, data) => log('info', message, data),
+    debug: (message, data) => log('debug', message, data)
+  };
+}
+
+/**
+ * The provider an API URL belongs to, or its host if it is not a known one
+ */
+export function providerOfUrl(url: URL): string {
+  const local = ['localhost', '127.0.0.1', '[::1]'].includes(url.hostname);
+  if (local && LOCAL_PORT_PROVIDERS[url.port]) {
+    return LOCAL_PORT_PROVIDERS[url.port];
+  }
+  const match = HOST_PROVIDERS.find(([host]) => url.hostname === host || url.hostname.endsWith(`.${host}`));
+  return match ? match[1] : url.host;
+}
+
+/**
+ * The `model` of a JSON request body, if it has one
+ */
+function modelOfBody(body: unknown): string | undefined {
+  if (typeof body !== 'string') return undefined;
+  try {
+    const model = JSON.parse(body)?.model;
+    return typeof model === 'string' ? model : undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+async function loggingFetch(input: Parameters<typeof fetch>[0], init?: Parameters<typeof fetch>[1]): Promise<Response> {
+  const url = new URL(typeof input === 'string' ? input : input instanceof URL ? input.href : input.url);
+  const method = init?.method ?? (input instanceof Request ? input.method : 'GET');
+  // The query string is left out: Gemini and others can carry the API key there
+  const endpoint = `${url.origin}${url.pathname}`;
+  const data: Record<string, unknown> = { provider: providerOfUrl(url), method, endpoint };
+  const model = modelOfBody(init?.body);
+  if (model) data.model = model;
+
+  const start = Date.now();
+  try {
+    const response = await unloggedFetch!(input, init);
+    writeLog({
+      timestamp: new Date().toISOString(),
+      level: 'debug',
+      component: 'api',
+      message: `${method} ${endpoint} ${response.status}`,
+      data: { ...data, status: response.status },
+      duration: Date.now() - start
+    });
+    return response;
+  } catch (error: any) {
+    writeLog({
+      timestamp: new Date().toISOString(),
+      level: 'debug',
+      component: 'api',
+      message: `${method} ${endpoint} failed`,
+      data: { ...data, error: error.cause?.message ?? error.message },
+      duration: Date.now() - start
+    });
+    throw error;
+  }
+}
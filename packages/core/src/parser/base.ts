@@ -12,6 +12,13 @@ import {
   Export,
   CodeChunk
 } from '@cv-git/shared';
+import { ChunkingOptions, chunkByLines, createChunkId, DEFAULT_MAX_CHUNK_LINES, resolveOverlapLines } from './chunking.js';
+
+/**
+ * Symbols that get their own chunk; type declarations are included so
+ * `cv symbol` can find them by name
+ */
+const CHUNKED_SYMBOL_KINDS = new Set(['function', 'method', 'class', 'interface', 'type', 'enum', 'struct']);
 
 /**
  * Tree-sitter node interface
@@ -84,6 +91,11 @@ export interface ILanguageParser {
    * Chunk code for embedding
    */
   chunkCode(content: string, symbols: SymbolNode[], filePath: string): CodeChunk[];
+
+  /**
+   * Configure chunk size limits
+   */
+  setChunkingOptions?(options: ChunkingOptions): void;
 }
 
 /**
@@ -93,11 +105,16 @@ export interface ILanguageParser {
 export abstract class BaseLanguageParser implements ILanguageParser {
   protected parser: Parser | null = null;
   protected config: ParserConfig;
+  protected chunkingOptions: ChunkingOptions = {};
 
   constructor(config: ParserConfig) {
     this.config = config;
   }
 
+  setChunkingOptions(options: ChunkingOptions): void {
+    this.chunkingOptions = options;
+  }
+
   abstract getLanguage(): string;
   abstract getSupportedExtensions(): string[];
   abstract initialize(): void;
@@ -161,11 +178,11 @@ export abstract class BaseLanguageParser implements ILanguageParser {
 
     // Chunk by symbol (preferred)
     for (const symbol of symbols) {
-      if (symbol.kind === 'function' || symbol.kind === 'method' || symbol.kind === 'class') {
+      if (CHUNKED_SYMBOL_KINDS.has(symbol.kind)) {
         const text = lines.slice(symbol.startLine - 1, symbol.endLine).join('\n');
 
         chunks.push({
-          id: this.generateChunkId(filePath, symbol.startLine, symbol.endLine),
+          id: this.generateChunkId(filePath, symbol.startLine, text),
           file: filePath,
           language: this.getLanguage(),
           startLine: symbol.startLine,
@@ -173,6 +190,7 @@ export abstract class BaseLanguageParser implements ILanguageParser {
           text,
           symbolName: symbol.name,
           symbolKind: symbol.kind,
+          signature: symbol.signature,
           summary: symbol.docstring,
           docstring: symbol.docstring,
           complexity: symbol.complexity
@@ -180,16 +198,10 @@ export abstract class BaseLanguageParser implements ILanguageParser {
       }
     }
 
-    // If no symbols or file is small, chunk the whole file
-    if (chunks.length === 0 && lines.length < 200) {
-      chunks.push({
-        id: this.generateChunkId(filePath, 1, lines.length),
-        file: filePath,
-        language: this.getLanguage(),
-        startLine: 1,
-        endLine: lines.length,
-        text: content
-      });
+    // No symbols: fall back to line windows
+    if (chunks.length === 0) {
+      const maxLines = this.chunkingOptions.maxChunkLines || DEFAULT_MAX_CHUNK_LINES;
+      chunks.push(...chunkByLines(filePath, content, this.getLanguage(), maxLines, resolveOverlapLines(this.chunkingOptions)));
     }
 
     return chunks;
@@ -302,10 +314,10 @@ export abstract class BaseLanguageParser implements ILanguageParser {
   }
 
   /**
-   * Generate unique chunk ID
+   * Generate a deterministic chunk ID from the location and content
    */
-  protected generateChunkId(filePath: string, startLine: number, endLine: number): string {
-    return `${filePath}:${startLine}-${endLine}`;
+  protected generateChunkId(filePath: string, startLine: number, text: string): string {
+    return createChunkId(filePath, startLine, text);
   }
 
   /**
@@ -0,0 +1,403 @@
+/**
+ * Chunking Strategies
+ * Splits source files into chunks for embedding
+ *
+ * Languages with a registered strategy are split at declaration boundaries
+ * so each chunk is a coherent symbol. Everything else falls back to fixed
+ * line windows.
+ */
+
+import { CodeChunk, SymbolKind, SymbolNode } from '@cv-git/shared';
+import { createHash } from 'crypto';
+import * as path from 'path';
+
+/**
+ * Default maximum chunk size in lines
+ */
+export const DEFAULT_MAX_CHUNK_LINES = 200;
+
+/**
+ * Default lines adjacent line windows share, so code cut by a window
+ * boundary is whole in one of the two windows
+ */
+export const DEFAULT_CHUNK_OVERLAP_LINES = 20;
+
+/**
+ * Options controlling chunk size
+ */
+export interface ChunkingOptions {
+  /** Maximum lines per chunk; larger declarations are split at statement boundaries */
+  maxChunkLines?: number;
+  /** Lines each line window repeats from the end of the previous one (at most half a window) */
+  overlapLines?: number;
+}
+
+/**
+ * A chunking strategy for one language
+ */
+export type ChunkStrategy = (
+  filePath: string,
+  content: string,
+  symbols: SymbolNode[],
+  maxChunkLines: number
+) => CodeChunk[];
+
+const strategies: Map<string, ChunkStrategy> = new Map([
+  ['.go', chunkGo]
+]);
+
+/**
+ * Check whether a file has a language-aware chunking strategy
+ */
+export function hasChunkStrategy(filePath: string): boolean {
+  return strategies.has(path.extname(filePath));
+}
+
+/**
+ * Chunk a file using the strategy registered for its extension,
+ * falling back to line windows for unknown languages
+ */
+export function chunkFile(
+  filePath: string,
+  content: string,
+  symbols: SymbolNode[] = [],
+  options: ChunkingOptions = {},
+  language?: string
+): CodeChunk[] {
+  const maxChunkLines = resolveMaxChunkLines(options);
+  const strategy = strategies.get(path.extname(filePath));
+
+  if (strategy) {
+    const chunks = strategy(filePath, content, symbols, maxChunkLines);
+    if (chunks.length > 0) {
+      return chunks;
+    }
+  }
+
+  return chunkByLines(filePath, content, language || 'unknown', maxChunkLines, resolveOverlapLines(options));
+}
+
+/**
+ * Fixed-size line window chunker. Windows after the first start
+ * `overlapLines` before the previous one ended.
+ */
+export function chunkByLines(
+  filePath: string,
+  content: string,
+  language: string,
+  maxChunkLines: number = DEFAULT_MAX_CHUNK_LINES,
+  overlapLines: number = 0
+): CodeChunk[] {
+  const chunks: CodeChunk[] = [];
+  const lines = content.split('\n');
+
+  if (content.trim().length === 0) {
+    return chunks;
+  }
+
+  // The overlap is capped at half a window so every window adds new lines
+  const overlap = Math.min(Math.max(0, Math.floor(overlapLines)), Math.floor(maxChunkLines / 2));
+  const step = maxChunkLines - overlap;
+
+  for (let i = 0; i < lines.length; i += step) {
+    const endLine = Math.min(i + maxChunkLines, lines.length);
+    const text = lines.slice(i, endLine).join('\n');
+    chunks.push({
+      id: createChunkId(filePath, i + 1, text),
+      file: filePath,
+      language,
+      startLine: i + 1,
+      endLine,
+      text
+    });
+    if (endLine === lines.length) break;
+  }
+
+  return chunks;
+}
+
+// ========== Go ==========
+
+interface GoDeclaration {
+  name: string;
+  kind: SymbolKind;
+  /** First line of the declaration including its doc comment (0-based) */
+  startIndex: number;
+  /** Line the declaration keyword is on (0-based) */
+  declIndex: number;
+  /** Last line of the declaration (0-based) */
+  endIndex: number;
+  docstring?: string;
+}
+
+interface GoScanState {
+  inBlockComment: boolean;
+  inRawString: boolean;
+}
+
+/**
+ * Chunk Go source at top-level declarations (funcs, methods, types, var/const blocks)
+ */
+function chunkGo(
+  filePath: string,
+  content: string,
+  symbols: SymbolNode[],
+  maxChunkLines: number
+): CodeChunk[] {
+  const lines = content.split('\n');
+  const chunks: CodeChunk[] = [];
+
+  for (const decl of findGoDeclarations(lines)) {
+    const symbol = symbols.find(s => s.startLine === decl.declIndex + 1 && s.name === decl.name.split('.').pop());
+    const kind = symbol?.kind || decl.kind;
+    const lineCount = decl.endIndex - decl.startIndex + 1;
+
+    const ranges = lineCount > maxChunkLines
+      ? splitAtStatements(lines, decl.startIndex, decl.endIndex, maxChunkLines)
+      : [[decl.startIndex, decl.endIndex]];
+
+    for (const [start, end] of ranges) {
+      const text = lines.slice(start, end + 1).join('\n');
+      chunks.push({
+        id: createChunkId(filePath, start + 1, text),
+        file: filePath,
+        language: 'go',
+        startLine: start + 1,
+        endLine: end + 1,
+        text,
+        symbolName: decl.name,
+        symbolKind: kind,
+        signature: symbol?.signature,
+        summary: decl.docstring,
+        docstring: decl.docstring,
+        complexity: symbol?.complexity
+      });
+    }
+  }
+
+  return chunks;
+}
+
+/**
+ * Find top-level Go declarations with their doc comments attached
+ */
+function findGoDeclarations(lines: string[]): GoDeclaration[] {
+  const declarations: GoDeclaration[] = [];
+  const state: GoScanState = { inBlockComment: false, inRawString: false };
+  let depth = 0;
+  let i = 0;
+
+  while (i < lines.length) {
+    const line = lines[i];
+    const atTopLevel = depth === 0 && !state.inBlockComment && !state.inRawString;
+    const header = atTopLevel ? parseGoDeclHeader(line) : null;
+
+    if (!header) {
+      depth += scanGoLine(line, state);
+      i++;
+      continue;
+    }
+
+    // Walk forward until all braces/parens opened by the declaration are closed
+    let end = i;
+    let declDepth = scanGoLine(line, state);
+    while (declDepth > 0 && end + 1 < lines.length) {
+      end++;
+      declDepth += scanGoLine(lines[end], state);
+    }
+
+    const startIndex = findDocCommentStart(lines, i);
+    const docstring = startIndex < i
+      ? lines.slice(startIndex, i).join('\n').trim()
+      : undefined;
+
+    declarations.push({
+      name: header.name,
+      kind: header.kind,
+      startIndex,
+      declIndex: i,
+      endIndex: end,
+      docstring
+    });
+
+    i = end + 1;
+  }
+
+  return declarations;
+}
+
+/**
+ * Identify a top-level declaration line and extract its name and kind
+ */
+function parseGoDeclHeader(line: string): { name: string; kind: SymbolKind } | null {
+  let match = line.match(/^func\s+\(\s*(?:\w+\s+)?\*?\s*(\w+)(?:\[[^\]]*\])?\s*\)\s*(\w+)/);
+  if (match) {
+    return { name: `${match[1]}.${match[2]}`, kind: 'method' };
+  }
+
+  match = line.match(/^func\s+(\w+)/);
+  if (match) {
+    return { name: match[1], kind: 'function' };
+  }
+
+  match = line.match(/^type\s+(\w+)(?:\[[^\]]*\])?\s+(struct|interface)?/);
+  if (match) {
+    const kind: SymbolKind = match[2] === 'struct'
+      ? 'struct'
+      : match[2] === 'interface' ? 'interface' : 'type';
+    return { name: match[1], kind };
+  }
+
+  match = line.match(/^(type|var|const)\s*\(/);
+  if (match) {
+    const kind: SymbolKind = match[1] === 'const' ? 'constant' : match[1] === 'var' ? 'variable' : 'type';
+    return { name: match[1], kind };
+  }
+
+  match = line.match(/^(var|const)\s+(\w+)/);
+  if (match) {
+    return { name: match[2], kind: match[1] === 'const' ? 'constant' : 'variable' };
+  }
+
+  return null;
+}
+
+/**
+ * Find the first line of the `//` comment block directly above a declaration
+ */
+function findDocCommentStart(lines: string[], declIndex: number): number {
+  let start = declIndex;
+  while (start > 0 && lines[start - 1].trim().startsWith('//')) {
+    start--;
+  }
+  return start;
+}
+
+/**
+ * Split a large declaration into ranges of at most maxChunkLines,
+ * cutting after lines where a statement in the body ends
+ */
+function splitAtStatements(
+  lines: string[],
+  startIndex: number,
+  endIndex: number,
+  maxChunkLines: number
+): Array<[number, number]> {
+  const ranges: Array<[number, number]> = [];
+  const state: GoScanState = { inBlockComment: false, inRawString: false };
+  let depth = 0;
+  let bodyDepth = -1;
+  let chunkStart = startIndex;
+  let lastBoundary = -1;
+
+  for (let i = startIndex; i <= endIndex; i++) {
+    depth += scanGoLine(lines[i], state);
+
+    // The body depth is the nesting level after the declaration's opening brace
+    if (bodyDepth < 0 && depth > 0) {
+      bodyDepth = depth;
+    }
+
+    if (depth === bodyDepth && !state.inBlockComment && !state.inRawString) {
+      lastBoundary = i;
+    }
+
+    if (i - chunkStart + 1 >= maxChunkLines && i < endIndex) {
+      const cut = lastBoundary >= chunkStart ? lastBoundary : i;
+      ranges.push([chunkStart, cut]);
+      chunkStart = cut + 1;
+      lastBoundary = -1;
+    }
+  }
+
+  if (chunkStart <= endIndex) {
+    ranges.push([chunkStart, endIndex]);
+  }
+
+  return ranges;
+}
+
+/**
+ * Net brace/paren depth change for one line of Go, ignoring strings and comments
+ */
+function scanGoLine(line: string, state: GoScanState): number {
+  let delta = 0;
+  let i = 0;
+
+  while (i < line.length) {
+    const ch = line[i];
+    const next = line[i + 1];
+
+    if (state.inBlockComment) {
+      if (ch === '*' && next === '/') {
+        state.inBlockComment = false;
+        i += 2;
+        continue;
+      }
+      i++;
+      continue;
+    }
+
+    if (state.inRawString) {
+      if (ch === '`') {
+        state.inRawString = false;
+      }
+      i++;
+      continue;
+    }
+
+    if (ch === '/' && next === '/') {
+      break;
+    }
+    if (ch === '/' && next === '*') {
+      state.inBlockComment = true;
+      i += 2;
+      continue;
+    }
+    if (ch === '`') {
+      state.inRawString = true;
+      i++;
+      continue;
+    }
+    if (ch === '"' || ch === '\'') {
+      // Skip interpreted string or rune literal
+      i++;
+      while (i < line.length && line[i] !== ch) {
+        if (line[i] === '\\') i++;
+        i++;
+      }
+      i++;
+      continue;
+    }
+
+    if (ch === '{' || ch === '(') delta++;
+    else if (ch === '}' || ch === ')') delta--;
+    i++;
+  }
+
+  return delta;
+}
+
+function resolveMaxChunkLines(options: ChunkingOptions): number {
+  const max = options.maxChunkLines;
+  return max && max > 0 ? Math.floor(max) : DEFAULT_MAX_CHUNK_LINES;
+}
+
+/**
+ * Line window overlap for the options (0 turns it off)
+ */
+export function resolveOverlapLines(options: ChunkingOptions): number {
+  const overlap = options.overlapLines;
+  return overlap !== undefined && overlap >= 0 ? Math.floor(overlap) : DEFAULT_CHUNK_OVERLAP_LINES;
+}
+
+/**
+ * Deterministic chunk ID from the file, start line and content, e.g.
+ * `src/db.go:42:3f9c1a0b7d2e4c68`. Point IDs derive from it, so a retried
+ * or concurrent sync of the same code upserts over its vectors instead of
+ * adding copies, and changed code never reuses the ID of what it replaced.
+ */
+export function createChunkId(filePath: string, startLine: number, text: string): string {
+  const contentHash = createHash('sha256').update(text).digest('hex').substring(0, 16);
+  return `${filePath}:${startLine}:${contentHash}`;
+}
@@ -0,0 +1,129 @@
+/**
+ * Comment Stripping
+ *
+ * Removes license headers and other comments from code before it is
+ * embedded, so boilerplate does not dilute what a chunk's vector means.
+ * Only the embedded text is stripped; the chunk keeps its original text
+ * for display.
+ *
+ * Only comments on lines of their own are removed: a trailing comment
+ * after code needs a tokenizer to tell apart from a string like "http://".
+ * Doc comments stay, since they describe the symbol they are attached to:
+ * blocks opened with a doc marker (/**, ///, //!), and any comment block
+ * directly above a declaration (Go doc comments, Ruby and Python #
+ * comments over a def).
+ */
+
+/**
+ * - none: embed comments as written
+ * - headers: drop the comment block at the top of a file (license, generated-code notices)
+ * - all: drop every comment except doc comments
+ */
+export type CommentStripMode = 'none' | 'headers' | 'all';
+
+export const COMMENT_STRIP_MODES: CommentStripMode[] = ['none', 'headers', 'all'];
+
+interface CommentSyntax {
+  line: string[];
+  block?: [string, string];
+  /** Markers that open a doc comment */
+  doc: string[];
+}
+
+const C_STYLE: CommentSyntax = { line: ['//'], block: ['/*', '*/'], doc: ['/**', '///', '//!', '/*!'] };
+const HASH: CommentSyntax = { line: ['#'], doc: [] };
+
+const COMMENT_SYNTAX: Record<string, CommentSyntax> = {
+  typescript: C_STYLE,
+  javascript: C_STYLE,
+  go: C_STYLE,
+  rust: C_STYLE,
+  java: C_STYLE,
+  c: C_STYLE,
+  cpp: C_STYLE,
+  csharp: C_STYLE,
+  kotlin: C_STYLE,
+  swift: C_STYLE,
+  scala: C_STYLE,
+  php: { line: ['//', '#'], block: ['/*', '*/'], doc: ['/**'] },
+  python: HASH,
+  ruby: HASH,
+  bash: HASH,
+  zsh: HASH
+};
+
+/** Lines a doc comment may sit directly above */
+const DECLARATION = /^(@|#\[|(export|default|declare|async|public|private|protected|internal|static|abstract|final|override|sealed|open|data|inline|unsafe|extern|virtual|pub(\([\w:]+\))?)\s)*(function|class|interface|type|enum|struct|trait|impl|fn|func|def|module|namespace|package|const|let|var|val|object|record|mod|static|macro_rules!)\b/;
+
+/**
+ * Text to embed for a chunk of code: comments removed per `mode`.
+ * `atFileStart` says the text begins at line 1, where headers are.
+ * Languages without a known comment syntax are left as they are.
+ */
+export function stripComments(text: string, language: string, mode: CommentStripMode, atFileStart: boolean = true): string {
+  const syntax = COMMENT_SYNTAX[language];
+  if (mode === 'none' || !syntax || (mode === 'headers' && !atFileStart)) {
+    return text;
+  }
+
+  const lines = text.split('\n');
+  const kept: string[] = [];
+  let i = 0;
+  const header = mode === 'headers';
+
+  while (i < lines.length) {
+    const end = commentBlockEnd(lines, i, syntax);
+    if (end < 0) {
+      // A shebang comes before the header
+      if (header && lines[i].trim() !== '' && !(i === 0 && lines[i].startsWith('#!'))) break;
+      kept.push(lines[i]);
+      i++;
+      continue;
+    }
+
+    // A doc comment, or a comment directly above a declaration, is kept
+    const next = lines[end + 1]?.trim() ?? '';
+    const isDoc = syntax.doc.some(marker => lines[i].trim().startsWith(marker));
+    if (isDoc || DECLARATION.test(next)) {
+      if (header) break;
+      kept.push(...lines.slice(i, end + 1));
+    }
+    i = end + 1;
+  }
+  kept.push(...lines.slice(i));
+
+  // Stripped comments leave blank lines behind
+  return kept.join('\n').replace(/\n{3,}/g, '\n\n').replace(/^\n+/, '');
+}
+
+/**
+ * Last line of the run of comment-only lines starting at `start`, or -1 if
+ * that line is not a comment. A block comment with code after its close
+ * is not counted, so its line is kept.
+ */
+function commentBlockEnd(lines: string[], start: number, syntax: CommentSyntax): number {
+  let end = -1;
+  let i = start;
+  while (i < lines.length) {
+    const line = lines[i].trim();
+    if (syntax.line.some(marker => line.startsWith(marker)) && !line.startsWith('#!') && !line.startsWith('#[')) {
+      end = i++;
+      continue;
+    }
+    if (syntax.block && line.startsWith(syntax.block[0])) {
+      // The opening marker's own characters do not close it (/*/)
+      let close = i;
+      while (close < lines.length && !(close === i ? line.slice(syntax.block[0].length) : lines[close]).includes(syntax.block[1])) {
+        close++;
+      }
+      if (close === lines.length || !lines[close].trim().endsWith(syntax.block[1])) {
+        return end;
+      }
+      end = close;
+      i = close + 1;
+      continue;
+    }
+    break;
+  }
+  return end;
+}
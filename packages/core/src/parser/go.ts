@@ -6,13 +6,15 @@
 import Parser from 'tree-sitter';
 import Go from 'tree-sitter-go';
 import { BaseLanguageParser, TreeSitterNode } from './base.js';
+import { chunkFile } from './chunking.js';
 import {
   SymbolNode,
   Import,
   Export,
   Parameter,
   CallInfo,
-  Visibility
+  Visibility,
+  CodeChunk
 } from '@cv-git/shared';
 
 /**
@@ -58,6 +60,13 @@ export class GoParser extends BaseLanguageParser {
     return symbols;
   }
 
+  /**
+   * Chunk at top-level declarations with doc comments attached
+   */
+  chunkCode(content: string, symbols: SymbolNode[], filePath: string): CodeChunk[] {
+    return chunkFile(filePath, content, symbols, this.chunkingOptions, 'go');
+  }
+
   /**
    * Extract function declarations
    */
@@ -11,6 +11,7 @@ import { ParsedFile, ParsedDocument } from '@cv-git/shared';
 import { ILanguageParser } from './base.js';
 import { createMarkdownParser, MarkdownParser } from './markdown.js';
 import { createSimpleParsers } from './simple.js';
+import { ChunkingOptions } from './chunking.js';
 import * as path from 'path';
 
 // Track if tree-sitter is available
@@ -25,8 +26,10 @@ export class CodeParser {
   private extensionMap: Map<string, string> = new Map();
   private markdownParser: MarkdownParser;
   private usingSimpleParsers: boolean = false;
+  private chunkingOptions: ChunkingOptions;
 
-  constructor() {
+  constructor(chunkingOptions: ChunkingOptions = {}) {
+    this.chunkingOptions = chunkingOptions;
     this.markdownParser = createMarkdownParser();
     this.initializeParsers();
   }
@@ -139,6 +142,7 @@ export class CodeParser {
    * Register a language parser
    */
   private registerParser(language: string, parser: ILanguageParser): void {
+    parser.setChunkingOptions?.(this.chunkingOptions);
     this.parsers.set(language, parser);
 
     // Map file extensions to language
@@ -240,8 +244,8 @@ export class CodeParser {
 /**
  * Create a parser instance
  */
-export function createParser(): CodeParser {
-  return new CodeParser();
+export function createParser(chunkingOptions?: ChunkingOptions): CodeParser {
+  return new CodeParser(chunkingOptions);
 }
 
 // Re-export base classes for extending
@@ -252,3 +256,14 @@ export function createParser(): CodeParser {
 // They are loaded dynamically via require() inside initializeTreeSitterParsers().
 export { ILanguageParser, BaseLanguageParser, TreeSitterNode } from './base.js';
 export { MarkdownParser, createMarkdownParser, MarkdownParserConfig } from './markdown.js';
+export {
+  chunkFile,
+  chunkByLines,
+  hasChunkStrategy,
+  createChunkId,
+  ChunkingOptions,
+  ChunkStrategy,
+  DEFAULT_MAX_CHUNK_LINES,
+  DEFAULT_CHUNK_OVERLAP_LINES
+} from './chunking.js';
+export { stripComments, CommentStripMode, COMMENT_STRIP_MODES } from './comments.js';
@@ -19,12 +19,14 @@ import {
   ImportType
 } from '@cv-git/shared';
 import { ILanguageParser, ParserConfig, TreeSitterNode } from './base.js';
+import { ChunkingOptions, chunkFile, createChunkId, hasChunkStrategy } from './chunking.js';
 
 /**
  * Simple regex-based parser for when tree-sitter is unavailable
  */
 export class SimpleParser implements ILanguageParser {
   private config: ParserConfig;
+  private chunkingOptions: ChunkingOptions = {};
   private patterns: {
     function: RegExp;
     class: RegExp;
@@ -111,6 +113,10 @@ export class SimpleParser implements ILanguageParser {
     // No-op for simple parser (no tree-sitter to initialize)
   }
 
+  setChunkingOptions(options: ChunkingOptions): void {
+    this.chunkingOptions = options;
+  }
+
   extractSymbols(_node: TreeSitterNode, _filePath: string, _content: string): SymbolNode[] {
     // Not used directly - parseFile handles extraction
     return [];
@@ -221,19 +227,22 @@ export class SimpleParser implements ILanguageParser {
       }
     }
 
-    // Create code chunks (by symbols or fixed-size blocks)
-    if (symbols.length > 0) {
+    // Create code chunks (language-aware, by symbols, or fixed-size blocks)
+    if (hasChunkStrategy(filePath)) {
+      chunks.push(...chunkFile(filePath, content, symbols, this.chunkingOptions, this.config.language));
+    } else if (symbols.length > 0) {
       for (const symbol of symbols) {
-        const chunkLines = lines.slice(symbol.startLine - 1, symbol.endLine);
+        const text = lines.slice(symbol.startLine - 1, symbol.endLine).join('\n');
         chunks.push({
-          id: `${filePath}:${symbol.startLine}`,
+          id: createChunkId(filePath, symbol.startLine, text),
           file: filePath,
           startLine: symbol.startLine,
           endLine: symbol.endLine,
-          text: chunkLines.join('\n'),
+          text,
           language: this.config.language,
           symbolName: symbol.name,
-          symbolKind: symbol.kind
+          symbolKind: symbol.kind,
+          signature: symbol.signature
         });
       }
     } else {
@@ -241,12 +250,13 @@ export class SimpleParser implements ILanguageParser {
       const chunkSize = 50;
       for (let i = 0; i < lines.length; i += chunkSize) {
         const endLine = Math.min(i + chunkSize, lines.length);
+        const text = lines.slice(i, endLine).join('\n');
         chunks.push({
-          id: `${filePath}:${i + 1}`,
+          id: createChunkId(filePath, i + 1, text),
           file: filePath,
           startLine: i + 1,
           endLine,
-          text: lines.slice(i, endLine).join('\n'),
+          text,
           language: this.config.language
         });
       }
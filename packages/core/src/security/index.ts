@@ -154,3 +154,5 @@ export function createSecurityScanner(): never {
 export function createAISecurityAnalyzer(): never {
   throw new Error(AISecurityAnalyzer.NOT_IMPLEMENTED);
 }
+
+export * from './redact.js';
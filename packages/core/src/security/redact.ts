@@ -0,0 +1,172 @@
+/**
+ * Secret Redaction
+ *
+ * Masks credentials in code before it is placed in an LLM prompt.
+ * Known key formats are matched by pattern; other quoted literals are
+ * masked when they look random (long, mixed letters and digits, high entropy).
+ * Only the secret value is replaced, so surrounding code keeps its shape.
+ */
+
+export const REDACTED_SECRET = '<REDACTED_SECRET>';
+
+/**
+ * Numbered placeholder of indexed redaction, for code the model rewrites and
+ * the secrets are put back into (see restoreRedactedSecrets)
+ */
+export function indexedSecretPlaceholder(index: number): string {
+  return `<REDACTED_SECRET_${index}>`;
+}
+
+/** A placeholder of either form, for values an earlier pattern already masked */
+const ANY_PLACEHOLDER = /<REDACTED_SECRET(?:_\d+)?>/;
+
+export interface RedactionPattern {
+  name: string;
+  /**
+   * Global regex. If it has a named group `secret`, only that group is replaced;
+   * otherwise the whole match is.
+   */
+  pattern: RegExp;
+}
+
+export const DEFAULT_SECRET_PATTERNS: readonly RedactionPattern[] = [
+  {
+    name: 'private-key',
+    pattern: /-----BEGIN [A-Z ]*PRIVATE KEY-----(?<secret>[\s\S]*?)-----END [A-Z ]*PRIVATE KEY-----/g
+  },
+  { name: 'aws-access-key-id', pattern: /\b(?:AKIA|ASIA)[0-9A-Z]{16}\b/g },
+  {
+    name: 'aws-secret-access-key',
+    pattern: /aws_secret_access_key["']?\s*[:=]\s*["']?(?<secret>[A-Za-z0-9/+=]{40})/gi
+  },
+  { name: 'bearer-token', pattern: /\bBearer\s+(?<secret>[A-Za-z0-9\-._~+/]{16,}=*)/g },
+  { name: 'github-token', pattern: /\bgh[pousr]_[A-Za-z0-9]{36,}\b/g },
+  { name: 'slack-token', pattern: /\bxox[abprs]-[A-Za-z0-9-]{10,}\b/g },
+  { name: 'api-key', pattern: /\bsk-(?:ant-|or-|proj-)?[A-Za-z0-9_-]{20,}\b/g },
+  {
+    name: 'assigned-secret',
+    pattern: /(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["'](?<secret>[^"'\s]{8,})["']/gi
+  }
+];
+
+/** Quoted literals that are candidates for the entropy check */
+const QUOTED_TOKEN = /(["'`])(?<secret>[A-Za-z0-9+/=_\-]{20,})\1/g;
+
+/** Bits per character above which a mixed alphanumeric literal is treated as random */
+const ENTROPY_THRESHOLD = 3.5;
+
+export interface RedactionResult {
+  text: string;
+  /** Number of values masked */
+  count: number;
+  /** With `indexed`, the masked values: secrets[n - 1] is behind placeholder n */
+  secrets?: string[];
+}
+
+export interface RedactionOptions {
+  /** Number each placeholder (<REDACTED_SECRET_1>, ...) and return the masked values */
+  indexed?: boolean;
+}
+
+/**
+ * Shannon entropy in bits per character
+ */
+export function shannonEntropy(value: string): number {
+  if (!value) return 0;
+
+  const counts = new Map<string, number>();
+  for (const ch of value) {
+    counts.set(ch, (counts.get(ch) || 0) + 1);
+  }
+
+  let entropy = 0;
+  for (const count of counts.values()) {
+    const p = count / value.length;
+    entropy -= p * Math.log2(p);
+  }
+  return entropy;
+}
+
+function looksRandom(value: string): boolean {
+  return /[0-9]/.test(value) && /[A-Za-z]/.test(value) && shannonEntropy(value) >= ENTROPY_THRESHOLD;
+}
+
+/**
+ * Redacts secrets using the default patterns plus any configured ones
+ */
+export class SecretRedactor {
+  private patterns: RedactionPattern[];
+
+  /**
+   * @param extraPatterns - Additional regex sources (e.g. from config `redaction.patterns`)
+   */
+  constructor(extraPatterns: string[] = []) {
+    this.patterns = [
+      ...DEFAULT_SECRET_PATTERNS,
+      ...extraPatterns.map((source, i) => {
+        try {
+          return { name: `custom-${i + 1}`, pattern: new RegExp(source, 'g') };
+        } catch (error: any) {
+          throw new Error(`Invalid redaction pattern ${JSON.stringify(source)}: ${error.message}`);
+        }
+      })
+    ];
+  }
+
+  redact(text: string, options: RedactionOptions = {}): RedactionResult {
+    const secrets: string[] = [];
+    const placeholder = (secret: string) => {
+      secrets.push(secret);
+      return options.indexed ? indexedSecretPlaceholder(secrets.length) : REDACTED_SECRET;
+    };
+    let result = text;
+
+    for (const { pattern } of this.patterns) {
+      result = replaceSecrets(result, pattern, placeholder);
+    }
+
+    result = replaceSecrets(result, QUOTED_TOKEN, (secret) => looksRandom(secret) ? placeholder(secret) : null);
+
+    return options.indexed ? { text: result, count: secrets.length, secrets } : { text: result, count: secrets.length };
+  }
+}
+
+/**
+ * Replace each match (or its `secret` group) with the placeholder mask
+ * returns for it; null leaves the value as it is
+ */
+function replaceSecrets(
+  text: string,
+  pattern: RegExp,
+  mask: (secret: string) => string | null
+): string {
+  const regex = new RegExp(pattern.source, pattern.flags.includes('g') ? pattern.flags : pattern.flags + 'g');
+
+  return text.replace(regex, (...args: any[]) => {
+    const match: string = args[0];
+    const groups = typeof args[args.length - 1] === 'object' ? args[args.length - 1] : undefined;
+    const secret: string | undefined = groups?.secret;
+
+    if (secret === undefined) {
+      return ANY_PLACEHOLDER.test(match) ? match : mask(match) ?? match;
+    }
+    // Skip values an earlier pattern already masked
+    if (!secret.trim() || ANY_PLACEHOLDER.test(secret)) {
+      return match;
+    }
+    const replacement = mask(secret);
+    if (replacement === null) {
+      return match;
+    }
+
+    const start = match.lastIndexOf(secret);
+    return match.slice(0, start) + replacement + match.slice(start + secret.length);
+  });
+}
+
+/**
+ * Redact secrets with the default patterns
+ */
+export function redactSecrets(text: string, extraPatterns: string[] = [], options: RedactionOptions = {}): RedactionResult {
+  return new SecretRedactor(extraPatterns).redact(text, options);
+}
@@ -12,6 +12,8 @@
 import Anthropic from '@anthropic-ai/sdk';
 import { GraphManager } from '../graph/index.js';
 import { VectorManager } from '../vector/index.js';
+import { proxyClientOptions } from '../config/proxy.js';
+import { recordCompletionUsage } from '../usage/index.js';
 import * as fs from 'fs/promises';
 import * as path from 'path';
 
@@ -141,7 +143,7 @@ export class CodebaseSummaryService {
     private graph: GraphManager,
     private vector?: VectorManager
   ) {
-    this.client = new Anthropic({ apiKey: options.apiKey });
+    this.client = new Anthropic({ apiKey: options.apiKey, ...proxyClientOptions() });
     this.model = options.model || 'claude-sonnet-4-5-20250514';
     this.maxTokens = options.maxTokens || 4096;
     this.repoRoot = options.repoRoot;
@@ -659,6 +661,10 @@ Return ONLY valid JSON, no markdown formatting.`;
       });
 
       const text = response.content[0].type === 'text' ? response.content[0].text : '';
+      recordCompletionUsage('anthropic', this.model, prompt, text, {
+        inputTokens: response.usage.input_tokens,
+        outputTokens: response.usage.output_tokens
+      });
 
       // Parse JSON from response
       const jsonMatch = text.match(/\{[\s\S]*\}/);
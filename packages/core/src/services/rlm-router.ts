@@ -15,6 +15,8 @@ import { GraphManager } from '../graph/index.js';
 import { GitManager } from '../git/index.js';
 import { SymbolNode } from '@cv-git/shared';
 import { loadCodebaseSummary, CodebaseSummary } from './codebase-summary.js';
+import { proxyClientOptions } from '../config/proxy.js';
+import { recordCompletionUsage } from '../usage/index.js';
 import { GraphService, createGraphService } from './graph-service.js';
 import { SemanticGraphService, createSemanticGraphService } from './semantic-graph.js';
 
@@ -147,7 +149,7 @@ export class RLMRouter {
     private graph?: GraphManager,
     private git?: GitManager
   ) {
-    this.client = new Anthropic({ apiKey: options.apiKey });
+    this.client = new Anthropic({ apiKey: options.apiKey, ...proxyClientOptions() });
     this.model = options.model || 'claude-sonnet-4-5-20250514';
     this.maxDepth = options.maxDepth || 5;
     this.maxTokens = options.maxTokens || 4096;
@@ -351,6 +353,10 @@ Return ONLY valid JSON, no markdown formatting.`;
       });
 
       const text = response.content[0].type === 'text' ? response.content[0].text : '';
+      recordCompletionUsage('anthropic', this.model, prompt, text, {
+        inputTokens: response.usage.input_tokens,
+        outputTokens: response.usage.output_tokens
+      });
 
       // Parse JSON from response
       const jsonMatch = text.match(/\{[\s\S]*\}/);
@@ -606,6 +612,10 @@ Keep the explanation concise and focused on the question.`;
       });
 
       const text = response.content[0].type === 'text' ? response.content[0].text : '';
+      recordCompletionUsage('anthropic', this.model, prompt, text, {
+        inputTokens: response.usage.input_tokens,
+        outputTokens: response.usage.output_tokens
+      });
       return { type: 'explanation', query: task.query, text };
 
     } catch (error: any) {
@@ -901,6 +911,10 @@ Provide the answer in clear, technical prose. If code examples would help, inclu
       });
 
       const answer = response.content[0].type === 'text' ? response.content[0].text : '';
+      recordCompletionUsage('anthropic', this.model, prompt, answer, {
+        inputTokens: response.usage.input_tokens,
+        outputTokens: response.usage.output_tokens
+      });
 
       // Extract sources from trace
       const sources = this.extractSources(ctx.trace);
@@ -0,0 +1,190 @@
+/**
+ * Chat Session Storage
+ *
+ * Persists `cv chat` conversations so follow-up questions keep their
+ * history. Each session is one JSON file in .cv/chats/<id>.json holding the
+ * questions, the answers, and the code context retrieved for each question.
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import { randomBytes } from 'crypto';
+import { getCVDir } from '@cv-git/shared';
+import { estimateTokens } from '../vector/embedding-batches.js';
+
+export interface ChatSessionMessage {
+  role: 'user' | 'assistant';
+  /** The question or answer as typed/received, without context */
+  content: string;
+  /** Code context retrieved for this question */
+  context?: string;
+  timestamp: number;
+}
+
+export interface ChatSession {
+  id: string;
+  createdAt: number;
+  updatedAt: number;
+  model: string;
+  messages: ChatSessionMessage[];
+}
+
+export interface ChatSessionSummary {
+  id: string;
+  createdAt: number;
+  updatedAt: number;
+  model: string;
+  /** Number of questions asked */
+  turns: number;
+  /** First question, shortened */
+  title: string;
+}
+
+const TITLE_LENGTH = 60;
+
+/**
+ * Directory holding a repository's chat sessions
+ */
+export function getChatsDir(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), 'chats');
+}
+
+/**
+ * New, empty session. IDs sort by creation time, e.g. 20261014-153012-a3f1
+ */
+export function createChatSession(model: string, now: Date = new Date()): ChatSession {
+  const stamp = now.toISOString().replace(/[-:]/g, '').replace('T', '-').slice(0, 15);
+  return {
+    id: `${stamp}-${randomBytes(2).toString('hex')}`,
+    createdAt: now.getTime(),
+    updatedAt: now.getTime(),
+    model,
+    messages: []
+  };
+}
+
+/**
+ * Write a session, replacing the previous version atomically
+ */
+export async function saveChatSession(repoRoot: string, session: ChatSession): Promise<void> {
+  const dir = getChatsDir(repoRoot);
+  await fs.mkdir(dir, { recursive: true });
+
+  session.updatedAt = Date.now();
+  const filePath = path.join(dir, `${session.id}.json`);
+  const tmpPath = `${filePath}.tmp`;
+  await fs.writeFile(tmpPath, JSON.stringify(session, null, 2), 'utf-8');
+  await fs.rename(tmpPath, filePath);
+}
+
+/**
+ * Load a session by ID or unique ID prefix
+ */
+export async function loadChatSession(repoRoot: string, id: string): Promise<ChatSession> {
+  const ids = (await listSessionIds(repoRoot)).filter(candidate => candidate.startsWith(id));
+  const exact = ids.find(candidate => candidate === id);
+
+  if (!exact && ids.length === 0) {
+    throw new Error(`No chat session ${id}. Run \`cv chat --list\` to see saved sessions.`);
+  }
+  if (!exact && ids.length > 1) {
+    throw new Error(`Chat session ${id} is ambiguous: ${ids.join(', ')}`);
+  }
+
+  const content = await fs.readFile(path.join(getChatsDir(repoRoot), `${exact ?? ids[0]}.json`), 'utf-8');
+  return JSON.parse(content) as ChatSession;
+}
+
+/**
+ * Most recently updated session, if any
+ */
+export async function loadLatestChatSession(repoRoot: string): Promise<ChatSession | null> {
+  const [latest] = await listChatSessions(repoRoot);
+  return latest ? loadChatSession(repoRoot, latest.id) : null;
+}
+
+/**
+ * Saved sessions, most recently updated first. Unreadable files are skipped.
+ */
+export async function listChatSessions(repoRoot: string): Promise<ChatSessionSummary[]> {
+  const summaries: ChatSessionSummary[] = [];
+
+  for (const id of await listSessionIds(repoRoot)) {
+    try {
+      const content = await fs.readFile(path.join(getChatsDir(repoRoot), `${id}.json`), 'utf-8');
+      const session = JSON.parse(content) as ChatSession;
+      const questions = session.messages.filter(m => m.role === 'user');
+      const first = (questions[0]?.content ?? '').replace(/\s+/g, ' ').trim();
+      summaries.push({
+        id: session.id,
+        createdAt: session.createdAt,
+        updatedAt: session.updatedAt,
+        model: session.model,
+        turns: questions.length,
+        title: first.length > TITLE_LENGTH ? `${first.slice(0, TITLE_LENGTH - 3)}...` : first
+      });
+    } catch {
+      // Partially written or hand-edited file
+    }
+  }
+
+  return summaries.sort((a, b) => b.updatedAt - a.updatedAt);
+}
+
+/**
+ * Messages as sent to the model: each question is preceded by the context
+ * retrieved for it, so a resumed session keeps the code it discussed. With
+ * `budgetTokens`, the oldest turns are left out until the rest fits; the
+ * latest question is always sent.
+ */
+export function toChatHistory(
+  session: ChatSession,
+  budgetTokens?: number
+): Array<{ role: 'user' | 'assistant'; content: string }> {
+  const messages = session.messages.map(message => ({
+    role: message.role,
+    content: message.context
+      ? `<codebase_context>\n${message.context}\n</codebase_context>\n\n${message.content}`
+      : message.content
+  }));
+  if (budgetTokens === undefined) {
+    return messages;
+  }
+
+  let tokens = messages.reduce((sum, message) => sum + estimateTokens(message.content), 0);
+  let start = 0;
+  while (tokens > budgetTokens && start < messages.length - 1) {
+    // Drop a whole turn: the question and the answers up to the next question
+    do {
+      tokens -= estimateTokens(messages[start].content);
+      start++;
+    } while (start < messages.length - 1 && messages[start].role !== 'user');
+  }
+  return messages.slice(start);
+}
+
+/**
+ * Session as Markdown (`/save` in cv chat): each question and answer under
+ * its own heading, without the retrieved context
+ */
+export function formatChatTranscript(session: ChatSession): string {
+  const lines = [
+    `# Chat session ${session.id}`,
+    '',
+    `Model: ${session.model} · started ${new Date(session.createdAt).toISOString()}`,
+    ''
+  ];
+  for (const message of session.messages) {
+    lines.push(message.role === 'user' ? '## You' : '## Assistant', '', message.content.trim(), '');
+  }
+  return lines.join('\n');
+}
+
+async function listSessionIds(repoRoot: string): Promise<string[]> {
+  try {
+    const entries = await fs.readdir(getChatsDir(repoRoot));
+    return entries.filter(name => name.endsWith('.json')).map(name => name.slice(0, -'.json'.length)).sort();
+  } catch {
+    return [];
+  }
+}
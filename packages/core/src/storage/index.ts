@@ -15,3 +15,4 @@ export * from './loader.js';
 export * from './authored.js';
 export * from './ingest.js';
 export * from './local-search.js';
+export * from './chat-sessions.js';
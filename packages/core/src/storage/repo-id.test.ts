@@ -11,6 +11,8 @@ import {
 } from './repo-id.js';
 import * as path from 'path';
 import * as os from 'os';
+import * as fs from 'fs';
+import { execSync } from 'child_process';
 
 describe('Repository ID Generation', () => {
   describe('generateRepoId', () => {
@@ -129,6 +131,32 @@ describe('Repository ID Generation', () => {
       expect(collectionA).not.toBe(collectionB);
     });
 
+    it('should give linked worktrees of one clone different IDs', () => {
+      const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'cv-worktree-test-'));
+      const main = path.join(dir, 'main');
+      const feature = path.join(dir, 'feature');
+      const git = (cmd: string, cwd: string) => execSync(`git ${cmd}`, { cwd, stdio: 'pipe' });
+      try {
+        fs.mkdirSync(main);
+        git('init -q', main);
+        git('remote add origin git@github.com:acme/widgets.git', main);
+        git('-c user.email=t@example.com -c user.name=t commit -q --allow-empty -m init', main);
+        git(`worktree add -q ${feature} -b feature`, main);
+
+        const mainId = generateRepoId(main);
+        expect(generateRepoId(feature)).not.toBe(mainId);
+        expect(generateRepoId(feature)).toBe(generateRepoId(feature));
+        // The main checkout keeps its remote-based ID, shared with other clones
+        const clone = path.join(dir, 'clone');
+        fs.mkdirSync(clone);
+        git('init -q', clone);
+        git('remote add origin https://github.com/acme/widgets.git', clone);
+        expect(generateRepoId(clone)).toBe(mainId);
+      } finally {
+        fs.rmSync(dir, { recursive: true, force: true });
+      }
+    });
+
     it('should maintain consistency across function calls', () => {
       const repoPath = '/projects/consistent-repo';
 
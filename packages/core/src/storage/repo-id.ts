@@ -16,6 +16,9 @@ import { execSync } from 'child_process';
  * 1. Git remote origin URL (most stable across machines)
  * 2. Absolute path (fallback for local-only repos)
  *
+ * Linked worktrees (`git worktree add`) share the main checkout's remote, so
+ * their path is added to keep each worktree's graph and vectors separate.
+ *
  * @param repoRoot - Absolute path to repository root
  * @returns 16-character hex string (collision-resistant, compact for readability)
  */
@@ -29,6 +32,11 @@ export function generateRepoId(repoRoot: string): string {
  * Get the best identifier for the repository
  */
 function getRepoIdentifier(repoRoot: string): string {
+  const base = getBaseIdentifier(repoRoot);
+  return isLinkedWorktree(repoRoot) ? `${base}#worktree:${path.resolve(repoRoot)}` : base;
+}
+
+function getBaseIdentifier(repoRoot: string): string {
   // Try to get git remote origin URL
   try {
     const remote = execSync('git remote get-url origin', {
@@ -49,6 +57,22 @@ function getRepoIdentifier(repoRoot: string): string {
   return path.resolve(repoRoot);
 }
 
+/**
+ * Whether the directory is a linked worktree rather than the main checkout
+ */
+function isLinkedWorktree(repoRoot: string): boolean {
+  try {
+    const [gitDir, commonDir] = execSync('git rev-parse --git-dir --git-common-dir', {
+      cwd: repoRoot,
+      encoding: 'utf-8',
+      stdio: ['pipe', 'pipe', 'pipe']
+    }).trim().split('\n');
+    return path.resolve(repoRoot, gitDir) !== path.resolve(repoRoot, commonDir);
+  } catch {
+    return false;
+  }
+}
+
 /**
  * Normalize git URL for consistent hashing
  *
@@ -0,0 +1,138 @@
+/**
+ * Ad-hoc Index
+ *
+ * `cv explain --no-index` answers from files named on the command line
+ * instead of a synced index. The files are chunked as cv sync chunks them,
+ * embedded, and kept in a 'memory' vector store for the one question, so
+ * retrieval and its filters work unchanged. Nothing is written to .cv.
+ * --max-files bounds how much a mistyped --dir can send to the embedding
+ * provider.
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import { CodeChunk, CodeChunkPayload, detectLanguage } from '@cv-git/shared';
+import { CodeParser } from '../parser/index.js';
+import { VectorManager } from '../vector/index.js';
+import { safeReadFile } from './file-utils.js';
+import { IgnoreRules } from './ignore.js';
+import { PackageResolver, SKIPPED_DIRS } from './packages.js';
+import { buildChunkPayload } from './chunk-payload.js';
+
+/** Files --no-index embeds unless --max-files says otherwise */
+export const DEFAULT_AD_HOC_MAX_FILES = 200;
+
+export interface AdHocIndexResult {
+  files: number;
+  chunks: number;
+  /** Files that could not be read or parsed, with the reason */
+  skipped: Array<{ file: string; reason: string }>;
+  /** The stored payloads, for checks against the whole ad-hoc index (--verify) */
+  payloads: CodeChunkPayload[];
+}
+
+/**
+ * Root-relative files to index: the `files` as given (already root-relative)
+ * and the source files under each of `dirs` (relative to `cwd`), leaving out
+ * ignored files, hidden and dependency directories, and files no parser
+ * reads. Throws when `dirs` is outside the root or there are more than
+ * `maxFiles` files.
+ */
+export async function collectAdHocFiles(
+  root: string,
+  files: string[],
+  dirs: string[],
+  maxFiles: number = DEFAULT_AD_HOC_MAX_FILES,
+  parser: CodeParser = new CodeParser(),
+  cwd: string = process.cwd()
+): Promise<string[]> {
+  const collected = new Set(files);
+  const tooMany = () => new Error(
+    `--no-index would embed more than ${maxFiles} files; name fewer with --file/--dir or raise --max-files`
+  );
+  if (collected.size > maxFiles) throw tooMany();
+
+  const ignoreRules = await IgnoreRules.load(root);
+  const walk = async (relative: string): Promise<void> => {
+    const entries = await fs.readdir(path.join(root, relative), { withFileTypes: true });
+    for (const entry of entries.sort((a, b) => a.name.localeCompare(b.name))) {
+      const file = relative ? `${relative}/${entry.name}` : entry.name;
+      if (entry.isDirectory()) {
+        if (!SKIPPED_DIRS.has(entry.name) && !entry.name.startsWith('.') && !ignoreRules.match(`${file}/`)) {
+          await walk(file);
+        }
+      } else if (entry.isFile() && parser.isLanguageSupported(detectLanguage(file)) && !ignoreRules.match(file)) {
+        collected.add(file);
+        if (collected.size > maxFiles) throw tooMany();
+      }
+    }
+  };
+
+  for (const dir of dirs) {
+    const absolute = path.resolve(cwd, dir);
+    const relative = path.relative(root, absolute);
+    if (relative.startsWith('..') || path.isAbsolute(relative)) {
+      throw new Error(`--dir ${dir} is outside the repository`);
+    }
+    const stat = await fs.stat(absolute).catch(() => null);
+    if (!stat?.isDirectory()) {
+      throw new Error(`--dir ${dir} is not a directory`);
+    }
+    await walk(relative.split(path.sep).join('/'));
+  }
+
+  return [...collected].sort();
+}
+
+/**
+ * Chunk and embed files into the code chunk collection of a connected
+ * vector manager (normally on the 'memory' backend)
+ */
+export async function indexAdHocFiles(
+  vector: VectorManager,
+  root: string,
+  files: string[],
+  options: { parser?: CodeParser; onProgress?: (embedded: number, total: number) => void } = {}
+): Promise<AdHocIndexResult> {
+  const parser = options.parser ?? new CodeParser();
+  const chunks: CodeChunk[] = [];
+  const imports = new Map<string, string[]>();
+  const skipped: AdHocIndexResult['skipped'] = [];
+  const payloads: CodeChunkPayload[] = [];
+
+  for (const file of files) {
+    const language = detectLanguage(file);
+    if (!parser.isLanguageSupported(language)) {
+      skipped.push({ file, reason: `no parser for ${language === 'unknown' ? 'this file type' : language}` });
+      continue;
+    }
+    const read = await safeReadFile(path.join(root, file));
+    if ('error' in read) {
+      skipped.push({ file, reason: read.error });
+      continue;
+    }
+    try {
+      const parsed = await parser.parseFile(file, read.content, language);
+      chunks.push(...parsed.chunks);
+      imports.set(file, parsed.imports.map(i => i.source));
+    } catch (error: any) {
+      skipped.push({ file, reason: error.message });
+    }
+  }
+
+  if (chunks.length > 0) {
+    const embeddings = await vector.embedBatch(chunks.map(chunk => vector.prepareCodeForEmbedding(chunk)), {
+      onProgress: options.onProgress,
+      cacheKeys: chunks.map(chunk => vector.embeddingCacheKey(chunk))
+    });
+    const packages = new PackageResolver(root);
+    payloads.push(...chunks.map(chunk => buildChunkPayload(chunk, { imports: imports.get(chunk.file) ?? [], packages })));
+    await vector.upsertBatch(vector.getCollectionNames().codeChunks, chunks.map((chunk, i) => ({
+      id: chunk.id,
+      vector: embeddings[i],
+      payload: payloads[i]
+    })));
+  }
+
+  return { files: files.length - skipped.length, chunks: chunks.length, skipped, payloads };
+}
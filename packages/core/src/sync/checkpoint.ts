@@ -0,0 +1,174 @@
+/**
+ * Sync Checkpoint
+ *
+ * Lets a full sync that was interrupted (Ctrl-C, crash, lost network) resume
+ * without re-embedding the files it already finished. As each embedding
+ * group is stored, its points are appended to a journal next to the
+ * persisted index and its files are recorded with their content hashes.
+ * The next full sync reloads the journal and skips files whose content
+ * hasn't changed. A sync that completes removes the checkpoint.
+ *
+ * Storage structure:
+ * .cv/index/checkpoint/
+ * ├── checkpoint.json      # Embedding identity, collection, finished files
+ * └── points.jsonl         # Points stored so far, one per line
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { getIndexDir, IndexSnapshotPoint } from '../vector/index-store.js';
+import { EmbeddingIdentity, checkIndexCompatibility } from '../vector/index-metadata.js';
+
+const CHECKPOINT_DIR = 'checkpoint';
+const CHECKPOINT_FILE = 'checkpoint.json';
+const POINTS_FILE = 'points.jsonl';
+
+export interface SyncCheckpoint {
+  /** Embedding provider/model/dimensions the journaled points were produced with */
+  fingerprint: EmbeddingIdentity;
+  /** Code chunk collection the points belong to */
+  collection: string;
+  startedAt: string;
+  updatedAt: string;
+  /** Content hash of every file whose chunks are all journaled */
+  files: Record<string, string>;
+  /** Points in the journal */
+  points: number;
+}
+
+function getCheckpointDir(repoRoot: string): string {
+  return path.join(getIndexDir(repoRoot), CHECKPOINT_DIR);
+}
+
+/**
+ * Hash recorded for a file's content
+ */
+export function hashCheckpointContent(content: string): string {
+  return createHash('sha256').update(content).digest('hex').substring(0, 16);
+}
+
+/**
+ * Read the checkpoint of an interrupted sync (null if there is none)
+ */
+export async function readSyncCheckpoint(repoRoot: string): Promise<SyncCheckpoint | null> {
+  try {
+    return JSON.parse(await fs.readFile(path.join(getCheckpointDir(repoRoot), CHECKPOINT_FILE), 'utf-8')) as SyncCheckpoint;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Why a checkpoint can't be resumed with the current embedding setup, or null if it can
+ */
+export function checkpointMismatch(checkpoint: SyncCheckpoint, current: EmbeddingIdentity, collection: string): string | null {
+  const compatibility = checkIndexCompatibility(checkpoint.fingerprint, current);
+  if (!compatibility.compatible) {
+    return `embedding ${compatibility.mismatches.join(', ')} changed`;
+  }
+  if (checkpoint.collection !== collection) {
+    return `collection changed from ${checkpoint.collection} to ${collection}`;
+  }
+  return null;
+}
+
+/**
+ * Start an empty checkpoint, discarding any previous one
+ */
+export async function startSyncCheckpoint(
+  repoRoot: string,
+  fingerprint: EmbeddingIdentity,
+  collection: string
+): Promise<SyncCheckpoint> {
+  const dir = getCheckpointDir(repoRoot);
+  await fs.rm(dir, { recursive: true, force: true });
+  await fs.mkdir(dir, { recursive: true });
+
+  const now = new Date().toISOString();
+  const checkpoint: SyncCheckpoint = { fingerprint, collection, startedAt: now, updatedAt: now, files: {}, points: 0 };
+  await fs.writeFile(path.join(dir, POINTS_FILE), '', 'utf-8');
+  await writeCheckpoint(dir, checkpoint);
+  return checkpoint;
+}
+
+/**
+ * Journal a stored group and mark its files done.
+ * Points are appended before the files are recorded, so a crash in between
+ * only means those files are embedded again (upserts are idempotent).
+ */
+export async function recordCheckpointGroup(
+  repoRoot: string,
+  checkpoint: SyncCheckpoint,
+  files: Map<string, string>,
+  points: IndexSnapshotPoint[]
+): Promise<void> {
+  const dir = getCheckpointDir(repoRoot);
+  if (points.length > 0) {
+    await fs.appendFile(path.join(dir, POINTS_FILE), points.map(point => JSON.stringify(point)).join('\n') + '\n', 'utf-8');
+  }
+
+  for (const [file, hash] of files) {
+    checkpoint.files[file] = hash;
+  }
+  checkpoint.points += points.length;
+  checkpoint.updatedAt = new Date().toISOString();
+  await writeCheckpoint(dir, checkpoint);
+}
+
+/**
+ * Points journaled by the interrupted sync
+ */
+export async function readCheckpointPoints(repoRoot: string): Promise<IndexSnapshotPoint[]> {
+  let content: string;
+  try {
+    content = await fs.readFile(path.join(getCheckpointDir(repoRoot), POINTS_FILE), 'utf-8');
+  } catch {
+    return [];
+  }
+
+  const points: IndexSnapshotPoint[] = [];
+  for (const line of content.split('\n')) {
+    if (!line.trim()) continue;
+    try {
+      points.push(JSON.parse(line) as IndexSnapshotPoint);
+    } catch {
+      // A line cut off by the interruption
+    }
+  }
+  return points;
+}
+
+/**
+ * Replace the journal, e.g. after dropping points of files that changed
+ */
+export async function rewriteSyncCheckpoint(
+  repoRoot: string,
+  checkpoint: SyncCheckpoint,
+  points: IndexSnapshotPoint[]
+): Promise<void> {
+  const dir = getCheckpointDir(repoRoot);
+  const journal = path.join(dir, POINTS_FILE);
+  await fs.writeFile(`${journal}.tmp`, points.map(point => JSON.stringify(point) + '\n').join(''), 'utf-8');
+  await fs.rename(`${journal}.tmp`, journal);
+
+  checkpoint.points = points.length;
+  checkpoint.updatedAt = new Date().toISOString();
+  await writeCheckpoint(dir, checkpoint);
+}
+
+/**
+ * Remove the checkpoint
+ */
+export async function clearSyncCheckpoint(repoRoot: string): Promise<void> {
+  await fs.rm(getCheckpointDir(repoRoot), { recursive: true, force: true });
+}
+
+/**
+ * Write checkpoint.json via a temp file so an interruption never leaves it half-written
+ */
+async function writeCheckpoint(dir: string, checkpoint: SyncCheckpoint): Promise<void> {
+  const file = path.join(dir, CHECKPOINT_FILE);
+  await fs.writeFile(`${file}.tmp`, JSON.stringify(checkpoint, null, 2), 'utf-8');
+  await fs.rename(`${file}.tmp`, file);
+}
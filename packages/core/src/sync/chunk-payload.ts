@@ -0,0 +1,36 @@
+/**
+ * Chunk Payloads
+ * The payload stored with each code chunk's embedding, shared by cv sync
+ * and the in-memory index of cv explain --no-index so both filter alike.
+ */
+
+import * as path from 'path';
+import { CodeChunk, CodeChunkPayload } from '@cv-git/shared';
+import { isTestFile } from '../ai/test-generation.js';
+import { PackageResolver } from './packages.js';
+
+export function buildChunkPayload(
+  chunk: CodeChunk,
+  options: { imports: string[]; packages: PackageResolver; commit?: string }
+): CodeChunkPayload {
+  return {
+    id: chunk.id,
+    file: chunk.file,
+    language: chunk.language,
+    symbolName: chunk.symbolName,
+    symbolKind: chunk.symbolKind,
+    signature: chunk.signature,
+    startLine: chunk.startLine,
+    endLine: chunk.endLine,
+    text: chunk.text,
+    summary: chunk.summary,
+    docstring: chunk.docstring,
+    imports: options.imports,
+    complexity: chunk.complexity,
+    lastModified: Date.now(),
+    commit: options.commit,
+    isTest: isTestFile(chunk.file),
+    package: options.packages.packageOf(chunk.file),
+    extension: path.extname(chunk.file).toLowerCase() || undefined
+  };
+}
@@ -21,9 +21,28 @@ export interface TrackedFile {
   contentHash: string;
   lastSyncedAt: string;
   size: number;
+  /** Modification time when synced; absent in state written before mtimes were kept */
+  mtimeMs?: number;
   type: 'code' | 'document';
 }
 
+/**
+ * File size and modification time, from fs.stat
+ */
+export interface FileStat {
+  mtimeMs: number;
+  size: number;
+}
+
+/**
+ * Why a file is or is not re-embedded
+ */
+export interface FileDecision {
+  file: string;
+  action: 'embed' | 'skip' | 'remove';
+  reason: 'new file' | 'content changed' | 'mtime changed, content unchanged' | 'mtime and size unchanged' | 'content unchanged' | 'deleted';
+}
+
 /**
  * Delta between two sync states
  */
@@ -32,6 +51,7 @@ export interface SyncDelta {
   modified: string[];   // Changed files
   deleted: string[];    // Removed files
   unchanged: string[];  // No changes
+  decisions?: FileDecision[];  // Why each file is re-embedded, skipped or removed
 }
 
 /**
@@ -157,15 +177,32 @@ export class DeltaSyncManager {
     return createHash('sha256').update(content).digest('hex').substring(0, 16);
   }
 
+  /**
+   * Whether a file's size and mtime are the ones recorded when it was last
+   * synced, so its content need not be read to know it is unchanged
+   */
+  async isUnchangedOnDisk(filePath: string, stat: FileStat): Promise<boolean> {
+    await this.load();
+    const tracked = this.state!.files[filePath];
+    return !!tracked && tracked.mtimeMs === stat.mtimeMs && tracked.size === stat.size;
+  }
+
   /**
    * Compute delta between current files and last synced state
    *
+   * The content hash decides: a file whose mtime moved but whose content
+   * hashes the same is unchanged (its new mtime is recorded). A file with
+   * a stat but no content was left unread because `isUnchangedOnDisk`
+   * said so, and counts as unchanged.
+   *
    * @param currentFiles - Map of file paths to their content
    * @param fileType - Type of files being synced
+   * @param stats - Size and mtime of the current files
    */
   async computeDelta(
     currentFiles: Map<string, string>,
-    fileType: 'code' | 'document' = 'code'
+    fileType: 'code' | 'document' = 'code',
+    stats?: Map<string, FileStat>
   ): Promise<SyncDelta> {
     await this.load();
 
@@ -173,25 +210,47 @@ export class DeltaSyncManager {
       added: [],
       modified: [],
       deleted: [],
-      unchanged: []
+      unchanged: [],
+      decisions: []
     };
 
     const currentPaths = new Set(currentFiles.keys());
 
+    // Files left unread because their size and mtime have not moved
+    for (const [filePath, stat] of stats || []) {
+      const tracked = this.state!.files[filePath];
+      if (!currentFiles.has(filePath) && tracked && tracked.mtimeMs === stat.mtimeMs && tracked.size === stat.size) {
+        currentPaths.add(filePath);
+        delta.unchanged.push(filePath);
+        delta.decisions!.push({ file: filePath, action: 'skip', reason: 'mtime and size unchanged' });
+      }
+    }
+
     // Check each current file
     for (const [filePath, content] of currentFiles) {
       const contentHash = this.computeHash(content);
       const tracked = this.state!.files[filePath];
+      const stat = stats?.get(filePath);
 
       if (!tracked) {
         // New file
         delta.added.push(filePath);
+        delta.decisions!.push({ file: filePath, action: 'embed', reason: 'new file' });
       } else if (tracked.contentHash !== contentHash) {
         // Modified file
         delta.modified.push(filePath);
+        delta.decisions!.push({ file: filePath, action: 'embed', reason: 'content changed' });
+      } else if (stat && (tracked.mtimeMs !== stat.mtimeMs || tracked.size !== stat.size)) {
+        // Touched but not changed: remember the new mtime so the next sync need not read it
+        tracked.mtimeMs = stat.mtimeMs;
+        tracked.size = stat.size;
+        this.dirty = true;
+        delta.unchanged.push(filePath);
+        delta.decisions!.push({ file: filePath, action: 'skip', reason: 'mtime changed, content unchanged' });
       } else {
         // Unchanged
         delta.unchanged.push(filePath);
+        delta.decisions!.push({ file: filePath, action: 'skip', reason: 'content unchanged' });
       }
     }
 
@@ -200,6 +259,7 @@ export class DeltaSyncManager {
       const tracked = this.state!.files[filePath];
       if (tracked.type === fileType && !currentPaths.has(filePath)) {
         delta.deleted.push(filePath);
+        delta.decisions!.push({ file: filePath, action: 'remove', reason: 'deleted' });
       }
     }
 
@@ -211,10 +271,12 @@ export class DeltaSyncManager {
    *
    * @param files - Map of file paths to their content
    * @param fileType - Type of files
+   * @param stats - Size and mtime of the files, checked first on the next sync
    */
   async markSynced(
     files: Map<string, string>,
-    fileType: 'code' | 'document' = 'code'
+    fileType: 'code' | 'document' = 'code',
+    stats?: Map<string, FileStat>
   ): Promise<void> {
     await this.load();
 
@@ -222,12 +284,14 @@ export class DeltaSyncManager {
 
     for (const [filePath, content] of files) {
       const contentHash = this.computeHash(content);
+      const stat = stats?.get(filePath);
 
       this.state!.files[filePath] = {
         path: filePath,
         contentHash,
         lastSyncedAt: now,
-        size: content.length,
+        size: stat ? stat.size : content.length,
+        mtimeMs: stat?.mtimeMs,
         type: fileType
       };
     }
@@ -0,0 +1,93 @@
+/**
+ * Sync Estimate
+ *
+ * `cv sync --estimate` previews what a full sync would send to the
+ * embedding provider before anything is embedded. Files are selected and
+ * chunked as cv sync does, oversized chunks are split or left out as the
+ * embedding limit requires, and each chunk is measured as it would be
+ * prepared for embedding, with the same token estimate the request
+ * batching uses. No API is called and nothing is stored.
+ */
+
+import { CodeChunk } from '@cv-git/shared';
+import { fitChunksToTokenLimit, estimateTokens } from '../vector/index.js';
+
+/**
+ * A file a sync would embed, as chunked by the parser
+ */
+export interface ChunkedFile {
+  file: string;
+  bytes: number;
+  chunks: CodeChunk[];
+}
+
+export interface EstimatedFile {
+  file: string;
+  bytes: number;
+  chunks: number;
+  tokens: number;
+}
+
+/**
+ * What a full sync would embed
+ */
+export interface SyncEstimate {
+  files: number;
+  chunks: number;
+  /** Estimated embedding input (~4 characters per token) */
+  tokens: number;
+  totalBytes: number;
+  /** Largest files, biggest first */
+  largestFiles: EstimatedFile[];
+  /** Pieces of code over the embedding model's token limit, which sync leaves out */
+  skippedChunks: number;
+  /** Files that could not be read or parsed, with the reason */
+  failed: Array<{ file: string; reason: string }>;
+}
+
+export interface SyncEstimateOptions {
+  /** Most tokens the embedding model accepts in one input (default: no limit) */
+  maxInputTokens?: number;
+  /** Text embedded for a chunk (default: the chunk text) */
+  prepare?: (chunk: CodeChunk) => string;
+  /** Largest files to report (default: 5) */
+  largest?: number;
+}
+
+/**
+ * Count the chunks and estimated tokens of chunked files
+ */
+export function estimateChunkedFiles(
+  files: ChunkedFile[],
+  options: SyncEstimateOptions = {},
+  failed: SyncEstimate['failed'] = []
+): SyncEstimate {
+  const prepare = options.prepare ?? ((chunk: CodeChunk) => chunk.text);
+  const estimated: EstimatedFile[] = [];
+  let skippedChunks = 0;
+
+  for (const file of files) {
+    const fitted = options.maxInputTokens
+      ? fitChunksToTokenLimit(file.chunks, options.maxInputTokens, prepare)
+      : { chunks: file.chunks, skipped: [] };
+    skippedChunks += fitted.skipped.length;
+    estimated.push({
+      file: file.file,
+      bytes: file.bytes,
+      chunks: fitted.chunks.length,
+      tokens: fitted.chunks.reduce((sum, chunk) => sum + estimateTokens(prepare(chunk)), 0)
+    });
+  }
+
+  return {
+    files: estimated.length,
+    chunks: estimated.reduce((sum, f) => sum + f.chunks, 0),
+    tokens: estimated.reduce((sum, f) => sum + f.tokens, 0),
+    totalBytes: estimated.reduce((sum, f) => sum + f.bytes, 0),
+    largestFiles: [...estimated]
+      .sort((a, b) => b.bytes - a.bytes || a.file.localeCompare(b.file))
+      .slice(0, options.largest ?? 5),
+    skippedChunks,
+    failed
+  };
+}
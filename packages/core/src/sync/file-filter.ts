@@ -0,0 +1,109 @@
+/**
+ * Sync File Filters
+ *
+ * Narrows a sync to part of a repository (`cv sync --include/--exclude/--ext`),
+ * e.g. a few services of a monorepo. Filters compose with .gitignore,
+ * .cvignore and the configured exclude patterns: a file must pass all of
+ * them. The filter a sync used is recorded in the index metadata so
+ * readers of the index know which files were never indexed.
+ */
+
+import { minimatch } from 'minimatch';
+
+/**
+ * Which files a sync may index
+ */
+export interface SyncFileFilter {
+  /** Only files matching one of these globs (a bare directory matches everything under it) */
+  include?: string[];
+  /** Never files matching one of these globs */
+  exclude?: string[];
+  /** Only files with one of these extensions (lowercase, with the leading dot) */
+  extensions?: string[];
+}
+
+/**
+ * Split and normalize extension lists: ".go,.ts", "go" and "TS" all work
+ */
+export function parseExtensions(values: string[]): string[] {
+  const extensions = values
+    .flatMap(value => value.split(','))
+    .map(ext => ext.trim().toLowerCase())
+    .filter(Boolean)
+    .map(ext => (ext.startsWith('.') ? ext : `.${ext}`));
+  return [...new Set(extensions)];
+}
+
+/**
+ * Drop empty lists so an unfiltered sync compares equal to no filter
+ */
+export function normalizeFileFilter(filter: SyncFileFilter | undefined): SyncFileFilter {
+  const normalized: SyncFileFilter = {};
+  const include = cleanPatterns(filter?.include);
+  const exclude = cleanPatterns(filter?.exclude);
+  const extensions = parseExtensions(filter?.extensions || []);
+  if (include.length > 0) normalized.include = include;
+  if (exclude.length > 0) normalized.exclude = exclude;
+  if (extensions.length > 0) normalized.extensions = extensions;
+  return normalized;
+}
+
+export function isFileFilterEmpty(filter: SyncFileFilter | undefined): boolean {
+  return !filter?.include?.length && !filter?.exclude?.length && !filter?.extensions?.length;
+}
+
+/**
+ * Why a filter leaves a file out, or null if the file passes
+ */
+export function fileFilterMiss(file: string, filter: SyncFileFilter | undefined): string | null {
+  if (!filter) return null;
+  const normalized = file.replace(/\\/g, '/');
+
+  if (filter.extensions?.length) {
+    const ext = extensionOf(normalized);
+    if (!filter.extensions.includes(ext)) {
+      return `extension not in --ext: ${ext || '(none)'}`;
+    }
+  }
+  if (filter.include?.length && !filter.include.some(pattern => matchesPattern(normalized, pattern))) {
+    return 'not matched by --include';
+  }
+  const excludedBy = filter.exclude?.find(pattern => matchesPattern(normalized, pattern));
+  if (excludedBy) {
+    return `matched --exclude ${excludedBy}`;
+  }
+  return null;
+}
+
+/**
+ * One-line description, e.g. "include services/api/**; ext .go,.ts"
+ */
+export function describeFileFilter(filter: SyncFileFilter | undefined): string {
+  const parts: string[] = [];
+  if (filter?.include?.length) parts.push(`include ${filter.include.join(', ')}`);
+  if (filter?.exclude?.length) parts.push(`exclude ${filter.exclude.join(', ')}`);
+  if (filter?.extensions?.length) parts.push(`ext ${filter.extensions.join(',')}`);
+  return parts.join('; ');
+}
+
+function matchesPattern(file: string, pattern: string): boolean {
+  // A pattern without wildcards names a file or a directory
+  if (!/[*?[\]{}]/.test(pattern)) {
+    const dir = pattern.replace(/\/+$/, '');
+    return file === dir || file.startsWith(`${dir}/`);
+  }
+  return minimatch(file, pattern, { dot: true });
+}
+
+function cleanPatterns(patterns: string[] | undefined): string[] {
+  const cleaned = (patterns || [])
+    .map(pattern => pattern.trim().replace(/\\/g, '/').replace(/^\.\//, ''))
+    .filter(Boolean);
+  return [...new Set(cleaned)];
+}
+
+function extensionOf(file: string): string {
+  const name = file.slice(file.lastIndexOf('/') + 1);
+  const dot = name.lastIndexOf('.');
+  return dot > 0 ? name.slice(dot).toLowerCase() : '';
+}
@@ -0,0 +1,139 @@
+/**
+ * Commit History Index
+ *
+ * `cv sync --history` embeds commit messages (and, with diffs enabled, a
+ * truncated diff of each commit) into the commits collection, so
+ * `cv search --history` can find the rationale recorded in commits rather
+ * than only the code it produced. Opt-in because every commit costs an
+ * embedding; later passes only embed commits made since the last one.
+ */
+
+import { CommitPayload, GitCommit } from '@cv-git/shared';
+import { GitManager } from '../git/index.js';
+import { VectorManager } from '../vector/index.js';
+import { readIndexMetadata, writeHistoryMetadata } from '../vector/index-metadata.js';
+import { truncateCommitMessage } from '../context/commit-history.js';
+
+/** Commits embedded per pass unless sync.history.maxCommits says otherwise */
+export const DEFAULT_HISTORY_MAX_COMMITS = 500;
+
+/** Diff text embedded per commit; the start of a diff says most about what changed */
+export const MAX_HISTORY_DIFF_CHARS = 4000;
+
+/** Changed paths listed per commit, so a mass rename doesn't drown the message */
+const MAX_HISTORY_FILES = 50;
+
+export interface HistoryIndexOptions {
+  /** Most commits to embed in this pass */
+  maxCommits?: number;
+  /** Embed a truncated diff with each message */
+  diffs?: boolean;
+  /** Called after each batch of commits is stored */
+  onProgress?: (indexed: number, total: number) => void;
+}
+
+export interface HistoryIndexResult {
+  /** Commits embedded in this pass */
+  indexed: number;
+  /** Commits in the history index after the pass */
+  total: number;
+  /** Re-embedded from the newest commit instead of continuing the last pass */
+  rebuilt: boolean;
+}
+
+/**
+ * Text embedded for a commit: the message, the files it touched, and the
+ * diff when one is given
+ */
+export function buildCommitText(commit: GitCommit, diff?: string): string {
+  let text = truncateCommitMessage(commit.message);
+
+  if (commit.files.length > 0) {
+    const listed = commit.files.slice(0, MAX_HISTORY_FILES);
+    const more = commit.files.length - listed.length;
+    text += `\n\nFiles changed:\n${listed.map(file => `- ${file}`).join('\n')}`;
+    if (more > 0) text += `\n- ... and ${more} more`;
+  }
+
+  if (diff && diff.trim()) {
+    const trimmed = diff.trim();
+    text += `\n\nDiff:\n${trimmed.length > MAX_HISTORY_DIFF_CHARS ? `${trimmed.slice(0, MAX_HISTORY_DIFF_CHARS)}\n[...]` : trimmed}`;
+  }
+
+  return text;
+}
+
+/**
+ * Embed commits made since the last history pass into the commits
+ * collection. The whole recent history is embedded again when there was
+ * no earlier pass, its commit is gone (rebase, force-push), or the diffs
+ * setting changed.
+ */
+export async function syncHistoryIndex(
+  repoRoot: string,
+  git: GitManager,
+  vector: VectorManager,
+  options: HistoryIndexOptions = {}
+): Promise<HistoryIndexResult> {
+  const maxCommits = options.maxCommits ?? DEFAULT_HISTORY_MAX_COMMITS;
+  const diffs = options.diffs ?? false;
+  const collection = vector.getCollectionNames().commits;
+
+  const previous = (await readIndexMetadata(repoRoot))?.history;
+  const resume = previous !== undefined && previous.diffs === diffs && await git.commitExists(previous.lastCommit);
+  if (previous && !resume) {
+    await vector.clearCollection(collection);
+  }
+
+  const commits = await git.getCommitLog(maxCommits, resume ? previous?.lastCommit : undefined);
+  const carried = resume ? previous?.commits ?? 0 : 0;
+  if (commits.length === 0) {
+    return { indexed: 0, total: carried, rebuilt: !resume };
+  }
+
+  const batchSize = 50;
+  let indexed = 0;
+  for (let start = 0; start < commits.length; start += batchSize) {
+    const batch = commits.slice(start, start + batchSize);
+    const texts: string[] = [];
+    for (const commit of batch) {
+      const diff = diffs ? await git.getCommitDiff(commit.sha, 1).catch(() => undefined) : undefined;
+      texts.push(buildCommitText(commit, diff));
+    }
+
+    const vectors = await vector.embedBatch(texts);
+    await vector.upsertBatch(collection, batch.map((commit, i) => ({
+      id: commit.sha,
+      vector: vectors[i],
+      payload: toCommitPayload(commit)
+    })));
+
+    indexed += batch.length;
+    options.onProgress?.(indexed, commits.length);
+  }
+
+  const total = carried + indexed;
+  await writeHistoryMetadata(repoRoot, {
+    lastCommit: commits[0].sha,
+    commits: total,
+    diffs,
+    updatedAt: new Date().toISOString()
+  });
+
+  return { indexed, total, rebuilt: !resume };
+}
+
+function toCommitPayload(commit: GitCommit): CommitPayload {
+  return {
+    id: commit.sha,
+    // A commit is not one file's; filesChanged lists the files it touched
+    file: '',
+    language: 'git',
+    sha: commit.sha,
+    message: commit.message,
+    author: commit.author,
+    timestamp: commit.date,
+    filesChanged: commit.files,
+    symbolsChanged: []
+  };
+}
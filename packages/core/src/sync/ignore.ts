@@ -0,0 +1,111 @@
+/**
+ * Ignore Rules
+ *
+ * Applies .gitignore and .cvignore rules to the files considered for sync.
+ * .cvignore uses the same syntax as .gitignore and holds cv-specific
+ * exclusions (generated code, fixtures, vendored SDKs) for files that
+ * should stay in git but not in the knowledge graph.
+ */
+
+import ignore, { Ignore } from 'ignore';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+
+export const CVIGNORE_FILE = '.cvignore';
+
+export type IgnoreSource = '.gitignore' | '.cvignore';
+
+interface ScopedRules {
+  /** Directory the rule file lives in, relative to the repo root ('' for root) */
+  dir: string;
+  rules: Ignore;
+}
+
+/**
+ * Compiled ignore rules for a repository
+ */
+export class IgnoreRules {
+  private constructor(
+    private gitignore: ScopedRules[],
+    private cvignore: ScopedRules[]
+  ) {}
+
+  /**
+   * Load the root rule files plus any nested .gitignore/.cvignore in the file list
+   */
+  static async load(repoRoot: string, trackedFiles: string[] = []): Promise<IgnoreRules> {
+    const gitignore = await loadScopedRules(repoRoot, '.gitignore', trackedFiles);
+    const cvignore = await loadScopedRules(repoRoot, CVIGNORE_FILE, trackedFiles);
+
+    // Repo-local excludes that are never committed
+    const excludeFile = await readIfExists(path.join(repoRoot, '.git', 'info', 'exclude'));
+    if (excludeFile) {
+      gitignore.unshift({ dir: '', rules: ignore().add(excludeFile) });
+    }
+
+    return new IgnoreRules(gitignore, cvignore);
+  }
+
+  /**
+   * Return the rule file that excludes a path, or null if it is not ignored.
+   * .cvignore is checked after .gitignore so it can only add exclusions.
+   */
+  match(filePath: string): IgnoreSource | null {
+    const normalized = filePath.replace(/\\/g, '/');
+
+    if (isIgnored(this.gitignore, normalized)) return '.gitignore';
+    if (isIgnored(this.cvignore, normalized)) return '.cvignore';
+    return null;
+  }
+}
+
+/**
+ * Evaluate rule files from the root down; deeper files override shallower ones
+ */
+function isIgnored(scopes: ScopedRules[], filePath: string): boolean {
+  let ignored = false;
+
+  for (const scope of scopes) {
+    if (scope.dir && !filePath.startsWith(scope.dir + '/')) continue;
+
+    const relative = scope.dir ? filePath.slice(scope.dir.length + 1) : filePath;
+    const result = scope.rules.test(relative);
+    if (result.ignored) ignored = true;
+    else if (result.unignored) ignored = false;
+  }
+
+  return ignored;
+}
+
+async function loadScopedRules(
+  repoRoot: string,
+  fileName: string,
+  trackedFiles: string[]
+): Promise<ScopedRules[]> {
+  const dirs = new Set<string>(['']);
+  for (const file of trackedFiles) {
+    if (path.posix.basename(file) === fileName) {
+      const dir = path.posix.dirname(file);
+      dirs.add(dir === '.' ? '' : dir);
+    }
+  }
+
+  const scopes: ScopedRules[] = [];
+  // Shallow directories first so nested files take precedence
+  for (const dir of [...dirs].sort((a, b) => a.split('/').length - b.split('/').length || a.localeCompare(b))) {
+    const content = await readIfExists(path.join(repoRoot, dir, fileName));
+    if (content) {
+      scopes.push({ dir, rules: ignore().add(content) });
+    }
+  }
+
+  return scopes;
+}
+
+async function readIfExists(filePath: string): Promise<string | null> {
+  try {
+    return await fs.readFile(filePath, 'utf-8');
+  } catch {
+    return null;
+  }
+}
@@ -8,23 +8,37 @@ import {
   FileNode,
   ParsedFile,
   CodeChunk,
-  CodeChunkPayload,
   DocumentNode,
   DocumentChunk,
   DocumentChunkPayload,
   ParsedDocument,
   CommitNode,
   ChangeType,
-  HierarchicalSummaryOptions
+  HierarchicalSummaryOptions,
+  WorktreeInfo
 } from '@cv-git/shared';
 import { HierarchicalSummaryService, createHierarchicalSummaryService, CostControlOptions, DeltaSummaryResult } from '../services/hierarchical-summary.js';
-import { shouldSyncFile, detectLanguage, getCVDir } from '@cv-git/shared';
+import { shouldSyncFile, detectLanguage, getCVDir, VectorError } from '@cv-git/shared';
 import { minimatch } from 'minimatch';
 import { GitManager } from '../git/index.js';
 import { CodeParser } from '../parser/index.js';
 import { GraphManager } from '../graph/index.js';
-import { VectorManager } from '../vector/index.js';
-import { DeltaSyncManager, createDeltaSyncManager, SyncDelta } from './delta.js';
+import {
+  VectorManager,
+  readIndexMetadata,
+  writeIndexMetadata,
+  clearIndexMetadata,
+  assertIndexCompatible,
+  getIndexDir,
+  readIndexSnapshotManifest,
+  clearIndexSnapshot,
+  fitChunksToTokenLimit,
+  IndexWarning,
+  IndexWarningUpdate,
+  IndexWorktree,
+  IndexSnapshotPoint
+} from '../vector/index.js';
+import { DeltaSyncManager, createDeltaSyncManager, SyncDelta, FileStat, FileDecision } from './delta.js';
 import { ManifoldService } from '../services/manifold-service.js';
 import * as fs from 'fs/promises';
 import * as path from 'path';
@@ -33,14 +47,60 @@ import * as path from 'path';
 export * from './delta.js';
 export * from './file-lock.js';
 export * from './file-utils.js';
-
-import { safeReadFile, logSkippedFile } from './file-utils.js';
+export * from './ignore.js';
+export * from './languages.js';
+export * from './pipeline.js';
+export * from './checkpoint.js';
+export * from './file-filter.js';
+export * from './watch.js';
+export * from './history-index.js';
+export * from './limits.js';
+export * from './packages.js';
+export * from './chunk-payload.js';
+export * from './ad-hoc-index.js';
+export * from './estimate.js';
+
+import { safeReadFile, logSkippedFile, checkFileReadable } from './file-utils.js';
+import { IgnoreRules } from './ignore.js';
+import { SyncFileFilter, normalizeFileFilter, fileFilterMiss } from './file-filter.js';
+import { RepoLanguages, detectRepoLanguages } from './languages.js';
+import { createEmbeddingProgress } from './progress.js';
+import { SyncLimits, SyncLimitCheck, SyncLimitError, checkSyncLimits } from './limits.js';
+import { PackageResolver } from './packages.js';
+import { buildChunkPayload } from './chunk-payload.js';
+import { runWorkers, DEFAULT_SYNC_CONCURRENCY } from './pipeline.js';
+import { ChunkedFile, SyncEstimate, estimateChunkedFiles } from './estimate.js';
+import {
+  SyncCheckpoint,
+  readSyncCheckpoint,
+  checkpointMismatch,
+  startSyncCheckpoint,
+  recordCheckpointGroup,
+  readCheckpointPoints,
+  rewriteSyncCheckpoint,
+  clearSyncCheckpoint,
+  hashCheckpointContent
+} from './checkpoint.js';
+
+/** Chunks collected from parsed files before they are sent to be embedded */
+const EMBED_GROUP_SIZE = 256;
+
+/** Chunk groups waiting to be embedded before parsing pauses */
+const MAX_QUEUED_EMBED_GROUPS = 2;
 
 export interface SyncOptions {
   incremental?: boolean;
   files?: string[];
   excludePatterns?: string[];
   includeLanguages?: string[];
+  fileFilter?: SyncFileFilter;    // --include/--exclude/--ext; extensions replace the language list
+  maxFileSize?: number;           // Skip files larger than this many bytes (default: CV_MAX_FILE_SIZE or 1MB)
+  concurrency?: number;           // Files read and parsed at once (default: 10)
+  signal?: AbortSignal;           // Cancels the sync (e.g. Ctrl-C); a full sync resumes from its checkpoint
+  onFileSkipped?: (file: string, reason: string) => void;  // Called for every file left out of the sync
+  onChunkSkipped?: (warning: IndexWarning) => void;         // Called for code too large to embed
+  onFileDecision?: (decision: FileDecision) => void;        // Called for every file a delta sync checks
+  includeUntracked?: boolean;     // Sync untracked files git does not ignore (default: true)
   // Document sync options
   includeDocs?: boolean;          // Include markdown files (default: true)
   docPatterns?: string[];         // Patterns for doc files (default: ['**/*.md'])
@@ -52,6 +112,10 @@ export interface SyncOptions {
   maxFiles?: number;              // Maximum files to process per run
   batchSize?: number;             // Batch size for embeddings (default: 50)
   continueFromLast?: boolean;     // Continue from last chunked sync position
+  restart?: boolean;              // Discard an interrupted full sync's checkpoint instead of resuming it
+  // Size guard (full and delta syncs): checked before anything is embedded
+  limits?: SyncLimits;            // Files, total bytes and per-file bytes one sync may embed without asking
+  confirmLimits?: (check: SyncLimitCheck) => Promise<boolean>;  // Asked when a limit is exceeded; false cancels with SyncLimitError
   // Hierarchical summary options
   generateSummaries?: boolean;    // Generate hierarchical summaries (default: true)
   summaryOptions?: {
@@ -70,6 +134,20 @@ export interface DocumentSyncResult {
   errors: string[];
 }
 
+/**
+ * Planned changes to the code vector collection for a delta sync
+ */
+interface VectorDeltaPlan {
+  /** No usable indexed commit - clear and re-embed everything */
+  rebuild: boolean;
+  /** Files to (re-)embed */
+  reembed: string[];
+  /** Files whose vectors should be deleted */
+  remove: string[];
+  /** Pure renames - vectors are moved, not re-embedded */
+  renames: Array<{ from: string; to: string }>;
+}
+
 /**
  * Sync error details for reporting
  */
@@ -113,6 +191,29 @@ export interface SyncReport {
 export class SyncEngine {
   private delta: DeltaSyncManager;
   private manifold?: ManifoldService;
+  /** Embedding batches that failed during the current sync */
+  private vectorFailures = 0;
+  /** Size limit for files read during the current sync */
+  private maxFileSize?: number;
+  /** Languages of the files selected by the current sync */
+  private repoLanguages?: RepoLanguages;
+  /** --include/--exclude/--ext filter of the current sync */
+  private fileFilter?: SyncFileFilter;
+  /** Cancels the current sync */
+  private signal?: AbortSignal;
+  /** HEAD when the current sync started, recorded on each chunk it embeds */
+  private syncCommit?: Promise<string | undefined>;
+  /** Code left out of the index during the current sync */
+  private chunkWarnings: IndexWarning[] = [];
+  /** Files whose vectors the current sync replaced or removed */
+  private embeddedFiles = new Set<string>();
+  /** Whether the current sync rebuilt the whole code collection */
+  private rebuiltIndex = false;
+  private onChunkSkipped?: (warning: IndexWarning) => void;
+  /** Checkout being synced, resolved when the sync starts */
+  private worktree?: WorktreeInfo;
+  /** Progress of the current full sync, journaled so an interrupted run can resume */
+  private checkpoint?: SyncCheckpoint;
 
   constructor(
     private repoRoot: string,
@@ -216,63 +317,44 @@ export class SyncEngine {
 
     console.log('Starting full sync...');
 
+    await this.checkVectorIndex();
+    this.vectorFailures = 0;
+    this.resetChunkWarnings(options, true);
+
     try {
-      // 1. Get all tracked files
+      // 1. Get all tracked files (and untracked ones git does not ignore)
       console.log('Getting tracked files...');
-      const allFiles = await this.git.getTrackedFiles();
+      const allFiles = await this.listRepoFiles(options);
       console.log(`Found ${allFiles.length} tracked files`);
 
       // 2. Filter files to sync
-      // Always include critical patterns (merge with any custom patterns)
-      const defaultPatterns = this.getDefaultExcludePatterns();
-      const customPatterns = options.excludePatterns || [];
-      const excludePatterns = [...new Set([...defaultPatterns, ...customPatterns])];
-      const includeLanguages = options.includeLanguages || this.getDefaultIncludeLanguages();
-
-      const filesToSync = allFiles.filter(f =>
-        shouldSyncFile(f, excludePatterns, includeLanguages)
-      );
+      const filesToSync = await this.selectFiles(allFiles, options);
+      this.repoLanguages = detectRepoLanguages(filesToSync);
 
       console.log(`Syncing ${filesToSync.length} files`);
+      await this.enforceLimits(filesToSync, options);
 
-      // 3. Parse all files (with parallelization)
-      console.log('Parsing files...');
-      const parsedFiles: ParsedFile[] = [];
-      const CONCURRENCY = 10; // Parse 10 files in parallel
-
-      // Process files in batches for parallel parsing
-      for (let i = 0; i < filesToSync.length; i += CONCURRENCY) {
-        const batch = filesToSync.slice(i, i + CONCURRENCY);
-        const batchResults = await Promise.allSettled(
-          batch.map(file => this.parseFile(file))
-        );
-
-        for (let j = 0; j < batchResults.length; j++) {
-          const result = batchResults[j];
-          const file = batch[j];
-          if (result.status === 'fulfilled') {
-            parsedFiles.push(result.value);
-          } else {
-            syncErrors.push({
-              file,
-              error: result.reason?.message || 'Unknown error',
-              phase: 'parse',
-              timestamp: Date.now()
-            });
-          }
-        }
-
-        const progress = Math.min(i + CONCURRENCY, filesToSync.length);
-        if (progress % 50 === 0 || progress === filesToSync.length) {
-          console.log(`Parsed ${progress}/${filesToSync.length} files`);
-        }
+      // 3. Parse files, embedding their chunks while later files are still parsed
+      //    (every file is re-embedded, so stale vectors are dropped first, then
+      //    anything an interrupted run already embedded is restored)
+      if (this.vector && this.vector.isConnected()) {
+        await this.vector.clearCollection(this.vector.getCollectionNames().codeChunks);
+        await this.openCheckpoint(filesToSync, options);
       }
+      console.log('Parsing files...');
+      const concurrency = options.concurrency ?? DEFAULT_SYNC_CONCURRENCY;
+      const { parsedFiles, embedded } = await this.parseAndEmbed(filesToSync, concurrency, syncErrors);
 
       console.log(`Successfully parsed ${parsedFiles.length} files`);
+      const vectorsStored = await embedded;
 
-      // 4. Update graph
+      // 4. Update graph, then link symbols to the vectors stored above
       console.log('Updating knowledge graph...');
-      await this.updateGraph(parsedFiles);
+      await this.updateGraph(parsedFiles, false);
+      if (vectorsStored > 0) {
+        await this.linkSymbolVectors(this.buildSymbolChunkMap(parsedFiles));
+      }
+      await this.recordIndexedCommit();
 
       // 5. Sync commit history (if enabled)
       const syncCommits = options.syncCommits !== false; // default: true
@@ -295,7 +377,7 @@ export class SyncEngine {
       let vectorCount = 0;
       if (this.vector && this.vector.isConnected()) {
         try {
-          const info = await this.vector.getCollectionInfo('code_chunks');
+          const info = await this.vector.getCollectionInfo(this.vector.getCollectionNames().codeChunks);
           vectorCount = info.points_count || 0;
         } catch (error) {
           // Collection might not exist yet
@@ -318,6 +400,10 @@ export class SyncEngine {
 
       // 8. Save sync state
       await this.saveSyncState(syncState);
+      await this.persistVectorIndex();
+      if (this.checkpoint && this.vectorFailures === 0) {
+        await clearSyncCheckpoint(this.repoRoot);
+      }
 
       // 9. Save sync report for error tracking
       const syncReport: SyncReport = {
@@ -357,9 +443,93 @@ export class SyncEngine {
       return syncState;
 
     } catch (error: any) {
-      console.error('Sync failed:', error);
+      if (!(error instanceof SyncLimitError)) {
+        console.error('Sync failed:', error);
+      }
       throw error;
+    } finally {
+      this.checkpoint = undefined;
+    }
+  }
+
+  /**
+   * Measure what a full sync would embed without embedding or storing
+   * anything (cv sync --estimate). Files are selected and chunked as
+   * fullSync() selects and chunks them, and measured as the vector manager
+   * prepares them, which needs no connection.
+   */
+  async estimateSync(options: SyncOptions = {}, largest?: number): Promise<SyncEstimate> {
+    const files = await this.selectFiles(await this.listRepoFiles(options), options);
+    const concurrency = options.concurrency ?? DEFAULT_SYNC_CONCURRENCY;
+    const chunked: ChunkedFile[] = [];
+    const failed: SyncEstimate['failed'] = [];
+
+    const chunkFile = async (file: string): Promise<ChunkedFile> => {
+      const parsed = await this.parseFile(file);
+      const { size } = await fs.stat(parsed.absolutePath);
+      return { file, bytes: size, chunks: parsed.chunks || [] };
+    };
+    for await (const result of runWorkers(files, concurrency, chunkFile)) {
+      this.signal?.throwIfAborted();
+      if ('error' in result) {
+        failed.push({ file: result.item, reason: result.error.message || 'Unknown error' });
+      } else {
+        chunked.push(result.value);
+      }
     }
+
+    const vector = this.vector;
+    return estimateChunkedFiles(chunked, {
+      maxInputTokens: vector?.getMaxInputTokens(),
+      prepare: vector ? chunk => vector.prepareCodeForEmbedding(chunk) : undefined,
+      largest
+    }, failed);
+  }
+
+  /**
+   * Resume the checkpoint of an interrupted full sync, or start a new one.
+   * Journaled vectors of files that haven't changed since are restored into
+   * the (just cleared) code collection; those files are then not re-embedded.
+   */
+  private async openCheckpoint(filesToSync: string[], options: SyncOptions): Promise<void> {
+    const vector = this.vector!;
+    const identity = vector.getEmbeddingInfo();
+    const collection = vector.getCollectionNames().codeChunks;
+
+    const existing = options.restart ? null : await readSyncCheckpoint(this.repoRoot);
+    const mismatch = existing ? checkpointMismatch(existing, identity, collection) : null;
+    if (options.restart) {
+      console.log('Discarding any interrupted sync checkpoint (--restart)');
+    } else if (mismatch) {
+      console.log(`Interrupted sync checkpoint can't be resumed (${mismatch}), starting over`);
+    }
+    if (!existing || mismatch) {
+      this.checkpoint = await startSyncCheckpoint(this.repoRoot, identity, collection);
+      return;
+    }
+
+    // Files that changed or are no longer synced are embedded again
+    const selected = new Set(filesToSync);
+    for (const [file, hash] of Object.entries(existing.files)) {
+      const result = selected.has(file) ? await safeReadFile(path.join(this.repoRoot, file), this.maxFileSize) : null;
+      if (!result || !('content' in result) || hashCheckpointContent(result.content) !== hash) {
+        delete existing.files[file];
+      }
+    }
+
+    // Later entries for a chunk ID replace earlier ones
+    const points = new Map<string, IndexSnapshotPoint>();
+    for (const point of await readCheckpointPoints(this.repoRoot)) {
+      if (existing.files[point.payload.file as string]) {
+        points.set(point.id, point);
+      }
+    }
+    const kept = [...points.values()];
+    await rewriteSyncCheckpoint(this.repoRoot, existing, kept);
+    await vector.upsertBatch(collection, kept);
+    this.checkpoint = existing;
+
+    console.log(`Resuming interrupted sync: ${Object.keys(existing.files).length} files already embedded (${kept.length} vectors restored)`);
   }
 
   /**
@@ -371,17 +541,12 @@ export class SyncEngine {
 
     console.log(`Starting incremental sync for ${changedFiles.length} files...`);
 
+    await this.checkVectorIndex();
+    this.resetChunkWarnings(options, false);
+
     try {
       // Filter files to sync
-      // Always include critical patterns (merge with any custom patterns)
-      const defaultPatterns = this.getDefaultExcludePatterns();
-      const customPatterns = options.excludePatterns || [];
-      const excludePatterns = [...new Set([...defaultPatterns, ...customPatterns])];
-      const includeLanguages = options.includeLanguages || this.getDefaultIncludeLanguages();
-
-      const filesToSync = changedFiles.filter(f =>
-        shouldSyncFile(f, excludePatterns, includeLanguages)
-      );
+      const filesToSync = await this.selectFiles(changedFiles, options, await this.git.getTrackedFiles());
 
       console.log(`Syncing ${filesToSync.length} files`);
 
@@ -409,7 +574,7 @@ export class SyncEngine {
       let vectorCount = 0;
       if (this.vector && this.vector.isConnected()) {
         try {
-          const info = await this.vector.getCollectionInfo('code_chunks');
+          const info = await this.vector.getCollectionInfo(this.vector.getCollectionNames().codeChunks);
           vectorCount = info.points_count || 0;
         } catch (error) {
           vectorCount = 0;
@@ -431,6 +596,7 @@ export class SyncEngine {
       };
 
       await this.saveSyncState(syncState);
+      await this.persistVectorIndex();
 
       console.log(`Incremental sync completed in ${syncState.syncDuration}s`);
 
@@ -457,37 +623,27 @@ export class SyncEngine {
 
     console.log('Starting delta sync...');
 
+    await this.checkVectorIndex();
+    this.vectorFailures = 0;
+    this.resetChunkWarnings(options, false);
+
     try {
-      // Check if full sync is needed
+      // Check if full sync is needed (none yet, or the last one was interrupted)
       const needsFull = await this.delta.needsFullSync();
-      if (needsFull) {
-        console.log('No previous sync state, performing full sync...');
+      const interrupted = !needsFull && !options.restart && !!this.vector && this.vector.isConnected() &&
+        (await readSyncCheckpoint(this.repoRoot)) !== null;
+      if (needsFull || interrupted) {
+        console.log(needsFull ? 'No previous sync state, performing full sync...' : 'Last full sync was interrupted, resuming it...');
         const fullResult = await this.fullSync(options);
 
         // Track all files for next delta
-        const allFiles = await this.git.getTrackedFiles();
-        const defaultPatterns = this.getDefaultExcludePatterns();
-        const customPatterns = options.excludePatterns || [];
-        const excludePatterns = [...new Set([...defaultPatterns, ...customPatterns])];
-        const includeLanguages = options.includeLanguages || this.getDefaultIncludeLanguages();
-
-        const filesToTrack = allFiles.filter(f =>
-          shouldSyncFile(f, excludePatterns, includeLanguages)
-        );
+        const allFiles = await this.listRepoFiles(options);
+        const filesToTrack = await this.selectFiles(allFiles, { ...options, onFileSkipped: () => {} }, allFiles, false);
 
         // Read content and mark as synced (using safe file reading with size limits)
-        const fileContents = new Map<string, string>();
-        for (const file of filesToTrack) {
-          const absolutePath = path.join(this.repoRoot, file);
-          const result = await safeReadFile(absolutePath);
-          if ('content' in result) {
-            fileContents.set(file, result.content);
-          } else {
-            logSkippedFile(file, result.error);
-          }
-        }
+        const { contents: fileContents, stats: fileStats } = await this.readCurrentFiles(filesToTrack);
 
-        await this.delta.markSynced(fileContents, 'code');
+        await this.delta.markSynced(fileContents, 'code', fileStats);
         await this.delta.setLastCommit(await this.git.getLastCommitSha());
         await this.delta.close();
 
@@ -502,38 +658,52 @@ export class SyncEngine {
         };
       }
 
-      // Get all current files
-      const allFiles = await this.git.getTrackedFiles();
-      const defaultPatterns = this.getDefaultExcludePatterns();
-      const customPatterns = options.excludePatterns || [];
-      const excludePatterns = [...new Set([...defaultPatterns, ...customPatterns])];
-      const includeLanguages = options.includeLanguages || this.getDefaultIncludeLanguages();
+      // Get all current files (untracked ones too: git history cannot see them)
+      const allFiles = await this.listRepoFiles(options);
+      const currentFiles = await this.selectFiles(allFiles, options);
+      this.repoLanguages = detectRepoLanguages(currentFiles);
 
-      const currentFiles = allFiles.filter(f =>
-        shouldSyncFile(f, excludePatterns, includeLanguages)
-      );
+      // Read current file contents, except files whose size and mtime have not moved
+      const { contents: fileContents, stats: fileStats } = await this.readCurrentFiles(currentFiles, true);
 
-      // Read current file contents (using safe file reading with size limits)
-      const fileContents = new Map<string, string>();
-      for (const file of currentFiles) {
-        const absolutePath = path.join(this.repoRoot, file);
-        const result = await safeReadFile(absolutePath);
-        if ('content' in result) {
-          fileContents.set(file, result.content);
-        } else {
-          logSkippedFile(file, result.error);
-        }
+      // Compute delta (the content hash decides, not the mtime)
+      const delta = await this.delta.computeDelta(fileContents, 'code', fileStats);
+      for (const decision of delta.decisions || []) {
+        options.onFileDecision?.(decision);
       }
 
-      // Compute delta
-      const delta = await this.delta.computeDelta(fileContents, 'code');
-
       console.log(`Delta: ${delta.added.length} added, ${delta.modified.length} modified, ${delta.deleted.length} deleted, ${delta.unchanged.length} unchanged`);
 
+      // Plan vector changes from git history since the last indexed commit
+      const vectorPlan = this.vector && this.vector.isConnected()
+        ? await this.planVectorDelta(delta, currentFiles)
+        : null;
+      const vectorsUpToDate = !vectorPlan || (!vectorPlan.rebuild &&
+        vectorPlan.reembed.length === 0 && vectorPlan.remove.length === 0 && vectorPlan.renames.length === 0);
+
+      if (vectorPlan && !vectorsUpToDate) {
+        console.log(`Vector delta: ${vectorPlan.reembed.length} to embed, ${vectorPlan.remove.length} to remove, ${vectorPlan.renames.length} renamed`);
+      }
+      await this.enforceLimits(
+        vectorPlan?.rebuild ? currentFiles : vectorPlan ? vectorPlan.reembed : [...delta.added, ...delta.modified],
+        options
+      );
+
       // If nothing changed in files, still sync commit history
-      if (delta.added.length === 0 && delta.modified.length === 0 && delta.deleted.length === 0) {
+      if (delta.added.length === 0 && delta.modified.length === 0 && delta.deleted.length === 0 && vectorsUpToDate) {
         console.log('No file changes detected');
 
+        // HEAD may have moved without touching indexed files
+        if (vectorPlan) {
+          await this.recordIndexedCommit();
+          if (!(await readIndexSnapshotManifest(getIndexDir(this.repoRoot)))) {
+            await this.persistVectorIndex();
+          }
+        }
+
+        // Keep the mtimes of files touched without being changed
+        await this.delta.save();
+
         // Sync commit history even when no file changes (new commits may exist)
         const syncCommits = options.syncCommits !== false;
         if (syncCommits) {
@@ -548,7 +718,7 @@ export class SyncEngine {
         let vectorCount = 0;
         if (this.vector && this.vector.isConnected()) {
           try {
-            const info = await this.vector.getCollectionInfo('code_chunks');
+            const info = await this.vector.getCollectionInfo(this.vector.getCollectionNames().codeChunks);
             vectorCount = info.points_count || 0;
           } catch (error) {
             vectorCount = 0;
@@ -591,9 +761,15 @@ export class SyncEngine {
         }
       }
 
-      // Update graph with changed files
+      // Update graph with changed files (vectors are handled by the git-based plan)
       if (parsedFiles.length > 0) {
-        await this.updateGraph(parsedFiles);
+        await this.updateGraph(parsedFiles, !vectorPlan);
+      }
+
+      if (vectorPlan && !vectorsUpToDate) {
+        await this.applyVectorDelta(vectorPlan, parsedFiles, syncErrors);
+      } else if (vectorPlan) {
+        await this.recordIndexedCommit();
       }
 
       // Generate delta summaries for changed files (if enabled, default: true)
@@ -648,7 +824,7 @@ export class SyncEngine {
           syncedContents.set(file, fileContents.get(file)!);
         }
       }
-      await this.delta.markSynced(syncedContents, 'code');
+      await this.delta.markSynced(syncedContents, 'code', fileStats);
       await this.delta.setLastCommit(await this.git.getLastCommitSha());
       await this.delta.close();
 
@@ -659,7 +835,7 @@ export class SyncEngine {
       let vectorCount = 0;
       if (this.vector && this.vector.isConnected()) {
         try {
-          const info = await this.vector.getCollectionInfo('code_chunks');
+          const info = await this.vector.getCollectionInfo(this.vector.getCollectionNames().codeChunks);
           vectorCount = info.points_count || 0;
         } catch (error) {
           vectorCount = 0;
@@ -682,6 +858,7 @@ export class SyncEngine {
       };
 
       await this.saveSyncState(syncState);
+      await this.persistVectorIndex();
 
       // Save sync report for error tracking
       const syncReport: SyncReport = {
@@ -723,7 +900,9 @@ export class SyncEngine {
       return syncState;
 
     } catch (error: any) {
-      console.error('Delta sync failed:', error);
+      if (!(error instanceof SyncLimitError)) {
+        console.error('Delta sync failed:', error);
+      }
       throw error;
     }
   }
@@ -766,7 +945,7 @@ export class SyncEngine {
       const fileContents = new Map<string, string>();
       for (const file of docFiles) {
         const absolutePath = path.join(this.repoRoot, file);
-        const result = await safeReadFile(absolutePath);
+        const result = await safeReadFile(absolutePath, this.maxFileSize);
         if ('content' in result) {
           fileContents.set(file, result.content);
         } else {
@@ -884,6 +1063,271 @@ export class SyncEngine {
     await this.delta.reset();
   }
 
+  /**
+   * Reject the sync if the active embedding config differs from the one
+   * the vector index was built with (mixed vectors make search meaningless)
+   */
+  private async checkVectorIndex(): Promise<void> {
+    try {
+      this.worktree = await this.git.getWorktreeInfo();
+    } catch {
+      this.worktree = undefined;
+    }
+
+    if (!this.vector) return;
+
+    const current = this.vector.getEmbeddingInfo();
+    const existing = await readIndexMetadata(this.repoRoot);
+    if (existing) {
+      assertIndexCompatible(existing, current);
+      return;
+    }
+
+    // No metadata yet (index from an older version) - fall back to dimensions
+    const issues = await this.vector.checkAllCollectionsCompatibility();
+    const populated = issues.filter(i => i.pointCount > 0);
+    if (populated.length > 0) {
+      throw new VectorError(
+        `Vector index was built with ${populated[0].existingDimensions}-dimension embeddings, ` +
+        `but ${current.provider} / ${current.model} produces ${current.dimensions}.\n` +
+        `Run 'cv sync --force' to rebuild the index with the current embedding provider.`,
+        { issues: populated, current }
+      );
+    }
+  }
+
+  /**
+   * Work out which code vectors need to change since the last indexed commit
+   * using `git diff --name-status <lastIndexedCommit> HEAD`, merged with the
+   * content-hash delta (which also covers uncommitted working tree edits)
+   */
+  private async planVectorDelta(delta: SyncDelta, currentFiles: string[]): Promise<VectorDeltaPlan> {
+    const current = new Set(currentFiles);
+    const metadata = await readIndexMetadata(this.repoRoot);
+    const lastCommit = metadata?.lastIndexedCommit;
+
+    // First vector sync, or the recorded commit is gone (rebase/gc) - rebuild
+    if (!lastCommit || !(await this.git.commitExists(lastCommit))) {
+      if (metadata?.reindexReason) {
+        console.log(`Vector index was marked for reindex (${metadata.reindexReason}), rebuilding...`);
+      }
+      return { rebuild: true, reembed: currentFiles, remove: [], renames: [] };
+    }
+
+    // Indexed in another worktree (e.g. .cv copied over) - its commit says nothing about this checkout
+    if (metadata?.worktree && this.worktree && metadata.worktree.path !== this.worktree.path) {
+      console.log(`Vector index was built in worktree ${metadata.worktree.path}, rebuilding for ${this.worktree.path}...`);
+      return { rebuild: true, reembed: currentFiles, remove: [], renames: [] };
+    }
+
+    // Vectors were lost (Qdrant restarted with nothing to restore) - rebuild
+    const info = await this.vector!.getCollectionInfo(this.vector!.getCollectionNames().codeChunks);
+    if (!info.points_count && currentFiles.length > 0) {
+      return { rebuild: true, reembed: currentFiles, remove: [], renames: [] };
+    }
+
+    const reembed = new Set<string>();
+    const remove = new Set<string>();
+    const renames: Array<{ from: string; to: string }> = [];
+
+    const headCommit = await this.git.getLastCommitSha();
+    const changes = lastCommit === headCommit ? [] : await this.git.getFileChangesSince(lastCommit, headCommit);
+
+    for (const change of changes) {
+      switch (change.status) {
+        case 'D':
+          remove.add(change.path);
+          break;
+        case 'R':
+          // Identical content: move the vectors instead of re-embedding
+          if (change.similarity === 100 && current.has(change.path)) {
+            renames.push({ from: change.oldPath!, to: change.path });
+          } else {
+            remove.add(change.oldPath!);
+            if (current.has(change.path)) reembed.add(change.path);
+          }
+          break;
+        default:
+          if (current.has(change.path)) {
+            reembed.add(change.path);
+          } else {
+            // No longer syncable (excluded, too large, etc.)
+            remove.add(change.path);
+          }
+      }
+    }
+
+    // Working tree changes not yet committed
+    for (const file of [...delta.added, ...delta.modified]) reembed.add(file);
+    for (const file of delta.deleted) remove.add(file);
+
+    // Renamed files show up in the content delta as delete + add
+    for (const { from, to } of renames) {
+      remove.delete(from);
+      reembed.delete(to);
+    }
+
+    // Chunk boundaries shift when a file changes, so drop old vectors first
+    for (const file of reembed) remove.add(file);
+
+    return { rebuild: false, reembed: [...reembed], remove: [...remove], renames };
+  }
+
+  /**
+   * Apply a vector delta plan and record the indexed commit on success
+   */
+  private async applyVectorDelta(
+    plan: VectorDeltaPlan,
+    parsedFiles: ParsedFile[],
+    syncErrors: SyncError[]
+  ): Promise<void> {
+    if (!this.vector || !this.vector.isConnected()) return;
+
+    const collection = this.vector.getCollectionNames().codeChunks;
+    const failuresBefore = this.vectorFailures;
+
+    try {
+      if (plan.rebuild) {
+        console.log('No indexed commit recorded, rebuilding code vectors...');
+        await this.vector.clearCollection(collection);
+        this.rebuiltIndex = true;
+      }
+
+      if (plan.remove.length > 0) {
+        console.log(`Removing vectors for ${plan.remove.length} files...`);
+        await this.vector.deleteByFiles(collection, plan.remove);
+        plan.remove.forEach(file => this.embeddedFiles.add(file));
+      }
+
+      for (const { from, to } of plan.renames) {
+        const moved = await this.vector.moveFileVectors(collection, from, to);
+        this.embeddedFiles.add(from);
+        console.log(`  Moved ${moved} vectors: ${from} → ${to}`);
+      }
+
+      // Reuse files already parsed for the graph update
+      const parsedByPath = new Map(parsedFiles.map(f => [f.path, f]));
+      const toEmbed: ParsedFile[] = [];
+      for (const file of plan.reembed) {
+        const parsed = parsedByPath.get(file);
+        if (parsed) {
+          toEmbed.push(parsed);
+          continue;
+        }
+        try {
+          toEmbed.push(await this.parseFile(file));
+        } catch (error: any) {
+          syncErrors.push({ file, error: error.message, phase: 'parse', timestamp: Date.now() });
+        }
+      }
+
+      if (toEmbed.length > 0) {
+        console.log(`Re-embedding ${toEmbed.length} files...`);
+        await this.updateVectorEmbeddings(toEmbed);
+      }
+
+      if (this.vectorFailures === failuresBefore) {
+        await writeIndexMetadata(this.repoRoot, this.vector.getEmbeddingInfo(), await this.git.getLastCommitSha(), this.repoLanguages, this.warningUpdate(), this.indexWorktree(), this.fileFilter);
+      }
+    } catch (error: any) {
+      // Leave lastIndexedCommit untouched so the next sync retries this delta
+      console.warn('Vector update failed: ' + error.message);
+      syncErrors.push({ file: '*', error: error.message, phase: 'vector', timestamp: Date.now() });
+    }
+  }
+
+  /**
+   * Record the indexed commit after a complete (non-delta) vector rebuild
+   */
+  private async recordIndexedCommit(): Promise<void> {
+    if (!this.vector || !this.vector.isConnected() || this.vectorFailures > 0) return;
+    await writeIndexMetadata(this.repoRoot, this.vector.getEmbeddingInfo(), await this.git.getLastCommitSha(), this.repoLanguages, this.warningUpdate(), this.indexWorktree(), this.fileFilter);
+  }
+
+  /**
+   * Worktree to record alongside the indexed commit
+   */
+  private indexWorktree(): IndexWorktree | undefined {
+    return this.worktree ? { path: this.worktree.path, branch: this.worktree.branch } : undefined;
+  }
+
+  /**
+   * Start collecting chunk warnings for a new sync
+   */
+  private resetChunkWarnings(options: SyncOptions, rebuild: boolean): void {
+    this.chunkWarnings = [];
+    this.embeddedFiles = new Set();
+    this.rebuiltIndex = rebuild;
+    this.onChunkSkipped = options.onChunkSkipped;
+  }
+
+  /**
+   * Warnings to store with the index metadata for this sync
+   */
+  private warningUpdate(): IndexWarningUpdate {
+    return {
+      files: this.rebuiltIndex ? undefined : [...this.embeddedFiles],
+      warnings: this.chunkWarnings
+    };
+  }
+
+  /**
+   * Split a file's chunks to fit the embedding model's input limit,
+   * recording whatever can't be embedded
+   */
+  private fitChunks(file: ParsedFile): void {
+    this.embeddedFiles.add(file.path);
+    if (!this.vector || !file.chunks || file.chunks.length === 0) return;
+
+    const vector = this.vector;
+    const { chunks, skipped } = fitChunksToTokenLimit(
+      file.chunks,
+      vector.getMaxInputTokens(),
+      chunk => vector.prepareCodeForEmbedding(chunk)
+    );
+    file.chunks = chunks;
+    for (const warning of skipped) {
+      this.chunkWarnings.push(warning);
+      this.onChunkSkipped?.(warning);
+    }
+  }
+
+  /**
+   * One-line note about skipped code, printed after embeddings are stored
+   */
+  private reportChunkWarnings(): void {
+    if (this.chunkWarnings.length === 0) return;
+    const files = new Set(this.chunkWarnings.map(w => w.file)).size;
+    console.warn(`⚠ Skipped ${this.chunkWarnings.length} oversized chunk(s) in ${files} file(s) that exceed the embedding model's token limit (see cv index status)`);
+  }
+
+  /**
+   * Drop all vectors and index metadata (used by cv sync --force)
+   */
+  async resetVectorIndex(): Promise<void> {
+    if (!this.vector) return;
+
+    for (const collection of Object.values(this.vector.getCollectionNames())) {
+      await this.vector.clearCollection(collection);
+    }
+    await clearIndexMetadata(this.repoRoot);
+    await clearIndexSnapshot(getIndexDir(this.repoRoot));
+  }
+
+  /**
+   * Snapshot all vectors to .cv/index so the index survives a Qdrant restart
+   */
+  private async persistVectorIndex(): Promise<void> {
+    if (!this.vector || !this.vector.isConnected() || this.vectorFailures > 0) return;
+
+    try {
+      const metadata = await readIndexMetadata(this.repoRoot);
+      await this.vector.saveIndex(getIndexDir(this.repoRoot), metadata?.lastIndexedCommit);
+    } catch (error: any) {
+      console.warn(`Failed to persist vector index: ${error.message}`);
+    }
+  }
+
   /**
    * Get chunked sync progress (for --continue flag)
    */
@@ -917,17 +1361,15 @@ export class SyncEngine {
 
     console.log(`Starting chunked sync (max ${maxFiles} files per run)...`);
 
-    try {
-      // Get all tracked files
-      const allFiles = await this.git.getTrackedFiles();
-      const defaultPatterns = this.getDefaultExcludePatterns();
-      const customPatterns = options.excludePatterns || [];
-      const excludePatterns = [...new Set([...defaultPatterns, ...customPatterns])];
-      const includeLanguages = options.includeLanguages || this.getDefaultIncludeLanguages();
+    await this.checkVectorIndex();
+    this.vectorFailures = 0;
+    this.resetChunkWarnings(options, false);
 
-      const filesToSync = allFiles.filter(f =>
-        shouldSyncFile(f, excludePatterns, includeLanguages)
-      );
+    try {
+      // Get all tracked files (and untracked ones git does not ignore)
+      const allFiles = await this.listRepoFiles(options);
+      const filesToSync = await this.selectFiles(allFiles, options);
+      this.repoLanguages = detectRepoLanguages(filesToSync);
 
       // Check for existing progress
       let progress = await this.delta.getChunkedProgress();
@@ -962,7 +1404,7 @@ export class SyncEngine {
 
       // Parse files in this chunk
       const parsedFiles: ParsedFile[] = [];
-      const CONCURRENCY = 10;
+      const CONCURRENCY = options.concurrency ?? DEFAULT_SYNC_CONCURRENCY;
 
       for (let i = 0; i < chunkFiles.length; i += CONCURRENCY) {
         const batch = chunkFiles.slice(i, i + CONCURRENCY);
@@ -1008,6 +1450,7 @@ export class SyncEngine {
       if (isComplete) {
         await this.delta.completeChunkedSync();
         console.log('Chunked sync complete!');
+        await this.recordIndexedCommit();
 
         // Sync commit history only when complete
         if (options.syncCommits !== false) {
@@ -1026,7 +1469,7 @@ export class SyncEngine {
       let vectorCount = 0;
       if (this.vector && this.vector.isConnected()) {
         try {
-          const info = await this.vector.getCollectionInfo('code_chunks');
+          const info = await this.vector.getCollectionInfo(this.vector.getCollectionNames().codeChunks);
           vectorCount = info.points_count || 0;
         } catch {
           vectorCount = 0;
@@ -1037,7 +1480,7 @@ export class SyncEngine {
       const fileContents = new Map<string, string>();
       for (const file of chunkFiles) {
         const absolutePath = path.join(this.repoRoot, file);
-        const result = await safeReadFile(absolutePath);
+        const result = await safeReadFile(absolutePath, this.maxFileSize);
         if ('content' in result) {
           fileContents.set(file, result.content);
         }
@@ -1060,6 +1503,7 @@ export class SyncEngine {
       };
 
       await this.saveSyncState(syncState);
+      await this.persistVectorIndex();
 
       console.log(`\nChunk processed in ${syncState.syncDuration?.toFixed(1)}s`);
 
@@ -1109,7 +1553,7 @@ export class SyncEngine {
    */
   private async parseFile(filePath: string): Promise<ParsedFile> {
     const absolutePath = path.join(this.repoRoot, filePath);
-    const result = await safeReadFile(absolutePath);
+    const result = await safeReadFile(absolutePath, this.maxFileSize);
 
     if ('error' in result) {
       throw new Error(result.error);
@@ -1125,7 +1569,7 @@ export class SyncEngine {
   /**
    * Update graph with parsed files
    */
-  private async updateGraph(parsedFiles: ParsedFile[]): Promise<void> {
+  private async updateGraph(parsedFiles: ParsedFile[], embed: boolean = true): Promise<void> {
     console.log('Creating file nodes...');
 
     // Get git hashes for all files in batch (more efficient than per-file)
@@ -1248,7 +1692,7 @@ export class SyncEngine {
 
     // Step 5: Generate and store vector embeddings (if VectorManager available)
     // Also links graph symbols to their vector chunk IDs
-    if (this.vector && this.vector.isConnected()) {
+    if (embed && this.vector && this.vector.isConnected()) {
       console.log('Generating vector embeddings...');
       const { vectorCount, symbolToChunkMap } = await this.updateVectorEmbeddings(parsedFiles);
       if (process.env.CV_DEBUG) {
@@ -1310,6 +1754,7 @@ export class SyncEngine {
       // Collect all code chunks from all files
       const allChunks: CodeChunk[] = [];
       for (const file of parsedFiles) {
+        this.fitChunks(file);
         if (file.chunks && file.chunks.length > 0) {
           allChunks.push(...file.chunks);
         }
@@ -1322,86 +1767,237 @@ export class SyncEngine {
 
       console.log(`Found ${allChunks.length} code chunks to embed`);
 
-      // Build symbol→chunk mapping as we process chunks
-      // This links graph symbol nodes to their vector chunk IDs
-      for (const chunk of allChunks) {
-        if (chunk.symbolName) {
-          // Find the symbol in parsed files to get qualified name
-          const file = parsedFiles.find(f => f.path === chunk.file);
-          if (file) {
-            const symbol = file.symbols.find(s =>
-              s.name === chunk.symbolName &&
-              s.startLine <= chunk.startLine &&
-              s.endLine >= chunk.endLine
-            );
-            if (symbol) {
-              const existing = symbolToChunkMap.get(symbol.qualifiedName) || [];
-              existing.push(chunk.id);
-              symbolToChunkMap.set(symbol.qualifiedName, existing);
-            }
-          }
-        }
+      const imports = new Map(parsedFiles.map(f => [f.path, f.imports.map(i => i.source)] as [string, string[]]));
+      const progress = createEmbeddingProgress();
+      try {
+        await this.embedAndStore(allChunks, imports, progress.update);
+      } finally {
+        progress.done();
       }
+      await writeIndexMetadata(this.repoRoot, this.vector.getEmbeddingInfo(), undefined, this.repoLanguages, this.warningUpdate(), undefined, this.fileFilter);
 
-      // Prepare chunks for embedding (add context)
-      const textsToEmbed = allChunks.map(chunk =>
-        this.vector!.prepareCodeForEmbedding(chunk)
-      );
+      // Link graph symbols to vector IDs
+      const symbolMap = this.buildSymbolChunkMap(parsedFiles);
+      await this.linkSymbolVectors(symbolMap);
 
-      // Generate embeddings in batch
-      console.log('Generating embeddings...');
-      const embeddings = await this.vector.embedBatch(textsToEmbed);
+      console.log(`✓ Stored ${allChunks.length} embeddings`);
+      this.reportChunkWarnings();
+      return { vectorCount: allChunks.length, symbolToChunkMap: symbolMap };
 
-      // Prepare batch upsert items
-      const items = allChunks.map((chunk, idx) => {
-        // Find the file this chunk belongs to
-        const file = parsedFiles.find(f => f.path === chunk.file);
-        const imports = file ? file.imports.map(i => i.source) : [];
+    } catch (error: any) {
+      // A cancelled sync stops here rather than carrying on without vectors
+      this.signal?.throwIfAborted();
+      console.warn('Embeddings skipped: ' + error.message);
+      this.vectorFailures++;
+      return { vectorCount: 0, symbolToChunkMap };
+    }
+  }
 
-        const payload: CodeChunkPayload = {
-          id: chunk.id,
-          file: chunk.file,
-          language: chunk.language,
-          symbolName: chunk.symbolName,
-          symbolKind: chunk.symbolKind,
-          startLine: chunk.startLine,
-          endLine: chunk.endLine,
-          text: chunk.text,
-          summary: chunk.summary,
-          docstring: chunk.docstring,
-          imports,
-          complexity: chunk.complexity,
-          lastModified: Date.now()
-        };
+  /**
+   * Parse files with a pool of workers, handing each file's chunks to the
+   * embedder as soon as it is parsed so chunking overlaps with in-flight
+   * embedding requests. Returns the parsed files in input order (the graph's
+   * call resolution depends on it) and the embedding stage, which may still
+   * be draining; it resolves to the number of vectors stored.
+   */
+  private async parseAndEmbed(
+    files: string[],
+    concurrency: number,
+    syncErrors: SyncError[]
+  ): Promise<{ parsedFiles: ParsedFile[]; embedded: Promise<number> }> {
+    const embedder = this.vector && this.vector.isConnected() ? this.createChunkEmbedder() : undefined;
+    const log = embedder ? embedder.log : (message: string) => console.log(message);
+    const parsed: Array<{ index: number; file: ParsedFile }> = [];
+    let done = 0;
+
+    for await (const result of runWorkers(files, concurrency, file => this.parseFile(file))) {
+      this.signal?.throwIfAborted();
+      done++;
+      if ('error' in result) {
+        syncErrors.push({
+          file: result.item,
+          error: result.error.message || 'Unknown error',
+          phase: 'parse',
+          timestamp: Date.now()
+        });
+      } else {
+        parsed.push({ index: result.index, file: result.value });
+        await embedder?.add(result.value);
+      }
 
-        return {
-          id: chunk.id,
-          vector: embeddings[idx],
-          payload
-        };
+      if (done % 50 === 0 || done === files.length) {
+        log(`Parsed ${done}/${files.length} files`);
+      }
+    }
+
+    return {
+      parsedFiles: parsed.sort((a, b) => a.index - b.index).map(p => p.file),
+      embedded: embedder ? embedder.finish() : Promise.resolve(0)
+    };
+  }
+
+  /**
+   * Collects chunks as files are parsed and embeds them in groups. Groups are
+   * embedded one at a time (each already fans out into concurrent requests,
+   * and the embedding cache is a single file), and add() waits while too many
+   * groups are queued so parsing can't run far ahead of the provider.
+   */
+  private createChunkEmbedder(): {
+    add: (file: ParsedFile) => Promise<void>;
+    log: (message: string) => void;
+    finish: () => Promise<number>;
+  } {
+    const progress = createEmbeddingProgress();
+    const imports = new Map<string, string[]>();
+    const queued: Promise<void>[] = [];
+    let chain: Promise<void> = Promise.resolve();
+    let pending: CodeChunk[] = [];
+    let pendingFiles = new Map<string, string>();
+    let found = 0;
+    let stored = 0;
+    let resumed = 0;
+    let failure: Error | undefined;
+
+    const flush = () => {
+      const chunks = pending;
+      const files = pendingFiles;
+      pending = [];
+      pendingFiles = new Map();
+      chain = chain.then(async () => {
+        if (failure) return;
+        try {
+          const points = await this.embedAndStore(chunks, imports, embedded => progress.update(stored + embedded, found));
+          stored += chunks.length;
+          if (this.checkpoint) {
+            await recordCheckpointGroup(this.repoRoot, this.checkpoint, files, points);
+          }
+        } catch (error: any) {
+          failure = error;
+        }
       });
+      queued.push(chain);
+    };
 
-      // Upsert to Qdrant in batches
-      console.log('Storing embeddings in Qdrant...');
-      await this.vector.upsertBatch('code_chunks', items);
+    return {
+      add: async (file: ParsedFile) => {
+        if (failure) return;
+        this.fitChunks(file);
+        if (!file.chunks || file.chunks.length === 0) return;
+
+        // Restored from the checkpoint of an interrupted sync
+        const hash = this.checkpoint ? hashCheckpointContent(file.content) : '';
+        if (this.checkpoint?.files[file.path] === hash) {
+          resumed += file.chunks.length;
+          return;
+        }
 
-      // Link graph symbols to vector IDs
-      if (symbolToChunkMap.size > 0) {
-        console.log(`Linking ${symbolToChunkMap.size} symbols to vector chunks...`);
-        const linkResult = await this.graph.batchUpdateSymbolVectorIds(symbolToChunkMap);
-        if (linkResult.errors.length > 0) {
-          console.warn(`  ${linkResult.errors.length} link errors (symbols may not exist in graph yet)`);
+        imports.set(file.path, file.imports.map(i => i.source));
+        pending.push(...file.chunks);
+        pendingFiles.set(file.path, hash);
+        found += file.chunks.length;
+
+        if (pending.length >= EMBED_GROUP_SIZE) {
+          flush();
+          while (queued.length > MAX_QUEUED_EMBED_GROUPS) {
+            await queued.shift();
+          }
         }
-        console.log(`  ✓ Linked ${linkResult.updated} symbols to vectors`);
+      },
+
+      log: progress.log,
+
+      finish: async () => {
+        if (pending.length > 0) flush();
+        await chain;
+        progress.done();
+
+        if (failure) {
+          this.signal?.throwIfAborted();
+          console.warn('Embeddings skipped: ' + failure.message);
+          this.vectorFailures++;
+          return 0;
+        }
+        if (stored + resumed === 0) {
+          console.log('No code chunks to embed');
+          return 0;
+        }
+
+        await writeIndexMetadata(this.repoRoot, this.vector!.getEmbeddingInfo(), undefined, this.repoLanguages, this.warningUpdate(), undefined, this.fileFilter);
+        console.log(`✓ Stored ${stored} embeddings` + (resumed > 0 ? ` (${resumed} more restored from the interrupted sync)` : ''));
+        this.reportChunkWarnings();
+        return stored + resumed;
       }
+    };
+  }
 
-      console.log(`✓ Stored ${allChunks.length} embeddings`);
-      return { vectorCount: allChunks.length, symbolToChunkMap };
+  /**
+   * Embed chunks and upsert them into the code chunk collection.
+   * Returns the stored points.
+   */
+  private async embedAndStore(
+    chunks: CodeChunk[],
+    importsByFile: Map<string, string[]>,
+    onProgress?: (embedded: number, total: number) => void
+  ): Promise<IndexSnapshotPoint[]> {
+    const vector = this.vector!;
+
+    // Prepare chunks for embedding (add context)
+    const embeddings = await vector.embedBatch(chunks.map(chunk => vector.prepareCodeForEmbedding(chunk)), {
+      onProgress,
+      cacheKeys: chunks.map(chunk => vector.embeddingCacheKey(chunk))
+    });
+
+    this.syncCommit ??= this.git.getLastCommitSha().catch(() => undefined);  // No commits yet
+    const commit = await this.syncCommit;
+    const packages = new PackageResolver(this.repoRoot);
+
+    const items = chunks.map((chunk, idx) => ({
+      id: chunk.id,
+      vector: embeddings[idx],
+      payload: buildChunkPayload(chunk, { imports: importsByFile.get(chunk.file) ?? [], packages, commit })
+    }));
+
+    await vector.upsertBatch(vector.getCollectionNames().codeChunks, items);
+    return items;
+  }
 
-    } catch (error: any) {
-      console.warn('Embeddings skipped: ' + error.message);
-      return { vectorCount: 0, symbolToChunkMap };
+  /**
+   * Map each symbol's qualified name to the IDs of the chunks inside it
+   */
+  private buildSymbolChunkMap(parsedFiles: ParsedFile[]): Map<string, string[]> {
+    const symbolToChunkMap = new Map<string, string[]>();
+
+    for (const file of parsedFiles) {
+      for (const chunk of file.chunks || []) {
+        if (!chunk.symbolName) continue;
+        const symbol = file.symbols.find(s =>
+          s.name === chunk.symbolName &&
+          s.startLine <= chunk.startLine &&
+          s.endLine >= chunk.endLine
+        );
+        if (symbol) {
+          const existing = symbolToChunkMap.get(symbol.qualifiedName) || [];
+          existing.push(chunk.id);
+          symbolToChunkMap.set(symbol.qualifiedName, existing);
+        }
+      }
     }
+
+    return symbolToChunkMap;
+  }
+
+  /**
+   * Link graph symbol nodes to their vector chunk IDs
+   */
+  private async linkSymbolVectors(symbolToChunkMap: Map<string, string[]>): Promise<void> {
+    if (symbolToChunkMap.size === 0) return;
+
+    console.log(`Linking ${symbolToChunkMap.size} symbols to vector chunks...`);
+    const linkResult = await this.graph.batchUpdateSymbolVectorIds(symbolToChunkMap);
+    if (linkResult.errors.length > 0) {
+      console.warn(`  ${linkResult.errors.length} link errors (symbols may not exist in graph yet)`);
+    }
+    console.log(`  ✓ Linked ${linkResult.updated} symbols to vectors`);
   }
 
   // ========== Document Sync Methods ==========
@@ -1447,7 +2043,7 @@ export class SyncEngine {
       for (const file of docFiles) {
         try {
           const absolutePath = path.join(this.repoRoot, file);
-          const result = await safeReadFile(absolutePath);
+          const result = await safeReadFile(absolutePath, this.maxFileSize);
 
           if ('error' in result) {
             logSkippedFile(file, result.error);
@@ -1827,6 +2423,158 @@ export class SyncEngine {
     return counts;
   }
 
+  /**
+   * Ask options.confirmLimits before a sync goes over its limits; without
+   * the callback the limits are not checked
+   */
+  private async enforceLimits(files: string[], options: SyncOptions): Promise<void> {
+    if (!options.confirmLimits || files.length === 0) return;
+    const check = await checkSyncLimits(this.repoRoot, files, options.limits);
+    if (check.exceeded.length > 0 && !(await options.confirmLimits(check))) {
+      throw new SyncLimitError(check);
+    }
+  }
+
+  /**
+   * Filter candidate files down to the ones that should be synced.
+   * Applies .gitignore, .cvignore, the --include/--exclude/--ext filter, exclude
+   * patterns, the language filter, and skips binary or oversized files,
+   * reporting why each file was left out.
+   * @param trackedFiles - Full tracked file list, used to find nested ignore files
+   * @param summarize - Log how many files were considered and skipped
+   */
+  /**
+   * Files a code sync considers: tracked files plus untracked files git does
+   * not ignore (generated code that is never committed), unless
+   * `includeUntracked` is false
+   */
+  private async listRepoFiles(options: SyncOptions): Promise<string[]> {
+    const tracked = await this.git.getTrackedFiles();
+    if (options.includeUntracked === false) return tracked;
+    return [...tracked, ...await this.git.getUntrackedFiles()];
+  }
+
+  /**
+   * Stat and read files (using safe file reading with size limits). With
+   * `skipUnchanged`, files whose size and mtime match the last sync are
+   * stat'ed but not read.
+   */
+  private async readCurrentFiles(
+    files: string[],
+    skipUnchanged: boolean = false
+  ): Promise<{ contents: Map<string, string>; stats: Map<string, FileStat> }> {
+    const contents = new Map<string, string>();
+    const stats = new Map<string, FileStat>();
+    for (const file of files) {
+      const absolutePath = path.join(this.repoRoot, file);
+      try {
+        const stat = await fs.stat(absolutePath);
+        stats.set(file, { mtimeMs: stat.mtimeMs, size: stat.size });
+        if (skipUnchanged && await this.delta.isUnchangedOnDisk(file, stats.get(file)!)) continue;
+      } catch {
+        // safeReadFile reports why the file cannot be read
+      }
+
+      const result = await safeReadFile(absolutePath, this.maxFileSize);
+      if ('content' in result) {
+        contents.set(file, result.content);
+      } else {
+        stats.delete(file);
+        logSkippedFile(file, result.error);
+      }
+    }
+    return { contents, stats };
+  }
+
+  private async selectFiles(
+    files: string[],
+    options: SyncOptions,
+    trackedFiles: string[] = files,
+    summarize: boolean = true
+  ): Promise<string[]> {
+    const defaultPatterns = this.getDefaultExcludePatterns();
+    const customPatterns = options.excludePatterns || [];
+    const excludePatterns = [...new Set([...defaultPatterns, ...customPatterns])];
+    const fileFilter = normalizeFileFilter(options.fileFilter);
+    // An extension list picks the languages itself
+    const includeLanguages = fileFilter.extensions ? [] : options.includeLanguages || this.getDefaultIncludeLanguages();
+    const ignoreRules = await IgnoreRules.load(this.repoRoot, trackedFiles);
+    const report = options.onFileSkipped;
+
+    this.maxFileSize = options.maxFileSize;
+    this.fileFilter = fileFilter;
+    this.signal = options.signal;
+    this.syncCommit = undefined;
+
+    const skippedBy = { ignored: 0, filtered: 0, excluded: 0, unreadable: 0 };
+    const candidates: string[] = [];
+    for (const file of files) {
+      const ignoredBy = ignoreRules.match(file);
+      if (ignoredBy) {
+        skippedBy.ignored++;
+        report?.(file, `matched ${ignoredBy}`);
+        continue;
+      }
+
+      const filteredBy = fileFilterMiss(file, fileFilter);
+      if (filteredBy) {
+        skippedBy.filtered++;
+        report?.(file, filteredBy);
+        continue;
+      }
+
+      if (!shouldSyncFile(file, excludePatterns, includeLanguages)) {
+        skippedBy.excluded++;
+        const language = detectLanguage(file);
+        const reason = language === 'unknown'
+          ? 'unsupported file type'
+          : includeLanguages.length > 0 && !includeLanguages.includes(language)
+            ? `language not included: ${language}`
+            : 'matched exclude pattern';
+        report?.(file, reason);
+        continue;
+      }
+
+      candidates.push(file);
+    }
+
+    // Binary and size checks touch the filesystem, so run them in batches
+    const selected: string[] = [];
+    const CONCURRENCY = 50;
+    for (let i = 0; i < candidates.length; i += CONCURRENCY) {
+      const batch = candidates.slice(i, i + CONCURRENCY);
+      const checks = await Promise.all(
+        batch.map(file => checkFileReadable(path.join(this.repoRoot, file), this.maxFileSize))
+      );
+
+      for (let j = 0; j < batch.length; j++) {
+        if (checks[j].readable) {
+          selected.push(batch[j]);
+          continue;
+        }
+        skippedBy.unreadable++;
+        if (report) {
+          report(batch[j], checks[j].reason || 'unreadable');
+        } else {
+          logSkippedFile(batch[j], checks[j].reason || 'unreadable');
+        }
+      }
+    }
+
+    const skipped = files.length - selected.length;
+    if (summarize && skipped > 0) {
+      const reasons = [
+        skippedBy.filtered > 0 ? `${skippedBy.filtered} outside --include/--exclude/--ext` : '',
+        skippedBy.ignored > 0 ? `${skippedBy.ignored} ignored` : '',
+        skippedBy.excluded > 0 ? `${skippedBy.excluded} excluded or unsupported` : '',
+        skippedBy.unreadable > 0 ? `${skippedBy.unreadable} binary or oversized` : ''
+      ].filter(Boolean);
+      console.log(`Considered ${files.length} files: ${selected.length} selected, ${skipped} skipped (${reasons.join(', ')})`);
+    }
+
+    return selected;
+  }
+
   /**
    * Get default exclude patterns
    */
@@ -1834,6 +2582,7 @@ export class SyncEngine {
     return [
       // JavaScript/Node
       'node_modules/**',
+      '**/node_modules/**',       // Nested packages in monorepos
       '.next/**',
       '.nuxt/**',
       '*.min.js',
@@ -1901,13 +2650,16 @@ export class SyncEngine {
       const commits = await this.git.getRecentCommits(depth);
       console.log(`Found ${commits.length} commits to sync`);
 
-      // Get current branch
+      // Get current branch ('HEAD' when detached)
       let currentBranch = 'unknown';
       try {
         currentBranch = await this.git.getCurrentBranch();
       } catch {
         // Fallback to 'unknown' if git fails
       }
+      if (currentBranch === 'HEAD') {
+        currentBranch = 'detached';
+      }
 
       // Process each commit
       for (const commit of commits) {
@@ -0,0 +1,167 @@
+/**
+ * Repository Languages
+ *
+ * Detects the dominant programming languages of a repository by file
+ * extension and count, so code generation can be told which language and
+ * idioms to use. `cv sync` records the result in the vector index metadata.
+ */
+
+import * as path from 'path';
+import { GitManager } from '../git/index.js';
+import { readIndexMetadata } from '../vector/index-metadata.js';
+
+/**
+ * Programming language display names by extension.
+ * Unlike detectLanguage (which picks a parser), JavaScript and TypeScript
+ * are kept apart here - asking for TypeScript in a JavaScript repo is
+ * exactly the kind of mismatch this is meant to prevent.
+ */
+const LANGUAGE_NAMES: Record<string, string> = {
+  '.ts': 'TypeScript',
+  '.tsx': 'TypeScript',
+  '.mts': 'TypeScript',
+  '.cts': 'TypeScript',
+  '.js': 'JavaScript',
+  '.jsx': 'JavaScript',
+  '.mjs': 'JavaScript',
+  '.cjs': 'JavaScript',
+  '.py': 'Python',
+  '.go': 'Go',
+  '.rs': 'Rust',
+  '.java': 'Java',
+  '.kt': 'Kotlin',
+  '.scala': 'Scala',
+  '.c': 'C',
+  '.h': 'C',
+  '.cpp': 'C++',
+  '.cc': 'C++',
+  '.hpp': 'C++',
+  '.cs': 'C#',
+  '.rb': 'Ruby',
+  '.php': 'PHP',
+  '.swift': 'Swift'
+};
+
+/** Lower-case names and common shorthands accepted by --language */
+const LANGUAGE_ALIASES: Record<string, string> = {
+  ts: 'TypeScript',
+  js: 'JavaScript',
+  py: 'Python',
+  golang: 'Go',
+  rs: 'Rust',
+  'c++': 'C++',
+  cpp: 'C++',
+  csharp: 'C#',
+  'c#': 'C#',
+  rb: 'Ruby',
+  kt: 'Kotlin',
+  ...Object.fromEntries(Object.values(LANGUAGE_NAMES).map(name => [name.toLowerCase(), name]))
+};
+
+/** Languages with at least this share of source files count as primary */
+const PRIMARY_SHARE = 0.2;
+const MAX_PRIMARY = 3;
+/** Retrieved chunks considered when picking a language for a mixed repo */
+const CHUNK_SAMPLE = 5;
+
+export interface RepoLanguages {
+  /** Source files per language */
+  counts: Record<string, number>;
+  /** Dominant languages, most common first */
+  primary: string[];
+}
+
+/**
+ * Programming language of a file by extension (undefined for non-code files)
+ */
+export function languageForFile(file: string): string | undefined {
+  return LANGUAGE_NAMES[path.extname(file).toLowerCase()];
+}
+
+/**
+ * Canonical display name for a user-supplied language (e.g. "golang" -> "Go")
+ */
+export function normalizeLanguageName(language: string): string {
+  return LANGUAGE_ALIASES[language.trim().toLowerCase()] || language.trim();
+}
+
+/**
+ * Count source files per language and pick the dominant ones
+ */
+export function detectRepoLanguages(files: string[]): RepoLanguages {
+  const counts: Record<string, number> = {};
+  for (const file of files) {
+    const language = languageForFile(file);
+    if (language) {
+      counts[language] = (counts[language] || 0) + 1;
+    }
+  }
+
+  const total = Object.values(counts).reduce((sum, n) => sum + n, 0);
+  const ranked = Object.entries(counts).sort((a, b) => b[1] - a[1] || a[0].localeCompare(b[0]));
+  const primary = ranked
+    .filter(([, n], idx) => idx === 0 || n / total >= PRIMARY_SHARE)
+    .slice(0, MAX_PRIMARY)
+    .map(([language]) => language);
+
+  return { counts, primary };
+}
+
+/**
+ * Languages recorded by the last sync, or detected from tracked files
+ * if the index has not recorded them yet
+ */
+export async function loadRepoLanguages(repoRoot: string, git: GitManager): Promise<RepoLanguages> {
+  const metadata = await readIndexMetadata(repoRoot);
+  if (metadata?.languages) {
+    return metadata.languages;
+  }
+  return detectRepoLanguages(await git.getTrackedFiles());
+}
+
+/**
+ * Language generated code should be written in.
+ * An explicit override wins; in a mixed repo the most-relevant retrieved
+ * chunks decide, otherwise the repo's dominant language is used.
+ * @param chunkFiles - Files of the retrieved chunks, most relevant first
+ */
+export function pickPromptLanguage(
+  repo: RepoLanguages | null,
+  chunkFiles: string[],
+  override?: string
+): string | undefined {
+  if (override) {
+    return normalizeLanguageName(override);
+  }
+
+  const votes = new Map<string, number>();
+  for (const file of chunkFiles.slice(0, CHUNK_SAMPLE)) {
+    const language = languageForFile(file);
+    if (language) {
+      votes.set(language, (votes.get(language) || 0) + 1);
+    }
+  }
+
+  const mixed = !repo || repo.primary.length !== 1;
+  if (mixed && votes.size > 0) {
+    // Map iteration follows first appearance, so ties go to the more relevant chunk
+    let best: string | undefined;
+    for (const [language, count] of votes) {
+      if (!best || count > votes.get(best)!) best = language;
+    }
+    return best;
+  }
+
+  return repo?.primary[0];
+}
+
+/**
+ * Prompt guidance telling the model which language to write
+ */
+export function buildLanguageInstruction(language: string, repoLanguages: string[] = []): string {
+  let instruction = `Write code in ${language}, following the idioms and conventions of the existing ${language} code.`;
+  if (repoLanguages.length > 1) {
+    instruction += ` The repository mixes ${repoLanguages.join(', ')}; this task concerns the ${language} code.`;
+  }
+  return instruction;
+}
@@ -0,0 +1,149 @@
+/**
+ * Sync Limits
+ *
+ * Guards against a stray huge directory turning `cv sync` into an
+ * hours-long embedding run: before anything is embedded, the files a sync
+ * selected are measured against a file count, a total size and a per-file
+ * size, and the caller is asked to confirm when one is exceeded.
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+
+/**
+ * Limits on what one sync embeds; 0 disables a limit
+ */
+export interface SyncLimits {
+  /** Files embedded by one sync */
+  maxFiles?: number;
+  /** Total bytes of the files embedded by one sync */
+  maxTotalBytes?: number;
+  /** Bytes of any one file (files over sync.maxFileSize are skipped, not asked about) */
+  maxBytes?: number;
+}
+
+export const DEFAULT_SYNC_LIMITS: Required<SyncLimits> = {
+  maxFiles: 10_000,
+  maxTotalBytes: 100 * 1024 * 1024,
+  maxBytes: 512 * 1024
+};
+
+/**
+ * Rough bytes of source per embedded chunk (chunks follow functions and
+ * classes, so most are far shorter than sync.maxChunkLines)
+ */
+const ESTIMATED_BYTES_PER_CHUNK = 2048;
+
+/**
+ * What a sync is about to embed
+ */
+export interface SyncPlan {
+  files: number;
+  totalBytes: number;
+  /** Largest files, biggest first */
+  largestFiles: Array<{ file: string; bytes: number }>;
+  /** Rough embedding input (~4 bytes per token) */
+  estimatedTokens: number;
+  /** Rough number of chunks to embed */
+  estimatedEmbeddings: number;
+}
+
+/**
+ * A limit a sync plan goes over
+ */
+export interface SyncLimitExceeded {
+  limit: keyof SyncLimits;
+  value: number;
+  /** Actual count, total, or size of the largest file */
+  actual: number;
+  /** Files over maxBytes */
+  files?: string[];
+}
+
+export interface SyncLimitCheck {
+  plan: SyncPlan;
+  limits: Required<SyncLimits>;
+  exceeded: SyncLimitExceeded[];
+}
+
+/**
+ * Cancelled because a sync went over its limits and was not confirmed
+ */
+export class SyncLimitError extends Error {
+  constructor(public readonly check: SyncLimitCheck) {
+    super(`Sync exceeds ${check.exceeded.map(e => e.limit).join(', ')}; not confirmed`);
+    this.name = 'SyncLimitError';
+  }
+}
+
+/**
+ * Fill unset limits with the defaults
+ */
+export function resolveSyncLimits(limits: SyncLimits = {}): Required<SyncLimits> {
+  return {
+    maxFiles: limits.maxFiles ?? DEFAULT_SYNC_LIMITS.maxFiles,
+    maxTotalBytes: limits.maxTotalBytes ?? DEFAULT_SYNC_LIMITS.maxTotalBytes,
+    maxBytes: limits.maxBytes ?? DEFAULT_SYNC_LIMITS.maxBytes
+  };
+}
+
+/**
+ * Measure the files a sync is about to embed (paths relative to repoRoot);
+ * files that cannot be stat'ed are left out
+ */
+export async function planSyncFiles(repoRoot: string, files: string[], largest: number = 5): Promise<SyncPlan> {
+  const sizes: Array<{ file: string; bytes: number }> = [];
+  const CONCURRENCY = 50;
+  for (let i = 0; i < files.length; i += CONCURRENCY) {
+    const batch = files.slice(i, i + CONCURRENCY);
+    const stats = await Promise.all(batch.map(file => fs.stat(path.join(repoRoot, file)).catch(() => null)));
+    for (let j = 0; j < batch.length; j++) {
+      if (stats[j]) sizes.push({ file: batch[j], bytes: stats[j]!.size });
+    }
+  }
+
+  let totalBytes = 0;
+  let estimatedTokens = 0;
+  let estimatedEmbeddings = 0;
+  for (const { bytes } of sizes) {
+    totalBytes += bytes;
+    estimatedTokens += Math.ceil(bytes / 4);
+    estimatedEmbeddings += Math.max(1, Math.ceil(bytes / ESTIMATED_BYTES_PER_CHUNK));
+  }
+
+  return {
+    files: sizes.length,
+    totalBytes,
+    largestFiles: [...sizes].sort((a, b) => b.bytes - a.bytes || a.file.localeCompare(b.file)).slice(0, largest),
+    estimatedTokens,
+    estimatedEmbeddings
+  };
+}
+
+/**
+ * Measure the files a sync is about to embed against its limits
+ */
+export async function checkSyncLimits(
+  repoRoot: string,
+  files: string[],
+  limits: SyncLimits = {}
+): Promise<SyncLimitCheck> {
+  const resolved = resolveSyncLimits(limits);
+  const plan = await planSyncFiles(repoRoot, files, Number.MAX_SAFE_INTEGER);
+  const exceeded: SyncLimitExceeded[] = [];
+
+  if (resolved.maxFiles > 0 && plan.files > resolved.maxFiles) {
+    exceeded.push({ limit: 'maxFiles', value: resolved.maxFiles, actual: plan.files });
+  }
+  if (resolved.maxTotalBytes > 0 && plan.totalBytes > resolved.maxTotalBytes) {
+    exceeded.push({ limit: 'maxTotalBytes', value: resolved.maxTotalBytes, actual: plan.totalBytes });
+  }
+  if (resolved.maxBytes > 0) {
+    const large = plan.largestFiles.filter(f => f.bytes > resolved.maxBytes);
+    if (large.length > 0) {
+      exceeded.push({ limit: 'maxBytes', value: resolved.maxBytes, actual: large[0].bytes, files: large.map(f => f.file) });
+    }
+  }
+
+  return { plan: { ...plan, largestFiles: plan.largestFiles.slice(0, 5) }, limits: resolved, exceeded };
+}
@@ -0,0 +1,122 @@
+/**
+ * Monorepo Packages
+ *
+ * Finds the package a file belongs to: the nearest directory above it with
+ * a package manifest (package.json, go.mod, Cargo.toml, ...). `cv sync`
+ * records it with each chunk, so `--package <dir>` can scope retrieval to
+ * a package and its nested packages, and citations can say which of
+ * several same-named files they mean.
+ */
+
+import * as fs from 'fs';
+import * as path from 'path';
+
+/** Files that make their directory a package */
+export const PACKAGE_MANIFESTS = [
+  'package.json',
+  'go.mod',
+  'Cargo.toml',
+  'pyproject.toml',
+  'setup.py',
+  'pom.xml',
+  'build.gradle',
+  'build.gradle.kts',
+  'composer.json',
+  'Gemfile',
+  'mix.exs',
+  'pubspec.yaml',
+  'Package.swift'
+];
+
+/** Package of files outside any other package */
+export const ROOT_PACKAGE = '.';
+
+/** Directories never searched for packages, or for the files of cv explain --no-index */
+export const SKIPPED_DIRS = new Set(['node_modules', '.git', '.cv', 'vendor', 'dist', 'build', 'target', '.venv', 'venv', '__pycache__']);
+
+/**
+ * Looks up the package of repo-relative files, caching each directory
+ */
+export class PackageResolver {
+  private readonly repoRoot: string;
+  private readonly packages = new Map<string, string>();
+
+  constructor(repoRoot: string) {
+    this.repoRoot = repoRoot;
+  }
+
+  /**
+   * Repo-relative directory of the file's package, ROOT_PACKAGE when no
+   * directory between it and the repository root has a manifest
+   */
+  packageOf(file: string): string {
+    return this.packageOfDir(path.posix.dirname(file.split(path.sep).join('/')));
+  }
+
+  private packageOfDir(dir: string): string {
+    if (dir === '.' || dir === '' || dir === '/') return ROOT_PACKAGE;
+
+    const cached = this.packages.get(dir);
+    if (cached !== undefined) return cached;
+
+    const found = hasManifest(path.join(this.repoRoot, dir)) ? dir : this.packageOfDir(path.posix.dirname(dir));
+    this.packages.set(dir, found);
+    return found;
+  }
+}
+
+/**
+ * Repo-relative directories of the packages at or under `dir`, sorted
+ */
+export function findPackages(repoRoot: string, dir: string = ROOT_PACKAGE): string[] {
+  const packages: string[] = [];
+  const walk = (relative: string) => {
+    const absolute = path.join(repoRoot, relative);
+    if (relative === ROOT_PACKAGE || hasManifest(absolute)) {
+      packages.push(relative);
+    }
+
+    let entries: fs.Dirent[];
+    try {
+      entries = fs.readdirSync(absolute, { withFileTypes: true });
+    } catch {
+      return;
+    }
+    for (const entry of entries) {
+      if (entry.isDirectory() && !SKIPPED_DIRS.has(entry.name)) {
+        walk(relative === ROOT_PACKAGE ? entry.name : `${relative}/${entry.name}`);
+      }
+    }
+  };
+  walk(dir);
+  return packages.sort();
+}
+
+/**
+ * The packages a `--package <dir>` scope covers: the package at `dir` and
+ * any nested in it. `dir` is relative to `cwd`. Throws if it is outside the
+ * repository, missing, or inside a package without being one.
+ */
+export function resolvePackageScope(repoRoot: string, dir: string, cwd: string = process.cwd()): string[] {
+  const absolute = path.resolve(cwd, dir);
+  const relative = path.relative(repoRoot, absolute);
+  if (relative.startsWith('..') || path.isAbsolute(relative)) {
+    throw new Error(`--package ${dir} is outside the repository`);
+  }
+  if (!fs.existsSync(absolute) || !fs.statSync(absolute).isDirectory()) {
+    throw new Error(`--package ${dir} is not a directory`);
+  }
+
+  const scope = relative === '' ? ROOT_PACKAGE : relative.split(path.sep).join('/');
+  const packages = findPackages(repoRoot, scope);
+  if (packages.length === 0) {
+    const owner = new PackageResolver(repoRoot).packageOf(`${scope}/file`);
+    throw new Error(`--package ${dir} has no package manifest (${PACKAGE_MANIFESTS.slice(0, 3).join(', ')}, ...) at or below it; ` +
+      `it belongs to ${owner === ROOT_PACKAGE ? 'the root package' : `the package in ${owner}`}`);
+  }
+  return packages;
+}
+
+function hasManifest(dir: string): boolean {
+  return PACKAGE_MANIFESTS.some(manifest => fs.existsSync(path.join(dir, manifest)));
+}
@@ -0,0 +1,113 @@
+/**
+ * Sync Pipeline
+ * Bounded channels and worker pools that let `cv sync` read and chunk files
+ * while earlier chunks are still being embedded
+ */
+
+import { mapWithConcurrency } from '@cv-git/shared';
+
+/** Files read and parsed at once during a full sync */
+export const DEFAULT_SYNC_CONCURRENCY = 10;
+
+/**
+ * Queue with a fixed capacity: send() waits while it is full, so a fast
+ * producer can't run ahead of a slow consumer. Items are received in the
+ * order they were sent.
+ */
+export class BoundedChannel<T> {
+  private items: T[] = [];
+  private closed = false;
+  private waitingSenders: Array<() => void> = [];
+  private waitingReceivers: Array<(result: IteratorResult<T>) => void> = [];
+  private readonly capacity: number;
+
+  constructor(capacity: number) {
+    this.capacity = Math.max(1, capacity);
+  }
+
+  /**
+   * Add an item, waiting for room if the channel is full
+   */
+  async send(item: T): Promise<void> {
+    if (this.closed) {
+      throw new Error('Cannot send on a closed channel');
+    }
+
+    const receiver = this.waitingReceivers.shift();
+    if (receiver) {
+      receiver({ value: item, done: false });
+      return;
+    }
+
+    while (this.items.length >= this.capacity) {
+      await new Promise<void>(resolve => this.waitingSenders.push(resolve));
+    }
+    this.items.push(item);
+  }
+
+  /**
+   * Take the next item; done once the channel is closed and drained
+   */
+  receive(): Promise<IteratorResult<T>> {
+    if (this.items.length > 0) {
+      const value = this.items.shift()!;
+      this.waitingSenders.shift()?.();
+      return Promise.resolve({ value, done: false });
+    }
+    if (this.closed) {
+      return Promise.resolve({ value: undefined, done: true });
+    }
+    return new Promise(resolve => this.waitingReceivers.push(resolve));
+  }
+
+  /**
+   * Stop accepting items. Items already queued can still be received.
+   */
+  close(): void {
+    this.closed = true;
+    for (const receiver of this.waitingReceivers.splice(0)) {
+      receiver({ value: undefined, done: true });
+    }
+  }
+
+  async *[Symbol.asyncIterator](): AsyncGenerator<T> {
+    while (true) {
+      const next = await this.receive();
+      if (next.done) return;
+      yield next.value;
+    }
+  }
+}
+
+/**
+ * Outcome of one worker call, tagged with the input's position
+ */
+export type WorkResult<T, R> =
+  | { index: number; item: T; value: R }
+  | { index: number; item: T; error: Error };
+
+/**
+ * Run fn over items with `concurrency` workers and stream the results, in
+ * completion order, through a channel holding at most `capacity` results.
+ * A failing item is reported as an error result instead of stopping the pool.
+ */
+export function runWorkers<T, R>(
+  items: T[],
+  concurrency: number,
+  fn: (item: T) => Promise<R>,
+  capacity: number = concurrency * 2
+): BoundedChannel<WorkResult<T, R>> {
+  const channel = new BoundedChannel<WorkResult<T, R>>(capacity);
+
+  mapWithConcurrency(items, concurrency, async (item, index) => {
+    let result: WorkResult<T, R>;
+    try {
+      result = { index, item, value: await fn(item) };
+    } catch (error: any) {
+      result = { index, item, error: error instanceof Error ? error : new Error(String(error)) };
+    }
+    await channel.send(result);
+  }).finally(() => channel.close());
+
+  return channel;
+}
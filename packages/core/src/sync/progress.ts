@@ -0,0 +1,75 @@
+/**
+ * Embedding Progress
+ * Renders a progress bar with throughput while chunks are embedded
+ */
+
+import { formatDuration } from '@cv-git/shared';
+
+const BAR_WIDTH = 30;
+
+export interface EmbeddingProgress {
+  /** Pass as VectorManager.embedBatch's onProgress */
+  update: (embedded: number, total: number) => void;
+  /** Print a line without breaking the bar */
+  log: (message: string) => void;
+  /** Finish the bar and print a final rate */
+  done: () => void;
+}
+
+/**
+ * Create a progress reporter. Redraws in place on a TTY,
+ * otherwise logs at every 10% so CI logs stay readable.
+ */
+export function createEmbeddingProgress(label: string = 'Embedding'): EmbeddingProgress {
+  const startedAt = Date.now();
+  const interactive = !!process.stdout.isTTY;
+  let lastEmbedded = 0;
+  let lastTotal = 0;
+  let lastLoggedDecile = -1;
+  let drawn = false;
+
+  const format = (embedded: number, total: number): string => {
+    const ratio = total > 0 ? embedded / total : 1;
+    const filled = Math.round(ratio * BAR_WIDTH);
+    const elapsedSec = Math.max((Date.now() - startedAt) / 1000, 0.001);
+    const rate = embedded / elapsedSec;
+    return `${label} [${'█'.repeat(filled)}${'░'.repeat(BAR_WIDTH - filled)}] ` +
+      `${embedded}/${total} chunks (${rate.toFixed(1)} chunks/s)`;
+  };
+
+  return {
+    update(embedded: number, total: number) {
+      lastEmbedded = embedded;
+      lastTotal = total;
+
+      if (interactive) {
+        process.stdout.write(`\r${format(embedded, total)}`);
+        drawn = true;
+        return;
+      }
+
+      const decile = total > 0 ? Math.floor((embedded / total) * 10) : 10;
+      if (decile > lastLoggedDecile) {
+        lastLoggedDecile = decile;
+        console.log(format(embedded, total));
+      }
+    },
+
+    log(message: string) {
+      if (!drawn) {
+        console.log(message);
+        return;
+      }
+      process.stdout.write(`\r\x1b[K${message}\n${format(lastEmbedded, lastTotal)}`);
+    },
+
+    done() {
+      if (drawn) {
+        process.stdout.write('\n');
+      }
+      if (lastTotal > 0) {
+        console.log(`Embedded ${lastEmbedded} chunks in ${formatDuration(Date.now() - startedAt)}`);
+      }
+    }
+  };
+}
@@ -0,0 +1,108 @@
+/**
+ * Watch Batching
+ *
+ * Collects file events for `cv sync --watch` into batches. A batch is
+ * flushed once the tree has been quiet for the debounce interval, so an
+ * editor's save storm (temp file, rename, rewrite, chmod) becomes one sync;
+ * a steady stream of events is still flushed after the maximum wait.
+ */
+
+import * as path from 'path';
+
+export type WatchEvent = 'add' | 'change' | 'unlink';
+
+/** Quiet time after the last event before a batch is synced */
+export const DEFAULT_WATCH_DEBOUNCE_MS = 500;
+
+/** Longest a batch waits for the tree to go quiet */
+export const DEFAULT_WATCH_MAX_WAIT_MS = 10000;
+
+export interface WatchBatch {
+  added: string[];
+  modified: string[];
+  deleted: string[];
+}
+
+/** Swap, backup and atomic-save files editors write next to the real one */
+const EDITOR_TEMP_PATTERNS = [
+  /\.sw[a-p]$/,             // vim swap files
+  /~$/,                     // backups (vim, emacs, gedit)
+  /^\.#/,                   // emacs lock files
+  /^#.*#$/,                 // emacs autosave
+  /^4913$/,                 // vim's write-permission probe
+  /\.(tmp|temp|bak|orig)$/i,
+  /___jb_(tmp|old)___$/,    // JetBrains safe write
+  /\.crswap$/,              // Chrome/VS Code web
+  /^\.~lock\./              // LibreOffice
+];
+
+/**
+ * Whether a path is an editor's temporary or backup file rather than source
+ */
+export function isEditorTempFile(file: string): boolean {
+  const name = path.basename(file);
+  return EDITOR_TEMP_PATTERNS.some(pattern => pattern.test(name));
+}
+
+/**
+ * Pending file events, coalesced per file
+ */
+export class ChangeBatcher {
+  private pending = new Map<string, WatchEvent>();
+  private firstEventAt = 0;
+  private lastEventAt = 0;
+
+  constructor(
+    private debounceMs: number = DEFAULT_WATCH_DEBOUNCE_MS,
+    private maxWaitMs: number = DEFAULT_WATCH_MAX_WAIT_MS
+  ) {}
+
+  get size(): number {
+    return this.pending.size;
+  }
+
+  /**
+   * Record an event. A file created and deleted within one batch drops out;
+   * deleted and recreated (an atomic save) counts as modified.
+   */
+  record(event: WatchEvent, file: string, now: number = Date.now()): void {
+    if (this.pending.size === 0) {
+      this.firstEventAt = now;
+    }
+    this.lastEventAt = now;
+
+    const existing = this.pending.get(file);
+    if (existing === 'add' && event === 'unlink') {
+      this.pending.delete(file);
+    } else if (existing === 'unlink' && event !== 'unlink') {
+      this.pending.set(file, 'change');
+    } else if (existing === 'add' && event === 'change') {
+      // Still new to the index
+    } else {
+      this.pending.set(file, event);
+    }
+  }
+
+  /**
+   * Milliseconds until the batch is due (0 when it is), or null with nothing pending
+   */
+  dueIn(now: number = Date.now()): number | null {
+    if (this.pending.size === 0) return null;
+    const quietAt = this.lastEventAt + this.debounceMs;
+    const deadline = this.firstEventAt + this.maxWaitMs;
+    return Math.max(0, Math.min(quietAt, deadline) - now);
+  }
+
+  /**
+   * Take the pending events as a batch and start a new one
+   */
+  take(): WatchBatch {
+    const batch: WatchBatch = { added: [], modified: [], deleted: [] };
+    for (const [file, event] of [...this.pending.entries()].sort(([a], [b]) => a.localeCompare(b))) {
+      const list = event === 'add' ? batch.added : event === 'change' ? batch.modified : batch.deleted;
+      list.push(file);
+    }
+    this.pending.clear();
+    return batch;
+  }
+}
@@ -0,0 +1,360 @@
+/**
+ * Usage Tracking
+ *
+ * Records the tokens of every completion and embedding request, estimates
+ * their cost from a pricing table, and appends them to a per-repository log
+ * that `cv usage` reports on. Like the proxy settings, the current session
+ * is process-wide: the CLI starts it before a command runs and flushes it
+ * when the process exits, so clients record usage without it being threaded
+ * through every constructor.
+ *
+ * Storage structure:
+ * .cv/usage.jsonl   # One request per line: time, command, provider, model, tokens, cost
+ */
+
+import * as fs from 'fs';
+import * as path from 'path';
+import { getCVDir } from '@cv-git/shared';
+import { estimateTokens } from '../vector/embedding-batches.js';
+
+const USAGE_FILE = 'usage.jsonl';
+
+export type UsageKind = 'completion' | 'embedding';
+
+/**
+ * Price of a model in USD per million tokens
+ */
+export interface ModelPricing {
+  input: number;
+  output: number;
+}
+
+/**
+ * One API request
+ */
+export interface UsageRecord {
+  timestamp: string;
+  command: string;
+  provider: string;
+  model: string;
+  kind: UsageKind;
+  inputTokens: number;
+  outputTokens: number;
+  /** Whether the token counts were estimated from text length rather than reported by the API */
+  estimated: boolean;
+  /** Estimated cost in USD, or null if the model's price is unknown */
+  cost: number | null;
+}
+
+export interface UsageEntry {
+  provider: string;
+  model: string;
+  kind: UsageKind;
+  inputTokens: number;
+  outputTokens?: number;
+  estimated?: boolean;
+}
+
+/**
+ * Totals for one group of records
+ */
+export interface UsageTotals {
+  key: string;
+  requests: number;
+  inputTokens: number;
+  outputTokens: number;
+  /** Sum of known costs */
+  cost: number;
+  /** Requests whose model has no known price */
+  unpriced: number;
+}
+
+/**
+ * List prices per million tokens, matched against the model name with any
+ * vendor prefix (openai/, anthropic/, models/) removed. Longer prefixes are
+ * checked first, so dated snapshots like claude-3-5-haiku-20241022 match.
+ */
+export const MODEL_PRICING: Record<string, ModelPricing> = {
+  // Anthropic
+  'claude-opus-4': { input: 15, output: 75 },
+  'claude-sonnet-4': { input: 3, output: 15 },
+  'claude-3-7-sonnet': { input: 3, output: 15 },
+  'claude-3-5-sonnet': { input: 3, output: 15 },
+  'claude-3.7-sonnet': { input: 3, output: 15 },
+  'claude-3.5-sonnet': { input: 3, output: 15 },
+  'claude-haiku-4-5': { input: 1, output: 5 },
+  'claude-3-5-haiku': { input: 0.8, output: 4 },
+  'claude-3-opus': { input: 15, output: 75 },
+  'claude-3-haiku': { input: 0.25, output: 1.25 },
+  // OpenAI
+  'gpt-4o-mini': { input: 0.15, output: 0.6 },
+  'gpt-4o': { input: 2.5, output: 10 },
+  'gpt-4.1-mini': { input: 0.4, output: 1.6 },
+  'gpt-4.1': { input: 2, output: 8 },
+  'gpt-4-turbo': { input: 10, output: 30 },
+  // Google
+  'gemini-2.5-pro': { input: 1.25, output: 10 },
+  'gemini-2.5-flash': { input: 0.3, output: 2.5 },
+  'gemini-2.0-flash': { input: 0.1, output: 0.4 },
+  'gemini-1.5-pro': { input: 1.25, output: 5 },
+  'gemini-1.5-flash': { input: 0.075, output: 0.3 },
+  // Embeddings
+  'text-embedding-3-small': { input: 0.02, output: 0 },
+  'text-embedding-3-large': { input: 0.13, output: 0 },
+  'text-embedding-ada-002': { input: 0.1, output: 0 },
+  'gemini-embedding-001': { input: 0.15, output: 0 },
+  'text-embedding-004': { input: 0, output: 0 },
+  'embed-english-v3.0': { input: 0.1, output: 0 },
+  'embed-multilingual-v3.0': { input: 0.1, output: 0 },
+  'voyage-code-3': { input: 0.18, output: 0 },
+  'voyage-3-lite': { input: 0.02, output: 0 },
+  'voyage-3': { input: 0.06, output: 0 }
+};
+
+/** Providers that run locally and cost nothing per token */
+const FREE_PROVIDERS = new Set(['ollama', 'lmstudio']);
+
+/** The current command and where its usage is logged */
+let session: { command: string; logFile: string | null } | null = null;
+
+/** Records not yet written to the log */
+let pending: UsageRecord[] = [];
+
+/** Every record of this process, for the footer */
+let recorded: UsageRecord[] = [];
+
+/**
+ * Path of a repository's usage log
+ */
+export function getUsageLogPath(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), USAGE_FILE);
+}
+
+/**
+ * Price of a model, or null if it isn't in the pricing table
+ */
+export function getModelPricing(provider: string, model: string): ModelPricing | null {
+  if (FREE_PROVIDERS.has(provider)) {
+    return { input: 0, output: 0 };
+  }
+
+  const name = model.toLowerCase().replace(/^models\//, '').replace(/^[\w.-]+\//, '');
+  const prefix = Object.keys(MODEL_PRICING)
+    .sort((a, b) => b.length - a.length)
+    .find(known => name === known || name.startsWith(`${known}-`) || name.startsWith(`${known}:`));
+  return prefix ? MODEL_PRICING[prefix] : null;
+}
+
+/**
+ * Estimated cost in USD of a request, or null if the model's price is unknown
+ */
+export function estimateUsageCost(provider: string, model: string, inputTokens: number, outputTokens: number = 0): number | null {
+  const pricing = getModelPricing(provider, model);
+  if (!pricing) return null;
+  return (inputTokens * pricing.input + outputTokens * pricing.output) / 1_000_000;
+}
+
+/**
+ * Start recording usage for a command. With a null logFile, usage is only
+ * kept in memory (e.g. outside a repository).
+ */
+export function startUsageSession(command: string, logFile: string | null): void {
+  session = { command, logFile };
+  pending = [];
+  recorded = [];
+}
+
+/**
+ * Record one API request
+ */
+export function recordUsage(entry: UsageEntry): void {
+  const outputTokens = entry.outputTokens ?? 0;
+  const record: UsageRecord = {
+    timestamp: new Date().toISOString(),
+    command: session?.command ?? 'unknown',
+    provider: entry.provider,
+    model: entry.model,
+    kind: entry.kind,
+    inputTokens: entry.inputTokens,
+    outputTokens,
+    estimated: entry.estimated ?? false,
+    cost: estimateUsageCost(entry.provider, entry.model, entry.inputTokens, outputTokens)
+  };
+  pending.push(record);
+  recorded.push(record);
+}
+
+/**
+ * Record a completion, estimating whichever token count the API didn't report
+ */
+export function recordCompletionUsage(
+  provider: string,
+  model: string,
+  prompt: string,
+  response: string,
+  usage?: { inputTokens?: number | null; outputTokens?: number | null } | null
+): void {
+  const inputTokens = usage?.inputTokens ?? null;
+  const outputTokens = usage?.outputTokens ?? null;
+  recordUsage({
+    provider,
+    model,
+    kind: 'completion',
+    inputTokens: inputTokens ?? estimateTokens(prompt),
+    outputTokens: outputTokens ?? estimateTokens(response),
+    estimated: inputTokens === null || outputTokens === null
+  });
+}
+
+/**
+ * Record an embedding request, estimating tokens when the API didn't report them
+ */
+export function recordEmbeddingUsage(provider: string, model: string, texts: string[], tokens?: number | null): void {
+  if (texts.length === 0) return;
+  recordUsage({
+    provider,
+    model,
+    kind: 'embedding',
+    inputTokens: tokens ?? texts.reduce((sum, text) => sum + estimateTokens(text), 0),
+    estimated: tokens === undefined || tokens === null
+  });
+}
+
+/**
+ * Everything recorded since the session started
+ */
+export function getSessionUsage(): UsageRecord[] {
+  return [...recorded];
+}
+
+/**
+ * Token totals of the completions among some records (e.g. the requests one
+ * answer took); estimated if any count was
+ */
+export function totalCompletionTokens(records: UsageRecord[]): { input: number; output: number; estimated: boolean } {
+  const completions = records.filter(record => record.kind === 'completion');
+  return {
+    input: completions.reduce((sum, record) => sum + record.inputTokens, 0),
+    output: completions.reduce((sum, record) => sum + record.outputTokens, 0),
+    estimated: completions.some(record => record.estimated)
+  };
+}
+
+/**
+ * Append pending records to the session's log. Synchronous so it can run
+ * from a process 'exit' handler, after process.exit() was called.
+ */
+export function flushUsageLog(): void {
+  if (!session?.logFile || pending.length === 0) return;
+  try {
+    fs.mkdirSync(path.dirname(session.logFile), { recursive: true });
+    fs.appendFileSync(session.logFile, pending.map(record => JSON.stringify(record) + '\n').join(''), 'utf-8');
+  } catch {
+    // Usage logging never fails a command
+  }
+  pending = [];
+}
+
+/**
+ * Read a repository's usage log, optionally from a date on
+ */
+export async function readUsageLog(repoRoot: string, since?: Date): Promise<UsageRecord[]> {
+  let content: string;
+  try {
+    content = await fs.promises.readFile(getUsageLogPath(repoRoot), 'utf-8');
+  } catch {
+    return [];
+  }
+
+  const records: UsageRecord[] = [];
+  for (const line of content.split('\n')) {
+    if (!line.trim()) continue;
+    try {
+      const record = JSON.parse(line) as UsageRecord;
+      if (!since || new Date(record.timestamp) >= since) {
+        records.push(record);
+      }
+    } catch {
+      // Skip a line cut off by a crash
+    }
+  }
+  return records;
+}
+
+/**
+ * Parse --since: a relative age (30m, 12h, 7d, 2w) or a date
+ */
+export function parseUsageSince(since: string, now: Date = new Date()): Date {
+  const relative = since.trim().match(/^(\d+)\s*([mhdw])$/i);
+  if (relative) {
+    const unit = { m: 60_000, h: 3_600_000, d: 86_400_000, w: 604_800_000 }[relative[2].toLowerCase() as 'm' | 'h' | 'd' | 'w'];
+    return new Date(now.getTime() - parseInt(relative[1], 10) * unit);
+  }
+
+  const date = new Date(since);
+  if (isNaN(date.getTime())) {
+    throw new Error(`Invalid --since: ${since} (expected e.g. 7d, 12h, or 2025-01-31)`);
+  }
+  return date;
+}
+
+/**
+ * Totals per command, local day, or model, largest cost first (days newest first)
+ */
+export function summarizeUsage(records: UsageRecord[], by: 'command' | 'day' | 'model'): UsageTotals[] {
+  const groups = new Map<string, UsageTotals>();
+  for (const record of records) {
+    const key = by === 'command' ? record.command
+      : by === 'model' ? `${record.provider}/${record.model}`
+      : formatLocalDay(new Date(record.timestamp));
+
+    const totals = groups.get(key) ?? { key, requests: 0, inputTokens: 0, outputTokens: 0, cost: 0, unpriced: 0 };
+    totals.requests++;
+    totals.inputTokens += record.inputTokens;
+    totals.outputTokens += record.outputTokens;
+    if (record.cost === null) {
+      totals.unpriced++;
+    } else {
+      totals.cost += record.cost;
+    }
+    groups.set(key, totals);
+  }
+
+  const totals = Array.from(groups.values());
+  return by === 'day'
+    ? totals.sort((a, b) => b.key.localeCompare(a.key))
+    : totals.sort((a, b) => b.cost - a.cost || b.inputTokens + b.outputTokens - (a.inputTokens + a.outputTokens) || a.key.localeCompare(b.key));
+}
+
+function formatLocalDay(date: Date): string {
+  const pad = (n: number) => String(n).padStart(2, '0');
+  return `${date.getFullYear()}-${pad(date.getMonth() + 1)}-${pad(date.getDate())}`;
+}
+
+/**
+ * Format a cost in USD with enough precision for small amounts
+ */
+export function formatUsageCost(cost: number): string {
+  if (cost === 0) return '$0';
+  if (cost < 0.01) return `$${cost.toFixed(4).replace(/0+$/, '')}`;
+  return `$${cost.toFixed(2)}`;
+}
+
+/**
+ * One-line footer for a command's usage, e.g. "~$0.003, 1,240 tokens"
+ * (null if nothing was recorded)
+ */
+export function formatUsageFooter(records: UsageRecord[]): string | null {
+  if (records.length === 0) return null;
+
+  const tokens = records.reduce((sum, r) => sum + r.inputTokens + r.outputTokens, 0);
+  const priced = records.filter(r => r.cost !== null);
+  const cost = priced.reduce((sum, r) => sum + r.cost!, 0);
+
+  const parts: string[] = [];
+  if (priced.length > 0) {
+    parts.push(`~${formatUsageCost(cost)}${priced.length < records.length ? '+' : ''}`);
+  }
+  parts.push(`${tokens.toLocaleString('en-US')} tokens`);
+  return parts.join(', ');
+}
@@ -0,0 +1,103 @@
+/**
+ * Chunk Token Limits
+ * Keeps every chunk sent for embedding under the model's input limit.
+ * Oversized chunks (typically generated or minified files) are split on line
+ * boundaries; lines that are too long on their own are skipped and reported
+ * rather than failing the whole sync.
+ */
+
+import { CodeChunk } from '@cv-git/shared';
+import { estimateTokens } from './embedding-batches.js';
+import { createChunkId } from '../parser/chunking.js';
+import type { IndexWarning } from './index-metadata.js';
+
+/**
+ * Share of the model limit chunks are fitted to. Token counts are estimated
+ * (~4 characters per token), and dense code can run over the estimate.
+ */
+export const TOKEN_LIMIT_HEADROOM = 0.9;
+
+export interface FittedChunks {
+  chunks: CodeChunk[];
+  /** Line ranges left out because even a single line exceeds the limit */
+  skipped: IndexWarning[];
+}
+
+/**
+ * Split chunks whose embedding text would exceed the model's token limit.
+ * `prepare` builds the text actually embedded (chunk plus context header).
+ */
+export function fitChunksToTokenLimit(
+  chunks: CodeChunk[],
+  maxTokens: number,
+  prepare: (chunk: CodeChunk) => string
+): FittedChunks {
+  const budget = Math.floor(maxTokens * TOKEN_LIMIT_HEADROOM);
+  const fitted: CodeChunk[] = [];
+  const skipped: IndexWarning[] = [];
+
+  for (const chunk of chunks) {
+    const tokens = estimateTokens(prepare(chunk));
+    if (tokens <= budget) {
+      fitted.push(chunk);
+      continue;
+    }
+
+    const overhead = estimateTokens(prepare({ ...chunk, text: '' }));
+    const lines = chunk.text.split('\n');
+    let start = 0;
+    let current: string[] = [];
+    let currentTokens = overhead;
+    let skipFrom = -1;
+
+    const emit = (end: number) => {
+      if (current.length === 0) return;
+      const startLine = chunk.startLine + start;
+      const endLine = chunk.startLine + end - 1;
+      const text = current.join('\n');
+      fitted.push({ ...chunk, id: createChunkId(chunk.file, startLine, text), startLine, endLine, text });
+      current = [];
+      currentTokens = overhead;
+    };
+    const endSkip = (end: number) => {
+      if (skipFrom < 0) return;
+      skipped.push(oversizedWarning(chunk, chunk.startLine + skipFrom, chunk.startLine + end - 1, lines.slice(skipFrom, end).join('\n'), maxTokens));
+      skipFrom = -1;
+    };
+
+    for (let i = 0; i < lines.length; i++) {
+      const lineTokens = estimateTokens(lines[i] + '\n');
+      if (overhead + lineTokens > budget) {
+        emit(i);
+        if (skipFrom < 0) skipFrom = i;
+        start = i + 1;
+        continue;
+      }
+      endSkip(i);
+
+      // Summing per-line estimates slightly overcounts, which errs on the safe side
+      if (current.length > 0 && currentTokens + lineTokens > budget) {
+        emit(i);
+        start = i;
+      }
+      current.push(lines[i]);
+      currentTokens += lineTokens;
+    }
+    emit(lines.length);
+    endSkip(lines.length);
+  }
+
+  return { chunks: fitted, skipped };
+}
+
+function oversizedWarning(chunk: CodeChunk, startLine: number, endLine: number, text: string, maxTokens: number): IndexWarning {
+  return {
+    type: 'oversized_chunk',
+    file: chunk.file,
+    startLine,
+    endLine,
+    symbolName: chunk.symbolName,
+    estimatedTokens: estimateTokens(text),
+    maxTokens
+  };
+}
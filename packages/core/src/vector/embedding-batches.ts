@@ -0,0 +1,57 @@
+/**
+ * Embedding Batches
+ * Groups texts into embeddings API requests bounded by item count and token budget
+ */
+
+/** Default texts per request (OpenAI accepts up to 2048) */
+export const DEFAULT_EMBEDDING_BATCH_SIZE = 100;
+
+/** Default estimated tokens per request (OpenAI allows 300k) */
+export const DEFAULT_EMBEDDING_BATCH_TOKENS = 100_000;
+
+/** Default number of requests in flight */
+export const DEFAULT_EMBEDDING_CONCURRENCY = 4;
+
+export interface EmbeddingBatchOptions {
+  /** Max texts per batch */
+  batchSize?: number;
+  /** Max estimated tokens per batch */
+  maxBatchTokens?: number;
+}
+
+/**
+ * Rough token estimate for source code (~4 characters per token)
+ */
+export function estimateTokens(text: string): number {
+  return Math.ceil(text.length / 4);
+}
+
+/**
+ * Split texts into contiguous batches.
+ * A single text larger than the token budget gets a batch of its own.
+ */
+export function planEmbeddingBatches(texts: string[], options: EmbeddingBatchOptions = {}): string[][] {
+  const batchSize = Math.max(1, options.batchSize ?? DEFAULT_EMBEDDING_BATCH_SIZE);
+  const maxTokens = Math.max(1, options.maxBatchTokens ?? DEFAULT_EMBEDDING_BATCH_TOKENS);
+
+  const batches: string[][] = [];
+  let current: string[] = [];
+  let currentTokens = 0;
+
+  for (const text of texts) {
+    const tokens = estimateTokens(text);
+    if (current.length > 0 && (current.length >= batchSize || currentTokens + tokens > maxTokens)) {
+      batches.push(current);
+      current = [];
+      currentTokens = 0;
+    }
+    current.push(text);
+    currentTokens += tokens;
+  }
+
+  if (current.length > 0) {
+    batches.push(current);
+  }
+
+  return batches;
+}
@@ -6,15 +6,29 @@
  *
  * Storage structure:
  * .cv/
- * └── embeddings/
- *     ├── index.json           # Maps embedding_id → metadata
- *     └── vectors/
- *         └── {embedding_id}.bin  # Binary float32 vector data
+ * └── cache/
+ *     └── embeddings/
+ *         ├── index.json           # Maps embedding_id → metadata
+ *         └── vectors/
+ *             └── {embedding_id}.bin  # Binary float32 vector data
+ *
+ * When the cache grows past its size limit, least recently used entries are evicted.
  */
 
 import { createHash } from 'crypto';
 import { promises as fs } from 'fs';
 import path from 'path';
+import { getCVDir } from '@cv-git/shared';
+
+/** Default cache size limit (1GB) */
+export const DEFAULT_EMBEDDING_CACHE_MAX_BYTES = 1024 * 1024 * 1024;
+
+/**
+ * Location of the embedding cache for a repository (.cv/cache/embeddings)
+ */
+export function getEmbeddingCacheDir(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), 'cache', 'embeddings');
+}
 
 export interface EmbeddingMetadata {
   id: string;
@@ -45,12 +59,17 @@ export interface CacheStats {
   cacheHits: number;
   cacheMisses: number;
   hitRate: number;
+  /** Lookups since this cache instance was opened (e.g. during one sync) */
+  sessionHits: number;
+  sessionMisses: number;
+  sessionHitRate: number;
+  maxSizeBytes: number;
   model: string;
   dimensions: number;
 }
 
 export interface EmbeddingCacheConfig {
-  cacheDir: string;           // Base directory for cache (e.g., .cv/embeddings)
+  cacheDir: string;           // Base directory for cache (e.g., .cv/cache/embeddings)
   model: string;              // Embedding model name
   dimensions: number;         // Vector dimensions
   maxSizeBytes?: number;      // Max cache size (optional, for eviction)
@@ -68,11 +87,12 @@ export class EmbeddingCache {
   private indexPath: string;
   private vectorsDir: string;
   private dirty = false;
+  private session = { hits: 0, misses: 0 };
 
   constructor(config: EmbeddingCacheConfig) {
     this.config = {
       ...config,
-      maxSizeBytes: config.maxSizeBytes ?? 1024 * 1024 * 1024  // 1GB default
+      maxSizeBytes: config.maxSizeBytes ?? DEFAULT_EMBEDDING_CACHE_MAX_BYTES
     };
     this.indexPath = path.join(config.cacheDir, 'index.json');
     this.vectorsDir = path.join(config.cacheDir, 'vectors');
@@ -105,10 +125,30 @@ export class EmbeddingCache {
    * Initialize the cache, creating directories and loading index
    */
   async initialize(): Promise<void> {
+    await this.migrateLegacyDir();
     await fs.mkdir(this.vectorsDir, { recursive: true });
     await this.loadIndex();
   }
 
+  /**
+   * Caches used to live in .cv/embeddings; move one into .cv/cache/embeddings
+   * so existing vectors are not recomputed
+   */
+  private async migrateLegacyDir(): Promise<void> {
+    const parent = path.dirname(this.config.cacheDir);
+    if (path.basename(parent) !== 'cache' || path.basename(this.config.cacheDir) !== 'embeddings') return;
+
+    const legacyDir = path.join(path.dirname(parent), 'embeddings');
+    if (await pathExists(this.config.cacheDir) || !await pathExists(path.join(legacyDir, 'index.json'))) return;
+
+    try {
+      await fs.mkdir(parent, { recursive: true });
+      await fs.rename(legacyDir, this.config.cacheDir);
+    } catch {
+      // Start a fresh cache if the old one cannot be moved
+    }
+  }
+
   /**
    * Load the index from disk
    */
@@ -178,6 +218,7 @@ export class EmbeddingCache {
 
     if (!metadata) {
       this.index!.stats.cacheMisses++;
+      this.session.misses++;
       this.dirty = true;
       return null;
     }
@@ -191,6 +232,7 @@ export class EmbeddingCache {
       metadata.accessCount++;
       metadata.lastAccessed = new Date().toISOString();
       this.index!.stats.cacheHits++;
+      this.session.hits++;
       this.dirty = true;
 
       return Array.from(vector);
@@ -198,7 +240,9 @@ export class EmbeddingCache {
       // Vector file missing, remove from index
       delete this.index!.entries[id];
       this.index!.stats.totalEntries--;
+      this.index!.stats.totalSizeBytes -= metadata.dimensions * 4;
       this.index!.stats.cacheMisses++;
+      this.session.misses++;
       this.dirty = true;
       return null;
     }
@@ -282,6 +326,7 @@ export class EmbeddingCache {
       ids.set(text, id);
     }
 
+    await this.evictLRU();
     await this.saveIndex();
     return ids;
   }
@@ -295,6 +340,7 @@ export class EmbeddingCache {
     const hits = this.index!.stats.cacheHits;
     const misses = this.index!.stats.cacheMisses;
     const total = hits + misses;
+    const sessionTotal = this.session.hits + this.session.misses;
 
     return {
       totalEntries: this.index!.stats.totalEntries,
@@ -302,6 +348,10 @@ export class EmbeddingCache {
       cacheHits: hits,
       cacheMisses: misses,
       hitRate: total > 0 ? hits / total : 0,
+      sessionHits: this.session.hits,
+      sessionMisses: this.session.misses,
+      sessionHitRate: sessionTotal > 0 ? this.session.hits / sessionTotal : 0,
+      maxSizeBytes: this.config.maxSizeBytes,
       model: this.config.model,
       dimensions: this.config.dimensions
     };
@@ -401,6 +451,7 @@ export class EmbeddingCache {
     }
 
     this.dirty = true;
+    await this.evictLRU();
     await this.saveIndex();
 
     return { imported, skipped };
@@ -456,20 +507,17 @@ export class EmbeddingCache {
 
     let evicted = 0;
     let currentSize = this.index!.stats.totalSizeBytes;
-    const bytesPerVector = this.config.dimensions * 4;  // float32
 
     for (const entry of entries) {
       if (currentSize <= target) break;
 
-      try {
-        await fs.unlink(path.join(this.vectorsDir, `${entry.id}.bin`));
-        delete this.index!.entries[entry.id];
-        this.index!.stats.totalEntries--;
-        currentSize -= bytesPerVector;
-        evicted++;
-      } catch {
-        // Ignore missing files
-      }
+      await fs.unlink(path.join(this.vectorsDir, `${entry.id}.bin`)).catch(() => {
+        // Already gone; drop the entry anyway
+      });
+      delete this.index!.entries[entry.id];
+      this.index!.stats.totalEntries--;
+      currentSize -= entry.dimensions * 4;  // float32
+      evicted++;
     }
 
     this.index!.stats.totalSizeBytes = currentSize;
@@ -487,6 +535,15 @@ export class EmbeddingCache {
   }
 }
 
+async function pathExists(filePath: string): Promise<boolean> {
+  try {
+    await fs.access(filePath);
+    return true;
+  } catch {
+    return false;
+  }
+}
+
 /**
  * Create an embedding cache instance
  */
@@ -0,0 +1,448 @@
+/**
+ * HNSW Index
+ *
+ * Hierarchical Navigable Small World graph (Malkov & Yashunin) for
+ * approximate nearest-neighbour search by cosine similarity. The local
+ * vector store searches large collections through it instead of scanning
+ * every vector. Vectors are compared by dot product, so callers insert and
+ * query unit-length vectors (see normalizeVector).
+ *
+ * The graph is serialized without its vectors (they are already in the
+ * index snapshot) and rebuilt from both on load.
+ */
+
+/** Links per node on the upper layers; layer 0 keeps twice as many */
+export const DEFAULT_HNSW_M = 16;
+
+/** Candidate list size while inserting */
+export const DEFAULT_HNSW_EF_CONSTRUCTION = 100;
+
+/** Candidate list size while searching */
+export const DEFAULT_HNSW_EF_SEARCH = 64;
+
+/** Collections with fewer points are searched exactly */
+export const DEFAULT_HNSW_MIN_POINTS = 5000;
+
+export interface HnswOptions {
+  /** Links per node (default: 16); higher improves recall at the cost of memory and build time */
+  m?: number;
+  /** Candidate list size while inserting (default: 100); higher builds slower with better recall */
+  efConstruction?: number;
+  /** Seed for level assignment, so the same points always build the same graph */
+  seed?: number;
+}
+
+/**
+ * HNSW settings of the local vector store (`vector.hnsw` in config)
+ */
+export interface HnswSettings extends HnswOptions {
+  /** Build and search HNSW graphs (default: true) */
+  enabled?: boolean;
+  /** Collections with fewer points are searched exactly (default: 5000) */
+  minPoints?: number;
+}
+
+/**
+ * Serialized graph
+ */
+export interface HnswGraph {
+  m: number;
+  efConstruction: number;
+  /** Point ID of each node */
+  ids: Array<string | number>;
+  /** Neighbour node indices per node, per layer (layer 0 first) */
+  links: number[][][];
+  entryPoint: number;
+  maxLevel: number;
+}
+
+export interface HnswMatch {
+  id: string | number;
+  score: number;
+}
+
+interface Candidate {
+  node: number;
+  score: number;
+}
+
+/**
+ * Scale a vector to unit length as a Float32Array (zero vectors stay zero)
+ */
+export function normalizeVector(vector: ArrayLike<number>): Float32Array {
+  const unit = new Float32Array(vector.length);
+  let norm = 0;
+  for (let i = 0; i < vector.length; i++) {
+    norm += vector[i] * vector[i];
+  }
+  norm = Math.sqrt(norm);
+  if (norm === 0) return unit;
+  for (let i = 0; i < vector.length; i++) {
+    unit[i] = vector[i] / norm;
+  }
+  return unit;
+}
+
+/**
+ * Dot product; the cosine similarity of two unit vectors
+ */
+export function dotProduct(a: Float32Array, b: Float32Array): number {
+  if (a.length !== b.length) return 0;
+  let dot = 0;
+  for (let i = 0; i < a.length; i++) {
+    dot += a[i] * b[i];
+  }
+  return dot;
+}
+
+/**
+ * Binary heap of candidates; `best` decides which score comes out first
+ */
+class CandidateHeap {
+  private items: Candidate[] = [];
+
+  constructor(private best: 'highest' | 'lowest') {}
+
+  get size(): number {
+    return this.items.length;
+  }
+
+  peek(): Candidate | undefined {
+    return this.items[0];
+  }
+
+  push(candidate: Candidate): void {
+    const items = this.items;
+    items.push(candidate);
+    let i = items.length - 1;
+    while (i > 0) {
+      const parent = (i - 1) >> 1;
+      if (!this.before(items[i], items[parent])) break;
+      [items[i], items[parent]] = [items[parent], items[i]];
+      i = parent;
+    }
+  }
+
+  pop(): Candidate | undefined {
+    const items = this.items;
+    const top = items[0];
+    const last = items.pop();
+    if (items.length > 0 && last) {
+      items[0] = last;
+      let i = 0;
+      for (;;) {
+        const left = 2 * i + 1;
+        const right = left + 1;
+        let next = i;
+        if (left < items.length && this.before(items[left], items[next])) next = left;
+        if (right < items.length && this.before(items[right], items[next])) next = right;
+        if (next === i) break;
+        [items[i], items[next]] = [items[next], items[i]];
+        i = next;
+      }
+    }
+    return top;
+  }
+
+  toArray(): Candidate[] {
+    return [...this.items];
+  }
+
+  private before(a: Candidate, b: Candidate): boolean {
+    return this.best === 'highest' ? a.score > b.score : a.score < b.score;
+  }
+}
+
+/**
+ * Deterministic PRNG (mulberry32) for level assignment
+ */
+function createRandom(seed: number): () => number {
+  let state = seed >>> 0;
+  return () => {
+    state = (state + 0x6D2B79F5) >>> 0;
+    let t = state;
+    t = Math.imul(t ^ (t >>> 15), t | 1);
+    t ^= t + Math.imul(t ^ (t >>> 7), t | 61);
+    return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+  };
+}
+
+export class HnswIndex {
+  private m: number;
+  private efConstruction: number;
+  private levelFactor: number;
+  private random: () => number;
+  private ids: Array<string | number> = [];
+  private vectors: Float32Array[] = [];
+  private links: number[][][] = [];
+  private nodeOf = new Map<string | number, number>();
+  private entryPoint = -1;
+  private maxLevel = -1;
+
+  constructor(options: HnswOptions = {}) {
+    this.m = Math.max(2, options.m ?? DEFAULT_HNSW_M);
+    this.efConstruction = Math.max(this.m, options.efConstruction ?? DEFAULT_HNSW_EF_CONSTRUCTION);
+    this.levelFactor = 1 / Math.log(this.m);
+    this.random = createRandom(options.seed ?? 42);
+  }
+
+  /**
+   * Rebuild an index from a serialized graph and the vectors of its points.
+   * Returns null if the graph is malformed or a point's vector is missing.
+   */
+  static fromGraph(graph: HnswGraph, vectorOf: (id: string | number) => Float32Array | undefined): HnswIndex | null {
+    if (!Array.isArray(graph?.ids) || !Array.isArray(graph.links) || graph.ids.length !== graph.links.length) {
+      return null;
+    }
+
+    const index = new HnswIndex({ m: graph.m, efConstruction: graph.efConstruction });
+    for (let node = 0; node < graph.ids.length; node++) {
+      const vector = vectorOf(graph.ids[node]);
+      if (!vector) return null;
+      index.ids.push(graph.ids[node]);
+      index.vectors.push(vector);
+      index.nodeOf.set(graph.ids[node], node);
+    }
+
+    const count = graph.ids.length;
+    const valid = graph.links.every(levels => Array.isArray(levels) && levels.length > 0 &&
+      levels.every(neighbors => Array.isArray(neighbors) && neighbors.every(n => Number.isInteger(n) && n >= 0 && n < count)));
+    if (!valid || (count > 0 && !(graph.entryPoint >= 0 && graph.entryPoint < count))) {
+      return null;
+    }
+
+    index.links = graph.links;
+    index.entryPoint = count > 0 ? graph.entryPoint : -1;
+    index.maxLevel = count > 0 ? graph.maxLevel : -1;
+    return index;
+  }
+
+  get size(): number {
+    return this.ids.length;
+  }
+
+  has(id: string | number): boolean {
+    return this.nodeOf.has(id);
+  }
+
+  /**
+   * Insert a unit vector. IDs already in the index must be removed first.
+   */
+  add(id: string | number, vector: Float32Array): void {
+    if (this.nodeOf.has(id)) {
+      throw new Error(`HNSW index already contains ${id}`);
+    }
+
+    const node = this.ids.length;
+    const level = Math.floor(-Math.log(1 - this.random()) * this.levelFactor);
+    this.ids.push(id);
+    this.vectors.push(vector);
+    this.links.push(Array.from({ length: level + 1 }, () => []));
+    this.nodeOf.set(id, node);
+
+    if (this.entryPoint < 0) {
+      this.entryPoint = node;
+      this.maxLevel = level;
+      return;
+    }
+
+    let entries = [this.entryPoint];
+    for (let layer = this.maxLevel; layer > level; layer--) {
+      entries = [this.searchLayer(vector, entries, 1, layer)[0].node];
+    }
+
+    for (let layer = Math.min(level, this.maxLevel); layer >= 0; layer--) {
+      const found = this.searchLayer(vector, entries, this.efConstruction, layer);
+      const neighbors = this.selectNeighbors(found, this.m);
+      this.links[node][layer] = neighbors.map(c => c.node);
+
+      for (const neighbor of neighbors) {
+        const list = this.links[neighbor.node][layer];
+        list.push(node);
+        if (list.length > this.maxLinks(layer)) {
+          this.links[neighbor.node][layer] = this.pruneLinks(neighbor.node, list, layer);
+        }
+      }
+      entries = found.map(c => c.node);
+    }
+
+    if (level > this.maxLevel) {
+      this.maxLevel = level;
+      this.entryPoint = node;
+    }
+  }
+
+  /**
+   * Remove points, relinking their neighbours so the graph stays connected
+   */
+  remove(ids: Iterable<string | number>): void {
+    const doomed = new Set<number>();
+    for (const id of ids) {
+      const node = this.nodeOf.get(id);
+      if (node !== undefined) doomed.add(node);
+    }
+    if (doomed.size === 0) return;
+
+    // Replace links into removed nodes with the removed nodes' own neighbours
+    for (let node = 0; node < this.ids.length; node++) {
+      if (doomed.has(node)) continue;
+      for (let layer = 0; layer < this.links[node].length; layer++) {
+        const list = this.links[node][layer];
+        if (!list.some(n => doomed.has(n))) continue;
+
+        const candidates = new Set<number>();
+        for (const n of list) {
+          if (!doomed.has(n)) {
+            candidates.add(n);
+            continue;
+          }
+          for (const m of this.links[n][layer] ?? []) {
+            if (m !== node && !doomed.has(m)) candidates.add(m);
+          }
+        }
+        this.links[node][layer] = this.pruneLinks(node, [...candidates], layer);
+      }
+    }
+
+    // Compact node numbers
+    const renumbered = new Map<number, number>();
+    const keptIds: Array<string | number> = [];
+    const vectors: Float32Array[] = [];
+    const links: number[][][] = [];
+    for (let node = 0; node < this.ids.length; node++) {
+      if (doomed.has(node)) continue;
+      renumbered.set(node, keptIds.length);
+      keptIds.push(this.ids[node]);
+      vectors.push(this.vectors[node]);
+      links.push(this.links[node]);
+    }
+    for (const levels of links) {
+      for (let layer = 0; layer < levels.length; layer++) {
+        levels[layer] = levels[layer].map(n => renumbered.get(n)!);
+      }
+    }
+
+    this.ids = keptIds;
+    this.vectors = vectors;
+    this.links = links;
+    this.nodeOf = new Map(keptIds.map((id, node) => [id, node]));
+
+    if (doomed.has(this.entryPoint) || keptIds.length === 0) {
+      // The highest remaining node becomes the entry point
+      this.entryPoint = -1;
+      this.maxLevel = -1;
+      for (let node = 0; node < links.length; node++) {
+        if (links[node].length - 1 > this.maxLevel) {
+          this.maxLevel = links[node].length - 1;
+          this.entryPoint = node;
+        }
+      }
+    } else {
+      this.entryPoint = renumbered.get(this.entryPoint)!;
+    }
+  }
+
+  /**
+   * The k points most similar to a unit query vector, best first.
+   * `ef` (at least k) trades speed for recall.
+   */
+  search(query: Float32Array, k: number, ef: number = DEFAULT_HNSW_EF_SEARCH): HnswMatch[] {
+    if (this.entryPoint < 0 || k <= 0) return [];
+
+    let entries = [this.entryPoint];
+    for (let layer = this.maxLevel; layer > 0; layer--) {
+      entries = [this.searchLayer(query, entries, 1, layer)[0].node];
+    }
+
+    return this.searchLayer(query, entries, Math.max(ef, k), 0)
+      .slice(0, k)
+      .map(c => ({ id: this.ids[c.node], score: c.score }));
+  }
+
+  /**
+   * Serialize the graph (without vectors)
+   */
+  toGraph(): HnswGraph {
+    return {
+      m: this.m,
+      efConstruction: this.efConstruction,
+      ids: this.ids,
+      links: this.links,
+      entryPoint: this.entryPoint,
+      maxLevel: this.maxLevel
+    };
+  }
+
+  private maxLinks(layer: number): number {
+    return layer === 0 ? this.m * 2 : this.m;
+  }
+
+  /**
+   * Best-first search of one layer; returns up to ef nodes, best first
+   */
+  private searchLayer(query: Float32Array, entries: number[], ef: number, layer: number): Candidate[] {
+    const visited = new Set<number>(entries);
+    const candidates = new CandidateHeap('highest');
+    const results = new CandidateHeap('lowest');
+
+    for (const node of entries) {
+      const candidate = { node, score: dotProduct(query, this.vectors[node]) };
+      candidates.push(candidate);
+      results.push(candidate);
+    }
+    while (results.size > ef) results.pop();
+
+    while (candidates.size > 0) {
+      const current = candidates.pop()!;
+      if (results.size >= ef && current.score < results.peek()!.score) break;
+
+      for (const neighbor of this.links[current.node][layer] ?? []) {
+        if (visited.has(neighbor)) continue;
+        visited.add(neighbor);
+
+        const score = dotProduct(query, this.vectors[neighbor]);
+        if (results.size < ef || score > results.peek()!.score) {
+          candidates.push({ node: neighbor, score });
+          results.push({ node: neighbor, score });
+          if (results.size > ef) results.pop();
+        }
+      }
+    }
+
+    return results.toArray().sort((a, b) => b.score - a.score);
+  }
+
+  /**
+   * Neighbour selection heuristic: prefer candidates that are closer to the
+   * new node than to any neighbour already chosen, so links point in
+   * different directions; fill up with the closest remaining ones.
+   */
+  private selectNeighbors(candidates: Candidate[], m: number): Candidate[] {
+    const selected: Candidate[] = [];
+    const skipped: Candidate[] = [];
+
+    for (const candidate of candidates) {
+      if (selected.length >= m) break;
+      const diverse = selected.every(chosen =>
+        dotProduct(this.vectors[candidate.node], this.vectors[chosen.node]) < candidate.score
+      );
+      (diverse ? selected : skipped).push(candidate);
+    }
+
+    for (const candidate of skipped) {
+      if (selected.length >= m) break;
+      selected.push(candidate);
+    }
+    return selected;
+  }
+
+  /**
+   * Trim a node's links on one layer back to the layer's limit
+   */
+  private pruneLinks(node: number, neighbors: number[], layer: number): number[] {
+    const scored = neighbors
+      .map(n => ({ node: n, score: dotProduct(this.vectors[node], this.vectors[n]) }))
+      .sort((a, b) => b.score - a.score);
+    return this.selectNeighbors(scored, this.maxLinks(layer)).map(c => c.node);
+  }
+}
@@ -0,0 +1,286 @@
+/**
+ * Index Archive
+ *
+ * Packs the persisted index (.cv/index and .cv/vector_index.json) into one
+ * portable file, so CI can build the index once and developers import it
+ * instead of each re-embedding the repository, for
+ * `cv index export/import`.
+ *
+ * The archive is gzipped JSON lines: a header with the embedding
+ * fingerprint, the commit the index was built at and where it was built,
+ * then one line per point and one per HNSW graph. Collection names carry
+ * the exporting repo's ID, and are renamed to the importing repo's.
+ */
+
+import { createReadStream, createWriteStream, promises as fs } from 'fs';
+import * as path from 'path';
+import * as readline from 'readline';
+import { Readable, pipeline } from 'stream';
+import { pipeline as pipelineAsync } from 'stream/promises';
+import { createGunzip, createGzip } from 'zlib';
+import { VectorError } from '@cv-git/shared';
+import {
+  IndexSnapshotManifest,
+  IndexSnapshotPoint,
+  getIndexDir,
+  readIndexSnapshotManifest,
+  readIndexSnapshotCollection,
+  readIndexHnswGraph,
+  writeIndexSnapshot,
+  writeIndexHnswGraph
+} from './index-store.js';
+import {
+  EmbeddingIdentity,
+  IndexProvenance,
+  IndexWorktree,
+  VectorIndexMetadata,
+  readIndexMetadata,
+  replaceIndexMetadata
+} from './index-metadata.js';
+import { HnswGraph } from './hnsw.js';
+
+export const INDEX_ARCHIVE_FORMAT = 'cv-index-archive';
+export const INDEX_ARCHIVE_VERSION = 1;
+
+/**
+ * First line of an archive
+ */
+export interface IndexArchiveHeader {
+  format: typeof INDEX_ARCHIVE_FORMAT;
+  version: number;
+  fingerprint: EmbeddingIdentity;
+  /** Commit the index was built at */
+  sourceCommit?: string;
+  /** Repo ID prefixing the collection names */
+  sourceRepoId?: string;
+  /** Repository root the index was built in */
+  sourcePath: string;
+  /** Point count per collection */
+  collections: Record<string, number>;
+  /** Index metadata at export time (warnings, languages, file filter, ...) */
+  metadata: VectorIndexMetadata | null;
+  exportedAt: string;
+}
+
+/**
+ * An archive read into memory
+ */
+export interface IndexArchive {
+  header: IndexArchiveHeader;
+  collections: Map<string, IndexSnapshotPoint[]>;
+  graphs: Map<string, HnswGraph>;
+}
+
+export interface IndexImportOptions {
+  /** Repo ID of the importing repository, to rename the collections to */
+  repoId?: string;
+  /** Worktree the imported index now belongs to */
+  worktree?: IndexWorktree;
+}
+
+/**
+ * Write the persisted index of a repository to an archive
+ */
+export async function exportIndexArchive(
+  repoRoot: string,
+  file: string,
+  options: { repoId?: string } = {}
+): Promise<IndexArchiveHeader> {
+  const indexDir = getIndexDir(repoRoot);
+  const manifest = await readIndexSnapshotManifest(indexDir);
+  if (!manifest) {
+    throw new VectorError(`No persisted index in ${indexDir}. Run \`cv sync\` first.`);
+  }
+  const metadata = await readIndexMetadata(repoRoot);
+
+  const header: IndexArchiveHeader = {
+    format: INDEX_ARCHIVE_FORMAT,
+    version: INDEX_ARCHIVE_VERSION,
+    fingerprint: manifest.fingerprint,
+    sourceCommit: manifest.lastIndexedCommit || metadata?.lastIndexedCommit,
+    sourceRepoId: options.repoId,
+    sourcePath: metadata?.worktree?.path || path.resolve(repoRoot),
+    collections: manifest.collections,
+    metadata,
+    exportedAt: new Date().toISOString()
+  };
+
+  // Stream line by line: a large index does not fit in one string
+  async function* archiveLines(): AsyncGenerator<string> {
+    yield JSON.stringify(header) + '\n';
+    for (const collection of Object.keys(manifest!.collections)) {
+      for (const point of await readIndexSnapshotCollection(indexDir, collection)) {
+        yield JSON.stringify({ collection, point }) + '\n';
+      }
+      const graph = await readIndexHnswGraph(indexDir, collection);
+      if (graph) {
+        yield JSON.stringify({ collection, hnsw: graph }) + '\n';
+      }
+    }
+  }
+
+  await fs.mkdir(path.dirname(path.resolve(file)), { recursive: true });
+  await pipelineAsync(Readable.from(archiveLines()), createGzip(), createWriteStream(file));
+  return header;
+}
+
+/**
+ * Read an archive (gzipped or plain JSON lines), streaming it line by line
+ */
+export async function readIndexArchive(file: string): Promise<IndexArchive> {
+  const handle = await fs.open(file, 'r');
+  let gzipped: boolean;
+  try {
+    const magic = Buffer.alloc(2);
+    const { bytesRead } = await handle.read(magic, 0, 2, 0);
+    // gzip magic number
+    gzipped = bytesRead === 2 && magic[0] === 0x1f && magic[1] === 0x8b;
+  } finally {
+    await handle.close();
+  }
+
+  const source = createReadStream(file);
+  const input: Readable = gzipped ? pipeline(source, createGunzip(), () => {}) : source;
+  const lines = readline.createInterface({ input, crlfDelay: Infinity });
+
+  let header: IndexArchiveHeader | undefined;
+  const collections = new Map<string, IndexSnapshotPoint[]>();
+  const graphs = new Map<string, HnswGraph>();
+  let lineNumber = 0;
+  try {
+    for await (const line of lines) {
+      lineNumber++;
+      if (!header) {
+        header = parseIndexArchiveHeader(line, file);
+        for (const name of Object.keys(header.collections)) {
+          collections.set(name, []);
+        }
+        continue;
+      }
+      if (!line.trim()) continue;
+      let entry: any;
+      try {
+        entry = JSON.parse(line);
+      } catch {
+        throw new VectorError(`${file} is corrupt (line ${lineNumber})`);
+      }
+      if (entry.hnsw) {
+        graphs.set(entry.collection, entry.hnsw);
+      } else if (entry.point) {
+        collections.get(entry.collection)?.push(entry.point);
+      }
+    }
+  } catch (error: any) {
+    if (error instanceof VectorError) throw error;
+    throw new VectorError(`${file} is not an index archive: ${error.message}`);
+  } finally {
+    lines.close();
+    source.destroy();
+  }
+
+  if (!header) {
+    throw new VectorError(`${file} is not an index archive`);
+  }
+
+  for (const [name, count] of Object.entries(header.collections)) {
+    const points = collections.get(name)?.length ?? 0;
+    if (points !== count) {
+      throw new VectorError(`${file} is incomplete: ${name} has ${points} of ${count} vectors`);
+    }
+  }
+
+  return { header, collections, graphs };
+}
+
+/**
+ * Parse and check the first line of an archive
+ */
+function parseIndexArchiveHeader(line: string, file: string): IndexArchiveHeader {
+  let header: IndexArchiveHeader;
+  try {
+    header = JSON.parse(line);
+  } catch {
+    throw new VectorError(`${file} is not an index archive`);
+  }
+  if (header?.format !== INDEX_ARCHIVE_FORMAT) {
+    throw new VectorError(`${file} is not an index archive`);
+  }
+  if (header.version !== INDEX_ARCHIVE_VERSION) {
+    throw new VectorError(`${file} is an index archive version ${header.version}; this version of cv-git reads version ${INDEX_ARCHIVE_VERSION}`);
+  }
+  return header;
+}
+
+/**
+ * Replace the persisted index of a repository with an archive's. The
+ * caller checks the fingerprint against the active embedding config first.
+ */
+export async function importIndexArchive(
+  repoRoot: string,
+  archive: IndexArchive,
+  archivePath: string,
+  options: IndexImportOptions = {}
+): Promise<IndexSnapshotManifest> {
+  const { header } = archive;
+  const rename = (collection: string): string =>
+    header.sourceRepoId && options.repoId && collection.startsWith(`${header.sourceRepoId}_`)
+      ? `${options.repoId}_${collection.slice(header.sourceRepoId.length + 1)}`
+      : collection;
+
+  const indexDir = getIndexDir(repoRoot);
+  const collections = new Map(Array.from(archive.collections, ([name, points]) => [rename(name), points]));
+  const manifest = await writeIndexSnapshot(indexDir, header.fingerprint, collections, header.sourceCommit);
+  for (const [name, graph] of archive.graphs) {
+    await writeIndexHnswGraph(indexDir, rename(name), graph);
+  }
+
+  const now = new Date().toISOString();
+  const importedFrom: IndexProvenance = {
+    archive: path.resolve(archivePath),
+    sourceCommit: header.sourceCommit,
+    sourcePath: header.sourcePath,
+    exportedAt: header.exportedAt,
+    importedAt: now
+  };
+  await replaceIndexMetadata(repoRoot, {
+    ...header.metadata,
+    ...header.fingerprint,
+    version: header.metadata?.version ?? 1,
+    lastIndexedCommit: header.sourceCommit,
+    worktree: options.worktree,
+    reindexReason: undefined,
+    importedFrom,
+    createdAt: header.metadata?.createdAt || now,
+    updatedAt: now
+  });
+
+  return manifest;
+}
+
+/**
+ * Warnings about paths that differ between the exporting and importing
+ * machines. Indexed files are relative to the repository root, so a
+ * different root only matters for absolute paths stored in the index.
+ */
+export function findIndexArchivePathWarnings(archive: IndexArchive, repoRoot: string): string[] {
+  const warnings: string[] = [];
+  const root = path.resolve(repoRoot);
+  if (archive.header.sourcePath !== root) {
+    warnings.push(`Index was built in ${archive.header.sourcePath}; this repository is at ${root}`);
+  }
+
+  let absolute = 0;
+  for (const points of archive.collections.values()) {
+    for (const point of points) {
+      const file = point.payload.file;
+      if (typeof file === 'string' && path.isAbsolute(file) && !file.startsWith(root + path.sep)) {
+        absolute++;
+      }
+    }
+  }
+  if (absolute > 0) {
+    warnings.push(`${absolute} vector${absolute === 1 ? ' references' : 's reference'} absolute paths outside this repository`);
+  }
+
+  return warnings;
+}
@@ -0,0 +1,329 @@
+/**
+ * Vector Index Metadata
+ *
+ * Records which embedding provider, model and dimension were used to build
+ * the vector index, so a later sync with a different provider is rejected
+ * instead of silently mixing incompatible vectors in the same collections.
+ *
+ * Stored in .cv/vector_index.json
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import { getCVDir, VectorError } from '@cv-git/shared';
+import type { RepoLanguages } from '../sync/languages.js';
+import { SyncFileFilter, isFileFilterEmpty } from '../sync/file-filter.js';
+
+const METADATA_FILE = 'vector_index.json';
+const METADATA_VERSION = 1;
+
+/**
+ * Provider input types for indexed documents and for search queries
+ */
+export interface EmbeddingInputTypes {
+  document: string;
+  query: string;
+}
+
+/**
+ * Embedding configuration an index was built with
+ */
+export interface EmbeddingIdentity {
+  provider: string;
+  model: string;
+  dimensions: number;
+  /** Set for asymmetric providers (Cohere, Voyage); queries must use the matching query type */
+  inputTypes?: EmbeddingInputTypes;
+}
+
+/**
+ * Checkout the indexed commit was synced from
+ */
+export interface IndexWorktree {
+  /** Worktree top-level directory */
+  path: string;
+  /** Branch checked out at the time; undefined if HEAD was detached */
+  branch?: string;
+}
+
+/**
+ * A repository indexed into a workspace index, at the commit it was synced to
+ */
+export interface IndexedRepo {
+  name: string;
+  /** Path from the workspace root; prefixes the repo's files in the index */
+  path: string;
+  /** HEAD of the repo when it was last indexed */
+  commit?: string;
+  /** Code chunks the repo contributed */
+  chunks: number;
+}
+
+/**
+ * Commit history embedded by `cv sync --history`
+ */
+export interface IndexedHistory {
+  /** Newest commit embedded; the next pass only embeds commits after it */
+  lastCommit: string;
+  /** Commits in the history index */
+  commits: number;
+  /** Whether commit diffs were embedded along with the messages */
+  diffs: boolean;
+  updatedAt: string;
+}
+
+/**
+ * Where an index imported with `cv index import` came from
+ */
+export interface IndexProvenance {
+  /** Archive the index was imported from */
+  archive: string;
+  /** Commit the index was built at */
+  sourceCommit?: string;
+  /** Repository root on the machine that built it */
+  sourcePath: string;
+  exportedAt: string;
+  importedAt: string;
+}
+
+/**
+ * Something the last sync left out of the index
+ */
+export interface IndexWarning {
+  /** Code too large for the embedding model, even on its own line */
+  type: 'oversized_chunk';
+  file: string;
+  startLine: number;
+  endLine: number;
+  symbolName?: string;
+  estimatedTokens: number;
+  maxTokens: number;
+}
+
+/**
+ * Warnings from a sync: replaces the stored warnings for `files` (the files
+ * that sync embedded or removed) and keeps the rest. Without `files` the
+ * whole index was rebuilt and all stored warnings are replaced.
+ */
+export interface IndexWarningUpdate {
+  files?: string[];
+  warnings: IndexWarning[];
+}
+
+/**
+ * Metadata stored alongside the vector index
+ */
+export interface VectorIndexMetadata extends EmbeddingIdentity {
+  version: number;
+  /** Commit SHA the code vectors were last brought up to date with */
+  lastIndexedCommit?: string;
+  /** Dominant languages of the repository at the last sync */
+  languages?: RepoLanguages;
+  /** Worktree lastIndexedCommit was indexed in */
+  worktree?: IndexWorktree;
+  /** Content the index is missing, by file */
+  warnings?: IndexWarning[];
+  /** --include/--exclude/--ext filter of the last sync; files outside it were never indexed */
+  fileFilter?: SyncFileFilter;
+  /** Why the index must be rebuilt (set by `cv index verify --fix`, cleared by the next sync) */
+  reindexReason?: string;
+  /** Member repositories of a workspace index, each with its own indexed commit */
+  repos?: IndexedRepo[];
+  /** Commit history index, if `cv sync --history` has run */
+  history?: IndexedHistory;
+  /** Archive the index was imported from, if it was not built here */
+  importedFrom?: IndexProvenance;
+  createdAt: string;
+  updatedAt: string;
+}
+
+/**
+ * Result of comparing an existing index with the active embedding config
+ */
+export interface IndexCompatibility {
+  compatible: boolean;
+  /** Fields that differ between the index and the active config */
+  mismatches: Array<keyof EmbeddingIdentity>;
+}
+
+function getMetadataPath(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), METADATA_FILE);
+}
+
+/**
+ * Read vector index metadata (null if the index has never been written)
+ */
+export async function readIndexMetadata(repoRoot: string): Promise<VectorIndexMetadata | null> {
+  try {
+    const content = await fs.readFile(getMetadataPath(repoRoot), 'utf-8');
+    return JSON.parse(content) as VectorIndexMetadata;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Write vector index metadata, preserving the original creation time,
+ * the last indexed commit (with its worktree), the repo languages, the
+ * warnings, the file filter and the workspace repos unless new ones are
+ * given (an empty filter clears it), the history index and where an
+ * imported index came from
+ */
+export async function writeIndexMetadata(
+  repoRoot: string,
+  identity: EmbeddingIdentity,
+  lastIndexedCommit?: string,
+  languages?: RepoLanguages,
+  warnings?: IndexWarningUpdate,
+  worktree?: IndexWorktree,
+  fileFilter?: SyncFileFilter,
+  repos?: IndexedRepo[]
+): Promise<VectorIndexMetadata> {
+  const existing = await readIndexMetadata(repoRoot);
+  const now = new Date().toISOString();
+
+  const metadata: VectorIndexMetadata = {
+    version: METADATA_VERSION,
+    provider: identity.provider,
+    model: identity.model,
+    dimensions: identity.dimensions,
+    inputTypes: identity.inputTypes,
+    lastIndexedCommit: lastIndexedCommit || existing?.lastIndexedCommit,
+    languages: languages || existing?.languages,
+    worktree: worktree || existing?.worktree,
+    warnings: mergeWarnings(existing?.warnings, warnings),
+    fileFilter: fileFilter ? (isFileFilterEmpty(fileFilter) ? undefined : fileFilter) : existing?.fileFilter,
+    repos: repos || existing?.repos,
+    history: existing?.history,
+    importedFrom: existing?.importedFrom,
+    createdAt: existing?.createdAt || now,
+    updatedAt: now
+  };
+
+  const metadataPath = getMetadataPath(repoRoot);
+  await fs.mkdir(path.dirname(metadataPath), { recursive: true });
+  await fs.writeFile(metadataPath, JSON.stringify(metadata, null, 2));
+
+  return metadata;
+}
+
+function mergeWarnings(existing: IndexWarning[] | undefined, update: IndexWarningUpdate | undefined): IndexWarning[] | undefined {
+  if (!update) {
+    return existing;
+  }
+  const replaced = new Set(update.files);
+  const kept = update.files ? (existing || []).filter(w => !replaced.has(w.file)) : [];
+  const merged = [...kept, ...update.warnings];
+  return merged.length > 0 ? merged : undefined;
+}
+
+/**
+ * Mark the index for a rebuild: without an indexed commit the next sync
+ * re-embeds every file, and writing its metadata clears the reason
+ */
+export async function markIndexForReindex(repoRoot: string, reason: string): Promise<void> {
+  const existing = await readIndexMetadata(repoRoot);
+  if (!existing) {
+    // No metadata: the next sync rebuilds anyway
+    return;
+  }
+
+  const metadata: VectorIndexMetadata = {
+    ...existing,
+    lastIndexedCommit: undefined,
+    worktree: undefined,
+    reindexReason: reason,
+    updatedAt: new Date().toISOString()
+  };
+  await fs.writeFile(getMetadataPath(repoRoot), JSON.stringify(metadata, null, 2));
+}
+
+/**
+ * Record a history index pass; a no-op until the code index has metadata
+ */
+export async function writeHistoryMetadata(repoRoot: string, history: IndexedHistory): Promise<void> {
+  const existing = await readIndexMetadata(repoRoot);
+  if (!existing) {
+    return;
+  }
+
+  const metadata: VectorIndexMetadata = { ...existing, history, updatedAt: new Date().toISOString() };
+  await fs.writeFile(getMetadataPath(repoRoot), JSON.stringify(metadata, null, 2));
+}
+
+/**
+ * Replace vector index metadata as a whole (used by `cv index import`)
+ */
+export async function replaceIndexMetadata(repoRoot: string, metadata: VectorIndexMetadata): Promise<void> {
+  const metadataPath = getMetadataPath(repoRoot);
+  await fs.mkdir(path.dirname(metadataPath), { recursive: true });
+  await fs.writeFile(metadataPath, JSON.stringify(metadata, null, 2));
+}
+
+/**
+ * Remove vector index metadata (used when the index is rebuilt from scratch)
+ */
+export async function clearIndexMetadata(repoRoot: string): Promise<void> {
+  try {
+    await fs.unlink(getMetadataPath(repoRoot));
+  } catch {
+    // Nothing to remove
+  }
+}
+
+/**
+ * Compare existing index metadata with the active embedding config
+ */
+export function checkIndexCompatibility(
+  existing: EmbeddingIdentity | null,
+  current: EmbeddingIdentity
+): IndexCompatibility {
+  if (!existing) {
+    return { compatible: true, mismatches: [] };
+  }
+
+  const mismatches: Array<keyof EmbeddingIdentity> = [];
+  if (existing.provider !== current.provider) mismatches.push('provider');
+  if (existing.model !== current.model) mismatches.push('model');
+  if (existing.dimensions !== current.dimensions) mismatches.push('dimensions');
+  if (existing.inputTypes?.document !== current.inputTypes?.document || existing.inputTypes?.query !== current.inputTypes?.query) {
+    mismatches.push('inputTypes');
+  }
+
+  return { compatible: mismatches.length === 0, mismatches };
+}
+
+/**
+ * Throw a VectorError if the active embedding config does not match the index
+ */
+export function assertIndexCompatible(
+  existing: EmbeddingIdentity | null,
+  current: EmbeddingIdentity
+): void {
+  const result = checkIndexCompatibility(existing, current);
+  if (result.compatible || !existing) {
+    return;
+  }
+
+  throw new VectorError(
+    'Embedding configuration does not match the existing vector index.\n' +
+    `  Index:   ${describeIdentity(existing)}\n` +
+    `  Current: ${describeIdentity(current)}\n` +
+    'Vectors from different embedding models cannot be mixed.\n' +
+    `Run 'cv sync --force' to rebuild the index, or switch back to ${existing.provider} (${existing.model}).`,
+    { existing, current, mismatches: result.mismatches }
+  );
+}
+
+/**
+ * Whether an error says the index was built with another embedding config
+ * (searching it would only return meaningless matches)
+ */
+export function isIndexMismatchError(error: unknown): boolean {
+  return error instanceof VectorError && Array.isArray(error.details?.mismatches);
+}
+
+function describeIdentity(identity: EmbeddingIdentity): string {
+  const inputTypes = identity.inputTypes ? `, ${identity.inputTypes.document}/${identity.inputTypes.query}` : '';
+  return `${identity.provider} / ${identity.model} (${identity.dimensions} dimensions${inputTypes})`;
+}
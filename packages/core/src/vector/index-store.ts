@@ -0,0 +1,213 @@
+/**
+ * Vector Index Store
+ *
+ * Persists the vector collections to disk so the index survives a Qdrant
+ * restart and can be restored without re-embedding.
+ *
+ * Storage structure:
+ * .cv/
+ * └── index/
+ *     ├── manifest.json        # Schema version, provider fingerprint, counts
+ *     ├── {collection}.jsonl   # One point per line: id, vector, payload
+ *     └── {collection}.hnsw.json  # HNSW graph (local backend, large collections only)
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import { getCVDir } from '@cv-git/shared';
+import { EmbeddingIdentity } from './index-metadata.js';
+import { HnswGraph } from './hnsw.js';
+
+const INDEX_DIR = 'index';
+const MANIFEST_FILE = 'manifest.json';
+
+/**
+ * Current on-disk schema version.
+ * Bump when the format changes and add a migration below.
+ */
+export const INDEX_SCHEMA_VERSION = 1;
+
+/**
+ * Migrations from a schema version to the next one.
+ * A stored index with no migration path is invalidated rather than misread.
+ */
+const MIGRATIONS: Record<number, (manifest: any) => any> = {};
+
+/**
+ * Manifest describing a persisted index
+ */
+export interface IndexSnapshotManifest {
+  schemaVersion: number;
+  /** Embedding provider/model/dimensions the vectors were produced with */
+  fingerprint: EmbeddingIdentity;
+  /** Commit the code vectors were last brought up to date with */
+  lastIndexedCommit?: string;
+  /** Point count per collection */
+  collections: Record<string, number>;
+  savedAt: string;
+}
+
+/**
+ * A single persisted vector
+ */
+export interface IndexSnapshotPoint {
+  id: string;
+  vector: number[];
+  payload: Record<string, unknown>;
+}
+
+/**
+ * Get the persisted index directory for a repository
+ */
+export function getIndexDir(repoRoot: string): string {
+  return path.join(getCVDir(repoRoot), INDEX_DIR);
+}
+
+/**
+ * Path of a collection's points file in the persisted index
+ */
+export function getIndexCollectionFile(indexDir: string, collection: string): string {
+  return path.join(indexDir, `${collection}.jsonl`);
+}
+
+/**
+ * Path of the persisted index manifest
+ */
+export function getIndexManifestFile(indexDir: string): string {
+  return path.join(indexDir, MANIFEST_FILE);
+}
+
+function getHnswFile(indexDir: string, collection: string): string {
+  return path.join(indexDir, `${collection}.hnsw.json`);
+}
+
+/**
+ * Read the index manifest, migrating older schema versions.
+ * Returns null if there is no index or its schema cannot be read.
+ */
+export async function readIndexSnapshotManifest(indexDir: string): Promise<IndexSnapshotManifest | null> {
+  let manifest: any;
+  try {
+    manifest = JSON.parse(await fs.readFile(getIndexManifestFile(indexDir), 'utf-8'));
+  } catch {
+    return null;
+  }
+
+  if (typeof manifest?.schemaVersion !== 'number') {
+    return null;
+  }
+
+  while (manifest.schemaVersion < INDEX_SCHEMA_VERSION) {
+    const migrate = MIGRATIONS[manifest.schemaVersion];
+    if (!migrate) {
+      return null;
+    }
+    manifest = migrate(manifest);
+  }
+
+  // Written by a newer version of cv-git
+  if (manifest.schemaVersion !== INDEX_SCHEMA_VERSION) {
+    return null;
+  }
+
+  return manifest as IndexSnapshotManifest;
+}
+
+/**
+ * Write all collections and the manifest.
+ * The manifest is written last so a partially written index is never loaded.
+ */
+export async function writeIndexSnapshot(
+  indexDir: string,
+  fingerprint: EmbeddingIdentity,
+  collections: Map<string, IndexSnapshotPoint[]>,
+  lastIndexedCommit?: string
+): Promise<IndexSnapshotManifest> {
+  await fs.mkdir(indexDir, { recursive: true });
+  await fs.rm(getIndexManifestFile(indexDir), { force: true });
+
+  // Drop files for collections that no longer exist, and graphs of the
+  // previous snapshot (they are written again after it)
+  for (const entry of await fs.readdir(indexDir)) {
+    const stale = entry.endsWith('.hnsw.json') ||
+      (entry.endsWith('.jsonl') && !collections.has(entry.slice(0, -'.jsonl'.length)));
+    if (stale) {
+      await fs.rm(path.join(indexDir, entry), { force: true });
+    }
+  }
+
+  const counts: Record<string, number> = {};
+  for (const [collection, points] of collections) {
+    const lines = points.map(point => JSON.stringify(point));
+    await fs.writeFile(
+      getIndexCollectionFile(indexDir, collection),
+      lines.length > 0 ? lines.join('\n') + '\n' : '',
+      'utf-8'
+    );
+    counts[collection] = points.length;
+  }
+
+  const manifest: IndexSnapshotManifest = {
+    schemaVersion: INDEX_SCHEMA_VERSION,
+    fingerprint,
+    lastIndexedCommit,
+    collections: counts,
+    savedAt: new Date().toISOString()
+  };
+
+  await fs.writeFile(getIndexManifestFile(indexDir), JSON.stringify(manifest, null, 2), 'utf-8');
+
+  return manifest;
+}
+
+/**
+ * Read the persisted points for one collection
+ */
+export async function readIndexSnapshotCollection(
+  indexDir: string,
+  collection: string
+): Promise<IndexSnapshotPoint[]> {
+  let content: string;
+  try {
+    content = await fs.readFile(getIndexCollectionFile(indexDir, collection), 'utf-8');
+  } catch {
+    return [];
+  }
+
+  const points: IndexSnapshotPoint[] = [];
+  for (const line of content.split('\n')) {
+    if (!line.trim()) continue;
+    try {
+      points.push(JSON.parse(line) as IndexSnapshotPoint);
+    } catch {
+      // Skip corrupted lines
+    }
+  }
+
+  return points;
+}
+
+/**
+ * Write the HNSW graph of a collection next to its points
+ */
+export async function writeIndexHnswGraph(indexDir: string, collection: string, graph: HnswGraph): Promise<void> {
+  await fs.writeFile(getHnswFile(indexDir, collection), JSON.stringify(graph), 'utf-8');
+}
+
+/**
+ * Read the HNSW graph of a collection (null if there is none or it is unreadable)
+ */
+export async function readIndexHnswGraph(indexDir: string, collection: string): Promise<HnswGraph | null> {
+  try {
+    return JSON.parse(await fs.readFile(getHnswFile(indexDir, collection), 'utf-8')) as HnswGraph;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Remove the persisted index
+ */
+export async function clearIndexSnapshot(indexDir: string): Promise<void> {
+  await fs.rm(indexDir, { recursive: true, force: true });
+}
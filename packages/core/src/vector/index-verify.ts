@@ -0,0 +1,280 @@
+/**
+ * Index Verification
+ *
+ * Checks the persisted index in .cv/index for the ways it goes bad: a
+ * manifest from another schema version, entries cut off or garbled by a
+ * crash, vectors of the wrong dimension, and vectors of files that no
+ * longer exist. Bad points can be pruned in place. An index that can't be
+ * trusted at all is marked for a rebuild on the next sync, for
+ * `cv index verify [--fix]`.
+ */
+
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import {
+  INDEX_SCHEMA_VERSION,
+  IndexSnapshotManifest,
+  IndexSnapshotPoint,
+  getIndexDir,
+  getIndexCollectionFile,
+  getIndexManifestFile,
+  readIndexSnapshotManifest,
+  writeIndexSnapshot,
+  clearIndexSnapshot
+} from './index-store.js';
+import { readIndexMetadata, markIndexForReindex } from './index-metadata.js';
+
+export type IndexIssueType =
+  | 'unreadable_manifest'
+  | 'schema_mismatch'
+  | 'fingerprint_mismatch'
+  | 'missing_collection'
+  | 'unreadable_entry'
+  | 'dimension_mismatch'
+  | 'dangling_vector'
+  | 'count_mismatch';
+
+/**
+ * One problem found in the index
+ */
+export interface IndexIssue {
+  type: IndexIssueType;
+  message: string;
+  /** Collection the issue is in (unset for the manifest) */
+  collection?: string;
+  /** Points affected */
+  count?: number;
+  /** Source files that no longer exist (dangling_vector) */
+  files?: string[];
+  /** Whether pruning or rewriting the snapshot fixes it; otherwise the index must be rebuilt */
+  repairable: boolean;
+}
+
+export interface IndexVerification {
+  indexDir: string;
+  /** Whether a persisted index exists at all */
+  persisted: boolean;
+  /** Points read across collections */
+  vectors: number;
+  issues: IndexIssue[];
+  /** Reason the index is already marked for a rebuild by an earlier --fix */
+  reindexPending?: string;
+}
+
+export interface IndexRepair {
+  /** Points removed from the snapshot */
+  pruned: number;
+  /** Set when the index was marked for a rebuild instead of (or after) pruning */
+  reindexReason?: string;
+}
+
+interface IndexScan {
+  verification: IndexVerification;
+  manifest: IndexSnapshotManifest | null;
+  /** Points that passed every check, by collection */
+  valid: Map<string, IndexSnapshotPoint[]>;
+}
+
+/**
+ * Check the persisted index without changing it
+ */
+export async function verifyIndex(repoRoot: string, indexDir: string = getIndexDir(repoRoot)): Promise<IndexVerification> {
+  return (await scanIndex(repoRoot, indexDir)).verification;
+}
+
+/**
+ * Prune bad points from the persisted index, or mark it for a rebuild (and
+ * drop the snapshot) when an issue can't be repaired in place
+ */
+export async function repairIndex(
+  repoRoot: string,
+  indexDir: string = getIndexDir(repoRoot)
+): Promise<IndexRepair & { verification: IndexVerification }> {
+  const { verification, manifest, valid } = await scanIndex(repoRoot, indexDir);
+
+  const unrepairable = verification.issues.find(issue => !issue.repairable);
+  if (unrepairable) {
+    const reason = unrepairable.collection ? `${unrepairable.collection}: ${unrepairable.message}` : unrepairable.message;
+    await clearIndexSnapshot(indexDir);
+    await markIndexForReindex(repoRoot, reason);
+    return { verification, pruned: verification.vectors, reindexReason: reason };
+  }
+
+  if (!manifest || verification.issues.length === 0) {
+    return { verification, pruned: 0 };
+  }
+
+  const kept = Array.from(valid.values()).reduce((sum, points) => sum + points.length, 0);
+  await writeIndexSnapshot(indexDir, manifest.fingerprint, valid, manifest.lastIndexedCommit);
+  return { verification, pruned: verification.vectors - kept };
+}
+
+async function scanIndex(repoRoot: string, indexDir: string): Promise<IndexScan> {
+  const issues: IndexIssue[] = [];
+  const valid = new Map<string, IndexSnapshotPoint[]>();
+  const metadata = await readIndexMetadata(repoRoot);
+  const verification: IndexVerification = {
+    indexDir,
+    persisted: false,
+    vectors: 0,
+    issues,
+    reindexPending: metadata?.reindexReason
+  };
+
+  // Look at the raw manifest so a bad one can be told apart from none
+  let raw: any;
+  try {
+    raw = JSON.parse(await fs.readFile(getIndexManifestFile(indexDir), 'utf-8'));
+  } catch (error: any) {
+    if (error.code === 'ENOENT') {
+      return { verification, manifest: null, valid };
+    }
+    verification.persisted = true;
+    issues.push({ type: 'unreadable_manifest', message: `manifest.json is unreadable: ${error.message}`, repairable: false });
+    return { verification, manifest: null, valid };
+  }
+  verification.persisted = true;
+
+  const manifest = await readIndexSnapshotManifest(indexDir);
+  if (!manifest) {
+    const version = typeof raw?.schemaVersion === 'number' ? `v${raw.schemaVersion}` : 'no version';
+    issues.push({
+      type: 'schema_mismatch',
+      message: `index schema is ${version}, this version of cv-git reads v${INDEX_SCHEMA_VERSION}`,
+      repairable: false
+    });
+    return { verification, manifest: null, valid };
+  }
+
+  const dimensions = manifest.fingerprint.dimensions;
+  if (metadata && (metadata.model !== manifest.fingerprint.model || metadata.dimensions !== dimensions)) {
+    issues.push({
+      type: 'fingerprint_mismatch',
+      message: `snapshot vectors are ${manifest.fingerprint.model} (${dimensions}d) but the index metadata says ` +
+        `${metadata.model} (${metadata.dimensions}d)`,
+      repairable: false
+    });
+  }
+
+  const fileExists = new Map<string, boolean>();
+  for (const [collection, expected] of Object.entries(manifest.collections)) {
+    let content: string;
+    try {
+      content = await fs.readFile(getIndexCollectionFile(indexDir, collection), 'utf-8');
+    } catch {
+      if (expected > 0) {
+        issues.push({
+          type: 'missing_collection',
+          collection,
+          message: `points file is missing (manifest lists ${expected} points)`,
+          count: expected,
+          repairable: false
+        });
+      }
+      valid.set(collection, []);
+      continue;
+    }
+
+    const points: IndexSnapshotPoint[] = [];
+    let unreadable = 0;
+    let wrongDimension = 0;
+    const dangling = new Map<string, number>();
+    let read = 0;
+
+    for (const line of content.split('\n')) {
+      if (!line.trim()) continue;
+      read++;
+
+      let point: IndexSnapshotPoint;
+      try {
+        point = JSON.parse(line);
+      } catch {
+        unreadable++;
+        continue;
+      }
+      if (!isSnapshotPoint(point)) {
+        unreadable++;
+        continue;
+      }
+      if (point.vector.length !== dimensions) {
+        wrongDimension++;
+        continue;
+      }
+
+      const file = point.payload.file;
+      if (typeof file === 'string' && file) {
+        if (!fileExists.has(file)) {
+          fileExists.set(file, await pathExists(path.join(repoRoot, file)));
+        }
+        if (!fileExists.get(file)) {
+          dangling.set(file, (dangling.get(file) ?? 0) + 1);
+          continue;
+        }
+      }
+
+      points.push(point);
+    }
+
+    verification.vectors += read;
+    valid.set(collection, points);
+
+    if (unreadable > 0) {
+      issues.push({
+        type: 'unreadable_entry',
+        collection,
+        message: `${unreadable} truncated or unreadable entr${unreadable === 1 ? 'y' : 'ies'}`,
+        count: unreadable,
+        repairable: true
+      });
+    }
+    if (wrongDimension > 0) {
+      // Every vector wrong means the fingerprint is, and pruning would empty the index
+      const all = wrongDimension === read - unreadable;
+      issues.push({
+        type: 'dimension_mismatch',
+        collection,
+        message: `${wrongDimension} vector${wrongDimension === 1 ? '' : 's'} not ${dimensions}-dimensional${all ? ' (all of them)' : ''}`,
+        count: wrongDimension,
+        repairable: !all
+      });
+    }
+    if (dangling.size > 0) {
+      const count = Array.from(dangling.values()).reduce((sum, n) => sum + n, 0);
+      issues.push({
+        type: 'dangling_vector',
+        collection,
+        message: `${count} vector${count === 1 ? '' : 's'} of ${dangling.size} deleted file${dangling.size === 1 ? '' : 's'}`,
+        count,
+        files: Array.from(dangling.keys()).sort(),
+        repairable: true
+      });
+    }
+    if (read !== expected) {
+      issues.push({
+        type: 'count_mismatch',
+        collection,
+        message: `${read} entries, the manifest lists ${expected}`,
+        count: Math.abs(read - expected),
+        repairable: true
+      });
+    }
+  }
+
+  return { verification, manifest, valid };
+}
+
+function isSnapshotPoint(point: any): point is IndexSnapshotPoint {
+  return !!point && typeof point === 'object' &&
+    (typeof point.id === 'string' || typeof point.id === 'number') &&
+    Array.isArray(point.vector) && point.vector.every((v: unknown) => typeof v === 'number' && Number.isFinite(v)) &&
+    !!point.payload && typeof point.payload === 'object';
+}
+
+async function pathExists(file: string): Promise<boolean> {
+  try {
+    await fs.access(file);
+    return true;
+  } catch {
+    return false;
+  }
+}
@@ -3,7 +3,7 @@
  */
 
 import { describe, it, expect, vi, beforeEach } from 'vitest';
-import { VectorManager, VectorManagerOptions, createVectorManager } from './index.js';
+import { VectorManager, VectorManagerOptions, createVectorManager, SEARCH_TIE_MARGIN } from './index.js';
 
 // Mock Qdrant client to avoid actual connections
 vi.mock('@qdrant/js-client-rest', () => ({
@@ -118,6 +118,31 @@ describe('VectorManager Repository Isolation', () => {
       expect(collections.commits).toBe('custom_commits');
       expect(collections.documentChunks).toBe('custom_doc_chunks');
     });
+
+    it('should name collections after the configured Qdrant collection over repoId', () => {
+      const manager = new VectorManager({
+        url: 'http://localhost:6333',
+        repoId: 'abc123def456',
+        qdrant: { collection: 'acme' },
+        ollamaUrl: 'http://localhost:11434'
+      });
+
+      const collections = manager.getCollectionNames();
+      expect(collections.codeChunks).toBe('acme_code_chunks');
+      expect(collections.summaries).toBe('acme_summaries');
+      expect(manager.getRepoId()).toBe('abc123def456');
+    });
+
+    it('should name collections after the configured Qdrant collection over explicit collections', () => {
+      const manager = new VectorManager({
+        url: 'http://localhost:6333',
+        qdrant: { collection: 'acme' },
+        collections: { codeChunks: 'code_chunks', docstrings: 'docstrings', commits: 'commits' },
+        ollamaUrl: 'http://localhost:11434'
+      });
+
+      expect(manager.getCollectionNames().codeChunks).toBe('acme_code_chunks');
+    });
   });
 
   describe('createVectorManager factory', () => {
@@ -419,3 +444,87 @@ describe('VectorManager Level-Aware Search', () => {
     });
   });
 });
+
+describe('VectorManager Metadata Filters', () => {
+  let manager: VectorManager;
+  let mockClient: any;
+
+  beforeEach(() => {
+    vi.clearAllMocks();
+    manager = new VectorManager({
+      url: 'http://localhost:6333',
+      repoId: 'test-repo',
+      ollamaUrl: 'http://localhost:11434'
+    });
+    mockClient = {
+      search: vi.fn().mockResolvedValue([]),
+      getCollection: vi.fn().mockResolvedValue({ config: { params: { vectors: { size: 768 } } } })
+    };
+    (manager as any).client = mockClient;
+    vi.spyOn(manager, 'embed').mockResolvedValue(new Array(768).fill(0));
+  });
+
+  it('should push kind, extension and package filters down to the store', async () => {
+    await manager.searchCode('token', 10, {
+      kinds: ['method'],
+      extensions: ['.go', '.ts'],
+      packages: ['services/auth'],
+      tests: 'exclude'
+    });
+
+    const filter = mockClient.search.mock.calls[0][1].filter;
+    expect(filter.must).toEqual([
+      { key: 'package', match: { value: 'services/auth' } },
+      { key: 'symbolKind', match: { value: 'method' } },
+      { key: 'extension', match: { any: ['.go', '.ts'] } }
+    ]);
+    expect(filter.must_not).toEqual([{ key: 'isTest', match: { value: true } }]);
+  });
+
+  it('should search without a filter when none is given', async () => {
+    await manager.searchCode('token', 10, { kinds: [], extensions: [] });
+
+    expect(mockClient.search.mock.calls[0][1].filter).toBeUndefined();
+  });
+});
+
+describe('VectorManager search order', () => {
+  let manager: VectorManager;
+  let mockClient: any;
+
+  const point = (file: string, startLine: number, score: number) => ({
+    id: `${file}:${startLine}`,
+    score,
+    payload: { _id: `${file}:${startLine}`, file, startLine, language: 'typescript', text: '' }
+  });
+
+  beforeEach(() => {
+    vi.clearAllMocks();
+    manager = new VectorManager({
+      url: 'http://localhost:6333',
+      repoId: 'test-repo',
+      ollamaUrl: 'http://localhost:11434'
+    });
+    mockClient = {
+      search: vi.fn().mockResolvedValue([]),
+      getCollection: vi.fn().mockResolvedValue({ config: { params: { vectors: { size: 768 } } } })
+    };
+    (manager as any).client = mockClient;
+    vi.spyOn(manager, 'embed').mockResolvedValue(new Array(768).fill(0));
+  });
+
+  it('should fetch past the limit so ties at the cutoff are ordered before it', async () => {
+    // Qdrant cut the tie at 0.8 in index order: src/z.ts came before src/a.ts
+    mockClient.search.mockResolvedValueOnce([
+      point('src/top.ts', 1, 0.9),
+      point('src/z.ts', 1, 0.8),
+      point('src/m.ts', 1, 0.8),
+      point('src/a.ts', 1, 0.8)
+    ]);
+
+    const results = await manager.searchCode('token', 2);
+
+    expect(mockClient.search.mock.calls[0][1].limit).toBe(2 + SEARCH_TIE_MARGIN);
+    expect(results.map(r => r.id)).toEqual(['src/top.ts:1', 'src/a.ts:1']);
+  });
+});
@@ -1,8 +1,10 @@
 /**
  * Vector Database Manager
- * Manages embeddings and semantic search using Qdrant
+ * Manages embeddings and semantic search using Qdrant (default), pgvector,
+ * or the local in-memory store backed by the persisted index
  */
 
+import { createHash } from 'crypto';
 import { QdrantClient } from '@qdrant/js-client-rest';
 import OpenAI from 'openai';
 import {
@@ -14,11 +16,88 @@ import {
   CodeChunk,
   VectorPayload,
   HierarchicalSummaryPayload,
-  HierarchyLevel
+  HierarchyLevel,
+  SymbolKind,
+  CVConfig
 } from '@cv-git/shared';
-import { chunkArray } from '@cv-git/shared';
-import { EmbeddingCache, createEmbeddingCache, CacheStats } from './embedding-cache.js';
+import {
+  chunkArray,
+  mapWithConcurrency,
+  retryWithBackoff,
+  RetryAttempt,
+  withRequestTimeout,
+  DEFAULT_EMBEDDING_TIMEOUT_MS
+} from '@cv-git/shared';
+import { EmbeddingCache, createEmbeddingCache, CacheStats, DEFAULT_EMBEDDING_CACHE_MAX_BYTES } from './embedding-cache.js';
 import { getVectorCollectionName } from '../storage/repo-id.js';
+import { checkIndexCompatibility, EmbeddingIdentity, EmbeddingInputTypes, isIndexMismatchError } from './index-metadata.js';
+import { AzureOpenAIDeployment, createAzureOpenAISDK } from '../ai/azure.js';
+import { GeminiClient, DEFAULT_GEMINI_EMBEDDING_MODEL } from '../ai/gemini.js';
+import { CohereClient, DEFAULT_COHERE_EMBEDDING_MODEL, COHERE_INPUT_TYPES } from '../ai/cohere.js';
+import { VoyageClient, DEFAULT_VOYAGE_EMBEDDING_MODEL, VOYAGE_INPUT_TYPES } from '../ai/voyage.js';
+import { HuggingFaceClient, DEFAULT_HUGGINGFACE_EMBEDDING_MODEL } from '../ai/huggingface.js';
+import { normalizeBaseUrl } from '../ai/openai-compatible.js';
+import { EmbeddingInputType } from '../ai/types.js';
+import { isTestFile } from '../ai/test-generation.js';
+import {
+  planEmbeddingBatches,
+  DEFAULT_EMBEDDING_BATCH_SIZE,
+  DEFAULT_EMBEDDING_BATCH_TOKENS,
+  DEFAULT_EMBEDDING_CONCURRENCY
+} from './embedding-batches.js';
+import {
+  readIndexSnapshotManifest,
+  readIndexSnapshotCollection,
+  writeIndexSnapshot,
+  writeIndexHnswGraph,
+  readIndexHnswGraph,
+  IndexSnapshotManifest,
+  IndexSnapshotPoint
+} from './index-store.js';
+import { PgVectorStore, PgVectorStoreOptions } from './pgvector.js';
+import { LocalVectorStore } from './local-store.js';
+import { HnswSettings } from './hnsw.js';
+import { sortSearchResults, SEARCH_TIE_MARGIN } from './result-order.js';
+import { proxyClientOptions } from '../config/proxy.js';
+import { createLogger } from '../logging/index.js';
+import { recordEmbeddingUsage } from '../usage/index.js';
+import { ProviderCandidate, ProviderServed, tryProviders } from '../ai/provider-fallback.js';
+import { CommentStripMode, stripComments } from '../parser/comments.js';
+
+/**
+ * The subset of the Qdrant client API VectorManager relies on.
+ * PgVectorStore and LocalVectorStore implement the same surface.
+ */
+interface VectorStoreClient {
+  getCollections(): Promise<{ collections: Array<{ name: string }> }>;
+  createCollection(name: string, options: { vectors: { size: number; distance: 'Cosine' } }): Promise<unknown>;
+  getCollection(name: string): Promise<any>;
+  deleteCollection(name: string): Promise<unknown>;
+  upsert(collection: string, request: { wait?: boolean; points: Array<{ id: string | number; vector: number[]; payload?: Record<string, unknown> }> }): Promise<unknown>;
+  search(collection: string, request: { vector: number[]; limit: number; filter?: any; with_payload?: boolean; with_vector?: boolean; params?: { hnsw_ef?: number } }): Promise<Array<{ id: string | number; score: number; payload?: Record<string, unknown> | null; vector?: unknown }>>;
+  scroll(collection: string, request: any): Promise<{ points: Array<{ id: string | number; vector?: unknown; payload?: Record<string, unknown> | null }>; next_page_offset?: unknown }>;
+  delete(collection: string, request: { wait?: boolean; points?: Array<string | number>; filter?: any }): Promise<unknown>;
+  /** Index a payload field so filters on it stay fast (Qdrant only; the other stores filter in memory or SQL) */
+  createPayloadIndex?(collection: string, request: { wait?: boolean; field_name: string; field_schema: 'keyword' | 'bool' }): Promise<unknown>;
+}
+
+const log = createLogger('vector');
+
+/** Payload fields searches filter on (--file, --language, --no-tests, --package, --kind, --ext), indexed in new Qdrant collections */
+const FILTERED_PAYLOAD_FIELDS: Record<string, 'keyword' | 'bool'> = {
+  file: 'keyword',
+  language: 'keyword',
+  isTest: 'bool',
+  package: 'keyword',
+  symbolKind: 'keyword',
+  extension: 'keyword'
+};
+
+/** Leave test file chunks out of a search, or search only them */
+export type TestChunkFilter = 'exclude' | 'only';
+
+/** 'memory' is the local store without a persisted index, filled by the caller (cv explain --no-index) */
+export type VectorBackend = 'qdrant' | 'pgvector' | 'local' | 'memory';
 
 export interface VectorCollections {
   codeChunks: string;
@@ -28,25 +107,59 @@ export interface VectorCollections {
   summaries: string;  // Hierarchical summaries (symbol, file, directory, repo)
 }
 
-// Embedding model configurations with their vector dimensions
-const EMBEDDING_MODELS: Record<string, { dimension: number; provider: 'openai' | 'openrouter' | 'ollama' | 'lmstudio' }> = {
+// Embedding model configurations with their vector dimensions and input limits (tokens)
+// `reducible` models are Matryoshka-trained: they return shorter vectors on request
+const EMBEDDING_MODELS: Record<string, { dimension: number; maxTokens: number; provider: 'openai' | 'openrouter' | 'ollama' | 'lmstudio' | 'gemini' | 'cohere' | 'voyage' | 'huggingface'; reducible?: boolean }> = {
   // OpenAI models (direct)
-  'text-embedding-3-small': { dimension: 1536, provider: 'openai' },
-  'text-embedding-3-large': { dimension: 3072, provider: 'openai' },
-  'text-embedding-ada-002': { dimension: 1536, provider: 'openai' },
+  'text-embedding-3-small': { dimension: 1536, maxTokens: 8191, provider: 'openai', reducible: true },
+  'text-embedding-3-large': { dimension: 3072, maxTokens: 8191, provider: 'openai', reducible: true },
+  'text-embedding-ada-002': { dimension: 1536, maxTokens: 8191, provider: 'openai' },
   // OpenRouter models (uses OpenAI-compatible API)
-  'openai/text-embedding-3-small': { dimension: 1536, provider: 'openrouter' },
-  'openai/text-embedding-3-large': { dimension: 3072, provider: 'openrouter' },
-  'openai/text-embedding-ada-002': { dimension: 1536, provider: 'openrouter' },
+  'openai/text-embedding-3-small': { dimension: 1536, maxTokens: 8191, provider: 'openrouter', reducible: true },
+  'openai/text-embedding-3-large': { dimension: 3072, maxTokens: 8191, provider: 'openrouter', reducible: true },
+  'openai/text-embedding-ada-002': { dimension: 1536, maxTokens: 8191, provider: 'openrouter' },
   // Ollama models (local)
-  'nomic-embed-text': { dimension: 768, provider: 'ollama' },
-  'mxbai-embed-large': { dimension: 1024, provider: 'ollama' },
-  'all-minilm': { dimension: 384, provider: 'ollama' },
-  'snowflake-arctic-embed': { dimension: 1024, provider: 'ollama' },
+  'nomic-embed-text': { dimension: 768, maxTokens: 8192, provider: 'ollama' },
+  'mxbai-embed-large': { dimension: 1024, maxTokens: 512, provider: 'ollama' },
+  'all-minilm': { dimension: 384, maxTokens: 256, provider: 'ollama' },
+  'snowflake-arctic-embed': { dimension: 1024, maxTokens: 512, provider: 'ollama' },
   // LM Studio models (local, OpenAI-compatible API)
   // Model IDs are runtime-fetched; these are common defaults
-  'nomic-ai/nomic-embed-text-v1.5-gguf': { dimension: 768, provider: 'lmstudio' },
-  'text-embedding-bge-small-en-v1.5': { dimension: 384, provider: 'lmstudio' },
+  'nomic-ai/nomic-embed-text-v1.5-gguf': { dimension: 768, maxTokens: 8192, provider: 'lmstudio' },
+  'text-embedding-bge-small-en-v1.5': { dimension: 384, maxTokens: 512, provider: 'lmstudio' },
+  // Google Gemini models
+  'text-embedding-004': { dimension: 768, maxTokens: 2048, provider: 'gemini', reducible: true },
+  // Cohere models
+  'embed-english-v3.0': { dimension: 1024, maxTokens: 512, provider: 'cohere' },
+  'embed-multilingual-v3.0': { dimension: 1024, maxTokens: 512, provider: 'cohere' },
+  'embed-english-light-v3.0': { dimension: 384, maxTokens: 512, provider: 'cohere' },
+  'embed-v4.0': { dimension: 1536, maxTokens: 128000, provider: 'cohere' },
+  // Voyage AI models
+  'voyage-code-3': { dimension: 1024, maxTokens: 32000, provider: 'voyage' },
+  'voyage-code-2': { dimension: 1536, maxTokens: 16000, provider: 'voyage' },
+  'voyage-3': { dimension: 1024, maxTokens: 32000, provider: 'voyage' },
+  'voyage-3-lite': { dimension: 512, maxTokens: 32000, provider: 'voyage' },
+  // HuggingFace models (Inference API or TEI); other model IDs have their dimension detected
+  'BAAI/bge-large-en-v1.5': { dimension: 1024, maxTokens: 512, provider: 'huggingface' },
+  'BAAI/bge-large-en': { dimension: 1024, maxTokens: 512, provider: 'huggingface' },
+  'BAAI/bge-base-en-v1.5': { dimension: 768, maxTokens: 512, provider: 'huggingface' },
+  'BAAI/bge-small-en-v1.5': { dimension: 384, maxTokens: 512, provider: 'huggingface' },
+  'sentence-transformers/all-MiniLM-L6-v2': { dimension: 384, maxTokens: 256, provider: 'huggingface' },
+};
+
+/** Input limit assumed for models not in the table (OpenAI's limit) */
+export const DEFAULT_EMBEDDING_MAX_TOKENS = 8191;
+
+// Display names for "client not initialized" errors
+const PROVIDER_NAMES: Record<string, string> = {
+  openai: 'OpenAI',
+  openrouter: 'OpenRouter',
+  azure: 'Azure OpenAI',
+  gemini: 'Gemini',
+  cohere: 'Cohere',
+  voyage: 'Voyage AI',
+  huggingface: 'HuggingFace',
+  'openai-compatible': 'OpenAI-compatible gateway'
 };
 
 // Model fallback order for OpenRouter (preferred)
@@ -69,9 +182,29 @@ const OLLAMA_MODEL_ORDER = [
   'all-minilm'
 ];
 
+/**
+ * Qdrant server settings beyond the URL
+ */
+export interface QdrantOptions {
+  /** API key for Qdrant Cloud or a server with service.api_key set */
+  apiKey?: string;
+  /** Base name of the collections in place of the repository ID (e.g. `acme` gives acme_code_chunks) */
+  collection?: string;
+}
+
 export interface VectorManagerOptions {
   /** Qdrant URL */
   url: string;
+  /** Vector store backend (default: qdrant) */
+  backend?: VectorBackend;
+  /** Qdrant API key and collection name, used when backend is 'qdrant' */
+  qdrant?: QdrantOptions;
+  /** Postgres connection settings, required when backend is 'pgvector' */
+  pgvector?: PgVectorStoreOptions;
+  /** HNSW graph settings when backend is 'local' (which also requires indexDir) */
+  hnsw?: HnswSettings;
+  /** HNSW candidate list size for searches on Qdrant and the local backend (default: the store's) */
+  efSearch?: number;
   /** Repository ID - when provided, uses isolated collections {repoId}_{collection} */
   repoId?: string;
   /** OpenRouter API key (preferred for embeddings) */
@@ -86,33 +219,124 @@ export interface VectorManagerOptions {
   ollamaUrl?: string;
   /** LM Studio URL for local embeddings (default: http://localhost:1234/v1) */
   lmstudioUrl?: string;
+  /** Azure OpenAI embedding deployment; takes precedence over other cloud keys */
+  azure?: AzureOpenAIDeployment;
+  /** Gemini API key; selects Gemini embeddings (text-embedding-004 by default) */
+  geminiApiKey?: string;
+  /** Cohere API key; selects Cohere embeddings (embed-english-v3.0 by default) */
+  cohereApiKey?: string;
+  /** Voyage AI API key; selects Voyage embeddings (voyage-code-3 by default) */
+  voyageApiKey?: string;
+  /** HuggingFace access token; selects HuggingFace embeddings (BAAI/bge-large-en-v1.5 by default) */
+  huggingfaceApiKey?: string;
+  /** Text Embeddings Inference server URL; selects HuggingFace embeddings served from it (token optional) */
+  huggingfaceUrl?: string;
+  /** OpenAI-compatible gateway (LiteLLM, vLLM, ...); selects its embeddings, with embeddingModel sent as given */
+  openaiCompatible?: { baseUrl: string; apiKey?: string };
   /** Enable content-addressed embedding cache */
   enableCache?: boolean;
-  /** Cache directory (default: .cv/embeddings) */
+  /** Cache directory (default: .cv/cache/embeddings) */
   cacheDir?: string;
-  /** Vector dimension size (default: auto-detected from model, 1536 for OpenAI, 768 for Ollama nomic-embed-text) */
+  /** Evict least recently used cache entries beyond this size (default: 1GB) */
+  cacheMaxSizeBytes?: number;
+  /** Persisted index directory (e.g. .cv/index); empty collections are restored from it on connect */
+  indexDir?: string;
+  /** Vector dimension size (default: detected from the first embedding for local providers, model table for cloud) */
   vectorSize?: number;
+  /**
+   * Ask the model for vectors of this many dimensions (OpenAI text-embedding-3, Gemini text-embedding-004).
+   * Default: the size the persisted index was built with, if it is a reduced one; otherwise the model's full size.
+   * 0 asks for the full size whatever the index was built with (e.g. to rebuild it).
+   */
+  embeddingDimensions?: number;
+  /** Max texts per embeddings API request (default: 100) */
+  embeddingBatchSize?: number;
+  /** Max estimated tokens per embeddings API request (default: 100000) */
+  embeddingBatchTokens?: number;
+  /** Embedding requests in flight at once (default: 4) */
+  embeddingConcurrency?: number;
+  /** Comments left out of the embedded code text; the stored text keeps them (default: none) */
+  stripComments?: CommentStripMode;
+  /** Attempts per embedding request on rate limits and transient errors (default: CV_MAX_RETRIES or 5) */
+  maxRetryAttempts?: number;
+  /** Called before each retried embedding request */
+  onRetry?: (info: RetryAttempt) => void;
+  /** Milliseconds an embeddings request may take before it is cancelled; 0 disables (default: 60s) */
+  embeddingTimeoutMs?: number;
+  /** Aborts in-flight embedding requests when signalled (e.g. Ctrl-C during sync) */
+  signal?: AbortSignal;
+  /** Providers tried in order while the active one is down (config embedding.providers); each must serve the same model */
+  embeddingFallbacks?: string[];
+  /** Called with the provider that answered each embeddings request, e.g. for verbose logging */
+  onEmbeddingServed?: (info: ProviderServed) => void;
+}
+
+export interface EmbedBatchOptions {
+  /** Called as texts are embedded (cache hits count as embedded) */
+  onProgress?: (embedded: number, total: number) => void;
+  /**
+   * Content to key the cache by, parallel to texts (default: the texts themselves).
+   * Lets code that moved between files reuse its vector even though the embedded
+   * text carries the file path.
+   */
+  cacheKeys?: string[];
 }
 
 export class VectorManager {
-  private client: QdrantClient | null = null;
+  private client: VectorStoreClient | null = null;
+  private pgStore: PgVectorStore | null = null;
+  private localStore: LocalVectorStore | null = null;
+  private backend: VectorBackend;
+  private pgvectorOptions?: PgVectorStoreOptions;
+  private hnsw?: HnswSettings;
+  private efSearch?: number;
   private openai: OpenAI | null = null;
   private openrouter: OpenAI | null = null;
+  private gemini: GeminiClient | null = null;
+  private cohere: CohereClient | null = null;
+  private voyage: VoyageClient | null = null;
+  private huggingface: HuggingFaceClient | null = null;
   private collections: VectorCollections;
   private embeddingModel: string;
-  private embeddingProvider: 'openai' | 'openrouter' | 'ollama' | 'lmstudio';
+  private embeddingProvider: 'openai' | 'openrouter' | 'ollama' | 'lmstudio' | 'azure' | 'gemini' | 'cohere' | 'voyage' | 'huggingface' | 'openai-compatible';
   private ollamaUrl: string;
   private lmstudioUrl: string;
   private openrouterApiKey?: string;
   private openaiApiKey?: string;
   private vectorSize: number;
+  private explicitVectorSize: boolean;
+  private reducedDimensions?: number;
+  private adoptDimensions: boolean;
+  /** Vector size of each populated collection searched so far */
+  private collectionDimensions = new Map<string, number>();
   private connected: boolean = false;
   private modelValidated: boolean = false;
   private url: string;
+  private qdrantApiKey?: string;
   private cache: EmbeddingCache | null = null;
   private cacheEnabled: boolean = false;
   private cacheDir: string;
+  private cacheMaxSizeBytes: number;
+  private indexDir?: string;
   private repoId?: string;
+  private azure?: AzureOpenAIDeployment;
+  private geminiApiKey?: string;
+  private cohereApiKey?: string;
+  private voyageApiKey?: string;
+  private huggingfaceApiKey?: string;
+  private huggingfaceUrl?: string;
+  private openaiCompatible?: { baseUrl: string; apiKey?: string };
+  private batchSize: number;
+  private maxBatchTokens: number;
+  private concurrency: number;
+  private stripCommentsMode: CommentStripMode;
+  private maxRetryAttempts?: number;
+  private onRetry?: (info: RetryAttempt) => void;
+  private embeddingTimeoutMs: number;
+  private signal?: AbortSignal;
+  private embeddingFallbackProviders: string[];
+  private embeddingFallbacks: Array<{ provider: string; client: OpenAI }> = [];
+  private onEmbeddingServed?: (info: ProviderServed) => void;
 
   constructor(options: VectorManagerOptions);
   /** @deprecated Use options object instead */
@@ -139,7 +363,19 @@ export class VectorManager {
     }
 
     this.url = opts.url;
+    this.backend = opts.backend || 'qdrant';
+    this.qdrantApiKey = opts.qdrant?.apiKey;
+    this.pgvectorOptions = opts.pgvector;
+    this.hnsw = opts.hnsw;
+    this.efSearch = opts.efSearch;
     this.repoId = opts.repoId;
+    this.azure = opts.azure;
+    this.geminiApiKey = opts.geminiApiKey;
+    this.cohereApiKey = opts.cohereApiKey;
+    this.voyageApiKey = opts.voyageApiKey;
+    this.huggingfaceApiKey = opts.huggingfaceApiKey;
+    this.huggingfaceUrl = opts.huggingfaceUrl;
+    this.openaiCompatible = opts.openaiCompatible;
     this.ollamaUrl = opts.ollamaUrl || process.env.OLLAMA_URL || process.env.CV_OLLAMA_URL || 'http://127.0.0.1:11434';
     this.lmstudioUrl = opts.lmstudioUrl || process.env.CV_LMSTUDIO_URL || process.env.LMSTUDIO_URL || 'http://127.0.0.1:1234/v1';
 
@@ -148,16 +384,18 @@ export class VectorManager {
     this.openaiApiKey = useLocal ? undefined : opts.openaiApiKey;
     this.openrouterApiKey = useLocal ? undefined : (opts.openrouterApiKey || process.env.OPENROUTER_API_KEY);
 
-    // Collection naming: if repoId provided, use repo-specific names for isolation
-    // Otherwise use explicit collections or defaults
-    if (opts.repoId && !opts.collections) {
+    // Collection naming: a configured Qdrant collection always names them. Otherwise
+    // explicit collections win, then repo-specific names from repoId for isolation,
+    // then the defaults
+    const collectionBase = opts.qdrant?.collection || (opts.collections ? undefined : opts.repoId);
+    if (collectionBase) {
       // Use repo-specific collection names for isolation
       this.collections = {
-        codeChunks: getVectorCollectionName(opts.repoId, 'code_chunks'),
-        docstrings: getVectorCollectionName(opts.repoId, 'docstrings'),
-        commits: getVectorCollectionName(opts.repoId, 'commits'),
-        documentChunks: getVectorCollectionName(opts.repoId, 'document_chunks'),
-        summaries: getVectorCollectionName(opts.repoId, 'summaries')
+        codeChunks: getVectorCollectionName(collectionBase, 'code_chunks'),
+        docstrings: getVectorCollectionName(collectionBase, 'docstrings'),
+        commits: getVectorCollectionName(collectionBase, 'commits'),
+        documentChunks: getVectorCollectionName(collectionBase, 'document_chunks'),
+        summaries: getVectorCollectionName(collectionBase, 'summaries')
       };
     } else {
       // Use explicit collections or defaults (shared mode)
@@ -172,23 +410,62 @@ export class VectorManager {
 
     // Cache settings
     this.cacheEnabled = opts.enableCache ?? true;  // Enabled by default
-    this.cacheDir = opts.cacheDir ?? '.cv/embeddings';
+    this.cacheDir = opts.cacheDir ?? '.cv/cache/embeddings';
+    this.cacheMaxSizeBytes = opts.cacheMaxSizeBytes ?? DEFAULT_EMBEDDING_CACHE_MAX_BYTES;
+    this.indexDir = opts.indexDir;
+
+    // Embedding request batching
+    this.batchSize = opts.embeddingBatchSize || DEFAULT_EMBEDDING_BATCH_SIZE;
+    this.maxBatchTokens = opts.embeddingBatchTokens || DEFAULT_EMBEDDING_BATCH_TOKENS;
+    this.concurrency = opts.embeddingConcurrency || DEFAULT_EMBEDDING_CONCURRENCY;
+    this.stripCommentsMode = opts.stripComments || 'none';
+    this.maxRetryAttempts = opts.maxRetryAttempts;
+    this.onRetry = opts.onRetry;
+    this.embeddingTimeoutMs = opts.embeddingTimeoutMs ?? DEFAULT_EMBEDDING_TIMEOUT_MS;
+    this.signal = opts.signal;
+    this.embeddingFallbackProviders = opts.embeddingFallbacks ?? [];
+    this.onEmbeddingServed = opts.onEmbeddingServed;
 
     // Default model based on available provider
-    // Local (Ollama/LM Studio) > OpenRouter > OpenAI
-    const defaultModel = opts.lmstudioUrl
-      ? 'nomic-ai/nomic-embed-text-v1.5-gguf'
-      : opts.ollamaUrl
-        ? 'nomic-embed-text'
-        : this.openrouterApiKey
-          ? 'openai/text-embedding-3-small'
-          : 'text-embedding-3-small';
-
-    this.embeddingModel = opts.embeddingModel || process.env.CV_EMBEDDING_MODEL || defaultModel;
+    // Gemini / Cohere / Voyage / HuggingFace / OpenAI-compatible (explicit) > Local (Ollama/LM Studio) > OpenRouter > OpenAI
+    const keyedProvider = opts.geminiApiKey ? 'gemini'
+      : opts.cohereApiKey ? 'cohere'
+        : opts.voyageApiKey ? 'voyage'
+          : opts.huggingfaceApiKey || opts.huggingfaceUrl ? 'huggingface'
+            : opts.openaiCompatible ? 'openai-compatible'
+              : undefined;
+    const defaultModel = keyedProvider === 'gemini'
+      ? DEFAULT_GEMINI_EMBEDDING_MODEL
+      : keyedProvider === 'cohere'
+        ? DEFAULT_COHERE_EMBEDDING_MODEL
+        : keyedProvider === 'voyage'
+          ? DEFAULT_VOYAGE_EMBEDDING_MODEL
+          : keyedProvider === 'huggingface'
+            ? DEFAULT_HUGGINGFACE_EMBEDDING_MODEL
+            : opts.lmstudioUrl
+              ? 'nomic-ai/nomic-embed-text-v1.5-gguf'
+              : opts.ollamaUrl
+                ? 'nomic-embed-text'
+                : this.openrouterApiKey && keyedProvider !== 'openai-compatible'
+                  ? 'openai/text-embedding-3-small'
+                  : 'text-embedding-3-small';
+
+    // Azure routes by deployment name, which stands in for the model.
+    // Keyed providers ignore models another provider serves (e.g. the nomic-embed-text config default);
+    // a gateway may serve any of them, so its model is kept as given.
+    let requestedModel = opts.embeddingModel || process.env.CV_EMBEDDING_MODEL;
+    if (keyedProvider && keyedProvider !== 'openai-compatible' && requestedModel && EMBEDDING_MODELS[requestedModel] && EMBEDDING_MODELS[requestedModel].provider !== keyedProvider) {
+      requestedModel = undefined;
+    }
+    this.embeddingModel = opts.azure?.deployment || requestedModel || defaultModel;
 
     // Determine provider from model name or available keys
     const modelConfig = EMBEDDING_MODELS[this.embeddingModel];
-    if (modelConfig) {
+    if (opts.azure) {
+      this.embeddingProvider = 'azure';
+    } else if (keyedProvider) {
+      this.embeddingProvider = keyedProvider;
+    } else if (modelConfig) {
       this.embeddingProvider = modelConfig.provider;
     } else if (this.openrouterApiKey) {
       this.embeddingProvider = 'openrouter';
@@ -198,24 +475,87 @@ export class VectorManager {
       this.embeddingProvider = 'ollama';
     }
 
-    this.vectorSize = opts.vectorSize || modelConfig?.dimension || 1536;
+    this.reducedDimensions = opts.embeddingDimensions || undefined;
+    this.adoptDimensions = opts.embeddingDimensions === undefined;
+    this.vectorSize = opts.embeddingDimensions || opts.vectorSize || modelConfig?.dimension || 1536;
+    this.explicitVectorSize = !!(opts.embeddingDimensions || opts.vectorSize);
   }
 
   /**
-   * Connect to Qdrant and initialize embedding provider
+   * Connect to the vector store and initialize embedding provider
    * Provider priority: OpenRouter > OpenAI > Ollama
    */
   async connect(): Promise<void> {
+    const storeName = this.backend === 'pgvector' ? 'pgvector'
+      : this.backend === 'local' || this.backend === 'memory' ? 'local vector store' : 'Qdrant';
     try {
-      // Initialize Qdrant client
-      this.client = new QdrantClient({ url: this.url });
+      if (this.backend === 'local' || this.backend === 'memory') {
+        // Collections live in memory and are loaded from (and saved to) the persisted index
+        if (this.backend === 'local' && !this.indexDir) {
+          throw new VectorError('local backend requires an index directory (.cv/index)');
+        }
+        this.localStore = new LocalVectorStore(this.hnsw);
+        this.client = this.localStore;
+      } else if (this.backend === 'pgvector') {
+        if (!this.pgvectorOptions?.connectionString) {
+          throw new VectorError('pgvector backend requires vector.pgvector.connectionString');
+        }
+        // Connecting also checks that `cv index init` has created the table
+        this.pgStore = new PgVectorStore(this.pgvectorOptions);
+        await this.pgStore.connect();
+        this.client = this.pgStore;
+      } else {
+        // Initialize Qdrant client
+        this.client = new QdrantClient({ url: this.url, apiKey: this.qdrantApiKey });
 
-      // Test connection
-      await this.client.getCollections();
+        // Test connection
+        await this.client.getCollections();
+      }
 
       // Initialize embedding provider based on what's available
       // Priority: Explicit local > OpenRouter > OpenAI > auto-detect local
-      if (this.embeddingProvider === 'lmstudio') {
+      if (this.embeddingProvider === 'azure' && this.azure) {
+        // Azure OpenAI deployment - api-key header, api-version query
+        this.openai = createAzureOpenAISDK(this.azure, 0);  // Retried by embedBatchWithRetry
+        this.modelValidated = true;  // No model fallback: the deployment is fixed
+      } else if (this.embeddingProvider === 'gemini') {
+        if (!this.geminiApiKey) {
+          throw new VectorError('Gemini API key required for Gemini embeddings. Run: cv auth setup gemini');
+        }
+        this.gemini = new GeminiClient({ apiKey: this.geminiApiKey, maxRetryAttempts: 1 });  // Retried by embedBatchWithRetry
+        this.modelValidated = true;
+      } else if (this.embeddingProvider === 'cohere') {
+        if (!this.cohereApiKey) {
+          throw new VectorError('Cohere API key required for Cohere embeddings. Run: cv auth setup cohere');
+        }
+        this.cohere = new CohereClient({ apiKey: this.cohereApiKey, maxRetryAttempts: 1 });  // Retried by embedBatchWithRetry
+        this.modelValidated = true;
+      } else if (this.embeddingProvider === 'voyage') {
+        if (!this.voyageApiKey) {
+          throw new VectorError('Voyage AI API key required for Voyage embeddings. Run: cv auth setup voyage');
+        }
+        this.voyage = new VoyageClient({ apiKey: this.voyageApiKey, maxRetryAttempts: 1 });  // Retried by embedBatchWithRetry
+        this.modelValidated = true;
+      } else if (this.embeddingProvider === 'huggingface') {
+        if (!this.huggingfaceApiKey && !this.huggingfaceUrl) {
+          throw new VectorError('HuggingFace token or TEI URL required for HuggingFace embeddings. Run: cv auth setup huggingface');
+        }
+        this.huggingface = new HuggingFaceClient({
+          apiKey: this.huggingfaceApiKey,
+          baseUrl: this.huggingfaceUrl,
+          maxRetryAttempts: 1  // Retried by embedBatchWithRetry
+        });
+        this.modelValidated = true;
+      } else if (this.embeddingProvider === 'openai-compatible' && this.openaiCompatible) {
+        // Any OpenAI-compatible gateway; the SDK requires a key, which gateways without authentication ignore
+        this.openai = new OpenAI({
+          apiKey: this.openaiCompatible.apiKey || 'none',
+          baseURL: normalizeBaseUrl(this.openaiCompatible.baseUrl),
+          maxRetries: 0,  // Retried by embedBatchWithRetry
+          ...proxyClientOptions()
+        });
+        this.modelValidated = true;  // No model fallback: the gateway decides what the name means
+      } else if (this.embeddingProvider === 'lmstudio') {
         // Explicit LM Studio request — uses OpenAI-compatible API
         await this.initLMStudio();
       } else if (this.embeddingProvider === 'ollama') {
@@ -225,7 +565,9 @@ export class VectorManager {
         // OpenRouter available - use it (preferred)
         this.openrouter = new OpenAI({
           apiKey: this.openrouterApiKey,
-          baseURL: 'https://openrouter.ai/api/v1'
+          baseURL: 'https://openrouter.ai/api/v1',
+          maxRetries: 0,  // Retried by embedBatchWithRetry
+          ...proxyClientOptions()
         });
         this.embeddingProvider = 'openrouter';
         // Use OpenRouter model naming
@@ -234,7 +576,7 @@ export class VectorManager {
         }
       } else if (this.openaiApiKey) {
         // Fall back to OpenAI
-        this.openai = new OpenAI({ apiKey: this.openaiApiKey });
+        this.openai = new OpenAI({ apiKey: this.openaiApiKey, maxRetries: 0, ...proxyClientOptions() });
         this.embeddingProvider = 'openai';
         // Use OpenAI model naming (strip openai/ prefix if present)
         if (this.embeddingModel.startsWith('openai/')) {
@@ -261,6 +603,29 @@ export class VectorManager {
         }
       }
 
+      // Queries must be embedded at the size the index was built with
+      if (this.adoptDimensions) {
+        await this.adoptIndexDimensions();
+      }
+      if (this.reducedDimensions) {
+        this.checkReducedDimensions(this.reducedDimensions);
+      }
+
+      // Local models, Azure deployments, HuggingFace and gateway models vary in dimension -
+      // detect it from a real response rather than trusting the model table
+      const probed = ['ollama', 'lmstudio', 'azure', 'huggingface', 'openai-compatible'].includes(this.embeddingProvider);
+      if (probed && !this.explicitVectorSize) {
+        await this.detectVectorSize();
+      }
+
+      this.initEmbeddingFallbacks();
+      log.debug(`Embedding with ${PROVIDER_NAMES[this.embeddingProvider] ?? this.embeddingProvider}`, {
+        provider: this.embeddingProvider,
+        model: this.embeddingModel,
+        dimensions: this.vectorSize,
+        store: storeName
+      });
+
       this.connected = true;
 
       // Initialize embedding cache if enabled
@@ -268,7 +633,8 @@ export class VectorManager {
         this.cache = createEmbeddingCache({
           cacheDir: this.cacheDir,
           model: this.embeddingModel,
-          dimensions: this.vectorSize
+          dimensions: this.vectorSize,
+          maxSizeBytes: this.cacheMaxSizeBytes
         });
         await this.cache.initialize();
       }
@@ -277,7 +643,58 @@ export class VectorManager {
       await this.ensureCollections();
 
     } catch (error: any) {
-      throw new VectorError(`Failed to connect to Qdrant: ${error.message}`, error);
+      throw new VectorError(`Failed to connect to ${storeName}: ${error.message}`, error);
+    }
+
+    // Reload vectors lost on a Qdrant restart
+    if (this.indexDir) {
+      try {
+        const restored = await this.restoreIndex(this.indexDir);
+        if (restored > 0) {
+          log.debug(`Restored ${restored} vectors from ${this.indexDir}`);
+        }
+      } catch (error: any) {
+        log.debug(`Could not restore persisted index: ${error.message}`);
+      }
+    }
+  }
+
+  /**
+   * Clients for the fallback embedding providers. Only a provider serving
+   * the active model can stand in for it: another model's vectors would not
+   * match the index. OpenRouter and OpenAI both serve OpenAI's models.
+   */
+  private initEmbeddingFallbacks(): void {
+    this.embeddingFallbacks = [];
+    const baseModel = this.embeddingModel.replace(/^openai\//, '');
+    const openAICompatible = ['openrouter', 'openai'];
+
+    for (const provider of this.embeddingFallbackProviders) {
+      if (provider === this.embeddingProvider) continue;
+
+      const sameModel = openAICompatible.includes(this.embeddingProvider) && openAICompatible.includes(provider) &&
+        EMBEDDING_MODELS[baseModel]?.provider === 'openai';
+      if (!sameModel) {
+        throw new VectorError(
+          `embedding.providers: ${PROVIDER_NAMES[provider] ?? provider} cannot embed with ${this.embeddingModel} ` +
+          `(${this.vectorSize} dimensions) like ${PROVIDER_NAMES[this.embeddingProvider]}, so its vectors would not match the index. ` +
+          `Fallback providers must serve the same model.`
+        );
+      }
+
+      const apiKey = provider === 'openrouter' ? this.openrouterApiKey : this.openaiApiKey;
+      if (!apiKey) {
+        throw new VectorError(`embedding.providers: no ${PROVIDER_NAMES[provider]} API key for the fallback. Run: cv auth setup ${provider}`);
+      }
+      this.embeddingFallbacks.push({
+        provider,
+        client: new OpenAI({
+          apiKey,
+          ...(provider === 'openrouter' ? { baseURL: 'https://openrouter.ai/api/v1' } : {}),
+          maxRetries: 0,  // Retried by embedBatchWithRetry
+          ...proxyClientOptions()
+        })
+      });
     }
   }
 
@@ -332,6 +749,77 @@ export class VectorManager {
     }
   }
 
+  /**
+   * Reduce to the dimensions of the persisted index when it was built from
+   * this model at less than its full size
+   */
+  private async adoptIndexDimensions(): Promise<void> {
+    if (!this.indexDir) return;
+    const manifest = await readIndexSnapshotManifest(this.indexDir).catch(() => null);
+    const fingerprint = manifest?.fingerprint;
+    const modelConfig = EMBEDDING_MODELS[this.embeddingModel];
+    if (
+      fingerprint &&
+      modelConfig?.reducible &&
+      fingerprint.provider === this.embeddingProvider &&
+      fingerprint.model === this.embeddingModel &&
+      fingerprint.dimensions < modelConfig.dimension
+    ) {
+      this.reducedDimensions = fingerprint.dimensions;
+      this.vectorSize = fingerprint.dimensions;
+      this.explicitVectorSize = true;
+    }
+  }
+
+  /**
+   * Throw a VectorError unless the model can return vectors of this size
+   */
+  private checkReducedDimensions(dimensions: number): void {
+    const modelConfig = EMBEDDING_MODELS[this.embeddingModel];
+    if (this.embeddingProvider === 'azure' || this.embeddingProvider === 'openai-compatible') {
+      // Deployments and gateway models are named by the user; the API rejects models that cannot reduce
+      return;
+    }
+    if (!modelConfig?.reducible) {
+      const reducible = Object.keys(EMBEDDING_MODELS).filter(model => EMBEDDING_MODELS[model].reducible && !model.includes('/'));
+      throw new VectorError(
+        `Embedding model ${this.embeddingModel} does not support reduced dimensions (embedding.outputDimensions). ` +
+        `Models that do: ${reducible.join(', ')}`
+      );
+    }
+    if (!Number.isInteger(dimensions) || dimensions < 1 || dimensions > modelConfig.dimension) {
+      throw new VectorError(`Embedding dimensions for ${this.embeddingModel} must be between 1 and ${modelConfig.dimension}, got ${dimensions}`);
+    }
+  }
+
+  /**
+   * The `dimensions` parameter of an OpenAI-compatible embeddings request
+   */
+  private dimensionsParam(): { dimensions?: number } {
+    return this.reducedDimensions ? { dimensions: this.reducedDimensions } : {};
+  }
+
+  /**
+   * Detect vector dimension by embedding a short probe text
+   */
+  private async detectVectorSize(): Promise<void> {
+    const probe = this.embeddingProvider === 'lmstudio'
+      ? await this.embedWithLMStudio('dimension probe')
+      : ['azure', 'huggingface', 'openai-compatible'].includes(this.embeddingProvider)
+        ? (await this.tryEmbeddingWithFallback('dimension probe')).embeddings[0]
+        : await this.embedWithOllama('dimension probe');
+
+    if (!probe || probe.length === 0) {
+      throw new VectorError(`Embedding model ${this.embeddingModel} returned an empty vector`);
+    }
+
+    if (probe.length !== this.vectorSize) {
+      log.debug(`Detected ${probe.length} dimensions for ${this.embeddingModel} (expected ${this.vectorSize})`);
+    }
+
+    this.vectorSize = probe.length;
+  }
+
   /**
    * Generate embedding using Ollama
    */
@@ -345,29 +833,30 @@ export class VectorManager {
       ? text.substring(0, maxLength) + '...'
       : text;
 
-    if (process.env.CV_DEBUG) {
-      console.log(`[VectorManager] Ollama embedding request: url=${this.ollamaUrl}, model=${this.embeddingModel}, textLen=${text.length}${text.length > maxLength ? ' (truncated)' : ''}`);
-    }
+    log.debug('Ollama embedding request', { url: this.ollamaUrl, model: this.embeddingModel, textLength: text.length, truncated: text.length > maxLength });
 
-    const response = await fetch(`${this.ollamaUrl}/api/embeddings`, {
-      method: 'POST',
-      headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({
-        model: this.embeddingModel,
-        prompt: truncatedText
-      }),
-      signal: AbortSignal.timeout(60000)  // Increased timeout for first request (model loading)
-    });
+    // The timeout also covers the first request, which waits for the model to load
+    const data = await this.embeddingRequest('Ollama embedding request', async signal => {
+      const response = await fetch(`${this.ollamaUrl}/api/embeddings`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({
+          model: this.embeddingModel,
+          prompt: truncatedText
+        }),
+        signal
+      });
 
-    if (!response.ok) {
-      const error = await response.text();
-      throw new Error(`Ollama embedding failed: ${error}`);
-    }
+      if (!response.ok) {
+        const error = await response.text();
+        throw new Error(`Ollama embedding failed: ${error}`);
+      }
 
-    const data = await response.json() as { embedding: number[] };
+      return await response.json() as { embedding: number[] };
+    });
 
-    if (process.env.CV_DEBUG && data.embedding) {
-      console.log(`[VectorManager] Ollama embedding success: dim=${data.embedding.length}`);
+    if (data.embedding) {
+      log.debug('Ollama embedding success', { dimensions: data.embedding.length });
     }
 
     return data.embedding;
@@ -394,6 +883,7 @@ export class VectorManager {
           break;
         } catch (error: any) {
           lastError = error;
+          if (this.signal?.aborted) break;
           // Wait before retry (Ollama runner might be restarting)
           if (retry < 2) {
             await new Promise(r => setTimeout(r, 1000 * (retry + 1)));
@@ -422,6 +912,7 @@ export class VectorManager {
     if (!this.openrouter) {
       throw new VectorError('OpenRouter client not initialized');
     }
+    const openrouter = this.openrouter;
 
     // Validate input
     const inputArray = Array.isArray(input) ? input : [input];
@@ -440,22 +931,25 @@ export class VectorManager {
     }
 
     // Models to try in order of preference
+    // Reduced vectors can only come from a model that reduces
     const modelsToTry = [
       this.embeddingModel,
       'openai/text-embedding-3-small',
       'openai/text-embedding-ada-002',
-    ].filter((m, i, arr) => arr.indexOf(m) === i); // Remove duplicates
+    ].filter((m, i, arr) => arr.indexOf(m) === i) // Remove duplicates
+      .filter(m => m === this.embeddingModel || !this.reducedDimensions || EMBEDDING_MODELS[m]?.reducible);
 
     let response: any;
     let lastError: Error | null = null;
 
     for (const model of modelsToTry) {
       try {
-        response = await this.openrouter.embeddings.create({
+        response = await this.embeddingRequest('OpenRouter embedding request', signal => openrouter.embeddings.create({
           model,
           input: validInputs.length === 1 ? validInputs[0] : validInputs,
-          encoding_format: 'float'
-        });
+          encoding_format: 'float',
+          ...this.dimensionsParam()
+        }, { signal }));
 
         // If we had to fall back to a different model, update our setting
         if (model !== this.embeddingModel) {
@@ -569,6 +1063,11 @@ export class VectorManager {
             distance: 'Cosine'
           }
         });
+
+        // Payload indexes let Qdrant apply filters before the vector search
+        for (const [field, schema] of Object.entries(FILTERED_PAYLOAD_FIELDS)) {
+          await this.client.createPayloadIndex?.(name, { wait: true, field_name: field, field_schema: schema });
+        }
       }
     } catch (error: any) {
       throw new VectorError(`Failed to ensure collection ${name}: ${error.message}`, error);
@@ -576,21 +1075,45 @@ export class VectorManager {
   }
 
   /**
-   * Generate embedding for text (with content-addressed caching)
+   * Generate embedding for text (with content-addressed caching).
+   * Search queries pass inputType 'query'; providers with asymmetric
+   * models embed them differently from indexed documents.
    */
-  async embed(text: string): Promise<number[]> {
+  async embed(text: string, inputType: EmbeddingInputType = 'document'): Promise<number[]> {
+    // Query vectors differ from document vectors for the same text
+    const cacheKey = inputType === 'query' && this.getInputTypes() ? `query:${text}` : text;
+
     // Check cache first
     if (this.cache) {
-      const cached = await this.cache.get(text);
+      const cached = await this.cache.get(cacheKey);
       if (cached) {
         return cached;
       }
     }
 
-    // Generate embedding
+    // Fallback providers stand in while the active one is down
+    const embedding = await tryProviders(
+      this.embeddingCandidates(),
+      candidate => candidate.client
+        ? this.embedWithFallbackProvider(candidate as ProviderCandidate<OpenAI>, [text]).then(embeddings => embeddings[0])
+        : this.embedWithActiveProvider(text, inputType),
+      { onServed: this.onEmbeddingServed }
+    );
+
+    // Store in cache
+    if (this.cache) {
+      await this.cache.set(cacheKey, embedding);
+    }
+
+    return embedding;
+  }
+
+  /**
+   * Embed one text with the active provider
+   */
+  private async embedWithActiveProvider(text: string, inputType: EmbeddingInputType): Promise<number[]> {
     let embedding: number[];
 
-    // Use the appropriate provider
     if (this.embeddingProvider === 'lmstudio') {
       embedding = await this.embedWithLMStudio(text);
     } else if (this.embeddingProvider === 'ollama') {
@@ -601,6 +1124,9 @@ export class VectorManager {
       }
       const result = await this.embedWithOpenRouter(text);
       embedding = result.embeddings[0];
+    } else if (['gemini', 'cohere', 'voyage', 'huggingface'].includes(this.embeddingProvider)) {
+      const result = await this.tryEmbeddingWithFallback(text, inputType);
+      embedding = result.embeddings[0];
     } else {
       // OpenAI direct
       if (!this.openai) {
@@ -608,11 +1134,13 @@ export class VectorManager {
       }
 
       try {
-        const response = await this.openai.embeddings.create({
+        const openai = this.openai;
+        const response = await this.embeddingRequest('OpenAI embedding request', signal => openai.embeddings.create({
           model: this.embeddingModel,
           input: text,
-          encoding_format: 'float'
-        });
+          encoding_format: 'float',
+          ...this.dimensionsParam()
+        }, { signal }));
 
         embedding = response.data[0].embedding;
       } catch (error: any) {
@@ -620,11 +1148,7 @@ export class VectorManager {
       }
     }
 
-    // Store in cache
-    if (this.cache) {
-      await this.cache.set(text, embedding);
-    }
-
+    recordEmbeddingUsage(this.embeddingProvider, this.embeddingModel, [text]);
     return embedding;
   }
 
@@ -730,38 +1254,36 @@ export class VectorManager {
       ? text.substring(0, maxLength) + '...'
       : text;
 
-    if (process.env.CV_DEBUG) {
-      console.log(`[VectorManager] LM Studio embedding request: url=${this.lmstudioUrl}, model=${this.embeddingModel}, textLen=${text.length}${text.length > maxLength ? ' (truncated)' : ''}`);
-    }
+    log.debug('LM Studio embedding request', { url: this.lmstudioUrl, model: this.embeddingModel, textLength: text.length, truncated: text.length > maxLength });
 
-    const response = await fetch(`${this.lmstudioUrl}/embeddings`, {
-      method: 'POST',
-      headers: {
-        'Content-Type': 'application/json',
-        'Authorization': 'Bearer lm-studio',
-      },
-      body: JSON.stringify({
-        model: this.embeddingModel,
-        input: [truncatedText],
-      }),
-      signal: AbortSignal.timeout(60000)
-    });
+    const data = await this.embeddingRequest('LM Studio embedding request', async signal => {
+      const response = await fetch(`${this.lmstudioUrl}/embeddings`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': 'Bearer lm-studio',
+        },
+        body: JSON.stringify({
+          model: this.embeddingModel,
+          input: [truncatedText],
+        }),
+        signal
+      });
 
-    if (!response.ok) {
-      const error = await response.text();
-      throw new Error(`LM Studio embedding failed: ${error}`);
-    }
+      if (!response.ok) {
+        const error = await response.text();
+        throw new Error(`LM Studio embedding failed: ${error}`);
+      }
 
-    const data = await response.json() as { data?: Array<{ embedding: number[] }> };
+      return await response.json() as { data?: Array<{ embedding: number[] }> };
+    });
     const embedding = data.data?.[0]?.embedding;
 
     if (!embedding) {
       throw new Error('LM Studio returned empty embedding');
     }
 
-    if (process.env.CV_DEBUG) {
-      console.log(`[VectorManager] LM Studio embedding success: dim=${embedding.length}`);
-    }
+    log.debug('LM Studio embedding success', { dimensions: embedding.length });
 
     return embedding;
   }
@@ -784,6 +1306,7 @@ export class VectorManager {
           break;
         } catch (error: any) {
           lastError = error;
+          if (this.signal?.aborted) break;
           if (retry < 2) {
             await new Promise(r => setTimeout(r, 1000 * (retry + 1)));
           }
@@ -805,7 +1328,10 @@ export class VectorManager {
   /**
    * Try to generate embeddings with automatic model fallback (including OpenRouter and Ollama)
    */
-  private async tryEmbeddingWithFallback(input: string | string[]): Promise<{ embeddings: number[][]; model: string }> {
+  private async tryEmbeddingWithFallback(
+    input: string | string[],
+    inputType: EmbeddingInputType = 'document'
+  ): Promise<{ embeddings: number[][]; model: string }> {
     // If using a local provider, use it directly
     if (this.embeddingProvider === 'lmstudio') {
       const texts = Array.isArray(input) ? input : [input];
@@ -824,17 +1350,59 @@ export class VectorManager {
       return this.embedWithOpenRouter(input);
     }
 
+    if (this.embeddingProvider === 'gemini') {
+      if (!this.gemini) {
+        throw new VectorError('Gemini client not initialized');
+      }
+      const texts = Array.isArray(input) ? input : [input];
+      const gemini = this.gemini;
+      const embeddings = await this.embeddingRequest('Gemini embedding request', signal => gemini.embed(texts, this.embeddingModel, signal, this.reducedDimensions));
+      return { embeddings, model: this.embeddingModel };
+    }
+
+    if (this.embeddingProvider === 'cohere') {
+      if (!this.cohere) {
+        throw new VectorError('Cohere client not initialized');
+      }
+      const texts = Array.isArray(input) ? input : [input];
+      const cohere = this.cohere;
+      const embeddings = await this.embeddingRequest('Cohere embedding request', signal => cohere.embed(texts, this.embeddingModel, inputType, signal));
+      return { embeddings, model: this.embeddingModel };
+    }
+
+    if (this.embeddingProvider === 'voyage') {
+      if (!this.voyage) {
+        throw new VectorError('Voyage AI client not initialized');
+      }
+      const texts = Array.isArray(input) ? input : [input];
+      const voyage = this.voyage;
+      const embeddings = await this.embeddingRequest('Voyage AI embedding request', signal => voyage.embed(texts, this.embeddingModel, inputType, signal));
+      return { embeddings, model: this.embeddingModel };
+    }
+
+    if (this.embeddingProvider === 'huggingface') {
+      if (!this.huggingface) {
+        throw new VectorError('HuggingFace client not initialized');
+      }
+      const texts = Array.isArray(input) ? input : [input];
+      const huggingface = this.huggingface;
+      const embeddings = await this.embeddingRequest('HuggingFace embedding request', signal => huggingface.embed(texts, this.embeddingModel, signal));
+      return { embeddings, model: this.embeddingModel };
+    }
+
     if (!this.openai) {
       throw new VectorError('OpenAI client not initialized');
     }
+    const openai = this.openai;
 
     // If model already validated, use it directly
     if (this.modelValidated) {
-      const response = await this.openai.embeddings.create({
+      const response = await this.embeddingRequest('OpenAI embedding request', signal => openai.embeddings.create({
         model: this.embeddingModel,
         input,
-        encoding_format: 'float'
-      });
+        encoding_format: 'float',
+        ...this.dimensionsParam()
+      }, { signal }));
       return {
         embeddings: response.data.map(d => d.embedding),
         model: this.embeddingModel
@@ -842,26 +1410,28 @@ export class VectorManager {
     }
 
     // Try models in fallback order
-    const modelsToTry = this.embeddingModel === OPENAI_MODEL_ORDER[0]
+    const modelsToTry = (this.embeddingModel === OPENAI_MODEL_ORDER[0]
       ? OPENAI_MODEL_ORDER
-      : [this.embeddingModel, ...OPENAI_MODEL_ORDER.filter(m => m !== this.embeddingModel)];
+      : [this.embeddingModel, ...OPENAI_MODEL_ORDER.filter(m => m !== this.embeddingModel)]
+    ).filter(m => m === this.embeddingModel || !this.reducedDimensions || EMBEDDING_MODELS[m]?.reducible);
 
     let lastError: Error | null = null;
     let allOpenAIFailed = true;
 
     for (const model of modelsToTry) {
       try {
-        const response = await this.openai.embeddings.create({
+        const response = await this.embeddingRequest('OpenAI embedding request', signal => openai.embeddings.create({
           model,
           input,
-          encoding_format: 'float'
-        });
+          encoding_format: 'float',
+          ...this.dimensionsParam()
+        }, { signal }));
 
         // Model works! Update settings
         if (model !== this.embeddingModel) {
           console.log(`Switched to embedding model: ${model}`);
           this.embeddingModel = model;
-          this.vectorSize = EMBEDDING_MODELS[model]?.dimension || 1536;
+          this.vectorSize = this.reducedDimensions || EMBEDDING_MODELS[model]?.dimension || 1536;
         }
         this.modelValidated = true;
         allOpenAIFailed = false;
@@ -888,7 +1458,9 @@ export class VectorManager {
       try {
         this.openrouter = new OpenAI({
           apiKey: this.openrouterApiKey,
-          baseURL: 'https://openrouter.ai/api/v1'
+          baseURL: 'https://openrouter.ai/api/v1',
+          maxRetries: 0,  // Retried by embedBatchWithRetry
+          ...proxyClientOptions()
         });
         this.embeddingProvider = 'openrouter';
         this.embeddingModel = 'openai/text-embedding-3-small';
@@ -940,124 +1512,213 @@ export class VectorManager {
   /**
    * Generate embeddings for multiple texts in batches (with content-addressed caching)
    */
-  async embedBatch(texts: string[]): Promise<number[][]> {
+  async embedBatch(texts: string[], options: EmbedBatchOptions = {}): Promise<number[][]> {
+    const keys = options.cacheKeys ?? texts;
+    if (keys.length !== texts.length) {
+      throw new VectorError(`cacheKeys has ${keys.length} entries for ${texts.length} texts`);
+    }
+
     // Check cache for existing embeddings
-    let textsToEmbed = texts;
-    const cachedEmbeddings = new Map<string, number[]>();
+    const result: (number[] | undefined)[] = new Array(texts.length);
+    let missing = texts.map((_, i) => i);
 
     if (this.cache) {
-      const cacheResult = await this.cache.getBatch(texts);
-      for (const [text, embedding] of cacheResult.cached) {
-        cachedEmbeddings.set(text, embedding);
-      }
-      textsToEmbed = cacheResult.missing;
+      const cacheResult = await this.cache.getBatch(keys);
+      missing = [];
+      keys.forEach((key, i) => {
+        const cached = cacheResult.cached.get(key);
+        if (cached) {
+          result[i] = cached;
+        } else {
+          missing.push(i);
+        }
+      });
 
-      if (process.env.CV_DEBUG && cacheResult.cached.size > 0) {
-        console.log(`[VectorManager] Cache hit: ${cacheResult.cached.size}/${texts.length} embeddings`);
+      if (cacheResult.cached.size > 0) {
+        log.debug(`Cache hit: ${texts.length - missing.length}/${texts.length} embeddings`);
       }
     }
 
+    const textsToEmbed = missing.map(i => texts[i]);
+    const keysToEmbed = missing.map(i => keys[i]);
+
+    let embedded = texts.length - textsToEmbed.length;
+    const reportProgress = (count: number) => {
+      embedded += count;
+      options.onProgress?.(embedded, texts.length);
+    };
+    reportProgress(0);
+
     // Generate embeddings for missing texts
     let newEmbeddings: number[][] = [];
+    let cachedPerBatch = false;
 
     if (textsToEmbed.length > 0) {
       // If using LM Studio, use LM Studio batch
       if (this.embeddingProvider === 'lmstudio') {
         newEmbeddings = await this.embedBatchWithLMStudio(textsToEmbed);
+        recordEmbeddingUsage(this.embeddingProvider, this.embeddingModel, textsToEmbed);
+        reportProgress(textsToEmbed.length);
       }
       // If using Ollama, use Ollama batch
       else if (this.embeddingProvider === 'ollama') {
         newEmbeddings = await this.embedBatchWithOllama(textsToEmbed);
+        recordEmbeddingUsage(this.embeddingProvider, this.embeddingModel, textsToEmbed);
+        reportProgress(textsToEmbed.length);
       }
-      // If using OpenRouter, use OpenRouter batch with retry logic
-      else if (this.embeddingProvider === 'openrouter') {
-        if (!this.openrouter) {
-          throw new VectorError('OpenRouter client not initialized');
-        }
-        const batchSize = 50; // Smaller batches to avoid rate limits
-        const batches = chunkArray(textsToEmbed, batchSize);
-        const maxRetries = 3;
-
-        for (let i = 0; i < batches.length; i++) {
-          const batch = batches[i];
-          let lastError: Error | null = null;
-
-          for (let retry = 0; retry < maxRetries; retry++) {
-            try {
-              const result = await this.embedWithOpenRouter(batch);
-              newEmbeddings.push(...result.embeddings);
-              lastError = null;
-              break;
-            } catch (error: any) {
-              lastError = error;
-              const isRetryable = error.message?.includes('No successful provider') ||
-                                  error.message?.includes('rate') ||
-                                  error.message?.includes('429') ||
-                                  error.message?.includes('503');
-              if (isRetryable && retry < maxRetries - 1) {
-                const delay = Math.pow(2, retry) * 1000 + Math.random() * 1000;
-                console.log(`OpenRouter batch ${i + 1}/${batches.length} failed, retrying in ${Math.round(delay / 1000)}s...`);
-                await new Promise(r => setTimeout(r, delay));
-              } else if (!isRetryable) {
-                throw error;
-              }
-            }
-          }
-
-          if (lastError) {
-            throw lastError;
-          }
-
-          // Progress indicator for large batches
-          if (batches.length > 10 && (i + 1) % 10 === 0) {
-            console.log(`Embedding progress: ${i + 1}/${batches.length} batches`);
-          }
-        }
-      }
-      // Using OpenAI directly
+      // OpenRouter / OpenAI / Gemini / Cohere / Voyage / HuggingFace: array requests with bounded concurrency
       else {
-        if (!this.openai) {
-          throw new VectorError('OpenAI client not initialized');
+        if (!this.getBatchClient()) {
+          throw new VectorError(`${PROVIDER_NAMES[this.embeddingProvider]} client not initialized`);
         }
 
+        cachedPerBatch = true;
         try {
-          // OpenAI allows up to 2048 inputs per request
-          const batchSize = 100; // Use smaller batches to be safe
-          const batches = chunkArray(textsToEmbed, batchSize);
-
-          for (const batch of batches) {
-            const result = await this.tryEmbeddingWithFallback(batch);
-            newEmbeddings.push(...result.embeddings);
-          }
+          newEmbeddings = await this.embedInBatches(textsToEmbed, keysToEmbed, reportProgress);
         } catch (error: any) {
-          throw new VectorError(`Failed to generate batch embeddings: ${error.message}`, error);
+          const cachedNote = this.cache && embedded > 0
+            ? ` (${embedded}/${texts.length} embeddings are cached; rerun to resume)`
+            : '';
+          if (error instanceof VectorError) {
+            error.message += cachedNote;
+            throw error;
+          }
+          throw new VectorError(`Failed to generate batch embeddings: ${error.message}${cachedNote}`, error);
         }
       }
 
-      // Store new embeddings in cache
-      if (this.cache && newEmbeddings.length > 0) {
+      // Store new embeddings in cache (cloud batches are cached as they complete)
+      if (this.cache && newEmbeddings.length > 0 && !cachedPerBatch) {
         const newCache = new Map<string, number[]>();
-        for (let i = 0; i < textsToEmbed.length; i++) {
-          newCache.set(textsToEmbed[i], newEmbeddings[i]);
+        for (let i = 0; i < keysToEmbed.length; i++) {
+          newCache.set(keysToEmbed[i], newEmbeddings[i]);
         }
         await this.cache.setBatch(newCache);
       }
     }
 
-    // Reconstruct embeddings in original order
-    const result: number[][] = [];
-    let newIndex = 0;
+    // Fill in new embeddings at their original positions
+    missing.forEach((index, i) => {
+      result[index] = newEmbeddings[i];
+    });
+
+    return result as number[][];
+  }
+
+  /**
+   * Embed texts with a cloud provider, several array requests at a time.
+   * Each batch retries on its own, so one failure doesn't redo finished batches.
+   * Finished batches go straight to the cache, so a rerun after a failure resumes.
+   */
+  private async embedInBatches(
+    texts: string[],
+    keys: string[],
+    onBatchDone: (count: number) => void
+  ): Promise<number[][]> {
+    const batches = planEmbeddingBatches(texts, {
+      batchSize: this.batchSize,
+      maxBatchTokens: this.maxBatchTokens
+    });
+
+    // Batches are contiguous, so each one's cache keys start where the previous batch ended
+    const offsets: number[] = [];
+    let offset = 0;
+    for (const batch of batches) {
+      offsets.push(offset);
+      offset += batch.length;
+    }
+
+    // Serialize cache writes; the cache index is a single file
+    let cacheWrites: Promise<void> = Promise.resolve();
 
-    for (const text of texts) {
-      const cached = cachedEmbeddings.get(text);
-      if (cached) {
-        result.push(cached);
-      } else {
-        result.push(newEmbeddings[newIndex++]);
+    const embedOne = async (batch: string[], index: number): Promise<number[][]> => {
+      const embeddings = await this.embedBatchWithRetry(batch, index, batches.length);
+
+      if (this.cache) {
+        const batchKeys = keys.slice(offsets[index], offsets[index] + batch.length);
+        const entries = new Map(batchKeys.map((key, i) => [key, embeddings[i]] as [string, number[]]));
+        cacheWrites = cacheWrites
+          .then(async () => { await this.cache!.setBatch(entries); })
+          .catch(() => { /* Cache is best-effort */ });
+        await cacheWrites;
       }
-    }
 
-    return result;
+      onBatchDone(batch.length);
+      return embeddings;
+    };
+
+    // The first request may fall back to another model or provider; settle that before fanning out
+    const first = await embedOne(batches[0], 0);
+    const rest = await mapWithConcurrency(batches.slice(1), this.concurrency, (batch, i) => embedOne(batch, i + 1));
+
+    return [first, ...rest].flat();
+  }
+
+  /**
+   * Run one embeddings request under the embedding timeout, cancelled by the
+   * manager's signal
+   */
+  private embeddingRequest<T>(what: string, fn: (signal: AbortSignal) => Promise<T>): Promise<T> {
+    return withRequestTimeout(what, fn, { timeoutMs: this.embeddingTimeoutMs, signal: this.signal });
+  }
+
+  /**
+   * Embed one batch, retrying rate limits and transient errors with backoff
+   */
+  private async embedBatchWithRetry(batch: string[], index: number, total: number): Promise<number[][]> {
+    const retry = <T>(fn: () => Promise<T>) => retryWithBackoff(fn, {
+      maxAttempts: this.maxRetryAttempts,
+      signal: this.signal,
+      onRetry: info => {
+        this.onRetry?.(info);
+        log.debug(`Embedding batch ${index + 1}/${total} failed (attempt ${info.attempt}/${info.maxAttempts}), retrying in ${Math.round(info.delayMs / 1000)}s: ${info.error?.message}`);
+      }
+    });
+
+    // Each provider gets its retries before the next one stands in
+    return tryProviders(
+      this.embeddingCandidates(),
+      async candidate => {
+        if (candidate.client) {
+          return retry(() => this.embedWithFallbackProvider(candidate as ProviderCandidate<OpenAI>, batch));
+        }
+        const result = await retry(() => this.tryEmbeddingWithFallback(batch));
+        // Recorded per batch so a sync that fails later still logs what it paid for
+        recordEmbeddingUsage(this.embeddingProvider, result.model, batch);
+        return result.embeddings;
+      },
+      { onServed: this.onEmbeddingServed }
+    );
+  }
+
+  /**
+   * The active provider (no client: its own code path), then the fallback
+   * providers with the same model under their names for it
+   */
+  private embeddingCandidates(): ProviderCandidate<OpenAI | null>[] {
+    const baseModel = this.embeddingModel.replace(/^openai\//, '');
+    return [
+      { provider: this.embeddingProvider, model: this.embeddingModel, client: null },
+      ...this.embeddingFallbacks.map(fallback => ({
+        provider: fallback.provider,
+        model: fallback.provider === 'openrouter' ? `openai/${baseModel}` : baseModel,
+        client: fallback.client
+      }))
+    ];
+  }
+
+  /**
+   * Embed texts with a fallback provider (one request)
+   */
+  private async embedWithFallbackProvider(candidate: ProviderCandidate<OpenAI>, texts: string[]): Promise<number[][]> {
+    const response = await this.embeddingRequest(`${PROVIDER_NAMES[candidate.provider]} embedding request`, signal => candidate.client.embeddings.create({
+      model: candidate.model,
+      input: texts,
+      encoding_format: 'float',
+      ...this.dimensionsParam()
+    }, { signal }));
+    recordEmbeddingUsage(candidate.provider, candidate.model, texts);
+    return response.data.map(d => d.embedding);
   }
 
   /**
@@ -1127,52 +1788,84 @@ export class VectorManager {
     collection: string,
     query: string,
     limit: number = 10,
-    filter?: any
+    filter?: any,
+    withVectors: boolean = false
   ): Promise<VectorSearchResult<T>[]> {
     if (!this.client) {
       throw new VectorError('Not connected to Qdrant');
     }
 
     try {
-      if (process.env.CV_DEBUG) {
-        console.log(`[VectorManager] Searching collection '${collection}' for query: "${query.slice(0, 50)}..."`);
-      }
+      log.debug(`Searching collection '${collection}' for query: "${query.slice(0, 50)}..."`);
 
       // Generate embedding for query
-      const queryVector = await this.embed(query);
+      const queryVector = await this.embed(query, 'query');
 
-      if (process.env.CV_DEBUG) {
-        console.log(`[VectorManager] Generated embedding of length ${queryVector.length}`);
-      }
+      log.debug(`Generated embedding of length ${queryVector.length}`);
+
+      await this.assertQueryDimensions(collection, queryVector.length);
 
-      // Search
+      // Search past the limit: the store cuts ties at the limit in index order
       const results = await this.client.search(collection, {
         vector: queryVector,
-        limit,
+        limit: limit + SEARCH_TIE_MARGIN,
         filter,
-        with_payload: true
+        with_payload: true,
+        with_vector: withVectors,
+        ...(this.efSearch ? { params: { hnsw_ef: this.efSearch } } : {})
       });
 
-      if (process.env.CV_DEBUG) {
-        console.log(`[VectorManager] Search returned ${results.length} raw results`);
-        if (results.length > 0) {
-          console.log(`[VectorManager] Top score: ${results[0].score.toFixed(4)}`);
-        }
-      }
+      log.debug(`Search returned ${results.length} raw results`, results.length > 0 ? { topScore: Number(results[0].score.toFixed(4)) } : undefined);
 
-      return results.map(result => ({
+      // Equal scores come back in index order, which can differ between runs
+      return sortSearchResults(results.map(result => ({
         id: result.payload?._id as string || String(result.id),
         score: result.score,
-        payload: result.payload as T
-      }));
+        payload: result.payload as T,
+        ...(withVectors && Array.isArray(result.vector) ? { vector: result.vector as number[] } : {})
+      }))).slice(0, limit);
     } catch (error: any) {
-      if (process.env.CV_DEBUG) {
-        console.error(`[VectorManager] Search error: ${error.message}`);
+      log.debug(`Search error: ${error.message}`);
+      if (isIndexMismatchError(error)) {
+        throw error;
       }
       throw new VectorError(`Search failed: ${error.message}`, error);
     }
   }
 
+  /**
+   * Fail fast if query vectors no longer fit the collection, i.e. the
+   * embedding model changed since the index was built: the store would
+   * reject the search deep inside, or score it against unrelated vectors
+   */
+  private async assertQueryDimensions(collection: string, queryDimensions: number): Promise<void> {
+    let indexed = this.collectionDimensions.get(collection);
+    if (indexed === undefined) {
+      const { existingDimensions, pointCount } = await this.checkCollectionCompatibility(collection);
+      // An empty collection has nothing to mismatch (and is filled at the current size)
+      if (!existingDimensions || pointCount === 0) {
+        return;
+      }
+      indexed = existingDimensions;
+      this.collectionDimensions.set(collection, indexed);
+    }
+    if (indexed === queryDimensions) {
+      return;
+    }
+
+    // The snapshot names the model, if it is the one the collection holds
+    const snapshot = await this.getIndexSnapshot().catch(() => null);
+    const existing = snapshot?.fingerprint.dimensions === indexed ? snapshot.fingerprint : undefined;
+    const current = { ...this.getEmbeddingInfo(), dimensions: queryDimensions };
+
+    throw new VectorError(
+      `Index built with ${existing ? `${existing.model} (${indexed} dims)` : `${indexed}-dimension embeddings`}; ` +
+      `current provider returns ${queryDimensions} (${current.provider} / ${current.model}).\n` +
+      `Run 'cv sync --force' to rebuild the index with the current embedding provider.`,
+      { existing, current, mismatches: ['dimensions'] }
+    );
+  }
+
   /**
    * Search code chunks
    */
@@ -1181,8 +1874,19 @@ export class VectorManager {
     limit: number = 10,
     options?: {
       language?: string;
-      file?: string;
+      /** Only chunks of this file, or of any of these files (repo-relative) */
+      file?: string | string[];
+      /** Leave out chunks of test files, or return only those */
+      tests?: TestChunkFilter;
+      /** Only chunks of these packages (repo-relative directories, see resolvePackageScope) */
+      packages?: string[];
+      /** Only chunks of symbols of these kinds */
+      kinds?: SymbolKind[];
+      /** Only chunks of files with these extensions ('.go', lowercase) */
+      extensions?: string[];
       minScore?: number;
+      /** Return each chunk's embedding (used to drop near-duplicates) */
+      withVectors?: boolean;
     }
   ): Promise<VectorSearchResult<CodeChunkPayload>[]> {
     const filter: any = {};
@@ -1195,21 +1899,59 @@ export class VectorManager {
       });
     }
 
-    if (options?.file) {
+    const files = typeof options?.file === 'string' ? [options.file] : options?.file ?? [];
+    if (files.length > 0) {
       filter.must = filter.must || [];
       filter.must.push({
         key: 'file',
-        match: { value: options.file }
+        match: files.length === 1 ? { value: files[0] } : { any: files }
+      });
+    }
+
+    if (options?.packages?.length) {
+      filter.must = filter.must || [];
+      filter.must.push({
+        key: 'package',
+        match: options.packages.length === 1 ? { value: options.packages[0] } : { any: options.packages }
       });
     }
 
-    const results = await this.search<CodeChunkPayload>(
+    if (options?.kinds?.length) {
+      filter.must = filter.must || [];
+      filter.must.push({
+        key: 'symbolKind',
+        match: options.kinds.length === 1 ? { value: options.kinds[0] } : { any: options.kinds }
+      });
+    }
+
+    if (options?.extensions?.length) {
+      filter.must = filter.must || [];
+      filter.must.push({
+        key: 'extension',
+        match: options.extensions.length === 1 ? { value: options.extensions[0] } : { any: options.extensions }
+      });
+    }
+
+    if (options?.tests === 'only') {
+      filter.must = filter.must || [];
+      filter.must.push({ key: 'isTest', match: { value: true } });
+    } else if (options?.tests === 'exclude') {
+      filter.must_not = [{ key: 'isTest', match: { value: true } }];
+    }
+
+    let results = await this.search<CodeChunkPayload>(
       this.collections.codeChunks,
       query,
       limit,
-      Object.keys(filter).length > 0 ? filter : undefined
+      Object.keys(filter).length > 0 ? filter : undefined,
+      options?.withVectors
     );
 
+    // Indexes from before chunks were classified have no isTest; go by the path
+    if (options?.tests === 'exclude') {
+      results = results.filter(r => !(r.payload.isTest ?? isTestFile(r.payload.file)));
+    }
+
     // Filter by minimum score if specified
     if (options?.minScore !== undefined) {
       return results.filter(r => r.score >= options.minScore!);
@@ -1218,6 +1960,25 @@ export class VectorManager {
     return results;
   }
 
+  /**
+   * Search the commit history index (filled by `cv sync --history`)
+   */
+  async searchCommits(
+    query: string,
+    limit: number = 10,
+    options?: {
+      minScore?: number;
+    }
+  ): Promise<VectorSearchResult<CommitPayload>[]> {
+    const results = await this.search<CommitPayload>(this.collections.commits, query, limit);
+
+    if (options?.minScore !== undefined) {
+      return results.filter(r => r.score >= options.minScore!);
+    }
+
+    return results;
+  }
+
   // ========== Hierarchical Summary Methods ==========
 
   /**
@@ -1441,6 +2202,114 @@ export class VectorManager {
     }
   }
 
+  /**
+   * Delete all vectors whose payload belongs to one of the given files
+   */
+  async deleteByFiles(collection: string, files: string[]): Promise<void> {
+    if (!this.client) {
+      throw new VectorError('Not connected to Qdrant');
+    }
+    if (files.length === 0) return;
+
+    try {
+      for (const batch of chunkArray(files, 100)) {
+        await this.client.delete(collection, {
+          wait: true,
+          filter: {
+            should: batch.map(file => ({ key: 'file', match: { value: file } }))
+          }
+        });
+      }
+    } catch (error: any) {
+      throw new VectorError(`Failed to delete vectors by file: ${error.message}`, error);
+    }
+  }
+
+  /**
+   * Payloads of the indexed code chunks for the given files
+   */
+  async getFileChunks(files: string[]): Promise<CodeChunkPayload[]> {
+    if (!this.client) {
+      throw new VectorError('Not connected to Qdrant');
+    }
+    if (files.length === 0) return [];
+
+    try {
+      const chunks: CodeChunkPayload[] = [];
+      for (const batch of chunkArray(files, 100)) {
+        const filter = { should: batch.map(file => ({ key: 'file', match: { value: file } })) };
+        let offset: string | number | undefined;
+
+        do {
+          const page: any = await this.client.scroll(this.collections.codeChunks, {
+            filter,
+            limit: 100,
+            offset,
+            with_vector: false,
+            with_payload: true
+          });
+          for (const point of page.points) {
+            chunks.push(point.payload as CodeChunkPayload);
+          }
+          offset = page.next_page_offset ?? undefined;
+        } while (offset !== undefined);
+      }
+      return chunks;
+    } catch (error: any) {
+      throw new VectorError(`Failed to read chunks by file: ${error.message}`, error);
+    }
+  }
+
+  /**
+   * Move vectors from one file path to another without re-embedding
+   * Used for pure renames where the content (and therefore the vectors) is unchanged
+   * @returns Number of vectors moved
+   */
+  async moveFileVectors(collection: string, oldPath: string, newPath: string): Promise<number> {
+    if (!this.client) {
+      throw new VectorError('Not connected to Qdrant');
+    }
+
+    try {
+      const filter = { must: [{ key: 'file', match: { value: oldPath } }] };
+      const moved: Array<{ id: string; vector: number[]; payload: any }> = [];
+      let offset: string | number | undefined;
+
+      do {
+        const page: any = await this.client.scroll(collection, {
+          filter,
+          limit: 100,
+          offset,
+          with_vector: true,
+          with_payload: true
+        });
+
+        for (const point of page.points) {
+          const payload = point.payload as Record<string, any>;
+          const oldId = (payload._id as string) || String(point.id);
+          const newId = oldId.startsWith(oldPath) ? newPath + oldId.slice(oldPath.length) : oldId;
+          const { _id, ...rest } = payload;
+          moved.push({
+            id: newId,
+            vector: point.vector as number[],
+            payload: { ...rest, id: newId, file: newPath }
+          });
+        }
+
+        offset = page.next_page_offset ?? undefined;
+      } while (offset !== undefined);
+
+      if (moved.length === 0) return 0;
+
+      await this.deleteByFiles(collection, [oldPath]);
+      await this.upsertBatch(collection, moved);
+
+      return moved.length;
+    } catch (error: any) {
+      throw new VectorError(`Failed to move vectors from ${oldPath} to ${newPath}: ${error.message}`, error);
+    }
+  }
+
   /**
    * Clear entire collection
    */
@@ -1451,6 +2320,7 @@ export class VectorManager {
 
     try {
       await this.client.deleteCollection(collection);
+      this.collectionDimensions.delete(collection);
       await this.ensureCollection(collection, this.vectorSize);
     } catch (error: any) {
       throw new VectorError(`Failed to clear collection: ${error.message}`, error);
@@ -1595,6 +2465,7 @@ export class VectorManager {
     } catch {
       // Collection might not exist
     }
+    this.collectionDimensions.delete(collection);
 
     await this.ensureCollection(collection, this.vectorSize);
 
@@ -1637,18 +2508,46 @@ export class VectorManager {
   /**
    * Get current embedding model and dimensions
    */
-  getEmbeddingInfo(): {
-    model: string;
-    provider: string;
-    dimensions: number;
-  } {
+  getEmbeddingInfo(): EmbeddingIdentity {
     return {
       model: this.embeddingModel,
       provider: this.embeddingProvider,
-      dimensions: this.vectorSize
+      dimensions: this.vectorSize,
+      inputTypes: this.getInputTypes()
     };
   }
 
+  /**
+   * Most tokens the active embedding model accepts in one input
+   */
+  getMaxInputTokens(): number {
+    return EMBEDDING_MODELS[this.embeddingModel]?.maxTokens ?? DEFAULT_EMBEDDING_MAX_TOKENS;
+  }
+
+  /**
+   * Document and query input types sent to asymmetric embedding providers
+   * (undefined for providers that embed both the same way)
+   */
+  private getInputTypes(): EmbeddingInputTypes | undefined {
+    if (this.embeddingProvider === 'cohere') return { ...COHERE_INPUT_TYPES };
+    if (this.embeddingProvider === 'voyage') return { ...VOYAGE_INPUT_TYPES };
+    return undefined;
+  }
+
+  /**
+   * Client used for array embedding requests by the active cloud provider
+   */
+  private getBatchClient(): unknown {
+    switch (this.embeddingProvider) {
+      case 'gemini': return this.gemini;
+      case 'cohere': return this.cohere;
+      case 'voyage': return this.voyage;
+      case 'huggingface': return this.huggingface;
+      case 'openrouter': return this.openrouter;
+      default: return this.openai;
+    }
+  }
+
   /**
    * Scroll through all points in a collection
    * Used for exporting vectors to file storage
@@ -1699,6 +2598,138 @@ export class VectorManager {
     }
   }
 
+  // ========== Index Persistence ==========
+
+  /**
+   * Write every non-empty collection to the persisted index
+   * @returns Number of vectors written
+   */
+  async saveIndex(indexDir: string | undefined = this.indexDir, lastIndexedCommit?: string): Promise<number> {
+    if (!this.client) {
+      throw new VectorError('Not connected to Qdrant');
+    }
+    if (!indexDir) {
+      throw new VectorError('No index directory configured');
+    }
+
+    const snapshot = new Map<string, IndexSnapshotPoint[]>();
+    let total = 0;
+
+    for (const collection of Object.values(this.collections)) {
+      const points: IndexSnapshotPoint[] = [];
+      let offset: string | undefined;
+
+      try {
+        do {
+          const page = await this.scroll(collection, 256, offset);
+          for (const point of page.points) {
+            const { _id, ...payload } = point.payload;
+            points.push({
+              id: (_id as string) || String(point.id),
+              vector: point.vector,
+              payload
+            });
+          }
+          offset = page.next_page_offset;
+        } while (offset !== undefined);
+      } catch {
+        // Collection does not exist yet
+        continue;
+      }
+
+      if (points.length > 0) {
+        snapshot.set(collection, points);
+        total += points.length;
+      }
+    }
+
+    await writeIndexSnapshot(indexDir, this.getEmbeddingInfo(), snapshot, lastIndexedCommit);
+
+    // The local backend persists HNSW graphs of large collections so queries don't rebuild them
+    if (this.localStore) {
+      for (const collection of snapshot.keys()) {
+        const graph = this.localStore.buildIndex(collection);
+        if (graph) {
+          await writeIndexHnswGraph(indexDir, collection, graph);
+        }
+      }
+    }
+
+    return total;
+  }
+
+  /**
+   * Load the persisted index into any empty collections.
+   * Skipped entirely if the index was built with a different embedding model.
+   * @returns Number of vectors restored
+   */
+  async restoreIndex(indexDir: string | undefined = this.indexDir): Promise<number> {
+    if (!this.client) {
+      throw new VectorError('Not connected to Qdrant');
+    }
+    if (!indexDir) return 0;
+
+    const manifest = await readIndexSnapshotManifest(indexDir);
+    if (!manifest || !checkIndexCompatibility(manifest.fingerprint, this.getEmbeddingInfo()).compatible) {
+      return 0;
+    }
+
+    let restored = 0;
+    for (const collection of Object.values(this.collections)) {
+      if (!manifest.collections[collection]) continue;
+
+      const info = await this.client.getCollection(collection);
+      if ((info.points_count ?? 0) > 0) continue;
+
+      const points = await readIndexSnapshotCollection(indexDir, collection);
+      await this.upsertBatch(collection, points);
+      restored += points.length;
+
+      if (this.localStore) {
+        const graph = await readIndexHnswGraph(indexDir, collection);
+        if (graph && !this.localStore.loadIndex(collection, graph)) {
+          log.debug(`HNSW graph of ${collection} is stale; searching exactly until the next sync`);
+        }
+      }
+    }
+
+    return restored;
+  }
+
+  /**
+   * Replace the collections the persisted index lists with its contents,
+   * e.g. once `cv index verify --fix` pruned it: restoreIndex only fills
+   * empty collections. Nothing is cleared if the index was built with a
+   * different embedding model.
+   * @returns Number of vectors loaded, or null if the index can't be used
+   */
+  async reloadIndex(indexDir: string | undefined = this.indexDir): Promise<number | null> {
+    if (!this.client) {
+      throw new VectorError('Not connected to Qdrant');
+    }
+    if (!indexDir) return null;
+
+    const manifest = await readIndexSnapshotManifest(indexDir);
+    if (!manifest || !checkIndexCompatibility(manifest.fingerprint, this.getEmbeddingInfo()).compatible) {
+      return null;
+    }
+
+    for (const collection of Object.values(this.collections)) {
+      if (manifest.collections[collection] === undefined) continue;
+      await this.clearCollection(collection);
+    }
+
+    return this.restoreIndex(indexDir);
+  }
+
+  /**
+   * Read the persisted index manifest (null if nothing has been saved)
+   */
+  async getIndexSnapshot(indexDir: string | undefined = this.indexDir): Promise<IndexSnapshotManifest | null> {
+    if (!indexDir) return null;
+    return readIndexSnapshotManifest(indexDir);
+  }
+
   /**
    * Close connection
    */
@@ -1709,6 +2740,12 @@ export class VectorManager {
       this.cache = null;
     }
 
+    if (this.pgStore) {
+      await this.pgStore.close();
+      this.pgStore = null;
+    }
+    this.localStore = null;
+
     this.connected = false;
     this.client = null;
     this.openai = null;
@@ -1772,6 +2809,13 @@ export class VectorManager {
     return this.connected;
   }
 
+  /**
+   * Get the vector store backend in use
+   */
+  getBackend(): VectorBackend {
+    return this.backend;
+  }
+
   /**
    * Get the repository ID (if using isolated mode)
    */
@@ -1779,6 +2823,13 @@ export class VectorManager {
     return this.repoId;
   }
 
+  /**
+   * Change the HNSW search candidates for later searches (undefined: the store's default)
+   */
+  setEfSearch(efSearch: number | undefined): void {
+    this.efSearch = efSearch;
+  }
+
   /**
    * Get the current collection names
    */
@@ -1787,16 +2838,12 @@ export class VectorManager {
   }
 
   /**
-   * Hash string ID to numeric ID for Qdrant
+   * Point ID for a string ID: a UUID made from its SHA-256, since Qdrant
+   * only accepts integers and UUIDs (the string ID stays in the payload)
    */
-  private hashId(id: string): number {
-    let hash = 0;
-    for (let i = 0; i < id.length; i++) {
-      const char = id.charCodeAt(i);
-      hash = ((hash << 5) - hash) + char;
-      hash = hash & hash; // Convert to 32-bit integer
-    }
-    return Math.abs(hash);
+  private hashId(id: string): string {
+    const hex = createHash('sha256').update(id).digest('hex');
+    return `${hex.slice(0, 8)}-${hex.slice(8, 12)}-${hex.slice(12, 16)}-${hex.slice(16, 20)}-${hex.slice(20, 32)}`;
   }
 
   /**
@@ -1823,10 +2870,30 @@ export class VectorManager {
 
     // Add the actual code
     parts.push('');
-    parts.push(chunk.text);
+    parts.push(this.embeddedCodeText(chunk));
 
     return parts.join('\n');
   }
+
+  /**
+   * Cache key for a chunk's embedding: the prepared text minus the file path,
+   * so identical code in another file (or after a move) hits the cache
+   */
+  embeddingCacheKey(chunk: CodeChunk): string {
+    return [
+      chunk.language,
+      chunk.symbolName ? `${chunk.symbolKind}: ${chunk.symbolName}` : '',
+      chunk.docstring || '',
+      this.embeddedCodeText(chunk)
+    ].join('\n');
+  }
+
+  /**
+   * Chunk text as embedded, with the comments the stripComments option leaves out
+   */
+  private embeddedCodeText(chunk: CodeChunk): string {
+    return stripComments(chunk.text, chunk.language, this.stripCommentsMode, chunk.startLine === 1);
+  }
 }
 
 /**
@@ -1848,8 +2915,60 @@ export function createVectorManager(
   return new VectorManager(urlOrOptions);
 }
 
+/**
+ * Backend options for createVectorManager from the `vector` config block.
+ * The pgvector connection string falls back to CV_PGVECTOR_URL, and the Qdrant API key to
+ * CV_QDRANT_API_KEY or QDRANT_API_KEY, so they can stay out of config.
+ */
+export function getVectorBackendOptions(vector?: CVConfig['vector']): Pick<VectorManagerOptions, 'backend' | 'pgvector' | 'hnsw' | 'qdrant'> {
+  if (vector?.provider === 'local') {
+    return { backend: 'local', hnsw: vector.hnsw };
+  }
+  if (vector?.provider !== 'pgvector') {
+    const qdrant: QdrantOptions = {
+      apiKey: vector?.qdrant?.apiKey || process.env.CV_QDRANT_API_KEY || process.env.QDRANT_API_KEY || undefined,
+      collection: vector?.qdrant?.collection
+    };
+    return qdrant.apiKey || qdrant.collection ? { qdrant } : {};
+  }
+  return {
+    backend: 'pgvector',
+    pgvector: {
+      connectionString: vector.pgvector?.connectionString || process.env.CV_PGVECTOR_URL || '',
+      table: vector.pgvector?.table
+    }
+  };
+}
+
+/**
+ * Vector dimensions of a known embedding model (undefined for unknown or local models)
+ */
+export function getEmbeddingModelDimensions(model: string): number | undefined {
+  return EMBEDDING_MODELS[model]?.dimension;
+}
+
 // Re-export cache types for external use
-export { EmbeddingCache, createEmbeddingCache, CacheStats } from './embedding-cache.js';
+export {
+  EmbeddingCache,
+  createEmbeddingCache,
+  CacheStats,
+  getEmbeddingCacheDir,
+  DEFAULT_EMBEDDING_CACHE_MAX_BYTES
+} from './embedding-cache.js';
+export * from './index-metadata.js';
+export * from './index-store.js';
+export * from './index-verify.js';
+export * from './index-archive.js';
+export * from './embedding-batches.js';
+export * from './chunk-limits.js';
+export * from './symbol-lookup.js';
+export * from './references.js';
+export * from './workspace-index.js';
+export * from './score-threshold.js';
+export * from './result-order.js';
+export * from './pgvector.js';
+export * from './local-store.js';
+export * from './hnsw.js';
 export type { EmbeddingMetadata, EmbeddingIndex, EmbeddingCacheConfig } from './embedding-cache.js';
 
 /**
@@ -0,0 +1,281 @@
+/**
+ * Local Vector Store
+ *
+ * Keeps collections in memory so no vector database is needed. VectorManager
+ * fills them from the persisted index (.cv/index) on connect and `cv sync`
+ * writes them back, so the index snapshot is the store's only storage.
+ *
+ * Collections smaller than `minPoints` are searched exactly. Larger ones are
+ * searched through an HNSW graph (see hnsw.ts) that sync builds and persists
+ * next to the snapshot; upserts and deletes after that update the graph in
+ * place instead of rebuilding it.
+ *
+ * Like PgVectorStore, the class mirrors the subset of the Qdrant client API
+ * that VectorManager uses, including its filter format and `params.hnsw_ef`
+ * on search.
+ */
+
+import {
+  HnswIndex,
+  HnswGraph,
+  HnswSettings,
+  normalizeVector,
+  dotProduct,
+  DEFAULT_HNSW_EF_SEARCH,
+  DEFAULT_HNSW_MIN_POINTS
+} from './hnsw.js';
+import { compareSearchResults } from './result-order.js';
+
+interface LocalPoint {
+  id: string | number;
+  /** Unit length, like Qdrant stores cosine vectors */
+  vector: Float32Array;
+  payload: Record<string, unknown>;
+}
+
+interface LocalCollection {
+  size: number;
+  points: Map<string | number, LocalPoint>;
+  index: HnswIndex | null;
+}
+
+interface ScoredPoint {
+  point: LocalPoint;
+  score: number;
+}
+
+/**
+ * Whether a payload satisfies a Qdrant-style filter
+ * ({ must, should, must_not } with { key, match: { value | text | any } } conditions)
+ */
+export function matchesFilter(payload: Record<string, unknown>, filter: any): boolean {
+  const condition = (clause: any): boolean => {
+    if (clause.must || clause.should || clause.must_not) {
+      return group(clause);
+    }
+    if (!clause.key || !clause.match) {
+      throw new Error(`Unsupported filter condition: ${JSON.stringify(clause)}`);
+    }
+
+    const value = payload[clause.key];
+    const { match } = clause;
+    if (match.value !== undefined) {
+      return value !== undefined && value !== null && String(value) === String(match.value);
+    }
+    if (match.text !== undefined) {
+      return typeof value === 'string' && value.includes(String(match.text));
+    }
+    if (Array.isArray(match.any)) {
+      return value !== undefined && value !== null && match.any.map(String).includes(String(value));
+    }
+    throw new Error(`Unsupported filter match: ${JSON.stringify(match)}`);
+  };
+
+  const group = (f: any): boolean =>
+    (!f.must?.length || f.must.every(condition)) &&
+    (!f.should?.length || f.should.some(condition)) &&
+    (!f.must_not?.length || !f.must_not.some(condition));
+
+  return filter ? group(filter) : true;
+}
+
+/**
+ * In-memory store with a Qdrant-compatible surface
+ */
+export class LocalVectorStore {
+  private collections = new Map<string, LocalCollection>();
+  private settings: HnswSettings;
+  private minPoints: number;
+
+  constructor(settings: HnswSettings = {}) {
+    this.settings = settings;
+    this.minPoints = settings.minPoints ?? DEFAULT_HNSW_MIN_POINTS;
+  }
+
+  async getCollections(): Promise<{ collections: Array<{ name: string }> }> {
+    return { collections: [...this.collections.keys()].map(name => ({ name })) };
+  }
+
+  async createCollection(name: string, options: { vectors: { size: number } }): Promise<void> {
+    if (!this.collections.has(name)) {
+      this.collections.set(name, { size: options.vectors.size, points: new Map(), index: null });
+    }
+  }
+
+  async getCollection(name: string): Promise<any> {
+    const collection = this.getLocalCollection(name);
+    return {
+      points_count: collection.points.size,
+      indexed_vectors_count: collection.index?.size ?? 0,
+      config: { params: { vectors: { size: collection.size, distance: 'Cosine' } } }
+    };
+  }
+
+  async deleteCollection(name: string): Promise<void> {
+    this.collections.delete(name);
+  }
+
+  async upsert(
+    collection: string,
+    request: { points: Array<{ id: string | number; vector: number[]; payload?: Record<string, unknown> }> }
+  ): Promise<void> {
+    const target = this.getLocalCollection(collection);
+    // The last write of an ID within a request wins
+    const points = new Map(request.points.map(point => [point.id, point]));
+
+    for (const point of points.values()) {
+      if (point.vector.length !== target.size) {
+        throw new Error(`Vector dimension error: expected dim: ${target.size}, got ${point.vector.length}`);
+      }
+    }
+
+    if (target.index) {
+      target.index.remove([...points.keys()].filter(id => target.points.has(id)));
+    }
+    for (const point of points.values()) {
+      const stored: LocalPoint = { id: point.id, vector: normalizeVector(point.vector), payload: point.payload ?? {} };
+      target.points.set(point.id, stored);
+      target.index?.add(point.id, stored.vector);
+    }
+  }
+
+  async search(
+    collection: string,
+    request: { vector: number[]; limit: number; filter?: any; with_vector?: boolean; params?: { hnsw_ef?: number } }
+  ): Promise<Array<{ id: string | number; score: number; payload: Record<string, unknown>; vector?: number[] }>> {
+    const target = this.getLocalCollection(collection);
+    if (request.vector.length !== target.size) {
+      throw new Error(`Vector dimension error: expected dim: ${target.size}, got ${request.vector.length}`);
+    }
+
+    const query = normalizeVector(request.vector);
+    const matches = (this.useIndex(target) && this.searchIndex(target, query, request)) ||
+      this.searchExact(target, query, request.limit, request.filter);
+
+    return matches.map(({ point, score }) => ({
+      id: point.id,
+      score,
+      payload: point.payload,
+      ...(request.with_vector ? { vector: Array.from(point.vector) } : {})
+    }));
+  }
+
+  /**
+   * Page through a collection in insertion order. The offset is a point
+   * offset, returned as next_page_offset while more points remain.
+   */
+  async scroll(
+    collection: string,
+    request: { limit?: number; offset?: string | number; filter?: any }
+  ): Promise<{ points: Array<{ id: string | number; vector: number[]; payload: Record<string, unknown> }>; next_page_offset?: number }> {
+    const target = this.getLocalCollection(collection);
+    const limit = request.limit ?? 100;
+    const offset = Number(request.offset ?? 0) || 0;
+
+    const matching = [...target.points.values()].filter(point => matchesFilter(point.payload, request.filter));
+    return {
+      points: matching.slice(offset, offset + limit).map(point => ({
+        id: point.id,
+        vector: Array.from(point.vector),
+        payload: point.payload
+      })),
+      next_page_offset: offset + limit < matching.length ? offset + limit : undefined
+    };
+  }
+
+  async delete(collection: string, request: { points?: Array<string | number>; filter?: any }): Promise<void> {
+    const target = this.getLocalCollection(collection);
+    const ids = request.points
+      ? request.points.filter(id => target.points.has(id))
+      : [...target.points.values()].filter(point => matchesFilter(point.payload, request.filter)).map(point => point.id);
+
+    for (const id of ids) {
+      target.points.delete(id);
+    }
+    target.index?.remove(ids);
+  }
+
+  /**
+   * Build the HNSW graph of a collection that has reached `minPoints`.
+   * @returns The collection's graph, or null if it is searched exactly
+   */
+  buildIndex(collection: string): HnswGraph | null {
+    const target = this.getLocalCollection(collection);
+    if (this.settings.enabled === false) return null;
+
+    if (!target.index && target.points.size >= this.minPoints) {
+      target.index = new HnswIndex(this.settings);
+      for (const point of target.points.values()) {
+        target.index.add(point.id, point.vector);
+      }
+    }
+    return target.index ? target.index.toGraph() : null;
+  }
+
+  /**
+   * Attach a persisted graph to a collection. Graphs that don't cover exactly the
+   * collection's points, or were built with a different m/efConstruction, are ignored
+   * and rebuilt by the next sync.
+   * @returns Whether the graph was attached
+   */
+  loadIndex(collection: string, graph: HnswGraph): boolean {
+    const target = this.getLocalCollection(collection);
+    if (this.settings.enabled === false) return false;
+
+    const expected = new HnswIndex(this.settings).toGraph();
+    if (graph.m !== expected.m || graph.efConstruction !== expected.efConstruction) {
+      return false;
+    }
+
+    const index = HnswIndex.fromGraph(graph, id => target.points.get(id)?.vector);
+    if (!index || index.size !== target.points.size) {
+      return false;
+    }
+    target.index = index;
+    return true;
+  }
+
+  private useIndex(collection: LocalCollection): boolean {
+    return this.settings.enabled !== false && collection.index !== null && collection.points.size >= this.minPoints;
+  }
+
+  /**
+   * Approximate search. With a filter, extra candidates are fetched and
+   * filtered; if too few pass, returns null so the caller searches exactly.
+   */
+  private searchIndex(
+    collection: LocalCollection,
+    query: Float32Array,
+    request: { limit: number; filter?: any; params?: { hnsw_ef?: number } }
+  ): ScoredPoint[] | null {
+    const ef = request.params?.hnsw_ef ?? DEFAULT_HNSW_EF_SEARCH;
+    const k = request.filter ? Math.max(request.limit * 10, ef) : request.limit;
+
+    const matches = collection.index!.search(query, k, Math.max(ef, k))
+      .map(match => ({ point: collection.points.get(match.id)!, score: match.score }))
+      .filter(match => matchesFilter(match.point.payload, request.filter))
+      .slice(0, request.limit);
+
+    return request.filter && matches.length < request.limit ? null : matches;
+  }
+
+  private searchExact(collection: LocalCollection, query: Float32Array, limit: number, filter?: any): ScoredPoint[] {
+    const matches: ScoredPoint[] = [];
+    for (const point of collection.points.values()) {
+      if (filter && !matchesFilter(point.payload, filter)) continue;
+      matches.push({ point, score: dotProduct(query, point.vector) });
+    }
+    // Ties at the cutoff break the same way however the points were loaded
+    return matches
+      .sort((a, b) => b.score - a.score || compareSearchResults({ ...a.point, score: a.score }, { ...b.point, score: b.score }))
+      .slice(0, limit);
+  }
+
+  private getLocalCollection(name: string): LocalCollection {
+    const collection = this.collections.get(name);
+    if (!collection) {
+      throw Object.assign(new Error(`Collection ${name} not found`), { status: 404 });
+    }
+    return collection;
+  }
+}
@@ -0,0 +1,240 @@
+/**
+ * Symbol References
+ * Finds the call sites of a symbol for `cv refs <name>`, from the chunk
+ * metadata and text persisted in .cv/index. The definitions come from the
+ * symbol names (see lookupSymbols); the call sites from an identifier scan
+ * of the indexed code in the same language, with comments and string
+ * literals skipped. Each site is attributed to the function or method
+ * chunk that encloses it.
+ *
+ * This is a heuristic: any identifier with the same name counts, so a
+ * method of another type with the same name is reported too.
+ */
+
+import { CodeChunkPayload, SymbolKind } from '@cv-git/shared';
+import { lookupSymbols, SymbolMatch } from './symbol-lookup.js';
+
+/**
+ * Languages whose files can call each other's symbols. The parsers index
+ * JavaScript as typescript; a C header may be included from C++.
+ */
+const LANGUAGE_FAMILIES: string[][] = [
+  ['typescript', 'javascript'],
+  ['c', 'cpp']
+];
+
+/** Languages whose line comments start with # rather than // */
+const HASH_COMMENT_LANGUAGES = ['python', 'ruby', 'bash', 'zsh'];
+
+/** Kinds that can enclose a call */
+const CALLER_KINDS: SymbolKind[] = ['function', 'method'];
+
+/** Keywords that introduce a definition of the name that follows */
+const DEFINITION_PREFIX = /\b(func|function|def|fn|class|interface|struct|enum|type|trait)\s+(\([^)]*\)\s*)?$/;
+
+export interface ReferenceOptions {
+  /** Include references that are not calls (assignments, imports, passing the function as a value) */
+  all?: boolean;
+  limit?: number;
+}
+
+export interface SymbolCaller {
+  name: string;
+  kind?: SymbolKind;
+  startLine: number;
+  endLine: number;
+}
+
+export interface SymbolReference {
+  file: string;
+  line: number;
+  /** 1-based column of the identifier */
+  column: number;
+  kind: 'call' | 'reference';
+  /** The function or method the reference is in; unset at the top level of a file */
+  caller?: SymbolCaller;
+  /** The source line, trimmed */
+  text: string;
+}
+
+export interface ReferenceResult {
+  definitions: SymbolMatch[];
+  references: SymbolReference[];
+}
+
+/**
+ * Definitions of a symbol (exact name matches) and the places it is used
+ */
+export function findReferences(chunks: CodeChunkPayload[], name: string, options: ReferenceOptions = {}): ReferenceResult {
+  const definitions = lookupSymbols(chunks, name).filter(match => match.name === name);
+
+  // With no definition indexed (e.g. a library function), scan every language
+  const languages = definitions.length > 0
+    ? new Set(definitions.flatMap(def => languageFamily(def.language)))
+    : null;
+
+  const identifier = new RegExp(`(?<![\\w$])${escapeRegExp(name)}(?![\\w$])`, 'g');
+  const byFile = groupByFile(chunks.filter(chunk => !languages || languages.has(chunk.language)));
+  const seen = new Set<string>();
+  const references: SymbolReference[] = [];
+
+  for (const [file, fileChunks] of byFile) {
+    for (const chunk of fileChunks) {
+      const lines = chunk.text.split('\n');
+      let inBlockComment = false;
+
+      lines.forEach((raw, index) => {
+        const line = chunk.startLine + index;
+        const stripped = stripCommentsAndStrings(raw, chunk.language, inBlockComment);
+        inBlockComment = stripped.inBlockComment;
+
+        for (const match of stripped.code.matchAll(identifier)) {
+          const column = match.index! + 1;
+          const key = `${file}:${line}:${column}`;
+          if (seen.has(key)) continue; // Overlapping chunks cover some lines twice
+          seen.add(key);
+
+          const before = stripped.code.slice(0, match.index);
+          if (DEFINITION_PREFIX.test(before) || isDefinitionLine(definitions, file, line)) {
+            continue;
+          }
+
+          const after = stripped.code.slice(match.index! + name.length);
+          const kind = /^\s*(<[^<>()]*>\s*)?\(/.test(after) ? 'call' : 'reference';
+          if (kind === 'reference' && !options.all) continue;
+
+          references.push({
+            file,
+            line,
+            column,
+            kind,
+            caller: enclosingCaller(fileChunks, line),
+            text: raw.trim()
+          });
+        }
+      });
+    }
+  }
+
+  references.sort((a, b) => a.file.localeCompare(b.file) || a.line - b.line || a.column - b.column);
+  return {
+    definitions,
+    references: options.limit ? references.slice(0, options.limit) : references
+  };
+}
+
+/**
+ * The smallest function or method chunk that covers a line. Chunks split
+ * from one long function all carry its name, so any of them will do.
+ */
+function enclosingCaller(fileChunks: CodeChunkPayload[], line: number): SymbolCaller | undefined {
+  let best: CodeChunkPayload | undefined;
+  for (const chunk of fileChunks) {
+    if (!chunk.symbolName || !chunk.symbolKind || !CALLER_KINDS.includes(chunk.symbolKind)) continue;
+    if (line < chunk.startLine || line > chunk.endLine) continue;
+    if (!best || chunk.endLine - chunk.startLine < best.endLine - best.startLine) {
+      best = chunk;
+    }
+  }
+  if (!best) return undefined;
+
+  // Report the whole symbol, not the piece of it this chunk holds
+  const pieces = fileChunks.filter(chunk => chunk.symbolName === best!.symbolName && chunk.symbolKind === best!.symbolKind);
+  return {
+    name: best.symbolName!,
+    kind: best.symbolKind,
+    startLine: Math.min(...pieces.map(chunk => chunk.startLine)),
+    endLine: Math.max(...pieces.map(chunk => chunk.endLine))
+  };
+}
+
+function isDefinitionLine(definitions: SymbolMatch[], file: string, line: number): boolean {
+  return definitions.some(def => def.file === file && def.startLine === line);
+}
+
+/**
+ * Languages whose symbols code in this language can use
+ */
+export function languageFamily(language: string): string[] {
+  return LANGUAGE_FAMILIES.find(family => family.includes(language)) ?? [language];
+}
+
+function groupByFile(chunks: CodeChunkPayload[]): Map<string, CodeChunkPayload[]> {
+  const byFile = new Map<string, CodeChunkPayload[]>();
+  for (const chunk of chunks) {
+    const list = byFile.get(chunk.file);
+    if (list) {
+      list.push(chunk);
+    } else {
+      byFile.set(chunk.file, [chunk]);
+    }
+  }
+  for (const list of byFile.values()) {
+    list.sort((a, b) => a.startLine - b.startLine);
+  }
+  return byFile;
+}
+
+/**
+ * A line with comments and the contents of string literals blanked out, so
+ * columns still line up. Block comments (/* *\/) may continue across lines.
+ */
+export function stripCommentsAndStrings(
+  line: string,
+  language: string,
+  inBlockComment = false
+): { code: string; inBlockComment: boolean } {
+  const hashComments = HASH_COMMENT_LANGUAGES.includes(language);
+  let code = '';
+  let quote: string | null = null;
+
+  for (let i = 0; i < line.length; i++) {
+    const char = line[i];
+    const next = line[i + 1];
+
+    if (inBlockComment) {
+      if (char === '*' && next === '/') {
+        inBlockComment = false;
+        code += '  ';
+        i++;
+      } else {
+        code += ' ';
+      }
+      continue;
+    }
+
+    if (quote) {
+      if (char === '\\') {
+        code += '  ';
+        i++;
+      } else if (char === quote) {
+        quote = null;
+        code += char;
+      } else {
+        code += ' ';
+      }
+      continue;
+    }
+
+    if (hashComments ? char === '#' : char === '/' && next === '/') {
+      break;
+    }
+    if (!hashComments && char === '/' && next === '*') {
+      inBlockComment = true;
+      code += '  ';
+      i++;
+      continue;
+    }
+    // Rust lifetimes ('a) look like an unterminated char literal
+    if (char === '"' || char === '`' || (char === "'" && !(language === 'rust' && line.indexOf("'", i + 1) === -1))) {
+      quote = char;
+    }
+    code += char;
+  }
+
+  return { code, inBlockComment };
+}
+
+function escapeRegExp(text: string): string {
+  return text.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+}
@@ -0,0 +1,53 @@
+/**
+ * Result Order
+ * Breaks score ties in search results deterministically. Stores return
+ * equal scores in whatever order their index yields them, which can change
+ * between runs or rebuilds; ties are ordered by file path, then start line,
+ * then ID, so the same query gives the same order (and the same --json).
+ */
+
+/**
+ * Candidates fetched past the requested limit, so results that tie at the
+ * cutoff are ordered before it is applied. A tie run longer than this past
+ * the cutoff can still be cut in store order.
+ */
+export const SEARCH_TIE_MARGIN = 10;
+
+interface OrderedResult {
+  id: string | number;
+  score: number;
+  payload?: Record<string, unknown> | null;
+}
+
+/**
+ * Higher score first; ties by file path, start line, then ID
+ */
+export function compareSearchResults(a: OrderedResult, b: OrderedResult): number {
+  return b.score - a.score ||
+    compareStrings(payloadPath(a), payloadPath(b)) ||
+    payloadLine(a) - payloadLine(b) ||
+    compareStrings(String(a.id), String(b.id));
+}
+
+/**
+ * Results sorted by compareSearchResults (a copy; the input is not changed)
+ */
+export function sortSearchResults<T extends OrderedResult>(results: T[]): T[] {
+  return [...results].sort(compareSearchResults);
+}
+
+/** Code and document chunks have a file; summaries a path */
+function payloadPath(result: OrderedResult): string {
+  const value = result.payload?.file ?? result.payload?.path;
+  return typeof value === 'string' ? value : '';
+}
+
+function payloadLine(result: OrderedResult): number {
+  const value = result.payload?.startLine;
+  return typeof value === 'number' ? value : 0;
+}
+
+/** Code-unit order, so ties break the same way in every locale */
+function compareStrings(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
@@ -0,0 +1,39 @@
+/**
+ * Score Threshold
+ * Filters search results by minimum similarity while remembering the best rejected score
+ */
+
+/** Default minimum similarity for context retrieval */
+export const DEFAULT_CONTEXT_MIN_SCORE = 0.25;
+
+/** Default number of chunks retrieved for context */
+export const DEFAULT_CONTEXT_TOP_K = 10;
+
+export interface ThresholdedResults<T> {
+  /** Results scoring at or above the threshold, in their original order */
+  results: T[];
+  /** Highest score among rejected results, if any were rejected */
+  nearMissScore?: number;
+}
+
+/**
+ * Keep results at or above minScore.
+ * The best rejected score tells users whether lowering the threshold would help.
+ */
+export function applyMinScore<T extends { score: number }>(
+  results: T[],
+  minScore: number
+): ThresholdedResults<T> {
+  const kept: T[] = [];
+  let nearMissScore: number | undefined;
+
+  for (const result of results) {
+    if (result.score >= minScore) {
+      kept.push(result);
+    } else if (nearMissScore === undefined || result.score > nearMissScore) {
+      nearMissScore = result.score;
+    }
+  }
+
+  return { results: kept, nearMissScore };
+}
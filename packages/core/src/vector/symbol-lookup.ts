@@ -0,0 +1,191 @@
+/**
+ * Symbol Lookup
+ * Finds symbols by name in the chunk metadata persisted in .cv/index, for
+ * `cv symbol <name>`. Unlike semantic search this needs no embedding call
+ * or running vector database: matching is on the stored symbol names,
+ * exact matches first, then fuzzy ones.
+ */
+
+import { CodeChunkPayload, SymbolKind } from '@cv-git/shared';
+import { readIndexSnapshotManifest, readIndexSnapshotCollection } from './index-store.js';
+
+/** Kind filters accepted by `cv symbol --kind` */
+export const SYMBOL_KIND_FILTERS: Record<string, SymbolKind[]> = {
+  func: ['function'],
+  method: ['method'],
+  type: ['class', 'interface', 'type', 'struct', 'enum']
+};
+
+/** Lines of code shown per match */
+const SNIPPET_LINES = 5;
+
+/** Score of a name that differs from the query only in case */
+const EXACT_CASE_INSENSITIVE = 0.95;
+
+/** Fuzzy matches scoring below this are dropped */
+const MIN_FUZZY_SCORE = 0.3;
+
+export interface SymbolLookupOptions {
+  /** A SYMBOL_KIND_FILTERS key or a symbol kind */
+  kind?: string;
+  limit?: number;
+}
+
+export interface SymbolMatch {
+  name: string;
+  kind?: SymbolKind;
+  file: string;
+  language: string;
+  startLine: number;
+  endLine: number;
+  signature?: string;
+  snippet: string;
+  match: 'exact' | 'fuzzy';
+  /** 1 for an exact, case-sensitive match */
+  score: number;
+}
+
+/**
+ * Code chunk payloads from the persisted index (empty if nothing has been saved)
+ */
+export async function readIndexedCodeChunks(indexDir: string): Promise<CodeChunkPayload[]> {
+  const manifest = await readIndexSnapshotManifest(indexDir);
+  if (!manifest) {
+    return [];
+  }
+
+  // Collections are prefixed with the repo ID when isolated
+  const chunks: CodeChunkPayload[] = [];
+  for (const collection of Object.keys(manifest.collections)) {
+    if (collection === 'code_chunks' || collection.endsWith('_code_chunks')) {
+      const points = await readIndexSnapshotCollection(indexDir, collection);
+      chunks.push(...points.map(point => point.payload as CodeChunkPayload));
+    }
+  }
+  return chunks;
+}
+
+/**
+ * Symbols whose name matches the query, exact matches first.
+ * Chunks split from one symbol are reported once, spanning all of them.
+ */
+export function lookupSymbols(chunks: CodeChunkPayload[], query: string, options: SymbolLookupOptions = {}): SymbolMatch[] {
+  const kinds = resolveKindFilter(options.kind);
+  const symbols = new Map<string, SymbolMatch>();
+
+  for (const chunk of chunks) {
+    if (!chunk.symbolName || (kinds && (!chunk.symbolKind || !kinds.includes(chunk.symbolKind)))) {
+      continue;
+    }
+    const score = scoreSymbolName(chunk.symbolName, query);
+    if (score < MIN_FUZZY_SCORE) {
+      continue;
+    }
+
+    const key = `${chunk.file}\0${chunk.symbolName}\0${chunk.symbolKind ?? ''}`;
+    const existing = symbols.get(key);
+    if (existing) {
+      if (chunk.startLine < existing.startLine) {
+        existing.startLine = chunk.startLine;
+        existing.snippet = snippet(chunk.text);
+        existing.signature = chunk.signature ?? existing.signature;
+      }
+      existing.endLine = Math.max(existing.endLine, chunk.endLine);
+      continue;
+    }
+
+    symbols.set(key, {
+      name: chunk.symbolName,
+      kind: chunk.symbolKind,
+      file: chunk.file,
+      language: chunk.language,
+      startLine: chunk.startLine,
+      endLine: chunk.endLine,
+      signature: chunk.signature,
+      snippet: snippet(chunk.text),
+      match: score >= EXACT_CASE_INSENSITIVE ? 'exact' : 'fuzzy',
+      score
+    });
+  }
+
+  const matches = [...symbols.values()].sort((a, b) =>
+    b.score - a.score ||
+    a.name.length - b.name.length ||
+    a.file.localeCompare(b.file) ||
+    a.startLine - b.startLine
+  );
+  return options.limit ? matches.slice(0, options.limit) : matches;
+}
+
+/**
+ * Kinds a --kind value stands for; throws on an unknown value
+ */
+export function resolveKindFilter(kind: string | undefined): SymbolKind[] | undefined {
+  if (!kind) {
+    return undefined;
+  }
+  const filter = SYMBOL_KIND_FILTERS[kind.toLowerCase()];
+  if (filter) {
+    return filter;
+  }
+  const all = new Set(Object.values(SYMBOL_KIND_FILTERS).flat());
+  if (all.has(kind as SymbolKind)) {
+    return [kind as SymbolKind];
+  }
+  throw new Error(`Unknown symbol kind "${kind}". Use one of: ${Object.keys(SYMBOL_KIND_FILTERS).join(', ')}`);
+}
+
+/**
+ * How well a symbol name matches the query, from 0 to 1: exact, then
+ * prefix, substring, typo (edit distance) and abbreviation (subsequence)
+ */
+export function scoreSymbolName(name: string, query: string): number {
+  if (name === query) return 1;
+
+  const n = name.toLowerCase();
+  const q = query.toLowerCase();
+  if (!q) return 0;
+  if (n === q) return EXACT_CASE_INSENSITIVE;
+
+  // Longer names matching the same prefix or substring rank lower
+  const coverage = q.length / n.length;
+  if (n.startsWith(q)) return 0.7 + 0.2 * coverage;
+  if (n.includes(q)) return 0.5 + 0.2 * coverage;
+
+  const maxTypos = q.length <= 4 ? 1 : 2;
+  const distance = editDistance(n, q);
+  if (distance <= maxTypos) return 0.6 - 0.1 * distance;
+
+  if (isSubsequence(q, n)) return 0.3 + 0.1 * coverage;
+  return 0;
+}
+
+function editDistance(a: string, b: string): number {
+  let previous = Array.from({ length: b.length + 1 }, (_, j) => j);
+  for (let i = 1; i <= a.length; i++) {
+    const current = [i];
+    for (let j = 1; j <= b.length; j++) {
+      current[j] = Math.min(
+        previous[j] + 1,
+        current[j - 1] + 1,
+        previous[j - 1] + (a[i - 1] === b[j - 1] ? 0 : 1)
+      );
+    }
+    previous = current;
+  }
+  return previous[b.length];
+}
+
+function isSubsequence(query: string, name: string): boolean {
+  let i = 0;
+  for (const char of name) {
+    if (char === query[i]) i++;
+    if (i === query.length) return true;
+  }
+  return false;
+}
+
+function snippet(text: string): string {
+  const lines = text.split('\n');
+  return lines.slice(0, SNIPPET_LINES).join('\n') + (lines.length > SNIPPET_LINES ? '\n...' : '');
+}
@@ -0,0 +1,154 @@
+/**
+ * Workspace Index
+ *
+ * One index over the repositories of a workspace (.cv/workspace.json), so
+ * `cv explain` and `cv search` run in the workspace root search all of
+ * them. Each member repo is synced on its own, into its own index at its
+ * own HEAD; the workspace index is then assembled from the members'
+ * persisted snapshots without embedding anything again. Paths are
+ * prefixed with the repo's path from the workspace root and each point
+ * records its repo, so citations name the repo and still resolve against
+ * the workspace root.
+ *
+ * Storage structure:
+ * <workspace>/.cv/
+ * ├── workspace.json          # Member repos
+ * ├── vector-index.json       # Index metadata, with each repo's indexed commit
+ * └── index/                  # Snapshot of the merged collections
+ */
+
+import * as path from 'path';
+import { VectorError } from '@cv-git/shared';
+import { checkIndexCompatibility, EmbeddingIdentity, IndexedRepo, IndexWarning, readIndexMetadata } from './index-metadata.js';
+import {
+  getIndexDir,
+  IndexSnapshotPoint,
+  readIndexSnapshotCollection,
+  readIndexSnapshotManifest
+} from './index-store.js';
+
+/**
+ * Collections merged into the workspace index. Commits and summaries are
+ * left out: commit SHAs only mean something in their own repo, and
+ * summaries link to parents by ID.
+ */
+export const WORKSPACE_COLLECTIONS = ['code_chunks', 'docstrings', 'document_chunks'] as const;
+
+export type WorkspaceCollection = typeof WORKSPACE_COLLECTIONS[number];
+
+export interface WorkspaceMember {
+  name: string;
+  /** Path from the workspace root */
+  path: string;
+  /** Absolute path to the repo */
+  root: string;
+}
+
+export interface WorkspaceIndex {
+  fingerprint: EmbeddingIdentity;
+  /** Points by workspace collection name */
+  collections: Map<string, IndexSnapshotPoint[]>;
+  repos: IndexedRepo[];
+  /** Code left out of the members' indexes, with workspace paths */
+  warnings: IndexWarning[];
+  /** Members that could not be included, and why */
+  skipped: Array<{ name: string; reason: string }>;
+}
+
+/**
+ * Path of a member repo's file in the workspace index (always with /)
+ */
+export function workspaceFilePath(repoPath: string, file: string): string {
+  const prefix = repoPath.split(path.sep).join('/').replace(/^\.(\/|$)/, '').replace(/\/+$/, '');
+  return prefix ? `${prefix}/${file}` : file;
+}
+
+/**
+ * Merge the members' persisted indexes into the workspace collections
+ * (`targets` names them). The first indexed member sets the embedding
+ * fingerprint; members embedded differently cannot share the index and
+ * are skipped.
+ */
+export async function buildWorkspaceIndex(
+  members: WorkspaceMember[],
+  targets: Record<WorkspaceCollection, string>
+): Promise<WorkspaceIndex> {
+  let fingerprint: EmbeddingIdentity | undefined;
+  const collections = new Map<string, IndexSnapshotPoint[]>();
+  const repos: IndexedRepo[] = [];
+  const warnings: IndexWarning[] = [];
+  const skipped: Array<{ name: string; reason: string }> = [];
+
+  for (const member of members) {
+    const indexDir = getIndexDir(member.root);
+    const manifest = await readIndexSnapshotManifest(indexDir);
+    if (!manifest) {
+      skipped.push({ name: member.name, reason: 'not indexed' });
+      continue;
+    }
+
+    if (!fingerprint) {
+      fingerprint = manifest.fingerprint;
+    } else if (!checkIndexCompatibility(fingerprint, manifest.fingerprint).compatible) {
+      skipped.push({
+        name: member.name,
+        reason: `embedded with ${manifest.fingerprint.provider} / ${manifest.fingerprint.model} (${manifest.fingerprint.dimensions}d), ` +
+          `the workspace index with ${fingerprint.provider} / ${fingerprint.model} (${fingerprint.dimensions}d)`
+      });
+      continue;
+    }
+
+    let chunks = 0;
+    for (const collection of WORKSPACE_COLLECTIONS) {
+      // Member collections are prefixed with the member's repo ID
+      const source = Object.keys(manifest.collections).find(name => name === collection || name.endsWith(`_${collection}`));
+      if (!source) continue;
+
+      const points = (await readIndexSnapshotCollection(indexDir, source)).map(point => toWorkspacePoint(member, point));
+      if (collection === 'code_chunks') chunks = points.length;
+
+      const target = targets[collection];
+      const merged = collections.get(target);
+      if (merged) {
+        merged.push(...points);
+      } else {
+        collections.set(target, points);
+      }
+    }
+
+    const metadata = await readIndexMetadata(member.root);
+    for (const warning of metadata?.warnings || []) {
+      warnings.push({ ...warning, file: workspaceFilePath(member.path, warning.file) });
+    }
+    repos.push({
+      name: member.name,
+      path: member.path,
+      commit: metadata?.lastIndexedCommit ?? manifest.lastIndexedCommit,
+      chunks
+    });
+  }
+
+  if (!fingerprint) {
+    throw new VectorError('No repository in the workspace has been indexed. Run `cv sync --force` in the workspace root.');
+  }
+
+  return { fingerprint, collections, repos, warnings, skipped };
+}
+
+/**
+ * A member's point with workspace IDs and paths, tagged with its repo
+ */
+function toWorkspacePoint(member: WorkspaceMember, point: IndexSnapshotPoint): IndexSnapshotPoint {
+  const payload: Record<string, unknown> = { ...point.payload, repo: member.name };
+  if (typeof payload.file === 'string') {
+    payload.file = workspaceFilePath(member.path, payload.file);
+  }
+  if (typeof payload.id === 'string') {
+    payload.id = workspaceFilePath(member.path, payload.id);
+  }
+  // A member's root package is the member's directory
+  if (typeof payload.package === 'string') {
+    payload.package = workspaceFilePath(member.path, payload.package).replace(/\/\.$/, '');
+  }
+  return { ...point, id: workspaceFilePath(member.path, point.id), payload };
+}
@@ -32,6 +32,13 @@ export {
   type AnthropicAPICredential,
   type OpenAIAPICredential,
   type OpenRouterAPICredential,
+  type OllamaEndpointCredential,
+  type AzureOpenAICredential,
+  type GeminiAPICredential,
+  type CohereAPICredential,
+  type VoyageAPICredential,
+  type HuggingFaceAPICredential,
+  type OpenAICompatibleCredential,
   type APIKeyCredential,
   // DNS providers
   type CloudflareCredential,
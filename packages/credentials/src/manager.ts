@@ -28,6 +28,13 @@ import {
   AnthropicAPICredential,
   OpenAIAPICredential,
   OpenRouterAPICredential,
+  OllamaEndpointCredential,
+  AzureOpenAICredential,
+  GeminiAPICredential,
+  CohereAPICredential,
+  VoyageAPICredential,
+  HuggingFaceAPICredential,
+  OpenAICompatibleCredential,
   // DNS providers
   CloudflareCredential,
   // DevOps/Cloud providers
@@ -45,11 +52,15 @@ export interface CredentialManagerOptions {
 
   /** Path to metadata file */
   metadataPath?: string;
+
+  /** Config profile whose credentials are preferred (default: CV_PROFILE) */
+  profile?: string;
 }
 
 export class CredentialManager {
   private storage: CredentialStorage;
   private metadataPath: string;
+  private profile?: string;
   private initialized: boolean = false;
 
   constructor(options?: CredentialManagerOptions) {
@@ -59,6 +70,14 @@ export class CredentialManager {
 
     // Use provided storage or start with keychain (will validate on init)
     this.storage = options?.storage || new KeychainStorage();
+    this.profile = options?.profile || process.env.CV_PROFILE || undefined;
+  }
+
+  /**
+   * Name new credentials are stored under: the active profile, else 'default'
+   */
+  getProfileName(): string {
+    return this.profile || 'default';
   }
 
   /**
@@ -136,10 +155,13 @@ export class CredentialManager {
   async retrieve(type: CredentialType, name?: string): Promise<Credential | null> {
     await this.init();
 
-    // If no name provided, get the first credential of this type
+    // If no name provided, prefer the active profile's credential, then 'default', then the first of this type
     if (!name) {
       const metadata = await this.loadMetadata();
-      const match = metadata.find((m) => m.type === type);
+      const ofType = metadata.filter((m) => m.type === type);
+      const match = ofType.find((m) => m.name === this.getProfileName()) ||
+        ofType.find((m) => m.name === 'default') ||
+        ofType[0];
       if (!match) return null;
       name = match.name;
     }
@@ -420,6 +442,62 @@ export class CredentialManager {
     return cred ? (cred as OpenRouterAPICredential).apiKey : null;
   }
 
+  /**
+   * Get Ollama endpoint (base URL and embedding model)
+   */
+  async getOllamaEndpoint(): Promise<OllamaEndpointCredential | null> {
+    const cred = await this.retrieve(CredentialType.OLLAMA_ENDPOINT);
+    return cred as OllamaEndpointCredential | null;
+  }
+
+  /**
+   * Get Azure OpenAI endpoint, key and deployments
+   */
+  async getAzureOpenAI(): Promise<AzureOpenAICredential | null> {
+    const cred = await this.retrieve(CredentialType.AZURE_OPENAI);
+    return cred as AzureOpenAICredential | null;
+  }
+
+  /**
+   * Get Google Gemini API key
+   */
+  async getGeminiKey(): Promise<string | null> {
+    const cred = await this.retrieve(CredentialType.GEMINI_API);
+    return cred ? (cred as GeminiAPICredential).apiKey : null;
+  }
+
+  /**
+   * Get Cohere API key
+   */
+  async getCohereKey(): Promise<string | null> {
+    const cred = await this.retrieve(CredentialType.COHERE_API);
+    return cred ? (cred as CohereAPICredential).apiKey : null;
+  }
+
+  /**
+   * Get Voyage AI API key
+   */
+  async getVoyageKey(): Promise<string | null> {
+    const cred = await this.retrieve(CredentialType.VOYAGE_API);
+    return cred ? (cred as VoyageAPICredential).apiKey : null;
+  }
+
+  /**
+   * Get HuggingFace access token and default embedding model
+   */
+  async getHuggingFace(): Promise<HuggingFaceAPICredential | null> {
+    const cred = await this.retrieve(CredentialType.HUGGINGFACE_API);
+    return cred as HuggingFaceAPICredential | null;
+  }
+
+  /**
+   * Get the OpenAI-compatible gateway URL, key and models
+   */
+  async getOpenAICompatible(): Promise<OpenAICompatibleCredential | null> {
+    const cred = await this.retrieve(CredentialType.OPENAI_COMPATIBLE);
+    return cred as OpenAICompatibleCredential | null;
+  }
+
   // ============================================================================
   // DNS Provider Credentials
   // ============================================================================
@@ -547,6 +625,26 @@ export class CredentialManager {
         type: CredentialType.OPENROUTER_API,
         name: 'default',
       },
+      {
+        envVar: 'GEMINI_API_KEY',
+        type: CredentialType.GEMINI_API,
+        name: 'default',
+      },
+      {
+        envVar: 'COHERE_API_KEY',
+        type: CredentialType.COHERE_API,
+        name: 'default',
+      },
+      {
+        envVar: 'VOYAGE_API_KEY',
+        type: CredentialType.VOYAGE_API,
+        name: 'default',
+      },
+      {
+        envVar: 'HF_TOKEN',
+        type: CredentialType.HUGGINGFACE_API,
+        name: 'default',
+      },
       // DNS providers
       {
         envVar: 'CLOUDFLARE_API_TOKEN',
@@ -646,6 +744,30 @@ export class CredentialManager {
           name,
           apiKey: value,
         });
+      } else if (type === CredentialType.GEMINI_API) {
+        await this.store<GeminiAPICredential>({
+          type: CredentialType.GEMINI_API,
+          name,
+          apiKey: value,
+        });
+      } else if (type === CredentialType.COHERE_API) {
+        await this.store<CohereAPICredential>({
+          type: CredentialType.COHERE_API,
+          name,
+          apiKey: value,
+        });
+      } else if (type === CredentialType.VOYAGE_API) {
+        await this.store<VoyageAPICredential>({
+          type: CredentialType.VOYAGE_API,
+          name,
+          apiKey: value,
+        });
+      } else if (type === CredentialType.HUGGINGFACE_API) {
+        await this.store<HuggingFaceAPICredential>({
+          type: CredentialType.HUGGINGFACE_API,
+          name,
+          apiKey: value,
+        });
       } else if (type === CredentialType.CLOUDFLARE_API) {
         await this.store<CloudflareCredential>({
           type: CredentialType.CLOUDFLARE_API,
@@ -14,6 +14,13 @@ export enum CredentialType {
   ANTHROPIC_API = 'anthropic_api',
   OPENAI_API = 'openai_api',
   OPENROUTER_API = 'openrouter_api',
+  OLLAMA_ENDPOINT = 'ollama_endpoint',
+  AZURE_OPENAI = 'azure_openai',
+  GEMINI_API = 'gemini_api',
+  COHERE_API = 'cohere_api',
+  VOYAGE_API = 'voyage_api',
+  HUGGINGFACE_API = 'huggingface_api',
+  OPENAI_COMPATIBLE = 'openai_compatible',
 
   // DNS providers
   CLOUDFLARE_API = 'cloudflare_api',
@@ -146,6 +153,106 @@ export interface OpenRouterAPICredential extends BaseCredential {
   apiKey: string;
 }
 
+/**
+ * Ollama endpoint credential (local embeddings, no API key)
+ */
+export interface OllamaEndpointCredential extends BaseCredential {
+  type: CredentialType.OLLAMA_ENDPOINT;
+
+  /** Base URL of the Ollama server (e.g. http://localhost:11434) */
+  baseUrl: string;
+
+  /** Embedding model name (e.g. nomic-embed-text) */
+  model: string;
+}
+
+/**
+ * Azure OpenAI credential (endpoint, key and deployment names)
+ */
+export interface AzureOpenAICredential extends BaseCredential {
+  type: CredentialType.AZURE_OPENAI;
+
+  /** Resource endpoint (e.g. https://my-resource.openai.azure.com) */
+  endpoint: string;
+
+  /** API key, sent as the api-key header */
+  apiKey: string;
+
+  /** api-version query parameter (e.g. 2024-06-01) */
+  apiVersion: string;
+
+  /** Deployment used for chat completions */
+  chatDeployment?: string;
+
+  /** Deployment used for embeddings */
+  embeddingDeployment?: string;
+}
+
+/**
+ * Google Gemini API key credential
+ */
+export interface GeminiAPICredential extends BaseCredential {
+  type: CredentialType.GEMINI_API;
+
+  /** API key, sent as the x-goog-api-key header */
+  apiKey: string;
+}
+
+/**
+ * Cohere API key credential
+ */
+export interface CohereAPICredential extends BaseCredential {
+  type: CredentialType.COHERE_API;
+
+  /** API key, sent as a bearer token */
+  apiKey: string;
+}
+
+/**
+ * Voyage AI API key credential
+ */
+export interface VoyageAPICredential extends BaseCredential {
+  type: CredentialType.VOYAGE_API;
+
+  /** API key, sent as a bearer token */
+  apiKey: string;
+}
+
+/**
+ * HuggingFace access token, default embedding model and optional TEI server
+ */
+export interface HuggingFaceAPICredential extends BaseCredential {
+  type: CredentialType.HUGGINGFACE_API;
+
+  /** Access token (hf_...), sent as a bearer token; optional for a TEI server */
+  apiKey?: string;
+
+  /** Embedding model ID (e.g. BAAI/bge-large-en-v1.5) */
+  model?: string;
+
+  /** Text Embeddings Inference server URL, used instead of the hosted Inference API */
+  baseUrl?: string;
+}
+
+/**
+ * OpenAI-compatible gateway (LiteLLM, vLLM, Groq, Together, ...) used for chat and embeddings
+ */
+export interface OpenAICompatibleCredential extends BaseCredential {
+  type: CredentialType.OPENAI_COMPATIBLE;
+
+  /** Base URL of the API, including the version path (e.g. http://localhost:4000/v1) */
+  baseUrl: string;
+
+  /** API key, sent as a bearer token; optional for gateways without authentication */
+  apiKey?: string;
+
+  /** Chat model, passed to the gateway as given */
+  chatModel?: string;
+
+  /** Embedding model, passed to the gateway as given */
+  embeddingModel?: string;
+}
+
 /**
  * Generic API key credential
  */
@@ -310,6 +417,13 @@ export type Credential =
   | AnthropicAPICredential
   | OpenAIAPICredential
   | OpenRouterAPICredential
+  | OllamaEndpointCredential
+  | AzureOpenAICredential
+  | GeminiAPICredential
+  | CohereAPICredential
+  | VoyageAPICredential
+  | HuggingFaceAPICredential
+  | OpenAICompatibleCredential
   | APIKeyCredential
   // DNS providers
   | CloudflareCredential
@@ -1,43 +1,24 @@
 /**
  * Credential utilities for MCP Server
  *
- * Provides secure access to API keys via the CredentialManager
- * instead of relying on environment variables.
+ * Keys are resolved by @cv-git/core the same way the CLI resolves them
+ * (CredentialManager, then environment variables); tools that need an
+ * embedding provider or chat backend use its resolvers directly. These
+ * wrappers return undefined for a missing key, as the tools expect.
  */
 
-import { CredentialManager } from '@cv-git/credentials';
-
-// Singleton credential manager instance
-let credentialManager: CredentialManager | null = null;
-
-/**
- * Get or create a CredentialManager instance
- */
-async function getCredentialManager(): Promise<CredentialManager> {
-  if (!credentialManager) {
-    credentialManager = new CredentialManager();
-    await credentialManager.init();
-  }
-  return credentialManager;
-}
+import {
+  getAnthropicApiKey as resolveAnthropicApiKey,
+  getOpenAIApiKey as resolveOpenAIApiKey,
+  getOpenRouterApiKey as resolveOpenRouterApiKey
+} from '@cv-git/core';
 
 /**
  * Get Anthropic API key from credential manager
  * Falls back to environment variable for backwards compatibility
  */
 export async function getAnthropicApiKey(): Promise<string | undefined> {
-  try {
-    const manager = await getCredentialManager();
-    const key = await manager.getAnthropicKey();
-    if (key) {
-      return key;
-    }
-  } catch (error) {
-    // Credential manager failed, fall back to env var
-  }
-
-  // Fallback to environment variable
-  return process.env.ANTHROPIC_API_KEY || process.env.CV_ANTHROPIC_KEY || undefined;
+  return (await resolveAnthropicApiKey()) ?? undefined;
 }
 
 /**
@@ -45,18 +26,7 @@ export async function getAnthropicApiKey(): Promise<string | undefined> {
  * Falls back to environment variable for backwards compatibility
  */
 export async function getOpenAIApiKey(): Promise<string | undefined> {
-  try {
-    const manager = await getCredentialManager();
-    const key = await manager.getOpenAIKey();
-    if (key) {
-      return key;
-    }
-  } catch (error) {
-    // Credential manager failed, fall back to env var
-  }
-
-  // Fallback to environment variable
-  return process.env.OPENAI_API_KEY || process.env.CV_OPENAI_KEY || undefined;
+  return (await resolveOpenAIApiKey()) ?? undefined;
 }
 
 /**
@@ -64,34 +34,7 @@ export async function getOpenAIApiKey(): Promise<string | undefined> {
  * Falls back to environment variable for backwards compatibility
  */
 export async function getOpenRouterApiKey(): Promise<string | undefined> {
-  try {
-    const manager = await getCredentialManager();
-    const key = await manager.getOpenRouterKey();
-    if (key) {
-      return key;
-    }
-  } catch (error) {
-    // Credential manager failed, fall back to env var
-  }
-
-  // Fallback to environment variable
-  return process.env.OPENROUTER_API_KEY || undefined;
-}
-
-/**
- * Get embedding credentials (OpenRouter preferred, then OpenAI)
- */
-export async function getEmbeddingCredentials(): Promise<{
-  openrouterApiKey?: string;
-  openaiApiKey?: string;
-}> {
-  const openrouterApiKey = await getOpenRouterApiKey();
-  const openaiApiKey = await getOpenAIApiKey();
-
-  return {
-    openrouterApiKey,
-    openaiApiKey,
-  };
+  return (await resolveOpenRouterApiKey()) ?? undefined;
 }
 
 /**
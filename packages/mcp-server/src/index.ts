@@ -21,6 +21,7 @@ import { listResources, readResource } from './resources.js';
 import {
   FindArgs,
   ExplainArgs,
+  GetSymbolArgs,
   GraphQueryArgs,
   DoArgs,
   ReviewArgs,
@@ -47,6 +48,7 @@ import {
 // Tool handlers
 import { handleFind } from './tools/search.js';
 import { handleExplain } from './tools/explain.js';
+import { handleGetSymbol } from './tools/symbol.js';
 import {
   handleGraphQuery,
   handleGraphStats,
@@ -283,6 +285,25 @@ This provides richer context than searching manually.`,
       required: ['target'],
     },
   },
+  {
+    name: 'cv_get_symbol',
+    description: 'Look up a function, method, or class by name or qualified name. Returns its file:line location, signature, and the callers and callees with their locations.',
+    inputSchema: {
+      type: 'object',
+      properties: {
+        query: {
+          type: 'string',
+          description: 'Symbol name (e.g. parseConfig) or qualified name (e.g. src/config.ts:parseConfig)',
+        },
+        limit: {
+          type: 'number',
+          description: 'Maximum number of matching symbols',
+          default: 5,
+        },
+      },
+      required: ['query'],
+    },
+  },
   {
     name: 'cv_reason',
     description: `Deep codebase reasoning using recursive LLM analysis. Uses a decompose-execute-aggregate pattern to break complex queries into sub-tasks (graph queries, semantic search, explanations) and synthesize comprehensive answers.
@@ -1646,6 +1667,11 @@ server.setRequestHandler(CallToolRequestSchema, async (request) => {
         result = await handleExplain(args as unknown as ExplainArgs);
         break;
 
+      case 'cv_get_symbol':
+        validateArgs(args, ['query']);
+        result = await handleGetSymbol(args as unknown as GetSymbolArgs);
+        break;
+
       case 'cv_reason':
         validateArgs(args, ['query']);
         result = await handleReason(args as unknown as ReasonArgs);
@@ -7,7 +7,7 @@
  */
 
 import { ToolResult } from '../types.js';
-import { successResult, errorResult, createIsolatedGraphManager, getServiceUrls } from '../utils.js';
+import { successResult, errorResult, createIsolatedGraphManager, getServiceUrls, redactOutput } from '../utils.js';
 import {
   configManager,
   createVectorManager,
@@ -225,7 +225,7 @@ export async function handleAutoContext(args: AutoContextArgs): Promise<ToolResu
     await vector.close();
     if (graph) await graph.close();
 
-    return successResult(output);
+    return successResult(redactOutput(output, config));
   } catch (error: any) {
     return errorResult('Failed to generate auto context', error);
   }
@@ -535,7 +535,7 @@ async function tryManifoldContext(args: AutoContextArgs): Promise<ToolResult | n
     return null; // Not enough data, fall back
   }
 
-  return successResult(result.context);
+  return successResult(redactOutput(result.context, await configManager.load(repoRoot)));
 }
 
 /**
@@ -8,13 +8,14 @@
  */
 
 import { ToolResult } from '../types.js';
-import { successResult, errorResult, createIsolatedGraphManager, getServiceUrls } from '../utils.js';
+import { successResult, errorResult, createIsolatedGraphManager, getServiceUrls, redactOutput } from '../utils.js';
 import {
   configManager,
   createVectorManager,
   hasLocalVectors,
   searchLocalVectors,
   createEmbedding,
+  getVectorBackendOptions,
 } from '@cv-git/core';
 import { findRepoRoot, VectorSearchResult, CodeChunkPayload, SymbolNode } from '@cv-git/shared';
 import { promises as fs } from 'fs';
@@ -77,6 +78,7 @@ export async function handleContext(args: ContextArgs): Promise<ToolResult> {
       // Initialize vector manager with proper options
       vector = createVectorManager({
         url: serviceUrls.qdrant,
+        ...getVectorBackendOptions(config.vector),
         openrouterApiKey,
         openaiApiKey,
         collections: config.vector.collections,
@@ -214,7 +216,7 @@ export async function handleContext(args: ContextArgs): Promise<ToolResult> {
       contextOutput = '> Note: Using local cache (Qdrant unavailable)\n\n' + contextOutput;
     }
 
-    return successResult(contextOutput);
+    return successResult(redactOutput(contextOutput, config));
   } catch (error: any) {
     return errorResult('Failed to generate context', error);
   }
@@ -11,11 +11,12 @@ import {
   createVectorManager,
   createIngestManager,
   createParser,
+  getEmbeddingCacheDir,
+  getEmbeddingCredentials,
 } from '@cv-git/core';
 import { findRepoRoot } from '@cv-git/shared';
 import { promises as fs } from 'fs';
 import * as path from 'path';
-import { getEmbeddingCredentials } from '../credentials.js';
 
 /**
  * Arguments for cv_docs_search
@@ -78,7 +79,7 @@ export async function handleDocsSearch(args: DocsSearchArgs): Promise<ToolResult
       openrouterApiKey: creds.openrouterApiKey,
       openaiApiKey: creds.openaiApiKey,
       collections: config.vector.collections,
-      cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+      cacheDir: getEmbeddingCacheDir(repoRoot)
     });
 
     await vector.connect();
@@ -280,7 +281,7 @@ export async function handleDocsIngest(args: DocsIngestArgs): Promise<ToolResult
           openrouterApiKey: creds.openrouterApiKey,
           openaiApiKey: creds.openaiApiKey,
           collections: config.vector.collections,
-          cacheDir: path.join(repoRoot, '.cv', 'embeddings')
+          cacheDir: getEmbeddingCacheDir(repoRoot)
         });
 
         await vector.connect();
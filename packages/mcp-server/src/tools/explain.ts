@@ -4,109 +4,96 @@
  */
 
 import { ExplainArgs, ToolResult } from '../types.js';
-import { successResult, errorResult, createIsolatedGraphManager } from '../utils.js';
+import {
+  successResult,
+  errorResult,
+  createIsolatedGraphManager,
+  formatContextSources,
+  getServiceUrls,
+  isRedactionEnabled,
+} from '../utils.js';
 import {
   configManager,
   createAIManager,
   createGitManager,
+  createVectorManagerFromCredentials,
+  getIndexDir,
+  resolveAIFallbacks,
+  resolveChatBackend,
+  VectorManager,
+  AIManagerFallback,
 } from '@cv-git/core';
-import * as fs from 'fs/promises';
-import * as path from 'path';
-import { getAnthropicApiKey } from '../credentials.js';
 
 /**
  * Handle cv_explain tool call
  */
 export async function handleExplain(args: ExplainArgs): Promise<ToolResult> {
   try {
-    const { target, noStream = false } = args;
+    const { target } = args;
 
     // Initialize graph manager with repo isolation
-    const { graph, repoRoot } = await createIsolatedGraphManager();
-    await graph.connect();
+    const { graph, repoRoot, repoId } = await createIsolatedGraphManager();
 
     // Load configuration
     const config = await configManager.load(repoRoot);
 
-    // Get API key from credential manager
-    const anthropicApiKey = config.ai.apiKey || await getAnthropicApiKey();
-    if (!anthropicApiKey) {
-      return errorResult(
-        'Anthropic API key not found. Run `cv auth setup anthropic`.'
-      );
+    // Resolve the chat provider (ai.provider) before connecting to anything
+    let backend: AIManagerFallback;
+    try {
+      backend = await resolveChatBackend(config);
+    } catch (error: any) {
+      return errorResult(error.message);
     }
 
-    // Initialize managers
-    const git = createGitManager(repoRoot);
+    await graph.connect();
+
+    // Vector manager for semantic retrieval (optional: explain still uses the graph without it)
+    let vector: VectorManager | undefined;
+    if (config.vector) {
+      try {
+        const serviceUrls = await getServiceUrls(config);
+        vector = await createVectorManagerFromCredentials(config, {
+          url: serviceUrls.qdrant,
+          repoId,
+          // Reload the persisted index if Qdrant lost it (e.g. after a restart)
+          indexDir: getIndexDir(repoRoot),
+        });
+        await vector.connect();
+      } catch {
+        vector = undefined;
+      }
+    }
 
     // Initialize AI manager with all dependencies
     const ai = createAIManager(
       {
-        provider: 'anthropic',
-        model: config.ai.model || 'claude-sonnet-4-5-20250514',
-        apiKey: anthropicApiKey,
+        ...backend,
+        model: config.models?.explain ?? backend.model ?? config.ai.model,
+        contextWindow: config.ai.contextWindow,
         maxTokens: config.ai.maxTokens,
         temperature: config.ai.temperature,
+        promptCaching: config.ai.promptCaching,
+        fallbacks: await resolveAIFallbacks(config, backend.provider),
+        redaction: {
+          enabled: isRedactionEnabled(config),
+          patterns: config.redaction?.patterns,
+        },
       },
-      undefined,  // vector manager not needed for explain
+      vector,
       graph,
-      git
+      createGitManager(repoRoot)
     );
 
-    // Search for the target in the graph
-    let context = '';
-
-    // Try to find as a symbol
-    const symbol = await graph.getSymbolNode(target);
-    if (symbol) {
-      context += `Symbol: ${symbol.name} (${symbol.kind})\n`;
-      context += `Location: ${symbol.file}:${symbol.startLine}\n\n`;
+    try {
+      // Generate explanation using AI, citing the code its context held
+      const context = await ai.gatherContext(target);
+      const explanation = await ai.explain(target, context);
 
-      if (symbol.docstring) {
-        context += `Documentation: ${symbol.docstring}\n\n`;
-      }
-
-      // Get symbol dependencies
-      const callees = await graph.getCallees(symbol.qualifiedName);
-      if (callees.length > 0) {
-        context += `Calls: ${callees.map((c: any) => c.name).join(', ')}\n`;
-      }
-
-      const callers = await graph.getCallers(symbol.qualifiedName);
-      if (callers.length > 0) {
-        context += `Called by: ${callers.map((c: any) => c.name).join(', ')}\n`;
-      }
-
-      // Get source code
-      try {
-        const filePath = path.join(repoRoot, symbol.file);
-        const sourceCode = await fs.readFile(filePath, 'utf-8');
-        const lines = sourceCode.split('\n');
-        const startLine = Math.max(0, symbol.startLine - 5);
-        const endLine = Math.min(lines.length, symbol.endLine + 5);
-        context += `\nSource code:\n${lines.slice(startLine, endLine).join('\n')}\n`;
-      } catch (error: any) {
-        context += `\nCould not read source file: ${error.message}\n`;
-      }
-    } else {
-      // Might be a file path or concept
-      try {
-        const filePath = target.startsWith('/') ? target : path.join(repoRoot, target);
-        const fileContent = await fs.readFile(filePath, 'utf-8');
-        context += `File: ${target}\n\n`;
-        context += `Content:\n${fileContent.slice(0, 5000)}${fileContent.length > 5000 ? '\n...(truncated)' : ''}\n`;
-      } catch {
-        // Not a file, treat as a concept
-        context += `Explaining concept: ${target}\n`;
-      }
+      return successResult(explanation + formatContextSources(context));
+    } finally {
+      await graph.close();
+      if (vector) await vector.close();
     }
-
-    await graph.close();
-
-    // Generate explanation using AI (let AI manager gather its own context)
-    const explanation = await ai.explain(target);
-
-    return successResult(explanation);
   } catch (error: any) {
     return errorResult('Code explanation failed', error);
   }
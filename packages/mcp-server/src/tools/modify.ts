@@ -42,6 +42,7 @@ export async function handleDo(args: DoArgs): Promise<ToolResult> {
       {
         provider: 'anthropic',
         model: config.ai.model || 'claude-sonnet-4-5-20250514',
+        contextWindow: config.ai.contextWindow,
         apiKey: anthropicApiKey,
         maxTokens: config.ai.maxTokens,
         temperature: config.ai.temperature,
@@ -119,6 +120,7 @@ export async function handleReview(args: ReviewArgs): Promise<ToolResult> {
       {
         provider: 'anthropic',
         model: config.ai.model || 'claude-sonnet-4-5-20250514',
+        contextWindow: config.ai.contextWindow,
         apiKey: anthropicApiKey,
         maxTokens: config.ai.maxTokens,
         temperature: config.ai.temperature,
@@ -10,9 +10,10 @@ import {
   createVectorManager,
   createGitManager,
   createRLMRouter,
+  getEmbeddingCredentials,
   RLMResult
 } from '@cv-git/core';
-import { getAnthropicApiKey, getEmbeddingCredentials } from '../credentials.js';
+import { getAnthropicApiKey } from '../credentials.js';
 
 /**
  * Tool arguments for cv_reason
@@ -8,13 +8,14 @@
  */
 
 import { FindArgs, ToolResult, SearchResult } from '../types.js';
-import { successResult, errorResult, formatSearchResults, getServiceUrls } from '../utils.js';
+import { successResult, errorResult, formatSearchResults, getServiceUrls, redactOutput } from '../utils.js';
 import {
   configManager,
   createVectorManager,
   hasLocalVectors,
   searchLocalVectors,
   createEmbedding,
+  getVectorBackendOptions,
 } from '@cv-git/core';
 import { findRepoRoot } from '@cv-git/shared';
 import { getOpenAIApiKey, getOpenRouterApiKey } from '../credentials.js';
@@ -56,6 +57,7 @@ export async function handleFind(args: FindArgs): Promise<ToolResult> {
       // Initialize vector manager with proper options
       const vector = createVectorManager({
         url: serviceUrls.qdrant,
+        ...getVectorBackendOptions(config.vector),
         openrouterApiKey,
         openaiApiKey,
         collections: config.vector.collections,
@@ -128,7 +130,7 @@ export async function handleFind(args: FindArgs): Promise<ToolResult> {
       formattedResults = '(Using local cache - Qdrant unavailable)\n\n' + formattedResults;
     }
 
-    return successResult(formattedResults);
+    return successResult(redactOutput(formattedResults, config));
   } catch (error: any) {
     return errorResult('Code search failed', error);
   }
@@ -0,0 +1,42 @@
+/**
+ * Unit tests for the cv_get_symbol tool handler
+ */
+
+import { describe, it, expect } from 'vitest';
+import { rankSymbolMatches, formatSymbolLookups } from './symbol.js';
+import { formatCitation } from '../utils.js';
+
+const symbol = (name: string, qualifiedName: string, startLine = 1, endLine = 5): any => ({
+  name,
+  qualifiedName,
+  kind: 'function',
+  file: qualifiedName.split(':')[0],
+  startLine,
+  endLine
+});
+
+describe('cv_get_symbol', () => {
+  it('formats single-line and range citations', () => {
+    expect(formatCitation('src/a.ts', 7, 7)).toBe('src/a.ts:7');
+    expect(formatCitation('src/a.ts', 7, 12)).toBe('src/a.ts:7-12');
+  });
+
+  it('prefers exact symbol matches over partial ones', () => {
+    const symbols = [symbol('parseConfigFile', 'src/a.ts:parseConfigFile'), symbol('parseConfig', 'src/b.ts:parseConfig')];
+    expect(rankSymbolMatches(symbols, 'parseConfig').map(s => s.qualifiedName)).toEqual(['src/b.ts:parseConfig']);
+    expect(rankSymbolMatches(symbols, 'parse')).toHaveLength(2);
+  });
+
+  it('cites the symbol and its callers and callees', () => {
+    const text = formatSymbolLookups([{
+      symbol: { ...symbol('login', 'src/auth.ts:login', 10, 24), signature: 'login(user: string)' },
+      callers: [symbol('handleLogin', 'src/routes.ts:handleLogin', 40, 40)],
+      callees: []
+    }]);
+
+    expect(text).toContain('Location: src/auth.ts:10-24');
+    expect(text).toContain('Signature: login(user: string)');
+    expect(text).toContain('- handleLogin (src/routes.ts:40)');
+    expect(text).not.toContain('Calls:');
+  });
+});
@@ -0,0 +1,108 @@
+/**
+ * Symbol Tool Handler
+ * Implements cv_get_symbol - look up a symbol by name, with file:line
+ * citations for it and for its callers and callees
+ */
+
+import { SymbolNode } from '@cv-git/shared';
+import { GetSymbolArgs, ToolResult } from '../types.js';
+import { successResult, errorResult, createIsolatedGraphManager, formatCitation } from '../utils.js';
+
+const MAX_RELATED_SYMBOLS = 10;
+
+export interface SymbolLookup {
+  symbol: SymbolNode;
+  callers: SymbolNode[];
+  callees: SymbolNode[];
+}
+
+/**
+ * Handle cv_get_symbol tool call
+ */
+export async function handleGetSymbol(args: GetSymbolArgs): Promise<ToolResult> {
+  try {
+    const { query, limit = 5 } = args;
+
+    // Initialize graph manager with repo isolation
+    const { graph } = await createIsolatedGraphManager();
+    await graph.connect();
+
+    try {
+      // A qualified name matches one symbol; otherwise search by name
+      const exact = await graph.getSymbolNode(query);
+      const matches = exact ? [exact] : rankSymbolMatches(await graph.searchEntities(query, limit * 4), query);
+      if (matches.length === 0) {
+        return errorResult(`Symbol not found: ${query}`);
+      }
+
+      const lookups: SymbolLookup[] = [];
+      for (const symbol of matches.slice(0, limit)) {
+        const [callers, callees] = await Promise.all([
+          graph.getCallers(symbol.qualifiedName),
+          graph.getCallees(symbol.qualifiedName)
+        ]);
+        lookups.push({ symbol, callers, callees });
+      }
+
+      return successResult(formatSymbolLookups(lookups));
+    } finally {
+      await graph.close();
+    }
+  } catch (error: any) {
+    return errorResult('Symbol lookup failed', error);
+  }
+}
+
+/**
+ * Symbols matching a lookup: exact name or qualified-name matches when there
+ * are any, otherwise everything the search returned
+ */
+export function rankSymbolMatches(symbols: SymbolNode[], query: string): SymbolNode[] {
+  const exact = symbols.filter(s =>
+    s.name === query || s.qualifiedName === query || s.qualifiedName.endsWith(`:${query}`)
+  );
+  return exact.length > 0 ? exact : symbols;
+}
+
+/**
+ * Format symbol lookups as text, citing each symbol's location
+ */
+export function formatSymbolLookups(lookups: SymbolLookup[]): string {
+  const lines: string[] = [];
+  lines.push(`Found ${lookups.length} symbol(s):\n`);
+
+  lookups.forEach(({ symbol, callers, callees }, i) => {
+    lines.push(`${i + 1}. ${symbol.name} (${symbol.kind})`);
+    lines.push(`   Qualified name: ${symbol.qualifiedName}`);
+    lines.push(`   Location: ${formatCitation(symbol.file, symbol.startLine, symbol.endLine)}`);
+
+    if (symbol.signature) {
+      lines.push(`   Signature: ${symbol.signature}`);
+    }
+
+    if (symbol.docstring) {
+      lines.push(`   Description: ${symbol.docstring.split('\n')[0]}`);
+    }
+
+    lines.push(...formatRelated('Called by', callers));
+    lines.push(...formatRelated('Calls', callees));
+    lines.push('');
+  });
+
+  return lines.join('\n');
+}
+
+function formatRelated(label: string, symbols: SymbolNode[]): string[] {
+  if (symbols.length === 0) {
+    return [];
+  }
+
+  const lines = [`   ${label}: ${symbols.length}`];
+  for (const symbol of symbols.slice(0, MAX_RELATED_SYMBOLS)) {
+    lines.push(`     - ${symbol.name} (${formatCitation(symbol.file, symbol.startLine, symbol.endLine)})`);
+  }
+  if (symbols.length > MAX_RELATED_SYMBOLS) {
+    lines.push(`     ... and ${symbols.length - MAX_RELATED_SYMBOLS} more`);
+  }
+  return lines;
+}
@@ -11,6 +11,7 @@ import {
   createParser,
   createVectorManager,
   createSyncEngine,
+  getVectorBackendOptions,
 } from '@cv-git/core';
 import { getOpenAIApiKey, getOpenRouterApiKey } from '../credentials.js';
 
@@ -35,7 +36,7 @@ export async function handleSync(args: SyncArgs): Promise<ToolResult> {
       return errorResult('Not a git repository');
     }
 
-    const parser = createParser();
+    const parser = createParser({ maxChunkLines: config.sync?.maxChunkLines });
 
     // Vector manager (optional)
     let vector = undefined;
@@ -50,6 +51,7 @@ export async function handleSync(args: SyncArgs): Promise<ToolResult> {
 
         vector = createVectorManager({
           url: serviceUrls.qdrant,
+          ...getVectorBackendOptions(config.vector),
           openaiApiKey,
           openrouterApiKey,
           collections: config.vector.collections,
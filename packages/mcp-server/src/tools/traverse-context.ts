@@ -10,7 +10,7 @@
  */
 
 import { ToolResult } from '../types.js';
-import { successResult, errorResult, createIsolatedGraphManager, getServiceUrls } from '../utils.js';
+import { successResult, errorResult, createIsolatedGraphManager, getServiceUrls, redactOutput } from '../utils.js';
 import {
   configManager,
   createVectorManager,
@@ -199,7 +199,7 @@ export async function handleTraverseContext(args: TraverseContextToolArgs): Prom
     await graph.close();
     await sessionService.close();
 
-    return successResult(output);
+    return successResult(redactOutput(output, config));
 
   } catch (error: any) {
     return errorResult('Failed to traverse context', error);
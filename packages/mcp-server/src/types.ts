@@ -22,6 +22,14 @@ export interface ExplainArgs {
   noStream?: boolean;
 }
 
+/**
+ * Tool arguments for cv_get_symbol
+ */
+export interface GetSymbolArgs {
+  query: string;
+  limit?: number;
+}
+
 /**
  * Tool arguments for cv_graph_query
  */
@@ -7,8 +7,8 @@ import * as fs from 'fs/promises';
 import * as path from 'path';
 import * as os from 'os';
 import { ToolResult, SearchResult, GraphResult } from './types.js';
-import { configManager, createGraphManager, readManifest, generateRepoId, GraphManager } from '@cv-git/core';
-import { findRepoRoot, getCVDir } from '@cv-git/shared';
+import { configManager, createGraphManager, readManifest, generateRepoId, GraphManager, SecretRedactor } from '@cv-git/core';
+import { findRepoRoot, getCVDir, Context, CVConfig } from '@cv-git/shared';
 
 /**
  * Load service URLs from ~/.cv/services.json if it exists
@@ -89,6 +89,47 @@ export async function getServiceUrls(config: any): Promise<{
   };
 }
 
+/**
+ * Whether code is masked for secrets before it reaches the model or the
+ * client: config `redaction.enabled`, unless the server was started with
+ * `cv mcp --no-redact` (CV_NO_REDACT)
+ */
+export function isRedactionEnabled(config: CVConfig): boolean {
+  return !process.env.CV_NO_REDACT && config.redaction?.enabled !== false;
+}
+
+/**
+ * Mask likely secrets in tool output that carries code, using the default
+ * patterns plus config `redaction.patterns`
+ */
+export function redactOutput(text: string, config: CVConfig): string {
+  return isRedactionEnabled(config) ? new SecretRedactor(config.redaction?.patterns).redact(text).text : text;
+}
+
+/**
+ * Citation for a line range: `<file>:<line>` or `<file>:<start>-<end>`
+ */
+export function formatCitation(file: string, startLine: number, endLine: number): string {
+  return startLine === endLine ? `${file}:${startLine}` : `${file}:${startLine}-${endLine}`;
+}
+
+/**
+ * Format the code and symbols an answer was based on as a list of citations,
+ * or an empty string if the context held none
+ */
+export function formatContextSources(context: Context): string {
+  const sources = [
+    ...context.chunks.map(chunk => {
+      const { file, startLine, endLine, symbolName } = chunk.payload;
+      return `- ${formatCitation(file, startLine, endLine)}${symbolName ? ` ${symbolName}` : ''}`;
+    }),
+    ...context.symbols.map(symbol => `- ${formatCitation(symbol.file, symbol.startLine, symbol.endLine)} ${symbol.name}`)
+  ];
+  const unique = Array.from(new Set(sources));
+
+  return unique.length > 0 ? `\n\nSources:\n${unique.join('\n')}` : '';
+}
+
 /**
  * Format search results as text
  */
@@ -105,7 +146,7 @@ export function formatSearchResults(results: SearchResult[]): string {
     const score = (result.score * 100).toFixed(1);
 
     lines.push(`${i + 1}. ${result.symbolName || 'Code chunk'} (${score}% match)`);
-    lines.push(`   Location: ${result.file}:${result.startLine}-${result.endLine}`);
+    lines.push(`   Location: ${formatCitation(result.file, result.startLine, result.endLine)}`);
 
     if (result.language) {
       lines.push(`   Language: ${result.language}`);
@@ -343,6 +343,7 @@ export interface CodeChunk {
   text: string;
   symbolName?: string;
   symbolKind?: SymbolKind;
+  signature?: string;
   summary?: string;
   docstring?: string;
   complexity?: number;
@@ -361,11 +362,40 @@ export interface VectorSearchResult<T extends VectorPayload = VectorPayload> {
   id: string;
   score: number;
   payload: T;
+  /** Stored embedding, only when the search asked for vectors */
+  vector?: number[];
+  /** Set when the chunk was not retrieved but pulled in as a definition (cv explain --depth) */
+  expansion?: ChunkExpansion;
+  /** Set when a reranker reordered the retrieved chunks; score is then the reranker's */
+  rerank?: ChunkRerank;
+}
+
+/**
+ * Where a chunk stood before reranking
+ */
+export interface ChunkRerank {
+  /** Similarity score from the vector search */
+  vectorScore: number;
+  /** 1-based position in the vector search results */
+  vectorRank: number;
+}
+
+/**
+ * How a chunk was reached by following references from retrieved code
+ */
+export interface ChunkExpansion {
+  /** The identifier whose definition the chunk holds */
+  name: string;
+  /** The chunk referencing it: its symbol name, or file:line */
+  from: string;
+  /** 1 for a reference from a retrieved chunk, 2 from an expanded one, ... */
+  depth: number;
 }
 
 export interface CodeChunkPayload extends VectorPayload {
   symbolName?: string;
   symbolKind?: SymbolKind;
+  signature?: string;
   startLine: number;
   endLine: number;
   text: string;
@@ -374,6 +404,16 @@ export interface CodeChunkPayload extends VectorPayload {
   imports: string[];
   complexity?: number;
   lastModified: number;
+  /** HEAD when the chunk was embedded (absent in repos without commits and older indexes) */
+  commit?: string;
+  /** From a test file by its language's naming convention (absent in older indexes) */
+  isTest?: boolean;
+  /** Repo-relative directory of the nearest package manifest above the file, '.' for the root (absent in older indexes) */
+  package?: string;
+  /** Lowercase file extension with its dot, e.g. '.go'; absent for files without one and in older indexes */
+  extension?: string;
+  /** From a file named with --file: fitted to the token budget before any search result */
+  pinned?: boolean;
 }
 
 export interface DocstringPayload extends VectorPayload {
@@ -572,6 +612,26 @@ export interface Context {
   commits?: CommitNode[];
   workingTreeStatus?: WorkingTreeStatus;
   prdContext?: any; // PRD context from cvPRD (AIContext type)
+  /** Best score among chunks rejected by the minScore threshold */
+  nearMissScore?: number;
+  /** Number of secrets masked in chunk text before prompting */
+  redactedSecrets?: number;
+  /** Chunks dropped or truncated to fit the model's context window */
+  budget?: { dropped: number; truncated: number; pinned?: number };
+  /** Chunks dropped as duplicates of a higher-scoring chunk */
+  duplicates?: number;
+  /** Definitions added by following references (cv explain --depth), and those left out for the budget */
+  expansion?: { depth: number; added: number; dropped: number };
+  /** Reranker that reordered the retrieved chunks, how many candidates it scored, and how many kept chunks changed position */
+  rerank?: { model: string; candidates: number; moved: number };
+  /** Language generated code should be written in */
+  language?: string;
+  /** Dominant languages of the repository */
+  repoLanguages?: string[];
+  /** Recent commits touching the retrieved files (cv explain --history), newest first */
+  history?: GitCommit[];
+  /** Commits left out, or messages cut short, to fit the token budget */
+  historyBudget?: { dropped: number; truncated: number };
 }
 
 export interface Plan {
@@ -589,6 +649,46 @@ export interface PlanStep {
   details?: string;
 }
 
+export type ReviewSeverity = 'low' | 'medium' | 'high' | 'critical';
+
+export interface ReviewFinding {
+  file: string;
+  startLine: number;
+  endLine: number;
+  severity: ReviewSeverity;
+  /** security, correctness, performance, best-practices, testing, documentation, style, or general */
+  category: string;
+  message: string;
+  suggestion?: string;
+}
+
+export interface ReviewSummary {
+  total: number;
+  bySeverity: Record<ReviewSeverity, number>;
+  byCategory: Record<string, number>;
+  /** Short overall assessment from the reviewer */
+  overview?: string;
+  /** Categories left out by --disable or review.disable */
+  disabledCategories?: string[];
+  /** Findings left out because the --baseline file accepts them */
+  baselined?: number;
+}
+
+/**
+ * Category rules applied to review findings (`review` in .cv/config.json, --disable)
+ */
+export interface ReviewRules {
+  /** Categories not to report, e.g. ["style"] */
+  disable?: string[];
+  /** Severity every finding of a category gets, e.g. { "style": "low", "security": "critical" } */
+  severity?: Partial<Record<string, ReviewSeverity>>;
+}
+
+export interface ReviewResult {
+  findings: ReviewFinding[];
+  summary: ReviewSummary;
+}
+
 export interface Diff {
   file: string;
   type: ChangeType;
@@ -637,6 +737,17 @@ export interface WorkingTreeStatus {
   staged: string[];
 }
 
+export interface WorktreeInfo {
+  /** Absolute path of the worktree's top-level directory */
+  path: string;
+  /** Checked-out branch; undefined when HEAD is detached */
+  branch?: string;
+  /** Resolved HEAD SHA; undefined before the first commit */
+  head?: string;
+  /** A worktree added with `git worktree add` (not the main checkout) */
+  linked: boolean;
+}
+
 export interface GitCommit {
   sha: string;
   message: string;
@@ -644,6 +755,30 @@ export interface GitCommit {
   authorEmail: string;
   date: number;
   files: string[];
+  /** `git log --shortstat` totals, when loaded */
+  stats?: { filesChanged: number; insertions: number; deletions: number };
+}
+
+/**
+ * One line of `git blame` output
+ */
+export interface BlameLine {
+  /** Line number in the current file */
+  line: number;
+  /** Line number in the commit that introduced it */
+  originalLine: number;
+  /** Path of the file in that commit (differs after a rename) */
+  originalFile: string;
+  sha: string;
+  author: string;
+  authorEmail: string;
+  /** Author time (ms since epoch) */
+  date: number;
+  /** Commit subject */
+  summary: string;
+  content: string;
+  /** True for lines not committed yet */
+  uncommitted: boolean;
 }
 
 export interface GitDiff {
@@ -653,6 +788,20 @@ export interface GitDiff {
   changes: string;
 }
 
+/**
+ * File change from `git diff --name-status`
+ */
+export interface GitFileChange {
+  /** A = added, M = modified, D = deleted, R = renamed, C = copied, T = type changed */
+  status: 'A' | 'M' | 'D' | 'R' | 'C' | 'T';
+  /** Current path (new path for renames/copies) */
+  path: string;
+  /** Previous path (renames/copies only) */
+  oldPath?: string;
+  /** Similarity percentage for renames/copies (100 = identical content) */
+  similarity?: number;
+}
+
 // ========== Workspace Types ==========
 
 /**
@@ -708,18 +857,141 @@ export interface CVConfig {
   };
   // Alias for llm (for backward compatibility)
   ai: {
-    provider: 'anthropic' | 'openai' | 'ollama';
+    provider: 'anthropic' | 'openai' | 'ollama' | 'azure' | 'gemini' | 'openai-compatible';
     model: string;
     apiKey?: string;
     maxTokens: number;
     temperature: number;
+    /** Context window to budget retrieved code against (default: the model's known window) */
+    contextWindow?: number;
+    /** Seconds a chat request may go without a response before it is cancelled; 0 disables (default: 120) */
+    timeout?: number;
+    /** Mark long prompts (retrieved code) for Anthropic prompt caching (default: false) */
+    promptCaching?: boolean;
+    /** Providers to try in order when one is down (5xx, timeout); ai.provider goes first */
+    providers?: Array<'anthropic' | 'azure' | 'gemini' | 'openai-compatible'>;
   };
   embedding: {
-    provider: 'openrouter' | 'openai' | 'ollama' | 'lmstudio';
+    provider: 'openrouter' | 'openai' | 'ollama' | 'lmstudio' | 'azure' | 'gemini' | 'cohere' | 'voyage' | 'huggingface' | 'openai-compatible';
+    /** Providers to try in order when one is down; each must serve embedding.model (OpenRouter and OpenAI) */
+    providers?: Array<'openrouter' | 'openai' | 'ollama' | 'lmstudio' | 'azure' | 'gemini' | 'cohere' | 'voyage' | 'huggingface' | 'openai-compatible'>;
     model: string;
     apiKey?: string;
     url?: string;
     dimensions: number;
+    /** Request vectors of this many dimensions from models that can shorten them (OpenAI text-embedding-3, Gemini text-embedding-004) */
+    outputDimensions?: number;
+    /** Max texts per embeddings API request (default: 100) */
+    batchSize?: number;
+    /** Max estimated tokens per embeddings API request (default: 100000) */
+    maxBatchTokens?: number;
+    /** Embedding requests in flight at once (default: 4) */
+    concurrency?: number;
+    /** Attempts per embedding request on rate limits and transient errors (default: CV_MAX_RETRIES or 5) */
+    maxRetryAttempts?: number;
+    /** Embedding cache size limit in bytes; least recently used entries are evicted (default: 1GB) */
+    cacheMaxBytes?: number;
+    /** Seconds an embeddings request may take before it is cancelled; 0 disables (default: 60) */
+    timeout?: number;
+  };
+  /** Azure OpenAI resource (API key comes from `cv auth setup azure` or AZURE_OPENAI_API_KEY) */
+  azure?: {
+    endpoint?: string;
+    apiVersion?: string;
+    /** Deployment used for explain/chat completions */
+    chatDeployment?: string;
+    /** Deployment used for sync embeddings */
+    embeddingDeployment?: string;
+  };
+  /** Per-command model defaults, e.g. a cheap model for chat and a strong one for review (overridden by --model) */
+  models?: Partial<Record<'explain' | 'do' | 'review' | 'chat' | 'code' | 'test' | 'refactor' | 'diff' | 'why' | 'docs' | 'summarize', string>>;
+  /** Per-command custom system prompts with {{variable}} placeholders, e.g. a security focus for review (overridden by --system-prompt) */
+  prompts?: Partial<Record<'explain' | 'do' | 'review' | 'chat', string>>;
+  /** Finding categories of cv review: which to leave out and which severity each gets (extended by --disable) */
+  review?: ReviewRules;
+  /** Commit-message conventions that messages generated by `cv commit` must follow */
+  commit?: {
+    /** 'conventional' (type(scope): subject), 'angular', 'gitmoji' or 'ticket' (default: conventional) */
+    convention?: 'conventional' | 'angular' | 'gitmoji' | 'ticket';
+    /** Subject line with {type}, {scope}, {emoji}, {ticket} and {subject} placeholders (default: the convention's) */
+    template?: string;
+    /** Scopes a message may use; others are rejected (default: any) */
+    scopes?: string[];
+    /** Most characters in the subject line (default: 72) */
+    maxSubjectLength?: number;
+    /** Regex finding the ticket ID in the branch name; the first group if it has one (default for 'ticket': [A-Z][A-Z0-9]+-\d+) */
+    ticketPattern?: string;
+    /** Further rules for the generator, e.g. "Write the subject in English" */
+    rules?: string[];
+  };
+  /** Context retrieval defaults for explain, do, review, chat, and code (overridden by --min-score/--top-k) */
+  search?: {
+    /** Minimum similarity (0-1) for a chunk to be included */
+    minScore?: number;
+    /** Number of chunks retrieved */
+    topK?: number;
+    /** Cosine similarity (0-1) above which a chunk is dropped as a near-duplicate of a better match (default: 0.97) */
+    dedupeThreshold?: number;
+    /** HNSW candidate list size per query on Qdrant and the local store; higher is slower with better recall (default: 64 locally) */
+    efSearch?: number;
+    /** Leave chunks of test files (foo_test.go, foo.test.ts, test_foo.py, ...) out of retrieved context (overridden by --tests/--no-tests) */
+    excludeTests?: boolean;
+    /** Reorder retrieved chunks with a cross-encoder before keeping the top topK (overridden by --rerank/--no-rerank) */
+    rerank?: {
+      /** Rerank explain, do, and review context (default: false) */
+      enabled?: boolean;
+      /** Cohere's rerank API, or a local server with a Cohere-compatible /rerank endpoint (default: cohere) */
+      provider?: 'cohere' | 'local';
+      /** Reranker model (default: rerank-v3.5 on Cohere; the server's own model locally) */
+      model?: string;
+      /** Base URL of the rerank API (default: Cohere's v2 API, or http://localhost:8080/v1 locally) */
+      url?: string;
+      /** Vector search results scored by the reranker (default: 50) */
+      candidates?: number;
+    };
+  };
+  /** Outbound HTTP settings for API, Qdrant, and CV-Hub requests (overridden by --proxy/--ca-bundle) */
+  network?: {
+    /** Proxy URL, e.g. http://proxy.example.com:8080 (default: HTTPS_PROXY or HTTP_PROXY) */
+    proxy?: string;
+    /** Comma-separated hosts that bypass the proxy (default: NO_PROXY; localhost always does) */
+    noProxy?: string;
+    /** PEM file of extra CA certificates, e.g. a TLS-intercepting proxy's root (default: CV_CA_BUNDLE) */
+    caBundle?: string;
+  };
+  /** Token and cost tracking of API requests, reported by `cv usage` */
+  usage?: {
+    /** Log each request to .cv/usage.jsonl (default: true) */
+    enabled?: boolean;
+    /** Print a "~$0.003, 1,240 tokens" line after each command that made requests (default: false) */
+    footer?: boolean;
+  };
+  /** Answers to repeated questions against an unchanged index (.cv/cache/responses) */
+  cache?: {
+    /** Seconds a cached response is served; 0 disables the cache (default: 86400) */
+    responseTtl?: number;
+  };
+  /** How `cv explain --open` opens cited code */
+  editor?: {
+    /** Command with {file} and {line} placeholders, e.g. "subl {file}:{line}" (default: $CV_EDITOR, $VISUAL or $EDITOR with its own line syntax) */
+    openCommand?: string;
+  };
+  /** Suggested next questions after `cv explain` and `cv chat` answers */
+  followUps?: {
+    /** Suggest follow-up questions grounded in the indexed code (default: false; per run --suggest/--no-suggest) */
+    enabled?: boolean;
+  };
+  /** Checking that the symbols a `cv explain` answer names exist in the index */
+  grounding?: {
+    /** Flag symbols the index does not have (default: false; per run --verify/--no-verify) */
+    enabled?: boolean;
+  };
+  /** Secret masking applied to code context before it is sent to an LLM */
+  redaction?: {
+    /** Default: true (disable per run with --no-redact) */
+    enabled?: boolean;
+    /** Extra regex sources; a named group `secret` limits masking to that part */
+    patterns?: string[];
   };
   graph: {
     provider: 'falkordb' | 'falkordblite' | 'ladybugdb' | 'auto';
@@ -728,9 +1000,38 @@ export interface CVConfig {
     database: string;
   };
   vector: {
-    provider: 'qdrant' | 'chroma';
+    provider: 'qdrant' | 'chroma' | 'pgvector' | 'local';
     url: string;
     embedded: boolean;
+    /** Postgres settings used when provider is 'pgvector' (create the table with `cv index init`) */
+    pgvector?: {
+      /** postgres:// connection string (default: CV_PGVECTOR_URL) */
+      connectionString?: string;
+      /** Table name, optionally schema-qualified (default: cv_vectors) */
+      table?: string;
+      /** ANN index created by `cv index init` (default: hnsw) */
+      indexType?: 'hnsw' | 'ivfflat';
+      /** Vector column dimensions (default: from the embedding model) */
+      dimensions?: number;
+    };
+    /** Qdrant server settings used when provider is 'qdrant' */
+    qdrant?: {
+      /** API key for Qdrant Cloud or a secured server (default: CV_QDRANT_API_KEY or QDRANT_API_KEY) */
+      apiKey?: string;
+      /** Base name of the collections, e.g. `acme` gives acme_code_chunks (default: the repository ID) */
+      collection?: string;
+    };
+    /** HNSW graphs of the local store (provider 'local'), built by `cv sync` and kept in .cv/index */
+    hnsw?: {
+      /** Build and search HNSW graphs (default: true) */
+      enabled?: boolean;
+      /** Links per node (default: 16) */
+      m?: number;
+      /** Candidate list size while building (default: 100) */
+      efConstruction?: number;
+      /** Collections with fewer chunks are searched exactly (default: 5000) */
+      minPoints?: number;
+    };
     collections: {
       codeChunks: string;
       docstrings: string;
@@ -743,6 +1044,32 @@ export interface CVConfig {
     syncOnCommit: boolean;
     excludePatterns: string[];
     includeLanguages: string[];
+    /** Maximum lines per code chunk; larger declarations are split at statement boundaries */
+    maxChunkLines?: number;
+    /** Lines adjacent line-window chunks share, for files chunked without declarations (default: 20; 0 disables) */
+    chunkOverlapLines?: number;
+    /** Skip files larger than this many bytes (default: CV_MAX_FILE_SIZE or 1MB) */
+    maxFileSize?: number;
+    /** Comments left out of embedded code: 'headers' (license blocks at the top of a file) or 'all' but doc comments (default: none) */
+    stripComments?: 'none' | 'headers' | 'all';
+    /** Ask before a sync embeds more than this (0 disables a limit) */
+    limits?: {
+      /** Files embedded by one sync (default: 10000) */
+      maxFiles?: number;
+      /** Total bytes embedded by one sync (default: 100MB) */
+      maxTotalBytes?: number;
+      /** Bytes of any one file (default: 512KB) */
+      maxBytes?: number;
+    };
+    /** Commit history index searched by `cv search --history` (opt-in: costs an embedding per commit) */
+    history?: {
+      /** Embed new commits on every sync, as if --history were given (default: false) */
+      enabled?: boolean;
+      /** Embed a truncated diff with each commit message (default: false) */
+      diffs?: boolean;
+      /** Most commits embedded per sync (default: 500) */
+      maxCommits?: number;
+    };
   };
   docs: {
     enabled: boolean;
@@ -761,8 +1088,16 @@ export interface CVConfig {
     apiKey?: string;
     enabled?: boolean;
   };
+  /** Named setups selected with --profile or CV_PROFILE, e.g. `work` (Azure) and `personal` (OpenRouter) */
+  profiles?: Record<string, ConfigProfile>;
 }
 
+/**
+ * Sections a profile overrides. Each section it sets replaces the base
+ * config's section whole, so providers, models and endpoints switch together.
+ */
+export type ConfigProfile = Partial<Omit<CVConfig, 'version' | 'repository' | 'profiles'>>;
+
 export interface SyncState {
   lastFullSync?: number;
   lastIncrementalSync?: number;
@@ -829,6 +1164,17 @@ export class ConfigError extends CVError {
   }
 }
 
+export class RequestTimeoutError extends CVError {
+  constructor(what: string, public timeoutMs: number) {
+    super(`${what} timed out after ${formatTimeout(timeoutMs)}`, 'REQUEST_TIMEOUT', { timeoutMs });
+    this.name = 'RequestTimeoutError';
+  }
+}
+
+function formatTimeout(ms: number): string {
+  return ms % 1000 === 0 ? `${ms / 1000}s` : `${ms}ms`;
+}
+
 // ========== Dependency Management Types ==========
 
 /**
@@ -4,7 +4,7 @@
 
 import * as path from 'path';
 import * as fs from 'fs/promises';
-import { CVWorkspace, WorkspaceRepo } from './types.js';
+import { CVWorkspace, RequestTimeoutError, WorkspaceRepo } from './types.js';
 
 /**
  * Get the .cv directory path for a repository
@@ -211,6 +211,30 @@ export function chunkArray<T>(array: T[], chunkSize: number): T[][] {
   return chunks;
 }
 
+/**
+ * Map items with at most `concurrency` calls in flight, preserving input order
+ */
+export async function mapWithConcurrency<T, R>(
+  items: T[],
+  concurrency: number,
+  fn: (item: T, index: number) => Promise<R>
+): Promise<R[]> {
+  const results: R[] = new Array(items.length);
+  let next = 0;
+
+  const worker = async (): Promise<void> => {
+    while (next < items.length) {
+      const index = next++;
+      results[index] = await fn(items[index], index);
+    }
+  };
+
+  const workers = Array.from({ length: Math.max(1, Math.min(concurrency, items.length)) }, worker);
+  await Promise.all(workers);
+
+  return results;
+}
+
 /**
  * Sleep for specified milliseconds
  */
@@ -243,6 +267,246 @@ export async function retry<T>(
   throw lastError;
 }
 
+export interface RetryAttempt {
+  /** Attempt that failed (1-based) */
+  attempt: number;
+  maxAttempts: number;
+  /** Delay before the next attempt */
+  delayMs: number;
+  error: any;
+}
+
+export interface RetryWithBackoffOptions {
+  /** Total attempts including the first (default: CV_MAX_RETRIES or 5) */
+  maxAttempts?: number;
+  /** Base delay for exponential backoff (default: 1000ms) */
+  baseDelayMs?: number;
+  /** Upper bound for a single delay (default: 60000ms) */
+  maxDelayMs?: number;
+  /** Decide whether an error is worth retrying (default: isRetryableApiError) */
+  isRetryable?: (error: any) => boolean;
+  /** Called before each retry, e.g. for verbose logging */
+  onRetry?: (info: RetryAttempt) => void;
+  /** Stops retrying once aborted, e.g. on Ctrl-C */
+  signal?: AbortSignal;
+}
+
+/**
+ * Default attempt count for API calls, overridable with CV_MAX_RETRIES
+ */
+export function getMaxRetryAttempts(): number {
+  const fromEnv = parseInt(process.env.CV_MAX_RETRIES || '', 10);
+  return Number.isFinite(fromEnv) && fromEnv > 0 ? fromEnv : 5;
+}
+
+/**
+ * Check whether an API error is transient (rate limit, overload, network)
+ */
+export function isRetryableApiError(error: any): boolean {
+  const status = error?.status ?? error?.statusCode ?? error?.response?.status;
+  if (status === 408 || status === 409 || status === 429 || (typeof status === 'number' && status >= 500)) {
+    return true;
+  }
+
+  const code = error?.code ?? error?.cause?.code;
+  if (['ECONNRESET', 'ETIMEDOUT', 'ECONNREFUSED', 'EAI_AGAIN', 'UND_ERR_SOCKET'].includes(code)) {
+    return true;
+  }
+
+  const message: string = error?.message || '';
+  return /rate limit|too many requests|\b429\b|\b503\b|overloaded|No successful provider/i.test(message);
+}
+
+/**
+ * Check whether an API error means the provider itself is failing (5xx,
+ * timeout, unreachable), so the next provider in a fallback chain should
+ * take the request. Rate limits and rejected requests are not outages;
+ * another provider would not treat them differently.
+ */
+export function isProviderOutage(error: any): boolean {
+  if (error instanceof RequestTimeoutError || error?.code === 'REQUEST_TIMEOUT') {
+    return true;
+  }
+
+  const status = error?.status ?? error?.statusCode ?? error?.response?.status ?? error?.cause?.status ?? error?.details?.status;
+  if (typeof status === 'number') {
+    return status >= 500;
+  }
+
+  // CVError wraps the original error in details
+  const codes = [error?.code, error?.cause?.code, error?.details?.code];
+  if (codes.some(code => ['ECONNRESET', 'ETIMEDOUT', 'ECONNREFUSED', 'ENOTFOUND', 'EAI_AGAIN', 'UND_ERR_SOCKET', 'UND_ERR_CONNECT_TIMEOUT'].includes(code))) {
+    return true;
+  }
+
+  const message: string = error?.message || '';
+  return /fetch failed|socket hang up|\b50[0-4]\b|No successful provider/i.test(message);
+}
+
+/**
+ * Read a Retry-After delay (seconds or HTTP date) from an API error, in milliseconds
+ */
+export function getRetryAfterMs(error: any, now: number = Date.now()): number | undefined {
+  const headers = error?.headers ?? error?.response?.headers;
+  if (!headers) return undefined;
+
+  const read = (name: string): string | undefined => {
+    const value = typeof headers.get === 'function' ? headers.get(name) : headers[name];
+    return value == null ? undefined : String(value);
+  };
+
+  const retryAfterMs = read('retry-after-ms');
+  if (retryAfterMs && Number.isFinite(Number(retryAfterMs))) {
+    return Math.max(0, Number(retryAfterMs));
+  }
+
+  const retryAfter = read('retry-after');
+  if (!retryAfter) return undefined;
+
+  const seconds = Number(retryAfter);
+  if (Number.isFinite(seconds)) {
+    return Math.max(0, seconds * 1000);
+  }
+
+  const date = Date.parse(retryAfter);
+  return Number.isFinite(date) ? Math.max(0, date - now) : undefined;
+}
+
+/**
+ * Compute the delay before a retry: Retry-After if the server sent one,
+ * otherwise exponential backoff with jitter
+ */
+export function getRetryDelay(
+  attempt: number,
+  error: any,
+  baseDelayMs: number = 1000,
+  maxDelayMs: number = 60000
+): number {
+  const retryAfter = getRetryAfterMs(error);
+  if (retryAfter !== undefined) {
+    return Math.min(retryAfter, maxDelayMs);
+  }
+
+  const ceiling = Math.min(baseDelayMs * Math.pow(2, attempt - 1), maxDelayMs);
+  return Math.round(ceiling / 2 + Math.random() * (ceiling / 2));
+}
+
+/**
+ * Retry a function on transient API errors, honoring Retry-After
+ */
+export async function retryWithBackoff<T>(
+  fn: () => Promise<T>,
+  options: RetryWithBackoffOptions = {}
+): Promise<T> {
+  const maxAttempts = Math.max(1, options.maxAttempts ?? getMaxRetryAttempts());
+  const isRetryable = options.isRetryable ?? isRetryableApiError;
+
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await fn();
+    } catch (error) {
+      if (attempt >= maxAttempts || !isRetryable(error) || options.signal?.aborted) {
+        throw error;
+      }
+
+      const delayMs = getRetryDelay(attempt, error, options.baseDelayMs, options.maxDelayMs);
+      options.onRetry?.({ attempt, maxAttempts, delayMs, error });
+      await sleep(delayMs);
+      options.signal?.throwIfAborted();
+    }
+  }
+}
+
+/**
+ * Turn a failed HTTP response into an error carrying the status and headers,
+ * so retryWithBackoff can recognise rate limits and honor Retry-After.
+ * `getMessage` reads the message from the provider's JSON error body; the
+ * raw body is used when it is not JSON or has none.
+ */
+export async function toApiError(
+  provider: string,
+  response: Response,
+  getMessage: (body: any) => string | undefined
+): Promise<Error> {
+  let message = await response.text();
+  try {
+    message = getMessage(JSON.parse(message)) || message;
+  } catch {
+    // Not JSON
+  }
+  const error: any = new Error(`${provider} API error: ${response.status} - ${message}`);
+  error.status = response.status;
+  error.headers = response.headers;
+  return error;
+}
+
+// ========== Request Timeouts ==========
+
+/** Default time a chat completion may take; streams reset it on every token */
+export const DEFAULT_CHAT_TIMEOUT_MS = 120_000;
+
+/** Default time an embeddings request may take */
+export const DEFAULT_EMBEDDING_TIMEOUT_MS = 60_000;
+
+export interface RequestTimeoutOptions {
+  /** Milliseconds before the request is cancelled; 0 means no timeout */
+  timeoutMs?: number;
+  /** Cancels the request when aborted, e.g. on Ctrl-C */
+  signal?: AbortSignal;
+}
+
+/**
+ * Run one API request with a signal that aborts on the timeout or on the
+ * caller's signal, whichever comes first. Rejects with RequestTimeoutError
+ * or the abort reason even if the request ignores its signal, so a stuck
+ * connection can never hang the command. Streaming requests call
+ * `keepAlive` as data arrives to restart the timer.
+ */
+export async function withRequestTimeout<T>(
+  what: string,
+  fn: (signal: AbortSignal, keepAlive: () => void) => Promise<T>,
+  options: RequestTimeoutOptions = {}
+): Promise<T> {
+  const { timeoutMs = 0, signal } = options;
+  signal?.throwIfAborted();
+
+  const controller = new AbortController();
+  let timer: ReturnType<typeof setTimeout> | undefined;
+  let fail: (reason: unknown) => void = () => {};
+  const aborted = new Promise<never>((_, reject) => {
+    fail = reason => {
+      controller.abort(reason);
+      reject(reason);
+    };
+  });
+
+  const keepAlive = () => {
+    if (timeoutMs <= 0) return;
+    clearTimeout(timer);
+    timer = setTimeout(() => fail(new RequestTimeoutError(what, timeoutMs)), timeoutMs);
+  };
+  const onAbort = () => fail(signal!.reason);
+
+  keepAlive();
+  signal?.addEventListener('abort', onAbort, { once: true });
+  try {
+    return await Promise.race([fn(controller.signal, keepAlive), aborted]);
+  } finally {
+    clearTimeout(timer);
+    signal?.removeEventListener('abort', onAbort);
+  }
+}
+
+/**
+ * Request timeout in milliseconds from a setting in seconds (0 disables it)
+ */
+export function timeoutFromSeconds(seconds: number | undefined, defaultMs: number): number {
+  if (seconds === undefined || seconds === null || !Number.isFinite(seconds) || seconds < 0) {
+    return defaultMs;
+  }
+  return Math.round(seconds * 1000);
+}
+
 // ========== Workspace Utilities ==========
 
 /**
@@ -304,13 +568,22 @@ export async function findChildGitRepos(dir: string): Promise<WorkspaceRepo[]> {
 }
 
 /**
- * Load workspace configuration
+ * Load workspace configuration. Repos may be listed by path alone (the
+ * file can be edited by hand); names and absolute paths are filled in.
  */
 export async function loadWorkspace(dir: string): Promise<CVWorkspace | null> {
   try {
     const workspacePath = path.join(dir, '.cv', 'workspace.json');
     const data = await fs.readFile(workspacePath, 'utf-8');
-    return JSON.parse(data) as CVWorkspace;
+    const workspace = JSON.parse(data) as CVWorkspace;
+    workspace.root = workspace.root || dir;
+    workspace.repos = (workspace.repos || []).map(repo => ({
+      ...repo,
+      name: repo.name || path.basename(repo.path),
+      absolutePath: repo.absolutePath || path.resolve(workspace.root, repo.path),
+      synced: repo.synced ?? false
+    }));
+    return workspace;
   } catch {
     return null;
   }
@@ -0,0 +1,54 @@
+/**
+ * Ad-hoc Index Tests
+ * Tests for collecting the files cv explain --no-index embeds
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { collectAdHocFiles } from '@cv-git/core';
+
+describe('collectAdHocFiles', () => {
+  let root: string;
+
+  const write = async (file: string, content = 'export const x = 1;\n') => {
+    await fs.mkdir(path.dirname(path.join(root, file)), { recursive: true });
+    await fs.writeFile(path.join(root, file), content);
+  };
+
+  beforeEach(async () => {
+    root = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-ad-hoc-test-'));
+    await write('.gitignore', 'generated/\n*.gen.ts\n');
+    await write('src/a.ts');
+    await write('src/b.py', 'x = 1\n');
+    await write('src/notes.bin', '');
+    await write('src/c.gen.ts');
+    await write('src/generated/d.ts');
+    await write('src/node_modules/dep/index.js');
+    await write('src/.hidden/e.ts');
+    await write('docs/readme.ts');
+  });
+
+  afterEach(async () => {
+    await fs.rm(root, { recursive: true, force: true });
+  });
+
+  it('should walk --dir for source files, leaving out ignored and dependency directories', async () => {
+    const files = await collectAdHocFiles(root, ['docs/readme.ts'], ['src'], 10, undefined, root);
+
+    expect(files).toEqual(['docs/readme.ts', 'src/a.ts', 'src/b.py']);
+  });
+
+  it('should resolve --dir against the current directory', async () => {
+    const files = await collectAdHocFiles(root, [], ['.'], 10, undefined, path.join(root, 'src'));
+
+    expect(files).toEqual(['src/a.ts', 'src/b.py']);
+  });
+
+  it('should refuse more than maxFiles files and directories outside the root', async () => {
+    await expect(collectAdHocFiles(root, [], ['src'], 1, undefined, root)).rejects.toThrow('more than 1 files');
+    await expect(collectAdHocFiles(root, [], ['..'], 10, undefined, root)).rejects.toThrow('outside the repository');
+    await expect(collectAdHocFiles(root, [], ['missing'], 10, undefined, root)).rejects.toThrow('not a directory');
+  });
+});
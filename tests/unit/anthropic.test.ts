@@ -0,0 +1,107 @@
+/**
+ * Anthropic Client Tests
+ * Tests for message mapping, prompt caching and streaming in the Anthropic provider
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import {
+  AnthropicClient,
+  toAnthropicMessages,
+  applyPromptCaching,
+  MIN_CACHEABLE_CHARS,
+  ANTHROPIC_API_VERSION
+} from '@cv-git/core';
+
+describe('toAnthropicMessages', () => {
+  it('should move system prompts to the top-level system field', () => {
+    const result = toAnthropicMessages(
+      [
+        { role: 'system', content: 'Be brief.' },
+        { role: 'user', content: 'Hi' },
+        { role: 'assistant', content: 'Hello' }
+      ],
+      'You are a code assistant.'
+    );
+
+    expect(result.system).toEqual([{ type: 'text', text: 'You are a code assistant.\n\nBe brief.' }]);
+    expect(result.messages).toEqual([
+      { role: 'user', content: [{ type: 'text', text: 'Hi' }] },
+      { role: 'assistant', content: [{ type: 'text', text: 'Hello' }] }
+    ]);
+  });
+
+  it('should merge consecutive messages with the same role', () => {
+    const result = toAnthropicMessages([
+      { role: 'user', content: 'Context' },
+      { role: 'user', content: 'Question' }
+    ]);
+
+    expect(result.system).toBeUndefined();
+    expect(result.messages).toEqual([
+      { role: 'user', content: [{ type: 'text', text: 'Context' }, { type: 'text', text: 'Question' }] }
+    ]);
+  });
+});
+
+describe('applyPromptCaching', () => {
+  it('should mark only blocks long enough to cache', () => {
+    const code = 'x'.repeat(MIN_CACHEABLE_CHARS);
+    const request = toAnthropicMessages(
+      [
+        { role: 'user', content: code },
+        { role: 'assistant', content: 'It parses the config.' },
+        { role: 'user', content: 'And the errors?' }
+      ],
+      'You are a code assistant.'
+    );
+
+    expect(applyPromptCaching(request)).toBe(1);
+    expect(request.system?.[0].cache_control).toBeUndefined();
+    expect(request.messages[0].content[0].cache_control).toEqual({ type: 'ephemeral' });
+    expect(request.messages[2].content[0].cache_control).toBeUndefined();
+  });
+});
+
+describe('AnthropicClient', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('should send the API key and version headers', async () => {
+    const fetchMock = vi.fn(async () => new Response(JSON.stringify({
+      content: [{ type: 'text', text: 'Hello' }],
+      usage: { input_tokens: 10, output_tokens: 2 }
+    })));
+    vi.stubGlobal('fetch', fetchMock);
+
+    const client = new AnthropicClient({ apiKey: 'sk-ant-test', model: 'claude-sonnet-4' });
+    expect(await client.chat([{ role: 'user', content: 'Hi' }], 'Be brief.')).toBe('Hello');
+
+    const [url, init] = fetchMock.mock.calls[0] as unknown as [string, RequestInit];
+    expect(url).toBe('https://api.anthropic.com/v1/messages');
+    expect(init.headers).toMatchObject({ 'x-api-key': 'sk-ant-test', 'anthropic-version': ANTHROPIC_API_VERSION });
+    expect(JSON.parse(init.body as string)).toMatchObject({
+      model: 'claude-sonnet-4',
+      system: [{ type: 'text', text: 'Be brief.' }],
+      messages: [{ role: 'user', content: [{ type: 'text', text: 'Hi' }] }]
+    });
+  });
+
+  it('should stream text deltas', async () => {
+    const events = [
+      { type: 'message_start', message: { usage: { input_tokens: 10 } } },
+      { type: 'content_block_delta', delta: { type: 'text_delta', text: 'Hel' } },
+      { type: 'content_block_delta', delta: { type: 'text_delta', text: 'lo' } },
+      { type: 'message_delta', usage: { output_tokens: 2 } }
+    ];
+    const body = events.map(e => `event: ${e.type}\ndata: ${JSON.stringify(e)}\n\n`).join('');
+    vi.stubGlobal('fetch', vi.fn(async () => new Response(body)));
+
+    const tokens: string[] = [];
+    const client = new AnthropicClient({ apiKey: 'sk-ant-test' });
+    const text = await client.chatStream([{ role: 'user', content: 'Hi' }], undefined, { onToken: t => tokens.push(t) });
+
+    expect(text).toBe('Hello');
+    expect(tokens).toEqual(['Hel', 'lo']);
+  });
+});
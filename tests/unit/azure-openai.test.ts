@@ -0,0 +1,77 @@
+/**
+ * Azure OpenAI Embedding Tests
+ * Tests the requests VectorManager sends to an Azure deployment: the
+ * api-key header, the api-version query and routing by deployment name
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { VectorManager, DEFAULT_AZURE_API_VERSION, configureLogging } from '@cv-git/core';
+
+describe('Azure OpenAI embeddings', () => {
+  let dir: string;
+  const originalFetch = globalThis.fetch;
+  const fetchMock = vi.fn(async (_url: string, init: RequestInit) => {
+    const { input } = JSON.parse(String(init.body)) as { input: string | string[] };
+    return new Response(JSON.stringify({
+      object: 'list',
+      data: [input].flat().map((_, index) => ({ object: 'embedding', index, embedding: [1, 0, 0, 0] })),
+      model: 'text-embedding-3-small',
+      usage: { prompt_tokens: 1, total_tokens: 1 }
+    }), { status: 200, headers: { 'content-type': 'application/json' } });
+  });
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-azure-test-'));
+    fetchMock.mockClear();
+    // The SDK is handed the global fetch while requests are logged
+    globalThis.fetch = fetchMock as unknown as typeof fetch;
+    configureLogging({ level: 'debug', file: path.join(dir, 'cv.log') });
+  });
+
+  afterEach(async () => {
+    configureLogging({ level: 'warn' });
+    globalThis.fetch = originalFetch;
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  const connect = async (apiVersion?: string) => {
+    const vector = new VectorManager({
+      url: '',
+      backend: 'memory',
+      azure: { endpoint: 'https://acme.openai.azure.com/', apiKey: 'azure-key', apiVersion, deployment: 'embed-small' },
+      vectorSize: 4,
+      enableCache: false
+    });
+    await vector.connect();
+    return vector;
+  };
+
+  it('should route embeddings to the deployment with the api-key header and api-version query', async () => {
+    const vector = await connect('2024-10-21');
+
+    expect(await vector.embed('hello')).toEqual([1, 0, 0, 0]);
+
+    const [url, init] = fetchMock.mock.calls[0];
+    const request = new URL(String(url));
+    expect(request.origin).toBe('https://acme.openai.azure.com');
+    expect(request.pathname).toBe('/openai/deployments/embed-small/embeddings');
+    expect(request.searchParams.get('api-version')).toBe('2024-10-21');
+
+    const headers = new Headers(init.headers as HeadersInit);
+    expect(headers.get('api-key')).toBe('azure-key');
+    expect(headers.get('authorization')).toBeNull();
+    expect(JSON.parse(String(init.body)).model).toBe('embed-small');
+  });
+
+  it('should use the default API version and record the deployment as the model', async () => {
+    const vector = await connect();
+
+    await vector.embed('hello');
+
+    expect(new URL(String(fetchMock.mock.calls[0][0])).searchParams.get('api-version')).toBe(DEFAULT_AZURE_API_VERSION);
+    expect(vector.getEmbeddingInfo()).toMatchObject({ provider: 'azure', model: 'embed-small' });
+  });
+});
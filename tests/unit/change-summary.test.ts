@@ -0,0 +1,147 @@
+/**
+ * Change Summary Tests
+ * Tests for cv explain --since: the prompt built from a range of commits,
+ * finding the indexed symbols it touches, and grouping commits into themes
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  buildChangeSummaryPrompt,
+  parseChangeSummary,
+  findTouchedSymbols,
+  OTHER_CHANGES_THEME,
+  ChangeSummaryCommit,
+  ChangeSummaryInput
+} from '@cv-git/core';
+import type { CodeChunkPayload } from '@cv-git/shared';
+
+const commit = (overrides: Partial<ChangeSummaryCommit>): ChangeSummaryCommit => ({
+  sha: 'a1b2c3d4e5f6a7b8c9d0a1b2c3d4e5f6a7b8c9d0',
+  message: 'Fix login',
+  author: 'Dana',
+  authorEmail: 'dana@example.com',
+  date: Date.UTC(2026, 9, 1),
+  files: [],
+  ...overrides
+});
+
+// Newest first, as git log lists them
+const revokeRoute = commit({ sha: 'ccc3333aaaa', message: 'Add POST /tokens/revoke', files: ['src/routes/tokens.ts'] });
+const readme = commit({ sha: 'bbb2222aaaa', message: 'Fix typo in README', files: ['README.md'] });
+const revoke = commit({
+  sha: 'aaa1111aaaa',
+  message: 'Add token revocation to AuthService\n\nStolen tokens stayed valid until they expired.',
+  files: ['src/auth/service.ts', 'src/auth/store.ts'],
+  diff: '+  async revoke(token: string) {\n+    await this.store.deny(token);\n+  }'
+});
+
+const input = (overrides: Partial<ChangeSummaryInput> = {}): ChangeSummaryInput => ({
+  since: 'v1.2.0',
+  commits: [revokeRoute, readme, revoke],
+  symbols: [],
+  ...overrides
+});
+
+const chunk = (overrides: Partial<CodeChunkPayload>): CodeChunkPayload => ({
+  id: 'chunk',
+  file: 'src/auth/service.ts',
+  language: 'typescript',
+  startLine: 1,
+  endLine: 10,
+  text: '',
+  ...overrides
+} as CodeChunkPayload);
+
+describe('buildChangeSummaryPrompt', () => {
+  it('should list commits oldest first with their messages, files and diffs', () => {
+    const prompt = buildChangeSummaryPrompt(input({
+      symbols: [{ file: 'src/auth/service.ts', symbolName: 'AuthService.revoke', symbolKind: 'method' }],
+      focus: 'authentication'
+    }));
+
+    expect(prompt).toContain('changes since v1.2.0');
+    expect(prompt).toContain('Focus on: authentication');
+    expect(prompt.indexOf('### aaa1111')).toBeLessThan(prompt.indexOf('### ccc3333'));
+    expect(prompt).toContain('Stolen tokens stayed valid until they expired.');
+    expect(prompt).toContain('Files: src/auth/service.ts, src/auth/store.ts');
+    expect(prompt).toContain('```diff\n+  async revoke(token: string) {');
+    expect(prompt).toContain('- `AuthService.revoke` (method) in src/auth/service.ts');
+  });
+
+  it('should split the diff budget between commits and note those left out', () => {
+    const commits = Array.from({ length: 30 }, (_, i) => commit({ sha: `${i}`.padStart(7, '0'), diff: '+x'.repeat(3000) }));
+    const prompt = buildChangeSummaryPrompt(input({ commits, totalCommits: 250 }));
+
+    expect(prompt.match(/\(diff truncated\)/g)).toHaveLength(30);
+    expect(prompt.length).toBeLessThan(70000);
+    expect(prompt).toContain('(220 older commits not shown)');
+  });
+});
+
+describe('findTouchedSymbols', () => {
+  it('should find symbols whose lines changed or whose name is the hunk context', () => {
+    const chunks = [
+      chunk({ symbolName: 'AuthService.revoke', symbolKind: 'method', startLine: 40, endLine: 52 }),
+      chunk({ symbolName: 'AuthService.login', startLine: 10, endLine: 30 }),
+      chunk({ symbolName: 'TokenStore', file: 'src/auth/store.ts', startLine: 1, endLine: 80 }),
+      chunk({ symbolName: 'TokenStore', file: 'src/auth/store.ts', startLine: 81, endLine: 120 })
+    ];
+    const ranges = [
+      { file: 'src/auth/service.ts', startLine: 44, endLine: 46, context: '' },
+      { file: 'src/auth/store.ts', startLine: 200, endLine: 204, context: 'export class TokenStore {' }
+    ];
+
+    expect(findTouchedSymbols(ranges, chunks)).toEqual([
+      { file: 'src/auth/service.ts', symbolName: 'AuthService.revoke', symbolKind: 'method' },
+      { file: 'src/auth/store.ts', symbolName: 'TokenStore', symbolKind: undefined }
+    ]);
+  });
+});
+
+describe('parseChangeSummary', () => {
+  it('should map short SHAs to commits and collect unassigned commits', () => {
+    const response = 'Here are the themes:\n' + JSON.stringify({
+      overview: 'Tokens can now be revoked before they expire.',
+      themes: [
+        {
+          title: 'Added token revocation to AuthService',
+          summary: 'Stolen tokens can be revoked through `AuthService.revoke` and a new route.',
+          commits: ['aaa1111', 'ccc3333', 'fff9999'],
+          files: ['src/auth/service.ts', 'src/elsewhere.ts']
+        },
+        { title: 'Made up', summary: 'Nothing real', commits: ['ddd4444'] }
+      ]
+    });
+
+    const summary = parseChangeSummary(response, input());
+
+    expect(summary.overview).toBe('Tokens can now be revoked before they expire.');
+    expect(summary.themes).toEqual([
+      {
+        title: 'Added token revocation to AuthService',
+        summary: 'Stolen tokens can be revoked through `AuthService.revoke` and a new route.',
+        commits: ['ccc3333aaaa', 'aaa1111aaaa'],
+        files: ['src/auth/service.ts']
+      },
+      { title: OTHER_CHANGES_THEME, summary: 'Fix typo in README', commits: ['bbb2222aaaa'], files: ['README.md'] }
+    ]);
+    expect(summary).toMatchObject({ since: 'v1.2.0', commitCount: 3, omitted: 0 });
+  });
+
+  it('should put a commit in one theme only and keep text that is not JSON', () => {
+    const twice = parseChangeSummary(JSON.stringify({
+      overview: '',
+      themes: [
+        { title: 'Revocation', summary: '', commits: ['aaa1111', 'ccc3333', 'bbb2222'] },
+        { title: 'Docs', summary: '', commits: ['bbb2222'] }
+      ]
+    }), input({ totalCommits: 5 }));
+    expect(twice.themes.map(t => t.title)).toEqual(['Revocation']);
+    expect(twice.omitted).toBe(2);
+
+    const prose = parseChangeSummary('Mostly token revocation work.', input());
+    expect(prose.overview).toBe('Mostly token revocation work.');
+    expect(prose.themes).toHaveLength(1);
+    expect(prose.themes[0].commits).toHaveLength(3);
+  });
+});
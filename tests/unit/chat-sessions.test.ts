@@ -0,0 +1,133 @@
+/**
+ * Chat Session Storage Tests
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  createChatSession,
+  saveChatSession,
+  loadChatSession,
+  loadLatestChatSession,
+  listChatSessions,
+  toChatHistory,
+  formatChatTranscript,
+  getChatsDir
+} from '../../packages/core/src/storage/chat-sessions.js';
+
+describe('Chat sessions', () => {
+  let repoRoot: string;
+
+  beforeEach(async () => {
+    repoRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-chat-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(repoRoot, { recursive: true, force: true });
+  });
+
+  it('creates time-ordered session IDs', () => {
+    const session = createChatSession('claude-sonnet-4-5', new Date('2026-10-14T15:30:12Z'));
+    expect(session.id).toMatch(/^20261014-153012-[0-9a-f]{4}$/);
+    expect(session.messages).toEqual([]);
+  });
+
+  it('saves to .cv/chats and loads by ID or prefix', async () => {
+    const session = createChatSession('gpt-4o');
+    session.messages.push({ role: 'user', content: 'how does auth work?', context: 'src/auth.ts', timestamp: 1 });
+    session.messages.push({ role: 'assistant', content: 'It uses tokens.', timestamp: 2 });
+    await saveChatSession(repoRoot, session);
+
+    const files = await fs.readdir(getChatsDir(repoRoot));
+    expect(files).toEqual([`${session.id}.json`]);
+
+    expect((await loadChatSession(repoRoot, session.id)).messages).toHaveLength(2);
+    expect((await loadChatSession(repoRoot, session.id.slice(0, 10))).id).toBe(session.id);
+    await expect(loadChatSession(repoRoot, 'nope')).rejects.toThrow('No chat session nope');
+  });
+
+  it('lists sessions newest first with a title from the first question', async () => {
+    const older = createChatSession('m', new Date('2026-01-01T00:00:00Z'));
+    older.messages.push({ role: 'user', content: 'first question', timestamp: 1 });
+    await saveChatSession(repoRoot, older);
+
+    const newer = createChatSession('m', new Date('2026-01-02T00:00:00Z'));
+    newer.messages.push({ role: 'user', content: 'x'.repeat(100), timestamp: 1 });
+    await new Promise(resolve => setTimeout(resolve, 5));
+    await saveChatSession(repoRoot, newer);
+
+    const sessions = await listChatSessions(repoRoot);
+    expect(sessions.map(s => s.id)).toEqual([newer.id, older.id]);
+    expect(sessions[0].title.length).toBe(60);
+    expect(sessions[1]).toMatchObject({ title: 'first question', turns: 1 });
+    expect((await loadLatestChatSession(repoRoot))?.id).toBe(newer.id);
+  });
+
+  it('returns nothing when no sessions exist', async () => {
+    expect(await listChatSessions(repoRoot)).toEqual([]);
+    expect(await loadLatestChatSession(repoRoot)).toBeNull();
+  });
+
+  it('re-attaches each question\'s context when rebuilding history', () => {
+    const session = createChatSession('m');
+    session.messages.push({ role: 'user', content: 'why?', context: 'code', timestamp: 1 });
+    session.messages.push({ role: 'assistant', content: 'because', timestamp: 2 });
+
+    expect(toChatHistory(session)).toEqual([
+      { role: 'user', content: '<codebase_context>\ncode\n</codebase_context>\n\nwhy?' },
+      { role: 'assistant', content: 'because' }
+    ]);
+  });
+
+  it('leaves out the oldest turns that do not fit the budget', () => {
+    const session = createChatSession('gpt-4o');
+    session.messages.push({ role: 'user', content: 'first '.repeat(400), timestamp: 1 });
+    session.messages.push({ role: 'assistant', content: 'answer one', timestamp: 2 });
+    session.messages.push({ role: 'user', content: 'second', timestamp: 3 });
+    session.messages.push({ role: 'assistant', content: 'answer two', timestamp: 4 });
+    session.messages.push({ role: 'user', content: 'third', timestamp: 5 });
+
+    expect(toChatHistory(session, 100)).toEqual([
+      { role: 'user', content: 'second' },
+      { role: 'assistant', content: 'answer two' },
+      { role: 'user', content: 'third' }
+    ]);
+    expect(toChatHistory(session, 100_000)).toHaveLength(5);
+  });
+
+  it('always sends the latest question', () => {
+    const session = createChatSession('gpt-4o');
+    session.messages.push({ role: 'user', content: 'why?', timestamp: 1 });
+    session.messages.push({ role: 'assistant', content: 'because', timestamp: 2 });
+    session.messages.push({ role: 'user', content: 'x'.repeat(4000), context: 'code', timestamp: 3 });
+
+    const history = toChatHistory(session, 10);
+    expect(history).toHaveLength(1);
+    expect(history[0].content.endsWith('x'.repeat(4000))).toBe(true);
+  });
+
+  it('exports the questions and answers as Markdown without the context', () => {
+    const session = createChatSession('claude-sonnet-4-5', new Date('2026-10-14T15:30:12Z'));
+    session.messages.push({ role: 'user', content: 'why?', context: 'code', timestamp: 1 });
+    session.messages.push({ role: 'assistant', content: 'because\n', timestamp: 2 });
+
+    const transcript = formatChatTranscript(session);
+    expect(transcript).toBe([
+      `# Chat session ${session.id}`,
+      '',
+      'Model: claude-sonnet-4-5 · started 2026-10-14T15:30:12.000Z',
+      '',
+      '## You',
+      '',
+      'why?',
+      '',
+      '## Assistant',
+      '',
+      'because',
+      ''
+    ].join('\n'));
+    expect(transcript).not.toContain('code');
+  });
+});
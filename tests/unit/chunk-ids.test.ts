@@ -0,0 +1,111 @@
+/**
+ * Chunk ID Tests
+ * Tests that chunk IDs are deterministic, that distinct IDs never share a
+ * point, and that a sync rerun after a failed embedding batch replaces its
+ * vectors instead of adding copies or losing any
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { ParsedFile } from '@cv-git/shared';
+import { SyncEngine, VectorManager, chunkByLines, createChunkId } from '@cv-git/core';
+
+const FILE = 'src/stats.ts';
+const content = Array.from({ length: 300 }, (_, i) => `export const stat${i} = ${i};`).join('\n');
+
+describe('createChunkId', () => {
+  it('should give the same code the same ID on every sync', () => {
+    expect(chunkByLines(FILE, content, 'typescript', 10).map(c => c.id))
+      .toEqual(chunkByLines(FILE, content, 'typescript', 10).map(c => c.id));
+  });
+
+  it('should change the ID when the code at a line changes', () => {
+    expect(createChunkId(FILE, 1, 'const a = 1;')).not.toBe(createChunkId(FILE, 1, 'const a = 2;'));
+    expect(createChunkId(FILE, 1, 'const a = 1;')).toMatch(/^src\/stats\.ts:1:[0-9a-f]{16}$/);
+  });
+});
+
+describe('chunk vectors', () => {
+  let repoRoot: string;
+  let vector: VectorManager;
+  // Embedding requests with code of this file fail while set
+  let failingFile: string | undefined;
+
+  const parsedFile = (file: string, lines: number): ParsedFile => {
+    const text = Array.from({ length: lines }, (_, i) => `export const ${path.basename(file, '.ts')}${i} = ${i};`).join('\n');
+    return {
+      path: file,
+      absolutePath: path.join(repoRoot, file),
+      language: 'typescript',
+      content: text,
+      symbols: [],
+      imports: [],
+      exports: [],
+      chunks: chunkByLines(file, text, 'typescript', 1)
+    };
+  };
+
+  // Embeds the files as a sync does, in groups of chunks, and returns the vectors stored
+  const embed = async (files: ParsedFile[]): Promise<number> => {
+    const git = { getLastCommitSha: async () => 'abc123' } as any;
+    const embedder = (new SyncEngine(repoRoot, git, {} as any, {} as any, vector) as any).createChunkEmbedder();
+    for (const file of files) {
+      await embedder.add({ ...file, chunks: [...file.chunks] });
+    }
+    return embedder.finish();
+  };
+
+  beforeEach(async () => {
+    repoRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-chunk-ids-test-'));
+    failingFile = undefined;
+
+    // One embedding per request, or a non-retryable error for the failing file
+    vi.stubGlobal('fetch', vi.fn(async (_url: string, init: RequestInit) => {
+      const { requests } = JSON.parse(String(init.body)) as { requests: Array<{ content: { parts: Array<{ text: string }> } }> };
+      if (failingFile && requests.some(r => r.content.parts[0].text.includes(`// File: ${failingFile}`))) {
+        return new Response(JSON.stringify({ error: { message: 'invalid request' } }), { status: 400 });
+      }
+      return new Response(JSON.stringify({ embeddings: requests.map(() => ({ values: [1, 0, 0, 0] })) }), { status: 200 });
+    }));
+    vi.spyOn(console, 'log').mockImplementation(() => {});
+    vi.spyOn(console, 'warn').mockImplementation(() => {});
+
+    vector = new VectorManager({ url: '', backend: 'memory', geminiApiKey: 'test-key', vectorSize: 4, enableCache: false });
+    await vector.connect();
+  });
+
+  afterEach(async () => {
+    vi.unstubAllGlobals();
+    vi.restoreAllMocks();
+    await vector.close();
+    await fs.rm(repoRoot, { recursive: true, force: true });
+  });
+
+  it('should keep every chunk once after the second batch fails and the sync is rerun', async () => {
+    // More chunks than one embedding group, so src/b.ts is embedded in a second batch
+    const files = [parsedFile('src/a.ts', 300), parsedFile('src/b.ts', 40)];
+    const expected = files.flatMap(file => file.chunks.map(chunk => chunk.id)).sort();
+
+    failingFile = 'src/b.ts';
+    expect(await embed(files)).toBe(0);
+    const partial = await vector.getFileChunks(['src/a.ts', 'src/b.ts']);
+    expect(partial.map(chunk => chunk.file)).toEqual(Array(300).fill('src/a.ts'));
+
+    failingFile = undefined;
+    expect(await embed(files)).toBe(expected.length);
+
+    const stored = await vector.getFileChunks(['src/a.ts', 'src/b.ts']);
+    expect(stored.map(chunk => chunk.id).sort()).toEqual(expected);
+    expect((await vector.getCollectionInfo(vector.getCollectionNames().codeChunks)).points_count).toBe(expected.length);
+  });
+
+  it('should store chunk IDs that a 32-bit hash would fold together as separate points', async () => {
+    // "Aa" and "BB" have the same 32-bit string hash
+    const point = (id: string) => ({ id, vector: [1, 0, 0, 0], payload: { id, file: FILE, startLine: 1, endLine: 1, text: id } });
+    await vector.upsertBatch(vector.getCollectionNames().codeChunks, [point(`${FILE}:1:Aa`), point(`${FILE}:1:BB`)]);
+
+    expect((await vector.getFileChunks([FILE])).map(chunk => chunk.id).sort()).toEqual([`${FILE}:1:Aa`, `${FILE}:1:BB`]);
+  });
+});
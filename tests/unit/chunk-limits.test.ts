@@ -0,0 +1,62 @@
+/**
+ * Chunk Token Limit Tests
+ * Tests that oversized chunks are split or skipped instead of failing the sync
+ */
+
+import { describe, it, expect } from 'vitest';
+import { fitChunksToTokenLimit, createChunkId } from '@cv-git/core';
+
+const chunk = (text: string, startLine: number = 1): any => ({
+  id: `src/gen.ts:${startLine}-${startLine + text.split('\n').length - 1}`,
+  file: 'src/gen.ts',
+  language: 'typescript',
+  startLine,
+  endLine: startLine + text.split('\n').length - 1,
+  text,
+  symbolName: 'generated'
+});
+
+const prepare = (c: any) => `// ${c.file}\n${c.text}`;
+
+describe('fitChunksToTokenLimit', () => {
+  it('leaves chunks under the limit untouched', () => {
+    const small = chunk('const a = 1;\nconst b = 2;');
+    const { chunks, skipped } = fitChunksToTokenLimit([small], 100, prepare);
+
+    expect(chunks).toEqual([small]);
+    expect(skipped).toEqual([]);
+  });
+
+  it('splits oversized chunks on line boundaries with their own line ranges', () => {
+    const lines = Array.from({ length: 40 }, (_, i) => `export const value${i} = ${i};`);
+    const { chunks, skipped } = fitChunksToTokenLimit([chunk(lines.join('\n'), 10)], 100, prepare);
+
+    expect(skipped).toEqual([]);
+    expect(chunks.length).toBeGreaterThan(1);
+    expect(chunks[0].startLine).toBe(10);
+    expect(chunks[chunks.length - 1].endLine).toBe(49);
+    expect(chunks.map(c => c.text).join('\n')).toBe(lines.join('\n'));
+    for (const c of chunks) {
+      expect(c.id).toBe(createChunkId('src/gen.ts', c.startLine, c.text));
+      expect(Math.ceil(prepare(c).length / 4)).toBeLessThanOrEqual(90);
+    }
+  });
+
+  it('skips lines too long to embed and reports them', () => {
+    const text = ['const before = 1;', 'x'.repeat(2000), 'y'.repeat(2000), 'const after = 2;'].join('\n');
+    const { chunks, skipped } = fitChunksToTokenLimit([chunk(text, 5)], 100, prepare);
+
+    expect(chunks.map(c => c.text)).toEqual(['const before = 1;', 'const after = 2;']);
+    expect(chunks.map(c => c.startLine)).toEqual([5, 8]);
+    expect(skipped).toHaveLength(1);
+    expect(skipped[0]).toMatchObject({
+      type: 'oversized_chunk',
+      file: 'src/gen.ts',
+      startLine: 6,
+      endLine: 7,
+      symbolName: 'generated',
+      maxTokens: 100
+    });
+    expect(skipped[0].estimatedTokens).toBeGreaterThan(100);
+  });
+});
@@ -0,0 +1,93 @@
+/**
+ * Chunk Overlap Tests
+ * Tests that overlapping line windows keep code cut by a window boundary
+ * retrievable as a whole, and that retrieval shows the shared lines once
+ */
+
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { VectorManager, chunkFile, deduplicateChunks } from '@cv-git/core';
+
+const FILE = 'src/session.txt';
+
+// refreshSession (lines 8-13) straddles the boundary between the 10-line windows 1-10 and 11-20
+const content = [
+  ...Array.from({ length: 7 }, (_, i) => `export const filler${i + 1} = ${i + 1};`),
+  'export function refreshSession(session) {',
+  '  if (session.expiresAt < Date.now()) {',
+  '    session.expiresAt = Date.now() + TTL;',
+  '  }',
+  '  return rotateKeys(session);',
+  '}',
+  ...Array.from({ length: 17 }, (_, i) => `export const filler${i + 14} = ${i + 14};`)
+].join('\n');
+
+/** Word counts hashed into buckets: texts sharing identifiers get similar vectors */
+function embedWords(text: string): number[] {
+  const values = new Array(768).fill(0);
+  for (const word of text.match(/[A-Za-z]+/g) ?? []) {
+    let hash = 0;
+    for (const ch of word) hash = (hash * 31 + ch.charCodeAt(0)) % 768;
+    values[hash] += 1;
+  }
+  return values;
+}
+
+describe('retrieval across a chunk boundary', () => {
+  let indexDir: string;
+
+  beforeEach(async () => {
+    indexDir = path.join(await fs.mkdtemp(path.join(os.tmpdir(), 'cv-chunk-overlap-test-')), 'index');
+    vi.stubGlobal('fetch', vi.fn(async (_url: string, init: RequestInit) => {
+      const body = JSON.parse(init.body as string);
+      const embeddings = body.requests.map((request: { content: { parts: Array<{ text: string }> } }) => ({
+        values: embedWords(request.content.parts[0].text)
+      }));
+      return new Response(JSON.stringify({ embeddings }), { status: 200, headers: { 'Content-Type': 'application/json' } });
+    }));
+  });
+
+  afterEach(async () => {
+    vi.unstubAllGlobals();
+    await fs.rm(path.dirname(indexDir), { recursive: true, force: true });
+  });
+
+  const search = async (overlapLines: number, limit: number) => {
+    const vector = new VectorManager({ url: '', backend: 'local', indexDir, geminiApiKey: 'test-key', enableCache: false });
+    await vector.connect();
+    const chunks = chunkFile(FILE, content, [], { maxChunkLines: 10, overlapLines }, 'text');
+    const vectors = await vector.embedBatch(chunks.map(chunk => chunk.text));
+    await vector.upsertBatch(vector.getCollectionNames().codeChunks, chunks.map((chunk, i) => ({
+      id: chunk.id,
+      vector: vectors[i],
+      payload: { id: chunk.id, file: chunk.file, language: chunk.language, startLine: chunk.startLine, endLine: chunk.endLine, text: chunk.text }
+    })));
+    return vector.searchCode('refreshSession rotateKeys', limit, { withVectors: true });
+  };
+
+  it('should retrieve code cut by a window boundary in one chunk', async () => {
+    const [unsplit] = await search(0, 1);
+    expect(unsplit.payload.text.includes('refreshSession') && unsplit.payload.text.includes('rotateKeys')).toBe(false);
+
+    const [best] = await search(5, 1);
+    expect(best.payload.startLine).toBeLessThanOrEqual(8);
+    expect(best.payload.endLine).toBeGreaterThanOrEqual(13);
+    expect(best.payload.text).toContain('export function refreshSession(session) {');
+    expect(best.payload.text).toContain('  return rotateKeys(session);\n}');
+  });
+
+  it('should show the lines adjacent windows share only once', async () => {
+    const results = await search(5, 3);
+    expect(results.length).toBe(3);
+
+    const { chunks } = deduplicateChunks(results);
+    const shown = chunks.flatMap(chunk => chunk.payload.text.split('\n'));
+    expect(new Set(shown).size).toBe(shown.length);
+    expect(chunks[0].payload.text).toContain('rotateKeys');
+    for (const chunk of chunks) {
+      expect(chunk.payload.text.split('\n')).toHaveLength(chunk.payload.endLine - chunk.payload.startLine + 1);
+    }
+  });
+});
@@ -0,0 +1,109 @@
+/**
+ * Chunking Strategy Unit Tests
+ * Tests for declaration-aware Go chunking and the line-window fallback
+ */
+
+import { describe, it, expect } from 'vitest';
+import { chunkFile, chunkByLines, hasChunkStrategy } from '@cv-git/core';
+
+const GO_SOURCE = `package auth
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxTokens is the token limit per user
+const MaxTokens = 10
+
+// AuthService manages users and tokens
+type AuthService struct {
+	users  map[string]*User
+	tokens map[string]string
+}
+
+// GetUserStats returns statistics about users and tokens
+// This is a complex function that might be a hotspot
+func (s *AuthService) GetUserStats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"users": len(s.users),
+	}
+	return stats
+}
+
+func format(d time.Duration) string {
+	return fmt.Sprintf("%s {", d)
+}
+`;
+
+describe('chunkFile', () => {
+  it('should select a strategy by extension', () => {
+    expect(hasChunkStrategy('service.go')).toBe(true);
+    expect(hasChunkStrategy('notes.txt')).toBe(false);
+  });
+
+  it('should split Go at top-level declarations with doc comments attached', () => {
+    const chunks = chunkFile('auth/service.go', GO_SOURCE);
+
+    expect(chunks.map(c => c.symbolName)).toEqual([
+      'MaxTokens',
+      'AuthService',
+      'AuthService.GetUserStats',
+      'format'
+    ]);
+
+    const stats = chunks[2];
+    expect(stats.symbolKind).toBe('method');
+    expect(stats.startLine).toBe(17);
+    expect(stats.endLine).toBe(24);
+    expect(stats.text.startsWith('// GetUserStats returns')).toBe(true);
+    expect(stats.docstring).toContain('hotspot');
+
+    expect(chunks[1].symbolKind).toBe('struct');
+    expect(chunks[3].endLine).toBe(28);
+  });
+
+  it('should split large declarations at statement boundaries', () => {
+    const body = Array.from({ length: 30 }, (_, i) => [
+      `\tif x > ${i} {`,
+      `\t\tx--`,
+      `\t}`
+    ].join('\n')).join('\n');
+    const source = `package main\n\nfunc big(x int) int {\n${body}\n\treturn x\n}\n`;
+
+    const chunks = chunkFile('big.go', source, [], { maxChunkLines: 20 });
+
+    expect(chunks.length).toBeGreaterThan(1);
+    for (const chunk of chunks) {
+      expect(chunk.symbolName).toBe('big');
+      expect(chunk.endLine - chunk.startLine + 1).toBeLessThanOrEqual(20);
+    }
+    // Every cut lands after a closing brace, never inside an if block
+    for (const chunk of chunks.slice(0, -1)) {
+      expect(chunk.text.split('\n').pop()).toBe('\t}');
+    }
+    expect(chunks[chunks.length - 1].endLine).toBe(95);
+  });
+
+  it('should fall back to line windows for unknown languages', () => {
+    const content = Array.from({ length: 25 }, (_, i) => `line ${i + 1}`).join('\n');
+    const chunks = chunkFile('data.txt', content, [], { maxChunkLines: 10, overlapLines: 0 }, 'text');
+
+    expect(chunks.map(c => [c.startLine, c.endLine])).toEqual([[1, 10], [11, 20], [21, 25]]);
+    expect(chunks[0].language).toBe('text');
+  });
+
+  it('should overlap adjacent line windows', () => {
+    const content = Array.from({ length: 25 }, (_, i) => `line ${i + 1}`).join('\n');
+
+    expect(chunkFile('data.txt', content, [], { maxChunkLines: 10, overlapLines: 3 }).map(c => [c.startLine, c.endLine]))
+      .toEqual([[1, 10], [8, 17], [15, 24], [22, 25]]);
+    // The default overlap is capped at half a window
+    expect(chunkFile('data.txt', content, [], { maxChunkLines: 10 }).map(c => [c.startLine, c.endLine]))
+      .toEqual([[1, 10], [6, 15], [11, 20], [16, 25]]);
+  });
+
+  it('should produce no windows for empty content', () => {
+    expect(chunkByLines('empty.txt', '', 'text')).toEqual([]);
+  });
+});
@@ -0,0 +1,55 @@
+/**
+ * Cited Location Tests
+ * Tests for finding the file:line references cv explain --open can jump to
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findCitedLocations } from '@cv-git/core';
+import { CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+
+function chunk(file: string, startLine: number, endLine: number, symbolName?: string): VectorSearchResult<CodeChunkPayload> {
+  return {
+    id: `${file}:${startLine}`,
+    score: 0.8,
+    payload: {
+      id: `${file}:${startLine}`,
+      file,
+      language: 'go',
+      symbolName,
+      startLine,
+      endLine,
+      text: '',
+      imports: [],
+      lastModified: 0
+    }
+  };
+}
+
+const chunks = [
+  chunk('internal/billing/service.go', 140, 180, 'Charge'),
+  chunk('internal/billing/invoice.go', 10, 40, 'NewInvoice')
+];
+
+describe('findCitedLocations', () => {
+  it('should rank cited locations by mentions, then by first citation', () => {
+    const answer = 'Invoices start in `invoice.go:12`. `Charge` (internal/billing/service.go:150) retries, ' +
+      'see service.go:150-160 again.';
+
+    expect(findCitedLocations(answer, chunks)).toEqual([
+      { file: 'internal/billing/service.go', line: 150, symbol: 'Charge', mentions: 2 },
+      { file: 'internal/billing/invoice.go', line: 12, symbol: 'NewInvoice', mentions: 1 }
+    ]);
+  });
+
+  it('should ignore citations of files outside the context', () => {
+    const locations = findCitedLocations('Compare with handler.go:20 and invoice.go:30', chunks);
+    expect(locations.map(l => `${l.file}:${l.line}`)).toEqual(['internal/billing/invoice.go:30']);
+  });
+
+  it('should fall back to the context chunks when nothing is cited', () => {
+    expect(findCitedLocations('Charge retries three times.', chunks)).toEqual([
+      { file: 'internal/billing/service.go', line: 140, symbol: 'Charge', mentions: 0 },
+      { file: 'internal/billing/invoice.go', line: 10, symbol: 'NewInvoice', mentions: 0 }
+    ]);
+  });
+});
@@ -0,0 +1,115 @@
+/**
+ * Code Insertion Tests
+ * Tests for placing generated code into a file for `cv code --apply`
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  extractGeneratedCode,
+  findSymbol,
+  resolveInsertionPoint,
+  insertCode,
+  endOfFile,
+  createInsertionEdit,
+  createEditParser
+} from '@cv-git/core';
+import { SymbolNode } from '@cv-git/shared';
+
+function symbol(name: string, qualifiedName: string, startLine: number, endLine: number): SymbolNode {
+  return {
+    name,
+    qualifiedName,
+    kind: 'method',
+    file: 'src/user.ts',
+    startLine,
+    endLine,
+    visibility: 'public',
+    isAsync: false,
+    isStatic: false,
+    complexity: 1,
+    createdAt: 0,
+    updatedAt: 0
+  };
+}
+
+const source = [
+  'export class UserService {',
+  '  login(user: string) {',
+  '    return true;',
+  '  }',
+  '}',
+  ''
+].join('\n');
+
+const symbols = [
+  symbol('UserService', 'src/user.ts:UserService', 1, 5),
+  symbol('login', 'src/user.ts:UserService.login', 2, 4)
+];
+
+describe('extractGeneratedCode', () => {
+  it('returns the first block that is not a search/replace edit', () => {
+    const response = 'Edit:\n```src/a.ts\n<<<<<<< SEARCH\na\n=======\nb\n>>>>>>> REPLACE\n```\nCode:\n```ts\nlogout() {}\n```\n';
+    expect(extractGeneratedCode(response)).toBe('logout() {}');
+    expect(extractGeneratedCode('No code here.')).toBeNull();
+  });
+});
+
+describe('findSymbol', () => {
+  it('matches a qualified name before a plain one', () => {
+    expect(findSymbol(symbols, 'UserService.login')?.startLine).toBe(2);
+    expect(findSymbol(symbols, 'login')?.startLine).toBe(2);
+    expect(findSymbol(symbols, 'logout')).toBeNull();
+  });
+});
+
+describe('insertCode', () => {
+  it('inserts after a symbol with its indentation and a blank line', () => {
+    const point = resolveInsertionPoint(source, { afterSymbol: 'login' }, symbols)!;
+    expect(point.afterLine).toBe(4);
+
+    const updated = insertCode(source, 'logout() {\n  return false;\n}', point);
+    expect(updated).toBe([
+      'export class UserService {',
+      '  login(user: string) {',
+      '    return true;',
+      '  }',
+      '',
+      '  logout() {',
+      '    return false;',
+      '  }',
+      '}',
+      ''
+    ].join('\n'));
+  });
+
+  it('returns null when the symbol is not in the file', () => {
+    expect(resolveInsertionPoint(source, { afterSymbol: 'logout' }, symbols)).toBeNull();
+  });
+
+  it('inserts at a line with the indentation of the code there', () => {
+    const point = resolveInsertionPoint(source, { line: 3 })!;
+    expect(insertCode(source, 'console.log(user);', point).split('\n').slice(1, 4)).toEqual([
+      '  login(user: string) {',
+      '    console.log(user);',
+      '    return true;'
+    ]);
+  });
+
+  it('keeps CRLF line endings and appends at the end of the file', () => {
+    const crlf = 'const a = 1;\r\n';
+    expect(insertCode(crlf, 'const b = 2;\nconst c = 3;', endOfFile(crlf)))
+      .toBe('const a = 1;\r\n\r\nconst b = 2;\r\nconst c = 3;\r\n');
+  });
+});
+
+describe('createInsertionEdit', () => {
+  it('shows the insertion as added lines with context', () => {
+    const point = resolveInsertionPoint(source, { afterSymbol: 'login' }, symbols)!;
+    const edit = createInsertionEdit('src/user.ts', source, 'logout() {}', point);
+    const diff = createEditParser().generateDiff(edit, source);
+
+    expect(diff.hunks).toHaveLength(1);
+    const changed = diff.hunks[0].lines.filter(line => line.type !== 'context');
+    expect(changed.map(line => `${line.type}:${line.content}`)).toEqual(['add:', 'add:  logout() {}']);
+  });
+});
@@ -0,0 +1,72 @@
+/**
+ * Cohere and Voyage AI Embedding Tests
+ * Tests that documents and queries are embedded with their own input types
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import { CohereClient, VoyageClient, checkIndexCompatibility } from '@cv-git/core';
+
+function mockFetch(body: unknown) {
+  const fetchMock = vi.fn().mockResolvedValue(new Response(JSON.stringify(body), { status: 200 }));
+  vi.stubGlobal('fetch', fetchMock);
+  return fetchMock;
+}
+
+function sentBody(fetchMock: ReturnType<typeof vi.fn>, call: number = 0): any {
+  return JSON.parse(fetchMock.mock.calls[call][1].body);
+}
+
+afterEach(() => {
+  vi.unstubAllGlobals();
+});
+
+describe('CohereClient', () => {
+  it('embeds documents as search_document and queries as search_query', async () => {
+    const fetchMock = mockFetch({ embeddings: { float: [[0.1, 0.2]] } });
+    const client = new CohereClient({ apiKey: 'co-key', maxRetryAttempts: 1 });
+
+    expect(await client.embed(['function a() {}'])).toEqual([[0.1, 0.2]]);
+    await client.embed(['where is auth handled?'], 'embed-english-v3.0', 'query');
+
+    expect(fetchMock.mock.calls[0][0]).toBe('https://api.cohere.com/v2/embed');
+    expect(sentBody(fetchMock, 0)).toMatchObject({ input_type: 'search_document', embedding_types: ['float'] });
+    expect(sentBody(fetchMock, 1)).toMatchObject({ input_type: 'search_query', texts: ['where is auth handled?'] });
+  });
+
+  it('splits more than 96 texts across requests', async () => {
+    const fetchMock = mockFetch({ embeddings: { float: [[1]] } });
+    await new CohereClient({ apiKey: 'co-key', maxRetryAttempts: 1 }).embed(Array.from({ length: 100 }, (_, i) => `t${i}`));
+
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+    expect(sentBody(fetchMock, 1).texts).toHaveLength(4);
+  });
+});
+
+describe('VoyageClient', () => {
+  it('sends the query input type and returns embeddings in input order', async () => {
+    const fetchMock = mockFetch({ data: [{ index: 1, embedding: [2] }, { index: 0, embedding: [1] }] });
+    const embeddings = await new VoyageClient({ apiKey: 'pa-key', maxRetryAttempts: 1 }).embed(['a', 'b'], 'voyage-code-3', 'query');
+
+    expect(embeddings).toEqual([[1], [2]]);
+    expect(fetchMock.mock.calls[0][0]).toBe('https://api.voyageai.com/v1/embeddings');
+    expect(sentBody(fetchMock)).toEqual({ model: 'voyage-code-3', input: ['a', 'b'], input_type: 'query' });
+  });
+});
+
+describe('input types in the index fingerprint', () => {
+  const cohere = {
+    provider: 'cohere',
+    model: 'embed-english-v3.0',
+    dimensions: 1024,
+    inputTypes: { document: 'search_document', query: 'search_query' }
+  };
+
+  it('accepts an index built with the same input types', () => {
+    expect(checkIndexCompatibility(cohere, { ...cohere }).compatible).toBe(true);
+  });
+
+  it('rejects an index built without the input types the provider now sends', () => {
+    const { inputTypes, ...untyped } = cohere;
+    expect(checkIndexCompatibility(untyped, cohere)).toEqual({ compatible: false, mismatches: ['inputTypes'] });
+  });
+});
@@ -0,0 +1,96 @@
+/**
+ * Comment Stripping Tests
+ * Tests for sync.stripComments: license headers and comments left out of
+ * the embedded text, doc comments kept
+ */
+
+import { describe, it, expect } from 'vitest';
+import { stripComments } from '@cv-git/core';
+
+const goFile = [
+  '// Copyright 2026 Example Corp.',
+  '// Licensed under the Apache License, Version 2.0.',
+  '',
+  '// Package auth issues session tokens.',
+  'package auth',
+  '',
+  '// Refresh extends a session by a day.',
+  'func Refresh(s *Session) {',
+  '\t// bump the expiry',
+  '\ts.Expiry = s.Expiry.Add(24 * time.Hour)',
+  '}'
+].join('\n');
+
+describe('stripComments', () => {
+  it('should leave the text alone with none, or for unknown languages', () => {
+    expect(stripComments(goFile, 'go', 'none')).toBe(goFile);
+    expect(stripComments('-- a comment\nSELECT 1;', 'sql', 'all')).toBe('-- a comment\nSELECT 1;');
+  });
+
+  it('should drop only the header with headers, keeping the package doc comment', () => {
+    expect(stripComments(goFile, 'go', 'headers')).toBe(goFile.split('\n').slice(3).join('\n'));
+    // A chunk from the middle of a file has no header
+    expect(stripComments(goFile, 'go', 'headers', false)).toBe(goFile);
+  });
+
+  it('should drop every comment but the ones attached to declarations with all', () => {
+    expect(stripComments(goFile, 'go', 'all')).toBe([
+      '// Package auth issues session tokens.',
+      'package auth',
+      '',
+      '// Refresh extends a session by a day.',
+      'func Refresh(s *Session) {',
+      '\ts.Expiry = s.Expiry.Add(24 * time.Hour)',
+      '}'
+    ].join('\n'));
+  });
+
+  it('should handle block comments and keep /** doc comments', () => {
+    const ts = [
+      '/*',
+      ' * MIT License',
+      ' */',
+      "import { a } from './a';",
+      '',
+      '/** Adds one. */',
+      'const addOne = (n: number) => {',
+      '  /* a note */',
+      '  return n + 1; // trailing comments stay',
+      '};',
+      'const url = "http://example.com";'
+    ].join('\n');
+
+    expect(stripComments(ts, 'typescript', 'all')).toBe([
+      "import { a } from './a';",
+      '',
+      '/** Adds one. */',
+      'const addOne = (n: number) => {',
+      '  return n + 1; // trailing comments stay',
+      '};',
+      'const url = "http://example.com";'
+    ].join('\n'));
+  });
+
+  it('should use # for Python, keeping the shebang, docstrings and comments over a def', () => {
+    const py = [
+      '#!/usr/bin/env python3',
+      '# SPDX-License-Identifier: MIT',
+      '',
+      '# Parses the config file.',
+      'def load(path):',
+      '    """Read path as YAML."""',
+      '    # open it',
+      '    return yaml.safe_load(open(path))'
+    ].join('\n');
+
+    expect(stripComments(py, 'python', 'all')).toBe([
+      '#!/usr/bin/env python3',
+      '',
+      '# Parses the config file.',
+      'def load(path):',
+      '    """Read path as YAML."""',
+      '    return yaml.safe_load(open(path))'
+    ].join('\n'));
+    expect(stripComments(py, 'python', 'headers')).toBe(['#!/usr/bin/env python3', ...py.split('\n').slice(2)].join('\n'));
+  });
+});
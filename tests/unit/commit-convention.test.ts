@@ -0,0 +1,128 @@
+/**
+ * Commit Convention Tests
+ * Tests for the commit.* config of cv commit: templates, scopes, subject
+ * length, ticket IDs from the branch name, and regenerating messages that
+ * break the rules
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import {
+  resolveCommitConvention,
+  extractTicketId,
+  formatCommitSubject,
+  validateCommitMessage,
+  createCommitAnalyzer,
+  CommitAnalysis
+} from '@cv-git/core';
+
+describe('resolveCommitConvention', () => {
+  it('should default to Conventional Commits with a 72-character subject', () => {
+    const convention = resolveCommitConvention();
+
+    expect(convention).toMatchObject({ name: 'conventional', template: '{type}({scope}): {subject}', maxSubjectLength: 72 });
+    expect(validateCommitMessage('feat(sync): add --estimate', convention)).toEqual([]);
+    expect(validateCommitMessage('fix: handle empty diffs', convention)).toEqual([]);
+  });
+
+  it('should take the ticket ID from the branch name, and leave {ticket} out without one', () => {
+    expect(extractTicketId('feature/ABC-123-login', '[A-Z]+-\\d+')).toBe('ABC-123');
+    expect(extractTicketId('jira/abc-9', '^jira/([a-z]+-\\d+)')).toBe('abc-9');
+    expect(() => extractTicketId('main', '(')).toThrow('Invalid commit.ticketPattern');
+
+    const ticket = resolveCommitConvention({ convention: 'ticket' }, 'feature/ABC-123-login');
+    expect(ticket.ticket).toBe('ABC-123');
+    expect(formatCommitSubject(ticket, { type: 'feat', subject: 'Add login' })).toBe('ABC-123 Add login');
+
+    const custom = resolveCommitConvention({ template: '[{ticket}] {type}: {subject}' }, 'main');
+    expect(custom).toMatchObject({ template: '{type}: {subject}', missingTicket: true });
+  });
+
+  it('should format gitmoji subjects from the commit type', () => {
+    const convention = resolveCommitConvention({ convention: 'gitmoji' });
+
+    expect(formatCommitSubject(convention, { type: 'fix', scope: 'cli', subject: 'Handle empty diffs' })).toBe(':bug: Handle empty diffs');
+    expect(validateCommitMessage(':bug: Handle empty diffs', convention)).toEqual([]);
+    expect(validateCommitMessage('🐛 Handle empty diffs', convention)).toEqual([]);
+    expect(validateCommitMessage('fix: handle empty diffs', convention)).toEqual(['The subject line does not follow the format <emoji> <subject>']);
+  });
+});
+
+describe('validateCommitMessage', () => {
+  it('should report the length, format, type and scope rules a message breaks', () => {
+    const convention = resolveCommitConvention({ convention: 'angular', scopes: ['core', 'cli'], maxSubjectLength: 30 });
+
+    expect(validateCommitMessage('feat(core): add retries\n\nBody', convention)).toEqual([]);
+    expect(validateCommitMessage('chore(docs): update the contributing guide\nBody', convention)).toEqual([
+      'The subject line has 42 characters; the limit is 30',
+      'The subject line must be followed by a blank line',
+      'Type "chore" is not one of: build, ci, docs, feat, fix, perf, refactor, test',
+      'Scope "docs" is not one of: core, cli'
+    ]);
+    expect(validateCommitMessage('Add retries', convention)).toEqual(['The subject line does not follow the format <type>(<scope>): <subject>']);
+  });
+
+  it('should require the ticket in the message when the template has no place for it', () => {
+    const convention = resolveCommitConvention({ ticketPattern: '[A-Z]+-\\d+' }, 'ABC-7-retries');
+
+    expect(validateCommitMessage('feat: add retries\n\nRefs: ABC-7', convention)).toEqual([]);
+    expect(validateCommitMessage('feat: add retries', convention)).toEqual(['The message does not reference ticket ABC-7']);
+  });
+});
+
+describe('CommitAnalyzer message conventions', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  const analysis: CommitAnalysis = {
+    filesChanged: ['packages/core/src/retry.ts'],
+    linesAdded: 10,
+    linesRemoved: 2,
+    symbolsAdded: [],
+    symbolsModified: [],
+    symbolsDeleted: [],
+    callersAffected: [],
+    modulesAffected: ['packages/core'],
+    complexityDelta: 0,
+    suggestedType: 'feat',
+    suggestedScope: 'core',
+    isBreakingChange: false,
+    breakingChanges: [],
+    rawDiff: 'diff --git a/packages/core/src/retry.ts b/packages/core/src/retry.ts\n'
+  };
+
+  it('should regenerate a message that breaks the convention, listing the broken rules', async () => {
+    const replies = ['feat(core): Added a much longer retry loop to every provider call', 'feat(core): retry provider calls'];
+    const prompts: string[] = [];
+    vi.stubGlobal('fetch', vi.fn(async (_url: string, init: RequestInit) => {
+      prompts.push(JSON.parse(init.body as string).messages[0].content);
+      return new Response(JSON.stringify({ choices: [{ message: { content: replies.shift() } }] }), { status: 200 });
+    }));
+
+    const analyzer = createCommitAnalyzer({
+      repoRoot: process.cwd(),
+      provider: 'openrouter',
+      apiKey: 'test-key',
+      convention: resolveCommitConvention({ scopes: ['core', 'cli'], maxSubjectLength: 50 })
+    });
+    const generated = await analyzer.generateMessage(analysis);
+
+    expect(generated.fullMessage).toBe('feat(core): retry provider calls');
+    expect(generated.violations).toEqual([]);
+    expect(prompts).toHaveLength(2);
+    expect(prompts[0]).toContain('<scope> is one of: core, cli');
+    expect(prompts[1]).toContain('- The subject line has 65 characters; the limit is 50');
+  });
+
+  it('should build the template message in the configured format', async () => {
+    const analyzer = createCommitAnalyzer({
+      repoRoot: process.cwd(),
+      provider: 'none',
+      convention: resolveCommitConvention({ convention: 'ticket' }, 'feature/CV-42-retries')
+    });
+    const generated = await analyzer.generateMessage(analysis);
+
+    expect(generated.subject).toBe('CV-42 update packages/core/src/retry.ts');
+    expect(generated.violations).toEqual([]);
+  });
+});
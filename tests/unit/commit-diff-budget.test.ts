@@ -0,0 +1,56 @@
+/**
+ * Commit Diff Budget Tests
+ * Tests for truncateDiff, which fits the staged diff cv commit sends to
+ * the model into --diff-budget tokens
+ */
+
+import { describe, it, expect } from 'vitest';
+import { truncateDiff } from '@cv-git/core';
+
+const fileDiff = (file: string, bodyLines: number): string => [
+  `diff --git a/${file} b/${file}`,
+  'index 1111111..2222222 100644',
+  `--- a/${file}`,
+  `+++ b/${file}`,
+  '@@ -1,3 +1,3 @@',
+  ...Array.from({ length: bodyLines }, (_, i) => `+const line${i} = ${i};`)
+].join('\n') + '\n';
+
+describe('truncateDiff', () => {
+  it('should return a diff within the budget unchanged', () => {
+    const diff = fileDiff('src/a.ts', 3);
+
+    expect(truncateDiff(diff, 4000)).toEqual({ text: diff, truncated: false });
+  });
+
+  it('should keep every file header when hunk bodies are cut', () => {
+    const diff = fileDiff('src/big.ts', 400) + fileDiff('src/small.ts', 400);
+
+    const { text, truncated } = truncateDiff(diff, 200);
+
+    expect(truncated).toBe(true);
+    expect(text.length).toBeLessThan(diff.length);
+    expect(text).toContain('diff --git a/src/big.ts b/src/big.ts');
+    expect(text).toContain('diff --git a/src/small.ts b/src/small.ts');
+    expect(text.match(/\.\.\. \(truncated\)/g)).toHaveLength(2);
+  });
+
+  it('should cut hunk bodies at a line boundary', () => {
+    const { text } = truncateDiff(fileDiff('src/a.ts', 400), 100);
+
+    for (const line of text.split('\n').filter(l => l.startsWith('+const'))) {
+      expect(line).toMatch(/^\+const line\d+ = \d+;$/);
+    }
+  });
+
+  it('should list headers only when they alone exceed the budget', () => {
+    const diff = Array.from({ length: 50 }, (_, i) => fileDiff(`src/file${i}.ts`, 2)).join('');
+
+    const { text, truncated } = truncateDiff(diff, 100);
+
+    expect(truncated).toBe(true);
+    expect(text).not.toContain('+const');
+    expect(text.endsWith('... (diff truncated)')).toBe(true);
+    expect(text.length).toBeLessThanOrEqual(100 * 4 + '\n... (diff truncated)'.length);
+  });
+});
@@ -0,0 +1,80 @@
+/**
+ * Commit History Context Tests
+ * Tests for cv explain --history: parsing git log output and fitting commits to the token budget
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  parseShortstatLog,
+  truncateCommitMessage,
+  fitHistoryToBudget,
+  formatCommitHistory
+} from '@cv-git/core';
+import type { GitCommit } from '@cv-git/shared';
+
+const commit = (overrides: Partial<GitCommit>): GitCommit => ({
+  sha: 'a1b2c3d4e5f6a7b8c9d0a1b2c3d4e5f6a7b8c9d0',
+  message: 'Fix login',
+  author: 'Dana',
+  authorEmail: 'dana@example.com',
+  date: Date.UTC(2026, 9, 1),
+  files: [],
+  ...overrides
+});
+
+describe('parseShortstatLog', () => {
+  it('should keep multi-line messages and read the stats of each commit', () => {
+    const output =
+      '\x1ea1b2c3\x1fDana\x1fdana@example.com\x1f1790000000\x1fUse sessions for auth\n\nTokens could not be revoked.\n\x1f\n\n' +
+      ' 3 files changed, 40 insertions(+), 12 deletions(-)\n' +
+      '\x1ed4e5f6\x1fLee\x1flee@example.com\x1f1780000000\x1fMerge branch main\n\x1f\n';
+
+    const commits = parseShortstatLog(output);
+
+    expect(commits).toHaveLength(2);
+    expect(commits[0]).toMatchObject({
+      sha: 'a1b2c3',
+      author: 'Dana',
+      message: 'Use sessions for auth\n\nTokens could not be revoked.',
+      date: 1790000000000,
+      stats: { filesChanged: 3, insertions: 40, deletions: 12 }
+    });
+    expect(commits[1].stats).toBeUndefined();
+  });
+});
+
+describe('fitHistoryToBudget', () => {
+  it('should truncate long messages at a line break', () => {
+    const message = `Subject\n\n${'Because of the reasons. '.repeat(30)}\n${'More detail. '.repeat(100)}`;
+    const truncated = truncateCommitMessage(message, 1000);
+
+    expect(truncated.length).toBeLessThan(1000);
+    expect(truncated.endsWith('\n[...]')).toBe(true);
+    expect(truncated).not.toContain('More detail');
+  });
+
+  it('should keep the newest commits that fit', () => {
+    const commits = [
+      commit({ sha: 'new', message: 'x'.repeat(400) }),
+      commit({ sha: 'old', message: 'y'.repeat(400) })
+    ];
+
+    const fitted = fitHistoryToBudget(commits, 150);
+
+    expect(fitted.commits.map(c => c.sha)).toEqual(['new']);
+    expect(fitted.dropped).toBe(1);
+    expect(fitHistoryToBudget(commits, 0).commits).toEqual([]);
+  });
+});
+
+describe('formatCommitHistory', () => {
+  it('should list each commit with its short SHA, stats and message body', () => {
+    const section = formatCommitHistory([
+      commit({ message: 'Use sessions for auth\n\nTokens could not be revoked.', stats: { filesChanged: 1, insertions: 5, deletions: 2 } })
+    ]);
+
+    expect(section).toContain('### a1b2c3d4 Use sessions for auth');
+    expect(section).toContain('Dana, 2026-10-01, 1 file changed (+5 -2)');
+    expect(section).toContain('Tokens could not be revoked.');
+  });
+});
@@ -0,0 +1,113 @@
+/**
+ * Config Environment Variable Tests
+ * Tests ${VAR} references in .cv/config.json values
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import {
+  ConfigManager,
+  expandEnvReferences,
+  interpolateConfigEnv,
+  isEnvReference,
+  listConfigKeys
+} from '@cv-git/core';
+
+describe('expandEnvReferences', () => {
+  it('should expand set variables and report unset ones', () => {
+    const env = { HOST: 'db.internal', PORT: '5432' };
+
+    expect(expandEnvReferences('postgres://${HOST}:${PORT}/cv', env)).toEqual({ value: 'postgres://db.internal:5432/cv', missing: [] });
+    expect(expandEnvReferences('${HOST}/${NOPE}', env)).toEqual({ value: 'db.internal/', missing: ['NOPE'] });
+    expect(expandEnvReferences('no references, $HOST or ${not valid}', env).value).toBe('no references, $HOST or ${not valid}');
+  });
+
+  it('should keep $${VAR} as a literal ${VAR}', () => {
+    expect(expandEnvReferences('$${HOST} is ${HOST}', { HOST: 'h' })).toEqual({ value: '${HOST} is h', missing: [] });
+  });
+
+  it('should recognise values that are only references', () => {
+    expect(isEnvReference('${OPENROUTER_API_KEY}')).toBe(true);
+    expect(isEnvReference('${A}${B}')).toBe(true);
+    expect(isEnvReference('sk-${SUFFIX}')).toBe(false);
+  });
+});
+
+describe('interpolateConfigEnv', () => {
+  const config = {
+    ai: { provider: 'anthropic', apiKey: '${ANTHROPIC_KEY}' },
+    embedding: { provider: 'openrouter', apiKey: '${OPENROUTER_KEY}' },
+    redaction: { patterns: ['${TOKEN_PATTERN}'] }
+  };
+
+  it('should expand references without touching the original', () => {
+    const env = { ANTHROPIC_KEY: 'sk-ant', OPENROUTER_KEY: 'sk-or', TOKEN_PATTERN: 'tok_[a-z]+' };
+    const expanded = interpolateConfigEnv(config, env);
+
+    expect(expanded).toEqual({
+      ai: { provider: 'anthropic', apiKey: 'sk-ant' },
+      embedding: { provider: 'openrouter', apiKey: 'sk-or' },
+      redaction: { patterns: ['tok_[a-z]+'] }
+    });
+    expect(config.ai.apiKey).toBe('${ANTHROPIC_KEY}');
+  });
+
+  it('should fail only when a value with an unset variable is read', () => {
+    const expanded = interpolateConfigEnv(config, { ANTHROPIC_KEY: 'sk-ant' });
+
+    expect(expanded.ai.apiKey).toBe('sk-ant');
+    expect(expanded.embedding.provider).toBe('openrouter');
+    expect(() => expanded.embedding.apiKey).toThrow(/embedding\.apiKey references \$\{OPENROUTER_KEY\}, which is not set/);
+    expect(() => expanded.redaction.patterns).toThrow(/redaction\.patterns references \$\{TOKEN_PATTERN\}/);
+  });
+});
+
+describe('ConfigManager with ${VAR} references', () => {
+  let dir: string;
+  const env = { ...process.env };
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-config-env-'));
+    await fs.mkdir(path.join(dir, '.cv'));
+    await fs.writeFile(path.join(dir, '.cv', 'config.json'), JSON.stringify({
+      version: '0.1.0',
+      repository: { root: dir, name: 'repo', initDate: '2026-01-01T00:00:00.000Z', repoId: 'repo-1' },
+      graph: { url: 'redis://localhost:6379', database: 'cv_repo_1' },
+      embedding: { provider: 'openrouter', model: 'openai/text-embedding-3-small', dimensions: 1536, apiKey: '${CV_TEST_OPENROUTER_KEY}' },
+      vector: { qdrant: { apiKey: '${CV_TEST_QDRANT_KEY}' } }
+    }));
+  });
+
+  afterEach(async () => {
+    process.env = { ...env };
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  it('should expand references on load and list them as written', async () => {
+    process.env.CV_TEST_OPENROUTER_KEY = 'sk-or-v1-secret';
+    delete process.env.CV_TEST_QDRANT_KEY;
+    const manager = new ConfigManager();
+
+    const config = await manager.load(dir);
+
+    expect(config.embedding.apiKey).toBe('sk-or-v1-secret');
+    expect(() => config.vector.qdrant!.apiKey).toThrow(/vector\.qdrant\.apiKey references \$\{CV_TEST_QDRANT_KEY\}/);
+    expect(listConfigKeys(manager.getLiteral())).toContainEqual(['embedding.apiKey', '${CV_TEST_OPENROUTER_KEY}']);
+  });
+
+  it('should keep the references in the file when a value is set', async () => {
+    process.env.CV_TEST_OPENROUTER_KEY = 'sk-or-v1-secret';
+    const manager = new ConfigManager();
+    await manager.load(dir);
+
+    await manager.setValue('search.topK', 8);
+    await manager.update({ vector: { url: 'http://localhost:6333' } });
+
+    const saved = JSON.parse(await fs.readFile(path.join(dir, '.cv', 'config.json'), 'utf-8'));
+    expect(saved.embedding.apiKey).toBe('${CV_TEST_OPENROUTER_KEY}');
+    expect(saved.vector.qdrant.apiKey).toBe('${CV_TEST_QDRANT_KEY}');
+    expect(saved.vector.url).toBe('http://localhost:6333');
+  });
+});
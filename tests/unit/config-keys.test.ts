@@ -0,0 +1,107 @@
+/**
+ * Config Key Tests
+ * Tests parsing and validation behind cv config get/set/unset/list
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import {
+  ConfigManager,
+  parseConfigValue,
+  listConfigKeys,
+  deleteConfigKey,
+  maskConfigSecret
+} from '@cv-git/core';
+import { CVConfig } from '@cv-git/shared';
+
+describe('parseConfigValue', () => {
+  it('should parse values to the key\'s type', () => {
+    expect(parseConfigValue('search.minScore', '0.4')).toBe(0.4);
+    expect(parseConfigValue('usage.footer', 'true')).toBe(true);
+    expect(parseConfigValue('redaction.patterns', 'sk_live_\\w+, ghp_\\w+')).toEqual(['sk_live_\\w+', 'ghp_\\w+']);
+    expect(parseConfigValue('profiles.work.ai.provider', 'azure')).toBe('azure');
+    expect(parseConfigValue('ai.providers', 'anthropic, gemini')).toEqual(['anthropic', 'gemini']);
+  });
+
+  it('should reject values outside the allowed range or set', () => {
+    expect(() => parseConfigValue('search.minScore', '-0.1')).toThrow(/at least 0/);
+    expect(() => parseConfigValue('search.topK', '2.5')).toThrow(/whole number/);
+    expect(() => parseConfigValue('ai.provider', 'acme')).toThrow(/expected one of: anthropic/);
+    expect(() => parseConfigValue('embedding.providers', 'openrouter, acme')).toThrow(/Invalid embedding\.providers: acme/);
+    expect(() => parseConfigValue('usage.footer', 'yes')).toThrow(/true or false/);
+  });
+
+  it('should reject unknown keys and list the section\'s keys', () => {
+    expect(() => parseConfigValue('search.minscore', '0.4')).toThrow(/Unknown config key: search\.minscore \(search keys: minScore/);
+    expect(() => parseConfigValue('profiles.work.repository.name', 'x')).toThrow(/cannot be set in a profile/);
+  });
+});
+
+describe('listConfigKeys', () => {
+  it('should flatten the config and mask secrets', () => {
+    const config = {
+      ai: { provider: 'anthropic', apiKey: 'sk-ant-api03-abcdef' },
+      vector: { pgvector: { connectionString: 'postgres://cv:hunter2@db:5432/cv' } }
+    } as unknown as CVConfig;
+
+    expect(listConfigKeys(config)).toEqual([
+      ['ai.provider', 'anthropic'],
+      ['ai.apiKey', 'sk-a****'],
+      ['vector.pgvector.connectionString', 'postgres://cv:****@db:5432/cv']
+    ]);
+    expect(maskConfigSecret('short')).toBe('****');
+  });
+});
+
+describe('deleteConfigKey', () => {
+  it('should remove sections left empty', () => {
+    const config = { search: { minScore: 0.4 }, ai: { model: 'm', timeout: 30 } };
+    expect(deleteConfigKey(config, 'search.minScore')).toBe(true);
+    expect(deleteConfigKey(config, 'ai.timeout')).toBe(true);
+    expect(deleteConfigKey(config, 'ai.timeout')).toBe(false);
+    expect(config).toEqual({ ai: { model: 'm' } });
+  });
+});
+
+describe('ConfigManager.setValue', () => {
+  let dir: string;
+  const env = { ...process.env };
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-config-keys-'));
+    await fs.mkdir(path.join(dir, '.cv'));
+    await fs.writeFile(path.join(dir, '.cv', 'config.json'), JSON.stringify({
+      version: '0.1.0',
+      repository: { root: dir, name: 'repo', initDate: '2026-01-01T00:00:00.000Z', repoId: 'repo-1' },
+      graph: { url: 'redis://localhost:6379', database: 'cv_repo_1' },
+      ai: { provider: 'anthropic', model: 'claude-sonnet-4', maxTokens: 4096, temperature: 0.2 },
+      profiles: { work: {} }
+    }));
+  });
+
+  afterEach(async () => {
+    process.env = { ...env };
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  it('should write into the selected profile, starting from a copy of the base section', async () => {
+    process.env.CV_PROFILE = 'work';
+    const manager = new ConfigManager();
+    await manager.load(dir);
+
+    const config = await manager.setValue('ai.provider', 'azure');
+    expect(config.ai).toMatchObject({ provider: 'azure', model: 'claude-sonnet-4' });
+
+    const saved = JSON.parse(await fs.readFile(path.join(dir, '.cv', 'config.json'), 'utf-8'));
+    expect(saved.ai.provider).toBe('anthropic');
+    expect(saved.profiles.work.ai).toMatchObject({ provider: 'azure', model: 'claude-sonnet-4' });
+  });
+
+  it('should refuse invalid values', async () => {
+    const manager = new ConfigManager();
+    await manager.load(dir);
+    await expect(manager.setValue('search.minScore', -1)).rejects.toThrow(/at least 0/);
+  });
+});
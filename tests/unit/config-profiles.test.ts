@@ -0,0 +1,74 @@
+/**
+ * Config Profile Tests
+ * Tests that --profile switches whole config sections and that --config
+ * loads an alternate file
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { ConfigManager, applyConfigProfile } from '@cv-git/core';
+import { CVConfig } from '@cv-git/shared';
+
+const base = {
+  version: '0.1.0',
+  repository: { root: '/repo', name: 'repo', initDate: '2026-01-01T00:00:00.000Z', repoId: 'repo-1234' },
+  ai: { provider: 'openrouter', model: 'anthropic/claude-sonnet-4', apiKey: 'sk-base' },
+  embedding: { provider: 'openrouter', model: 'openai/text-embedding-3-small' },
+  graph: { url: 'redis://localhost:6379', database: 'cv_repo_1234' },
+  profiles: {
+    work: {
+      ai: { provider: 'azure', model: 'gpt-4o', endpoint: 'https://work.openai.azure.com' }
+    }
+  }
+};
+
+describe('applyConfigProfile', () => {
+  it('should replace each section the profile sets and keep the rest', () => {
+    const config = applyConfigProfile(base as unknown as CVConfig, 'work');
+    expect(config.ai).toEqual({ provider: 'azure', model: 'gpt-4o', endpoint: 'https://work.openai.azure.com' });
+    expect(config.embedding).toEqual(base.embedding);
+  });
+
+  it('should reject an unknown profile and list the defined ones', () => {
+    expect(() => applyConfigProfile(base as unknown as CVConfig, 'personal')).toThrow(/Unknown profile: personal \(defined: work\)/);
+  });
+});
+
+describe('ConfigManager profiles', () => {
+  let dir: string;
+  const env = { ...process.env };
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-profiles-'));
+    await fs.mkdir(path.join(dir, '.cv'));
+    await fs.writeFile(path.join(dir, '.cv', 'config.json'), JSON.stringify(base));
+  });
+
+  afterEach(async () => {
+    process.env = { ...env };
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  it('should apply CV_PROFILE and update the profile, not the base section', async () => {
+    process.env.CV_PROFILE = 'work';
+    const manager = new ConfigManager();
+
+    expect((await manager.load(dir)).ai.provider).toBe('azure');
+    expect(manager.getProfile()).toBe('work');
+
+    await manager.update({ ai: { model: 'gpt-4.1' } } as Partial<CVConfig>);
+    const saved = JSON.parse(await fs.readFile(path.join(dir, '.cv', 'config.json'), 'utf-8'));
+    expect(saved.profiles.work.ai.model).toBe('gpt-4.1');
+    expect(saved.ai.model).toBe('anthropic/claude-sonnet-4');
+  });
+
+  it('should load the file named by CV_CONFIG instead of .cv/config.json', async () => {
+    const alternate = path.join(dir, 'ci.json');
+    await fs.writeFile(alternate, JSON.stringify({ ...base, ai: { ...base.ai, model: 'ci-model' } }));
+    process.env.CV_CONFIG = alternate;
+
+    expect((await new ConfigManager().load(dir)).ai.model).toBe('ci-model');
+  });
+});
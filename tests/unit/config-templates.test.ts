@@ -0,0 +1,72 @@
+/**
+ * Config Template Tests
+ * Tests the starter configs written by cv init --template
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import {
+  ConfigManager,
+  CONFIG_TEMPLATES,
+  isConfigTemplateName,
+  findConfigPlaceholders,
+  validateConfigValue
+} from '@cv-git/core';
+
+describe('ConfigManager.init with a template', () => {
+  let dir: string;
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-template-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  it('should write the template over the defaults', async () => {
+    const config = await new ConfigManager().init(dir, 'demo', 'openai');
+
+    expect(config.embedding).toMatchObject({ provider: 'openai', model: 'text-embedding-3-small', dimensions: 1536 });
+    expect(config.ai.provider).toBe('anthropic');
+    expect(config.repository.name).toBe('demo');
+    expect(config.graph.database).not.toBe('cv-git');
+
+    const saved = JSON.parse(await fs.readFile(path.join(dir, '.cv', 'config.json'), 'utf-8'));
+    expect(saved.embedding.provider).toBe('openai');
+    expect(saved.sync.excludePatterns).toContain('node_modules/**');
+  });
+
+  it('should leave placeholders for the Azure values it cannot guess', async () => {
+    const config = await new ConfigManager().init(dir, 'demo', 'azure');
+
+    expect(config.ai.provider).toBe('azure');
+    expect(config.embedding.provider).toBe('azure');
+    expect(findConfigPlaceholders(config)).toEqual(['azure.endpoint', 'azure.chatDeployment', 'azure.embeddingDeployment']);
+  });
+
+  it('should write the plain defaults without a template', async () => {
+    const config = await new ConfigManager().init(dir, 'demo');
+
+    expect(config.embedding.provider).toBe('ollama');
+    expect(findConfigPlaceholders(config)).toEqual([]);
+  });
+});
+
+describe('CONFIG_TEMPLATES', () => {
+  it('should only use values cv config would accept, and never API keys', () => {
+    for (const [name, template] of Object.entries(CONFIG_TEMPLATES)) {
+      expect(isConfigTemplateName(name)).toBe(true);
+      for (const [section, values] of Object.entries(template.config)) {
+        for (const [key, value] of Object.entries(values as Record<string, unknown>)) {
+          expect(key).not.toBe('apiKey');
+          validateConfigValue(`${section}.${key}`, value);
+        }
+      }
+    }
+    expect(isConfigTemplateName('anthropic')).toBe(false);
+    expect(isConfigTemplateName('toString')).toBe(false);
+  });
+});
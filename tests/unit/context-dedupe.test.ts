@@ -0,0 +1,112 @@
+/**
+ * Context Deduplication Tests
+ * Tests for collapsing duplicate chunks before they reach the context budget
+ */
+
+import { describe, it, expect } from 'vitest';
+import { deduplicateChunks, cosineSimilarity } from '@cv-git/core';
+import { CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+
+function chunk(id: string, score: number, text: string, vector?: number[]): VectorSearchResult<CodeChunkPayload> {
+  return {
+    id,
+    score,
+    vector,
+    payload: {
+      id,
+      file: `${id}.ts`,
+      language: 'typescript',
+      startLine: 1,
+      endLine: text.split('\n').length,
+      text,
+      imports: [],
+      lastModified: 0
+    }
+  };
+}
+
+describe('cosineSimilarity', () => {
+  it('is 1 for parallel vectors and 0 for mismatched dimensions', () => {
+    expect(cosineSimilarity([1, 2], [2, 4])).toBeCloseTo(1);
+    expect(cosineSimilarity([1, 0], [1, 0, 0])).toBe(0);
+  });
+});
+
+describe('deduplicateChunks', () => {
+  it('drops exact text duplicates, keeping the higher score', () => {
+    const result = deduplicateChunks([
+      chunk('vendor/retry', 0.7, 'function retry() {}\n'),
+      chunk('src/retry', 0.9, 'function retry() {}'),
+      chunk('src/other', 0.5, 'function other() {}')
+    ]);
+
+    expect(result.duplicates).toBe(1);
+    expect(result.chunks.map(c => c.id)).toEqual(['src/retry', 'src/other']);
+  });
+
+  it('drops near-duplicates above the threshold and strips vectors', () => {
+    const chunks = [
+      chunk('a', 0.9, 'function a() { return 1; }', [1, 0, 0]),
+      chunk('a-copy', 0.8, 'function a() { return 2; }', [0.99, 0.05, 0]),
+      chunk('b', 0.6, 'function b() {}', [0, 1, 0])
+    ];
+
+    const result = deduplicateChunks(chunks, 0.95);
+    expect(result.chunks.map(c => c.id)).toEqual(['a', 'b']);
+    expect(result.chunks.every(c => c.vector === undefined)).toBe(true);
+
+    expect(deduplicateChunks(chunks, 0.9999).duplicates).toBe(0);
+  });
+
+  it('keeps chunks without vectors unless their text matches', () => {
+    const result = deduplicateChunks([
+      chunk('file', 1, 'whole file'),
+      chunk('a', 0.9, 'function a() {}', [1, 0])
+    ]);
+    expect(result.duplicates).toBe(0);
+  });
+
+  describe('pinned --file chunks', () => {
+    const lines = (from: number, to: number) =>
+      Array.from({ length: to - from + 1 }, (_, i) => `const line${from + i} = ${from + i};`).join('\n');
+
+    const pin = (c: VectorSearchResult<CodeChunkPayload>) => ({ ...c, payload: { ...c.payload, pinned: true } });
+
+    const inFile = (id: string, score: number, startLine: number, endLine: number): VectorSearchResult<CodeChunkPayload> => {
+      const base = chunk(id, score, lines(startLine, endLine));
+      return { ...base, payload: { ...base.payload, file: 'src/auth.ts', startLine, endLine } };
+    };
+
+    it('keeps a pinned chunk whole when a higher-scoring hit overlaps it', () => {
+      const result = deduplicateChunks([
+        inFile('hit', 0.9, 1, 10),
+        pin(inFile('pinned', 0.5, 5, 20))
+      ]);
+
+      const pinned = result.chunks.find(c => c.id === 'pinned')!;
+      expect(pinned.payload).toMatchObject({ startLine: 5, endLine: 20, text: lines(5, 20) });
+      expect(result.chunks.find(c => c.id === 'hit')!.payload).toMatchObject({ startLine: 1, endLine: 4 });
+    });
+
+    it('drops the hit, not the pinned chunk, when their text or embedding matches', () => {
+      const result = deduplicateChunks([
+        chunk('src/retry', 0.9, 'function retry() {}'),
+        pin(chunk('vendor/retry', 0.2, 'function retry() {}')),
+        chunk('a', 0.8, 'function a() { return 1; }', [1, 0]),
+        pin(chunk('a-pinned', 0.1, 'function a() { return 2; }', [1, 0]))
+      ]);
+
+      expect(result.chunks.map(c => c.id)).toEqual(['vendor/retry', 'a-pinned']);
+      expect(result.duplicates).toBe(2);
+    });
+
+    it('keeps pinned chunks that repeat each other', () => {
+      const result = deduplicateChunks([
+        pin(inFile('whole', 1, 1, 20)),
+        pin(inFile('part', 0.9, 5, 10))
+      ]);
+
+      expect(result.duplicates).toBe(0);
+    });
+  });
+});
@@ -0,0 +1,87 @@
+/**
+ * Context Expansion Tests
+ * Tests for following references from retrieved chunks to their definitions (cv explain --depth)
+ */
+
+import { describe, it, expect } from 'vitest';
+import { expandWithDefinitions, numberSources, MAX_EXPAND_DEPTH } from '@cv-git/core';
+import { CodeChunkPayload, SymbolKind, VectorSearchResult } from '@cv-git/shared';
+
+function payload(file: string, startLine: number, symbolName: string, text: string, symbolKind: SymbolKind = 'function', language = 'typescript'): CodeChunkPayload {
+  return {
+    id: `${file}:${startLine}`,
+    file,
+    language,
+    symbolName,
+    symbolKind,
+    startLine,
+    endLine: startLine + text.split('\n').length - 1,
+    text,
+    imports: [],
+    lastModified: 0
+  };
+}
+
+const verifyPassword = payload('src/auth/verify.ts', 1, 'verifyPassword',
+  'export function verifyPassword(user, password) {\n  // compare with the stored hash\n  return hashPassword(password, user.salt) === user.hash;\n}');
+const hashPassword = payload('src/auth/hash.ts', 1, 'hashPassword',
+  'export function hashPassword(password, salt) {\n  return pbkdf2(password, salt);\n}');
+const pbkdf2 = payload('src/crypto/pbkdf2.ts', 1, 'pbkdf2', 'export function pbkdf2(input, salt) {\n  return derive(input, salt);\n}');
+const hashPasswordPy = payload('tools/hash.py', 1, 'hashPassword', 'def hashPassword(p):\n    pass', 'function', 'python');
+
+const indexed = [verifyPassword, hashPassword, pbkdf2, hashPasswordPy];
+
+const retrieved: VectorSearchResult<CodeChunkPayload>[] = [{ id: verifyPassword.id, score: 0.82, payload: verifyPassword }];
+
+describe('expandWithDefinitions', () => {
+  it('should add nothing at depth 0', () => {
+    expect(expandWithDefinitions(retrieved, indexed, { depth: 0, budgetTokens: 10000 }).chunks).toEqual([]);
+  });
+
+  it('should pull in the definitions a retrieved chunk calls, in the same language', () => {
+    const expanded = expandWithDefinitions(retrieved, indexed, { depth: 1, budgetTokens: 10000 });
+
+    expect(expanded.chunks.map(chunk => chunk.payload.file)).toEqual(['src/auth/hash.ts']);
+    expect(expanded.chunks[0].expansion).toEqual({ name: 'hashPassword', from: 'verifyPassword', depth: 1 });
+    expect(expanded.chunks[0].score).toBe(0.82);
+    expect(expanded.tokens > 0).toBe(true);
+  });
+
+  it('should follow references from expanded chunks at greater depths, up to the cap', () => {
+    const expanded = expandWithDefinitions(retrieved, indexed, { depth: 2, budgetTokens: 10000 });
+
+    expect(expanded.chunks.map(chunk => [chunk.payload.symbolName, chunk.expansion!.from, chunk.expansion!.depth])).toEqual([
+      ['hashPassword', 'verifyPassword', 1],
+      ['pbkdf2', 'hashPassword', 2]
+    ]);
+    expect(expandWithDefinitions(retrieved, indexed, { depth: 99, budgetTokens: 10000 }).chunks).toHaveLength(2);
+    expect(MAX_EXPAND_DEPTH).toBe(3);
+  });
+
+  it('should leave out definitions that do not fit the budget', () => {
+    const expanded = expandWithDefinitions(retrieved, indexed, { depth: 2, budgetTokens: 5 });
+
+    expect(expanded.chunks).toEqual([]);
+    expect(expanded.dropped).toBe(1);
+  });
+
+  it('should not add code already in the context', () => {
+    const both = [...retrieved, { id: hashPassword.id, score: 0.7, payload: hashPassword }];
+
+    const expanded = expandWithDefinitions(both, indexed, { depth: 1, budgetTokens: 10000 });
+
+    expect(expanded.chunks.map(chunk => chunk.payload.symbolName)).toEqual(['pbkdf2']);
+  });
+});
+
+describe('numberSources with expanded chunks', () => {
+  it('should number expanded definitions after the retrieved sections and keep how they were reached', () => {
+    const expanded = expandWithDefinitions(retrieved, indexed, { depth: 1, budgetTokens: 10000 });
+
+    const sources = numberSources([...retrieved, ...expanded.chunks]);
+
+    expect(sources.map(source => [source.index, source.symbol])).toEqual([[1, 'verifyPassword'], [2, 'hashPassword']]);
+    expect(sources[0].expansion).toBeUndefined();
+    expect(sources[1].expansion).toEqual({ name: 'hashPassword', from: 'verifyPassword', depth: 1 });
+  });
+});
@@ -266,6 +266,54 @@ describe('DeltaSyncManager', () => {
     });
   });
 
+  describe('mtime and hash', () => {
+    const stat = (mtimeMs: number, size: number) => ({ mtimeMs, size });
+
+    it('should need no read when size and mtime have not moved', async () => {
+      await manager.markSynced(new Map([['gen/api.ts', 'export type Api = {}']]), 'code', new Map([['gen/api.ts', stat(1000, 20)]]));
+
+      expect(await manager.isUnchangedOnDisk('gen/api.ts', stat(1000, 20))).toBe(true);
+      expect(await manager.isUnchangedOnDisk('gen/api.ts', stat(2000, 20))).toBe(false);
+      expect(await manager.isUnchangedOnDisk('gen/other.ts', stat(1000, 20))).toBe(false);
+
+      // Left unread: a stat but no content
+      const delta = await manager.computeDelta(new Map(), 'code', new Map([['gen/api.ts', stat(1000, 20)]]));
+      expect(delta.unchanged).toEqual(['gen/api.ts']);
+      expect(delta.deleted).toEqual([]);
+      expect(delta.decisions).toEqual([{ file: 'gen/api.ts', action: 'skip', reason: 'mtime and size unchanged' }]);
+    });
+
+    it('should prefer the content hash when only the mtime changed', async () => {
+      const files = new Map([['gen/api.ts', 'export type Api = {}'], ['gen/client.ts', 'v1']]);
+      await manager.markSynced(files, 'code', new Map([['gen/api.ts', stat(1000, 20)], ['gen/client.ts', stat(1000, 2)]]));
+
+      const delta = await manager.computeDelta(
+        new Map([['gen/api.ts', 'export type Api = {}'], ['gen/client.ts', 'v2'], ['gen/new.ts', 'x']]),
+        'code',
+        new Map([['gen/api.ts', stat(5000, 20)], ['gen/client.ts', stat(5000, 2)], ['gen/new.ts', stat(5000, 1)]])
+      );
+
+      expect(delta.unchanged).toEqual(['gen/api.ts']);
+      expect(delta.modified).toEqual(['gen/client.ts']);
+      expect(delta.added).toEqual(['gen/new.ts']);
+      expect(delta.decisions!.map(d => [d.file, d.reason])).toEqual([
+        ['gen/api.ts', 'mtime changed, content unchanged'],
+        ['gen/client.ts', 'content changed'],
+        ['gen/new.ts', 'new file']
+      ]);
+
+      // The new mtime is kept, so the next sync skips the read
+      expect((await manager.getTrackedFile('gen/api.ts'))?.mtimeMs).toBe(5000);
+      expect(await manager.isUnchangedOnDisk('gen/api.ts', stat(5000, 20))).toBe(true);
+    });
+
+    it('should read files synced before mtimes were kept', async () => {
+      await manager.markSynced(new Map([['src/index.ts', 'v1']]), 'code');
+
+      expect(await manager.isUnchangedOnDisk('src/index.ts', stat(1000, 2))).toBe(false);
+    });
+  });
+
   describe('content hash consistency', () => {
     it('should produce same hash for same content', async () => {
       const content = 'same content';
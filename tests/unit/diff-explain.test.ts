@@ -0,0 +1,107 @@
+/**
+ * Diff Explanation Tests
+ * Tests for diff splitting and complexity correlation used by `cv diff --explain`
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  parseChangedRanges,
+  splitDiff,
+  findComplexChanges,
+  parseDiffExplanation
+} from '@cv-git/core';
+
+const DIFF = [
+  'diff --git a/internal/stats.go b/internal/stats.go',
+  'index 1111111..2222222 100644',
+  '--- a/internal/stats.go',
+  '+++ b/internal/stats.go',
+  '@@ -40,6 +40,8 @@ func GetUserStats(ctx context.Context, id string) (*Stats, error) {',
+  ' \trows, err := db.Query(ctx, q, id)',
+  '-\tif err != nil {',
+  '+\tif err != nil && !errors.Is(err, sql.ErrNoRows) {',
+  '+\t\tlog.Printf("stats: %v", err)',
+  ' \t\treturn nil, err',
+  ' \t}',
+  'diff --git a/old.go b/old.go',
+  'deleted file mode 100644',
+  '--- a/old.go',
+  '+++ /dev/null',
+  '@@ -1,3 +0,0 @@',
+  '-package main',
+  '-',
+  '-func old() {}',
+  ''
+].join('\n');
+
+function chunk(file: string, symbolName: string, startLine: number, endLine: number, complexity: number) {
+  return { id: `${file}:${startLine}`, file, language: 'go', symbolName, startLine, endLine, text: '', imports: [], complexity, lastModified: 0 };
+}
+
+describe('parseChangedRanges', () => {
+  it('should read new-side ranges and the old side of deleted files', () => {
+    expect(parseChangedRanges(DIFF)).toEqual([
+      { file: 'internal/stats.go', startLine: 40, endLine: 47, context: 'func GetUserStats(ctx context.Context, id string) (*Stats, error) {' },
+      { file: 'old.go', startLine: 1, endLine: 3, context: '' }
+    ]);
+  });
+});
+
+describe('splitDiff', () => {
+  it('should keep small diffs in one part', () => {
+    expect(splitDiff(DIFF)).toHaveLength(1);
+  });
+
+  it('should split between files and repeat headers when a file is split', () => {
+    const parts = splitDiff(DIFF, 300);
+
+    expect(parts.length).toBeGreaterThan(1);
+    for (const part of parts) {
+      expect(part.startsWith('diff --git ')).toBe(true);
+    }
+  });
+});
+
+describe('findComplexChanges', () => {
+  it('should flag complex functions whose chunks overlap changed lines', () => {
+    const changes = findComplexChanges(parseChangedRanges(DIFF), [
+      chunk('internal/stats.go', 'GetUserStats', 30, 80, 14),
+      chunk('internal/stats.go', 'formatStats', 90, 100, 20),
+      chunk('old.go', 'old', 3, 3, 1)
+    ]);
+
+    expect(changes).toEqual([
+      { file: 'internal/stats.go', symbolName: 'GetUserStats', complexity: 14, startLine: 30, endLine: 80 }
+    ]);
+  });
+
+  it('should match on the hunk function context when lines have moved', () => {
+    const changes = findComplexChanges(parseChangedRanges(DIFF), [
+      chunk('internal/stats.go', 'GetUserStats', 120, 170, 14)
+    ]);
+
+    expect(changes.map(c => c.symbolName)).toEqual(['GetUserStats']);
+  });
+
+  it('should respect the threshold', () => {
+    const chunks = [chunk('internal/stats.go', 'GetUserStats', 30, 80, 14)];
+    expect(findComplexChanges(parseChangedRanges(DIFF), chunks, 15)).toEqual([]);
+  });
+});
+
+describe('parseDiffExplanation', () => {
+  it('should parse summary and risks from JSON', () => {
+    const result = parseDiffExplanation(
+      '```json\n{"summary": "Ignore missing rows.", "risks": [{"description": "Callers may get nil stats", "file": "internal/stats.go", "severity": "HIGH"}, {"severity": "low"}]}\n```',
+      [],
+      1
+    );
+
+    expect(result.summary).toBe('Ignore missing rows.');
+    expect(result.risks).toEqual([{ description: 'Callers may get nil stats', file: 'internal/stats.go', severity: 'high' }]);
+  });
+
+  it('should fall back to the raw response', () => {
+    expect(parseDiffExplanation('Just a summary.', [], 1).summary).toBe('Just a summary.');
+  });
+});
@@ -0,0 +1,102 @@
+/**
+ * Diff Review Unit Tests
+ * Tests for numbering changed hunks and mapping findings onto changed lines
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseDiffHunks, formatNumberedDiff, mapFindingsToDiff, summarizeFindings, filterDiffFiles } from '@cv-git/core';
+import type { ReviewFinding } from '@cv-git/shared';
+
+const diff = `diff --git a/src/cart.ts b/src/cart.ts
+index 1111111..2222222 100644
+--- a/src/cart.ts
++++ b/src/cart.ts
+@@ -10,4 +10,5 @@ export function total(items: Item[]) {
+   let sum = 0;
+-  for (const item of items) sum += item.price;
++  for (const item of items) {
++    sum += item.price * item.quantity;
++  }
+   return sum;
+@@ -40,3 +41,2 @@ export function clear() {
+   items.length = 0;
+-  notify();
+ }
+diff --git a/src/new.ts b/src/new.ts
+new file mode 100644
+--- /dev/null
++++ b/src/new.ts
+@@ -0,0 +1,2 @@
++export const a = 1;
++export const b = 2;
+`;
+
+const finding = (overrides: Partial<ReviewFinding>): ReviewFinding => ({
+  file: 'src/cart.ts',
+  startLine: 1,
+  endLine: 1,
+  severity: 'medium',
+  category: 'correctness',
+  message: 'Issue',
+  ...overrides
+});
+
+const review = (findings: ReviewFinding[]) => ({ findings, summary: summarizeFindings(findings, 'Overview') });
+
+describe('parseDiffHunks', () => {
+  it('should number lines as in the new file', () => {
+    const files = parseDiffHunks(diff);
+
+    expect(files.map(f => [f.path, f.type])).toEqual([['src/cart.ts', 'modify'], ['src/new.ts', 'create']]);
+    const added = files[0].hunks[0].lines.filter(l => l.type === 'add').map(l => l.newLineNumber);
+    expect(added).toEqual([11, 12, 13]);
+    expect(files[0].hunks[1].lines.find(l => l.type === 'remove')?.newLineNumber).toBeUndefined();
+  });
+});
+
+describe('filterDiffFiles', () => {
+  it('should keep only the sections of matching files', () => {
+    const filtered = filterDiffFiles(diff, file => file === 'src/new.ts');
+
+    expect(filtered.startsWith('diff --git a/src/new.ts b/src/new.ts')).toBe(true);
+    expect(parseDiffHunks(filtered).map(f => f.path)).toEqual(['src/new.ts']);
+    expect(filterDiffFiles(diff, () => false)).toBe('');
+  });
+});
+
+describe('formatNumberedDiff', () => {
+  it('should prefix each line with its new line number and marker', () => {
+    const text = formatNumberedDiff(parseDiffHunks(diff));
+
+    expect(text).toContain('### src/cart.ts');
+    expect(text).toContain(' 12 +     sum += item.price * item.quantity;');
+    expect(text).toContain('    -   notify();');
+    expect(text).toContain('  1 + export const a = 1;');
+  });
+});
+
+describe('mapFindingsToDiff', () => {
+  const files = parseDiffHunks(diff);
+
+  it('should narrow a finding to the added lines it covers', () => {
+    const result = mapFindingsToDiff(review([finding({ startLine: 10, endLine: 12 })]), files);
+    expect(result.findings[0]).toMatchObject({ startLine: 11, endLine: 12 });
+  });
+
+  it('should snap a finding outside the changes to the nearest added line', () => {
+    const result = mapFindingsToDiff(review([finding({ startLine: 30, endLine: 30 })]), files);
+    expect(result.findings[0]).toMatchObject({ startLine: 13, endLine: 13 });
+  });
+
+  it('should match paths with a/ b/ or ./ prefixes', () => {
+    const result = mapFindingsToDiff(review([finding({ file: 'b/src/new.ts', startLine: 2 })]), files);
+    expect(result.findings[0]).toMatchObject({ file: 'src/new.ts', startLine: 2, endLine: 2 });
+  });
+
+  it('should drop findings on files outside the diff and recount', () => {
+    const result = mapFindingsToDiff(review([finding({ file: 'src/other.ts' }), finding({ startLine: 11 })]), files);
+    expect(result.findings).toHaveLength(1);
+    expect(result.summary.total).toBe(1);
+    expect(result.summary.overview).toBe('Overview');
+  });
+});
@@ -0,0 +1,91 @@
+/**
+ * Doc Comment Tests
+ * Tests for finding undocumented exports and inserting generated comments
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findDocTargets, insertDocComments, parseDocComments } from '@cv-git/core';
+
+const symbol = (name: string, kind: string, startLine: number, endLine: number): any => ({
+  name,
+  qualifiedName: `src/file:${name}`,
+  kind,
+  file: 'src/file',
+  startLine,
+  endLine,
+  visibility: 'public'
+});
+
+const GO_SOURCE = `package config
+
+// Load reads the config file.
+func Load() {}
+
+func Parse(s string) error {
+	return nil
+}
+
+func helper() {}
+`;
+
+const GO_SYMBOLS = [symbol('Load', 'function', 4, 4), symbol('Parse', 'function', 6, 8), symbol('helper', 'function', 10, 10)];
+
+describe('findDocTargets', () => {
+  it('lists exported Go functions without comments', () => {
+    expect(findDocTargets(GO_SYMBOLS, GO_SOURCE, 'go').map(t => t.symbol.name)).toEqual(['Parse']);
+  });
+
+  it('includes documented symbols when overwriting', () => {
+    const targets = findDocTargets(GO_SYMBOLS, GO_SOURCE, 'go', true);
+    expect(targets.map(t => [t.symbol.name, t.documented])).toEqual([['Load', true], ['Parse', false]]);
+  });
+
+  it('only counts TypeScript exports and public methods of exported classes', () => {
+    const source = 'export class Store {\n  get() {}\n}\nclass Internal {\n  run() {}\n}\nexport function open() {}\n';
+    const symbols = [
+      symbol('Store', 'class', 1, 3),
+      symbol('get', 'method', 2, 2),
+      symbol('Internal', 'class', 4, 6),
+      symbol('run', 'method', 5, 5),
+      symbol('open', 'function', 7, 7)
+    ];
+    expect(findDocTargets(symbols, source, 'typescript').map(t => t.symbol.name)).toEqual(['Store', 'get', 'open']);
+  });
+});
+
+describe('insertDocComments', () => {
+  it('starts Go comments with the symbol name', () => {
+    const targets = findDocTargets(GO_SYMBOLS, GO_SOURCE, 'go');
+    const comments = parseDocComments('{"src/file:Parse": "Reads s and reports malformed input."}', targets);
+    expect(insertDocComments(GO_SOURCE, 'go', comments)).toContain(
+      '// Parse reads s and reports malformed input.\nfunc Parse(s string) error {'
+    );
+  });
+
+  it('replaces existing comments when overwriting', () => {
+    const targets = findDocTargets(GO_SYMBOLS, GO_SOURCE, 'go', true);
+    const updated = insertDocComments(GO_SOURCE, 'go', [{ symbol: targets[0].symbol, text: 'Load reads and validates the config file.' }]);
+    expect(updated).toContain('// Load reads and validates the config file.\nfunc Load() {}');
+    expect(updated).not.toContain('// Load reads the config file.');
+  });
+
+  it('writes JSDoc above decorators with the member indentation', () => {
+    const source = 'export class Api {\n  @get\n  list() {}\n}\n';
+    const updated = insertDocComments(source, 'typescript', [{ symbol: symbol('list', 'method', 3, 3), text: 'Lists items.' }]);
+    expect(updated).toBe('export class Api {\n  /**\n   * Lists items.\n   */\n  @get\n  list() {}\n}\n');
+  });
+
+  it('puts Python docstrings inside the body', () => {
+    const source = 'def fetch(url,\n          retries):\n    return url\n';
+    const updated = insertDocComments(source, 'python', [{ symbol: symbol('fetch', 'function', 1, 3), text: 'Fetch a URL.' }]);
+    expect(updated).toBe('def fetch(url,\n          retries):\n    """Fetch a URL."""\n    return url\n');
+  });
+});
+
+describe('parseDocComments', () => {
+  it('skips symbols the model left out and rejects non-JSON', () => {
+    const targets = findDocTargets(GO_SYMBOLS, GO_SOURCE, 'go', true);
+    expect(parseDocComments('```json\n{"src/file:Parse": "Parse parses."}\n```', targets).map(c => c.symbol.name)).toEqual(['Parse']);
+    expect(() => parseDocComments('Sorry, no.', targets)).toThrow('JSON');
+  });
+});
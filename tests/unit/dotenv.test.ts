@@ -0,0 +1,75 @@
+/**
+ * .env File Tests
+ * Tests for parsing .env files and loading them without overriding the environment
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { parseDotenv, loadDotenv } from '@cv-git/core';
+
+describe('parseDotenv', () => {
+  it('should parse plain, exported and commented lines', () => {
+    expect(parseDotenv([
+      '# Provider keys',
+      'OPENAI_API_KEY=sk-test',
+      'export OPENROUTER_API_KEY = sk-or-test',
+      'EMPTY=',
+      'URL=http://localhost:11434 # local ollama',
+      'not a variable'
+    ].join('\n'))).toEqual({
+      OPENAI_API_KEY: 'sk-test',
+      OPENROUTER_API_KEY: 'sk-or-test',
+      EMPTY: '',
+      URL: 'http://localhost:11434'
+    });
+  });
+
+  it('should keep single-quoted values literally and unescape double-quoted ones', () => {
+    const vars = parseDotenv([
+      "LITERAL='a #b \\n ${HOME}'",
+      'ESCAPED="line1\\nline2 \\"quoted\\""',
+      'MULTILINE="-----BEGIN KEY-----',
+      'abc',
+      '-----END KEY-----"',
+      'AFTER=1'
+    ].join('\r\n'));
+
+    expect(vars.LITERAL).toBe('a #b \\n ${HOME}');
+    expect(vars.ESCAPED).toBe('line1\nline2 "quoted"');
+    expect(vars.MULTILINE).toBe('-----BEGIN KEY-----\nabc\n-----END KEY-----');
+    expect(vars.AFTER).toBe('1');
+  });
+});
+
+describe('loadDotenv', () => {
+  let dir: string;
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-dotenv-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  it('should not override variables that are already set', async () => {
+    const file = path.join(dir, '.env');
+    await fs.writeFile(file, 'OPENAI_API_KEY=from-file\nANTHROPIC_API_KEY=from-file\n');
+    const env: NodeJS.ProcessEnv = { OPENAI_API_KEY: 'from-shell' };
+
+    const result = await loadDotenv(file, { env });
+
+    expect(env).toEqual({ OPENAI_API_KEY: 'from-shell', ANTHROPIC_API_KEY: 'from-file' });
+    expect(result.loaded).toEqual(['ANTHROPIC_API_KEY']);
+    expect(result.skipped).toEqual(['OPENAI_API_KEY']);
+  });
+
+  it('should skip a missing file unless it is required', async () => {
+    const file = path.join(dir, 'missing.env');
+
+    expect((await loadDotenv(file, { env: {} })).loaded).toEqual([]);
+    await expect(loadDotenv(file, { required: true, env: {} })).rejects.toThrow('no such file');
+  });
+});
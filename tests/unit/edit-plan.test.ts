@@ -0,0 +1,126 @@
+/**
+ * Edit Plan Unit Tests
+ * Tests for turning cv do's edit blocks into a plan that is reviewed before it is applied
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  buildEditPlan,
+  findChangedSincePlan,
+  createEditParser,
+  readEditPlan,
+  writeEditPlan,
+  hashFileContent
+} from '@cv-git/core';
+
+const response = [
+  'Add a retry helper and use it.',
+  '',
+  '```src/retry.ts',
+  'export const retry = () => {};',
+  '```',
+  '',
+  '```src/client.ts',
+  '<<<<<<< SEARCH',
+  'const timeout = 1000;',
+  '=======',
+  'const timeout = 5000;',
+  '>>>>>>> REPLACE',
+  '```',
+  '',
+  '```src/legacy.ts',
+  '<<<<<<< DELETE',
+  '>>>>>>> DELETE',
+  '```',
+  '',
+  '```src/client.ts',
+  '<<<<<<< SEARCH',
+  'const retries = 0;',
+  '=======',
+  'const retries = 3;',
+  '>>>>>>> REPLACE',
+  '```',
+  '',
+  '```src/missing.ts',
+  '<<<<<<< SEARCH',
+  'nothing',
+  '=======',
+  'something',
+  '>>>>>>> REPLACE',
+  '```'
+].join('\n');
+
+describe('edit plans', () => {
+  let root: string;
+  const client = 'import { get } from "./http";\nconst timeout = 1000;\nconst retries = 0;\n';
+
+  beforeEach(async () => {
+    root = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-edit-plan-test-'));
+    await fs.mkdir(path.join(root, 'src'));
+    await fs.writeFile(path.join(root, 'src', 'client.ts'), client);
+    await fs.writeFile(path.join(root, 'src', 'legacy.ts'), 'old\n');
+  });
+
+  afterEach(async () => {
+    await fs.rm(root, { recursive: true, force: true });
+  });
+
+  const plan = async () => buildEditPlan(root, 'add retries', createEditParser().parseResponse(response, 'test'), {
+    head: 'abc1234',
+    now: new Date('2026-01-01T00:00:00Z')
+  });
+
+  it('should resolve edit blocks into file contents and diffs without writing', async () => {
+    const { plan: editPlan, rejected } = await plan();
+
+    expect(editPlan.edits.map(edit => [edit.type, edit.file])).toEqual([
+      ['create', 'src/retry.ts'],
+      ['modify', 'src/client.ts'],
+      ['delete', 'src/legacy.ts']
+    ]);
+
+    // Both blocks for the file are combined into one edit
+    const modify = editPlan.edits[1];
+    expect(modify.content).toBe('import { get } from "./http";\nconst timeout = 5000;\nconst retries = 3;\n');
+    expect(modify.baseHash).toBe(hashFileContent(client));
+    expect(modify.diff).toContain('-const timeout = 1000;\n-const retries = 0;\n+const timeout = 5000;\n+const retries = 3;');
+    expect(editPlan.edits[0].baseHash).toBe(null);
+    expect(editPlan.edits[0].diff.startsWith('--- /dev/null\n+++ b/src/retry.ts\n')).toBe(true);
+
+    expect(rejected).toEqual([{ file: 'src/missing.ts', error: 'file does not exist' }]);
+    expect(await fs.readFile(path.join(root, 'src', 'client.ts'), 'utf-8')).toBe(client);
+  });
+
+  it('should report files changed since the plan was made', async () => {
+    const { plan: editPlan } = await plan();
+    expect(await findChangedSincePlan(root, editPlan)).toEqual([]);
+
+    await fs.writeFile(path.join(root, 'src', 'client.ts'), client + '// edited by hand\n');
+    await fs.writeFile(path.join(root, 'src', 'retry.ts'), 'export {};\n');
+
+    expect(await findChangedSincePlan(root, editPlan)).toEqual(['src/retry.ts', 'src/client.ts']);
+  });
+
+  it('should reject edits outside the repository', async () => {
+    const edits = createEditParser().parseResponse('```../outside.ts\nx\n```', 'test');
+    const { plan: editPlan, rejected } = await buildEditPlan(root, 'escape', edits);
+
+    expect(editPlan.edits).toEqual([]);
+    expect(rejected[0].error).toMatch(/outside the repository/);
+  });
+
+  it('should round-trip a plan file and reject other files', async () => {
+    const { plan: editPlan } = await plan();
+    const file = path.join(root, '.cv', 'plans', 'do.json');
+
+    await writeEditPlan(file, editPlan);
+    expect(await readEditPlan(file)).toEqual(editPlan);
+
+    await fs.writeFile(file, JSON.stringify({ task: 'x', edits: [{ file: 'a.ts', type: 'modify' }] }));
+    await expect(readEditPlan(file)).rejects.toThrow(/not a cv do plan/);
+    await expect(readEditPlan(path.join(root, 'none.json'))).rejects.toThrow(/Plan not found/);
+  });
+});
@@ -0,0 +1,53 @@
+/**
+ * Embedding Batch Planning Tests
+ * Tests for request batching by count and token budget, and the worker pool
+ */
+
+import { describe, it, expect } from 'vitest';
+import { planEmbeddingBatches, estimateTokens } from '@cv-git/core';
+import { mapWithConcurrency } from '@cv-git/shared';
+
+describe('planEmbeddingBatches', () => {
+  it('should split by batch size', () => {
+    const texts = Array.from({ length: 7 }, (_, i) => `text ${i}`);
+    const batches = planEmbeddingBatches(texts, { batchSize: 3 });
+
+    expect(batches.map(b => b.length)).toEqual([3, 3, 1]);
+    expect(batches.flat()).toEqual(texts);
+  });
+
+  it('should split by estimated tokens', () => {
+    const text = 'x'.repeat(400); // ~100 tokens
+    expect(estimateTokens(text)).toBe(100);
+
+    const batches = planEmbeddingBatches([text, text, text, text], { batchSize: 100, maxBatchTokens: 250 });
+    expect(batches.map(b => b.length)).toEqual([2, 2]);
+  });
+
+  it('should give an oversized text its own batch', () => {
+    const batches = planEmbeddingBatches(['small', 'x'.repeat(4000), 'small'], { maxBatchTokens: 100 });
+    expect(batches.map(b => b.length)).toEqual([1, 1, 1]);
+  });
+
+  it('should return no batches for no texts', () => {
+    expect(planEmbeddingBatches([])).toEqual([]);
+  });
+});
+
+describe('mapWithConcurrency', () => {
+  it('should preserve order and bound in-flight calls', async () => {
+    let inFlight = 0;
+    let maxInFlight = 0;
+
+    const results = await mapWithConcurrency([5, 1, 3, 2, 4], 2, async (n) => {
+      inFlight++;
+      maxInFlight = Math.max(maxInFlight, inFlight);
+      await new Promise(r => setTimeout(r, n));
+      inFlight--;
+      return n * 10;
+    });
+
+    expect(results).toEqual([50, 10, 30, 20, 40]);
+    expect(maxInFlight).toBe(2);
+  });
+});
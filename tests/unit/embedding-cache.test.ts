@@ -7,7 +7,7 @@ import { describe, it, expect, beforeEach, afterEach } from 'vitest';
 import { promises as fs } from 'fs';
 import * as path from 'path';
 import * as os from 'os';
-import { EmbeddingCache, createEmbeddingCache } from '@cv-git/core';
+import { EmbeddingCache, createEmbeddingCache, getEmbeddingCacheDir } from '@cv-git/core';
 
 describe('EmbeddingCache', () => {
   let tempDir: string;
@@ -352,6 +352,51 @@ describe('EmbeddingCache', () => {
 
       expect(evicted).toBe(0);
     });
+
+    it('should evict automatically past maxSizeBytes on setBatch', async () => {
+      const small = createEmbeddingCache({
+        cacheDir: path.join(tempDir, 'small'),
+        model: 'openai/text-embedding-3-small',
+        dimensions: 4,
+        maxSizeBytes: 32  // Two 4-dimension vectors
+      });
+      await small.initialize();
+
+      await small.setBatch(new Map([['a', [1, 1, 1, 1]]]));
+      await small.setBatch(new Map([['b', [2, 2, 2, 2]], ['c', [3, 3, 3, 3]]]));
+
+      const stats = await small.getStats();
+      expect(stats.totalEntries).toBe(2);
+      expect(stats.totalSizeBytes).toBeLessThanOrEqual(32);
+      expect(await small.has('c')).toBe(true);
+
+      await small.close();
+    });
+  });
+
+  describe('session stats', () => {
+    it('should count hits and misses for this instance only', async () => {
+      await cache.set('seen', Array.from({ length: 1536 }, () => 0.1));
+      await cache.get('seen');
+      await cache.get('unseen');
+      await cache.close();
+
+      const reopened = createEmbeddingCache({
+        cacheDir: tempDir,
+        model: 'openai/text-embedding-3-small',
+        dimensions: 1536
+      });
+      await reopened.initialize();
+      await reopened.get('seen');
+
+      const stats = await reopened.getStats();
+      expect(stats.cacheHits).toBe(2);
+      expect(stats.sessionHits).toBe(1);
+      expect(stats.sessionMisses).toBe(0);
+      expect(stats.sessionHitRate).toBe(1);
+
+      await reopened.close();
+    });
   });
 
   describe('persistence', () => {
@@ -394,5 +439,29 @@ describe('EmbeddingCache', () => {
 
       await newCache.close();
     });
+
+    it('should move a legacy .cv/embeddings cache into .cv/cache/embeddings', async () => {
+      const repoRoot = path.join(tempDir, 'repo');
+      const legacy = createEmbeddingCache({
+        cacheDir: path.join(repoRoot, '.cv', 'embeddings'),
+        model: 'openai/text-embedding-3-small',
+        dimensions: 4
+      });
+      await legacy.initialize();
+      await legacy.setBatch(new Map([['kept', [1, 2, 3, 4]]]));
+      await legacy.close();
+
+      const migrated = createEmbeddingCache({
+        cacheDir: getEmbeddingCacheDir(repoRoot),
+        model: 'openai/text-embedding-3-small',
+        dimensions: 4
+      });
+      await migrated.initialize();
+
+      expect(await migrated.get('kept')).toEqual([1, 2, 3, 4]);
+      await expect(fs.access(path.join(repoRoot, '.cv', 'embeddings'))).rejects.toThrow();
+
+      await migrated.close();
+    });
   });
 });
@@ -0,0 +1,119 @@
+/**
+ * Embedding Dimensions Tests
+ * Tests for shortened (Matryoshka) embeddings: the reduced size is requested
+ * from the provider, recorded in the index, and used again for queries;
+ * query vectors of another size are rejected before searching
+ */
+
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { VectorManager } from '@cv-git/core';
+
+/** Gemini embedding requests, as sent */
+let requests: Array<{ requests: Array<{ outputDimensionality?: number }> }>;
+
+const manager = (indexDir: string, embeddingDimensions?: number) => new VectorManager({
+  url: '',
+  backend: 'local',
+  indexDir,
+  geminiApiKey: 'test-key',
+  embeddingDimensions,
+  enableCache: false
+});
+
+describe('reduced embedding dimensions', () => {
+  let indexDir: string;
+
+  beforeEach(async () => {
+    indexDir = path.join(await fs.mkdtemp(path.join(os.tmpdir(), 'cv-dimensions-test-')), 'index');
+    requests = [];
+    vi.stubGlobal('fetch', vi.fn(async (_url: string, init: RequestInit) => {
+      const body = JSON.parse(init.body as string);
+      requests.push(body);
+      const embeddings = body.requests.map((request: { outputDimensionality?: number }) => ({
+        values: new Array(request.outputDimensionality ?? 768).fill(0.1)
+      }));
+      return new Response(JSON.stringify({ embeddings }), { status: 200, headers: { 'Content-Type': 'application/json' } });
+    }));
+  });
+
+  afterEach(async () => {
+    vi.unstubAllGlobals();
+    await fs.rm(path.dirname(indexDir), { recursive: true, force: true });
+  });
+
+  const buildIndex = async (dimensions: number) => {
+    const indexer = manager(indexDir, dimensions);
+    await indexer.connect();
+    const [vector] = await indexer.embedBatch(['export function parseConfig() {}']);
+    await indexer.upsertBatch(indexer.getCollectionNames().codeChunks, [{
+      id: 'src/config.ts:1',
+      vector,
+      payload: { id: 'src/config.ts:1', file: 'src/config.ts', startLine: 1, endLine: 1, text: 'export function parseConfig() {}' }
+    }]);
+    await indexer.saveIndex();
+    return vector;
+  };
+
+  it('should request query vectors at the size the index was built with', async () => {
+    const indexed = await buildIndex(256);
+    expect(indexed).toHaveLength(256);
+    expect(requests[0].requests[0].outputDimensionality).toBe(256);
+
+    // No size configured at query time: it comes from the persisted index
+    const querier = manager(indexDir);
+    await querier.connect();
+    const query = await querier.embed('where is the config parsed?', 'query');
+
+    expect(requests[requests.length - 1].requests[0].outputDimensionality).toBe(256);
+    expect(query).toHaveLength(256);
+    expect(querier.getEmbeddingInfo().dimensions).toBe(256);
+    expect((await querier.getIndexSnapshot())?.fingerprint.dimensions).toBe(256);
+  });
+
+  it('should use the full size when asked to, whatever the index was built with', async () => {
+    await buildIndex(256);
+
+    const rebuilder = manager(indexDir, 0);
+    await rebuilder.connect();
+    const vector = await rebuilder.embed('export function parseConfig() {}');
+
+    expect(requests[requests.length - 1].requests[0].outputDimensionality).toBeUndefined();
+    expect(vector).toHaveLength(768);
+    expect(rebuilder.getEmbeddingInfo().dimensions).toBe(768);
+  });
+
+  it('should reject models that cannot shorten their vectors', async () => {
+    const cohere = new VectorManager({
+      url: '',
+      backend: 'local',
+      indexDir,
+      cohereApiKey: 'test-key',
+      embeddingDimensions: 256,
+      enableCache: false
+    });
+
+    await expect(cohere.connect()).rejects.toThrow(/does not support reduced dimensions/);
+  });
+
+  it('should reject more dimensions than the model has', async () => {
+    await expect(manager(indexDir, 1024).connect()).rejects.toThrow(/between 1 and 768/);
+  });
+
+  it('should fail fast when query vectors do not match the index', async () => {
+    await buildIndex(256);
+
+    // Same index, but the provider now returns full-size vectors
+    const querier = manager(indexDir, 256);
+    await querier.connect();
+    vi.stubGlobal('fetch', vi.fn(async () => new Response(
+      JSON.stringify({ embeddings: [{ values: new Array(768).fill(0.1) }] }),
+      { status: 200, headers: { 'Content-Type': 'application/json' } }
+    )));
+
+    await expect(querier.searchCode('where is the config parsed?'))
+      .rejects.toThrow(/^Index built with text-embedding-004 \(256 dims\); current provider returns 768 .*cv sync --force/s);
+  });
+});
@@ -0,0 +1,73 @@
+/**
+ * File Context Tests
+ * Tests for including explicitly named files as context (--file)
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs/promises';
+import * as path from 'path';
+import * as os from 'os';
+import { gatherFileChunks, mergeFileChunks } from '@cv-git/core';
+
+function searchHit(file: string, startLine: number, score: number) {
+  const id = `${file}:${startLine}`;
+  return {
+    id,
+    score,
+    payload: { id, file, language: 'typescript', startLine, endLine: startLine + 5, text: '', imports: [], lastModified: 0 }
+  };
+}
+
+describe('gatherFileChunks', () => {
+  let repoRoot: string;
+
+  beforeEach(async () => {
+    repoRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-file-context-'));
+    await fs.mkdir(path.join(repoRoot, 'src'));
+    await fs.writeFile(path.join(repoRoot, 'src/small.ts'), 'export const a = 1;\nexport const b = 2;\n');
+    await fs.writeFile(path.join(repoRoot, 'src/large.ts'), 'const line = 1;\n'.repeat(200));
+  });
+
+  afterEach(async () => {
+    await fs.rm(repoRoot, { recursive: true, force: true });
+  });
+
+  it('should include small files whole with a full score', async () => {
+    const [chunk] = await gatherFileChunks(repoRoot, ['src/small.ts'], { query: 'constants' });
+
+    expect(chunk.score).toBe(1);
+    expect(chunk.payload.file).toBe('src/small.ts');
+    expect(chunk.payload.text).toContain('export const b = 2;');
+    expect(chunk.payload.wholeFile).toBe(true);
+  });
+
+  it('should fall back to the head of large files without a vector store', async () => {
+    const [chunk] = await gatherFileChunks(repoRoot, ['src/large.ts'], { query: 'line', maxBytes: 100 });
+
+    expect(chunk.payload.text.length).toBeLessThanOrEqual(100);
+    expect(chunk.payload.text.endsWith('const line = 1;')).toBe(true);
+    expect(chunk.payload.wholeFile).toBe(false);
+  });
+
+  it('should fail for missing files', async () => {
+    await expect(gatherFileChunks(repoRoot, ['src/missing.ts'], { query: 'x' })).rejects.toThrow();
+  });
+});
+
+describe('mergeFileChunks', () => {
+  it('should put file chunks first and drop search hits they already cover', async () => {
+    const whole = {
+      id: 'src/a.ts:file',
+      score: 1,
+      payload: { id: 'src/a.ts:file', file: 'src/a.ts', language: 'typescript', startLine: 1, endLine: 10, text: '', imports: [], lastModified: 0, wholeFile: true }
+    };
+    const fromLargeFile = searchHit('src/big.ts', 40, 0.3);
+
+    const merged = mergeFileChunks(
+      [whole, fromLargeFile],
+      [searchHit('src/a.ts', 1, 0.9), searchHit('src/big.ts', 40, 0.3), searchHit('src/c.ts', 1, 0.6)]
+    );
+
+    expect(merged.map(c => c.id)).toEqual(['src/a.ts:file', 'src/big.ts:40', 'src/c.ts:1']);
+  });
+});
@@ -0,0 +1,60 @@
+/**
+ * Follow-up Suggestion Tests
+ * Tests the grounding of cv explain/chat --suggest questions in the indexed code
+ */
+
+import { describe, it, expect } from 'vitest';
+import { buildFollowUpPrompt, parseFollowUps, FollowUpSubject } from '@cv-git/core';
+
+const subjects: FollowUpSubject[] = [
+  { file: 'src/auth/session.ts', symbol: 'refreshSession' },
+  { file: 'src/auth/session.ts', symbol: 'SessionStore' },
+  { file: 'src/config.ts' }
+];
+
+describe('buildFollowUpPrompt', () => {
+  it('should list each indexed file and symbol once', () => {
+    const prompt = buildFollowUpPrompt('How are sessions refreshed?', 'They are refreshed by `refreshSession`.', [...subjects, subjects[0]]);
+
+    expect(prompt).toContain('How are sessions refreshed?');
+    expect(prompt.match(/- `refreshSession` in `src\/auth\/session\.ts`/g)).toHaveLength(1);
+    expect(prompt).toContain('- `src/config.ts`');
+    expect(prompt).toContain('Suggest 3 short follow-up questions');
+  });
+});
+
+describe('parseFollowUps', () => {
+  it('should keep questions that name only indexed code', () => {
+    const response = 'Here you go:\n' + JSON.stringify([
+      'Where is `SessionStore` persisted?',
+      'What does `validateToken` check?',
+      'Which settings in `src/config.ts:10-24` affect `refreshSession()`?',
+      'How does login work?'
+    ]);
+
+    expect(parseFollowUps(response, subjects)).toEqual([
+      'Where is `SessionStore` persisted?',
+      'Which settings in `src/config.ts:10-24` affect `refreshSession()`?'
+    ]);
+  });
+
+  it('should drop duplicates and stop at the count', () => {
+    const response = JSON.stringify([
+      'What calls `refreshSession`?',
+      'what calls `refreshSession`?',
+      'Where is `SessionStore` created?',
+      'What reads `src/config.ts`?'
+    ]);
+
+    expect(parseFollowUps(response, subjects, 2)).toEqual([
+      'What calls `refreshSession`?',
+      'Where is `SessionStore` created?'
+    ]);
+  });
+
+  it('should return no suggestions for a malformed response', () => {
+    expect(parseFollowUps('I cannot suggest anything.', subjects)).toEqual([]);
+    expect(parseFollowUps('["unterminated', subjects)).toEqual([]);
+    expect(parseFollowUps('[1, 2]', subjects)).toEqual([]);
+  });
+});
@@ -0,0 +1,69 @@
+/**
+ * Gemini Client Tests
+ * Tests for message mapping and safety-block handling in the Gemini provider
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  toGeminiContents,
+  extractGeminiText,
+  GeminiSafetyError
+} from '@cv-git/core';
+
+describe('toGeminiContents', () => {
+  it('should map assistant to model and move system prompts to systemInstruction', () => {
+    const result = toGeminiContents(
+      [
+        { role: 'system', content: 'Be brief.' },
+        { role: 'user', content: 'Hi' },
+        { role: 'assistant', content: 'Hello' }
+      ],
+      'You are a code assistant.'
+    );
+
+    expect(result.systemInstruction).toEqual({ parts: [{ text: 'You are a code assistant.\n\nBe brief.' }] });
+    expect(result.contents).toEqual([
+      { role: 'user', parts: [{ text: 'Hi' }] },
+      { role: 'model', parts: [{ text: 'Hello' }] }
+    ]);
+  });
+
+  it('should merge consecutive messages with the same role', () => {
+    const result = toGeminiContents([
+      { role: 'user', content: 'Context' },
+      { role: 'user', content: 'Question' }
+    ]);
+
+    expect(result.systemInstruction).toBeUndefined();
+    expect(result.contents).toEqual([{ role: 'user', parts: [{ text: 'Context' }, { text: 'Question' }] }]);
+  });
+});
+
+describe('extractGeminiText', () => {
+  it('should join the text parts of the first candidate', () => {
+    expect(extractGeminiText({
+      candidates: [{ content: { parts: [{ text: 'Hello ' }, { text: 'world' }] }, finishReason: 'STOP' }]
+    })).toBe('Hello world');
+  });
+
+  it('should throw a safety error when the prompt is blocked', () => {
+    const blocked = {
+      promptFeedback: {
+        blockReason: 'SAFETY',
+        safetyRatings: [
+          { category: 'HARM_CATEGORY_DANGEROUS_CONTENT', probability: 'HIGH' },
+          { category: 'HARM_CATEGORY_HARASSMENT', probability: 'NEGLIGIBLE' }
+        ]
+      }
+    };
+
+    expect(() => extractGeminiText(blocked)).toThrow(GeminiSafetyError);
+    expect(() => extractGeminiText(blocked)).toThrow(/blocked the prompt: SAFETY \(dangerous content\)/);
+  });
+
+  it('should throw a safety error when the response is stopped', () => {
+    expect(() => extractGeminiText({
+      candidates: [{ content: { parts: [] }, finishReason: 'RECITATION' }]
+    })).toThrow(/stopped the response: RECITATION/);
+  });
+});
@@ -0,0 +1,125 @@
+/**
+ * GitHub Review Unit Tests
+ * Tests for cv review --github: finding the pull request, mapping lines to
+ * diff positions, and editing cv's own comments on re-runs
+ */
+
+import { describe, it, expect } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  resolveGitHubPullRequest,
+  mapDiffPositions,
+  planGitHubReview,
+  findingFingerprint,
+  summarizeFindings
+} from '@cv-git/core';
+import type { ReviewFinding, ReviewResult } from '@cv-git/shared';
+
+const finding = (overrides: Partial<ReviewFinding>): ReviewFinding => ({
+  file: 'src/a.ts',
+  startLine: 1,
+  endLine: 1,
+  severity: 'medium',
+  category: 'correctness',
+  message: 'Issue',
+  ...overrides
+});
+
+const result = (findings: ReviewFinding[]): ReviewResult => ({ findings, summary: summarizeFindings(findings, 'Overview') });
+
+const diff = [
+  'diff --git a/src/a.ts b/src/a.ts',
+  'index 1111111..2222222 100644',
+  '--- a/src/a.ts',
+  '+++ b/src/a.ts',
+  '@@ -1,3 +1,4 @@',
+  ' const a = 1;',
+  '-const b = 2;',
+  '+const b = 3;',
+  '+const c = 4;',
+  ' const d = 5;',
+  '@@ -20,2 +21,3 @@ function f() {',
+  ' return a;',
+  '+return b;',
+  ' }',
+  'diff --git a/src/new.ts b/src/new.ts',
+  'new file mode 100644',
+  '--- /dev/null',
+  '+++ b/src/new.ts',
+  '@@ -0,0 +1,2 @@',
+  '+export const x = 1;',
+  '+export const y = 2;',
+  ''
+].join('\n');
+
+describe('mapDiffPositions', () => {
+  it('should count positions from the first hunk header of each file, including later headers', () => {
+    const positions = mapDiffPositions(diff);
+
+    expect([...positions.get('src/a.ts')!]).toEqual([[1, 1], [2, 3], [3, 4], [4, 5], [21, 7], [22, 8], [23, 9]]);
+    expect([...positions.get('src/new.ts')!]).toEqual([[1, 1], [2, 2]]);
+  });
+});
+
+describe('planGitHubReview', () => {
+  const bug = finding({ startLine: 2, message: 'b changed meaning', severity: 'high' });
+  const marker = (f: ReviewFinding) => `<!-- cv-review:${findingFingerprint(f)} -->`;
+
+  it('should comment on the diff position of each finding and list the rest in the summary', () => {
+    const outside = finding({ startLine: 10, message: 'Old code' });
+    const plan = planGitHubReview(result([bug, outside]), diff, { inline: [], conversation: [] });
+
+    expect(plan.create).toHaveLength(1);
+    expect(plan.create[0]).toMatchObject({ path: 'src/a.ts', position: 3, line: 2 });
+    expect(plan.create[0].body).toContain(marker(bug));
+    expect(plan.create[0].body).toContain('**HIGH** · correctness');
+    expect(plan.unplaced).toEqual([outside]);
+    expect(plan.update).toEqual([]);
+    expect(plan.summary.id).toBeUndefined();
+    expect(plan.summary.body).toContain('**2 finding(s)** (1 high, 1 medium)');
+    expect(plan.summary.body).toContain('`src/a.ts:10`');
+  });
+
+  it('should edit its earlier comments instead of posting them again, and mark gone findings resolved', () => {
+    const first = planGitHubReview(result([bug]), diff, { inline: [], conversation: [] });
+    const gone = finding({ file: 'src/new.ts', message: 'Fixed since' });
+    const inline = [
+      { id: 1, body: first.create[0].body },
+      { id: 2, body: `${marker(gone)}\nold text` },
+      { id: 3, body: 'A reviewer comment' }
+    ];
+    const conversation = [{ id: 9, body: '<!-- cv-review:summary -->\nold summary' }];
+
+    const same = planGitHubReview(result([bug]), diff, { inline, conversation });
+    expect(same.create).toEqual([]);
+    expect(same.update).toEqual([{ id: 2, body: `${marker(gone)}\n✅ No longer reported by \`cv review\`.`, resolved: true }]);
+    expect(same.summary.id).toBe(9);
+
+    const reworded = planGitHubReview(result([{ ...bug, suggestion: 'Keep 2' }]), diff, { inline, conversation });
+    expect(reworded.create).toEqual([]);
+    expect(reworded.update[0]).toMatchObject({ id: 1, resolved: false });
+    expect(reworded.update[0].body).toContain('**Suggestion:** Keep 2');
+  });
+});
+
+describe('resolveGitHubPullRequest', () => {
+  it('should prefer flags, then the Actions event, then a pull request ref', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-github-review-'));
+    const eventPath = path.join(dir, 'event.json');
+    await fs.writeFile(eventPath, JSON.stringify({ pull_request: { number: 12 } }));
+    try {
+      const env = { GITHUB_REPOSITORY: 'acme/app', GITHUB_EVENT_PATH: eventPath, GITHUB_REF: 'refs/pull/34/merge' };
+
+      expect(await resolveGitHubPullRequest({ repo: 'other/lib', pr: '7' }, env)).toEqual({ owner: 'other', repo: 'lib', number: 7 });
+      expect(await resolveGitHubPullRequest({}, env)).toEqual({ owner: 'acme', repo: 'app', number: 12 });
+      expect(await resolveGitHubPullRequest({}, { GITHUB_REPOSITORY: 'acme/app', GITHUB_REF: 'refs/pull/34/merge' }))
+        .toEqual({ owner: 'acme', repo: 'app', number: 34 });
+      await expect(resolveGitHubPullRequest({}, { GITHUB_REPOSITORY: 'acme/app' })).rejects.toThrow('No pull request');
+      await expect(resolveGitHubPullRequest({ pr: '7' }, {})).rejects.toThrow('No repository');
+    } finally {
+      await fs.rm(dir, { recursive: true, force: true });
+    }
+  });
+});
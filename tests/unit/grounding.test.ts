@@ -0,0 +1,45 @@
+/**
+ * Answer Grounding Tests
+ * Tests for flagging symbols an answer names that the index does not have
+ */
+
+import { describe, it, expect } from 'vitest';
+import { CodeChunkPayload } from '@cv-git/shared';
+import { buildGroundingIndex, extractSymbolReferences, findUngroundedSymbols } from '@cv-git/core';
+
+const chunk = (symbolName: string, text: string): CodeChunkPayload => ({
+  id: symbolName,
+  file: 'src/auth.ts',
+  language: 'typescript',
+  symbolName,
+  symbolKind: 'method',
+  startLine: 1,
+  endLine: 3,
+  text
+} as CodeChunkPayload);
+
+describe('extractSymbolReferences', () => {
+  it('should take identifiers and calls from inline code, not from fenced blocks', () => {
+    const answer = [
+      'The `AuthService.login()` method hashes with `hashPassword(password)` and reads `auth.ts`.',
+      'It returns `null` when `OPENAI_API_KEY` is unset; see `--verbose`.',
+      '```ts',
+      'sketchOnly();',
+      '```',
+      'Then `hashPassword` again.'
+    ].join('\n');
+
+    expect(extractSymbolReferences(answer)).toEqual(['AuthService.login', 'hashPassword']);
+  });
+});
+
+describe('findUngroundedSymbols', () => {
+  it('should flag names the index neither defines nor uses', () => {
+    const known = buildGroundingIndex([
+      chunk('AuthService.login', 'login(password) {\n  return hashPassword(password) && JSON.parse(this.raw);\n}')
+    ]);
+    const answer = '`AuthService.login` calls `hashPassword`, `JSON.parse` and `AuthService.revokeAllTokens()`, then `refreshSession`.';
+
+    expect(findUngroundedSymbols(answer, known)).toEqual(['AuthService.revokeAllTokens', 'refreshSession']);
+  });
+});
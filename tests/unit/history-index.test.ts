@@ -0,0 +1,179 @@
+/**
+ * History Index Tests
+ * Tests for cv sync --history: embedding commit messages into the commits
+ * collection, continuing from the last pass, and searching them
+ */
+
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  VectorManager,
+  GitManager,
+  parseNameOnlyLog,
+  buildCommitText,
+  syncHistoryIndex,
+  readIndexMetadata,
+  writeIndexMetadata
+} from '@cv-git/core';
+import type { GitCommit } from '@cv-git/shared';
+
+const commit = (overrides: Partial<GitCommit>): GitCommit => ({
+  sha: 'a1b2c3d4e5f6a7b8c9d0a1b2c3d4e5f6a7b8c9d0',
+  message: 'Fix login',
+  author: 'Dana',
+  authorEmail: 'dana@example.com',
+  date: Date.UTC(2026, 9, 1),
+  files: [],
+  ...overrides
+});
+
+/** Word counts hashed into buckets: texts sharing words get similar vectors */
+function embedWords(text: string): number[] {
+  const values = new Array(768).fill(0);
+  for (const word of text.toLowerCase().match(/[a-z0-9]+/g) ?? []) {
+    let hash = 0;
+    for (const ch of word) hash = (hash * 31 + ch.charCodeAt(0)) % 768;
+    values[hash] += 1;
+  }
+  return values;
+}
+
+describe('parseNameOnlyLog', () => {
+  it('should keep multi-line messages and list the files of each commit', () => {
+    const output =
+      '\x1ea1b2c3\x1fDana\x1fdana@example.com\x1f1790000000\x1fSet token expiry to 24h\n\nShorter tokens logged mobile users out.\n\x1f\n\n' +
+      'src/auth/token.ts\nsrc/auth/token.test.ts\n' +
+      '\x1ed4e5f6\x1fLee\x1flee@example.com\x1f1780000000\x1fMerge branch main\n\x1f\n';
+
+    const commits = parseNameOnlyLog(output);
+
+    expect(commits).toHaveLength(2);
+    expect(commits[0]).toMatchObject({
+      sha: 'a1b2c3',
+      author: 'Dana',
+      message: 'Set token expiry to 24h\n\nShorter tokens logged mobile users out.',
+      date: 1790000000000,
+      files: ['src/auth/token.ts', 'src/auth/token.test.ts']
+    });
+    expect(commits[1].files).toEqual([]);
+  });
+});
+
+describe('buildCommitText', () => {
+  it('should embed the message with the files it touched', () => {
+    const text = buildCommitText(commit({ message: 'Set token expiry to 24h', files: ['src/auth/token.ts'] }));
+    expect(text).toBe('Set token expiry to 24h\n\nFiles changed:\n- src/auth/token.ts');
+  });
+
+  it('should cap the file list and the diff', () => {
+    const files = Array.from({ length: 60 }, (_, i) => `src/file${i}.ts`);
+    const text = buildCommitText(commit({ files }), `+${'x'.repeat(5000)}`);
+
+    expect(text).toContain('- src/file49.ts\n- ... and 10 more');
+    expect(text).not.toContain('src/file50.ts');
+    expect(text).toContain('\n\nDiff:\n+x');
+    expect(text.endsWith('\n[...]')).toBe(true);
+    expect(text.length).toBeLessThan(6000);
+  });
+});
+
+describe('syncHistoryIndex', () => {
+  let repoRoot: string;
+  let log: GitCommit[];
+  let embedded: string[];
+
+  const tokenCommit = commit({
+    sha: 'aaa1',
+    message: 'Set token expiry to 24h\n\nShorter expiry logged mobile users out between sessions.',
+    files: ['src/auth/generateToken.ts']
+  });
+  const cacheCommit = commit({ sha: 'bbb2', message: 'Cache rendered templates', files: ['src/render/cache.ts'] });
+  const logCommit = commit({ sha: 'ccc3', message: 'Rotate log files daily', files: ['src/logging.ts'] });
+
+  // Log newest first; `since` returns the commits made after it
+  const git = {
+    getCommitLog: async (limit: number, since?: string) => {
+      const end = since ? log.findIndex(c => c.sha === since) : log.length;
+      return log.slice(0, end < 0 ? log.length : end).slice(0, limit);
+    },
+    commitExists: async (sha: string) => log.some(c => c.sha === sha),
+    getCommitDiff: async (sha: string) => `diff --git a/${sha} b/${sha}`
+  } as unknown as GitManager;
+
+  const connect = async () => {
+    const vector = new VectorManager({
+      url: '',
+      backend: 'local',
+      indexDir: path.join(repoRoot, '.cv', 'index'),
+      geminiApiKey: 'test-key',
+      enableCache: false
+    });
+    await vector.connect();
+    return vector;
+  };
+
+  beforeEach(async () => {
+    repoRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-history-index-test-'));
+    await writeIndexMetadata(repoRoot, { provider: 'gemini', model: 'text-embedding-004', dimensions: 768 }, 'head');
+    log = [cacheCommit, tokenCommit];
+    embedded = [];
+    vi.stubGlobal('fetch', vi.fn(async (_url: string, init: RequestInit) => {
+      const body = JSON.parse(init.body as string);
+      const embeddings = body.requests.map((request: { content: { parts: Array<{ text: string }> } }) => {
+        embedded.push(request.content.parts[0].text);
+        return { values: embedWords(request.content.parts[0].text) };
+      });
+      return new Response(JSON.stringify({ embeddings }), { status: 200, headers: { 'Content-Type': 'application/json' } });
+    }));
+  });
+
+  afterEach(async () => {
+    vi.unstubAllGlobals();
+    await fs.rm(repoRoot, { recursive: true, force: true });
+  });
+
+  it('should make the rationale in commit messages searchable', async () => {
+    const vector = await connect();
+    const result = await syncHistoryIndex(repoRoot, git, vector);
+
+    expect(result).toEqual({ indexed: 2, total: 2, rebuilt: true });
+    const [best] = await vector.searchCommits('why was token expiry set to 24h', 2);
+    expect(best.payload).toMatchObject({ sha: 'aaa1', filesChanged: ['src/auth/generateToken.ts'] });
+    expect(best.payload.message).toContain('logged mobile users out');
+
+    const metadata = await readIndexMetadata(repoRoot);
+    expect(metadata?.history).toMatchObject({ lastCommit: 'bbb2', commits: 2, diffs: false });
+    expect(metadata?.lastIndexedCommit).toBe('head');
+  });
+
+  it('should only embed commits made since the last pass', async () => {
+    const vector = await connect();
+    await syncHistoryIndex(repoRoot, git, vector);
+
+    log = [logCommit, ...log];
+    embedded = [];
+    const result = await syncHistoryIndex(repoRoot, git, vector);
+
+    expect(result).toEqual({ indexed: 1, total: 3, rebuilt: false });
+    expect(embedded).toEqual([buildCommitText(logCommit)]);
+    expect((await readIndexMetadata(repoRoot))?.history?.lastCommit).toBe('ccc3');
+  });
+
+  it('should embed everything again when the diffs setting changes or the last commit is gone', async () => {
+    const vector = await connect();
+    await syncHistoryIndex(repoRoot, git, vector);
+
+    const withDiffs = await syncHistoryIndex(repoRoot, git, vector, { diffs: true });
+    expect(withDiffs).toEqual({ indexed: 2, total: 2, rebuilt: true });
+    expect(embedded.slice(-2).every(text => text.includes('\n\nDiff:\ndiff --git'))).toBe(true);
+
+    // History rewritten: the last embedded commit no longer exists
+    log = [logCommit, tokenCommit];
+    const rewritten = await syncHistoryIndex(repoRoot, git, vector, { diffs: true });
+    expect(rewritten).toEqual({ indexed: 2, total: 2, rebuilt: true });
+    const results = await vector.searchCommits('cache rendered templates', 5);
+    expect(results.map(r => r.payload.sha).sort()).toEqual(['aaa1', 'ccc3']);
+  });
+});
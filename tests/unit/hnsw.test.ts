@@ -0,0 +1,148 @@
+/**
+ * HNSW Unit Tests
+ * Tests approximate search recall against brute force and the local vector store
+ */
+
+import { describe, it, expect } from 'vitest';
+import { HnswIndex, LocalVectorStore, normalizeVector, dotProduct, matchesFilter } from '@cv-git/core';
+
+// Deterministic clustered vectors, roughly like embeddings of related code
+function createRandom(seed: number): () => number {
+  let state = seed;
+  return () => {
+    state = (state * 16807) % 2147483647;
+    return state / 2147483647;
+  };
+}
+
+const random = createRandom(7);
+const dimensions = 32;
+const centers = Array.from({ length: 20 }, () => Array.from({ length: dimensions }, () => random() * 2 - 1));
+const sample = (): number[] => {
+  const center = centers[Math.floor(random() * centers.length)];
+  return center.map(x => x + (random() * 2 - 1) * 0.6);
+};
+
+const points = Array.from({ length: 2000 }, (_, i) => ({ id: i, vector: normalizeVector(sample()) }));
+const queries = Array.from({ length: 50 }, () => normalizeVector(sample()));
+
+function exactTopK(query: Float32Array, k: number, ids: Set<number> = new Set(points.map(p => p.id))): Set<number> {
+  return new Set(points
+    .filter(p => ids.has(p.id))
+    .map(p => ({ id: p.id, score: dotProduct(query, p.vector) }))
+    .sort((a, b) => b.score - a.score)
+    .slice(0, k)
+    .map(p => p.id));
+}
+
+function recall(index: HnswIndex, ef: number, ids?: Set<number>): number {
+  let found = 0;
+  for (const query of queries) {
+    const expected = exactTopK(query, 10, ids);
+    found += index.search(query, 10, ef).filter(match => expected.has(match.id as number)).length;
+  }
+  return found / (queries.length * 10);
+}
+
+describe('HnswIndex', () => {
+  const index = new HnswIndex({ m: 16, efConstruction: 100 });
+  for (const point of points) {
+    index.add(point.id, point.vector);
+  }
+
+  it('should find nearly the same neighbours as brute force', () => {
+    expect(recall(index, 64)).toBeGreaterThan(0.95);
+  });
+
+  it('should improve recall with a larger ef', () => {
+    expect(recall(index, 128)).toBeGreaterThanOrEqual(recall(index, 10));
+  });
+
+  it('should round-trip through a serialized graph', () => {
+    const graph = JSON.parse(JSON.stringify(index.toGraph()));
+    const loaded = HnswIndex.fromGraph(graph, id => points[id as number]?.vector);
+
+    expect(loaded?.size).toBe(points.length);
+    expect(loaded!.search(queries[0], 5)).toEqual(index.search(queries[0], 5));
+    expect(HnswIndex.fromGraph(graph, () => undefined)).toBe(null);
+  });
+
+  it('should keep recall after removing points', () => {
+    const copy = HnswIndex.fromGraph(JSON.parse(JSON.stringify(index.toGraph())), id => points[id as number].vector)!;
+    const removed = points.filter(p => p.id % 3 === 0).map(p => p.id);
+    copy.remove(removed);
+
+    const remaining = new Set(points.filter(p => p.id % 3 !== 0).map(p => p.id));
+    expect(copy.size).toBe(remaining.size);
+    expect(queries.every(q => copy.search(q, 10).every(match => remaining.has(match.id as number)))).toBe(true);
+    expect(recall(copy, 64, remaining)).toBeGreaterThan(0.9);
+  });
+});
+
+describe('LocalVectorStore', () => {
+  const upsertAll = async (store: LocalVectorStore) => {
+    await store.createCollection('code', { vectors: { size: dimensions } });
+    await store.upsert('code', {
+      points: points.map(p => ({ id: p.id, vector: Array.from(p.vector), payload: { file: `src/${p.id % 4}.ts` } }))
+    });
+  };
+
+  it('should search small collections exactly', async () => {
+    const store = new LocalVectorStore({ minPoints: 5000 });
+    await upsertAll(store);
+
+    expect(store.buildIndex('code')).toBe(null);
+    const results = await store.search('code', { vector: Array.from(queries[0]), limit: 10 });
+    expect(new Set(results.map(r => r.id))).toEqual(exactTopK(queries[0], 10));
+  });
+
+  it('should search large collections through the graph and honour filters', async () => {
+    const store = new LocalVectorStore({ minPoints: 1000 });
+    await upsertAll(store);
+    expect(store.buildIndex('code')).not.toBe(null);
+    expect((await store.getCollection('code')).indexed_vectors_count).toBe(points.length);
+
+    const filter = { must: [{ key: 'file', match: { value: 'src/1.ts' } }] };
+    const results = await store.search('code', { vector: Array.from(queries[0]), limit: 5, filter, params: { hnsw_ef: 128 } });
+    expect(results).toHaveLength(5);
+    expect(results.every(r => r.payload.file === 'src/1.ts')).toBe(true);
+  });
+
+  it('should update the graph on upsert and delete', async () => {
+    const store = new LocalVectorStore({ minPoints: 1000 });
+    await upsertAll(store);
+    store.buildIndex('code');
+
+    await store.delete('code', { filter: { must: [{ key: 'file', match: { value: 'src/0.ts' } }] } });
+    await store.upsert('code', { points: [{ id: 1, vector: Array.from(queries[1]), payload: { file: 'moved.ts' } }] });
+
+    const info = await store.getCollection('code');
+    expect(info.points_count).toBe(1500);
+    expect(info.indexed_vectors_count).toBe(1500);
+    const [best] = await store.search('code', { vector: Array.from(queries[1]), limit: 1 });
+    expect(best.payload.file).toBe('moved.ts');
+  });
+
+  it('should ignore a graph built with other settings', async () => {
+    const store = new LocalVectorStore({ minPoints: 1000 });
+    await upsertAll(store);
+    const graph = store.buildIndex('code')!;
+
+    const other = new LocalVectorStore({ minPoints: 1000, m: 8 });
+    await upsertAll(other);
+    expect(other.loadIndex('code', graph)).toBe(false);
+
+    const same = new LocalVectorStore({ minPoints: 1000 });
+    await upsertAll(same);
+    expect(same.loadIndex('code', graph)).toBe(true);
+  });
+});
+
+describe('matchesFilter', () => {
+  it('should evaluate must, should and must_not', () => {
+    const payload = { file: 'src/a.ts', language: 'typescript' };
+    expect(matchesFilter(payload, { should: [{ key: 'file', match: { value: 'src/b.ts' } }, { key: 'file', match: { value: 'src/a.ts' } }] })).toBe(true);
+    expect(matchesFilter(payload, { must_not: [{ key: 'language', match: { any: ['typescript', 'javascript'] } }] })).toBe(false);
+    expect(matchesFilter(payload, { must: [{ key: 'file', match: { text: 'src/' } }] })).toBe(true);
+  });
+});
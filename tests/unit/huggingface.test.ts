@@ -0,0 +1,58 @@
+/**
+ * HuggingFace Embedding Tests
+ * Tests for the Inference API and TEI endpoints and the response shapes they return
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import { HuggingFaceClient, toEmbeddings } from '@cv-git/core';
+
+function mockFetch(body: unknown) {
+  const fetchMock = vi.fn().mockImplementation(async () => new Response(JSON.stringify(body), { status: 200 }));
+  vi.stubGlobal('fetch', fetchMock);
+  return fetchMock;
+}
+
+afterEach(() => {
+  vi.unstubAllGlobals();
+});
+
+describe('toEmbeddings', () => {
+  it('normalizes one vector per input', () => {
+    expect(toEmbeddings([[3, 4], [0, 2]], 2)).toEqual([[0.6, 0.8], [0, 1]]);
+  });
+
+  it('accepts a single vector for a single input', () => {
+    expect(toEmbeddings([3, 4], 1)).toEqual([[0.6, 0.8]]);
+  });
+
+  it('mean-pools token embeddings', () => {
+    const [vector] = toEmbeddings([[[1, 0], [0, 1]]], 1);
+    expect(vector[0]).toBeCloseTo(Math.SQRT1_2);
+    expect(vector[1]).toBeCloseTo(Math.SQRT1_2);
+  });
+
+  it('rejects a response with the wrong number of vectors', () => {
+    expect(() => toEmbeddings([[1, 0]], 2)).toThrow('1 embeddings for 2 inputs');
+  });
+});
+
+describe('HuggingFaceClient', () => {
+  it('posts to the feature-extraction pipeline of the model with the token', async () => {
+    const fetchMock = mockFetch([[1, 0]]);
+    await new HuggingFaceClient({ apiKey: 'hf_token', maxRetryAttempts: 1 }).embed(['a'], 'BAAI/bge-large-en');
+
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe('https://router.huggingface.co/hf-inference/models/BAAI/bge-large-en/pipeline/feature-extraction');
+    expect(init.headers.Authorization).toBe('Bearer hf_token');
+    expect(JSON.parse(init.body)).toEqual({ inputs: ['a'], normalize: true, truncate: true });
+  });
+
+  it('posts to a TEI server without a token', async () => {
+    const fetchMock = mockFetch([[0, 1]]);
+    const embeddings = await new HuggingFaceClient({ baseUrl: 'http://localhost:8080/', maxRetryAttempts: 1 }).embed(['a']);
+
+    expect(embeddings).toEqual([[0, 1]]);
+    expect(fetchMock.mock.calls[0][0]).toBe('http://localhost:8080/embed');
+    expect(fetchMock.mock.calls[0][1].headers.Authorization).toBeUndefined();
+  });
+});
@@ -0,0 +1,56 @@
+/**
+ * Ignore Rules Tests
+ * Tests for .gitignore / .cvignore handling during sync
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { IgnoreRules } from '../../packages/core/src/sync/ignore.js';
+
+describe('IgnoreRules', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-ignore-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(tempDir, { recursive: true, force: true });
+  });
+
+  it('should report which file ignores a path', async () => {
+    await fs.writeFile(path.join(tempDir, '.gitignore'), 'dist/\n*.log\n');
+    await fs.writeFile(path.join(tempDir, '.cvignore'), '# generated\nsrc/generated/**\n');
+
+    const rules = await IgnoreRules.load(tempDir);
+
+    expect(rules.match('dist/index.js')).toBe('.gitignore');
+    expect(rules.match('debug.log')).toBe('.gitignore');
+    expect(rules.match('src/generated/api.ts')).toBe('.cvignore');
+    expect(rules.match('src/index.ts')).toBeNull();
+  });
+
+  it('should apply nested ignore files relative to their directory', async () => {
+    await fs.mkdir(path.join(tempDir, 'packages', 'web'), { recursive: true });
+    await fs.writeFile(path.join(tempDir, '.gitignore'), '*.tmp\n');
+    await fs.writeFile(path.join(tempDir, 'packages', 'web', '.cvignore'), 'fixtures/\n!keep.tmp\n');
+
+    const rules = await IgnoreRules.load(tempDir, [
+      'packages/web/.cvignore',
+      'packages/web/src/app.ts'
+    ]);
+
+    expect(rules.match('packages/web/fixtures/user.json')).toBe('.cvignore');
+    expect(rules.match('fixtures/user.json')).toBeNull();
+    expect(rules.match('packages/web/src/app.ts')).toBeNull();
+    // .cvignore can only add exclusions, not re-include gitignored files
+    expect(rules.match('packages/web/keep.tmp')).toBe('.gitignore');
+  });
+
+  it('should ignore nothing without rule files', async () => {
+    const rules = await IgnoreRules.load(tempDir);
+    expect(rules.match('src/index.ts')).toBeNull();
+  });
+});
@@ -0,0 +1,118 @@
+/**
+ * Index Archive Tests
+ * Tests for cv index export/import: packing .cv/index into one file and
+ * loading it into another checkout with its own repo ID
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  exportIndexArchive,
+  readIndexArchive,
+  importIndexArchive,
+  findIndexArchivePathWarnings,
+  writeIndexSnapshot,
+  writeIndexMetadata,
+  readIndexMetadata,
+  readIndexSnapshotManifest,
+  readIndexSnapshotCollection,
+  getIndexDir
+} from '@cv-git/core';
+
+const identity = { provider: 'ollama', model: 'nomic-embed-text', dimensions: 3 };
+
+function point(id: string, file: string) {
+  return { id, vector: [0.1, 0.2, 0.3], payload: { file, text: id } };
+}
+
+describe('Index archive', () => {
+  let tmp: string;
+  let builder: string;
+  let developer: string;
+  let archiveFile: string;
+
+  beforeEach(async () => {
+    tmp = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-index-archive-test-'));
+    builder = path.join(tmp, 'ci');
+    developer = path.join(tmp, 'dev');
+    archiveFile = path.join(tmp, 'artifacts', 'index.cvindex');
+
+    await writeIndexSnapshot(getIndexDir(builder), identity, new Map([
+      ['ci0000_code_chunks', [point('a', 'src/a.ts'), point('b', 'src/b.ts')]],
+      ['ci0000_docstrings', [point('c', 'src/a.ts')]]
+    ]), 'abc123');
+    await writeIndexMetadata(builder, identity, 'abc123', undefined, undefined, { path: builder, branch: 'main' });
+  });
+
+  afterEach(async () => {
+    await fs.rm(tmp, { recursive: true, force: true });
+  });
+
+  it('should round-trip the vectors, fingerprint and source commit', async () => {
+    const header = await exportIndexArchive(builder, archiveFile, { repoId: 'ci0000' });
+    expect(header).toMatchObject({ fingerprint: identity, sourceCommit: 'abc123', sourcePath: builder });
+
+    const archive = await readIndexArchive(archiveFile);
+    expect(archive.header.collections).toEqual({ ci0000_code_chunks: 2, ci0000_docstrings: 1 });
+    expect(archive.collections.get('ci0000_code_chunks')).toEqual([point('a', 'src/a.ts'), point('b', 'src/b.ts')]);
+  });
+
+  it('should import under the local repo ID and record where the index came from', async () => {
+    await exportIndexArchive(builder, archiveFile, { repoId: 'ci0000' });
+    const archive = await readIndexArchive(archiveFile);
+
+    await importIndexArchive(developer, archive, archiveFile, { repoId: 'dev111', worktree: { path: developer, branch: 'feature' } });
+
+    const manifest = await readIndexSnapshotManifest(getIndexDir(developer));
+    expect(manifest?.collections).toEqual({ dev111_code_chunks: 2, dev111_docstrings: 1 });
+    expect(manifest?.lastIndexedCommit).toBe('abc123');
+    expect(await readIndexSnapshotCollection(getIndexDir(developer), 'dev111_docstrings')).toEqual([point('c', 'src/a.ts')]);
+
+    const metadata = await readIndexMetadata(developer);
+    expect(metadata).toMatchObject({ ...identity, lastIndexedCommit: 'abc123', worktree: { path: developer, branch: 'feature' } });
+    expect(metadata?.importedFrom).toMatchObject({ archive: archiveFile, sourceCommit: 'abc123', sourcePath: builder });
+
+    // A later sync keeps the provenance
+    await writeIndexMetadata(developer, identity, 'def456');
+    expect((await readIndexMetadata(developer))?.importedFrom?.sourceCommit).toBe('abc123');
+  });
+
+  it('should warn when the paths differ between machines', async () => {
+    await writeIndexSnapshot(getIndexDir(builder), identity, new Map([
+      ['code_chunks', [point('a', 'src/a.ts'), point('b', '/home/ci/other/b.ts')]]
+    ]), 'abc123');
+    await exportIndexArchive(builder, archiveFile);
+    const archive = await readIndexArchive(archiveFile);
+
+    expect(findIndexArchivePathWarnings(archive, builder)).toEqual([
+      '1 vector references absolute paths outside this repository'
+    ]);
+    expect(findIndexArchivePathWarnings(archive, developer)[0]).toBe(`Index was built in ${builder}; this repository is at ${developer}`);
+  });
+
+  it('should reject files that are not complete archives', async () => {
+    await fs.writeFile(path.join(tmp, 'notes.txt'), 'hello\n');
+    await expect(readIndexArchive(path.join(tmp, 'notes.txt'))).rejects.toThrow(/not an index archive/);
+
+    await exportIndexArchive(builder, archiveFile);
+    const { gunzipSync } = await import('zlib');
+    const lines = gunzipSync(await fs.readFile(archiveFile)).toString('utf-8').trim().split('\n');
+    await fs.writeFile(archiveFile, lines.slice(0, -1).join('\n') + '\n');
+    await expect(readIndexArchive(archiveFile)).rejects.toThrow(/incomplete/);
+  });
+
+  it('should report a corrupt line and a cut-off gzip stream', async () => {
+    await exportIndexArchive(builder, archiveFile);
+    const { gunzipSync, gzipSync } = await import('zlib');
+    const lines = gunzipSync(await fs.readFile(archiveFile)).toString('utf-8').trim().split('\n');
+    await fs.writeFile(archiveFile, gzipSync([lines[0], '{"collection":', ...lines.slice(1)].join('\n') + '\n'));
+    await expect(readIndexArchive(archiveFile)).rejects.toThrow(/corrupt \(line 2\)/);
+
+    await exportIndexArchive(builder, archiveFile);
+    const gzipped = await fs.readFile(archiveFile);
+    await fs.writeFile(archiveFile, gzipped.subarray(0, gzipped.length - 12));
+    await expect(readIndexArchive(archiveFile)).rejects.toThrow(/not an index archive/);
+  });
+});
@@ -0,0 +1,159 @@
+/**
+ * Index Verification Tests
+ * Tests for detecting and repairing corrupt or stale entries in .cv/index
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  verifyIndex,
+  repairIndex,
+  writeIndexSnapshot,
+  writeIndexMetadata,
+  readIndexMetadata,
+  readIndexSnapshotManifest,
+  readIndexSnapshotCollection,
+  getIndexDir,
+  getIndexCollectionFile,
+  getIndexManifestFile,
+  VectorManager
+} from '@cv-git/core';
+
+const identity = { provider: 'ollama', model: 'nomic-embed-text', dimensions: 3 };
+
+function point(id: string, file: string, vector: number[] = [0.1, 0.2, 0.3]) {
+  return { id, vector, payload: { file, text: id } };
+}
+
+describe('Index verification', () => {
+  let repoRoot: string;
+  let indexDir: string;
+
+  beforeEach(async () => {
+    repoRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-index-verify-test-'));
+    indexDir = getIndexDir(repoRoot);
+    await fs.writeFile(path.join(repoRoot, 'kept.ts'), 'export const a = 1;\n');
+    await writeIndexMetadata(repoRoot, identity, 'abc123');
+  });
+
+  afterEach(async () => {
+    await fs.rm(repoRoot, { recursive: true, force: true });
+  });
+
+  it('should find no issues in a healthy index', async () => {
+    await writeIndexSnapshot(indexDir, identity, new Map([['code', [point('a', 'kept.ts')]]]), 'abc123');
+
+    const verification = await verifyIndex(repoRoot);
+    expect(verification.persisted).toBe(true);
+    expect(verification.vectors).toBe(1);
+    expect(verification.issues).toEqual([]);
+  });
+
+  it('should report unreadable entries, wrong dimensions and deleted files', async () => {
+    await writeIndexSnapshot(indexDir, identity, new Map([['code', [
+      point('a', 'kept.ts'),
+      point('b', 'deleted.ts'),
+      point('c', 'kept.ts', [0.1, 0.2])
+    ]]]), 'abc123');
+    await fs.appendFile(getIndexCollectionFile(indexDir, 'code'), '{"id":"d","vector":[0.1,');
+
+    const types = (await verifyIndex(repoRoot)).issues.map(issue => issue.type);
+    expect(types).toEqual(['unreadable_entry', 'dimension_mismatch', 'dangling_vector', 'count_mismatch']);
+
+    const dangling = (await verifyIndex(repoRoot)).issues.find(issue => issue.type === 'dangling_vector');
+    expect(dangling?.files).toEqual(['deleted.ts']);
+    expect(dangling?.repairable).toBe(true);
+  });
+
+  it('should prune bad vectors and keep the rest', async () => {
+    await writeIndexSnapshot(indexDir, identity, new Map([['code', [
+      point('a', 'kept.ts'),
+      point('b', 'deleted.ts')
+    ]]]), 'abc123');
+    await fs.appendFile(getIndexCollectionFile(indexDir, 'code'), 'not json\n');
+
+    const repair = await repairIndex(repoRoot);
+    expect(repair.pruned).toBe(2);
+    expect(repair.reindexReason).toBeUndefined();
+
+    expect((await readIndexSnapshotCollection(indexDir, 'code')).map(p => p.id)).toEqual(['a']);
+    expect((await readIndexSnapshotManifest(indexDir))?.lastIndexedCommit).toBe('abc123');
+    expect((await verifyIndex(repoRoot)).issues).toEqual([]);
+  });
+
+  it('should mark the index for a rebuild when its schema cannot be read', async () => {
+    await writeIndexSnapshot(indexDir, identity, new Map([['code', [point('a', 'kept.ts')]]]), 'abc123');
+    const manifestFile = getIndexManifestFile(indexDir);
+    const manifest = JSON.parse(await fs.readFile(manifestFile, 'utf-8'));
+    await fs.writeFile(manifestFile, JSON.stringify({ ...manifest, schemaVersion: 99 }));
+
+    const issues = (await verifyIndex(repoRoot)).issues;
+    expect(issues.map(issue => issue.type)).toEqual(['schema_mismatch']);
+    expect(issues[0].repairable).toBe(false);
+
+    const repair = await repairIndex(repoRoot);
+    expect(repair.reindexReason).toContain('v99');
+    expect((await verifyIndex(repoRoot)).persisted).toBe(false);
+
+    const metadata = await readIndexMetadata(repoRoot);
+    expect(metadata?.lastIndexedCommit).toBeUndefined();
+    expect(metadata?.reindexReason).toContain('v99');
+  });
+
+  it('should not prune a collection whose vectors are all the wrong size', async () => {
+    await writeIndexSnapshot(indexDir, identity, new Map([['code', [point('a', 'kept.ts', [0.1, 0.2])]]]), 'abc123');
+
+    const [issue] = (await verifyIndex(repoRoot)).issues;
+    expect(issue.type).toBe('dimension_mismatch');
+    expect(issue.repairable).toBe(false);
+  });
+});
+
+describe('VectorManager.reloadIndex', () => {
+  let repoRoot: string;
+  let vector: VectorManager;
+
+  const chunk = (file: string) => ({
+    id: `${file}:1:abcd`,
+    vector: [1, 0, 0, 0],
+    payload: { id: `${file}:1:abcd`, file, language: 'typescript', startLine: 1, endLine: 5, text: 'export const x = 1;' }
+  });
+
+  beforeEach(async () => {
+    repoRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-index-reload-test-'));
+    await fs.writeFile(path.join(repoRoot, 'kept.ts'), 'export const a = 1;\n');
+    vi.stubGlobal('fetch', vi.fn(async () =>
+      new Response(JSON.stringify({ embeddings: [{ values: [1, 0, 0, 0] }] }), { status: 200 })));
+    vector = new VectorManager({ url: '', backend: 'memory', geminiApiKey: 'test-key', vectorSize: 4, enableCache: false });
+    await vector.connect();
+  });
+
+  afterEach(async () => {
+    vi.unstubAllGlobals();
+    await vector.close();
+    await fs.rm(repoRoot, { recursive: true, force: true });
+  });
+
+  it('should drop the vectors --fix pruned from the snapshot from the store', async () => {
+    const indexDir = getIndexDir(repoRoot);
+    await vector.upsertBatch(vector.getCollectionNames().codeChunks, [chunk('kept.ts'), chunk('deleted.ts')]);
+    await vector.saveIndex(indexDir);
+
+    expect((await repairIndex(repoRoot)).pruned).toBe(1);
+    expect(await vector.reloadIndex(indexDir)).toBe(1);
+
+    expect(await vector.getFileChunks(['deleted.ts'])).toEqual([]);
+    expect(await vector.getFileChunks(['kept.ts'])).toHaveLength(1);
+  });
+
+  it('should leave the store alone when the snapshot was built with another model', async () => {
+    const indexDir = getIndexDir(repoRoot);
+    await vector.upsertBatch(vector.getCollectionNames().codeChunks, [chunk('deleted.ts')]);
+    await writeIndexSnapshot(indexDir, identity, new Map([[vector.getCollectionNames().codeChunks, []]]));
+
+    expect(await vector.reloadIndex(indexDir)).toBeNull();
+    expect(await vector.getFileChunks(['deleted.ts'])).toHaveLength(1);
+  });
+});
@@ -0,0 +1,91 @@
+/**
+ * Inline Citation Tests
+ * Tests checking cv explain's [n] markers against the sections given to the model
+ */
+
+import { describe, it, expect } from 'vitest';
+import { numberSources, resolveInlineCitations, EXPLAIN_PROMPT_CHUNKS, createAIManager } from '@cv-git/core';
+import { CodeChunkPayload, Context, VectorSearchResult } from '@cv-git/shared';
+
+function chunk(file: string, startLine: number, endLine: number, symbolName?: string): VectorSearchResult<CodeChunkPayload> {
+  return {
+    id: `${file}:${startLine}`,
+    score: 0.8,
+    payload: {
+      id: `${file}:${startLine}`,
+      file,
+      language: 'typescript',
+      symbolName,
+      startLine,
+      endLine,
+      text: '',
+      imports: [],
+      lastModified: 0
+    }
+  };
+}
+
+const sources = numberSources([
+  chunk('src/billing/charge.ts', 10, 40, 'charge'),
+  chunk('src/billing/retry.ts', 5, 25, 'withRetry')
+]);
+
+describe('numberSources', () => {
+  it('should number the sections the explain prompt includes from 1', () => {
+    expect(sources.map(s => `${s.index} ${s.file}`)).toEqual(['1 src/billing/charge.ts', '2 src/billing/retry.ts']);
+
+    const many = Array.from({ length: EXPLAIN_PROMPT_CHUNKS + 2 }, (_, i) => chunk(`f${i}.ts`, 1, 2));
+    expect(numberSources(many)).toHaveLength(EXPLAIN_PROMPT_CHUNKS);
+  });
+});
+
+describe('resolveInlineCitations', () => {
+  it('should map each cited source to the offsets of its markers', () => {
+    const answer = 'charge() retries failed payments [1][2]. Retries back off [2].';
+    const { text, citations, dropped } = resolveInlineCitations(answer, sources);
+
+    expect(text).toBe(answer);
+    expect(dropped).toEqual([]);
+    expect(citations.map(c => [c.index, c.offsets.map(o => text.slice(o.start, o.end))])).toEqual([
+      [1, ['[1]']],
+      [2, ['[2]', '[2]']]
+    ]);
+  });
+
+  it('should drop markers for sources that were never given', () => {
+    const { text, citations, dropped } = resolveInlineCitations('It logs each attempt [4]. See [1, 7].', sources);
+
+    expect(text).toBe('It logs each attempt. See [1].');
+    expect(dropped).toEqual([4, 7]);
+    expect(citations.map(c => c.index)).toEqual([1]);
+    expect(text.slice(citations[0].offsets[0].start, citations[0].offsets[0].end)).toBe('[1]');
+  });
+
+  it('should leave brackets in code and links alone', () => {
+    const answer = 'Use `attempts[3]` and [docs](https://example.com) or [9](https://example.com).\n```ts\nqueue[5]\n```';
+    expect(resolveInlineCitations(answer, sources)).toEqual({ text: answer, citations: [], dropped: [] });
+  });
+});
+
+describe('AIManager.explainPromptChunks', () => {
+  const many = Array.from({ length: EXPLAIN_PROMPT_CHUNKS + 2 }, (_, i) => chunk(`f${i}.ts`, 1, 2));
+  const context: Context = { chunks: many, symbols: [], files: [] };
+
+  it('should list the numbered sections the prompt carries', () => {
+    const ai = createAIManager({ provider: 'anthropic', model: 'claude-sonnet-4', apiKey: 'sk-ant-test' });
+    expect(ai.explainPromptChunks(context)).toEqual(many.slice(0, EXPLAIN_PROMPT_CHUNKS));
+    expect(ai.getModel()).toBe('claude-sonnet-4');
+  });
+
+  it('should follow what a raw custom prompt places', () => {
+    const raw = (template: string) => createAIManager({
+      provider: 'anthropic',
+      model: 'claude-sonnet-4',
+      apiKey: 'sk-ant-test',
+      systemPrompt: { template, raw: true, command: 'explain' }
+    });
+
+    expect(raw('Explain {{input}} using:\n{{code}}').explainPromptChunks(context)).toEqual(many);
+    expect(raw('Explain {{input}} briefly').explainPromptChunks(context)).toEqual([]);
+  });
+});
@@ -0,0 +1,94 @@
+/**
+ * Line History Tests
+ * Tests for blame parsing and commit grouping used by `cv why`
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  parseBlamePorcelain,
+  parseLineTarget,
+  groupBlameByCommit,
+  selectHunks,
+  formatLineRanges
+} from '@cv-git/core';
+
+const SHA_A = 'a'.repeat(40);
+const SHA_B = 'b'.repeat(40);
+
+const PORCELAIN = [
+  `${SHA_A} 10 12 2`,
+  'author Ada',
+  'author-mail <ada@example.com>',
+  'author-time 1700000000',
+  'summary Retry on 429',
+  'filename src/http.ts',
+  '\tfor (let attempt = 0; attempt < 3; attempt++) {',
+  `${SHA_A} 11 13`,
+  '\t  await sleep(backoff(attempt));',
+  `${SHA_B} 4 14 1`,
+  'author Grace',
+  'author-mail <grace@example.com>',
+  'author-time 1600000000',
+  'summary Initial client',
+  'filename src/client.ts',
+  '\t}',
+  ''
+].join('\n');
+
+describe('parseBlamePorcelain', () => {
+  it('reuses commit headers for repeated commits', () => {
+    const lines = parseBlamePorcelain(PORCELAIN);
+    expect(lines).toHaveLength(3);
+    expect(lines[1]).toMatchObject({ line: 13, originalLine: 11, author: 'Ada', summary: 'Retry on 429', originalFile: 'src/http.ts' });
+    expect(lines[2]).toMatchObject({ line: 14, sha: SHA_B, authorEmail: 'grace@example.com', date: 1600000000000, content: '}' });
+  });
+});
+
+describe('groupBlameByCommit', () => {
+  it('puts the commit behind most lines first', () => {
+    const commits = groupBlameByCommit(parseBlamePorcelain(PORCELAIN));
+    expect(commits.map(c => c.sha)).toEqual([SHA_A, SHA_B]);
+    expect(commits[0].lines).toEqual([12, 13]);
+    expect(commits[0].originalLines).toEqual([10, 11]);
+  });
+});
+
+describe('parseLineTarget', () => {
+  it('parses a single line and a range', () => {
+    expect(parseLineTarget('src/a.ts:42')).toEqual({ file: 'src/a.ts', startLine: 42, endLine: 42 });
+    expect(parseLineTarget('src/a.ts:10-20')).toEqual({ file: 'src/a.ts', startLine: 10, endLine: 20 });
+  });
+
+  it('rejects missing or reversed lines', () => {
+    expect(() => parseLineTarget('src/a.ts')).toThrow();
+    expect(() => parseLineTarget('src/a.ts:9-3')).toThrow();
+  });
+});
+
+describe('selectHunks', () => {
+  it('keeps only hunks containing the lines', () => {
+    const diff = [
+      'diff --git a/src/http.ts b/src/http.ts',
+      '--- a/src/http.ts',
+      '+++ b/src/http.ts',
+      '@@ -1,2 +1,3 @@',
+      ' import x;',
+      '+import y;',
+      '@@ -8,2 +9,4 @@ function get() {',
+      '+  for (let attempt = 0; attempt < 3; attempt++) {',
+      '+    await sleep(backoff(attempt));',
+      ''
+    ].join('\n');
+
+    const selected = selectHunks(diff, [10, 11]);
+    expect(selected).toContain('@@ -8,2 +9,4 @@');
+    expect(selected).not.toContain('+import y;');
+    expect(selected.startsWith('diff --git')).toBe(true);
+  });
+});
+
+describe('formatLineRanges', () => {
+  it('collapses consecutive lines', () => {
+    expect(formatLineRanges([14, 12, 13, 20])).toBe('12-14, 20');
+  });
+});
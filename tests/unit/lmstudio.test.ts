@@ -0,0 +1,56 @@
+/**
+ * LM Studio Client Tests
+ * Tests that streaming requests forward the caller's abort signal without
+ * leaving a listener on it
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import { LMStudioClient } from '@cv-git/core';
+
+const sse = (...tokens: string[]) => [
+  ...tokens.map(token => `data: ${JSON.stringify({ choices: [{ delta: { content: token } }] })}\n\n`),
+  'data: [DONE]\n\n'
+].join('');
+
+afterEach(() => {
+  vi.unstubAllGlobals();
+});
+
+describe('LMStudioClient.chatStream', () => {
+  const client = new LMStudioClient({ baseUrl: 'http://localhost:1234/v1', model: 'qwen2.5-coder' });
+
+  it('should remove its abort listener once the stream completes', async () => {
+    vi.stubGlobal('fetch', vi.fn(async () => new Response(sse('Hel', 'lo'), { status: 200 })));
+    const signal = new AbortController().signal;
+    const add = vi.spyOn(signal, 'addEventListener');
+    const remove = vi.spyOn(signal, 'removeEventListener');
+
+    for (let i = 0; i < 3; i++) {
+      expect(await client.chatStream([{ role: 'user', content: 'hi' }], undefined, { signal })).toBe('Hello');
+    }
+
+    expect(add).toHaveBeenCalledTimes(3);
+    expect(remove.mock.calls.map(([, listener]) => listener)).toEqual(add.mock.calls.map(([, listener]) => listener));
+  });
+
+  it('should remove its abort listener when the request fails', async () => {
+    vi.stubGlobal('fetch', vi.fn(async () => new Response('model not loaded', { status: 500 })));
+    const signal = new AbortController().signal;
+    const remove = vi.spyOn(signal, 'removeEventListener');
+
+    await expect(client.chatStream([{ role: 'user', content: 'hi' }], undefined, { signal })).rejects.toThrow('LM Studio API error: 500');
+    expect(remove).toHaveBeenCalledTimes(1);
+  });
+
+  it('should abort the request when the caller aborts', async () => {
+    const controller = new AbortController();
+    vi.stubGlobal('fetch', vi.fn((_url: string, init: RequestInit) => new Promise((_, reject) => {
+      init.signal!.addEventListener('abort', () => reject(init.signal!.reason));
+    })));
+
+    const pending = client.chatStream([{ role: 'user', content: 'hi' }], undefined, { signal: controller.signal });
+    controller.abort();
+
+    await expect(pending).rejects.toMatchObject({ name: 'AbortError' });
+  });
+});
@@ -0,0 +1,97 @@
+/**
+ * Logging Tests
+ * Tests for log levels, --log-file output and API request logging
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  resolveLogLevel,
+  configureLogging,
+  createLogger,
+  providerOfUrl,
+  isRequestLoggingEnabled
+} from '@cv-git/core';
+
+describe('resolveLogLevel', () => {
+  it('should prefer the flag, then CV_LOG, then CV_DEBUG', () => {
+    expect(resolveLogLevel('info', { CV_LOG: 'error', CV_DEBUG: '1' })).toBe('info');
+    expect(resolveLogLevel(undefined, { CV_LOG: 'ERROR', CV_DEBUG: '1' })).toBe('error');
+    expect(resolveLogLevel(undefined, { CV_DEBUG: '1' })).toBe('debug');
+    expect(resolveLogLevel(undefined, {})).toBe('warn');
+  });
+
+  it('should reject unknown levels', () => {
+    expect(() => resolveLogLevel('verbose', {})).toThrow('Invalid log level "verbose"');
+  });
+});
+
+describe('providerOfUrl', () => {
+  it('should name API hosts and local services by provider', () => {
+    expect(providerOfUrl(new URL('https://openrouter.ai/api/v1/embeddings'))).toBe('openrouter');
+    expect(providerOfUrl(new URL('https://acme.openai.azure.com/openai/deployments/x'))).toBe('azure');
+    expect(providerOfUrl(new URL('https://api.openai.com/v1/embeddings'))).toBe('openai');
+    expect(providerOfUrl(new URL('http://localhost:11434/api/embeddings'))).toBe('ollama');
+    expect(providerOfUrl(new URL('https://llm.internal:8443/v1'))).toBe('llm.internal:8443');
+  });
+});
+
+describe('configureLogging', () => {
+  let dir: string;
+  let logFile: string;
+  const originalFetch = globalThis.fetch;
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-logging-test-'));
+    logFile = path.join(dir, 'cv.log');
+  });
+
+  afterEach(async () => {
+    configureLogging({ level: 'warn' });
+    globalThis.fetch = originalFetch;
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  const readEntries = async () => (await fs.readFile(logFile, 'utf-8')).trim().split('\n').map(line => JSON.parse(line));
+
+  it('should write entries at or above the level to the log file', async () => {
+    configureLogging({ level: 'info', file: logFile });
+    const log = createLogger('test');
+
+    log.debug('hidden');
+    log.info('shown', { files: 2 });
+    log.error('also shown');
+
+    const entries = await readEntries();
+    expect(entries.map(e => e.message)).toEqual(['shown', 'also shown']);
+    expect(entries[0]).toMatchObject({ level: 'info', component: 'test', data: { files: 2 } });
+  });
+
+  it('should log API requests at debug without their query string', async () => {
+    globalThis.fetch = (async () => new Response('{}', { status: 429 })) as typeof fetch;
+    configureLogging({ level: 'debug', file: logFile });
+    expect(isRequestLoggingEnabled()).toBe(true);
+
+    await fetch('https://generativelanguage.googleapis.com/v1beta/models/m:embedContent?key=secret', {
+      method: 'POST',
+      body: JSON.stringify({ model: 'text-embedding-004' })
+    });
+
+    const [entry] = await readEntries();
+    expect(entry.component).toBe('api');
+    expect(entry.data).toMatchObject({
+      provider: 'gemini',
+      model: 'text-embedding-004',
+      method: 'POST',
+      endpoint: 'https://generativelanguage.googleapis.com/v1beta/models/m:embedContent',
+      status: 429
+    });
+    expect(typeof entry.duration).toBe('number');
+    expect(JSON.stringify(entry)).not.toContain('secret');
+
+    configureLogging({ level: 'warn' });
+    expect(isRequestLoggingEnabled()).toBe(false);
+  });
+});
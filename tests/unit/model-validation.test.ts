@@ -0,0 +1,44 @@
+/**
+ * Model Validation Tests
+ * Tests for checking --model and models.<command> against the provider
+ */
+
+import { describe, it, expect } from 'vitest';
+import { isKnownModel, validateModel, getKnownModels, ConfigError } from '@cv-git/core';
+
+describe('isKnownModel', () => {
+  it('accepts Anthropic aliases and dated snapshots', () => {
+    expect(isKnownModel('anthropic', 'claude-sonnet-4-5')).toBe(true);
+    expect(isKnownModel('anthropic', 'claude-3-5-sonnet-20241022')).toBe(true);
+    expect(isKnownModel('anthropic', 'claude-sonet-4-5')).toBe(false);
+    expect(isKnownModel('anthropic', 'gpt-4o')).toBe(false);
+  });
+
+  it('accepts Gemini models and versioned variants', () => {
+    expect(isKnownModel('gemini', 'gemini-1.5-pro-002')).toBe(true);
+    expect(isKnownModel('gemini', 'models/gemini-2.5-flash')).toBe(true);
+    expect(isKnownModel('gemini', 'claude-sonnet-4-5')).toBe(false);
+  });
+
+  it('accepts OpenRouter aliases and full vendor/model IDs', () => {
+    expect(isKnownModel('openrouter', 'gpt-4o')).toBe(true);
+    expect(isKnownModel('openrouter', 'qwen/qwen-2.5-coder-32b-instruct')).toBe(true);
+    expect(isKnownModel('openrouter', 'gpt4o')).toBe(false);
+  });
+
+  it('leaves Azure deployments and Ollama tags to the server', () => {
+    expect(isKnownModel('azure', 'my-gpt4o-deployment')).toBe(true);
+    expect(isKnownModel('ollama', 'qwen2.5-coder:14b')).toBe(true);
+  });
+});
+
+describe('validateModel', () => {
+  it('returns known models unchanged', () => {
+    expect(validateModel('anthropic', 'claude-opus-4')).toBe('claude-opus-4');
+  });
+
+  it('lists the known models for an unknown name', () => {
+    expect(() => validateModel('gemini', 'gemini-ultra')).toThrow(ConfigError);
+    expect(() => validateModel('gemini', 'gemini-ultra')).toThrow(getKnownModels('gemini').join(', '));
+  });
+});
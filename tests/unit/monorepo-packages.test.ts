@@ -0,0 +1,64 @@
+/**
+ * Monorepo Package Tests
+ * Tests for finding the package a file belongs to and the packages a
+ * --package scope covers
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { PackageResolver, findPackages, resolvePackageScope, ROOT_PACKAGE } from '@cv-git/core';
+
+describe('Monorepo packages', () => {
+  let repo: string;
+
+  const write = async (file: string, content = '') => {
+    await fs.mkdir(path.join(repo, path.dirname(file)), { recursive: true });
+    await fs.writeFile(path.join(repo, file), content);
+  };
+
+  beforeEach(async () => {
+    repo = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-monorepo-packages-test-'));
+    await write('package.json', '{}');
+    await write('packages/core/package.json', '{}');
+    await write('packages/core/src/index.ts');
+    await write('packages/cli/package.json', '{}');
+    await write('packages/cli/src/index.ts');
+    await write('services/api/go.mod', 'module api');
+    await write('services/api/internal/server.go');
+    await write('services/api/node_modules/dep/package.json', '{}');
+    await write('scripts/release.sh');
+  });
+
+  afterEach(async () => {
+    await fs.rm(repo, { recursive: true, force: true });
+  });
+
+  it('should record the nearest directory with a manifest', () => {
+    const resolver = new PackageResolver(repo);
+    expect(resolver.packageOf('packages/core/src/index.ts')).toBe('packages/core');
+    expect(resolver.packageOf('packages/cli/src/index.ts')).toBe('packages/cli');
+    expect(resolver.packageOf('services/api/internal/server.go')).toBe('services/api');
+    expect(resolver.packageOf('scripts/release.sh')).toBe(ROOT_PACKAGE);
+    expect(resolver.packageOf('README.md')).toBe(ROOT_PACKAGE);
+  });
+
+  it('should find packages without descending into dependencies', () => {
+    expect(findPackages(repo)).toEqual(['.', 'packages/cli', 'packages/core', 'services/api']);
+    expect(findPackages(repo, 'packages')).toEqual(['packages/cli', 'packages/core']);
+  });
+
+  it('should scope --package to the package and those nested in it', () => {
+    expect(resolvePackageScope(repo, 'packages/core', repo)).toEqual(['packages/core']);
+    expect(resolvePackageScope(repo, 'packages', repo)).toEqual(['packages/cli', 'packages/core']);
+    expect(resolvePackageScope(repo, '..', path.join(repo, 'services/api/internal'))).toEqual(['services/api']);
+  });
+
+  it('should reject directories that are not packages or are outside the repository', () => {
+    expect(() => resolvePackageScope(repo, 'packages/core/src', repo)).toThrow('belongs to the package in packages/core');
+    expect(() => resolvePackageScope(repo, 'scripts', repo)).toThrow('belongs to the root package');
+    expect(() => resolvePackageScope(repo, 'missing', repo)).toThrow('is not a directory');
+    expect(() => resolvePackageScope(repo, '..', repo)).toThrow('is outside the repository');
+  });
+});
@@ -0,0 +1,61 @@
+/**
+ * Named Prompt Tests
+ * Tests for .cv/prompts: listing templates, loading one by name, and the
+ * {{query}} and {{files}} values a command sets
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { listNamedPrompts, loadNamedPrompt, getPromptsDir, applySystemPrompt } from '@cv-git/core';
+
+describe('Named prompts', () => {
+  let repo: string;
+
+  beforeEach(async () => {
+    repo = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-named-prompts-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(repo, { recursive: true, force: true });
+  });
+
+  it('should list nothing without a .cv/prompts directory', async () => {
+    expect(await listNamedPrompts(repo)).toEqual([]);
+  });
+
+  it('should list prompts by name with their first line as description', async () => {
+    const dir = getPromptsDir(repo);
+    await fs.mkdir(dir, { recursive: true });
+    await fs.writeFile(path.join(dir, 'security.md'), '\n# Security review\nLook for injection in {{files}}.');
+    await fs.writeFile(path.join(dir, 'api.md'), 'Check {{query}} for breaking API changes.');
+    await fs.writeFile(path.join(dir, 'notes.txt'), 'not a prompt');
+
+    const prompts = await listNamedPrompts(repo);
+    expect(prompts.map(p => [p.name, p.description])).toEqual([
+      ['api', 'Check {{query}} for breaking API changes.'],
+      ['security', 'Security review']
+    ]);
+    expect(await loadNamedPrompt(repo, 'api')).toBe('Check {{query}} for breaking API changes.');
+  });
+
+  it('should name the available prompts when one is missing, and refuse paths', async () => {
+    await fs.mkdir(getPromptsDir(repo), { recursive: true });
+    await fs.writeFile(path.join(getPromptsDir(repo), 'security.md'), 'Be paranoid.');
+
+    await expect(loadNamedPrompt(repo, 'style')).rejects.toThrow('available: security');
+    await expect(loadNamedPrompt(repo, '../config')).rejects.toThrow('Invalid prompt name');
+  });
+
+  it('should fill {{query}} and {{files}} from the values the command sets', () => {
+    const options = {
+      template: 'Review {{query}} ({{files}}) in {{repo}}',
+      command: 'review',
+      repo: 'acme',
+      variables: { query: 'staged changes', files: 'src/a.ts, src/b.ts' }
+    };
+    expect(applySystemPrompt(options, 'BUILT-IN', 'diff').system).toBe('Review staged changes (src/a.ts, src/b.ts) in acme');
+    expect(applySystemPrompt({ ...options, variables: undefined }, 'BUILT-IN').system).toBe('Review  () in acme');
+  });
+});
@@ -0,0 +1,48 @@
+/**
+ * OpenAI-Compatible Gateway Tests
+ * Tests for model names passed through verbatim and the setup connectivity check
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import { OpenAICompatibleClient, OpenRouterClient, listOpenAICompatibleModels } from '@cv-git/core';
+
+function mockFetch(body: unknown, status = 200) {
+  const fetchMock = vi.fn().mockImplementation(async () => new Response(JSON.stringify(body), { status }));
+  vi.stubGlobal('fetch', fetchMock);
+  return fetchMock;
+}
+
+afterEach(() => {
+  vi.unstubAllGlobals();
+});
+
+describe('OpenAICompatibleClient', () => {
+  it('should keep the model name as given, while the OpenRouter preset resolves aliases', () => {
+    const gateway = new OpenAICompatibleClient({ baseUrl: 'http://localhost:4000/v1/', model: 'claude-sonnet-4' });
+    expect(gateway.getModel()).toBe('claude-sonnet-4');
+    expect(gateway.getProvider()).toBe('openai-compatible');
+
+    const openrouter = new OpenRouterClient({ apiKey: 'sk-or-test', model: 'claude-sonnet-4' });
+    expect(openrouter.getModel()).toBe('anthropic/claude-sonnet-4');
+    expect(openrouter.getProvider()).toBe('openrouter');
+  });
+});
+
+describe('listOpenAICompatibleModels', () => {
+  it('should list model IDs from {baseUrl}/models with the key as a bearer token', async () => {
+    const fetchMock = mockFetch({ object: 'list', data: [{ id: 'llama-3.1-70b' }, { id: 'bge-m3' }] });
+
+    expect(await listOpenAICompatibleModels('http://localhost:4000/v1/', 'sk-litellm')).toEqual(['llama-3.1-70b', 'bge-m3']);
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe('http://localhost:4000/v1/models');
+    expect(init.headers.Authorization).toBe('Bearer sk-litellm');
+  });
+
+  it('should reject a refused key and a URL that is not an OpenAI API', async () => {
+    mockFetch({ error: 'unauthorized' }, 401);
+    await expect(listOpenAICompatibleModels('https://api.groq.com/openai/v1', 'bad')).rejects.toThrow('HTTP 401 (check the API key)');
+
+    mockFetch({ status: 'ok' });
+    await expect(listOpenAICompatibleModels('http://localhost:8000')).rejects.toThrow('did not return an OpenAI-style model list');
+  });
+});
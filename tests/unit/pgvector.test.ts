@@ -0,0 +1,122 @@
+/**
+ * pgvector Backend Tests
+ * Tests for the SQL built by the pgvector store and its config wiring
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  buildPgFilter,
+  buildPgVectorMigration,
+  quotePgTable,
+  toPgVectorLiteral,
+  getVectorBackendOptions
+} from '@cv-git/core';
+
+describe('buildPgFilter', () => {
+  it('should translate must conditions into parameterized payload checks', () => {
+    const { sql, params } = buildPgFilter({
+      must: [
+        { key: 'language', match: { value: 'typescript' } },
+        { key: 'level', match: { value: 2 } }
+      ]
+    }, 3);
+
+    expect(sql).toBe("payload->>'language' = $4 AND payload->>'level' = $5");
+    expect(params).toEqual(['typescript', '2']);
+  });
+
+  it('should OR should conditions and support text and any matches', () => {
+    const { sql, params } = buildPgFilter({
+      should: [
+        { key: 'file', match: { value: 'a.ts' } },
+        { key: 'path', match: { text: 'src/' } },
+        { key: 'kind', match: { any: ['function', 'method'] } }
+      ]
+    });
+
+    expect(sql).toBe("(payload->>'file' = $1 OR payload->>'path' LIKE '%' || $2 || '%' OR payload->>'kind' = ANY($3))");
+    expect(params).toEqual(['a.ts', 'src/', ['function', 'method']]);
+  });
+
+  it('should let rows without the field through must_not, as Qdrant does', () => {
+    const { sql, params } = buildPgFilter({ must_not: [{ key: 'isTest', match: { value: true } }] });
+
+    expect(sql).toBe("NOT COALESCE(payload->>'isTest' = $1, FALSE)");
+    expect(params).toEqual(['true']);
+  });
+
+  it('should match everything without a filter', () => {
+    expect(buildPgFilter(undefined)).toEqual({ sql: 'TRUE', params: [] });
+  });
+
+  it('should reject conditions it cannot translate', () => {
+    expect(() => buildPgFilter({ must: [{ key: 'score', range: { gt: 1 } }] })).toThrow(/Unsupported filter/);
+    expect(() => buildPgFilter({ must: [{ key: "file' OR 1=1 --", match: { value: 'x' } }] })).toThrow(/Unsupported filter key/);
+  });
+});
+
+describe('buildPgVectorMigration', () => {
+  it('should create the table keyed by collection and chunk ID with an hnsw index', () => {
+    const statements = buildPgVectorMigration({ dimensions: 768 });
+
+    expect(statements[0]).toBe('CREATE EXTENSION IF NOT EXISTS vector');
+    expect(statements[1]).toContain('CREATE TABLE IF NOT EXISTS "cv_vectors"');
+    expect(statements[1]).toContain('embedding vector(768) NOT NULL');
+    expect(statements[1]).toContain('PRIMARY KEY (collection, id)');
+    expect(statements[2]).toContain('USING hnsw (embedding vector_cosine_ops)');
+  });
+
+  it('should build an ivfflat index with the requested lists', () => {
+    const statements = buildPgVectorMigration({ table: 'search.vectors', dimensions: 1536, indexType: 'ivfflat', lists: 50 });
+
+    expect(statements[1]).toContain('"search"."vectors"');
+    expect(statements[2]).toContain('"vectors_embedding_idx"');
+    expect(statements[2]).toContain('USING ivfflat (embedding vector_cosine_ops) WITH (lists = 50)');
+  });
+
+  it('should turn numeric point IDs of older tables into text', () => {
+    const statements = buildPgVectorMigration({ table: 'search.vectors', dimensions: 768 });
+
+    expect(statements[1]).toContain('point_id TEXT NOT NULL');
+    expect(statements[4]).toContain("table_schema = 'search' AND table_name = 'vectors'");
+    expect(statements[4]).toContain('ALTER TABLE "search"."vectors" ALTER COLUMN point_id TYPE TEXT');
+  });
+
+  it('should reject invalid dimensions', () => {
+    expect(() => buildPgVectorMigration({ dimensions: 0 })).toThrow(/Invalid pgvector dimensions/);
+  });
+});
+
+describe('quotePgTable', () => {
+  it('should refuse names that are not plain identifiers', () => {
+    expect(quotePgTable('public.cv_vectors')).toBe('"public"."cv_vectors"');
+    expect(() => quotePgTable('vectors; DROP TABLE users')).toThrow(/Invalid pgvector table name/);
+  });
+});
+
+describe('toPgVectorLiteral', () => {
+  it('should format vectors as pgvector literals', () => {
+    expect(toPgVectorLiteral([0.5, -1, 2])).toBe('[0.5,-1,2]');
+  });
+});
+
+describe('getVectorBackendOptions', () => {
+  const base = { url: 'http://localhost:6333', embedded: false, collections: {} as any };
+
+  it('should leave Qdrant as the default backend', () => {
+    expect(getVectorBackendOptions({ ...base, provider: 'qdrant' })).toEqual({});
+  });
+
+  it('should pass pgvector settings through', () => {
+    const options = getVectorBackendOptions({
+      ...base,
+      provider: 'pgvector',
+      pgvector: { connectionString: 'postgres://localhost/cv', table: 'vectors' }
+    });
+
+    expect(options).toEqual({
+      backend: 'pgvector',
+      pgvector: { connectionString: 'postgres://localhost/cv', table: 'vectors' }
+    });
+  });
+});
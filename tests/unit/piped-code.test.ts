@@ -0,0 +1,52 @@
+/**
+ * Piped Code Tests
+ * Tests for the context and diff built from code read on stdin
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  STDIN_FILE,
+  resolveLanguageHint,
+  buildPipedCodeContext,
+  buildPipedCodeDiff,
+  parseDiffHunks
+} from '@cv-git/core';
+
+const code = 'package main\n\nfunc main() {\n\tprintln("hi")\n}\n';
+
+describe('resolveLanguageHint', () => {
+  it('accepts extensions and language names', () => {
+    expect(resolveLanguageHint('go')).toBe('go');
+    expect(resolveLanguageHint('.py')).toBe('python');
+    expect(resolveLanguageHint('TSX')).toBe('typescript');
+    expect(resolveLanguageHint('Elixir')).toBe('elixir');
+    expect(resolveLanguageHint(undefined)).toBe('');
+  });
+});
+
+describe('buildPipedCodeContext', () => {
+  it('holds the whole input as one chunk', () => {
+    const context = buildPipedCodeContext(code, 'go');
+
+    expect(context.chunks).toHaveLength(1);
+    expect(context.chunks[0].payload).toMatchObject({
+      file: STDIN_FILE,
+      language: 'go',
+      startLine: 1,
+      endLine: 5,
+      text: code.trimEnd()
+    });
+    expect(context.symbols).toEqual([]);
+  });
+});
+
+describe('buildPipedCodeDiff', () => {
+  it('adds every line as a new file', () => {
+    const [file] = parseDiffHunks(buildPipedCodeDiff(code));
+
+    expect(file.path).toBe(STDIN_FILE);
+    expect(file.type).toBe('create');
+    expect(file.hunks[0].lines.map(l => l.newLineNumber)).toEqual([1, 2, 3, 4, 5]);
+    expect(file.hunks[0].lines.every(l => l.type === 'add')).toBe(true);
+  });
+});
@@ -0,0 +1,79 @@
+/**
+ * Provider Fallback Tests
+ * Tests for config ai.providers / embedding.providers: which errors count
+ * as a provider being down, and moving down the chain when one is
+ */
+
+import { describe, it, expect } from 'vitest';
+import { tryProviders, ProviderServed } from '@cv-git/core';
+import { isProviderOutage, RequestTimeoutError, VectorError } from '@cv-git/shared';
+
+const statusError = (status: number) => Object.assign(new Error(`${status} status code`), { status });
+
+describe('isProviderOutage', () => {
+  it('should treat 5xx, timeouts and unreachable hosts as outages', () => {
+    expect(isProviderOutage(statusError(500))).toBe(true);
+    expect(isProviderOutage(statusError(503))).toBe(true);
+    expect(isProviderOutage(new RequestTimeoutError('Anthropic request', 120000))).toBe(true);
+    expect(isProviderOutage(Object.assign(new Error('connect ECONNREFUSED'), { code: 'ECONNREFUSED' }))).toBe(true);
+    expect(isProviderOutage(new TypeError('fetch failed'))).toBe(true);
+    // Wrapped by VectorManager
+    expect(isProviderOutage(new VectorError('Failed to generate embedding: 502', statusError(502)))).toBe(true);
+  });
+
+  it('should not treat rate limits or rejected requests as outages', () => {
+    expect(isProviderOutage(statusError(429))).toBe(false);
+    expect(isProviderOutage(statusError(400))).toBe(false);
+    expect(isProviderOutage(statusError(401))).toBe(false);
+    expect(isProviderOutage(new Error('Invalid model'))).toBe(false);
+  });
+});
+
+describe('tryProviders', () => {
+  const candidates = [
+    { provider: 'anthropic', model: 'claude-sonnet-4-5', client: 'a' },
+    { provider: 'gemini', model: 'gemini-2.0-flash', client: 'g' },
+    { provider: 'azure', model: 'gpt-4o', client: 'z' }
+  ];
+
+  it('should move down the chain while providers are down and report who served', async () => {
+    const served: ProviderServed[] = [];
+    const tried: string[] = [];
+
+    const result = await tryProviders(candidates, async candidate => {
+      tried.push(candidate.client);
+      if (candidate.provider === 'anthropic') throw statusError(529);
+      return `answer from ${candidate.provider}`;
+    }, { onServed: info => served.push(info) });
+
+    expect(result).toBe('answer from gemini');
+    expect(tried).toEqual(['a', 'g']);
+    expect(served).toHaveLength(1);
+    expect(served[0]).toMatchObject({ provider: 'gemini', model: 'gemini-2.0-flash' });
+    expect(served[0].failed.map(f => f.provider)).toEqual(['anthropic']);
+  });
+
+  it('should throw errors that are not outages without trying the next provider', async () => {
+    const tried: string[] = [];
+    await expect(tryProviders(candidates, async candidate => {
+      tried.push(candidate.provider);
+      throw statusError(400);
+    })).rejects.toThrow('400 status code');
+    expect(tried).toEqual(['anthropic']);
+  });
+
+  it('should throw the last error when every provider is down', async () => {
+    await expect(tryProviders(candidates, async candidate => {
+      throw Object.assign(new Error(`${candidate.provider} is down`), { status: 503 });
+    })).rejects.toThrow('azure is down');
+  });
+
+  it('should keep the error when canFallBack says no', async () => {
+    const tried: string[] = [];
+    await expect(tryProviders(candidates, async candidate => {
+      tried.push(candidate.provider);
+      throw statusError(500);
+    }, { canFallBack: () => false })).rejects.toThrow('500 status code');
+    expect(tried).toEqual(['anthropic']);
+  });
+});
@@ -0,0 +1,119 @@
+/**
+ * Refactor Tests
+ * Tests for splicing, secret restoration, and diffs used by `cv refactor`
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  applyRefactor,
+  createUnifiedDiff,
+  extractRefactoredCode,
+  restoreRedactedSecrets,
+  REDACTED_SECRET,
+  redactSecrets,
+  RefactorTarget
+} from '@cv-git/core';
+
+const FILE = [
+  'import { db } from "./db";',
+  '',
+  'export function load(id: string) {',
+  '  return db.get(id);',
+  '}',
+  '',
+  'export function save(id: string, value: unknown) {',
+  '  db.set(id, value);',
+  '}',
+  ''
+].join('\n');
+
+describe('applyRefactor', () => {
+  it('replaces only the symbol lines', () => {
+    const target: RefactorTarget = {
+      file: 'src/store.ts',
+      language: 'typescript',
+      content: FILE,
+      symbol: { name: 'load', kind: 'function', startLine: 3, endLine: 5 }
+    };
+
+    const result = applyRefactor(target, 'export async function load(id: string) {\n  return await db.get(id);\n}\n');
+
+    expect(result).toContain('export async function load');
+    expect(result).toContain('export function save(id: string, value: unknown) {');
+    expect(result.startsWith('import { db } from "./db";\n')).toBe(true);
+    expect(result.endsWith('}\n')).toBe(true);
+  });
+
+  it('keeps the trailing newline when replacing the whole file', () => {
+    const target: RefactorTarget = { file: 'a.ts', language: 'typescript', content: 'const a = 1;\n' };
+    expect(applyRefactor(target, 'const a = 2;')).toBe('const a = 2;\n');
+  });
+});
+
+describe('extractRefactoredCode', () => {
+  it('returns the body of the fenced block', () => {
+    const response = 'Here you go:\n```typescript\nconst x = `a`;\n```\nDone.';
+    expect(extractRefactoredCode(response)).toBe('const x = `a`;\n');
+  });
+
+  it('returns the response as is without a fence', () => {
+    expect(extractRefactoredCode('const x = 1;')).toBe('const x = 1;');
+  });
+});
+
+describe('restoreRedactedSecrets', () => {
+  const secrets = ['sk-live-123', 'tok-456'];
+
+  it('puts each secret back into its own numbered placeholder', () => {
+    const code = 'const apiKey = "<REDACTED_SECRET_1>";\nconst otherToken = "<REDACTED_SECRET_2>";\n';
+    expect(restoreRedactedSecrets(code, secrets))
+      .toBe('const apiKey = "sk-live-123";\nconst otherToken = "tok-456";\n');
+  });
+
+  it('follows the placeholders when the model reorders or joins the statements', () => {
+    const code = 'const tokens = ["<REDACTED_SECRET_2>", "<REDACTED_SECRET_1>"];\n';
+    expect(restoreRedactedSecrets(code, secrets)).toBe('const tokens = ["tok-456", "sk-live-123"];\n');
+  });
+
+  it('refuses when a placeholder is missing, repeated, unknown or un-numbered', () => {
+    expect(() => restoreRedactedSecrets('const a = "<REDACTED_SECRET_1>";\n', secrets)).toThrow(/_2> is missing.*--no-redact/);
+    expect(() => restoreRedactedSecrets('"<REDACTED_SECRET_1>" "<REDACTED_SECRET_1>" "<REDACTED_SECRET_2>"', secrets))
+      .toThrow('<REDACTED_SECRET_1> appears more than once');
+    expect(() => restoreRedactedSecrets('"<REDACTED_SECRET_1>" "<REDACTED_SECRET_2>" "<REDACTED_SECRET_3>"', secrets))
+      .toThrow('unknown placeholder <REDACTED_SECRET_3>');
+    expect(() => restoreRedactedSecrets(`"<REDACTED_SECRET_1>" "<REDACTED_SECRET_2>" "${REDACTED_SECRET}"`, secrets))
+      .toThrow(`${REDACTED_SECRET} has no number`);
+  });
+
+  it('restores adjacent placeholders without an empty secret', () => {
+    expect(restoreRedactedSecrets('<REDACTED_SECRET_1><REDACTED_SECRET_2>', secrets)).toBe('sk-live-123tok-456');
+  });
+
+  it('puts back the secrets indexed redaction masked', () => {
+    const original = 'const pair = "ghp_' + 'a'.repeat(36) + '" + "sk-' + 'b'.repeat(24) + '";\n';
+    const { text, secrets: masked } = redactSecrets(original, [], { indexed: true });
+
+    expect(text).toBe('const pair = "<REDACTED_SECRET_1>" + "<REDACTED_SECRET_2>";\n');
+    expect(restoreRedactedSecrets(text, masked!)).toBe(original);
+  });
+});
+
+describe('createUnifiedDiff', () => {
+  it('returns an empty string for identical files', () => {
+    expect(createUnifiedDiff('a.ts', FILE, FILE)).toBe('');
+  });
+
+  it('renders a single hunk with context', () => {
+    const after = FILE.replace('  return db.get(id);', '  return db.get(id) ?? null;');
+    const diff = createUnifiedDiff('src/store.ts', FILE, after);
+
+    expect(diff).toContain('--- a/src/store.ts\n+++ b/src/store.ts\n');
+    expect(diff).toContain('@@ -1,7 +1,7 @@');
+    expect(diff).toContain('-  return db.get(id);\n+  return db.get(id) ?? null;');
+  });
+
+  it('numbers an empty side from the line before it', () => {
+    const diff = createUnifiedDiff('a.ts', '', 'one\n');
+    expect(diff).toContain('@@ -0,0 +1,1 @@');
+  });
+});
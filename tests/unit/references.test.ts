@@ -0,0 +1,97 @@
+/**
+ * Symbol References Tests
+ * Tests for finding the call sites of a symbol in stored chunk metadata
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findReferences, stripCommentsAndStrings } from '@cv-git/core';
+
+const chunk = (file: string, language: string, startLine: number, text: string, symbolName?: string, symbolKind?: string): any => ({
+  id: `${file}:${startLine}`,
+  file,
+  language,
+  symbolName,
+  symbolKind,
+  startLine,
+  endLine: startLine + text.split('\n').length - 1,
+  text,
+  imports: [],
+  lastModified: 0
+});
+
+const chunks = [
+  chunk('auth/token.go', 'go', 10, [
+    'func generateToken(user string) (string, error) {',
+    '\treturn sign(user)',
+    '}'
+  ].join('\n'), 'generateToken', 'function'),
+  chunk('auth/auth.go', 'go', 20, [
+    'func (s *Service) Authenticate(user, pass string) (string, error) {',
+    '\t// generateToken is only called once the password checks out',
+    '\tif !s.check(user, pass) {',
+    '\t\treturn "", errors.New("generateToken: bad password")',
+    '\t}',
+    '\treturn generateToken(user)',
+    '}'
+  ].join('\n'), 'Authenticate', 'method'),
+  chunk('auth/auth.go', 'go', 30, 'var tokenFn = generateToken'),
+  chunk('web/token.ts', 'typescript', 1, 'export function refresh() { return generateToken(); }', 'refresh', 'function')
+];
+
+describe('findReferences', () => {
+  it('lists the callers of a function with the function each call is in', () => {
+    const { definitions, references } = findReferences(chunks, 'generateToken');
+
+    expect(definitions.map(def => `${def.file}:${def.startLine}`)).toEqual(['auth/token.go:10']);
+    expect(references).toEqual([{
+      file: 'auth/auth.go',
+      line: 25,
+      column: 9,
+      kind: 'call',
+      caller: { name: 'Authenticate', kind: 'method', startLine: 20, endLine: 26 },
+      text: 'return generateToken(user)'
+    }]);
+  });
+
+  it('skips comments and strings, and files in other languages', () => {
+    const { references } = findReferences(chunks, 'generateToken', { all: true });
+
+    expect(references.map(ref => [ref.file, ref.line, ref.kind, ref.caller?.name])).toEqual([
+      ['auth/auth.go', 25, 'call', 'Authenticate'],
+      ['auth/auth.go', 30, 'reference', undefined]
+    ]);
+  });
+
+  it('scans every language when the symbol has no indexed definition', () => {
+    const { definitions, references } = findReferences(chunks, 'sign');
+
+    expect(definitions).toEqual([]);
+    expect(references.map(ref => [ref.file, ref.caller?.name])).toEqual([['auth/token.go', 'generateToken']]);
+  });
+
+  it('does not count the definition or a method declared with the same name', () => {
+    const methods = [
+      chunk('a.ts', 'typescript', 1, 'function load() {}\nclass Store {\n  load() { return 1; }\n}', 'load', 'function'),
+      chunk('b.ts', 'typescript', 1, 'export function main() {\n  load();\n}', 'main', 'function')
+    ];
+    const { references } = findReferences(methods, 'load');
+
+    // The method declaration looks like a call; it is reported, but the definition line is not
+    expect(references.map(ref => `${ref.file}:${ref.line}`)).toEqual(['a.ts:3', 'b.ts:2']);
+  });
+});
+
+describe('stripCommentsAndStrings', () => {
+  it('blanks comments and string contents but keeps the columns', () => {
+    const { code } = stripCommentsAndStrings('call("a(b)") // call()', 'typescript');
+    expect(code).toBe('call("    ") ');
+    expect(stripCommentsAndStrings('run()  # run()', 'python').code).toBe('run()  ');
+  });
+
+  it('carries block comments across lines', () => {
+    const first = stripCommentsAndStrings('x(); /* start', 'go');
+    expect(first.inBlockComment).toBe(true);
+    const second = stripCommentsAndStrings('still */ y()', 'go', first.inBlockComment);
+    expect(second).toEqual({ code: '         y()', inBlockComment: false });
+  });
+});
@@ -0,0 +1,67 @@
+/**
+ * Repository Language Tests
+ * Tests for dominant-language detection used to steer code generation
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  detectRepoLanguages,
+  pickPromptLanguage,
+  normalizeLanguageName,
+  buildLanguageInstruction
+} from '@cv-git/core';
+
+describe('detectRepoLanguages', () => {
+  it('should count source files by extension and ignore other files', () => {
+    const languages = detectRepoLanguages(['main.go', 'lexer.go', 'README.md', 'go.mod', 'tools/gen.py']);
+
+    expect(languages.counts).toEqual({ Go: 2, Python: 1 });
+    expect(languages.primary).toEqual(['Go', 'Python']);
+  });
+
+  it('should tell JavaScript and TypeScript apart', () => {
+    const languages = detectRepoLanguages(['a.js', 'b.js', 'c.mjs', 'd.js', 'e.js', 'types.d.ts']);
+
+    expect(languages.primary).toEqual(['JavaScript']);
+  });
+
+  it('should leave languages under a fifth of the files out of the primary list', () => {
+    const files = [...Array.from({ length: 9 }, (_, i) => `src/f${i}.rs`), 'build.py'];
+
+    expect(detectRepoLanguages(files).primary).toEqual(['Rust']);
+  });
+});
+
+describe('pickPromptLanguage', () => {
+  const mixed = { counts: { Go: 6, TypeScript: 4 }, primary: ['Go', 'TypeScript'] };
+
+  it('should prefer an explicit override', () => {
+    expect(pickPromptLanguage(mixed, ['web/app.ts'], 'golang')).toBe('Go');
+  });
+
+  it('should follow the most relevant chunks in a mixed repo', () => {
+    expect(pickPromptLanguage(mixed, ['web/app.ts', 'web/api.ts', 'cmd/main.go'])).toBe('TypeScript');
+    expect(pickPromptLanguage(mixed, ['cmd/main.go', 'web/app.ts'])).toBe('Go');
+  });
+
+  it('should use the dominant language of a single-language repo', () => {
+    const goOnly = { counts: { Go: 10 }, primary: ['Go'] };
+    expect(pickPromptLanguage(goOnly, ['scripts/release.py'])).toBe('Go');
+    expect(pickPromptLanguage(mixed, [])).toBe('Go');
+  });
+});
+
+describe('normalizeLanguageName', () => {
+  it('should map shorthands to display names', () => {
+    expect(normalizeLanguageName('ts')).toBe('TypeScript');
+    expect(normalizeLanguageName('PYTHON')).toBe('Python');
+    expect(normalizeLanguageName('Elixir')).toBe('Elixir');
+  });
+});
+
+describe('buildLanguageInstruction', () => {
+  it('should mention the other languages of a mixed repo', () => {
+    expect(buildLanguageInstruction('Go', ['Go', 'TypeScript'])).toContain('mixes Go, TypeScript');
+    expect(buildLanguageInstruction('Go', ['Go'])).not.toContain('mixes');
+  });
+});
@@ -0,0 +1,108 @@
+/**
+ * Repository Overview Tests
+ * Tests for the file sampling and prompt behind `cv summarize`
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  countImporters,
+  getOverviewModule,
+  selectOverviewSamples,
+  buildRepoOverviewPrompt
+} from '@cv-git/core';
+import { CodeChunkPayload } from '@cv-git/shared';
+
+function chunk(file: string, overrides: Partial<CodeChunkPayload> = {}): CodeChunkPayload {
+  return {
+    id: `${file}:${overrides.startLine ?? 1}`,
+    file,
+    language: 'typescript',
+    startLine: 1,
+    endLine: 10,
+    text: `// ${file}`,
+    imports: [],
+    lastModified: 0,
+    ...overrides
+  } as CodeChunkPayload;
+}
+
+describe('countImporters', () => {
+  it('resolves relative and dotted imports to indexed files', () => {
+    const counts = countImporters(new Map([
+      ['src/auth/service.ts', new Set(['./tokens.js', 'jsonwebtoken'])],
+      ['src/api/routes.ts', new Set(['../auth/tokens', '../auth'])],
+      ['src/auth/tokens.ts', new Set<string>()],
+      ['src/auth/index.ts', new Set(['./service.js'])],
+      ['app/main.py', new Set(['app.models'])],
+      ['app/models.py', new Set<string>()]
+    ]));
+
+    expect(counts.get('src/auth/tokens.ts')).toBe(2);
+    expect(counts.get('src/auth/index.ts')).toBe(1);
+    expect(counts.get('src/auth/service.ts')).toBe(1);
+    expect(counts.get('app/models.py')).toBe(1);
+    expect(counts.has('src/api/routes.ts')).toBe(false);
+  });
+});
+
+describe('getOverviewModule', () => {
+  it('groups files by their first two directories', () => {
+    expect(getOverviewModule('packages/core/src/ai/index.ts')).toBe('packages/core');
+    expect(getOverviewModule('src/main.go')).toBe('src');
+    expect(getOverviewModule('setup.py')).toBe('.');
+  });
+});
+
+describe('selectOverviewSamples', () => {
+  const chunks = [
+    chunk('packages/core/src/util.ts', { symbolName: 'helper', symbolKind: 'function' }),
+    chunk('packages/core/src/auth.ts', { symbolName: 'AuthService', symbolKind: 'class', imports: ['./util.js'] }),
+    chunk('packages/core/src/auth.ts', { symbolName: 'refresh', symbolKind: 'function', startLine: 20, imports: ['./util.js'] }),
+    chunk('packages/core/src/db.ts', { imports: ['./util.js'] }),
+    chunk('packages/cli/src/index.ts', { symbolName: 'main', symbolKind: 'function', imports: ['./commands.js'] }),
+    chunk('packages/cli/src/commands.ts', { symbolName: 'run', symbolKind: 'function', imports: ['./helpers.js'] }),
+    chunk('packages/cli/src/helpers.ts', {}),
+    chunk('tests/unit/auth.test.ts', { imports: ['../../packages/core/src/auth.js'] })
+  ];
+
+  it('takes the most central file of each module before the next of any', () => {
+    const sample = selectOverviewSamples(chunks, 'brief');
+    const files = sample.files.map(f => f.file);
+
+    expect(files.slice(0, 2).sort()).toEqual(['packages/cli/src/index.ts', 'packages/core/src/util.ts']);
+    expect(files).not.toContain('tests/unit/auth.test.ts');
+    expect(sample.totalFiles).toBe(7);
+    expect(sample.modules[0]).toEqual({ name: 'packages/cli', files: 3 });
+  });
+
+  it('prefers named, descriptive chunks and limits them per depth', () => {
+    const brief = selectOverviewSamples(chunks, 'brief').files.find(f => f.file === 'packages/core/src/auth.ts')!;
+    const detailed = selectOverviewSamples(chunks, 'detailed').files.find(f => f.file === 'packages/core/src/auth.ts')!;
+
+    expect(brief.chunks.map(c => c.symbolName)).toEqual(['AuthService']);
+    expect(detailed.chunks.map(c => c.symbolName)).toEqual(['AuthService', 'refresh']);
+    expect(brief.symbols).toEqual(['AuthService', 'refresh']);
+  });
+
+  it('falls back to test files when nothing else is indexed', () => {
+    const sample = selectOverviewSamples([chunk('tests/a.test.ts')], 'brief');
+    expect(sample.files.map(f => f.file)).toEqual(['tests/a.test.ts']);
+  });
+});
+
+describe('buildRepoOverviewPrompt', () => {
+  it('lists modules and annotates sampled files', () => {
+    const sample = selectOverviewSamples([
+      chunk('src/auth.ts', { symbolName: 'AuthService', symbolKind: 'class' }),
+      chunk('src/index.ts', { imports: ['./auth.js'] })
+    ]);
+    const prompt = buildRepoOverviewPrompt(sample, 'brief', 'acme');
+
+    expect(prompt).toContain('acme repository');
+    expect(prompt).toContain('- src (2 files)');
+    expect(prompt).toContain('### src/auth.ts (src, imported by 1 file)');
+    expect(prompt).toContain('### src/index.ts (src, entry point)');
+    expect(prompt).toContain('Symbols: AuthService');
+    expect(prompt).toContain('Keep it short');
+  });
+});
@@ -0,0 +1,90 @@
+/**
+ * Request Timeout Tests
+ * Tests that requests are cancelled on timeout or abort instead of hanging
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  withRequestTimeout,
+  timeoutFromSeconds,
+  RequestTimeoutError,
+  DEFAULT_CHAT_TIMEOUT_MS
+} from '@cv-git/shared';
+
+/** A request that never answers, like a stalled connection */
+const hang = (signal: AbortSignal) => new Promise<string>((_, reject) => {
+  signal.addEventListener('abort', () => reject(signal.reason));
+});
+
+const sleep = (ms: number) => new Promise(resolve => setTimeout(resolve, ms));
+
+describe('withRequestTimeout', () => {
+  it('should cancel the request and fail with a timeout error', async () => {
+    let requestSignal: AbortSignal | undefined;
+    const request = withRequestTimeout('anthropic request', signal => {
+      requestSignal = signal;
+      return hang(signal);
+    }, { timeoutMs: 20 });
+
+    await expect(request).rejects.toThrow(RequestTimeoutError);
+    await expect(request).rejects.toThrow('anthropic request timed out after 20ms');
+    expect(requestSignal?.aborted).toBe(true);
+  });
+
+  it('should not hang when the request ignores its signal', async () => {
+    const request = withRequestTimeout('embedding request', () => new Promise<never>(() => {}), { timeoutMs: 20 });
+    await expect(request).rejects.toThrow('embedding request timed out after 20ms');
+  });
+
+  it('should restart the timer on keepAlive', async () => {
+    const request = withRequestTimeout('stream', async (_signal, keepAlive) => {
+      for (let i = 0; i < 4; i++) {
+        await sleep(15);
+        keepAlive();
+      }
+      return 'done';
+    }, { timeoutMs: 40 });
+
+    await expect(request).resolves.toBe('done');
+  });
+
+  it('should cancel on the caller signal', async () => {
+    const controller = new AbortController();
+    const request = withRequestTimeout('request', hang, { signal: controller.signal });
+    controller.abort();
+
+    await expect(request).rejects.toMatchObject({ name: 'AbortError' });
+  });
+
+  it('should not start a request once the caller signal is aborted', async () => {
+    let started = false;
+    const request = withRequestTimeout('request', async () => {
+      started = true;
+      return 'late';
+    }, { signal: AbortSignal.abort() });
+
+    await expect(request).rejects.toMatchObject({ name: 'AbortError' });
+    expect(started).toBe(false);
+  });
+
+  it('should never time out with a zero timeout', async () => {
+    const request = withRequestTimeout('request', async () => {
+      await sleep(30);
+      return 'slow';
+    }, { timeoutMs: 0 });
+
+    await expect(request).resolves.toBe('slow');
+  });
+});
+
+describe('timeoutFromSeconds', () => {
+  it('should convert seconds and keep zero as no timeout', () => {
+    expect(timeoutFromSeconds(300, DEFAULT_CHAT_TIMEOUT_MS)).toBe(300_000);
+    expect(timeoutFromSeconds(0, DEFAULT_CHAT_TIMEOUT_MS)).toBe(0);
+  });
+
+  it('should fall back to the default when unset or invalid', () => {
+    expect(timeoutFromSeconds(undefined, DEFAULT_CHAT_TIMEOUT_MS)).toBe(120_000);
+    expect(timeoutFromSeconds(-1, DEFAULT_CHAT_TIMEOUT_MS)).toBe(120_000);
+  });
+});
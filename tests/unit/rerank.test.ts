@@ -0,0 +1,132 @@
+/**
+ * Reranking Tests
+ * Tests for reordering retrieved chunks with a cross-encoder (search.rerank)
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import { rerankChunks, createReranker, Reranker, CohereClient } from '@cv-git/core';
+import { CodeChunkPayload, VectorSearchResult } from '@cv-git/shared';
+
+function chunk(file: string, score: number, text: string): VectorSearchResult<CodeChunkPayload> {
+  return {
+    id: file,
+    score,
+    payload: {
+      id: file,
+      file,
+      language: 'typescript',
+      symbolName: file.replace(/.*\/|\.ts$/g, ''),
+      symbolKind: 'function',
+      startLine: 1,
+      endLine: 3,
+      text,
+      imports: [],
+      lastModified: 0
+    }
+  };
+}
+
+const retrieved = [
+  chunk('src/logging/logger.ts', 0.81, 'export function logger(token) { console.log(token); }'),
+  chunk('src/auth/session.ts', 0.78, 'export function session(token) { return verify(token); }'),
+  chunk('src/auth/verify.ts', 0.74, 'export function verify(token) { return jwt.verify(token, key); }')
+];
+
+/** Scores documents by how many of the query's words they contain */
+const wordReranker: Reranker = {
+  model: 'word-overlap',
+  async rerank(query, documents) {
+    const words = query.toLowerCase().split(/\s+/);
+    return documents
+      .map((document, index) => ({ index, score: words.filter(word => document.includes(word)).length / words.length }))
+      .sort((a, b) => b.score - a.score);
+  }
+};
+
+describe('rerankChunks', () => {
+  it('should reorder by the reranker score and keep the top k', async () => {
+    const reranked = await rerankChunks('verify token jwt', retrieved, wordReranker, 2);
+
+    expect(reranked.chunks.map(c => c.payload.file)).toEqual(['src/auth/verify.ts', 'src/auth/session.ts']);
+    expect(reranked.chunks[0].score).toBe(1);
+    expect(reranked.moved).toBe(1);
+  });
+
+  it('should record the vector score and rank of each kept chunk', async () => {
+    const reranked = await rerankChunks('verify token jwt', retrieved, wordReranker, 3);
+
+    expect(reranked.chunks.map(c => c.rerank)).toEqual([
+      { vectorScore: 0.74, vectorRank: 3 },
+      { vectorScore: 0.78, vectorRank: 2 },
+      { vectorScore: 0.81, vectorRank: 1 }
+    ]);
+    // The second chunk stayed where the vector search put it
+    expect(reranked.moved).toBe(2);
+  });
+
+  it('should send each chunk with its file and symbol', async () => {
+    const documents: string[] = [];
+    const recording: Reranker = {
+      model: 'recording',
+      async rerank(_query, docs) {
+        documents.push(...docs);
+        return docs.map((_doc, index) => ({ index, score: 1 - index / 10 }));
+      }
+    };
+
+    const reranked = await rerankChunks('q', retrieved.slice(0, 1), recording, 5);
+
+    expect(documents[0]).toBe('src/logging/logger.ts (logger)\nexport function logger(token) { console.log(token); }');
+    expect(reranked.moved).toBe(0);
+  });
+
+  it('should ignore indices the reranker invents or repeats', async () => {
+    const confused: Reranker = {
+      model: 'confused',
+      async rerank() {
+        return [{ index: 7, score: 0.9 }, { index: 1, score: 0.8 }, { index: 1, score: 0.7 }, { index: 0, score: 0.1 }];
+      }
+    };
+
+    const reranked = await rerankChunks('q', retrieved, confused, 5);
+
+    expect(reranked.chunks.map(c => c.payload.file)).toEqual(['src/auth/session.ts', 'src/logging/logger.ts']);
+  });
+});
+
+describe('createReranker', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('should require a Cohere API key unless the reranker is local', () => {
+    expect(() => createReranker({ provider: 'cohere' })).toThrow(/Cohere API key required/);
+    expect(createReranker({ provider: 'local' }).model).toBe('local reranker');
+    expect(createReranker({ apiKey: 'key' }).model).toBe('rerank-v3.5');
+  });
+
+  it('should map rerank results to scores, most relevant first', async () => {
+    const fetchMock = vi.fn().mockResolvedValue(new Response(JSON.stringify({
+      results: [{ index: 1, relevance_score: 0.2 }, { index: 0, relevance_score: 0.9 }]
+    }), { status: 200 }));
+    vi.stubGlobal('fetch', fetchMock);
+
+    const reranker = createReranker({ provider: 'local', url: 'http://localhost:9000/v1/', model: 'bge-reranker', maxRetryAttempts: 1 });
+    const scores = await reranker.rerank('query', ['a', 'b']);
+
+    expect(scores).toEqual([{ index: 0, score: 0.9 }, { index: 1, score: 0.2 }]);
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe('http://localhost:9000/v1/rerank');
+    expect(JSON.parse(init.body as string)).toEqual({ model: 'bge-reranker', query: 'query', documents: ['a', 'b'] });
+    // A local server without a key gets no Authorization header
+    expect((init.headers as Record<string, string>).Authorization).toBeUndefined();
+  });
+
+  it('should not call the API for no documents', async () => {
+    const fetchMock = vi.fn();
+    vi.stubGlobal('fetch', fetchMock);
+
+    expect(await new CohereClient({ apiKey: 'co-key', maxRetryAttempts: 1 }).rerank('query', [])).toEqual([]);
+    expect(fetchMock).not.toHaveBeenCalled();
+  });
+});
@@ -0,0 +1,69 @@
+/**
+ * Response Cache Tests
+ * Tests for the answers cv explain serves again against an unchanged index
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { ResponseCache, ResponseCacheKey, responseCacheId } from '@cv-git/core';
+
+const key: ResponseCacheKey = {
+  command: 'explain',
+  model: 'anthropic/claude-sonnet-4',
+  query: 'How are retries handled?',
+  indexedCommit: 'abc123',
+  indexUpdatedAt: '2026-03-01T11:00:00.000Z',
+  topK: 10,
+  minScore: 0.25,
+  options: { files: [], tests: 'exclude' }
+};
+
+describe('responseCacheId', () => {
+  it('should ignore whitespace and option order but not case', () => {
+    const id = responseCacheId(key);
+    expect(responseCacheId({ ...key, query: '  How are   retries\nhandled? ' })).toBe(id);
+    expect(responseCacheId({ ...key, options: { tests: 'exclude', files: [] } })).toBe(id);
+    expect(responseCacheId({ ...key, query: 'how are retries handled?' })).not.toBe(id);
+    expect(responseCacheId({ ...key, minScore: 0.3 })).not.toBe(id);
+  });
+
+  it('should change when the index is re-synced without a new commit', () => {
+    expect(responseCacheId({ ...key, indexUpdatedAt: '2026-03-01T11:05:00.000Z' })).not.toBe(responseCacheId(key));
+  });
+});
+
+describe('ResponseCache', () => {
+  let dir: string;
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-response-cache-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  it('should serve a stored response until it expires', async () => {
+    const cache = new ResponseCache(dir, 60);
+    const now = Date.parse('2026-03-01T12:00:00Z');
+    await cache.set(key, { answer: 'With backoff [1]' }, now);
+
+    expect((await cache.get<{ answer: string }>(key, now + 1000))?.value).toEqual({ answer: 'With backoff [1]' });
+    expect(await cache.get(key, now + 61_000)).toBeNull();
+  });
+
+  it('should miss and drop entries once the index moves to another commit', async () => {
+    const cache = new ResponseCache(dir);
+    await cache.set(key, 'old');
+
+    const synced = { ...key, indexedCommit: 'def456' };
+    expect(await cache.get(synced)).toBeNull();
+
+    await cache.set({ ...synced, query: 'Where is the config loaded?' }, 'new');
+    expect(await cache.get(key)).toBeNull();
+    expect(await fs.readdir(dir)).toHaveLength(1);
+    expect(await cache.clear()).toBe(1);
+  });
+});
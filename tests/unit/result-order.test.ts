@@ -0,0 +1,65 @@
+/**
+ * Result Order Tests
+ * Tests that search results with equal scores come back in the same order
+ * on every run, however the points were stored
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import { VectorManager, compareSearchResults } from '@cv-git/core';
+
+const points = [
+  { id: 'src/b.ts:1:bbbb', file: 'src/b.ts', startLine: 1 },
+  { id: 'src/a.ts:40:cccc', file: 'src/a.ts', startLine: 40 },
+  { id: 'src/a.ts:9:ffff', file: 'src/a.ts', startLine: 9 },
+  { id: 'src/a.ts:9:aaaa', file: 'src/a.ts', startLine: 9 },
+  { id: 'lib/z.ts:3:dddd', file: 'lib/z.ts', startLine: 3 }
+];
+
+describe('compareSearchResults', () => {
+  it('should order by score, then file path, start line and ID', () => {
+    const results = [
+      { id: 'x', score: 0.5, payload: { file: 'src/a.ts', startLine: 1 } },
+      ...points.map(p => ({ id: p.id, score: 0.9, payload: p }))
+    ];
+
+    expect(results.sort(compareSearchResults).map(r => r.id)).toEqual([
+      'lib/z.ts:3:dddd', 'src/a.ts:9:aaaa', 'src/a.ts:9:ffff', 'src/a.ts:40:cccc', 'src/b.ts:1:bbbb', 'x'
+    ]);
+  });
+});
+
+describe('tied search results', () => {
+  beforeEach(() => {
+    // Every text embeds to the same vector, so every chunk ties with every other
+    vi.stubGlobal('fetch', vi.fn(async (_url: string, init: RequestInit) => {
+      const body = JSON.parse(init.body as string);
+      const embeddings = body.requests.map(() => ({ values: [1, 0, 0, 0] }));
+      return new Response(JSON.stringify({ embeddings }), { status: 200, headers: { 'Content-Type': 'application/json' } });
+    }));
+  });
+
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  const search = async (stored: typeof points) => {
+    const vector = new VectorManager({ url: '', backend: 'memory', geminiApiKey: 'test-key', vectorSize: 4, enableCache: false });
+    await vector.connect();
+    await vector.upsertBatch(vector.getCollectionNames().codeChunks, stored.map(p => ({
+      id: p.id,
+      vector: [1, 0, 0, 0],
+      payload: { ...p, language: 'typescript', endLine: p.startLine + 5, text: 'export const x = 1;' }
+    })));
+    return (await vector.searchCode('where is x defined', 4)).map(r => r.id);
+  };
+
+  it('should return the same order for the same query, whatever order the chunks were stored in', async () => {
+    const first = await search(points);
+    const second = await search(points);
+    const reversed = await search([...points].reverse());
+
+    expect(first).toEqual(['lib/z.ts:3:dddd', 'src/a.ts:9:aaaa', 'src/a.ts:9:ffff', 'src/a.ts:40:cccc']);
+    expect(second).toEqual(first);
+    expect(reversed).toEqual(first);
+  });
+});
@@ -0,0 +1,81 @@
+/**
+ * Retrieval Bench Tests
+ * Tests for cv bench: loading golden cases, sweep ranges, and recall@k / MRR
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseBenchCases, parseSweepRange, scoreBenchCase, summarizeBench } from '@cv-git/core';
+
+describe('parseBenchCases', () => {
+  it('should read a YAML list or a JSON object with cases', () => {
+    const yaml = [
+      '- query: where are sessions refreshed',
+      '  expected_files: [src/auth/session.ts, ./src/auth/token.ts]',
+      '- query: retry policy',
+      '  expected_file: src/http/retry.ts'
+    ].join('\n');
+    expect(parseBenchCases(yaml)).toEqual([
+      { query: 'where are sessions refreshed', expectedFiles: ['src/auth/session.ts', 'src/auth/token.ts'] },
+      { query: 'retry policy', expectedFiles: ['src/http/retry.ts'] }
+    ]);
+
+    const json = JSON.stringify({ cases: [{ query: 'retry policy', expectedFiles: ['src/http/retry.ts'] }] });
+    expect(parseBenchCases(json)).toEqual([{ query: 'retry policy', expectedFiles: ['src/http/retry.ts'] }]);
+  });
+
+  it('should reject files with no cases, or cases without a query or files', () => {
+    expect(() => parseBenchCases('[]', 'golden.yaml')).toThrow('golden.yaml has no cases');
+    expect(() => parseBenchCases('[{"expected_files": ["a.ts"]}]')).toThrow('case 1 has no query');
+    expect(() => parseBenchCases('[{"query": "retry"}]')).toThrow('has no expected_files');
+  });
+});
+
+describe('parseSweepRange', () => {
+  it('should expand start:end:step including the end, without float drift', () => {
+    expect(parseSweepRange('min-score', '0.1:0.5:0.05')).toEqual([0.1, 0.15, 0.2, 0.25, 0.3, 0.35, 0.4, 0.45, 0.5]);
+    expect(parseSweepRange('top-k', '5:20:5')).toEqual([5, 10, 15, 20]);
+    expect(parseSweepRange('ef', '64,128,256')).toEqual([64, 128, 256]);
+  });
+
+  it('should reject unknown settings and out-of-range values', () => {
+    expect(() => parseSweepRange('temperature', '0:1:0.1')).toThrow('Cannot sweep temperature');
+    expect(() => parseSweepRange('min-score', '0.5:0.1:0.1')).toThrow('Invalid sweep range');
+    expect(() => parseSweepRange('min-score', '0.5,1.5')).toThrow('between 0 and 1');
+    expect(() => parseSweepRange('top-k', '2.5,5')).toThrow('positive integers');
+  });
+});
+
+describe('scoring', () => {
+  const benchCase = { query: 'sessions', expectedFiles: ['src/session.ts', 'src/token.ts'] };
+  const hits = [
+    { file: 'src/index.ts', score: 0.8 },
+    { file: 'src/session.ts', score: 0.7 },
+    { file: 'src/session.ts', score: 0.6 },
+    { file: 'src/token.ts', score: 0.3 }
+  ];
+
+  it('should rank distinct files and find the first expected one', () => {
+    const result = scoreBenchCase(benchCase, hits, 0, 10);
+    expect(result.retrievedFiles).toEqual(['src/index.ts', 'src/session.ts', 'src/token.ts']);
+    expect(result.recall).toBe(1);
+    expect(result.firstHitRank).toBe(2);
+    expect(result.reciprocalRank).toBe(0.5);
+  });
+
+  it('should apply min-score and top-k before scoring', () => {
+    const result = scoreBenchCase(benchCase, hits, 0.5, 10);
+    expect(result.missing).toEqual(['src/token.ts']);
+    expect(result.recall).toBe(0.5);
+
+    expect(scoreBenchCase(benchCase, hits, 0, 1)).toMatchObject({ recall: 0, reciprocalRank: 0, firstHitRank: undefined });
+  });
+
+  it('should average recall and reciprocal rank over cases', () => {
+    const other = { query: 'retry', expectedFiles: ['src/retry.ts'] };
+    const summary = summarizeBench([benchCase, other], [hits, [{ file: 'src/retry.ts', score: 0.9 }]], 0.5, 10);
+    expect(summary.recall).toBe(0.75);
+    expect(summary.mrr).toBe(0.75);
+    expect(summary.hits).toBe(2);
+    expect(summary.cases).toBe(2);
+  });
+});
@@ -0,0 +1,117 @@
+/**
+ * Retry With Backoff Tests
+ * Tests for Retry-After handling and transient error detection
+ */
+
+import { describe, it, expect, vi } from 'vitest';
+import {
+  retryWithBackoff,
+  getRetryAfterMs,
+  getRetryDelay,
+  isRetryableApiError,
+  toApiError
+} from '@cv-git/shared';
+
+const rateLimited = (headers: Record<string, string> = {}) =>
+  Object.assign(new Error('429 Too Many Requests'), { status: 429, headers });
+
+describe('getRetryAfterMs', () => {
+  it('should read seconds, milliseconds, and HTTP dates', () => {
+    expect(getRetryAfterMs(rateLimited({ 'retry-after': '3' }))).toBe(3000);
+    expect(getRetryAfterMs(rateLimited({ 'retry-after-ms': '250' }))).toBe(250);
+
+    const now = Date.parse('2026-01-01T00:00:00Z');
+    expect(getRetryAfterMs(rateLimited({ 'retry-after': 'Thu, 01 Jan 2026 00:00:10 GMT' }), now)).toBe(10000);
+  });
+
+  it('should support Headers objects', () => {
+    const error = { status: 429, headers: new Headers({ 'retry-after': '2' }) };
+    expect(getRetryAfterMs(error)).toBe(2000);
+  });
+
+  it('should return undefined without a header', () => {
+    expect(getRetryAfterMs(new Error('boom'))).toBeUndefined();
+  });
+});
+
+describe('getRetryDelay', () => {
+  it('should prefer Retry-After, capped at the max delay', () => {
+    expect(getRetryDelay(1, rateLimited({ 'retry-after': '5' }))).toBe(5000);
+    expect(getRetryDelay(1, rateLimited({ 'retry-after': '600' }), 1000, 30000)).toBe(30000);
+  });
+
+  it('should back off exponentially with jitter', () => {
+    for (let attempt = 1; attempt <= 4; attempt++) {
+      const delay = getRetryDelay(attempt, new Error('503'), 1000);
+      const ceiling = 1000 * Math.pow(2, attempt - 1);
+      expect(delay).toBeGreaterThanOrEqual(ceiling / 2);
+      expect(delay).toBeLessThanOrEqual(ceiling);
+    }
+  });
+});
+
+describe('isRetryableApiError', () => {
+  it('should retry rate limits, server errors, and network resets', () => {
+    expect(isRetryableApiError({ status: 429 })).toBe(true);
+    expect(isRetryableApiError({ status: 502 })).toBe(true);
+    expect(isRetryableApiError({ code: 'ECONNRESET' })).toBe(true);
+    expect(isRetryableApiError(new Error('No successful provider responses'))).toBe(true);
+  });
+
+  it('should not retry client errors', () => {
+    expect(isRetryableApiError({ status: 400, message: 'Bad request' })).toBe(false);
+    expect(isRetryableApiError({ status: 401, message: 'Invalid API key' })).toBe(false);
+  });
+});
+
+describe('retryWithBackoff', () => {
+  it('should retry until success and report each retry', async () => {
+    const fn = vi.fn()
+      .mockRejectedValueOnce(rateLimited({ 'retry-after-ms': '1' }))
+      .mockRejectedValueOnce(rateLimited({ 'retry-after-ms': '1' }))
+      .mockResolvedValue('ok');
+    const onRetry = vi.fn();
+
+    await expect(retryWithBackoff(fn, { maxAttempts: 5, onRetry })).resolves.toBe('ok');
+    expect(fn).toHaveBeenCalledTimes(3);
+    expect(onRetry.mock.calls.map(([info]) => info.attempt)).toEqual([1, 2]);
+  });
+
+  it('should give up after max attempts', async () => {
+    const fn = vi.fn().mockRejectedValue(rateLimited({ 'retry-after-ms': '1' }));
+
+    await expect(retryWithBackoff(fn, { maxAttempts: 2 })).rejects.toThrow('429');
+    expect(fn).toHaveBeenCalledTimes(2);
+  });
+
+  it('should not retry non-retryable errors', async () => {
+    const fn = vi.fn().mockRejectedValue(Object.assign(new Error('Invalid API key'), { status: 401 }));
+
+    await expect(retryWithBackoff(fn, { maxAttempts: 5 })).rejects.toThrow('Invalid API key');
+    expect(fn).toHaveBeenCalledTimes(1);
+  });
+});
+
+describe('toApiError', () => {
+  it('should read the message from the JSON body and keep status and headers', async () => {
+    const response = new Response(JSON.stringify({ error: { message: 'Rate limit exceeded' } }), {
+      status: 429,
+      headers: { 'retry-after': '2' }
+    });
+
+    const error: any = await toApiError('Gemini', response, body => body.error?.message);
+
+    expect(error.message).toBe('Gemini API error: 429 - Rate limit exceeded');
+    expect(error.status).toBe(429);
+    expect(getRetryAfterMs(error)).toBe(2000);
+    expect(isRetryableApiError(error)).toBe(true);
+  });
+
+  it('should fall back to the raw body when it is not JSON or has no message', async () => {
+    const html = await toApiError('Cohere', new Response('<h1>Bad Gateway</h1>', { status: 502 }), body => body.message);
+    const empty = await toApiError('Voyage', new Response('{"other":1}', { status: 400 }), body => body.detail);
+
+    expect(html.message).toBe('Cohere API error: 502 - <h1>Bad Gateway</h1>');
+    expect(empty.message).toBe('Voyage API error: 400 - {"other":1}');
+  });
+});
@@ -0,0 +1,112 @@
+/**
+ * Review Baseline Unit Tests
+ * Tests for fingerprinting findings and leaving out those a baseline accepts
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  normalizeFindingMessage,
+  findingFingerprint,
+  createReviewBaseline,
+  applyReviewBaseline,
+  readReviewBaseline,
+  writeReviewBaseline,
+  summarizeFindings
+} from '@cv-git/core';
+import type { ReviewFinding, ReviewResult } from '@cv-git/shared';
+
+const finding = (overrides: Partial<ReviewFinding>): ReviewFinding => ({
+  file: 'src/a.ts',
+  startLine: 1,
+  endLine: 1,
+  severity: 'medium',
+  category: 'correctness',
+  message: 'Issue',
+  ...overrides
+});
+
+const result = (findings: ReviewFinding[]): ReviewResult => ({ findings, summary: summarizeFindings(findings, 'Overview') });
+
+describe('findingFingerprint', () => {
+  it('should ignore case, quoting, whitespace and line numbers in the message', () => {
+    expect(normalizeFindingMessage('Missing `null` check on line 42.')).toBe('missing null check on line');
+    expect(findingFingerprint(finding({ message: 'Missing `null` check on line 42.' })))
+      .toBe(findingFingerprint(finding({ message: 'missing null  check on line 57', startLine: 57 })));
+  });
+
+  it('should differ by file and by message', () => {
+    const base = findingFingerprint(finding({}));
+    expect(findingFingerprint(finding({ file: 'src/b.ts' }))).not.toBe(base);
+    expect(findingFingerprint(finding({ message: 'Other issue' }))).not.toBe(base);
+  });
+});
+
+describe('applyReviewBaseline', () => {
+  const legacy = [
+    finding({ file: 'src/legacy.ts', startLine: 10, message: 'SQL built by string concatenation', category: 'security', severity: 'high' }),
+    finding({ file: 'src/legacy.ts', startLine: 30, message: 'Function is too long' })
+  ];
+  const baseline = createReviewBaseline(legacy, new Date('2026-01-01T00:00:00Z'));
+
+  it('should leave out accepted findings, even after they move lines', () => {
+    const moved = legacy.map(f => ({ ...f, startLine: f.startLine + 5, endLine: f.endLine + 5 }));
+    const fresh = finding({ file: 'src/new.ts', message: 'Unhandled promise rejection' });
+
+    const filtered = applyReviewBaseline(result([...moved, fresh]), baseline);
+
+    expect(filtered.findings).toEqual([fresh]);
+    expect(filtered.summary.total).toBe(1);
+    expect(filtered.summary.baselined).toBe(2);
+    expect(filtered.summary.overview).toBe('Overview');
+  });
+
+  it('should suppress only as many findings as the baseline recorded', () => {
+    const twice = [legacy[1], { ...legacy[1], startLine: 80, endLine: 80 }];
+
+    const filtered = applyReviewBaseline(result(twice), baseline);
+
+    expect(filtered.findings).toHaveLength(1);
+    expect(filtered.summary.baselined).toBe(1);
+  });
+});
+
+describe('review baseline files', () => {
+  let dir: string;
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-review-baseline-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(dir, { recursive: true, force: true });
+  });
+
+  it('should round-trip a baseline ordered by file', async () => {
+    const file = path.join(dir, '.cv', 'review-baseline.json');
+    const baseline = createReviewBaseline([finding({ file: 'src/z.ts' }), finding({ file: 'src/a.ts' })]);
+
+    await writeReviewBaseline(file, baseline);
+    const read = await readReviewBaseline(file);
+
+    expect(read.version).toBe(1);
+    expect(read.findings.map(entry => entry.file)).toEqual(['src/a.ts', 'src/z.ts']);
+    expect(read.findings[0]).toEqual({
+      fingerprint: findingFingerprint(finding({})),
+      file: 'src/a.ts',
+      category: 'correctness',
+      severity: 'medium',
+      message: 'Issue'
+    });
+  });
+
+  it('should reject a missing or malformed file', async () => {
+    await expect(readReviewBaseline(path.join(dir, 'missing.json'))).rejects.toThrow(/--write-baseline/);
+
+    const bad = path.join(dir, 'bad.json');
+    await fs.writeFile(bad, JSON.stringify({ findings: [{ file: 'src/a.ts' }] }));
+    await expect(readReviewBaseline(bad)).rejects.toThrow(/not a review baseline/);
+  });
+});
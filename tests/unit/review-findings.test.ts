@@ -0,0 +1,194 @@
+/**
+ * Review Findings Unit Tests
+ * Tests for parsing, ordering, and gating structured review output
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  parseReviewResponse,
+  ReviewParseError,
+  sortFindings,
+  findingsAtOrAbove,
+  reviewExitCode,
+  REVIEW_EXIT_CODES,
+  summarizeFindings,
+  normalizeCategory,
+  parseReviewCategories,
+  applyReviewRules,
+  groupFindingsByCategory
+} from '@cv-git/core';
+import type { ReviewFinding } from '@cv-git/shared';
+
+const finding = (overrides: Partial<ReviewFinding>): ReviewFinding => ({
+  file: 'src/a.ts',
+  startLine: 1,
+  endLine: 1,
+  severity: 'medium',
+  category: 'correctness',
+  message: 'Issue',
+  ...overrides
+});
+
+describe('parseReviewResponse', () => {
+  it('should parse findings wrapped in surrounding text', () => {
+    const response = `Here is the review:
+{
+  "overview": "Mostly fine.",
+  "findings": [
+    { "file": "src/b.ts", "startLine": 4, "endLine": 2, "severity": "HIGH", "category": "Security", "message": "Unsanitized input" },
+    { "file": "src/a.ts", "line": 10, "severity": "bogus", "message": "Missing null check", "suggestion": "Guard it" },
+    { "message": "No file" }
+  ]
+}`;
+
+    const result = parseReviewResponse(response);
+
+    expect(result.findings).toEqual([
+      { file: 'src/a.ts', startLine: 10, endLine: 10, severity: 'medium', category: 'general', message: 'Missing null check', suggestion: 'Guard it' },
+      { file: 'src/b.ts', startLine: 4, endLine: 4, severity: 'high', category: 'security', message: 'Unsanitized input' }
+    ]);
+    expect(result.summary.total).toBe(2);
+    expect(result.summary.bySeverity).toEqual({ low: 0, medium: 1, high: 1, critical: 0 });
+    expect(result.summary.overview).toBe('Mostly fine.');
+  });
+
+  it('should throw on a response without findings JSON, so a gate cannot pass it as clean', () => {
+    expect(() => parseReviewResponse('Looks good to me!')).toThrow(ReviewParseError);
+    expect(() => parseReviewResponse('{"findings": [{"file": "src/a.ts", "mess')).toThrow(ReviewParseError);
+    expect(() => parseReviewResponse('{"overview": "Fine"}')).toThrow('no "findings" list');
+
+    try {
+      parseReviewResponse('Looks good to me!');
+    } catch (error: any) {
+      expect(error.response).toBe('Looks good to me!');
+    }
+  });
+
+  it('should return no findings for an empty findings list', () => {
+    const result = parseReviewResponse('{"findings": [], "overview": "Clean"}');
+    expect(result.findings).toEqual([]);
+    expect(result.summary.total).toBe(0);
+  });
+});
+
+describe('sortFindings', () => {
+  it('should order by file, then line, then severity', () => {
+    const sorted = sortFindings([
+      finding({ file: 'src/b.ts', startLine: 1 }),
+      finding({ file: 'src/a.ts', startLine: 20 }),
+      finding({ file: 'src/a.ts', startLine: 5, severity: 'low' }),
+      finding({ file: 'src/a.ts', startLine: 5, severity: 'critical' })
+    ]);
+
+    expect(sorted.map(f => `${f.file}:${f.startLine}:${f.severity}`)).toEqual([
+      'src/a.ts:5:critical',
+      'src/a.ts:5:low',
+      'src/a.ts:20:medium',
+      'src/b.ts:1:medium'
+    ]);
+  });
+});
+
+describe('findingsAtOrAbove', () => {
+  it('should include the threshold severity and above', () => {
+    const findings = [
+      finding({ severity: 'low' }),
+      finding({ severity: 'high' }),
+      finding({ severity: 'critical' })
+    ];
+
+    expect(findingsAtOrAbove(findings, 'high').map(f => f.severity)).toEqual(['high', 'critical']);
+    expect(findingsAtOrAbove(findings, 'low')).toHaveLength(3);
+    expect(findingsAtOrAbove([finding({ severity: 'medium' })], 'critical')).toHaveLength(0);
+  });
+});
+
+describe('summarizeFindings', () => {
+  it('should count categories in sorted key order', () => {
+    const summary = summarizeFindings([
+      finding({ category: 'style' }),
+      finding({ category: 'performance' }),
+      finding({ category: 'style' })
+    ]);
+
+    expect(Object.entries(summary.byCategory)).toEqual([['performance', 1], ['style', 2]]);
+  });
+});
+
+describe('review categories', () => {
+  it('should map reviewer labels onto the fixed categories', () => {
+    expect(normalizeCategory('Perf')).toBe('performance');
+    expect(normalizeCategory('hotspot')).toBe('performance');
+    expect(normalizeCategory('Best Practices')).toBe('best-practices');
+    expect(normalizeCategory('vulnerability')).toBe('security');
+    expect(normalizeCategory('astrology')).toBe('general');
+  });
+
+  it('should give a finding without a severity its category default', () => {
+    const result = parseReviewResponse(JSON.stringify({
+      findings: [
+        { file: 'src/stats.go', line: 42, category: 'performance', message: 'GetUserStats queries once per user' },
+        { file: 'src/auth.go', line: 7, category: 'security', message: 'Token compared with ==' },
+        { file: 'src/auth.go', line: 9, category: 'style', message: 'Inconsistent naming' }
+      ]
+    }));
+
+    expect(result.findings.map(f => `${f.category}:${f.severity}`)).toEqual(['security:high', 'style:low', 'performance:medium']);
+  });
+
+  it('should reject unknown --disable categories', () => {
+    expect(parseReviewCategories(['style,docs', 'bogus'])).toEqual({ categories: ['style', 'documentation'], unknown: ['bogus'] });
+  });
+});
+
+describe('reviewExitCode', () => {
+  it('should exit with the highest severity found', () => {
+    expect(reviewExitCode([])).toBe(0);
+    expect(reviewExitCode([finding({ severity: 'low' })])).toBe(1);
+    expect(reviewExitCode([finding({ severity: 'low' }), finding({ severity: 'medium' })])).toBe(2);
+    expect(reviewExitCode([finding({ severity: 'high' }), finding({ severity: 'low' })])).toBe(3);
+    expect(reviewExitCode([finding({ severity: 'critical' })])).toBe(3);
+  });
+
+  it('should exit 1 only at or above --fail-on', () => {
+    const findings = [finding({ severity: 'low' }), finding({ severity: 'medium' })];
+
+    expect(reviewExitCode(findings, 'medium')).toBe(1);
+    expect(reviewExitCode(findings, 'high')).toBe(0);
+    expect(reviewExitCode([finding({ severity: 'critical' })], 'low')).toBe(1);
+  });
+
+  it('should keep the error code apart from every severity', () => {
+    const severityCodes = [REVIEW_EXIT_CODES.low, REVIEW_EXIT_CODES.medium, REVIEW_EXIT_CODES.high, REVIEW_EXIT_CODES.critical];
+    expect(severityCodes).not.toContain(REVIEW_EXIT_CODES.error);
+    expect(REVIEW_EXIT_CODES.clean).toBe(0);
+  });
+});
+
+describe('applyReviewRules', () => {
+  const result = {
+    findings: [
+      finding({ category: 'style', severity: 'medium' }),
+      finding({ file: 'src/b.ts', category: 'performance', severity: 'low' })
+    ],
+    summary: summarizeFindings([])
+  };
+
+  it('should drop disabled categories and pin severities', () => {
+    const applied = applyReviewRules(result, { disable: ['style'], severity: { performance: 'high' } });
+
+    expect(applied.findings).toEqual([finding({ file: 'src/b.ts', category: 'performance', severity: 'high' })]);
+    expect(applied.summary.bySeverity.high).toBe(1);
+    expect(applied.summary.disabledCategories).toEqual(['style']);
+  });
+
+  it('should group findings in category order', () => {
+    const groups = groupFindingsByCategory([
+      finding({ category: 'style' }),
+      finding({ category: 'general' }),
+      finding({ category: 'security' })
+    ]);
+
+    expect(groups.map(g => g.category)).toEqual(['security', 'style', 'general']);
+  });
+});
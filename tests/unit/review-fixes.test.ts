@@ -0,0 +1,113 @@
+/**
+ * Review Fix Tests
+ * Tests for cv review --fix: the fix prompt, parsing the model's answer,
+ * splicing fixes into a file, and checking the review run again
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  buildReviewFixPrompt,
+  parseReviewFix,
+  checkFixable,
+  applyReviewFixes,
+  isFindingResolved,
+  NO_FIX_MARKER
+} from '@cv-git/core';
+import type { ReviewFinding } from '@cv-git/shared';
+
+const content = [
+  'export function generateToken(user) {',
+  '  const expiresIn = 86400;',
+  '  const token = sign({ id: user.id }, SECRET);',
+  '  console.log(token);',
+  '  return { token, expiresIn };',
+  '}',
+  ''
+].join('\n');
+
+const finding = (overrides: Partial<ReviewFinding>): ReviewFinding => ({
+  file: 'src/auth.ts',
+  startLine: 4,
+  endLine: 4,
+  severity: 'high',
+  category: 'security',
+  message: 'The token is written to the log',
+  ...overrides
+});
+
+describe('buildReviewFixPrompt', () => {
+  it('should mark the lines to replace and show the rest as read-only', () => {
+    const prompt = buildReviewFixPrompt(finding({ suggestion: 'Remove the log statement' }), 'typescript', content);
+
+    expect(prompt).toContain('## Finding (high security, src/auth.ts line 4)');
+    expect(prompt).toContain('Suggested fix: Remove the log statement');
+    expect(prompt).toContain('## Line 4 (to replace)\n\n```typescript\n  console.log(token);\n```');
+    expect(prompt).toContain('## Code before line 4 (do not change)');
+    expect(prompt).toContain('  return { token, expiresIn };');
+    expect(prompt).toContain(`respond with exactly ${NO_FIX_MARKER}`);
+  });
+});
+
+describe('parseReviewFix', () => {
+  it('should return the code block, or null when there is no safe fix', () => {
+    expect(parseReviewFix('```typescript\n  logger.debug("token issued");\n```')).toBe('  logger.debug("token issued");');
+    expect(parseReviewFix('```\n```')).toBe('');
+    expect(parseReviewFix(NO_FIX_MARKER)).toBeNull();
+    expect(parseReviewFix('I would restructure the module instead.')).toBeNull();
+  });
+});
+
+describe('checkFixable', () => {
+  it('should skip findings without usable lines', () => {
+    expect(checkFixable(finding({}), content)).toBeUndefined();
+    expect(checkFixable(finding({ startLine: 0, endLine: 0 }), content)).toBe('no line range to fix');
+    expect(checkFixable(finding({ startLine: 9, endLine: 12 }), content)).toMatch(/past the end of the file/);
+  });
+});
+
+describe('applyReviewFixes', () => {
+  it('should replace only the lines of each finding', () => {
+    const result = applyReviewFixes(content, [
+      { finding: finding({ startLine: 2, endLine: 2, category: 'correctness', message: 'Magic number' }), replacement: '  const expiresIn = 24 * 60 * 60; // 24h\n  const issuedAt = Date.now();' },
+      { finding: finding({}), replacement: '' }
+    ]);
+
+    expect(result.content).toBe([
+      'export function generateToken(user) {',
+      '  const expiresIn = 24 * 60 * 60; // 24h',
+      '  const issuedAt = Date.now();',
+      '  const token = sign({ id: user.id }, SECRET);',
+      '  return { token, expiresIn };',
+      '}',
+      ''
+    ].join('\n'));
+    expect(result.applied.map(fix => [fix.newStartLine, fix.newEndLine])).toEqual([[2, 3], [5, 5]]);
+    expect(result.skipped).toEqual([]);
+  });
+
+  it('should keep the first of two overlapping fixes and skip fixes that change nothing', () => {
+    const result = applyReviewFixes(content, [
+      { finding: finding({ startLine: 3, endLine: 4 }), replacement: '  const token = sign({ id: user.id }, SECRET);' },
+      { finding: finding({ startLine: 4, endLine: 5, severity: 'low' }), replacement: '  return { token };' },
+      { finding: finding({ startLine: 1, endLine: 1 }), replacement: 'export function generateToken(user) {' }
+    ]);
+
+    expect(result.applied).toHaveLength(1);
+    expect(result.skipped.map(s => s.reason)).toEqual(['overlaps the lines of another fix', 'the fix changes nothing']);
+    expect(result.content).not.toContain('console.log');
+  });
+});
+
+describe('isFindingResolved', () => {
+  const [fix] = applyReviewFixes(content, [{ finding: finding({}), replacement: '  audit(user.id);' }]).applied;
+
+  it('should count a fix as resolved when the review no longer reports it', () => {
+    expect(isFindingResolved(fix, [])).toBe(true);
+    expect(isFindingResolved(fix, [finding({ startLine: 1, endLine: 1, category: 'style', message: 'Missing return type' })])).toBe(true);
+  });
+
+  it('should count a fix as unresolved when the same finding comes back', () => {
+    expect(isFindingResolved(fix, [finding({ startLine: 9, endLine: 9, message: 'The `token` is written to the log.' })])).toBe(false);
+    expect(isFindingResolved(fix, [finding({ message: 'User IDs are sent to the audit log unhashed' })])).toBe(false);
+  });
+});
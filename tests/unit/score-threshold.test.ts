@@ -0,0 +1,31 @@
+/**
+ * Score Threshold Tests
+ * Tests for minScore filtering and near-miss reporting
+ */
+
+import { describe, it, expect } from 'vitest';
+import { applyMinScore } from '@cv-git/core';
+
+describe('applyMinScore', () => {
+  const results = [
+    { id: 'a', score: 0.82 },
+    { id: 'b', score: 0.21 },
+    { id: 'c', score: 0.4 },
+    { id: 'd', score: 0.33 }
+  ];
+
+  it('should keep results at or above the threshold in order', () => {
+    const { results: kept } = applyMinScore(results, 0.4);
+    expect(kept.map(r => r.id)).toEqual(['a', 'c']);
+  });
+
+  it('should report the best rejected score', () => {
+    expect(applyMinScore(results, 0.4).nearMissScore).toBe(0.33);
+    expect(applyMinScore(results, 0.9).nearMissScore).toBe(0.82);
+  });
+
+  it('should leave nearMissScore undefined when nothing was rejected', () => {
+    expect(applyMinScore(results, 0).nearMissScore).toBeUndefined();
+    expect(applyMinScore([], 0.5)).toEqual({ results: [], nearMissScore: undefined });
+  });
+});
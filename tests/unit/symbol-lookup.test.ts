@@ -0,0 +1,74 @@
+/**
+ * Symbol Lookup Tests
+ * Tests for finding symbols by name in stored chunk metadata
+ */
+
+import { describe, it, expect } from 'vitest';
+import { lookupSymbols, scoreSymbolName } from '@cv-git/core';
+
+const chunk = (symbolName: string, symbolKind: string, file: string, startLine: number, endLine: number, text = `function ${symbolName}() {}`): any => ({
+  id: `${file}:${startLine}-${endLine}`,
+  file,
+  language: 'typescript',
+  symbolName,
+  symbolKind,
+  startLine,
+  endLine,
+  text,
+  imports: [],
+  lastModified: 0
+});
+
+const chunks = [
+  chunk('parseConfigFile', 'function', 'src/config/file.ts', 10, 30),
+  chunk('parseConfig', 'function', 'src/config/index.ts', 5, 20),
+  chunk('ParseConfig', 'class', 'src/legacy.ts', 1, 40),
+  chunk('renderConfig', 'method', 'src/ui.ts', 12, 18),
+  { ...chunk('unused', 'function', 'src/x.ts', 1, 2), symbolName: undefined }
+];
+
+describe('scoreSymbolName', () => {
+  it('ranks exact, case-insensitive, prefix, substring and typo matches in order', () => {
+    const exact = scoreSymbolName('parseConfig', 'parseConfig');
+    const caseless = scoreSymbolName('ParseConfig', 'parseConfig');
+    const prefix = scoreSymbolName('parseConfigFile', 'parseConfig');
+    const substring = scoreSymbolName('reparseConfig', 'parseConfig');
+    const typo = scoreSymbolName('parseConfig', 'parseConfg');
+
+    expect(exact).toBe(1);
+    expect(caseless).toBeLessThan(exact);
+    expect(prefix).toBeLessThan(caseless);
+    expect(substring).toBeLessThan(prefix);
+    expect(typo).toBeGreaterThan(0);
+    expect(scoreSymbolName('parseConfigFile', 'pcf')).toBeGreaterThan(0);
+    expect(scoreSymbolName('renderConfig', 'database')).toBe(0);
+  });
+});
+
+describe('lookupSymbols', () => {
+  it('lists exact matches before fuzzy ones', () => {
+    const matches = lookupSymbols(chunks, 'parseConfig');
+
+    expect(matches.map(m => [m.file, m.match])).toEqual([
+      ['src/config/index.ts', 'exact'],
+      ['src/legacy.ts', 'exact'],
+      ['src/config/file.ts', 'fuzzy']
+    ]);
+  });
+
+  it('filters by kind', () => {
+    expect(lookupSymbols(chunks, 'config', { kind: 'type' }).map(m => m.name)).toEqual(['ParseConfig']);
+    expect(lookupSymbols(chunks, 'config', { kind: 'method' }).map(m => m.name)).toEqual(['renderConfig']);
+    expect(() => lookupSymbols(chunks, 'config', { kind: 'macro' })).toThrow(/Unknown symbol kind/);
+  });
+
+  it('reports a symbol split across chunks once, spanning all of them', () => {
+    const split = [
+      chunk('bigHandler', 'function', 'src/h.ts', 51, 100, 'part two'),
+      { ...chunk('bigHandler', 'function', 'src/h.ts', 1, 50, 'function bigHandler() {'), signature: 'function bigHandler(): void' }
+    ];
+    const [match] = lookupSymbols(split, 'bigHandler');
+
+    expect(match).toMatchObject({ startLine: 1, endLine: 100, signature: 'function bigHandler(): void', snippet: 'function bigHandler() {' });
+  });
+});
@@ -0,0 +1,73 @@
+/**
+ * Sync Checkpoint Unit Tests
+ * Tests for journaling embedded groups so an interrupted full sync can resume
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  startSyncCheckpoint,
+  recordCheckpointGroup,
+  readSyncCheckpoint,
+  readCheckpointPoints,
+  rewriteSyncCheckpoint,
+  clearSyncCheckpoint,
+  checkpointMismatch,
+  hashCheckpointContent
+} from '@cv-git/core';
+
+const identity = { provider: 'openai', model: 'text-embedding-3-small', dimensions: 1536 };
+const point = (id: string, file: string) => ({ id, vector: [0.1, 0.2], payload: { file } });
+
+describe('Sync checkpoint', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-checkpoint-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(tempDir, { recursive: true, force: true });
+  });
+
+  it('should journal groups and reload them', async () => {
+    const checkpoint = await startSyncCheckpoint(tempDir, identity, 'code_chunks');
+    await recordCheckpointGroup(tempDir, checkpoint, new Map([['a.ts', hashCheckpointContent('a')]]), [point('1', 'a.ts'), point('2', 'a.ts')]);
+    await recordCheckpointGroup(tempDir, checkpoint, new Map([['b.ts', hashCheckpointContent('b')]]), [point('3', 'b.ts')]);
+
+    const saved = await readSyncCheckpoint(tempDir);
+    expect(saved?.files).toEqual({ 'a.ts': hashCheckpointContent('a'), 'b.ts': hashCheckpointContent('b') });
+    expect(saved?.points).toBe(3);
+    expect((await readCheckpointPoints(tempDir)).map(p => p.id)).toEqual(['1', '2', '3']);
+  });
+
+  it('should ignore a journal line cut off by an interruption', async () => {
+    const checkpoint = await startSyncCheckpoint(tempDir, identity, 'code_chunks');
+    await recordCheckpointGroup(tempDir, checkpoint, new Map([['a.ts', 'h']]), [point('1', 'a.ts')]);
+    await fs.appendFile(path.join(tempDir, '.cv', 'index', 'checkpoint', 'points.jsonl'), '{"id":"2","vec');
+
+    expect((await readCheckpointPoints(tempDir)).map(p => p.id)).toEqual(['1']);
+  });
+
+  it('should compact the journal and clear it', async () => {
+    const checkpoint = await startSyncCheckpoint(tempDir, identity, 'code_chunks');
+    await recordCheckpointGroup(tempDir, checkpoint, new Map([['a.ts', 'h']]), [point('1', 'a.ts'), point('2', 'b.ts')]);
+    await rewriteSyncCheckpoint(tempDir, checkpoint, [point('1', 'a.ts')]);
+
+    expect((await readSyncCheckpoint(tempDir))?.points).toBe(1);
+    expect(await readCheckpointPoints(tempDir)).toHaveLength(1);
+
+    await clearSyncCheckpoint(tempDir);
+    expect(await readSyncCheckpoint(tempDir)).toBe(null);
+  });
+
+  it('should refuse to resume with a different model or collection', async () => {
+    const checkpoint = await startSyncCheckpoint(tempDir, identity, 'code_chunks');
+
+    expect(checkpointMismatch(checkpoint, identity, 'code_chunks')).toBe(null);
+    expect(checkpointMismatch(checkpoint, { ...identity, model: 'text-embedding-3-large' }, 'code_chunks')).toContain('model');
+    expect(checkpointMismatch(checkpoint, identity, 'other_chunks')).toContain('collection');
+  });
+});
@@ -0,0 +1,49 @@
+/**
+ * Sync Estimate Tests
+ * Tests for cv sync --estimate: counting the chunks and tokens a full sync
+ * would embed
+ */
+
+import { describe, it, expect } from 'vitest';
+import { CodeChunk } from '@cv-git/shared';
+import { estimateChunkedFiles } from '@cv-git/core';
+
+const chunk = (file: string, text: string, startLine: number = 1): CodeChunk => ({
+  id: `${file}:${startLine}`,
+  file,
+  language: 'typescript',
+  startLine,
+  endLine: startLine + text.split('\n').length - 1,
+  text
+});
+
+describe('estimateChunkedFiles', () => {
+  it('should count chunks and tokens of the text as prepared, and list the largest files', () => {
+    const estimate = estimateChunkedFiles([
+      { file: 'src/a.ts', bytes: 80, chunks: [chunk('src/a.ts', 'x'.repeat(40)), chunk('src/a.ts', 'y'.repeat(40), 2)] },
+      { file: 'src/b.ts', bytes: 400, chunks: [chunk('src/b.ts', 'z'.repeat(400))] },
+      { file: 'src/c.ts', bytes: 0, chunks: [] }
+    ], { prepare: c => `// File: ${c.file}\n${c.text}`, largest: 2 });
+
+    // "// File: src/a.ts\n" adds 18 characters to each chunk
+    expect(estimate).toMatchObject({ files: 3, chunks: 3, totalBytes: 480, skippedChunks: 0, failed: [] });
+    expect(estimate.tokens).toBe(15 + 15 + 105);
+    expect(estimate.largestFiles).toEqual([
+      { file: 'src/b.ts', bytes: 400, chunks: 1, tokens: 105 },
+      { file: 'src/a.ts', bytes: 80, chunks: 2, tokens: 30 }
+    ]);
+  });
+
+  it('should split and skip code over the embedding model limit as sync does', () => {
+    const lines = Array.from({ length: 20 }, (_, i) => `const value${i} = ${i};`).join('\n');
+    const estimate = estimateChunkedFiles([
+      { file: 'src/gen.ts', bytes: 2400, chunks: [chunk('src/gen.ts', lines), chunk('src/gen.ts', 'q'.repeat(2000), 21)] }
+    ], { maxInputTokens: 50 });
+
+    // ~95 tokens of declarations become three windows under the 45-token budget;
+    // a single 500-token line cannot be split and is left out
+    expect(estimate.chunks).toBe(3);
+    expect(estimate.tokens).toBe(96);
+    expect(estimate.skippedChunks).toBe(1);
+  });
+});
@@ -0,0 +1,74 @@
+/**
+ * Sync File Filter Tests
+ * Tests for cv sync --include/--exclude/--ext and how the filter is recorded in the index
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  parseExtensions,
+  normalizeFileFilter,
+  fileFilterMiss,
+  describeFileFilter,
+  writeIndexMetadata
+} from '@cv-git/core';
+
+describe('parseExtensions', () => {
+  it('splits comma lists and adds the leading dot', () => {
+    expect(parseExtensions(['.go,ts', 'TS', ' .py '])).toEqual(['.go', '.ts', '.py']);
+  });
+});
+
+describe('fileFilterMiss', () => {
+  const filter = normalizeFileFilter({
+    include: ['services/api', 'libs/**/*.go'],
+    exclude: ['**/gen/**'],
+    extensions: ['.go,.ts']
+  });
+
+  it('passes files inside an included directory or glob', () => {
+    expect(fileFilterMiss('services/api/main.go', filter)).toBeNull();
+    expect(fileFilterMiss('libs/auth/token.go', filter)).toBeNull();
+  });
+
+  it('says why a file is left out', () => {
+    expect(fileFilterMiss('services/web/app.ts', filter)).toBe('not matched by --include');
+    expect(fileFilterMiss('services/api/gen/types.go', filter)).toBe('matched --exclude **/gen/**');
+    expect(fileFilterMiss('services/api/tool.py', filter)).toBe('extension not in --ext: .py');
+  });
+
+  it('does not treat a directory prefix as a match', () => {
+    expect(fileFilterMiss('services/api-v2/main.go', filter)).toBe('not matched by --include');
+  });
+
+  it('passes everything without a filter', () => {
+    expect(fileFilterMiss('anything.rs', normalizeFileFilter({ include: [], extensions: [] }))).toBeNull();
+  });
+
+  it('describes the filter', () => {
+    expect(describeFileFilter(filter)).toBe('include services/api, libs/**/*.go; exclude **/gen/**; ext .go,.ts');
+  });
+});
+
+describe('file filter in index metadata', () => {
+  const identity = { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 };
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-file-filter-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(tempDir, { recursive: true, force: true });
+  });
+
+  it('keeps the filter until a sync records another one, and clears it for an unfiltered sync', async () => {
+    const filter = normalizeFileFilter({ include: ['services/api'] });
+    await writeIndexMetadata(tempDir, identity, 'abc123', undefined, undefined, undefined, filter);
+
+    expect((await writeIndexMetadata(tempDir, identity)).fileFilter).toEqual({ include: ['services/api'] });
+    expect((await writeIndexMetadata(tempDir, identity, undefined, undefined, undefined, undefined, {})).fileFilter).toBeUndefined();
+  });
+});
@@ -0,0 +1,66 @@
+/**
+ * Sync Limits Tests
+ * Tests for sync.limits: measuring what a sync is about to embed and
+ * finding the limits it goes over
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { checkSyncLimits, planSyncFiles, resolveSyncLimits, DEFAULT_SYNC_LIMITS } from '@cv-git/core';
+
+describe('Sync limits', () => {
+  let repo: string;
+
+  beforeEach(async () => {
+    repo = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-sync-limits-test-'));
+    await fs.mkdir(path.join(repo, 'src'));
+    await fs.writeFile(path.join(repo, 'src/a.ts'), 'x'.repeat(1000));
+    await fs.writeFile(path.join(repo, 'src/b.ts'), 'x'.repeat(5000));
+    await fs.writeFile(path.join(repo, 'src/c.ts'), 'x'.repeat(100));
+  });
+
+  afterEach(async () => {
+    await fs.rm(repo, { recursive: true, force: true });
+  });
+
+  it('should measure files, bytes and projected embeddings', async () => {
+    const plan = await planSyncFiles(repo, ['src/a.ts', 'src/b.ts', 'src/c.ts', 'src/gone.ts'], 2);
+
+    expect(plan.files).toBe(3);
+    expect(plan.totalBytes).toBe(6100);
+    expect(plan.estimatedTokens).toBe(250 + 1250 + 25);
+    // One chunk per 2KB, at least one per file
+    expect(plan.estimatedEmbeddings).toBe(1 + 3 + 1);
+    expect(plan.largestFiles).toEqual([{ file: 'src/b.ts', bytes: 5000 }, { file: 'src/a.ts', bytes: 1000 }]);
+  });
+
+  it('should pass a sync within the default limits', async () => {
+    const check = await checkSyncLimits(repo, ['src/a.ts', 'src/b.ts', 'src/c.ts']);
+    expect(check.exceeded).toEqual([]);
+    expect(check.limits).toEqual(DEFAULT_SYNC_LIMITS);
+  });
+
+  it('should report every limit a sync goes over', async () => {
+    const check = await checkSyncLimits(repo, ['src/a.ts', 'src/b.ts', 'src/c.ts'], {
+      maxFiles: 2,
+      maxTotalBytes: 4096,
+      maxBytes: 900
+    });
+
+    expect(check.exceeded).toEqual([
+      { limit: 'maxFiles', value: 2, actual: 3 },
+      { limit: 'maxTotalBytes', value: 4096, actual: 6100 },
+      { limit: 'maxBytes', value: 900, actual: 5000, files: ['src/b.ts', 'src/a.ts'] }
+    ]);
+  });
+
+  it('should treat 0 as no limit', async () => {
+    const limits = resolveSyncLimits({ maxFiles: 0, maxTotalBytes: 0, maxBytes: 0 });
+    expect(limits).toEqual({ maxFiles: 0, maxTotalBytes: 0, maxBytes: 0 });
+
+    const check = await checkSyncLimits(repo, ['src/a.ts', 'src/b.ts', 'src/c.ts'], limits);
+    expect(check.exceeded).toEqual([]);
+  });
+});
@@ -0,0 +1,81 @@
+/**
+ * Sync Pipeline Tests
+ */
+
+import { describe, it, expect } from 'vitest';
+import { BoundedChannel, runWorkers } from '../../packages/core/src/sync/pipeline.js';
+
+const tick = () => new Promise(resolve => setTimeout(resolve, 0));
+
+describe('BoundedChannel', () => {
+  it('delivers items in send order and ends after close', async () => {
+    const channel = new BoundedChannel<number>(2);
+    const received: number[] = [];
+    const consumer = (async () => {
+      for await (const item of channel) received.push(item);
+    })();
+
+    for (const item of [1, 2, 3, 4, 5]) await channel.send(item);
+    channel.close();
+    await consumer;
+
+    expect(received).toEqual([1, 2, 3, 4, 5]);
+  });
+
+  it('makes senders wait while the channel is full', async () => {
+    const channel = new BoundedChannel<number>(1);
+    await channel.send(1);
+
+    let sent = false;
+    const blocked = channel.send(2).then(() => { sent = true; });
+    await tick();
+    expect(sent).toBe(false);
+
+    expect(await channel.receive()).toEqual({ value: 1, done: false });
+    await blocked;
+    expect(sent).toBe(true);
+    expect(await channel.receive()).toEqual({ value: 2, done: false });
+  });
+
+  it('rejects sends after close', async () => {
+    const channel = new BoundedChannel<number>(1);
+    channel.close();
+    await expect(channel.send(1)).rejects.toThrow('closed');
+  });
+});
+
+describe('runWorkers', () => {
+  it('tags results with their input index and reports failures', async () => {
+    const results = [];
+    for await (const result of runWorkers(['a', 'b', 'bad', 'c'], 2, async item => {
+      if (item === 'bad') throw new Error('cannot parse');
+      await new Promise(resolve => setTimeout(resolve, item === 'a' ? 10 : 0));
+      return item.toUpperCase();
+    })) {
+      results.push(result);
+    }
+
+    const sorted = results.sort((x, y) => x.index - y.index);
+    expect(sorted.map(r => r.item)).toEqual(['a', 'b', 'bad', 'c']);
+    expect(sorted[0]).toMatchObject({ value: 'A' });
+    expect(sorted[2]).toMatchObject({ error: expect.objectContaining({ message: 'cannot parse' }) });
+  });
+
+  it('never runs more than the given number of workers', async () => {
+    let active = 0;
+    let peak = 0;
+    const channel = runWorkers(Array.from({ length: 20 }, (_, i) => i), 3, async item => {
+      active++;
+      peak = Math.max(peak, active);
+      await tick();
+      active--;
+      return item;
+    });
+
+    let count = 0;
+    for await (const _ of channel) count++;
+
+    expect(count).toBe(20);
+    expect(peak).toBeLessThanOrEqual(3);
+  });
+});
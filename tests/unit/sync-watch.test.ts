@@ -0,0 +1,52 @@
+/**
+ * Sync Watch Tests
+ * Tests batching of file events for cv sync --watch
+ */
+
+import { describe, it, expect } from 'vitest';
+import { ChangeBatcher, isEditorTempFile } from '@cv-git/core';
+
+describe('ChangeBatcher', () => {
+  it('should wait for the debounce interval after the last event', () => {
+    const batcher = new ChangeBatcher(500, 10000);
+    expect(batcher.dueIn(0)).toBeNull();
+
+    batcher.record('change', 'src/a.ts', 1000);
+    batcher.record('change', 'src/a.ts', 1300);
+    expect(batcher.dueIn(1300)).toBe(500);
+    expect(batcher.dueIn(1900)).toBe(0);
+  });
+
+  it('should flush a save storm after the maximum wait', () => {
+    const batcher = new ChangeBatcher(500, 2000);
+    for (let t = 0; t <= 1900; t += 100) {
+      batcher.record('change', 'src/a.ts', t);
+    }
+    expect(batcher.dueIn(1900)).toBe(100);
+  });
+
+  it('should coalesce events per file', () => {
+    const batcher = new ChangeBatcher();
+    batcher.record('add', 'src/new.ts');
+    batcher.record('change', 'src/new.ts');
+    batcher.record('add', 'src/scratch.ts');
+    batcher.record('unlink', 'src/scratch.ts');
+    // Atomic save: the editor deletes and recreates the file
+    batcher.record('unlink', 'src/saved.ts');
+    batcher.record('add', 'src/saved.ts');
+    batcher.record('unlink', 'src/old.ts');
+
+    expect(batcher.take()).toEqual({ added: ['src/new.ts'], modified: ['src/saved.ts'], deleted: ['src/old.ts'] });
+    expect(batcher.size).toBe(0);
+  });
+});
+
+describe('isEditorTempFile', () => {
+  it('should recognize swap, backup and safe-write files', () => {
+    for (const file of ['src/.a.ts.swp', 'src/a.ts~', 'src/.#a.ts', 'src/4913', 'src/a.ts___jb_tmp___', 'src/a.ts.orig']) {
+      expect(isEditorTempFile(file)).toBe(true);
+    }
+    expect(isEditorTempFile('src/a.ts')).toBe(false);
+    expect(isEditorTempFile('src/swap.ts')).toBe(false);
+  });
+});
@@ -0,0 +1,83 @@
+/**
+ * Custom System Prompt Tests
+ * Tests for interpolating and composing per-command system prompts
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  interpolatePrompt,
+  getPromptVariables,
+  applySystemPrompt,
+  composeSystemPrompt,
+  SystemPromptOptions
+} from '@cv-git/core';
+import { Context } from '@cv-git/shared';
+
+function context(files: Array<[string, string]>): Context {
+  return {
+    chunks: files.map(([file, language], i) => ({
+      id: `${file}:1`,
+      score: 0.9,
+      payload: { id: `${file}:1`, file, language, startLine: 1, endLine: 3, text: `code ${i}`, imports: [], lastModified: 0 }
+    })),
+    symbols: [],
+    files: []
+  };
+}
+
+const review: SystemPromptOptions = { template: 'Security review of {{repo}} ({{language}}): {{files}}', command: 'review', repo: 'acme' };
+
+describe('interpolatePrompt', () => {
+  it('replaces known variables and leaves unknown ones', () => {
+    expect(interpolatePrompt('{{ command }} for {{who}}', { command: 'explain' })).toBe('explain for {{who}}');
+  });
+});
+
+describe('getPromptVariables', () => {
+  it('uses the most common language and distinct files of the context', () => {
+    const vars = getPromptVariables(review, 'diff', context([['a.go', 'go'], ['b.go', 'go'], ['a.go', 'go'], ['c.py', 'python']]));
+
+    expect(vars.language).toBe('go');
+    expect(vars.files).toBe('a.go, b.go, c.py');
+    expect(vars.code).toContain('### c.py:1\n```python\ncode 3\n```');
+  });
+
+  it('prefers the language code is generated in', () => {
+    const ctx = { ...context([['a.py', 'python']]), language: 'go' };
+    expect(getPromptVariables(review, '', ctx).language).toBe('go');
+  });
+});
+
+describe('applySystemPrompt', () => {
+  const ctx = context([['src/auth.go', 'go']]);
+
+  it('sends the custom prompt as the system prompt next to the built-in one', () => {
+    expect(applySystemPrompt(review, 'BUILT-IN', 'diff', ctx)).toEqual({
+      system: 'Security review of acme (go): src/auth.go',
+      prompt: 'BUILT-IN'
+    });
+    expect(applySystemPrompt(undefined, 'BUILT-IN', 'diff', ctx)).toEqual({ prompt: 'BUILT-IN' });
+  });
+
+  it('replaces the built-in prompt in raw mode', () => {
+    const placed = applySystemPrompt({ ...review, template: 'Review:\n{{input}}', raw: true }, 'BUILT-IN', 'the diff', ctx);
+    expect(placed).toEqual({ prompt: 'Review:\nthe diff' });
+
+    const appended = applySystemPrompt({ ...review, template: 'Be terse.', raw: true }, 'BUILT-IN', 'the diff', ctx);
+    expect(appended.system).toBeUndefined();
+    expect(appended.prompt).toMatch(/^Be terse\.\n\nthe diff\n\n### src\/auth\.go:1/);
+  });
+
+  it('keeps the built-in prompt in raw mode when there is no input', () => {
+    expect(applySystemPrompt({ ...review, raw: true }, 'PLAN FORMAT', undefined, ctx).prompt).toBe('PLAN FORMAT');
+  });
+});
+
+describe('composeSystemPrompt', () => {
+  it('puts the custom prompt first, or alone in raw mode', () => {
+    const custom: SystemPromptOptions = { template: 'Teach {{command}}', command: 'chat' };
+    expect(composeSystemPrompt('BUILT-IN', custom)).toBe('Teach chat\n\nBUILT-IN');
+    expect(composeSystemPrompt('BUILT-IN', { ...custom, raw: true })).toBe('Teach chat');
+    expect(composeSystemPrompt('BUILT-IN', undefined)).toBe('BUILT-IN');
+  });
+});
@@ -0,0 +1,69 @@
+/**
+ * Test Generation Tests
+ * Tests for test framework detection and test file placement used by `cv test`
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  detectTestFramework,
+  getTestFilePath,
+  isTestFile,
+  extractCodeBlock
+} from '@cv-git/core';
+
+describe('detectTestFramework', () => {
+  it('should detect frameworks from imports in existing tests', () => {
+    expect(detectTestFramework('typescript', ["import { describe, it } from 'vitest';"])).toBe('vitest');
+    expect(detectTestFramework('typescript', ["const fn = jest.fn();"])).toBe('jest');
+    expect(detectTestFramework('python', ['import pytest\n'])).toBe('pytest');
+    expect(detectTestFramework('go', ['import (\n\t"testing"\n\t"github.com/stretchr/testify/assert"\n)'])).toBe('testify');
+  });
+
+  it('should fall back to the language default', () => {
+    expect(detectTestFramework('go', [])).toBe('testing');
+    expect(detectTestFramework('typescript', [])).toBe('vitest');
+  });
+});
+
+describe('getTestFilePath', () => {
+  it('should put Go tests next to the source as _test.go', () => {
+    expect(getTestFilePath('pkg/parser/lexer.go', 'go')).toBe('pkg/parser/lexer_test.go');
+  });
+
+  it('should follow an existing tests directory for TypeScript', () => {
+    expect(getTestFilePath('packages/core/src/vector/index.ts', 'typescript', ['tests/unit/a.test.ts']))
+      .toBe('tests/unit/index.test.ts');
+    expect(getTestFilePath('src/app.ts', 'typescript', ['src/other.spec.ts'])).toBe('src/app.spec.ts');
+  });
+
+  it('should mirror src/main to src/test for Java', () => {
+    expect(getTestFilePath('src/main/java/com/acme/Parser.java', 'java'))
+      .toBe('src/test/java/com/acme/ParserTest.java');
+  });
+});
+
+describe('isTestFile', () => {
+  it('should recognise common test file layouts', () => {
+    expect(isTestFile('src/auth.test.ts')).toBe(true);
+    expect(isTestFile('pkg/auth_test.go')).toBe(true);
+    expect(isTestFile('tests/test_auth.py')).toBe(true);
+    expect(isTestFile('src/contest.ts')).toBe(false);
+  });
+
+  it('should recognise test naming conventions of other languages', () => {
+    expect(isTestFile('spec/models/user_spec.rb')).toBe(true);
+    expect(isTestFile('app/src/test/kotlin/UserServiceTest.kt')).toBe(true);
+    expect(isTestFile('Billing.Tests/InvoiceTests.cs')).toBe(true);
+    expect(isTestFile('internal/billing/service.go')).toBe(false);
+  });
+});
+
+describe('extractCodeBlock', () => {
+  it('should return the contents of the first fenced block', () => {
+    expect(extractCodeBlock('Here you go:\n```go\npackage main\n```\nDone.')).toBe('package main\n');
+  });
+
+  it('should return the response itself when there is no block', () => {
+    expect(extractCodeBlock('package main')).toBe('package main\n');
+  });
+});
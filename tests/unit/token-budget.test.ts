@@ -0,0 +1,93 @@
+/**
+ * Token Budget Tests
+ * Tests for fitting retrieved chunks into the model's context window
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+  fitChunksToBudget,
+  getContextBudget,
+  getModelContextWindow,
+  DEFAULT_CONTEXT_WINDOW,
+  PROMPT_OVERHEAD_TOKENS
+} from '@cv-git/core';
+
+function chunk(id: string, score: number, tokens: number) {
+  const text = Array.from({ length: tokens / 10 }, () => 'x'.repeat(39)).join('\n');
+  return {
+    id,
+    score,
+    payload: { id, file: `${id}.ts`, language: 'typescript', startLine: 1, endLine: tokens / 10, text, imports: [], lastModified: 0 }
+  };
+}
+
+describe('getModelContextWindow', () => {
+  it('should use the model catalogue, then name prefixes', () => {
+    expect(getModelContextWindow('claude-sonnet-4-5')).toBe(200000);
+    expect(getModelContextWindow('anthropic/claude-3-5-sonnet-20241022')).toBe(200000);
+    expect(getModelContextWindow('gpt-4-0613')).toBe(8192);
+    expect(getModelContextWindow('some-local-model')).toBe(DEFAULT_CONTEXT_WINDOW);
+  });
+});
+
+describe('getContextBudget', () => {
+  it('should reserve room for the answer and the rest of the prompt', () => {
+    expect(getContextBudget({ model: 'gpt-4', maxOutputTokens: 1000 })).toBe(8192 - 1000 - PROMPT_OVERHEAD_TOKENS);
+  });
+
+  it('should prefer a configured context window', () => {
+    expect(getContextBudget({ model: 'claude-sonnet-4-5', maxOutputTokens: 4000, contextWindow: 16000 }))
+      .toBe(16000 - 4000 - PROMPT_OVERHEAD_TOKENS);
+  });
+});
+
+describe('fitChunksToBudget', () => {
+  it('should keep everything that fits, highest score first', () => {
+    const result = fitChunksToBudget([chunk('b', 0.5, 100), chunk('a', 0.9, 100)], 1000);
+
+    expect(result.chunks.map(c => c.id)).toEqual(['a', 'b']);
+    expect(result.dropped).toBe(0);
+    expect(result.truncated).toBe(0);
+  });
+
+  it('should truncate the chunk that overflows when enough room is left', () => {
+    const result = fitChunksToBudget([chunk('a', 0.9, 400), chunk('b', 0.8, 1000)], 1000);
+
+    expect(result.chunks.map(c => c.id)).toEqual(['a', 'b']);
+    expect(result.truncated).toBe(1);
+    expect(result.chunks[1].payload.text.length).toBeLessThan(chunk('b', 0.8, 1000).payload.text.length);
+    expect(result.tokens).toBeLessThanOrEqual(1000);
+  });
+
+  it('should drop low-ranked chunks once the budget is used up', () => {
+    const result = fitChunksToBudget(
+      [chunk('a', 0.9, 900), chunk('b', 0.8, 500), chunk('c', 0.7, 50)],
+      1000
+    );
+
+    expect(result.chunks.map(c => c.id)).toEqual(['a', 'c']);
+    expect(result.dropped).toBe(1);
+    expect(result.pinned).toBe(0);
+  });
+
+  it('should fit --file chunks before higher-scoring search hits', () => {
+    const file = { ...chunk('file', 0.3, 600), payload: { ...chunk('file', 0.3, 600).payload, pinned: true } };
+    const result = fitChunksToBudget([chunk('a', 0.9, 500), chunk('b', 0.8, 300), file], 1000);
+
+    expect(result.chunks.map(c => c.id)).toEqual(['file', 'a']);
+    expect(result.chunks[0].payload.text).toBe(file.payload.text);
+    expect(result.truncated).toBe(1);
+    expect(result.dropped).toBe(1);
+    expect(result.pinned).toBe(0);
+  });
+
+  it('should cut --file chunks only when they alone exceed the budget', () => {
+    const file = { ...chunk('file', 0.3, 1500), payload: { ...chunk('file', 0.3, 1500).payload, pinned: true } };
+    const result = fitChunksToBudget([chunk('a', 0.9, 100), file], 1000);
+
+    expect(result.chunks.map(c => c.id)).toEqual(['file']);
+    expect(result.truncated).toBe(1);
+    expect(result.dropped).toBe(1);
+    expect(result.pinned).toBe(1);
+  });
+});
@@ -0,0 +1,148 @@
+/**
+ * Usage Tracking Tests
+ * Tests for the pricing, log, and summaries behind `cv usage`
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import {
+  getModelPricing,
+  estimateUsageCost,
+  startUsageSession,
+  recordCompletionUsage,
+  recordEmbeddingUsage,
+  getSessionUsage,
+  flushUsageLog,
+  readUsageLog,
+  getUsageLogPath,
+  parseUsageSince,
+  summarizeUsage,
+  formatUsageFooter,
+  totalCompletionTokens,
+  UsageRecord
+} from '@cv-git/core';
+
+function record(overrides: Partial<UsageRecord> = {}): UsageRecord {
+  return {
+    timestamp: '2025-03-10T12:00:00.000Z',
+    command: 'explain',
+    provider: 'anthropic',
+    model: 'claude-sonnet-4-5-20250929',
+    kind: 'completion',
+    inputTokens: 1000,
+    outputTokens: 200,
+    estimated: false,
+    cost: 0.006,
+    ...overrides
+  };
+}
+
+describe('getModelPricing', () => {
+  it('matches dated snapshots and vendor-prefixed names', () => {
+    expect(getModelPricing('anthropic', 'claude-3-5-haiku-20241022')).toEqual({ input: 0.8, output: 4 });
+    expect(getModelPricing('openrouter', 'openai/gpt-4o-mini')).toEqual({ input: 0.15, output: 0.6 });
+    expect(getModelPricing('gemini', 'models/gemini-2.0-flash')).toEqual({ input: 0.1, output: 0.4 });
+  });
+
+  it('treats local providers as free and unknown models as unpriced', () => {
+    expect(getModelPricing('ollama', 'nomic-embed-text')).toEqual({ input: 0, output: 0 });
+    expect(getModelPricing('azure', 'my-deployment')).toBeNull();
+    expect(estimateUsageCost('azure', 'my-deployment', 1000)).toBeNull();
+  });
+
+  it('estimates cost from input and output prices', () => {
+    expect(estimateUsageCost('anthropic', 'claude-sonnet-4-5', 1_000_000, 100_000)).toBeCloseTo(4.5);
+  });
+});
+
+describe('usage log', () => {
+  let repoRoot: string;
+
+  beforeEach(() => {
+    repoRoot = fs.mkdtempSync(path.join(os.tmpdir(), 'cv-usage-'));
+  });
+
+  afterEach(() => {
+    startUsageSession('test', null);
+    fs.rmSync(repoRoot, { recursive: true, force: true });
+  });
+
+  it('appends session records and reads them back', async () => {
+    startUsageSession('index status', getUsageLogPath(repoRoot));
+    recordCompletionUsage('anthropic', 'claude-sonnet-4-5', 'prompt', 'answer', { inputTokens: 120, outputTokens: 30 });
+    recordEmbeddingUsage('openai', 'text-embedding-3-small', ['a'.repeat(400)]);
+    flushUsageLog();
+    flushUsageLog();
+
+    const records = await readUsageLog(repoRoot);
+    expect(records).toHaveLength(2);
+    expect(records[0]).toMatchObject({ command: 'index status', inputTokens: 120, outputTokens: 30, estimated: false });
+    expect(records[1]).toMatchObject({ kind: 'embedding', outputTokens: 0, estimated: true });
+    expect(records[1].inputTokens).toBeGreaterThan(0);
+    expect(getSessionUsage()).toHaveLength(2);
+
+    expect(await readUsageLog(repoRoot, new Date(Date.now() + 60_000))).toEqual([]);
+  });
+
+  it('keeps usage in memory without a log file', async () => {
+    startUsageSession('chat', null);
+    recordCompletionUsage('ollama', 'llama3', 'hello', 'hi');
+    flushUsageLog();
+
+    expect(getSessionUsage()[0]).toMatchObject({ cost: 0, estimated: true });
+    expect(await readUsageLog(repoRoot)).toEqual([]);
+  });
+});
+
+describe('parseUsageSince', () => {
+  const now = new Date('2025-03-10T12:00:00Z');
+
+  it('accepts relative ages and dates', () => {
+    expect(parseUsageSince('7d', now).toISOString()).toBe('2025-03-03T12:00:00.000Z');
+    expect(parseUsageSince('12h', now).toISOString()).toBe('2025-03-10T00:00:00.000Z');
+    expect(parseUsageSince('2025-01-31T00:00:00Z', now).toISOString()).toBe('2025-01-31T00:00:00.000Z');
+  });
+
+  it('rejects anything else', () => {
+    expect(() => parseUsageSince('last week', now)).toThrow('Invalid --since');
+  });
+});
+
+describe('summarizeUsage', () => {
+  it('totals by command, largest cost first, counting unpriced requests', () => {
+    const totals = summarizeUsage([
+      record({ command: 'explain', cost: 0.01 }),
+      record({ command: 'review', cost: 0.05 }),
+      record({ command: 'explain', provider: 'azure', model: 'my-deployment', cost: null })
+    ], 'command');
+
+    expect(totals.map(t => t.key)).toEqual(['review', 'explain']);
+    expect(totals[1]).toMatchObject({ requests: 2, inputTokens: 2000, cost: 0.01, unpriced: 1 });
+  });
+
+  it('groups by provider and model', () => {
+    const totals = summarizeUsage([record(), record({ provider: 'openai', model: 'gpt-4o', cost: 0.1 })], 'model');
+    expect(totals.map(t => t.key)).toEqual(['openai/gpt-4o', 'anthropic/claude-sonnet-4-5-20250929']);
+  });
+});
+
+describe('formatUsageFooter', () => {
+  it('summarizes cost and tokens, marking unpriced requests', () => {
+    expect(formatUsageFooter([])).toBeNull();
+    expect(formatUsageFooter([record({ cost: 0.003, inputTokens: 1000, outputTokens: 240 })])).toBe('~$0.003, 1,240 tokens');
+    expect(formatUsageFooter([record({ cost: 0.02 }), record({ cost: null })])).toBe('~$0.02+, 2,400 tokens');
+  });
+});
+
+describe('totalCompletionTokens', () => {
+  it('sums completions only and is estimated if any count was', () => {
+    expect(totalCompletionTokens([
+      record({ inputTokens: 1000, outputTokens: 200 }),
+      record({ kind: 'embedding', inputTokens: 50, outputTokens: 0 }),
+      record({ inputTokens: 300, outputTokens: 40, estimated: true })
+    ])).toEqual({ input: 1300, output: 240, estimated: true });
+    expect(totalCompletionTokens([])).toEqual({ input: 0, output: 0, estimated: false });
+  });
+});
@@ -0,0 +1,199 @@
+/**
+ * Vector Delta Tests
+ * Tests for the delta sync plan built from `git diff --name-status` since
+ * the last indexed commit: renames, first syncs and branch switches
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import { GitManager, SyncEngine, VectorManager, writeIndexMetadata } from '@cv-git/core';
+
+const identity = { provider: 'gemini', model: 'text-embedding-004', dimensions: 4 };
+const noDelta = { added: [], modified: [], deleted: [], unchanged: [] };
+
+describe('vector delta', () => {
+  let repoRoot: string;
+  let git: GitManager;
+  let vector: VectorManager;
+
+  const run = (...args: string[]): string =>
+    execFileSync('git', ['-c', 'user.name=Test', '-c', 'user.email=test@example.com', ...args], { cwd: repoRoot, encoding: 'utf-8' }).trim();
+
+  const write = (file: string, content: string) =>
+    fs.mkdir(path.dirname(path.join(repoRoot, file)), { recursive: true })
+      .then(() => fs.writeFile(path.join(repoRoot, file), content));
+
+  const commitAll = (message: string): string => {
+    run('add', '-A');
+    run('commit', '-q', '-m', message);
+    return run('rev-parse', 'HEAD');
+  };
+
+  const chunk = (file: string, startLine: number) => ({
+    id: `${file}:${startLine}:abcd`,
+    vector: [1, 0, 0, 0],
+    payload: {
+      id: `${file}:${startLine}:abcd`,
+      file,
+      language: 'typescript',
+      startLine,
+      endLine: startLine + 5,
+      text: 'export const x = 1;'
+    }
+  });
+
+  const plan = (currentFiles: string[], delta = noDelta) =>
+    (new SyncEngine(repoRoot, git, {} as any, {} as any, vector) as any).planVectorDelta(delta, currentFiles);
+
+  beforeEach(async () => {
+    repoRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-vector-delta-test-'));
+    run('init', '-q', '-b', 'main');
+    git = new GitManager(repoRoot);
+
+    vi.stubGlobal('fetch', vi.fn(async () =>
+      new Response(JSON.stringify({ embeddings: [{ values: [1, 0, 0, 0] }] }), { status: 200 })));
+    vector = new VectorManager({ url: '', backend: 'memory', geminiApiKey: 'test-key', vectorSize: 4, enableCache: false });
+    await vector.connect();
+  });
+
+  afterEach(async () => {
+    vi.unstubAllGlobals();
+    await vector.close();
+    await fs.rm(repoRoot, { recursive: true, force: true });
+  });
+
+  describe('getFileChangesSince', () => {
+    it('should report a pure rename as R with the old path and 100% similarity', async () => {
+      await write('src/old.ts', 'export function login(user: string) {\n  return user.length > 0;\n}\n');
+      const base = commitAll('add login');
+      run('mv', 'src/old.ts', 'src/auth.ts');
+      commitAll('rename');
+
+      expect(await git.getFileChangesSince(base)).toEqual([
+        { status: 'R', path: 'src/auth.ts', oldPath: 'src/old.ts', similarity: 100 }
+      ]);
+    });
+
+    it('should report added, modified and deleted files', async () => {
+      await write('src/a.ts', 'export const a = 1;\n');
+      await write('src/b.ts', 'export const b = 1;\n');
+      const base = commitAll('base');
+      await write('src/a.ts', 'export const a = 2;\n');
+      await fs.rm(path.join(repoRoot, 'src/b.ts'));
+      await write('src/c.ts', 'export const c = 1;\n');
+      commitAll('change');
+
+      const changes = await git.getFileChangesSince(base);
+      expect(changes).toEqual(expect.arrayContaining([
+        { status: 'M', path: 'src/a.ts' },
+        { status: 'D', path: 'src/b.ts' },
+        { status: 'A', path: 'src/c.ts' }
+      ]));
+      expect(changes).toHaveLength(3);
+    });
+
+    it('should diff across branches after a checkout', async () => {
+      await write('src/shared.ts', 'export const shared = 1;\n');
+      commitAll('base');
+      run('checkout', '-q', '-b', 'feature');
+      await write('src/feature.ts', 'export const feature = 1;\n');
+      const featureHead = commitAll('feature work');
+      run('checkout', '-q', 'main');
+      await write('src/main-only.ts', 'export const mainOnly = 1;\n');
+      commitAll('main work');
+
+      expect(await git.getFileChangesSince(featureHead)).toEqual(expect.arrayContaining([
+        { status: 'D', path: 'src/feature.ts' },
+        { status: 'A', path: 'src/main-only.ts' }
+      ]));
+    });
+  });
+
+  describe('moveFileVectors', () => {
+    it('should move chunks to the new path without touching other files', async () => {
+      const collection = vector.getCollectionNames().codeChunks;
+      await vector.upsertBatch(collection, [chunk('src/old.ts', 1), chunk('src/old.ts', 20), chunk('src/other.ts', 1)]);
+
+      expect(await vector.moveFileVectors(collection, 'src/old.ts', 'src/new.ts')).toBe(2);
+
+      expect(await vector.getFileChunks(['src/old.ts'])).toEqual([]);
+      const moved = await vector.getFileChunks(['src/new.ts']);
+      expect(moved.map(c => c.id).sort()).toEqual(['src/new.ts:1:abcd', 'src/new.ts:20:abcd']);
+      expect(moved.every(c => c.file === 'src/new.ts')).toBe(true);
+      expect(await vector.getFileChunks(['src/other.ts'])).toHaveLength(1);
+    });
+
+    it('should move nothing when the file has no vectors', async () => {
+      expect(await vector.moveFileVectors(vector.getCollectionNames().codeChunks, 'src/missing.ts', 'src/new.ts')).toBe(0);
+    });
+  });
+
+  describe('planVectorDelta', () => {
+    it('should rebuild on the first sync, when no commit was recorded', async () => {
+      await write('src/a.ts', 'export const a = 1;\n');
+      commitAll('base');
+
+      expect(await plan(['src/a.ts'])).toEqual({ rebuild: true, reembed: ['src/a.ts'], remove: [], renames: [] });
+    });
+
+    it('should rebuild when the recorded commit no longer exists', async () => {
+      await write('src/a.ts', 'export const a = 1;\n');
+      commitAll('base');
+      await writeIndexMetadata(repoRoot, identity, '0123456789abcdef0123456789abcdef01234567');
+
+      expect((await plan(['src/a.ts'])).rebuild).toBe(true);
+    });
+
+    it('should move the vectors of a pure rename instead of re-embedding', async () => {
+      await write('src/old.ts', 'export function login(user: string) {\n  return user.length > 0;\n}\n');
+      await write('src/keep.ts', 'export const keep = 1;\n');
+      await writeIndexMetadata(repoRoot, identity, commitAll('base'));
+      await vector.upsertBatch(vector.getCollectionNames().codeChunks, [chunk('src/old.ts', 1), chunk('src/keep.ts', 1)]);
+      run('mv', 'src/old.ts', 'src/auth.ts');
+      commitAll('rename');
+
+      // The content-hash delta sees the rename as a delete plus an add
+      const result = await plan(['src/auth.ts', 'src/keep.ts'], { ...noDelta, added: ['src/auth.ts'], deleted: ['src/old.ts'] });
+
+      expect(result).toEqual({ rebuild: false, reembed: [], remove: [], renames: [{ from: 'src/old.ts', to: 'src/auth.ts' }] });
+    });
+
+    it('should re-embed a rename whose content changed and drop the old path', async () => {
+      await write('src/old.ts', 'export function login(user: string) {\n  return user.length > 0;\n}\n');
+      await writeIndexMetadata(repoRoot, identity, commitAll('base'));
+      await vector.upsertBatch(vector.getCollectionNames().codeChunks, [chunk('src/old.ts', 1)]);
+      run('mv', 'src/old.ts', 'src/auth.ts');
+      await write('src/auth.ts', 'export function login(user: string) {\n  return user.trim().length > 0;\n}\n');
+      commitAll('rename and edit');
+
+      const result = await plan(['src/auth.ts']);
+
+      expect(result.rebuild).toBe(false);
+      expect(result.renames).toEqual([]);
+      expect(result.reembed).toEqual(['src/auth.ts']);
+      expect(result.remove.sort()).toEqual(['src/auth.ts', 'src/old.ts']);
+    });
+
+    it('should remove the old branch\'s files and embed the new branch\'s after a checkout', async () => {
+      await write('src/shared.ts', 'export const shared = 1;\n');
+      commitAll('base');
+      run('checkout', '-q', '-b', 'feature');
+      await write('src/feature.ts', 'export const feature = 1;\n');
+      await writeIndexMetadata(repoRoot, identity, commitAll('feature work'));
+      await vector.upsertBatch(vector.getCollectionNames().codeChunks, [chunk('src/shared.ts', 1), chunk('src/feature.ts', 1)]);
+      run('checkout', '-q', 'main');
+      await write('src/main-only.ts', 'export const mainOnly = 1;\n');
+      commitAll('main work');
+
+      const result = await plan(['src/shared.ts', 'src/main-only.ts']);
+
+      expect(result.rebuild).toBe(false);
+      expect(result.reembed).toEqual(['src/main-only.ts']);
+      expect(result.remove.sort()).toEqual(['src/feature.ts', 'src/main-only.ts']);
+      expect(result.renames).toEqual([]);
+    });
+  });
+});
@@ -0,0 +1,139 @@
+/**
+ * Vector Index Metadata Unit Tests
+ * Tests for detecting embedding provider/model/dimension mismatches
+ * and for the persisted index store in .cv/index
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  readIndexMetadata,
+  writeIndexMetadata,
+  clearIndexMetadata,
+  checkIndexCompatibility,
+  assertIndexCompatible,
+  writeIndexSnapshot,
+  readIndexSnapshotManifest,
+  readIndexSnapshotCollection,
+  clearIndexSnapshot,
+  INDEX_SCHEMA_VERSION
+} from '@cv-git/core';
+
+describe('Vector index metadata', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-index-meta-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(tempDir, { recursive: true, force: true });
+  });
+
+  it('should return null when no index has been written', async () => {
+    expect(await readIndexMetadata(tempDir)).toBeNull();
+  });
+
+  it('should round-trip metadata and preserve createdAt', async () => {
+    const first = await writeIndexMetadata(tempDir, { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 });
+    const second = await writeIndexMetadata(tempDir, { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 });
+
+    const stored = await readIndexMetadata(tempDir);
+    expect(stored?.provider).toBe('ollama');
+    expect(stored?.dimensions).toBe(768);
+    expect(second.createdAt).toBe(first.createdAt);
+  });
+
+  it('should replace warnings only for re-synced files', async () => {
+    const identity = { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 };
+    const warning = (file: string): any => ({ type: 'oversized_chunk', file, startLine: 1, endLine: 1, estimatedTokens: 9000, maxTokens: 8192 });
+
+    await writeIndexMetadata(tempDir, identity, undefined, undefined, { warnings: [warning('a.min.js'), warning('b.min.js')] });
+    await writeIndexMetadata(tempDir, identity, undefined, undefined, { files: ['a.min.js'], warnings: [] });
+    await writeIndexMetadata(tempDir, identity, 'abc123');
+
+    const stored = await readIndexMetadata(tempDir);
+    expect(stored?.warnings?.map(w => w.file)).toEqual(['b.min.js']);
+  });
+
+  it('should remove metadata on clear', async () => {
+    await writeIndexMetadata(tempDir, { provider: 'openai', model: 'text-embedding-3-small', dimensions: 1536 });
+    await clearIndexMetadata(tempDir);
+    expect(await readIndexMetadata(tempDir)).toBeNull();
+  });
+
+  it('should treat a missing index as compatible', () => {
+    const result = checkIndexCompatibility(null, { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 });
+    expect(result.compatible).toBe(true);
+  });
+
+  it('should report every mismatched field', () => {
+    const result = checkIndexCompatibility(
+      { provider: 'openai', model: 'text-embedding-3-small', dimensions: 1536 },
+      { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 }
+    );
+    expect(result.compatible).toBe(false);
+    expect(result.mismatches).toEqual(['provider', 'model', 'dimensions']);
+  });
+
+  it('should throw a clear error when providers are mixed', () => {
+    expect(() => assertIndexCompatible(
+      { provider: 'openai', model: 'text-embedding-3-small', dimensions: 1536 },
+      { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 }
+    )).toThrow(/cv sync --force/);
+  });
+});
+
+describe('Vector index store', () => {
+  let indexDir: string;
+  const fingerprint = { provider: 'ollama', model: 'nomic-embed-text', dimensions: 3 };
+
+  beforeEach(async () => {
+    indexDir = path.join(await fs.mkdtemp(path.join(os.tmpdir(), 'cv-index-store-test-')), 'index');
+  });
+
+  afterEach(async () => {
+    await fs.rm(path.dirname(indexDir), { recursive: true, force: true });
+  });
+
+  it('should round-trip vectors with the provider fingerprint', async () => {
+    const points = [
+      { id: 'auth/service.go:193-220', vector: [0.1, 0.2, 0.3], payload: { file: 'auth/service.go' } },
+      { id: 'auth/service.go:12-15', vector: [0.4, 0.5, 0.6], payload: { file: 'auth/service.go' } }
+    ];
+
+    await writeIndexSnapshot(indexDir, fingerprint, new Map([['repo_code_chunks', points]]), 'abc123');
+
+    const manifest = await readIndexSnapshotManifest(indexDir);
+    expect(manifest?.schemaVersion).toBe(INDEX_SCHEMA_VERSION);
+    expect(manifest?.fingerprint).toEqual(fingerprint);
+    expect(manifest?.lastIndexedCommit).toBe('abc123');
+    expect(manifest?.collections).toEqual({ repo_code_chunks: 2 });
+    expect(await readIndexSnapshotCollection(indexDir, 'repo_code_chunks')).toEqual(points);
+  });
+
+  it('should drop collections that are no longer present', async () => {
+    const point = { id: 'a', vector: [1, 2, 3], payload: {} };
+    await writeIndexSnapshot(indexDir, fingerprint, new Map([['repo_commits', [point]]]));
+    await writeIndexSnapshot(indexDir, fingerprint, new Map([['repo_code_chunks', [point]]]));
+
+    expect(await readIndexSnapshotCollection(indexDir, 'repo_commits')).toEqual([]);
+  });
+
+  it('should invalidate an index written with an unknown schema version', async () => {
+    await writeIndexSnapshot(indexDir, fingerprint, new Map());
+    const manifestPath = path.join(indexDir, 'manifest.json');
+    const manifest = JSON.parse(await fs.readFile(manifestPath, 'utf-8'));
+    await fs.writeFile(manifestPath, JSON.stringify({ ...manifest, schemaVersion: INDEX_SCHEMA_VERSION + 1 }));
+
+    expect(await readIndexSnapshotManifest(indexDir)).toBeNull();
+  });
+
+  it('should return null after clearing', async () => {
+    await writeIndexSnapshot(indexDir, fingerprint, new Map());
+    await clearIndexSnapshot(indexDir);
+    expect(await readIndexSnapshotManifest(indexDir)).toBeNull();
+  });
+});
@@ -0,0 +1,101 @@
+/**
+ * Workspace Index Tests
+ * Tests for merging the indexes of a workspace's repos into one
+ */
+
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import * as path from 'path';
+import * as os from 'os';
+import {
+  buildWorkspaceIndex,
+  workspaceFilePath,
+  getIndexDir,
+  writeIndexSnapshot,
+  writeIndexMetadata
+} from '@cv-git/core';
+
+const identity = { provider: 'openai', model: 'text-embedding-3-small', dimensions: 3 };
+
+const targets = { code_chunks: 'ws_code_chunks', docstrings: 'ws_docstrings', document_chunks: 'ws_document_chunks' };
+
+const point = (file: string, line: number) => ({
+  id: `${file}:${line}`,
+  vector: [0.1, 0.2, 0.3],
+  payload: { id: `${file}:${line}`, file, startLine: line, endLine: line + 2, text: 'func Handle() {}' }
+});
+
+describe('buildWorkspaceIndex', () => {
+  let root: string;
+
+  beforeEach(async () => {
+    root = await fs.mkdtemp(path.join(os.tmpdir(), 'cv-workspace-index-test-'));
+  });
+
+  afterEach(async () => {
+    await fs.rm(root, { recursive: true, force: true });
+  });
+
+  const indexRepo = async (name: string, commit: string, points: ReturnType<typeof point>[], fingerprint = identity) => {
+    const repoRoot = path.join(root, name);
+    await writeIndexSnapshot(getIndexDir(repoRoot), fingerprint, new Map([
+      [`${name}id_code_chunks`, points],
+      [`${name}id_commits`, [point('commit', 1)]]
+    ]), commit);
+    await writeIndexMetadata(repoRoot, fingerprint, commit);
+    return { name, path: name, root: repoRoot };
+  };
+
+  it('should prefix paths with the repo and keep each repo\'s commit', async () => {
+    const members = [
+      await indexRepo('api', 'a1b2c3d', [point('handlers/auth.go', 10)]),
+      await indexRepo('billing', 'e4f5a6b', [point('src/invoice.ts', 1), point('src/tax.ts', 5)])
+    ];
+
+    const index = await buildWorkspaceIndex(members, targets);
+
+    const chunks = index.collections.get('ws_code_chunks')!;
+    expect(chunks.map(chunk => chunk.id)).toEqual(['api/handlers/auth.go:10', 'billing/src/invoice.ts:1', 'billing/src/tax.ts:5']);
+    expect(chunks[0].payload).toEqual({
+      id: 'api/handlers/auth.go:10',
+      file: 'api/handlers/auth.go',
+      repo: 'api',
+      startLine: 10,
+      endLine: 12,
+      text: 'func Handle() {}'
+    });
+    // Commits only mean something in their own repo
+    expect([...index.collections.keys()]).toEqual(['ws_code_chunks']);
+    expect(index.repos).toEqual([
+      { name: 'api', path: 'api', commit: 'a1b2c3d', chunks: 1 },
+      { name: 'billing', path: 'billing', commit: 'e4f5a6b', chunks: 2 }
+    ]);
+    expect(index.fingerprint).toEqual(identity);
+  });
+
+  it('should skip repos that are not indexed or embedded differently', async () => {
+    const members = [
+      await indexRepo('api', 'a1b2c3d', [point('main.go', 1)]),
+      await indexRepo('web', 'c0ffee1', [point('app.ts', 1)], { ...identity, dimensions: 1536 }),
+      { name: 'docs', path: 'docs', root: path.join(root, 'docs') }
+    ];
+
+    const index = await buildWorkspaceIndex(members, targets);
+
+    expect(index.repos.map(repo => repo.name)).toEqual(['api']);
+    expect(index.skipped.map(skipped => skipped.name)).toEqual(['web', 'docs']);
+    expect(index.skipped[1].reason).toBe('not indexed');
+  });
+
+  it('should fail when no repo has been indexed', async () => {
+    await expect(buildWorkspaceIndex([{ name: 'api', path: 'api', root: path.join(root, 'api') }], targets))
+      .rejects.toThrow(/No repository in the workspace has been indexed/);
+  });
+});
+
+describe('workspaceFilePath', () => {
+  it('should join with forward slashes', () => {
+    expect(workspaceFilePath('services/api/', 'main.go')).toBe('services/api/main.go');
+    expect(workspaceFilePath('.', 'main.go')).toBe('main.go');
+  });
+});